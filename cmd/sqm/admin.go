@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var agentAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add an agent to a running 'sqm serve' or 'sqm run --admin'",
+	Long: `Create an agent with the server's provider and add it to the running
+collective at --server, without restarting it. Requires an API token.
+
+Example:
+  sqm agent add reviewer -c code.review,security --model claude-3-haiku-20240307`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		capsStr, _ := cmd.Flags().GetStringSlice("capabilities")
+		model, _ := cmd.Flags().GetString("model")
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		if len(capsStr) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: --capabilities is required")
+			os.Exit(1)
+		}
+
+		caps := make([]identity.CapabilityType, len(capsStr))
+		for i, c := range capsStr {
+			caps[i] = identity.CapabilityType(c)
+		}
+		body := map[string]interface{}{"name": args[0], "capabilities": caps, "model": model}
+
+		var snapshot collective.AgentSnapshot
+		if err := apiRequest(http.MethodPost, server, "/api/agents", token, body, &snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n  Agent %s added (SID %s)\n\n", snapshot.Name, snapshot.SID)
+	},
+}
+
+var agentPauseCmd = &cobra.Command{
+	Use:   "pause <sid>",
+	Short: "Stop an idle agent taking tasks until resumed",
+	Long: `Pause an agent of the collective in this process if one is active,
+otherwise of the running collective at --server (API token required). A
+paused agent doesn't bid; tasks queued for it wait. Working agents can't be
+paused.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		adminAgent(cmd, args[0], "pause")
+		fmt.Printf("\n  Agent %s paused\n\n", args[0])
+	},
+}
+
+var agentResumeCmd = &cobra.Command{
+	Use:   "resume <sid>",
+	Short: "Return an agent paused with 'sqm agent pause' to work",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		adminAgent(cmd, args[0], "resume")
+		fmt.Printf("\n  Agent %s resumed\n\n", args[0])
+	},
+}
+
+var agentRemoveCmd = &cobra.Command{
+	Use:   "remove <sid>",
+	Short: "Stop an agent and remove it from a running collective",
+	Long: `Like 'sqm agent stop', for the running collective at --server when none
+is active in this process (API token required). Tasks queued for the agent
+are reassigned.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		adminAgent(cmd, args[0], "")
+		fmt.Printf("\n  Agent %s stopped and removed from collective.\n\n", args[0])
+	},
+}
+
+// adminAgent pauses, resumes or (with no action) removes an agent of the
+// active collective, or of the one at --server
+func adminAgent(cmd *cobra.Command, sid, action string) {
+	var err error
+	if activeCollective != nil {
+		switch action {
+		case "pause":
+			err = activeCollective.PauseAgent(sid)
+		case "resume":
+			err = activeCollective.ResumeAgent(sid)
+		default:
+			err = activeCollective.Leave(sid)
+		}
+	} else {
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		path := "/api/agents/" + url.PathEscape(sid)
+		method := http.MethodDelete
+		if action != "" {
+			path += "/" + action
+			method = http.MethodPost
+		}
+		err = apiRequest(method, server, path, token, nil, nil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+	agentAddCmd.Flags().StringSliceP("capabilities", "c", []string{}, "Agent capabilities")
+	agentAddCmd.Flags().StringP("model", "m", "", "LLM model (default the server's)")
+	for _, cmd := range []*cobra.Command{agentAddCmd, agentPauseCmd, agentResumeCmd, agentRemoveCmd} {
+		cmd.Flags().String("server", "http://127.0.0.1:8420", "Server to manage when no collective is active")
+		cmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+		agentCmd.AddCommand(cmd)
+	}
+}
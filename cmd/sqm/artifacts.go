@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+var taskArtifactsCmd = &cobra.Command{
+	Use:   "artifacts [task-id]",
+	Short: "List, print or save the stored artifacts of a finished task",
+	Long: `List the artifacts a collective with storage kept for a finished task: its
+output, checkpoints, tool results and the files generated in the output.
+Each is stored once under the SHA-256 of its content (in the storage
+backend's blobs: a local directory, S3 or Cloud Storage), and checked
+against it when read.
+
+--get prints one artifact to stdout; -o saves them all under a directory.
+Artifacts are read from the collective in this process if one is active,
+otherwise from a running 'sqm serve' at --server.
+
+Example:
+  sqm task artifacts 3f2c...
+  sqm task artifacts 3f2c... --get file/main.go > main.go
+  sqm task artifacts 3f2c... -o ./out`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		taskID := args[0]
+		server, _ := cmd.Flags().GetString("server")
+		name, _ := cmd.Flags().GetString("get")
+		dir, _ := cmd.Flags().GetString("output")
+
+		artifacts, err := fetchArtifacts(server, taskID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		switch {
+		case name != "":
+			err = fmt.Errorf("task %s has no artifact %q", taskID, name)
+			for _, a := range artifacts {
+				if a.Name == name {
+					err = copyArtifact(server, a.Digest, os.Stdout)
+					break
+				}
+			}
+		case dir != "":
+			err = saveArtifacts(server, artifacts, dir)
+		default:
+			printArtifacts(taskID, artifacts)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// fetchArtifacts returns a task's artifacts from the active collective or
+// the server
+func fetchArtifacts(server, taskID string) ([]agent.Artifact, error) {
+	if activeCollective != nil {
+		return activeCollective.TaskArtifacts(context.Background(), taskID)
+	}
+	var resp struct {
+		Artifacts []agent.Artifact `json:"artifacts"`
+	}
+	err := apiRequest(http.MethodGet, server, "/api/tasks/"+url.PathEscape(taskID)+"/artifacts", "", nil, &resp)
+	return resp.Artifacts, err
+}
+
+// copyArtifact writes the content with a digest to w
+func copyArtifact(server, digest string, w io.Writer) error {
+	if activeCollective == nil {
+		return apiRequest(http.MethodGet, server, "/api/artifacts/"+digest, "", nil, w)
+	}
+	r, err := activeCollective.OpenArtifact(context.Background(), digest)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// saveArtifacts writes each artifact to dir/<name>
+func saveArtifacts(server string, artifacts []agent.Artifact, dir string) error {
+	for _, a := range artifacts {
+		if !storage.ValidKey(a.Name) {
+			return fmt.Errorf("artifact name %q is not a relative path", a.Name)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(a.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = copyArtifact(server, a.Digest, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", a.Name, err)
+		}
+		fmt.Printf("  %s (%d bytes)\n", path, a.Size)
+	}
+	return nil
+}
+
+// printArtifacts lists a task's artifacts as a table
+func printArtifacts(taskID string, artifacts []agent.Artifact) {
+	fmt.Printf("\n  Artifacts of task %s\n", taskID)
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	if len(artifacts) == 0 {
+		fmt.Println("  None stored.")
+		fmt.Println()
+		return
+	}
+	fmt.Printf("  %-32s %10s  %s\n", "NAME", "SIZE", "DIGEST")
+	for _, a := range artifacts {
+		fmt.Printf("  %-32s %10d  %s\n", a.Name, a.Size, a.Digest)
+	}
+	fmt.Println()
+}
+
+func init() {
+	taskArtifactsCmd.Flags().String("get", "", "Print the artifact with this name to stdout")
+	taskArtifactsCmd.Flags().StringP("output", "o", "", "Save every artifact under this directory")
+	taskArtifactsCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query when no collective is active")
+	taskCmd.AddCommand(taskArtifactsCmd)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+var attestCmd = &cobra.Command{
+	Use:   "attest <membership|consensus|advertisement> [proposal-id]",
+	Short: "Print a claim signed with the collective's own key",
+	Long: `Sign a collective-level claim with the collective's key, so other
+collectives and outside verifiers can trust it by checking one signature
+rather than one per agent:
+
+  membership     the agents in the collective and the keys they joined with
+  consensus      the outcome and votes of a decided consensus round
+  advertisement  the collective's agents, capabilities and teams, for
+                 federating with others, naming --endpoint
+
+The claim is taken from the collective in this process if one is active,
+otherwise from a running 'sqm serve' at --server. The collective's key is
+kept in ~/.squaremind/collective.key. Check an attestation with
+'sqm attest verify'.
+
+Examples:
+  sqm attest membership > members.json
+  sqm attest consensus 6f1c2a... --server http://prod:8080
+  sqm attest verify members.json --trust 3b6a27bc...`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind := args[0]
+		endpoint, _ := cmd.Flags().GetString("endpoint")
+		if (kind == collective.AttestConsensus) != (len(args) == 2) {
+			fmt.Fprintf(os.Stderr, "Error: a proposal ID is needed for consensus attestations, and only for them\n")
+			os.Exit(1)
+		}
+
+		var (
+			attestation *collective.Attestation
+			err         error
+		)
+		if activeCollective != nil {
+			switch kind {
+			case collective.AttestMembership:
+				attestation, err = activeCollective.AttestMembership()
+			case collective.AttestConsensus:
+				attestation, err = activeCollective.AttestConsensus(args[1])
+			case collective.AttestAdvertisement:
+				attestation, err = activeCollective.Advertise(endpoint)
+			default:
+				err = fmt.Errorf("unknown attestation %q: use membership, consensus or advertisement", kind)
+			}
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			path := "/api/attestations/" + kind
+			if len(args) == 2 {
+				path += "/" + url.PathEscape(args[1])
+			}
+			if endpoint != "" {
+				path += "?endpoint=" + url.QueryEscape(endpoint)
+			}
+			attestation = &collective.Attestation{}
+			err = apiRequest(http.MethodGet, server, path, "", nil, attestation)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		data, _ := json.MarshalIndent(attestation, "", "  ")
+		fmt.Println(string(data))
+	},
+}
+
+var attestVerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Verify an attestation written by 'sqm attest'",
+	Long: `Check an attestation's signature and print its claim. With --trust,
+only attestations signed by one of the given collective keys are accepted.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		trustHex, _ := cmd.Flags().GetStringSlice("trust")
+		trusted, err := parseTrustedKeys(trustHex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		var attestation collective.Attestation
+		if err := json.Unmarshal(data, &attestation); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v: %v\n", collective.ErrInvalidAttestation, err)
+			os.Exit(1)
+		}
+		if err := attestation.Verify(trusted...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var claim interface{}
+		_ = attestation.Decode(&claim)
+		pretty, _ := json.MarshalIndent(claim, "  ", "  ")
+
+		fmt.Printf("\n  Valid %s attestation from '%s' (%s)\n", attestation.Kind, attestation.Collective, attestation.CollectiveID)
+		fmt.Printf("  Issued:    %s\n", attestation.IssuedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Signed by: %s\n\n", hex.EncodeToString(attestation.Signer))
+		fmt.Printf("  %s\n\n", pretty)
+	},
+}
+
+func init() {
+	attestCmd.Flags().String("endpoint", "", "Where the collective accepts tasks, for advertisements (default the server's address)")
+	attestCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to ask when no collective is active")
+	attestVerifyCmd.Flags().StringSlice("trust", nil, "Hex public keys of the collectives whose attestations are accepted (default any valid signature)")
+	attestCmd.AddCommand(attestVerifyCmd)
+	rootCmd.AddCommand(attestCmd)
+}
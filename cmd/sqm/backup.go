@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/backup"
+	"github.com/square-mind/squaremind/pkg/config"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up and restore persisted state",
+	Long: `Archive the state in ~/.squaremind into a single file, and restore it.
+
+Components:
+  config      config file (without secrets), event triggers, migration manifest
+  keystore    API keys, tokens and other secrets, encrypted with a passphrase
+  tasks       scheduled tasks
+  memory      collective memory database
+  reputation  agent reputation history
+  artifacts   execution logs and the workflow library
+
+The passphrase is read from --passphrase-file or $SQM_BACKUP_PASSPHRASE.
+Without one the keystore is left out of the archive.`,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create [file]",
+	Short: "Write a backup archive",
+	Long: `Write a backup archive of the selected components (default: all) to file,
+or to sqm-backup-<timestamp>.tar.gz in the current directory.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := backupOptions(cmd)
+
+		path := fmt.Sprintf("sqm-backup-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+		if len(args) == 1 {
+			path = args[0]
+		}
+		if opts.Passphrase == "" {
+			opts.Components = withoutKeystore(opts.Components)
+			fmt.Fprintln(os.Stderr, "Note: no passphrase given; the keystore (API keys, tokens and other secrets) is not backed up")
+		}
+
+		manifest, err := backup.Create(path, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n  Backed up %d files to %s\n", len(manifest.Files), path)
+		printManifest(manifest)
+		fmt.Println()
+	},
+}
+
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Check a backup archive's integrity",
+	Long: `Check that every file in a backup archive matches the digest recorded in its
+manifest. With a passphrase the keystore is decrypted as well.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := backupOptions(cmd)
+
+		manifest, err := backup.Verify(args[0], opts.Passphrase)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n  %s is intact: %d files, created %s\n", args[0], len(manifest.Files), manifest.Created.Local().Format(time.RFC1123))
+		printManifest(manifest)
+		if manifest.Has(backup.ComponentKeystore) && opts.Passphrase == "" {
+			fmt.Println("  (keystore not decrypted; pass a passphrase to check it)")
+		}
+		fmt.Println()
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore state from a backup archive",
+	Long: `Restore the selected components (default: all in the archive) into
+~/.squaremind. The archive is verified before anything is written, and each
+file that is replaced is kept alongside with a .pre-restore suffix.
+
+Restoring the config keeps the API keys and tokens already configured unless
+the keystore is restored too. Stop 'sqm serve' before restoring.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := backupOptions(cmd)
+
+		manifest, err := backup.Verify(args[0], "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if opts.Passphrase == "" && manifest.Has(backup.ComponentKeystore) {
+			if len(opts.Components) > 0 && len(withoutKeystore(opts.Components)) < len(opts.Components) {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", backup.ErrPassphraseRequired)
+				os.Exit(1)
+			}
+			if len(opts.Components) == 0 {
+				opts.Components = withoutKeystore(manifest.Components)
+			}
+			fmt.Fprintln(os.Stderr, "Note: no passphrase given; the keystore is not restored")
+		}
+
+		result, err := backup.Restore(args[0], opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		names := make([]string, len(result.Components))
+		for i, c := range result.Components {
+			names[i] = string(c)
+		}
+		fmt.Printf("\n  Restored %s (%d files) into %s\n", strings.Join(names, ", "), len(result.Files), opts.Dir)
+		fmt.Print("  Run 'sqm migrate' if the backup came from an older release\n\n")
+	},
+}
+
+// backupOptions reads the flags shared by the backup commands
+func backupOptions(cmd *cobra.Command) backup.Options {
+	only, _ := cmd.Flags().GetStringSlice("only")
+	passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+
+	components, err := backup.ParseComponents(only)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	passphrase := os.Getenv("SQM_BACKUP_PASSPHRASE")
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+	return backup.Options{Dir: config.DefaultStateDir(), Components: components, Passphrase: passphrase}
+}
+
+// withoutKeystore drops the keystore from a component list. An empty list
+// (all components) becomes every component but the keystore.
+func withoutKeystore(components []backup.Component) []backup.Component {
+	if len(components) == 0 {
+		components = backup.AllComponents
+	}
+	var kept []backup.Component
+	for _, c := range components {
+		if c != backup.ComponentKeystore {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// printManifest lists an archive's components with their file counts and sizes
+func printManifest(manifest *backup.Manifest) {
+	for _, c := range manifest.Components {
+		files, size := 0, int64(0)
+		for _, f := range manifest.Files {
+			if f.Component == c {
+				files++
+				size += f.Size
+			}
+		}
+		fmt.Printf("    %-12s %3d files  %8d bytes\n", c, files, size)
+	}
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{backupCreateCmd, backupVerifyCmd, backupRestoreCmd} {
+		cmd.Flags().String("passphrase-file", "", "File holding the keystore passphrase (default $SQM_BACKUP_PASSPHRASE)")
+		backupCmd.AddCommand(cmd)
+	}
+	backupCreateCmd.Flags().StringSlice("only", nil, "Components to back up (default all)")
+	backupRestoreCmd.Flags().StringSlice("only", nil, "Components to restore (default all in the archive)")
+	rootCmd.AddCommand(backupCmd)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	sqmtest "github.com/square-mind/squaremind/pkg/testing"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Load-test the scheduler and market with simulated agents",
+	Long: `Spawn simulated agents (no LLM provider, so no tokens are spent), submit
+synthetic tasks of mixed complexity and capability requirements, and
+report:
+
+  throughput          tasks finished per second
+  assignment latency  from submission until an agent was assigned (mean,
+                      p50, p95, p99, max)
+  bids                bids received per assigned task
+  lock contention     contended mutex events and time spent blocked on
+                      mutexes while the load ran
+
+Each agent holds two of six capabilities and each task requires one, dealt
+from --seed, so runs are repeatable and can be compared before and after a
+scheduler or market change. Use --json to keep the results.
+
+Example:
+  sqm bench --agents 50 --tasks 2000
+  sqm bench -n 20 -m 500 --concurrency 100 --bid-timeout 10ms --json > before.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		agents, _ := cmd.Flags().GetInt("agents")
+		tasks, _ := cmd.Flags().GetInt("tasks")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		bidTimeout, _ := cmd.Flags().GetDuration("bid-timeout")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if agents < 1 || tasks < 1 {
+			fmt.Fprintf(os.Stderr, "Error: --agents and --tasks must be at least 1\n")
+			os.Exit(1)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		if !asJSON {
+			fmt.Printf("\n  Benchmarking %d simulated agents with %d tasks...\n", agents, tasks)
+		}
+		report, err := sqmtest.RunLoad(ctx, sqmtest.LoadConfig{
+			Agents:      agents,
+			Tasks:       tasks,
+			Concurrency: concurrency,
+			BidTimeout:  bidTimeout,
+			Seed:        seed,
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(report)
+			return
+		}
+		printBenchReport(report, err != nil)
+	},
+}
+
+// printBenchReport renders a load test's measurements
+func printBenchReport(r *sqmtest.LoadReport, interrupted bool) {
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	if interrupted {
+		fmt.Println("  Interrupted: results cover the tasks finished so far")
+	}
+	fmt.Printf("  Tasks: %d   Completed: %d   Failed: %d   Duration: %s\n",
+		r.Tasks, r.Completed, r.Failed, r.Duration.Round(time.Millisecond))
+	fmt.Printf("  Throughput: %.1f tasks/s\n", r.Throughput)
+
+	a := r.Assignment
+	fmt.Printf("\n  %-20s %10s %10s %10s %10s %10s\n", "", "MEAN", "P50", "P95", "P99", "MAX")
+	fmt.Printf("  %-20s %10s %10s %10s %10s %10s\n", "Assignment latency",
+		benchDuration(a.Mean), benchDuration(a.P50), benchDuration(a.P95), benchDuration(a.P99), benchDuration(a.Max))
+
+	fmt.Printf("\n  Bids per task: %.1f mean (min %d, max %d)\n", r.BidsMean, r.BidsMin, r.BidsMax)
+	fmt.Printf("  Lock contention: %d contended events, %s blocked\n", r.LockContentions, benchDuration(r.LockWait))
+	fmt.Println()
+}
+
+// benchDuration rounds a duration for display
+func benchDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+func init() {
+	benchCmd.Flags().IntP("agents", "n", 20, "Simulated agents to spawn")
+	benchCmd.Flags().IntP("tasks", "m", 500, "Synthetic tasks to submit")
+	benchCmd.Flags().Int("concurrency", 0, "Tasks submitted at once (default: one per agent)")
+	benchCmd.Flags().Duration("bid-timeout", 5*time.Millisecond, "Market bid collection period")
+	benchCmd.Flags().Int64("seed", 1, "Seed for the task mix and agent capabilities")
+	benchCmd.Flags().Bool("json", false, "Print the results as JSON")
+	rootCmd.AddCommand(benchCmd)
+}
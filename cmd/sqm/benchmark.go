@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/benchmark"
+)
+
+var agentBenchmarkCmd = &cobra.Command{
+	Use:   "benchmark [sid]",
+	Short: "Benchmark an agent's capability and attach a signed proof",
+	Long: `Run a standard eval suite against an agent. The score is signed by the
+collective and attached to the agent's capability as a proof, and the agent
+then matches tasks needing it at a boosted proficiency until the proof
+expires. Code suites run the agent's answers against held-back tests in
+--sandbox.
+
+Examples:
+  sqm agent benchmark --list
+  sqm agent benchmark <sid> --suite go-basics --sandbox process
+  sqm agent benchmark <sid> --suite security`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		list, _ := cmd.Flags().GetBool("list")
+		if list {
+			fmt.Println("\n  Benchmark Suites")
+			fmt.Println("  ─────────────────────────────────────────────────────────────")
+			for _, s := range benchmark.Suites() {
+				fmt.Printf("  %-18s %-16s %d cases\n", s.Name, s.Capability, len(s.Cases))
+			}
+			fmt.Println()
+			return
+		}
+		if len(args) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: an agent SID is required")
+			os.Exit(1)
+		}
+		if activeCollective == nil {
+			fmt.Fprintln(os.Stderr, "No collective initialized.")
+			os.Exit(1)
+		}
+
+		name, _ := cmd.Flags().GetString("suite")
+		kind, _ := cmd.Flags().GetString("sandbox")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		suite, err := benchmark.Lookup(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		executor, err := openSandbox(kind)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		report, err := activeCollective.Benchmark(ctx, args[0], suite, executor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON {
+			data, _ := json.MarshalIndent(report, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		fmt.Printf("\n  Benchmark %s (%s) for %s\n", report.Suite, report.Capability, report.AgentSID)
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		for _, c := range report.Cases {
+			fmt.Printf("  %-20s %.2f  %s\n", c.Name, c.Score, c.Detail)
+		}
+		fmt.Printf("\n  Score: %.2f  Tokens: %d  Duration: %s\n", report.Score, report.TokensUsed, report.Duration.Round(time.Millisecond))
+		fmt.Printf("  Proof signed by %s, valid until %s\n\n", report.Proof.Signer, report.Proof.ExpiresAt.Format("2006-01-02 15:04"))
+	},
+}
+
+func init() {
+	agentBenchmarkCmd.Flags().String("suite", "go-basics", "Suite to run, by name or capability")
+	agentBenchmarkCmd.Flags().String("sandbox", "process", "Sandbox for code suites (process or container)")
+	agentBenchmarkCmd.Flags().Bool("list", false, "List the standard suites")
+	agentBenchmarkCmd.Flags().Bool("json", false, "Print the report as JSON")
+	agentCmd.AddCommand(agentBenchmarkCmd)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var capabilityCmd = &cobra.Command{
+	Use:   "capability",
+	Short: "List and define agent capabilities",
+}
+
+var capabilityListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List built-in and custom capabilities",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("\n  Capabilities")
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		for _, def := range identity.DefaultCapabilityRegistry().List() {
+			kind := "custom"
+			if def.Builtin {
+				kind = "built-in"
+			}
+			fmt.Printf("  %-20s %-9s %s\n", def.Type, kind, def.Description)
+			if len(def.Parents) > 0 {
+				parents := make([]string, len(def.Parents))
+				for i, p := range def.Parents {
+					parents[i] = string(p)
+				}
+				share := def.Inheritance
+				if share == 0 {
+					share = identity.DefaultInheritance
+				}
+				fmt.Printf("  %-20s counts %.0f%% towards %s\n", "", share*100, strings.Join(parents, ", "))
+			}
+			targets := make([]string, 0, len(def.Satisfies))
+			for target := range def.Satisfies {
+				targets = append(targets, string(target))
+			}
+			sort.Strings(targets)
+			for _, target := range targets {
+				share := def.Satisfies[identity.CapabilityType(target)]
+				fmt.Printf("  %-20s counts %.0f%% towards %s\n", "", share*100, target)
+			}
+		}
+		fmt.Println()
+	},
+}
+
+var capabilityDefineCmd = &cobra.Command{
+	Use:   "define [name]",
+	Short: "Define or update a custom capability",
+	Long: `Define a custom capability, saved to ~/.squaremind/capabilities.yaml.
+
+A capability counts partially towards its parents, so agents that have it
+can take tasks requiring the parent at a reduced match score:
+
+  sqm capability define code.refactor.go --parent code.refactor \
+    --description "Refactor Go code" --inheritance 0.8
+  sqm capability define data.sql --satisfies analysis=0.3`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		description, _ := cmd.Flags().GetString("description")
+		parents, _ := cmd.Flags().GetStringSlice("parent")
+		inheritance, _ := cmd.Flags().GetFloat64("inheritance")
+		rules, _ := cmd.Flags().GetStringSlice("satisfies")
+
+		def := identity.CapabilityDefinition{
+			Type:        identity.CapabilityType(args[0]),
+			Description: description,
+			Inheritance: inheritance,
+		}
+		for _, p := range parents {
+			def.Parents = append(def.Parents, identity.CapabilityType(p))
+		}
+		if len(rules) > 0 {
+			def.Satisfies = make(map[identity.CapabilityType]float64, len(rules))
+			for _, rule := range rules {
+				target, share, ok := strings.Cut(rule, "=")
+				value, err := strconv.ParseFloat(share, 64)
+				if !ok || err != nil {
+					fmt.Fprintf(os.Stderr, "Error: --satisfies %q must be capability=share\n", rule)
+					os.Exit(1)
+				}
+				def.Satisfies[identity.CapabilityType(target)] = value
+			}
+		}
+
+		registry := identity.DefaultCapabilityRegistry()
+		if err := registry.Register(def); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		path := config.DefaultCapabilitiesPath()
+		if err := registry.SaveFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  Defined capability %s in %s\n", def.Type, path)
+	},
+}
+
+var capabilityRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove a custom capability",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registry := identity.DefaultCapabilityRegistry()
+		if err := registry.Remove(identity.CapabilityType(args[0])); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		path := config.DefaultCapabilitiesPath()
+		if err := registry.SaveFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  Removed capability %s\n", args[0])
+	},
+}
+
+// warnUnknownCapabilities notes capabilities that aren't registered, which
+// only match requirements naming them exactly
+func warnUnknownCapabilities(caps []identity.CapabilityType) {
+	registry := identity.DefaultCapabilityRegistry()
+	for _, c := range caps {
+		if !registry.Known(c) {
+			fmt.Fprintf(os.Stderr, "Note: %s is not a known capability; define it with 'sqm capability define %s'\n", c, c)
+		}
+	}
+}
+
+func init() {
+	capabilityDefineCmd.Flags().String("description", "", "What the capability covers")
+	capabilityDefineCmd.Flags().StringSlice("parent", nil, "Broader capability this one specialises (repeatable)")
+	capabilityDefineCmd.Flags().Float64("inheritance", 0, "Share of proficiency counted towards each parent (default 0.5)")
+	capabilityDefineCmd.Flags().StringSlice("satisfies", nil, "Other capability this one counts towards, as capability=share")
+
+	capabilityCmd.AddCommand(capabilityListCmd)
+	capabilityCmd.AddCommand(capabilityDefineCmd)
+	capabilityCmd.AddCommand(capabilityRemoveCmd)
+	rootCmd.AddCommand(capabilityCmd)
+}
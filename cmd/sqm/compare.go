@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [task]",
+	Short: "Run a task on several models and compare the results",
+	Long: `Run the same task on two or more models in parallel, have the outputs
+critiqued and rank the models by quality, with the tokens and time each
+took. Without --judge, each output is reviewed by the other models.
+
+Models are given as [provider:]model:
+  claude:claude-sonnet-4-20250514   Anthropic (ANTHROPIC_API_KEY)
+  openai:gpt-4o                     OpenAI (OPENAI_API_KEY)
+  local:llama3@http://localhost:11434/v1/chat/completions
+                                    An OpenAI-compatible local server
+The provider may be left out of claude-, gpt-, o1 and o3 models.
+
+Examples:
+  sqm compare "Design a rate limiter for our API" -m claude-sonnet-4-20250514 -m gpt-4o
+  sqm compare "Review this diff for races" -r code.review \
+    -m claude-sonnet-4-20250514 -m local:qwen2.5-coder@http://localhost:8080/v1/chat/completions \
+    --judge claude-opus-4-20250514`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		models, _ := cmd.Flags().GetStringArray("model")
+		judgeModel, _ := cmd.Flags().GetString("judge")
+		requires, _ := cmd.Flags().GetStringSlice("requires")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		specs := make([]collective.ModelSpec, len(models))
+		for i, m := range models {
+			spec, err := parseModelSpec(m)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			specs[i] = spec
+		}
+
+		c := activeCollective
+		if c == nil {
+			c = collective.NewCollective("compare", collective.DefaultCollectiveConfig())
+		}
+		if judgeModel != "" {
+			spec, err := parseModelSpec(judgeModel)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: judge: %v\n", err)
+				os.Exit(1)
+			}
+			judge, err := agent.NewAgent(agent.AgentConfig{Name: judgeModel, Provider: spec.Provider, Model: spec.Model})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: judge: %v\n", err)
+				os.Exit(1)
+			}
+			c.SetJudge(judge)
+		}
+
+		capTypes := make([]identity.CapabilityType, len(requires))
+		for i, r := range requires {
+			capTypes[i] = identity.CapabilityType(r)
+		}
+		task := agent.NewTask(args[0], capTypes)
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		if !asJSON {
+			fmt.Printf("\n  Comparing %d models...\n", len(specs))
+		}
+		report, err := c.Compare(ctx, task, specs)
+		if report == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(report)
+			return
+		}
+		printComparison(report)
+	},
+}
+
+// parseModelSpec reads a [provider:]model argument, with the local
+// provider's endpoint after an @
+func parseModelSpec(arg string) (collective.ModelSpec, error) {
+	vendor, model, ok := strings.Cut(arg, ":")
+	if !ok {
+		vendor, model = "", arg
+		switch {
+		case strings.HasPrefix(model, "claude-"):
+			vendor = "claude"
+		case strings.HasPrefix(model, "gpt-"), strings.HasPrefix(model, "o1"), strings.HasPrefix(model, "o3"):
+			vendor = "openai"
+		}
+	}
+	if model == "" {
+		return collective.ModelSpec{}, fmt.Errorf("model %q: no model named", arg)
+	}
+
+	spec := collective.ModelSpec{Name: arg, Model: model}
+	switch vendor {
+	case "claude", "anthropic":
+		key := apiKey
+		if key == "" {
+			key = cfg.GetAnthropicKey()
+		}
+		if key == "" {
+			return spec, fmt.Errorf("model %q: no Anthropic API key configured", arg)
+		}
+		spec.Provider = llm.NewClaudeProvider(key)
+	case "openai":
+		key := cfg.GetOpenAIKey()
+		if key == "" {
+			return spec, fmt.Errorf("model %q: no OpenAI API key configured", arg)
+		}
+		spec.Provider = llm.NewOpenAIProvider(key)
+	case "local":
+		name, endpoint, ok := strings.Cut(model, "@")
+		if !ok || name == "" || endpoint == "" {
+			return spec, fmt.Errorf("model %q: want local:<model>@<endpoint>", arg)
+		}
+		spec.Model, spec.Provider = name, llm.NewLocalProvider(endpoint, name)
+	case "":
+		return spec, fmt.Errorf("model %q: name its provider, e.g. claude:%s or openai:%s", arg, model, model)
+	default:
+		return spec, fmt.Errorf("model %q: unknown provider %q (claude, openai or local)", arg, vendor)
+	}
+	return spec, nil
+}
+
+// printComparison renders a model comparison, best model first
+func printComparison(r *collective.ModelComparison) {
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	fmt.Printf("  Task:  %s\n", r.Task)
+	fmt.Printf("  Judge: %s   Duration: %s\n", r.Judge, r.Duration.Round(time.Millisecond))
+
+	fmt.Printf("\n  %-4s  %-36s  %7s  %7s  %8s\n", "RANK", "MODEL", "QUALITY", "TOKENS", "TIME")
+	for _, m := range r.Results {
+		quality := fmt.Sprintf("%.2f", m.Quality)
+		if m.Error != "" {
+			quality = "failed"
+		} else if len(m.Judgements) == 0 {
+			quality = "-"
+		}
+		fmt.Printf("  %-4d  %-36s  %7s  %7d  %8s\n", m.Rank, truncateLine(m.Name, 36), quality, m.TokensUsed, m.Duration.Round(time.Millisecond))
+	}
+
+	for _, m := range r.Results {
+		fmt.Printf("\n  [%d] %s\n", m.Rank, m.Name)
+		if m.Error != "" {
+			fmt.Printf("      Error: %s\n", m.Error)
+			continue
+		}
+		for _, j := range m.Judgements {
+			fmt.Printf("      %s: %.2f", j.Judge, j.Quality)
+			if j.Feedback != "" {
+				fmt.Printf("  %s", truncateLine(j.Feedback, 100))
+			}
+			fmt.Println()
+		}
+		fmt.Printf("%s\n", indentText(m.Output, false))
+	}
+
+	if r.Winner != "" {
+		fmt.Printf("\n  Winner: %s\n", r.Winner)
+	}
+	fmt.Println()
+}
+
+func init() {
+	compareCmd.Flags().StringArrayP("model", "m", nil, "Model to compare, [provider:]model (repeat for each)")
+	compareCmd.Flags().String("judge", "", "Model critiquing every output, [provider:]model (default: the models review each other)")
+	compareCmd.Flags().StringSliceP("requires", "r", []string{}, "Capabilities the task requires")
+	compareCmd.Flags().Bool("json", false, "Print the comparison as JSON")
+	rootCmd.AddCommand(compareCmd)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Configuration management",
+	Long: `Get, set and list the settings in ~/.squaremind/config.yaml.
+
+Settings can be grouped into named profiles that override the base ones, so
+one file can hold, say, a work and a personal API key. --profile (or
+$SQM_PROFILE) selects a profile for any command; with config set and unset
+it edits that profile instead of the base settings.
+
+Example:
+  sqm config set api-key sk-ant-...
+  sqm config set --profile work api-key sk-ant-...
+  sqm config set --profile work max-agents 20
+  sqm --profile work swarm "Audit the billing service"`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Print a configuration value",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		requireProfile()
+		value, err := cfg.Get(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Set a configuration value",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, value := args[0], args[1]
+		editConfig(func(c *config.Config) error { return c.Set(key, value) })
+		fmt.Printf("\n  %s set%s.\n\n", key, profileSuffix())
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset [key]",
+	Short: "Remove a configuration value",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := args[0]
+		editConfig(func(c *config.Config) error { return c.Unset(key) })
+		fmt.Printf("\n  %s unset%s.\n\n", key, profileSuffix())
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configuration values",
+	Run: func(cmd *cobra.Command, args []string) {
+		requireProfile()
+		reveal, _ := cmd.Flags().GetBool("show-secrets")
+
+		// Where each value comes from: the selected profile or the base settings
+		var profile *config.Config
+		if name := activeProfile(); name != "" {
+			if raw, err := config.Load(); err == nil {
+				profile, _ = raw.Profile(name, false)
+			}
+		}
+
+		fmt.Println()
+		for _, k := range config.Keys {
+			value, _ := cfg.Get(k.Name)
+			if k.Secret && !reveal {
+				value = maskSecret(value)
+			}
+			source := ""
+			if profile != nil {
+				if v, _ := profile.Get(k.Name); v != "" {
+					source = "  (profile " + activeProfile() + ")"
+				}
+			}
+			if value == "" {
+				value = "-"
+			}
+			fmt.Printf("  %-20s %s%s\n", k.Name, value, source)
+		}
+
+		if raw, err := config.Load(); err == nil && len(raw.Profiles) > 0 {
+			fmt.Printf("\n  Profiles: %s\n", strings.Join(raw.ProfileNames(), ", "))
+		}
+		fmt.Println()
+	},
+}
+
+// activeProfile returns the profile selected by --profile or $SQM_PROFILE
+func activeProfile() string {
+	if profileName != "" {
+		return profileName
+	}
+	return os.Getenv("SQM_PROFILE")
+}
+
+// profileSuffix names the profile a config edit applied to
+func profileSuffix() string {
+	if name := activeProfile(); name != "" {
+		return " in profile " + name
+	}
+	return ""
+}
+
+// loadConfig reads the config file with the active profile applied
+func loadConfig() (*config.Config, error) {
+	c, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if name := activeProfile(); name != "" {
+		return c.WithProfile(name)
+	}
+	return c, nil
+}
+
+// requireProfile exits if the active profile doesn't exist. Config commands
+// that only read settings use it, since the profile isn't created for them.
+func requireProfile() {
+	if _, err := loadConfig(); isUnknownProfile(err) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// editConfig applies edit to the base settings, or the active profile's
+// (creating it), and saves the config file
+func editConfig(edit func(*config.Config) error) {
+	c, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	target := c
+	if name := activeProfile(); name != "" {
+		target, _ = c.Profile(name, true)
+	}
+	if err := edit(target); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := c.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// maskSecret shows just enough of a secret to tell which one is set
+func maskSecret(s string) string {
+	switch {
+	case s == "":
+		return ""
+	case len(s) <= 8:
+		return "****"
+	}
+	return s[:4] + "..." + s[len(s)-4:]
+}
+
+// isUnknownProfile reports whether loading the config failed only because
+// the selected profile doesn't exist yet
+func isUnknownProfile(err error) bool {
+	return errors.Is(err, config.ErrUnknownProfile)
+}
+
+func init() {
+	configListCmd.Flags().Bool("show-secrets", false, "Print API keys and webhooks in full")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/cli"
+)
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Open the web dashboard for the collective",
+	Long: `Start the collective and serve a web dashboard that visualizes agents,
+task queues, the knowledge graph, reputation trends and consensus rounds.
+
+The dashboard updates live from the collective event stream.
+
+Example:
+  sqm dashboard --addr 127.0.0.1:8420`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if activeCollective == nil {
+			fmt.Fprintln(os.Stderr, "No collective initialized. Run 'sqm init <name>' first.")
+			os.Exit(1)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		if err := activeCollective.Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting collective: %v\n", err)
+			os.Exit(1)
+		}
+		defer activeCollective.Stop()
+
+		fmt.Println(cli.SmallBanner())
+		fmt.Printf("  %sDashboard:%s http://%s/\n", cli.Bold, cli.Reset, dashboardAddr)
+		fmt.Println("  Press Ctrl+C to stop")
+
+		if err := newServer().ListenAndServe(ctx, dashboardAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Dashboard error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("\n  Shutting down...")
+	},
+}
+
+var dashboardAddr string
+
+func init() {
+	dashboardCmd.Flags().StringVar(&dashboardAddr, "addr", "127.0.0.1:8420", "Address to serve the dashboard on")
+	rootCmd.AddCommand(dashboardCmd)
+}
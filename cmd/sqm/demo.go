@@ -12,6 +12,7 @@ import (
 	"github.com/square-mind/squaremind/pkg/agent"
 	"github.com/square-mind/squaremind/pkg/cli"
 	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/coordination"
 	"github.com/square-mind/squaremind/pkg/identity"
 	"github.com/square-mind/squaremind/pkg/llm"
 )
@@ -71,6 +72,7 @@ func runDemo(cmd *cobra.Command, args []string) {
 		MaxAgents:          10,
 		ConsensusThreshold: 0.67,
 		ReputationDecay:    0.01,
+		BidThreshold:       &coordination.BidThreshold{Step: 0.1}, // Relaxed to the default floor if nobody bids
 	})
 	spinner.Stop(true)
 
@@ -197,9 +199,10 @@ func runDemo(cmd *cobra.Command, args []string) {
 	fmt.Println()
 	fmt.Printf("  %s┌─ MARKET BIDDING ─────────────────────────┐%s\n", cli.Yellow, cli.Reset)
 
+	threshold := c.GetMarket().BidThreshold()
 	for _, a := range spawnedAgents {
 		score := a.Capabilities.MatchScore(task.Required)
-		if score > 0.3 {
+		if score >= threshold.Floor {
 			time.Sleep(200 * time.Millisecond)
 			fmt.Printf("  %s│%s  %s%-15s%s bid: capability=%.2f stake=%.1f %s│%s\n",
 				cli.Yellow, cli.Reset,
@@ -224,7 +227,7 @@ func runDemo(cmd *cobra.Command, args []string) {
 	spinner.Start()
 
 	// Actually call the LLM
-	result, err := c.Submit(task)
+	result, err := c.SubmitCtx(ctx, task)
 
 	if err != nil {
 		spinner.Stop(false)
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/server"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+// pingTimeout bounds each provider ping
+const pingTimeout = 15 * time.Second
+
+// minTokenLength is the shortest API bearer token doctor doesn't flag as weak
+const minTokenLength = 16
+
+// daemonInfo is what a running server reports about itself on /healthz
+type daemonInfo struct {
+	Version  string                   `json:"version"`
+	Protocol int                      `json:"protocol"`
+	Health   *collective.HealthReport `json:"health"`
+}
+
+// fetchDaemon reads the status of a running server
+func fetchDaemon(server string) (daemonInfo, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(strings.TrimRight(server, "/") + "/healthz")
+	if err != nil {
+		return daemonInfo{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return daemonInfo{}, fmt.Errorf("server returned %s", resp.Status)
+	}
+	var info daemonInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return daemonInfo{}, err
+	}
+	return info, nil
+}
+
+// checkConfig verifies that the config file parses and its settings make sense
+func checkConfig(path string) collective.HealthCheck {
+	check := collective.HealthCheck{Name: "config", Score: 1}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		check.Detail = "no config file; using defaults and environment"
+		return check
+	}
+	c, err := config.LoadFromPath(path)
+	if err != nil {
+		check.Score = 0
+		check.Detail = err.Error()
+		check.Remediations = append(check.Remediations, fmt.Sprintf("fix the YAML in %s, or remove it and re-run 'sqm config set'", path))
+		return check
+	}
+
+	for i, t := range c.APITokens {
+		if t.Token == "" || t.Submitter == "" {
+			check.Score = 0.5
+			check.Remediations = append(check.Remediations, fmt.Sprintf("api_tokens entry %d needs both token and submitter", i+1))
+		}
+	}
+	if c.TokenBudget < 0 {
+		check.Score = 0.5
+		check.Remediations = append(check.Remediations, "token_budget can't be negative; use 0 for unlimited")
+	}
+	if c.WorkflowIndex != "" && !strings.HasPrefix(c.WorkflowIndex, "http://") && !strings.HasPrefix(c.WorkflowIndex, "https://") {
+		check.Score = 0.5
+		check.Remediations = append(check.Remediations, "workflow_index should be an http(s) URL")
+	}
+	check.Detail = path
+	if len(check.Remediations) > 0 {
+		check.Detail = fmt.Sprintf("%s has %d problem(s)", path, len(check.Remediations))
+	}
+	return check
+}
+
+// checkAPIKeys sends each configured provider a one-token completion to
+// confirm its key is accepted. With ping false the keys are only counted.
+func checkAPIKeys(ctx context.Context, anthropicKey, openaiKey string, ping bool) collective.HealthCheck {
+	check := collective.HealthCheck{Name: "api keys", Score: 1}
+
+	var providers []llm.Provider
+	if anthropicKey != "" {
+		providers = append(providers, llm.NewClaudeProvider(anthropicKey))
+	}
+	if openaiKey != "" {
+		providers = append(providers, llm.NewOpenAIProvider(openaiKey))
+	}
+	if len(providers) == 0 {
+		check.Score = 0
+		check.Detail = "no LLM API key configured; agents can't run tasks"
+		check.Remediations = append(check.Remediations, "run 'sqm config set api-key <key>' or export ANTHROPIC_API_KEY (or OPENAI_API_KEY)")
+		return check
+	}
+	if !ping {
+		check.Detail = fmt.Sprintf("%d configured (not pinged)", len(providers))
+		return check
+	}
+
+	valid := 0
+	for _, p := range providers {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		_, err := p.Complete(pingCtx, llm.CompletionRequest{Prompt: "ping", MaxTokens: 1})
+		cancel()
+
+		switch {
+		case err == nil:
+			valid++
+		case isAuthError(err.Error()):
+			key := "api-key"
+			if p.Name() == "openai" {
+				key = "openai-key"
+			}
+			check.Remediations = append(check.Remediations, fmt.Sprintf("the %s key was rejected; set a new one with 'sqm config set %s <key>'", p.Name(), key))
+		default:
+			check.Remediations = append(check.Remediations, fmt.Sprintf("couldn't reach %s (%v); check the network and any proxy settings", p.Name(), err))
+		}
+	}
+	check.Score = float64(valid) / float64(len(providers))
+	check.Detail = fmt.Sprintf("%d of %d keys accepted", valid, len(providers))
+	return check
+}
+
+// checkKeystore verifies the secrets in the config file are stored safely:
+// the file is private to its owner and bearer tokens are unique and long enough
+func checkKeystore(path string) collective.HealthCheck {
+	check := collective.HealthCheck{Name: "keystore", Score: 1, Detail: "no stored secrets"}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return check
+	}
+	c, err := config.LoadFromPath(path)
+	if err != nil {
+		check.Score = 0
+		check.Detail = "config file unreadable"
+		return check
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		check.Score = 0
+		check.Remediations = append(check.Remediations, fmt.Sprintf("%s is readable by other users (%v); run 'chmod 600 %s'", path, info.Mode().Perm(), path))
+	}
+	seen := make(map[string]string, len(c.APITokens))
+	for _, t := range c.APITokens {
+		if t.Token == "" {
+			continue
+		}
+		if other, ok := seen[t.Token]; ok {
+			check.Score = 0
+			check.Remediations = append(check.Remediations, fmt.Sprintf("submitters %q and %q share a bearer token; give each its own", other, t.Submitter))
+		}
+		seen[t.Token] = t.Submitter
+		if len(t.Token) < minTokenLength {
+			if check.Score > 0.5 {
+				check.Score = 0.5
+			}
+			check.Remediations = append(check.Remediations, fmt.Sprintf("the bearer token for %q is shorter than %d characters; replace it with a random one", t.Submitter, minTokenLength))
+		}
+	}
+
+	secrets := len(seen)
+	if c.AnthropicAPIKey != "" {
+		secrets++
+	}
+	if c.OpenAIAPIKey != "" {
+		secrets++
+	}
+	check.Detail = fmt.Sprintf("%d secrets in %s", secrets, path)
+	return check
+}
+
+// checkStorage verifies the state directory is writable and the files
+// persisted there load
+func checkStorage(dir string) collective.HealthCheck {
+	check := collective.HealthCheck{Name: "storage", Score: 1, Detail: dir}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		check.Score = 0
+		check.Detail = err.Error()
+		check.Remediations = append(check.Remediations, fmt.Sprintf("create %s and make it writable by this user", dir))
+		return check
+	}
+	probe, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		check.Score = 0
+		check.Detail = fmt.Sprintf("%s is not writable: %v", dir, err)
+		check.Remediations = append(check.Remediations, fmt.Sprintf("fix the permissions of %s (e.g. 'chmod u+w %s')", dir, dir))
+		return check
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	problem := func(format string, args ...interface{}) {
+		check.Score = 0.5
+		check.Remediations = append(check.Remediations, fmt.Sprintf(format, args...))
+	}
+	if err := coordination.NewReputationRegistry().LoadFile(config.DefaultReputationPath()); err != nil {
+		problem("reputation history won't load (%v); restore it with 'sqm reputation import' or move the file aside", err)
+	}
+	if _, err := collective.NewScheduler(nil, config.DefaultSchedulePath()); err != nil {
+		problem("schedules won't load (%v); fix or remove %s", err, config.DefaultSchedulePath())
+	}
+	if _, err := workflow.LoadTriggerConfig(config.DefaultTriggersPath()); err != nil {
+		problem("triggers won't load (%v); check them with 'sqm workflow triggers'", err)
+	}
+	if pending, err := pendingMigrations(); err != nil {
+		problem("state can't be migrated (%v)", err)
+	} else if len(pending) > 0 {
+		problem("%d state migrations pending; preview them with 'sqm migrate --dry-run', then run 'sqm migrate'", len(pending))
+	}
+	store, err := collective.OpenSQLiteMemoryStore(config.DefaultMemoryPath())
+	if err != nil {
+		problem("collective memory won't open (%v); memory is kept in process only. Rebuild sqm with CGO_ENABLED=1 or move %s aside", err, config.DefaultMemoryPath())
+	} else {
+		store.Close()
+	}
+
+	if len(check.Remediations) > 0 {
+		check.Detail = fmt.Sprintf("%s is writable, with %d problem(s)", dir, len(check.Remediations))
+	}
+	return check
+}
+
+// checkPort verifies that serve mode can listen on addr. A port held by a
+// running sqm daemon is reported as such rather than as a conflict.
+func checkPort(addr string) collective.HealthCheck {
+	check := collective.HealthCheck{Name: "port", Score: 1, Detail: addr + " is free"}
+
+	ln, err := net.Listen("tcp", addr)
+	if err == nil {
+		ln.Close()
+		return check
+	}
+
+	_, port, splitErr := net.SplitHostPort(addr)
+	if splitErr == nil {
+		if info, err := fetchDaemon("http://127.0.0.1:" + port); err == nil && info.Protocol > 0 {
+			check.Score = 0.5
+			check.Detail = addr + " is in use by a running sqm daemon"
+			check.Remediations = append(check.Remediations, "stop the running daemon before 'sqm serve', or pass it a different --addr")
+			return check
+		}
+	}
+	check.Score = 0
+	check.Detail = fmt.Sprintf("can't listen on %s: %v", addr, err)
+	if errors.Is(err, os.ErrPermission) {
+		check.Remediations = append(check.Remediations, "ports below 1024 need privileges; use 'sqm serve --addr :8080'")
+	} else {
+		check.Remediations = append(check.Remediations, fmt.Sprintf("free %s or run 'sqm serve --addr' with another port", addr))
+	}
+	return check
+}
+
+// checkDaemon compares a running daemon's API protocol and version with this binary
+func checkDaemon(url string, info daemonInfo, err error) collective.HealthCheck {
+	check := collective.HealthCheck{Name: "daemon", Score: 1}
+
+	switch {
+	case err != nil:
+		check.Detail = "no daemon at " + url
+	case info.Protocol != server.ProtocolVersion:
+		check.Score = 0
+		check.Detail = fmt.Sprintf("daemon speaks protocol %d, this sqm speaks %d", info.Protocol, server.ProtocolVersion)
+		check.Remediations = append(check.Remediations, "restart the daemon with this sqm build, or use the sqm binary it was started with")
+	case info.Version != version:
+		check.Score = 0.5
+		check.Detail = fmt.Sprintf("daemon runs sqm %s, this is %s", info.Version, version)
+		check.Remediations = append(check.Remediations, "restart the daemon to pick up the upgrade")
+	default:
+		check.Detail = fmt.Sprintf("sqm %s, protocol %d at %s", info.Version, info.Protocol, url)
+	}
+	return check
+}
+
+// diagnoseEnvironment runs every environment check
+func diagnoseEnvironment(ctx context.Context, serverURL, addr string, ping bool) ([]collective.HealthCheck, daemonInfo, error) {
+	configPath := config.DefaultConfigPath()
+
+	anthropicKey := apiKey
+	if anthropicKey == "" {
+		anthropicKey = cfg.GetAnthropicKey()
+	}
+
+	info, err := fetchDaemon(serverURL)
+	checks := []collective.HealthCheck{
+		checkConfig(configPath),
+		checkAPIKeys(ctx, anthropicKey, cfg.GetOpenAIKey(), ping),
+		checkKeystore(configPath),
+		checkStorage(config.DefaultStateDir()),
+		checkPort(addr),
+		checkDaemon(serverURL, info, err),
+	}
+	return checks, info, err
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the environment and the collective, and suggest fixes",
+	Long: `Check that sqm can run here and compute the collective's health score.
+
+Environment checks:
+  config    The config file parses and its settings make sense
+  api keys  Each configured provider accepts its key (a one-token ping)
+  keystore  Secrets in the config file are private and bearer tokens are sound
+  storage   ~/.squaremind is writable and the state saved there loads
+  port      'sqm serve' can listen on --addr
+  daemon    A daemon at --server speaks this sqm's protocol and version
+
+The collective's health comes from agent availability, unmet capability
+demand, queue latency, consensus backlog, token budget headroom and LLM
+provider health. The collective in this process is diagnosed if one is
+active, otherwise the daemon at --server.
+
+Exits non-zero when an environment check fails or the collective is
+unhealthy.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		serverURL, _ := cmd.Flags().GetString("server")
+		addr, _ := cmd.Flags().GetString("addr")
+		offline, _ := cmd.Flags().GetBool("offline")
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		checks, daemon, daemonErr := diagnoseEnvironment(ctx, serverURL, addr, !offline)
+		failed := false
+		for _, check := range checks {
+			failed = failed || check.Score == 0
+		}
+		fmt.Println("\n  Environment")
+		printChecks(checks)
+
+		var report *collective.HealthReport
+		switch {
+		case activeCollective != nil:
+			r := activeCollective.Health()
+			report = &r
+		case daemonErr == nil && daemon.Health != nil:
+			report = daemon.Health
+		}
+		if report == nil {
+			fmt.Println("\n  No collective in this process or at " + serverURL + "; skipping health score")
+		} else {
+			printHealth(*report)
+			failed = failed || report.Status == collective.HealthUnhealthy
+		}
+
+		var remediations []string
+		for _, check := range checks {
+			remediations = append(remediations, check.Remediations...)
+		}
+		if report != nil {
+			remediations = append(remediations, report.Remediations()...)
+		}
+		printRemediations(remediations)
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// printHealth prints a health report's score and checks
+func printHealth(report collective.HealthReport) {
+	fmt.Printf("\n  Health: %.0f/100 (%s)\n", report.Score, report.Status)
+	printChecks(report.Checks)
+}
+
+// printChecks prints one line per check
+func printChecks(checks []collective.HealthCheck) {
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	for _, check := range checks {
+		mark := "✓"
+		switch {
+		case check.Score == 0:
+			mark = "✗"
+		case check.Score < 1:
+			mark = "!"
+		}
+		fmt.Printf("  %s %-10s %3.0f%%  %s\n", mark, check.Name, check.Score*100, check.Detail)
+	}
+}
+
+// printRemediations prints suggested fixes, if any
+func printRemediations(remediations []string) {
+	if len(remediations) > 0 {
+		fmt.Println("\n  Suggested fixes:")
+		for _, r := range remediations {
+			fmt.Printf("    - %s\n", r)
+		}
+	}
+	fmt.Println()
+}
+
+// providerHealthProbe reports the router's providers as a health check
+func providerHealthProbe(router *llm.Router) collective.HealthProbe {
+	return func() collective.HealthCheck {
+		providers := router.Health()
+		check := collective.HealthCheck{Name: "providers", Score: 1}
+
+		healthy := 0
+		for _, h := range providers {
+			if h.Healthy {
+				healthy++
+				continue
+			}
+			if isAuthError(h.LastError) {
+				check.Remediations = append(check.Remediations, fmt.Sprintf("rotate the %s API key; it was rejected: %s", h.Name, h.LastError))
+			} else {
+				check.Remediations = append(check.Remediations, fmt.Sprintf("check %s's status; requests are failing over: %s", h.Name, h.LastError))
+			}
+		}
+		if len(providers) > 0 {
+			check.Score = float64(healthy) / float64(len(providers))
+		}
+		check.Detail = fmt.Sprintf("%d of %d providers healthy", healthy, len(providers))
+		return check
+	}
+}
+
+// isAuthError reports whether a provider error looks like a rejected API key
+func isAuthError(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, marker := range []string{"401", "403", "unauthorized", "invalid x-api-key", "invalid api key", "authentication"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	doctorCmd.Flags().String("server", "http://127.0.0.1:8420", "Running daemon to check, and to diagnose when no collective is active")
+	doctorCmd.Flags().String("addr", ":8080", "Address 'sqm serve' will listen on")
+	doctorCmd.Flags().Bool("offline", false, "Don't ping the LLM providers")
+	rootCmd.AddCommand(doctorCmd)
+}
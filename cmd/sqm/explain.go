@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var taskExplainCmd = &cobra.Command{
+	Use:   "explain [task-id]",
+	Short: "Show why a task was assigned to the agent that got it",
+	Long: `Show how a task's latest assignment was decided: every bid ranked with the
+contribution of each scoring factor (capability match, reputation, stake),
+what became of it, the agents that could not bid and why, and the tie-break
+rule if the winner tied with the runner-up.
+
+The explanation is read from the collective in this process if one is
+active, otherwise from a running 'sqm serve' or 'sqm dashboard' at --server.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		taskID := args[0]
+
+		var (
+			exp *collective.AssignmentExplanation
+			err error
+		)
+		if activeCollective != nil {
+			var ok bool
+			if exp, ok = activeCollective.ExplainAssignment(taskID); !ok {
+				err = fmt.Errorf("no assignment recorded for task %s", taskID)
+			}
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			exp, err = fetchExplanation(server, taskID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printExplanation(exp)
+	},
+}
+
+// printExplanation renders an assignment explanation as tables
+func printExplanation(exp *collective.AssignmentExplanation) {
+	required := make([]string, len(exp.Required))
+	for i, r := range exp.Required {
+		required[i] = string(r)
+	}
+	if len(required) == 0 {
+		required = append(required, "none")
+	}
+
+	fmt.Printf("\n  Task %s\n", exp.TaskID)
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	fmt.Printf("  Mode: %s   Scope: %s   Required: %s\n", exp.Mode, exp.Scope, strings.Join(required, ", "))
+	switch {
+	case exp.Pinned:
+		fmt.Printf("  Pinned to %s; no auction was held.\n\n", shortActor(exp.Winner))
+		return
+	case exp.Winner != "":
+		fmt.Printf("  Winner: %s   Auction: %s   Stake committed: %.2f\n", shortActor(exp.Winner), exp.Auction, exp.Stake)
+	default:
+		fmt.Printf("  Not assigned: %s\n", exp.Error)
+	}
+
+	if len(exp.Bids) > 0 {
+		fmt.Printf("\n  %-4s %-20s %7s %11s %11s %7s  %s\n", "RANK", "AGENT", "SCORE", "CAPABILITY", "REPUTATION", "STAKE", "OUTCOME")
+		for _, b := range exp.Bids {
+			contributions := make(map[string]float64, len(b.Factors))
+			for _, f := range b.Factors {
+				contributions[f.Name] = f.Contribution
+			}
+			fmt.Printf("  %-4d %-20s %7.4f %11.4f %11.4f %7.4f  %s\n",
+				b.Rank, agentLabel(b.Agent, b.Bid.AgentSID), b.Score,
+				contributions[coordination.FactorCapability],
+				contributions[coordination.FactorReputation],
+				contributions[coordination.FactorStake],
+				b.Outcome)
+		}
+		fmt.Printf("  Score = capability×%.1f + reputation/100×%.1f + stake/100×%.1f",
+			coordination.CapabilityWeight, coordination.ReputationWeight, coordination.StakeWeight)
+		if hint, ok := exp.Bids[0].Factor(coordination.FactorHint); ok {
+			fmt.Printf(" + hint×%.2f", hint.Weight)
+		}
+		fmt.Println()
+		if exp.Auction == coordination.AuctionReverse {
+			fmt.Println("  Reverse auction: ranked by estimated time, then score")
+		}
+		if exp.Rotated {
+			fmt.Println("  Review rotation: another bidder's turn came ahead of the best bid")
+		}
+	}
+	for _, r := range exp.Relaxed {
+		fmt.Printf("  No bids at %.2f: threshold relaxed to %.2f\n", r.From, r.To)
+	}
+
+	for _, conflict := range exp.Conflicts {
+		fmt.Printf("\n  Conflict of interest (%s): %s\n", conflict.Kind, conflict.Detail)
+	}
+
+	if tb := exp.TieBreak; tb != nil {
+		rule := "higher capability score"
+		switch tb.Rule {
+		case coordination.TieBreakAgentSID:
+			rule = "agent SID order"
+		case coordination.TieBreakLeastRecent:
+			rule = "least recent assignment"
+		case coordination.TieBreakLowestCost:
+			rule = "lowest cost"
+		case coordination.TieBreakLottery:
+			rule = "bid lottery"
+		}
+		fmt.Printf("\n  Tie-break: tied with %s at %.4f, decided by %s\n", shortActor(tb.RunnerUp), tb.Score, rule)
+	}
+
+	for _, b := range exp.Bids {
+		if b.Outcome != collective.OutcomeWon || b.Bid.Match == nil {
+			continue
+		}
+		fmt.Printf("\n  Capability match of %s: %.4f (coverage %.2f × proficiency %.2f)\n",
+			agentLabel(b.Agent, b.Bid.AgentSID), b.Bid.Match.Score, b.Bid.Match.Coverage, b.Bid.Match.AvgProficiency)
+		printRequirements(b.Bid.Match)
+	}
+
+	if len(exp.Excluded) > 0 {
+		fmt.Println("\n  Did not bid:")
+		for _, ex := range exp.Excluded {
+			line := fmt.Sprintf("    %-20s %s", agentLabel(ex.Agent, ex.AgentSID), ex.Reason)
+			if ex.Match != nil {
+				line += fmt.Sprintf(" (match %.4f < %.2f)", ex.Match.Score, exp.Threshold)
+			}
+			fmt.Println(line)
+		}
+	}
+	fmt.Println()
+}
+
+// printRequirements lists how each required capability was met
+func printRequirements(match *identity.MatchBreakdown) {
+	for _, r := range match.Requirements {
+		if r.Source == "" {
+			fmt.Printf("    %-20s unmatched\n", r.Required)
+			continue
+		}
+		fmt.Printf("    %-20s via %-18s %.2f × %.2f = %.4f\n", r.Required, r.Source, r.Proficiency, r.Credit, r.Score)
+	}
+}
+
+// agentLabel names an agent, falling back to its abbreviated SID
+func agentLabel(name, sid string) string {
+	if name != "" {
+		return name
+	}
+	return shortActor(sid)
+}
+
+// fetchExplanation reads an assignment explanation from a running server
+func fetchExplanation(server, taskID string) (*collective.AssignmentExplanation, error) {
+	endpoint := strings.TrimRight(server, "/") + "/api/tasks/" + url.PathEscape(taskID) + "/explain"
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("no collective in this process and server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("no assignment recorded for task %s", taskID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var exp collective.AssignmentExplanation
+	if err := json.NewDecoder(resp.Body).Decode(&exp); err != nil {
+		return nil, err
+	}
+	return &exp, nil
+}
+
+func init() {
+	taskExplainCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query when no collective is active")
+	taskCmd.AddCommand(taskExplainCmd)
+}
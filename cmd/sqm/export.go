@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/training"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export a collective snapshot, or execution logs as a fine-tuning dataset",
+	Long: `Convert recorded executions (prompt, context, output, quality score and
+reviewer critique) into a JSONL fine-tuning dataset.
+
+Given a file, export a signed snapshot of the collective instead: its agents,
+capabilities, reputations, memory and pending tasks, for 'sqm import' on
+another machine or for checking into version control. The snapshot is taken
+from the collective in this process if one is active, otherwise from a
+running 'sqm serve' at --server, which requires an API token.
+
+Examples:
+  sqm export --out dataset.jsonl --min-quality 0.8 --capability code.write
+  sqm export collective.json --server http://prod:8080`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			exportSnapshot(cmd, args[0])
+			return
+		}
+
+		logPath, _ := cmd.Flags().GetString("log")
+		outPath, _ := cmd.Flags().GetString("out")
+		format, _ := cmd.Flags().GetString("format")
+		minQuality, _ := cmd.Flags().GetFloat64("min-quality")
+		capsStr, _ := cmd.Flags().GetStringSlice("capability")
+		includeFailed, _ := cmd.Flags().GetBool("include-failed")
+		critique, _ := cmd.Flags().GetBool("critique")
+
+		log, err := training.LoadExecutionLog(logPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading execution log: %v\n", err)
+			os.Exit(1)
+		}
+
+		caps := make([]identity.CapabilityType, len(capsStr))
+		for i, c := range capsStr {
+			caps[i] = identity.CapabilityType(c)
+		}
+
+		out := os.Stdout
+		if outPath != "" && outPath != "-" {
+			f, err := os.Create(outPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating output: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		stats, err := training.Export(out, log.Records(), training.ExportOptions{
+			Format: training.Format(format),
+			Filter: training.Filter{
+				MinQuality:    minQuality,
+				Capabilities:  caps,
+				IncludeFailed: includeFailed,
+			},
+			IncludeCritique: critique,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(os.Stderr, "\n  Exported %d of %d executions", stats.Exported, stats.Total)
+		fmt.Fprintf(os.Stderr, " (skipped: %d low quality, %d failed, %d capability mismatch)\n\n",
+			stats.LowQuality, stats.Failed, stats.Unmatched)
+	},
+}
+
+func init() {
+	exportCmd.Flags().String("log", config.DefaultExecutionLogPath(), "Execution log to read")
+	exportCmd.Flags().StringP("out", "o", "", "Output file (default stdout)")
+	exportCmd.Flags().StringP("format", "f", string(training.FormatChat), "Dataset format (chat/completion)")
+	exportCmd.Flags().Float64P("min-quality", "q", 0.7, "Minimum quality score")
+	exportCmd.Flags().StringSliceP("capability", "c", []string{}, "Only export executions requiring these capabilities")
+	exportCmd.Flags().Bool("include-failed", false, "Include failed executions")
+	exportCmd.Flags().Bool("critique", false, "Include reviewer critique in the system prompt")
+	exportCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to snapshot when no collective is active")
+	exportCmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+	exportCmd.Flags().String("key", config.DefaultSnapshotKeyPath(), "Key to sign the snapshot with, created if missing")
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/incident"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// recorder keeps this process's recent logs and the active collective's
+// events for incident captures
+var recorder = incident.NewRecorder(0, 0)
+
+var incidentCmd = &cobra.Command{
+	Use:   "incident",
+	Short: "Capture debugging bundles of production issues",
+}
+
+var incidentCaptureCmd = &cobra.Command{
+	Use:   "capture",
+	Short: "Snapshot the collective into a redacted bundle",
+	Long: `Capture a bundle for debugging a production issue: recent logs, the
+event stream, task timelines, queue state, consensus rounds, health and
+provider errors, as JSON files in a gzipped tar archive.
+
+API keys, bearer tokens and webhook URLs from the config file, fields named
+like secrets, and credential-shaped strings are replaced with [REDACTED].
+
+The bundle is taken from the collective in this process if one is active,
+otherwise from a running 'sqm serve' at --server, which requires an API
+token. 'sqm serve' also captures bundles on its own when tasks keep failing
+or the health score collapses; see the incidents section of the config
+file and 'sqm incident list'.
+
+Example:
+  sqm incident capture --reason "checkout tasks timing out" -o incident.tar.gz`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		reason, _ := cmd.Flags().GetString("reason")
+
+		var (
+			body io.Reader
+			name string
+		)
+		if activeCollective != nil {
+			if reason == "" {
+				reason = "captured with sqm incident capture"
+			}
+			bundle := newCapturer().Capture(reason)
+			pr, pw := io.Pipe()
+			go func() {
+				_, err := bundle.WriteTo(pw)
+				pw.CloseWithError(err)
+			}()
+			body, name = pr, bundle.FileName()
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			token, _ := cmd.Flags().GetString("token")
+			resp, err := fetchIncident(server, token, reason)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer resp.Body.Close()
+			body, name = resp.Body, "incident-"+time.Now().UTC().Format("20060102T150405Z")+".tar.gz"
+		}
+
+		if output == "-" {
+			if _, err := io.Copy(os.Stdout, body); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		if output == "" {
+			output = name
+		}
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		n, err := io.Copy(f, body)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  Incident bundle written to %s (%d bytes)\n", output, n)
+	},
+}
+
+var incidentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List bundles captured automatically",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := incidentTriggers().Dir
+		matches, _ := filepath.Glob(filepath.Join(dir, "incident-*.tar.gz"))
+		if len(matches) == 0 {
+			fmt.Printf("  No incident bundles in %s\n", dir)
+			return
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+		for _, path := range matches {
+			if info, err := os.Stat(path); err == nil {
+				fmt.Printf("  %s  %8d bytes  %s\n", info.ModTime().Format(time.RFC3339), info.Size(), path)
+			}
+		}
+	},
+}
+
+// fetchIncident requests a bundle from a running server
+func fetchIncident(server, token, reason string) (*http.Response, error) {
+	endpoint := strings.TrimRight(server, "/") + "/api/incident"
+	if reason != "" {
+		endpoint += "?reason=" + url.QueryEscape(reason)
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("server unreachable: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// newCapturer creates the incident capturer of the active collective, with
+// this process's recorder, provider errors and the configured secrets,
+// keyring credentials included
+func newCapturer() *incident.Capturer {
+	k := incident.NewCapturer(activeCollective, recorder)
+	if router, ok := baseProvider().(*llm.Router); ok {
+		k.AddSource("providers", func() interface{} { return router.Stats() })
+	}
+
+	secrets := []string{apiKey, cfg.AnthropicAPIKey, cfg.OpenAIAPIKey, cfg.SlackWebhook}
+	for _, t := range cfg.APITokens {
+		secrets = append(secrets, t.Token)
+	}
+	for _, p := range cfg.Profiles {
+		if p == nil {
+			continue
+		}
+		secrets = append(secrets, p.AnthropicAPIKey, p.OpenAIAPIKey, p.SlackWebhook)
+		for _, c := range p.Keyring {
+			secrets = append(secrets, c.Key)
+		}
+		for _, t := range p.APITokens {
+			secrets = append(secrets, t.Token)
+		}
+	}
+	if keyring != nil {
+		secrets = append(secrets, keyring.Secrets()...)
+	}
+	k.AddSecrets(secrets...)
+	return k
+}
+
+// incidentTriggers returns the configured automatic capture settings
+func incidentTriggers() incident.TriggerConfig {
+	triggers := cfg.Incidents
+	if triggers.Dir == "" {
+		triggers.Dir = config.DefaultIncidentDir()
+	}
+	return triggers
+}
+
+func init() {
+	incidentCaptureCmd.Flags().StringP("output", "o", "", "File to write the bundle to, or - for stdout (default: incident-<time>.tar.gz)")
+	incidentCaptureCmd.Flags().String("reason", "", "What is being investigated, recorded in the bundle")
+	incidentCaptureCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to capture when no collective is active")
+	incidentCaptureCmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+	incidentCmd.AddCommand(incidentCaptureCmd)
+	incidentCmd.AddCommand(incidentListCmd)
+	rootCmd.AddCommand(incidentCmd)
+}
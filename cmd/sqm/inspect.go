@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var agentInspectCmd = &cobra.Command{
+	Use:   "inspect [sid]",
+	Short: "Show an agent's full profile",
+	Long: `Show everything the collective knows about an agent: its identity and
+public key, capability proficiencies and benchmark proofs, reputation with
+its recent history, memory, current task, uptime, queues and token spend.
+
+The SID may be abbreviated to any unique prefix.
+
+Examples:
+  sqm agent inspect 3f9a
+  sqm agent inspect 3f9a --history 50
+  sqm agent inspect 3f9a --json`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if activeCollective == nil {
+			fmt.Fprintln(os.Stderr, "No collective initialized.")
+			os.Exit(1)
+		}
+
+		history, _ := cmd.Flags().GetInt("history")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		sid, err := resolveSID(activeCollective.GetReputation(), args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		p, err := activeCollective.Profile(sid, history)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON {
+			data, _ := json.MarshalIndent(p, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Printf("\n  Agent %s\n", p.Name)
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		fmt.Printf("  SID:         %s\n", p.SID)
+		fmt.Printf("  Public Key:  %s\n", p.PublicKey)
+		if p.ParentSID != "" {
+			fmt.Printf("  Parent:      %s (generation %d)\n", p.ParentSID, p.Generation)
+		}
+		fmt.Printf("  Created:     %s\n", p.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		if p.Model != "" {
+			fmt.Printf("  Model:       %s\n", p.Model)
+		}
+		if len(p.Models) > 0 {
+			fmt.Printf("  Models:      %s\n", p.Models)
+		}
+		if len(p.Labels) > 0 {
+			fmt.Printf("  Labels:      %s\n", p.Labels)
+		}
+		if len(p.CostTags) > 0 {
+			fmt.Printf("  Cost tags:   %s\n", p.CostTags)
+		}
+		fmt.Printf("  State:       %s\n", p.State)
+		fmt.Printf("  Uptime:      %s (last active %s)\n", p.Uptime.Round(time.Second), p.LastActive.Local().Format("2006-01-02 15:04:05"))
+		if p.Slots > 1 {
+			fmt.Printf("  Slots:       %d of %d busy\n", len(p.Tasks), p.Slots)
+			for _, t := range p.Tasks {
+				fmt.Printf("  Working on:  %s %q for %s\n", t.ID, t.Description, time.Since(t.Started).Round(time.Second))
+			}
+		} else if t := p.CurrentTask; t != nil {
+			fmt.Printf("  Working on:  %s %q for %s\n", t.ID, t.Description, time.Since(t.Started).Round(time.Second))
+		}
+
+		fmt.Println("\n  Capabilities:")
+		if len(p.Capabilities) == 0 {
+			fmt.Println("    None.")
+		}
+		for _, c := range p.Capabilities {
+			fmt.Printf("    %-18s %.2f", c.Type, c.Proficiency)
+			if proof := c.Proof; proof != nil {
+				status := "expired"
+				if c.ProofValid {
+					status = "valid until " + proof.ExpiresAt.Local().Format("2006-01-02 15:04")
+				}
+				fmt.Printf("  proof: %s %.2f by %s, %s (effective %.2f)", proof.Benchmark, proof.Score, proof.Signer, status, c.Effective)
+			}
+			fmt.Println()
+		}
+
+		rep := p.Reputation
+		fmt.Println("\n  Reputation:")
+		fmt.Printf("    Overall:     %.1f\n", rep.Overall)
+		fmt.Printf("    Reliability: %.1f\n", rep.Reliability)
+		fmt.Printf("    Quality:     %.1f\n", rep.Quality)
+		fmt.Printf("    Cooperation: %.1f\n", rep.Cooperation)
+		fmt.Printf("    Honesty:     %.1f\n", rep.Honesty)
+		if rep.Staked > 0 {
+			fmt.Printf("    Staked:      %.1f\n", rep.Staked)
+		}
+		fmt.Printf("    Tasks:       %d completed, %d failed\n", rep.TasksCompleted, rep.TasksFailed)
+		fmt.Printf("\n  Recent history (%d of %d events):\n", len(p.History), p.Events)
+		if len(p.History) == 0 {
+			fmt.Println("    No events recorded.")
+		}
+		for _, event := range p.History {
+			fmt.Printf("    %s  %-13s %+7.2f  %s\n",
+				event.Timestamp.Local().Format("2006-01-02 15:04:05"), event.Type, event.Delta, event.Reason)
+		}
+
+		fmt.Println("\n  Memory:")
+		fmt.Printf("    %d short-term, %d long-term, %d episodes", p.Memory.ShortTerm, p.Memory.LongTerm, p.Memory.Episodes)
+		if !p.Memory.LastEpisode.IsZero() {
+			fmt.Printf(" (last %s)", p.Memory.LastEpisode.Local().Format("2006-01-02 15:04:05"))
+		}
+		fmt.Println()
+
+		q := p.Queue
+		fmt.Println("\n  Queues:")
+		fmt.Printf("    Tasks %d/%d, results %d/%d (%s on overflow)\n", q.QueuedTasks, q.TaskCapacity, q.QueuedResults, q.ResultCapacity, q.Overflow)
+		if q.RejectedTasks+q.DroppedTasks+q.DroppedResults > 0 {
+			fmt.Printf("    %d tasks refused, %d tasks dropped, %d results dropped\n", q.RejectedTasks, q.DroppedTasks, q.DroppedResults)
+		}
+
+		fmt.Println("\n  Usage:")
+		fmt.Printf("    %d tasks (%d failed), %d tokens, %.1f%% of the collective's spend\n\n",
+			p.Usage.Tasks, p.Usage.Failed, p.Usage.Tokens, p.Share*100)
+	},
+}
+
+func init() {
+	agentInspectCmd.Flags().Int("history", 0, "Recent reputation events to show (0 = 10, negative = all)")
+	agentInspectCmd.Flags().Bool("json", false, "Print the profile as JSON")
+	agentCmd.AddCommand(agentInspectCmd)
+}
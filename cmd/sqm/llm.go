@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/llmlog"
+)
+
+var llmCmd = &cobra.Command{
+	Use:   "llm",
+	Short: "Inspect the requests agents make to LLM providers",
+	Long: `Inspect the requests agents make to LLM providers.
+
+Any command can record its LLM traffic with --record, and be run again
+offline, answered from the recording, with --mock:
+
+  sqm swarm "Build a URL shortener" --record shortener.json
+  sqm swarm "Build a URL shortener" --mock shortener.json
+
+Mock scripts can also be written by hand, with templated responses to the
+prompts they match and injected latency and failures:
+
+  {
+    "responses": [
+      {"match": "Review", "content": "LGTM", "latency": 200000000},
+      {"match": "Fix the (\\w+) bug", "content": "Fixed the {{index .Groups 1}} bug", "times": 1},
+      {"match": "Fix the", "error": "rate limited"}
+    ],
+    "default": "Completed: {{.Prompt}}",
+    "error_rate": 0.1,
+    "seed": 42
+  }
+
+Latencies are in nanoseconds. A request gets the first response matching it
+that isn't used up (times), and recorded prompts match whatever IDs agents
+and tasks get.`,
+}
+
+var llmLogCmd = &cobra.Command{
+	Use:   "log [id]",
+	Short: "Show logged LLM requests and responses",
+	Long: `Show the requests agents made to LLM providers and the responses they
+got, most recent first, or one request in full by its ID (or a unique prefix
+of it). Requests are logged once llm_log is set in the config:
+
+  llm_log:
+    path: /var/log/sqm/llm.jsonl    # Default ~/.squaremind/llm.jsonl
+    keep_pii: false                 # Redact emails, phone numbers... too
+    rules:                          # Further patterns to redact
+      - name: ticket
+        pattern: 'TICKET-\d+'
+
+API keys and the secrets in the config are always redacted before a request
+is written.
+
+Examples:
+  sqm llm log --agent Coder --since 1h
+  sqm llm log --task 3f2c9a1e --errors
+  sqm llm log --grep "rate limiter"
+  sqm llm log 7d41b0c2 --full`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		full, _ := cmd.Flags().GetBool("full")
+		path := llmLogPath()
+
+		if len(args) == 1 {
+			e, err := llmlog.Find(path, args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if asJSON {
+				data, _ := json.MarshalIndent(e, "", "  ")
+				fmt.Println(string(data))
+				return
+			}
+			printLLMEntry(e, full)
+			return
+		}
+
+		var f llmlog.Filter
+		f.Agent, _ = cmd.Flags().GetString("agent")
+		f.Task, _ = cmd.Flags().GetString("task")
+		f.Model, _ = cmd.Flags().GetString("model")
+		f.Errors, _ = cmd.Flags().GetBool("errors")
+		f.Contains, _ = cmd.Flags().GetString("grep")
+		f.Limit, _ = cmd.Flags().GetInt("limit")
+		if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+			f.Since = time.Now().Add(-since)
+		}
+
+		entries, err := llmlog.Read(path, f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if asJSON {
+			data, _ := json.MarshalIndent(entries, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		if len(entries) == 0 {
+			if cfg.LLMLog == nil {
+				fmt.Println("  No requests logged. Set llm_log in the config to log them.")
+			} else {
+				fmt.Println("  No requests logged.")
+			}
+			return
+		}
+		if full {
+			for _, e := range entries {
+				printLLMEntry(e, true)
+			}
+			return
+		}
+
+		fmt.Printf("\n  %-8s  %-19s  %-12s  %-8s  %-24s  %6s  %7s  %s\n", "ID", "TIME", "AGENT", "TASK", "MODEL", "TOKENS", "TIME", "PROMPT")
+		for _, e := range entries {
+			prompt := e.Prompt
+			if n := len(e.Messages); n > 0 {
+				prompt = e.Messages[n-1].Content
+			}
+			if e.Error != "" {
+				prompt = "ERROR " + e.Error
+			}
+			fmt.Printf("  %-8s  %-19s  %-12s  %-8s  %-24s  %6d  %7s  %s\n",
+				shortActor(e.ID), e.Time.Local().Format("2006-01-02 15:04:05"), truncateLine(e.Agent, 12),
+				shortActor(e.TaskID), truncateLine(e.Model, 24), e.TokensUsed, e.Duration.Round(time.Millisecond), truncateLine(prompt, 50))
+		}
+		fmt.Printf("\n  Show one in full with: sqm llm log <id>\n\n")
+	},
+}
+
+// printLLMEntry renders a logged request and its response
+func printLLMEntry(e *llmlog.Entry, full bool) {
+	fmt.Printf("\n  Request %s\n", e.ID)
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	fmt.Printf("  Time:      %s (%s)\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Duration.Round(time.Millisecond))
+	fmt.Printf("  Provider:  %s, %s\n", e.Provider, e.Kind)
+	if e.Model != "" {
+		fmt.Printf("  Model:     %s\n", e.Model)
+	}
+	if e.Agent != "" || e.AgentSID != "" {
+		fmt.Printf("  Agent:     %s (%s)\n", e.Agent, shortActor(e.AgentSID))
+	}
+	if e.TaskID != "" {
+		fmt.Printf("  Task:      %s\n", e.TaskID)
+	}
+	if e.Format != "" {
+		fmt.Printf("  Format:    %s\n", e.Format)
+	}
+	if len(e.Tools) > 0 {
+		fmt.Printf("  Tools:     %v\n", e.Tools)
+	}
+	fmt.Printf("  Tokens:    %d", e.TokensUsed)
+	if e.ThinkingTokens > 0 {
+		fmt.Printf(" (%d thinking)", e.ThinkingTokens)
+	}
+	fmt.Println()
+	if e.Redactions > 0 {
+		fmt.Printf("  Redacted:  %d values\n", e.Redactions)
+	}
+	if e.Error != "" {
+		fmt.Printf("  Error:     %s\n", e.Error)
+	}
+
+	if e.System != "" {
+		fmt.Printf("\n  System:\n%s\n", indentText(e.System, full))
+	}
+	if e.Prompt != "" {
+		fmt.Printf("\n  Prompt:\n%s\n", indentText(e.Prompt, full))
+	}
+	for _, m := range e.Messages {
+		fmt.Printf("\n  %s:\n%s\n", m.Role, indentText(m.Content, full))
+	}
+	for i, turn := range e.Turns {
+		fmt.Printf("\n  Tool turn %d:\n", i+1)
+		for _, call := range turn.Calls {
+			fmt.Printf("      call %s %s\n", call.Name, truncateLine(string(call.Input), 80))
+		}
+		for _, out := range turn.Outputs {
+			fmt.Printf("      output %s\n", truncateLine(out.Content, 80))
+		}
+	}
+	if e.Response != "" {
+		fmt.Printf("\n  Response:\n%s\n", indentText(e.Response, full))
+	}
+	for _, call := range e.ToolCalls {
+		fmt.Printf("\n  Tool call: %s %s\n", call.Name, truncateLine(string(call.Input), 80))
+	}
+	fmt.Println()
+}
+
+// llmLogPath returns where LLM requests are logged
+func llmLogPath() string {
+	if cfg != nil && cfg.LLMLog != nil && cfg.LLMLog.Path != "" {
+		return cfg.LLMLog.Path
+	}
+	return config.DefaultLLMLogPath()
+}
+
+// logProvider wraps p to log its requests if the config asks for it
+func logProvider(p llm.Provider) (llm.Provider, error) {
+	if p == nil || cfg.LLMLog == nil {
+		return p, nil
+	}
+	redactor, err := cfg.LLMLog.Redactor(configSecrets()...)
+	if err != nil {
+		return nil, err
+	}
+	log, err := llmlog.Open(llmLogPath(), cfg.LLMLog.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+	return llmlog.Wrap(p, log, redactor), nil
+}
+
+// configSecrets returns the secrets the config holds, so request logs can
+// redact them whatever their shape
+func configSecrets() []string {
+	secrets := []string{apiKey, cfg.GetAnthropicKey(), cfg.GetOpenAIKey(), cfg.SlackWebhook}
+	for _, t := range cfg.APITokens {
+		secrets = append(secrets, t.Token)
+	}
+	for _, c := range cfg.Keyring {
+		secrets = append(secrets, c.Key)
+		if c.KeyEnv != "" {
+			secrets = append(secrets, os.Getenv(c.KeyEnv))
+		}
+	}
+	if cfg.GitHub != nil {
+		secrets = append(secrets, cfg.GitHub.Token)
+	}
+	if cfg.Slack != nil {
+		secrets = append(secrets, cfg.Slack.Token)
+	}
+	return secrets
+}
+
+// baseProvider returns the configured provider without the request log,
+// recording or candidate slot wrapped around it
+func baseProvider() llm.Provider {
+	p := provider
+	for {
+		w, ok := p.(interface{ Unwrap() llm.Provider })
+		if !ok {
+			return p
+		}
+		p = w.Unwrap()
+	}
+}
+
+func init() {
+	llmLogCmd.Flags().String("agent", "", "Only requests of this agent (name or SID prefix)")
+	llmLogCmd.Flags().String("task", "", "Only requests for this task (ID prefix)")
+	llmLogCmd.Flags().String("model", "", "Only requests to this model (prefix)")
+	llmLogCmd.Flags().Bool("errors", false, "Only failed requests")
+	llmLogCmd.Flags().String("grep", "", "Only requests whose prompt or response contains this text")
+	llmLogCmd.Flags().Duration("since", 0, "Only requests made within this long, e.g. 1h (0 = all)")
+	llmLogCmd.Flags().Int("limit", 50, "Show at most this many requests (0 = all)")
+	llmLogCmd.Flags().Bool("full", false, "Show requests in full")
+	llmLogCmd.Flags().Bool("json", false, "Print requests as JSON")
+	llmCmd.AddCommand(llmLogCmd)
+	rootCmd.AddCommand(llmCmd)
+}
@@ -3,30 +3,50 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
-	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/square-mind/squaremind/pkg/agent"
 	"github.com/square-mind/squaremind/pkg/collective"
 	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/eventsink"
 	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/integrations/github"
 	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/logging"
+	"github.com/square-mind/squaremind/pkg/roles"
+	"github.com/square-mind/squaremind/pkg/runlog"
+	"github.com/square-mind/squaremind/pkg/sandbox"
+	"github.com/square-mind/squaremind/pkg/tools"
 )
 
 var (
 	version = "0.1.0"
 
 	// Global flags
-	apiKey string
+	apiKey      string
+	logLevel    string
+	profileName string
+	mockScript  string
+	recordPath  string
 
 	// Global state for CLI session
 	activeCollective *collective.Collective
 	provider         llm.Provider
+	candidate        *llm.CandidateSlot  // Candidate model in front of the configured provider (nil if none is configured)
+	keyring          *agent.Keyring      // Credentials bound to capabilities and teams (nil if none are configured)
+	contracts        *agent.ContractSet  // Behavior contracts agents are held to (nil if none are configured)
+	toolbox          *tools.Toolbox      // Tools of the configured MCP servers (nil if none are configured)
+	integrations     []agent.Integration // Act on agents' results outside the collective (GitHub...)
+	runRecorder      *runlog.Recorder    // Records the runs of tasks submitted from this process (nil before init)
 	cfg              *config.Config
 )
 
@@ -41,28 +61,110 @@ Many Agents. One Mind. Fair coordination. Transparent markets.
 Learn more: https://squaremind.xyz`,
 	Version: version,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		// Load config file
-		var err error
-		cfg, err = config.Load()
+		// Library activity is logged through slog to stderr
+		level, err := logging.ParseLevel(logLevel)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not load config: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Warning: %v, using warn\n", err)
+			level = slog.LevelWarn
+		}
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+		// Recent activity is also kept for incident captures, whatever the level
+		logging.SetDefault(logging.Tee(logging.NewSlog(nil), recorder.Logger(slog.LevelInfo)))
+
+		// Load config file; config commands may name a profile they create
+		cfg, err = loadConfig()
+		if isUnknownProfile(err) && cmd.Parent() != configCmd {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			if !isUnknownProfile(err) {
+				fmt.Fprintf(os.Stderr, "Warning: could not load config: %v\n", err)
+			}
 			cfg = &config.Config{}
 		}
 
+		// Custom capabilities extend the built-in ones for matching
+		if err := identity.DefaultCapabilityRegistry().LoadFile(config.DefaultCapabilitiesPath()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not load custom capabilities: %v\n", err)
+		}
+		if err := roles.Default().LoadDir(config.DefaultRolesDir()); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not load roles: %v\n", err)
+		}
+
 		// Initialize provider with priority: CLI flag > env var > config file
 		key := apiKey
 		if key == "" {
 			key = cfg.GetAnthropicKey()
 		}
-		if key != "" {
+		openaiKey := cfg.GetOpenAIKey()
+
+		switch {
+		case key != "" && openaiKey != "":
+			// With both vendors configured, each serves its own models and
+			// covers for the other during an outage
+			claude, openai := llm.NewClaudeProvider(key), llm.NewOpenAIProvider(openaiKey)
+			provider = llm.NewRouter(claude, openai).
+				Route("claude-", claude).
+				Route("gpt-", openai).
+				Route("o1", openai).
+				Route("o3", openai).
+				Route("o4", openai)
+		case key != "":
 			provider = llm.NewClaudeProvider(key)
-			return
+		case openaiKey != "":
+			// Fallback to OpenAI if no Anthropic key
+			provider = llm.NewOpenAIProvider(openaiKey)
+		}
+		if cfg.Candidate != nil && provider != nil {
+			if candidate, err = cfg.Candidate.Slot(provider); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: candidate: %v\n", err)
+				os.Exit(1)
+			}
+			provider = candidate
+		}
+		if mockScript != "" {
+			script, err := llm.LoadMockScript(mockScript)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --mock: %v\n", err)
+				os.Exit(1)
+			}
+			provider, candidate = llm.NewMockProvider(script), nil
+		}
+		if recordPath != "" && provider != nil {
+			provider = llm.NewRecordingProvider(provider).WithFile(recordPath)
+		}
+		if provider, err = logProvider(provider); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: llm_log: %v\n", err)
+			os.Exit(1)
 		}
 
-		// Fallback to OpenAI if no Anthropic key
-		openaiKey := cfg.GetOpenAIKey()
-		if openaiKey != "" {
-			provider = llm.NewOpenAIProvider(openaiKey)
+		if len(cfg.Keyring) > 0 {
+			if keyring, err = agent.NewKeyring(cfg.Keyring...); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: keyring: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if cfg.Contracts != nil {
+			if contracts, err = agent.NewContractSet(*cfg.Contracts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: contracts: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if cfg.Queues != nil {
+			if err := cfg.Queues.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: queues: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if len(cfg.MCPServers) > 0 {
+			if toolbox, err = tools.NewToolbox(cfg.MCPServers...); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: mcp_servers: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if cfg.GitHub != nil {
+			integrations = append(integrations, github.New(*cfg.GitHub))
 		}
 	},
 }
@@ -76,17 +178,124 @@ var initCmd = &cobra.Command{
 		name := args[0]
 
 		maxAgents, _ := cmd.Flags().GetInt("max-agents")
+		if !cmd.Flags().Changed("max-agents") && cfg.MaxAgents > 0 {
+			maxAgents = cfg.MaxAgents
+		}
 		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		if !cmd.Flags().Changed("threshold") && cfg.ConsensusThreshold > 0 {
+			threshold = cfg.ConsensusThreshold
+		}
+		decay, _ := cmd.Flags().GetFloat64("reputation-decay")
+		if !cmd.Flags().Changed("reputation-decay") && cfg.ReputationDecay > 0 {
+			decay = cfg.ReputationDecay
+		}
+		decayName, _ := cmd.Flags().GetString("decay-curve")
+		if !cmd.Flags().Changed("decay-curve") && cfg.ReputationDecayCurve != "" {
+			decayName = cfg.ReputationDecayCurve
+		}
+		decayCurve, err := agent.ParseDecayCurve(decayName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if decay < 0 || decay >= 1 {
+			fmt.Fprintln(os.Stderr, "Error: --reputation-decay must be in [0, 1)")
+			os.Exit(1)
+		}
+		gated, _ := cmd.Flags().GetBool("admission")
+		auction, _ := cmd.Flags().GetString("auction")
+		if _, err := coordination.NewAuctionStrategy(auction); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		tieBreak, _ := cmd.Flags().GetString("tie-break")
+		if _, err := coordination.NewTieBreaker(tieBreak); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		bidThreshold := cfg.BidThreshold
+		if bidThreshold != nil {
+			if err := bidThreshold.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		tokenBudget := cfg.TokenBudget
+		store := cfg.Storage
+		episodes := cfg.Episodes
+		if episodes != nil && episodes.CacheDir == "" {
+			withCache := *episodes
+			withCache.CacheDir = config.DefaultEpisodeCacheDir()
+			episodes = &withCache
+		}
+		sinks := cfg.EventSinks
+		bidTimeout := cfg.BidTimeout
+		qos := cfg.QoS
+		preemption := cfg.Preemption
+		deadlines := cfg.Deadlines
+		if deadlines != nil {
+			if err := deadlines.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		digests := cfg.Digests
+		reviews := cfg.Reviews
+		antiAffinity := cfg.AntiAffinity
+		webhook := cfg.SlackWebhook
+		collectiveKey, err := loadKey(config.DefaultCollectiveKeyPath())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: collective key: %v\n", err)
+			os.Exit(1)
+		}
 
 		cfg := collective.CollectiveConfig{
-			MinAgents:          2,
-			MaxAgents:          maxAgents,
-			ConsensusThreshold: threshold,
-			ReputationDecay:    0.01,
+			MinAgents:            2,
+			MaxAgents:            maxAgents,
+			ConsensusThreshold:   threshold,
+			ReputationDecay:      decay,
+			ReputationPath:       config.DefaultReputationPath(),
+			ReputationDecayCurve: decayCurve,
+			MemoryPath:           config.DefaultMemoryPath(),
+			TokenBudget:          tokenBudget,
+			Storage:              store,
+			Episodes:             episodes,
+			Auction:              auction,
+			TieBreak:             tieBreak,
+			BidThreshold:         bidThreshold,
+			QoS:                  qos,
+			Preemption:           preemption,
+			Deadlines:            deadlines,
+			Digests:              digests,
+			Reviews:              reviews,
+			AntiAffinity:         antiAffinity,
+			Keyring:              keyring,
+			Models:               cfg.Models,
+			Key:                  collectiveKey,
+		}
+		if gated {
+			policy := collective.DefaultAdmissionPolicy()
+			cfg.Admission = &policy
 		}
 
 		c := collective.NewCollective(name, cfg)
+		if bidTimeout > 0 {
+			c.GetMarket().SetBidTimeout(bidTimeout)
+		}
+		if webhook != "" {
+			c.OnDigest(collective.DigestWebhook(webhook))
+		}
+		// Agents found dead or stuck are replaced with fresh ones
+		c.SetLifecycle(agent.NewLifecycleManager(agent.NewRuntime(agent.DefaultRuntimeConfig()), provider, ""))
+		if router, ok := baseProvider().(*llm.Router); ok {
+			c.AddHealthProbe(providerHealthProbe(router))
+		}
+		if _, err := eventsink.Attach(c, sinks); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: events not published: %v\n", err)
+		}
+		recorder.Attach(c)
 		activeCollective = c
+		runRecorder = runlog.NewRecorder(c, runlog.NewStore(config.DefaultRunsDir()))
 
 		fmt.Printf("\n  Collective '%s' initialized\n\n", name)
 		fmt.Printf("  ID: %s\n", c.ID)
@@ -98,24 +307,140 @@ var initCmd = &cobra.Command{
 var spawnCmd = &cobra.Command{
 	Use:   "spawn [name]",
 	Short: "Spawn a new squaremind agent",
-	Long:  `Spawn a new autonomous agent with the specified capabilities.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Spawn a new autonomous agent with the specified capabilities.
+
+With --role, the agent takes its capabilities, system prompt, temperature
+and preferred model from a role template. The built-in roles are architect,
+researcher, implementer, critic, writer and tester; YAML files in
+~/.squaremind/roles add roles or replace built-in ones (see sqm role list).
+Flags given alongside --role override the role's settings. With
+--model-for, tasks requiring a capability run on a model of their own, so
+routine work can go to a cheaper model than the agent's; the models set
+in the config apply to the capabilities the agent doesn't assign. With
+--slots, the agent works on several tasks at once, as it spends most of
+each one waiting on the LLM; the market favors agents with free slots.
+
+Example:
+  sqm spawn designer --role architect
+  sqm spawn reviewer --role critic --model gpt-4
+  sqm spawn coder -c code.write,documentation --model-for documentation=claude-3-5-haiku-20241022
+  sqm spawn researcher --role researcher --slots 4`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
 		caps, _ := cmd.Flags().GetStringSlice("capabilities")
 		model, _ := cmd.Flags().GetString("model")
+		roleName, _ := cmd.Flags().GetString("role")
+
+		var role *roles.Role
+		if roleName != "" {
+			r, err := roles.Default().Get(roleName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			role = &r
+			if !cmd.Flags().Changed("capabilities") {
+				caps = nil
+				for _, c := range r.Capabilities {
+					caps = append(caps, string(c))
+				}
+			}
+		}
+		if !cmd.Flags().Changed("model") {
+			switch {
+			case role != nil && role.Model(modelAvailable) != "":
+				model = role.Model(modelAvailable)
+			case cfg.DefaultModel != "":
+				model = cfg.DefaultModel
+			}
+		}
+		labelPairs, _ := cmd.Flags().GetStringSlice("label")
+		costPairs, _ := cmd.Flags().GetStringSlice("cost-tag")
+		modelPairs, _ := cmd.Flags().GetStringSlice("model-for")
+		sandboxKind, _ := cmd.Flags().GetString("sandbox")
+		refineIterations, _ := cmd.Flags().GetInt("refine")
+		refineThreshold, _ := cmd.Flags().GetFloat64("refine-threshold")
+		criticSID, _ := cmd.Flags().GetString("critic")
+		systemPrompt, _ := cmd.Flags().GetString("system-prompt")
+		temperature, _ := cmd.Flags().GetFloat64("temperature")
+		maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+		slots, _ := cmd.Flags().GetInt("slots")
+		if slots <= 0 {
+			slots = cfg.AgentSlots
+		}
+		if temperature < 0 || temperature > 2 {
+			fmt.Fprintf(os.Stderr, "Error: --temperature must be between 0 and 2\n")
+			os.Exit(1)
+		}
+
+		labels, err := agent.ParseLabels(labelPairs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		costTags, err := agent.ParseLabels(costPairs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		models, err := agent.ParseModelMap(modelPairs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		box, err := openSandbox(sandboxKind)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var refine *agent.RefineConfig
+		if refineIterations > 0 {
+			refine = &agent.RefineConfig{Iterations: refineIterations, Threshold: refineThreshold}
+			if criticSID != "" {
+				if activeCollective == nil {
+					fmt.Fprintln(os.Stderr, "Error: --critic needs an active collective")
+					os.Exit(1)
+				}
+				critic, ok := activeCollective.GetAgent(criticSID)
+				if !ok {
+					fmt.Fprintf(os.Stderr, "Error: critic %s not found\n", criticSID)
+					os.Exit(1)
+				}
+				refine.Critic = critic
+			}
+		}
 
 		// Convert string capabilities to types
 		capTypes := make([]identity.CapabilityType, len(caps))
 		for i, c := range caps {
 			capTypes[i] = identity.CapabilityType(c)
 		}
+		warnUnknownCapabilities(capTypes)
 
 		cfg := agent.AgentConfig{
 			Name:         name,
 			Capabilities: capTypes,
 			Model:        model,
+			Models:       models,
 			Provider:     provider,
+			Labels:       labels,
+			CostTags:     costTags,
+			Sandbox:      box,
+			Contracts:    contracts,
+			Queue:        cfg.Queues,
+			Slots:        slots,
+			Refine:       refine,
+			Tools:        agentTools(),
+			Integrations: integrations,
+			Recorder:     agentRecorder(),
+			SystemPrompt: systemPrompt,
+			Temperature:  temperature,
+			MaxTokens:    maxTokens,
+		}
+		if role != nil {
+			role.Apply(&cfg)
 		}
 
 		a, err := agent.NewAgent(cfg)
@@ -126,6 +451,9 @@ var spawnCmd = &cobra.Command{
 
 		// Join collective if one is active
 		if activeCollective != nil {
+			if policy := activeCollective.AdmissionPolicy(); policy != nil && policy.WorkDifficulty > 0 {
+				a.Identity.ProveWork(policy.WorkDifficulty)
+			}
 			if err := activeCollective.Join(a); err != nil {
 				fmt.Fprintf(os.Stderr, "Error joining collective: %v\n", err)
 				os.Exit(1)
@@ -135,17 +463,64 @@ var spawnCmd = &cobra.Command{
 		fmt.Printf("\n  Squaremind agent '%s' spawned\n\n", name)
 		fmt.Printf("  SID: %s\n", a.Identity.SID)
 		fmt.Printf("  Public Key: %s...\n", a.Identity.PublicKeyHex()[:16])
+		if role != nil {
+			fmt.Printf("  Role: %s\n", role.Name)
+		}
 		fmt.Printf("  Capabilities: %v\n", caps)
 		fmt.Printf("  Model: %s\n", model)
+		if len(labels) > 0 {
+			fmt.Printf("  Labels: %s\n", labels)
+		}
+		if len(costTags) > 0 {
+			fmt.Printf("  Cost tags: %s\n", costTags)
+		}
+		if box != nil {
+			fmt.Printf("  Sandbox: %s\n", sandboxKind)
+		}
 		fmt.Printf("  Reputation: %.1f\n\n", a.Reputation.Overall)
 	},
 }
 
+// agentTools returns the toolbox agents are given, nil if there is none
+func agentTools() agent.Toolbox {
+	if toolbox == nil {
+		return nil
+	}
+	return toolbox
+}
+
+// agentRecorder returns the run recorder agents report executions to, nil
+// if runs aren't recorded
+func agentRecorder() agent.ExecutionRecorder {
+	if runRecorder == nil {
+		return nil
+	}
+	return runRecorder
+}
+
+// openSandbox creates the executor named by a --sandbox flag, or nil for
+// none
+func openSandbox(kind string) (sandbox.Executor, error) {
+	if kind == "" || kind == "none" {
+		return nil, nil
+	}
+	return sandbox.New(kind, sandbox.DefaultConfig())
+}
+
 var runCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Start the collective",
-	Long:  `Start all agents in the collective and begin autonomous operation.`,
+	Long: `Start all agents in the collective and begin autonomous operation.
+
+With --admin, the collective is also served over HTTP at that address, as
+'sqm serve' does, so it can be reconfigured while it runs: agents added,
+removed, paused and resumed ('sqm agent add|remove|pause|resume'),
+thresholds changed ('sqm parameters propose') and the collective paused or
+drained ('sqm pause --drain'). Changes need an API token from api_tokens
+in the config file.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		adminAddr, _ := cmd.Flags().GetString("admin")
+
 		if activeCollective == nil {
 			fmt.Fprintln(os.Stderr, "No collective initialized. Run 'sqm init <name>' first.")
 			os.Exit(1)
@@ -163,9 +538,21 @@ var runCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if err := openScheduler().Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: scheduler not started: %v\n", err)
+		}
+
 		fmt.Println("\n  Starting Squaremind collective...")
 		fmt.Printf("  Name: %s\n", activeCollective.Name)
 		fmt.Printf("  Agents: %d\n", activeCollective.Size())
+		if adminAddr != "" {
+			go func() {
+				if err := newServer().ListenAndServe(ctx, adminAddr); err != nil {
+					fmt.Fprintf(os.Stderr, "Admin server error: %v\n", err)
+				}
+			}()
+			fmt.Printf("  Admin API: %s\n", adminAddr)
+		}
 		fmt.Println("  Press Ctrl+C to stop")
 
 		// Wait for shutdown
@@ -193,31 +580,45 @@ var statusCmd = &cobra.Command{
 
 		stats := activeCollective.Stats()
 		fmt.Printf("  Name: %s\n", stats.Name)
+		if stats.Mode != collective.ModeRunning {
+			fmt.Printf("  Mode: %s\n", stats.Mode)
+		}
 		fmt.Printf("  Agents: %d\n", stats.AgentCount)
 		fmt.Printf("  Tasks Pending: %d\n", stats.PendingTasks)
 		fmt.Printf("  Tasks Active: %d\n", stats.ActiveTasks)
 		fmt.Printf("  Tasks Completed: %d\n", stats.CompletedTasks)
 		fmt.Printf("  Avg Reputation: %.1f\n", stats.AvgReputation)
+		if stats.RejectedTasks+stats.DroppedTasks+stats.DroppedResults > 0 {
+			fmt.Printf("  Queue Overflows: %d tasks refused, %d tasks dropped, %d results dropped\n", stats.RejectedTasks, stats.DroppedTasks, stats.DroppedResults)
+		}
 		fmt.Println()
 
+		if router, ok := baseProvider().(*llm.Router); ok {
+			routing := router.Stats()
+			fmt.Printf("  Providers: %d requests, %d failovers, %d failed, %d cancelled\n", routing.Requests, routing.Failovers, routing.Failures, routing.Cancelled)
+			for _, h := range routing.Providers {
+				health := "healthy"
+				if !h.Healthy {
+					health = "unhealthy: " + h.LastError
+				}
+				fmt.Printf("    - %s [%s] %d requests, %d failures\n", h.Name, health, h.Requests, h.Failures)
+			}
+			fmt.Println()
+		}
+
 		// List agents
 		agents := activeCollective.GetAgents()
 		if len(agents) > 0 {
 			fmt.Println("  Agents:")
 			for _, a := range agents {
 				state := string(a.GetState())
-				caps := a.Capabilities.List()
-				capStrs := make([]string, len(caps))
-				for i, c := range caps {
-					capStrs[i] = string(c)
-				}
 				fmt.Printf("    - %s (%s) [%s] rep=%.1f\n",
 					a.Identity.Name,
 					a.Identity.SIDShort(),
 					state,
 					a.Reputation.Overall,
 				)
-				fmt.Printf("      capabilities: %s\n", strings.Join(capStrs, ", "))
+				fmt.Printf("      capabilities: %s\n", formatProficiencies(a))
 			}
 		}
 		fmt.Println()
@@ -244,6 +645,68 @@ var taskSubmitCmd = &cobra.Command{
 		capsStr, _ := cmd.Flags().GetStringSlice("requires")
 		reward, _ := cmd.Flags().GetFloat64("reward")
 		async, _ := cmd.Flags().GetBool("async")
+		team, _ := cmd.Flags().GetString("team")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		priorityStr, _ := cmd.Flags().GetString("priority")
+		placementSpecs, _ := cmd.Flags().GetStringSlice("placement")
+		steps, _ := cmd.Flags().GetStringArray("step")
+		auction, _ := cmd.Flags().GetString("auction")
+		costPairs, _ := cmd.Flags().GetStringSlice("cost-tag")
+		systemPrompt, _ := cmd.Flags().GetString("system-prompt")
+		temperature, _ := cmd.Flags().GetFloat64("temperature")
+		maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+		qos, _ := cmd.Flags().GetString("qos")
+		author, _ := cmd.Flags().GetString("author")
+		metaPairs, _ := cmd.Flags().GetStringSlice("meta")
+		prefer, _ := cmd.Flags().GetStringSlice("prefer")
+		ban, _ := cmd.Flags().GetStringSlice("ban")
+		nearSpecs, _ := cmd.Flags().GetStringSlice("near")
+		hintWeight, _ := cmd.Flags().GetFloat64("hint-weight")
+
+		if temperature < 0 || temperature > 2 {
+			fmt.Fprintf(os.Stderr, "Error: --temperature must be between 0 and 2\n")
+			os.Exit(1)
+		}
+		if _, ok := activeCollective.QoSPolicies()[agent.QoSClass(qos)]; !ok {
+			fmt.Fprintf(os.Stderr, "Error: %v: %s\n", collective.ErrUnknownQoSClass, qos)
+			os.Exit(1)
+		}
+		priority, err := parsePriority(priorityStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		costTags, err := agent.ParseLabels(costPairs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		placement, err := agent.ParseConstraints(placementSpecs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		metadata, err := agent.ParseLabels(metaPairs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		near, err := agent.ParseConstraints(nearSpecs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		hints := agent.RoutingHints{Prefer: prefer, Ban: ban, Near: near, Weight: hintWeight}
+		if err := hints.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if auction != "" {
+			if _, err := coordination.NewAuctionStrategy(auction); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
 
 		// Convert capabilities
 		caps := make([]identity.CapabilityType, len(capsStr))
@@ -254,12 +717,39 @@ var taskSubmitCmd = &cobra.Command{
 		task := agent.NewTask(description, caps)
 		task.Complexity = complexity
 		task.Reward = reward
-		task.Deadline = time.Now().Add(time.Hour)
+		task.Team = team
+		task.Priority = priority
+		task.WithPlacement(placement...)
+		task.Steps = steps
+		task.Auction = auction
+		task.WithCostTags(costTags)
+		task.WithSystemPrompt(systemPrompt).WithMaxTokens(maxTokens)
+		if cmd.Flags().Changed("temperature") {
+			task.WithTemperature(temperature)
+		}
+		task.WithQoS(agent.QoSClass(qos))
+		task.WithAuthor(author)
+		task.WithMetadata(metadata)
+		task.WithHints(hints)
 
 		fmt.Printf("\n  Submitting task: %s\n", description)
 		fmt.Printf("  Task ID: %s\n", task.ID)
 		fmt.Printf("  Complexity: %s\n", complexity)
-		fmt.Printf("  Required capabilities: %v\n\n", capsStr)
+		fmt.Printf("  QoS class: %s\n", qos)
+		fmt.Printf("  Required capabilities: %v\n", capsStr)
+		if len(placement) > 0 {
+			fmt.Printf("  Placement: %v\n", placementSpecs)
+		}
+		if len(costTags) > 0 {
+			fmt.Printf("  Cost tags: %s\n", costTags)
+		}
+		if task.Hints != nil {
+			fmt.Printf("  Routing hints: prefer %v, ban %v, near %v\n", prefer, ban, nearSpecs)
+		}
+		for i, step := range steps {
+			fmt.Printf("  Step %d: %s\n", i+1, step)
+		}
+		fmt.Println()
 
 		if async {
 			id, err := activeCollective.SubmitAsync(task)
@@ -269,9 +759,30 @@ var taskSubmitCmd = &cobra.Command{
 			}
 			fmt.Printf("  Task submitted asynchronously. ID: %s\n\n", id)
 		} else {
-			result, err := activeCollective.Submit(task)
+			// Ctrl+C cancels the task instead of leaving the agent working
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+			if timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			recording := runRecorder != nil && runRecorder.Begin(task.ID, runlog.KindTask, description) == nil
+			result, err := activeCollective.SubmitCtx(ctx, task)
+			if recording {
+				output := ""
+				if result != nil {
+					output = result.Output
+				}
+				if _, err := runRecorder.End(output, err); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: run not recorded: %v\n", err)
+				}
+			}
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				if result != nil && result.Partial {
+					printPartial(result)
+				}
 				os.Exit(1)
 			}
 			fmt.Printf("  Task completed!\n")
@@ -301,16 +812,14 @@ var agentListCmd = &cobra.Command{
 		fmt.Printf("\n  Agents (%d total):\n\n", len(agents))
 
 		for _, a := range agents {
-			caps := a.Capabilities.List()
-			capStrs := make([]string, len(caps))
-			for i, c := range caps {
-				capStrs[i] = string(c)
-			}
 			fmt.Printf("  Name: %s\n", a.Identity.Name)
 			fmt.Printf("  SID: %s\n", a.Identity.SID)
 			fmt.Printf("  State: %s\n", a.GetState())
 			fmt.Printf("  Reputation: %.1f\n", a.Reputation.Overall)
-			fmt.Printf("  Capabilities: %s\n", strings.Join(capStrs, ", "))
+			fmt.Printf("  Capabilities: %s\n", formatProficiencies(a))
+			if len(a.Labels) > 0 {
+				fmt.Printf("  Labels: %s\n", a.Labels)
+			}
 			fmt.Printf("  Tasks Completed: %d\n", a.Reputation.TasksCompleted)
 			fmt.Println()
 		}
@@ -337,56 +846,122 @@ var agentStopCmd = &cobra.Command{
 	},
 }
 
-var configCmd = &cobra.Command{
-	Use:   "config",
-	Short: "Configuration management",
+// formatProficiencies renders an agent's capabilities with their learned proficiency
+func formatProficiencies(a *agent.Agent) string {
+	profs := a.Capabilities.Proficiencies()
+	caps := make([]string, 0, len(profs))
+	for c := range profs {
+		caps = append(caps, string(c))
+	}
+	sort.Strings(caps)
+
+	parts := make([]string, len(caps))
+	for i, c := range caps {
+		parts[i] = fmt.Sprintf("%s (%.2f)", c, profs[identity.CapabilityType(c)])
+	}
+	return strings.Join(parts, ", ")
 }
 
-var configSetCmd = &cobra.Command{
-	Use:   "set [key] [value]",
-	Short: "Set a configuration value",
-	Args:  cobra.ExactArgs(2),
-	Run: func(cmd *cobra.Command, args []string) {
-		key := args[0]
-		value := args[1]
-
-		switch key {
-		case "api-key":
-			provider = llm.NewClaudeProvider(value)
-			fmt.Printf("\n  API key configured for Claude.\n\n")
-		case "openai-key":
-			provider = llm.NewOpenAIProvider(value)
-			fmt.Printf("\n  API key configured for OpenAI.\n\n")
-		default:
-			fmt.Fprintf(os.Stderr, "Unknown config key: %s\n", key)
-			os.Exit(1)
+// printPartial shows the work salvaged from a task that was cut short
+func printPartial(result *agent.TaskResult) {
+	fmt.Printf("\n  Partial result (task did not finish):\n")
+	for _, cp := range result.Checkpoints {
+		fmt.Printf("  Checkpoint %s: %s\n", cp.Label, cp.State)
+	}
+	for _, tr := range result.ToolResults {
+		if tr.Error != "" {
+			fmt.Printf("  Tool %s failed: %s\n", tr.Tool, tr.Error)
+			continue
 		}
-	},
+		fmt.Printf("  Tool %s: %s\n", tr.Tool, tr.Output)
+	}
+	if result.Output != "" {
+		fmt.Printf("  Output so far:\n%s\n", result.Output)
+	}
+	fmt.Println()
+}
+
+// parsePriority accepts low, normal, high, critical or an integer priority
+func parsePriority(s string) (int, error) {
+	switch strings.ToLower(s) {
+	case "low":
+		return agent.PriorityLow, nil
+	case "normal", "":
+		return agent.PriorityNormal, nil
+	case "high":
+		return agent.PriorityHigh, nil
+	case "critical":
+		return agent.PriorityCritical, nil
+	}
+	p, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid priority %q: use low, normal, high, critical or a number", s)
+	}
+	return p, nil
 }
 
 func init() {
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&apiKey, "api-key", "", "Anthropic API key (overrides env and config)")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "Log level for collective activity (debug/info/warn/error)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Configuration profile to use (default: $SQM_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&mockScript, "mock", "", "Answer LLM requests from a mock script instead of a provider (see sqm llm)")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "Record LLM requests and responses to a mock script file")
 
 	// Init command flags
 	initCmd.Flags().IntP("max-agents", "m", 100, "Maximum number of agents")
 	initCmd.Flags().Float64P("threshold", "t", 0.67, "Consensus threshold (0.0-1.0)")
+	initCmd.Flags().Float64("reputation-decay", 0.01, "Share of an idle agent's reputation lost per day (0 = none)")
+	initCmd.Flags().String("decay-curve", string(agent.DecayLinear), "How idle agents' reputation decays: linear, exponential or none")
+	initCmd.Flags().Bool("admission", false, "Require proof of work or a member's voucher to join")
+	initCmd.Flags().String("auction", coordination.AuctionWeighted, "Market auction strategy: weighted, sealed_bid, vickrey or reverse")
+	initCmd.Flags().String("tie-break", coordination.TieBreakCapability, "How bids ranked level are ordered: capability_score, least_recently_assigned, lowest_cost or lottery")
 
 	// Spawn command flags
 	spawnCmd.Flags().StringSliceP("capabilities", "c", []string{"code.write"}, "Agent capabilities")
 	spawnCmd.Flags().StringP("model", "m", string(llm.DefaultModel), "LLM model to use")
+	spawnCmd.Flags().StringP("role", "r", "", "Role template to spawn from (see sqm role list)")
+	spawnCmd.Flags().StringSlice("model-for", []string{}, "Run tasks requiring a capability on another model (e.g. documentation=claude-3-5-haiku-20241022)")
+	spawnCmd.Flags().StringSlice("label", []string{}, "Placement labels of the agent's host (e.g. region=eu,gpu=true)")
+	spawnCmd.Flags().String("system-prompt", "", "Instructions the agent works under (overrides the role's)")
+	spawnCmd.Flags().Float64("temperature", 0, "Sampling temperature, 0-2 (0 = the role's or the provider default)")
+	spawnCmd.Flags().Int("max-tokens", 0, "Limit on each response when a task sets none (0 = provider default)")
+	spawnCmd.Flags().Int("slots", 0, "Tasks the agent works on at once (0 = agent-slots from the config, else 1)")
+	spawnCmd.Flags().StringSlice("cost-tag", []string{}, "Charge the agent's tokens to these labels unless a task sets its own (e.g. cost-center=ml)")
+	spawnCmd.Flags().Int("refine", 0, "Have each output critiqued and revise it up to this many times (0 = off)")
+	spawnCmd.Flags().Float64("refine-threshold", agent.DefaultRefineThreshold, "Critique quality, 0-1, at which an output is accepted")
+	spawnCmd.Flags().String("critic", "", "SID of the agent critiquing the outputs (default the agent itself)")
+	spawnCmd.Flags().String("sandbox", "", "Run the code the agent writes and feed failures back to it: process or container (code.write and testing agents)")
 
 	// Task submit flags
 	taskSubmitCmd.Flags().StringP("complexity", "x", "medium", "Task complexity (low/medium/high)")
 	taskSubmitCmd.Flags().StringSliceP("requires", "r", []string{}, "Required capabilities")
 	taskSubmitCmd.Flags().Float64P("reward", "w", 10, "Reputation reward")
 	taskSubmitCmd.Flags().BoolP("async", "a", false, "Submit asynchronously")
+	taskSubmitCmd.Flags().String("team", "", "Route the task to a named team")
+	taskSubmitCmd.Flags().Duration("timeout", 0, "Cancel the task if it has not finished within this duration (0 = no limit)")
+	taskSubmitCmd.Flags().String("priority", "normal", "Task priority (low/normal/high/critical or a number)")
+	taskSubmitCmd.Flags().String("qos", string(agent.QoSStandard), "Quality-of-service class: interactive, standard, batch or one from the config file")
+	taskSubmitCmd.Flags().StringSlice("placement", []string{}, "Only run on agents whose labels match (e.g. region=eu, gpu, zone!=public)")
+	taskSubmitCmd.Flags().StringArray("step", []string{}, "A step of a multi-step task, performed in order as one conversation (repeatable)")
+	taskSubmitCmd.Flags().String("auction", "", "Auction strategy for this task (default: the collective's)")
+	taskSubmitCmd.Flags().String("system-prompt", "", "Instructions for this task, replacing the agent's")
+	taskSubmitCmd.Flags().Float64("temperature", 0, "Sampling temperature for this task, 0-2 (unset = the agent's)")
+	taskSubmitCmd.Flags().Int("max-tokens", 0, "Limit on the response (0 = the agent's)")
+	taskSubmitCmd.Flags().StringSlice("cost-tag", []string{}, "Charge the task's tokens to these labels (e.g. cost-center=ml,project=search)")
+	taskSubmitCmd.Flags().String("author", "", "SID of the agent whose work this task reviews, checked against the anti-affinity policy")
+	taskSubmitCmd.Flags().StringSlice("meta", []string{}, "Settings for integrations (e.g. repo=acme/widgets,base_branch=main)")
+	taskSubmitCmd.Flags().StringSlice("prefer", []string{}, "Favour bids from these agents (SIDs or prefixes)")
+	taskSubmitCmd.Flags().StringSlice("ban", []string{}, "Agents that may not bid on the task (SIDs or prefixes)")
+	taskSubmitCmd.Flags().StringSlice("near", []string{}, "Favour bids from agents whose labels satisfy these constraints (e.g. region=eu)")
+	taskSubmitCmd.Flags().Float64("hint-weight", 0, "Weight of the routing hints in bid scores (0 = the market's default)")
+
+	runCmd.Flags().String("admin", "", "Also serve the HTTP API on this address for live reconfiguration (e.g. 127.0.0.1:8420)")
 
 	// Add subcommands
 	taskCmd.AddCommand(taskSubmitCmd)
 	agentCmd.AddCommand(agentListCmd)
 	agentCmd.AddCommand(agentStopCmd)
-	configCmd.AddCommand(configSetCmd)
 
 	// Add all commands to root
 	rootCmd.AddCommand(initCmd)
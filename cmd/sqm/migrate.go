@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/migrate"
+)
+
+// stateMigrations upgrade the files in the state directory. Append new
+// migrations for a store with the next version; never edit or reorder ones
+// that have been released.
+var stateMigrations = []migrate.Migration{
+	{
+		Store:       "config",
+		Version:     1,
+		Description: "restrict the config file, which holds API keys, to its owner",
+		Apply: func(dir string) error {
+			path := filepath.Join(dir, "config.yaml")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				return nil
+			}
+			return os.Chmod(path, 0600)
+		},
+	},
+	{
+		Store:       "memory",
+		Version:     1,
+		Description: "stamp the memory database with its schema version",
+		Apply: func(dir string) error {
+			path := filepath.Join(dir, "memory.db")
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				return nil
+			}
+			store, err := collective.OpenSQLiteMemoryStore(path)
+			if err != nil {
+				return err
+			}
+			return store.Close()
+		},
+	},
+	{
+		Store:       "reputation",
+		Version:     1,
+		Description: "check the reputation history is in snapshot format 1",
+		Apply: func(dir string) error {
+			return coordination.NewReputationRegistry().LoadFile(filepath.Join(dir, "reputation.json"))
+		},
+	},
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade persisted state to this release",
+	Long: `Upgrade the config, memory database and reputation history in
+~/.squaremind to the formats this release uses.
+
+The directory is copied to ~/.squaremind/backups/<timestamp> first, and
+restored from the copy if a migration fails. 'sqm serve' migrates on start,
+so running this by hand is only needed to preview (--dry-run) or to upgrade
+without starting the daemon.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if dryRun {
+			pending, err := pendingMigrations()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if len(pending) == 0 {
+				fmt.Print("\n  State is up to date\n\n")
+				return
+			}
+			fmt.Printf("\n  %d pending migrations in %s:\n", len(pending), config.DefaultStateDir())
+			for _, m := range pending {
+				fmt.Printf("    %-12s v%d  %s\n", m.Store, m.Version, m.Description)
+			}
+			fmt.Println()
+			return
+		}
+
+		result, err := migrateState()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(result.Applied) == 0 {
+			fmt.Print("\n  State is up to date\n\n")
+			return
+		}
+		printMigrations(result)
+		fmt.Println()
+	},
+}
+
+// stateMigrator returns a migrator for the state directory
+func stateMigrator() (*migrate.Migrator, error) {
+	return migrate.New(config.DefaultStateDir(), stateMigrations)
+}
+
+// pendingMigrations returns the migrations the state directory still needs
+func pendingMigrations() ([]migrate.Migration, error) {
+	m, err := stateMigrator()
+	if err != nil {
+		return nil, err
+	}
+	return m.Pending()
+}
+
+// migrateState applies pending migrations to the state directory
+func migrateState() (migrate.Result, error) {
+	m, err := stateMigrator()
+	if err != nil {
+		return migrate.Result{}, err
+	}
+	result, err := m.Run()
+	if errors.Is(err, migrate.ErrNewerState) {
+		return result, fmt.Errorf("%w; upgrade sqm or restore a backup from %s", err, filepath.Join(config.DefaultStateDir(), migrate.BackupDir))
+	}
+	return result, err
+}
+
+// printMigrations lists the migrations a run applied
+func printMigrations(result migrate.Result) {
+	fmt.Printf("\n  Applied %d migrations (backup in %s):\n", len(result.Applied), result.Backup)
+	for _, m := range result.Applied {
+		fmt.Printf("    %-12s v%d  %s\n", m.Store, m.Version, m.Description)
+	}
+}
+
+func init() {
+	migrateCmd.Flags().Bool("dry-run", false, "List pending migrations without applying them")
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+var parametersCmd = &cobra.Command{
+	Use:   "parameters",
+	Short: "Show or propose changes to consensus-gated collective settings",
+	Long: `The consensus threshold, agent limit, bid timeout and minimum bid score
+of a running 'sqm serve' change only through a proposal its agents accept.
+
+Example:
+  sqm parameters show
+  sqm parameters propose --threshold 0.75 --bid-timeout 10s`,
+}
+
+var parametersShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the current parameters of the server at --server",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+
+		var params collective.Parameters
+		if err := apiRequest(http.MethodGet, server, "/api/parameters", "", nil, &params); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n  Consensus threshold: %.2f\n  Max agents: %d\n  Bid timeout: %v\n  Min bid score: %.2f\n\n",
+			params.ConsensusThreshold, params.MaxAgents, params.BidTimeout, params.MinBidScore)
+	},
+}
+
+var parametersProposeCmd = &cobra.Command{
+	Use:   "propose",
+	Short: "Propose a parameter change for the agents to vote on",
+	Long: `Propose a change to the parameters of the server at --server. Its agents
+vote on it, weighted by reputation, and the change applies only if it
+reaches the consensus threshold. Parameters without a flag keep their
+value. Requires an API token.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		maxAgents, _ := cmd.Flags().GetInt("max-agents")
+		bidTimeout, _ := cmd.Flags().GetDuration("bid-timeout")
+		minBidScore, _ := cmd.Flags().GetFloat64("min-bid-score")
+
+		body := map[string]interface{}{}
+		if threshold != 0 {
+			body["consensus_threshold"] = threshold
+		}
+		if maxAgents != 0 {
+			body["max_agents"] = maxAgents
+		}
+		if bidTimeout != 0 {
+			body["bid_timeout"] = bidTimeout.String()
+		}
+		if minBidScore != 0 {
+			body["min_bid_score"] = minBidScore
+		}
+		if len(body) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: nothing to change: set --threshold, --max-agents, --bid-timeout or --min-bid-score")
+			os.Exit(1)
+		}
+
+		var proposal collective.ParameterProposal
+		if err := apiRequest(http.MethodPost, server, "/api/parameters", token, body, &proposal); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\n  Proposal %s %s (%d for, %d against)\n\n", proposal.ProposalID, proposal.Result, proposal.For, proposal.Against)
+	},
+}
+
+func init() {
+	parametersProposeCmd.Flags().Float64("threshold", 0, "New consensus threshold (0-1)")
+	parametersProposeCmd.Flags().Int("max-agents", 0, "New agent limit")
+	parametersProposeCmd.Flags().Duration("bid-timeout", 0, "New market bid timeout")
+	parametersProposeCmd.Flags().Float64("min-bid-score", 0, "New capability score agents need to bid (0-1)")
+	parametersProposeCmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+	for _, cmd := range []*cobra.Command{parametersShowCmd, parametersProposeCmd} {
+		cmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query")
+		parametersCmd.AddCommand(cmd)
+	}
+	rootCmd.AddCommand(parametersCmd)
+}
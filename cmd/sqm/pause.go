@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Stop dispatching tasks, for deploys and incident response",
+	Long: `Pause a running 'sqm serve' at --server. New submissions are accepted
+and wait; tasks already assigned run to completion. With --maintenance,
+schedules and the capability gap events autoscalers act on are suspended
+too. With --drain, new submissions are refused instead of held, so the
+process can be stopped once the tasks already assigned have finished.
+Requires an API token.
+
+With --wait, wait until no assigned task is still running.
+
+Example:
+  sqm pause --maintenance --reason "deploying v0.9" --wait
+  sqm pause --drain --wait 10m
+  sqm resume`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		maintenance, _ := cmd.Flags().GetBool("maintenance")
+		drain, _ := cmd.Flags().GetBool("drain")
+		reason, _ := cmd.Flags().GetString("reason")
+		wait, _ := cmd.Flags().GetDuration("wait")
+
+		mode := collective.ModePaused
+		switch {
+		case maintenance && drain:
+			fmt.Fprintln(os.Stderr, "Error: --maintenance and --drain can't be combined")
+			os.Exit(1)
+		case maintenance:
+			mode = collective.ModeMaintenance
+		case drain:
+			mode = collective.ModeDraining
+		}
+		status, err := setMode(cmd, mode, reason)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printMode(status)
+
+		if wait <= 0 || status.InFlight == 0 {
+			return
+		}
+		fmt.Printf("  Waiting for %d in-flight tasks...\n", status.InFlight)
+		server, _ := cmd.Flags().GetString("server")
+		deadline := time.Now().Add(wait)
+		for status.InFlight > 0 {
+			if time.Now().After(deadline) {
+				fmt.Fprintf(os.Stderr, "Error: %d tasks still in flight after %v\n", status.InFlight, wait)
+				os.Exit(1)
+			}
+			time.Sleep(time.Second)
+			if err := apiRequest(http.MethodGet, server, "/api/mode", "", nil, &status); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		fmt.Println("  Drained: no tasks in flight")
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume dispatching after 'sqm pause'",
+	Long: `Resume a paused or draining 'sqm serve' at --server, or bring it out of
+maintenance mode, dispatching the submissions held meanwhile. Requires an
+API token.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		status, err := setMode(cmd, collective.ModeRunning, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		printMode(status)
+	},
+}
+
+// setMode changes the run mode of the server at --server
+func setMode(cmd *cobra.Command, mode collective.RunMode, reason string) (collective.ModeStatus, error) {
+	server, _ := cmd.Flags().GetString("server")
+	token, _ := cmd.Flags().GetString("token")
+
+	var status collective.ModeStatus
+	body := map[string]string{"mode": string(mode), "reason": reason}
+	err := apiRequest(http.MethodPut, server, "/api/mode", token, body, &status)
+	return status, err
+}
+
+// printMode prints a run mode status
+func printMode(status collective.ModeStatus) {
+	fmt.Printf("\n  Mode: %s", status.Mode)
+	if status.Reason != "" {
+		fmt.Printf(" (%s)", status.Reason)
+	}
+	fmt.Printf("\n  Held submissions: %d\n  In-flight tasks: %d\n\n", status.Held, status.InFlight)
+}
+
+func init() {
+	pauseCmd.Flags().Bool("maintenance", false, "Also suspend schedules and autoscaling signals")
+	pauseCmd.Flags().Bool("drain", false, "Refuse new submissions until resumed")
+	pauseCmd.Flags().String("reason", "", "Why the collective is paused, shown in its status")
+	pauseCmd.Flags().Duration("wait", 0, "Wait up to this long for in-flight tasks to finish")
+	for _, cmd := range []*cobra.Command{pauseCmd, resumeCmd} {
+		cmd.Flags().String("server", "http://127.0.0.1:8420", "Server to pause or resume")
+		cmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+		rootCmd.AddCommand(cmd)
+	}
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report on the collective",
+}
+
+var reportGapsCmd = &cobra.Command{
+	Use:   "gaps",
+	Short: "Show which capabilities the collective lacks and which agents to spawn",
+	Long: `Analyze recent auctions that no capable agent bid in or that were won with
+a weak capability match, and queued tasks no agent can take, to report the
+capabilities the collective lacks or holds too weakly. Each gap is one of:
+
+  missing          no agent holds the capability
+  low_proficiency  agents hold it, but none proficiently enough
+  combination      agents hold it, but not with the other capabilities its tasks need
+  capacity         capable agents exist, but too few to go round
+
+Suggested agent templates group the unmet tasks by the capabilities they
+require. The maintenance loop publishes a capability_gaps event on the
+activity stream whenever the set of gaps changes, for an autoscaler to act on.
+
+The report is built from the collective in this process if one is active,
+otherwise read from a running 'sqm serve' or 'sqm dashboard' at --server.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		window, _ := cmd.Flags().GetDuration("window")
+
+		var (
+			report *collective.GapReport
+			err    error
+		)
+		if activeCollective != nil {
+			report = activeCollective.CapabilityGaps(collective.GapConfig{Window: window})
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			report, err = fetchGapReport(server, window)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printGapReport(report)
+	},
+}
+
+var reportUsageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show token spend by agent and chargeback by cost tag",
+	Long: `Attribute the tokens spent on tasks to the agents that ran them and, with
+--by, to the values of a cost tag for chargeback.
+
+A task is charged to the cost tags of the agent that ran it (sqm spawn
+--cost-tag), overridden by its own (sqm task submit --cost-tag). Tasks are
+also charged to the team they were routed to and the client that submitted
+them, under the team and submitter tags, unless tagged otherwise. Spend on
+tasks without the reported tag is shown as (untagged).
+
+The same attribution is exported at /metrics for Prometheus.
+
+Example:
+  sqm report usage --by cost-center
+  sqm report usage --by project --server http://collective:8420`,
+	Run: func(cmd *cobra.Command, args []string) {
+		by, _ := cmd.Flags().GetString("by")
+
+		var (
+			report *collective.UsageReport
+			err    error
+		)
+		if activeCollective != nil {
+			report = activeCollective.UsageReport(by)
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			report, err = fetchUsageReport(server, by)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printUsageReport(report)
+	},
+}
+
+var reportKeysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Show the use of each keyring credential",
+	Long: `Show how many tasks, requests and tokens each credential in the keyring
+was used for.
+
+Credentials bind provider keys and tool secrets to capabilities and teams,
+so that, say, the security team's tasks run under a separate audited key:
+
+  keyring:
+    - {name: security, kind: anthropic, key_env: SECURITY_ANTHROPIC_KEY, teams: [security]}
+    - {name: scanner, kind: tool, key_env: SCANNER_TOKEN, env: SCANNER_TOKEN, capabilities: [security]}
+
+Example:
+  sqm report keys
+  sqm report keys --server http://collective:8420`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			usage []agent.KeyUsage
+			err   error
+		)
+		if activeCollective != nil {
+			if k := activeCollective.GetKeyring(); k != nil {
+				usage = k.Usage()
+			}
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			usage, err = fetchKeyUsage(server)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(usage) == 0 {
+			fmt.Println("\n  No keyring credentials configured.")
+			fmt.Println()
+			return
+		}
+		fmt.Printf("\n  %-20s %-10s %6s %8s %6s %10s  %s\n", "CREDENTIAL", "KIND", "TASKS", "REQUESTS", "ERRORS", "TOKENS", "LAST USED")
+		for _, u := range usage {
+			last := "-"
+			if !u.LastUsed.IsZero() {
+				last = u.LastUsed.Format(time.RFC3339)
+			}
+			fmt.Printf("  %-20s %-10s %6d %8d %6d %10d  %s\n", u.Name, u.Kind, u.Tasks, u.Requests, u.Errors, u.TokensUsed, last)
+		}
+		fmt.Println()
+	},
+}
+
+var reportDigestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Summarize the tasks finished in the last day or week",
+	Long: `Show a digest of the tasks the collective finished in the last day or,
+with --period weekly, the last week: for each task what was asked, the agent
+that did it, the key outcome and its quality, newest first, with totals per
+agent.
+
+Summaries are drawn from each task's description and output as it finishes,
+without spending tokens, and kept for eight days.
+
+To post digests to the slack_webhook on a schedule, list them in the config
+file (cron defaults to 09:00 daily, or Mondays for weekly digests):
+
+  digests:
+    - period: daily
+    - period: weekly
+      cron: "0 17 * * 5"
+
+Example:
+  sqm report digest
+  sqm report digest --period weekly --server http://collective:8420`,
+	Run: func(cmd *cobra.Command, args []string) {
+		p, _ := cmd.Flags().GetString("period")
+		period, err := collective.ParseDigestPeriod(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		var digest *collective.Digest
+		if activeCollective != nil {
+			digest = activeCollective.Digest(period, time.Time{})
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			digest, err = fetchDigest(server, period)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printDigest(digest)
+	},
+}
+
+// printDigest renders a digest as a summary and a task list
+func printDigest(d *collective.Digest) {
+	fmt.Printf("\n  %s digest, %s to %s\n", strings.ToUpper(string(d.Period[:1]))+string(d.Period[1:]),
+		d.Start.Format("Jan 2 15:04"), d.End.Format("Jan 2 15:04"))
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	fmt.Printf("  Tasks: %d   Completed: %d   Failed: %d   Quality: %.2f   Tokens: %d\n",
+		d.Tasks, d.Completed, d.Failed, d.Quality, d.Tokens)
+
+	if len(d.Summaries) == 0 {
+		fmt.Println("\n  No tasks finished.")
+		fmt.Println()
+		return
+	}
+
+	fmt.Printf("\n  %-20s %-10s %6s %6s %8s\n", "AGENT", "SID", "TASKS", "FAILED", "QUALITY")
+	for _, a := range d.Agents {
+		sid := a.SID
+		if len(sid) > 8 {
+			sid = sid[:8]
+		}
+		fmt.Printf("  %-20s %-10s %6d %6d %8.2f\n", a.Name, sid, a.Tasks, a.Failed, a.Quality)
+	}
+
+	fmt.Println()
+	for _, s := range d.Summaries {
+		who := s.AgentName
+		if who == "" {
+			who = s.AgentSID
+		}
+		fmt.Printf("  %s  %s\n", s.FinishedAt.Format("Jan 2 15:04"), s.Asked)
+		fmt.Printf("      %s, %s, quality %.2f: %s\n", who, s.Status, s.Quality, s.Outcome)
+	}
+	fmt.Println()
+}
+
+// fetchDigest reads a digest from a running server
+func fetchDigest(server string, period collective.DigestPeriod) (*collective.Digest, error) {
+	endpoint := strings.TrimRight(server, "/") + "/api/digest?period=" + url.QueryEscape(string(period))
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("no collective in this process and server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var digest collective.Digest
+	if err := json.NewDecoder(resp.Body).Decode(&digest); err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
+
+// printUsageReport renders a usage report as tables
+func printUsageReport(report *collective.UsageReport) {
+	fmt.Printf("\n  Token usage since %s\n", report.Since.Format(time.RFC3339))
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	fmt.Printf("  Tasks: %d   Failed: %d   Tokens: %d\n", report.Tasks, report.Failed, report.Tokens)
+
+	if len(report.Agents) > 0 {
+		fmt.Printf("\n  %-20s %-10s %6s %6s %10s  %s\n", "AGENT", "SID", "TASKS", "FAILED", "TOKENS", "COST TAGS")
+		for _, a := range report.Agents {
+			sid := a.SID
+			if len(sid) > 8 {
+				sid = sid[:8]
+			}
+			fmt.Printf("  %-20s %-10s %6d %6d %10d  %s\n", a.Name, sid, a.Tasks, a.Failed, a.Tokens, a.CostTags)
+		}
+	}
+
+	if report.By != "" {
+		fmt.Printf("\n  Chargeback by %s\n", report.By)
+		fmt.Printf("  %-24s %6s %6s %10s %7s\n", strings.ToUpper(report.By), "TASKS", "FAILED", "TOKENS", "SHARE")
+		for _, line := range report.Chargeback {
+			fmt.Printf("  %-24s %6d %6d %10d %6.1f%%\n", line.Value, line.Tasks, line.Failed, line.Tokens, line.Share*100)
+		}
+	} else if len(report.Keys) > 0 {
+		fmt.Printf("\n  Charge back with --by: %s\n", strings.Join(report.Keys, ", "))
+	}
+	fmt.Println()
+}
+
+// fetchKeyUsage reads the keyring's usage from a running server
+func fetchKeyUsage(server string) ([]agent.KeyUsage, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(strings.TrimRight(server, "/") + "/api/keys")
+	if err != nil {
+		return nil, fmt.Errorf("no collective in this process and server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var usage []agent.KeyUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// fetchUsageReport reads a usage report from a running server
+func fetchUsageReport(server, by string) (*collective.UsageReport, error) {
+	endpoint := strings.TrimRight(server, "/") + "/api/usage"
+	if by != "" {
+		endpoint += "?by=" + url.QueryEscape(by)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("no collective in this process and server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var report collective.UsageReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// printGapReport renders a gap report as tables
+func printGapReport(report *collective.GapReport) {
+	fmt.Printf("\n  Capability gaps (last %s)\n", report.Window)
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	fmt.Printf("  Auctions: %d   Unassigned: %d   Low score: %d   Pending: %d   Unplaceable: %d\n",
+		report.Auctions, report.Unassigned, report.LowScore, report.Pending, report.Unplaceable)
+
+	if len(report.Gaps) == 0 {
+		fmt.Println("\n  No capability gaps.")
+		fmt.Println()
+		return
+	}
+
+	fmt.Printf("\n  %-16s %-16s %6s %10s %9s %7s %8s %7s %9s\n",
+		"CAPABILITY", "KIND", "DEMAND", "UNASSIGNED", "LOW SCORE", "PENDING", "CAPACITY", "HOLDERS", "BEST")
+	for _, g := range report.Gaps {
+		fmt.Printf("  %-16s %-16s %6d %10d %9d %7d %8d %7d %9.2f\n",
+			g.Capability, g.Kind, g.Demand, g.Unassigned, g.LowScore, g.Pending, g.Capacity, g.Holders, g.BestProficiency)
+	}
+
+	if len(report.Suggestions) > 0 {
+		fmt.Println("\n  Suggested agents:")
+		for _, t := range report.Suggestions {
+			caps := make([]string, len(t.Capabilities))
+			for i, c := range t.Capabilities {
+				caps[i] = string(c)
+			}
+			spawn := "sqm spawn " + t.Name
+			if len(caps) > 0 {
+				spawn += " -c " + strings.Join(caps, ",")
+			}
+			fmt.Printf("    %d × %-30s %3d tasks, %-16s %s\n", t.Count, t.Name, t.Tasks, t.Reason, spawn)
+		}
+	}
+	fmt.Println()
+}
+
+// fetchGapReport reads a gap report from a running server
+func fetchGapReport(server string, window time.Duration) (*collective.GapReport, error) {
+	endpoint := strings.TrimRight(server, "/") + "/api/gaps"
+	if window > 0 {
+		endpoint += "?window=" + url.QueryEscape(window.String())
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("no collective in this process and server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var report collective.GapReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func init() {
+	reportGapsCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query when no collective is active")
+	reportGapsCmd.Flags().Duration("window", time.Hour, "How far back auctions are considered")
+	reportCmd.AddCommand(reportGapsCmd)
+	reportUsageCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query when no collective is active")
+	reportUsageCmd.Flags().String("by", "", "Cost tag to charge back by (e.g. cost-center, project, team, submitter)")
+	reportCmd.AddCommand(reportUsageCmd)
+	reportKeysCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query when no collective is active")
+	reportCmd.AddCommand(reportKeysCmd)
+	reportDigestCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query when no collective is active")
+	reportDigestCmd.Flags().String("period", string(collective.DigestDaily), "Span of the digest: daily or weekly")
+	reportCmd.AddCommand(reportDigestCmd)
+	rootCmd.AddCommand(reportCmd)
+}
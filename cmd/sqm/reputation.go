@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/coordination"
+)
+
+var reputationCmd = &cobra.Command{
+	Use:   "reputation",
+	Short: "Inspect, export and import agent reputation",
+}
+
+var reputationShowCmd = &cobra.Command{
+	Use:   "show [sid]",
+	Short: "Show an agent's reputation and full event history",
+	Long: `Show an agent's reputation scores and every recorded reputation event.
+
+The SID may be abbreviated to any unique prefix. Reputation is read from the
+collective in this process if one is active, otherwise from the saved
+reputation file.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registry, err := openReputation()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		sid, err := resolveSID(registry, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		record, err := registry.Record(sid)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		rep := record.Reputation
+		status := "active"
+		if !record.Active {
+			status = "not in collective"
+		}
+
+		fmt.Printf("\n  Agent %s (%s)\n", record.AgentSID, status)
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		fmt.Printf("  Overall:     %.1f\n", rep.Overall)
+		fmt.Printf("  Reliability: %.1f\n", rep.Reliability)
+		fmt.Printf("  Quality:     %.1f\n", rep.Quality)
+		fmt.Printf("  Cooperation: %.1f\n", rep.Cooperation)
+		fmt.Printf("  Honesty:     %.1f\n", rep.Honesty)
+		if cal := rep.Calibration; cal.Samples > 0 {
+			fmt.Printf("  Calibration: confidence %.2f, quality %.2f, mean error %.2f over %d results\n",
+				cal.MeanConfidence, cal.MeanQuality, cal.MeanError, cal.Samples)
+		}
+		fmt.Printf("  Tasks:       %d completed, %d failed\n", rep.TasksCompleted, rep.TasksFailed)
+		if !rep.LastActive.IsZero() {
+			fmt.Printf("  Last active: %s\n", rep.LastActive.Local().Format("2006-01-02 15:04:05"))
+		}
+
+		fmt.Printf("\n  History (%d events):\n", len(record.History))
+		if len(record.History) == 0 {
+			fmt.Println("    No events recorded.")
+		}
+		for _, event := range record.History {
+			fmt.Printf("    %s  %-13s %+7.2f  %s\n",
+				event.Timestamp.Local().Format("2006-01-02 15:04:05"), event.Type, event.Delta, event.Reason)
+		}
+		fmt.Println()
+	},
+}
+
+var reputationExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export reputation and history as JSON (stdout if no file is given)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		registry, err := openReputation()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		data, err := registry.ExportJSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(args) == 0 {
+			fmt.Println(string(data))
+			return
+		}
+		if err := os.WriteFile(args[0], data, 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  Exported reputation to %s\n", args[0])
+	},
+}
+
+var reputationImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import reputation exported from another session",
+	Long: `Import a reputation export. Agents in the export replace any saved
+reputation with the same SID; agents not yet in the collective take it on when
+they join.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		registry, err := openReputation()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := registry.ImportJSON(data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		path := config.DefaultReputationPath()
+		if err := registry.SaveFile(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  Imported %d agents into %s\n", len(registry.Snapshot().Agents), path)
+	},
+}
+
+// openReputation returns the active collective's registry, or one loaded
+// from the saved reputation file
+func openReputation() (*coordination.ReputationRegistry, error) {
+	if activeCollective != nil {
+		return activeCollective.GetReputation(), nil
+	}
+
+	registry := coordination.NewReputationRegistry()
+	if err := registry.LoadFile(config.DefaultReputationPath()); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// resolveSID expands a unique SID prefix
+func resolveSID(registry *coordination.ReputationRegistry, prefix string) (string, error) {
+	var matches []string
+	for _, record := range registry.Snapshot().Agents {
+		if record.AgentSID == prefix {
+			return prefix, nil
+		}
+		if strings.HasPrefix(record.AgentSID, prefix) {
+			matches = append(matches, record.AgentSID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", coordination.ErrUnknownAgent, prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("SID prefix %s is ambiguous (%d agents)", prefix, len(matches))
+	}
+}
+
+func init() {
+	reputationCmd.AddCommand(reputationShowCmd)
+	reputationCmd.AddCommand(reputationExportCmd)
+	reputationCmd.AddCommand(reputationImportCmd)
+	rootCmd.AddCommand(reputationCmd)
+}
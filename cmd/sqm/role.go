@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/roles"
+)
+
+var roleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "List and inspect agent role templates",
+	Long: `Role templates bundle the capabilities, system prompt, temperature and
+preferred models of an agent; spawn one with sqm spawn --role.
+
+Add a role, or replace a built-in one, with a YAML file in
+~/.squaremind/roles named after it:
+
+  description: Database specialist
+  capabilities: [analysis, code.write]
+  system_prompt: You are a database administrator. Favour correctness over speed.
+  temperature: 0.2
+  models: [claude-3-5-sonnet-20241022, gpt-4-turbo]`,
+}
+
+var roleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List built-in and custom roles",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("\n  Roles")
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		for _, role := range roles.Default().List() {
+			kind := "custom"
+			if role.Builtin {
+				kind = "built-in"
+			}
+			fmt.Printf("  %-14s %-9s %s\n", role.Name, kind, role.Description)
+		}
+		fmt.Printf("\n  Custom roles: %s\n\n", config.DefaultRolesDir())
+	},
+}
+
+var roleShowCmd = &cobra.Command{
+	Use:   "show [role]",
+	Short: "Show a role template",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		role, err := roles.Default().Get(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		caps := make([]string, len(role.Capabilities))
+		for i, c := range role.Capabilities {
+			caps[i] = string(c)
+		}
+		fmt.Printf("\n  Role: %s\n", role.Name)
+		if role.Description != "" {
+			fmt.Printf("  Description: %s\n", role.Description)
+		}
+		fmt.Printf("  Capabilities: %s\n", strings.Join(caps, ", "))
+		if role.Temperature > 0 {
+			fmt.Printf("  Temperature: %.2f\n", role.Temperature)
+		}
+		if len(role.Models) > 0 {
+			fmt.Printf("  Models: %s\n", strings.Join(role.Models, ", "))
+			if model := role.Model(modelAvailable); model != "" {
+				fmt.Printf("  Spawns with: %s\n", model)
+			}
+		}
+		if role.SystemPrompt != "" {
+			fmt.Printf("\n  %s\n", role.SystemPrompt)
+		}
+		fmt.Println()
+	},
+}
+
+// modelAvailable reports whether the configured provider serves a model
+func modelAvailable(model string) bool {
+	switch baseProvider().(type) {
+	case *llm.Router, *llm.MockProvider:
+		return true
+	case *llm.ClaudeProvider:
+		return strings.HasPrefix(model, "claude-")
+	case *llm.OpenAIProvider:
+		return !strings.HasPrefix(model, "claude-")
+	}
+	return false
+}
+
+func init() {
+	roleCmd.AddCommand(roleListCmd)
+	roleCmd.AddCommand(roleShowCmd)
+	rootCmd.AddCommand(roleCmd)
+}
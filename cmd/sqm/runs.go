@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/runlog"
+)
+
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "Browse and replay recorded task and swarm runs",
+	Long: `Every 'sqm swarm' run, and every task submitted with 'sqm task submit',
+is recorded in ~/.squaremind/runs: each agent's prompt and response, the
+bids and how each task was assigned, and the timings.
+
+Run IDs may be abbreviated to any unique prefix.
+
+Examples:
+  sqm runs list
+  sqm runs show 3f2c9a1e
+  sqm runs replay 3f2c9a1e --model gpt-4o`,
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recorded runs, most recent first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		runs, err := openRunStore().List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(runs) == 0 {
+			fmt.Println("  No runs recorded.")
+			return
+		}
+		if limit > 0 && len(runs) > limit {
+			runs = runs[:limit]
+		}
+
+		fmt.Printf("\n  %-8s  %-8s  %-9s  %5s  %7s  %8s  %-16s  %s\n", "ID", "KIND", "STATUS", "TASKS", "TOKENS", "DURATION", "STARTED", "TASK")
+		for _, run := range runs {
+			fmt.Printf("  %-8s  %-8s  %-9s  %5d  %7d  %8s  %-16s  %s\n",
+				shortActor(run.ID), run.Kind, run.Status, len(run.Executions), run.TokensUsed(),
+				run.Duration().Round(time.Second), run.StartedAt.Local().Format("2006-01-02 15:04"), truncateLine(run.Task, 50))
+		}
+		fmt.Println()
+	},
+}
+
+var runsShowCmd = &cobra.Command{
+	Use:   "show [id]",
+	Short: "Show a run's prompts, responses, bids and timings",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		asJSON, _ := cmd.Flags().GetBool("json")
+		full, _ := cmd.Flags().GetBool("full")
+
+		run, err := openRunStore().Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if asJSON {
+			data, _ := json.MarshalIndent(run, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Printf("\n  Run %s\n", run.ID)
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		fmt.Printf("  Kind:      %s\n", run.Kind)
+		if run.ReplayOf != "" {
+			fmt.Printf("  Replay of: %s on %s\n", run.ReplayOf, run.Model)
+		}
+		fmt.Printf("  Task:      %s\n", run.Task)
+		fmt.Printf("  Status:    %s\n", run.Status)
+		if run.Error != "" {
+			fmt.Printf("  Error:     %s\n", run.Error)
+		}
+		fmt.Printf("  Started:   %s\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Duration:  %s\n", run.Duration().Round(time.Millisecond))
+		fmt.Printf("  Models:    %s\n", strings.Join(run.Models(), ", "))
+		fmt.Printf("  Tokens:    %d\n", run.TokensUsed())
+
+		for i, e := range run.Executions {
+			agentName := e.AgentName
+			if agentName == "" {
+				agentName = shortActor(e.AgentSID)
+			}
+			fmt.Printf("\n  [%d] Task %s  %s on %s  %s, %d tokens, %s\n", i+1, shortActor(e.TaskID), agentName, e.Model,
+				e.Status, e.TokensUsed, e.Duration.Round(time.Millisecond))
+			if a := e.Assignment; a != nil {
+				fmt.Printf("      Assigned by %s", a.Mode)
+				if a.Auction != "" {
+					fmt.Printf(" (%s auction)", a.Auction)
+				}
+				fmt.Printf(": %d bids", len(a.Bids))
+				if a.Pinned {
+					fmt.Print(", pinned")
+				}
+				fmt.Println()
+				for _, bid := range a.Bids {
+					fmt.Printf("        %-12s %-10s %.2f\n", truncateLine(bid.Agent, 12), bid.Outcome, bid.Score)
+				}
+			}
+			if e.Error != "" {
+				fmt.Printf("      Error: %s\n", e.Error)
+			}
+			fmt.Printf("      Prompt:\n%s\n", indentText(e.Prompt, full))
+			fmt.Printf("      Response:\n%s\n", indentText(e.Output, full))
+		}
+		if run.Output != "" {
+			fmt.Printf("\n  Output:\n%s\n", indentText(run.Output, true))
+		}
+		fmt.Println()
+	},
+}
+
+var runsReplayCmd = &cobra.Command{
+	Use:   "replay [id]",
+	Short: "Send a run's prompts to another model and compare",
+	Long: `Send the prompt of each of a run's executions to another model, as
+recorded, and compare the responses with the original ones side by side:
+status, tokens, time and how much of the wording the two share. Every
+execution gets the same input as the original, so differences come from
+the model alone. The replay is recorded as a run of its own.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		model, _ := cmd.Flags().GetString("model")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		if model == "" {
+			fmt.Fprintln(os.Stderr, "Error: --model is required")
+			os.Exit(1)
+		}
+		if provider == nil {
+			fmt.Fprintln(os.Stderr, "Error: no API key configured")
+			os.Exit(1)
+		}
+		store := openRunStore()
+		base, err := store.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		replay, err := runlog.Replay(ctx, provider, base, model)
+		if replay == nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if err := store.Save(replay); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: replay not recorded: %v\n", err)
+		}
+
+		cmp := runlog.Compare(base, replay)
+		if asJSON {
+			data, _ := json.MarshalIndent(cmp, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Printf("\n  Replay %s of run %s\n", replay.ID, base.ID)
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		fmt.Printf("  %-8s  %-12s  %-21s  %-15s  %-19s  %s\n", "TASK", "AGENT", "STATUS", "TOKENS", "TIME", "SIMILARITY")
+		for _, row := range cmp.Rows {
+			fmt.Printf("  %-8s  %-12s  %-21s  %-15s  %-19s  %.2f\n",
+				shortActor(row.TaskID), truncateLine(row.AgentName, 12),
+				fmt.Sprintf("%s/%s", row.BaseStatus, row.ReplayStatus),
+				fmt.Sprintf("%d/%d", row.BaseTokens, row.ReplayTokens),
+				fmt.Sprintf("%s/%s", row.BaseDuration.Round(time.Millisecond), row.ReplayDuration.Round(time.Millisecond)),
+				row.Similarity)
+		}
+		fmt.Printf("\n  Tokens: %d on %s, %d on %s\n", base.TokensUsed(), strings.Join(base.Models(), ", "), replay.TokensUsed(), model)
+		fmt.Printf("  Show the responses with: sqm runs show %s\n\n", shortActor(replay.ID))
+	},
+}
+
+// openRunStore opens the recorded runs
+func openRunStore() *runlog.Store {
+	return runlog.NewStore(config.DefaultRunsDir())
+}
+
+// truncateLine shortens text to n characters on one line
+func truncateLine(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return text
+}
+
+// indentText indents a prompt or response for display, cutting it to its
+// first lines unless full
+func indentText(text string, full bool) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if !full && len(lines) > 8 {
+		lines = append(lines[:8], fmt.Sprintf("... (%d more lines, --full to show)", len(lines)-8))
+	}
+	return "        " + strings.Join(lines, "\n        ")
+}
+
+func init() {
+	runsListCmd.Flags().Int("limit", 20, "Show at most this many runs (0 = all)")
+	runsShowCmd.Flags().Bool("json", false, "Print the run as JSON")
+	runsShowCmd.Flags().Bool("full", false, "Show prompts and responses in full")
+	runsReplayCmd.Flags().String("model", "", "Model to send the prompts to")
+	runsReplayCmd.Flags().Bool("json", false, "Print the comparison as JSON")
+	runsCmd.AddCommand(runsListCmd, runsShowCmd, runsReplayCmd)
+	rootCmd.AddCommand(runsCmd)
+}
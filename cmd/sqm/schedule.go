@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var taskScheduleCmd = &cobra.Command{
+	Use:   "schedule [description]",
+	Short: "Schedule a deferred or recurring task",
+	Long: `Schedule a task to be submitted later or on a recurring basis. Schedules are
+saved to disk and fired by 'sqm run' or 'sqm serve'.
+
+Exactly one of --at, --in, --every or --cron is required.
+
+Examples:
+  sqm task schedule "Summarize open issues" --in 30m
+  sqm task schedule "Rotate logs" --every 6h
+  sqm task schedule "Write weekly report" --cron "0 9 * * 1"`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		at, _ := cmd.Flags().GetString("at")
+		in, _ := cmd.Flags().GetDuration("in")
+		every, _ := cmd.Flags().GetDuration("every")
+		cronExpr, _ := cmd.Flags().GetString("cron")
+		complexity, _ := cmd.Flags().GetString("complexity")
+		capsStr, _ := cmd.Flags().GetStringSlice("requires")
+		reward, _ := cmd.Flags().GetFloat64("reward")
+		team, _ := cmd.Flags().GetString("team")
+		priorityStr, _ := cmd.Flags().GetString("priority")
+
+		set := 0
+		for _, given := range []bool{at != "", in > 0, every > 0, cronExpr != ""} {
+			if given {
+				set++
+			}
+		}
+		if set != 1 {
+			fmt.Fprintln(os.Stderr, "Specify exactly one of --at, --in, --every or --cron.")
+			os.Exit(1)
+		}
+
+		priority, perr := parsePriority(priorityStr)
+		if perr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", perr)
+			os.Exit(1)
+		}
+
+		caps := make([]identity.CapabilityType, len(capsStr))
+		for i, c := range capsStr {
+			caps[i] = identity.CapabilityType(c)
+		}
+
+		task := agent.NewTask(args[0], caps)
+		task.Complexity = complexity
+		task.Reward = reward
+		task.Deadline = task.CreatedAt.Add(time.Hour)
+		task.Team = team
+		task.Priority = priority
+
+		scheduler := openScheduler()
+
+		var (
+			sched *collective.Schedule
+			err   error
+		)
+		switch {
+		case at != "":
+			var when time.Time
+			when, err = time.Parse(time.RFC3339, at)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --at must be RFC 3339 (e.g. 2025-01-02T15:04:05Z): %v\n", err)
+				os.Exit(1)
+			}
+			sched, err = scheduler.RunAt(task, when)
+		case in > 0:
+			sched, err = scheduler.RunAt(task, time.Now().Add(in))
+		case every > 0:
+			sched, err = scheduler.Every(task, every)
+		default:
+			sched, err = scheduler.Cron(task, cronExpr)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n  Scheduled task: %s\n", args[0])
+		fmt.Printf("  Schedule ID: %s\n", sched.ID)
+		fmt.Printf("  Kind: %s\n", describeSchedule(*sched))
+		fmt.Printf("  Next run: %s\n\n", sched.NextRun.Local().Format(time.RFC1123))
+	},
+}
+
+var taskSchedulesCmd = &cobra.Command{
+	Use:   "schedules",
+	Short: "List scheduled tasks",
+	Run: func(cmd *cobra.Command, args []string) {
+		schedules := openScheduler().List()
+		if len(schedules) == 0 {
+			fmt.Println("No scheduled tasks.")
+			return
+		}
+
+		fmt.Printf("\n  Scheduled tasks (%d)\n", len(schedules))
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		for _, s := range schedules {
+			fmt.Printf("  %s  %-20s  next %s  runs %d\n",
+				s.ID[:8], describeSchedule(s), s.NextRun.Local().Format("2006-01-02 15:04"), s.Runs)
+			fmt.Printf("            %s\n", s.Task.Description)
+		}
+		fmt.Println()
+	},
+}
+
+var taskUnscheduleCmd = &cobra.Command{
+	Use:   "unschedule [schedule-id]",
+	Short: "Cancel a scheduled task",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scheduler := openScheduler()
+
+		// Accept the short ID shown by 'sqm task schedules'
+		id := args[0]
+		for _, s := range scheduler.List() {
+			if len(id) >= 8 && len(s.ID) >= len(id) && s.ID[:len(id)] == id {
+				id = s.ID
+				break
+			}
+		}
+
+		if err := scheduler.Cancel(id); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Cancelled schedule %s\n", id)
+	},
+}
+
+// openScheduler loads the persisted schedules for the active collective
+func openScheduler() *collective.Scheduler {
+	scheduler, err := collective.NewScheduler(activeCollective, config.DefaultSchedulePath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading schedules: %v\n", err)
+		os.Exit(1)
+	}
+	return scheduler
+}
+
+// describeSchedule formats how a schedule recurs
+func describeSchedule(s collective.Schedule) string {
+	switch s.Kind {
+	case collective.ScheduleEvery:
+		return "every " + s.Interval.String()
+	case collective.ScheduleCron:
+		return "cron " + s.Cron
+	default:
+		return "once"
+	}
+}
+
+func init() {
+	taskScheduleCmd.Flags().String("at", "", "Run once at an RFC 3339 time")
+	taskScheduleCmd.Flags().Duration("in", 0, "Run once after a delay (e.g. 30m)")
+	taskScheduleCmd.Flags().Duration("every", 0, "Run repeatedly at an interval (e.g. 6h)")
+	taskScheduleCmd.Flags().String("cron", "", "Run on a cron expression (e.g. \"0 9 * * 1\")")
+	taskScheduleCmd.Flags().StringP("complexity", "x", "medium", "Task complexity (low/medium/high)")
+	taskScheduleCmd.Flags().StringSliceP("requires", "r", []string{}, "Required capabilities")
+	taskScheduleCmd.Flags().Float64P("reward", "w", 10, "Reputation reward")
+	taskScheduleCmd.Flags().String("team", "", "Route the task to a named team")
+	taskScheduleCmd.Flags().String("priority", "normal", "Task priority (low/normal/high or a number)")
+
+	taskCmd.AddCommand(taskScheduleCmd)
+	taskCmd.AddCommand(taskSchedulesCmd)
+	taskCmd.AddCommand(taskUnscheduleCmd)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/selftest"
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Verify the installation end to end",
+	Long: `Run a canonical suite of checks, each against an ephemeral collective whose
+agents answer from a simulated provider, so no API key is needed and no
+tokens are spent:
+
+  market       a task auction assigns work to the only capable agent
+  consensus    a reputation-weighted vote applies one parameter change and
+               rejects another
+  workflow     a workflow's review loop revises until the reviewer approves
+  gossip       a broadcast over the local transport reaches every agent
+  persistence  results, memory and reputation survive a restart on
+               filesystem storage
+
+Exits non-zero if any check fails, so it can gate a deploy after install or
+upgrade.
+
+Example:
+  sqm selftest
+  sqm selftest --check market,persistence --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checks, _ := cmd.Flags().GetStringSlice("check")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		report, err := selftest.Run(ctx, selftest.Config{Checks: checks, Timeout: timeout, Seed: seed})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(report)
+		} else {
+			descriptions := make(map[string]string)
+			for _, check := range selftest.Checks() {
+				descriptions[check.Name] = check.Description
+			}
+			fmt.Printf("\n  Self-test (%s)\n", version)
+			fmt.Println("  ─────────────────────────────────────────────────────────────")
+			for _, r := range report.Results {
+				mark := "✓"
+				if !r.Passed {
+					mark = "✗"
+				}
+				fmt.Printf("  %s %-12s %6s  %s\n", mark, r.Name, r.Duration.Round(time.Millisecond), descriptions[r.Name])
+				if !r.Passed {
+					fmt.Printf("      %s\n", r.Error)
+				}
+			}
+			fmt.Printf("\n  %d passed, %d failed in %s\n\n", report.Passed, report.Failed, report.Duration.Round(time.Millisecond))
+		}
+
+		if !report.OK() {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	selftestCmd.Flags().StringSliceP("check", "c", nil, "Run only these checks (default all)")
+	selftestCmd.Flags().Duration("timeout", 30*time.Second, "Limit on each check")
+	selftestCmd.Flags().Int64("seed", 1, "Seed of the simulated checks")
+	selftestCmd.Flags().Bool("json", false, "Print the report as JSON")
+	rootCmd.AddCommand(selftestCmd)
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/incident"
+	"github.com/square-mind/squaremind/pkg/integrations/slack"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the collective in server mode",
+	Long: `Start the collective and expose it over HTTP.
+
+Endpoints:
+  /healthz     Liveness check
+  /api/stats   Collective statistics
+  /api/mode    Run mode (GET), or pause, maintenance, drain and resume (PUT,
+               bearer token); see 'sqm pause --help'
+  /api/parameters  Consensus threshold, agent limit, bid timeout and minimum
+                   bid score (GET), or a change for the agents to vote on
+                   (POST, bearer token)
+  /api/agents  Agent snapshots (GET) or a new agent (POST, bearer token);
+               POST /api/agents/<sid>/pause or /resume and DELETE
+               /api/agents/<sid> manage one (bearer token)
+  /api/tasks   Task snapshot (GET) or task submission (POST, bearer token
+               from api_tokens in the config file)
+  /events      WebSocket stream of collective activity (JSON events)
+  /api/hooks/<name>  Webhook for event-triggered workflows (bearer token)
+  /api/approvals     Human steps of triggered workflows
+  /api/usage?by=<tag>  Token spend by agent and chargeback by cost tag
+  /api/digest?period=daily|weekly  Summaries of recently finished tasks
+  /metrics     Prometheus metrics, including cost attribution
+  /api/incident      Redacted incident bundle (bearer token); see
+                     'sqm incident capture --help'
+  /api/resources/<kind>/<id>
+               Agents, teams, routes and budgets managed declaratively:
+               PUT to create or update, DELETE to remove (bearer token),
+               with If-Match on the ETag for safe concurrent changes
+  /api/snapshot      Collective snapshot (GET) or import (POST, bearer token)
+  /api/replication   State for standbys (bearer token)
+  /api/standby       Standby status; POST /api/standby/promote to promote
+                     (bearer token); see 'sqm standby --help'
+  /slack/events      Slack Events API, when the slack section of the config
+                     file is set (signed by Slack)
+
+Incident bundles are captured into ~/.squaremind/incidents when tasks keep
+failing or the health score collapses, as set by the incidents section of
+the config file.
+
+Digests of finished tasks are posted to slack_webhook as set by the digests
+section of the config file; see 'sqm report digest --help'.
+
+Workflows are started by the rules in the triggers file; see
+'sqm workflow triggers --help'.
+
+With a slack section in the config file, members of the configured channels
+submit tasks by mentioning the bot (or messaging it directly). Progress and
+the result are posted in a thread, and each Slack user submits as the
+submitter mapped in slack.users, or slack:<user ID>.
+
+With --standby-of, the collective is a warm standby of the server at that
+URL: it replicates the primary's agents, reputation, memory and unfinished
+tasks and stays in maintenance mode until promoted, automatically with
+--auto-promote once the primary stops answering.
+
+State in ~/.squaremind is migrated to this release's formats on start; see
+'sqm migrate --help'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Bring persisted state up to this release before anything reads it
+		result, err := migrateState()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error migrating state: %v\n", err)
+			os.Exit(1)
+		}
+		if len(result.Applied) > 0 {
+			printMigrations(result)
+		}
+
+		if activeCollective == nil {
+			fmt.Fprintln(os.Stderr, "No collective initialized. Run 'sqm init <name>' first.")
+			os.Exit(1)
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		var standby *collective.Standby
+		if standbyOf != "" {
+			standby = collective.NewStandby(activeCollective, server.NewReplicationClient(standbyOf, primaryToken), collective.StandbyConfig{
+				Interval:    syncInterval,
+				AutoPromote: autoPromote,
+				NewAgent:    newImportedAgent,
+			})
+		}
+
+		if err := activeCollective.Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting collective: %v\n", err)
+			os.Exit(1)
+		}
+		defer activeCollective.Stop()
+
+		if err := openScheduler().Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: scheduler not started: %v\n", err)
+		}
+
+		if candidate != nil {
+			candidate.OnChange(func(promoted bool, reason string) {
+				fmt.Printf("\n  Candidate model promoted: %v (%s)\n", promoted, reason)
+			})
+			go candidate.Run(ctx)
+		}
+
+		srv := newServer()
+		if standby != nil {
+			srv.SetStandby(standby)
+			go standby.Run(ctx)
+			fmt.Printf("\n  Standby of %s (auto-promote: %v)\n", standbyOf, autoPromote)
+		}
+		go newCapturer().Watch(ctx, incidentTriggers(), func(path string, b *incident.Bundle) {
+			fmt.Printf("\n  Incident captured (%s): %s\n", b.Manifest.Reason, path)
+		})
+		triggers, engine, err := startTriggers(ctx, triggersPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: triggers not started: %v\n", err)
+		}
+		if triggers != nil {
+			srv.SetTriggers(triggers)
+			srv.SetInbox(engine.Inbox())
+			fmt.Printf("\n  %d workflow triggers active\n", len(triggers.Rules()))
+		}
+
+		if cfg.Slack != nil {
+			bot := slack.New(*cfg.Slack, activeCollective)
+			if err := bot.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: slack bot not started: %v\n", err)
+			} else {
+				srv.Handle("/slack/events", bot)
+				defer bot.Close()
+				fmt.Println("\n  Slack bot listening at /slack/events")
+			}
+		}
+
+		fmt.Printf("\n  Serving collective %s on %s\n", activeCollective.Name, serveAddr)
+		fmt.Println("  Press Ctrl+C to stop")
+
+		if err := srv.ListenAndServe(ctx, serveAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("\n  Shutting down...")
+	},
+}
+
+var (
+	serveAddr    string
+	triggersPath string
+
+	// Warm standby of another server
+	standbyOf    string
+	primaryToken string
+	autoPromote  bool
+	syncInterval time.Duration
+)
+
+// newServer creates a server for the active collective with the configured API tokens
+func newServer() *server.Server {
+	srv := server.New(activeCollective)
+	srv.SetVersion(version)
+	if activeCollective != nil {
+		srv.SetIncidentCapturer(newCapturer())
+	}
+	for _, t := range cfg.APITokens {
+		srv.AddToken(server.APIToken{Token: t.Token, Submitter: t.Submitter, Weight: t.Weight})
+	}
+	if provider != nil {
+		srv.SetAgentFactory(func(name string, spec server.AgentSpec) (*agent.Agent, error) {
+			model := spec.Model
+			if model == "" {
+				model = cfg.DefaultModel
+			}
+			if model == "" {
+				model = string(llm.DefaultModel)
+			}
+			return agent.NewAgent(agent.AgentConfig{
+				Name:         name,
+				Capabilities: spec.Capabilities,
+				Provider:     provider,
+				Model:        model,
+				Labels:       spec.Labels,
+				CostTags:     spec.CostTags,
+				SystemPrompt: spec.SystemPrompt,
+				Temperature:  spec.Temperature,
+				MaxTokens:    spec.MaxTokens,
+				Contracts:    contracts,
+				Queue:        cfg.Queues,
+				Slots:        cfg.AgentSlots,
+				Tools:        agentTools(),
+				Integrations: integrations,
+			})
+		})
+	}
+	return srv
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&triggersPath, "triggers", config.DefaultTriggersPath(), "Triggers file for event-triggered workflows")
+	serveCmd.Flags().StringVar(&standbyOf, "standby-of", "", "Run as a warm standby of the server at this URL")
+	serveCmd.Flags().StringVar(&primaryToken, "primary-token", os.Getenv("SQM_PRIMARY_TOKEN"), "API token for the primary (default $SQM_PRIMARY_TOKEN)")
+	serveCmd.Flags().BoolVar(&autoPromote, "auto-promote", false, "Propose promotion as soon as the primary stops answering")
+	serveCmd.Flags().DurationVar(&syncInterval, "sync-interval", 5*time.Second, "How often a standby syncs with the primary")
+	rootCmd.AddCommand(serveCmd)
+}
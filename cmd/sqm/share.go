@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var taskShareCmd = &cobra.Command{
+	Use:   "share [task-id]",
+	Short: "Print a time-limited URL to a finished task's output",
+	Long: `Print a signed URL that reads a finished task's stored output without
+credentials until it expires, for sharing large artifacts with reviewers
+outside the collective.
+
+The collective must keep its artifacts in S3 or Cloud Storage (the s3 or
+gcs section of storage in the config file). The URL is issued by the
+collective in this process if one is active, otherwise by a running
+'sqm serve' at --server, which requires an API token.
+
+Example:
+  sqm task share 3f2c... --expires 48h`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		taskID := args[0]
+		expires, _ := cmd.Flags().GetDuration("expires")
+
+		var (
+			link string
+			err  error
+		)
+		if activeCollective != nil {
+			link, err = activeCollective.ArtifactURL(context.Background(), taskID, expires)
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			token, _ := cmd.Flags().GetString("token")
+			var resp struct {
+				URL string `json:"url"`
+			}
+			path := "/api/tasks/" + url.PathEscape(taskID) + "/artifact-url?expires=" + url.QueryEscape(expires.String())
+			err = apiRequest(http.MethodGet, server, path, token, nil, &resp)
+			link = resp.URL
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n  Output of task %s, valid until %s:\n\n  %s\n\n", taskID, time.Now().Add(expires).Format(time.RFC1123), link)
+	},
+}
+
+func init() {
+	taskShareCmd.Flags().Duration("expires", 24*time.Hour, "How long the URL stays valid (at most 168h)")
+	taskShareCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to ask when no collective is active")
+	taskShareCmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+	taskCmd.AddCommand(taskShareCmd)
+}
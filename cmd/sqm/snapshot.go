@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a collective snapshot written by 'sqm export'",
+	Long: `Verify a signed snapshot and add its agents, capabilities, reputations,
+memory and pending tasks to the collective in this process if one is active,
+otherwise to a running 'sqm serve' at --server, which requires an API token.
+
+Agents keep their SIDs, lineage, proficiencies and reputations but get fresh
+key pairs, since private keys are never exported; agents already in the
+collective are skipped. With --trust, only snapshots signed by one of the
+given keys are accepted.
+
+Example:
+  sqm import collective.json --trust 3b6a27bc...`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		trustHex, _ := cmd.Flags().GetStringSlice("trust")
+		skipPending, _ := cmd.Flags().GetBool("skip-pending")
+
+		trusted, err := parseTrustedKeys(trustHex)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		snapshot, signer, err := collective.OpenSnapshot(data, trusted...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if skipPending {
+			snapshot.Pending = nil
+		}
+
+		var result collective.ImportResult
+		if activeCollective != nil {
+			r, err := activeCollective.Import(snapshot, collective.ImportOptions{NewAgent: newImportedAgent})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			result = *r
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			token, _ := cmd.Flags().GetString("token")
+			if err := apiRequest(http.MethodPost, server, "/api/snapshot", token, snapshot, &result); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		fmt.Printf("\n  Imported snapshot of '%s' taken %s\n", snapshot.Collective, snapshot.ExportedAt.Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Signed by: %s\n\n", hex.EncodeToString(signer))
+		fmt.Printf("  Agents:   %d (%d already present)\n", result.Agents, len(result.Skipped))
+		fmt.Printf("  Episodes: %d\n", result.Episodes)
+		fmt.Printf("  Pending:  %d\n\n", result.Pending)
+	},
+}
+
+// exportSnapshot writes a signed snapshot of the active collective, or of
+// the server's, to path
+func exportSnapshot(cmd *cobra.Command, path string) {
+	keyPath, _ := cmd.Flags().GetString("key")
+	key, err := loadKey(keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var snapshot *collective.Snapshot
+	if activeCollective != nil {
+		snapshot = activeCollective.Export()
+	} else {
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		snapshot = &collective.Snapshot{}
+		if err := apiRequest(http.MethodGet, server, "/api/snapshot", token, nil, snapshot); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	data, err := snapshot.Sign(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n  Exported '%s' to %s\n", snapshot.Collective, path)
+	fmt.Printf("  Agents: %d, episodes: %d, pending tasks: %d\n", len(snapshot.Agents), len(snapshot.Memory.Episodes), len(snapshot.Pending))
+	fmt.Printf("  Signed by: %s\n\n", hex.EncodeToString(key.Public().(ed25519.PublicKey)))
+}
+
+// loadKey reads a hex-encoded signing key, such as the one snapshots are
+// signed with, creating it if it doesn't exist
+func loadKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key.Seed())+"\n"), 0600); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid key %s", path)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// parseTrustedKeys decodes the hex public keys given with --trust
+func parseTrustedKeys(hexKeys []string) ([]ed25519.PublicKey, error) {
+	var trusted []ed25519.PublicKey
+	for _, h := range hexKeys {
+		key, err := hex.DecodeString(h)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid --trust key %q", h)
+		}
+		trusted = append(trusted, key)
+	}
+	return trusted, nil
+}
+
+// newImportedAgent creates an imported agent with this host's provider
+func newImportedAgent(cfg agent.AgentConfig) (*agent.Agent, error) {
+	cfg.Provider = provider
+	cfg.Keyring = keyring
+	cfg.Contracts = contracts
+	cfg.Tools = agentTools()
+	cfg.Integrations = integrations
+	cfg.Recorder = agentRecorder()
+	if cfg.Model == "" {
+		cfg.Model = string(llm.DefaultModel)
+	}
+	return agent.NewAgent(cfg)
+}
+
+func init() {
+	importCmd.Flags().StringSlice("trust", nil, "Hex public keys whose snapshots are accepted (default any valid signature)")
+	importCmd.Flags().Bool("skip-pending", false, "Leave out the snapshot's pending tasks")
+	importCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to import into when no collective is active")
+	importCmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+	rootCmd.AddCommand(importCmd)
+}
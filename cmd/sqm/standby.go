@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+var standbyCmd = &cobra.Command{
+	Use:   "standby",
+	Short: "Inspect and promote a warm standby",
+	Long: `A warm standby is an 'sqm serve --standby-of <primary URL>' that
+replicates a primary's agents, reputation, memory and unfinished tasks and
+stays in maintenance mode until it is promoted.
+
+Promotion is put to the standby's agents, weighted by reputation, and is
+refused while the primary still answers unless forced. The promoted standby
+resubmits the primary's unfinished tasks and fences the old primary into
+maintenance mode whenever it answers again, so the two never dispatch side
+by side.`,
+}
+
+var standbyStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show a standby's replication state",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+		var status collective.StandbyStatus
+		if err := apiRequest(http.MethodGet, server, "/api/standby", "", nil, &status); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n  Primary:   %s (epoch %d)\n", status.Primary, status.Epoch)
+		if !status.LastSync.IsZero() {
+			fmt.Printf("  Last sync: %s ago (%d syncs)\n", time.Since(status.LastSync).Round(time.Second), status.Syncs)
+		}
+		fmt.Printf("  Agents:    %d\n", status.Agents)
+		fmt.Printf("  Pending:   %d tasks to resubmit on promotion\n", status.Pending)
+		switch {
+		case status.Promoted:
+			fmt.Printf("  State:     promoted %s (old primary fenced: %v)\n", status.PromotedAt.Format(time.RFC3339), status.Fenced)
+		case status.PrimaryDown:
+			fmt.Printf("  State:     primary down after %d failed syncs: %s\n", status.Failures, status.LastError)
+		case status.Failures > 0:
+			fmt.Printf("  State:     %d failed syncs: %s\n", status.Failures, status.LastError)
+		default:
+			fmt.Println("  State:     replicating")
+		}
+		fmt.Println()
+	},
+}
+
+var standbyPromoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Promote a standby to primary",
+	Long: `Propose promoting the standby at --server. Its agents vote; by default
+they approve once the primary has missed enough syncs, or when --force is
+given, which also skips checking that the primary is unreachable. Requires
+an API token.
+
+Example:
+  sqm standby promote --server http://standby:8080 --reason "primary host lost"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		reason, _ := cmd.Flags().GetString("reason")
+		force, _ := cmd.Flags().GetBool("force")
+
+		var promotion collective.Promotion
+		body := map[string]interface{}{"reason": reason, "force": force}
+		if err := apiRequest(http.MethodPost, server, "/api/standby/promote", token, body, &promotion); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n  Promoted to epoch %d (%d for, %d against)\n", promotion.Epoch, promotion.For, promotion.Against)
+		fmt.Printf("  Resubmitted %d unfinished tasks\n", promotion.Resubmitted)
+		if promotion.Fenced {
+			fmt.Println("  Old primary fenced")
+		} else {
+			fmt.Println("  Old primary unreachable; it is fenced when it answers again")
+		}
+		fmt.Println()
+	},
+}
+
+func init() {
+	for _, c := range []*cobra.Command{standbyStatusCmd, standbyPromoteCmd} {
+		c.Flags().String("server", "http://127.0.0.1:8420", "Standby server")
+		standbyCmd.AddCommand(c)
+	}
+	standbyPromoteCmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+	standbyPromoteCmd.Flags().String("reason", "", "Why the standby is promoted, recorded in its logs and events")
+	standbyPromoteCmd.Flags().Bool("force", false, "Promote even if the primary still answers")
+	rootCmd.AddCommand(standbyCmd)
+}
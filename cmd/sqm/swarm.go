@@ -4,8 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -13,8 +14,11 @@ import (
 	"github.com/square-mind/squaremind/pkg/agent"
 	"github.com/square-mind/squaremind/pkg/cli"
 	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
 	"github.com/square-mind/squaremind/pkg/identity"
 	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/roles"
+	"github.com/square-mind/squaremind/pkg/runlog"
 )
 
 var swarmCmd = &cobra.Command{
@@ -23,26 +27,130 @@ var swarmCmd = &cobra.Command{
 	Long: `Execute a complex task using Squaremind's swarm intelligence.
 
 This spawns multiple specialized agents that work together:
+  - Architect: Orchestrates - decomposes the task and synthesizes the result
   - Researcher: Analyzes the problem space
-  - Architect: Designs the solution approach
   - Implementer: Creates the implementation
   - Critic: Reviews and identifies issues
-  - Synthesizer: Combines outputs into final result
-
-The agents coordinate through fair markets and gossip protocols,
-demonstrating emergent collective intelligence.
+  - Writer: Documents and explains
+
+The orchestrator breaks the task into subtasks, which run as a workflow:
+the agents bid for them in the market, independent subtasks run in
+parallel, and each sees the results it builds on. The same pipeline is
+available to programs as Collective.ExecuteSwarm.
+
+With --plan-file, the swarm follows a pipeline defined in YAML instead: its
+roles are spawned, each as a team, and its phases run in dependency order
+on their roles' teams, within the pipeline's token budget:
+
+  name: code-review
+  token_budget: 60000
+  roles:
+    - {name: coder, capabilities: [code.write], count: 2}
+    - {name: reviewer, capabilities: [code.review, security]}
+  phases:
+    - {id: implement, role: coder, prompt: "Implement: {{.Params.task}}"}
+    - id: review
+      role: reviewer
+      prompt: Review the implementation for defects and security issues
+      depends_on: [implement]
+      max_tokens: 2000
+
+Every run is recorded in ~/.squaremind/runs with each agent's prompt and
+response, the bids and the timings; see 'sqm runs --help'.
+
+Each run's progress is checkpointed to ~/.squaremind/swarms under its run
+ID as each phase finishes: the plan, every completed subtask or pipeline
+phase, and the final output. If the swarm fails, times out, is interrupted
+or crashes, --resume continues it with the same task and plan, skipping
+the phases already done.
 
 Example:
   sqm swarm "Design a microservices architecture for an e-commerce platform"
-  sqm swarm "Write a comprehensive security audit checklist"`,
-	Args: cobra.ExactArgs(1),
-	Run:  runSwarm,
+  sqm swarm "Write a comprehensive security audit checklist"
+  sqm swarm -f examples/swarms/code-review.yaml "Add rate limiting to the API"
+  sqm swarm --resume 3f2c9a1e-...`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if swarmResume != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: runSwarm,
 }
 
-var swarmAgents int
+var (
+	swarmAgents   int
+	swarmTimeout  time.Duration
+	swarmPlanFile string
+	swarmSandbox  string
+	swarmResume   string
+)
+
+// swarmRoles are the specialists spawned for a swarm without a plan file;
+// the first is the orchestrator
+var swarmRoles = []collective.SwarmRole{
+	{
+		Name:         "Architect",
+		Capabilities: []identity.CapabilityType{identity.CapArchitecture, identity.CapAnalysis, identity.CapDocumentation},
+		Description:  "Orchestration & solution design",
+	},
+	{
+		Name:         "Researcher",
+		Capabilities: []identity.CapabilityType{identity.CapResearch, identity.CapAnalysis},
+		Description:  "Problem analysis & research",
+	},
+	{
+		Name:         "Implementer",
+		Capabilities: []identity.CapabilityType{identity.CapCodeWrite, identity.CapCodeRefactor, identity.CapTesting},
+		Description:  "Concrete implementation",
+	},
+	{
+		Name:         "Critic",
+		Capabilities: []identity.CapabilityType{identity.CapCodeReview, identity.CapSecurity, identity.CapTesting},
+		Description:  "Critical review & security",
+	},
+	{
+		Name:         "Writer",
+		Capabilities: []identity.CapabilityType{identity.CapDocumentation, identity.CapResearch},
+		Description:  "Documentation & explanation",
+	},
+}
 
 func runSwarm(cmd *cobra.Command, args []string) {
-	task := args[0]
+	checkpoints := collective.NewFileCheckpointStore(config.DefaultSwarmDir())
+
+	// A resumed run takes its task and pipeline from the checkpoint
+	var task *agent.Task
+	var pipeline *collective.SwarmPipeline
+	if swarmResume != "" {
+		if swarmPlanFile != "" {
+			fmt.Fprintln(os.Stderr, "Error: --resume uses the plan of the run being resumed; drop --plan-file")
+			os.Exit(1)
+		}
+		cp, err := checkpoints.LoadCheckpoint(swarmResume)
+		if err == nil && cp.Status == collective.SwarmCompleted {
+			err = fmt.Errorf("%w: %s", collective.ErrSwarmFinished, swarmResume)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		task, pipeline = cp.Task(), cp.Pipeline
+	} else {
+		task = agent.NewTask(args[0], nil).WithComplexity("high")
+	}
+	if swarmPlanFile != "" {
+		var err error
+		if pipeline, err = collective.LoadSwarmPipeline(swarmPlanFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	box, err := openSandbox(swarmSandbox)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	fmt.Println(cli.SmallBanner())
 
@@ -51,8 +159,25 @@ func runSwarm(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	fmt.Printf("  %sTask:%s %s\n", cli.Bold, cli.Reset, task)
-	fmt.Printf("  %sMode:%s Swarm Intelligence (%d agents)\n", cli.Dim, cli.Reset, swarmAgents)
+	// Without a plan file, the built-in roles limited to the requested count
+	spawnRoles := swarmRoles
+	if pipeline != nil {
+		spawnRoles = pipeline.Roles
+	} else if swarmAgents < len(spawnRoles) {
+		spawnRoles = spawnRoles[:swarmAgents]
+	}
+	size := 0
+	for _, role := range spawnRoles {
+		size += max(role.Count, 1)
+	}
+
+	fmt.Printf("  %sTask:%s %s\n", cli.Bold, cli.Reset, task.Description)
+	fmt.Printf("  %sRun:%s  %s\n", cli.Dim, cli.Reset, task.ID)
+	if pipeline != nil {
+		fmt.Printf("  %sMode:%s Pipeline %s (%d agents, %d phases)\n", cli.Dim, cli.Reset, pipeline.Name, size, len(pipeline.Phases))
+	} else {
+		fmt.Printf("  %sMode:%s Swarm Intelligence (%d agents)\n", cli.Dim, cli.Reset, size)
+	}
 	fmt.Println()
 	fmt.Println(cli.Divider(55))
 
@@ -61,247 +186,158 @@ func runSwarm(cmd *cobra.Command, args []string) {
 
 	c := collective.NewCollective("SwarmMind", collective.CollectiveConfig{
 		MinAgents:          2,
-		MaxAgents:          swarmAgents,
+		MaxAgents:          size,
 		ConsensusThreshold: 0.67,
+		Swarm:              collective.DefaultSwarmConfig(),
+		Keyring:            keyring,
+		Models:             cfg.Models,
 	})
-
-	// Define swarm roles
-	roles := []struct {
-		name   string
-		caps   []identity.CapabilityType
-		prompt string
-		desc   string
-	}{
-		{
-			name:   "Researcher",
-			caps:   []identity.CapabilityType{identity.CapResearch, identity.CapAnalysis},
-			prompt: "You are a thorough researcher. Analyze the problem, identify key considerations, constraints, and relevant prior art. Be comprehensive but concise.",
-			desc:   "Problem analysis & research",
-		},
-		{
-			name:   "Architect",
-			caps:   []identity.CapabilityType{identity.CapArchitecture, identity.CapAnalysis},
-			prompt: "You are a systems architect. Based on the analysis, design a high-level solution architecture. Focus on structure, patterns, and key decisions.",
-			desc:   "Solution design & architecture",
-		},
-		{
-			name:   "Implementer",
-			caps:   []identity.CapabilityType{identity.CapCodeWrite, identity.CapDocumentation},
-			prompt: "You are a skilled implementer. Take the architecture and create a concrete implementation plan with specific steps, code patterns, or detailed instructions.",
-			desc:   "Concrete implementation",
-		},
-		{
-			name:   "Critic",
-			caps:   []identity.CapabilityType{identity.CapCodeReview, identity.CapSecurity},
-			prompt: "You are a critical reviewer. Identify potential issues, edge cases, security concerns, and improvements. Be constructive but thorough.",
-			desc:   "Critical review & security",
-		},
-		{
-			name:   "Synthesizer",
-			caps:   []identity.CapabilityType{identity.CapDocumentation, identity.CapAnalysis},
-			prompt: "You are a synthesizer. Combine all the inputs from other agents into a coherent, well-structured final output. Resolve conflicts and create a unified response.",
-			desc:   "Synthesis & final output",
-		},
-	}
-
-	// Limit to requested agent count
-	if swarmAgents < len(roles) {
-		roles = roles[:swarmAgents]
+	if cfg.BidTimeout > 0 {
+		c.GetMarket().SetBidTimeout(cfg.BidTimeout)
 	}
+	c.SetCheckpointStore(checkpoints)
+	runs := runlog.NewRecorder(c, runlog.NewStore(config.DefaultRunsDir()))
 
 	agents := make([]*agent.Agent, 0)
-	agentPrompts := make(map[string]string)
-
-	for _, role := range roles {
-		spinner := cli.NewSpinner(fmt.Sprintf("Spawning %s...", role.name))
-		spinner.Start()
-		time.Sleep(200 * time.Millisecond)
-
-		a, err := agent.NewAgent(agent.AgentConfig{
-			Name:         role.name,
-			Capabilities: role.caps,
-			Provider:     provider,
-			Model:        string(llm.DefaultModel),
-		})
-		if err != nil {
-			spinner.Stop(false)
-			continue
-		}
-
-		_ = c.Join(a)
-		agents = append(agents, a)
-		agentPrompts[a.Identity.SID] = role.prompt
-		spinner.StopWithMessage(true, fmt.Sprintf("%s%s%s - %s", cli.BrightGreen, role.name, cli.Reset, role.desc))
-	}
-
-	// Start collective
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	startSpinner := cli.NewSpinner("Starting collective intelligence...")
-	startSpinner.Start()
-	_ = c.Start(ctx)
-	time.Sleep(300 * time.Millisecond)
-	startSpinner.Stop(true)
-
-	// Execute swarm coordination
-	fmt.Println(cli.Section("SWARM EXECUTION"))
-
-	results := make(map[string]string)
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	// Phase 1: Research (runs first)
-	fmt.Println()
-	fmt.Printf("  %s┌─ PHASE 1: RESEARCH ──────────────────────┐%s\n", cli.Cyan, cli.Reset)
-
-	if len(agents) > 0 {
-		researcher := agents[0]
-		spinner := cli.NewSpinner(fmt.Sprintf("%s analyzing problem...", researcher.Identity.Name))
-		spinner.Start()
-
-		resp, err := provider.Complete(ctx, llm.CompletionRequest{
-			System:    agentPrompts[researcher.Identity.SID],
-			Prompt:    fmt.Sprintf("Analyze this task and provide key insights:\n\n%s", task),
-			MaxTokens: 500,
-		})
+	for _, role := range spawnRoles {
+		count := max(role.Count, 1)
+		for i := 1; i <= count; i++ {
+			name := role.Name
+			if count > 1 {
+				name = fmt.Sprintf("%s-%d", role.Name, i)
+			}
+			model := role.Model
+			if model == "" {
+				model = cfg.DefaultModel
+			}
+			if model == "" {
+				model = string(llm.DefaultModel)
+			}
 
-		if err != nil {
-			spinner.Stop(false)
-			fmt.Printf("  %s│%s  Error: %v\n", cli.Cyan, cli.Reset, err)
-		} else {
-			spinner.Stop(true)
-			results["research"] = resp.Content
-			preview := resp.Content
-			if len(preview) > 100 {
-				preview = preview[:100] + "..."
+			spinner := cli.NewSpinner(fmt.Sprintf("Spawning %s...", name))
+			spinner.Start()
+
+			agentCfg := agent.AgentConfig{
+				Name:         name,
+				Capabilities: role.Capabilities,
+				Provider:     provider,
+				Model:        model,
+				Sandbox:      box,
+				Contracts:    contracts,
+				Queue:        cfg.Queues,
+				Slots:        cfg.AgentSlots,
+				Tools:        agentTools(),
+				Integrations: integrations,
+				Recorder:     runs,
+			}
+			// Roles matching a template work under its prompt
+			if template, err := roles.Default().Get(role.Name); err == nil {
+				template.Apply(&agentCfg)
 			}
-			fmt.Printf("  %s│%s  %s%s%s\n", cli.Cyan, cli.Reset, cli.Dim, preview, cli.Reset)
-		}
-	}
-	fmt.Printf("  %s└──────────────────────────────────────────┘%s\n", cli.Cyan, cli.Reset)
 
-	// Phase 2: Parallel execution (Architect + Implementer)
-	if len(agents) >= 3 {
-		fmt.Println()
-		fmt.Printf("  %s┌─ PHASE 2: PARALLEL DESIGN ───────────────┐%s\n", cli.Yellow, cli.Reset)
-
-		wg.Add(2)
-
-		// Architect
-		go func() {
-			defer wg.Done()
-			architect := agents[1]
-			resp, err := provider.Complete(ctx, llm.CompletionRequest{
-				System: agentPrompts[architect.Identity.SID],
-				Prompt: fmt.Sprintf("Based on this research:\n%s\n\nDesign a solution for:\n%s",
-					results["research"], task),
-				MaxTokens: 500,
-			})
+			a, err := agent.NewAgent(agentCfg)
 			if err == nil {
-				mu.Lock()
-				results["architecture"] = resp.Content
-				mu.Unlock()
-				fmt.Printf("  %s│%s  %s✓ Architect%s completed design\n", cli.Yellow, cli.Reset, cli.Green, cli.Reset)
+				if pipeline != nil {
+					err = c.JoinRole(role.Name, a)
+				} else {
+					err = c.Join(a)
+				}
 			}
-		}()
-
-		// Implementer
-		go func() {
-			defer wg.Done()
-			time.Sleep(500 * time.Millisecond) // Slight delay to use architecture
-			implementer := agents[2]
-			resp, err := provider.Complete(ctx, llm.CompletionRequest{
-				System: agentPrompts[implementer.Identity.SID],
-				Prompt: fmt.Sprintf("Create implementation details for:\n%s\n\nContext:\n%s",
-					task, results["research"]),
-				MaxTokens: 500,
-			})
-			if err == nil {
-				mu.Lock()
-				results["implementation"] = resp.Content
-				mu.Unlock()
-				fmt.Printf("  %s│%s  %s✓ Implementer%s completed plan\n", cli.Yellow, cli.Reset, cli.Green, cli.Reset)
+			if err != nil {
+				spinner.Stop(false)
+				continue
 			}
-		}()
 
-		spinner := cli.NewSpinner("Agents working in parallel...")
-		spinner.Start()
-		wg.Wait()
-		spinner.Stop(true)
-		fmt.Printf("  %s└──────────────────────────────────────────┘%s\n", cli.Yellow, cli.Reset)
+			agents = append(agents, a)
+			spinner.StopWithMessage(true, fmt.Sprintf("%s%s%s - %s", cli.BrightGreen, name, cli.Reset, role.Description))
+		}
+	}
+	if len(agents) == 0 {
+		fmt.Println(cli.Error("  No agents could be spawned"))
+		os.Exit(1)
+	}
+	if pipeline == nil {
+		_ = c.SetOrchestrator(agents[0].Identity.SID)
 	}
 
-	// Phase 3: Critical Review
-	if len(agents) >= 4 {
-		fmt.Println()
-		fmt.Printf("  %s┌─ PHASE 3: CRITICAL REVIEW ───────────────┐%s\n", cli.Red, cli.Reset)
-
-		critic := agents[3]
-		spinner := cli.NewSpinner(fmt.Sprintf("%s reviewing outputs...", critic.Identity.Name))
-		spinner.Start()
+	// Ctrl+C stops the run cleanly, leaving a checkpoint to resume
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, swarmTimeout)
+	defer cancel()
 
-		allOutputs := fmt.Sprintf("Research:\n%s\n\nArchitecture:\n%s\n\nImplementation:\n%s",
-			results["research"], results["architecture"], results["implementation"])
-
-		resp, err := provider.Complete(ctx, llm.CompletionRequest{
-			System:    agentPrompts[critic.Identity.SID],
-			Prompt:    fmt.Sprintf("Review these outputs for issues and improvements:\n\n%s", allOutputs),
-			MaxTokens: 400,
-		})
-
-		if err == nil {
-			results["critique"] = resp.Content
-			spinner.Stop(true)
-			fmt.Printf("  %s│%s  Issues identified and improvements suggested\n", cli.Red, cli.Reset)
-		} else {
-			spinner.Stop(false)
-		}
-		fmt.Printf("  %s└──────────────────────────────────────────┘%s\n", cli.Red, cli.Reset)
+	if err := c.Start(ctx); err != nil {
+		fmt.Println(cli.Error(fmt.Sprintf("  Could not start collective: %v", err)))
+		os.Exit(1)
 	}
+	defer c.Stop()
 
-	// Phase 4: Synthesis
+	// Execute swarm coordination
+	fmt.Println(cli.Section("SWARM EXECUTION"))
 	fmt.Println()
-	fmt.Printf("  %s┌─ PHASE 4: SYNTHESIS ──────────────────────┐%s\n", cli.Green, cli.Reset)
 
-	var synthesizer *agent.Agent
-	if len(agents) >= 5 {
-		synthesizer = agents[4]
-	} else if len(agents) > 0 {
-		synthesizer = agents[0] // Use researcher as fallback
+	kind := runlog.KindSwarm
+	if pipeline != nil {
+		kind = runlog.KindPipeline
 	}
+	recording := runs.Begin(task.ID, kind, task.Description) == nil
 
-	if synthesizer != nil {
-		spinner := cli.NewSpinner("Synthesizing final output...")
+	var result *collective.SwarmResult
+	switch {
+	case swarmResume != "":
+		spinner := cli.NewSpinner(fmt.Sprintf("Resuming run %s...", swarmResume))
 		spinner.Start()
+		result, err = c.ResumeSwarm(ctx, swarmResume)
+		spinner.Stop(err == nil)
+	case pipeline != nil:
+		spinner := cli.NewSpinner(fmt.Sprintf("Running pipeline %s...", pipeline.Name))
+		spinner.Start()
+		result, err = c.ExecutePipeline(ctx, pipeline, task)
+		spinner.Stop(err == nil)
+	default:
+		spinner := cli.NewSpinner(fmt.Sprintf("%s decomposing the task, swarm executing subtasks...", agents[0].Identity.Name))
+		spinner.Start()
+		result, err = c.ExecuteSwarm(ctx, task)
+		spinner.Stop(err == nil)
+	}
 
-		allResults := ""
-		for k, v := range results {
-			allResults += fmt.Sprintf("\n=== %s ===\n%s\n", strings.ToUpper(k), v)
+	if recording {
+		output := ""
+		if result != nil {
+			output = result.Output
 		}
+		if _, err := runs.End(output, err); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: run not recorded: %v\n", err)
+		}
+	}
 
-		resp, err := provider.Complete(ctx, llm.CompletionRequest{
-			System: "You are a synthesizer. Create a comprehensive, well-structured final response that combines all inputs. Format it beautifully with clear sections.",
-			Prompt: fmt.Sprintf("Original task: %s\n\nAgent outputs to synthesize:\n%s\n\nCreate a final, comprehensive response.",
-				task, allResults),
-			MaxTokens: 1500,
-		})
-
-		spinner.Stop(err == nil)
-
-		if err == nil {
-			results["final"] = resp.Content
+	if result != nil {
+		fmt.Println()
+		fmt.Printf("  %s┌─ PLAN ───────────────────────────────────┐%s\n", cli.Cyan, cli.Reset)
+		for _, sub := range result.Plan.Subtasks {
+			status := "pending"
+			if result.Run != nil && result.Run.Steps[sub.ID] != nil {
+				status = string(result.Run.Steps[sub.ID].Status)
+			}
+			deps := ""
+			if len(sub.DependsOn) > 0 {
+				deps = fmt.Sprintf(" %s(after %s)%s", cli.Dim, strings.Join(sub.DependsOn, ", "), cli.Reset)
+			}
+			fmt.Printf("  %s│%s  %-12s %-10s%s\n", cli.Cyan, cli.Reset, sub.ID, status, deps)
 		}
+		fmt.Printf("  %s└──────────────────────────────────────────┘%s\n", cli.Cyan, cli.Reset)
+	}
+	if err != nil {
+		fmt.Println(cli.Error(fmt.Sprintf("\n  Swarm failed: %v", err)))
+		fmt.Printf("  %sResume with: sqm swarm --resume %s%s\n", cli.Dim, task.ID, cli.Reset)
 	}
-	fmt.Printf("  %s└──────────────────────────────────────────┘%s\n", cli.Green, cli.Reset)
 
 	// Display final result
 	fmt.Println(cli.Section("SWARM OUTPUT"))
 
-	if final, ok := results["final"]; ok {
+	if err == nil {
 		fmt.Println()
-		lines := strings.Split(final, "\n")
-		for _, line := range lines {
+		for _, line := range strings.Split(result.Output, "\n") {
 			fmt.Printf("  %s\n", line)
 		}
 		fmt.Println()
@@ -310,17 +346,30 @@ func runSwarm(cmd *cobra.Command, args []string) {
 	}
 
 	// Summary
+	completed := 0
+	if result != nil && result.Run != nil {
+		completed = len(result.Run.Completed)
+	}
 	fmt.Println(cli.Divider(55))
 	fmt.Printf("\n  %sSwarm Statistics:%s\n", cli.Bold, cli.Reset)
 	fmt.Printf("  %s• Agents deployed:%s %d\n", cli.Dim, cli.Reset, len(agents))
-	fmt.Printf("  %s• Phases completed:%s %d\n", cli.Dim, cli.Reset, len(results))
-	fmt.Printf("  %s• Coordination:%s Gossip + Market\n", cli.Dim, cli.Reset)
+	fmt.Printf("  %s• Subtasks completed:%s %d\n", cli.Dim, cli.Reset, completed)
+	if pipeline != nil {
+		fmt.Printf("  %s• Coordination:%s Pipeline %s + Team markets\n", cli.Dim, cli.Reset, pipeline.Name)
+	} else {
+		fmt.Printf("  %s• Coordination:%s Orchestrator + Market\n", cli.Dim, cli.Reset)
+	}
+	if result != nil && result.TokensUsed > 0 {
+		fmt.Printf("  %s• Tokens used:%s %d\n", cli.Dim, cli.Reset, result.TokensUsed)
+	}
 	fmt.Println()
-
-	c.Stop()
 }
 
 func init() {
 	swarmCmd.Flags().IntVarP(&swarmAgents, "agents", "n", 5, "Number of agents in swarm (2-5)")
+	swarmCmd.Flags().DurationVar(&swarmTimeout, "timeout", 10*time.Minute, "Time limit for the whole swarm")
+	swarmCmd.Flags().StringVarP(&swarmPlanFile, "plan-file", "f", "", "Run the pipeline defined in this YAML file instead of the built-in roles")
+	swarmCmd.Flags().StringVar(&swarmSandbox, "sandbox", "", "Run the code agents write and feed failures back to them: process or container")
+	swarmCmd.Flags().StringVar(&swarmResume, "resume", "", "Continue the checkpointed run with this ID, skipping its completed phases")
 	rootCmd.AddCommand(swarmCmd)
 }
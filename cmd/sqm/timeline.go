@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+var taskTimelineCmd = &cobra.Command{
+	Use:   "timeline [task-id]",
+	Short: "Show a task's journey through the collective",
+	Long: `Show each stage a task passed through (submitted, listed, bids, consensus,
+assigned, running, tool calls, review, completed) with timestamps and actors.
+
+The timeline is read from the collective in this process if one is active,
+otherwise from a running 'sqm serve' or 'sqm dashboard' at --server.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		taskID := args[0]
+
+		var (
+			timeline []collective.TimelineEntry
+			err      error
+		)
+		if activeCollective != nil {
+			var ok bool
+			if timeline, ok = activeCollective.Timeline(taskID); !ok {
+				err = fmt.Errorf("no timeline for task %s", taskID)
+			}
+		} else {
+			server, _ := cmd.Flags().GetString("server")
+			timeline, err = fetchTimeline(server, taskID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n  Task %s\n", taskID)
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		if len(timeline) == 0 {
+			fmt.Println("  No stages recorded.")
+			return
+		}
+
+		start := timeline[0].Timestamp
+		for _, entry := range timeline {
+			offset := entry.Timestamp.Sub(start).Round(time.Millisecond)
+			line := fmt.Sprintf("  %s  %9s  %-10s", entry.Timestamp.Local().Format("15:04:05.000"), "+"+offset.String(), entry.Stage)
+			if entry.Actor != "" {
+				line += "  " + shortActor(entry.Actor)
+			}
+			if entry.Detail != "" {
+				line += "  " + entry.Detail
+			}
+			fmt.Println(line)
+		}
+		fmt.Printf("\n  Total: %v\n\n", timeline[len(timeline)-1].Timestamp.Sub(start).Round(time.Millisecond))
+	},
+}
+
+// fetchTimeline reads a task timeline from a running server
+func fetchTimeline(server, taskID string) ([]collective.TimelineEntry, error) {
+	endpoint := strings.TrimRight(server, "/") + "/api/tasks/" + url.PathEscape(taskID) + "/timeline"
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("no collective in this process and server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("no timeline for task %s", taskID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	var body struct {
+		Timeline []collective.TimelineEntry `json:"timeline"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Timeline, nil
+}
+
+// shortActor abbreviates agent SIDs for display
+func shortActor(actor string) string {
+	if len(actor) == 36 && strings.Count(actor, "-") == 4 {
+		return actor[:8]
+	}
+	return actor
+}
+
+func init() {
+	taskTimelineCmd.Flags().String("server", "http://127.0.0.1:8420", "Server to query when no collective is active")
+	taskCmd.AddCommand(taskTimelineCmd)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "List and call the tools of MCP servers",
+	Long: `Agents may call the tools of Model Context Protocol (MCP) servers listed
+under mcp_servers in the config file. Each server is a command speaking MCP
+on stdio or a streamable HTTP endpoint:
+
+  mcp_servers:
+    - name: fs
+      command: [npx, -y, "@modelcontextprotocol/server-filesystem", /srv/repo]
+    - name: github
+      url: https://mcp.example.com/github
+      headers:
+        Authorization: Bearer ghp_...
+      tools: [search_issues, get_file_contents]
+
+Tools are offered to the LLM as <server>__<tool>.`,
+}
+
+var toolsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Connect to the MCP servers and list their tools",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if toolbox == nil {
+			fmt.Println("No MCP servers configured.")
+			return
+		}
+		defer toolbox.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := toolbox.Connect(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		fmt.Println("\n  Tools")
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		for _, t := range toolbox.List() {
+			fmt.Printf("  %-32s %s\n", t.Name, t.Description)
+		}
+		fmt.Println()
+	},
+}
+
+var toolsCallCmd = &cobra.Command{
+	Use:   "call [tool] [json arguments]",
+	Short: "Call a tool as an agent would",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if toolbox == nil {
+			fmt.Fprintln(os.Stderr, "Error: no MCP servers configured")
+			os.Exit(1)
+		}
+		defer toolbox.Close()
+
+		var input json.RawMessage
+		if len(args) == 2 {
+			if !json.Valid([]byte(args[1])) {
+				fmt.Fprintln(os.Stderr, "Error: arguments must be JSON")
+				os.Exit(1)
+			}
+			input = json.RawMessage(args[1])
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if err := toolbox.Connect(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		output, err := toolbox.Call(ctx, args[0], input)
+		if output != "" {
+			fmt.Println(output)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsListCmd)
+	toolsCmd.AddCommand(toolsCallCmd)
+	rootCmd.AddCommand(toolsCmd)
+}
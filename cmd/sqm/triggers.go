@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+var workflowTriggersCmd = &cobra.Command{
+	Use:   "triggers",
+	Short: "Show the event triggers 'sqm serve' will run",
+	Long: `Validate and list the trigger rules and event sources in the triggers file
+(default ~/.squaremind/triggers.yaml).
+
+Rules start a workflow from the library when a matching event fires:
+
+  watch:
+    - {dir: ./inbox, pattern: "*.md", interval: 5s}
+  budget: {tokens: 2000000, thresholds: [0.8, 1.0]}
+  reputation: {drop: 15, window: 1h}
+  triggers:
+    - name: triage-new-specs
+      on: file_created
+      workflow: review-revise
+      params: {topic: "{{.Event.Data.name}}"}
+    - name: deploy-on-push
+      on: webhook
+      match: {hook: github}
+      workflow: secure-feature-delivery
+      cooldown: 10m
+
+Events: webhook (POST /api/hooks/<name>), file_created, budget_threshold,
+reputation_anomaly, and collective activity such as task_failed or agent_left.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("triggers")
+		tc, err := workflow.LoadTriggerConfig(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("\n  Triggers (%s)\n", path)
+		fmt.Println("  ─────────────────────────────────────────────────────────────")
+		if len(tc.Triggers) == 0 {
+			fmt.Println("  No trigger rules configured.")
+		}
+		for _, rule := range tc.Triggers {
+			line := fmt.Sprintf("  %-28s on %-18s -> %s", rule.Name, rule.On, rule.Workflow)
+			if rule.Cooldown > 0 {
+				line += fmt.Sprintf(" (cooldown %v)", rule.Cooldown)
+			}
+			fmt.Println(line)
+		}
+		for _, w := range tc.Watch {
+			fmt.Printf("  watching %s %s\n", w.Dir, w.Pattern)
+		}
+		if tc.Budget != nil {
+			fmt.Printf("  token budget %d\n", tc.Budget.Tokens)
+		}
+		if tc.Reputation != nil {
+			fmt.Printf("  reputation drops over %.1f within %v\n", tc.Reputation.Drop, tc.Reputation.Window)
+		}
+		fmt.Println()
+	},
+}
+
+// startTriggers loads the triggers file and starts its rules and event
+// sources against the active collective. Returns nil if no rules are configured.
+func startTriggers(ctx context.Context, path string) (*workflow.Triggers, *workflow.Engine, error) {
+	tc, err := workflow.LoadTriggerConfig(path)
+	if err != nil || len(tc.Triggers) == 0 {
+		return nil, nil, err
+	}
+
+	engine := workflow.NewEngine(activeCollective)
+	registry := workflow.NewRegistry(config.DefaultWorkflowDir())
+	if cfg.WorkflowIndex != "" {
+		registry.WithIndex(cfg.WorkflowIndex)
+	}
+	engine.SetRegistry(registry)
+
+	triggers := workflow.NewTriggers(engine)
+	for _, rule := range tc.Triggers {
+		if err := triggers.AddRule(rule); err != nil {
+			return nil, nil, err
+		}
+	}
+	triggers.OnRun(func(tr workflow.TriggerRun) {
+		status := "failed"
+		if tr.Run != nil {
+			status = string(tr.Run.Status)
+		}
+		fmt.Printf("  [trigger %s] %s workflow %s\n", tr.Rule, tr.Event.Type, status)
+	})
+	if err := triggers.Start(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	for _, w := range tc.Watch {
+		interval := w.Interval
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		go func(w workflow.WatchSource) {
+			if err := workflow.WatchFiles(ctx, w.Dir, w.Pattern, interval, triggers.Fire); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: not watching %s: %v\n", w.Dir, err)
+			}
+		}(w)
+	}
+	go bridgeCollectiveEvents(ctx, activeCollective, triggers, tc)
+
+	return triggers, engine, nil
+}
+
+// bridgeCollectiveEvents fires trigger events for collective activity,
+// deriving budget and reputation anomaly events on the way
+func bridgeCollectiveEvents(ctx context.Context, c *collective.Collective, triggers *workflow.Triggers, tc *workflow.TriggerConfig) {
+	var budget *workflow.BudgetMonitor
+	if tc.Budget != nil {
+		budget = workflow.NewBudgetMonitor(tc.Budget.Tokens, tc.Budget.Thresholds...)
+	}
+	var watch *workflow.ReputationWatch
+	if tc.Reputation != nil {
+		watch = workflow.NewReputationWatch(tc.Reputation.Drop, tc.Reputation.Window)
+	}
+
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data := map[string]interface{}{"agent_sid": e.AgentSID, "task_id": e.TaskID}
+			for k, v := range e.Data {
+				data[k] = v
+			}
+			triggers.Fire(workflow.Event{Type: string(e.Type), Data: data, Timestamp: e.Timestamp})
+
+			switch e.Type {
+			case collective.EventTaskCompleted, collective.EventTaskFailed:
+				if tokens, ok := e.Data["tokens_used"].(int); ok && budget != nil {
+					for _, threshold := range budget.Add(tokens) {
+						triggers.Fire(threshold)
+					}
+				}
+			case collective.EventReputationChanged:
+				delta, _ := e.Data["delta"].(float64)
+				reason, _ := e.Data["reason"].(string)
+				if watch != nil {
+					if anomaly, ok := watch.Observe(e.AgentSID, delta, reason, e.Timestamp); ok {
+						triggers.Fire(anomaly)
+					}
+				}
+			}
+		}
+	}
+}
+
+func init() {
+	workflowTriggersCmd.Flags().String("triggers", config.DefaultTriggersPath(), "Triggers file")
+	workflowCmd.AddCommand(workflowTriggersCmd)
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+var workflowCmd = &cobra.Command{
+	Use:   "workflow",
+	Short: "Run declarative multi-step workflows",
+}
+
+var workflowRunCmd = &cobra.Command{
+	Use:   "run [file-or-name]",
+	Short: "Run a workflow definition on the collective",
+	Long: `Run a workflow definition file, or a named workflow from the library. Steps
+are submitted as tasks once the steps they depend on have completed, and
+workflow steps invoke other workflows from the library.
+
+If a step fails after its retries, no further steps start and the
+compensations of completed steps run in reverse order, undoing side effects
+such as created branches or generated files.
+
+Human steps wait for someone to respond. They are prompted for on the
+terminal, posted to the Slack webhook in the config file (slack_webhook), and
+with --addr served at /api/approvals for 'sqm workflow respond'.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if activeCollective == nil {
+			fmt.Fprintln(os.Stderr, "No collective initialized.")
+			os.Exit(1)
+		}
+
+		registry := openWorkflowRegistry(cmd)
+		wf, err := loadWorkflow(cmd.Context(), registry, args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		paramStrs, _ := cmd.Flags().GetStringArray("param")
+		params, err := parseParams(paramStrs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		addr, _ := cmd.Flags().GetString("addr")
+
+		// Ctrl+C stops the workflow and compensates completed steps
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+
+		engine := workflow.NewEngine(activeCollective)
+		engine.SetRegistry(registry)
+		inbox := engine.Inbox()
+		if interactive {
+			inbox.OnRequest(promptHuman(inbox))
+		}
+		if cfg.SlackWebhook != "" {
+			inbox.OnRequest(workflow.SlackNotifier(cfg.SlackWebhook))
+		}
+		if addr != "" {
+			srv := newServer()
+			srv.SetInbox(inbox)
+			go func() {
+				if err := srv.ListenAndServe(ctx, addr); err != nil {
+					fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+				}
+			}()
+			fmt.Printf("\n  Approvals served at http://%s/api/approvals\n", addr)
+		}
+
+		fmt.Printf("\n  Running workflow: %s (%d steps)\n\n", wf.Name, len(wf.Steps))
+
+		run, err := engine.Run(ctx, wf, params)
+		printRun(run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var workflowValidateCmd = &cobra.Command{
+	Use:   "validate [file-or-name]",
+	Short: "Check a workflow definition without running it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		wf, err := loadWorkflow(cmd.Context(), openWorkflowRegistry(cmd), args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		compensated := 0
+		for _, step := range wf.Steps {
+			if step.Compensate != nil {
+				compensated++
+			}
+		}
+		fmt.Printf("  Workflow '%s' is valid: %d steps, %d with compensation\n", wf.Name, len(wf.Steps), compensated)
+	},
+}
+
+var workflowListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workflows in the local library and remote index",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := openWorkflowRegistry(cmd).List(cmd.Context())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if len(entries) == 0 {
+			fmt.Println("  No workflows found.")
+			return
+		}
+		for _, entry := range entries {
+			source := "local"
+			if entry.Remote {
+				source = "remote"
+			}
+			fmt.Printf("  %-30s %-7s %s\n", entry.Name, source, entry.Description)
+		}
+	},
+}
+
+var workflowInstallCmd = &cobra.Command{
+	Use:   "install [name]",
+	Short: "Copy a workflow from the remote index into the local library",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := openWorkflowRegistry(cmd).Install(cmd.Context(), args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  Installed %s to %s\n", args[0], path)
+	},
+}
+
+var workflowApprovalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "List human steps waiting for a response",
+	Long: `List the approval and input requests of a workflow run served with
+'sqm workflow run --addr'.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+
+		var pending []workflow.HumanRequest
+		if err := apiRequest(http.MethodGet, server, "/api/approvals", "", nil, &pending); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(pending) == 0 {
+			fmt.Println("  No pending requests.")
+			return
+		}
+		for _, req := range pending {
+			fmt.Printf("\n  %s  %s/%s  (%s, waiting %v)\n", req.ID, req.Workflow, req.StepID, req.Kind, time.Since(req.CreatedAt).Round(time.Second))
+			fmt.Printf("    %s\n", strings.ReplaceAll(strings.TrimSpace(req.Prompt), "\n", "\n    "))
+		}
+		fmt.Println()
+	},
+}
+
+var workflowRespondCmd = &cobra.Command{
+	Use:   "respond [request-id]",
+	Short: "Approve, reject or answer a human step",
+	Long: `Respond to a human step of a workflow run served with 'sqm workflow run
+--addr'. Requires an API token from api_tokens in the server's config file.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+		approve, _ := cmd.Flags().GetBool("approve")
+		reject, _ := cmd.Flags().GetBool("reject")
+		value, _ := cmd.Flags().GetString("value")
+
+		if approve && reject {
+			fmt.Fprintln(os.Stderr, "Error: --approve and --reject are mutually exclusive")
+			os.Exit(1)
+		}
+		if !approve && !reject && value == "" {
+			fmt.Fprintln(os.Stderr, "Error: give --approve, --reject or --value")
+			os.Exit(1)
+		}
+
+		resp := workflow.HumanResponse{Approved: approve, Value: value}
+		var req workflow.HumanRequest
+		if err := apiRequest(http.MethodPost, server, "/api/approvals/"+url.PathEscape(args[0]), token, resp, &req); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("  %s/%s: %s\n", req.Workflow, req.StepID, req.Status)
+	},
+}
+
+// apiRequest calls the HTTP API of a running server, decoding the JSON
+// response into out, or copying the body to it if out is an io.Writer
+func apiRequest(method, server, path, token string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(server, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error != "" {
+			return errors.New(apiErr.Error)
+		}
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if w, ok := out.(io.Writer); ok {
+		_, err = io.Copy(w, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// promptHuman answers human requests from the terminal, one at a time. A
+// request answered elsewhere first is reported and skipped.
+func promptHuman(inbox *workflow.Inbox) workflow.Notifier {
+	var mu sync.Mutex
+	reader := bufio.NewReader(os.Stdin)
+
+	return func(req workflow.HumanRequest) {
+		go func() {
+			mu.Lock()
+			defer mu.Unlock()
+
+			fmt.Printf("\n  [%s] %s\n", req.StepID, strings.TrimSpace(req.Prompt))
+			if req.Kind == workflow.HumanInput {
+				fmt.Print("  > ")
+			} else {
+				fmt.Print("  Approve? [y/N] ")
+			}
+			line, err := reader.ReadString('\n')
+			if err != nil && line == "" {
+				return
+			}
+			line = strings.TrimSpace(line)
+
+			resp := workflow.HumanResponse{Value: line, Responder: "terminal"}
+			if req.Kind != workflow.HumanInput {
+				answer := strings.ToLower(line)
+				resp = workflow.HumanResponse{Approved: answer == "y" || answer == "yes", Responder: "terminal"}
+			}
+			if err := inbox.Respond(req.ID, resp); err != nil {
+				fmt.Printf("  %v\n", err)
+			}
+		}()
+	}
+}
+
+// openWorkflowRegistry opens the workflow library from --library and the
+// configured remote index
+func openWorkflowRegistry(cmd *cobra.Command) *workflow.Registry {
+	dir, _ := cmd.Flags().GetString("library")
+	registry := workflow.NewRegistry(dir)
+	if cfg.WorkflowIndex != "" {
+		registry.WithIndex(cfg.WorkflowIndex)
+	}
+	return registry
+}
+
+// loadWorkflow reads a workflow file, or looks the name up in the library
+func loadWorkflow(ctx context.Context, registry *workflow.Registry, ref string) (*workflow.Workflow, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return workflow.LoadFile(ref)
+	}
+	return registry.Get(ctx, ref)
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// parseParams parses key=value workflow parameters
+func parseParams(pairs []string) (map[string]string, error) {
+	params := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid parameter %q (want key=value)", pair)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// printRun prints each step's outcome
+func printRun(run *workflow.Run) {
+	if run == nil {
+		return
+	}
+	fmt.Println("  ─────────────────────────────────────────────────────────────")
+	for _, id := range run.Order {
+		step := run.Steps[id]
+		line := fmt.Sprintf("  %-20s %-20s", id, step.Status)
+		if step.Iterations > 1 {
+			line += fmt.Sprintf(" (%d iterations)", step.Iterations)
+		}
+		if step.Attempts > 1 {
+			line += fmt.Sprintf(" (%d attempts)", step.Attempts)
+		}
+		if step.Responder != "" {
+			line += " (by " + step.Responder + ")"
+		}
+		if step.SkipReason != "" {
+			line += " (" + step.SkipReason + ")"
+		}
+		fmt.Println(line)
+		if step.Error != "" {
+			fmt.Printf("    error: %s\n", step.Error)
+		}
+		if step.CompensationError != "" {
+			fmt.Printf("    compensation error: %s\n", step.CompensationError)
+		}
+	}
+	fmt.Printf("\n  Workflow %s in %v\n\n", run.Status, run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond))
+}
+
+func init() {
+	workflowCmd.PersistentFlags().String("library", config.DefaultWorkflowDir(), "Directory of the local workflow library")
+
+	workflowRunCmd.Flags().StringArrayP("param", "p", nil, "Workflow parameter as key=value (repeatable)")
+	workflowRunCmd.Flags().Bool("interactive", isTerminal(os.Stdin), "Prompt for human steps on the terminal")
+	workflowRunCmd.Flags().String("addr", "", "Serve the approvals API on this address while running")
+
+	workflowApprovalsCmd.Flags().String("server", "http://127.0.0.1:8420", "Workflow run serving approvals")
+	workflowRespondCmd.Flags().String("server", "http://127.0.0.1:8420", "Workflow run serving approvals")
+	workflowRespondCmd.Flags().String("token", os.Getenv("SQM_API_TOKEN"), "API token (default $SQM_API_TOKEN)")
+	workflowRespondCmd.Flags().Bool("approve", false, "Approve the step")
+	workflowRespondCmd.Flags().Bool("reject", false, "Reject the step")
+	workflowRespondCmd.Flags().String("value", "", "Input value, or a comment on an approval")
+
+	workflowCmd.AddCommand(workflowRunCmd)
+	workflowCmd.AddCommand(workflowValidateCmd)
+	workflowCmd.AddCommand(workflowListCmd)
+	workflowCmd.AddCommand(workflowInstallCmd)
+	workflowCmd.AddCommand(workflowApprovalsCmd)
+	workflowCmd.AddCommand(workflowRespondCmd)
+	rootCmd.AddCommand(workflowCmd)
+}
@@ -166,7 +166,7 @@ func main() {
 		task.Complexity = taskDef.complexity
 		task.Deadline = time.Now().Add(time.Hour)
 
-		result, err := c.Submit(task)
+		result, err := c.SubmitCtx(ctx, task)
 		if err != nil {
 			fmt.Printf("  Status: FAILED - %v\n\n", err)
 		} else {
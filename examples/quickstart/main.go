@@ -127,7 +127,7 @@ func main() {
 	task.Deadline = time.Now().Add(time.Hour)
 	task.Reward = 10
 
-	result, err := c.Submit(task)
+	result, err := c.SubmitCtx(ctx, task)
 	if err != nil {
 		fmt.Printf("Task failed: %v\n", err)
 	} else {
@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/mattn/go-sqlite3 v1.14.22
 	github.com/spf13/cobra v1.8.0
 	golang.org/x/crypto v0.18.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/square-mind/squaremind/pkg/identity"
 	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/logging"
+	"github.com/square-mind/squaremind/pkg/sandbox"
 )
 
 // AgentState represents the current state of an agent
@@ -30,25 +33,97 @@ type Agent struct {
 
 	// Capabilities
 	Capabilities *identity.CapabilitySet
+	Learning     identity.LearningConfig
+	delegations  []*identity.DelegationProof // Capabilities other agents lent this one
+
+	// Placement labels of the host the agent runs on
+	Labels Labels
+
+	// Cost attribution labels charged for tasks that don't set their own
+	CostTags Labels
 
 	// LLM Backend
-	Provider llm.Provider
-	Model    string
+	Provider  llm.Provider
+	Model     string
+	Models    ModelMap            // Models by required capability, for tasks that name none (nil = Model for every task)
+	Reasoning llm.ReasoningPolicy // Per-complexity reasoning budgets (nil disables)
+	keyring   *Keyring            // Credentials bound to capabilities and teams (nil = Provider for everything)
+
+	// Role instructions sent as the system prompt, the sampling temperature
+	// (0 = provider default) and the limit on each response (0 = provider
+	// default); tasks may override each
+	SystemPrompt string
+	Temperature  float64
+	MaxTokens    int
 
 	// State
 	State       AgentState
-	CurrentTask *Task
+	CurrentTask *Task           // Task of the first busy slot
+	slots       []slot          // Worker slots, each running at most one task
+	expired     map[string]bool // IDs of queued tasks Expire dropped
+	settling    sync.Mutex      // Serializes recording the outcomes of tasks finishing in different slots
 
 	// Reputation
-	Reputation *Reputation
+	Reputation      *Reputation
+	reputationGuard func(func()) // Runs the slots' updates of Reputation (nil = runs them directly)
 
 	// Memory
 	Memory *AgentMemory
 
-	// Channels for coordination
+	// Token accounting
+	usage Usage
+
+	// Digest of the last result signed, which the next one chains to
+	lastSigned string
+
+	// Execution logging (nil disables)
+	Recorder ExecutionRecorder
+
+	// Runs the code written by code.write and testing agents (nil disables)
+	Sandbox        sandbox.Executor
+	SandboxRetries int // LLM calls to fix code that fails in the sandbox
+
+	// Behavior contracts results are checked against (nil disables)
+	Contracts *ContractSet
+
+	// Critic-driven revision of outputs (nil disables)
+	Refine *RefineConfig
+
+	// Tools the LLM may call while answering a task, and the rounds of
+	// calls allowed per response (nil disables)
+	Tools      Toolbox
+	ToolRounds int
+
+	// Integrations acting on completed results (pull requests...)
+	Integrations []Integration
+
+	// Context window conversations are fitted into (0 = DefaultContextTokens)
+	ContextTokens int
+
+	// Prompt budgets, task prompt templates by capability, and the memory
+	// recalled into prompts beyond the agent's own
+	Prompt          PromptConfig
+	promptTemplates map[identity.CapabilityType]*template.Template
+	defaultModels   ModelMap // Models of the capabilities Models leaves out, such as the collective's
+	shared          SharedMemory
+
+	// Callbacks invoked when the agent starts working on a task
+	onTaskStart []func(*Task)
+
+	// Liveness reporting
+	heartbeatInterval time.Duration
+	onHeartbeat       []func(Heartbeat)
+
+	logger logging.Logger
+
+	// Channels for coordination, and what overflowed them
+	queue      QueueConfig
+	queueStats QueueStats
 	taskChan   chan *Task
 	resultChan chan *TaskResult
 	stopChan   chan struct{}
+	wakeChan   chan struct{}
+	stopOnce   sync.Once
 
 	// Lifecycle
 	StartedAt  time.Time
@@ -60,8 +135,50 @@ type AgentConfig struct {
 	Name         string
 	Capabilities []identity.CapabilityType
 	Provider     llm.Provider
+	Keyring      *Keyring // Provider keys and tool credentials bound to capabilities and teams (nil disables)
 	Model        string
+	Models       ModelMap // Models tasks run on by required capability, before Model (e.g. documentation on a small model)
 	ParentSID    string
+	Learning     *identity.LearningConfig // Proficiency learning rates (defaults if nil)
+	Reasoning    llm.ReasoningPolicy      // Extended thinking per task complexity (nil disables)
+	Recorder     ExecutionRecorder        // Receives a record of every LLM execution (nil disables)
+	Logger       logging.Logger           // Structured logger (defaults to the "agent" component logger)
+	Labels       Labels                   // Placement labels of the agent's host (region, gpu, trusted-zone...)
+	CostTags     Labels                   // Cost attribution labels (cost-center, project...) the agent's spend is charged to
+	SystemPrompt string                   // Role instructions the agent works under (see pkg/roles)
+	Temperature  float64                  // Sampling temperature (0 = provider default)
+	MaxTokens    int                      // Limit on each response when a task sets none (0 = provider default)
+
+	// Sandbox runs the code a code.write or testing agent produces; failures
+	// are fed back to the LLM up to SandboxRetries times (nil disables)
+	Sandbox        sandbox.Executor
+	SandboxRetries int // 0 = DefaultSandboxRetries, negative = run without retrying
+
+	// Contracts are the rules outputs must follow per capability; broken
+	// ones are fed back to the LLM and lower the result's quality (nil disables)
+	Contracts *ContractSet
+
+	// Refine has each output critiqued, by the agent itself or another, and
+	// revised until it passes the critic's quality threshold (nil disables)
+	Refine *RefineConfig
+
+	// Tools the LLM may call, such as those of MCP servers; the outputs
+	// are returned to it for up to ToolRounds rounds per response (nil disables)
+	Tools      Toolbox
+	ToolRounds int // 0 = DefaultToolRounds
+
+	Integrations []Integration // Act on completed results outside the collective, such as opening pull requests
+
+	ContextTokens int // Context window of the model, for fitting conversations (0 = DefaultContextTokens)
+
+	Queue *QueueConfig // Task queue and results channel sizes and overflow policy (defaults if nil)
+
+	// Slots is how many tasks the agent works on at once; LLM calls are I/O
+	// bound, so one agent can wait on several (0 = 1, strictly serial)
+	Slots int
+
+	Prompt          *PromptConfig                      // Prompt budgets (defaults if nil)
+	PromptTemplates map[identity.CapabilityType]string // Task prompt templates (text/template over PromptData) by required capability
 }
 
 // NewAgent creates a new squaremind agent
@@ -81,76 +198,291 @@ func NewAgent(cfg AgentConfig) (*Agent, error) {
 		})
 	}
 
+	learning := identity.DefaultLearningConfig()
+	if cfg.Learning != nil {
+		learning = *cfg.Learning
+	}
+
+	promptCfg := DefaultPromptConfig()
+	if cfg.Prompt != nil {
+		promptCfg = *cfg.Prompt
+	}
+	templates, err := parsePromptTemplates(cfg.PromptTemplates)
+	if err != nil {
+		return nil, err
+	}
+
+	var queue QueueConfig
+	if cfg.Queue != nil {
+		if err := cfg.Queue.Validate(); err != nil {
+			return nil, err
+		}
+		queue = *cfg.Queue
+	}
+	queue = queue.withDefaults()
+
+	retries := cfg.SandboxRetries
+	if retries == 0 {
+		retries = DefaultSandboxRetries
+	}
+	toolRounds := cfg.ToolRounds
+	if toolRounds <= 0 {
+		toolRounds = DefaultToolRounds
+	}
+	slots := cfg.Slots
+	if slots <= 0 {
+		slots = 1
+	}
+
+	labels := make(Labels, len(cfg.Labels))
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	costTags := make(Labels, len(cfg.CostTags))
+	for k, v := range cfg.CostTags {
+		costTags[k] = v
+	}
+	var models ModelMap
+	if len(cfg.Models) > 0 {
+		models = make(ModelMap, len(cfg.Models))
+		for capType, model := range cfg.Models {
+			models[capType] = model
+		}
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = logging.Component("agent")
+	}
+	logger = logger.With("agent", id.SID, "name", cfg.Name)
+
 	return &Agent{
-		Identity:     id,
-		Capabilities: capSet,
-		Provider:     cfg.Provider,
-		Model:        cfg.Model,
-		State:        StateInitializing,
-		Reputation:   NewReputation(),
-		Memory:       NewAgentMemory(),
-		taskChan:     make(chan *Task, 10),
-		resultChan:   make(chan *TaskResult, 10),
-		stopChan:     make(chan struct{}),
-		StartedAt:    time.Now(),
-		LastActive:   time.Now(),
+		Identity:        id,
+		Capabilities:    capSet,
+		Learning:        learning,
+		Labels:          labels,
+		CostTags:        costTags,
+		Provider:        cfg.Provider,
+		keyring:         cfg.Keyring,
+		Model:           cfg.Model,
+		Models:          models,
+		Reasoning:       cfg.Reasoning,
+		SystemPrompt:    cfg.SystemPrompt,
+		Temperature:     cfg.Temperature,
+		MaxTokens:       cfg.MaxTokens,
+		Recorder:        cfg.Recorder,
+		Sandbox:         cfg.Sandbox,
+		SandboxRetries:  max(retries, 0),
+		Contracts:       cfg.Contracts,
+		Refine:          cfg.Refine,
+		Tools:           cfg.Tools,
+		ToolRounds:      toolRounds,
+		Integrations:    cfg.Integrations,
+		ContextTokens:   cfg.ContextTokens,
+		Prompt:          promptCfg,
+		promptTemplates: templates,
+		logger:          logger,
+		State:           StateInitializing,
+		slots:           make([]slot, slots),
+		Reputation:      NewReputation(),
+		Memory:          NewAgentMemory(),
+		queue:           queue,
+		taskChan:        make(chan *Task, queue.TaskCapacity),
+		resultChan:      make(chan *TaskResult, queue.ResultCapacity),
+		stopChan:        make(chan struct{}),
+		wakeChan:        make(chan struct{}, 1),
+		StartedAt:       time.Now(),
+		LastActive:      time.Now(),
 	}, nil
 }
 
-// Start begins the agent's autonomous operation
+// Start begins the agent's autonomous operation. Starting a running agent is a
+// no-op; a terminated agent cannot be restarted.
 func (a *Agent) Start(ctx context.Context) error {
 	a.mu.Lock()
-	a.State = StateIdle
-	a.mu.Unlock()
+	defer a.mu.Unlock()
+
+	switch a.State {
+	case StateTerminated:
+		return ErrAgentTerminated
+	case StateInitializing:
+		if err := a.transitionLocked(StateIdle); err != nil {
+			return err
+		}
+		go a.runLoop(ctx)
+		go a.heartbeatLoop(ctx)
+	}
 
-	go a.runLoop(ctx)
 	return nil
 }
 
-// runLoop is the main agent operation loop
+// runLoop is the main agent operation loop. It takes a queued task whenever
+// a slot is free and runs it in that slot; on stopping, tasks already running
+// finish before the agent terminates.
 func (a *Agent) runLoop(ctx context.Context) {
+	var workers sync.WaitGroup
+	defer a.terminate()
+	defer workers.Wait()
+
 	for {
+		// Stopping takes priority over queued work so it can be drained
+		select {
+		case <-a.stopChan:
+			return
+		default:
+		}
+
+		// Paused agents, and busy ones, leave tasks queued until resumed or
+		// a slot frees up
+		tasks := a.taskChan
+		if a.GetState() == StatePaused || a.FreeSlots() == 0 {
+			tasks = nil
+		}
+
 		select {
 		case <-ctx.Done():
-			a.terminate()
 			return
 		case <-a.stopChan:
-			a.terminate()
 			return
-		case task := <-a.taskChan:
-			a.executeTask(ctx, task)
+		case <-a.wakeChan:
+			// State changed, re-evaluate
+		case task := <-tasks:
+			// Tasks abandoned by their submitter while queued are skipped
+			if err := task.Context().Err(); err != nil {
+				a.log().Debug("skipping abandoned task", "task", task.ID, "error", err)
+				continue
+			}
+			if a.dropExpired(task.ID) {
+				a.log().Debug("skipping expired task", "task", task.ID)
+				continue
+			}
+			if i, ok := a.beginTask(ctx, task); ok {
+				workers.Add(1)
+				go func() {
+					defer workers.Done()
+					a.executeTask(task, i)
+				}()
+			}
 		}
 	}
 }
 
-// executeTask handles task execution
-func (a *Agent) executeTask(ctx context.Context, task *Task) {
-	a.mu.Lock()
-	a.State = StateWorking
-	a.CurrentTask = task
-	a.LastActive = time.Now()
-	a.mu.Unlock()
+// beginTask moves the agent into StateWorking for a task and puts it in a
+// free slot, waiting out a pause that raced with receiving it. Returns the
+// slot, or false if the agent stopped instead.
+func (a *Agent) beginTask(ctx context.Context, task *Task) (int, bool) {
+	for {
+		a.mu.Lock()
+		err := a.transitionLocked(StateWorking)
+		if err == nil {
+			i := a.claimSlotLocked(ctx, task)
+			a.LastActive = a.slots[i].started
+			handlers := a.onTaskStart
+			a.mu.Unlock()
+
+			a.log().Debug("task started", "task", task.ID, "complexity", task.Complexity)
+
+			for _, h := range handlers {
+				h(task)
+			}
+			return i, true
+		}
+		paused := a.State == StatePaused
+		a.mu.Unlock()
 
+		if !paused {
+			return 0, false
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, false
+		case <-a.stopChan:
+			return 0, false
+		case <-a.wakeChan:
+		}
+	}
+}
+
+// executeTask handles the execution of the task in slot i. The agent must
+// already be in StateWorking.
+func (a *Agent) executeTask(task *Task, i int) {
 	startTime := time.Now()
 
+	a.mu.Lock()
+	taskCtx, cancel := a.slots[i].ctx, a.slots[i].cancel
+	// The LLM call is cancelled if either the agent or the submitter gives up
+	defer cancel()
+	stopWatching := context.AfterFunc(task.Context(), cancel)
+	defer stopWatching()
+	if a.expired[task.ID] {
+		// Expired between leaving the queue and starting
+		delete(a.expired, task.ID)
+		a.slots[i].expiring = true
+		cancel()
+	}
+	a.mu.Unlock()
+
 	// Execute with LLM
-	result, err := a.performTask(ctx, task)
+	result, err := a.performTask(taskCtx, task)
 	result.Duration = time.Since(startTime)
 	result.Timestamp = time.Now()
 	result.AgentSID = a.Identity.SID
 
 	a.mu.Lock()
-	a.State = StateIdle
-	a.CurrentTask = nil
+	s := a.releaseSlotLocked(i)
+	if a.freeSlotsLocked() == len(a.slots) {
+		// Fails harmlessly if the agent was terminated mid-task
+		_ = a.transitionLocked(StateIdle)
+	}
+	preempted := s.preempting && err != nil
+	expired := s.expiring && err != nil
 	a.mu.Unlock()
+	a.signalWake()
+
+	// Outcomes of tasks finishing together are recorded one at a time
+	a.settling.Lock()
+	defer a.settling.Unlock()
+
+	// Nobody is waiting for an abandoned task's result, and its outcome says
+	// nothing about the agent's ability
+	if abandoned := task.Context().Err(); abandoned != nil {
+		a.log().Info("task abandoned by submitter", "task", task.ID, "duration", result.Duration, "error", abandoned)
+		return
+	}
+
+	// A preempted task isn't the agent's failure: it resumes from a checkpoint
+	// later. The result is unsigned, as it isn't a task's outcome.
+	if preempted {
+		a.log().Info("task preempted", "task", task.ID, "duration", result.Duration)
+		a.checkpoint(task)
+		result.Status = TaskFailed
+		result.Error = ErrPreempted.Error()
+		result.Preempted = true
+		a.deliver(task, result)
+		return
+	}
+	a.Memory.Forget(checkpointKey(task.ID))
+
+	if expired {
+		result.Status = TaskFailed
+		result.Error = ErrDeadlineExceeded.Error()
+		result.Expired = true
+		err = ErrDeadlineExceeded
+	}
 
 	// Update reputation based on result
 	if err != nil {
-		a.Reputation.RecordFailure()
+		a.log().Warn("task failed", "task", task.ID, "duration", result.Duration, "error", err)
+		a.updateReputation(a.Reputation.RecordFailure)
 	} else {
-		a.Reputation.RecordSuccess(result.Quality)
+		a.log().Info("task completed", "task", task.ID, "duration", result.Duration, "quality", result.Quality, "tokens", result.TokensUsed)
+		a.updateReputation(func() { a.Reputation.RecordSuccess(result.Quality) })
 	}
 
+	// Adapt proficiency of the capabilities exercised by this task
+	a.Capabilities.Learn(task.Required, err == nil, result.Quality, a.Learning)
+
 	// Add to episodic memory
 	a.Memory.AddEpisode(Episode{
 		Type:    "task_completion",
@@ -163,18 +495,74 @@ func (a *Agent) executeTask(ctx context.Context, task *Task) {
 		Salience: result.Quality,
 	})
 
-	// Send result
-	select {
-	case a.resultChan <- result:
-	default:
-		// Channel full, drop result
+	// Sign only results that are delivered, so the chain has no gaps
+	a.signResult(result)
+	a.deliver(task, result)
+}
+
+// deliver sends a result to the task's submitter if it asked for it, else
+// to the shared channel, by the agent's overflow policy. Only the shared
+// channel can be evicted from.
+func (a *Agent) deliver(task *Task, result *TaskResult) {
+	var results chan<- *TaskResult = a.resultChan
+	var evict <-chan *TaskResult = a.resultChan
+	if task.results != nil {
+		results, evict = task.results, nil
+	}
+	evicted, dropped, err := offer(task.Context(), a.stopChan, results, evict, result, a.queue.Overflow)
+	if dropped {
+		a.countOverflow(func(s *QueueStats) { s.DroppedResults++ })
+		a.log().Error("result channel full, dropping oldest result", "task", evicted.TaskID)
+	}
+	if err != nil {
+		a.countOverflow(func(s *QueueStats) { s.DroppedResults++ })
+		a.log().Error("result channel full, dropping result", "task", task.ID, "error", err)
 	}
 }
 
+// requestSettings returns the role instructions, sampling temperature (nil =
+// the provider's default) and response limit of the LLM requests for a task:
+// the task's own where it sets them, the agent's otherwise
+func (a *Agent) requestSettings(task *Task) (system string, temperature *float64, maxTokens int) {
+	system, maxTokens = a.SystemPrompt, a.MaxTokens
+	if a.Temperature > 0 {
+		t := a.Temperature
+		temperature = &t
+	}
+	if task == nil {
+		return system, temperature, maxTokens
+	}
+	if task.SystemPrompt != "" {
+		system = task.SystemPrompt
+	}
+	if task.Temperature != nil {
+		t := *task.Temperature
+		temperature = &t
+	}
+	if task.MaxTokens > 0 {
+		maxTokens = task.MaxTokens
+	}
+	return system, temperature, maxTokens
+}
+
+// withCaller tags requests made under ctx as the agent's work on a task
+func (a *Agent) withCaller(ctx context.Context, task *Task) context.Context {
+	return llm.ContextWithCaller(ctx, llm.Caller{AgentSID: a.Identity.SID, Agent: a.Identity.Name, TaskID: task.ID})
+}
+
 // performTask uses the LLM to perform the actual task
 func (a *Agent) performTask(ctx context.Context, task *Task) (*TaskResult, error) {
+	ctx, err := a.bindKeys(a.withCaller(ctx, task), task)
+	if err != nil {
+		return &TaskResult{
+			TaskID: task.ID,
+			Status: TaskFailed,
+			Error:  err.Error(),
+		}, err
+	}
+
 	// If no provider, return simulated result
-	if a.Provider == nil {
+	if a.provider(ctx) == nil {
 		return &TaskResult{
 			TaskID:  task.ID,
 			Status:  TaskCompleted,
@@ -184,73 +572,212 @@ func (a *Agent) performTask(ctx context.Context, task *Task) (*TaskResult, error
 	}
 
 	prompt := a.buildPrompt(task)
+	if task.Format == nil {
+		// A confidence line would break formatted output
+		prompt += confidenceRequest
+	}
+	system, temperature, maxTokens := a.requestSettings(task)
+	req := llm.CompletionRequest{
+		Model:          a.requestModel(task),
+		System:         system,
+		Prompt:         prompt,
+		MaxTokens:      maxTokens,
+		Temperature:    temperature,
+		Reasoning:      a.Reasoning.For(task.Complexity),
+		ResponseFormat: task.Format,
+	}
 
-	response, err := a.Provider.Complete(ctx, llm.CompletionRequest{
-		Model:  a.Model,
-		Prompt: prompt,
-	})
+	// Work is collected as it's produced so it survives a timeout
+	progress := task.Progress()
+	if progress == nil {
+		progress = NewProgress()
+	}
+	ctx = ContextWithProgress(ctx, progress)
+
+	if len(task.Steps) > 0 {
+		return a.performSteps(ctx, task, progress)
+	}
+
+	var (
+		response    *llm.CompletionResponse
+		toolResults []ToolResult
+	)
+	if a.Tools != nil {
+		response, toolResults, err = a.completeWithTools(ctx, req, progress)
+	} else {
+		response, err = a.complete(ctx, req, progress)
+	}
 	if err != nil {
-		return &TaskResult{
+		if response != nil {
+			a.recordUsage(response)
+		}
+		result := &TaskResult{
 			TaskID: task.ID,
 			Status: TaskFailed,
 			Error:  err.Error(),
-		}, err
+		}
+		progress.Salvage(result)
+		a.recordExecution(task, req, result)
+		return result, err
 	}
 
-	return &TaskResult{
-		TaskID:  task.ID,
-		Status:  TaskCompleted,
-		Output:  response.Content,
-		Quality: 0.8, // Would be evaluated by quality assessment
-	}, nil
-}
+	a.recordUsage(response)
 
-// buildPrompt constructs the prompt for the LLM
-func (a *Agent) buildPrompt(task *Task) string {
-	capsJSON := a.Capabilities.ToJSON()
-
-	return fmt.Sprintf(`You are a squaremind AI agent with the following identity:
-Name: %s
-SID: %s
-Capabilities: %s
+	result := &TaskResult{
+		TaskID:         task.ID,
+		Status:         TaskCompleted,
+		Output:         response.Content,
+		Quality:        0.8, // Would be evaluated by quality assessment
+		TokensUsed:     response.TokensUsed,
+		ThinkingTokens: response.ThinkingTokens,
+		ToolResults:    toolResults,
+	}
+	if a.Refine != nil {
+		a.refine(ctx, task, req, result, progress)
+	}
+	if a.runsCode() {
+		a.verifyInSandbox(ctx, req, result, progress)
+	}
+	if a.Contracts != nil {
+		a.enforceContracts(ctx, task, req, result, progress)
+	}
+	result.Output, result.Confidence = extractConfidence(result.Output)
+	a.runIntegrations(ctx, task, result, progress)
+	a.recordExecution(task, req, result)
+	a.reportQuality(ctx, result)
+	return result, nil
+}
 
-Your task:
-%s
+// complete sends a request to the provider, streaming the response into
+// progress if the provider supports it
+func (a *Agent) complete(ctx context.Context, req llm.CompletionRequest, progress *Progress) (*llm.CompletionResponse, error) {
+	provider := a.provider(ctx)
+	if streamer, ok := provider.(llm.StreamingProvider); ok {
+		return streamer.Stream(ctx, req, progress.AppendOutput)
+	}
+	return provider.Complete(ctx, req)
+}
 
-Requirements:
-%s
+// recordUsage adds a provider response's token counts to the agent's totals
+func (a *Agent) recordUsage(response *llm.CompletionResponse) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.usage.Requests++
+	a.usage.TokensUsed += response.TokensUsed
+	a.usage.ThinkingTokens += response.ThinkingTokens
+}
 
-Perform this task to the best of your ability. Be thorough and precise.`,
-		a.Identity.Name,
-		a.Identity.SID,
-		capsJSON,
-		task.Description,
-		task.Requirements,
-	)
+// GetUsage returns the agent's cumulative token usage
+func (a *Agent) GetUsage() Usage {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.usage
 }
 
-// Stop signals the agent to stop
+// Stop signals the agent to stop. Safe to call more than once.
 func (a *Agent) Stop() {
-	close(a.stopChan)
+	a.stopOnce.Do(func() {
+		close(a.stopChan)
+	})
+
+	// An agent that was never started has no run loop to terminate it
+	a.mu.Lock()
+	if a.State == StateInitializing {
+		_ = a.transitionLocked(StateTerminated)
+	}
+	a.mu.Unlock()
 }
 
 // terminate cleans up agent resources
 func (a *Agent) terminate() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	a.State = StateTerminated
+	_ = a.transitionLocked(StateTerminated)
+}
+
+// SetLogger replaces the agent's logger
+func (a *Agent) SetLogger(l logging.Logger) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.logger = l.With("agent", a.Identity.SID, "name", a.Identity.Name)
+}
+
+// log returns the agent's logger
+func (a *Agent) log() logging.Logger {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.logger
+}
+
+// OnTaskStart registers a callback invoked when the agent begins working on a task
+func (a *Agent) OnTaskStart(handler func(*Task)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onTaskStart = append(a.onTaskStart, handler)
+}
+
+// SubmitTask queues a task for the agent. A full queue is handled by the
+// agent's overflow policy: the task is refused with ErrQueueFull, waits for
+// room, or evicts the oldest queued task, which fails with ErrQueueFull.
+func (a *Agent) SubmitTask(task *Task) error {
+	return a.submit(task, a.queue.Overflow)
+}
+
+// TrySubmitTask queues a task like SubmitTask but never waits: under
+// OverflowBlock a full queue refuses the task. For callers holding locks.
+func (a *Agent) TrySubmitTask(task *Task) error {
+	policy := a.queue.Overflow
+	if policy == OverflowBlock {
+		policy = OverflowError
+	}
+	return a.submit(task, policy)
 }
 
-// SubmitTask submits a task to the agent
-func (a *Agent) SubmitTask(task *Task) {
-	select {
-	case a.taskChan <- task:
-	default:
-		// Channel full
+// submit queues a task by an overflow policy
+func (a *Agent) submit(task *Task, policy OverflowPolicy) error {
+	evicted, dropped, err := offer(task.Context(), a.stopChan, a.taskChan, a.taskChan, task, policy)
+	if dropped {
+		a.countOverflow(func(s *QueueStats) { s.DroppedTasks++ })
+		a.log().Warn("task queue full, dropping oldest task", "task", evicted.ID)
+		a.failQueued(evicted)
 	}
+	if err != nil {
+		a.countOverflow(func(s *QueueStats) { s.RejectedTasks++ })
+		a.log().Error("task queue full, refusing task", "task", task.ID, "error", err)
+		return fmt.Errorf("task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+// failQueued reports a task evicted from the queue as failed, so its
+// submitter isn't left waiting
+func (a *Agent) failQueued(task *Task) {
+	result := &TaskResult{
+		TaskID:    task.ID,
+		AgentSID:  a.Identity.SID,
+		Status:    TaskFailed,
+		Error:     ErrQueueFull.Error(),
+		Timestamp: time.Now(),
+	}
+	a.signResult(result)
+	a.deliver(task, result)
 }
 
-// GetResults returns the results channel
+// DrainQueue removes and returns tasks that were submitted but not yet started
+func (a *Agent) DrainQueue() []*Task {
+	var tasks []*Task
+	for {
+		select {
+		case task := <-a.taskChan:
+			tasks = append(tasks, task)
+		default:
+			return tasks
+		}
+	}
+}
+
+// GetResults returns the channel receiving the results of tasks submitted
+// without WithResults
 func (a *Agent) GetResults() <-chan *TaskResult {
 	return a.resultChan
 }
@@ -262,27 +789,40 @@ func (a *Agent) GetState() AgentState {
 	return a.State
 }
 
-// GetCurrentTask returns the current task being worked on
+// GetCurrentTask returns the current task being worked on, that of the
+// first busy slot if the agent runs several (see GetCurrentTasks)
 func (a *Agent) GetCurrentTask() *Task {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 	return a.CurrentTask
 }
 
-// Pause pauses the agent
-func (a *Agent) Pause() {
+// LastActivity returns when the agent last started a task, or was created
+func (a *Agent) LastActivity() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.LastActive
+}
+
+// Pause pauses an idle agent; queued tasks wait until it resumes.
+// Returns ErrInvalidTransition if the agent is working, initializing or terminated.
+func (a *Agent) Pause() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	if a.State == StateIdle {
-		a.State = StatePaused
-	}
+	return a.transitionLocked(StatePaused)
 }
 
 // Resume resumes a paused agent
-func (a *Agent) Resume() {
+func (a *Agent) Resume() error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	if a.State == StatePaused {
-		a.State = StateIdle
+
+	if a.State != StatePaused && a.State != StateIdle {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, a.State, StateIdle)
 	}
+	if err := a.transitionLocked(StateIdle); err != nil {
+		return err
+	}
+	a.signalWake()
+	return nil
 }
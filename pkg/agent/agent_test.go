@@ -2,10 +2,19 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/prompt"
+	"github.com/square-mind/squaremind/pkg/sandbox"
 )
 
 func TestNewAgent(t *testing.T) {
@@ -167,6 +176,116 @@ func TestReputation(t *testing.T) {
 	}
 }
 
+func TestReputation_RecordCalibration(t *testing.T) {
+	rep := NewReputation()
+	rep.RecordCalibration(0.9, 0.3)
+	rep.RecordCalibration(0.7, 0.5)
+
+	cal := rep.Calibration
+	if cal.Samples != 2 {
+		t.Errorf("Expected 2 samples, got %d", cal.Samples)
+	}
+	if math.Abs(cal.MeanError-0.4) > 1e-9 || math.Abs(cal.Overconfidence()-0.4) > 1e-9 {
+		t.Errorf("Expected mean error and overconfidence 0.4, got %f and %f", cal.MeanError, cal.Overconfidence())
+	}
+	if rep.Honesty >= 50 {
+		t.Errorf("Expected miscalibration to lower honesty, got %f", rep.Honesty)
+	}
+
+	rep = NewReputation()
+	rep.RecordCalibration(0.8, 0.8)
+	if rep.Honesty <= 50 || rep.Overall <= 50 {
+		t.Errorf("Expected an accurate estimate to raise honesty and overall, got %f and %f", rep.Honesty, rep.Overall)
+	}
+}
+
+func TestExtractConfidence(t *testing.T) {
+	tests := []struct {
+		output, want string
+		confidence   float64
+	}{
+		{"Done.\n\nConfidence: 0.85", "Done.", 0.85},
+		{"Done.\n**Confidence:** 70%\n", "Done.\n", 0.7},
+		{"Confidence: 0.2\nDone.\nConfidence: 0.9", "Confidence: 0.2\nDone.", 0.9},
+		{"No rating here", "No rating here", 0},
+		{"Done.\nConfidence: 7", "Done.\nConfidence: 7", 0},
+	}
+	for _, tt := range tests {
+		got, confidence := extractConfidence(tt.output)
+		if got != tt.want || confidence != tt.confidence {
+			t.Errorf("extractConfidence(%q) = %q, %f; expected %q, %f", tt.output, got, confidence, tt.want, tt.confidence)
+		}
+	}
+
+	provider := &scriptedProvider{responses: []string{"Answer\nConfidence: 0.6"}}
+	a, _ := NewAgent(AgentConfig{Name: "Agent", Provider: provider})
+	result, _ := a.performTask(context.Background(), NewTask("Do it", nil))
+	if !strings.Contains(provider.prompts[0], "Confidence: ") {
+		t.Error("Expected the prompt to ask for a confidence rating")
+	}
+	if result.Output != "Answer" || result.Confidence != 0.6 {
+		t.Errorf("Expected the rating taken off the output, got %q and %f", result.Output, result.Confidence)
+	}
+}
+
+func TestReputation_DecayFactor(t *testing.T) {
+	now := time.Now()
+	rep := NewReputation()
+	rep.LastActive = now.Add(-DecayGrace - 10*24*time.Hour)
+
+	tests := []struct {
+		curve DecayCurve
+		rate  float64
+		want  float64
+	}{
+		{DecayLinear, 0.01, 0.9},
+		{"", 0.01, 0.9},
+		{DecayExponential, 0.01, math.Pow(0.99, 10)},
+		{DecayNone, 0.01, 1},
+		{DecayLinear, 0, 1},
+		{DecayLinear, 0.2, DecayFloor},
+	}
+	for _, tt := range tests {
+		rep.SetDecay(tt.curve, tt.rate)
+		if got := rep.DecayFactor(now); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("DecayFactor(%q, %v) = %f, expected %f", tt.curve, tt.rate, got, tt.want)
+		}
+	}
+
+	rep.LastActive = now.Add(-DecayGrace / 2)
+	if got := rep.DecayFactor(now); got != 1 {
+		t.Errorf("Expected no decay within the grace period, got %f", got)
+	}
+}
+
+func TestReputation_ApplyDecayIdempotent(t *testing.T) {
+	rep := NewReputation()
+	rep.SetDecay(DecayLinear, 0.01)
+	rep.LastActive = time.Now().Add(-DecayGrace - 10*24*time.Hour)
+
+	rep.ApplyDecay()
+	first := rep.Overall
+	for i := 0; i < 100; i++ {
+		rep.ApplyDecay()
+	}
+	if math.Abs(rep.Overall-first) > 0.01 {
+		t.Errorf("Expected repeated decay not to compound, got %f then %f", first, rep.Overall)
+	}
+	if math.Abs(first-45) > 0.01 {
+		t.Errorf("Expected overall 45 after 10 idle days at 1%%, got %f", first)
+	}
+
+	// Activity restores the undecayed score
+	rep.RecordSuccess(0.5)
+	if rep.Overall < 50 {
+		t.Errorf("Expected activity to end the decay, got %f", rep.Overall)
+	}
+
+	if _, err := ParseDecayCurve("sigmoid"); err == nil {
+		t.Error("Expected an unknown curve to be rejected")
+	}
+}
+
 func TestAgentMemory(t *testing.T) {
 	mem := NewAgentMemory()
 
@@ -205,3 +324,1633 @@ func TestAgentMemory(t *testing.T) {
 		t.Errorf("Expected 1 episode, got %d", len(mem.Episodic))
 	}
 }
+
+// blockingProvider blocks Complete until released or cancelled
+type blockingProvider struct {
+	release chan struct{}
+}
+
+func (p *blockingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	select {
+	case <-p.release:
+		return &llm.CompletionResponse{Content: "done"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *blockingProvider) Name() string {
+	return "blocking"
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to AgentState
+		allowed  bool
+	}{
+		{StateInitializing, StateIdle, true},
+		{StateIdle, StateWorking, true},
+		{StateIdle, StatePaused, true},
+		{StateWorking, StateIdle, true},
+		{StateWorking, StatePaused, false},
+		{StatePaused, StateWorking, false},
+		{StatePaused, StateIdle, true},
+		{StateTerminated, StateIdle, false},
+		{StateInitializing, StateWorking, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("CanTransition(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
+
+func TestAgent_StopIdempotent(t *testing.T) {
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent"})
+
+	// Stopping an agent that never started terminates it directly
+	agent.Stop()
+	agent.Stop()
+
+	if agent.GetState() != StateTerminated {
+		t.Errorf("Expected state Terminated, got %s", agent.GetState())
+	}
+
+	if err := agent.Start(context.Background()); !errors.Is(err, ErrAgentTerminated) {
+		t.Errorf("Expected ErrAgentTerminated restarting a stopped agent, got %v", err)
+	}
+}
+
+func TestAgent_PauseWhileWorking(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent", Provider: provider})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	agent.SubmitTask(NewTask("Long task", nil))
+
+	deadline := time.Now().Add(time.Second)
+	for agent.GetState() != StateWorking && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := agent.Pause(); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition pausing a working agent, got %v", err)
+	}
+
+	close(provider.release)
+	<-agent.GetResults()
+}
+
+func TestAgent_PausedAgentHoldsTasks(t *testing.T) {
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	if err := agent.Pause(); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+
+	agent.SubmitTask(NewTask("Queued task", nil))
+
+	select {
+	case <-agent.GetResults():
+		t.Fatal("Paused agent should not execute tasks")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := agent.Resume(); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+
+	select {
+	case <-agent.GetResults():
+	case <-time.After(time.Second):
+		t.Error("Resumed agent should execute the queued task")
+	}
+}
+
+func TestTask_WithResults(t *testing.T) {
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	results := make(chan *TaskResult, 1)
+	routed := NewTask("Routed task", nil).WithResults(results)
+	agent.SubmitTask(routed)
+	agent.SubmitTask(NewTask("Shared task", nil))
+
+	select {
+	case result := <-results:
+		if result.TaskID != routed.ID {
+			t.Errorf("Expected result of %s, got %s", routed.ID, result.TaskID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No result on the task's channel")
+	}
+	select {
+	case result := <-agent.GetResults():
+		if result.TaskID == routed.ID {
+			t.Errorf("Expected routed result to bypass the shared channel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("No result on the shared channel")
+	}
+}
+
+func TestAgent_ConcurrentLifecycle(t *testing.T) {
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			_ = agent.Pause()
+		}()
+		go func() {
+			defer wg.Done()
+			_ = agent.Resume()
+		}()
+		go func() {
+			defer wg.Done()
+			agent.SubmitTask(NewTask("Concurrent task", nil))
+		}()
+		go func() {
+			defer wg.Done()
+			_ = agent.Start(ctx)
+		}()
+	}
+	wg.Wait()
+
+	// Concurrent double stop must not panic
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		agent.Stop()
+	}()
+	go func() {
+		defer wg.Done()
+		agent.Stop()
+	}()
+	wg.Wait()
+
+	deadline := time.Now().Add(time.Second)
+	for agent.GetState() != StateTerminated && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if agent.GetState() != StateTerminated {
+		t.Errorf("Expected state Terminated, got %s", agent.GetState())
+	}
+}
+
+// recorderFunc adapts a function to ExecutionRecorder
+type recorderFunc func(ExecutionRecord)
+
+func (f recorderFunc) Record(record ExecutionRecord) {
+	f(record)
+}
+
+func TestAgent_RecordsExecutions(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	close(provider.release)
+
+	records := make(chan ExecutionRecord, 1)
+	agent, _ := NewAgent(AgentConfig{
+		Name:     "TestAgent",
+		Provider: provider,
+		Recorder: recorderFunc(func(r ExecutionRecord) { records <- r }),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	task := NewTask("Recorded task", nil).WithRequirements("Use Go")
+	agent.SubmitTask(task)
+
+	select {
+	case record := <-records:
+		if record.TaskID != task.ID {
+			t.Errorf("Expected task ID %s, got %s", task.ID, record.TaskID)
+		}
+		if record.Output != "done" {
+			t.Errorf("Expected output 'done', got '%s'", record.Output)
+		}
+		if record.Context != "Use Go" {
+			t.Errorf("Expected context 'Use Go', got '%s'", record.Context)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an execution record")
+	}
+}
+
+// qualityProvider answers at once and takes note of the quality reported
+type qualityProvider struct {
+	blockingProvider
+	reported chan float64
+}
+
+func (p *qualityProvider) ReportQuality(quality float64) {
+	p.reported <- quality
+}
+
+func TestAgent_ReportsQuality(t *testing.T) {
+	provider := &qualityProvider{blockingProvider: blockingProvider{release: make(chan struct{})}, reported: make(chan float64, 1)}
+	close(provider.release)
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent", Provider: provider})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	agent.SubmitTask(NewTask("Graded task", nil))
+	result := <-agent.GetResults()
+
+	select {
+	case quality := <-provider.reported:
+		if quality != result.Quality {
+			t.Errorf("Expected quality %f reported, got %f", result.Quality, quality)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the result's quality reported to the provider")
+	}
+}
+
+func TestAgent_TaskContextCancelsExecution(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent", Provider: provider})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	taskCtx, cancelTask := context.WithCancel(context.Background())
+	agent.SubmitTask(NewTask("Abandoned task", nil).WithContext(taskCtx))
+
+	deadline := time.Now().Add(time.Second)
+	for agent.GetState() != StateWorking && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancelTask()
+
+	deadline = time.Now().Add(time.Second)
+	for agent.GetState() != StateIdle && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if agent.GetState() != StateIdle {
+		t.Fatalf("Expected agent to be released, got state %s", agent.GetState())
+	}
+
+	select {
+	case result := <-agent.GetResults():
+		t.Errorf("Expected no result for an abandoned task, got %+v", result)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Tasks abandoned while queued are skipped
+	agent.SubmitTask(NewTask("Cancelled before start", nil).WithContext(taskCtx))
+	select {
+	case result := <-agent.GetResults():
+		t.Errorf("Expected cancelled task to be skipped, got %+v", result)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+// streamingProvider streams a fixed delta, then fails
+type streamingProvider struct {
+	delta string
+	err   error
+}
+
+func (p *streamingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return p.Stream(ctx, req, nil)
+}
+
+func (p *streamingProvider) Stream(ctx context.Context, req llm.CompletionRequest, onDelta func(string)) (*llm.CompletionResponse, error) {
+	if onDelta != nil {
+		onDelta(p.delta)
+	}
+	ProgressFromContext(ctx).AddToolResult(ToolResult{Tool: "search", Output: "3 hits"})
+	return &llm.CompletionResponse{Content: p.delta}, p.err
+}
+
+func (p *streamingProvider) Name() string {
+	return "streaming"
+}
+
+func TestProgress_Salvage(t *testing.T) {
+	var empty *Progress
+	empty.AppendOutput("ignored")
+	if empty.Salvage(&TaskResult{}) {
+		t.Error("Expected nil progress to salvage nothing")
+	}
+
+	p := NewProgress()
+	if p.Salvage(&TaskResult{}) {
+		t.Error("Expected empty progress to salvage nothing")
+	}
+
+	p.AppendOutput("half ")
+	p.AppendOutput("done")
+	p.Checkpoint("outline", "3 sections")
+
+	result := &TaskResult{Status: TaskFailed}
+	if !p.Salvage(result) {
+		t.Fatal("Expected progress to be salvaged")
+	}
+	if !result.Partial {
+		t.Error("Expected result to be marked partial")
+	}
+	if result.Output != "half done" {
+		t.Errorf("Expected output 'half done', got '%s'", result.Output)
+	}
+	if len(result.Checkpoints) != 1 || result.Checkpoints[0].Label != "outline" {
+		t.Errorf("Expected outline checkpoint, got %+v", result.Checkpoints)
+	}
+}
+
+func TestAgent_PartialResultOnFailure(t *testing.T) {
+	provider := &streamingProvider{delta: "partial answer", err: errors.New("connection reset")}
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent", Provider: provider})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	agent.SubmitTask(NewTask("Interrupted task", nil))
+
+	select {
+	case result := <-agent.GetResults():
+		if result.Status != TaskFailed {
+			t.Errorf("Expected status 'failed', got '%s'", result.Status)
+		}
+		if !result.Partial {
+			t.Error("Expected result to be marked partial")
+		}
+		if result.Output != "partial answer" {
+			t.Errorf("Expected streamed output, got '%s'", result.Output)
+		}
+		if len(result.ToolResults) != 1 || result.ToolResults[0].Tool != "search" {
+			t.Errorf("Expected search tool result, got %+v", result.ToolResults)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a result")
+	}
+}
+
+func TestAgent_HeartbeatAndCancelTask(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent", Provider: provider})
+	agent.SetHeartbeatInterval(5 * time.Millisecond)
+
+	beats := make(chan Heartbeat, 100)
+	agent.OnHeartbeat(func(hb Heartbeat) {
+		select {
+		case beats <- hb:
+		default:
+		}
+	})
+
+	if agent.CancelTask() {
+		t.Errorf("Expected CancelTask to report no task on an idle agent")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	task := NewTask("Long task", nil)
+	agent.SubmitTask(task)
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case hb := <-beats:
+			if hb.AgentSID != agent.Identity.SID {
+				t.Fatalf("Expected heartbeat from %s, got %s", agent.Identity.SID, hb.AgentSID)
+			}
+			if hb.State != StateWorking {
+				continue
+			}
+			if hb.TaskID != task.ID || hb.TaskStarted.IsZero() {
+				t.Errorf("Expected working heartbeat to name the task and its start")
+			}
+		case <-deadline:
+			t.Fatal("No heartbeat reported the agent working")
+		}
+		break
+	}
+
+	if !agent.CancelTask() {
+		t.Errorf("Expected CancelTask to cancel the running task")
+	}
+	result := <-agent.GetResults()
+	if result.Status != TaskFailed {
+		t.Errorf("Expected cancelled task to fail, got %s", result.Status)
+	}
+}
+
+func TestAgent_Slots(t *testing.T) {
+	provider := &blockingProvider{release: make(chan struct{})}
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent", Provider: provider, Slots: 2})
+	if agent.Slots() != 2 || agent.Load() != 0 {
+		t.Fatalf("Expected 2 idle slots, got %d at load %f", agent.Slots(), agent.Load())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	tasks := []*Task{NewTask("First", nil), NewTask("Second", nil), NewTask("Third", nil)}
+	for _, task := range tasks {
+		agent.SubmitTask(task)
+	}
+	for deadline := time.Now().Add(time.Second); agent.FreeSlots() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected both slots to start a task")
+		}
+	}
+
+	// The third task waits for a slot
+	active := agent.ActiveTasks()
+	if len(active) != 2 || active[0].TaskID != tasks[0].ID || active[1].TaskID != tasks[1].ID {
+		t.Errorf("Expected the first two tasks running, got %+v", active)
+	}
+	if agent.GetState() != StateWorking || agent.Load() != 1 {
+		t.Errorf("Expected a fully loaded working agent, got %s at %f", agent.GetState(), agent.Load())
+	}
+	if hb := agent.Heartbeat(); hb.Slots != 2 || len(hb.Running()) != 2 || hb.TaskID != tasks[0].ID {
+		t.Errorf("Expected the heartbeat to report both tasks, got %+v", hb)
+	}
+
+	// Preempting one task frees its slot for the third
+	if !agent.Preempt(tasks[1].ID) {
+		t.Fatal("Expected the second task to be preempted")
+	}
+	result := <-agent.GetResults()
+	if result.TaskID != tasks[1].ID || !result.Preempted {
+		t.Errorf("Expected the second task preempted, got %+v", result)
+	}
+	for deadline := time.Now().Add(time.Second); len(agent.GetCurrentTasks()) != 2 || agent.GetCurrentTasks()[1].ID != tasks[2].ID; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the third task to take the free slot")
+		}
+	}
+
+	close(provider.release)
+	done := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case result := <-agent.GetResults():
+			done[result.TaskID] = result.Status == TaskCompleted
+		case <-time.After(time.Second):
+			t.Fatal("Expected the running tasks to complete")
+		}
+	}
+	if !done[tasks[0].ID] || !done[tasks[2].ID] {
+		t.Errorf("Expected the first and third tasks completed, got %v", done)
+	}
+	for deadline := time.Now().Add(time.Second); agent.GetState() != StateIdle; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the agent idle once its slots are free, got %s", agent.GetState())
+		}
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	labels := Labels{"region": "eu", "gpu": "true"}
+	tests := []struct {
+		spec    string
+		matches bool
+	}{
+		{"region=eu", true},
+		{"region=us|eu", true},
+		{"region=us", false},
+		{"region!=us", true},
+		{"zone!=public", true},
+		{"gpu", true},
+		{"trusted-zone", false},
+		{"!trusted-zone", true},
+		{"!gpu", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.spec)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := c.Matches(labels); got != tt.matches {
+			t.Errorf("Expected %q to match %v, got %v", tt.spec, tt.matches, got)
+		}
+		if c.String() != tt.spec {
+			t.Errorf("Expected %q to round-trip, got %q", tt.spec, c.String())
+		}
+	}
+
+	for _, bad := range []string{"", "=eu", "region=", "region=eu|", "!", "bad key=x"} {
+		if _, err := ParseConstraint(bad); !errors.Is(err, ErrInvalidConstraint) {
+			t.Errorf("Expected ErrInvalidConstraint for %q, got %v", bad, err)
+		}
+	}
+	if _, err := ParseLabels([]string{"region"}); !errors.Is(err, ErrInvalidConstraint) {
+		t.Errorf("Expected ErrInvalidConstraint for a label without a value, got %v", err)
+	}
+}
+
+func TestTask_PlaceableOn(t *testing.T) {
+	region, _ := ParseConstraint("region=eu")
+	gpu, _ := ParseConstraint("gpu")
+	task := NewTask("Train locally", nil).WithPlacement(region, gpu)
+
+	if !task.PlaceableOn(Labels{"region": "eu", "gpu": "a100"}) {
+		t.Errorf("Expected task placeable on an EU GPU host")
+	}
+	if task.PlaceableOn(Labels{"region": "eu"}) {
+		t.Errorf("Expected task not placeable without a GPU")
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded Task
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(decoded.Placement) != 2 || decoded.Placement[0].String() != "region=eu" {
+		t.Errorf("Expected placement to survive JSON, got %v", decoded.Placement)
+	}
+}
+
+func TestTask_JSONCompatibility(t *testing.T) {
+	// A task written before priorities and complexity were recorded
+	var old Task
+	if err := json.Unmarshal([]byte(`{"id":"t1","description":"legacy"}`), &old); err != nil {
+		t.Fatalf("Failed to decode legacy task: %v", err)
+	}
+	if old.Priority != PriorityNormal || old.Complexity != "medium" || old.Status != TaskPending {
+		t.Errorf("Expected NewTask defaults for missing fields, got priority %d, complexity %q, status %q", old.Priority, old.Complexity, old.Status)
+	}
+
+	// A task from a newer release keeps the fields this one doesn't know
+	var newer Task
+	if err := json.Unmarshal([]byte(`{"schema_version":9,"id":"t2","priority":1,"retry_policy":{"max":3}}`), &newer); err != nil {
+		t.Fatalf("Failed to decode newer task: %v", err)
+	}
+	if newer.Priority != 1 {
+		t.Errorf("Expected priority 1, got %d", newer.Priority)
+	}
+
+	data, err := json.Marshal(&newer)
+	if err != nil {
+		t.Fatalf("Failed to encode task: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to decode fields: %v", err)
+	}
+	if string(fields["retry_policy"]) != `{"max":3}` {
+		t.Errorf("Expected unknown field preserved, got %s", fields["retry_policy"])
+	}
+	if string(fields["schema_version"]) != "1" {
+		t.Errorf("Expected schema version %d, got %s", TaskSchemaVersion, fields["schema_version"])
+	}
+}
+
+func TestReputation_JSONCompatibility(t *testing.T) {
+	var rep Reputation
+	if err := json.Unmarshal([]byte(`{"overall":80,"tasks_completed":4}`), &rep); err != nil {
+		t.Fatalf("Failed to decode reputation: %v", err)
+	}
+
+	if rep.Overall != 80 || rep.TasksCompleted != 4 {
+		t.Errorf("Expected stored values kept, got overall %v, tasks %d", rep.Overall, rep.TasksCompleted)
+	}
+	if rep.Honesty != 50 || rep.DecayRate != 0.01 {
+		t.Errorf("Expected baseline for missing dimensions, got honesty %v, decay %v", rep.Honesty, rep.DecayRate)
+	}
+
+	result := TaskResult{TaskID: "t1", Status: TaskCompleted}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to encode result: %v", err)
+	}
+	var decoded TaskResult
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.TaskID != "t1" || decoded.Status != TaskCompleted {
+		t.Errorf("Expected result to round-trip, got %+v (%v)", decoded, err)
+	}
+}
+
+// scriptedProvider returns its responses in turn and records the prompts
+type scriptedProvider struct {
+	responses []string
+	prompts   []string
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.prompts = append(p.prompts, req.Prompt)
+	content := p.responses[min(len(p.prompts), len(p.responses))-1]
+	return &llm.CompletionResponse{Content: content, TokensUsed: 10}, nil
+}
+
+func (p *scriptedProvider) Name() string {
+	return "scripted"
+}
+
+// fakeSandbox fails code containing "bug" and passes anything else
+type fakeSandbox struct {
+	runs int
+}
+
+func (s *fakeSandbox) Run(ctx context.Context, p *sandbox.Program) (*sandbox.Result, error) {
+	s.runs++
+	for _, code := range p.Files {
+		if strings.Contains(code, "bug") {
+			return &sandbox.Result{Compiled: true, ExitCode: 1, Passed: 1, Failed: 1, Output: "--- FAIL: TestAdd"}, nil
+		}
+	}
+	return &sandbox.Result{Compiled: true, Passed: 2}, nil
+}
+
+func TestAgent_SandboxFeedback(t *testing.T) {
+	provider := &scriptedProvider{responses: []string{
+		"```go\nfunc Add() {} // bug\n```",
+		"```go\nfunc Add() {}\n```",
+	}}
+	box := &fakeSandbox{}
+	a, _ := NewAgent(AgentConfig{
+		Name:         "Coder",
+		Capabilities: []identity.CapabilityType{identity.CapCodeWrite},
+		Provider:     provider,
+		Sandbox:      box,
+	})
+
+	result, err := a.performTask(context.Background(), NewTask("Write Add", nil))
+	if err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+
+	if box.runs != 2 || len(provider.prompts) != 2 {
+		t.Fatalf("Expected 2 sandbox runs and 2 LLM calls, got %d and %d", box.runs, len(provider.prompts))
+	}
+	if !strings.Contains(provider.prompts[1], "--- FAIL: TestAdd") {
+		t.Errorf("Expected the failure fed into the next call, got %q", provider.prompts[1])
+	}
+	if result.TestsPassed != 2 || result.TestsFailed != 0 {
+		t.Errorf("Expected 2 passed and 0 failed, got %d and %d", result.TestsPassed, result.TestsFailed)
+	}
+	if result.Quality != 0.95 {
+		t.Errorf("Expected quality 0.95 for passing code, got %v", result.Quality)
+	}
+	if result.TokensUsed != 20 || len(result.ToolResults) != 2 {
+		t.Errorf("Expected tokens of both calls and 2 tool results, got %d and %d", result.TokensUsed, len(result.ToolResults))
+	}
+
+	// Without retries the failing code is scored as it is
+	provider = &scriptedProvider{responses: []string{"```go\n// bug\n```"}}
+	a, _ = NewAgent(AgentConfig{
+		Name:           "Tester",
+		Capabilities:   []identity.CapabilityType{identity.CapTesting},
+		Provider:       provider,
+		Sandbox:        &fakeSandbox{},
+		SandboxRetries: -1,
+	})
+	result, _ = a.performTask(context.Background(), NewTask("Write tests", nil))
+	if len(provider.prompts) != 1 || result.TestsFailed != 1 || result.Quality >= 0.8 {
+		t.Errorf("Expected one call and a failing score, got %d calls, %d failed, quality %v", len(provider.prompts), result.TestsFailed, result.Quality)
+	}
+
+	// Agents without code capabilities don't run their output
+	box = &fakeSandbox{}
+	a, _ = NewAgent(AgentConfig{
+		Name:         "Writer",
+		Capabilities: []identity.CapabilityType{identity.CapDocumentation},
+		Provider:     &scriptedProvider{responses: []string{"```go\n// bug\n```"}},
+		Sandbox:      box,
+	})
+	_, _ = a.performTask(context.Background(), NewTask("Document", nil))
+	if box.runs != 0 {
+		t.Errorf("Expected no sandbox runs for a documentation agent, got %d", box.runs)
+	}
+}
+
+func TestContractSet_Check(t *testing.T) {
+	cs, err := NewContractSet(ContractConfig{})
+	if err != nil {
+		t.Fatalf("NewContractSet failed: %v", err)
+	}
+	review := []identity.CapabilityType{identity.CapCodeReview}
+	research := []identity.CapabilityType{identity.CapResearch}
+
+	if v := cs.Check(review, "Looks fine to me."); len(v) != 1 || v[0].Contract != "review-findings" {
+		t.Errorf("Expected an unstructured review to break review-findings, got %v", v)
+	}
+	for _, output := range []string{"- [high]: SQL injection in query()", "Finding 1: unchecked error", "No issues found."} {
+		if v := cs.Check(review, output); len(v) != 0 {
+			t.Errorf("Expected %q to meet the review contract, got %v", output, v)
+		}
+	}
+	if v := cs.Check(research, "Go was released in 2009."); len(v) != 1 {
+		t.Errorf("Expected uncited research to break a contract, got %v", v)
+	}
+	if v := cs.Check(research, "Go was released in 2009 [1]."); len(v) != 0 {
+		t.Errorf("Expected cited research to meet the contract, got %v", v)
+	}
+	if v := cs.Check([]identity.CapabilityType{identity.CapDocumentation}, "anything"); len(v) != 0 {
+		t.Errorf("Expected no contracts for documentation, got %v", v)
+	}
+
+	// Rules replace defaults of the same name
+	cs, _ = NewContractSet(ContractConfig{Rules: []Contract{
+		{Name: "review-findings", Capability: identity.CapCodeReview, Match: []string{"^LGTM$"}},
+	}})
+	if v := cs.Check(review, "LGTM"); len(v) != 0 || len(cs.Contracts()) != 2 {
+		t.Errorf("Expected the rule to replace the default, got %v and %d contracts", v, len(cs.Contracts()))
+	}
+
+	if _, err := NewContractSet(ContractConfig{Rules: []Contract{{Name: "bad", Capability: identity.CapResearch, Match: []string{"("}}}}); !errors.Is(err, ErrInvalidContract) {
+		t.Errorf("Expected ErrInvalidContract, got %v", err)
+	}
+	if _, err := NewContractSet(ContractConfig{Rules: []Contract{{Name: "empty"}}}); !errors.Is(err, ErrInvalidContract) {
+		t.Errorf("Expected ErrInvalidContract for a contract without patterns, got %v", err)
+	}
+}
+
+func TestAgent_Contracts(t *testing.T) {
+	contracts, _ := NewContractSet(ContractConfig{})
+	provider := &scriptedProvider{responses: []string{"Looks fine.", "- [low]: rename x"}}
+	a, _ := NewAgent(AgentConfig{
+		Name:         "Reviewer",
+		Capabilities: []identity.CapabilityType{identity.CapCodeReview},
+		Provider:     provider,
+		Contracts:    contracts,
+	})
+	task := NewTask("Review the diff", []identity.CapabilityType{identity.CapCodeReview})
+
+	result, err := a.performTask(context.Background(), task)
+	if err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	if len(provider.prompts) != 2 || !strings.Contains(provider.prompts[1], "No issues found") {
+		t.Fatalf("Expected a re-prompt naming the contract, got %d calls", len(provider.prompts))
+	}
+	if result.Output != "- [low]: rename x" || len(result.ContractViolations) != 0 || result.Quality != 0.8 {
+		t.Errorf("Expected the fixed output at full quality, got %q, %v, %v", result.Output, result.ContractViolations, result.Quality)
+	}
+	if result.TokensUsed != 20 {
+		t.Errorf("Expected tokens of both calls, got %d", result.TokensUsed)
+	}
+
+	// An output still breaking the contract is recorded and penalised
+	provider = &scriptedProvider{responses: []string{"Looks fine."}}
+	a.Provider = provider
+	result, _ = a.performTask(context.Background(), NewTask("Review again", []identity.CapabilityType{identity.CapCodeReview}))
+	if len(provider.prompts) != 1+DefaultContractRetries {
+		t.Errorf("Expected %d calls, got %d", 1+DefaultContractRetries, len(provider.prompts))
+	}
+	if len(result.ContractViolations) != 1 || result.Quality != 0.4 {
+		t.Errorf("Expected one violation and quality 0.4, got %v and %v", result.ContractViolations, result.Quality)
+	}
+}
+
+func TestAgent_Refine(t *testing.T) {
+	// Self-critique: draft, critique, revision, critique
+	provider := &scriptedProvider{responses: []string{
+		"draft",
+		`{"quality": 0.4, "feedback": "handle empty input"}`,
+		"revised",
+		"Verdict:\n```json\n{\"quality\": 0.9, \"feedback\": \"\"}\n```",
+	}}
+	a, _ := NewAgent(AgentConfig{Name: "Writer", Provider: provider, Refine: &RefineConfig{}})
+	result, err := a.performTask(context.Background(), NewTask("Write a parser", nil))
+	if err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	if len(provider.prompts) != 4 || !strings.Contains(provider.prompts[2], "handle empty input") {
+		t.Fatalf("Expected a revision prompt with the feedback, got %d calls", len(provider.prompts))
+	}
+	if result.Output != "revised" || result.Iterations != 1 || result.Quality != 0.9 {
+		t.Errorf("Expected the revision at quality 0.9 after 1 iteration, got %q, %d, %v", result.Output, result.Iterations, result.Quality)
+	}
+	if result.Critique == nil || result.Critique.Quality != 0.9 {
+		t.Errorf("Expected the final critique on the result, got %+v", result.Critique)
+	}
+	if result.TokensUsed != 40 {
+		t.Errorf("Expected tokens of all 4 calls, got %d", result.TokensUsed)
+	}
+
+	// A second agent as critic that is never satisfied stops at the limit
+	criticProvider := &scriptedProvider{responses: []string{`{"quality": 0.3, "feedback": "not yet"}`}}
+	critic, _ := NewAgent(AgentConfig{Name: "Critic", Provider: criticProvider})
+	provider = &scriptedProvider{responses: []string{"v1", "v2", "v3", "v4"}}
+	a, _ = NewAgent(AgentConfig{Name: "Writer", Provider: provider, Refine: &RefineConfig{Critic: critic, Iterations: 2}})
+	result, _ = a.performTask(context.Background(), NewTask("Write a parser", nil))
+	if len(provider.prompts) != 3 || len(criticProvider.prompts) != 3 {
+		t.Errorf("Expected 3 completions and 3 critiques, got %d and %d", len(provider.prompts), len(criticProvider.prompts))
+	}
+	if result.Output != "v3" || result.Iterations != 2 || result.Quality != 0.3 {
+		t.Errorf("Expected v3 after 2 iterations at quality 0.3, got %q, %d, %v", result.Output, result.Iterations, result.Quality)
+	}
+
+	// An unreadable critique keeps the output as it is
+	provider = &scriptedProvider{responses: []string{"draft", "no idea"}}
+	a, _ = NewAgent(AgentConfig{Name: "Writer", Provider: provider, Refine: &RefineConfig{}})
+	result, _ = a.performTask(context.Background(), NewTask("Write a parser", nil))
+	if result.Output != "draft" || result.Iterations != 0 || result.Quality != 0.8 || result.Critique != nil {
+		t.Errorf("Expected the draft unrefined, got %q, %d, %v", result.Output, result.Iterations, result.Quality)
+	}
+}
+
+func TestParseCritique(t *testing.T) {
+	c, err := parseCritique(`Here: {"quality": 0.7, "feedback": "ok"} done`)
+	if err != nil || c.Quality != 0.7 || c.Feedback != "ok" {
+		t.Errorf("Expected quality 0.7 with feedback, got %+v, %v", c, err)
+	}
+	for _, content := range []string{"none", `{"quality": 2}`, `{"quality": "high"}`} {
+		if _, err := parseCritique(content); !errors.Is(err, ErrInvalidCritique) {
+			t.Errorf("Expected ErrInvalidCritique for %q, got %v", content, err)
+		}
+	}
+}
+
+// toolCallingProvider asks for the lookup tool until it has been given an
+// output, then answers with it
+type toolCallingProvider struct {
+	scriptedProvider
+	requests []llm.ToolRequest
+}
+
+func (p *toolCallingProvider) CompleteTools(ctx context.Context, req llm.ToolRequest) (*llm.CompletionResponse, error) {
+	p.requests = append(p.requests, req)
+	if len(req.Turns) == 0 {
+		return &llm.CompletionResponse{
+			TokensUsed: 10,
+			ToolCalls: []llm.ToolCall{
+				{ID: "1", Name: "lookup", Input: json.RawMessage(`{"key":"answer"}`)},
+				{ID: "2", Name: "missing"},
+			},
+		}, nil
+	}
+	turn := req.Turns[len(req.Turns)-1]
+	return &llm.CompletionResponse{Content: "The answer is " + turn.Outputs[0].Content, TokensUsed: 10}, nil
+}
+
+type fakeToolbox struct{}
+
+func (fakeToolbox) Tools(ctx context.Context) ([]llm.Tool, error) {
+	return []llm.Tool{{Name: "lookup", Description: "Look a key up"}}, nil
+}
+
+func (fakeToolbox) Call(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	if name != "lookup" {
+		return "", errors.New("unknown tool")
+	}
+	return "42", nil
+}
+
+func TestAgent_Tools(t *testing.T) {
+	provider := &toolCallingProvider{}
+	a, _ := NewAgent(AgentConfig{
+		Name:         "Researcher",
+		Capabilities: []identity.CapabilityType{identity.CapResearch},
+		Provider:     provider,
+		Tools:        fakeToolbox{},
+	})
+
+	result, err := a.performTask(context.Background(), NewTask("What is the answer?", nil))
+	if err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	if result.Output != "The answer is 42" {
+		t.Errorf("Expected the tool output used, got %q", result.Output)
+	}
+	if len(provider.requests) != 2 || len(provider.requests[0].Tools) != 1 {
+		t.Fatalf("Expected 2 requests offering the tool, got %d", len(provider.requests))
+	}
+	outputs := provider.requests[1].Turns[0].Outputs
+	if len(outputs) != 2 || outputs[0].CallID != "1" || !outputs[1].IsError {
+		t.Errorf("Expected both outputs returned, the unknown tool as an error, got %+v", outputs)
+	}
+	if len(result.ToolResults) != 2 || result.ToolResults[0].Tool != "lookup" || result.ToolResults[1].Error == "" {
+		t.Errorf("Expected both calls recorded, got %+v", result.ToolResults)
+	}
+	if result.TokensUsed != 20 {
+		t.Errorf("Expected tokens of both requests, got %d", result.TokensUsed)
+	}
+
+	// Providers that can't call tools get the plain request
+	plain := &scriptedProvider{responses: []string{"no tools"}}
+	a.Provider = plain
+	result, _ = a.performTask(context.Background(), NewTask("Anything", nil))
+	if result.Output != "no tools" || len(result.ToolResults) != 0 {
+		t.Errorf("Expected a plain completion, got %q", result.Output)
+	}
+}
+
+// fakeIntegration handles tasks with a "target" metadata key, failing for "bad"
+type fakeIntegration struct{}
+
+func (fakeIntegration) Name() string { return "fake" }
+
+func (fakeIntegration) Handle(ctx context.Context, a *Agent, task *Task, result *TaskResult) (string, error) {
+	switch task.Metadata["target"] {
+	case "":
+		return "", nil
+	case "bad":
+		return "", errors.New("unreachable")
+	}
+	return "published " + result.Output, nil
+}
+
+func TestAgent_Integrations(t *testing.T) {
+	a, _ := NewAgent(AgentConfig{
+		Name:         "Publisher",
+		Provider:     &scriptedProvider{responses: []string{"done"}},
+		Integrations: []Integration{fakeIntegration{}},
+	})
+
+	result, _ := a.performTask(context.Background(), NewTask("Publish", nil).WithMetadata(map[string]string{"target": "x"}))
+	if len(result.ToolResults) != 1 || result.ToolResults[0].Output != "published done" {
+		t.Errorf("Expected the integration recorded, got %+v", result.ToolResults)
+	}
+
+	result, _ = a.performTask(context.Background(), NewTask("Publish", nil).WithMetadata(map[string]string{"target": "bad"}))
+	if result.Status != TaskCompleted || len(result.ToolResults) != 1 || result.ToolResults[0].Error != "unreachable" {
+		t.Errorf("Expected a completed task with the failure recorded, got %s and %+v", result.Status, result.ToolResults)
+	}
+
+	result, _ = a.performTask(context.Background(), NewTask("Other", nil))
+	if len(result.ToolResults) != 0 {
+		t.Errorf("Expected nothing recorded for tasks the integration skips, got %+v", result.ToolResults)
+	}
+}
+
+// chatProvider answers chat requests with the number of the turn and
+// records the messages it was sent
+type chatProvider struct {
+	mu       sync.Mutex
+	requests [][]llm.Message
+}
+
+func (p *chatProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return nil, errors.New("chat only")
+}
+
+func (p *chatProvider) Chat(ctx context.Context, req llm.ChatRequest) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req.Messages)
+	return &llm.CompletionResponse{Content: fmt.Sprintf("answer %d", len(p.requests)), TokensUsed: 5}, nil
+}
+
+func (p *chatProvider) Name() string {
+	return "chat"
+}
+
+func TestAgent_MultiStepTask(t *testing.T) {
+	provider := &chatProvider{}
+	a, _ := NewAgent(AgentConfig{Name: "Planner", Provider: provider})
+	a.Memory.Store("repo", "squaremind")
+
+	task := NewTask("Design a cache", nil).WithSteps("Outline it", "Write it up")
+	result, err := a.performTask(context.Background(), task)
+	if err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+
+	if result.Output != "answer 2" || result.TokensUsed != 10 {
+		t.Errorf("Expected the last step's answer and tokens of both, got %q and %d", result.Output, result.TokensUsed)
+	}
+	if len(provider.requests) != 2 {
+		t.Fatalf("Expected 2 chat calls, got %d", len(provider.requests))
+	}
+
+	// The second call carries the first exchange, with memory in the system prompt
+	second := provider.requests[1]
+	if len(second) != 4 || second[0].Role != "system" || second[2].Content != "answer 1" {
+		t.Fatalf("Expected system, user, assistant, user; got %+v", second)
+	}
+	if !strings.Contains(second[0].Content, "repo: squaremind") {
+		t.Errorf("Expected short-term memory in the system prompt, got %q", second[0].Content)
+	}
+	if !strings.Contains(second[1].Content, "Design a cache") || !strings.Contains(second[3].Content, "Write it up") {
+		t.Errorf("Expected the task then the second step, got %+v", second)
+	}
+}
+
+// requestProvider records the requests it answers
+type requestProvider struct {
+	requests []llm.CompletionRequest
+}
+
+func (p *requestProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.requests = append(p.requests, req)
+	return &llm.CompletionResponse{Content: "done", TokensUsed: 5}, nil
+}
+
+func (p *requestProvider) Name() string {
+	return "request"
+}
+
+func TestAgent_RequestSettings(t *testing.T) {
+	tests := []struct {
+		name            string
+		task            *Task
+		wantSystem      string
+		wantTemperature float64
+		wantMaxTokens   int
+	}{
+		{"agent settings", NewTask("Review", nil), "You review code.", 0.3, 800},
+		{"task overrides", NewTask("Review", nil).WithSystemPrompt("You audit security.").WithTemperature(0.9).WithMaxTokens(200), "You audit security.", 0.9, 200},
+		{"partial override", NewTask("Review", nil).WithTemperature(0.1), "You review code.", 0.1, 800},
+		{"zero temperature", NewTask("Review", nil).WithTemperature(0), "You review code.", 0, 800},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &requestProvider{}
+			a, _ := NewAgent(AgentConfig{
+				Name:         "Reviewer",
+				Provider:     provider,
+				SystemPrompt: "You review code.",
+				Temperature:  0.3,
+				MaxTokens:    800,
+			})
+
+			if _, err := a.performTask(context.Background(), tt.task); err != nil {
+				t.Fatalf("performTask failed: %v", err)
+			}
+			req := provider.requests[0]
+			if req.System != tt.wantSystem || req.Temperature == nil || *req.Temperature != tt.wantTemperature || req.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("Expected system %q, temperature %v, max tokens %d; got %q, %v, %d",
+					tt.wantSystem, tt.wantTemperature, tt.wantMaxTokens, req.System, req.Temperature, req.MaxTokens)
+			}
+
+			// Conversations use the same settings, with the identity after the instructions
+			provider.requests = nil
+			if _, err := a.NewConversation(tt.task).Send(context.Background(), "Go on"); err != nil {
+				t.Fatalf("Send failed: %v", err)
+			}
+			req = provider.requests[0]
+			if !strings.HasPrefix(req.System, tt.wantSystem+"\n\n") || !strings.Contains(req.System, "Name: Reviewer") {
+				t.Errorf("Expected the instructions then the identity, got %q", req.System)
+			}
+			if req.Temperature == nil || *req.Temperature != tt.wantTemperature || req.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("Expected temperature %v and max tokens %d, got %v and %d", tt.wantTemperature, tt.wantMaxTokens, req.Temperature, req.MaxTokens)
+			}
+		})
+	}
+
+	// Neither setting one leaves it to the provider
+	provider := &requestProvider{}
+	a, _ := NewAgent(AgentConfig{Name: "Reviewer", Provider: provider})
+	if _, err := a.performTask(context.Background(), NewTask("Review", nil)); err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	if req := provider.requests[0]; req.Temperature != nil {
+		t.Errorf("Expected no temperature sent, got %v", *req.Temperature)
+	}
+}
+
+func TestAgent_ResponseFormat(t *testing.T) {
+	provider := &requestProvider{}
+	a, _ := NewAgent(AgentConfig{Name: "Planner", Provider: provider})
+
+	format := llm.JSONSchema("plan", `{"type": "object", "required": ["steps"]}`)
+	if _, err := a.performTask(context.Background(), NewTask("Plan the release", nil).WithResponseFormat(format)); err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	req := provider.requests[0]
+	if req.ResponseFormat != format {
+		t.Errorf("Expected the task's format on the request, got %+v", req.ResponseFormat)
+	}
+	if strings.Contains(req.Prompt, "Confidence") {
+		t.Error("Expected no confidence line requested of formatted output")
+	}
+
+	provider.requests = nil
+	_, _ = a.Critique(context.Background(), NewTask("Plan the release", nil), "ship it")
+	if f := provider.requests[0].ResponseFormat; f == nil || f.Name != "critique" {
+		t.Errorf("Expected critiques requested in the critique format, got %+v", f)
+	}
+}
+
+func TestAgent_MockProvider(t *testing.T) {
+	mock := llm.NewMockProvider(llm.MockScript{
+		Responses: []llm.MockResponse{
+			{Match: `Fix the (\w+) bug`, Content: "Fixed the {{index .Groups 1}} bug", Times: 1},
+			{Match: `Fix the`, Error: "rate limited"},
+		},
+		Default: "Nothing to do",
+	})
+	recorder := llm.NewRecordingProvider(mock)
+	a, _ := NewAgent(AgentConfig{Name: "Fixer", Provider: recorder})
+
+	result, err := a.performTask(context.Background(), NewTask("Fix the login bug", nil))
+	if err != nil || result.Output != "Fixed the login bug" {
+		t.Errorf("Expected the templated response, got %q (%v)", result.Output, err)
+	}
+	if _, err := a.performTask(context.Background(), NewTask("Fix the login bug", nil)); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("Expected the scripted error once the first response is used up, got %v", err)
+	}
+	if result, _ := a.performTask(context.Background(), NewTask("Write docs", nil)); result.Output != "Nothing to do" {
+		t.Errorf("Expected the default response, got %q", result.Output)
+	}
+	if mock.Calls() != 3 {
+		t.Errorf("Expected 3 calls, got %d", mock.Calls())
+	}
+
+	// The recording replays the same traffic offline, in order
+	replay := llm.NewMockProvider(recorder.Script())
+	a, _ = NewAgent(AgentConfig{Name: "Fixer", Provider: replay})
+	if result, _ := a.performTask(context.Background(), NewTask("Fix the login bug", nil)); result.Output != "Fixed the login bug" {
+		t.Errorf("Expected the recorded response replayed, got %q", result.Output)
+	}
+	if _, err := a.performTask(context.Background(), NewTask("Fix the login bug", nil)); err == nil {
+		t.Error("Expected the recorded error replayed")
+	}
+	if _, err := a.performTask(context.Background(), NewTask("Write tests", nil)); !errors.Is(err, llm.ErrNoMockResponse) {
+		t.Errorf("Expected ErrNoMockResponse for an unrecorded prompt, got %v", err)
+	}
+
+	// Injected failures and latency repeat with the seed
+	failures := func() int {
+		p := llm.NewMockProvider(llm.MockScript{Default: "ok", ErrorRate: 0.5, Seed: 7})
+		n := 0
+		for i := 0; i < 20; i++ {
+			if _, err := p.Complete(context.Background(), llm.CompletionRequest{Prompt: "hi"}); errors.Is(err, llm.ErrInjected) {
+				n++
+			}
+		}
+		return n
+	}
+	if n := failures(); n == 0 || n == 20 || n != failures() {
+		t.Errorf("Expected the same share of injected failures on each run, got %d", n)
+	}
+}
+
+func TestAgent_CapabilityModels(t *testing.T) {
+	a, _ := NewAgent(AgentConfig{
+		Name:         "Coder",
+		Capabilities: []identity.CapabilityType{identity.CapCodeWrite, identity.CapDocumentation},
+		Model:        "frontier",
+		Models:       ModelMap{identity.CapDocumentation: "small"},
+	})
+	a.SetDefaultModels(ModelMap{identity.CapDocumentation: "ignored", identity.CapResearch: "medium"})
+
+	tests := []struct {
+		task *Task
+		want string
+	}{
+		{NewTask("Write the README", []identity.CapabilityType{identity.CapDocumentation}), "small"},
+		{NewTask("Survey caches", []identity.CapabilityType{identity.CapResearch}), "medium"},
+		{NewTask("Implement the cache", []identity.CapabilityType{identity.CapCodeWrite}), "frontier"},
+		{NewTask("Document the cache", []identity.CapabilityType{identity.CapCodeWrite, identity.CapDocumentation}), "small"},
+		{NewTask("Write the README", []identity.CapabilityType{identity.CapDocumentation}).WithModel("pinned"), "pinned"},
+	}
+	for _, tt := range tests {
+		if got := a.requestModel(tt.task); got != tt.want {
+			t.Errorf("%s: expected model %s, got %s", tt.task.Description, tt.want, got)
+		}
+	}
+	if got := a.requestModel(nil); got != "frontier" {
+		t.Errorf("Expected requests without a task on the agent's model, got %s", got)
+	}
+
+	models, err := ParseModelMap([]string{"documentation=small", " code.write=frontier"})
+	if err != nil || models.String() != "code.write=frontier,documentation=small" {
+		t.Errorf("Expected both assignments parsed, got %v (%v)", models, err)
+	}
+	if _, err := ParseModelMap([]string{"documentation"}); err == nil {
+		t.Error("Expected an assignment without a model to fail")
+	}
+}
+
+func TestConversation_Truncation(t *testing.T) {
+	provider := &chatProvider{}
+	a, _ := NewAgent(AgentConfig{Name: "Talker", Provider: provider, ContextTokens: 600})
+	conv := a.NewConversation(NewTask("Chat", nil).WithMaxTokens(100))
+
+	long := strings.Repeat("x", 400) // About 100 tokens
+	ctx := context.Background()
+	if _, err := conv.Send(ctx, "the task"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	for i := 0; i < 6; i++ {
+		if _, err := conv.Send(ctx, long); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	history := conv.History()
+	if len(history) != 14 {
+		t.Fatalf("Expected 14 turns of history, got %d", len(history))
+	}
+
+	window := provider.requests[len(provider.requests)-1][1:]
+	if len(window) >= 13 {
+		t.Fatalf("Expected old turns left out, got %d", len(window))
+	}
+	if window[0].Content != "the task" || window[1].Content != "answer 1" {
+		t.Errorf("Expected the first exchange kept, got %+v", window[:2])
+	}
+	for i, m := range window {
+		want := "user"
+		if i%2 == 1 {
+			want = "assistant"
+		}
+		if m.Role != want {
+			t.Fatalf("Expected alternating turns, got %s at %d", m.Role, i)
+		}
+	}
+	if window[len(window)-1].Content != long {
+		t.Error("Expected the pending turn last")
+	}
+}
+
+// staticMemory recalls the same memories for every query
+type staticMemory []prompt.Candidate
+
+func (m staticMemory) Recall(query string, limit int) []prompt.Candidate {
+	return append([]prompt.Candidate(nil), m...)
+}
+
+func TestAgent_BuildPrompt(t *testing.T) {
+	a, _ := NewAgent(AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	a.Memory.AddEpisode(Episode{Content: "Completed task: Add an LRU cache to the session store", Salience: 0.9})
+	a.Memory.AddEpisode(Episode{Content: "Completed task: Write the release notes", Salience: 0.9})
+	a.SetSharedMemory(staticMemory{
+		{Content: "Caches must be invalidated on session logout", Salience: 0.5},
+		{Content: "The dashboard uses server-sent events", Salience: 0.5},
+	})
+
+	text := a.buildPrompt(NewTask("Add a cache to the user store", nil))
+	for _, want := range []string{"code.write (0.50)", "LRU cache to the session store", "invalidated on session logout", "Add a cache to the user store"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected prompt to contain %q, got:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"release notes", "server-sent events", "{"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("Expected prompt to leave out %q, got:\n%s", unwanted, text)
+		}
+	}
+
+	// A small budget cuts the task statement rather than overflowing
+	a.Prompt.Budget = 120
+	long := NewTask("Add a cache "+strings.Repeat("and more detail ", 200), nil)
+	if n := prompt.NewApproxTokenizer().Count(a.buildPrompt(long)); n > 120 {
+		t.Errorf("Expected the prompt to fit 120 tokens, got %d", n)
+	}
+}
+
+func TestAgent_PromptTemplates(t *testing.T) {
+	a, err := NewAgent(AgentConfig{
+		Name:         "Reviewer",
+		Capabilities: []identity.CapabilityType{identity.CapCodeReview},
+		PromptTemplates: map[identity.CapabilityType]string{
+			identity.CapCodeReview: "Review as {{.Name}}: {{.Task}}",
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+
+	review := a.buildPrompt(NewTask("Check the parser", []identity.CapabilityType{identity.CapCodeReview}))
+	if review != "Review as Reviewer: Check the parser" {
+		t.Errorf("Expected the code.review template, got %q", review)
+	}
+	other := a.buildPrompt(NewTask("Check the parser", nil))
+	if !strings.Contains(other, "Your task:") {
+		t.Errorf("Expected the default template for other tasks, got %q", other)
+	}
+
+	_, err = NewAgent(AgentConfig{PromptTemplates: map[identity.CapabilityType]string{identity.CapTesting: "{{.Task"}})
+	if !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("Expected ErrInvalidTemplate, got %v", err)
+	}
+}
+
+func TestAgent_SignedResults(t *testing.T) {
+	agent, _ := NewAgent(AgentConfig{Name: "TestAgent"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = agent.Start(ctx)
+	defer agent.Stop()
+
+	var results []*TaskResult
+	for _, description := range []string{"First task", "Second task"} {
+		agent.SubmitTask(NewTask(description, nil))
+		select {
+		case result := <-agent.GetResults():
+			results = append(results, result)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for result")
+		}
+	}
+
+	for _, r := range results {
+		if err := VerifyResult(r, agent.Identity.PublicKey); err != nil {
+			t.Errorf("Expected result of %s to verify, got %v", r.TaskID, err)
+		}
+	}
+	if results[0].Previous != "" || results[1].Previous != results[0].Digest() {
+		t.Errorf("Expected the second result to chain to the first")
+	}
+	if err := VerifyChain(results, agent.Identity.PublicKey); err != nil {
+		t.Errorf("Expected chain to verify, got %v", err)
+	}
+	if err := VerifyChain([]*TaskResult{results[1], results[0]}, agent.Identity.PublicKey); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected ErrBrokenChain for reordered results, got %v", err)
+	}
+
+	// Signatures survive encoding
+	data, _ := json.Marshal(results[1])
+	var decoded TaskResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := VerifyResult(&decoded, agent.Identity.PublicKey); err != nil {
+		t.Errorf("Expected decoded result to verify, got %v", err)
+	}
+
+	other, _ := NewAgent(AgentConfig{Name: "Other"})
+	tampered := *results[0]
+	tampered.Output = "Forged output"
+	tests := []struct {
+		name   string
+		result *TaskResult
+		key    []byte
+		want   error
+	}{
+		{"unsigned", &TaskResult{TaskID: "t"}, agent.Identity.PublicKey, ErrUnsignedResult},
+		{"tampered", &tampered, agent.Identity.PublicKey, ErrInvalidSignature},
+		{"wrong key", results[0], other.Identity.PublicKey, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		if err := VerifyResult(tt.result, tt.key); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestKeyring_Resolve(t *testing.T) {
+	general, security, audited := &requestProvider{}, &requestProvider{}, &requestProvider{}
+	k, err := NewKeyring(
+		Credential{Name: "general", Kind: CredentialAnthropic, Provider: general},
+		Credential{Name: "security", Kind: CredentialAnthropic, Provider: security, Capabilities: []identity.CapabilityType{identity.CapSecurity}},
+		Credential{Name: "audited", Kind: CredentialOpenAI, Provider: audited, Teams: []string{"security"}},
+		Credential{Name: "scanner", Kind: CredentialTool, Key: "s3cret", Env: "SCANNER_TOKEN", Capabilities: []identity.CapabilityType{identity.CapSecurity}},
+	)
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		task      *Task
+		wantCred  string
+		wantTools int
+	}{
+		{"unscoped", NewTask("Write docs", []identity.CapabilityType{identity.CapDocumentation}), "general", 0},
+		{"capability", NewTask("Audit deps", []identity.CapabilityType{identity.CapSecurity}), "security", 1},
+		{"team", NewTask("Fix bug", []identity.CapabilityType{identity.CapCodeWrite}).WithTeam("security"), "audited", 0},
+		{"team outranks capability", NewTask("Audit", []identity.CapabilityType{identity.CapSecurity}).WithTeam("security"), "audited", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := k.Resolve(tt.task)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if b.Credential != tt.wantCred {
+				t.Errorf("Expected credential %s, got %s", tt.wantCred, b.Credential)
+			}
+			if len(b.Tools) != tt.wantTools {
+				t.Errorf("Expected %d tool credentials, got %v", tt.wantTools, b.Tools)
+			}
+			if tt.wantTools > 0 && b.Env["SCANNER_TOKEN"] != "s3cret" {
+				t.Errorf("Expected the scanner token in the env, got %v", b.Env)
+			}
+		})
+	}
+
+	for _, creds := range [][]Credential{
+		{{Name: "scanner", Kind: CredentialTool, Key: "x"}},
+		{{Name: "a", Kind: "vault"}},
+		{{Kind: CredentialAnthropic}},
+		{{Name: "a", Kind: CredentialAnthropic}, {Name: "a", Kind: CredentialOpenAI}},
+	} {
+		if _, err := NewKeyring(creds...); !errors.Is(err, ErrInvalidCredential) {
+			t.Errorf("Expected ErrInvalidCredential for %+v, got %v", creds, err)
+		}
+	}
+
+	missing, _ := NewKeyring(Credential{Name: "ops", Kind: CredentialAnthropic, KeyEnv: "SQM_TEST_UNSET_KEY"})
+	if _, err := missing.Resolve(NewTask("Deploy", nil)); !errors.Is(err, ErrMissingKey) {
+		t.Errorf("Expected ErrMissingKey, got %v", err)
+	}
+	if secrets := k.Secrets(); len(secrets) != 1 || secrets[0] != "s3cret" {
+		t.Errorf("Expected the scanner secret, got %v", secrets)
+	}
+}
+
+// envSandbox records the environment programs are run with
+type envSandbox struct {
+	env map[string]string
+}
+
+func (s *envSandbox) Run(ctx context.Context, p *sandbox.Program) (*sandbox.Result, error) {
+	s.env = p.Env
+	return &sandbox.Result{Compiled: true, Passed: 1}, nil
+}
+
+func TestAgent_Keyring(t *testing.T) {
+	general, audited := &requestProvider{}, &requestProvider{}
+	k, _ := NewKeyring(
+		Credential{Name: "audited", Kind: CredentialAnthropic, Provider: audited, Teams: []string{"security"}},
+		Credential{Name: "scanner", Kind: CredentialTool, Key: "s3cret", Env: "SCANNER_TOKEN", Teams: []string{"security"}},
+	)
+	box := &envSandbox{}
+	a, _ := NewAgent(AgentConfig{
+		Name:         "Coder",
+		Capabilities: []identity.CapabilityType{identity.CapCodeWrite},
+		Provider:     general,
+		Keyring:      k,
+		Sandbox:      box,
+	})
+
+	if _, err := a.performTask(context.Background(), NewTask("Write docs", nil)); err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	if len(general.requests) != 1 || len(audited.requests) != 0 {
+		t.Errorf("Expected an unbound task on the agent's provider, got %d general and %d audited requests", len(general.requests), len(audited.requests))
+	}
+
+	if _, err := a.performTask(context.Background(), NewTask("Patch the auth bypass", nil).WithTeam("security")); err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	if len(general.requests) != 1 || len(audited.requests) != 1 {
+		t.Errorf("Expected the security task on the audited key, got %d general and %d audited requests", len(general.requests), len(audited.requests))
+	}
+
+	usage := map[string]KeyUsage{}
+	for _, u := range k.Usage() {
+		usage[u.Name] = u
+	}
+	if u := usage["audited"]; u.Tasks != 1 || u.Requests != 1 || u.TokensUsed != 5 || u.LastUsed.IsZero() {
+		t.Errorf("Expected one task, request and 5 tokens on the audited key, got %+v", u)
+	}
+	if u := usage["scanner"]; u.Tasks != 1 {
+		t.Errorf("Expected one task on the scanner credential, got %+v", u)
+	}
+
+	// Code runs with the tool credentials of the task
+	k2, _ := NewKeyring(Credential{Name: "scanner", Kind: CredentialTool, Key: "s3cret", Env: "SCANNER_TOKEN"})
+	a.SetKeyring(k2)
+	coder := &scriptedProvider{responses: []string{"```go\nfunc Scan() {}\n```"}}
+	a.Provider = coder
+	if _, err := a.performTask(context.Background(), NewTask("Write a scanner", nil)); err != nil {
+		t.Fatalf("performTask failed: %v", err)
+	}
+	if box.env["SCANNER_TOKEN"] != "s3cret" {
+		t.Errorf("Expected the sandbox to get the scanner token, got %v", box.env)
+	}
+	if u := k2.Usage()[0]; u.Requests != 1 {
+		t.Errorf("Expected one sandbox run on the scanner credential, got %+v", u)
+	}
+
+	// A task bound to a missing key fails rather than falling back
+	k3, _ := NewKeyring(Credential{Name: "ops", Kind: CredentialAnthropic, KeyEnv: "SQM_TEST_UNSET_KEY", Teams: []string{"ops"}})
+	a.SetKeyring(k3)
+	result, err := a.performTask(context.Background(), NewTask("Rotate certs", nil).WithTeam("ops"))
+	if !errors.Is(err, ErrMissingKey) || result.Status != TaskFailed {
+		t.Errorf("Expected a failed task with ErrMissingKey, got %v (%s)", err, result.Status)
+	}
+	if len(coder.prompts) != 1 {
+		t.Errorf("Expected no request on the agent's provider, got %d", len(coder.prompts))
+	}
+}
+
+func TestAgent_QueueOverflow(t *testing.T) {
+	newQueued := func(policy OverflowPolicy) *Agent {
+		a, err := NewAgent(AgentConfig{Name: "Queued", Queue: &QueueConfig{TaskCapacity: 1, Overflow: policy}})
+		if err != nil {
+			t.Fatalf("NewAgent failed: %v", err)
+		}
+		return a
+	}
+
+	// Refused: the submitter hears about it
+	a := newQueued(OverflowError)
+	_ = a.SubmitTask(NewTask("First", nil))
+	if err := a.SubmitTask(NewTask("Second", nil)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+	if stats := a.QueueStats(); stats.RejectedTasks != 1 || stats.QueuedTasks != 1 || stats.TaskCapacity != 1 {
+		t.Errorf("Expected 1 of 1 queued and 1 rejected, got %+v", stats)
+	}
+	if data, _ := json.Marshal(a.QueueStats()); !strings.Contains(string(data), `"queued_tasks":1`) || !strings.Contains(string(data), `"rejected_tasks":1`) {
+		t.Errorf("Expected snake_case queue stats, got %s", data)
+	}
+
+	// Evicted: the oldest task fails rather than vanishing
+	a = newQueued(OverflowDropOldest)
+	results := make(chan *TaskResult, 1)
+	first := NewTask("First", nil).WithResults(results)
+	_ = a.SubmitTask(first)
+	if err := a.SubmitTask(NewTask("Second", nil)); err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+	select {
+	case result := <-results:
+		if result.TaskID != first.ID || result.Status != TaskFailed || result.Error != ErrQueueFull.Error() {
+			t.Errorf("Expected the first task to fail with ErrQueueFull, got %+v", result)
+		}
+	default:
+		t.Error("Expected a result for the evicted task")
+	}
+	if queued := a.DrainQueue(); len(queued) != 1 || queued[0].Description != "Second" {
+		t.Errorf("Expected the second task queued, got %v", queued)
+	}
+	if stats := a.QueueStats(); stats.DroppedTasks != 1 {
+		t.Errorf("Expected 1 dropped task, got %+v", stats)
+	}
+
+	// Blocked: waits for room, or for the submitter to give up
+	a = newQueued(OverflowBlock)
+	_ = a.SubmitTask(NewTask("First", nil))
+	done := make(chan error, 1)
+	go func() { done <- a.SubmitTask(NewTask("Second", nil)) }()
+	select {
+	case err := <-done:
+		t.Fatalf("Expected SubmitTask to wait for room, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	a.DrainQueue()
+	if err := <-done; err != nil {
+		t.Errorf("Expected the task queued once there was room, got %v", err)
+	}
+	a.DrainQueue()
+	_ = a.SubmitTask(NewTask("Filler", nil))
+	if err := a.TrySubmitTask(NewTask("Third", nil)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected TrySubmitTask not to wait, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := a.SubmitTask(NewTask("Fourth", nil).WithContext(ctx)); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull once the task's context ended, got %v", err)
+	}
+}
+
+func TestNewAgent_InvalidQueue(t *testing.T) {
+	for _, q := range []QueueConfig{{Overflow: "spill"}, {TaskCapacity: -1}} {
+		if _, err := NewAgent(AgentConfig{Name: "Bad", Queue: &q}); !errors.Is(err, ErrInvalidOverflow) {
+			t.Errorf("Expected ErrInvalidOverflow for %+v, got %v", q, err)
+		}
+	}
+}
+
+func TestRuntime_SubmitTaskOverflow(t *testing.T) {
+	r := NewRuntime(RuntimeConfig{MaxAgents: 1, TaskBuffer: 1, Overflow: OverflowDropOldest})
+	first := NewTask("First", nil)
+	_ = r.SubmitTask(first)
+	if err := r.SubmitTask(NewTask("Second", nil)); err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+
+	select {
+	case result := <-r.GetResults():
+		if result.TaskID != first.ID || result.Status != TaskFailed {
+			t.Errorf("Expected the evicted task reported failed, got %+v", result)
+		}
+	default:
+		t.Error("Expected a result for the evicted task")
+	}
+	if stats := r.Stats(); stats.DroppedTasks != 1 || stats.PendingTasks != 1 {
+		t.Errorf("Expected 1 dropped and 1 pending task, got %+v", stats)
+	}
+}
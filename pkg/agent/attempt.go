@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// ErrNoProvider is returned by Attempt for an agent with no LLM provider
+var ErrNoProvider = errors.New("agent has no LLM provider")
+
+// Attempt answers a task with the agent's provider, model and prompt
+// settings outside its queue. Nothing is learned, recorded or signed, and
+// no tools, sandbox, contracts or integrations run, so benchmarks can
+// measure what the agent writes without side effects.
+func (a *Agent) Attempt(ctx context.Context, task *Task) (*TaskResult, error) {
+	ctx, err := a.bindKeys(a.withCaller(ctx, task), task)
+	if err != nil {
+		return nil, err
+	}
+	provider := a.provider(ctx)
+	if provider == nil {
+		return nil, ErrNoProvider
+	}
+
+	system, temperature, maxTokens := a.requestSettings(task)
+	response, err := provider.Complete(ctx, llm.CompletionRequest{
+		Model:          a.requestModel(task),
+		System:         system,
+		Prompt:         a.buildPrompt(task),
+		MaxTokens:      maxTokens,
+		Temperature:    temperature,
+		Reasoning:      a.Reasoning.For(task.Complexity),
+		ResponseFormat: task.Format,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.recordUsage(response)
+	return &TaskResult{
+		TaskID:         task.ID,
+		AgentSID:       a.Identity.SID,
+		Status:         TaskCompleted,
+		Output:         response.Content,
+		TokensUsed:     response.TokensUsed,
+		ThinkingTokens: response.ThinkingTokens,
+		Timestamp:      time.Now(),
+	}, nil
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// confidenceRequest asks the LLM to rate its own answer
+const confidenceRequest = `
+
+On the last line of your answer, rate how likely your answer fully and correctly completes the task, as "Confidence: " followed by a number from 0.0 to 1.0.`
+
+// confidencePattern matches a "Confidence: 0.8" or "Confidence: 80%" line
+var confidencePattern = regexp.MustCompile(`(?im)^[ \t*_]*confidence[ \t*_]*:[ \t*_]*([0-9]*\.?[0-9]+)[ \t]*(%?)[ \t*_.]*$`)
+
+// extractConfidence removes the last confidence line from an output and
+// returns the rating it states, or 0 if there is none
+func extractConfidence(output string) (string, float64) {
+	matches := confidencePattern.FindAllStringSubmatchIndex(output, -1)
+	if len(matches) == 0 {
+		return output, 0
+	}
+	m := matches[len(matches)-1]
+	value, err := strconv.ParseFloat(output[m[2]:m[3]], 64)
+	if err != nil {
+		return output, 0
+	}
+	if m[5] > m[4] {
+		value /= 100
+	}
+	if value < 0 || value > 1 {
+		return output, 0
+	}
+	return strings.TrimRight(output[:m[0]], " \t\n") + output[m[1]:], value
+}
@@ -0,0 +1,215 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// ErrInvalidContract is returned for a contract without a capability or
+// patterns, or with a pattern that doesn't compile
+var ErrInvalidContract = errors.New("invalid contract")
+
+// DefaultContractRetries is how many times an agent asks the LLM to fix an
+// output that breaks a contract
+const DefaultContractRetries = 1
+
+// contractPenalty scales a result's quality for each contract it breaks
+const contractPenalty = 0.5
+
+// Contract is a machine-checkable rule the output of tasks requiring a
+// capability must follow: it must match at least one of the patterns
+type Contract struct {
+	Name        string                  `yaml:"name" json:"name"`
+	Capability  identity.CapabilityType `yaml:"capability" json:"capability"`
+	Description string                  `yaml:"description,omitempty" json:"description,omitempty"` // Shown to the LLM when it's asked for a fix
+	Match       []string                `yaml:"match" json:"match"`                                 // Regular expressions; one must match the output
+
+	patterns []*regexp.Regexp
+}
+
+// ContractViolation records a contract a result broke
+type ContractViolation struct {
+	Contract    string                  `json:"contract"`
+	Capability  identity.CapabilityType `json:"capability"`
+	Description string                  `json:"description,omitempty"`
+}
+
+// ContractConfig configures the contracts agents are held to
+type ContractConfig struct {
+	Retries    int        `yaml:"retries,omitempty"`     // 0 = DefaultContractRetries, negative = never re-prompt
+	NoDefaults bool       `yaml:"no_defaults,omitempty"` // Don't start from DefaultContracts
+	Rules      []Contract `yaml:"rules,omitempty"`       // Added to the defaults, replacing any of the same name
+}
+
+// DefaultContracts returns the built-in contracts: reviews state findings
+// or that there are none, research cites its sources
+func DefaultContracts() []Contract {
+	return []Contract{
+		{
+			Name:        "review-findings",
+			Capability:  identity.CapCodeReview,
+			Description: `List each finding on its own line with a severity (e.g. "- [high] ...") or say explicitly "No issues found".`,
+			Match: []string{
+				`(?im)^\s*(?:[-*]|\d+[.)])?\s*\**\[?(?:critical|high|major|medium|minor|low|nit|info)\]?\**\s*[:\-]`,
+				`(?im)^\s*(?:[-*]|\d+[.)])?\s*\**(?:finding|issue)\s*\d*\**\s*:`,
+				`(?i)\bno (?:issues|problems|findings)\b`,
+			},
+		},
+		{
+			Name:        "research-citations",
+			Capability:  identity.CapResearch,
+			Description: "Cite your sources: link them or number them ([1], [2]...) under a Sources heading.",
+			Match: []string{
+				`https?://\S+`,
+				`\[\d+\]`,
+				`(?im)^\s*#*\s*(?:sources|references|citations)\s*:?\s*$`,
+			},
+		},
+	}
+}
+
+// ContractSet is the compiled contracts an agent checks its results against
+type ContractSet struct {
+	Retries   int // LLM calls to fix an output that breaks a contract
+	contracts []Contract
+}
+
+// NewContractSet compiles the configured contracts
+func NewContractSet(cfg ContractConfig) (*ContractSet, error) {
+	var contracts []Contract
+	if !cfg.NoDefaults {
+		contracts = DefaultContracts()
+	}
+	for _, rule := range cfg.Rules {
+		replaced := false
+		for i := range contracts {
+			if contracts[i].Name == rule.Name {
+				contracts[i], replaced = rule, true
+			}
+		}
+		if !replaced {
+			contracts = append(contracts, rule)
+		}
+	}
+
+	for i := range contracts {
+		c := &contracts[i]
+		if c.Name == "" || c.Capability == "" || len(c.Match) == 0 {
+			return nil, fmt.Errorf("%w: %q needs a name, a capability and patterns", ErrInvalidContract, c.Name)
+		}
+		c.patterns = make([]*regexp.Regexp, len(c.Match))
+		for j, pattern := range c.Match {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidContract, c.Name, err)
+			}
+			c.patterns[j] = re
+		}
+	}
+
+	retries := cfg.Retries
+	if retries == 0 {
+		retries = DefaultContractRetries
+	}
+	return &ContractSet{Retries: max(retries, 0), contracts: contracts}, nil
+}
+
+// Contracts returns the contracts in the set
+func (cs *ContractSet) Contracts() []Contract {
+	return append([]Contract(nil), cs.contracts...)
+}
+
+// Check returns the contracts of the required capabilities an output breaks
+func (cs *ContractSet) Check(required []identity.CapabilityType, output string) []ContractViolation {
+	var violations []ContractViolation
+	for _, c := range cs.contracts {
+		if !hasCapability(required, c.Capability) || c.matches(output) {
+			continue
+		}
+		violations = append(violations, ContractViolation{
+			Contract:    c.Name,
+			Capability:  c.Capability,
+			Description: c.Description,
+		})
+	}
+	return violations
+}
+
+func (c *Contract) matches(output string) bool {
+	for _, re := range c.patterns {
+		if re.MatchString(output) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasCapability(caps []identity.CapabilityType, want identity.CapabilityType) bool {
+	for _, c := range caps {
+		if c == want {
+			return true
+		}
+	}
+	return false
+}
+
+// enforceContracts checks a result against the contracts of its task's
+// capabilities. While it breaks any, the LLM is told which and asked for a
+// fix, up to the set's Retries times; a failed fix attempt leaves the
+// previous output in place. Contracts still broken are recorded on the
+// result and halve its quality each.
+func (a *Agent) enforceContracts(ctx context.Context, task *Task, req llm.CompletionRequest, result *TaskResult, progress *Progress) {
+	violations := a.Contracts.Check(task.Required, result.Output)
+	for attempt := 0; len(violations) > 0 && attempt < a.Contracts.Retries; attempt++ {
+		fix := req
+		fix.Prompt = contractPrompt(req.Prompt, result.Output, violations)
+		response, err := a.complete(ctx, fix, progress)
+		if response != nil {
+			a.recordUsage(response)
+			result.TokensUsed += response.TokensUsed
+			result.ThinkingTokens += response.ThinkingTokens
+		}
+		if err != nil {
+			a.log().Warn("contract fix request failed", "task", result.TaskID, "error", err)
+			break
+		}
+		result.Output = response.Content
+		violations = a.Contracts.Check(task.Required, result.Output)
+		a.log().Debug("contract fix", "task", result.TaskID, "attempt", attempt+1, "violations", len(violations))
+	}
+
+	result.ContractViolations = violations
+	for _, v := range violations {
+		result.Quality *= contractPenalty
+		a.log().Warn("result breaks contract", "task", result.TaskID, "contract", v.Contract)
+	}
+}
+
+// contractPrompt asks the LLM to correct an output that breaks contracts
+func contractPrompt(original, output string, violations []ContractViolation) string {
+	var rules strings.Builder
+	for _, v := range violations {
+		desc := v.Description
+		if desc == "" {
+			desc = fmt.Sprintf("Follow the %s contract for %s output.", v.Contract, v.Capability)
+		}
+		fmt.Fprintf(&rules, "- %s\n", desc)
+	}
+	return fmt.Sprintf(`%s
+
+Your previous answer was:
+
+%s
+
+It doesn't meet these requirements:
+
+%s
+Give your complete answer again, meeting them.`,
+		original, output, rules.String())
+}
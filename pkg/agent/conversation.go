@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// DefaultContextTokens is the context window conversations are fitted into
+// when the agent doesn't set one
+const DefaultContextTokens = 32000
+
+// defaultResponseTokens is the room kept for the response when a
+// conversation doesn't limit it
+const defaultResponseTokens = 4096
+
+// Conversation is a multi-turn exchange between an agent and its LLM. Each
+// Send adds to the history, so later turns see what came before. The system
+// prompt carries the agent's identity and its short-term memory; when the
+// history outgrows the context window the oldest turns after the first are
+// left out of requests, keeping the task statement and the latest exchanges.
+type Conversation struct {
+	mu sync.Mutex
+
+	agent       *Agent
+	model       string
+	system      string
+	messages    []llm.Message
+	maxTokens   int      // Limit on each response (0 = provider default)
+	temperature *float64 // Sampling temperature (nil = provider default)
+	reasoning   *llm.ReasoningConfig
+	used        int // Tokens used by all turns
+}
+
+// NewConversation starts a conversation for a task. The task's model, system
+// prompt, temperature and MaxTokens override the agent's, and its complexity
+// picks the reasoning budget.
+func (a *Agent) NewConversation(task *Task) *Conversation {
+	instructions, temperature, maxTokens := a.requestSettings(task)
+	c := &Conversation{
+		agent:       a,
+		model:       a.requestModel(task),
+		system:      a.systemPrompt(instructions),
+		maxTokens:   maxTokens,
+		temperature: temperature,
+	}
+	if task != nil {
+		c.reasoning = a.Reasoning.For(task.Complexity)
+	}
+	return c
+}
+
+// systemPrompt gives the role instructions, introduces the agent and lists
+// what it has in short-term memory, which takes at most a quarter of the
+// context window
+func (a *Agent) systemPrompt(instructions string) string {
+	var b strings.Builder
+	if instructions != "" {
+		b.WriteString(instructions)
+		b.WriteString("\n\n")
+	}
+	fmt.Fprintf(&b, "You are a squaremind AI agent with the following identity:\nName: %s\nSID: %s\nCapabilities: %s",
+		a.Identity.Name, a.Identity.SID, capabilitySummary(a.Capabilities))
+
+	memory := a.Memory.Entries()
+	if len(memory) == 0 {
+		return b.String()
+	}
+	keys := make([]string, 0, len(memory))
+	for k := range memory {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	budget := a.contextTokens() / 4
+	b.WriteString("\n\nContext from your memory:")
+	for _, k := range keys {
+		entry := fmt.Sprintf("\n- %s: %v", k, memory[k])
+		if budget -= estimateTokens(entry); budget < 0 {
+			break
+		}
+		b.WriteString(entry)
+	}
+	return b.String()
+}
+
+// contextTokens returns the agent's context window, applying the default
+func (a *Agent) contextTokens() int {
+	if a.ContextTokens <= 0 {
+		return DefaultContextTokens
+	}
+	return a.ContextTokens
+}
+
+// Send adds a user turn, asks the LLM to answer it in the light of the
+// history, and records and returns the answer. A failed turn is removed.
+func (c *Conversation) Send(ctx context.Context, content string) (*llm.CompletionResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.messages = append(c.messages, llm.Message{Role: "user", Content: content})
+	window := c.windowLocked()
+
+	a := c.agent
+	provider := a.provider(ctx)
+	var (
+		response *llm.CompletionResponse
+		err      error
+	)
+	if chat, ok := provider.(llm.ChatProvider); ok {
+		messages := append([]llm.Message{{Role: "system", Content: c.system}}, window...)
+		response, err = chat.Chat(ctx, llm.ChatRequest{
+			Model:       c.model,
+			Messages:    messages,
+			MaxTokens:   c.maxTokens,
+			Temperature: c.temperature,
+			Reasoning:   c.reasoning,
+		})
+	} else {
+		response, err = provider.Complete(ctx, llm.CompletionRequest{
+			Model:       c.model,
+			System:      c.system,
+			Prompt:      transcript(window),
+			MaxTokens:   c.maxTokens,
+			Temperature: c.temperature,
+			Reasoning:   c.reasoning,
+		})
+	}
+	if response != nil {
+		a.recordUsage(response)
+		c.used += response.TokensUsed
+	}
+	if err != nil {
+		c.messages = c.messages[:len(c.messages)-1]
+		return response, err
+	}
+
+	c.messages = append(c.messages, llm.Message{Role: "assistant", Content: response.Content})
+	return response, nil
+}
+
+// windowLocked returns the turns that fit the context window: the first
+// exchange, which states the task, then as many of the latest exchanges as
+// fit. Exchanges are dropped whole so turns keep alternating. Caller must
+// hold c.mu.
+func (c *Conversation) windowLocked() []llm.Message {
+	reserve := c.maxTokens
+	if reserve <= 0 {
+		reserve = defaultResponseTokens
+	}
+	budget := c.agent.contextTokens() - reserve - estimateTokens(c.system)
+
+	msgs := c.messages
+	total := 0
+	for _, m := range msgs {
+		total += estimateTokens(m.Content)
+	}
+	if total <= budget || len(msgs) <= 3 {
+		return append([]llm.Message(nil), msgs...)
+	}
+
+	// msgs is user/assistant pairs followed by the pending user turn
+	head, pending := msgs[:2], msgs[len(msgs)-1]
+	budget -= estimateTokens(head[0].Content) + estimateTokens(head[1].Content) + estimateTokens(pending.Content)
+
+	keep := len(msgs) - 1
+	for i := len(msgs) - 3; i >= 2; i -= 2 {
+		cost := estimateTokens(msgs[i].Content) + estimateTokens(msgs[i+1].Content)
+		if cost > budget {
+			break
+		}
+		budget -= cost
+		keep = i
+	}
+
+	window := append([]llm.Message(nil), head...)
+	window = append(window, msgs[keep:len(msgs)-1]...)
+	return append(window, pending)
+}
+
+// History returns every turn of the conversation, including any left out of
+// request describes the conversation's settings as a single request with
+// prompt, for execution records
+func (c *Conversation) request(prompt string) llm.CompletionRequest {
+	return llm.CompletionRequest{Model: c.model, System: c.system, Prompt: prompt, MaxTokens: c.maxTokens, Temperature: c.temperature}
+}
+
+// requests to fit the context window
+func (c *Conversation) History() []llm.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]llm.Message(nil), c.messages...)
+}
+
+// Window returns the turns the next request would include
+func (c *Conversation) Window() []llm.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.windowLocked()
+}
+
+// TokensUsed returns the tokens used by all turns so far
+func (c *Conversation) TokensUsed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used
+}
+
+// transcript flattens turns into a prompt for providers without chat
+func transcript(messages []llm.Message) string {
+	var b strings.Builder
+	for _, m := range messages {
+		role := "User"
+		if m.Role == "assistant" {
+			role = "Assistant"
+		}
+		fmt.Fprintf(&b, "%s: %s\n\n", role, m.Content)
+	}
+	b.WriteString("Assistant:")
+	return b.String()
+}
+
+// estimateTokens approximates the tokens in text at about 4 characters each
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// performSteps works through a multi-step task as a conversation: the task
+// and its first step open it, each further step is a new turn, and the
+// answer to the last step is the result. Each answer is checkpointed.
+func (a *Agent) performSteps(ctx context.Context, task *Task, progress *Progress) (*TaskResult, error) {
+	conv := a.NewConversation(task)
+	result := &TaskResult{TaskID: task.ID}
+
+	var prompts []string
+	for i, step := range task.Steps {
+		content := fmt.Sprintf("Step %d of %d: %s", i+1, len(task.Steps), step)
+		if i == 0 {
+			content = fmt.Sprintf("Your task:\n%s\n\nRequirements:\n%s\n\nWork through it step by step; each message gives the next step.\n\n%s",
+				task.Description, task.Requirements, content)
+		}
+		prompts = append(prompts, content)
+
+		response, err := conv.Send(ctx, content)
+		if response != nil {
+			result.TokensUsed += response.TokensUsed
+			result.ThinkingTokens += response.ThinkingTokens
+		}
+		if err != nil {
+			result.Status = TaskFailed
+			result.Error = fmt.Sprintf("step %d: %v", i+1, err)
+			progress.Salvage(result)
+			a.recordExecution(task, conv.request(strings.Join(prompts, "\n\n")), result)
+			return result, err
+		}
+
+		if i > 0 {
+			progress.AppendOutput("\n\n")
+		}
+		progress.AppendOutput(response.Content)
+		progress.Checkpoint(fmt.Sprintf("step %d", i+1), response.Content)
+		result.Output = response.Content
+	}
+
+	result.Status = TaskCompleted
+	result.Quality = 0.8 // Would be evaluated by quality assessment
+	a.runIntegrations(ctx, task, result, progress)
+	a.recordExecution(task, conv.request(strings.Join(prompts, "\n\n")), result)
+	a.reportQuality(ctx, result)
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package agent
+
+import "errors"
+
+// ErrDeadlineExceeded is the error of a result whose task was cancelled at
+// its deadline
+var ErrDeadlineExceeded = errors.New("task deadline exceeded")
+
+// Expire cancels a task that has run past its deadline. If the agent is
+// running it, the LLM call is cancelled and the result, marked Expired,
+// fails with ErrDeadlineExceeded. A task still queued is dropped when the
+// agent reaches it. Returns whether the agent was running taskID.
+func (a *Agent) Expire(taskID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if i := a.slotOfLocked(taskID); i >= 0 && a.slots[i].cancel != nil {
+		a.slots[i].expiring = true
+		a.slots[i].cancel()
+		return true
+	}
+	if a.expired == nil {
+		a.expired = make(map[string]bool)
+	}
+	a.expired[taskID] = true
+	return false
+}
+
+// dropExpired reports whether a queued task was expired before the agent
+// reached it, forgetting the expiry
+func (a *Agent) dropExpired(taskID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !a.expired[taskID] {
+		return false
+	}
+	delete(a.expired, taskID)
+	return true
+}
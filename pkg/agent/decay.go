@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DecayCurve is how an idle agent's reputation decays
+type DecayCurve string
+
+const (
+	DecayLinear      DecayCurve = "linear"      // Loses DecayRate of the score per idle day
+	DecayExponential DecayCurve = "exponential" // Keeps 1-DecayRate of what is left per idle day
+	DecayNone        DecayCurve = "none"        // Never decays
+)
+
+// Decay bounds
+const (
+	DecayGrace = 24 * time.Hour // Idle time before decay starts
+	DecayFloor = 0.5            // Share of the score decay never takes away
+)
+
+// ParseDecayCurve parses a decay curve name; empty means linear
+func ParseDecayCurve(s string) (DecayCurve, error) {
+	switch c := DecayCurve(s); c {
+	case "":
+		return DecayLinear, nil
+	case DecayLinear, DecayExponential, DecayNone:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown decay curve %q (want linear, exponential or none)", s)
+}
+
+// SetDecay sets how the reputation decays from the next time Overall is
+// computed
+func (r *Reputation) SetDecay(curve DecayCurve, rate float64) {
+	r.DecayCurve = curve
+	r.DecayRate = rate
+}
+
+// DecayFactor returns the share of the score left at now after the idle
+// time since LastActive, less DecayGrace. It depends only on that time, so
+// the score is the same however often decay is applied.
+func (r *Reputation) DecayFactor(now time.Time) float64 {
+	if r.DecayRate <= 0 || r.LastActive.IsZero() || r.DecayCurve == DecayNone {
+		return 1
+	}
+	days := (now.Sub(r.LastActive) - DecayGrace).Hours() / 24
+	if days <= 0 {
+		return 1
+	}
+
+	var factor float64
+	switch r.DecayCurve {
+	case DecayExponential:
+		factor = math.Pow(1-math.Min(r.DecayRate, 1), days)
+	default:
+		factor = 1 - r.DecayRate*days
+	}
+	return math.Max(factor, DecayFloor)
+}
+
+// ApplyDecay recomputes Overall for the time the agent has been idle. An
+// agent within DecayGrace of its last activity is left as it is.
+func (r *Reputation) ApplyDecay() {
+	if r.DecayFactor(time.Now()) < 1 {
+		r.recalculateOverall()
+	}
+}
@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// Delegate issues a proof letting another agent bid on tasks requiring a
+// capability this agent holds, valid for duration
+func (a *Agent) Delegate(delegateSID string, capability identity.CapabilityType, duration time.Duration) (*identity.DelegationProof, error) {
+	if !a.Capabilities.Has(capability) {
+		return nil, fmt.Errorf("%w: %s does not hold %s", identity.ErrInvalidDelegation, a.Identity.SID, capability)
+	}
+	return identity.NewDelegationProof(a.Identity, delegateSID, capability, duration)
+}
+
+// AddDelegation gives the agent a delegation proof to present when bidding.
+// The market verifies it; here it is only checked to be issued to this
+// agent and unexpired.
+func (a *Agent) AddDelegation(proof *identity.DelegationProof) error {
+	if proof.DelegateSID != a.Identity.SID {
+		return fmt.Errorf("%w: issued to %s", identity.ErrInvalidDelegation, proof.DelegateSID)
+	}
+	if !proof.IsValid() {
+		return fmt.Errorf("%w: expired at %s", identity.ErrInvalidDelegation, proof.ExpiresAt.Format(time.RFC3339))
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.delegations = append(a.delegations, proof)
+	return nil
+}
+
+// Delegations returns the agent's unexpired delegation proofs, dropping
+// expired ones
+func (a *Agent) Delegations() []*identity.DelegationProof {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	valid := a.delegations[:0]
+	for _, proof := range a.delegations {
+		if proof.IsValid() {
+			valid = append(valid, proof)
+		}
+	}
+	a.delegations = valid
+	return append([]*identity.DelegationProof(nil), valid...)
+}
@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"github.com/square-mind/squaremind/pkg/schema"
+)
+
+// Schema versions of the JSON encodings of agent types. Bump one when a
+// change to its type needs old encodings upgraded on decode; adding fields
+// doesn't, as missing fields take defaults and unknown ones are kept.
+const (
+	TaskSchemaVersion       = 1
+	TaskResultSchemaVersion = 1
+	ReputationSchemaVersion = 1
+)
+
+// MarshalJSON encodes the task with its schema version
+func (t Task) MarshalJSON() ([]byte, error) {
+	type plain Task
+	return schema.Encode(plain(t), TaskSchemaVersion, t.extra)
+}
+
+// UnmarshalJSON decodes a task written by any release. Fields the encoding
+// lacks take the defaults of NewTask.
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	p := plain{
+		Status:     TaskPending,
+		Complexity: "medium",
+		Priority:   PriorityNormal,
+	}
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*t = Task(p)
+	t.extra = extra
+	return nil
+}
+
+// MarshalJSON encodes the result with its schema version
+func (r TaskResult) MarshalJSON() ([]byte, error) {
+	type plain TaskResult
+	return schema.Encode(plain(r), TaskResultSchemaVersion, r.extra)
+}
+
+// UnmarshalJSON decodes a result written by any release
+func (r *TaskResult) UnmarshalJSON(data []byte) error {
+	type plain TaskResult
+	var p plain
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*r = TaskResult(p)
+	r.extra = extra
+	return nil
+}
+
+// MarshalJSON encodes the reputation with its schema version
+func (r Reputation) MarshalJSON() ([]byte, error) {
+	type plain Reputation
+	return schema.Encode(plain(r), ReputationSchemaVersion, r.extra)
+}
+
+// UnmarshalJSON decodes a reputation written by any release. Dimensions the
+// encoding lacks start at the baseline of NewReputation.
+func (r *Reputation) UnmarshalJSON(data []byte) error {
+	type plain Reputation
+	p := plain(*NewReputation())
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*r = Reputation(p)
+	r.extra = extra
+	return nil
+}
@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// ExecutionRecord captures a single LLM task execution: what the agent was asked,
+// what it produced and how the output was judged
+type ExecutionRecord struct {
+	TaskID       string                    `json:"task_id"`
+	AgentSID     string                    `json:"agent_sid"`
+	Model        string                    `json:"model,omitempty"`
+	Capabilities []identity.CapabilityType `json:"capabilities,omitempty"` // Capabilities the task required
+	System       string                    `json:"system,omitempty"`
+	Prompt       string                    `json:"prompt"`
+	Context      string                    `json:"context,omitempty"` // Task requirements and other supplied context
+	MaxTokens    int                       `json:"max_tokens,omitempty"`
+	Temperature  *float64                  `json:"temperature,omitempty"`
+	Output       string                    `json:"output"`
+	Error        string                    `json:"error,omitempty"`
+	Status       TaskStatus                `json:"status"`
+	Quality      float64                   `json:"quality"`
+	TokensUsed   int                       `json:"tokens_used,omitempty"`
+	Critique     string                    `json:"critique,omitempty"` // Reviewer feedback, if any
+	Timestamp    time.Time                 `json:"timestamp"`
+}
+
+// ExecutionRecorder receives execution records as agents complete tasks
+type ExecutionRecorder interface {
+	Record(record ExecutionRecord)
+}
+
+// recordExecution forwards an execution of req to the agent's recorder, if any
+func (a *Agent) recordExecution(task *Task, req llm.CompletionRequest, result *TaskResult) {
+	if a.Recorder == nil {
+		return
+	}
+
+	a.Recorder.Record(ExecutionRecord{
+		TaskID:       task.ID,
+		AgentSID:     a.Identity.SID,
+		Model:        req.Model,
+		Capabilities: task.Required,
+		System:       req.System,
+		Prompt:       req.Prompt,
+		Context:      task.Requirements,
+		MaxTokens:    req.MaxTokens,
+		Temperature:  req.Temperature,
+		Output:       result.Output,
+		Error:        result.Error,
+		Status:       result.Status,
+		Quality:      result.Quality,
+		TokensUsed:   result.TokensUsed,
+		Timestamp:    time.Now(),
+	})
+}
+
+// reportQuality passes a completed result's quality to the provider that
+// served the task, for providers that act on it, like a candidate slot
+func (a *Agent) reportQuality(ctx context.Context, result *TaskResult) {
+	llm.ReportQuality(a.provider(ctx), result.Quality)
+}
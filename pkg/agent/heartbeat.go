@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultHeartbeatInterval is how often a running agent reports its liveness
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// Heartbeat is an agent's periodic liveness report. A working agent includes
+// the task it is on and when it started, so a monitor can tell a busy agent
+// from a stuck one; an agent with several slots lists every task running.
+type Heartbeat struct {
+	AgentSID    string       `json:"agent_sid"`
+	State       AgentState   `json:"state"`
+	TaskID      string       `json:"task_id,omitempty"`
+	TaskStarted time.Time    `json:"task_started,omitempty"`
+	Slots       int          `json:"slots,omitempty"` // Tasks the agent works on at once (0 = 1)
+	Tasks       []ActiveTask `json:"tasks,omitempty"` // Tasks running, by slot, when the agent has several
+	Timestamp   time.Time    `json:"timestamp"`
+}
+
+// Running returns the tasks the heartbeat reports running
+func (hb Heartbeat) Running() []ActiveTask {
+	if len(hb.Tasks) > 0 || hb.TaskID == "" {
+		return hb.Tasks
+	}
+	return []ActiveTask{{TaskID: hb.TaskID, Started: hb.TaskStarted}}
+}
+
+// SetHeartbeatInterval sets how often the agent reports its liveness (0 =
+// DefaultHeartbeatInterval). It takes effect after the next heartbeat.
+func (a *Agent) SetHeartbeatInterval(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.heartbeatInterval = d
+}
+
+// OnHeartbeat registers a callback invoked with each of the agent's heartbeats
+func (a *Agent) OnHeartbeat(handler func(Heartbeat)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.onHeartbeat = append(a.onHeartbeat, handler)
+}
+
+// Heartbeat returns the agent's current liveness report
+func (a *Agent) Heartbeat() Heartbeat {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.heartbeatLocked()
+}
+
+// heartbeatLocked builds a heartbeat. Caller must hold a.mu.
+func (a *Agent) heartbeatLocked() Heartbeat {
+	hb := Heartbeat{
+		AgentSID:  a.Identity.SID,
+		State:     a.State,
+		Timestamp: time.Now(),
+	}
+	active := a.activeTasksLocked()
+	if len(active) > 0 {
+		hb.TaskID = active[0].TaskID
+		hb.TaskStarted = active[0].Started
+	}
+	if len(a.slots) > 1 {
+		hb.Slots = len(a.slots)
+		hb.Tasks = active
+	}
+	return hb
+}
+
+// heartbeatLoop reports the agent's liveness until it stops
+func (a *Agent) heartbeatLoop(ctx context.Context) {
+	interval := a.getHeartbeatInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		a.mu.RLock()
+		hb := a.heartbeatLocked()
+		handlers := a.onHeartbeat
+		a.mu.RUnlock()
+
+		for _, h := range handlers {
+			h(hb)
+		}
+		if d := a.getHeartbeatInterval(); d != interval {
+			interval = d
+			ticker.Reset(interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// getHeartbeatInterval returns the configured heartbeat interval or the default
+func (a *Agent) getHeartbeatInterval() time.Duration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.heartbeatInterval <= 0 {
+		return DefaultHeartbeatInterval
+	}
+	return a.heartbeatInterval
+}
+
+// CancelTask cancels the tasks the agent is working on, if any. They fail as
+// if their submitters had given up. Returns false if the agent is idle.
+func (a *Agent) CancelTask() bool {
+	a.mu.RLock()
+	var cancels []context.CancelFunc
+	for _, s := range a.slots {
+		if s.cancel != nil {
+			cancels = append(cancels, s.cancel)
+		}
+	}
+	a.mu.RUnlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels) > 0
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidHints is returned for routing hints with a negative weight
+var ErrInvalidHints = errors.New("invalid routing hints")
+
+// RoutingHints are soft preferences on who runs a task. Unlike Placement
+// they don't bypass the market: agents still bid, and the hints only add a
+// weighted factor to each bid's score. Banned agents may not bid at all.
+// Agents are named by SID, or by a prefix of one as sqm prints them.
+type RoutingHints struct {
+	Prefer []string     `json:"prefer,omitempty"` // Agents whose bids are favoured
+	Ban    []string     `json:"ban,omitempty"`    // Agents that may not bid
+	Near   []Constraint `json:"near,omitempty"`   // Labels favoured bidders run near, e.g. region=eu
+	Weight float64      `json:"weight,omitempty"` // Weight of the hint factor in bid scores (0 = the market's default)
+}
+
+// Validate checks the hints
+func (h *RoutingHints) Validate() error {
+	if h.Weight < 0 {
+		return fmt.Errorf("%w: weight %.2f is negative", ErrInvalidHints, h.Weight)
+	}
+	return nil
+}
+
+// Bans reports whether the hints ban an agent
+func (h *RoutingHints) Bans(sid string) bool {
+	return h != nil && matchesSID(h.Ban, sid)
+}
+
+// Affinity scores how well an agent fits the hints, from 0 to 1: the share
+// of the given hints it meets, counting being preferred as one hint and each
+// locality constraint its labels satisfy as another
+func (h *RoutingHints) Affinity(sid string, labels Labels) float64 {
+	if h == nil {
+		return 0
+	}
+	total, met := 0, 0
+	if len(h.Prefer) > 0 {
+		total++
+		if matchesSID(h.Prefer, sid) {
+			met++
+		}
+	}
+	for _, c := range h.Near {
+		total++
+		if c.Matches(labels) {
+			met++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(met) / float64(total)
+}
+
+// matchesSID reports whether sid is, or starts with, one of the given SIDs
+func matchesSID(sids []string, sid string) bool {
+	for _, s := range sids {
+		if s != "" && strings.HasPrefix(sid, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithHints steers the task toward or away from agents without bypassing bidding
+func (t *Task) WithHints(hints RoutingHints) *Task {
+	if len(hints.Prefer) == 0 && len(hints.Ban) == 0 && len(hints.Near) == 0 {
+		t.Hints = nil
+		return t
+	}
+	t.Hints = &hints
+	return t
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoutingHints_Affinity(t *testing.T) {
+	near, _ := ParseConstraints([]string{"region=eu", "gpu"})
+	h := &RoutingHints{Prefer: []string{"sq-abc"}, Ban: []string{"sq-bad"}, Near: near}
+
+	tests := []struct {
+		sid    string
+		labels Labels
+		want   float64
+	}{
+		{"sq-abcdef", Labels{"region": "eu", "gpu": "true"}, 1},
+		{"sq-abcdef", nil, 1.0 / 3},
+		{"sq-other", Labels{"region": "eu"}, 1.0 / 3},
+		{"sq-other", nil, 0},
+	}
+	for _, tt := range tests {
+		if got := h.Affinity(tt.sid, tt.labels); got != tt.want {
+			t.Errorf("Expected affinity %.2f for %s %s, got %.2f", tt.want, tt.sid, tt.labels, got)
+		}
+	}
+
+	if !h.Bans("sq-badcafe") || h.Bans("sq-abcdef") {
+		t.Error("Expected only the banned prefix to be banned")
+	}
+	var none *RoutingHints
+	if none.Bans("sq-bad") || none.Affinity("sq-abc", nil) != 0 {
+		t.Error("Expected nil hints to neither ban nor favour")
+	}
+	if err := (&RoutingHints{Weight: -1}).Validate(); !errors.Is(err, ErrInvalidHints) {
+		t.Errorf("Expected ErrInvalidHints, got %v", err)
+	}
+}
+
+func TestTask_WithHints(t *testing.T) {
+	task := NewTask("steer me", nil).WithHints(RoutingHints{Weight: 0.5})
+	if task.Hints != nil {
+		t.Error("Expected hints naming no agents or labels to be dropped")
+	}
+
+	task.WithHints(RoutingHints{Prefer: []string{"sq-abc"}})
+	data, err := task.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	var decoded Task
+	if err := decoded.UnmarshalJSON(data); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	if decoded.Hints == nil || decoded.Hints.Prefer[0] != "sq-abc" {
+		t.Errorf("Expected hints to survive encoding, got %+v", decoded.Hints)
+	}
+}
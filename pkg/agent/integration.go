@@ -0,0 +1,32 @@
+package agent
+
+import "context"
+
+// Integration acts on a task's result outside the collective, such as
+// opening a pull request with the code it wrote
+type Integration interface {
+	// Name labels the integration's entries in a result's ToolResults
+	Name() string
+
+	// Handle acts on a completed result. It returns a summary of what it
+	// did (a pull request URL...), or "" if the task isn't one it handles.
+	Handle(ctx context.Context, a *Agent, task *Task, result *TaskResult) (string, error)
+}
+
+// runIntegrations hands a completed result to each of the agent's
+// integrations and records what they did. A failing integration is
+// recorded and logged but doesn't fail the task.
+func (a *Agent) runIntegrations(ctx context.Context, task *Task, result *TaskResult, progress *Progress) {
+	for _, in := range a.Integrations {
+		summary, err := in.Handle(ctx, a, task, result)
+		if err != nil {
+			a.log().Warn("integration failed", "integration", in.Name(), "task", task.ID, "error", err)
+			a.recordToolResult(result, progress, ToolResult{Tool: in.Name(), Output: summary, Error: err.Error()})
+			continue
+		}
+		if summary != "" {
+			a.log().Info("integration ran", "integration", in.Name(), "task", task.ID, "summary", summary)
+			a.recordToolResult(result, progress, ToolResult{Tool: in.Name(), Output: summary})
+		}
+	}
+}
@@ -0,0 +1,424 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+var (
+	ErrInvalidCredential = errors.New("invalid credential")
+	ErrMissingKey        = errors.New("credential has no key")
+)
+
+// CredentialKind is what a keyring credential authenticates with
+type CredentialKind string
+
+const (
+	CredentialAnthropic CredentialKind = "anthropic" // An Anthropic API key
+	CredentialOpenAI    CredentialKind = "openai"    // An OpenAI API key
+	CredentialTool      CredentialKind = "tool"      // A secret handed to sandboxed programs
+)
+
+// Credential is a provider key or tool secret, bound to the tasks it is used
+// for. A credential with capabilities applies to tasks requiring one of them,
+// one with teams to tasks routed to one of them, and one with neither to any
+// task.
+type Credential struct {
+	Name         string                    `json:"name" yaml:"name"`
+	Kind         CredentialKind            `json:"kind" yaml:"kind"`
+	Key          string                    `json:"-" yaml:"key,omitempty"`                     // The secret
+	KeyEnv       string                    `json:"key_env,omitempty" yaml:"key_env,omitempty"` // Or the environment variable holding it, read at execution time
+	BaseURL      string                    `json:"base_url,omitempty" yaml:"base_url,omitempty"`
+	Env          string                    `json:"env,omitempty" yaml:"env,omitempty"` // Tool credentials: the variable programs read it from
+	Capabilities []identity.CapabilityType `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+	Teams        []string                  `json:"teams,omitempty" yaml:"teams,omitempty"`
+
+	// Provider is used for the credential instead of one built from its key
+	Provider llm.Provider `json:"-" yaml:"-"`
+}
+
+// validate checks a credential can be resolved
+func (c *Credential) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: no name", ErrInvalidCredential)
+	}
+	switch c.Kind {
+	case CredentialAnthropic, CredentialOpenAI:
+	case CredentialTool:
+		if c.Env == "" {
+			return fmt.Errorf("%w: tool credential %s names no env variable", ErrInvalidCredential, c.Name)
+		}
+	default:
+		return fmt.Errorf("%w: %s has unknown kind %q (want anthropic, openai or tool)", ErrInvalidCredential, c.Name, c.Kind)
+	}
+	return nil
+}
+
+// match reports whether the credential applies to a task and how specific
+// the binding is: team bindings outrank capability bindings, which outrank
+// unscoped credentials
+func (c *Credential) match(task *Task) (int, bool) {
+	score := 0
+	if len(c.Capabilities) > 0 {
+		found := false
+		for _, capability := range c.Capabilities {
+			for _, required := range task.Required {
+				if capability == required {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		score++
+	}
+	if len(c.Teams) > 0 {
+		found := false
+		for _, team := range c.Teams {
+			if team == task.Team {
+				found = true
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		score += 2
+	}
+	return score, true
+}
+
+// secret returns the credential's key, reading KeyEnv if it has none
+func (c *Credential) secret() string {
+	if c.Key != "" {
+		return c.Key
+	}
+	if c.KeyEnv != "" {
+		return os.Getenv(c.KeyEnv)
+	}
+	return ""
+}
+
+// KeyUsage is what a credential has been used for
+type KeyUsage struct {
+	Name           string         `json:"name"`
+	Kind           CredentialKind `json:"kind"`
+	Tasks          int            `json:"tasks"`    // Tasks that executed with it
+	Requests       int            `json:"requests"` // LLM requests, or sandbox runs for tool credentials
+	Errors         int            `json:"errors"`
+	TokensUsed     int            `json:"tokens_used"`
+	ThinkingTokens int            `json:"thinking_tokens"`
+	LastUsed       time.Time      `json:"last_used,omitempty"`
+}
+
+// Keyring holds the credentials tasks execute with and accounts for their
+// use. Keys are resolved when a task starts, so a key read from the
+// environment can be rotated without restarting.
+type Keyring struct {
+	mu sync.Mutex
+
+	credentials []Credential
+	providers   map[string]llm.Provider // Built from a key, by credential name
+	keys        map[string]string       // The key each provider was built with
+	usage       map[string]*KeyUsage
+}
+
+// NewKeyring creates a keyring of credentials; earlier ones win ties
+// between equally specific bindings
+func NewKeyring(credentials ...Credential) (*Keyring, error) {
+	k := &Keyring{
+		providers: make(map[string]llm.Provider),
+		keys:      make(map[string]string),
+		usage:     make(map[string]*KeyUsage),
+	}
+	for _, c := range credentials {
+		if err := c.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := k.usage[c.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate name %s", ErrInvalidCredential, c.Name)
+		}
+		k.credentials = append(k.credentials, c)
+		k.usage[c.Name] = &KeyUsage{Name: c.Name, Kind: c.Kind}
+	}
+	return k, nil
+}
+
+// Credentials returns the keyring's credentials without their keys
+func (k *Keyring) Credentials() []Credential {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	out := make([]Credential, len(k.credentials))
+	for i, c := range k.credentials {
+		c.Key = ""
+		c.Provider = nil
+		out[i] = c
+	}
+	return out
+}
+
+// Secrets returns the keys held in the keyring, for redaction
+func (k *Keyring) Secrets() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var secrets []string
+	for _, c := range k.credentials {
+		if s := c.secret(); s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
+// Usage returns each credential's usage, by name
+func (k *Keyring) Usage() []KeyUsage {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	out := make([]KeyUsage, 0, len(k.usage))
+	for _, u := range k.usage {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// KeyBinding is the credentials a task executes with
+type KeyBinding struct {
+	Credential string            // Provider credential (empty = the agent's own provider)
+	Provider   llm.Provider      // Metered provider of Credential
+	Tools      []string          // Tool credentials
+	Env        map[string]string // Tool credential keys by variable
+
+	keyring *Keyring
+}
+
+// Resolve binds a task to the most specific provider credential that applies
+// to it and, for each tool variable, the most specific tool credential. A
+// task bound to a credential whose key is missing fails rather than falling
+// back to another key.
+func (k *Keyring) Resolve(task *Task) (*KeyBinding, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	b := &KeyBinding{keyring: k}
+	var provider *Credential
+	best := -1
+	tools := make(map[string]*Credential)
+	toolScores := make(map[string]int)
+	for i := range k.credentials {
+		c := &k.credentials[i]
+		score, ok := c.match(task)
+		if !ok {
+			continue
+		}
+		if c.Kind == CredentialTool {
+			if prev, ok := toolScores[c.Env]; !ok || score > prev {
+				tools[c.Env], toolScores[c.Env] = c, score
+			}
+			continue
+		}
+		if score > best {
+			provider, best = c, score
+		}
+	}
+
+	now := time.Now()
+	if provider != nil {
+		p, err := k.providerLocked(provider)
+		if err != nil {
+			return nil, err
+		}
+		b.Credential, b.Provider = provider.Name, p
+		k.usage[provider.Name].Tasks++
+		k.usage[provider.Name].LastUsed = now
+	}
+	for env, c := range tools {
+		secret := c.secret()
+		if secret == "" {
+			return nil, fmt.Errorf("%w: %s", ErrMissingKey, c.Name)
+		}
+		if b.Env == nil {
+			b.Env = make(map[string]string)
+		}
+		b.Env[env] = secret
+		b.Tools = append(b.Tools, c.Name)
+		k.usage[c.Name].Tasks++
+		k.usage[c.Name].LastUsed = now
+	}
+	sort.Strings(b.Tools)
+	return b, nil
+}
+
+// providerLocked returns the metered provider of a credential, building it
+// again if its key changed
+func (k *Keyring) providerLocked(c *Credential) (llm.Provider, error) {
+	if c.Provider != nil {
+		if p, ok := k.providers[c.Name]; ok {
+			return p, nil
+		}
+		p := k.meter(c.Name, c.Provider)
+		k.providers[c.Name] = p
+		return p, nil
+	}
+
+	secret := c.secret()
+	if secret == "" {
+		return nil, fmt.Errorf("%w: %s", ErrMissingKey, c.Name)
+	}
+	if p, ok := k.providers[c.Name]; ok && k.keys[c.Name] == secret {
+		return p, nil
+	}
+
+	var inner llm.Provider
+	if c.Kind == CredentialOpenAI {
+		openai := llm.NewOpenAIProvider(secret)
+		if c.BaseURL != "" {
+			openai.WithBaseURL(c.BaseURL)
+		}
+		inner = openai
+	} else {
+		claude := llm.NewClaudeProvider(secret)
+		if c.BaseURL != "" {
+			claude.WithBaseURL(c.BaseURL)
+		}
+		inner = claude
+	}
+	p := k.meter(c.Name, inner)
+	k.providers[c.Name], k.keys[c.Name] = p, secret
+	return p, nil
+}
+
+// record adds a request made with a credential to its usage
+func (k *Keyring) record(name string, response *llm.CompletionResponse, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	u := k.usage[name]
+	if u == nil {
+		return
+	}
+	u.Requests++
+	u.LastUsed = time.Now()
+	if err != nil {
+		u.Errors++
+	}
+	if response != nil {
+		u.TokensUsed += response.TokensUsed
+		u.ThinkingTokens += response.ThinkingTokens
+	}
+}
+
+// recordRun adds a sandbox run with the binding's tool credentials to their
+// usage
+func (b *KeyBinding) recordRun(err error) {
+	if b == nil || b.keyring == nil {
+		return
+	}
+	for _, name := range b.Tools {
+		b.keyring.record(name, nil, err)
+	}
+}
+
+// meter wraps a provider to account its requests to a credential
+func (k *Keyring) meter(name string, p llm.Provider) llm.Provider {
+	m := &meteredProvider{Provider: p, keyring: k, credential: name}
+	if _, ok := p.(llm.ChatProvider); ok {
+		return &meteredChatProvider{m}
+	}
+	return m
+}
+
+// meteredProvider accounts each request to a keyring credential
+type meteredProvider struct {
+	llm.Provider
+	keyring    *Keyring
+	credential string
+}
+
+func (m *meteredProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	response, err := m.Provider.Complete(ctx, req)
+	m.keyring.record(m.credential, response, err)
+	return response, err
+}
+
+// Stream streams if the provider can and completes otherwise
+func (m *meteredProvider) Stream(ctx context.Context, req llm.CompletionRequest, onDelta func(string)) (*llm.CompletionResponse, error) {
+	streamer, ok := m.Provider.(llm.StreamingProvider)
+	if !ok {
+		return m.Complete(ctx, req)
+	}
+	response, err := streamer.Stream(ctx, req, onDelta)
+	m.keyring.record(m.credential, response, err)
+	return response, err
+}
+
+// meteredChatProvider is a metered provider of multi-turn chats
+type meteredChatProvider struct {
+	*meteredProvider
+}
+
+func (m *meteredChatProvider) Chat(ctx context.Context, req llm.ChatRequest) (*llm.CompletionResponse, error) {
+	response, err := m.Provider.(llm.ChatProvider).Chat(ctx, req)
+	m.keyring.record(m.credential, response, err)
+	return response, err
+}
+
+type bindingKey struct{}
+
+// contextWithBinding returns a context carrying a task's key binding
+func contextWithBinding(ctx context.Context, b *KeyBinding) context.Context {
+	return context.WithValue(ctx, bindingKey{}, b)
+}
+
+// bindingFromContext returns the key binding carried by ctx, or nil
+func bindingFromContext(ctx context.Context) *KeyBinding {
+	b, _ := ctx.Value(bindingKey{}).(*KeyBinding)
+	return b
+}
+
+// SetKeyring sets the keyring tasks resolve their credentials from (nil
+// disables)
+func (a *Agent) SetKeyring(k *Keyring) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keyring = k
+}
+
+// Keyring returns the keyring the agent's tasks resolve credentials from
+func (a *Agent) Keyring() *Keyring {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.keyring
+}
+
+// bindKeys resolves a task's credentials from the agent's keyring into ctx
+func (a *Agent) bindKeys(ctx context.Context, task *Task) (context.Context, error) {
+	k := a.Keyring()
+	if k == nil {
+		return ctx, nil
+	}
+	b, err := k.Resolve(task)
+	if err != nil {
+		return ctx, err
+	}
+	if b.Credential != "" || len(b.Tools) > 0 {
+		a.log().Debug("task bound to credentials", "task", task.ID, "credential", b.Credential, "tools", b.Tools)
+	}
+	return contextWithBinding(ctx, b), nil
+}
+
+// provider returns the provider requests made under ctx go to: the one bound
+// to the task if any, the agent's own otherwise
+func (a *Agent) provider(ctx context.Context) llm.Provider {
+	if b := bindingFromContext(ctx); b != nil && b.Provider != nil {
+		return b.Provider
+	}
+	return a.Provider
+}
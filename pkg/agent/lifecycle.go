@@ -41,6 +41,7 @@ func (lm *LifecycleManager) Spawn(ctx context.Context, name string, capabilities
 		Capabilities: capabilities,
 		Provider:     lm.provider,
 		Model:        lm.model,
+		Labels:       lm.runtime.Labels(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
@@ -74,6 +75,7 @@ func (lm *LifecycleManager) SpawnChild(ctx context.Context, parent *Agent, name
 		Capabilities: capabilities,
 		Provider:     lm.provider,
 		Model:        lm.model,
+		Labels:       lm.runtime.Labels(),
 		ParentSID:    parent.Identity.SID,
 	})
 	if err != nil {
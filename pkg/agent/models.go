@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// ModelMap assigns LLM models to capabilities, so tasks needing routine
+// capabilities run on cheap models and only those needing hard ones on
+// frontier models
+type ModelMap map[identity.CapabilityType]string
+
+// ParseModelMap parses "capability=model" pairs
+func ParseModelMap(pairs []string) (ModelMap, error) {
+	models := make(ModelMap, len(pairs))
+	for _, pair := range pairs {
+		capType, model, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || capType == "" || model == "" {
+			return nil, fmt.Errorf("model assignment %q must be capability=model", pair)
+		}
+		models[identity.CapabilityType(capType)] = model
+	}
+	return models, nil
+}
+
+// For returns the model of the first of the capabilities that has one, or
+// "" if none does
+func (m ModelMap) For(caps []identity.CapabilityType) string {
+	for _, capType := range caps {
+		if model := m[capType]; model != "" {
+			return model
+		}
+	}
+	return ""
+}
+
+// String formats the map as sorted capability=model pairs
+func (m ModelMap) String() string {
+	pairs := make([]string, 0, len(m))
+	for capType, model := range m {
+		pairs = append(pairs, string(capType)+"="+model)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// SetDefaultModels sets the models of capabilities the agent doesn't map
+// itself, such as its collective's (nil clears)
+func (a *Agent) SetDefaultModels(m ModelMap) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.defaultModels = m
+}
+
+// requestModel returns the model of the LLM requests for a task: the task's
+// if it names one, then the model the agent maps its first mapped required
+// capability to, then its default models', and the agent's own otherwise
+func (a *Agent) requestModel(task *Task) string {
+	if task == nil {
+		return a.Model
+	}
+	if task.Model != "" {
+		return task.Model
+	}
+	if model := a.Models.For(task.Required); model != "" {
+		return model
+	}
+	a.mu.RLock()
+	defaults := a.defaultModels
+	a.mu.RUnlock()
+	if model := defaults.For(task.Required); model != "" {
+		return model
+	}
+	return a.Model
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrQueueFull       = errors.New("queue full")
+	ErrInvalidOverflow = errors.New("invalid overflow policy")
+)
+
+// DefaultQueueCapacity is how many tasks, and results, an agent buffers
+const DefaultQueueCapacity = 10
+
+// OverflowPolicy is what happens to a task or result sent to a full channel
+type OverflowPolicy string
+
+const (
+	OverflowError      OverflowPolicy = "error"       // Refuse it with ErrQueueFull (default)
+	OverflowBlock      OverflowPolicy = "block"       // Wait for room until the task's context ends or the agent stops
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // Evict the oldest queued one to make room
+)
+
+// Validate checks the policy is known; empty is OverflowError
+func (p OverflowPolicy) Validate() error {
+	switch p {
+	case "", OverflowError, OverflowBlock, OverflowDropOldest:
+		return nil
+	}
+	return fmt.Errorf("%w: %q (want error, block or drop_oldest)", ErrInvalidOverflow, p)
+}
+
+// QueueConfig sizes an agent's task queue and results channel and sets what
+// happens when one is full. Nothing is dropped silently: refused and evicted
+// tasks fail with ErrQueueFull and every loss is counted in QueueStats.
+type QueueConfig struct {
+	TaskCapacity   int            `yaml:"task_capacity,omitempty" json:"task_capacity,omitempty"`     // 0 = DefaultQueueCapacity
+	ResultCapacity int            `yaml:"result_capacity,omitempty" json:"result_capacity,omitempty"` // 0 = DefaultQueueCapacity
+	Overflow       OverflowPolicy `yaml:"overflow,omitempty" json:"overflow,omitempty"`
+}
+
+// Validate checks the capacities and the overflow policy
+func (q QueueConfig) Validate() error {
+	if q.TaskCapacity < 0 || q.ResultCapacity < 0 {
+		return fmt.Errorf("%w: negative capacity", ErrInvalidOverflow)
+	}
+	return q.Overflow.Validate()
+}
+
+// withDefaults fills unset fields
+func (q QueueConfig) withDefaults() QueueConfig {
+	if q.TaskCapacity == 0 {
+		q.TaskCapacity = DefaultQueueCapacity
+	}
+	if q.ResultCapacity == 0 {
+		q.ResultCapacity = DefaultQueueCapacity
+	}
+	if q.Overflow == "" {
+		q.Overflow = OverflowError
+	}
+	return q
+}
+
+// QueueStats reports an agent's queues and what overflowed them
+type QueueStats struct {
+	Overflow       OverflowPolicy `json:"overflow"`
+	QueuedTasks    int            `json:"queued_tasks"`
+	TaskCapacity   int            `json:"task_capacity"`
+	QueuedResults  int            `json:"queued_results"`
+	ResultCapacity int            `json:"result_capacity"`
+	RejectedTasks  int            `json:"rejected_tasks"`  // Refused with ErrQueueFull or while waiting for room
+	DroppedTasks   int            `json:"dropped_tasks"`   // Evicted by newer tasks
+	DroppedResults int            `json:"dropped_results"` // Refused or evicted results
+}
+
+// QueueStats returns the agent's queue depths and overflow counters
+func (a *Agent) QueueStats() QueueStats {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	stats := a.queueStats
+	stats.Overflow = a.queue.Overflow
+	stats.QueuedTasks, stats.TaskCapacity = len(a.taskChan), cap(a.taskChan)
+	stats.QueuedResults, stats.ResultCapacity = len(a.resultChan), cap(a.resultChan)
+	return stats
+}
+
+// countOverflow applies a change to the overflow counters
+func (a *Agent) countOverflow(update func(*QueueStats)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	update(&a.queueStats)
+}
+
+// offer sends v on ch by policy. evict receives from the same channel for
+// OverflowDropOldest; without it the new value is refused instead. Returns
+// the value evicted to make room, if any, even when another sender took the
+// room first and v is refused.
+func offer[T any](ctx context.Context, stop <-chan struct{}, ch chan<- T, evict <-chan T, v T, policy OverflowPolicy) (evicted T, ok bool, err error) {
+	select {
+	case ch <- v:
+		return evicted, false, nil
+	default:
+	}
+
+	switch policy {
+	case OverflowBlock:
+		select {
+		case ch <- v:
+			return evicted, false, nil
+		case <-ctx.Done():
+			return evicted, false, fmt.Errorf("%w: %v", ErrQueueFull, ctx.Err())
+		case <-stop:
+			return evicted, false, ErrAgentTerminated
+		}
+	case OverflowDropOldest:
+		if evict == nil {
+			break
+		}
+		select {
+		case evicted = <-evict:
+			ok = true
+		default:
+		}
+		select {
+		case ch <- v:
+			return evicted, ok, nil
+		default:
+			// Another sender took the room
+		}
+	}
+	return evicted, ok, ErrQueueFull
+}
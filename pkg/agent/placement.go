@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidConstraint is returned for a malformed label or placement constraint
+var ErrInvalidConstraint = errors.New("invalid placement constraint")
+
+// Labels describe where an agent runs, e.g. region=eu, gpu=true,
+// trusted-zone=pci. Agents take the labels of the host runtime they were
+// spawned on; tasks are placed on agents by matching constraints against them.
+type Labels map[string]string
+
+// ParseLabels parses "key=value" pairs
+func ParseLabels(pairs []string) (Labels, error) {
+	labels := make(Labels, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || !validLabelKey(key) || value == "" {
+			return nil, fmt.Errorf("%w: label %q must be key=value", ErrInvalidConstraint, pair)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// String formats the labels as sorted key=value pairs
+func (l Labels) String() string {
+	pairs := make([]string, 0, len(l))
+	for key, value := range l {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ConstraintOp is how a constraint tests a label
+type ConstraintOp string
+
+const (
+	ConstraintIn        ConstraintOp = "="  // Label is one of Values
+	ConstraintNotIn     ConstraintOp = "!=" // Label is missing or none of Values
+	ConstraintExists    ConstraintOp = "exists"
+	ConstraintNotExists ConstraintOp = "!exists"
+)
+
+// Constraint restricts which agents a task may be placed on. It is written
+//
+//	region=eu        region is eu
+//	region=eu|us     region is eu or us
+//	zone!=public     zone is missing or anything but public
+//	gpu              the gpu label is set
+//	!untrusted       the untrusted label is not set
+type Constraint struct {
+	Key    string
+	Op     ConstraintOp
+	Values []string
+}
+
+// ParseConstraint parses a constraint in the form documented on Constraint
+func ParseConstraint(s string) (Constraint, error) {
+	s = strings.TrimSpace(s)
+
+	var c Constraint
+	switch {
+	case strings.Contains(s, "!="):
+		key, values, _ := strings.Cut(s, "!=")
+		c = Constraint{Key: key, Op: ConstraintNotIn, Values: strings.Split(values, "|")}
+	case strings.Contains(s, "="):
+		key, values, _ := strings.Cut(s, "=")
+		c = Constraint{Key: key, Op: ConstraintIn, Values: strings.Split(values, "|")}
+	case strings.HasPrefix(s, "!"):
+		c = Constraint{Key: s[1:], Op: ConstraintNotExists}
+	default:
+		c = Constraint{Key: s, Op: ConstraintExists}
+	}
+
+	if !validLabelKey(c.Key) {
+		return Constraint{}, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+	}
+	for _, v := range c.Values {
+		if v == "" {
+			return Constraint{}, fmt.Errorf("%w: %q has an empty value", ErrInvalidConstraint, s)
+		}
+	}
+	return c, nil
+}
+
+// ParseConstraints parses a list of constraints, all of which must hold
+func ParseConstraints(specs []string) ([]Constraint, error) {
+	constraints := make([]Constraint, 0, len(specs))
+	for _, spec := range specs {
+		c, err := ParseConstraint(spec)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+	return constraints, nil
+}
+
+// Matches reports whether a set of labels satisfies the constraint
+func (c Constraint) Matches(labels Labels) bool {
+	value, ok := labels[c.Key]
+	switch c.Op {
+	case ConstraintExists:
+		return ok
+	case ConstraintNotExists:
+		return !ok
+	case ConstraintIn:
+		return ok && contains(c.Values, value)
+	case ConstraintNotIn:
+		return !ok || !contains(c.Values, value)
+	}
+	return false
+}
+
+// String formats the constraint as ParseConstraint reads it
+func (c Constraint) String() string {
+	switch c.Op {
+	case ConstraintExists:
+		return c.Key
+	case ConstraintNotExists:
+		return "!" + c.Key
+	default:
+		return c.Key + string(c.Op) + strings.Join(c.Values, "|")
+	}
+}
+
+// MarshalText encodes the constraint in its string form
+func (c Constraint) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes a constraint from its string form
+func (c *Constraint) UnmarshalText(text []byte) error {
+	parsed, err := ParseConstraint(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// validLabelKey reports whether key is a usable label name
+func validLabelKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' || r == '/') {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether values includes v
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package agent
+
+import "errors"
+
+// ErrPreempted is the error of a result whose task was stopped for a more
+// urgent one
+var ErrPreempted = errors.New("preempted by a more urgent task")
+
+// PreemptedCheckpoint labels the checkpoint a preempted task resumes from
+const PreemptedCheckpoint = "preempted"
+
+// Preempt stops the agent's current task if it is taskID, so a more urgent
+// task can have the agent. The work produced so far is checkpointed into the
+// task's progress and the agent's short-term memory, and the result is
+// marked Preempted rather than counted as a failure; when the task runs
+// again, its prompt resumes from the checkpoint. Returns false if the agent
+// isn't running taskID.
+func (a *Agent) Preempt(taskID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	i := a.slotOfLocked(taskID)
+	if i < 0 || a.slots[i].cancel == nil {
+		return false
+	}
+	a.slots[i].preempting = true
+	a.slots[i].cancel()
+	return true
+}
+
+// checkpointKey is the short-term memory key of a preempted task's checkpoint
+func checkpointKey(taskID string) string {
+	return "checkpoint:" + taskID
+}
+
+// checkpoint saves the work a preempted task produced: its streamed output,
+// or else the state of its latest checkpoint
+func (a *Agent) checkpoint(task *Task) {
+	progress := task.Progress()
+	state := progress.Output()
+	if state == "" {
+		if last, ok := progress.LastCheckpoint(""); ok {
+			state = last.State
+		}
+	}
+	progress.Checkpoint(PreemptedCheckpoint, state)
+	a.Memory.Store(checkpointKey(task.ID), state)
+}
@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToolResult is the output of a tool invoked while performing a task
+type ToolResult struct {
+	Tool      string    `json:"tool"`
+	Output    string    `json:"output"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Checkpoint is intermediate state saved while performing a task
+type Checkpoint struct {
+	Label     string    `json:"label"`
+	State     string    `json:"state"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Progress accumulates the work produced while a task runs (streamed output,
+// tool results and checkpoints) so it can be salvaged if the task is cut
+// short. It is safe for concurrent use, and methods on a nil Progress are
+// no-ops so producers can report unconditionally.
+type Progress struct {
+	mu sync.Mutex
+
+	output      strings.Builder
+	toolResults []ToolResult
+	checkpoints []Checkpoint
+}
+
+// NewProgress creates an empty progress record
+func NewProgress() *Progress {
+	return &Progress{}
+}
+
+// AppendOutput adds streamed output text
+func (p *Progress) AppendOutput(text string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.output.WriteString(text)
+}
+
+// AddToolResult records a tool's output
+func (p *Progress) AddToolResult(r ToolResult) {
+	if p == nil {
+		return
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.toolResults = append(p.toolResults, r)
+}
+
+// Checkpoint records intermediate state under a label
+func (p *Progress) Checkpoint(label, state string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checkpoints = append(p.checkpoints, Checkpoint{Label: label, State: state, Timestamp: time.Now()})
+}
+
+// LastCheckpoint returns the latest checkpoint with a label, or the latest
+// of all if label is empty
+func (p *Progress) LastCheckpoint(label string) (Checkpoint, bool) {
+	if p == nil {
+		return Checkpoint{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := len(p.checkpoints) - 1; i >= 0; i-- {
+		if label == "" || p.checkpoints[i].Label == label {
+			return p.checkpoints[i], true
+		}
+	}
+	return Checkpoint{}, false
+}
+
+// Output returns the output streamed so far
+func (p *Progress) Output() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.output.String()
+}
+
+// Empty reports whether no work has been recorded
+func (p *Progress) Empty() bool {
+	if p == nil {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.output.Len() == 0 && len(p.toolResults) == 0 && len(p.checkpoints) == 0
+}
+
+// Salvage copies the recorded work into a failed result and marks it partial.
+// Returns false, leaving the result untouched, if nothing was recorded.
+func (p *Progress) Salvage(result *TaskResult) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.output.Len() == 0 && len(p.toolResults) == 0 && len(p.checkpoints) == 0 {
+		return false
+	}
+	result.Partial = true
+	result.Output = p.output.String()
+	result.ToolResults = append([]ToolResult(nil), p.toolResults...)
+	result.Checkpoints = append([]Checkpoint(nil), p.checkpoints...)
+	return true
+}
+
+type progressKey struct{}
+
+// ContextWithProgress returns a context carrying a task's progress record.
+// Agents pass it to their provider so tool runners can report into it.
+func ContextWithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFromContext returns the progress record carried by ctx, or nil
+func ProgressFromContext(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/prompt"
+)
+
+var ErrInvalidTemplate = errors.New("invalid prompt template")
+
+// PromptConfig budgets the prompts built for tasks. Memory shares are
+// fractions of the prompt budget.
+type PromptConfig struct {
+	Budget        int     `json:"budget" yaml:"budget"`                 // Tokens for a whole prompt (0 = the context window less room for the response)
+	MemoryShare   float64 `json:"memory_share" yaml:"memory_share"`     // For the agent's own relevant experiences
+	SharedShare   float64 `json:"shared_share" yaml:"shared_share"`     // For relevant memories of the collective
+	MaxMemories   int     `json:"max_memories" yaml:"max_memories"`     // Most memories recalled from each source
+	MinSimilarity float64 `json:"min_similarity" yaml:"min_similarity"` // Least similarity to the task for a memory to be recalled
+}
+
+// DefaultPromptConfig returns the default prompt budgets
+func DefaultPromptConfig() PromptConfig {
+	return PromptConfig{
+		MemoryShare:   0.15,
+		SharedShare:   0.15,
+		MaxMemories:   5,
+		MinSimilarity: 0.1,
+	}
+}
+
+// SharedMemory is memory beyond the agent's own, such as its collective's,
+// searched for material relevant to a task
+type SharedMemory interface {
+	Recall(query string, limit int) []prompt.Candidate
+}
+
+// SetSharedMemory sets the memory searched alongside the agent's own when
+// building task prompts (nil disables)
+func (a *Agent) SetSharedMemory(m SharedMemory) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.shared = m
+}
+
+// PromptData is what task prompt templates are rendered with
+type PromptData struct {
+	Name         string
+	SID          string
+	Capabilities string // Held capabilities with proficiencies: "code.write (0.80), testing (0.50)"
+	Task         string
+	Requirements string
+	Complexity   string
+	Experience   string // Relevant past episodes of the agent, one per line
+	Shared       string // Relevant memories of the collective, one per line
+	Resumed      string // Work done on the task before it was preempted
+}
+
+// defaultPromptTemplate is used for tasks whose capabilities have no template
+var defaultPromptTemplate = template.Must(template.New("task").Parse(`You are a squaremind AI agent with the following identity:
+Name: {{.Name}}
+SID: {{.SID}}
+Capabilities: {{.Capabilities}}
+{{if .Experience}}
+Relevant experience:
+{{.Experience}}
+{{end}}{{if .Shared}}
+From the collective's memory:
+{{.Shared}}
+{{end}}
+Your task:
+{{.Task}}
+
+Requirements:
+{{.Requirements}}
+{{if .Resumed}}
+Your task was paused for a more urgent one. Continue from the work done so far:
+{{.Resumed}}
+{{end}}
+Perform this task to the best of your ability. Be thorough and precise.`))
+
+// parsePromptTemplates parses task prompt templates keyed by capability
+func parsePromptTemplates(sources map[identity.CapabilityType]string) (map[identity.CapabilityType]*template.Template, error) {
+	templates := make(map[identity.CapabilityType]*template.Template, len(sources))
+	for capType, source := range sources {
+		tmpl, err := template.New(string(capType)).Option("missingkey=error").Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", ErrInvalidTemplate, capType, err)
+		}
+		templates[capType] = tmpl
+	}
+	return templates, nil
+}
+
+// templateFor returns the template of the first required capability that
+// has one, or the default
+func (a *Agent) templateFor(task *Task) *template.Template {
+	for _, req := range task.Required {
+		if tmpl, ok := a.promptTemplates[req]; ok {
+			return tmpl
+		}
+	}
+	return defaultPromptTemplate
+}
+
+// buildPrompt constructs the prompt for the LLM within the prompt budget:
+// the agent's past episodes and the shared memories most similar to the
+// task fill their shares of it, and the task statement is cut to whatever
+// room is left, requirements first
+func (a *Agent) buildPrompt(task *Task) string {
+	tokenizer := prompt.NewApproxTokenizer()
+	cfg := a.Prompt
+	budget := cfg.Budget
+	if budget <= 0 {
+		_, _, reserve := a.requestSettings(task)
+		if reserve <= 0 {
+			reserve = defaultResponseTokens
+		}
+		budget = max(a.contextTokens()-reserve, 0)
+	}
+
+	query := task.Description + "\n" + task.Requirements
+	recall := func(candidates []prompt.Candidate, share float64) string {
+		for i := range candidates {
+			candidates[i].Content = compactMemory(tokenizer, candidates[i].Content)
+		}
+		selected := prompt.Retrieve(tokenizer, query, candidates, prompt.RetrieveOptions{
+			Budget:        int(float64(budget) * share),
+			Limit:         cfg.MaxMemories,
+			MinSimilarity: cfg.MinSimilarity,
+		})
+		lines := make([]string, len(selected))
+		for i, r := range selected {
+			lines[i] = "- " + r.Content
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	data := PromptData{
+		Name:         a.Identity.Name,
+		SID:          a.Identity.SID,
+		Capabilities: capabilitySummary(a.Capabilities),
+		Complexity:   task.Complexity,
+	}
+	if cfg.MemoryShare > 0 {
+		data.Experience = recall(a.episodeCandidates(), cfg.MemoryShare)
+	}
+	a.mu.RLock()
+	shared := a.shared
+	a.mu.RUnlock()
+	if shared != nil && cfg.SharedShare > 0 {
+		data.Shared = recall(shared.Recall(query, 4*max(cfg.MaxMemories, 1)), cfg.SharedShare)
+	}
+	// A preempted task picks up where it left off, within half the budget
+	if resumed, ok := task.Progress().LastCheckpoint(PreemptedCheckpoint); ok {
+		data.Resumed = tokenizer.Truncate(resumed.State, budget/2)
+	}
+
+	tmpl := a.templateFor(task)
+	frame, err := renderPrompt(tmpl, data)
+	if err != nil {
+		a.log().Warn("prompt template failed, using the default", "task", task.ID, "error", err)
+		tmpl = defaultPromptTemplate
+		frame, _ = renderPrompt(tmpl, data)
+	}
+
+	// The task statement gets the room the rest of the prompt leaves
+	room := budget - tokenizer.Count(frame)
+	data.Task = tokenizer.Truncate(task.Description, room)
+	data.Requirements = tokenizer.Truncate(task.Requirements, room-tokenizer.Count(data.Task))
+	text, _ := renderPrompt(tmpl, data)
+	return text
+}
+
+// renderPrompt executes a prompt template
+func renderPrompt(tmpl *template.Template, data PromptData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// episodeCandidates offers the agent's episodes for recall, newest first
+func (a *Agent) episodeCandidates() []prompt.Candidate {
+	episodes := a.Memory.Episodes()
+	candidates := make([]prompt.Candidate, 0, len(episodes))
+	for i := len(episodes) - 1; i >= 0; i-- {
+		ep := episodes[i]
+		candidates = append(candidates, prompt.Candidate{Source: ep.Type, Content: ep.Content, Salience: ep.Salience})
+	}
+	return candidates
+}
+
+// memoryTokens caps each memory recalled into a prompt
+const memoryTokens = 80
+
+// compactMemory puts a memory on one line, cut to memoryTokens
+func compactMemory(tokenizer prompt.Tokenizer, content string) string {
+	line := strings.Join(strings.Fields(content), " ")
+	if cut := tokenizer.Truncate(line, memoryTokens); cut != line {
+		return strings.TrimSpace(cut) + "..."
+	}
+	return line
+}
+
+// capabilitySummary lists capabilities with their proficiencies, by name
+func capabilitySummary(cs *identity.CapabilitySet) string {
+	proficiencies := cs.Proficiencies()
+	types := make([]string, 0, len(proficiencies))
+	for t := range proficiencies {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%s (%.2f)", t, proficiencies[identity.CapabilityType(t)])
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
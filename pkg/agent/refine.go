@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// ErrInvalidCritique is returned for a critique response that isn't a
+// quality score with feedback
+var ErrInvalidCritique = errors.New("invalid critique")
+
+// Refinement defaults
+const (
+	DefaultRefineIterations = 2
+	DefaultRefineThreshold  = 0.8
+)
+
+// Critic reviews a task's output and scores it
+type Critic interface {
+	Critique(ctx context.Context, task *Task, output string) (*Critique, error)
+}
+
+// critiqueFormat holds critique responses to a quality score with feedback
+var critiqueFormat = llm.JSONSchema("critique", `{
+	"type": "object",
+	"properties": {
+		"quality": {"type": "number", "description": "How well the answer fulfils the task, 0.0 to 1.0"},
+		"feedback": {"type": "string", "description": "What to improve, or empty if nothing"}
+	},
+	"required": ["quality", "feedback"],
+	"additionalProperties": false
+}`)
+
+// Critique is a critic's verdict on an output
+type Critique struct {
+	Quality    float64 `json:"quality"` // 0.0 - 1.0
+	Feedback   string  `json:"feedback,omitempty"`
+	TokensUsed int     `json:"tokens_used,omitempty"`
+}
+
+// RefineConfig makes an agent have each output critiqued and revise it
+// until the critique's quality reaches Threshold or it has revised
+// Iterations times
+type RefineConfig struct {
+	Critic     Critic  // Reviews the outputs (nil = the agent critiques its own, see Agent.Critique)
+	Iterations int     // Revisions at most (0 = DefaultRefineIterations)
+	Threshold  float64 // Quality an output is accepted at (0 = DefaultRefineThreshold)
+}
+
+// withDefaults fills unset fields with the defaults
+func (c RefineConfig) withDefaults() RefineConfig {
+	if c.Iterations <= 0 {
+		c.Iterations = DefaultRefineIterations
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = DefaultRefineThreshold
+	}
+	return c
+}
+
+// Critique has the agent's LLM review an output of a task, so an agent can
+// serve as another's critic, or its own
+func (a *Agent) Critique(ctx context.Context, task *Task, output string) (*Critique, error) {
+	// A critic works under its own keys, not those of the agent it reviews
+	ctx, err := a.bindKeys(contextWithBinding(a.withCaller(ctx, task), nil), task)
+	if err != nil {
+		return nil, err
+	}
+	provider := a.provider(ctx)
+	if provider == nil {
+		return nil, ErrNoProvider
+	}
+
+	response, err := provider.Complete(ctx, llm.CompletionRequest{
+		Model:          a.requestModel(nil),
+		System:         a.SystemPrompt,
+		Prompt:         critiquePrompt(task, output),
+		MaxTokens:      a.MaxTokens,
+		ResponseFormat: critiqueFormat,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.recordUsage(response)
+
+	critique, err := parseCritique(response.Content)
+	if err != nil {
+		return nil, err
+	}
+	critique.TokensUsed = response.TokensUsed
+	return critique, nil
+}
+
+// refine has the result's output critiqued and, while its quality is below
+// the threshold, asks the LLM to revise it with the critic's feedback. The
+// result's quality is the last critique's; a failed critique or revision
+// leaves the previous output in place.
+func (a *Agent) refine(ctx context.Context, task *Task, req llm.CompletionRequest, result *TaskResult, progress *Progress) {
+	cfg := a.Refine.withDefaults()
+	critic := cfg.Critic
+	if critic == nil {
+		critic = a
+	}
+
+	for {
+		critique, err := critic.Critique(ctx, task, result.Output)
+		if err != nil {
+			a.log().Warn("critique failed", "task", result.TaskID, "iteration", result.Iterations, "error", err)
+			return
+		}
+		result.TokensUsed += critique.TokensUsed
+		result.Critique = critique
+		result.Quality = critique.Quality
+		a.log().Debug("output critiqued", "task", result.TaskID, "iteration", result.Iterations, "quality", critique.Quality)
+
+		if critique.Quality >= cfg.Threshold || result.Iterations >= cfg.Iterations {
+			return
+		}
+
+		revise := req
+		revise.Prompt = revisePrompt(req.Prompt, result.Output, critique)
+		response, err := a.complete(ctx, revise, progress)
+		if response != nil {
+			a.recordUsage(response)
+			result.TokensUsed += response.TokensUsed
+			result.ThinkingTokens += response.ThinkingTokens
+		}
+		if err != nil {
+			a.log().Warn("revision request failed", "task", result.TaskID, "error", err)
+			return
+		}
+		result.Output = response.Content
+		result.Iterations++
+	}
+}
+
+// parseCritique reads the JSON object of a critique response, which may be
+// surrounded by prose or a code fence
+func parseCritique(content string) (*Critique, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON object in response", ErrInvalidCritique)
+	}
+	var critique Critique
+	if err := json.Unmarshal([]byte(content[start:end+1]), &critique); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCritique, err)
+	}
+	if critique.Quality < 0 || critique.Quality > 1 {
+		return nil, fmt.Errorf("%w: quality %v out of range", ErrInvalidCritique, critique.Quality)
+	}
+	return &critique, nil
+}
+
+// critiquePrompt asks the LLM to review an output of a task
+func critiquePrompt(task *Task, output string) string {
+	return fmt.Sprintf(`Review this answer to the task below. Judge whether it is correct, complete and fulfils the task, and list what should be improved.
+
+Task: %s
+
+Answer:
+
+%s
+
+Reply with only a JSON object: {"quality": <0.0 to 1.0>, "feedback": "<what to improve, or empty if nothing>"}`,
+		task.Description, output)
+}
+
+// revisePrompt asks the LLM to improve an output with a critic's feedback
+func revisePrompt(original, output string, critique *Critique) string {
+	return fmt.Sprintf(`%s
+
+Your previous answer was:
+
+%s
+
+A reviewer rated it %.2f of 1 and said:
+
+%s
+
+Revise it to address the review and give your complete answer again.`,
+		original, output, critique.Quality, critique.Feedback)
+}
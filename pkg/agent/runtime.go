@@ -13,16 +13,23 @@ type Runtime struct {
 
 	agents    map[string]*Agent // SID -> Agent
 	maxAgents int
+	labels    Labels
 	taskQueue chan *Task
 	results   chan *TaskResult
+	overflow  OverflowPolicy
+	dropped   RuntimeStats // Overflow counters only
 	stopChan  chan struct{}
+	stopOnce  sync.Once
 	wg        sync.WaitGroup
 }
 
 // RuntimeConfig configures the runtime
 type RuntimeConfig struct {
-	MaxAgents  int
-	TaskBuffer int
+	MaxAgents    int
+	TaskBuffer   int
+	ResultBuffer int            // 0 = TaskBuffer
+	Overflow     OverflowPolicy // What happens to tasks and results sent to a full channel (empty = OverflowError)
+	Labels       Labels         // Placement labels of this host, given to the agents spawned on it
 }
 
 // DefaultRuntimeConfig returns default configuration
@@ -35,11 +42,19 @@ func DefaultRuntimeConfig() RuntimeConfig {
 
 // NewRuntime creates a new agent runtime
 func NewRuntime(cfg RuntimeConfig) *Runtime {
+	if cfg.ResultBuffer <= 0 {
+		cfg.ResultBuffer = cfg.TaskBuffer
+	}
+	if cfg.Overflow == "" || cfg.Overflow.Validate() != nil {
+		cfg.Overflow = OverflowError
+	}
 	return &Runtime{
 		agents:    make(map[string]*Agent),
 		maxAgents: cfg.MaxAgents,
+		labels:    cfg.Labels,
 		taskQueue: make(chan *Task, cfg.TaskBuffer),
-		results:   make(chan *TaskResult, cfg.TaskBuffer),
+		results:   make(chan *TaskResult, cfg.ResultBuffer),
+		overflow:  cfg.Overflow,
 		stopChan:  make(chan struct{}),
 	}
 }
@@ -70,6 +85,15 @@ func (r *Runtime) Unregister(sid string) error {
 	return nil
 }
 
+// Labels returns the host's placement labels
+func (r *Runtime) Labels() Labels {
+	labels := make(Labels, len(r.labels))
+	for k, v := range r.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
 // GetAgent returns an agent by SID
 func (r *Runtime) GetAgent(sid string) (*Agent, bool) {
 	r.mu.RLock()
@@ -114,9 +138,11 @@ func (r *Runtime) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop stops the runtime
+// Stop stops the runtime. Safe to call more than once.
 func (r *Runtime) Stop() {
-	close(r.stopChan)
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
 
 	// Stop all agents
 	r.mu.RLock()
@@ -128,16 +154,40 @@ func (r *Runtime) Stop() {
 	r.wg.Wait()
 }
 
-// SubmitTask submits a task to the runtime for execution
+// SubmitTask submits a task to the runtime for execution. A full queue is
+// handled by the runtime's overflow policy; a task evicted to make room is
+// reported on the results channel as failed with ErrQueueFull.
 func (r *Runtime) SubmitTask(task *Task) error {
-	select {
-	case r.taskQueue <- task:
-		return nil
-	default:
-		return fmt.Errorf("task queue full")
+	evicted, dropped, err := offer(task.Context(), r.stopChan, r.taskQueue, r.taskQueue, task, r.overflow)
+	if dropped {
+		r.count(func(s *RuntimeStats) { s.DroppedTasks++ })
+		r.publish(&TaskResult{TaskID: evicted.ID, Status: TaskFailed, Error: ErrQueueFull.Error(), Timestamp: time.Now()})
+	}
+	if err != nil {
+		r.count(func(s *RuntimeStats) { s.RejectedTasks++ })
+		return fmt.Errorf("task %s: %w", task.ID, err)
+	}
+	return nil
+}
+
+// publish puts a result on the results channel by the overflow policy
+func (r *Runtime) publish(result *TaskResult) {
+	_, dropped, err := offer(context.Background(), r.stopChan, r.results, r.results, result, r.overflow)
+	if dropped {
+		r.count(func(s *RuntimeStats) { s.DroppedResults++ })
+	}
+	if err != nil {
+		r.count(func(s *RuntimeStats) { s.DroppedResults++ })
 	}
 }
 
+// count applies a change to the overflow counters
+func (r *Runtime) count(update func(*RuntimeStats)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	update(&r.dropped)
+}
+
 // GetResults returns the results channel
 func (r *Runtime) GetResults() <-chan *TaskResult {
 	return r.results
@@ -168,7 +218,7 @@ func (r *Runtime) assignTask(task *Task) {
 	var bestScore float64
 
 	for _, a := range r.agents {
-		if a.GetState() != StateIdle {
+		if a.GetState() != StateIdle || !task.PlaceableOn(a.Labels) {
 			continue
 		}
 
@@ -182,14 +232,18 @@ func (r *Runtime) assignTask(task *Task) {
 	if bestAgent != nil && bestScore > 0.5 {
 		task.Status = TaskAssigned
 		task.AssignedTo = bestAgent.Identity.SID
-		bestAgent.SubmitTask(task)
-	} else {
-		// No suitable agent found, re-queue
-		go func() {
-			time.Sleep(time.Second)
-			r.taskQueue <- task
-		}()
+		if bestAgent.SubmitTask(task) == nil {
+			return
+		}
+		task.Status = TaskPending
+		task.AssignedTo = ""
 	}
+
+	// No suitable agent found, or its queue is full: re-queue
+	go func() {
+		time.Sleep(time.Second)
+		r.taskQueue <- task
+	}()
 }
 
 // collectResults collects results from all agents
@@ -206,20 +260,21 @@ func (r *Runtime) collectResults(ctx context.Context) {
 		case <-r.stopChan:
 			return
 		case <-ticker.C:
+			var collected []*TaskResult
 			r.mu.RLock()
 			for _, a := range r.agents {
 				select {
 				case result := <-a.GetResults():
-					select {
-					case r.results <- result:
-					default:
-						// Results channel full
-					}
+					collected = append(collected, result)
 				default:
 					// No results from this agent
 				}
 			}
 			r.mu.RUnlock()
+
+			for _, result := range collected {
+				r.publish(result)
+			}
 		}
 	}
 }
@@ -230,6 +285,10 @@ type RuntimeStats struct {
 	IdleAgents    int
 	WorkingAgents int
 	PendingTasks  int
+
+	RejectedTasks  int // Refused by a full task queue
+	DroppedTasks   int // Evicted from the task queue by newer tasks
+	DroppedResults int // Refused or evicted by a full results channel
 }
 
 // Stats returns current runtime statistics
@@ -237,10 +296,9 @@ func (r *Runtime) Stats() RuntimeStats {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	stats := RuntimeStats{
-		TotalAgents:  len(r.agents),
-		PendingTasks: len(r.taskQueue),
-	}
+	stats := r.dropped
+	stats.TotalAgents = len(r.agents)
+	stats.PendingTasks = len(r.taskQueue)
 
 	for _, a := range r.agents {
 		switch a.GetState() {
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/sandbox"
+)
+
+// DefaultSandboxRetries is how many times an agent asks the LLM to fix code
+// that fails in its sandbox
+const DefaultSandboxRetries = 2
+
+// runsCode reports whether the agent checks the code it writes in a sandbox
+func (a *Agent) runsCode() bool {
+	return a.Sandbox != nil &&
+		(a.Capabilities.Has(identity.CapCodeWrite) || a.Capabilities.Has(identity.CapTesting))
+}
+
+// verifyInSandbox runs the code in a result's output and scores the result
+// by how much of it passed. While it fails, the LLM is shown the execution
+// results and asked for a fix, up to SandboxRetries times. The result keeps
+// the last output and its scores; a failed fix attempt leaves the previous
+// one in place.
+func (a *Agent) verifyInSandbox(ctx context.Context, req llm.CompletionRequest, result *TaskResult, progress *Progress) {
+	for attempt := 0; ; attempt++ {
+		program, err := sandbox.ExtractProgram(result.Output)
+		if err != nil {
+			if !errors.Is(err, sandbox.ErrNoProgram) {
+				a.log().Warn("could not extract code for the sandbox", "task", result.TaskID, "error", err)
+			}
+			return
+		}
+
+		binding := bindingFromContext(ctx)
+		if binding != nil {
+			program.Env = binding.Env
+		}
+		run, err := a.Sandbox.Run(ctx, program)
+		binding.recordRun(err)
+		if err != nil {
+			a.recordToolResult(result, progress, ToolResult{Tool: "sandbox", Error: err.Error()})
+			a.log().Warn("sandbox run failed", "task", result.TaskID, "error", err)
+			return
+		}
+		a.recordToolResult(result, progress, ToolResult{Tool: "sandbox", Output: run.Summary()})
+		result.TestsPassed = run.Passed
+		result.TestsFailed = run.Failed
+		result.Quality = sandboxQuality(run)
+		a.log().Debug("sandbox run", "task", result.TaskID, "attempt", attempt+1, "passed", run.Passed, "failed", run.Failed, "compiled", run.Compiled)
+
+		if run.Success() || attempt >= a.SandboxRetries {
+			return
+		}
+
+		// Show the LLM what went wrong and take its fix
+		fix := req
+		fix.Prompt = fixPrompt(req.Prompt, result.Output, run)
+		response, err := a.complete(ctx, fix, progress)
+		if response != nil {
+			a.recordUsage(response)
+			result.TokensUsed += response.TokensUsed
+			result.ThinkingTokens += response.ThinkingTokens
+		}
+		if err != nil {
+			a.log().Warn("sandbox fix request failed", "task", result.TaskID, "error", err)
+			return
+		}
+		result.Output = response.Content
+	}
+}
+
+// recordToolResult adds a tool's output to a result and the task's progress
+func (a *Agent) recordToolResult(result *TaskResult, progress *Progress, r ToolResult) {
+	r.Timestamp = time.Now()
+	progress.AddToolResult(r)
+	result.ToolResults = append(result.ToolResults, r)
+}
+
+// sandboxQuality scores an output by its sandbox run: code that doesn't
+// build or finish scores low, otherwise quality rises with the pass rate
+func sandboxQuality(run *sandbox.Result) float64 {
+	if !run.Compiled || run.TimedOut {
+		return 0.2
+	}
+	return 0.3 + 0.65*run.PassRate()
+}
+
+// fixPrompt asks the LLM to correct code that failed in the sandbox
+func fixPrompt(original, output string, run *sandbox.Result) string {
+	return fmt.Sprintf(`%s
+
+Your previous answer was:
+
+%s
+
+Its code was compiled and run in a sandbox:
+
+%s
+
+Fix the problems and give your complete answer again, with all code in fenced code blocks.`,
+		original, output, run.Summary())
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var (
+	ErrUnsignedResult   = errors.New("result is not signed")
+	ErrInvalidSignature = errors.New("invalid result signature")
+	ErrBrokenChain      = errors.New("result chain is broken")
+)
+
+// ActionTaskResult is the signed action type of a task result
+const ActionTaskResult = "task_result"
+
+// Digest returns the SHA-256 of the result's signed fields: what was
+// produced, by whom, for which task, and the digest it chains to
+func (r *TaskResult) Digest() string {
+	data, _ := json.Marshal(struct {
+		TaskID     string     `json:"task_id"`
+		AgentSID   string     `json:"agent_sid"`
+		Status     TaskStatus `json:"status"`
+		Output     string     `json:"output"`
+		Error      string     `json:"error"`
+		Quality    float64    `json:"quality"`
+		TokensUsed int        `json:"tokens_used"`
+		Partial    bool       `json:"partial"`
+		Timestamp  string     `json:"timestamp"`
+		Previous   string     `json:"previous"`
+	}{
+		r.TaskID, r.AgentSID, r.Status, r.Output, r.Error, r.Quality,
+		r.TokensUsed, r.Partial, r.Timestamp.UTC().Format(time.RFC3339Nano), r.Previous,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signResult signs a finished result with the agent's identity key, chained
+// to the last result the agent signed
+func (a *Agent) signResult(r *TaskResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r.Previous = a.lastSigned
+	digest := r.Digest()
+	signed, err := identity.NewSignedAction(a.Identity, identity.Action{
+		Type:   ActionTaskResult,
+		Target: r.TaskID,
+		Payload: map[string]interface{}{
+			"digest":   digest,
+			"previous": r.Previous,
+		},
+	})
+	if err != nil {
+		a.log().Error("could not sign result", "task", r.TaskID, "error", err)
+		return
+	}
+	r.Signature = signed
+	a.lastSigned = digest
+}
+
+// VerifyResult checks that a result was signed with publicKey and hasn't
+// been altered since
+func VerifyResult(r *TaskResult, publicKey ed25519.PublicKey) error {
+	sig := r.Signature
+	if sig == nil {
+		return ErrUnsignedResult
+	}
+	if sig.Action.Type != ActionTaskResult || sig.Action.Target != r.TaskID || sig.AgentSID != r.AgentSID {
+		return fmt.Errorf("%w: signed for another task or agent", ErrInvalidSignature)
+	}
+	if digest, _ := sig.Action.Payload["digest"].(string); digest != r.Digest() {
+		return fmt.Errorf("%w: result altered after signing", ErrInvalidSignature)
+	}
+	if previous, _ := sig.Action.Payload["previous"].(string); previous != r.Previous {
+		return fmt.Errorf("%w: chain link altered after signing", ErrInvalidSignature)
+	}
+	if !sig.Verify(publicKey) {
+		return fmt.Errorf("%w: not signed by agent %s", ErrInvalidSignature, r.AgentSID)
+	}
+	return nil
+}
+
+// VerifyChain checks a run of results signed by one agent, oldest first:
+// each must verify and chain to the one before it, so results dropped,
+// inserted or reordered are detected. The first result may chain to one
+// outside the run.
+func VerifyChain(results []*TaskResult, publicKey ed25519.PublicKey) error {
+	for i, r := range results {
+		if err := VerifyResult(r, publicKey); err != nil {
+			return fmt.Errorf("result %d (task %s): %w", i, r.TaskID, err)
+		}
+		if i > 0 && r.Previous != results[i-1].Digest() {
+			return fmt.Errorf("%w: result %d (task %s) does not follow task %s", ErrBrokenChain, i, r.TaskID, results[i-1].TaskID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"time"
+)
+
+// slot is one of an agent's worker slots, each running at most one task
+type slot struct {
+	task       *Task
+	started    time.Time
+	ctx        context.Context    // The task's execution, cancelled if the agent gives up
+	cancel     context.CancelFunc // Cancels the task's execution
+	preempting bool               // Preempt stopped the task
+	expiring   bool               // Expire stopped the task
+}
+
+// ActiveTask is a task running in one of an agent's worker slots
+type ActiveTask struct {
+	Slot    int       `json:"slot"`
+	TaskID  string    `json:"task_id"`
+	Started time.Time `json:"started"`
+}
+
+// Slots returns how many tasks the agent works on at once
+func (a *Agent) Slots() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.slots)
+}
+
+// FreeSlots returns how many more tasks the agent can start now
+func (a *Agent) FreeSlots() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.freeSlotsLocked()
+}
+
+// Load returns the share of the agent's slots that are busy (0-1)
+func (a *Agent) Load() float64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return float64(len(a.slots)-a.freeSlotsLocked()) / float64(len(a.slots))
+}
+
+// ActiveTasks returns the tasks the agent is running, by slot
+func (a *Agent) ActiveTasks() []ActiveTask {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.activeTasksLocked()
+}
+
+// GetCurrentTasks returns every task the agent is working on, by slot
+func (a *Agent) GetCurrentTasks() []*Task {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var tasks []*Task
+	for _, s := range a.slots {
+		if s.task != nil {
+			tasks = append(tasks, s.task)
+		}
+	}
+	return tasks
+}
+
+// freeSlotsLocked counts the idle slots. Caller must hold a.mu.
+func (a *Agent) freeSlotsLocked() int {
+	free := 0
+	for _, s := range a.slots {
+		if s.task == nil {
+			free++
+		}
+	}
+	return free
+}
+
+// activeTasksLocked lists the busy slots. Caller must hold a.mu.
+func (a *Agent) activeTasksLocked() []ActiveTask {
+	var active []ActiveTask
+	for i, s := range a.slots {
+		if s.task != nil {
+			active = append(active, ActiveTask{Slot: i, TaskID: s.task.ID, Started: s.started})
+		}
+	}
+	return active
+}
+
+// claimSlotLocked puts a task in the first free slot with a context of its
+// own under ctx, returning the slot's index or -1 if every slot is busy.
+// Caller must hold a.mu.
+func (a *Agent) claimSlotLocked(ctx context.Context, task *Task) int {
+	for i := range a.slots {
+		if a.slots[i].task == nil {
+			taskCtx, cancel := context.WithCancel(ctx)
+			a.slots[i] = slot{task: task, started: time.Now(), ctx: taskCtx, cancel: cancel}
+			a.syncCurrentTaskLocked()
+			return i
+		}
+	}
+	return -1
+}
+
+// releaseSlotLocked frees a slot, returning what it held. Caller must hold
+// a.mu.
+func (a *Agent) releaseSlotLocked(i int) slot {
+	s := a.slots[i]
+	a.slots[i] = slot{}
+	a.syncCurrentTaskLocked()
+	return s
+}
+
+// slotOfLocked returns the index of the slot running taskID, or -1. Caller
+// must hold a.mu.
+func (a *Agent) slotOfLocked(taskID string) int {
+	for i, s := range a.slots {
+		if s.task != nil && s.task.ID == taskID {
+			return i
+		}
+	}
+	return -1
+}
+
+// syncCurrentTaskLocked points CurrentTask at the task in the first busy
+// slot. Caller must hold a.mu.
+func (a *Agent) syncCurrentTaskLocked() {
+	a.CurrentTask = nil
+	for _, s := range a.slots {
+		if s.task != nil {
+			a.CurrentTask = s.task
+			return
+		}
+	}
+}
+
+// Settle runs fn while none of the agent's slots is recording a task's
+// outcome, so others updating the agent's reputation don't race its slots
+func (a *Agent) Settle(fn func()) {
+	a.settling.Lock()
+	defer a.settling.Unlock()
+	fn()
+}
+
+// SetReputationGuard sets what the agent's slots update its reputation
+// through: guard runs each update holding the lock of whoever else changes
+// the same Reputation, such as a registry it is registered with (nil runs
+// them directly)
+func (a *Agent) SetReputationGuard(guard func(func())) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reputationGuard = guard
+}
+
+// updateReputation runs fn, an update of the agent's reputation, through its
+// reputation guard
+func (a *Agent) updateReputation(fn func()) {
+	a.mu.RLock()
+	guard := a.reputationGuard
+	a.mu.RUnlock()
+	if guard == nil {
+		fn()
+		return
+	}
+	guard(fn)
+}
+
+// OverallReputation returns the agent's overall reputation, read through its
+// reputation guard
+func (a *Agent) OverallReputation() float64 {
+	var overall float64
+	a.updateReputation(func() { overall = a.Reputation.Overall })
+	return overall
+}
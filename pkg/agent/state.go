@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid agent state transition")
+	ErrAgentTerminated   = errors.New("agent terminated")
+)
+
+// allowedTransitions is the agent state machine. Terminated is final.
+var allowedTransitions = map[AgentState][]AgentState{
+	StateInitializing: {StateIdle, StateTerminated},
+	StateIdle:         {StateWorking, StatePaused, StateTerminated},
+	StateWorking:      {StateIdle, StateTerminated},
+	StatePaused:       {StateIdle, StateTerminated},
+	StateTerminated:   {},
+}
+
+// CanTransition reports whether the state machine allows moving from one state to another
+func CanTransition(from, to AgentState) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionLocked moves the agent to a new state. Transitioning to the current
+// state is a no-op. Caller must hold a.mu.
+func (a *Agent) transitionLocked(to AgentState) error {
+	if a.State == to {
+		return nil
+	}
+	if !CanTransition(a.State, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, a.State, to)
+	}
+	a.logger.Debug("agent state changed", "from", a.State, "to", to)
+	a.State = to
+	return nil
+}
+
+// signalWake nudges the run loop to re-evaluate its state
+func (a *Agent) signalWake() {
+	select {
+	case a.wakeChan <- struct{}{}:
+	default:
+		// Wake already pending
+	}
+}
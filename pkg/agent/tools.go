@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// DefaultToolRounds is how many rounds of tool calls an agent allows the
+// LLM per response
+const DefaultToolRounds = 8
+
+// Toolbox provides tools an agent's LLM may call, such as those of MCP
+// servers (see pkg/tools)
+type Toolbox interface {
+	// Tools returns the definitions offered to the LLM
+	Tools(ctx context.Context) ([]llm.Tool, error)
+
+	// Call runs a tool with JSON-encoded arguments and returns its output
+	Call(ctx context.Context, name string, input json.RawMessage) (string, error)
+}
+
+// completeWithTools sends a request offering the agent's tools, runs the
+// tools the LLM calls and returns their outputs to it, until it answers
+// without calls or ToolRounds rounds have passed. Each call is recorded in
+// progress and returned. Providers that can't call tools, or an empty
+// toolbox, get the plain request.
+func (a *Agent) completeWithTools(ctx context.Context, req llm.CompletionRequest, progress *Progress) (*llm.CompletionResponse, []ToolResult, error) {
+	provider, ok := a.provider(ctx).(llm.ToolProvider)
+	if !ok {
+		response, err := a.complete(ctx, req, progress)
+		return response, nil, err
+	}
+	tools, err := a.Tools.Tools(ctx)
+	if err != nil {
+		a.log().Warn("tools unavailable", "error", err)
+	}
+	if len(tools) == 0 {
+		response, err := a.complete(ctx, req, progress)
+		return response, nil, err
+	}
+
+	toolReq := llm.ToolRequest{CompletionRequest: req, Tools: tools}
+	total := &llm.CompletionResponse{}
+	var results []ToolResult
+	for round := 0; ; round++ {
+		response, err := provider.CompleteTools(ctx, toolReq)
+		if response != nil {
+			total.TokensUsed += response.TokensUsed
+			total.ThinkingTokens += response.ThinkingTokens
+		}
+		if err != nil {
+			return total, results, err
+		}
+		total.Content = response.Content
+		total.FinishReason = response.FinishReason
+		if len(response.ToolCalls) == 0 {
+			progress.AppendOutput(response.Content)
+			return total, results, nil
+		}
+		if round >= a.ToolRounds {
+			a.log().Warn("tool rounds exhausted", "rounds", round)
+			progress.AppendOutput(response.Content)
+			return total, results, nil
+		}
+
+		turn := llm.ToolTurn{Text: response.Content, Calls: response.ToolCalls}
+		for _, call := range response.ToolCalls {
+			output, err := a.Tools.Call(ctx, call.Name, call.Input)
+			r := ToolResult{Tool: call.Name, Output: output, Timestamp: time.Now()}
+			out := llm.ToolOutput{CallID: call.ID, Content: output}
+			if err != nil {
+				r.Error = err.Error()
+				out.Content, out.IsError = err.Error(), true
+			}
+			a.log().Debug("tool call", "tool", call.Name, "error", r.Error)
+			progress.AddToolResult(r)
+			results = append(results, r)
+			turn.Outputs = append(turn.Outputs, out)
+		}
+		toolReq.Turns = append(toolReq.Turns, turn)
+	}
+}
@@ -1,11 +1,16 @@
 package agent
 
 import (
+	"context"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/schema"
 )
 
 // TaskStatus represents the status of a task
@@ -19,6 +24,24 @@ const (
 	TaskFailed    TaskStatus = "failed"
 )
 
+// Task priorities. Higher values are admitted first when execution slots are scarce.
+const (
+	PriorityLow      = 0
+	PriorityNormal   = 5
+	PriorityHigh     = 10
+	PriorityCritical = 20 // May preempt low-priority running tasks where the collective allows it
+)
+
+// QoSClass is a task's quality-of-service class, which sets its latency
+// target, model, retry budget and share of the execution slots
+type QoSClass string
+
+const (
+	QoSInteractive QoSClass = "interactive"
+	QoSStandard    QoSClass = "standard" // Tasks that don't declare a class
+	QoSBatch       QoSClass = "batch"
+)
+
 // Task represents a unit of work
 type Task struct {
 	ID           string                    `json:"id"`
@@ -28,9 +51,37 @@ type Task struct {
 	Required     []identity.CapabilityType `json:"required_capabilities"`
 	Deadline     time.Time                 `json:"deadline"`
 	Reward       float64                   `json:"reward"` // Reputation points
+	Priority     int                       `json:"priority"`
+	QoS          QoSClass                  `json:"qos,omitempty"` // Quality-of-service class (empty = standard)
 	Status       TaskStatus                `json:"status"`
-	AssignedTo   string                    `json:"assigned_to,omitempty"` // Agent SID
+	AssignedTo   string                    `json:"assigned_to,omitempty"`   // Agent SID
+	Team         string                    `json:"team,omitempty"`          // Route to a named team (empty = whole collective)
+	Submitter    string                    `json:"submitter,omitempty"`     // Client or session that submitted the task, for fair scheduling
+	Placement    []Constraint              `json:"placement,omitempty"`     // Constraints on the labels of the agent that runs it
+	Hints        *RoutingHints             `json:"hints,omitempty"`         // Soft preferences the market weighs bids by
+	Model        string                    `json:"model,omitempty"`         // LLM model for this task (empty = the agent's)
+	MaxTokens    int                       `json:"max_tokens,omitempty"`    // Limit on the LLM response (0 = the agent's limit)
+	SystemPrompt string                    `json:"system_prompt,omitempty"` // Replaces the agent's role instructions for this task
+	Temperature  *float64                  `json:"temperature,omitempty"`   // Sampling temperature for this task, 0 included (nil = the agent's)
+	Steps        []string                  `json:"steps,omitempty"`         // Performed in turn as one conversation; the last step's answer is the output
+	Format       *llm.ResponseFormat       `json:"format,omitempty"`        // Makes the output a JSON object (nil = free text)
+	Auction      string                    `json:"auction,omitempty"`       // Market auction strategy (empty = the market's default)
+	CostTags     Labels                    `json:"cost_tags,omitempty"`     // Cost attribution labels (cost-center, project...) its tokens are charged to
+	Author       string                    `json:"author,omitempty"`        // SID of the agent whose work the task reviews or judges
+	Metadata     map[string]string         `json:"metadata,omitempty"`      // Settings for integrations (repo, base_branch...)
 	CreatedAt    time.Time                 `json:"created_at"`
+
+	// ctx is the submitter's context; cancelling it abandons the task
+	ctx context.Context
+
+	// progress collects work produced while the task runs
+	progress *Progress
+
+	// results receives the task's result instead of the agent's shared channel
+	results chan<- *TaskResult
+
+	// extra holds fields written by a newer release, kept for re-encoding
+	extra schema.Extra
 }
 
 // NewTask creates a new task
@@ -41,6 +92,7 @@ func NewTask(description string, required []identity.CapabilityType) *Task {
 		Required:    required,
 		Status:      TaskPending,
 		Complexity:  "medium",
+		Priority:    PriorityNormal,
 		CreatedAt:   time.Now(),
 	}
 }
@@ -51,6 +103,51 @@ func (t *Task) WithComplexity(complexity string) *Task {
 	return t
 }
 
+// WithModel runs the task on a model other than the agent's
+func (t *Task) WithModel(model string) *Task {
+	t.Model = model
+	return t
+}
+
+// WithQoS sets the task's quality-of-service class
+func (t *Task) WithQoS(class QoSClass) *Task {
+	t.QoS = class
+	return t
+}
+
+// WithMaxTokens limits the length of the LLM response
+func (t *Task) WithMaxTokens(maxTokens int) *Task {
+	t.MaxTokens = maxTokens
+	return t
+}
+
+// WithSystemPrompt replaces the agent's role instructions for this task
+func (t *Task) WithSystemPrompt(prompt string) *Task {
+	t.SystemPrompt = prompt
+	return t
+}
+
+// WithTemperature sets the sampling temperature for this task. 0 asks for
+// the most deterministic output rather than the agent's temperature.
+func (t *Task) WithTemperature(temperature float64) *Task {
+	t.Temperature = &temperature
+	return t
+}
+
+// WithResponseFormat makes the task's output a JSON object matching the
+// format's schema, so it can be parsed reliably
+func (t *Task) WithResponseFormat(format *llm.ResponseFormat) *Task {
+	t.Format = format
+	return t
+}
+
+// WithSteps makes the task multi-step: the agent performs the steps in
+// order as turns of one conversation
+func (t *Task) WithSteps(steps ...string) *Task {
+	t.Steps = steps
+	return t
+}
+
 // WithDeadline sets the task deadline
 func (t *Task) WithDeadline(deadline time.Time) *Task {
 	t.Deadline = deadline
@@ -63,6 +160,121 @@ func (t *Task) WithReward(reward float64) *Task {
 	return t
 }
 
+// WithPriority sets the task priority
+func (t *Task) WithPriority(priority int) *Task {
+	t.Priority = priority
+	return t
+}
+
+// WithAuction sets the auction strategy the market assigns the task by
+func (t *Task) WithAuction(auction string) *Task {
+	t.Auction = auction
+	return t
+}
+
+// WithTeam routes the task to a named team within the collective
+func (t *Task) WithTeam(team string) *Task {
+	t.Team = team
+	return t
+}
+
+// WithAuthor marks the task as a review or judgement of work by the agent
+// with the given SID, so the anti-affinity policy can check the agent
+// assigned to it for conflicts of interest
+func (t *Task) WithAuthor(sid string) *Task {
+	t.Author = sid
+	return t
+}
+
+// WithMetadata adds settings read by integrations, such as the repo and
+// base_branch a GitHub integration opens its pull request against
+func (t *Task) WithMetadata(metadata map[string]string) *Task {
+	if len(metadata) == 0 {
+		return t
+	}
+	if t.Metadata == nil {
+		t.Metadata = make(map[string]string, len(metadata))
+	}
+	for k, v := range metadata {
+		t.Metadata[k] = v
+	}
+	return t
+}
+
+// WithSubmitter records which client or session submitted the task
+func (t *Task) WithSubmitter(submitter string) *Task {
+	t.Submitter = submitter
+	return t
+}
+
+// WithCostTags adds cost attribution labels; they take precedence over the
+// cost tags of the agent that runs the task
+func (t *Task) WithCostTags(tags Labels) *Task {
+	if len(tags) == 0 {
+		return t
+	}
+	if t.CostTags == nil {
+		t.CostTags = make(Labels, len(tags))
+	}
+	for k, v := range tags {
+		t.CostTags[k] = v
+	}
+	return t
+}
+
+// WithPlacement restricts the task to agents whose labels satisfy every constraint
+func (t *Task) WithPlacement(constraints ...Constraint) *Task {
+	t.Placement = append(t.Placement, constraints...)
+	return t
+}
+
+// PlaceableOn reports whether an agent with the given labels may run the task
+func (t *Task) PlaceableOn(labels Labels) bool {
+	for _, c := range t.Placement {
+		if !c.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithContext attaches the submitter's context. Agents skip a task whose
+// context is done before it starts and cancel its LLM call if it ends mid-run.
+func (t *Task) WithContext(ctx context.Context) *Task {
+	t.ctx = ctx
+	return t
+}
+
+// Context returns the submitter's context, or context.Background if none was attached
+func (t *Task) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// WithProgress attaches a record that the executing agent fills with streamed
+// output, tool results and checkpoints, so a submitter can salvage the work if
+// the task is cut short
+func (t *Task) WithProgress(p *Progress) *Task {
+	t.progress = p
+	return t
+}
+
+// Progress returns the task's progress record, or nil if none was attached
+func (t *Task) Progress() *Progress {
+	return t.progress
+}
+
+// WithResults routes the task's result to ch instead of the executing
+// agent's shared result channel, so concurrent submitters each receive their
+// own result. A result that doesn't fit in ch is handled by the agent's
+// overflow policy, except that drop-oldest drops it.
+func (t *Task) WithResults(ch chan<- *TaskResult) *Task {
+	t.results = ch
+	return t
+}
+
 // WithRequirements sets the task requirements
 func (t *Task) WithRequirements(requirements string) *Task {
 	t.Requirements = requirements
@@ -79,6 +291,81 @@ type TaskResult struct {
 	Quality   float64       `json:"quality"` // 0.0 - 1.0
 	Duration  time.Duration `json:"duration"`
 	Timestamp time.Time     `json:"timestamp"`
+
+	TokensUsed     int `json:"tokens_used,omitempty"`
+	ThinkingTokens int `json:"thinking_tokens,omitempty"` // Portion of TokensUsed spent on reasoning
+
+	// Confidence is the quality the agent expected of its output, 0.0 - 1.0
+	// (0 = not reported)
+	Confidence float64 `json:"confidence,omitempty"`
+
+	// Tests of the output run in the agent's sandbox
+	TestsPassed int `json:"tests_passed,omitempty"`
+	TestsFailed int `json:"tests_failed,omitempty"`
+
+	// Behavior contracts the output still broke after re-prompting
+	ContractViolations []ContractViolation `json:"contract_violations,omitempty"`
+
+	// Revisions made on a critic's feedback, and the critique of the final output
+	Iterations int       `json:"iterations,omitempty"`
+	Critique   *Critique `json:"critique,omitempty"`
+
+	// Partial marks a failed result whose Output, ToolResults and Checkpoints
+	// hold the work produced before the task was cut short
+	Partial     bool         `json:"partial,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+	Checkpoints []Checkpoint `json:"checkpoints,omitempty"`
+
+	// Preempted marks a result whose task was stopped for a more urgent one
+	// and is to run again from its checkpoint
+	Preempted bool `json:"preempted,omitempty"`
+
+	// Expired marks a result whose task was cancelled at its deadline
+	Expired bool `json:"expired,omitempty"`
+
+	// Artifacts reference the output, checkpoints, tool results and
+	// generated files a collective with storage keeps content-addressed
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+
+	// Signature is the producing agent's signature over the result's
+	// Digest; Previous is the digest of the result it signed before, so an
+	// agent's results form a verifiable chain
+	Previous  string                 `json:"previous,omitempty"`
+	Signature *identity.SignedAction `json:"signature,omitempty"`
+
+	extra schema.Extra // Fields written by a newer release
+}
+
+// Names of the artifacts of a task result
+const (
+	ArtifactOutput           = "output"      // The result's output
+	ArtifactCheckpointPrefix = "checkpoint/" // checkpoint/<label>: intermediate state
+	ArtifactToolPrefix       = "tool/"       // tool/<n>-<tool>: a tool result's output
+	ArtifactFilePrefix       = "file/"       // file/<path>: a file generated in the output
+)
+
+// Artifact is a piece of a task's result kept in content-addressed storage
+type Artifact struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"` // sha256:<hex> of the content
+	Size   int    `json:"size"`
+}
+
+// Artifact returns the result's artifact with a name
+func (r *TaskResult) Artifact(name string) (Artifact, bool) {
+	for _, a := range r.Artifacts {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Artifact{}, false
+}
+
+// Usage tracks an agent's cumulative LLM token consumption
+type Usage struct {
+	Requests       int `json:"requests"`
+	TokensUsed     int `json:"tokens_used"`
+	ThinkingTokens int `json:"thinking_tokens"`
 }
 
 // Reputation tracks an agent's reputation
@@ -89,11 +376,18 @@ type Reputation struct {
 	Cooperation float64 `json:"cooperation"` // Works well with others
 	Honesty     float64 `json:"honesty"`     // Accurate self-assessment
 
+	Calibration Calibration `json:"calibration"` // Reported confidence against measured quality
+
 	TasksCompleted int `json:"tasks_completed"`
 	TasksFailed    int `json:"tasks_failed"`
 
-	LastActive time.Time `json:"last_active"`
-	DecayRate  float64   `json:"decay_rate"` // Daily decay percentage
+	Staked float64 `json:"staked,omitempty"` // Reputation locked vouching for other agents or bid on tasks
+
+	LastActive time.Time  `json:"last_active"`
+	DecayRate  float64    `json:"decay_rate"`            // Share of Overall lost per idle day (0 = no decay)
+	DecayCurve DecayCurve `json:"decay_curve,omitempty"` // How the loss accrues (empty = linear)
+
+	extra schema.Extra // Fields written by a newer release
 }
 
 // NewReputation creates a new reputation starting at baseline
@@ -114,16 +408,50 @@ func (r *Reputation) RecordSuccess(quality float64) {
 	r.TasksCompleted++
 	r.Quality = r.Quality*0.9 + quality*100*0.1 // Exponential moving average
 	r.Reliability = r.Reliability*0.95 + 100*0.05
-	r.recalculateOverall()
 	r.LastActive = time.Now()
+	r.recalculateOverall()
 }
 
 // RecordFailure updates reputation after failed task
 func (r *Reputation) RecordFailure() {
 	r.TasksFailed++
 	r.Reliability = r.Reliability * 0.9 // 10% penalty
-	r.recalculateOverall()
 	r.LastActive = time.Now()
+	r.recalculateOverall()
+}
+
+// RecordCalibration updates honesty by how far the confidence an agent
+// reported for a result was from the quality it was measured at: a gap of
+// a quarter holds it level, smaller ones raise it and larger ones lower it
+func (r *Reputation) RecordCalibration(confidence, quality float64) {
+	gap := math.Abs(confidence - quality)
+	r.Calibration.add(confidence, quality, gap)
+	r.Honesty = r.Honesty*0.9 + math.Max(1-2*gap, 0)*100*0.1
+	r.recalculateOverall()
+}
+
+// Calibration summarizes how well an agent's reported confidence matches
+// the measured quality of its results
+type Calibration struct {
+	Samples        int     `json:"samples"`
+	MeanConfidence float64 `json:"mean_confidence"`
+	MeanQuality    float64 `json:"mean_quality"`
+	MeanError      float64 `json:"mean_error"` // Mean absolute gap between confidence and quality
+}
+
+// add folds one result into the running means
+func (c *Calibration) add(confidence, quality, gap float64) {
+	c.Samples++
+	n := float64(c.Samples)
+	c.MeanConfidence += (confidence - c.MeanConfidence) / n
+	c.MeanQuality += (quality - c.MeanQuality) / n
+	c.MeanError += (gap - c.MeanError) / n
+}
+
+// Overconfidence is how much the agent's confidence exceeds its quality on
+// average; negative when it underrates its work
+func (c Calibration) Overconfidence() float64 {
+	return c.MeanConfidence - c.MeanQuality
 }
 
 // RecordCooperation updates cooperation score
@@ -132,25 +460,60 @@ func (r *Reputation) RecordCooperation(score float64) {
 	r.recalculateOverall()
 }
 
-// recalculateOverall updates the overall score
-func (r *Reputation) recalculateOverall() {
-	r.Overall = (r.Reliability + r.Quality + r.Cooperation + r.Honesty) / 4
+// LockStake sets aside reputation as a stake; it doesn't count towards Overall until unlocked
+func (r *Reputation) LockStake(amount float64) {
+	r.Staked += amount
+	r.recalculateOverall()
 }
 
-// ApplyDecay applies time-based reputation decay
-func (r *Reputation) ApplyDecay() {
-	daysSinceActive := time.Since(r.LastActive).Hours() / 24
-	if daysSinceActive > 1 {
-		decayFactor := 1.0 - (r.DecayRate * daysSinceActive)
-		if decayFactor < 0.5 {
-			decayFactor = 0.5 // Floor at 50% of original
-		}
-		r.Overall *= decayFactor
+// UnlockStake returns staked reputation
+func (r *Reputation) UnlockStake(amount float64) {
+	r.Staked -= amount
+	if r.Staked < 0 {
+		r.Staked = 0
+	}
+	r.recalculateOverall()
+}
+
+// ForfeitStake gives up staked reputation permanently: the stake is released
+// but charged against honesty, so Overall stays reduced by the same amount
+func (r *Reputation) ForfeitStake(amount float64) {
+	r.Honesty -= amount * 4
+	if r.Honesty < 0 {
+		r.Honesty = 0
+	}
+	r.UnlockStake(amount)
+}
+
+// ReturnStake returns staked reputation with a reward on top, credited to
+// honesty as ForfeitStake charges it, so Overall rises by the reward
+func (r *Reputation) ReturnStake(amount, reward float64) {
+	r.Honesty += reward * 4
+	if r.Honesty > 100 {
+		r.Honesty = 100
 	}
+	r.UnlockStake(amount)
 }
 
-// AgentMemory represents an agent's memory store
+// Recalculate updates Overall after component scores are changed directly
+func (r *Reputation) Recalculate() {
+	r.recalculateOverall()
+}
+
+// recalculateOverall updates the overall score, decayed for the time the
+// agent has been idle
+func (r *Reputation) recalculateOverall() {
+	r.Overall = (r.Reliability+r.Quality+r.Cooperation+r.Honesty)/4*r.DecayFactor(time.Now()) - r.Staked
+	if r.Overall < 0 {
+		r.Overall = 0
+	}
+}
+
+// AgentMemory represents an agent's memory store. Its methods are safe for
+// the concurrent use of an agent's slots.
 type AgentMemory struct {
+	mu sync.RWMutex
+
 	ShortTerm map[string]interface{} `json:"short_term"`
 	LongTerm  map[string]interface{} `json:"long_term"`
 	Episodic  []Episode              `json:"episodic"`
@@ -177,11 +540,15 @@ func NewAgentMemory() *AgentMemory {
 
 // Store stores a value in short-term memory
 func (m *AgentMemory) Store(key string, value interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.ShortTerm[key] = value
 }
 
 // Recall retrieves a value from memory (checks short-term first, then long-term)
 func (m *AgentMemory) Recall(key string) (interface{}, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	if v, ok := m.ShortTerm[key]; ok {
 		return v, true
 	}
@@ -191,8 +558,18 @@ func (m *AgentMemory) Recall(key string) (interface{}, bool) {
 	return nil, false
 }
 
+// Forget removes a value from short-term and long-term memory
+func (m *AgentMemory) Forget(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.ShortTerm, key)
+	delete(m.LongTerm, key)
+}
+
 // Consolidate moves important short-term memories to long-term
 func (m *AgentMemory) Consolidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	// Simple implementation: move everything
 	for k, v := range m.ShortTerm {
 		m.LongTerm[k] = v
@@ -204,6 +581,8 @@ func (m *AgentMemory) Consolidate() {
 func (m *AgentMemory) AddEpisode(ep Episode) {
 	ep.ID = uuid.New().String()
 	ep.Timestamp = time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.Episodic = append(m.Episodic, ep)
 
 	// Keep only last 100 episodes
@@ -211,3 +590,28 @@ func (m *AgentMemory) AddEpisode(ep Episode) {
 		m.Episodic = m.Episodic[len(m.Episodic)-100:]
 	}
 }
+
+// Entries returns a copy of short-term memory
+func (m *AgentMemory) Entries() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entries := make(map[string]interface{}, len(m.ShortTerm))
+	for k, v := range m.ShortTerm {
+		entries[k] = v
+	}
+	return entries
+}
+
+// Episodes returns a copy of the episodic memories, oldest first
+func (m *AgentMemory) Episodes() []Episode {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Episode(nil), m.Episodic...)
+}
+
+// Size returns how many short-term, long-term and episodic memories there are
+func (m *AgentMemory) Size() (shortTerm, longTerm, episodes int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.ShortTerm), len(m.LongTerm), len(m.Episodic)
+}
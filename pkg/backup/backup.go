@@ -0,0 +1,892 @@
+// Package backup archives the state squaremind keeps on disk into a single
+// file and restores it. Every file in an archive is listed in its manifest
+// with a SHA-256 digest, so a damaged archive is detected before anything is
+// restored, and the secrets from the config file are stored encrypted.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/eventsink"
+	"github.com/square-mind/squaremind/pkg/storage"
+	"github.com/square-mind/squaremind/pkg/tools"
+)
+
+// FormatVersion is the archive layout written by Create
+const FormatVersion = 1
+
+// manifestName is the archive entry holding the manifest. It is written last,
+// once every file's digest is known.
+const manifestName = "manifest.json"
+
+var (
+	// ErrIntegrity is returned for an archive that is damaged or incomplete
+	ErrIntegrity = errors.New("backup integrity check failed")
+
+	// ErrDecrypt is returned when the keystore can't be decrypted, usually
+	// because the passphrase is wrong
+	ErrDecrypt = errors.New("keystore decryption failed (wrong passphrase?)")
+
+	// ErrPassphraseRequired is returned when the keystore is selected without a passphrase
+	ErrPassphraseRequired = errors.New("the keystore needs a passphrase")
+
+	// ErrUnknownComponent is returned for a component name that doesn't exist
+	ErrUnknownComponent = errors.New("unknown backup component")
+)
+
+// Component is a group of state files that is backed up and restored together
+type Component string
+
+const (
+	ComponentConfig     Component = "config"     // Config file without its secrets, event triggers, custom capabilities and the migration manifest
+	ComponentKeystore   Component = "keystore"   // API keys, bearer tokens and the other secrets from the config file, encrypted
+	ComponentTasks      Component = "tasks"      // Scheduled tasks and swarm run checkpoints
+	ComponentMemory     Component = "memory"     // Collective memory database
+	ComponentReputation Component = "reputation" // Agent reputation history
+	ComponentArtifacts  Component = "artifacts"  // Execution logs, the workflow library and recorded runs
+)
+
+// AllComponents lists every component in archive order
+var AllComponents = []Component{
+	ComponentConfig,
+	ComponentKeystore,
+	ComponentTasks,
+	ComponentMemory,
+	ComponentReputation,
+	ComponentArtifacts,
+}
+
+// componentFiles are the files and directories of the plain components,
+// relative to the state directory
+var componentFiles = map[Component][]string{
+	ComponentConfig:     {"triggers.yaml", "capabilities.yaml", "state.json"}, // config.yaml is handled separately
+	ComponentTasks:      {"schedules.json", "swarms"},
+	ComponentReputation: {"reputation.json"},
+	ComponentArtifacts:  {"executions.jsonl", "workflows", "runs"},
+}
+
+const (
+	configFile   = "config.yaml"
+	memoryFile   = "memory.db"
+	keystoreFile = "secrets.yaml.enc"
+)
+
+// ParseComponents parses a list of component names
+func ParseComponents(names []string) ([]Component, error) {
+	components := make([]Component, 0, len(names))
+	for _, name := range names {
+		c := Component(strings.TrimSpace(name))
+		if !c.valid() {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownComponent, name)
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+// valid reports whether c is a known component
+func (c Component) valid() bool {
+	for _, known := range AllComponents {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Manifest describes an archive's contents
+type Manifest struct {
+	FormatVersion int         `json:"format_version"`
+	Created       time.Time   `json:"created"`
+	Components    []Component `json:"components"`
+	Files         []File      `json:"files"`
+	KDF           *KDF        `json:"kdf,omitempty"` // Set when the keystore is included
+}
+
+// File is one archived file. Digest covers the bytes as stored, so an
+// encrypted file is checked without the passphrase.
+type File struct {
+	Component Component `json:"component"`
+	Name      string    `json:"name"` // Path within the archive
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	Encrypted bool      `json:"encrypted,omitempty"`
+}
+
+// Has reports whether the archive includes a component
+func (m *Manifest) Has(c Component) bool {
+	for _, included := range m.Components {
+		if included == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Options selects what Create archives or Restore restores
+type Options struct {
+	Dir        string      // State directory (e.g. ~/.squaremind)
+	Components []Component // Empty = all (all in the archive, for Restore)
+	Passphrase string      // Encrypts or decrypts the keystore
+}
+
+// keystore holds the secrets split out of the config file. Secrets of list
+// entries are kept in the entries' order, with the name or submitter they
+// belong to, and only put back into an entry that still matches.
+type keystore struct {
+	AnthropicAPIKey string        `yaml:"anthropic_api_key,omitempty"`
+	OpenAIAPIKey    string        `yaml:"openai_api_key,omitempty"`
+	APITokens       []namedSecret `yaml:"api_tokens,omitempty"` // Bearer tokens by submitter
+	SlackWebhook    string        `yaml:"slack_webhook,omitempty"`
+
+	Storage    *storageSecrets `yaml:"storage,omitempty"`
+	Episodes   *s3Secrets      `yaml:"episodes,omitempty"`
+	EventSinks []sinkSecrets   `yaml:"event_sinks,omitempty"`
+	Keyring    []namedSecret   `yaml:"keyring,omitempty"` // Credential keys by credential name
+	MCPServers []serverSecrets `yaml:"mcp_servers,omitempty"`
+	GitHub     *githubSecrets  `yaml:"github,omitempty"`
+	Slack      *slackSecrets   `yaml:"slack,omitempty"`
+
+	// Tokens holds the bearer tokens of archives written before APITokens,
+	// keyed by submitter
+	Tokens map[string]string `yaml:"tokens,omitempty"`
+
+	Profiles map[string]keystore `yaml:"profiles,omitempty"` // The secrets of each config profile
+}
+
+// namedSecret is the secret of a list entry and the name it belongs to
+type namedSecret struct {
+	Name   string `yaml:"name"`
+	Secret string `yaml:"secret"`
+}
+
+// s3Secrets are the credentials of an S3-compatible bucket
+type s3Secrets struct {
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"`
+	SessionToken    string `yaml:"session_token,omitempty"`
+}
+
+// storageSecrets are the credentials of the storage backend's blob store
+type storageSecrets struct {
+	S3  *s3Secrets `yaml:"s3,omitempty"`
+	GCS *s3Secrets `yaml:"gcs,omitempty"`
+}
+
+// sinkSecrets are an event sink's NATS credentials
+type sinkSecrets struct {
+	Token    string `yaml:"token,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// serverSecrets are the environment and headers of an MCP server
+type serverSecrets struct {
+	Name    string            `yaml:"name"`
+	Env     map[string]string `yaml:"env,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// githubSecrets is the GitHub integration's token
+type githubSecrets struct {
+	Token string `yaml:"token,omitempty"`
+}
+
+// slackSecrets are the Slack bot's token and signing secret
+type slackSecrets struct {
+	Token         string `yaml:"token,omitempty"`
+	SigningSecret string `yaml:"signing_secret,omitempty"`
+}
+
+// splitSecrets separates a config into its secrets and a copy without them.
+// The config itself is left unchanged.
+func splitSecrets(cfg *config.Config) (*config.Config, keystore) {
+	public := *cfg
+	secrets := keystore{
+		AnthropicAPIKey: cfg.AnthropicAPIKey,
+		OpenAIAPIKey:    cfg.OpenAIAPIKey,
+		SlackWebhook:    cfg.SlackWebhook,
+	}
+	public.AnthropicAPIKey = ""
+	public.OpenAIAPIKey = ""
+	public.SlackWebhook = ""
+
+	if cfg.APITokens != nil {
+		public.APITokens = make([]config.APIToken, len(cfg.APITokens))
+		secrets.APITokens = make([]namedSecret, len(cfg.APITokens))
+		for i, t := range cfg.APITokens {
+			secrets.APITokens[i] = namedSecret{Name: t.Submitter, Secret: t.Token}
+			t.Token = ""
+			public.APITokens[i] = t
+		}
+	}
+	if cfg.Storage != nil {
+		s := *cfg.Storage
+		secrets.Storage = &storageSecrets{}
+		s.S3, secrets.Storage.S3 = splitS3(s.S3)
+		if s.GCS != nil {
+			gcs := *s.GCS
+			secrets.Storage.GCS = &s3Secrets{SecretAccessKey: gcs.SecretAccessKey}
+			gcs.SecretAccessKey = ""
+			s.GCS = &gcs
+		}
+		public.Storage = &s
+	}
+	if cfg.Episodes != nil {
+		e := *cfg.Episodes
+		s3, s := splitS3(&e.S3)
+		e.S3, secrets.Episodes = *s3, s
+		public.Episodes = &e
+	}
+	if cfg.EventSinks != nil {
+		public.EventSinks = make([]eventsink.Config, len(cfg.EventSinks))
+		secrets.EventSinks = make([]sinkSecrets, len(cfg.EventSinks))
+		for i, sink := range cfg.EventSinks {
+			secrets.EventSinks[i] = sinkSecrets{Token: sink.Token, Password: sink.Password}
+			sink.Token, sink.Password = "", ""
+			public.EventSinks[i] = sink
+		}
+	}
+	if cfg.Keyring != nil {
+		public.Keyring = make([]agent.Credential, len(cfg.Keyring))
+		secrets.Keyring = make([]namedSecret, len(cfg.Keyring))
+		for i, cred := range cfg.Keyring {
+			secrets.Keyring[i] = namedSecret{Name: cred.Name, Secret: cred.Key}
+			cred.Key = ""
+			public.Keyring[i] = cred
+		}
+	}
+	if cfg.MCPServers != nil {
+		public.MCPServers = make([]tools.ServerConfig, len(cfg.MCPServers))
+		secrets.MCPServers = make([]serverSecrets, len(cfg.MCPServers))
+		for i, server := range cfg.MCPServers {
+			secrets.MCPServers[i] = serverSecrets{Name: server.Name, Env: server.Env, Headers: server.Headers}
+			server.Env, server.Headers = nil, nil
+			public.MCPServers[i] = server
+		}
+	}
+	if cfg.GitHub != nil {
+		gh := *cfg.GitHub
+		secrets.GitHub = &githubSecrets{Token: gh.Token}
+		gh.Token = ""
+		public.GitHub = &gh
+	}
+	if cfg.Slack != nil {
+		s := *cfg.Slack
+		secrets.Slack = &slackSecrets{Token: s.Token, SigningSecret: s.SigningSecret}
+		s.Token, s.SigningSecret = "", ""
+		public.Slack = &s
+	}
+
+	if len(cfg.Profiles) > 0 {
+		public.Profiles = make(map[string]*config.Config, len(cfg.Profiles))
+		secrets.Profiles = make(map[string]keystore, len(cfg.Profiles))
+		for name, profile := range cfg.Profiles {
+			if profile == nil {
+				continue
+			}
+			public.Profiles[name], secrets.Profiles[name] = splitSecrets(profile)
+		}
+	}
+	return &public, secrets
+}
+
+// splitS3 separates a bucket config into its credentials and a copy without them
+func splitS3(cfg *storage.S3Config) (*storage.S3Config, *s3Secrets) {
+	if cfg == nil {
+		return nil, nil
+	}
+	public := *cfg
+	public.SecretAccessKey, public.SessionToken = "", ""
+	return &public, &s3Secrets{SecretAccessKey: cfg.SecretAccessKey, SessionToken: cfg.SessionToken}
+}
+
+// apply fills a config's secrets from the keystore
+func (k keystore) apply(cfg *config.Config) {
+	cfg.AnthropicAPIKey = k.AnthropicAPIKey
+	cfg.OpenAIAPIKey = k.OpenAIAPIKey
+	cfg.SlackWebhook = k.SlackWebhook
+	for i, t := range cfg.APITokens {
+		if i < len(k.APITokens) && k.APITokens[i].Name == t.Submitter {
+			cfg.APITokens[i].Token = k.APITokens[i].Secret
+		} else if token, ok := k.Tokens[t.Submitter]; ok {
+			cfg.APITokens[i].Token = token
+		}
+	}
+	if cfg.Storage != nil && k.Storage != nil {
+		k.Storage.S3.apply(cfg.Storage.S3)
+		if cfg.Storage.GCS != nil && k.Storage.GCS != nil {
+			cfg.Storage.GCS.SecretAccessKey = k.Storage.GCS.SecretAccessKey
+		}
+	}
+	if cfg.Episodes != nil {
+		k.Episodes.apply(&cfg.Episodes.S3)
+	}
+	for i := range cfg.EventSinks {
+		if i < len(k.EventSinks) {
+			cfg.EventSinks[i].Token = k.EventSinks[i].Token
+			cfg.EventSinks[i].Password = k.EventSinks[i].Password
+		}
+	}
+	for i, cred := range cfg.Keyring {
+		if i < len(k.Keyring) && k.Keyring[i].Name == cred.Name {
+			cfg.Keyring[i].Key = k.Keyring[i].Secret
+		}
+	}
+	for i, server := range cfg.MCPServers {
+		if i < len(k.MCPServers) && k.MCPServers[i].Name == server.Name {
+			cfg.MCPServers[i].Env = k.MCPServers[i].Env
+			cfg.MCPServers[i].Headers = k.MCPServers[i].Headers
+		}
+	}
+	if cfg.GitHub != nil && k.GitHub != nil {
+		cfg.GitHub.Token = k.GitHub.Token
+	}
+	if cfg.Slack != nil && k.Slack != nil {
+		cfg.Slack.Token = k.Slack.Token
+		cfg.Slack.SigningSecret = k.Slack.SigningSecret
+	}
+	for name, profile := range cfg.Profiles {
+		if ks, ok := k.Profiles[name]; ok && profile != nil {
+			ks.apply(profile)
+		}
+	}
+}
+
+// apply fills a bucket config's credentials
+func (s *s3Secrets) apply(cfg *storage.S3Config) {
+	if s == nil || cfg == nil {
+		return
+	}
+	cfg.SecretAccessKey = s.SecretAccessKey
+	cfg.SessionToken = s.SessionToken
+}
+
+// Create writes an archive of the selected components to dst. Files that
+// don't exist are skipped. The keystore requires a passphrase.
+func Create(dst string, opts Options) (*Manifest, error) {
+	components := opts.Components
+	if len(components) == 0 {
+		components = AllComponents
+	}
+	manifest := &Manifest{FormatVersion: FormatVersion, Created: time.Now().UTC()}
+
+	var key []byte
+	for _, c := range components {
+		if !c.valid() {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownComponent, c)
+		}
+		if c == ComponentKeystore {
+			if opts.Passphrase == "" {
+				return nil, ErrPassphraseRequired
+			}
+			kdf, err := newKDF()
+			if err != nil {
+				return nil, err
+			}
+			if key, err = kdf.key(opts.Passphrase); err != nil {
+				return nil, err
+			}
+			manifest.KDF = kdf
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".sqm-backup-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	gz := gzip.NewWriter(tmp)
+	w := &archiveWriter{tw: tar.NewWriter(gz), manifest: manifest}
+	for _, c := range components {
+		if err := w.addComponent(opts.Dir, c, key); err != nil {
+			return nil, fmt.Errorf("%s: %w", c, err)
+		}
+		manifest.Components = append(manifest.Components, c)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := w.writeEntry(manifestName, data); err != nil {
+		return nil, err
+	}
+	if err := w.tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if err := tmp.Chmod(0600); err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, os.Rename(tmp.Name(), dst)
+}
+
+// archiveWriter adds files to an archive and records them in its manifest
+type archiveWriter struct {
+	tw       *tar.Writer
+	manifest *Manifest
+}
+
+// addComponent archives one component's files
+func (w *archiveWriter) addComponent(dir string, c Component, key []byte) error {
+	switch c {
+	case ComponentConfig:
+		cfg, ok, err := loadConfig(filepath.Join(dir, configFile))
+		if err != nil || !ok {
+			if err == nil {
+				err = w.addPaths(dir, c)
+			}
+			return err
+		}
+		public, _ := splitSecrets(cfg)
+		data, err := yaml.Marshal(public)
+		if err != nil {
+			return err
+		}
+		if err := w.addBytes(c, path.Join(string(c), configFile), data, false); err != nil {
+			return err
+		}
+		return w.addPaths(dir, c)
+
+	case ComponentKeystore:
+		cfg, ok, err := loadConfig(filepath.Join(dir, configFile))
+		if err != nil || !ok {
+			return err
+		}
+		_, secrets := splitSecrets(cfg)
+		data, err := yaml.Marshal(secrets)
+		if err != nil {
+			return err
+		}
+		sealed, err := encrypt(key, data)
+		if err != nil {
+			return err
+		}
+		return w.addBytes(c, path.Join(string(c), keystoreFile), sealed, true)
+
+	case ComponentMemory:
+		src := filepath.Join(dir, memoryFile)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			return nil
+		}
+		// Copy through SQLite so a live database is captured consistently
+		store, err := collective.OpenSQLiteMemoryStore(src)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		snapshotDir, err := os.MkdirTemp("", "sqm-memory-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(snapshotDir)
+		snapshot := filepath.Join(snapshotDir, memoryFile)
+		if err := store.Backup(snapshot); err != nil {
+			return err
+		}
+		return w.addFile(c, path.Join(string(c), memoryFile), snapshot)
+
+	default:
+		return w.addPaths(dir, c)
+	}
+}
+
+// addPaths archives a plain component's files and directories
+func (w *archiveWriter) addPaths(dir string, c Component) error {
+	for _, rel := range componentFiles[c] {
+		root := filepath.Join(dir, rel)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && p == root {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			name, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			return w.addFile(c, path.Join(string(c), filepath.ToSlash(name)), p)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFile streams a file into the archive
+func (w *archiveWriter) addFile(c Component, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := w.tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(w.tw, io.TeeReader(f, h))
+	if err != nil {
+		return err
+	}
+	w.manifest.Files = append(w.manifest.Files, File{Component: c, Name: name, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))})
+	return nil
+}
+
+// addBytes adds generated content to the archive
+func (w *archiveWriter) addBytes(c Component, name string, data []byte, encrypted bool) error {
+	if err := w.writeEntry(name, data); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	w.manifest.Files = append(w.manifest.Files, File{Component: c, Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), Encrypted: encrypted})
+	return nil
+}
+
+// writeEntry writes one in-memory entry
+func (w *archiveWriter) writeEntry(name string, data []byte) error {
+	if err := w.tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// loadConfig reads a config file, reporting whether it exists
+func loadConfig(path string) (*config.Config, bool, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	cfg, err := config.LoadFromPath(path)
+	return cfg, err == nil, err
+}
+
+// Verify checks an archive's integrity: every file listed in the manifest is
+// present with the recorded size and digest, and nothing else is. With a
+// passphrase the keystore is also decrypted.
+func Verify(src, passphrase string) (*Manifest, error) {
+	manifest, keystoreData, err := scan(src)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != "" && keystoreData != nil {
+		if _, err := openKeystore(manifest, keystoreData, passphrase); err != nil {
+			return nil, err
+		}
+	}
+	return manifest, nil
+}
+
+// scan reads an archive, checking its digests against the manifest, and
+// returns the manifest and the encrypted keystore
+func scan(src string) (*Manifest, []byte, error) {
+	type seen struct {
+		size   int64
+		digest string
+	}
+	var (
+		manifest     *Manifest
+		keystoreData []byte
+		files        = make(map[string]seen)
+	)
+
+	err := readArchive(src, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name == manifestName {
+			manifest = &Manifest{}
+			if err := json.NewDecoder(r).Decode(manifest); err != nil {
+				return fmt.Errorf("%w: manifest: %v", ErrIntegrity, err)
+			}
+			return nil
+		}
+
+		h := sha256.New()
+		var buf *bytes.Buffer
+		if strings.HasPrefix(hdr.Name, string(ComponentKeystore)+"/") {
+			buf = &bytes.Buffer{}
+			r = io.TeeReader(r, buf)
+		}
+		n, err := io.Copy(h, r)
+		if err != nil {
+			return err
+		}
+		files[hdr.Name] = seen{size: n, digest: hex.EncodeToString(h.Sum(nil))}
+		if buf != nil {
+			keystoreData = buf.Bytes()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("%w: no manifest", ErrIntegrity)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported format version %d", ErrIntegrity, manifest.FormatVersion)
+	}
+	for _, f := range manifest.Files {
+		got, ok := files[f.Name]
+		switch {
+		case !ok:
+			return nil, nil, fmt.Errorf("%w: %s is missing", ErrIntegrity, f.Name)
+		case got.size != f.Size || got.digest != f.SHA256:
+			return nil, nil, fmt.Errorf("%w: %s is corrupt", ErrIntegrity, f.Name)
+		}
+		delete(files, f.Name)
+	}
+	for name := range files {
+		return nil, nil, fmt.Errorf("%w: %s is not in the manifest", ErrIntegrity, name)
+	}
+	return manifest, keystoreData, nil
+}
+
+// readArchive calls fn for each regular file in a gzipped tar archive
+func readArchive(src string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrIntegrity, err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrIntegrity, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// openKeystore decrypts the archived keystore
+func openKeystore(manifest *Manifest, data []byte, passphrase string) (keystore, error) {
+	var secrets keystore
+	if manifest.KDF == nil {
+		return secrets, fmt.Errorf("%w: keystore has no key derivation parameters", ErrIntegrity)
+	}
+	key, err := manifest.KDF.key(passphrase)
+	if err != nil {
+		return secrets, err
+	}
+	plaintext, err := decrypt(key, data)
+	if err != nil {
+		return secrets, err
+	}
+	if err := yaml.Unmarshal(plaintext, &secrets); err != nil {
+		return secrets, fmt.Errorf("%w: keystore: %v", ErrIntegrity, err)
+	}
+	return secrets, nil
+}
+
+// RestoreResult lists what a restore wrote
+type RestoreResult struct {
+	Components []Component
+	Files      []string // Paths written in the state directory
+}
+
+// Restore writes the selected components of an archive back into the state
+// directory. The whole archive is verified first, so a damaged archive
+// changes nothing. Files that are replaced are kept alongside with a
+// ".pre-restore" suffix. Restoring the config keeps the current secrets
+// unless the keystore is restored too; restoring only the keystore puts its
+// secrets into the current config.
+func Restore(src string, opts Options) (*RestoreResult, error) {
+	manifest, keystoreData, err := scan(src)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := make(map[Component]bool)
+	components := opts.Components
+	if len(components) == 0 {
+		components = manifest.Components
+	}
+	for _, c := range components {
+		if !c.valid() {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownComponent, c)
+		}
+		if manifest.Has(c) {
+			selected[c] = true
+		}
+	}
+
+	var secrets *keystore
+	if selected[ComponentKeystore] && keystoreData != nil {
+		if opts.Passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		k, err := openKeystore(manifest, keystoreData, opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		secrets = &k
+	}
+
+	result := &RestoreResult{}
+	for _, c := range AllComponents {
+		if selected[c] {
+			result.Components = append(result.Components, c)
+		}
+	}
+
+	var archivedConfig []byte
+	err = readArchive(src, func(hdr *tar.Header, r io.Reader) error {
+		c, rel, ok := strings.Cut(hdr.Name, "/")
+		if !ok || !selected[Component(c)] || Component(c) == ComponentKeystore {
+			return nil
+		}
+		if Component(c) == ComponentConfig && rel == configFile {
+			data, err := io.ReadAll(r)
+			archivedConfig = data
+			return err
+		}
+
+		dst, err := safeJoin(opts.Dir, rel)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(dst, r); err != nil {
+			return err
+		}
+		if Component(c) == ComponentMemory {
+			// A stale write-ahead log would be replayed over the restored database
+			os.Remove(dst + "-wal")
+			os.Remove(dst + "-shm")
+		}
+		result.Files = append(result.Files, dst)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if archivedConfig != nil || secrets != nil {
+		dst, err := restoreConfig(opts.Dir, archivedConfig, secrets)
+		if err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, dst)
+	}
+	sort.Strings(result.Files)
+	return result, nil
+}
+
+// restoreConfig writes the config file from the archived config and/or
+// keystore, filling whatever wasn't restored from the current config
+func restoreConfig(dir string, archived []byte, secrets *keystore) (string, error) {
+	dst := filepath.Join(dir, configFile)
+	current, _, err := loadConfig(dst)
+	if err != nil {
+		return "", err
+	}
+	if current == nil {
+		current = &config.Config{}
+	}
+	_, currentSecrets := splitSecrets(current)
+
+	cfg := current
+	if archived != nil {
+		cfg = &config.Config{}
+		if err := yaml.Unmarshal(archived, cfg); err != nil {
+			return "", fmt.Errorf("%w: config: %v", ErrIntegrity, err)
+		}
+		currentSecrets.apply(cfg)
+	}
+	if secrets != nil {
+		secrets.apply(cfg)
+	}
+
+	if err := keepPrevious(dst); err != nil {
+		return "", err
+	}
+	return dst, cfg.SaveToPath(dst)
+}
+
+// safeJoin joins an archive path to dir, refusing paths that escape it
+func safeJoin(dir, rel string) (string, error) {
+	clean := filepath.FromSlash(path.Clean(rel))
+	if !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("%w: unsafe path %q", ErrIntegrity, rel)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// writeFile replaces dst with the contents of r, keeping the previous file
+func writeFile(dst string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	if err := keepPrevious(dst); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// keepPrevious moves an existing file aside before it is replaced
+func keepPrevious(dst string) error {
+	if _, err := os.Stat(dst); err != nil {
+		return nil
+	}
+	return os.Rename(dst, dst+".pre-restore")
+}
@@ -0,0 +1,265 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/config"
+	"github.com/square-mind/squaremind/pkg/eventsink"
+	"github.com/square-mind/squaremind/pkg/integrations/github"
+	"github.com/square-mind/squaremind/pkg/integrations/slack"
+	"github.com/square-mind/squaremind/pkg/storage"
+	"github.com/square-mind/squaremind/pkg/tools"
+)
+
+func init() {
+	kdfIterations = 1000 // Keep tests fast
+}
+
+// fixtureSecrets are the secrets in the config writeState creates
+var fixtureSecrets = []string{
+	"sk-ant-secret", "sk-openai-secret", "token-for-alice-0001", "token-for-alice-0002", "webhook-secret",
+	"storage-s3-secret", "storage-s3-session", "storage-gcs-secret", "episodes-secret", "episodes-session",
+	"nats-token-secret", "nats-password-secret", "keyring-key-secret", "mcp-env-secret", "mcp-header-secret",
+	"github-token-secret", "slack-bot-secret", "slack-signing-secret", "sk-ant-work", "github-work-secret",
+}
+
+// writeState creates a state directory with a config, schedules, reputation and a workflow
+func writeState(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{
+		AnthropicAPIKey: "sk-ant-secret",
+		OpenAIAPIKey:    "sk-openai-secret",
+		DefaultModel:    "claude",
+		APITokens: []config.APIToken{
+			{Token: "token-for-alice-0001", Submitter: "alice"},
+			{Token: "token-for-alice-0002", Submitter: "alice"},
+		},
+		SlackWebhook: "https://hooks.slack.com/services/webhook-secret",
+		Storage: &storage.Config{
+			Driver: storage.DriverSQLite,
+			S3:     &storage.S3Config{Bucket: "results", AccessKeyID: "AKIA", SecretAccessKey: "storage-s3-secret", SessionToken: "storage-s3-session"},
+			GCS:    &storage.GCSConfig{Bucket: "results", SecretAccessKey: "storage-gcs-secret"},
+		},
+		Episodes:   &collective.EpisodeStoreConfig{S3: storage.S3Config{Bucket: "episodes", SecretAccessKey: "episodes-secret", SessionToken: "episodes-session"}},
+		EventSinks: []eventsink.Config{{Kind: "nats", Token: "nats-token-secret", User: "sqm", Password: "nats-password-secret"}},
+		Keyring:    []agent.Credential{{Name: "primary", Kind: agent.CredentialAnthropic, Key: "keyring-key-secret"}},
+		MCPServers: []tools.ServerConfig{{Name: "docs", URL: "https://mcp.example.com", Env: map[string]string{"API_KEY": "mcp-env-secret"}, Headers: map[string]string{"Authorization": "mcp-header-secret"}}},
+		GitHub:     &github.Config{Token: "github-token-secret"},
+		Slack:      &slack.Config{Token: "slack-bot-secret", SigningSecret: "slack-signing-secret"},
+		Profiles:   map[string]*config.Config{"work": {AnthropicAPIKey: "sk-ant-work", MaxAgents: 20, GitHub: &github.Config{Token: "github-work-secret"}}},
+	}
+	if err := cfg.SaveToPath(filepath.Join(dir, "config.yaml")); err != nil {
+		t.Fatalf("SaveToPath failed: %v", err)
+	}
+	files := map[string]string{
+		"schedules.json":           `[]`,
+		"reputation.json":          `{"agents":{}}`,
+		"workflows/review.yaml":    "name: review\n",
+		"executions.jsonl":         "{}\n",
+		"triggers.yaml":            "triggers: []\n",
+		"backups/20240101/ignored": "not archived",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0700)
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	store, err := collective.OpenSQLiteMemoryStore(filepath.Join(dir, "memory.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteMemoryStore failed: %v", err)
+	}
+	store.Close()
+	return dir
+}
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// RFC 7914 section 11
+	got := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestBackup_CreateAndRestore(t *testing.T) {
+	src := writeState(t)
+	archive := filepath.Join(t.TempDir(), "state.tar.gz")
+
+	manifest, err := Create(archive, Options{Dir: src, Passphrase: "hunter2"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !manifest.Has(ComponentKeystore) || manifest.KDF == nil {
+		t.Errorf("Expected the keystore to be included")
+	}
+	for _, f := range manifest.Files {
+		if strings.Contains(f.Name, "backups/") {
+			t.Errorf("Expected migration backups to be skipped, got %s", f.Name)
+		}
+	}
+
+	// Secrets never appear in plain text
+	data, _ := os.ReadFile(archive)
+	gz, _ := gzip.NewReader(strings.NewReader(string(data)))
+	plain, _ := io.ReadAll(gz)
+	for _, secret := range fixtureSecrets {
+		if strings.Contains(string(plain), secret) {
+			t.Errorf("Expected %s to be encrypted in the archive", secret)
+		}
+	}
+
+	if _, err := Verify(archive, "hunter2"); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if _, err := Verify(archive, "wrong"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt, got %v", err)
+	}
+
+	dst := t.TempDir()
+	result, err := Restore(archive, Options{Dir: dst, Passphrase: "hunter2"})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if len(result.Components) != len(manifest.Components) {
+		t.Errorf("Expected %d components restored, got %d", len(manifest.Components), len(result.Components))
+	}
+
+	cfg, err := config.LoadFromPath(filepath.Join(dst, "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadFromPath failed: %v", err)
+	}
+	if cfg.AnthropicAPIKey != "sk-ant-secret" || cfg.DefaultModel != "claude" {
+		t.Errorf("Expected config and secrets restored, got %+v", cfg)
+	}
+	if len(cfg.APITokens) != 2 || cfg.APITokens[0].Token != "token-for-alice-0001" || cfg.APITokens[1].Token != "token-for-alice-0002" {
+		t.Errorf("Expected both of alice's API tokens restored, got %+v", cfg.APITokens)
+	}
+	if work := cfg.Profiles["work"]; work == nil || work.AnthropicAPIKey != "sk-ant-work" || work.MaxAgents != 20 || work.GitHub.Token != "github-work-secret" {
+		t.Errorf("Expected the work profile and its secrets restored, got %+v", work)
+	}
+	restored, _ := yaml.Marshal(cfg)
+	for _, secret := range fixtureSecrets {
+		if !strings.Contains(string(restored), secret) {
+			t.Errorf("Expected %s restored", secret)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dst, "memory.db")); err != nil {
+		t.Errorf("Expected memory database restored: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dst, "workflows", "review.yaml"))
+	if err != nil || string(got) != "name: review\n" {
+		t.Errorf("Expected workflow restored, got %q (%v)", got, err)
+	}
+}
+
+func TestBackup_KeystoreNeedsPassphrase(t *testing.T) {
+	src := writeState(t)
+	archive := filepath.Join(t.TempDir(), "state.tar.gz")
+
+	if _, err := Create(archive, Options{Dir: src}); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("Expected ErrPassphraseRequired, got %v", err)
+	}
+
+	manifest, err := Create(archive, Options{Dir: src, Components: []Component{ComponentConfig, ComponentTasks}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if manifest.Has(ComponentKeystore) || manifest.KDF != nil {
+		t.Errorf("Expected no keystore")
+	}
+
+	// Restoring the config keeps the secrets already in place
+	dst := t.TempDir()
+	current := &config.Config{AnthropicAPIKey: "sk-ant-current"}
+	_ = current.SaveToPath(filepath.Join(dst, "config.yaml"))
+	if _, err := Restore(archive, Options{Dir: dst}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	cfg, _ := config.LoadFromPath(filepath.Join(dst, "config.yaml"))
+	if cfg.AnthropicAPIKey != "sk-ant-current" || cfg.DefaultModel != "claude" {
+		t.Errorf("Expected archived config with current secrets, got %+v", cfg)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "config.yaml.pre-restore")); err != nil {
+		t.Errorf("Expected previous config kept aside: %v", err)
+	}
+}
+
+func TestBackup_SelectiveRestore(t *testing.T) {
+	src := writeState(t)
+	archive := filepath.Join(t.TempDir(), "state.tar.gz")
+	if _, err := Create(archive, Options{Dir: src, Passphrase: "hunter2"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	dst := t.TempDir()
+	result, err := Restore(archive, Options{Dir: dst, Components: []Component{ComponentReputation}})
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if len(result.Files) != 1 || filepath.Base(result.Files[0]) != "reputation.json" {
+		t.Errorf("Expected only reputation.json restored, got %v", result.Files)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "config.yaml")); !os.IsNotExist(err) {
+		t.Errorf("Expected config not restored")
+	}
+
+	if _, err := ParseComponents([]string{"tasks", "bogus"}); !errors.Is(err, ErrUnknownComponent) {
+		t.Errorf("Expected ErrUnknownComponent, got %v", err)
+	}
+}
+
+func TestBackup_DetectsCorruption(t *testing.T) {
+	src := writeState(t)
+	archive := filepath.Join(t.TempDir(), "state.tar.gz")
+	if _, err := Create(archive, Options{Dir: src, Components: []Component{ComponentTasks, ComponentReputation}}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Rewrite the archive with one file's contents changed
+	tampered := filepath.Join(t.TempDir(), "tampered.tar.gz")
+	out, _ := os.Create(tampered)
+	gzw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzw)
+	err := readArchive(archive, func(hdr *tar.Header, r io.Reader) error {
+		data, _ := io.ReadAll(r)
+		if hdr.Name == "reputation/reputation.json" {
+			data = []byte(`{"agents":{"x":1}}`)
+		}
+		hdr.Size = int64(len(data))
+		_ = tw.WriteHeader(hdr)
+		_, err := tw.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("readArchive failed: %v", err)
+	}
+	tw.Close()
+	gzw.Close()
+	out.Close()
+
+	if _, err := Verify(tampered, ""); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("Expected ErrIntegrity, got %v", err)
+	}
+	dst := t.TempDir()
+	if _, err := Restore(tampered, Options{Dir: dst}); !errors.Is(err, ErrIntegrity) {
+		t.Errorf("Expected ErrIntegrity, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dst); len(entries) != 0 {
+		t.Errorf("Expected nothing restored from a damaged archive, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// kdfIterations is the PBKDF2 work factor for new archives
+var kdfIterations = 600000
+
+// KDF records how the encryption key was derived from the passphrase
+type KDF struct {
+	Algorithm  string `json:"algorithm"` // "pbkdf2-sha256"
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+}
+
+// newKDF returns key derivation parameters with a fresh salt
+func newKDF() (*KDF, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &KDF{Algorithm: "pbkdf2-sha256", Salt: salt, Iterations: kdfIterations}, nil
+}
+
+// key derives the 256-bit encryption key for a passphrase
+func (k *KDF) key(passphrase string) ([]byte, error) {
+	if k.Algorithm != "pbkdf2-sha256" || k.Iterations < 1 {
+		return nil, fmt.Errorf("%w: unsupported key derivation %q", ErrIntegrity, k.Algorithm)
+	}
+	return pbkdf2SHA256([]byte(passphrase), k.Salt, k.Iterations, 32), nil
+}
+
+// pbkdf2SHA256 implements PBKDF2 (RFC 8018) with HMAC-SHA256
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	key := make([]byte, 0, keyLen)
+	var counter [4]byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		u := prf.Sum(nil)
+
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// encrypt seals plaintext with AES-256-GCM; the nonce is prepended
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// decrypt opens data sealed by encrypt
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// newGCM returns an AES-GCM cipher for key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
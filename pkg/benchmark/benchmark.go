@@ -0,0 +1,224 @@
+// Package benchmark measures an agent's capabilities against standard eval
+// sets and signs the scores into capability proofs. Code cases are scored
+// by running held-back tests against the agent's code in a sandbox; other
+// cases by the findings the answer names.
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/sandbox"
+)
+
+var (
+	ErrUnknownSuite = errors.New("unknown benchmark suite")
+	ErrNoExecutor   = errors.New("benchmark has code cases but no sandbox")
+	ErrNoSigner     = errors.New("benchmark runner has no signer")
+)
+
+// DefaultValidity is how long a benchmark proof counts
+const DefaultValidity = 30 * 24 * time.Hour
+
+// Case is one eval of a suite. A case with Tests is scored by the share of
+// them the code in the answer passes; otherwise by the share of Expect
+// entries the answer mentions, each entry matching any of its
+// "|"-separated alternatives, case-insensitively.
+type Case struct {
+	Name     string            `json:"name"`
+	Prompt   string            `json:"prompt"`
+	Language sandbox.Language  `json:"language,omitempty"`
+	Tests    map[string]string `json:"tests,omitempty"` // File name -> contents, replacing any tests in the answer
+	Expect   []string          `json:"expect,omitempty"`
+}
+
+// Suite is a set of cases exercising one capability
+type Suite struct {
+	Name       string                  `json:"name"`
+	Capability identity.CapabilityType `json:"capability"`
+	Cases      []Case                  `json:"cases"`
+}
+
+// hasCode reports whether any case of the suite runs tests
+func (s Suite) hasCode() bool {
+	for _, c := range s.Cases {
+		if len(c.Tests) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CaseResult is how an agent did on one case
+type CaseResult struct {
+	Name       string  `json:"name"`
+	Score      float64 `json:"score"` // 0.0 - 1.0
+	Detail     string  `json:"detail,omitempty"`
+	TokensUsed int     `json:"tokens_used,omitempty"`
+}
+
+// Report is the outcome of running a suite against an agent
+type Report struct {
+	Suite      string                    `json:"suite"`
+	Capability identity.CapabilityType   `json:"capability"`
+	AgentSID   string                    `json:"agent_sid"`
+	Cases      []CaseResult              `json:"cases"`
+	Score      float64                   `json:"score"` // Mean of the case scores
+	TokensUsed int                       `json:"tokens_used"`
+	Duration   time.Duration             `json:"duration"`
+	Proof      *identity.CapabilityProof `json:"proof"`
+}
+
+// Runner runs suites against agents and signs the scores
+type Runner struct {
+	Signer   *identity.SquaremindIdentity // Signs the proofs
+	Executor sandbox.Executor             // Runs the tests of code cases
+	ValidFor time.Duration                // How long proofs count (default DefaultValidity)
+}
+
+// NewRunner creates a runner signing with signer and running code cases in
+// executor
+func NewRunner(signer *identity.SquaremindIdentity, executor sandbox.Executor) *Runner {
+	return &Runner{Signer: signer, Executor: executor, ValidFor: DefaultValidity}
+}
+
+// Run has the agent attempt every case of the suite and returns its scores
+// with a signed proof of the mean. The proof is not attached to the agent.
+// A case the agent fails to answer scores 0; cancelling ctx stops the run.
+func (r *Runner) Run(ctx context.Context, a *agent.Agent, suite Suite) (*Report, error) {
+	if r.Signer == nil {
+		return nil, ErrNoSigner
+	}
+	if suite.hasCode() && r.Executor == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoExecutor, suite.Name)
+	}
+
+	start := time.Now()
+	report := &Report{Suite: suite.Name, Capability: suite.Capability, AgentSID: a.Identity.SID}
+	var total float64
+	for _, c := range suite.Cases {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result := r.runCase(ctx, a, suite.Capability, c)
+		report.Cases = append(report.Cases, result)
+		report.TokensUsed += result.TokensUsed
+		total += result.Score
+	}
+	if len(suite.Cases) > 0 {
+		report.Score = total / float64(len(suite.Cases))
+	}
+	report.Duration = time.Since(start)
+
+	validFor := r.ValidFor
+	if validFor <= 0 {
+		validFor = DefaultValidity
+	}
+	report.Proof = identity.NewBenchmarkProof(r.Signer, a.Identity.SID, suite.Capability, suite.Name, report.Score, validFor)
+	return report, nil
+}
+
+// runCase has the agent attempt one case and scores the answer
+func (r *Runner) runCase(ctx context.Context, a *agent.Agent, capability identity.CapabilityType, c Case) CaseResult {
+	task := agent.NewTask(c.Prompt, []identity.CapabilityType{capability})
+	answer, err := a.Attempt(ctx, task)
+	if err != nil {
+		return CaseResult{Name: c.Name, Detail: err.Error()}
+	}
+	result := CaseResult{Name: c.Name, TokensUsed: answer.TokensUsed}
+	if len(c.Tests) > 0 {
+		result.Score, result.Detail = r.scoreCode(ctx, c, answer.Output)
+	} else {
+		result.Score, result.Detail = scoreFindings(c, answer.Output)
+	}
+	return result
+}
+
+// scoreCode runs the case's tests against the code in an answer, in place
+// of any tests the answer wrote
+func (r *Runner) scoreCode(ctx context.Context, c Case, output string) (float64, string) {
+	program, err := sandbox.ExtractProgram(output)
+	if err != nil {
+		return 0, err.Error()
+	}
+	if c.Language != "" && program.Language != c.Language {
+		return 0, fmt.Sprintf("answered in %s, want %s", program.Language, c.Language)
+	}
+	for name := range program.Files {
+		if strings.HasSuffix(name, "_test.go") || strings.HasPrefix(name, "test_") {
+			delete(program.Files, name)
+		}
+	}
+	for name, contents := range c.Tests {
+		program.Files[name] = contents
+	}
+
+	run, err := r.Executor.Run(ctx, program)
+	if err != nil {
+		return 0, err.Error()
+	}
+	if !run.Compiled || run.TimedOut {
+		return 0, strings.SplitN(run.Summary(), "\n", 2)[0]
+	}
+	return run.PassRate(), fmt.Sprintf("%d passed, %d failed", run.Passed, run.Failed)
+}
+
+// scoreFindings scores an answer by the expected findings it mentions
+func scoreFindings(c Case, output string) (float64, string) {
+	if len(c.Expect) == 0 {
+		return 0, "nothing expected"
+	}
+	output = strings.ToLower(output)
+	var found int
+	var missed []string
+	for _, expect := range c.Expect {
+		alternatives := strings.Split(expect, "|")
+		hit := false
+		for _, alt := range alternatives {
+			if strings.Contains(output, strings.ToLower(strings.TrimSpace(alt))) {
+				hit = true
+				break
+			}
+		}
+		if hit {
+			found++
+		} else {
+			missed = append(missed, alternatives[0])
+		}
+	}
+	detail := fmt.Sprintf("%d of %d findings", found, len(c.Expect))
+	if len(missed) > 0 {
+		detail += "; missed " + strings.Join(missed, ", ")
+	}
+	return float64(found) / float64(len(c.Expect)), detail
+}
+
+// Suites returns the standard suites, by name
+func Suites() []Suite {
+	suites := make([]Suite, len(standard))
+	copy(suites, standard)
+	sort.Slice(suites, func(i, j int) bool { return suites[i].Name < suites[j].Name })
+	return suites
+}
+
+// Lookup returns the standard suite with a name, or the first exercising a
+// capability of that name
+func Lookup(name string) (Suite, error) {
+	for _, s := range standard {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	for _, s := range standard {
+		if string(s.Capability) == name {
+			return s, nil
+		}
+	}
+	return Suite{}, fmt.Errorf("%w: %q", ErrUnknownSuite, name)
+}
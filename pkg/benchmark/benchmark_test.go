@@ -0,0 +1,147 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/sandbox"
+)
+
+// staticProvider answers every request with the same content
+type staticProvider struct {
+	content string
+}
+
+func (p *staticProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return &llm.CompletionResponse{Content: p.content, TokensUsed: 10}, nil
+}
+
+func (p *staticProvider) Name() string {
+	return "static"
+}
+
+// fakeExecutor records the program it ran and returns a fixed result
+type fakeExecutor struct {
+	result  sandbox.Result
+	program *sandbox.Program
+}
+
+func (e *fakeExecutor) Run(ctx context.Context, p *sandbox.Program) (*sandbox.Result, error) {
+	e.program = p
+	result := e.result
+	return &result, nil
+}
+
+func newAgent(t *testing.T, content string, caps ...identity.CapabilityType) *agent.Agent {
+	t.Helper()
+	a, err := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: caps, Provider: &staticProvider{content: content}})
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	return a
+}
+
+func TestRunner_RunFindings(t *testing.T) {
+	signer, _ := identity.NewSquaremindIdentity("collective", "")
+	a := newAgent(t, "This concatenates user input into the query: SQL injection.", identity.CapSecurity)
+	suite := Suite{
+		Name:       "findings",
+		Capability: identity.CapSecurity,
+		Cases: []Case{
+			{Name: "sqli", Prompt: "Review this", Expect: []string{"sql injection|sqli", "parameterized|prepared statement"}},
+			{Name: "all", Prompt: "Review this", Expect: []string{"injection"}},
+		},
+	}
+
+	report, err := NewRunner(signer, nil).Run(context.Background(), a, suite)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Cases) != 2 {
+		t.Fatalf("Expected 2 case results, got %d", len(report.Cases))
+	}
+	if report.Cases[0].Score != 0.5 || report.Cases[1].Score != 1 {
+		t.Errorf("Expected case scores 0.5 and 1, got %+v", report.Cases)
+	}
+	if math.Abs(report.Score-0.75) > 1e-9 {
+		t.Errorf("Expected score 0.75, got %f", report.Score)
+	}
+	if report.TokensUsed != 20 {
+		t.Errorf("Expected 20 tokens used, got %d", report.TokensUsed)
+	}
+
+	proof := report.Proof
+	if proof == nil || !proof.Verify(signer.PublicKey) {
+		t.Fatal("Expected a proof signed by the runner's signer")
+	}
+	if proof.Subject != a.Identity.SID || proof.Capability != identity.CapSecurity || proof.Score != report.Score {
+		t.Errorf("Expected proof of the report for the agent, got %+v", proof)
+	}
+	if a.Capabilities.Get(identity.CapSecurity).Proof != nil {
+		t.Error("Expected Run not to attach the proof to the agent")
+	}
+}
+
+func TestRunner_RunCode(t *testing.T) {
+	signer, _ := identity.NewSquaremindIdentity("collective", "")
+	answer := "```go solution.go\npackage solution\n```\n```go solution_test.go\npackage solution\n// weak tests\n```\n"
+	a := newAgent(t, answer, identity.CapCodeWrite)
+	suite := Suite{
+		Name:       "code",
+		Capability: identity.CapCodeWrite,
+		Cases: []Case{{
+			Name:     "case",
+			Prompt:   "Write it",
+			Language: sandbox.LangGo,
+			Tests:    map[string]string{"solution_test.go": "package solution\n// held-back tests\n"},
+		}},
+	}
+
+	if _, err := NewRunner(signer, nil).Run(context.Background(), a, suite); !errors.Is(err, ErrNoExecutor) {
+		t.Errorf("Expected ErrNoExecutor, got %v", err)
+	}
+
+	executor := &fakeExecutor{result: sandbox.Result{Compiled: true, Passed: 3, Failed: 1}}
+	report, err := NewRunner(signer, executor).Run(context.Background(), a, suite)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Score != 0.75 {
+		t.Errorf("Expected score 0.75, got %f", report.Score)
+	}
+	if !strings.Contains(executor.program.Files["solution_test.go"], "held-back") {
+		t.Error("Expected the case's tests to replace the answer's")
+	}
+
+	executor.result = sandbox.Result{Compiled: false, Failed: 1}
+	report, _ = NewRunner(signer, executor).Run(context.Background(), a, suite)
+	if report.Score != 0 {
+		t.Errorf("Expected score 0 for code that doesn't compile, got %f", report.Score)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	byName, err := Lookup("go-basics")
+	if err != nil || byName.Capability != identity.CapCodeWrite {
+		t.Errorf("Expected go-basics for code.write, got %+v, %v", byName, err)
+	}
+	byCapability, err := Lookup(string(identity.CapSecurity))
+	if err != nil || byCapability.Name != "security-basics" {
+		t.Errorf("Expected security-basics by capability, got %+v, %v", byCapability, err)
+	}
+	if _, err := Lookup("nope"); !errors.Is(err, ErrUnknownSuite) {
+		t.Errorf("Expected ErrUnknownSuite, got %v", err)
+	}
+
+	for _, s := range Suites() {
+		if len(s.Cases) == 0 {
+			t.Errorf("Expected suite %s to have cases", s.Name)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package benchmark
+
+import (
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/sandbox"
+)
+
+// goAnswer is appended to the prompts of Go code cases
+const goAnswer = "\n\nReply with the code only, in one ```go solution.go block declaring package solution. Do not write tests."
+
+// standard is the built-in eval set
+var standard = []Suite{
+	{
+		Name:       "go-basics",
+		Capability: identity.CapCodeWrite,
+		Cases: []Case{
+			{
+				Name:     "reverse",
+				Prompt:   "Write a Go function Reverse(s string) string that reverses s by runes, so multi-byte characters survive." + goAnswer,
+				Language: sandbox.LangGo,
+				Tests: map[string]string{"solution_test.go": `package solution
+
+import "testing"
+
+func TestReverseASCII(t *testing.T) {
+	if got := Reverse("abc"); got != "cba" {
+		t.Errorf("Reverse(abc) = %q", got)
+	}
+}
+
+func TestReverseEmpty(t *testing.T) {
+	if got := Reverse(""); got != "" {
+		t.Errorf("Reverse of empty = %q", got)
+	}
+}
+
+func TestReverseRunes(t *testing.T) {
+	if got := Reverse("héllo, 世界"); got != "界世 ,olléh" {
+		t.Errorf("Reverse = %q", got)
+	}
+}
+`},
+			},
+			{
+				Name:     "word-count",
+				Prompt:   "Write a Go function WordCount(s string) map[string]int counting the words of s, case-insensitively, where words are runs of letters and digits." + goAnswer,
+				Language: sandbox.LangGo,
+				Tests: map[string]string{"solution_test.go": `package solution
+
+import "testing"
+
+func TestWordCountSimple(t *testing.T) {
+	got := WordCount("the cat and the hat")
+	if got["the"] != 2 || got["cat"] != 1 || len(got) != 4 {
+		t.Errorf("WordCount = %v", got)
+	}
+}
+
+func TestWordCountCaseAndPunctuation(t *testing.T) {
+	got := WordCount("Go, go! GO? gopher")
+	if got["go"] != 3 || got["gopher"] != 1 || len(got) != 2 {
+		t.Errorf("WordCount = %v", got)
+	}
+}
+
+func TestWordCountEmpty(t *testing.T) {
+	if got := WordCount("  ... "); len(got) != 0 {
+		t.Errorf("WordCount = %v", got)
+	}
+}
+`},
+			},
+			{
+				Name:     "merge-intervals",
+				Prompt:   "Write a Go function Merge(intervals [][2]int) [][2]int that merges overlapping or touching closed intervals and returns them sorted by start. The input may be unsorted and must not be modified." + goAnswer,
+				Language: sandbox.LangGo,
+				Tests: map[string]string{"solution_test.go": `package solution
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeOverlapping(t *testing.T) {
+	got := Merge([][2]int{{8, 10}, {1, 3}, {2, 6}, {15, 18}})
+	want := [][2]int{{1, 6}, {8, 10}, {15, 18}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %v, want %v", got, want)
+	}
+}
+
+func TestMergeTouching(t *testing.T) {
+	got := Merge([][2]int{{1, 4}, {4, 5}})
+	if !reflect.DeepEqual(got, [][2]int{{1, 5}}) {
+		t.Errorf("Merge = %v", got)
+	}
+}
+
+func TestMergeLeavesInput(t *testing.T) {
+	in := [][2]int{{5, 6}, {1, 2}}
+	Merge(in)
+	if in[0] != [2]int{5, 6} {
+		t.Errorf("Merge modified its input: %v", in)
+	}
+}
+
+func TestMergeEmpty(t *testing.T) {
+	if got := Merge(nil); len(got) != 0 {
+		t.Errorf("Merge(nil) = %v", got)
+	}
+}
+`},
+			},
+		},
+	},
+	{
+		Name:       "review-basics",
+		Capability: identity.CapCodeReview,
+		Cases: []Case{
+			{
+				Name: "off-by-one",
+				Prompt: "Review this Go function and list its bugs:\n\n" +
+					"```go\nfunc Sum(xs []int) int {\n\ttotal := 0\n\tfor i := 0; i <= len(xs); i++ {\n\t\ttotal += xs[i]\n\t}\n\treturn total\n}\n```",
+				Expect: []string{"off-by-one|off by one|<= len|out of range|out of bounds", "panic|index"},
+			},
+			{
+				Name: "unchecked-error",
+				Prompt: "Review this Go function and list its bugs:\n\n" +
+					"```go\nfunc ReadConfig(path string) []byte {\n\tf, _ := os.Open(path)\n\tdata, _ := io.ReadAll(f)\n\treturn data\n}\n```",
+				Expect: []string{"error|err", "close|leak", "nil"},
+			},
+			{
+				Name: "data-race",
+				Prompt: "Review this Go code and list its bugs:\n\n" +
+					"```go\nvar hits = map[string]int{}\n\nfunc Handle(w http.ResponseWriter, r *http.Request) {\n\thits[r.URL.Path]++\n}\n```",
+				Expect: []string{"race|concurrent", "mutex|sync"},
+			},
+		},
+	},
+	{
+		Name:       "security-basics",
+		Capability: identity.CapSecurity,
+		Cases: []Case{
+			{
+				Name: "sql-injection",
+				Prompt: "Find the security problems in this Go code and say how to fix them:\n\n" +
+					"```go\nfunc FindUser(db *sql.DB, name string) (*sql.Rows, error) {\n\treturn db.Query(\"SELECT * FROM users WHERE name = '\" + name + \"'\")\n}\n```",
+				Expect: []string{"sql injection|injection", "parameter|placeholder|prepared"},
+			},
+			{
+				Name: "hardcoded-secret",
+				Prompt: "Find the security problems in this Go code and say how to fix them:\n\n" +
+					"```go\nconst apiKey = \"sk_live_4f9a8b7c6d5e\"\n\nfunc Client() *http.Client {\n\treturn &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}\n}\n```",
+				Expect: []string{"hardcoded|hard-coded|secret", "environment|secret manager|vault|config", "insecureskipverify|certificate|tls verification"},
+			},
+			{
+				Name: "path-traversal",
+				Prompt: "Find the security problems in this Go code and say how to fix them:\n\n" +
+					"```go\nfunc Serve(w http.ResponseWriter, r *http.Request) {\n\thttp.ServeFile(w, r, filepath.Join(\"/srv/files\", r.URL.Query().Get(\"name\")))\n}\n```",
+				Expect: []string{"traversal|../", "clean|validate|sanitize|allowlist|rel"},
+			},
+		},
+	},
+}
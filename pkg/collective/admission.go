@@ -0,0 +1,149 @@
+package collective
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// ErrAdmissionDenied is returned when a joining agent presents no acceptable
+// proof of work or voucher
+var ErrAdmissionDenied = errors.New("admission denied")
+
+// AdmissionPolicy makes joining cost something, so the collective can't be
+// flooded with throwaway identities. An agent is admitted if its identity
+// carries a proof of work of at least WorkDifficulty bits over its public key,
+// or a voucher from an existing member staking at least MinStake reputation.
+// The stake is returned once the newcomer completes StakeReleaseTasks tasks,
+// and forfeited if its reputation falls below StakeForfeitBelow first.
+type AdmissionPolicy struct {
+	WorkDifficulty int `json:"work_difficulty"` // Leading zero bits required (0 = proof of work not accepted)
+
+	MinStake             float64 `json:"min_stake"`              // Reputation a voucher must stake (0 = vouching not accepted)
+	MinVoucherReputation float64 `json:"min_voucher_reputation"` // Reputation a voucher needs after staking
+	StakeReleaseTasks    int     `json:"stake_release_tasks"`
+	StakeForfeitBelow    float64 `json:"stake_forfeit_below"`
+}
+
+// DefaultAdmissionPolicy accepts a proof of work that takes tens of
+// milliseconds to find, or a modest stake from an established member
+func DefaultAdmissionPolicy() AdmissionPolicy {
+	return AdmissionPolicy{
+		WorkDifficulty:       16,
+		MinStake:             5,
+		MinVoucherReputation: 40,
+		StakeReleaseTasks:    3,
+		StakeForfeitBelow:    30,
+	}
+}
+
+// AdmissionPolicy returns the collective's admission policy, or nil if joining is open
+func (c *Collective) AdmissionPolicy() *AdmissionPolicy {
+	return c.config.Admission
+}
+
+// vouch is a stake held against a vouched-for member
+type vouch struct {
+	voucherSID string
+	stake      float64
+	tasksDone  int
+}
+
+// admitLocked checks a joining agent against the admission policy, locking
+// the voucher's stake if admitted by voucher. Caller must hold c.mu.
+func (c *Collective) admitLocked(a *agent.Agent) error {
+	policy := c.config.Admission
+	if policy == nil {
+		return nil
+	}
+	id := a.Identity
+
+	if policy.WorkDifficulty > 0 && id.Work.Verify(id.PublicKey, policy.WorkDifficulty) {
+		return nil
+	}
+
+	v := id.Voucher
+	if policy.MinStake <= 0 || v == nil {
+		if policy.WorkDifficulty > 0 {
+			return fmt.Errorf("%w: proof of work of %d bits required", ErrAdmissionDenied, policy.WorkDifficulty)
+		}
+		return fmt.Errorf("%w: voucher required", ErrAdmissionDenied)
+	}
+
+	voucher, ok := c.agents[v.VoucherSID]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: voucher %s is not a member", ErrAdmissionDenied, v.VoucherSID)
+	case v.VoucheeSID != id.SID || !v.VoucheeKey.Equal(id.PublicKey):
+		return fmt.Errorf("%w: voucher was issued for another identity", ErrAdmissionDenied)
+	case !v.Verify(voucher.Identity.PublicKey):
+		return fmt.Errorf("%w: invalid voucher signature", ErrAdmissionDenied)
+	case !v.IsValid():
+		return fmt.Errorf("%w: voucher expired", ErrAdmissionDenied)
+	case v.Stake < policy.MinStake:
+		return fmt.Errorf("%w: stake %.1f below minimum %.1f", ErrAdmissionDenied, v.Stake, policy.MinStake)
+	case voucher.Reputation.Overall-v.Stake < policy.MinVoucherReputation:
+		return fmt.Errorf("%w: voucher reputation %.1f too low to stake %.1f", ErrAdmissionDenied, voucher.Reputation.Overall, v.Stake)
+	}
+	if _, vouched := c.vouches[id.SID]; vouched {
+		return fmt.Errorf("%w: stake already held for %s", ErrAdmissionDenied, id.SID)
+	}
+
+	if err := c.reputation.LockStake(v.VoucherSID, v.Stake, "Vouched for "+id.SID); err != nil {
+		return fmt.Errorf("%w: %v", ErrAdmissionDenied, err)
+	}
+	c.vouches[id.SID] = &vouch{voucherSID: v.VoucherSID, stake: v.Stake}
+	c.logger.Info("agent vouched", "agent", id.SID, "voucher", v.VoucherSID, "stake", v.Stake)
+	return nil
+}
+
+// settleVouch advances the stake held against a vouched-for agent after a
+// task: returning it once the agent has proven itself, or forfeiting it if
+// the agent's reputation collapses first
+func (c *Collective) settleVouch(sid string, succeeded bool) {
+	policy := c.config.Admission
+	if policy == nil {
+		return
+	}
+
+	c.mu.Lock()
+	v, ok := c.vouches[sid]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	if succeeded {
+		v.tasksDone++
+	}
+
+	release := v.tasksDone >= policy.StakeReleaseTasks
+	forfeit := false
+	if rep := c.reputation.Get(sid); rep != nil && rep.Overall < policy.StakeForfeitBelow {
+		forfeit = true
+	}
+	if release || forfeit {
+		delete(c.vouches, sid)
+	}
+	c.mu.Unlock()
+
+	switch {
+	case forfeit:
+		_ = c.reputation.ForfeitStake(v.voucherSID, v.stake, "Vouched-for agent "+sid+" fell below standing")
+		c.log().Warn("voucher stake forfeited", "agent", sid, "voucher", v.voucherSID, "stake", v.stake)
+	case release:
+		_ = c.reputation.ReleaseStake(v.voucherSID, v.stake, "Vouched-for agent "+sid+" proved reliable")
+		c.log().Info("voucher stake released", "agent", sid, "voucher", v.voucherSID, "stake", v.stake)
+	}
+}
+
+// releaseVouchLocked returns the stake held against an agent that left.
+// Caller must hold c.mu.
+func (c *Collective) releaseVouchLocked(sid string) {
+	v, ok := c.vouches[sid]
+	if !ok {
+		return
+	}
+	delete(c.vouches, sid)
+	_ = c.reputation.ReleaseStake(v.voucherSID, v.stake, "Vouched-for agent "+sid+" left")
+}
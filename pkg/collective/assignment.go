@@ -0,0 +1,284 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+)
+
+var (
+	ErrAssignmentRejected = errors.New("all candidate assignments rejected by consensus")
+	ErrNoPlacement        = errors.New("no agent satisfies the task's placement constraints")
+)
+
+// marketProposerSID is the proposer recorded on market-originated assignment proposals
+const marketProposerSID = "market"
+
+// AssignmentMode selects how tasks are matched to agents
+type AssignmentMode string
+
+const (
+	AssignmentMarket    AssignmentMode = "market"    // Highest-scoring bid wins outright
+	AssignmentConsensus AssignmentMode = "consensus" // Market proposes, agents ratify by reputation-weighted vote
+)
+
+// AssignmentVoter decides how an agent votes on a proposed task assignment
+type AssignmentVoter func(voter *agent.Agent, task *agent.Task, candidate *coordination.TaskAssignment) bool
+
+// DefaultAssignmentVoter approves a candidate unless the voter itself is
+// clearly better suited to the task (by more than 0.1 match score)
+func DefaultAssignmentVoter(voter *agent.Agent, task *agent.Task, candidate *coordination.TaskAssignment) bool {
+	own := voter.Capabilities.MatchScore(task.Required)
+	return own <= candidate.Bid.CapabilityScore+0.1
+}
+
+// SetAssignmentVoter overrides the voting policy used in consensus assignment mode
+func (c *Collective) SetAssignmentVoter(voter AssignmentVoter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.assignmentVoter = voter
+}
+
+// assignmentScope is the set of agents and coordination primitives a task is assigned within
+type assignmentScope struct {
+	name      string // Reported as the listing actor in task timelines
+	agents    map[string]*agent.Agent
+	market    *coordination.TaskMarket
+	consensus *coordination.ConsensusEngine
+	mode      AssignmentMode
+	excluded  []Exclusion // Agents left out by placement constraints or conflicts of interest
+}
+
+// scopeFor returns the assignment scope for a task: its team if one is named,
+// otherwise the whole collective, narrowed to the agents its placement
+// constraints allow and the anti-affinity policy doesn't block
+func (c *Collective) scopeFor(task *agent.Task) (assignmentScope, error) {
+	agents := c.agentMap()
+
+	scope := assignmentScope{
+		name:      marketProposerSID,
+		agents:    agents,
+		market:    c.market,
+		consensus: c.consensus,
+		mode:      c.config.AssignmentMode,
+	}
+	if task.Team != "" {
+		team, ok := c.GetTeam(task.Team)
+		if !ok {
+			return assignmentScope{}, fmt.Errorf("%w: %s", ErrTeamNotFound, task.Team)
+		}
+		scope = team.scope(agents)
+	}
+
+	if len(task.Placement) > 0 {
+		placeable := make(map[string]*agent.Agent, len(scope.agents))
+		for sid, a := range scope.agents {
+			if task.PlaceableOn(a.Labels) {
+				placeable[sid] = a
+			} else {
+				scope.excluded = append(scope.excluded, Exclusion{AgentSID: sid, Agent: a.Identity.Name, Reason: ExcludedPlacement})
+			}
+		}
+		if len(placeable) == 0 {
+			return assignmentScope{}, fmt.Errorf("%w: %s", ErrNoPlacement, placementString(task.Placement))
+		}
+		scope.agents = placeable
+	}
+
+	if err := c.screenConflicts(task, &scope); err != nil {
+		return assignmentScope{}, err
+	}
+	c.screenLate(task, &scope)
+	return scope, nil
+}
+
+// placementString formats placement constraints for messages
+func placementString(constraints []agent.Constraint) string {
+	specs := make([]string, len(constraints))
+	for i, c := range constraints {
+		specs[i] = c.String()
+	}
+	return strings.Join(specs, ", ")
+}
+
+// assign matches a task to an agent according to the configured assignment
+// mode and reserves one of the agent's slots; the caller must release it.
+// Agents holding a task in every slot are passed over, and ErrNoBids is
+// returned if every capable agent is busy.
+func (c *Collective) assign(task *agent.Task) (*coordination.TaskAssignment, error) {
+	scope, err := c.scopeFor(task)
+	if err != nil {
+		c.explanations.Record(&AssignmentExplanation{TaskID: task.ID, Required: task.Required, Error: err.Error(), Timestamp: time.Now()})
+		return nil, err
+	}
+
+	// Pinned tasks queue behind whatever their agent is doing
+	if pinned := c.pinnedAssignment(task); pinned != nil {
+		c.reserve(pinned.AgentSID, false)
+		c.explanations.Record(&AssignmentExplanation{
+			TaskID:    task.ID,
+			Mode:      scope.mode,
+			Scope:     scope.name,
+			Required:  task.Required,
+			Winner:    pinned.AgentSID,
+			Pinned:    true,
+			Bids:      []BidExplanation{},
+			Excluded:  []Exclusion{},
+			Timestamp: time.Now(),
+		})
+		return pinned, nil
+	}
+
+	c.timelines.Record(task.ID, StageListed, scope.name, "")
+	au := newAuction(task, scope, c.unreserved(scope.agents))
+
+	var assignment *coordination.TaskAssignment
+	switch {
+	case scope.mode == AssignmentConsensus:
+		assignment, err = c.assignByConsensus(task, au)
+	case c.reviews != nil && c.reviews.covers(task):
+		assignment, err = c.assignByRotation(task, au)
+	default:
+		assignment, err = c.assignByMarket(task, au)
+	}
+
+	winner := ""
+	if assignment != nil {
+		winner = assignment.AgentSID
+		if c.reviews != nil {
+			c.reviews.noteAssignment(winner, time.Now())
+		}
+	}
+	exp := au.explain(c.reputation, winner, err)
+	exp.Conflicts = c.checkConflicts(task, winner)
+	c.explanations.Record(exp)
+	if winner != "" {
+		scope.market.NoteAssignment(winner, time.Now())
+	}
+	c.noteBidders(exp)
+	return assignment, err
+}
+
+// assignByMarket assigns a task to the best free bidder
+func (c *Collective) assignByMarket(task *agent.Task, au *auction) (*coordination.TaskAssignment, error) {
+	scope := au.scope
+	assignment, err := scope.market.AssignTask(task, scope.agents, c.reputation)
+	if err != nil {
+		return nil, err
+	}
+	if c.reserve(assignment.AgentSID, true) {
+		return assignment, nil
+	}
+
+	// A concurrent task took the winner during bidding; fall back to the next best bid
+	ranked, err := scope.market.RankBids(task.ID, c.reputation)
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range ranked {
+		if c.reserve(candidate.AgentSID, true) {
+			return candidate, nil
+		}
+		au.outcome(candidate.AgentSID, OutcomeBusy)
+	}
+	return nil, fmt.Errorf("%w: every bidder is busy", coordination.ErrNoBids)
+}
+
+// assignByConsensus walks the market's ranked bids and proposes each free
+// candidate to the consensus engine, assigning to the first one the scope's
+// agents ratify
+func (c *Collective) assignByConsensus(task *agent.Task, au *auction) (*coordination.TaskAssignment, error) {
+	scope := au.scope
+	if err := scope.market.SolicitBids(task, scope.agents); err != nil {
+		return nil, err
+	}
+
+	ranked, err := scope.market.RankBids(task.ID, c.reputation)
+	if err != nil {
+		return nil, err
+	}
+	// Review stages are proposed in rotation order
+	candidates, take := ranked, func(string) {}
+	if c.reviews != nil && c.reviews.covers(task) {
+		candidates, take = c.reviews.order(ranked, c.reputation, time.Now())
+		au.rotated = candidates[0].AgentSID != ranked[0].AgentSID
+	}
+
+	proposed := false
+	for _, candidate := range candidates {
+		if !c.reserve(candidate.AgentSID, true) {
+			au.outcome(candidate.AgentSID, OutcomeBusy)
+			continue // Took another task during bidding
+		}
+		proposed = true
+		if c.ratifyAssignment(task, candidate, scope) {
+			c.timelines.Record(task.ID, StageConsensus, candidate.AgentSID, "ratified")
+			take(candidate.AgentSID)
+			return candidate, nil
+		}
+		c.release(candidate.AgentSID)
+		au.outcome(candidate.AgentSID, OutcomeRejected)
+		c.timelines.Record(task.ID, StageConsensus, candidate.AgentSID, "rejected")
+	}
+
+	if !proposed {
+		return nil, fmt.Errorf("%w: every bidder is busy", coordination.ErrNoBids)
+	}
+	return nil, ErrAssignmentRejected
+}
+
+// ratifyAssignment runs one reputation-weighted consensus round on a candidate.
+// Every agent in scope other than the candidate votes.
+func (c *Collective) ratifyAssignment(task *agent.Task, candidate *coordination.TaskAssignment, scope assignmentScope) bool {
+	c.mu.RLock()
+	voter := c.assignmentVoter
+	c.mu.RUnlock()
+
+	round, err := scope.consensus.Propose(context.Background(), marketProposerSID, coordination.ConsensusTypeTaskAssignment, map[string]interface{}{
+		"task_id":          task.ID,
+		"agent_sid":        candidate.AgentSID,
+		"capability_score": candidate.Bid.CapabilityScore,
+	})
+	if err != nil {
+		return false
+	}
+
+	weights := make(map[string]float64)
+	for sid, a := range scope.agents {
+		if sid == candidate.AgentSID {
+			continue
+		}
+
+		weight := 50.0 // Default
+		if rep := c.reputation.Get(sid); rep != nil {
+			weight = rep.Overall
+		}
+		weights[sid] = weight
+
+		_ = scope.consensus.SubmitVote(coordination.Vote{
+			AgentSID:   sid,
+			ProposalID: round.Proposal.ID,
+			Value:      voter(a, task, candidate),
+		})
+	}
+
+	accepted, _ := scope.consensus.CheckWeightedConsensus(round.Proposal.ID, weights)
+	return accepted
+}
+
+// agentMap returns a snapshot of the collective's agents keyed by SID
+func (c *Collective) agentMap() map[string]*agent.Agent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	agents := make(map[string]*agent.Agent, len(c.agents))
+	for sid, a := range c.agents {
+		agents[sid] = a
+	}
+	return agents
+}
@@ -0,0 +1,237 @@
+package collective
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var (
+	ErrInvalidAttestation   = errors.New("invalid attestation")
+	ErrUntrustedAttestation = errors.New("attestation signed by an untrusted key")
+	ErrRoundNotDecided      = errors.New("consensus round not decided")
+)
+
+// Kinds of claim a collective attests to
+const (
+	AttestMembership    = "membership"
+	AttestConsensus     = "consensus"
+	AttestAdvertisement = "advertisement"
+)
+
+// Attestation is a claim signed with the collective's own key, so other
+// collectives and outside verifiers can trust it knowing one key rather
+// than checking the signature of every agent behind it
+type Attestation struct {
+	Kind         string            `json:"kind"`
+	Collective   string            `json:"collective"`
+	CollectiveID string            `json:"collective_id"`
+	Claim        json.RawMessage   `json:"claim"`
+	IssuedAt     time.Time         `json:"issued_at"`
+	Signer       ed25519.PublicKey `json:"signer"`
+	Signature    []byte            `json:"signature"`
+}
+
+// MembershipClaim lists the collective's agents and the keys they joined with
+type MembershipClaim struct {
+	Version uint64   `json:"version"` // Bumped on every join and leave
+	Members []Member `json:"members"` // Ordered by SID
+}
+
+// Member is an agent in a membership claim
+type Member struct {
+	SID          string                    `json:"sid"`
+	Name         string                    `json:"name"`
+	PublicKey    ed25519.PublicKey         `json:"public_key"`
+	Capabilities []identity.CapabilityType `json:"capabilities,omitempty"`
+}
+
+// ConsensusClaim is the outcome of a decided consensus round
+type ConsensusClaim struct {
+	ProposalID string                     `json:"proposal_id"`
+	Type       coordination.ConsensusType `json:"type"`
+	Proposer   string                     `json:"proposer"`
+	Data       map[string]interface{}     `json:"data,omitempty"`
+	Result     string                     `json:"result"`
+	Accepts    []string                   `json:"accepts"` // SIDs that voted for, ordered
+	Rejects    []string                   `json:"rejects"` // SIDs that voted against, ordered
+}
+
+// AdvertisementClaim describes the collective to others it may federate with
+type AdvertisementClaim struct {
+	Endpoint     string                          `json:"endpoint,omitempty"` // Where the collective accepts tasks
+	Agents       int                             `json:"agents"`
+	Capabilities map[identity.CapabilityType]int `json:"capabilities"` // Agents holding each capability
+	Teams        []string                        `json:"teams,omitempty"`
+}
+
+// Identity returns the collective's own identity; its SID is the collective's ID
+func (c *Collective) Identity() *identity.SquaremindIdentity {
+	return c.identity
+}
+
+// newCollectiveIdentity creates the identity of a collective, keyed by key
+// or a fresh key if it is nil
+func newCollectiveIdentity(id, name string, key ed25519.PrivateKey) (*identity.SquaremindIdentity, error) {
+	if key == nil {
+		ident, err := identity.NewSquaremindIdentity(name, "")
+		if err != nil {
+			return nil, err
+		}
+		ident.SID = id
+		return ident, nil
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("collective key is %d bytes, not %d", len(key), ed25519.PrivateKeySize)
+	}
+	return &identity.SquaremindIdentity{
+		SID:        id,
+		PublicKey:  key.Public().(ed25519.PublicKey),
+		PrivateKey: key,
+		Name:       name,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// Attest signs a claim of the given kind with the collective's key
+func (c *Collective) Attest(kind string, claim interface{}) (*Attestation, error) {
+	data, err := json.Marshal(claim)
+	if err != nil {
+		return nil, err
+	}
+	a := &Attestation{
+		Kind:         kind,
+		Collective:   c.Name,
+		CollectiveID: c.ID,
+		Claim:        data,
+		IssuedAt:     time.Now().UTC(),
+		Signer:       c.identity.PublicKey,
+	}
+	payload, err := a.payload()
+	if err != nil {
+		return nil, err
+	}
+	a.Signature = c.identity.Sign(payload)
+	return a, nil
+}
+
+// AttestMembership signs the collective's current members
+func (c *Collective) AttestMembership() (*Attestation, error) {
+	c.mu.RLock()
+	claim := MembershipClaim{Version: c.membershipVersion, Members: make([]Member, 0, len(c.agents))}
+	for sid, a := range c.agents {
+		claim.Members = append(claim.Members, Member{
+			SID:          sid,
+			Name:         a.Identity.Name,
+			PublicKey:    c.keys[sid],
+			Capabilities: a.Capabilities.List(),
+		})
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(claim.Members, func(i, j int) bool {
+		return claim.Members[i].SID < claim.Members[j].SID
+	})
+	return c.Attest(AttestMembership, claim)
+}
+
+// AttestConsensus signs the outcome of a decided consensus round
+func (c *Collective) AttestConsensus(proposalID string) (*Attestation, error) {
+	round, ok := c.consensus.Round(proposalID)
+	if !ok || round.Result == "pending" {
+		return nil, fmt.Errorf("%w: %s", ErrRoundNotDecided, proposalID)
+	}
+	claim := ConsensusClaim{
+		ProposalID: round.Proposal.ID,
+		Type:       round.Proposal.Type,
+		Proposer:   round.Proposal.Proposer,
+		Data:       round.Proposal.Data,
+		Result:     round.Result,
+		Accepts:    []string{},
+		Rejects:    []string{},
+	}
+	for sid, vote := range round.Votes {
+		if vote.Value {
+			claim.Accepts = append(claim.Accepts, sid)
+		} else {
+			claim.Rejects = append(claim.Rejects, sid)
+		}
+	}
+	sort.Strings(claim.Accepts)
+	sort.Strings(claim.Rejects)
+	return c.Attest(AttestConsensus, claim)
+}
+
+// Advertise signs a description of the collective for others to federate
+// with, naming the endpoint it accepts tasks at
+func (c *Collective) Advertise(endpoint string) (*Attestation, error) {
+	claim := AdvertisementClaim{
+		Endpoint:     endpoint,
+		Capabilities: make(map[identity.CapabilityType]int),
+	}
+	for _, a := range c.GetAgents() {
+		claim.Agents++
+		for _, capType := range a.Capabilities.List() {
+			claim.Capabilities[capType]++
+		}
+	}
+	for _, team := range c.Teams() {
+		claim.Teams = append(claim.Teams, team.Name)
+	}
+	return c.Attest(AttestAdvertisement, claim)
+}
+
+// payload returns the bytes an attestation's signature covers. The claim is
+// compacted, so reformatting it doesn't invalidate the signature.
+func (a *Attestation) payload() ([]byte, error) {
+	var claim bytes.Buffer
+	if err := json.Compact(&claim, a.Claim); err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Kind         string          `json:"kind"`
+		Collective   string          `json:"collective"`
+		CollectiveID string          `json:"collective_id"`
+		Claim        json.RawMessage `json:"claim"`
+		IssuedAt     string          `json:"issued_at"`
+	}{a.Kind, a.Collective, a.CollectiveID, claim.Bytes(), a.IssuedAt.UTC().Format(time.RFC3339Nano)})
+}
+
+// Verify checks the attestation's signature. With trusted keys, the signer
+// must be one of them.
+func (a *Attestation) Verify(trusted ...ed25519.PublicKey) error {
+	if len(a.Signer) != ed25519.PublicKeySize || len(a.Signature) == 0 {
+		return fmt.Errorf("%w: not signed", ErrInvalidAttestation)
+	}
+	payload, err := a.payload()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttestation, err)
+	}
+	if !ed25519.Verify(a.Signer, payload, a.Signature) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidAttestation)
+	}
+	if len(trusted) == 0 {
+		return nil
+	}
+	for _, key := range trusted {
+		if key.Equal(a.Signer) {
+			return nil
+		}
+	}
+	return ErrUntrustedAttestation
+}
+
+// Decode unmarshals the attestation's claim into v
+func (a *Attestation) Decode(v interface{}) error {
+	if err := json.Unmarshal(a.Claim, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttestation, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package collective
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func TestCollective_AttestMembership(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(nil)
+	cfg := DefaultCollectiveConfig()
+	cfg.Key = key
+	c := NewCollective("TestCollective", cfg)
+	if !c.Identity().PublicKey.Equal(key.Public()) || c.Identity().SID != c.ID {
+		t.Fatal("Expected the collective to sign with the configured key under its own ID")
+	}
+
+	a1, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	a2, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent2", Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+	_ = c.Join(a1)
+	_ = c.Join(a2)
+
+	attestation, err := c.AttestMembership()
+	if err != nil {
+		t.Fatalf("AttestMembership failed: %v", err)
+	}
+	if err := attestation.Verify(c.Identity().PublicKey); err != nil {
+		t.Errorf("Expected the attestation to verify, got %v", err)
+	}
+
+	// It survives a round trip through JSON, reformatted
+	data, _ := json.MarshalIndent(attestation, "", "    ")
+	var decoded Attestation
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := decoded.Verify(); err != nil {
+		t.Errorf("Expected the decoded attestation to verify, got %v", err)
+	}
+	var claim MembershipClaim
+	if err := decoded.Decode(&claim); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if claim.Version != c.MembershipVersion() || len(claim.Members) != 2 {
+		t.Errorf("Expected 2 members at version %d, got %d at %d", c.MembershipVersion(), len(claim.Members), claim.Version)
+	}
+	for _, m := range claim.Members {
+		a, _ := c.GetAgent(m.SID)
+		if !m.PublicKey.Equal(a.Identity.PublicKey) {
+			t.Errorf("Expected %s's key in the claim", m.Name)
+		}
+	}
+
+	decoded.Claim = json.RawMessage(`{"version":99,"members":[]}`)
+	if err := decoded.Verify(); !errors.Is(err, ErrInvalidAttestation) {
+		t.Errorf("Expected ErrInvalidAttestation for an altered claim, got %v", err)
+	}
+	other, _, _ := ed25519.GenerateKey(nil)
+	if err := attestation.Verify(other); !errors.Is(err, ErrUntrustedAttestation) {
+		t.Errorf("Expected ErrUntrustedAttestation, got %v", err)
+	}
+}
+
+func TestCollective_AttestConsensus(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	consensus := c.GetConsensus()
+	round, _ := consensus.Propose(context.Background(), "sq-a", coordination.ConsensusTypeParameterChange, map[string]interface{}{"name": "bid_timeout"})
+
+	if _, err := c.AttestConsensus(round.Proposal.ID); !errors.Is(err, ErrRoundNotDecided) {
+		t.Errorf("Expected ErrRoundNotDecided for a pending round, got %v", err)
+	}
+
+	_ = consensus.SubmitVote(coordination.Vote{AgentSID: "sq-c", ProposalID: round.Proposal.ID, Value: false})
+	_ = consensus.SubmitVote(coordination.Vote{AgentSID: "sq-b", ProposalID: round.Proposal.ID, Value: true})
+	consensus.CheckConsensus(round.Proposal.ID, 3)
+
+	attestation, err := c.AttestConsensus(round.Proposal.ID)
+	if err != nil {
+		t.Fatalf("AttestConsensus failed: %v", err)
+	}
+	var claim ConsensusClaim
+	_ = attestation.Decode(&claim)
+	if claim.Result != "accepted" || len(claim.Accepts) != 2 || claim.Accepts[0] != "sq-a" || len(claim.Rejects) != 1 {
+		t.Errorf("Expected an accepted round with votes for from sq-a and sq-b, got %+v", claim)
+	}
+	if err := attestation.Verify(c.Identity().PublicKey); err != nil {
+		t.Errorf("Expected the attestation to verify, got %v", err)
+	}
+}
+
+func TestCollective_Advertise(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a)
+
+	attestation, err := c.Advertise("https://squad.example.com")
+	if err != nil {
+		t.Fatalf("Advertise failed: %v", err)
+	}
+	var claim AdvertisementClaim
+	_ = attestation.Decode(&claim)
+	if claim.Endpoint != "https://squad.example.com" || claim.Agents != 1 || claim.Capabilities[identity.CapCodeWrite] != 1 {
+		t.Errorf("Expected one code.write agent at the endpoint, got %+v", claim)
+	}
+}
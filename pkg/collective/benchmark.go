@@ -0,0 +1,66 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/benchmark"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/sandbox"
+)
+
+var ErrCapabilityNotHeld = errors.New("agent does not hold the capability")
+
+// Benchmark runs a suite against an agent, signs its score with the
+// collective's key and attaches the proof to the agent's capability, which
+// then matches tasks at a boosted proficiency until the proof expires.
+// executor runs the suite's code cases.
+func (c *Collective) Benchmark(ctx context.Context, sid string, suite benchmark.Suite, executor sandbox.Executor) (*benchmark.Report, error) {
+	a, ok := c.GetAgent(sid)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, sid)
+	}
+	if !a.Capabilities.Has(suite.Capability) {
+		return nil, fmt.Errorf("%w: %s lacks %s", ErrCapabilityNotHeld, sid, suite.Capability)
+	}
+
+	report, err := benchmark.NewRunner(c.identity, executor).Run(ctx, a, suite)
+	if err != nil {
+		return nil, err
+	}
+	a.Capabilities.Prove(suite.Capability, report.Proof)
+
+	c.log().Info("capability benchmarked", "agent", sid, "suite", suite.Name, "capability", suite.Capability,
+		"score", report.Score, "tokens", report.TokensUsed, "duration", report.Duration)
+	c.events.Publish(Event{
+		Type:     EventCapabilityProven,
+		AgentSID: sid,
+		Data: map[string]interface{}{
+			"suite":      suite.Name,
+			"capability": string(suite.Capability),
+			"score":      report.Score,
+			"expires_at": report.Proof.ExpiresAt,
+		},
+	})
+	return report, nil
+}
+
+// checkProofsLocked drops the benchmark proofs of a joining agent that this
+// collective didn't sign for it, so agents can't boost their own matching.
+// Caller must hold c.mu.
+func (c *Collective) checkProofsLocked(a *agent.Agent) {
+	for _, t := range a.Capabilities.List() {
+		capability, _ := a.Capabilities.Lookup(t)
+		proof := capability.Proof
+		if proof == nil || proof.Type != identity.ProofBenchmark {
+			continue
+		}
+		if proof.Subject == a.Identity.SID && proof.Capability == t && proof.Verify(c.identity.PublicKey) {
+			continue
+		}
+		a.Capabilities.Prove(t, nil)
+		c.logger.Warn("dropped benchmark proof not signed by this collective", "agent", a.Identity.SID, "capability", t, "signer", proof.Signer)
+	}
+}
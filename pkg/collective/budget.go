@@ -0,0 +1,96 @@
+package collective
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+var (
+	ErrBudgetNotFound = errors.New("budget not found")
+	ErrBudgetExceeded = errors.New("token budget exhausted")
+)
+
+// Budget caps the LLM tokens spent on the tasks of a submitter, a team, or
+// both; with neither set it caps every task. Once Used reaches Tokens, new
+// matching tasks fail with ErrBudgetExceeded.
+type Budget struct {
+	Name      string `json:"name"`
+	Tokens    int    `json:"tokens"`
+	Submitter string `json:"submitter,omitempty"`
+	Team      string `json:"team,omitempty"`
+	Used      int    `json:"used"`
+}
+
+// matches reports whether a task counts against the budget
+func (b *Budget) matches(task *agent.Task) bool {
+	return (b.Submitter == "" || b.Submitter == task.Submitter) && (b.Team == "" || b.Team == task.Team)
+}
+
+// SetBudget adds a budget, or changes the one with its name. The tokens
+// already used are kept; b.Used is ignored.
+func (c *Collective) SetBudget(b Budget) error {
+	if b.Name == "" || b.Tokens <= 0 {
+		return fmt.Errorf("budget needs a name and a positive token limit")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b.Used = 0
+	if existing, ok := c.budgets[b.Name]; ok {
+		b.Used = existing.Used
+	}
+	c.budgets[b.Name] = &b
+	return nil
+}
+
+// RemoveBudget removes a budget
+func (c *Collective) RemoveBudget(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.budgets[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrBudgetNotFound, name)
+	}
+	delete(c.budgets, name)
+	return nil
+}
+
+// Budgets returns the budgets and their usage, by name
+func (c *Collective) Budgets() []Budget {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	budgets := make([]Budget, 0, len(c.budgets))
+	for _, b := range c.budgets {
+		budgets = append(budgets, *b)
+	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].Name < budgets[j].Name })
+	return budgets
+}
+
+// checkBudgets returns ErrBudgetExceeded if a budget the task counts against
+// is used up
+func (c *Collective) checkBudgets(task *agent.Task) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, b := range c.budgets {
+		if b.matches(task) && b.Used >= b.Tokens {
+			return fmt.Errorf("%w: %s (%d of %d tokens used)", ErrBudgetExceeded, b.Name, b.Used, b.Tokens)
+		}
+	}
+	return nil
+}
+
+// chargeBudgets adds the tokens a task used to the budgets it counts against
+func (c *Collective) chargeBudgets(task *agent.Task, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range c.budgets {
+		if b.matches(task) {
+			b.Used += tokens
+		}
+	}
+}
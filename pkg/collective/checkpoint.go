@@ -0,0 +1,257 @@
+package collective
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+var (
+	ErrCheckpointNotFound = errors.New("swarm checkpoint not found")
+	ErrSwarmFinished      = errors.New("swarm run already completed")
+)
+
+// SwarmRunStatus is where a checkpointed swarm run got to
+type SwarmRunStatus string
+
+const (
+	SwarmRunning   SwarmRunStatus = "running"
+	SwarmCompleted SwarmRunStatus = "completed"
+	SwarmFailed    SwarmRunStatus = "failed" // Resumable
+)
+
+// SwarmCheckpoint is the saved progress of a swarm run, keyed by the ID of
+// the task it runs. It is saved after each phase: the plan once the task is
+// decomposed, the workflow run as each subtask or pipeline phase completes,
+// and the output once synthesized. ResumeSwarm continues a run from it.
+type SwarmCheckpoint struct {
+	RunID       string         `json:"run_id"` // The swarm task's ID
+	Description string         `json:"task"`
+	Complexity  string         `json:"complexity,omitempty"`
+	Team        string         `json:"team,omitempty"`
+	Submitter   string         `json:"submitter,omitempty"`
+	Pipeline    *SwarmPipeline `json:"pipeline,omitempty"` // Set for pipeline runs
+	Plan        *SwarmPlan     `json:"plan,omitempty"`     // Once decomposed
+	Run         *workflow.Run  `json:"run,omitempty"`      // Subtasks run so far
+	Output      string         `json:"output,omitempty"`   // Once completed
+	TokensUsed  int            `json:"tokens_used,omitempty"`
+	Status      SwarmRunStatus `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// Task recreates the swarm's task, under its original ID
+func (cp *SwarmCheckpoint) Task() *agent.Task {
+	task := agent.NewTask(cp.Description, nil).
+		WithComplexity(cp.Complexity).
+		WithTeam(cp.Team).
+		WithSubmitter(cp.Submitter)
+	task.ID = cp.RunID
+	return task
+}
+
+// CheckpointStore saves swarm checkpoints
+type CheckpointStore interface {
+	SaveCheckpoint(cp *SwarmCheckpoint) error
+	LoadCheckpoint(runID string) (*SwarmCheckpoint, error)
+	ListCheckpoints() ([]*SwarmCheckpoint, error)
+}
+
+// FileCheckpointStore keeps each checkpoint in a JSON file named after its
+// run ID
+type FileCheckpointStore struct {
+	mu sync.Mutex
+
+	dir string
+}
+
+// NewFileCheckpointStore creates a store in dir, which is created on the
+// first save
+func NewFileCheckpointStore(dir string) *FileCheckpointStore {
+	return &FileCheckpointStore{dir: dir}
+}
+
+// path returns the file of a run's checkpoint
+func (s *FileCheckpointStore) path(runID string) (string, error) {
+	if runID == "" || runID != filepath.Base(runID) || strings.HasPrefix(runID, ".") {
+		return "", fmt.Errorf("%w: invalid run ID %q", ErrCheckpointNotFound, runID)
+	}
+	return filepath.Join(s.dir, runID+".json"), nil
+}
+
+// SaveCheckpoint writes a checkpoint, replacing the run's previous one
+func (s *FileCheckpointStore) SaveCheckpoint(cp *SwarmCheckpoint) error {
+	path, err := s.path(cp.RunID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+
+	// Write atomically so a crash never leaves a truncated checkpoint
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadCheckpoint reads a run's checkpoint
+func (s *FileCheckpointStore) LoadCheckpoint(runID string) (*SwarmCheckpoint, error) {
+	path, err := s.path(runID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, runID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cp SwarmCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", runID, err)
+	}
+	return &cp, nil
+}
+
+// ListCheckpoints returns every checkpoint, most recently updated first.
+// Unreadable files are skipped.
+func (s *FileCheckpointStore) ListCheckpoints() ([]*SwarmCheckpoint, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []*SwarmCheckpoint
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		if cp, err := s.LoadCheckpoint(strings.TrimSuffix(name, ".json")); err == nil {
+			checkpoints = append(checkpoints, cp)
+		}
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].UpdatedAt.After(checkpoints[j].UpdatedAt)
+	})
+	return checkpoints, nil
+}
+
+// SetCheckpointStore makes ExecuteSwarm and ExecutePipeline save each
+// run's progress so ResumeSwarm can continue it
+func (c *Collective) SetCheckpointStore(store CheckpointStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checkpoints = store
+}
+
+// checkpointStore returns where swarm runs are saved, or nil
+func (c *Collective) checkpointStore() CheckpointStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.checkpoints
+}
+
+// ResumeSwarm continues a checkpointed swarm run. Phases it completed are
+// skipped: the saved plan is used instead of decomposing the task again,
+// completed subtasks keep their output, and the rest run as in
+// ExecuteSwarm or ExecutePipeline. A pipeline run's roles must already be
+// staffed.
+func (c *Collective) ResumeSwarm(ctx context.Context, runID string) (*SwarmResult, error) {
+	store := c.checkpointStore()
+	if store == nil {
+		return nil, fmt.Errorf("%w: no checkpoint store", ErrCheckpointNotFound)
+	}
+	cp, err := store.LoadCheckpoint(runID)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Status == SwarmCompleted {
+		return nil, fmt.Errorf("%w: %s", ErrSwarmFinished, runID)
+	}
+
+	c.log().Info("swarm resumed", "run", runID, "completed", completedSteps(cp.Run))
+	if cp.Pipeline != nil {
+		return c.executePipeline(ctx, cp.Pipeline, cp.Task(), cp)
+	}
+	return c.executeSwarm(ctx, cp.Task(), cp)
+}
+
+// newCheckpoint starts the checkpoint of a swarm run of task
+func newCheckpoint(task *agent.Task, pipeline *SwarmPipeline) *SwarmCheckpoint {
+	now := time.Now()
+	return &SwarmCheckpoint{
+		RunID:       task.ID,
+		Description: task.Description,
+		Complexity:  task.Complexity,
+		Team:        task.Team,
+		Submitter:   task.Submitter,
+		Pipeline:    pipeline,
+		Status:      SwarmRunning,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// saveCheckpoint saves a run's progress, if the collective keeps it. The
+// run goes on if saving fails; it just can't be resumed from this point.
+func (c *Collective) saveCheckpoint(cp *SwarmCheckpoint) {
+	store := c.checkpointStore()
+	if store == nil {
+		return
+	}
+	cp.UpdatedAt = time.Now()
+	if err := store.SaveCheckpoint(cp); err != nil {
+		c.log().Warn("saving swarm checkpoint failed", "run", cp.RunID, "error", err)
+	}
+}
+
+// finishCheckpoint records how a run ended
+func (c *Collective) finishCheckpoint(cp *SwarmCheckpoint, swarm *SwarmResult, err error) {
+	if swarm != nil {
+		if swarm.Run != nil {
+			cp.Run = swarm.Run
+		}
+		cp.TokensUsed = swarm.TokensUsed
+		cp.Output = swarm.Output
+	}
+	cp.Status, cp.Error = SwarmCompleted, ""
+	if err != nil {
+		cp.Status, cp.Error = SwarmFailed, err.Error()
+	}
+	c.saveCheckpoint(cp)
+}
+
+// completedSteps counts the steps a run completed
+func completedSteps(run *workflow.Run) int {
+	if run == nil {
+		return 0
+	}
+	return len(run.Completed)
+}
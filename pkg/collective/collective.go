@@ -2,7 +2,9 @@ package collective
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -10,6 +12,9 @@ import (
 
 	"github.com/square-mind/squaremind/pkg/agent"
 	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/logging"
+	"github.com/square-mind/squaremind/pkg/storage"
 )
 
 var (
@@ -22,11 +27,17 @@ type Collective struct {
 	mu sync.RWMutex
 
 	// Identity
-	Name string
-	ID   string
+	Name     string
+	ID       string
+	identity *identity.SquaremindIdentity // Signs attestations; its SID is ID
 
 	// Agents
-	agents map[string]*agent.Agent // SID -> Agent
+	agents            map[string]*agent.Agent      // SID -> Agent
+	membershipVersion uint64                       // Incremented on every join/leave
+	keys              map[string]ed25519.PublicKey // SID -> public key of every agent that joined, kept after it leaves
+
+	// Lifecycle; runCtx is set while the collective is running
+	runCtx context.Context
 
 	// Coordination
 	gossip     *coordination.GossipProtocol
@@ -34,16 +45,100 @@ type Collective struct {
 	consensus  *coordination.ConsensusEngine
 	reputation *coordination.ReputationRegistry
 
+	// Teams, the rules routing tasks to them, and token budgets
+	teams   map[string]*Team
+	routes  map[string]*RoutingRule
+	budgets map[string]*Budget
+
+	// Token spend by agent and cost tag
+	usage *usageLedger
+
 	// Shared Memory
 	memory *CollectiveMemory
 
+	// Activity stream
+	events       *EventBus
+	timelines    *TimelineStore
+	explanations *explanationStore
+
 	// Configuration
-	config CollectiveConfig
+	config          CollectiveConfig
+	assignmentVoter AssignmentVoter
+	checkpoints     CheckpointStore // Where swarm runs save their progress (nil = not saved)
+	judge           agent.Critic    // Scores model comparisons (nil = the models review each other)
+
+	// Consensus-gated parameter changes, and proposers waiting for theirs to apply
+	parameterVoter ParameterVoter
+	parameterWaits map[string]chan struct{}
+
+	// Logging
+	logger  logging.Logger
+	logBase logging.Logger // Set by SetLogger; nil uses the default component loggers
+
+	// Quality-of-service classes
+	qos *qosState
+
+	// Summaries of finished tasks and the schedules posting their digests
+	digests *digestLog
+
+	// Turns of review stages (nil = reviews go to the best bid)
+	reviews *reviewRotation
+
+	// Lineage, team and bidding history behind conflicts of interest, and
+	// the policy applying them to reviews (nil = not checked)
+	conflicts    *conflictTracker
+	antiAffinity *AntiAffinityPolicy
+
+	// Credentials the tasks of agents without their own keyring execute with
+	keyring *agent.Keyring
+
+	// Models of capabilities agents don't assign models themselves
+	models agent.ModelMap
 
 	// Task tracking
-	pendingTasks   []*agent.Task
+	queue          *FairQueue
+	pending        *taskQueue
 	activeTasks    map[string]*agent.Task
 	completedTasks []*agent.TaskResult
+	requeue        map[string]chan struct{}   // Task ID -> closed when its agent leaves before starting it
+	vouches        map[string]*vouch          // Vouched-for agent SID -> stake held
+	escrow         map[string]*escrowedStake  // Task ID -> stake its agent bid, until settled
+	pins           map[string]string          // Task ID -> agent SID it must run on, bypassing the market
+	reserved       map[string]int             // Agent SID -> dispatched tasks it hasn't returned
+	released       chan struct{}              // Closed and replaced whenever a reservation ends
+	preemptions    map[string]*preemption     // Preempted task ID -> the task it made way for
+	lateAgents     map[string]map[string]bool // Task ID -> agents it ran past its deadline on
+
+	// Swarm orchestrator SID (empty = chosen by the market)
+	orchestrator string
+
+	// Storage backend for results, artifacts, reputation, memory and the
+	// audit log (nil = the path settings or in-memory only)
+	store storage.Storage
+
+	// Capability gaps last published, as sorted "capability:kind" pairs
+	gapKinds string
+
+	// Pause and maintenance mode: resumed is closed when dispatching resumes
+	// (nil while running) and held counts the submissions waiting for it
+	mode    ModeStatus
+	resumed chan struct{}
+	held    int
+
+	// Replication epoch, raised each time a standby is promoted; fencedBy is
+	// the collective whose promotion fenced this one off (empty = not fenced)
+	epoch    uint64
+	fencedBy string
+
+	// Activity behind the health report
+	health *healthTracker
+
+	// Liveness: each member's last heartbeat, and the manager that respawns
+	// agents removed as unresponsive (nil = no respawn)
+	beats     map[string]agent.Heartbeat
+	lifecycle *agent.LifecycleManager
+
+	decayedAt time.Time // When maintenance last applied reputation decay
 }
 
 // CollectiveConfig holds collective configuration
@@ -51,7 +146,116 @@ type CollectiveConfig struct {
 	MinAgents          int     `json:"min_agents"`
 	MaxAgents          int     `json:"max_agents"`
 	ConsensusThreshold float64 `json:"consensus_threshold"` // e.g., 0.67 for 2/3
-	ReputationDecay    float64 `json:"reputation_decay"`    // Daily decay rate
+	ReputationDecay    float64 `json:"reputation_decay"`    // Share of an idle agent's reputation lost per day (0 = no decay)
+
+	ReputationDecayCurve agent.DecayCurve `json:"reputation_decay_curve,omitempty"` // linear (default), exponential or none
+
+	AssignmentMode AssignmentMode `json:"assignment_mode,omitempty"` // "market" (default) or "consensus"
+
+	// Auction is the market's strategy for tasks that don't name one:
+	// weighted (default), sealed_bid, vickrey or reverse
+	Auction string `json:"auction,omitempty"`
+
+	// TieBreak orders bids the auction ranks level: capability_score
+	// (default), least_recently_assigned, lowest_cost or lottery
+	TieBreak string `json:"tie_break,omitempty"`
+
+	// BidThreshold is the capability score needed to bid and how it is
+	// relaxed when nobody bids (nil = a fixed coordination.MinCapabilityScore)
+	BidThreshold *coordination.BidThreshold `json:"bid_threshold,omitempty"`
+
+	// StakeReward is the share of the stake an agent's winning bid commits
+	// that is added to its reputation when it completes the task on time; a
+	// failed or late task forfeits the stake (0 = DefaultStakeReward,
+	// negative = no reward)
+	StakeReward float64 `json:"stake_reward,omitempty"`
+
+	// Fair scheduling: at most MaxConcurrentTasks run at once, each on its own
+	// agent (0 = unlimited), shared between submitters in proportion to their
+	// weights (default 1)
+	MaxConcurrentTasks int            `json:"max_concurrent_tasks,omitempty"`
+	SubmitterWeights   map[string]int `json:"submitter_weights,omitempty"`
+
+	// Waiting tasks gain priority over time (zero value = DefaultPriorityAging,
+	// negative Interval disables aging)
+	PriorityAging PriorityAging `json:"priority_aging,omitempty"`
+
+	// Reputation is restored from this file on creation and saved on Stop and
+	// during maintenance (empty = in-memory only)
+	ReputationPath string `json:"reputation_path,omitempty"`
+
+	// MemoryPath is an SQLite database that collective memory is restored
+	// from and written to (empty = in-memory only)
+	MemoryPath string `json:"memory_path,omitempty"`
+
+	// Storage keeps task results, artifacts, reputation, memory and an
+	// audit log in a storage backend, in place of ReputationPath and
+	// MemoryPath (nil = not kept)
+	Storage *storage.Config `json:"storage,omitempty"`
+
+	// Episodes keeps episodic memory in an S3-compatible bucket, apart from
+	// the rest of memory, so history isn't bounded by one database and
+	// instances sharing the bucket share it (nil = with the rest of memory)
+	Episodes *EpisodeStoreConfig `json:"episodes,omitempty"`
+
+	// Admission gates Join on proof of work or a member's voucher (nil = open)
+	Admission *AdmissionPolicy `json:"admission,omitempty"`
+
+	// Swarm bounds task decomposition by ExecuteSwarm (zero value = defaults)
+	Swarm SwarmConfig `json:"swarm,omitempty"`
+
+	// TokenBudget is the LLM token allowance whose headroom counts towards
+	// the health score (0 = unlimited)
+	TokenBudget int `json:"token_budget,omitempty"`
+
+	// Heartbeat sets how unresponsive agents are detected (zero value = defaults)
+	Heartbeat HeartbeatConfig `json:"heartbeat,omitempty"`
+
+	// Gossip bounds the adaptive fanout, TTL and interval (zero value = defaults)
+	Gossip coordination.GossipConfig `json:"gossip,omitempty"`
+
+	// Gaps tunes the capability gap analysis run during maintenance (zero
+	// value = defaults)
+	Gaps GapConfig `json:"gaps,omitempty"`
+
+	// Preemption lets urgent tasks take agents from running low-priority
+	// ones (nil = tasks run to completion once assigned)
+	Preemption *PreemptionPolicy `json:"preemption,omitempty"`
+
+	// Deadlines orders waiting tasks by deadline, cancels tasks that run
+	// past theirs and retries or reassigns them (nil = deadlines only
+	// expire market listings and forfeit stakes)
+	Deadlines *DeadlinePolicy `json:"deadlines,omitempty"`
+
+	// QoS adds quality-of-service classes or replaces the policies of the
+	// built-in ones (nil = DefaultQoSPolicies)
+	QoS map[agent.QoSClass]QoSPolicy `json:"qos,omitempty"`
+
+	// Digests posts daily or weekly task digests to the notifiers
+	// registered with OnDigest
+	Digests []DigestSchedule `json:"digests,omitempty"`
+
+	// Reviews rotates review stages between the agents able to do them
+	// (nil = they go to the best bid like any task)
+	Reviews *ReviewRotation `json:"reviews,omitempty"`
+
+	// AntiAffinity checks the agent assigned a task that names an author for
+	// conflicts of interest with it (nil = not checked)
+	AntiAffinity *AntiAffinityPolicy `json:"anti_affinity,omitempty"`
+
+	// Keyring binds provider keys and tool credentials to capabilities and
+	// teams for agents that join without one of their own (nil = agents use
+	// their own providers)
+	Keyring *agent.Keyring `json:"-"`
+
+	// Models assigns models to capabilities for the tasks of agents that
+	// don't assign that capability a model themselves (nil = each agent's
+	// model)
+	Models agent.ModelMap `json:"models,omitempty"`
+
+	// Key is the collective's own signing key for attestations (nil = a
+	// fresh key each time the collective is created)
+	Key ed25519.PrivateKey `json:"-"`
 }
 
 // DefaultCollectiveConfig returns sensible defaults
@@ -61,28 +265,210 @@ func DefaultCollectiveConfig() CollectiveConfig {
 		MaxAgents:          100,
 		ConsensusThreshold: 0.67,
 		ReputationDecay:    0.01,
+		AssignmentMode:     AssignmentMarket,
+		PriorityAging:      DefaultPriorityAging(),
+		Swarm:              DefaultSwarmConfig(),
 	}
 }
 
 // NewCollective creates a new collective
 func NewCollective(name string, cfg CollectiveConfig) *Collective {
-	return &Collective{
-		Name:           name,
-		ID:             uuid.New().String(),
-		agents:         make(map[string]*agent.Agent),
-		gossip:         coordination.NewGossipProtocol(),
-		market:         coordination.NewTaskMarket(),
-		consensus:      coordination.NewConsensusEngine(cfg.ConsensusThreshold),
-		reputation:     coordination.NewReputationRegistry(),
-		memory:         NewCollectiveMemory(),
-		config:         cfg,
-		activeTasks:    make(map[string]*agent.Task),
-		pendingTasks:   make([]*agent.Task, 0),
-		completedTasks: make([]*agent.TaskResult, 0),
-	}
-}
-
-// Join adds an agent to the collective
+	c := &Collective{
+		Name:            name,
+		ID:              uuid.New().String(),
+		agents:          make(map[string]*agent.Agent),
+		keys:            make(map[string]ed25519.PublicKey),
+		gossip:          coordination.NewGossipProtocol(),
+		market:          coordination.NewTaskMarket(),
+		consensus:       coordination.NewConsensusEngine(cfg.ConsensusThreshold),
+		reputation:      coordination.NewReputationRegistry(),
+		teams:           make(map[string]*Team),
+		routes:          make(map[string]*RoutingRule),
+		budgets:         make(map[string]*Budget),
+		usage:           newUsageLedger(),
+		memory:          NewCollectiveMemory(),
+		events:          NewEventBus(256),
+		timelines:       NewTimelineStore(1000),
+		explanations:    newExplanationStore(1000),
+		config:          cfg,
+		assignmentVoter: DefaultAssignmentVoter,
+		parameterVoter:  DefaultParameterVoter,
+		parameterWaits:  make(map[string]chan struct{}),
+		qos:             newQoSState(cfg.QoS),
+		digests:         newDigestLog(),
+		conflicts:       newConflictTracker(),
+		queue:           NewFairQueue(cfg.MaxConcurrentTasks),
+		activeTasks:     make(map[string]*agent.Task),
+		pending:         newTaskQueue(),
+		completedTasks:  make([]*agent.TaskResult, 0),
+		requeue:         make(map[string]chan struct{}),
+		vouches:         make(map[string]*vouch),
+		escrow:          make(map[string]*escrowedStake),
+		pins:            make(map[string]string),
+		reserved:        make(map[string]int),
+		released:        make(chan struct{}),
+		preemptions:     make(map[string]*preemption),
+		lateAgents:      make(map[string]map[string]bool),
+		mode:            ModeStatus{Mode: ModeRunning, Since: time.Now()},
+		health:          newHealthTracker(),
+		beats:           make(map[string]agent.Heartbeat),
+		logger:          logging.Component("collective"),
+	}
+	c.logger = c.logger.With("collective", name)
+	ident, err := newCollectiveIdentity(c.ID, name, cfg.Key)
+	if err != nil {
+		c.logger.Warn("signing attestations with a fresh key", "error", err)
+		ident, _ = newCollectiveIdentity(c.ID, name, nil)
+	}
+	c.identity = ident
+	c.market.SetAgentLookup(c.GetAgent)
+	if cfg.Auction != "" {
+		if strategy, err := coordination.NewAuctionStrategy(cfg.Auction); err != nil {
+			c.logger.Warn("keeping the default auction strategy", "error", err)
+		} else {
+			c.market.SetAuction(strategy)
+		}
+	}
+	if cfg.TieBreak != "" {
+		if tb, err := coordination.NewTieBreaker(cfg.TieBreak); err != nil {
+			c.logger.Warn("keeping the default tie-breaker", "error", err)
+		} else {
+			c.market.SetTieBreaker(tb)
+		}
+	}
+	curve, err := agent.ParseDecayCurve(string(cfg.ReputationDecayCurve))
+	if err != nil {
+		c.logger.Warn("using linear reputation decay", "error", err)
+		curve = agent.DecayLinear
+	}
+	c.reputation.SetDecay(curve, cfg.ReputationDecay)
+	if cfg.Deadlines != nil {
+		if err := cfg.Deadlines.Validate(); err != nil {
+			c.logger.Warn("deadlines not enforced", "error", err)
+			c.config.Deadlines = nil
+		} else {
+			c.queue.SetEDF(cfg.Deadlines.EDF)
+		}
+	}
+	if cfg.BidThreshold != nil {
+		if err := c.market.SetBidThreshold(*cfg.BidThreshold); err != nil {
+			c.logger.Warn("keeping the default bid threshold", "error", err)
+		}
+	}
+
+	if cfg.Reviews != nil {
+		c.reviews = newReviewRotation(*cfg.Reviews)
+	}
+	if cfg.AntiAffinity != nil {
+		policy := cfg.AntiAffinity.withDefaults()
+		c.antiAffinity = &policy
+	}
+	c.keyring = cfg.Keyring
+	c.models = cfg.Models
+	for _, schedule := range cfg.Digests {
+		if err := c.ScheduleDigest(schedule); err != nil {
+			c.logger.Warn("skipping digest schedule", "period", schedule.Period, "error", err)
+		}
+	}
+
+	for submitter, weight := range cfg.SubmitterWeights {
+		c.queue.SetWeight(submitter, weight)
+	}
+	if cfg.PriorityAging != (PriorityAging{}) {
+		c.queue.SetAging(cfg.PriorityAging)
+	}
+	if cfg.Gossip != (coordination.GossipConfig{}) {
+		c.gossip.SetConfig(cfg.Gossip)
+	}
+	if cfg.Storage != nil {
+		if err := c.openStorage(*cfg.Storage); err != nil {
+			c.logger.Warn("could not open storage", "driver", cfg.Storage.Driver, "error", err)
+		}
+	} else if cfg.ReputationPath != "" {
+		if err := c.reputation.LoadFile(cfg.ReputationPath); err != nil {
+			c.logger.Warn("could not restore reputation", "path", cfg.ReputationPath, "error", err)
+		}
+	}
+	if cfg.Storage == nil && cfg.MemoryPath != "" {
+		if err := c.openMemory(cfg.MemoryPath); err != nil {
+			c.logger.Warn("could not open memory store, keeping memory in-process", "path", cfg.MemoryPath, "error", err)
+		}
+	}
+	if cfg.Episodes != nil {
+		if err := c.openEpisodes(*cfg.Episodes); err != nil {
+			c.logger.Warn("could not open episode store, keeping episodes with the rest of memory", "bucket", cfg.Episodes.S3.Bucket, "error", err)
+		}
+	}
+
+	c.market.OnBid(c.publishBid)
+	c.consensus.OnAccept(c.onProposalAccepted)
+	c.gossip.OnMessage(coordination.MsgHeartbeat, c.observeHeartbeat)
+
+	c.reputation.OnChange(func(e coordination.ReputationEvent) {
+		c.events.Publish(Event{
+			Type:     EventReputationChanged,
+			AgentSID: e.AgentSID,
+			Data: map[string]interface{}{
+				"change": e.Type,
+				"delta":  e.Delta,
+				"reason": e.Reason,
+			},
+		})
+	})
+
+	return c
+}
+
+// SetLogger replaces the logger used by the collective and its coordination
+// components. Each component logs with its own "component" attribute.
+func (c *Collective) SetLogger(l logging.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.logBase = l
+	c.logger = c.componentLoggerLocked("collective").With("collective", c.Name)
+	c.gossip.SetLogger(c.componentLoggerLocked("gossip"))
+	c.market.SetLogger(c.componentLoggerLocked("market"))
+	c.consensus.SetLogger(c.componentLoggerLocked("consensus"))
+	c.reputation.SetLogger(c.componentLoggerLocked("reputation"))
+	for name, team := range c.teams {
+		team.market.SetLogger(c.componentLoggerLocked("market").With("team", name))
+		team.consensus.SetLogger(c.componentLoggerLocked("consensus").With("team", name))
+	}
+}
+
+// componentLoggerLocked returns a logger for a named component. Caller must hold c.mu.
+func (c *Collective) componentLoggerLocked(component string) logging.Logger {
+	if c.logBase == nil {
+		return logging.Component(component)
+	}
+	return c.logBase.With("component", component)
+}
+
+// log returns the collective's logger
+func (c *Collective) log() logging.Logger {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logger
+}
+
+// publishBid publishes a market bid as a collective event
+func (c *Collective) publishBid(bid *coordination.Bid) {
+	c.timelines.Record(bid.TaskID, StageBid, bid.AgentSID, fmt.Sprintf("capability %.2f", bid.CapabilityScore))
+	c.events.Publish(Event{
+		Type:     EventBidPlaced,
+		AgentSID: bid.AgentSID,
+		TaskID:   bid.TaskID,
+		Data: map[string]interface{}{
+			"capability_score": bid.CapabilityScore,
+			"reputation_stake": bid.ReputationStake,
+			"estimated_time":   bid.EstimatedTime.String(),
+		},
+	})
+}
+
+// Join adds an agent to the collective. Agents joining a running collective
+// are started with the collective's context.
 func (c *Collective) Join(a *agent.Agent) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -90,43 +476,159 @@ func (c *Collective) Join(a *agent.Agent) error {
 	if len(c.agents) >= c.config.MaxAgents {
 		return ErrCollectiveFull
 	}
+	if err := c.admitLocked(a); err != nil {
+		c.logger.Warn("agent refused admission", "agent", a.Identity.SID, "name", a.Identity.Name, "error", err)
+		return err
+	}
+	c.checkProofsLocked(a)
+
+	if c.runCtx != nil {
+		if err := a.Start(c.runCtx); err != nil {
+			return err
+		}
+	}
+
+	sid := a.Identity.SID
+	a.SetSharedMemory(c.memory)
+	if c.keyring != nil && a.Keyring() == nil {
+		a.SetKeyring(c.keyring)
+	}
+	if len(c.models) > 0 {
+		a.SetDefaultModels(c.models)
+	}
+	a.OnTaskStart(func(task *agent.Task) {
+		c.timelines.Record(task.ID, StageRunning, sid, "")
+	})
 
-	c.agents[a.Identity.SID] = a
-	c.gossip.AddPeer(a.Identity.SID)
-	c.reputation.Register(a.Identity.SID, a.Reputation)
+	c.agents[sid] = a
+	c.keys[sid] = a.Identity.PublicKey
+	c.conflicts.noteParent(sid, a.Identity.ParentSID)
+	c.watchLocked(a)
+	c.reputation.Register(sid, a.Reputation)
+	a.SetReputationGuard(c.reputation.Guard)
+	c.publishMembershipLocked()
+	c.logger.Info("agent joined", "agent", sid, "name", a.Identity.Name, "size", len(c.agents))
 
 	// Broadcast join to other agents
 	c.gossip.Broadcast(coordination.Message{
-		Type:    coordination.MsgAgentJoined,
-		From:    a.Identity.SID,
-		Payload: a.Identity,
+		Type: coordination.MsgAgentJoined,
+		From: a.Identity.SID,
+		Payload: coordination.AgentJoined{
+			SID:        a.Identity.SID,
+			Name:       a.Identity.Name,
+			PublicKey:  a.Identity.PublicKey,
+			ParentSID:  a.Identity.ParentSID,
+			Generation: a.Identity.Generation,
+			CreatedAt:  a.Identity.CreatedAt,
+		},
+	})
+
+	c.events.Publish(Event{
+		Type:     EventAgentJoined,
+		AgentSID: a.Identity.SID,
+		Data:     map[string]interface{}{"name": a.Identity.Name},
 	})
 
 	return nil
 }
 
-// Leave removes an agent from the collective
+// Leave removes an agent from the collective. If the collective is running the
+// agent is stopped: its in-flight task finishes and queued tasks are reassigned.
 func (c *Collective) Leave(sid string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if _, exists := c.agents[sid]; !exists {
+	a, exists := c.agents[sid]
+	if !exists {
 		return ErrAgentNotFound
 	}
 
 	delete(c.agents, sid)
-	c.gossip.RemovePeer(sid)
+	delete(c.beats, sid)
+	if c.orchestrator == sid {
+		c.orchestrator = ""
+	}
+	c.releaseVouchLocked(sid)
+	c.refundAgentStakesLocked(sid)
 	c.reputation.Unregister(sid)
+	c.removeFromTeamsLocked(sid)
+	if c.reviews != nil {
+		c.reviews.forget(sid)
+	}
+	c.publishMembershipLocked()
+
+	if c.runCtx != nil {
+		a.Stop()
+		c.requeueLocked(a.DrainQueue())
+	}
+	c.logger.Info("agent left", "agent", sid, "size", len(c.agents))
 
 	// Broadcast leave
 	c.gossip.Broadcast(coordination.Message{
-		Type: coordination.MsgAgentLeft,
-		From: sid,
+		Type:    coordination.MsgAgentLeft,
+		From:    sid,
+		Payload: coordination.AgentLeft{SID: sid},
+	})
+
+	c.events.Publish(Event{
+		Type:     EventAgentLeft,
+		AgentSID: sid,
 	})
 
 	return nil
 }
 
+// publishMembershipLocked bumps the membership version, rebalances gossip peers
+// and gossips the new view. Caller must hold c.mu.
+func (c *Collective) publishMembershipLocked() {
+	c.membershipVersion++
+
+	members := make([]string, 0, len(c.agents))
+	for sid := range c.agents {
+		members = append(members, sid)
+	}
+	view := coordination.MembershipView{
+		Version: c.membershipVersion,
+		Members: members,
+	}
+
+	c.gossip.ApplyMembership(view)
+	c.gossip.Broadcast(coordination.Message{
+		Type:    coordination.MsgMembership,
+		Payload: view,
+	})
+}
+
+// requeueLocked hands tasks drained from a departing agent back to their
+// submitters for reassignment. Caller must hold c.mu.
+func (c *Collective) requeueLocked(tasks []*agent.Task) {
+	for _, task := range tasks {
+		ch, ok := c.requeue[task.ID]
+		if !ok {
+			continue
+		}
+		delete(c.requeue, task.ID)
+		delete(c.activeTasks, task.ID)
+		task.Status = agent.TaskPending
+		task.AssignedTo = ""
+		c.pending.push(task)
+		close(ch)
+		c.logger.Info("task requeued", "task", task.ID)
+	}
+}
+
+// removePendingLocked drops a task from the pending queue. Caller must hold c.mu.
+func (c *Collective) removePendingLocked(taskID string) {
+	c.pending.remove(taskID)
+}
+
+// MembershipVersion returns the current membership version
+func (c *Collective) MembershipVersion() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.membershipVersion
+}
+
 // GetAgent returns an agent by SID
 func (c *Collective) GetAgent(sid string) (*agent.Agent, bool) {
 	c.mu.RLock()
@@ -136,58 +638,365 @@ func (c *Collective) GetAgent(sid string) (*agent.Agent, bool) {
 	return a, ok
 }
 
-// Submit submits a task to the collective
+// Submit submits a task to the collective and blocks until it finishes.
+//
+// Deprecated: Submit cannot be cancelled and waits indefinitely for the
+// assigned agent. Use SubmitCtx.
 func (c *Collective) Submit(task *agent.Task) (*agent.TaskResult, error) {
+	return c.SubmitCtx(context.Background(), task)
+}
+
+// SubmitCtx submits a task to the collective and waits for its result. The
+// task's QoS class sets its deadline, model, response limit, retry budget,
+// share of the execution slots and dispatch rate where the task doesn't; an
+// unknown class fails with ErrUnknownQoSClass. A
+// task without a team is routed by the routing rules, and is refused with
+// ErrBudgetExceeded if a budget it counts against is used up. While the
+// collective is paused the task waits to be dispatched. If ctx
+// is cancelled or times out first, the agent's LLM call is cancelled, the
+// agent is released, the task is recorded as failed and ctx's error is
+// returned. If the agent had produced output, tool results or checkpoints by
+// then, they are returned alongside the error in a result marked Partial.
+// Under a DeadlinePolicy that enforces deadlines, an attempt still running
+// at the task's deadline is cancelled and the task failed, retried with a
+// cheaper model or reassigned as the policy says.
+func (c *Collective) SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error) {
+	if err := c.accepting(); err != nil {
+		return nil, err
+	}
+	policy, err := c.applyQoS(task)
+	if err != nil {
+		return nil, err
+	}
+	c.route(task)
+	if err := c.checkBudgets(task); err != nil {
+		return nil, err
+	}
+
+	results := make(chan *agent.TaskResult, resultBuffer)
+	task.WithContext(ctx).WithResults(results)
+	if task.Progress() == nil {
+		task.WithProgress(agent.NewProgress())
+	}
+
 	c.mu.Lock()
-	c.pendingTasks = append(c.pendingTasks, task)
+	c.pending.push(task)
 	c.mu.Unlock()
+	c.timelines.Record(task.ID, StageSubmitted, "", task.Description)
+	if c.config.Preemption != nil {
+		defer c.endPreemptions(task.ID)
+	}
+	if c.config.Deadlines != nil {
+		defer c.forgetLate(task.ID)
+	}
+
+	// Wait for the class's dispatch rate, then a fair share of the execution
+	// slots, preempting a low-priority task for one if urgent enough
+	if err := c.throttle(ctx, task, policy); err != nil {
+		return c.abandon(task, "", err)
+	}
+	submitted := time.Now()
+	if c.queue.full() {
+		c.preemptFor(task)
+	}
+	if err := c.queue.AcquireDeadline(ctx, task.Submitter, task.Priority, policy.Weight, admissionDeadline(task, policy)); err != nil {
+		return c.abandon(task, "", err)
+	}
+	slotHeld := true
+	defer func() {
+		if slotHeld {
+			c.queue.Release()
+		}
+	}()
+	c.health.recordWait(time.Since(submitted))
+
+	// Hold the slot while the collective is paused, so the fair queue keeps
+	// its order for the submissions behind this one
+	if err := c.awaitResume(ctx, task); err != nil {
+		return c.abandon(task, "", err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return c.abandon(task, "", err)
+	}
 
 	// Broadcast task to market
 	c.gossip.Broadcast(coordination.Message{
 		Type:    coordination.MsgTaskAvailable,
-		Payload: task,
+		Payload: coordination.TaskAvailable{Task: task},
 	})
 
-	// Let market handle bidding and assignment
-	assignment, err := c.market.AssignTask(task, c.agents, c.reputation)
-	if err != nil {
-		return nil, err
+	// Reassign until an agent that stays in the collective produces a result,
+	// and after failed attempts while the class's retry budget lasts
+	var (
+		assignment *coordination.TaskAssignment
+		result     *agent.TaskResult
+		retries    int
+		late       int
+	)
+	for result == nil {
+		// Let market (and consensus, if configured) handle bidding and assignment
+		released := c.releaseSignal()
+		assignment, err = c.assign(task)
+		if errors.Is(err, coordination.ErrNoBids) {
+			// An urgent task takes a capable agent from a low-priority one
+			c.preemptFor(task)
+			if c.awaitAgent(ctx, task, released) {
+				continue
+			}
+		}
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return c.abandon(task, "", ctxErr)
+			}
+			c.timelines.Record(task.ID, StageFailed, "", err.Error())
+			if errors.Is(err, coordination.ErrNoBids) {
+				c.health.recordNoBids(task.Required, time.Now())
+			}
+			c.log().Warn("task assignment failed", "task", task.ID, "team", task.Team, "error", err)
+			c.mu.Lock()
+			c.removePendingLocked(task.ID)
+			c.mu.Unlock()
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			c.release(assignment.AgentSID)
+			return c.abandon(task, "", err)
+		}
+		result = c.dispatch(ctx, task, assignment, results, c.enforcedDeadline(task))
+		if result != nil && result.Preempted && ctx.Err() == nil {
+			if err := c.yield(ctx, task, result, policy); err != nil {
+				slotHeld = false
+				return c.abandon(task, "", err)
+			}
+			result = nil
+			continue
+		}
+		if result != nil && result.Expired && ctx.Err() == nil {
+			if late++; c.retryLate(task, result, late) {
+				result = nil
+			}
+			continue
+		}
+		if result != nil && result.Status != agent.TaskCompleted && retries < policy.Retries && ctx.Err() == nil {
+			retries++
+			c.retryFailed(task, result, retries, policy.Retries)
+			result = nil
+		}
+	}
+
+	// The submitter gave up before the agent finished
+	if err := ctx.Err(); err != nil && result.Status != agent.TaskCompleted {
+		return c.abandon(task, assignment.AgentSID, err)
 	}
 
-	// Move to active
+	// Update reputation, in step with the agent's other slots settling theirs
+	c.settleAgent(assignment.AgentSID, func() {
+		if result.Status == agent.TaskCompleted {
+			c.reputation.RecordTaskSuccess(assignment.AgentSID, result.Quality)
+			if result.Confidence > 0 {
+				c.reputation.RecordCalibration(assignment.AgentSID, result.Confidence, result.Quality)
+			}
+		} else {
+			c.reputation.RecordTaskFailure(assignment.AgentSID)
+		}
+		c.settleStake(task, result)
+	})
+	c.chargeBudgets(task, result.TokensUsed)
+	c.attributeUsage(task, result)
+	c.recordQoS(task, result, policy)
+	c.recordDigest(task, result)
+	c.settleVouch(assignment.AgentSID, result.Status == agent.TaskCompleted)
+
+	completion, stage := EventTaskCompleted, StageCompleted
+	if result.Status != agent.TaskCompleted {
+		completion, stage = EventTaskFailed, StageFailed
+	}
+	c.timelines.Record(task.ID, stage, assignment.AgentSID, fmt.Sprintf("quality %.2f in %s", result.Quality, result.Duration))
+	data := map[string]interface{}{
+		"quality":     result.Quality,
+		"duration":    result.Duration.String(),
+		"tokens_used": result.TokensUsed,
+	}
+	if task.Submitter != "" {
+		data["submitter"] = task.Submitter
+	}
+	c.events.Publish(Event{
+		Type:     completion,
+		AgentSID: assignment.AgentSID,
+		TaskID:   task.ID,
+		Data:     data,
+	})
+
+	// Record completion
+	c.persistResult(result)
 	c.mu.Lock()
-	c.activeTasks[task.ID] = task
-	task.Status = agent.TaskAssigned
-	task.AssignedTo = assignment.AgentSID
+	delete(c.activeTasks, task.ID)
+	delete(c.requeue, task.ID)
+	c.completedTasks = append(c.completedTasks, result)
 	c.mu.Unlock()
 
-	// Submit to assigned agent
-	assignedAgent := c.agents[assignment.AgentSID]
-	assignedAgent.SubmitTask(task)
+	return result, nil
+}
 
-	// Wait for result
-	result := <-assignedAgent.GetResults()
+// abandon records a task whose submitter cancelled it as failed. The agent's
+// reputation is left untouched since the agent was not at fault. Returns the
+// work salvaged from the task's progress as a partial result, or nil if the
+// agent had produced nothing.
+func (c *Collective) abandon(task *agent.Task, agentSID string, cause error) (*agent.TaskResult, error) {
+	task.Status = agent.TaskFailed
 
-	// Update reputation
-	if result.Status == agent.TaskCompleted {
-		c.reputation.RecordTaskSuccess(assignment.AgentSID, result.Quality)
-	} else {
-		c.reputation.RecordTaskFailure(assignment.AgentSID)
+	result := &agent.TaskResult{
+		TaskID:    task.ID,
+		AgentSID:  agentSID,
+		Status:    agent.TaskFailed,
+		Error:     cause.Error(),
+		Timestamp: time.Now(),
 	}
+	partial := task.Progress().Salvage(result)
+	c.persistResult(result)
 
-	// Record completion
 	c.mu.Lock()
+	c.refundStakeLocked(task.ID, "cancelled by its submitter")
+	c.removePendingLocked(task.ID)
 	delete(c.activeTasks, task.ID)
+	delete(c.requeue, task.ID)
 	c.completedTasks = append(c.completedTasks, result)
 	c.mu.Unlock()
 
-	return result, nil
+	detail := cause.Error()
+	if partial {
+		detail += fmt.Sprintf(" (partial: %d bytes output, %d tool results, %d checkpoints)", len(result.Output), len(result.ToolResults), len(result.Checkpoints))
+	}
+	c.timelines.Record(task.ID, StageFailed, agentSID, detail)
+	c.events.Publish(Event{
+		Type:     EventTaskFailed,
+		AgentSID: agentSID,
+		TaskID:   task.ID,
+		Data:     map[string]interface{}{"error": cause.Error(), "partial": partial},
+	})
+	c.log().Info("task cancelled by submitter", "task", task.ID, "agent", agentSID, "error", cause, "partial", partial)
+
+	err := fmt.Errorf("task %s: %w", task.ID, cause)
+	if !partial {
+		return nil, err
+	}
+	return result, err
+}
+
+// dispatch hands an assigned task to its agent and waits for the agent's
+// result on results, then releases the agent's reservation. Returns nil if
+// the agent left the collective before starting the task or its queue was
+// full, or a failed result
+// if ctx ends first. If due passes first, the task is expired on the agent
+// and the result is marked Expired (zero = no deadline enforced).
+func (c *Collective) dispatch(ctx context.Context, task *agent.Task, assignment *coordination.TaskAssignment, results <-chan *agent.TaskResult, due time.Time) *agent.TaskResult {
+	defer c.release(assignment.AgentSID)
+	requeued := make(chan struct{})
+
+	var expire <-chan time.Time
+	if !due.IsZero() {
+		timer := time.NewTimer(time.Until(due))
+		defer timer.Stop()
+		expire = timer.C
+	}
+
+	// Membership is checked and the task queued under the lock so a concurrent
+	// Leave either sees the task in the agent's queue or the agent is gone
+	c.mu.Lock()
+	assignedAgent, ok := c.agents[assignment.AgentSID]
+	if !ok {
+		c.mu.Unlock()
+		return nil
+	}
+	c.removePendingLocked(task.ID)
+	c.activeTasks[task.ID] = task
+	c.requeue[task.ID] = requeued
+	task.Status = agent.TaskAssigned
+	task.AssignedTo = assignment.AgentSID
+	c.timelines.Record(task.ID, StageAssigned, assignment.AgentSID, "")
+	c.escrowStakeLocked(task.ID, assignment)
+	if err := assignedAgent.TrySubmitTask(task); err != nil {
+		// The agent's queue is full: hand the task back for reassignment
+		c.refundStakeLocked(task.ID, "not queued: "+err.Error())
+		c.requeueLocked([]*agent.Task{task})
+		c.mu.Unlock()
+		c.timelines.Record(task.ID, StageRequeued, assignment.AgentSID, err.Error())
+		c.events.Publish(Event{
+			Type:     EventTaskRequeued,
+			AgentSID: assignment.AgentSID,
+			TaskID:   task.ID,
+			Data:     map[string]interface{}{"error": err.Error()},
+		})
+		return nil
+	}
+	c.mu.Unlock()
+
+	c.events.Publish(Event{
+		Type:     EventTaskAssigned,
+		AgentSID: assignment.AgentSID,
+		TaskID:   task.ID,
+		Data: map[string]interface{}{
+			"capability_score": assignment.Bid.CapabilityScore,
+			"auction":          assignment.Auction,
+			"stake":            assignment.Stake,
+		},
+	})
+
+	for {
+		select {
+		case result := <-results:
+			// A result from an agent the task was taken away from is stale
+			if result.AgentSID != assignment.AgentSID {
+				continue
+			}
+			return result
+		case <-expire:
+			expire = nil
+			c.events.Publish(Event{
+				Type:     EventTaskExpired,
+				AgentSID: assignment.AgentSID,
+				TaskID:   task.ID,
+				Data:     map[string]interface{}{"deadline": due},
+			})
+			if assignedAgent.Expire(task.ID) {
+				continue // The agent reports the cancelled call
+			}
+			return &agent.TaskResult{
+				TaskID:   task.ID,
+				AgentSID: assignment.AgentSID,
+				Status:   agent.TaskFailed,
+				Error:    agent.ErrDeadlineExceeded.Error(),
+				Expired:  true,
+			}
+		case <-requeued:
+			c.timelines.Record(task.ID, StageRequeued, assignment.AgentSID, "agent left the collective")
+			c.events.Publish(Event{
+				Type:     EventTaskRequeued,
+				AgentSID: assignment.AgentSID,
+				TaskID:   task.ID,
+			})
+			return nil
+		case <-ctx.Done():
+			// The agent sees the same context: it skips the task if still queued
+			// or cancels its LLM call if running
+			return &agent.TaskResult{
+				TaskID:   task.ID,
+				AgentSID: assignment.AgentSID,
+				Status:   agent.TaskFailed,
+				Error:    ctx.Err().Error(),
+			}
+		}
+	}
 }
 
 // SubmitAsync submits a task without waiting for result
 func (c *Collective) SubmitAsync(task *agent.Task) (string, error) {
+	if err := c.accepting(); err != nil {
+		return "", err
+	}
 	go func() {
-		_, _ = c.Submit(task)
+		_, _ = c.SubmitCtx(context.Background(), task)
 	}()
 	return task.ID, nil
 }
@@ -211,20 +1020,33 @@ func (c *Collective) Size() int {
 	return len(c.agents)
 }
 
-// Start begins collective operation
+// Start begins collective operation. Agents that join afterwards are started
+// with the same context.
 func (c *Collective) Start(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Start all coordination systems
 	go c.gossip.Start(ctx)
 	go c.market.Start(ctx)
 	go c.runMaintenanceLoop(ctx)
+	go c.runLivenessMonitor(ctx)
+	c.startTeamsLocked(ctx)
+	if c.store != nil {
+		events, _ := c.events.Subscribe()
+		go c.recordAudit(events)
+	}
 
-	// Start all agents
-	for _, a := range c.agents {
+	// Start all agents; silence before now doesn't count against them
+	for sid, a := range c.agents {
 		if err := a.Start(ctx); err != nil {
 			return err
 		}
+		c.beats[sid] = agent.Heartbeat{AgentSID: sid, State: a.GetState(), Timestamp: time.Now()}
 	}
 
+	c.runCtx = ctx
+	c.logger.Info("collective started", "agents", len(c.agents))
 	return nil
 }
 
@@ -236,10 +1058,32 @@ func (c *Collective) Stop() {
 	for _, a := range c.agents {
 		a.Stop()
 	}
+	c.runCtx = nil
 
 	c.market.Close()
+	c.closeTeamsLocked()
+	c.events.Close()
+	if err := c.SaveReputation(); err != nil {
+		c.logger.Warn("could not save reputation", "path", c.config.ReputationPath, "error", err)
+	}
+	c.logger.Info("collective stopped")
+}
+
+// SaveReputation writes the reputation registry to the configured storage
+// or ReputationPath. It is a no-op if neither is configured.
+func (c *Collective) SaveReputation() error {
+	if c.store != nil {
+		return c.reputation.SaveTo(context.Background(), c.store.Documents())
+	}
+	if c.config.ReputationPath == "" {
+		return nil
+	}
+	return c.reputation.SaveFile(c.config.ReputationPath)
 }
 
+// reputationDecayInterval is how often maintenance applies reputation decay
+const reputationDecayInterval = time.Hour
+
 // runMaintenanceLoop handles periodic collective maintenance
 func (c *Collective) runMaintenanceLoop(ctx context.Context) {
 	ticker := time.NewTicker(time.Minute)
@@ -251,6 +1095,8 @@ func (c *Collective) runMaintenanceLoop(ctx context.Context) {
 			return
 		case <-ticker.C:
 			c.maintenance()
+			c.checkGaps()
+			c.postDigests(time.Now())
 		}
 	}
 }
@@ -260,18 +1106,74 @@ func (c *Collective) maintenance() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Apply reputation decay
-	c.reputation.ApplyDecayAll()
+	// Apply reputation decay. It depends only on idle time, so applying it
+	// less often than every tick just records fewer decay events.
+	if now := time.Now(); now.Sub(c.decayedAt) >= reputationDecayInterval {
+		c.reputation.ApplyDecayAll()
+		c.decayedAt = now
+	}
+
+	if err := c.SaveReputation(); err != nil {
+		c.logger.Warn("could not save reputation", "path", c.config.ReputationPath, "error", err)
+	}
+}
 
-	// Reassign stalled tasks
-	for id, task := range c.activeTasks {
-		if !task.Deadline.IsZero() && time.Since(task.CreatedAt) > task.Deadline.Sub(task.CreatedAt)*2 {
-			// Task is taking too long, consider reassignment
-			delete(c.activeTasks, id)
-			task.Status = agent.TaskPending
-			c.pendingTasks = append(c.pendingTasks, task)
-		}
+// Events returns a channel of collective activity events. The channel is
+// closed when the collective stops; use SubscribeEvents to unsubscribe earlier.
+func (c *Collective) Events() <-chan Event {
+	ch, _ := c.events.Subscribe()
+	return ch
+}
+
+// SubscribeEvents returns a channel of collective activity events and a
+// function that ends the subscription
+func (c *Collective) SubscribeEvents() (<-chan Event, func()) {
+	return c.events.Subscribe()
+}
+
+// Timeline returns the recorded journey of a task through the collective
+func (c *Collective) Timeline(taskID string) ([]TimelineEntry, bool) {
+	return c.timelines.Get(taskID)
+}
+
+// RecordTimeline appends a stage to a task's timeline. Components outside the
+// collective, such as tool runners and reviewers, use it to report progress.
+func (c *Collective) RecordTimeline(taskID string, stage TimelineStage, actor, detail string) {
+	c.timelines.Record(taskID, stage, actor, detail)
+}
+
+// openMemory replaces the in-process memory with one persisted at path
+func (c *Collective) openMemory(path string) error {
+	store, err := OpenSQLiteMemoryStore(path)
+	if err != nil {
+		return err
+	}
+	memory, err := NewPersistentMemory(store)
+	if err != nil {
+		store.Close()
+		return err
+	}
+	c.memory = memory
+	return nil
+}
+
+// openEpisodes replaces the memory with one keeping its episodes in the
+// configured bucket and the rest where it was (in process if nowhere)
+func (c *Collective) openEpisodes(cfg EpisodeStoreConfig) error {
+	episodes, err := OpenEpisodeStore(cfg)
+	if err != nil {
+		return err
 	}
+	base := c.memory.store
+	if base == nil {
+		base = NewDocumentMemoryStore(storage.NewMemory().Documents())
+	}
+	memory, err := NewPersistentMemory(WithEpisodes(base, episodes))
+	if err != nil {
+		return err
+	}
+	c.memory = memory
+	return nil
 }
 
 // GetMemory returns the collective memory
@@ -289,11 +1191,27 @@ func (c *Collective) GetGossip() *coordination.GossipProtocol {
 	return c.gossip
 }
 
+// GetKeyring returns the keyring agents without their own resolve credentials
+// from (nil if none)
+func (c *Collective) GetKeyring() *agent.Keyring {
+	return c.keyring
+}
+
 // GetMarket returns the task market
 func (c *Collective) GetMarket() *coordination.TaskMarket {
 	return c.market
 }
 
+// GetQueue returns the fair-share admission queue
+func (c *Collective) GetQueue() *FairQueue {
+	return c.queue
+}
+
+// SetSubmitterWeight sets a submitter's share of the execution slots
+func (c *Collective) SetSubmitterWeight(submitter string, weight int) {
+	c.queue.SetWeight(submitter, weight)
+}
+
 // GetConsensus returns the consensus engine
 func (c *Collective) GetConsensus() *coordination.ConsensusEngine {
 	return c.consensus
@@ -307,6 +1225,12 @@ type CollectiveStats struct {
 	CompletedTasks int
 	PendingTasks   int
 	AvgReputation  float64
+	Teams          int
+	QueuedTasks    int // Waiting for a fair-share execution slot
+	RejectedTasks  int // Refused by a full agent queue (and reassigned)
+	DroppedTasks   int // Evicted from agent queues by newer tasks
+	DroppedResults int // Refused or evicted by full result channels
+	Mode           RunMode
 }
 
 // Stats returns current collective statistics
@@ -314,12 +1238,22 @@ func (c *Collective) Stats() CollectiveStats {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	return CollectiveStats{
+	stats := CollectiveStats{
 		Name:           c.Name,
 		AgentCount:     len(c.agents),
 		ActiveTasks:    len(c.activeTasks),
 		CompletedTasks: len(c.completedTasks),
-		PendingTasks:   len(c.pendingTasks),
+		PendingTasks:   c.pending.Len(),
 		AvgReputation:  c.reputation.AverageReputation(),
+		Teams:          len(c.teams),
+		QueuedTasks:    c.queue.queued(),
+		Mode:           c.mode.Mode,
+	}
+	for _, a := range c.agents {
+		q := a.QueueStats()
+		stats.RejectedTasks += q.RejectedTasks
+		stats.DroppedTasks += q.DroppedTasks
+		stats.DroppedResults += q.DroppedResults
 	}
+	return stats
 }
@@ -2,13 +2,76 @@ package collective
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/benchmark"
+	"github.com/square-mind/squaremind/pkg/coordination"
 	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/logging"
+	"github.com/square-mind/squaremind/pkg/storage"
 )
 
+// blockingProvider blocks Complete until released or cancelled
+type blockingProvider struct {
+	release chan struct{}
+}
+
+func (p *blockingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	select {
+	case <-p.release:
+		return &llm.CompletionResponse{Content: "done"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *blockingProvider) Name() string {
+	return "blocking"
+}
+
+// stallingProvider streams a delta and a checkpoint, then stalls until cancelled
+type stallingProvider struct{}
+
+func (p *stallingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return p.Stream(ctx, req, nil)
+}
+
+func (p *stallingProvider) Stream(ctx context.Context, req llm.CompletionRequest, onDelta func(string)) (*llm.CompletionResponse, error) {
+	if onDelta != nil {
+		onDelta("first draft")
+	}
+	agent.ProgressFromContext(ctx).Checkpoint("draft", "intro written")
+	<-ctx.Done()
+	return &llm.CompletionResponse{Content: "first draft"}, ctx.Err()
+}
+
+func (p *stallingProvider) Name() string {
+	return "stalling"
+}
+
+// waitFor polls cond until it holds or the timeout elapses
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
 func TestNewCollective(t *testing.T) {
 	cfg := CollectiveConfig{
 		MinAgents:          2,
@@ -151,6 +214,66 @@ func TestCollective_Stats(t *testing.T) {
 	}
 }
 
+func TestCollective_StatsQueueOverflow(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Queue: &agent.QueueConfig{TaskCapacity: 1}})
+	_ = c.Join(a)
+	_ = a.SubmitTask(agent.NewTask("First", nil))
+	_ = a.SubmitTask(agent.NewTask("Second", nil))
+
+	if stats := c.Stats(); stats.RejectedTasks != 1 {
+		t.Errorf("Expected 1 rejected task, got %d", stats.RejectedTasks)
+	}
+}
+
+func TestCollective_Profile(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+
+	a, _ := agent.NewAgent(agent.AgentConfig{
+		Name:         "Profiled",
+		Model:        "test-model",
+		Capabilities: []identity.CapabilityType{identity.CapResearch, identity.CapCodeWrite},
+	})
+	_ = c.Join(a)
+	for i := 0; i < 12; i++ {
+		c.GetReputation().RecordTaskSuccess(a.Identity.SID, 0.9)
+	}
+	task := agent.NewTask("Research", nil)
+	c.attributeUsage(task, &agent.TaskResult{TaskID: task.ID, AgentSID: a.Identity.SID, Status: agent.TaskCompleted, TokensUsed: 30})
+	c.attributeUsage(task, &agent.TaskResult{TaskID: task.ID, AgentSID: "other", Status: agent.TaskCompleted, TokensUsed: 10})
+
+	p, err := c.Profile(a.Identity.SID, 0)
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	if p.Name != "Profiled" || p.PublicKey != a.Identity.PublicKeyHex() || p.Model != "test-model" {
+		t.Errorf("Expected the agent's identity, got %+v", p)
+	}
+	if len(p.Capabilities) != 2 || p.Capabilities[0].Type != identity.CapCodeWrite {
+		t.Errorf("Expected 2 capabilities sorted by type, got %+v", p.Capabilities)
+	}
+	if len(p.History) != DefaultProfileHistory || p.Events <= DefaultProfileHistory {
+		t.Errorf("Expected the last %d of more events, got %d of %d", DefaultProfileHistory, len(p.History), p.Events)
+	}
+	if p.Reputation.TasksCompleted != 12 {
+		t.Errorf("Expected 12 tasks completed, got %d", p.Reputation.TasksCompleted)
+	}
+	if p.Usage.Tokens != 30 || p.Share != 0.75 {
+		t.Errorf("Expected 30 tokens, 75%% of the spend, got %d and %.2f", p.Usage.Tokens, p.Share)
+	}
+	if p.CurrentTask != nil || p.State != agent.StateInitializing {
+		t.Errorf("Expected an idle unstarted agent, got %s working on %+v", p.State, p.CurrentTask)
+	}
+
+	if all, _ := c.Profile(a.Identity.SID, -1); len(all.History) != all.Events {
+		t.Errorf("Expected the whole history, got %d of %d", len(all.History), all.Events)
+	}
+	if _, err := c.Profile("missing", 0); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+}
+
 func TestCollective_StartStop(t *testing.T) {
 	c := NewCollective("TestCollective", DefaultCollectiveConfig())
 
@@ -205,3 +328,1966 @@ func TestCollective_GetComponents(t *testing.T) {
 		t.Error("GetConsensus should not return nil")
 	}
 }
+
+func TestCollective_ConsensusAssignment(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.AssignmentMode = AssignmentConsensus
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a1, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	a2, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent2", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	a3, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent3", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a1)
+	_ = c.Join(a2)
+	_ = c.Join(a3)
+
+	// Everyone refuses to ratify Agent1, forcing fallback to the next bidder
+	c.SetAssignmentVoter(func(voter *agent.Agent, task *agent.Task, candidate *coordination.TaskAssignment) bool {
+		return candidate.AgentSID != a1.Identity.SID
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	result, err := c.Submit(agent.NewTask("Consensus task", nil))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	if result.AgentSID == a1.Identity.SID {
+		t.Error("Rejected candidate should not have been assigned the task")
+	}
+
+	if stats := c.GetConsensus().Stats(); stats.AcceptedRounds != 1 {
+		t.Errorf("Expected 1 accepted consensus round, got %d", stats.AcceptedRounds)
+	}
+}
+
+func TestCollective_ConsensusAssignment_AllRejected(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.AssignmentMode = AssignmentConsensus
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a1, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	a2, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent2", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a1)
+	_ = c.Join(a2)
+
+	c.SetAssignmentVoter(func(voter *agent.Agent, task *agent.Task, candidate *coordination.TaskAssignment) bool {
+		return false
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	_, err := c.Submit(agent.NewTask("Contested task", nil))
+	if err != ErrAssignmentRejected {
+		t.Errorf("Expected ErrAssignmentRejected, got %v", err)
+	}
+}
+
+func TestCollective_Events(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	if _, err := c.Submit(agent.NewTask("Observed task", nil)); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	seen := make(map[EventType]bool)
+	timeout := time.After(time.Second)
+	for !seen[EventTaskCompleted] && !seen[EventTaskFailed] {
+		select {
+		case e := <-events:
+			seen[e.Type] = true
+		case <-timeout:
+			t.Fatalf("Timed out waiting for completion event, saw %v", seen)
+		}
+	}
+
+	for _, expected := range []EventType{EventAgentJoined, EventBidPlaced, EventTaskAssigned, EventReputationChanged} {
+		if !seen[expected] {
+			t.Errorf("Expected %s event", expected)
+		}
+	}
+}
+
+func TestEventBus_Unsubscribe(t *testing.T) {
+	bus := NewEventBus(1)
+
+	ch, unsubscribe := bus.Subscribe()
+	bus.Publish(Event{Type: EventAgentJoined})
+	bus.Publish(Event{Type: EventAgentLeft}) // buffer full, dropped
+
+	if stats := bus.Stats(); stats.Dropped != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", stats.Dropped)
+	}
+
+	unsubscribe()
+	unsubscribe()
+
+	if e := <-ch; e.Type != EventAgentJoined {
+		t.Errorf("Expected buffered agent_joined event, got %s", e.Type)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("Channel should be closed after unsubscribe")
+	}
+}
+
+func TestCollective_LateJoinerStarted(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Late", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	if err := c.Join(a); err != nil {
+		t.Fatalf("Failed to join: %v", err)
+	}
+
+	if state := a.GetState(); state != agent.StateIdle {
+		t.Errorf("Expected late joiner to be idle, got %s", state)
+	}
+
+	if v := c.MembershipVersion(); v != 1 {
+		t.Errorf("Expected membership version 1, got %d", v)
+	}
+
+	if peers := c.GetGossip().PeerCount(); peers != 1 {
+		t.Errorf("Expected 1 gossip peer, got %d", peers)
+	}
+}
+
+func TestCollective_LeaveRequeuesTasks(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(100 * time.Millisecond)
+
+	provider := &blockingProvider{release: make(chan struct{})}
+	leaver, _ := agent.NewAgent(agent.AgentConfig{Name: "Leaver", Provider: provider})
+	_ = c.Join(leaver)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	results := make(chan *agent.TaskResult, 2)
+	submit := func(task *agent.Task) {
+		result, err := c.Submit(task)
+		if err != nil {
+			t.Errorf("Submit failed: %v", err)
+		}
+		results <- result
+	}
+
+	// One task runs on the leaver; a second pinned to it queues behind
+	second := agent.NewTask("Second task", nil)
+	c.pin(second.ID, leaver.Identity.SID)
+	go submit(agent.NewTask("First task", nil))
+	waitFor(t, time.Second, func() bool { return leaver.GetState() == agent.StateWorking })
+	go submit(second)
+	waitFor(t, time.Second, func() bool { return c.Stats().ActiveTasks == 2 })
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	stayer, _ := agent.NewAgent(agent.AgentConfig{Name: "Stayer"})
+	_ = c.Join(stayer)
+
+	if err := c.Leave(leaver.Identity.SID); err != nil {
+		t.Fatalf("Failed to leave: %v", err)
+	}
+
+	select {
+	case result := <-results:
+		if result.AgentSID != stayer.Identity.SID {
+			t.Errorf("Expected queued task to be reassigned to remaining agent")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Queued task was not reassigned")
+	}
+
+	// The in-flight task is allowed to finish on the departing agent
+	close(provider.release)
+	select {
+	case result := <-results:
+		if result.AgentSID != leaver.Identity.SID {
+			t.Errorf("Expected in-flight task to complete on departing agent")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("In-flight task did not complete")
+	}
+
+	waitFor(t, time.Second, func() bool { return leaver.GetState() == agent.StateTerminated })
+
+	if v := c.MembershipVersion(); v != 3 {
+		t.Errorf("Expected membership version 3, got %d", v)
+	}
+}
+
+func TestCollective_TaskSnapshot(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Submit(agent.NewTask("Snapshot task", nil)); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	snapshot := c.TaskSnapshot(2)
+	if len(snapshot.Completed) != 2 {
+		t.Errorf("Expected 2 completed results, got %d", len(snapshot.Completed))
+	}
+	if len(snapshot.Pending) != 0 {
+		t.Errorf("Expected no pending tasks, got %d", len(snapshot.Pending))
+	}
+	if len(snapshot.Active) != 0 {
+		t.Errorf("Expected no active tasks, got %d", len(snapshot.Active))
+	}
+}
+
+func TestCollective_Timeline(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	task := agent.NewTask("Timeline task", nil)
+	if _, err := c.Submit(task); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	timeline, ok := c.Timeline(task.ID)
+	if !ok {
+		t.Fatal("Expected a timeline for the submitted task")
+	}
+
+	expected := []TimelineStage{StageSubmitted, StageListed, StageBid, StageAssigned, StageRunning, StageCompleted}
+	if len(timeline) != len(expected) {
+		t.Fatalf("Expected %d stages, got %d: %+v", len(expected), len(timeline), timeline)
+	}
+	for i, stage := range expected {
+		if timeline[i].Stage != stage {
+			t.Errorf("Expected stage %d to be '%s', got '%s'", i, stage, timeline[i].Stage)
+		}
+	}
+	if timeline[3].Actor != a.Identity.SID {
+		t.Errorf("Expected assigned actor %s, got %s", a.Identity.SID, timeline[3].Actor)
+	}
+}
+
+func TestCollective_ExplainAssignment(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	var coders []*agent.Agent
+	for _, name := range []string{"Coder1", "Coder2"} {
+		a, _ := agent.NewAgent(agent.AgentConfig{Name: name, Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+		_ = c.Join(a)
+		coders = append(coders, a)
+	}
+	writer, _ := agent.NewAgent(agent.AgentConfig{Name: "Writer", Capabilities: []identity.CapabilityType{identity.CapDocumentation}})
+	_ = c.Join(writer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	task := agent.NewTask("Explained task", []identity.CapabilityType{identity.CapCodeWrite})
+	result, err := c.Submit(task)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	exp, ok := c.ExplainAssignment(task.ID)
+	if !ok {
+		t.Fatal("Expected an explanation for the submitted task")
+	}
+	if exp.Winner != result.AgentSID {
+		t.Errorf("Expected winner %s, got %s", result.AgentSID, exp.Winner)
+	}
+	if len(exp.Bids) != 2 {
+		t.Fatalf("Expected 2 bids, got %d", len(exp.Bids))
+	}
+	if exp.Bids[0].Outcome != OutcomeWon || exp.Bids[1].Outcome != OutcomeOutranked {
+		t.Errorf("Expected outcomes won and outranked, got %s and %s", exp.Bids[0].Outcome, exp.Bids[1].Outcome)
+	}
+	if len(exp.Bids[0].Factors) != 3 || exp.Bids[0].Bid.Match == nil {
+		t.Errorf("Expected the winning bid to carry its factors and match breakdown")
+	}
+
+	// Identical coders tie on every factor, so the SID decides
+	first := coders[0].Identity.SID
+	if coders[1].Identity.SID < first {
+		first = coders[1].Identity.SID
+	}
+	if exp.Winner != first {
+		t.Errorf("Expected the coder with the lower SID to win, got %s", exp.Winner)
+	}
+	if exp.TieBreak == nil || exp.TieBreak.Rule != coordination.TieBreakAgentSID {
+		t.Errorf("Expected a tie-break on agent SID, got %+v", exp.TieBreak)
+	}
+
+	if len(exp.Excluded) != 1 {
+		t.Fatalf("Expected 1 excluded agent, got %d", len(exp.Excluded))
+	}
+	if ex := exp.Excluded[0]; ex.AgentSID != writer.Identity.SID || ex.Reason != coordination.IneligibleCapability || ex.Match == nil {
+		t.Errorf("Expected Writer excluded on capability with its match, got %+v", ex)
+	}
+}
+
+func TestCollective_RelaxedBidThreshold(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.BidThreshold = &coordination.BidThreshold{Step: 0.25, Floor: 0.25}
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(coder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	// Matches 0.25: below the 0.5 minimum, but one relaxation reaches it
+	task := agent.NewTask("Stretch task", []identity.CapabilityType{identity.CapCodeWrite, identity.CapTesting})
+	if _, err := c.Submit(task); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	exp, ok := c.ExplainAssignment(task.ID)
+	if !ok {
+		t.Fatal("Expected an explanation for the submitted task")
+	}
+	if exp.Winner != coder.Identity.SID || exp.Threshold != 0.25 {
+		t.Errorf("Expected the coder to win at 0.25, got %s at %.2f", exp.Winner, exp.Threshold)
+	}
+	if len(exp.Relaxed) != 1 || exp.Relaxed[0].From != coordination.MinCapabilityScore {
+		t.Errorf("Expected one relaxation from %.2f, got %+v", coordination.MinCapabilityScore, exp.Relaxed)
+	}
+}
+
+func TestTimelineStore_Evicts(t *testing.T) {
+	s := NewTimelineStore(2)
+	s.Record("a", StageSubmitted, "", "")
+	s.Record("b", StageSubmitted, "", "")
+	s.Record("a", StageCompleted, "", "")
+	s.Record("c", StageSubmitted, "", "")
+
+	if s.Len() != 2 {
+		t.Errorf("Expected 2 timelines, got %d", s.Len())
+	}
+	if _, ok := s.Get("a"); ok {
+		t.Error("Expected oldest timeline to be evicted")
+	}
+	if _, ok := s.Get("c"); !ok {
+		t.Error("Expected newest timeline to be kept")
+	}
+}
+
+func TestCollective_SetLogger(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		entries []logging.Entry
+	)
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.SetLogger(logging.NewHook(slog.LevelDebug, func(e logging.Entry) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, e)
+	}))
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	if _, err := c.Submit(agent.NewTask("Logged task", nil)); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		seen[fmt.Sprintf("%v/%s", e.Attrs["component"], e.Message)] = true
+	}
+	for _, want := range []string{"collective/agent joined", "market/task assigned", "market/bid received", "gossip/membership updated"} {
+		if !seen[want] {
+			t.Errorf("Expected log entry %q, got %v", want, seen)
+		}
+	}
+}
+
+func TestCollective_SubmitCtxTimeout(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	provider := &blockingProvider{release: make(chan struct{})}
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Slow", Provider: provider})
+	_ = c.Join(a)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(runCtx)
+	defer c.Stop()
+
+	before := a.Reputation.Overall
+
+	ctx, cancelTask := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelTask()
+
+	task := agent.NewTask("Never finishes", nil)
+	result, err := c.SubmitCtx(ctx, task)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if result != nil {
+		t.Errorf("Expected no result, got %+v", result)
+	}
+
+	// The LLM call is cancelled and the agent released
+	waitFor(t, time.Second, func() bool { return a.GetState() == agent.StateIdle })
+
+	if task.Status != agent.TaskFailed {
+		t.Errorf("Expected task status 'failed', got '%s'", task.Status)
+	}
+	if a.Reputation.Overall != before {
+		t.Errorf("Expected reputation unchanged at %f, got %f", before, a.Reputation.Overall)
+	}
+
+	snapshot := c.TaskSnapshot(10)
+	if len(snapshot.Active) != 0 {
+		t.Errorf("Expected no active tasks, got %d", len(snapshot.Active))
+	}
+	if len(snapshot.Completed) != 1 || snapshot.Completed[0].Status != agent.TaskFailed {
+		t.Errorf("Expected one failed result, got %+v", snapshot.Completed)
+	}
+}
+
+func TestCollective_Slots(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	provider := &blockingProvider{release: make(chan struct{})}
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Pool", Provider: provider, Slots: 3})
+	_ = c.Join(a)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(runCtx)
+	defer c.Stop()
+
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			_, err := c.SubmitCtx(context.Background(), agent.NewTask(fmt.Sprintf("Task %d", i), nil))
+			results <- err
+		}(i)
+	}
+
+	// One agent runs every task at once, a slot each
+	waitFor(t, 2*time.Second, func() bool { return a.FreeSlots() == 0 })
+	if active := a.ActiveTasks(); len(active) != 3 {
+		t.Errorf("Expected 3 tasks running, got %+v", active)
+	}
+
+	close(provider.release)
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Errorf("Expected every task to complete, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the tasks to complete")
+		}
+	}
+}
+
+func TestCollective_SlotsSettleConcurrently(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Pool", Provider: &staticProvider{content: "done"}, Slots: 4})
+	_ = c.Join(a)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(runCtx)
+	defer c.Stop()
+
+	// Stakes are locked for new tasks while other slots record outcomes. Tasks
+	// submitted while every slot is busy may draw no bids.
+	const tasks = 40
+	var wg sync.WaitGroup
+	completed := make(chan struct{}, tasks)
+	for i := 0; i < tasks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := c.SubmitCtx(context.Background(), agent.NewTask(fmt.Sprintf("Task %d", i), nil)); err == nil {
+				completed <- struct{}{}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(completed) <= 4 {
+		t.Errorf("Expected the slots to complete tasks side by side, got %d completed", len(completed))
+	}
+
+	var staked float64
+	c.reputation.Guard(func() { staked = a.Reputation.Staked })
+	if math.Abs(staked) > 1e-9 {
+		t.Errorf("Expected every stake settled, got %v still staked", staked)
+	}
+}
+
+func TestCollective_SubmitCtxCancelled(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.SubmitCtx(ctx, agent.NewTask("Cancelled", nil)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if stats := c.Stats(); stats.PendingTasks != 0 {
+		t.Errorf("Expected no pending tasks, got %d", stats.PendingTasks)
+	}
+}
+
+func TestCollective_SubmitCtxPartialResult(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Slow", Provider: &stallingProvider{}})
+	_ = c.Join(a)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(runCtx)
+	defer c.Stop()
+
+	ctx, cancelTask := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelTask()
+
+	result, err := c.SubmitCtx(ctx, agent.NewTask("Long essay", nil))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if result == nil || !result.Partial {
+		t.Fatalf("Expected a partial result, got %+v", result)
+	}
+	if result.Status != agent.TaskFailed {
+		t.Errorf("Expected status 'failed', got '%s'", result.Status)
+	}
+	if result.Output != "first draft" {
+		t.Errorf("Expected output 'first draft', got '%s'", result.Output)
+	}
+	if len(result.Checkpoints) != 1 || result.Checkpoints[0].State != "intro written" {
+		t.Errorf("Expected draft checkpoint, got %+v", result.Checkpoints)
+	}
+
+	snapshot := c.TaskSnapshot(10)
+	if len(snapshot.Completed) != 1 || !snapshot.Completed[0].Partial {
+		t.Errorf("Expected the recorded result to be partial, got %+v", snapshot.Completed)
+	}
+}
+
+func TestCollective_AdmissionPolicy(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	policy := AdmissionPolicy{WorkDifficulty: 8, MinStake: 5, MinVoucherReputation: 30, StakeReleaseTasks: 1, StakeForfeitBelow: 10}
+	cfg.Admission = &policy
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	// No credentials
+	drifter, _ := agent.NewAgent(agent.AgentConfig{Name: "Drifter"})
+	if err := c.Join(drifter); !errors.Is(err, ErrAdmissionDenied) {
+		t.Errorf("Expected ErrAdmissionDenied, got %v", err)
+	}
+
+	// Proof of work
+	founder, _ := agent.NewAgent(agent.AgentConfig{Name: "Founder"})
+	founder.Identity.ProveWork(policy.WorkDifficulty)
+	if err := c.Join(founder); err != nil {
+		t.Fatalf("Expected proof of work to be accepted, got %v", err)
+	}
+
+	// Voucher from an existing member locks the member's stake
+	newcomer, _ := agent.NewAgent(agent.AgentConfig{Name: "Newcomer"})
+	v, _ := identity.NewVoucher(founder.Identity, newcomer.Identity, 5, time.Hour)
+	newcomer.Identity.Voucher = v
+
+	before := founder.Reputation.Overall
+	if err := c.Join(newcomer); err != nil {
+		t.Fatalf("Expected voucher to be accepted, got %v", err)
+	}
+	if got := founder.Reputation.Overall; got != before-5 {
+		t.Errorf("Expected voucher reputation %f while staked, got %f", before-5, got)
+	}
+
+	// A voucher can't be reused for another identity
+	impostor, _ := agent.NewAgent(agent.AgentConfig{Name: "Impostor"})
+	impostor.Identity.Voucher = v
+	if err := c.Join(impostor); !errors.Is(err, ErrAdmissionDenied) {
+		t.Errorf("Expected reused voucher to be denied, got %v", err)
+	}
+
+	// The stake is returned once the newcomer completes a task
+	c.settleVouch(newcomer.Identity.SID, true)
+	if founder.Reputation.Staked != 0 {
+		t.Errorf("Expected stake released, got %f still staked", founder.Reputation.Staked)
+	}
+	if got := founder.Reputation.Overall; got != before {
+		t.Errorf("Expected reputation %f after release, got %f", before, got)
+	}
+}
+
+func TestCollective_CapabilityGaps(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(coder)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	if report := c.CapabilityGaps(GapConfig{}); len(report.Gaps) != 0 || len(report.Suggestions) != 0 {
+		t.Errorf("Expected no gaps before any auction, got %+v", report)
+	}
+
+	if _, err := c.Submit(agent.NewTask("Served task", []identity.CapabilityType{identity.CapCodeWrite})); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	submitCtx, submitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer submitCancel()
+	if _, err := c.SubmitCtx(submitCtx, agent.NewTask("Unserved task", []identity.CapabilityType{identity.CapCodeWrite, identity.CapSecurity})); err == nil {
+		t.Fatal("Expected a task nobody is capable of to fail")
+	}
+
+	report := c.CapabilityGaps(GapConfig{})
+	if report.Auctions != 2 || report.Unassigned != 1 {
+		t.Errorf("Expected 2 auctions with 1 unassigned, got %d and %d", report.Auctions, report.Unassigned)
+	}
+
+	kinds := make(map[identity.CapabilityType]GapKind)
+	for _, g := range report.Gaps {
+		kinds[g.Capability] = g.Kind
+	}
+	tests := []struct {
+		capability identity.CapabilityType
+		kind       GapKind
+	}{
+		{identity.CapSecurity, GapMissing},
+		{identity.CapCodeWrite, GapCombination},
+	}
+	for _, tt := range tests {
+		if kinds[tt.capability] != tt.kind {
+			t.Errorf("Expected %s gap to be %s, got %q", tt.capability, tt.kind, kinds[tt.capability])
+		}
+	}
+
+	if len(report.Suggestions) != 1 {
+		t.Fatalf("Expected 1 suggested agent, got %d", len(report.Suggestions))
+	}
+	if s := report.Suggestions[0]; s.Name != "code.write+security" || s.Count != 1 || s.Tasks != 1 {
+		t.Errorf("Expected one code.write+security agent for 1 task, got %+v", s)
+	}
+
+	// Maintenance announces the gaps once, until they change
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+	c.checkGaps()
+	c.checkGaps()
+	published := 0
+	for len(events) > 0 {
+		if e := <-events; e.Type == EventCapabilityGaps {
+			published++
+		}
+	}
+	if published != 1 {
+		t.Errorf("Expected 1 capability_gaps event, got %d", published)
+	}
+}
+
+func TestCollective_VerifyResult(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	var results []*agent.TaskResult
+	for _, description := range []string{"First task", "Second task"} {
+		result, err := c.Submit(agent.NewTask(description, nil))
+		if err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		results = append(results, result)
+	}
+
+	// Results still verify after their agent leaves
+	_ = c.Leave(a.Identity.SID)
+	if err := c.VerifyResult(results[0]); err != nil {
+		t.Errorf("Expected result to verify, got %v", err)
+	}
+	if err := c.VerifyResultChain(results); err != nil {
+		t.Errorf("Expected chain to verify, got %v", err)
+	}
+
+	forged := *results[1]
+	forged.AgentSID = "unknown"
+	if err := c.VerifyResult(&forged); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+}
+
+func TestCollective_Storage(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.Storage = &storage.Config{Driver: storage.DriverFilesystem, Path: t.TempDir()}
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	_ = c.Start(ctx)
+
+	task := agent.NewTask("Stored task", nil)
+	result, err := c.Submit(task)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	c.Stop()
+
+	stored, err := c.StoredResult(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("StoredResult failed: %v", err)
+	}
+	if stored.AgentSID != result.AgentSID || stored.Output != result.Output {
+		t.Errorf("Expected stored result to match, got %+v", stored)
+	}
+	if err := c.VerifyResult(stored); err != nil {
+		t.Errorf("Expected stored result to verify, got %v", err)
+	}
+	artifact, err := c.Artifact(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("Artifact failed: %v", err)
+	}
+	data, _ := io.ReadAll(artifact)
+	artifact.Close()
+	if string(data) != result.Output {
+		t.Errorf("Expected artifact %q, got %q", result.Output, data)
+	}
+	if _, err := c.StoredResult(ctx, "missing"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	// The audit log is written as events arrive
+	var completed bool
+	for deadline := time.Now().Add(time.Second); !completed && time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		events, err := c.AuditLog(ctx, start)
+		if err != nil {
+			t.Fatalf("AuditLog failed: %v", err)
+		}
+		for _, e := range events {
+			completed = completed || (e.Type == EventTaskCompleted && e.TaskID == task.ID)
+		}
+	}
+	if !completed {
+		t.Error("Expected task_completed in the audit log")
+	}
+
+	// Reputation is restored from the same storage
+	want, _ := c.reputation.Record(a.Identity.SID)
+	restored := NewCollective("TestCollective", cfg)
+	record, err := restored.reputation.Record(a.Identity.SID)
+	if err != nil {
+		t.Fatalf("Expected reputation restored from storage, got %v", err)
+	}
+	if record.Reputation.TasksCompleted == 0 || record.Reputation.TasksCompleted != want.Reputation.TasksCompleted {
+		t.Errorf("Expected %d completed tasks, got %d", want.Reputation.TasksCompleted, record.Reputation.TasksCompleted)
+	}
+}
+
+func TestCollective_Auction(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.Auction = coordination.AuctionVickrey
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	for _, name := range []string{"Coder1", "Coder2"} {
+		a, _ := agent.NewAgent(agent.AgentConfig{Name: name, Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+		_ = c.Join(a)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	tests := []struct {
+		auction string
+		want    string
+	}{
+		{"", coordination.AuctionVickrey},
+		{coordination.AuctionReverse, coordination.AuctionReverse},
+	}
+	for _, tt := range tests {
+		task := agent.NewTask("Auctioned task", []identity.CapabilityType{identity.CapCodeWrite}).WithAuction(tt.auction)
+		if _, err := c.Submit(task); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		exp, _ := c.ExplainAssignment(task.ID)
+		if exp == nil || exp.Auction != tt.want || len(exp.Bids) != 2 {
+			t.Fatalf("Expected 2 bids in a %s auction, got %+v", tt.want, exp)
+		}
+		// Identical bidders: the Vickrey winner needs its whole stake to match the runner-up
+		if exp.Stake != exp.Bids[0].Bid.ReputationStake {
+			t.Errorf("Expected stake %f, got %f", exp.Bids[0].Bid.ReputationStake, exp.Stake)
+		}
+	}
+
+	if _, err := c.Submit(agent.NewTask("Bad auction", nil).WithAuction("dutch")); !errors.Is(err, coordination.ErrUnknownAuction) {
+		t.Errorf("Expected ErrUnknownAuction, got %v", err)
+	}
+}
+
+// failingProvider fails every completion
+type failingProvider struct{}
+
+func (p *failingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return nil, errors.New("model unavailable")
+}
+
+func (p *failingProvider) Name() string {
+	return "failing"
+}
+
+func TestCollective_StakeEscrow(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider llm.Provider
+		deadline time.Duration
+		want     string
+	}{
+		{"completed", nil, 0, "stake_returned"},
+		{"failed", &failingProvider{}, 0, "stake_forfeited"},
+		{"late", nil, -time.Second, "stake_forfeited"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCollective("TestCollective", DefaultCollectiveConfig())
+			c.GetMarket().SetBidTimeout(time.Millisecond)
+			a, _ := agent.NewAgent(agent.AgentConfig{Name: "Bidder", Provider: tt.provider})
+			_ = c.Join(a)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			_ = c.Start(ctx)
+			defer c.Stop()
+
+			task := agent.NewTask("Staked task", nil)
+			if tt.deadline != 0 {
+				task.WithDeadline(time.Now().Add(tt.deadline))
+			}
+			if _, err := c.Submit(task); err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+			exp, _ := c.ExplainAssignment(task.ID)
+			if exp == nil || exp.Stake <= 0 {
+				t.Fatalf("Expected a stake committed, got %+v", exp)
+			}
+
+			var locked, settled *coordination.ReputationEvent
+			for _, e := range c.GetReputation().GetHistory(a.Identity.SID) {
+				e := e
+				switch e.Type {
+				case "stake_locked":
+					locked = &e
+				case "stake_returned", "stake_forfeited":
+					settled = &e
+				}
+			}
+			if locked == nil || settled == nil {
+				t.Fatalf("Expected the stake locked and settled, got %+v", c.GetReputation().GetHistory(a.Identity.SID))
+			}
+			if settled.Type != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, settled.Type)
+			}
+			if math.Abs(locked.Delta+exp.Stake) > 1e-9 {
+				t.Errorf("Expected %.2f locked, got %.2f", exp.Stake, -locked.Delta)
+			}
+			switch tt.want {
+			case "stake_returned":
+				// The stake comes back with the reward on top
+				want := exp.Stake * (1 + DefaultStakeReward)
+				if math.Abs(settled.Delta-want) > 1e-9 {
+					t.Errorf("Expected %.2f returned, got %.2f", want, settled.Delta)
+				}
+			case "stake_forfeited":
+				if settled.Delta != 0 {
+					t.Errorf("Expected the forfeited stake to stay off Overall, got delta %.2f", settled.Delta)
+				}
+			}
+			if a.Reputation.Staked != 0 {
+				t.Errorf("Expected nothing left staked, got %.2f", a.Reputation.Staked)
+			}
+		})
+	}
+}
+
+func TestCollective_PauseResume(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	provider := &blockingProvider{release: make(chan struct{})}
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+
+	inFlight := make(chan error, 1)
+	go func() {
+		_, err := c.Submit(agent.NewTask("In flight", nil))
+		inFlight <- err
+	}()
+	waitFor(t, time.Second, func() bool { return c.Mode().InFlight == 1 })
+
+	c.Pause("deploy")
+	if mode := c.Mode(); mode.Mode != ModePaused || mode.Reason != "deploy" {
+		t.Errorf("Expected mode paused for deploy, got %+v", mode)
+	}
+	if c.Stats().Mode != ModePaused {
+		t.Errorf("Expected stats mode paused, got %s", c.Stats().Mode)
+	}
+
+	held := make(chan error, 1)
+	go func() {
+		_, err := c.Submit(agent.NewTask("Held", nil))
+		held <- err
+	}()
+	waitFor(t, time.Second, func() bool { return c.Mode().Held == 1 })
+
+	// In-flight work finishes while paused; the held task doesn't start
+	close(provider.release)
+	if err := <-inFlight; err != nil {
+		t.Errorf("Expected in-flight task to finish, got %v", err)
+	}
+	select {
+	case err := <-held:
+		t.Fatalf("Expected the task to be held while paused, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.Resume()
+	select {
+	case err := <-held:
+		if err != nil {
+			t.Errorf("Expected held task to complete, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Held task did not run after Resume")
+	}
+
+	var modes []string
+	for len(modes) < 2 {
+		select {
+		case e := <-events:
+			if e.Type == EventModeChanged {
+				modes = append(modes, e.Data["mode"].(string))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 2 mode events, got %v", modes)
+		}
+	}
+	if modes[0] != "paused" || modes[1] != "running" {
+		t.Errorf("Expected paused then running, got %v", modes)
+	}
+}
+
+func TestCollective_PauseCancelled(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker"})
+	_ = c.Join(a)
+	c.Pause("")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.SubmitCtx(ctx, agent.NewTask("Held", nil)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if held := c.Mode().Held; held != 0 {
+		t.Errorf("Expected no held submissions, got %d", held)
+	}
+}
+
+// staticProvider answers every request with the same content
+type staticProvider struct {
+	content string
+}
+
+func (p *staticProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return &llm.CompletionResponse{Content: p.content}, nil
+}
+
+func (p *staticProvider) Name() string {
+	return "static"
+}
+
+func TestCollective_TaskArtifacts(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.Storage = &storage.Config{Driver: storage.DriverMemory}
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	output := "Here it is:\n```go main.go\npackage main\n```\n"
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Provider: &staticProvider{content: output}})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	first := agent.NewTask("Write a program", nil)
+	result, err := c.Submit(first)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	out, ok := result.Artifact(agent.ArtifactOutput)
+	if !ok || out.Digest != storage.Digest([]byte(output)) || out.Size != len(output) {
+		t.Errorf("Expected the output artifact on the result, got %+v", result.Artifacts)
+	}
+	file, ok := result.Artifact(agent.ArtifactFilePrefix + "main.go")
+	if !ok {
+		t.Fatalf("Expected the generated file as an artifact, got %+v", result.Artifacts)
+	}
+
+	stored, err := c.TaskArtifacts(ctx, first.ID)
+	if err != nil || len(stored) != len(result.Artifacts) {
+		t.Errorf("Expected the stored result to reference %d artifacts, got %v (%v)", len(result.Artifacts), stored, err)
+	}
+	r, err := c.OpenArtifact(ctx, file.Digest)
+	if err != nil {
+		t.Fatalf("OpenArtifact failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "package main\n" {
+		t.Errorf("Expected the file content, got %q", data)
+	}
+
+	// The same output from another task is stored once
+	second := agent.NewTask("Write it again", nil)
+	again, _ := c.Submit(second)
+	if o, _ := again.Artifact(agent.ArtifactOutput); o.Digest != out.Digest {
+		t.Errorf("Expected identical outputs to share a digest, got %s and %s", out.Digest, o.Digest)
+	}
+	if keys, _ := c.Storage().Blobs().List(ctx, ArtifactPrefix); len(keys) != 2 {
+		t.Errorf("Expected 2 distinct blobs, got %v", keys)
+	}
+}
+
+func TestCollective_Drain(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker"})
+	_ = c.Join(a)
+
+	c.Drain("shutdown")
+	if mode := c.Mode(); mode.Mode != ModeDraining || mode.Reason != "shutdown" {
+		t.Errorf("Expected mode draining for shutdown, got %+v", mode)
+	}
+	if _, err := c.SubmitAsync(agent.NewTask("Refused", nil)); !errors.Is(err, ErrDraining) {
+		t.Errorf("Expected ErrDraining from SubmitAsync, got %v", err)
+	}
+	if _, err := c.SubmitCtx(context.Background(), agent.NewTask("Refused", nil)); !errors.Is(err, ErrDraining) {
+		t.Errorf("Expected ErrDraining from SubmitCtx, got %v", err)
+	}
+
+	c.Resume()
+	if _, err := c.SubmitAsync(agent.NewTask("Accepted", nil)); err != nil {
+		t.Errorf("Expected submissions to be accepted after Resume, got %v", err)
+	}
+}
+
+func TestCollective_PauseAgent(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker"})
+	_ = c.Join(a)
+	sid := a.Identity.SID
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+
+	if err := c.PauseAgent("sq:unknown"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+	if err := c.PauseAgent(sid); err != nil {
+		t.Fatalf("PauseAgent failed: %v", err)
+	}
+	if snapshot, _ := c.AgentSnapshot(sid); snapshot.State != agent.StatePaused {
+		t.Errorf("Expected agent paused, got %s", snapshot.State)
+	}
+	if err := c.ResumeAgent(sid); err != nil {
+		t.Fatalf("ResumeAgent failed: %v", err)
+	}
+	if snapshot, _ := c.AgentSnapshot(sid); snapshot.State != agent.StateIdle {
+		t.Errorf("Expected agent idle, got %s", snapshot.State)
+	}
+
+	var types []EventType
+	for len(types) < 2 {
+		select {
+		case e := <-events:
+			if e.AgentSID == sid {
+				types = append(types, e.Type)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 2 agent events, got %v", types)
+		}
+	}
+	if types[0] != EventAgentPaused || types[1] != EventAgentResumed {
+		t.Errorf("Expected agent_paused then agent_resumed, got %v", types)
+	}
+}
+
+func TestCollective_ProposeMinBidScore(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent"})
+	_ = c.Join(a)
+
+	if _, err := c.ProposeParameters(context.Background(), a.Identity.SID, Parameters{MinBidScore: 1.5}); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("Expected ErrInvalidParameter for a score above 1, got %v", err)
+	}
+	if _, err := c.ProposeParameters(context.Background(), a.Identity.SID, Parameters{MinBidScore: 0.05}); err != nil {
+		t.Fatalf("ProposeParameters failed: %v", err)
+	}
+	threshold := c.GetMarket().BidThreshold()
+	if threshold.Min != 0.05 || threshold.Floor > threshold.Min {
+		t.Errorf("Expected a min bid score of 0.05 with the floor at or below it, got %+v", threshold)
+	}
+	if got := c.Parameters().MinBidScore; got != 0.05 {
+		t.Errorf("Expected parameters to report min bid score 0.05, got %v", got)
+	}
+}
+
+func TestCollective_ProposeParameters(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.ConsensusThreshold = 0.67
+	c := NewCollective("TestCollective", cfg)
+	_, _ = c.CreateTeam("backend", TeamConfig{})
+
+	var sids []string
+	for i := 0; i < 3; i++ {
+		a, _ := agent.NewAgent(agent.AgentConfig{Name: fmt.Sprintf("Agent%d", i)})
+		_ = c.Join(a)
+		sids = append(sids, a.Identity.SID)
+	}
+
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+
+	change := Parameters{ConsensusThreshold: 0.5, MaxAgents: 10, BidTimeout: 50 * time.Millisecond, MinBidScore: 0.4}
+	proposal, err := c.ProposeParameters(context.Background(), sids[0], change)
+	if err != nil {
+		t.Fatalf("ProposeParameters failed: %v", err)
+	}
+	if !proposal.Accepted || proposal.For != 3 || proposal.Previous.ConsensusThreshold != 0.67 {
+		t.Errorf("Expected a unanimous accepted proposal, got %+v", proposal)
+	}
+	if got := c.Parameters(); got != change {
+		t.Errorf("Expected parameters %+v, got %+v", change, got)
+	}
+	team, _ := c.GetTeam("backend")
+	if team.GetMarket().BidTimeout() != 50*time.Millisecond {
+		t.Errorf("Expected the team market to take the new bid timeout, got %s", team.GetMarket().BidTimeout())
+	}
+	select {
+	case e := <-events:
+		if e.Type != EventParametersChanged || e.AgentSID != sids[0] {
+			t.Errorf("Expected a parameters_changed event from the proposer, got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected a parameters_changed event")
+	}
+
+	// Two of three agents object: the change falls short of the threshold
+	c.SetParameterVoter(func(voter *agent.Agent, current, change Parameters) bool {
+		return change.MaxAgents >= current.MaxAgents
+	})
+	if _, err := c.ProposeParameters(context.Background(), sids[0], Parameters{MaxAgents: 5, BidTimeout: time.Second}); !errors.Is(err, ErrParameterRejected) {
+		t.Errorf("Expected ErrParameterRejected, got %v", err)
+	}
+	if got := c.Parameters(); got != change {
+		t.Errorf("Expected a rejected change to leave every parameter as it was, got %+v", got)
+	}
+
+	tests := []struct {
+		name   string
+		change Parameters
+	}{
+		{"empty", Parameters{}},
+		{"threshold above 1", Parameters{ConsensusThreshold: 1.5}},
+		{"negative bid timeout", Parameters{BidTimeout: -time.Second}},
+		{"max agents below size", Parameters{MaxAgents: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.ProposeParameters(context.Background(), sids[0], tt.change); !errors.Is(err, ErrInvalidParameter) {
+				t.Errorf("Expected ErrInvalidParameter, got %v", err)
+			}
+		})
+	}
+}
+
+// flakyProvider fails its first failures completions and records the model
+// and response limit of each request
+type flakyProvider struct {
+	mu        sync.Mutex
+	failures  int
+	models    []string
+	maxTokens []int
+}
+
+func (p *flakyProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.models = append(p.models, req.Model)
+	p.maxTokens = append(p.maxTokens, req.MaxTokens)
+	if p.failures > 0 {
+		p.failures--
+		return nil, errors.New("model overloaded")
+	}
+	return &llm.CompletionResponse{Content: "done", TokensUsed: 10}, nil
+}
+
+func (p *flakyProvider) Name() string {
+	return "flaky"
+}
+
+func TestCollective_QoS(t *testing.T) {
+	tests := []struct {
+		name     string
+		class    agent.QoSClass
+		failures int
+		status   agent.TaskStatus
+		retries  int
+	}{
+		{"standard retries once", agent.QoSStandard, 1, agent.TaskCompleted, 1},
+		{"interactive fails fast", agent.QoSInteractive, 1, agent.TaskFailed, 0},
+		{"batch exhausts its budget", agent.QoSBatch, 5, agent.TaskFailed, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &flakyProvider{failures: tt.failures}
+			c := NewCollective("TestCollective", DefaultCollectiveConfig())
+			c.GetMarket().SetBidTimeout(time.Millisecond)
+			a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider, Model: "agent-model"})
+			_ = c.Join(a)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			_ = c.Start(ctx)
+			defer c.Stop()
+
+			task := agent.NewTask("Classed task", nil).WithQoS(tt.class)
+			result, err := c.Submit(task)
+			if err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+			if result.Status != tt.status {
+				t.Errorf("Expected %s, got %s (%s)", tt.status, result.Status, result.Error)
+			}
+
+			stats := c.QoSStats()[tt.class]
+			if stats.Tasks != 1 || stats.Retries != tt.retries {
+				t.Errorf("Expected 1 task and %d retries, got %+v", tt.retries, stats)
+			}
+			if !task.Deadline.IsZero() {
+				t.Errorf("Expected the latency target to leave the deadline unset, got %v", task.Deadline)
+			}
+		})
+	}
+
+	t.Run("model policy", func(t *testing.T) {
+		provider := &flakyProvider{}
+		c := NewCollective("TestCollective", DefaultCollectiveConfig())
+		c.GetMarket().SetBidTimeout(time.Millisecond)
+		if err := c.SetQoSPolicy(agent.QoSBatch, QoSPolicy{Model: "batch-model", MaxTokens: 256}); err != nil {
+			t.Fatalf("SetQoSPolicy failed: %v", err)
+		}
+		a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider, Model: "agent-model"})
+		_ = c.Join(a)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_ = c.Start(ctx)
+		defer c.Stop()
+
+		_, _ = c.Submit(agent.NewTask("Batch task", nil).WithQoS(agent.QoSBatch))
+		_, _ = c.Submit(agent.NewTask("Pinned model", nil).WithQoS(agent.QoSBatch).WithModel("task-model"))
+		_, _ = c.Submit(agent.NewTask("Standard task", nil))
+
+		want := []string{"batch-model", "task-model", "agent-model"}
+		if fmt.Sprint(provider.models) != fmt.Sprint(want) {
+			t.Errorf("Expected models %v, got %v", want, provider.models)
+		}
+		if provider.maxTokens[0] != 256 {
+			t.Errorf("Expected the class's response limit, got %d", provider.maxTokens[0])
+		}
+	})
+
+	t.Run("unknown class", func(t *testing.T) {
+		c := NewCollective("TestCollective", DefaultCollectiveConfig())
+		if _, err := c.Submit(agent.NewTask("Task", nil).WithQoS("realtime")); !errors.Is(err, ErrUnknownQoSClass) {
+			t.Errorf("Expected ErrUnknownQoSClass, got %v", err)
+		}
+		if err := c.SetQoSPolicy("realtime", QoSPolicy{Retries: -1}); !errors.Is(err, ErrInvalidQoS) {
+			t.Errorf("Expected ErrInvalidQoS, got %v", err)
+		}
+	})
+}
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	now := time.Now()
+	l := newRateLimiter(10, 2)
+
+	// Two back to back, then one every 100ms
+	want := []time.Duration{0, 0, 100 * time.Millisecond, 200 * time.Millisecond}
+	for i, w := range want {
+		if got := l.reserve(now); got != w {
+			t.Errorf("Reservation %d: expected %v, got %v", i, w, got)
+		}
+	}
+	if got := l.reserve(now.Add(time.Second)); got != 0 {
+		t.Errorf("Expected no wait after the limiter caught up, got %v", got)
+	}
+}
+
+// preemptibleProvider streams a draft of the long task and then blocks
+// until cancelled; every other request completes at once
+type preemptibleProvider struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *preemptibleProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return p.Stream(ctx, req, func(string) {})
+}
+
+func (p *preemptibleProvider) Stream(ctx context.Context, req llm.CompletionRequest, onDelta func(string)) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, req.Prompt)
+	p.mu.Unlock()
+
+	if strings.Contains(req.Prompt, "Long analysis") && !strings.Contains(req.Prompt, "draft section one") {
+		onDelta("draft section one")
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &llm.CompletionResponse{Content: "done"}, nil
+}
+
+func (p *preemptibleProvider) Name() string {
+	return "preemptible"
+}
+
+func TestCollective_Preemption(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	policy := DefaultPreemptionPolicy()
+	cfg.Preemption = &policy
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: &preemptibleProvider{}})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	long := agent.NewTask("Long analysis", nil).WithPriority(agent.PriorityLow)
+	done := make(chan *agent.TaskResult, 1)
+	go func() {
+		result, _ := c.Submit(long)
+		done <- result
+	}()
+	waitFor(t, 2*time.Second, func() bool { return a.GetState() == agent.StateWorking })
+
+	urgent, err := c.Submit(agent.NewTask("Urgent fix", nil).WithPriority(agent.PriorityCritical))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if urgent.Status != agent.TaskCompleted {
+		t.Fatalf("Expected the critical task to complete, got %s (%s)", urgent.Status, urgent.Error)
+	}
+
+	var result *agent.TaskResult
+	select {
+	case result = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("The preempted task never resumed")
+	}
+	if result == nil || result.Status != agent.TaskCompleted {
+		t.Fatalf("Expected the preempted task to complete on resuming, got %+v", result)
+	}
+	if !urgent.Timestamp.Before(result.Timestamp) {
+		t.Error("Expected the critical task to finish first")
+	}
+
+	timeline, _ := c.Timeline(long.ID)
+	preempted := false
+	for _, e := range timeline {
+		if e.Stage == StagePreempted {
+			preempted = true
+		}
+	}
+	if !preempted {
+		t.Errorf("Expected a preempted stage in the timeline, got %+v", timeline)
+	}
+	if cp, ok := long.Progress().LastCheckpoint(agent.PreemptedCheckpoint); !ok || cp.State != "draft section one" {
+		t.Errorf("Expected the draft checkpointed, got %+v", cp)
+	}
+	for _, e := range c.GetReputation().GetHistory(a.Identity.SID) {
+		if e.Type == "task_failure" || e.Type == "stake_forfeited" {
+			t.Errorf("Expected no penalty for a preempted task, got %s", e.Type)
+		}
+	}
+}
+
+func TestCollective_Digest(t *testing.T) {
+	provider := &flakyProvider{failures: 1}
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	_, _ = c.Submit(agent.NewTask("Summarize the incident. Then file a ticket.", nil).WithQoS(agent.QoSInteractive))
+	_, _ = c.Submit(agent.NewTask("Draft the\nrelease notes", nil))
+
+	d := c.Digest(DigestDaily, time.Time{})
+	if d.Tasks != 2 || d.Completed != 1 || d.Failed != 1 || len(d.Summaries) != 2 {
+		t.Fatalf("Expected 1 completed and 1 failed task, got %+v", d)
+	}
+	if d.Quality != d.Summaries[0].Quality {
+		t.Errorf("Expected the completed task's quality %.2f, got %.2f", d.Summaries[0].Quality, d.Quality)
+	}
+	if len(d.Agents) != 1 || d.Agents[0].Name != "Worker" || d.Agents[0].Tasks != 2 {
+		t.Errorf("Expected both tasks credited to Worker, got %+v", d.Agents)
+	}
+
+	latest, first := d.Summaries[0], d.Summaries[1]
+	if latest.Asked != "Draft the release notes" || latest.Outcome != "done" || latest.AgentName != "Worker" {
+		t.Errorf("Expected the newest task first with its outcome, got %+v", latest)
+	}
+	if first.Asked != "Summarize the incident." || !strings.HasPrefix(first.Outcome, "Failed: ") {
+		t.Errorf("Expected the first sentence asked and the failure, got %+v", first)
+	}
+
+	if old := c.Digest(DigestWeekly, time.Now().Add(-8*24*time.Hour)); old.Tasks != 0 {
+		t.Errorf("Expected no tasks in an earlier week, got %d", old.Tasks)
+	}
+
+	// Scheduled digests go to the registered notifiers when due
+	var posted []*Digest
+	c.OnDigest(func(d *Digest) { posted = append(posted, d) })
+	if err := c.ScheduleDigest(DigestSchedule{Period: "monthly"}); !errors.Is(err, ErrUnknownDigestPeriod) {
+		t.Errorf("Expected ErrUnknownDigestPeriod, got %v", err)
+	}
+	if err := c.ScheduleDigest(DigestSchedule{Period: DigestWeekly, Cron: "0 9 * *"}); !errors.Is(err, ErrInvalidCron) {
+		t.Errorf("Expected ErrInvalidCron, got %v", err)
+	}
+	if err := c.ScheduleDigest(DigestSchedule{Period: DigestWeekly}); err != nil {
+		t.Fatalf("ScheduleDigest failed: %v", err)
+	}
+
+	c.postDigests(time.Now())
+	if len(posted) != 0 {
+		t.Errorf("Expected no digest before the schedule is due, got %d", len(posted))
+	}
+	next := time.Now().Add(8 * 24 * time.Hour)
+	c.postDigests(next)
+	c.postDigests(next)
+	if len(posted) != 1 || posted[0].Period != DigestWeekly || !posted[0].End.Equal(next) {
+		t.Errorf("Expected one weekly digest when due, got %d", len(posted))
+	}
+	if text := d.Text(1); !strings.Contains(text, "2 tasks: 1 completed, 1 failed") || !strings.Contains(text, "…and 1 more") {
+		t.Errorf("Expected totals and a truncated list, got:\n%s", text)
+	}
+}
+
+func TestCollective_ReviewRotation(t *testing.T) {
+	tests := []struct {
+		name      string
+		rotation  *ReviewRotation
+		reviewers int
+	}{
+		{"best bid", nil, 1},
+		{"rotation", &ReviewRotation{}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultCollectiveConfig()
+			cfg.Reviews = tt.rotation
+			c := NewCollective("TestCollective", cfg)
+			c.GetMarket().SetBidTimeout(time.Millisecond)
+			for _, name := range []string{"Reviewer1", "Reviewer2", "Reviewer3"} {
+				a, _ := agent.NewAgent(agent.AgentConfig{Name: name, Capabilities: []identity.CapabilityType{identity.CapCodeReview, identity.CapCodeWrite}})
+				_ = c.Join(a)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			_ = c.Start(ctx)
+			defer c.Stop()
+
+			reviewers := make(map[string]int)
+			rotated := 0
+			for i := 0; i < 6; i++ {
+				result, err := c.Submit(agent.NewTask("Review the change", []identity.CapabilityType{identity.CapCodeReview}))
+				if err != nil {
+					t.Fatalf("Submit failed: %v", err)
+				}
+				reviewers[result.AgentSID]++
+				if exp, _ := c.ExplainAssignment(result.TaskID); exp != nil && exp.Rotated {
+					rotated++
+				}
+			}
+			if len(reviewers) != tt.reviewers {
+				t.Errorf("Expected reviews spread over %d agents, got %v", tt.reviewers, reviewers)
+			}
+			for sid, n := range reviewers {
+				if tt.rotation != nil && n != 2 {
+					t.Errorf("Expected 2 reviews each, got %d for %s", n, sid)
+				}
+			}
+			if (rotated > 0) != (tt.rotation != nil) {
+				t.Errorf("Expected rotated assignments only with rotation, got %d", rotated)
+			}
+
+			// Other tasks still go to the best bid
+			first, _ := c.Submit(agent.NewTask("Write code", []identity.CapabilityType{identity.CapCodeWrite}))
+			second, _ := c.Submit(agent.NewTask("Write code", []identity.CapabilityType{identity.CapCodeWrite}))
+			if first.AgentSID != second.AgentSID {
+				t.Errorf("Expected non-review tasks to keep going to the best bid")
+			}
+			if loads := c.ReviewLoads(); tt.rotation != nil && len(loads) != 3 {
+				t.Errorf("Expected load recorded for 3 agents, got %v", loads)
+			}
+		})
+	}
+}
+
+func TestCollective_ConflictOfInterest(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    *AntiAffinityPolicy
+		unrelated bool // An unrelated reviewer joins alongside the author's child
+		want      string
+		wantErr   error
+	}{
+		{"not checked", nil, false, "child", nil},
+		{"warn", &AntiAffinityPolicy{}, false, "child", nil},
+		{"block", &AntiAffinityPolicy{Action: ConflictBlock}, true, "unrelated", nil},
+		{"block without alternative", &AntiAffinityPolicy{Action: ConflictBlock}, false, "", ErrConflictOfInterest},
+		{"lineage not counted", &AntiAffinityPolicy{Action: ConflictBlock, Relations: []ConflictKind{ConflictCoalition}}, false, "child", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultCollectiveConfig()
+			cfg.AntiAffinity = tt.policy
+			c := NewCollective("TestCollective", cfg)
+			c.GetMarket().SetBidTimeout(time.Millisecond)
+			events := c.Events()
+
+			author, _ := agent.NewAgent(agent.AgentConfig{Name: "Author", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+			child, _ := agent.NewAgent(agent.AgentConfig{Name: "Child", ParentSID: author.Identity.SID, Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+			_ = c.Join(author)
+			_ = c.Join(child)
+			sids := map[string]string{"child": child.Identity.SID}
+			if tt.unrelated {
+				other, _ := agent.NewAgent(agent.AgentConfig{Name: "Unrelated", Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+				_ = c.Join(other)
+				sids["unrelated"] = other.Identity.SID
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			_ = c.Start(ctx)
+			defer c.Stop()
+
+			task := agent.NewTask("Review the change", []identity.CapabilityType{identity.CapCodeReview}).WithAuthor(author.Identity.SID)
+			result, err := c.Submit(task)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Submit failed: %v", err)
+			}
+			if result.AgentSID != sids[tt.want] {
+				t.Errorf("Expected the review assigned to %s, got %s", tt.want, result.AgentSID)
+			}
+
+			exp, _ := c.ExplainAssignment(task.ID)
+			warned := tt.policy != nil && tt.policy.Action != ConflictBlock
+			if warned != (exp != nil && len(exp.Conflicts) == 1 && exp.Conflicts[0].Kind == ConflictLineage) {
+				t.Errorf("Expected a lineage conflict explained only when warning, got %+v", exp)
+			}
+			published := false
+			for len(events) > 0 {
+				if e := <-events; e.Type == EventConflictOfInterest {
+					published = true
+				}
+			}
+			if published != warned {
+				t.Errorf("Expected conflict_of_interest published %v, got %v", warned, published)
+			}
+		})
+	}
+}
+
+func TestCollective_Conflicts(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	agents := make([]*agent.Agent, 4)
+	for i := range agents {
+		agents[i], _ = agent.NewAgent(agent.AgentConfig{Name: fmt.Sprintf("Agent%d", i), Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+		_ = c.Join(agents[i])
+	}
+	a, b, d, e := agents[0].Identity.SID, agents[1].Identity.SID, agents[2].Identity.SID, agents[3].Identity.SID
+
+	_, _ = c.CreateTeam("backend", TeamConfig{})
+	_ = c.AddToTeam("backend", a)
+	_ = c.AddToTeam("backend", b)
+	for i := 0; i < 10; i++ {
+		c.conflicts.noteBidders([]string{d, e})
+	}
+	c.conflicts.noteBidders([]string{a, d})
+
+	tests := []struct {
+		name     string
+		reviewer string
+		author   string
+		want     []ConflictKind
+	}{
+		{"self", a, a, []ConflictKind{ConflictSelf}},
+		{"coalition", b, a, []ConflictKind{ConflictCoalition}},
+		{"co-bidding", e, d, []ConflictKind{ConflictCoBidding}},
+		{"too few co-bids", a, d, nil},
+		{"none", b, e, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts := c.Conflicts(tt.reviewer, tt.author)
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("Expected %v, got %+v", tt.want, conflicts)
+			}
+			for i, conflict := range conflicts {
+				if conflict.Kind != tt.want[i] {
+					t.Errorf("Expected %s, got %s", tt.want[i], conflict.Kind)
+				}
+			}
+		})
+	}
+
+	// Provenance outlives membership
+	_ = c.Leave(b)
+	if conflicts := c.Conflicts(b, a); len(conflicts) != 1 {
+		t.Errorf("Expected the coalition kept after leaving, got %+v", conflicts)
+	}
+}
+
+func TestCollective_ExportImport(t *testing.T) {
+	src := NewCollective("Source", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{
+		Name:         "Coder",
+		Capabilities: []identity.CapabilityType{identity.CapCodeWrite},
+		SystemPrompt: "Write Go",
+	})
+	a.Capabilities.Add(&identity.Capability{Type: identity.CapCodeWrite, Proficiency: 0.9})
+	if err := src.Join(a); err != nil {
+		t.Fatalf("Failed to join: %v", err)
+	}
+	src.reputation.Get(a.Identity.SID).TasksCompleted = 7
+	src.memory.Contribute(a.Identity.SID, "learned something", nil)
+	src.memory.AddConcept("caching", "keep results", a.Identity.SID)
+
+	_, key, _ := ed25519.GenerateKey(nil)
+	data, err := src.Export().Sign(key)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	snapshot, signer, err := OpenSnapshot(data, key.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	if !signer.Equal(key.Public()) {
+		t.Error("Expected the signing key to be returned")
+	}
+
+	dst := NewCollective("Destination", DefaultCollectiveConfig())
+	result, err := dst.Import(snapshot, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if result.Agents != 1 {
+		t.Errorf("Expected 1 agent imported, got %d", result.Agents)
+	}
+
+	imported, ok := dst.GetAgent(a.Identity.SID)
+	if !ok {
+		t.Fatal("Expected the agent to keep its SID")
+	}
+	if imported.Identity.Name != "Coder" || imported.SystemPrompt != "Write Go" {
+		t.Errorf("Expected name and prompt to be kept, got %s %q", imported.Identity.Name, imported.SystemPrompt)
+	}
+	if p := imported.Capabilities.Proficiency(identity.CapCodeWrite); p != 0.9 {
+		t.Errorf("Expected proficiency 0.9, got %f", p)
+	}
+	if n := dst.reputation.Get(a.Identity.SID).TasksCompleted; n != 7 {
+		t.Errorf("Expected 7 completed tasks, got %d", n)
+	}
+	if len(dst.memory.Query("learned")) == 0 {
+		t.Error("Expected episodes to be imported")
+	}
+	if dst.memory.Stats().ConceptCount != 1 {
+		t.Errorf("Expected 1 concept, got %d", dst.memory.Stats().ConceptCount)
+	}
+
+	// Importing again skips agents already present
+	result, _ = dst.Import(snapshot, ImportOptions{})
+	if result.Agents != 0 || len(result.Skipped) != 1 || result.Episodes != 0 {
+		t.Errorf("Expected a repeated import to add nothing, got %+v", result)
+	}
+
+	// Tampering and untrusted signers are rejected
+	tampered := strings.Replace(string(data), "Coder", "Hacker", 1)
+	if _, _, err := OpenSnapshot([]byte(tampered)); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Errorf("Expected ErrInvalidSnapshot, got %v", err)
+	}
+	other, _, _ := ed25519.GenerateKey(nil)
+	if _, _, err := OpenSnapshot(data, other); !errors.Is(err, ErrUntrustedSnapshot) {
+		t.Errorf("Expected ErrUntrustedSnapshot, got %v", err)
+	}
+}
+
+func TestCollective_Models(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.Models = agent.ModelMap{identity.CapDocumentation: "small", identity.CapCodeWrite: "ignored"}
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	// Answers with the model each request was made for
+	a, _ := agent.NewAgent(agent.AgentConfig{
+		Name:         "Agent1",
+		Capabilities: []identity.CapabilityType{identity.CapCodeWrite, identity.CapDocumentation},
+		Provider:     llm.NewMockProvider(llm.MockScript{Default: "{{.Model}}"}),
+		Model:        "frontier",
+		Models:       agent.ModelMap{identity.CapCodeWrite: "coder"},
+	})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	tests := []struct {
+		caps []identity.CapabilityType
+		want string
+	}{
+		{[]identity.CapabilityType{identity.CapDocumentation}, "small"},
+		{[]identity.CapabilityType{identity.CapCodeWrite}, "coder"},
+		{nil, "frontier"},
+	}
+	for _, tt := range tests {
+		result, err := c.Submit(agent.NewTask("Do the work", tt.caps))
+		if err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		if result.Output != tt.want {
+			t.Errorf("Expected %v to run on %s, got %s", tt.caps, tt.want, result.Output)
+		}
+	}
+}
+
+func TestCollective_Benchmark(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{
+		Name:         "Agent1",
+		Capabilities: []identity.CapabilityType{identity.CapSecurity},
+		Provider:     &staticProvider{content: "Classic SQL injection; use a parameterized query."},
+	})
+	_ = c.Join(a)
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+
+	suite := benchmark.Suite{
+		Name:       "findings",
+		Capability: identity.CapSecurity,
+		Cases:      []benchmark.Case{{Name: "sqli", Prompt: "Review this", Expect: []string{"sql injection", "parameterized"}}},
+	}
+	before := a.Capabilities.MatchScore([]identity.CapabilityType{identity.CapSecurity})
+
+	report, err := c.Benchmark(context.Background(), a.Identity.SID, suite, nil)
+	if err != nil {
+		t.Fatalf("Benchmark failed: %v", err)
+	}
+	if report.Score != 1 {
+		t.Errorf("Expected score 1, got %f", report.Score)
+	}
+	if proof := a.Capabilities.Get(identity.CapSecurity).Proof; proof != report.Proof {
+		t.Error("Expected the proof attached to the agent's capability")
+	}
+	if after := a.Capabilities.MatchScore([]identity.CapabilityType{identity.CapSecurity}); after <= before {
+		t.Errorf("Expected the proof to raise the match score above %f, got %f", before, after)
+	}
+	proven := false
+	for !proven {
+		select {
+		case e := <-events:
+			proven = e.Type == EventCapabilityProven && e.AgentSID == a.Identity.SID
+		case <-time.After(time.Second):
+			t.Fatal("Expected a capability_proven event")
+		}
+	}
+
+	suite.Capability = identity.CapCodeWrite
+	if _, err := c.Benchmark(context.Background(), a.Identity.SID, suite, nil); !errors.Is(err, ErrCapabilityNotHeld) {
+		t.Errorf("Expected ErrCapabilityNotHeld, got %v", err)
+	}
+	if _, err := c.Benchmark(context.Background(), "missing", suite, nil); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+}
+
+func TestCollective_JoinDropsForeignProof(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite, identity.CapSecurity}})
+
+	forger, _ := identity.NewSquaremindIdentity("forger", "")
+	a.Capabilities.Prove(identity.CapCodeWrite, identity.NewBenchmarkProof(forger, a.Identity.SID, identity.CapCodeWrite, "go-basics", 1, time.Hour))
+	valid := identity.NewBenchmarkProof(c.Identity(), a.Identity.SID, identity.CapSecurity, "security-basics", 1, time.Hour)
+	a.Capabilities.Prove(identity.CapSecurity, valid)
+
+	if err := c.Join(a); err != nil {
+		t.Fatalf("Join failed: %v", err)
+	}
+	if a.Capabilities.Get(identity.CapCodeWrite).Proof != nil {
+		t.Error("Expected a proof signed by another key to be dropped")
+	}
+	if a.Capabilities.Get(identity.CapSecurity).Proof != valid {
+		t.Error("Expected a proof signed by the collective to be kept")
+	}
+}
+
+func TestCollective_ReputationDecayConfig(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.ReputationDecay = 0.1
+	cfg.ReputationDecayCurve = agent.DecayExponential
+	c := NewCollective("TestCollective", cfg)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+
+	if a.Reputation.DecayCurve != agent.DecayExponential || a.Reputation.DecayRate != 0.1 {
+		t.Errorf("Expected the collective's decay on the agent, got %s at %v", a.Reputation.DecayCurve, a.Reputation.DecayRate)
+	}
+
+	a.Reputation.LastActive = time.Now().Add(-agent.DecayGrace - 2*24*time.Hour)
+	c.maintenance()
+	if want := 50 * 0.9 * 0.9; math.Abs(a.Reputation.Overall-want) > 0.01 {
+		t.Errorf("Expected overall %f after 2 idle days, got %f", want, a.Reputation.Overall)
+	}
+
+	cfg.ReputationDecayCurve = "sigmoid"
+	c = NewCollective("TestCollective", cfg)
+	b, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent2"})
+	_ = c.Join(b)
+	if b.Reputation.DecayCurve != agent.DecayLinear {
+		t.Errorf("Expected an unknown curve to fall back to linear, got %s", b.Reputation.DecayCurve)
+	}
+}
+
+func TestCollective_MaintenanceLeavesOverdueTasksActive(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	task := agent.NewTask("slow", nil)
+	task.CreatedAt = time.Now().Add(-3 * time.Hour)
+	task.Deadline = task.CreatedAt.Add(time.Hour)
+	task.Status = agent.TaskRunning
+	c.activeTasks[task.ID] = task
+
+	c.maintenance()
+
+	if _, ok := c.activeTasks[task.ID]; !ok {
+		t.Error("Expected a task past twice its deadline to stay active")
+	}
+	if got := c.Stats().PendingTasks; got != 0 {
+		t.Errorf("Expected no pending tasks, got %d", got)
+	}
+}
@@ -0,0 +1,212 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+var (
+	ErrTooFewModels   = errors.New("comparison needs at least two models")
+	ErrDuplicateModel = errors.New("model compared twice")
+)
+
+// ModelSpec is a model Compare runs a task on
+type ModelSpec struct {
+	Name     string       `json:"name"` // Label in the report (default <provider>/<model>)
+	Provider llm.Provider `json:"-"`
+	Model    string       `json:"model,omitempty"` // Empty = the provider's default
+}
+
+// label returns the spec's name in the report
+func (s ModelSpec) label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	model := s.Model
+	if model == "" {
+		model = "default"
+	}
+	return s.Provider.Name() + "/" + model
+}
+
+// Judgement is one critic's verdict on a model's output
+type Judgement struct {
+	Judge    string  `json:"judge"`
+	Quality  float64 `json:"quality"`
+	Feedback string  `json:"feedback,omitempty"`
+}
+
+// ModelResult is how one model did on the compared task
+type ModelResult struct {
+	Name       string        `json:"name"`
+	Model      string        `json:"model,omitempty"`
+	Rank       int           `json:"rank"` // 1 = best quality; failed models rank last
+	Output     string        `json:"output,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Quality    float64       `json:"quality"` // Mean of the judgements, 0.0 - 1.0
+	Judgements []Judgement   `json:"judgements,omitempty"`
+	TokensUsed int           `json:"tokens_used"` // Answering, not judging
+	Duration   time.Duration `json:"duration"`
+}
+
+// ModelComparison is the report of Compare
+type ModelComparison struct {
+	TaskID    string        `json:"task_id"`
+	Task      string        `json:"task"`
+	Judge     string        `json:"judge"` // The critic, or "peers"
+	Winner    string        `json:"winner,omitempty"`
+	Results   []ModelResult `json:"results"` // Best first
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// SetJudge makes Compare score outputs with critic instead of having the
+// compared models review each other
+func (c *Collective) SetJudge(critic agent.Critic) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.judge = critic
+}
+
+// Compare runs a task on each model in parallel and scores the outputs.
+// Each model answers through an agent of its own outside the collective,
+// with the task's required capabilities and nothing learned or recorded
+// (see agent.Attempt), so the models get the same prompt. The outputs are
+// critiqued by the judge set with SetJudge or, without one, by each of the
+// other models, and ranked by mean quality. A model that fails to answer
+// ranks last with its error; one whose critiques all fail scores 0.
+func (c *Collective) Compare(ctx context.Context, task *agent.Task, specs []ModelSpec) (*ModelComparison, error) {
+	if len(specs) < 2 {
+		return nil, fmt.Errorf("%w: got %d", ErrTooFewModels, len(specs))
+	}
+	contenders := make([]*agent.Agent, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		if spec.Provider == nil {
+			return nil, fmt.Errorf("%w: %s", agent.ErrNoProvider, spec.Name)
+		}
+		if seen[spec.label()] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateModel, spec.label())
+		}
+		seen[spec.label()] = true
+		a, err := agent.NewAgent(agent.AgentConfig{
+			Name:         spec.label(),
+			Capabilities: task.Required,
+			Model:        spec.Model,
+			Provider:     spec.Provider,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", spec.label(), err)
+		}
+		contenders[i] = a
+	}
+
+	c.mu.RLock()
+	judge := c.judge
+	c.mu.RUnlock()
+
+	start := time.Now()
+	report := &ModelComparison{
+		TaskID:    task.ID,
+		Task:      task.Description,
+		Judge:     "peers",
+		Results:   make([]ModelResult, len(specs)),
+		Timestamp: start,
+	}
+	if a, ok := judge.(*agent.Agent); ok {
+		report.Judge = a.Identity.Name
+	} else if judge != nil {
+		report.Judge = "judge"
+	}
+
+	var wg sync.WaitGroup
+	for i := range specs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result := &report.Results[i]
+			result.Name, result.Model = specs[i].label(), specs[i].Model
+
+			attemptStart := time.Now()
+			answer, err := contenders[i].Attempt(ctx, task)
+			result.Duration = time.Since(attemptStart)
+			if err != nil {
+				result.Error = err.Error()
+				return
+			}
+			result.Output, result.TokensUsed = answer.Output, answer.TokensUsed
+		}(i)
+	}
+	wg.Wait()
+
+	// Judge once every output is in, so peers review in parallel too
+	for i := range report.Results {
+		if report.Results[i].Error != "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result := &report.Results[i]
+			if judge != nil {
+				result.Judgements = c.judgeOutput(ctx, task, result.Output, map[string]agent.Critic{report.Judge: judge})
+			} else {
+				peers := make(map[string]agent.Critic, len(contenders)-1)
+				for j, peer := range contenders {
+					if j != i {
+						peers[specs[j].label()] = peer
+					}
+				}
+				result.Judgements = c.judgeOutput(ctx, task, result.Output, peers)
+			}
+			if n := len(result.Judgements); n > 0 {
+				var total float64
+				for _, j := range result.Judgements {
+					total += j.Quality
+				}
+				result.Quality = total / float64(n)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		a, b := report.Results[i], report.Results[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		return a.Quality > b.Quality
+	})
+	for i := range report.Results {
+		report.Results[i].Rank = i + 1
+	}
+	if best := report.Results[0]; best.Error == "" && len(best.Judgements) > 0 {
+		report.Winner = best.Name
+	}
+	report.Duration = time.Since(start)
+
+	c.log().Info("models compared", "task", task.ID, "models", len(specs), "winner", report.Winner, "duration", report.Duration)
+	return report, ctx.Err()
+}
+
+// judgeOutput has each critic review an output, skipping failed critiques
+func (c *Collective) judgeOutput(ctx context.Context, task *agent.Task, output string, critics map[string]agent.Critic) []Judgement {
+	var judgements []Judgement
+	for name, critic := range critics {
+		critique, err := critic.Critique(ctx, task, output)
+		if err != nil {
+			c.log().Warn("comparison critique failed", "task", task.ID, "judge", name, "error", err)
+			continue
+		}
+		judgements = append(judgements, Judgement{Judge: name, Quality: critique.Quality, Feedback: critique.Feedback})
+	}
+	sort.Slice(judgements, func(i, j int) bool { return judgements[i].Judge < judgements[j].Judge })
+	return judgements
+}
@@ -0,0 +1,87 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// gradingProvider answers tasks with a fixed answer and critiques answers
+// by whether they mention an LRU cache
+type gradingProvider struct {
+	name   string
+	answer string
+}
+
+func (p *gradingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	if strings.HasPrefix(req.Prompt, "Review this answer") {
+		if strings.Contains(req.Prompt, "LRU") {
+			return &llm.CompletionResponse{Content: `{"quality": 0.9}`, TokensUsed: 5}, nil
+		}
+		return &llm.CompletionResponse{Content: `{"quality": 0.3, "feedback": "say how entries are evicted"}`, TokensUsed: 5}, nil
+	}
+	return &llm.CompletionResponse{Content: p.answer, TokensUsed: 20}, nil
+}
+
+func (p *gradingProvider) Name() string {
+	return p.name
+}
+
+func TestCollective_Compare(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	task := agent.NewTask("Pick a cache for the API", []identity.CapabilityType{identity.CapResearch})
+
+	specs := []ModelSpec{
+		{Provider: &gradingProvider{name: "weak", answer: "use a map"}, Model: "small"},
+		{Name: "strong", Provider: &gradingProvider{name: "strong", answer: "use an LRU cache"}},
+		{Name: "down", Provider: &failingProvider{}},
+	}
+	report, err := c.Compare(context.Background(), task, specs)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	if report.Winner != "strong" || report.Judge != "peers" {
+		t.Errorf("Expected strong to win on peer review, got %q judged by %q", report.Winner, report.Judge)
+	}
+	if len(report.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(report.Results))
+	}
+	strong, weak, down := report.Results[0], report.Results[1], report.Results[2]
+	if strong.Name != "strong" || weak.Name != "weak/small" || down.Name != "down" {
+		t.Errorf("Expected strong, weak/small, down, got %s, %s, %s", strong.Name, weak.Name, down.Name)
+	}
+	// The failed model can't review, so each output is judged by the other answering model
+	if len(strong.Judgements) != 1 || strong.Judgements[0].Judge != "weak/small" || strong.Quality != 0.9 {
+		t.Errorf("Expected strong judged 0.9 by weak/small, got %+v", strong.Judgements)
+	}
+	if weak.Quality != 0.3 || weak.Judgements[0].Feedback == "" || weak.TokensUsed != 20 {
+		t.Errorf("Expected weak judged 0.3 with feedback, got %+v", weak)
+	}
+	if down.Error == "" || down.Rank != 3 || down.Judgements != nil {
+		t.Errorf("Expected the failed model ranked last with its error, got %+v", down)
+	}
+
+	// A judge replaces peer review
+	judge, _ := agent.NewAgent(agent.AgentConfig{Name: "Judge", Provider: &gradingProvider{name: "judge"}})
+	c.SetJudge(judge)
+	report, err = c.Compare(context.Background(), task, specs[:2])
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if report.Judge != "Judge" || report.Results[0].Judgements[0].Judge != "Judge" {
+		t.Errorf("Expected outputs judged by Judge, got %q", report.Judge)
+	}
+
+	if _, err := c.Compare(context.Background(), task, specs[:1]); !errors.Is(err, ErrTooFewModels) {
+		t.Errorf("Expected ErrTooFewModels, got %v", err)
+	}
+	if _, err := c.Compare(context.Background(), task, []ModelSpec{specs[1], specs[1]}); !errors.Is(err, ErrDuplicateModel) {
+		t.Errorf("Expected ErrDuplicateModel, got %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+package collective
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+var ErrConflictOfInterest = errors.New("every agent able to take the task has a conflict of interest with its author")
+
+// ConflictKind is a relationship that compromises an agent's review of
+// another's work
+type ConflictKind string
+
+const (
+	ConflictSelf      ConflictKind = "self"       // The agent is the author
+	ConflictLineage   ConflictKind = "lineage"    // One spawned the other, directly or through descendants, or both share a parent
+	ConflictCoalition ConflictKind = "coalition"  // The two have served on the same team
+	ConflictCoBidding ConflictKind = "co_bidding" // The two bid for the same tasks unusually often
+)
+
+// ConflictAction is what the collective does when the agent picked to
+// review a task has a conflict of interest with its author
+type ConflictAction string
+
+const (
+	ConflictWarn  ConflictAction = "warn"  // Assign anyway, but log, publish and explain the conflict
+	ConflictBlock ConflictAction = "block" // Leave conflicted agents out of the auction
+)
+
+// Conflict is one relationship between a reviewer and an author
+type Conflict struct {
+	Kind   ConflictKind `json:"kind"`
+	Detail string       `json:"detail"`
+}
+
+// AntiAffinityPolicy keeps agents from reviewing or judging work they have
+// a stake in. It applies to tasks that name an Author.
+type AntiAffinityPolicy struct {
+	// Action is warn (default) or block
+	Action ConflictAction `json:"action,omitempty" yaml:"action,omitempty"`
+
+	// Relations that count as conflicts (default: all of them)
+	Relations []ConflictKind `json:"relations,omitempty" yaml:"relations,omitempty"`
+
+	// MinCoBids is how many auctions two agents must have bid in together
+	// before co-bidding counts (default 10)
+	MinCoBids int `json:"min_co_bids,omitempty" yaml:"min_co_bids,omitempty"`
+
+	// CoBidShare is the share of the less active agent's bids that must
+	// have been alongside the other's (default 0.5)
+	CoBidShare float64 `json:"co_bid_share,omitempty" yaml:"co_bid_share,omitempty"`
+}
+
+// DefaultAntiAffinityPolicy warns about every kind of conflict
+func DefaultAntiAffinityPolicy() AntiAffinityPolicy {
+	return AntiAffinityPolicy{
+		Action:     ConflictWarn,
+		Relations:  []ConflictKind{ConflictSelf, ConflictLineage, ConflictCoalition, ConflictCoBidding},
+		MinCoBids:  10,
+		CoBidShare: 0.5,
+	}
+}
+
+// withDefaults fills unset fields from DefaultAntiAffinityPolicy. Any
+// action other than block warns.
+func (p AntiAffinityPolicy) withDefaults() AntiAffinityPolicy {
+	d := DefaultAntiAffinityPolicy()
+	if p.Action != ConflictBlock {
+		p.Action = d.Action
+	}
+	if len(p.Relations) == 0 {
+		p.Relations = d.Relations
+	}
+	if p.MinCoBids <= 0 {
+		p.MinCoBids = d.MinCoBids
+	}
+	if p.CoBidShare <= 0 {
+		p.CoBidShare = d.CoBidShare
+	}
+	return p
+}
+
+// counts reports whether the policy treats a kind of relationship as a conflict
+func (p AntiAffinityPolicy) counts(kind ConflictKind) bool {
+	for _, k := range p.Relations {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// agentPair is two agent SIDs in sorted order
+type agentPair [2]string
+
+func pairOf(a, b string) agentPair {
+	if b < a {
+		a, b = b, a
+	}
+	return agentPair{a, b}
+}
+
+// conflictTracker is the provenance behind conflicts of interest: who
+// spawned whom, which agents have shared a team and how often agents bid
+// for the same tasks. It outlives membership, so an agent that leaves and
+// rejoins keeps its history.
+type conflictTracker struct {
+	mu sync.Mutex
+
+	parents    map[string]string             // Agent SID -> the SID of the agent that spawned it
+	coalitions map[agentPair]map[string]bool // Pair -> teams both have served on
+	bids       map[string]int                // Agent SID -> auctions it bid in
+	coBids     map[agentPair]int             // Pair -> auctions both bid in
+}
+
+func newConflictTracker() *conflictTracker {
+	return &conflictTracker{
+		parents:    make(map[string]string),
+		coalitions: make(map[agentPair]map[string]bool),
+		bids:       make(map[string]int),
+		coBids:     make(map[agentPair]int),
+	}
+}
+
+// noteParent records that parent spawned sid
+func (t *conflictTracker) noteParent(sid, parent string) {
+	if parent == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.parents[sid] = parent
+}
+
+// noteTeam records that sid joined a team alongside members
+func (t *conflictTracker) noteTeam(team, sid string, members []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, m := range members {
+		if m == sid {
+			continue
+		}
+		pair := pairOf(sid, m)
+		if t.coalitions[pair] == nil {
+			t.coalitions[pair] = make(map[string]bool)
+		}
+		t.coalitions[pair][team] = true
+	}
+}
+
+// noteBidders records the agents that bid in one auction
+func (t *conflictTracker) noteBidders(sids []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i, a := range sids {
+		t.bids[a]++
+		for _, b := range sids[i+1:] {
+			t.coBids[pairOf(a, b)]++
+		}
+	}
+}
+
+// ancestorsLocked returns sid's ancestors, nearest first
+func (t *conflictTracker) ancestorsLocked(sid string) []string {
+	var chain []string
+	seen := map[string]bool{sid: true}
+	for parent := t.parents[sid]; parent != "" && !seen[parent]; parent = t.parents[parent] {
+		seen[parent] = true
+		chain = append(chain, parent)
+	}
+	return chain
+}
+
+// between returns the relationships between a reviewer and an author that
+// the policy counts as conflicts
+func (t *conflictTracker) between(reviewer, author string, policy AntiAffinityPolicy) []Conflict {
+	var conflicts []Conflict
+	if reviewer == author {
+		if policy.counts(ConflictSelf) {
+			conflicts = append(conflicts, Conflict{Kind: ConflictSelf, Detail: "reviewer is the author"})
+		}
+		return conflicts
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if policy.counts(ConflictLineage) {
+		if detail := t.lineageLocked(reviewer, author); detail != "" {
+			conflicts = append(conflicts, Conflict{Kind: ConflictLineage, Detail: detail})
+		}
+	}
+
+	pair := pairOf(reviewer, author)
+	if teams := t.coalitions[pair]; len(teams) > 0 && policy.counts(ConflictCoalition) {
+		names := make([]string, 0, len(teams))
+		for name := range teams {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		conflicts = append(conflicts, Conflict{Kind: ConflictCoalition, Detail: "served together on " + strings.Join(names, ", ")})
+	}
+
+	if together := t.coBids[pair]; together >= policy.MinCoBids && policy.counts(ConflictCoBidding) {
+		fewer := min(t.bids[reviewer], t.bids[author])
+		if fewer > 0 && float64(together)/float64(fewer) >= policy.CoBidShare {
+			conflicts = append(conflicts, Conflict{
+				Kind:   ConflictCoBidding,
+				Detail: fmt.Sprintf("bid together in %d of %d auctions", together, fewer),
+			})
+		}
+	}
+	return conflicts
+}
+
+// lineageLocked describes how two agents are related by spawning, or
+// returns "" if they aren't
+func (t *conflictTracker) lineageLocked(reviewer, author string) string {
+	for i, sid := range t.ancestorsLocked(author) {
+		if sid == reviewer {
+			return fmt.Sprintf("reviewer is the author's ancestor (%d generations)", i+1)
+		}
+	}
+	for i, sid := range t.ancestorsLocked(reviewer) {
+		if sid == author {
+			return fmt.Sprintf("author is the reviewer's ancestor (%d generations)", i+1)
+		}
+	}
+	if parent := t.parents[reviewer]; parent != "" && parent == t.parents[author] {
+		return "both were spawned by " + parent
+	}
+	return ""
+}
+
+// Conflicts returns the relationships between a reviewer and an author that
+// the anti-affinity policy counts as conflicts of interest, or every known
+// relationship if the collective has no policy
+func (c *Collective) Conflicts(reviewer, author string) []Conflict {
+	policy := DefaultAntiAffinityPolicy()
+	if c.antiAffinity != nil {
+		policy = *c.antiAffinity
+	}
+	return c.conflicts.between(reviewer, author, policy)
+}
+
+// screenConflicts leaves the agents with a conflict of interest with the
+// task's author out of its scope, if the anti-affinity policy blocks them
+func (c *Collective) screenConflicts(task *agent.Task, scope *assignmentScope) error {
+	if task.Author == "" || c.antiAffinity == nil || c.antiAffinity.Action != ConflictBlock {
+		return nil
+	}
+	allowed := make(map[string]*agent.Agent, len(scope.agents))
+	for sid, a := range scope.agents {
+		if len(c.conflicts.between(sid, task.Author, *c.antiAffinity)) > 0 {
+			scope.excluded = append(scope.excluded, Exclusion{AgentSID: sid, Agent: a.Identity.Name, Reason: ExcludedConflict})
+			continue
+		}
+		allowed[sid] = a
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("%w: %s", ErrConflictOfInterest, task.Author)
+	}
+	scope.agents = allowed
+	return nil
+}
+
+// noteBidders records who bid together in an auction, for co-bidding conflicts
+func (c *Collective) noteBidders(exp *AssignmentExplanation) {
+	if len(exp.Bids) < 2 {
+		return
+	}
+	sids := make([]string, len(exp.Bids))
+	for i, b := range exp.Bids {
+		sids[i] = b.Bid.AgentSID
+	}
+	c.conflicts.noteBidders(sids)
+}
+
+// checkConflicts returns the winner's conflicts of interest with the task's
+// author under the anti-affinity policy, after logging and publishing them
+func (c *Collective) checkConflicts(task *agent.Task, winner string) []Conflict {
+	if task.Author == "" || winner == "" || c.antiAffinity == nil {
+		return nil
+	}
+	conflicts := c.conflicts.between(winner, task.Author, *c.antiAffinity)
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	kinds := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		kinds[i] = string(conflict.Kind)
+	}
+	c.log().Warn("reviewer has a conflict of interest with the author", "task", task.ID, "agent", winner, "author", task.Author, "conflicts", strings.Join(kinds, ","))
+	c.events.Publish(Event{
+		Type:     EventConflictOfInterest,
+		AgentSID: winner,
+		TaskID:   task.ID,
+		Data:     map[string]interface{}{"author": task.Author, "conflicts": conflicts},
+	})
+	return conflicts
+}
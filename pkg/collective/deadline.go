@@ -0,0 +1,150 @@
+package collective
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+var ErrInvalidDeadlinePolicy = errors.New("invalid deadline policy")
+
+// LateAction is what becomes of a task cancelled at its deadline
+type LateAction string
+
+const (
+	LateFail     LateAction = "fail"     // The task fails (default)
+	LateCheaper  LateAction = "cheaper"  // The task is retried with DeadlinePolicy.CheaperModel
+	LateReassign LateAction = "reassign" // The task is reassigned to another agent
+)
+
+// ExcludedLate is the reason an agent that let a task run past its deadline
+// is left out when the task is reassigned
+const ExcludedLate = "late"
+
+// DeadlinePolicy is how the collective treats task deadlines: the order
+// waiting tasks are admitted in, whether an agent's LLM call is cancelled
+// once its task's deadline passes and what becomes of the task then
+type DeadlinePolicy struct {
+	EDF          bool          `json:"edf,omitempty" yaml:"edf,omitempty"`                     // Admit waiting tasks earliest deadline first
+	Enforce      bool          `json:"enforce,omitempty" yaml:"enforce,omitempty"`             // Cancel a task's LLM call at its deadline
+	Late         LateAction    `json:"late,omitempty" yaml:"late,omitempty"`                   // What becomes of a cancelled task (default LateFail)
+	Attempts     int           `json:"attempts,omitempty" yaml:"attempts,omitempty"`           // Late retries or reassignments before the task fails (0 = 1)
+	Extension    time.Duration `json:"extension,omitempty" yaml:"extension,omitempty"`         // Time each late attempt gets (0 = the task's original allowance)
+	CheaperModel string        `json:"cheaper_model,omitempty" yaml:"cheaper_model,omitempty"` // Model LateCheaper retries with (empty = Claude 3 Haiku)
+}
+
+// withDefaults fills unset fields
+func (p DeadlinePolicy) withDefaults() DeadlinePolicy {
+	if p.Late == "" {
+		p.Late = LateFail
+	}
+	if p.Attempts == 0 {
+		p.Attempts = 1
+	}
+	if p.CheaperModel == "" {
+		p.CheaperModel = string(llm.ModelClaude3Haiku)
+	}
+	return p
+}
+
+// Validate reports an unknown late action or a negative limit
+func (p DeadlinePolicy) Validate() error {
+	switch p.Late {
+	case "", LateFail, LateCheaper, LateReassign:
+	default:
+		return fmt.Errorf("%w: unknown late action %q (want %s, %s or %s)", ErrInvalidDeadlinePolicy, p.Late, LateFail, LateCheaper, LateReassign)
+	}
+	if p.Attempts < 0 || p.Extension < 0 {
+		return fmt.Errorf("%w: negative limit", ErrInvalidDeadlinePolicy)
+	}
+	return nil
+}
+
+// deadlinePolicy returns the configured policy with defaults, or nil
+func (c *Collective) deadlinePolicy() *DeadlinePolicy {
+	if c.config.Deadlines == nil {
+		return nil
+	}
+	p := c.config.Deadlines.withDefaults()
+	return &p
+}
+
+// enforcedDeadline returns when a task's attempt is cancelled, or zero if
+// deadlines aren't enforced or the task has none
+func (c *Collective) enforcedDeadline(task *agent.Task) time.Time {
+	if p := c.deadlinePolicy(); p == nil || !p.Enforce {
+		return time.Time{}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return task.Deadline
+}
+
+// retryLate settles an attempt cancelled at its deadline and readies the
+// task for another under the late policy: with the cheaper model, or away
+// from the agent that ran late. Returns false if the task is to fail
+// instead, because the policy says so or its late attempts are used up.
+func (c *Collective) retryLate(task *agent.Task, result *agent.TaskResult, attempt int) bool {
+	p := c.deadlinePolicy()
+	if p == nil || p.Late == LateFail || attempt > p.Attempts {
+		return false
+	}
+	c.retryFailed(task, result, attempt, p.Attempts)
+
+	c.mu.Lock()
+	allowance := p.Extension
+	if allowance == 0 {
+		allowance = task.Deadline.Sub(task.CreatedAt)
+	}
+	task.Deadline = time.Now().Add(allowance)
+	switch p.Late {
+	case LateCheaper:
+		task.Model = p.CheaperModel
+	case LateReassign:
+		if c.lateAgents[task.ID] == nil {
+			c.lateAgents[task.ID] = make(map[string]bool)
+		}
+		c.lateAgents[task.ID][result.AgentSID] = true
+	}
+	due := task.Deadline
+	c.mu.Unlock()
+
+	c.timelines.Record(task.ID, StageWaiting, result.AgentSID, fmt.Sprintf("deadline missed, %s: due %s", p.Late, due.Format(time.RFC3339)))
+	return true
+}
+
+// screenLate leaves the agents that ran late on a task out of its scope,
+// unless no other agent would be left
+func (c *Collective) screenLate(task *agent.Task, scope *assignmentScope) {
+	c.mu.RLock()
+	late := c.lateAgents[task.ID]
+	c.mu.RUnlock()
+	if len(late) == 0 {
+		return
+	}
+
+	allowed := make(map[string]*agent.Agent, len(scope.agents))
+	var excluded []Exclusion
+	for sid, a := range scope.agents {
+		if late[sid] {
+			excluded = append(excluded, Exclusion{AgentSID: sid, Agent: a.Identity.Name, Reason: ExcludedLate})
+			continue
+		}
+		allowed[sid] = a
+	}
+	if len(allowed) == 0 {
+		return
+	}
+	scope.agents = allowed
+	scope.excluded = append(scope.excluded, excluded...)
+}
+
+// forgetLate drops the record of agents that ran late on a task
+func (c *Collective) forgetLate(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.lateAgents, taskID)
+}
@@ -0,0 +1,365 @@
+package collective
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+var ErrUnknownDigestPeriod = errors.New("unknown digest period")
+
+// DigestPeriod is the span of time a digest covers
+type DigestPeriod string
+
+const (
+	DigestDaily  DigestPeriod = "daily"
+	DigestWeekly DigestPeriod = "weekly"
+)
+
+// ParseDigestPeriod parses "daily" or "weekly"
+func ParseDigestPeriod(s string) (DigestPeriod, error) {
+	switch p := DigestPeriod(strings.ToLower(strings.TrimSpace(s))); p {
+	case DigestDaily, DigestWeekly:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w: %q (want daily or weekly)", ErrUnknownDigestPeriod, s)
+}
+
+// Span returns how far back a digest for the period reaches
+func (p DigestPeriod) Span() time.Duration {
+	if p == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Summary length limits, in characters
+const (
+	digestAskedLength   = 160
+	digestOutcomeLength = 240
+)
+
+// TaskSummary is the digest entry of one finished task: what was asked, who
+// did it, the key outcome and its quality
+type TaskSummary struct {
+	TaskID     string           `json:"task_id"`
+	Asked      string           `json:"asked"`
+	AgentSID   string           `json:"agent_sid"`
+	AgentName  string           `json:"agent_name,omitempty"`
+	Team       string           `json:"team,omitempty"`
+	Status     agent.TaskStatus `json:"status"`
+	Outcome    string           `json:"outcome"`
+	Quality    float64          `json:"quality"`
+	TokensUsed int              `json:"tokens_used,omitempty"`
+	FinishedAt time.Time        `json:"finished_at"`
+}
+
+// DigestAgent is one agent's share of a digest
+type DigestAgent struct {
+	SID     string  `json:"sid"`
+	Name    string  `json:"name,omitempty"`
+	Tasks   int     `json:"tasks"`
+	Failed  int     `json:"failed"`
+	Quality float64 `json:"quality"` // Mean over completed tasks
+}
+
+// Digest summarizes the tasks the collective finished in a period, newest
+// first
+type Digest struct {
+	Collective string        `json:"collective"`
+	Period     DigestPeriod  `json:"period"`
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	Tasks      int           `json:"tasks"`
+	Completed  int           `json:"completed"`
+	Failed     int           `json:"failed"`
+	Tokens     int           `json:"tokens"`
+	Quality    float64       `json:"quality"` // Mean over completed tasks
+	Agents     []DigestAgent `json:"agents,omitempty"`
+	Summaries  []TaskSummary `json:"summaries"`
+}
+
+// Text renders the digest as a short message for a chat channel, listing at
+// most max tasks (0 = all)
+func (d *Digest) Text(max int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s %s digest* (%s – %s)\n", d.Collective, d.Period,
+		d.Start.Format("Jan 2 15:04"), d.End.Format("Jan 2 15:04"))
+	if d.Tasks == 0 {
+		b.WriteString("No tasks finished.")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%d tasks: %d completed, %d failed, mean quality %.2f, %d tokens\n",
+		d.Tasks, d.Completed, d.Failed, d.Quality, d.Tokens)
+
+	for i, s := range d.Summaries {
+		if max > 0 && i == max {
+			fmt.Fprintf(&b, "…and %d more\n", len(d.Summaries)-max)
+			break
+		}
+		who := s.AgentName
+		if who == "" && len(s.AgentSID) > 8 {
+			who = s.AgentSID[:8]
+		} else if who == "" {
+			who = s.AgentSID
+		}
+		fmt.Fprintf(&b, "• %s — %s (%s, quality %.2f): %s\n", s.Asked, who, s.Status, s.Quality, s.Outcome)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// DigestSchedule posts the digest of a period whenever a cron expression
+// matches
+type DigestSchedule struct {
+	Period DigestPeriod `json:"period" yaml:"period"`
+	Cron   string       `json:"cron,omitempty" yaml:"cron,omitempty"` // Default: 09:00 daily, or Mondays for weekly
+}
+
+// DigestNotifier receives each scheduled digest, e.g. to post it to chat
+type DigestNotifier func(d *Digest)
+
+// digestRetention is how long task summaries are kept: a weekly digest's
+// span with a day to spare
+const digestRetention = 8 * 24 * time.Hour
+
+// scheduledDigest is a digest schedule and when it next posts
+type scheduledDigest struct {
+	DigestSchedule
+	cron *CronSchedule
+	next time.Time
+}
+
+// digestLog keeps recent task summaries and the digest schedules
+type digestLog struct {
+	mu sync.Mutex
+
+	summaries []TaskSummary // Oldest first
+	schedules []*scheduledDigest
+	notifiers []DigestNotifier
+}
+
+func newDigestLog() *digestLog {
+	return &digestLog{}
+}
+
+// summarize shortens text to its first sentence or line, at most max
+// characters, with whitespace collapsed
+func summarize(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, end := range []string{". ", "! ", "? "} {
+		if i := strings.Index(text, end); i >= 0 {
+			text = text[:i+1]
+		}
+	}
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// firstLine returns the first non-blank line of text, skipping markdown
+// headings and code fences
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#>*- "))
+		if line != "" && !strings.HasPrefix(line, "```") {
+			return line
+		}
+	}
+	return ""
+}
+
+// recordDigest summarizes a finished task for the digests
+func (c *Collective) recordDigest(task *agent.Task, result *agent.TaskResult) {
+	c.mu.RLock()
+	a := c.agents[result.AgentSID]
+	c.mu.RUnlock()
+
+	s := TaskSummary{
+		TaskID:     task.ID,
+		Asked:      summarize(task.Description, digestAskedLength),
+		AgentSID:   result.AgentSID,
+		Team:       task.Team,
+		Status:     result.Status,
+		Quality:    result.Quality,
+		TokensUsed: result.TokensUsed,
+		FinishedAt: result.Timestamp,
+	}
+	if a != nil {
+		s.AgentName = a.Identity.Name
+	}
+	if s.FinishedAt.IsZero() {
+		s.FinishedAt = time.Now()
+	}
+	if result.Status == agent.TaskCompleted {
+		s.Outcome = summarize(firstLine(result.Output), digestOutcomeLength)
+	} else {
+		s.Outcome = summarize("Failed: "+result.Error, digestOutcomeLength)
+	}
+
+	l := c.digests
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.summaries = append(l.summaries, s)
+
+	cutoff := time.Now().Add(-digestRetention)
+	drop := sort.Search(len(l.summaries), func(i int) bool {
+		return l.summaries[i].FinishedAt.After(cutoff)
+	})
+	if drop > 0 {
+		l.summaries = append([]TaskSummary(nil), l.summaries[drop:]...)
+	}
+}
+
+// Digest summarizes the tasks finished in the period ending at end (zero =
+// now)
+func (c *Collective) Digest(period DigestPeriod, end time.Time) *Digest {
+	if end.IsZero() {
+		end = time.Now()
+	}
+	d := &Digest{
+		Collective: c.Name,
+		Period:     period,
+		Start:      end.Add(-period.Span()),
+		End:        end,
+		Summaries:  make([]TaskSummary, 0),
+	}
+
+	l := c.digests
+	l.mu.Lock()
+	for i := len(l.summaries) - 1; i >= 0; i-- {
+		s := l.summaries[i]
+		if s.FinishedAt.After(end) {
+			continue
+		}
+		if !s.FinishedAt.After(d.Start) {
+			break
+		}
+		d.Summaries = append(d.Summaries, s)
+	}
+	l.mu.Unlock()
+
+	agents := make(map[string]*DigestAgent)
+	quality := make(map[string]float64)
+	total := 0.0
+	for _, s := range d.Summaries {
+		d.Tasks++
+		d.Tokens += s.TokensUsed
+		da, ok := agents[s.AgentSID]
+		if !ok {
+			da = &DigestAgent{SID: s.AgentSID, Name: s.AgentName}
+			agents[s.AgentSID] = da
+		}
+		da.Tasks++
+		if s.Status == agent.TaskCompleted {
+			d.Completed++
+			total += s.Quality
+			quality[s.AgentSID] += s.Quality
+		} else {
+			d.Failed++
+			da.Failed++
+		}
+	}
+	if d.Completed > 0 {
+		d.Quality = total / float64(d.Completed)
+	}
+	for sid, da := range agents {
+		if completed := da.Tasks - da.Failed; completed > 0 {
+			da.Quality = quality[sid] / float64(completed)
+		}
+		d.Agents = append(d.Agents, *da)
+	}
+	sort.Slice(d.Agents, func(i, j int) bool {
+		if d.Agents[i].Tasks != d.Agents[j].Tasks {
+			return d.Agents[i].Tasks > d.Agents[j].Tasks
+		}
+		return d.Agents[i].SID < d.Agents[j].SID
+	})
+	return d
+}
+
+// ScheduleDigest posts the period's digest to the notifiers registered with
+// OnDigest whenever the schedule's cron expression matches
+func (c *Collective) ScheduleDigest(schedule DigestSchedule) error {
+	if _, err := ParseDigestPeriod(string(schedule.Period)); err != nil {
+		return err
+	}
+	if schedule.Cron == "" {
+		schedule.Cron = "0 9 * * *"
+		if schedule.Period == DigestWeekly {
+			schedule.Cron = "0 9 * * 1"
+		}
+	}
+	cron, err := ParseCron(schedule.Cron)
+	if err != nil {
+		return err
+	}
+
+	l := c.digests
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.schedules = append(l.schedules, &scheduledDigest{
+		DigestSchedule: schedule,
+		cron:           cron,
+		next:           cron.Next(time.Now()),
+	})
+	return nil
+}
+
+// OnDigest registers a notifier for scheduled digests
+func (c *Collective) OnDigest(n DigestNotifier) {
+	l := c.digests
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.notifiers = append(l.notifiers, n)
+}
+
+// postDigests sends the digests whose schedules are due at now
+func (c *Collective) postDigests(now time.Time) {
+	l := c.digests
+	l.mu.Lock()
+	var due []DigestPeriod
+	for _, s := range l.schedules {
+		if now.Before(s.next) {
+			continue
+		}
+		due = append(due, s.Period)
+		s.next = s.cron.Next(now)
+	}
+	notifiers := append([]DigestNotifier(nil), l.notifiers...)
+	l.mu.Unlock()
+
+	for _, period := range due {
+		d := c.Digest(period, now)
+		for _, n := range notifiers {
+			n(d)
+		}
+	}
+}
+
+// DigestWebhook posts digests to a chat incoming webhook (Slack and
+// compatible services) as a text message listing at most 20 tasks
+func DigestWebhook(webhookURL string) DigestNotifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(d *Digest) {
+		body, _ := json.Marshal(map[string]string{"text": d.Text(20)})
+
+		// Deliver in the background so a slow webhook doesn't delay maintenance
+		go func() {
+			resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(body))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+}
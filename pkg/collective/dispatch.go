@@ -0,0 +1,122 @@
+package collective
+
+import (
+	"context"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// resultBuffer is the capacity of each submission's result channel. Results
+// from agents a task was taken away from are discarded, so it only needs room
+// for the few that can arrive before then.
+const resultBuffer = 4
+
+// Tasks run concurrently, each in its own agent slot: a slot is reserved from
+// assignment until its result is in, and agents with every slot reserved
+// don't take part in further assignments. A task that only a fully reserved
+// agent could run waits for one to be released instead of failing for lack
+// of bids. Concurrency is bounded by the fair queue (MaxConcurrentTasks).
+
+// reserve marks one of an agent's slots as holding a task. An exclusive
+// reservation fails if every slot already holds one.
+func (c *Collective) reserve(sid string, exclusive bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if exclusive && c.fullLocked(sid) {
+		return false
+	}
+	c.reserved[sid]++
+	return true
+}
+
+// release ends an agent's reservation and wakes tasks waiting for an agent
+func (c *Collective) release(sid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n := c.reserved[sid] - 1; n > 0 {
+		c.reserved[sid] = n
+	} else {
+		delete(c.reserved, sid)
+	}
+	close(c.released)
+	c.released = make(chan struct{})
+}
+
+// releaseSignal returns a channel closed the next time an agent is released
+func (c *Collective) releaseSignal() <-chan struct{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.released
+}
+
+// settleAgent runs fn while the agent isn't settling a task of its own, or
+// right away if the agent has left
+func (c *Collective) settleAgent(sid string, fn func()) {
+	c.mu.RLock()
+	a, ok := c.agents[sid]
+	c.mu.RUnlock()
+	if !ok {
+		fn()
+		return
+	}
+	a.Settle(fn)
+}
+
+// fullLocked reports whether an agent holds as many tasks as it has slots.
+// Caller must hold c.mu.
+func (c *Collective) fullLocked(sid string) bool {
+	slots := 1
+	if a, ok := c.agents[sid]; ok {
+		slots = a.Slots()
+	}
+	return c.reserved[sid] >= slots
+}
+
+// unreserved returns the agents in a set with a slot free to take a task
+func (c *Collective) unreserved(agents map[string]*agent.Agent) map[string]*agent.Agent {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	free := make(map[string]*agent.Agent, len(agents))
+	for sid, a := range agents {
+		if !c.fullLocked(sid) {
+			free[sid] = a
+		}
+	}
+	return free
+}
+
+// awaitAgent waits for a busy agent able to run task to be released: one
+// with every slot reserved or running. Returns false at once if no busy
+// agent could run it, or when ctx ends.
+func (c *Collective) awaitAgent(ctx context.Context, task *agent.Task, released <-chan struct{}) bool {
+	scope, err := c.scopeFor(task)
+	if err != nil {
+		return false
+	}
+
+	capable := false
+	threshold := scope.market.BidThreshold().Min
+	c.mu.RLock()
+	for sid, a := range scope.agents {
+		busy := c.fullLocked(sid) || a.FreeSlots() == 0
+		if busy && a.Capabilities.MatchScore(task.Required) >= threshold {
+			capable = true
+			break
+		}
+	}
+	c.mu.RUnlock()
+	if !capable {
+		return false
+	}
+
+	c.timelines.Record(task.ID, StageWaiting, "", "every capable agent is busy")
+	select {
+	case <-released:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
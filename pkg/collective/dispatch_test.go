@@ -0,0 +1,286 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// gatedProvider echoes the prompt once released, tracking how many calls run at once
+type gatedProvider struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+	release chan struct{}
+}
+
+func (p *gatedProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running--
+		p.mu.Unlock()
+	}()
+
+	select {
+	case <-p.release:
+		return &llm.CompletionResponse{Content: req.Prompt}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *gatedProvider) Name() string {
+	return "gated"
+}
+
+func (p *gatedProvider) Peak() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peak
+}
+
+// submitAll submits tasks concurrently and delivers their results by task ID
+// once all are done
+func submitAll(t *testing.T, c *Collective, tasks []*agent.Task) <-chan map[string]*agent.TaskResult {
+	done := make(chan map[string]*agent.TaskResult, 1)
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]*agent.TaskResult)
+	)
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task *agent.Task) {
+			defer wg.Done()
+			result, err := c.SubmitCtx(context.Background(), task)
+			if err != nil {
+				t.Errorf("SubmitCtx failed: %v", err)
+				return
+			}
+			mu.Lock()
+			results[task.ID] = result
+			mu.Unlock()
+		}(task)
+	}
+	go func() {
+		wg.Wait()
+		done <- results
+	}()
+	return done
+}
+
+func TestCollective_ParallelDispatch(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(20 * time.Millisecond)
+
+	provider := &gatedProvider{release: make(chan struct{})}
+	for i := 0; i < 3; i++ {
+		a, _ := agent.NewAgent(agent.AgentConfig{Name: fmt.Sprintf("Worker%d", i), Provider: provider})
+		_ = c.Join(a)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	var tasks []*agent.Task
+	for i := 0; i < 3; i++ {
+		tasks = append(tasks, agent.NewTask(fmt.Sprintf("Task number %d", i), nil))
+	}
+
+	done := submitAll(t, c, tasks)
+	waitFor(t, 2*time.Second, func() bool { return provider.Peak() == 3 })
+	close(provider.release)
+	results := <-done
+
+	if peak := provider.Peak(); peak != 3 {
+		t.Errorf("Expected 3 tasks running at once, got %d", peak)
+	}
+	agents := make(map[string]bool)
+	for _, task := range tasks {
+		result := results[task.ID]
+		if result == nil {
+			continue
+		}
+		if result.TaskID != task.ID || !strings.Contains(result.Output, task.Description) {
+			t.Errorf("Expected the result of %q, got %q", task.Description, result.Output)
+		}
+		agents[result.AgentSID] = true
+	}
+	if len(agents) != 3 {
+		t.Errorf("Expected each task on its own agent, got %d agents", len(agents))
+	}
+}
+
+func TestCollective_WaitsForBusyAgent(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(10 * time.Millisecond)
+
+	provider := &gatedProvider{release: make(chan struct{})}
+	worker, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider})
+	_ = c.Join(worker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	first := agent.NewTask("First task", nil)
+	second := agent.NewTask("Second task", nil)
+	done := submitAll(t, c, []*agent.Task{first, second})
+	waiting := func(task *agent.Task) bool {
+		timeline, _ := c.Timeline(task.ID)
+		for _, entry := range timeline {
+			if entry.Stage == StageWaiting {
+				return true
+			}
+		}
+		return false
+	}
+	waitFor(t, time.Second, func() bool { return waiting(first) || waiting(second) })
+	close(provider.release)
+	results := <-done
+
+	for _, task := range []*agent.Task{first, second} {
+		result := results[task.ID]
+		if result == nil || !strings.Contains(result.Output, task.Description) {
+			t.Errorf("Expected %q to complete with its own result, got %+v", task.Description, result)
+		}
+	}
+	if peak := provider.Peak(); peak != 1 {
+		t.Errorf("Expected the agent to run one task at a time, got %d", peak)
+	}
+}
+
+func TestCollective_Placement(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(20 * time.Millisecond)
+
+	eu, _ := agent.NewAgent(agent.AgentConfig{Name: "EU", Labels: agent.Labels{"region": "eu"}})
+	us, _ := agent.NewAgent(agent.AgentConfig{Name: "US", Labels: agent.Labels{"region": "us"}})
+	_ = c.Join(eu)
+	_ = c.Join(us)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	region, _ := agent.ParseConstraint("region=eu")
+	for i := 0; i < 3; i++ {
+		result, err := c.SubmitCtx(ctx, agent.NewTask("Process EU customer records", nil).WithPlacement(region))
+		if err != nil {
+			t.Fatalf("SubmitCtx failed: %v", err)
+		}
+		if result.AgentSID != eu.Identity.SID {
+			t.Errorf("Expected the task placed on the EU agent, got %s", result.AgentSID)
+		}
+	}
+
+	gpu, _ := agent.ParseConstraint("gpu")
+	_, err := c.SubmitCtx(ctx, agent.NewTask("Train a model", nil).WithPlacement(gpu))
+	if !errors.Is(err, ErrNoPlacement) {
+		t.Errorf("Expected ErrNoPlacement, got %v", err)
+	}
+}
+
+// lateProvider stalls calls until cancelled: the first stall calls, or
+// every call not for the model cheap. The rest are answered.
+type lateProvider struct {
+	mu    sync.Mutex
+	calls int
+	stall int
+	cheap string
+}
+
+func (p *lateProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	stall := p.calls <= p.stall || (p.cheap != "" && req.Model != p.cheap)
+	p.mu.Unlock()
+
+	if !stall {
+		return &llm.CompletionResponse{Content: "done in time"}, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (p *lateProvider) Name() string {
+	return "stalling"
+}
+
+func TestCollective_Deadlines(t *testing.T) {
+	submit := func(t *testing.T, policy DeadlinePolicy, provider llm.Provider, agents int) (*Collective, *agent.Task, *agent.TaskResult) {
+		cfg := DefaultCollectiveConfig()
+		cfg.Deadlines = &policy
+		c := NewCollective("TestCollective", cfg)
+		c.GetMarket().SetBidTimeout(time.Millisecond)
+		for i := 0; i < agents; i++ {
+			a, _ := agent.NewAgent(agent.AgentConfig{Name: fmt.Sprintf("Worker%d", i), Provider: provider})
+			_ = c.Join(a)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		_ = c.Start(ctx)
+		t.Cleanup(c.Stop)
+
+		task := agent.NewTask("Due soon", nil).WithDeadline(time.Now().Add(50 * time.Millisecond))
+		result, _ := c.SubmitCtx(ctx, task)
+		if result == nil {
+			t.Fatal("Expected a result")
+		}
+		return c, task, result
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		_, _, result := submit(t, DeadlinePolicy{Enforce: true}, &lateProvider{stall: 1}, 1)
+		if result.Status != agent.TaskFailed || !result.Expired || result.Error != agent.ErrDeadlineExceeded.Error() {
+			t.Errorf("Expected the task to expire, got %+v", result)
+		}
+	})
+
+	t.Run("cheaper", func(t *testing.T) {
+		policy := DeadlinePolicy{Enforce: true, Late: LateCheaper, CheaperModel: "cheap-model"}
+		_, task, result := submit(t, policy, &lateProvider{cheap: "cheap-model"}, 1)
+		if result.Status != agent.TaskCompleted || task.Model != "cheap-model" {
+			t.Errorf("Expected the cheaper model to complete the task, got %+v with model %q", result, task.Model)
+		}
+	})
+
+	t.Run("reassign", func(t *testing.T) {
+		c, task, result := submit(t, DeadlinePolicy{Enforce: true, Late: LateReassign}, &lateProvider{stall: 1}, 2)
+		if result.Status != agent.TaskCompleted {
+			t.Fatalf("Expected the reassigned task to complete, got %+v", result)
+		}
+		timeline, _ := c.Timeline(task.ID)
+		var assigned []string
+		for _, entry := range timeline {
+			if entry.Stage == StageAssigned {
+				assigned = append(assigned, entry.Actor)
+			}
+		}
+		if len(assigned) != 2 || assigned[0] == assigned[1] || assigned[1] != result.AgentSID {
+			t.Errorf("Expected the task reassigned to another agent, got assignments %v", assigned)
+		}
+	})
+
+	if err := (DeadlinePolicy{Late: "panic"}).Validate(); !errors.Is(err, ErrInvalidDeadlinePolicy) {
+		t.Errorf("Expected ErrInvalidDeadlinePolicy, got %v", err)
+	}
+}
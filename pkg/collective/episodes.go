@@ -0,0 +1,211 @@
+package collective
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+// episodePrefix is where a BlobEpisodeStore keeps episodes in its blobs
+const episodePrefix = "episodes/"
+
+// EpisodeStore keeps episodic memory apart from the rest of a MemoryStore,
+// so it can grow beyond what one database holds and be shared by several
+// collective instances
+type EpisodeStore interface {
+	SaveEpisode(ep CollectiveEpisode) error
+	QueryEpisodes(query string) ([]CollectiveEpisode, error) // Case-insensitive content match, oldest first
+	CountEpisodes() (int, error)
+	RecentEpisodes(limit int) ([]CollectiveEpisode, error) // The most recent, oldest first
+}
+
+// EpisodeStoreConfig keeps episodes in an S3-compatible bucket. Collectives
+// configured with the same bucket and prefix share episodic memory.
+type EpisodeStoreConfig struct {
+	S3       storage.S3Config `json:"s3" yaml:"s3"`
+	CacheDir string           `json:"cache_dir,omitempty" yaml:"cache_dir"` // Local copies of fetched episodes (empty = none)
+}
+
+// OpenEpisodeStore opens the bucket cfg names as an episode store
+func OpenEpisodeStore(cfg EpisodeStoreConfig) (*BlobEpisodeStore, error) {
+	blobs, err := storage.NewS3Blobs(cfg.S3)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlobEpisodeStore(blobs, cfg.CacheDir), nil
+}
+
+// BlobEpisodeStore is an EpisodeStore in a blob store, one object per
+// episode keyed by time, so listing returns episodes in order. Episodes
+// don't change once saved: each fetched is copied to the cache directory
+// and read from there afterwards.
+type BlobEpisodeStore struct {
+	mu sync.Mutex
+
+	blobs    storage.Blobs
+	cacheDir string
+}
+
+// NewBlobEpisodeStore keeps episodes in blobs, caching them in cacheDir if
+// set
+func NewBlobEpisodeStore(blobs storage.Blobs, cacheDir string) *BlobEpisodeStore {
+	return &BlobEpisodeStore{blobs: blobs, cacheDir: cacheDir}
+}
+
+// episodeKey returns the key of an episode: its timestamp in nanoseconds,
+// zero-padded to sort, and its ID
+func episodeKey(ep CollectiveEpisode) string {
+	return fmt.Sprintf("%s%020d-%s.json", episodePrefix, ep.Timestamp.UnixNano(), url.QueryEscape(ep.ID))
+}
+
+// SaveEpisode stores an episode
+func (s *BlobEpisodeStore) SaveEpisode(ep CollectiveEpisode) error {
+	data, err := json.Marshal(ep)
+	if err != nil {
+		return err
+	}
+	key := episodeKey(ep)
+	if err := s.blobs.Put(context.Background(), key, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	s.cache(key, data)
+	return nil
+}
+
+// QueryEpisodes reads every stored episode and returns those whose content
+// contains query
+func (s *BlobEpisodeStore) QueryEpisodes(query string) ([]CollectiveEpisode, error) {
+	keys, err := s.blobs.List(context.Background(), episodePrefix)
+	if err != nil {
+		return nil, err
+	}
+	episodes, err := s.load(keys)
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(query)
+	matches := make([]CollectiveEpisode, 0)
+	for _, ep := range episodes {
+		if strings.Contains(strings.ToLower(ep.Content), query) {
+			matches = append(matches, ep)
+		}
+	}
+	return matches, nil
+}
+
+// CountEpisodes returns the number of stored episodes
+func (s *BlobEpisodeStore) CountEpisodes() (int, error) {
+	keys, err := s.blobs.List(context.Background(), episodePrefix)
+	return len(keys), err
+}
+
+// RecentEpisodes returns the limit most recent episodes, oldest first
+func (s *BlobEpisodeStore) RecentEpisodes(limit int) ([]CollectiveEpisode, error) {
+	keys, err := s.blobs.List(context.Background(), episodePrefix)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > limit {
+		keys = keys[len(keys)-limit:]
+	}
+	return s.load(keys)
+}
+
+// load reads the episodes at keys, from the cache where it has them
+func (s *BlobEpisodeStore) load(keys []string) ([]CollectiveEpisode, error) {
+	episodes := make([]CollectiveEpisode, 0, len(keys))
+	for _, key := range keys {
+		data, ok := s.cached(key)
+		if !ok {
+			var err error
+			if data, err = storage.ReadBlob(context.Background(), s.blobs, key); err != nil {
+				if errors.Is(err, storage.ErrNotFound) {
+					continue // Deleted since it was listed
+				}
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			s.cache(key, data)
+		}
+		var ep CollectiveEpisode
+		if err := json.Unmarshal(data, &ep); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		episodes = append(episodes, ep)
+	}
+	return episodes, nil
+}
+
+// cachePath returns where the cache keeps the episode at key
+func (s *BlobEpisodeStore) cachePath(key string) string {
+	return filepath.Join(s.cacheDir, filepath.FromSlash(key))
+}
+
+// cached returns the cached copy of the episode at key
+func (s *BlobEpisodeStore) cached(key string) ([]byte, bool) {
+	if s.cacheDir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(s.cachePath(key))
+	return data, err == nil
+}
+
+// cache copies an episode to the cache directory. The cache only saves
+// fetches, so failures are ignored.
+func (s *BlobEpisodeStore) cache(key string, data []byte) {
+	if s.cacheDir == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := s.cachePath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, path)
+}
+
+// WithEpisodes returns store with its episodes kept in episodes instead
+func WithEpisodes(store MemoryStore, episodes EpisodeStore) MemoryStore {
+	return &episodeMemoryStore{MemoryStore: store, episodes: episodes}
+}
+
+type episodeMemoryStore struct {
+	MemoryStore
+	episodes EpisodeStore
+}
+
+func (s *episodeMemoryStore) SaveEpisode(ep CollectiveEpisode) error {
+	return s.episodes.SaveEpisode(ep)
+}
+
+func (s *episodeMemoryStore) QueryEpisodes(query string) ([]CollectiveEpisode, error) {
+	return s.episodes.QueryEpisodes(query)
+}
+
+func (s *episodeMemoryStore) CountEpisodes() (int, error) {
+	return s.episodes.CountEpisodes()
+}
+
+func (s *episodeMemoryStore) Load(limit int) (*MemorySnapshot, error) {
+	snapshot, err := s.MemoryStore.Load(0)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Episodes, err = s.episodes.RecentEpisodes(limit); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,83 @@
+package collective
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+func TestBlobEpisodeStore_Shared(t *testing.T) {
+	blobs := storage.NewMemory().Blobs()
+
+	// Two instances keep their concepts apart and share episodes
+	first, err := NewPersistentMemory(WithEpisodes(NewDocumentMemoryStore(storage.NewMemory().Documents()), NewBlobEpisodeStore(blobs, "")))
+	if err != nil {
+		t.Fatalf("NewPersistentMemory failed: %v", err)
+	}
+	second, err := NewPersistentMemory(WithEpisodes(NewDocumentMemoryStore(storage.NewMemory().Documents()), NewBlobEpisodeStore(blobs, "")))
+	if err != nil {
+		t.Fatalf("NewPersistentMemory failed: %v", err)
+	}
+
+	first.Contribute("agent-1", "Cache invalidation is hard", nil)
+	second.Contribute("agent-2", "Cache warming helps", nil)
+	first.AddConcept("goroutine", "lightweight thread", "agent-1")
+
+	results := first.Query("cache")
+	if len(results) != 2 || results[0].Participants[0] != "agent-1" || results[1].Participants[0] != "agent-2" {
+		t.Errorf("Expected both instances' episodes oldest first, got %+v", results)
+	}
+	if stats := second.Stats(); stats.EpisodeCount != 2 || stats.ConceptCount != 0 {
+		t.Errorf("Expected 2 shared episodes and no concepts, got %+v", stats)
+	}
+
+	// A new instance restores the most recent episodes from the bucket
+	restored, err := NewPersistentMemory(WithEpisodes(NewDocumentMemoryStore(storage.NewMemory().Documents()), NewBlobEpisodeStore(blobs, "")))
+	if err != nil {
+		t.Fatalf("NewPersistentMemory failed: %v", err)
+	}
+	if len(restored.episodes) != 2 {
+		t.Errorf("Expected 2 restored episodes, got %d", len(restored.episodes))
+	}
+}
+
+func TestBlobEpisodeStore_Cache(t *testing.T) {
+	blobs := storage.NewMemory().Blobs()
+	dir := t.TempDir()
+	writer := NewBlobEpisodeStore(blobs, "")
+	base := time.Now()
+	for i, content := range []string{"one", "two", "three"} {
+		ep := CollectiveEpisode{ID: content, Content: content, Timestamp: base.Add(time.Duration(i) * time.Second)}
+		if err := writer.SaveEpisode(ep); err != nil {
+			t.Fatalf("SaveEpisode failed: %v", err)
+		}
+	}
+
+	reader := NewBlobEpisodeStore(blobs, dir)
+	recent, err := reader.RecentEpisodes(2)
+	if err != nil {
+		t.Fatalf("RecentEpisodes failed: %v", err)
+	}
+	if len(recent) != 2 || recent[0].Content != "two" || recent[1].Content != "three" {
+		t.Errorf("Expected two then three, got %+v", recent)
+	}
+
+	// Fetched episodes are read from the cache afterwards
+	keys, _ := blobs.List(context.Background(), episodePrefix)
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(keys[2]))); err != nil {
+		t.Errorf("Expected a cached copy of the newest episode, got %v", err)
+	}
+	_ = blobs.Put(context.Background(), keys[2], strings.NewReader("not an episode"))
+	if recent, err := reader.RecentEpisodes(1); err != nil || len(recent) != 1 || recent[0].Content != "three" {
+		t.Errorf("Expected the cached episode, got %+v (%v)", recent, err)
+	}
+
+	if n, _ := reader.CountEpisodes(); n != 3 {
+		t.Errorf("Expected 3 episodes, got %d", n)
+	}
+}
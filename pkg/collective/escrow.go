@@ -0,0 +1,114 @@
+package collective
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+)
+
+// DefaultStakeReward is the share of a bid's stake added to the agent's
+// reputation when it completes the task on time
+const DefaultStakeReward = 0.1
+
+// escrowedStake is reputation an agent staked on a task it was assigned
+type escrowedStake struct {
+	agentSID string
+	amount   float64
+}
+
+// stakeReward returns the configured reward rate
+func (c *Collective) stakeReward() float64 {
+	switch reward := c.config.StakeReward; {
+	case reward < 0:
+		return 0
+	case reward == 0:
+		return DefaultStakeReward
+	default:
+		return reward
+	}
+}
+
+// escrowStakeLocked locks the stake an assignment commits the agent to until
+// its task is settled. Caller must hold c.mu.
+func (c *Collective) escrowStakeLocked(taskID string, assignment *coordination.TaskAssignment) {
+	if assignment.Stake <= 0 {
+		return
+	}
+	if err := c.reputation.LockStake(assignment.AgentSID, assignment.Stake, "Staked on task "+taskID); err != nil {
+		return
+	}
+	c.escrow[taskID] = &escrowedStake{agentSID: assignment.AgentSID, amount: assignment.Stake}
+}
+
+// takeEscrow removes and returns the stake held on a task, if any
+func (c *Collective) takeEscrow(taskID string) *escrowedStake {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.takeEscrowLocked(taskID)
+}
+
+// takeEscrowLocked removes and returns the stake held on a task. Caller must
+// hold c.mu.
+func (c *Collective) takeEscrowLocked(taskID string) *escrowedStake {
+	stake, ok := c.escrow[taskID]
+	if !ok {
+		return nil
+	}
+	delete(c.escrow, taskID)
+	return stake
+}
+
+// settleStake settles the stake held on a task its agent finished: returned
+// with a reward if the task completed by its deadline, forfeited if it failed
+// or came in late
+func (c *Collective) settleStake(task *agent.Task, result *agent.TaskResult) {
+	stake := c.takeEscrow(task.ID)
+	if stake == nil {
+		return
+	}
+
+	late := !task.Deadline.IsZero() && time.Now().After(task.Deadline)
+	switch {
+	case result.Status != agent.TaskCompleted:
+		c.slash(task.ID, stake, "failed")
+	case late:
+		c.slash(task.ID, stake, "missed its deadline")
+	default:
+		reward := stake.amount * c.stakeReward()
+		_ = c.reputation.ReturnStake(stake.agentSID, stake.amount, reward, fmt.Sprintf("Task %s completed; reward %.2f", task.ID, reward))
+		c.log().Debug("stake returned", "task", task.ID, "agent", stake.agentSID, "stake", stake.amount, "reward", reward)
+	}
+}
+
+// slashStake forfeits the stake held on a task its agent failed to deliver
+func (c *Collective) slashStake(taskID, why string) {
+	if stake := c.takeEscrow(taskID); stake != nil {
+		c.slash(taskID, stake, why)
+	}
+}
+
+// slash forfeits a stake taken from escrow
+func (c *Collective) slash(taskID string, stake *escrowedStake, why string) {
+	_ = c.reputation.ForfeitStake(stake.agentSID, stake.amount, "Task "+taskID+" "+why)
+	c.log().Warn("stake slashed", "task", taskID, "agent", stake.agentSID, "stake", stake.amount, "reason", why)
+}
+
+// refundStakeLocked returns the stake held on a task without a reward, when
+// the task ended through no fault of the agent. Caller must hold c.mu.
+func (c *Collective) refundStakeLocked(taskID, why string) {
+	if stake := c.takeEscrowLocked(taskID); stake != nil {
+		_ = c.reputation.ReleaseStake(stake.agentSID, stake.amount, "Task "+taskID+" "+why)
+	}
+}
+
+// refundAgentStakesLocked returns every stake an agent that is leaving holds,
+// before its reputation is archived. Caller must hold c.mu.
+func (c *Collective) refundAgentStakesLocked(sid string) {
+	for taskID, stake := range c.escrow {
+		if stake.agentSID == sid {
+			c.refundStakeLocked(taskID, "released when its agent left")
+		}
+	}
+}
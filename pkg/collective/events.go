@@ -0,0 +1,132 @@
+package collective
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType represents types of collective activity events
+type EventType string
+
+const (
+	EventAgentJoined        EventType = "agent_joined"
+	EventAgentLeft          EventType = "agent_left"
+	EventAgentUnresponsive  EventType = "agent_unresponsive"
+	EventAgentPaused        EventType = "agent_paused"  // An operator paused the agent
+	EventAgentResumed       EventType = "agent_resumed" // An operator resumed the paused agent
+	EventBidPlaced          EventType = "bid_placed"
+	EventTaskAssigned       EventType = "task_assigned"
+	EventTaskRequeued       EventType = "task_requeued"
+	EventTaskRetried        EventType = "task_retried"   // A failed attempt is reassigned under its QoS class's retry budget
+	EventTaskPreempted      EventType = "task_preempted" // A running task was stopped for a more urgent one
+	EventTaskExpired        EventType = "task_expired"   // A task ran past its deadline and was cancelled
+	EventTaskCompleted      EventType = "task_completed"
+	EventTaskFailed         EventType = "task_failed"
+	EventReputationChanged  EventType = "reputation_changed"
+	EventCapabilityProven   EventType = "capability_proven"    // A benchmark of an agent's capability was run and its proof attached
+	EventCapabilityGaps     EventType = "capability_gaps"      // The set of capability gaps changed; Data holds the gaps and suggested agents
+	EventModeChanged        EventType = "mode_changed"         // The collective was paused, put in maintenance, drained or resumed
+	EventParametersChanged  EventType = "parameters_changed"   // A parameter change passed consensus and was applied
+	EventConflictOfInterest EventType = "conflict_of_interest" // A task was assigned to an agent with a conflict of interest with its author
+	EventPromoted           EventType = "promoted"             // A standby passed consensus and took over from its primary
+	EventFenced             EventType = "fenced"               // A promoted standby superseded this collective, which stopped dispatching
+)
+
+// Event is a single observable piece of collective activity
+type Event struct {
+	Type      EventType              `json:"type"`
+	AgentSID  string                 `json:"agent_sid,omitempty"`
+	TaskID    string                 `json:"task_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// EventBus fans collective events out to subscribers
+type EventBus struct {
+	mu sync.RWMutex
+
+	subscribers map[int]chan Event
+	nextID      int
+	bufferSize  int
+	dropped     int
+}
+
+// NewEventBus creates an event bus whose subscriber channels hold bufferSize events
+func NewEventBus(bufferSize int) *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]chan Event),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe returns a channel of future events and a function that ends the subscription
+func (b *EventBus) Subscribe() (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	ch := make(chan Event, b.bufferSize)
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if sub, ok := b.subscribers[id]; ok {
+				delete(b.subscribers, id)
+				close(sub)
+			}
+		})
+	}
+
+	return ch, cancel
+}
+
+// Publish delivers an event to every subscriber. Slow subscribers whose
+// buffers are full miss the event rather than stalling the collective.
+func (b *EventBus) Publish(e Event) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+			b.dropped++
+		}
+	}
+}
+
+// Close ends all subscriptions
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, ch := range b.subscribers {
+		delete(b.subscribers, id)
+		close(ch)
+	}
+}
+
+// EventBusStats reports event bus activity
+type EventBusStats struct {
+	Subscribers int
+	Dropped     int
+}
+
+// Stats returns current event bus statistics
+func (b *EventBus) Stats() EventBusStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return EventBusStats{
+		Subscribers: len(b.subscribers),
+		Dropped:     b.dropped,
+	}
+}
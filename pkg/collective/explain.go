@@ -0,0 +1,243 @@
+package collective
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// BidOutcome is what became of a bid in an auction
+type BidOutcome string
+
+const (
+	OutcomeWon       BidOutcome = "won"
+	OutcomeOutranked BidOutcome = "outranked"
+	OutcomeBusy      BidOutcome = "busy"     // Took another task during bidding
+	OutcomeRejected  BidOutcome = "rejected" // Voted down in consensus mode
+)
+
+// Reasons an agent in scope didn't bid, beyond the market's own
+// (coordination.IneligibleNotIdle, IneligibleCapability and IneligibleBanned)
+const (
+	ExcludedPlacement = "placement" // Labels don't satisfy the task's placement constraints
+	ExcludedBusy      = "busy"      // Holding another task
+	ExcludedConflict  = "conflict"  // Has a conflict of interest with the task's author
+)
+
+// BidExplanation is one scored bid of an auction and what became of it
+type BidExplanation struct {
+	coordination.BidScore
+	Rank    int        `json:"rank"`
+	Agent   string     `json:"agent"`
+	Outcome BidOutcome `json:"outcome"`
+}
+
+// Exclusion is an agent in scope that didn't bid, and why
+type Exclusion struct {
+	AgentSID string                   `json:"agent_sid"`
+	Agent    string                   `json:"agent"`
+	Reason   string                   `json:"reason"`
+	Match    *identity.MatchBreakdown `json:"match,omitempty"` // Set when the match score was too low
+}
+
+// TieBreakDecision records a winner that only outranked the next bid on a
+// tie-break rule
+type TieBreakDecision struct {
+	RunnerUp string  `json:"runner_up"`
+	Score    float64 `json:"score"`
+	Rule     string  `json:"rule"` // The market's tie-breaker, or coordination.TieBreakCapability or TieBreakAgentSID
+}
+
+// AssignmentExplanation is how a task's latest assignment was decided: every
+// bid with its per-factor scores, the agents filtered out before bidding and
+// any tie-break that picked the winner
+type AssignmentExplanation struct {
+	TaskID    string                    `json:"task_id"`
+	Mode      AssignmentMode            `json:"mode"`
+	Scope     string                    `json:"scope"`
+	Required  []identity.CapabilityType `json:"required"`
+	Auction   string                    `json:"auction,omitempty"` // Strategy the bids were ranked by
+	Winner    string                    `json:"winner,omitempty"`
+	Stake     float64                   `json:"stake,omitempty"`   // Reputation the winner committed, as priced by the auction
+	Pinned    bool                      `json:"pinned,omitempty"`  // Assigned to a pinned agent without an auction
+	Rotated   bool                      `json:"rotated,omitempty"` // Review rotation put another bidder ahead of the best bid
+	Threshold float64                   `json:"threshold"`         // Capability score bids needed, after any relaxation
+	Relaxed   []coordination.Relaxation `json:"relaxed,omitempty"` // How the threshold was lowered after rounds without bids
+	Bids      []BidExplanation          `json:"bids"`
+	Excluded  []Exclusion               `json:"excluded"`
+	TieBreak  *TieBreakDecision         `json:"tie_break,omitempty"`
+	Conflicts []Conflict                `json:"conflicts,omitempty"` // The winner's conflicts of interest with the task's author
+	Error     string                    `json:"error,omitempty"`     // Why no agent was assigned
+	Timestamp time.Time                 `json:"timestamp"`
+}
+
+// auction collects what happens during one assignment attempt
+type auction struct {
+	task     *agent.Task
+	scope    assignmentScope
+	excluded []Exclusion
+	outcomes map[string]BidOutcome
+	rotated  bool // Review rotation passed over the best bid
+}
+
+// newAuction starts recording an assignment attempt within scope. Agents
+// in scope but not in free are holding other tasks.
+func newAuction(task *agent.Task, scope assignmentScope, free map[string]*agent.Agent) *auction {
+	au := &auction{
+		task:     task,
+		scope:    scope,
+		excluded: append([]Exclusion(nil), scope.excluded...),
+		outcomes: make(map[string]BidOutcome),
+	}
+	for sid, a := range scope.agents {
+		if _, ok := free[sid]; !ok {
+			au.excluded = append(au.excluded, Exclusion{AgentSID: sid, Agent: a.Identity.Name, Reason: ExcludedBusy})
+		}
+	}
+	au.scope.agents = free
+	return au
+}
+
+// outcome records what became of an agent's bid
+func (au *auction) outcome(sid string, o BidOutcome) {
+	au.outcomes[sid] = o
+}
+
+// explain builds the explanation from the market's bids once the auction
+// is decided. winner is empty and err set if no agent was assigned.
+func (au *auction) explain(reputation *coordination.ReputationRegistry, winner string, err error) *AssignmentExplanation {
+	exp := &AssignmentExplanation{
+		TaskID:    au.task.ID,
+		Mode:      au.scope.mode,
+		Scope:     au.scope.name,
+		Required:  au.task.Required,
+		Winner:    winner,
+		Rotated:   au.rotated,
+		Bids:      []BidExplanation{},
+		Excluded:  au.excluded,
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		exp.Error = err.Error()
+	}
+
+	strategy := au.scope.market.AuctionFor(au.task.ID)
+	exp.Auction = strategy.Name()
+	exp.Threshold = au.scope.market.Threshold(au.task.ID)
+	exp.Relaxed = au.scope.market.Relaxations(au.task.ID)
+	scores, _ := au.scope.market.ScoreBids(au.task.ID, reputation)
+	ties, tc := au.scope.market.TieBreaker(), au.scope.market.TieContext(au.task.ID)
+	bidders := make(map[string]bool, len(scores))
+	for i, s := range scores {
+		sid := s.Bid.AgentSID
+		bidders[sid] = true
+
+		outcome, ok := au.outcomes[sid]
+		if sid == winner {
+			outcome = OutcomeWon
+		} else if !ok {
+			outcome = OutcomeOutranked
+		}
+		b := BidExplanation{BidScore: s, Rank: i + 1, Outcome: outcome}
+		if a, ok := au.scope.agents[sid]; ok {
+			b.Agent = a.Identity.Name
+		}
+		exp.Bids = append(exp.Bids, b)
+
+		if sid == winner {
+			exp.Stake = strategy.Price(scores[i:])
+		}
+		if sid == winner && i+1 < len(scores) && coordination.Tied(strategy, &scores[i], &scores[i+1]) {
+			if rule := coordination.TieBreakBy(ties, tc, &scores[i], &scores[i+1]); rule != "" {
+				exp.TieBreak = &TieBreakDecision{RunnerUp: scores[i+1].Bid.AgentSID, Score: s.Score, Rule: rule}
+			}
+		}
+	}
+
+	for sid, a := range au.scope.agents {
+		if bidders[sid] {
+			continue
+		}
+		match, reason := au.scope.market.Eligibility(a, au.task)
+		if reason == "" {
+			continue // Became eligible after bidding closed
+		}
+		ex := Exclusion{AgentSID: sid, Agent: a.Identity.Name, Reason: reason}
+		if reason == coordination.IneligibleCapability {
+			ex.Match = &match
+		}
+		exp.Excluded = append(exp.Excluded, ex)
+	}
+	sort.Slice(exp.Excluded, func(i, j int) bool { return exp.Excluded[i].AgentSID < exp.Excluded[j].AgentSID })
+	if exp.Excluded == nil {
+		exp.Excluded = []Exclusion{}
+	}
+	return exp
+}
+
+// explanationStore keeps the latest assignment explanation for the most
+// recent tasks
+type explanationStore struct {
+	mu sync.RWMutex
+
+	explanations map[string]*AssignmentExplanation
+	order        []string // TaskIDs, oldest first
+	limit        int
+}
+
+// newExplanationStore creates a store holding explanations for up to limit tasks
+func newExplanationStore(limit int) *explanationStore {
+	return &explanationStore{
+		explanations: make(map[string]*AssignmentExplanation),
+		limit:        limit,
+	}
+}
+
+// Record stores a task's explanation, replacing any earlier one
+func (s *explanationStore) Record(exp *AssignmentExplanation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.explanations[exp.TaskID]; !exists {
+		s.order = append(s.order, exp.TaskID)
+		if s.limit > 0 && len(s.order) > s.limit {
+			delete(s.explanations, s.order[0])
+			s.order = s.order[1:]
+		}
+	}
+	s.explanations[exp.TaskID] = exp
+}
+
+// Get returns a task's explanation
+func (s *explanationStore) Get(taskID string) (*AssignmentExplanation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exp, ok := s.explanations[taskID]
+	return exp, ok
+}
+
+// Since returns the explanations recorded at or after t, oldest first
+func (s *explanationStore) Since(t time.Time) []*AssignmentExplanation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var recent []*AssignmentExplanation
+	for _, id := range s.order {
+		if exp := s.explanations[id]; !exp.Timestamp.Before(t) {
+			recent = append(recent, exp)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].Timestamp.Before(recent[j].Timestamp) })
+	return recent
+}
+
+// ExplainAssignment returns how a task's latest assignment was decided. The
+// explanation is shared and must not be modified.
+func (c *Collective) ExplainAssignment(taskID string) (*AssignmentExplanation, bool) {
+	return c.explanations.Get(taskID)
+}
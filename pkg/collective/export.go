@@ -0,0 +1,344 @@
+package collective
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var (
+	ErrInvalidSnapshot   = errors.New("invalid snapshot")
+	ErrUntrustedSnapshot = errors.New("snapshot signed by an untrusted key")
+)
+
+// SnapshotVersion is the version of the snapshot format written by Export
+const SnapshotVersion = 1
+
+// Snapshot is a portable copy of a collective: its agents with their
+// capabilities and reputations, its memory and its pending tasks
+type Snapshot struct {
+	Version    int           `json:"version"`
+	Collective string        `json:"collective"`
+	ExportedAt time.Time     `json:"exported_at"`
+	Agents     []AgentRecord `json:"agents"`
+	Memory     MemoryRecords `json:"memory"`
+	Pending    []*agent.Task `json:"pending,omitempty"`
+}
+
+// AgentRecord is an agent in a snapshot. Private keys are never exported:
+// an imported agent keeps its SID and lineage under a fresh key pair.
+type AgentRecord struct {
+	Identity     *identity.SquaremindIdentity        `json:"identity"`
+	Capabilities map[identity.CapabilityType]float64 `json:"capabilities"`
+	Reputation   *agent.Reputation                   `json:"reputation"`
+	Model        string                              `json:"model,omitempty"`
+	Models       agent.ModelMap                      `json:"models,omitempty"`
+	SystemPrompt string                              `json:"system_prompt,omitempty"`
+	Temperature  float64                             `json:"temperature,omitempty"`
+	MaxTokens    int                                 `json:"max_tokens,omitempty"`
+	Slots        int                                 `json:"slots,omitempty"`
+	Labels       agent.Labels                        `json:"labels,omitempty"`
+	CostTags     agent.Labels                        `json:"cost_tags,omitempty"`
+}
+
+// MemoryRecords is collective memory in a snapshot
+type MemoryRecords struct {
+	Episodes []CollectiveEpisode `json:"episodes,omitempty"`
+	Concepts []*Concept          `json:"concepts,omitempty"`
+	Contexts []*SharedContext    `json:"contexts,omitempty"`
+	Nodes    []*KnowledgeNode    `json:"nodes,omitempty"`
+	Edges    []*KnowledgeEdge    `json:"edges,omitempty"`
+}
+
+// signedSnapshot is the encoding of a signed snapshot. The signature covers
+// the compacted snapshot, so reformatting the file doesn't invalidate it.
+type signedSnapshot struct {
+	Snapshot  json.RawMessage   `json:"snapshot"`
+	Signer    ed25519.PublicKey `json:"signer"`
+	Signature []byte            `json:"signature"`
+}
+
+// Export returns a snapshot of the collective, agents ordered by name
+func (c *Collective) Export() *Snapshot {
+	s := &Snapshot{
+		Version:    SnapshotVersion,
+		Collective: c.Name,
+		ExportedAt: time.Now(),
+		Memory:     c.memory.records(),
+	}
+
+	agents := c.GetAgents()
+	sort.Slice(agents, func(i, j int) bool {
+		if agents[i].Identity.Name != agents[j].Identity.Name {
+			return agents[i].Identity.Name < agents[j].Identity.Name
+		}
+		return agents[i].Identity.SID < agents[j].Identity.SID
+	})
+	for _, a := range agents {
+		reputation := c.reputation.Get(a.Identity.SID)
+		if reputation == nil {
+			reputation = a.Reputation
+		}
+		rep := *reputation
+		s.Agents = append(s.Agents, AgentRecord{
+			Identity:     a.Identity,
+			Capabilities: a.Capabilities.Proficiencies(),
+			Reputation:   &rep,
+			Model:        a.Model,
+			Models:       a.Models,
+			SystemPrompt: a.SystemPrompt,
+			Temperature:  a.Temperature,
+			MaxTokens:    a.MaxTokens,
+			Slots:        a.Slots(),
+			Labels:       a.Labels,
+			CostTags:     a.CostTags,
+		})
+	}
+
+	for _, task := range c.TaskSnapshot(0).Pending {
+		task := task
+		s.Pending = append(s.Pending, &task)
+	}
+	return s
+}
+
+// Sign encodes the snapshot signed with key
+func (s *Snapshot) Sign(key ed25519.PrivateKey) ([]byte, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(signedSnapshot{
+		Snapshot:  payload,
+		Signer:    key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, payload),
+	}, "", "  ")
+}
+
+// OpenSnapshot decodes a signed snapshot and verifies its signature. With
+// trusted keys, the signer must be one of them. It returns the snapshot and
+// the key it was signed with.
+func OpenSnapshot(data []byte, trusted ...ed25519.PublicKey) (*Snapshot, ed25519.PublicKey, error) {
+	var signed signedSnapshot
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if len(signed.Signer) != ed25519.PublicKeySize || len(signed.Snapshot) == 0 {
+		return nil, nil, fmt.Errorf("%w: not signed", ErrInvalidSnapshot)
+	}
+
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, signed.Snapshot); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if !ed25519.Verify(signed.Signer, payload.Bytes(), signed.Signature) {
+		return nil, nil, fmt.Errorf("%w: bad signature", ErrInvalidSnapshot)
+	}
+	if len(trusted) > 0 {
+		ok := false
+		for _, key := range trusted {
+			if key.Equal(signed.Signer) {
+				ok = true
+			}
+		}
+		if !ok {
+			return nil, nil, ErrUntrustedSnapshot
+		}
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(payload.Bytes(), &s); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, nil, err
+	}
+	return &s, signed.Signer, nil
+}
+
+// validate checks the snapshot can be imported
+func (s *Snapshot) validate() error {
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, s.Version)
+	}
+	for _, rec := range s.Agents {
+		if rec.Identity == nil || rec.Identity.SID == "" {
+			return fmt.Errorf("%w: agent without identity", ErrInvalidSnapshot)
+		}
+	}
+	return nil
+}
+
+// ImportOptions controls how a snapshot is imported
+type ImportOptions struct {
+	// NewAgent creates the agent a record describes, such as with the
+	// importing host's provider (nil = agent.NewAgent without one)
+	NewAgent func(cfg agent.AgentConfig) (*agent.Agent, error)
+
+	// SkipPending leaves the snapshot's pending tasks out
+	SkipPending bool
+}
+
+// ImportResult is what an import added
+type ImportResult struct {
+	Agents   int      `json:"agents"`
+	Skipped  []string `json:"skipped,omitempty"` // SIDs of agents already in the collective
+	Episodes int      `json:"episodes"`
+	Pending  int      `json:"pending"`
+}
+
+// Import adds a snapshot's agents, memory and pending tasks to the
+// collective. Agents keep their SIDs, names, lineage, proficiencies and
+// reputations; agents already in the collective are skipped. Pending tasks
+// are submitted again without waiting for them.
+func (c *Collective) Import(s *Snapshot, opts ImportOptions) (*ImportResult, error) {
+	newAgent := opts.NewAgent
+	if newAgent == nil {
+		newAgent = agent.NewAgent
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for _, rec := range s.Agents {
+		if _, ok := c.GetAgent(rec.Identity.SID); ok {
+			result.Skipped = append(result.Skipped, rec.Identity.SID)
+			continue
+		}
+		if _, err := c.importAgent(rec, newAgent); err != nil {
+			return result, err
+		}
+		result.Agents++
+	}
+
+	result.Episodes = c.memory.restore(s.Memory)
+
+	if !opts.SkipPending {
+		for _, task := range s.Pending {
+			if task == nil {
+				continue
+			}
+			if _, err := c.SubmitAsync(task); err != nil {
+				return result, err
+			}
+			result.Pending++
+		}
+	}
+	c.logger.Info("snapshot imported", "from", s.Collective, "agents", result.Agents, "skipped", len(result.Skipped), "episodes", result.Episodes, "pending", result.Pending)
+	return result, nil
+}
+
+// importAgent creates and joins the agent a record describes
+func (c *Collective) importAgent(rec AgentRecord, newAgent func(agent.AgentConfig) (*agent.Agent, error)) (*agent.Agent, error) {
+	caps := make([]identity.CapabilityType, 0, len(rec.Capabilities))
+	for capType := range rec.Capabilities {
+		caps = append(caps, capType)
+	}
+	sort.Slice(caps, func(i, j int) bool { return caps[i] < caps[j] })
+	a, err := newAgent(agent.AgentConfig{
+		Name:         rec.Identity.Name,
+		Capabilities: caps,
+		Model:        rec.Model,
+		Models:       rec.Models,
+		ParentSID:    rec.Identity.ParentSID,
+		Labels:       rec.Labels,
+		CostTags:     rec.CostTags,
+		SystemPrompt: rec.SystemPrompt,
+		Temperature:  rec.Temperature,
+		MaxTokens:    rec.MaxTokens,
+		Slots:        rec.Slots,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: %w", rec.Identity.Name, err)
+	}
+	a.Identity.SID = rec.Identity.SID
+	a.Identity.CreatedAt = rec.Identity.CreatedAt
+	a.Identity.Generation = rec.Identity.Generation
+	for capType, proficiency := range rec.Capabilities {
+		a.Capabilities.Add(&identity.Capability{Type: capType, Proficiency: proficiency})
+	}
+	if rec.Reputation != nil {
+		*a.Reputation = *rec.Reputation
+	}
+	if err := c.Join(a); err != nil {
+		return nil, fmt.Errorf("agent %s: %w", rec.Identity.Name, err)
+	}
+	return a, nil
+}
+
+// records copies the memory for a snapshot, episodes oldest first
+func (m *CollectiveMemory) records() MemoryRecords {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r := MemoryRecords{Episodes: append([]CollectiveEpisode(nil), m.episodes...)}
+	for _, concept := range m.concepts {
+		r.Concepts = append(r.Concepts, concept)
+	}
+	sort.Slice(r.Concepts, func(i, j int) bool { return r.Concepts[i].ID < r.Concepts[j].ID })
+	for _, ctx := range m.activeContexts {
+		r.Contexts = append(r.Contexts, ctx)
+	}
+	sort.Slice(r.Contexts, func(i, j int) bool { return r.Contexts[i].ID < r.Contexts[j].ID })
+	for _, node := range m.knowledgeGraph.nodes {
+		r.Nodes = append(r.Nodes, node)
+	}
+	sort.Slice(r.Nodes, func(i, j int) bool { return r.Nodes[i].ID < r.Nodes[j].ID })
+	for _, fromEdges := range m.knowledgeGraph.edges {
+		r.Edges = append(r.Edges, fromEdges...)
+	}
+	sort.SliceStable(r.Edges, func(i, j int) bool {
+		if r.Edges[i].From != r.Edges[j].From {
+			return r.Edges[i].From < r.Edges[j].From
+		}
+		return r.Edges[i].To < r.Edges[j].To
+	})
+	return r
+}
+
+// restore adds a snapshot's memory, skipping episodes already held, and
+// returns the number of episodes added
+func (m *CollectiveMemory) restore(r MemoryRecords) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	held := make(map[string]bool, len(m.episodes))
+	for _, ep := range m.episodes {
+		held[ep.ID] = true
+	}
+	added := 0
+	for _, ep := range r.Episodes {
+		if held[ep.ID] {
+			continue
+		}
+		m.episodes = append(m.episodes, ep)
+		m.persist("episode", func(s MemoryStore) error { return s.SaveEpisode(ep) })
+		added++
+	}
+	for _, concept := range r.Concepts {
+		m.concepts[concept.ID] = concept
+		m.persist("concept", func(s MemoryStore) error { return s.SaveConcept(concept) })
+	}
+	for _, ctx := range r.Contexts {
+		m.activeContexts[ctx.ID] = ctx
+		m.persist("context", func(s MemoryStore) error { return s.SaveContext(ctx) })
+	}
+	for _, node := range r.Nodes {
+		m.knowledgeGraph.AddNode(node)
+		m.persist("node", func(s MemoryStore) error { return s.SaveNode(node) })
+	}
+	for _, edge := range r.Edges {
+		m.knowledgeGraph.AddEdge(edge)
+		m.persist("edge", func(s MemoryStore) error { return s.SaveEdge(edge) })
+	}
+	return added
+}
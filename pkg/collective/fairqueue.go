@@ -0,0 +1,354 @@
+package collective
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// AnonymousSubmitter is the submitter recorded for tasks that don't name one
+const AnonymousSubmitter = "anonymous"
+
+// PriorityAging raises the effective priority of waiting tasks by one level
+// per Interval, up to Cap, so low-priority work can't starve indefinitely
+type PriorityAging struct {
+	Interval time.Duration `json:"interval"` // Wait that earns one level (0 = no aging)
+	Cap      int           `json:"cap"`      // Highest priority reachable by aging
+}
+
+// DefaultPriorityAging lets a low-priority task catch up with high-priority
+// work after waiting a little under two minutes
+func DefaultPriorityAging() PriorityAging {
+	return PriorityAging{
+		Interval: 10 * time.Second,
+		Cap:      agent.PriorityHigh,
+	}
+}
+
+// Effective returns the priority of a task that has waited for the given duration
+func (a PriorityAging) Effective(priority int, waited time.Duration) int {
+	if a.Interval <= 0 || priority >= a.Cap {
+		return priority
+	}
+	aged := priority + int(waited/a.Interval)
+	if aged > a.Cap {
+		return a.Cap
+	}
+	return aged
+}
+
+// FairQueue admits tasks into a fixed number of execution slots. The waiting
+// task with the highest effective priority goes first; ties are shared between
+// submitters by smooth weighted round-robin so a bulk submitter can't starve
+// interactive ones. A submitter's weight is scaled by the class weight of the
+// task it offers, so its interactive work outweighs its batch work. In EDF
+// mode the waiting task with the earliest deadline goes first instead, and
+// tasks without one only once none with a deadline wait. With no slot limit
+// every task is admitted at once.
+type FairQueue struct {
+	mu sync.Mutex
+
+	slots int // 0 = unlimited
+	inUse int
+
+	weights       map[string]int
+	defaultWeight int
+	aging         PriorityAging
+	edf           bool // Earliest deadline first
+	now           func() time.Time
+
+	waiting map[string][]*fairWaiter // Submitter -> FIFO of waiters
+	credit  map[string]int           // Smooth WRR current weight per waiting submitter
+	total   int                      // Total waiters
+	granted map[string]int           // Admissions per submitter
+}
+
+// fairWaiter is a blocked Acquire call
+type fairWaiter struct {
+	ready    chan struct{}
+	granted  bool
+	priority int
+	weight   int // Class weight
+	since    time.Time
+	deadline time.Time // Zero if the task has none
+}
+
+// NewFairQueue creates a queue with the given number of execution slots (0 = unlimited)
+func NewFairQueue(slots int) *FairQueue {
+	return &FairQueue{
+		slots:         slots,
+		weights:       make(map[string]int),
+		defaultWeight: 1,
+		aging:         DefaultPriorityAging(),
+		now:           time.Now,
+		waiting:       make(map[string][]*fairWaiter),
+		credit:        make(map[string]int),
+		granted:       make(map[string]int),
+	}
+}
+
+// SetWeight sets a submitter's share of the slots relative to other submitters
+func (q *FairQueue) SetWeight(submitter string, weight int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if weight < 1 {
+		weight = 1
+	}
+	q.weights[submitter] = weight
+}
+
+// Weight returns a submitter's weight
+func (q *FairQueue) Weight(submitter string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.weightLocked(submitter)
+}
+
+// SetAging replaces the priority aging policy
+func (q *FairQueue) SetAging(aging PriorityAging) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.aging = aging
+}
+
+// SetEDF turns earliest-deadline-first admission on or off
+func (q *FairQueue) SetEDF(edf bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.edf = edf
+}
+
+func (q *FairQueue) weightLocked(submitter string) int {
+	if w, ok := q.weights[submitter]; ok {
+		return w
+	}
+	return q.defaultWeight
+}
+
+// Acquire blocks until the submitter is granted a slot for a task of the given
+// priority or ctx ends. Every successful Acquire must be paired with a Release.
+func (q *FairQueue) Acquire(ctx context.Context, submitter string, priority int) error {
+	return q.AcquireWeighted(ctx, submitter, priority, 1)
+}
+
+// AcquireWeighted is Acquire for a task whose class weight scales the
+// submitter's share of contended slots
+func (q *FairQueue) AcquireWeighted(ctx context.Context, submitter string, priority, weight int) error {
+	return q.AcquireDeadline(ctx, submitter, priority, weight, time.Time{})
+}
+
+// AcquireDeadline is AcquireWeighted for a task due by deadline, which
+// orders it in EDF mode (zero = no deadline)
+func (q *FairQueue) AcquireDeadline(ctx context.Context, submitter string, priority, weight int, deadline time.Time) error {
+	if weight < 1 {
+		weight = 1
+	}
+	if submitter == "" {
+		submitter = AnonymousSubmitter
+	}
+
+	q.mu.Lock()
+	if q.slots <= 0 || (q.inUse < q.slots && q.total == 0) {
+		if q.slots > 0 {
+			q.inUse++
+		}
+		q.granted[submitter]++
+		q.mu.Unlock()
+		return nil
+	}
+
+	w := &fairWaiter{ready: make(chan struct{}), priority: priority, weight: weight, since: q.now(), deadline: deadline}
+	q.waiting[submitter] = append(q.waiting[submitter], w)
+	q.total++
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		if w.granted {
+			// Lost the race with Release; hand the slot on
+			q.granted[submitter]--
+			q.releaseLocked()
+		} else {
+			q.removeLocked(submitter, w)
+		}
+		q.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Release returns a slot, admitting the next waiter chosen by weighted round-robin
+func (q *FairQueue) Release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+// releaseLocked frees or hands over a slot. Caller must hold q.mu.
+func (q *FairQueue) releaseLocked() {
+	if q.slots <= 0 {
+		return
+	}
+	if q.total == 0 {
+		q.inUse--
+		return
+	}
+
+	submitter, i := q.nextLocked()
+	waiters := q.waiting[submitter]
+	w := waiters[i]
+	q.waiting[submitter] = append(waiters[:i], waiters[i+1:]...)
+	q.total--
+	if len(q.waiting[submitter]) == 0 {
+		delete(q.waiting, submitter)
+		delete(q.credit, submitter)
+	}
+
+	// The slot passes directly to the waiter, so inUse is unchanged
+	q.granted[submitter]++
+	w.granted = true
+	close(w.ready)
+}
+
+// nextLocked picks the next waiter: in EDF mode the one with the earliest
+// deadline if any has one. Otherwise each submitter offers its highest
+// effective-priority waiter (oldest first on ties), and submitters offering the
+// top priority share by smooth weighted round-robin. Returns the submitter and
+// the waiter's index. Caller must hold q.mu and ensure someone is waiting.
+func (q *FairQueue) nextLocked() (string, int) {
+	now := q.now()
+
+	// Iterate in a stable order so ties resolve deterministically
+	submitters := make([]string, 0, len(q.waiting))
+	for s := range q.waiting {
+		submitters = append(submitters, s)
+	}
+	sort.Strings(submitters)
+
+	if q.edf {
+		if s, i := q.earliestLocked(submitters); s != "" {
+			return s, i
+		}
+	}
+
+	type head struct{ index, priority, weight int }
+	heads := make(map[string]head, len(submitters))
+	top := 0
+	for n, s := range submitters {
+		h := head{}
+		for i, w := range q.waiting[s] {
+			// Waiters are in arrival order, so strict > keeps the oldest on ties
+			if p := q.aging.Effective(w.priority, now.Sub(w.since)); i == 0 || p > h.priority {
+				h = head{index: i, priority: p, weight: w.weight}
+			}
+		}
+		heads[s] = h
+		if n == 0 || h.priority > top {
+			top = h.priority
+		}
+	}
+
+	best, total := "", 0
+	for _, s := range submitters {
+		if heads[s].priority != top {
+			continue
+		}
+		w := q.weightLocked(s) * heads[s].weight
+		q.credit[s] += w
+		total += w
+		if best == "" || q.credit[s] > q.credit[best] {
+			best = s
+		}
+	}
+	q.credit[best] -= total
+	return best, heads[best].index
+}
+
+// earliestLocked returns the waiter with the earliest deadline, the oldest on
+// ties, or "" if none has one. Caller must hold q.mu.
+func (q *FairQueue) earliestLocked(submitters []string) (string, int) {
+	best, index := "", 0
+	var due, since time.Time
+	for _, s := range submitters {
+		for i, w := range q.waiting[s] {
+			if w.deadline.IsZero() {
+				continue
+			}
+			if best == "" || w.deadline.Before(due) || (w.deadline.Equal(due) && w.since.Before(since)) {
+				best, index, due, since = s, i, w.deadline, w.since
+			}
+		}
+	}
+	return best, index
+}
+
+// removeLocked drops an abandoned waiter. Caller must hold q.mu.
+func (q *FairQueue) removeLocked(submitter string, w *fairWaiter) {
+	waiters := q.waiting[submitter]
+	for i, candidate := range waiters {
+		if candidate == w {
+			q.waiting[submitter] = append(waiters[:i], waiters[i+1:]...)
+			q.total--
+			break
+		}
+	}
+	if len(q.waiting[submitter]) == 0 {
+		delete(q.waiting, submitter)
+		delete(q.credit, submitter)
+	}
+}
+
+// full reports whether every execution slot is in use
+func (q *FairQueue) full() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.slots > 0 && q.inUse >= q.slots
+}
+
+// agingPolicy returns the priority aging policy
+func (q *FairQueue) agingPolicy() PriorityAging {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.aging
+}
+
+// queued returns the number of waiting tasks
+func (q *FairQueue) queued() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.total
+}
+
+// FairQueueStats reports queue occupancy
+type FairQueueStats struct {
+	Slots   int            `json:"slots"` // 0 = unlimited
+	InUse   int            `json:"in_use"`
+	Waiting map[string]int `json:"waiting"` // Submitter -> queued tasks
+	Granted map[string]int `json:"granted"` // Submitter -> admitted tasks
+}
+
+// Stats returns current queue statistics
+func (q *FairQueue) Stats() FairQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := FairQueueStats{
+		Slots:   q.slots,
+		InUse:   q.inUse,
+		Waiting: make(map[string]int, len(q.waiting)),
+		Granted: make(map[string]int, len(q.granted)),
+	}
+	for s, waiters := range q.waiting {
+		stats.Waiting[s] = len(waiters)
+	}
+	for s, n := range q.granted {
+		stats.Granted[s] = n
+	}
+	return stats
+}
@@ -0,0 +1,320 @@
+package collective
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+func TestFairQueue_Unlimited(t *testing.T) {
+	q := NewFairQueue(0)
+
+	for i := 0; i < 100; i++ {
+		if err := q.Acquire(context.Background(), "bulk", agent.PriorityNormal); err != nil {
+			t.Fatalf("Expected immediate admission, got %v", err)
+		}
+	}
+	if got := q.Stats().Granted["bulk"]; got != 100 {
+		t.Errorf("Expected 100 admissions, got %d", got)
+	}
+}
+
+func TestFairQueue_WeightedRoundRobin(t *testing.T) {
+	q := NewFairQueue(1)
+	q.SetWeight("interactive", 2)
+
+	// Hold the only slot so everything else queues
+	if err := q.Acquire(context.Background(), "bulk", agent.PriorityNormal); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	order := make(chan string, 9)
+	enqueue := func(submitter string) {
+		before := q.queued()
+		go func() {
+			if err := q.Acquire(context.Background(), submitter, agent.PriorityNormal); err == nil {
+				order <- submitter
+			}
+		}()
+		// Wait until the waiter is registered so arrival order is fixed
+		deadline := time.Now().Add(time.Second)
+		for q.queued() == before {
+			if time.Now().After(deadline) {
+				t.Fatalf("Waiter for %s never queued", submitter)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// A bulk submitter floods the queue before interactive tasks arrive
+	for i := 0; i < 6; i++ {
+		enqueue("bulk")
+	}
+	for i := 0; i < 3; i++ {
+		enqueue("interactive")
+	}
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		q.Release()
+		got = append(got, <-order)
+	}
+
+	interactive := 0
+	for _, s := range got {
+		if s == "interactive" {
+			interactive++
+		}
+	}
+	// Weight 2:1 means interactive gets two of every three slots while waiting
+	if interactive != 3 {
+		t.Errorf("Expected all 3 interactive tasks in the first 6 admissions, got %d (%v)", interactive, got)
+	}
+	if got[0] != "interactive" {
+		t.Errorf("Expected interactive to be admitted first, got %s", got[0])
+	}
+}
+
+func TestFairQueue_CancelWhileWaiting(t *testing.T) {
+	q := NewFairQueue(1)
+	if err := q.Acquire(context.Background(), "a", agent.PriorityNormal); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Acquire(ctx, "b", agent.PriorityNormal); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded, got %v", err)
+	}
+
+	stats := q.Stats()
+	if len(stats.Waiting) != 0 {
+		t.Errorf("Expected no waiters after cancellation, got %v", stats.Waiting)
+	}
+
+	// The slot returns to the pool and is available again
+	q.Release()
+	if err := q.Acquire(context.Background(), "b", agent.PriorityNormal); err != nil {
+		t.Errorf("Expected admission after release, got %v", err)
+	}
+	if stats := q.Stats(); stats.InUse != 1 {
+		t.Errorf("Expected 1 slot in use, got %d", stats.InUse)
+	}
+}
+
+func TestPriorityAging_Effective(t *testing.T) {
+	aging := PriorityAging{Interval: time.Second, Cap: agent.PriorityHigh}
+
+	if got := aging.Effective(agent.PriorityLow, 0); got != agent.PriorityLow {
+		t.Errorf("Expected %d without waiting, got %d", agent.PriorityLow, got)
+	}
+	if got := aging.Effective(agent.PriorityLow, 3500*time.Millisecond); got != agent.PriorityLow+3 {
+		t.Errorf("Expected %d after 3.5s, got %d", agent.PriorityLow+3, got)
+	}
+	if got := aging.Effective(agent.PriorityLow, time.Hour); got != agent.PriorityHigh {
+		t.Errorf("Expected aging capped at %d, got %d", agent.PriorityHigh, got)
+	}
+	if got := aging.Effective(agent.PriorityHigh+5, time.Hour); got != agent.PriorityHigh+5 {
+		t.Errorf("Expected priority above the cap to be unchanged, got %d", got)
+	}
+	if got := (PriorityAging{Interval: -1}).Effective(agent.PriorityLow, time.Hour); got != agent.PriorityLow {
+		t.Errorf("Expected no aging when disabled, got %d", got)
+	}
+}
+
+func TestFairQueue_PriorityAging(t *testing.T) {
+	q := NewFairQueue(1)
+	q.SetAging(PriorityAging{Interval: time.Minute, Cap: agent.PriorityHigh})
+
+	var mu sync.Mutex
+	now := time.Now()
+	q.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+
+	if err := q.Acquire(context.Background(), "svc", agent.PriorityHigh); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	order := make(chan int, 4)
+	enqueue := func(priority int) {
+		queued := q.queued()
+		go func() {
+			if err := q.Acquire(context.Background(), "svc", priority); err == nil {
+				order <- priority
+			}
+		}()
+		deadline := time.Now().Add(time.Second)
+		for q.queued() == queued {
+			if time.Now().After(deadline) {
+				t.Fatal("Waiter never queued")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// A fresh high-priority task beats an older low-priority one
+	enqueue(agent.PriorityLow)
+	enqueue(agent.PriorityHigh)
+	q.Release()
+	if got := <-order; got != agent.PriorityHigh {
+		t.Errorf("Expected high priority admitted first, got %d", got)
+	}
+
+	// After waiting long enough the low-priority task ages up to the cap and,
+	// being older, wins the tie against newly arriving high-priority work
+	advance(time.Duration(agent.PriorityHigh-agent.PriorityLow) * time.Minute)
+	enqueue(agent.PriorityHigh)
+	q.Release()
+	if got := <-order; got != agent.PriorityLow {
+		t.Errorf("Expected aged low-priority task admitted, got %d", got)
+	}
+}
+
+func TestFairQueue_ClassWeight(t *testing.T) {
+	q := NewFairQueue(1)
+	if err := q.Acquire(context.Background(), "holder", agent.PriorityNormal); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	order := make(chan string, 8)
+	enqueue := func(submitter string, weight int) {
+		before := q.queued()
+		go func() {
+			if err := q.AcquireWeighted(context.Background(), submitter, agent.PriorityNormal, weight); err == nil {
+				order <- submitter
+			}
+		}()
+		deadline := time.Now().Add(time.Second)
+		for q.queued() == before {
+			if time.Now().After(deadline) {
+				t.Fatalf("Waiter for %s never queued", submitter)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Batch work arrives first, but interactive work outweighs it 4:1
+	for i := 0; i < 4; i++ {
+		enqueue("batch", 1)
+	}
+	for i := 0; i < 4; i++ {
+		enqueue("interactive", 4)
+	}
+
+	var got []string
+	for i := 0; i < 5; i++ {
+		q.Release()
+		got = append(got, <-order)
+	}
+	interactive := 0
+	for _, s := range got {
+		if s == "interactive" {
+			interactive++
+		}
+	}
+	if interactive != 4 {
+		t.Errorf("Expected all 4 interactive tasks in the first 5 admissions, got %d (%v)", interactive, got)
+	}
+}
+
+func TestFairQueue_EDF(t *testing.T) {
+	q := NewFairQueue(1)
+	q.SetEDF(true)
+	if err := q.Acquire(context.Background(), "holder", agent.PriorityNormal); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	now := time.Now()
+	order := make(chan string, 4)
+	enqueue := func(name string, priority int, deadline time.Time) {
+		before := q.queued()
+		go func() {
+			if err := q.AcquireDeadline(context.Background(), name, priority, 1, deadline); err == nil {
+				order <- name
+			}
+		}()
+		wait := time.Now().Add(time.Second)
+		for q.queued() == before {
+			if time.Now().After(wait) {
+				t.Fatalf("Waiter for %s never queued", name)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// Deadlines outrank priority; work without one waits for the rest
+	enqueue("undated", agent.PriorityCritical, time.Time{})
+	enqueue("later", agent.PriorityLow, now.Add(2*time.Hour))
+	enqueue("sooner", agent.PriorityLow, now.Add(time.Hour))
+
+	for _, want := range []string{"sooner", "later", "undated"} {
+		q.Release()
+		if got := <-order; got != want {
+			t.Errorf("Expected %s admitted next, got %s", want, got)
+		}
+	}
+}
+
+func TestTaskQueue_Order(t *testing.T) {
+	q := newTaskQueue()
+	base := time.Now()
+	add := func(id string, priority int, age time.Duration) {
+		task := agent.NewTask(id, nil).WithPriority(priority)
+		task.ID = id
+		task.CreatedAt = base.Add(-age)
+		q.push(task)
+	}
+	add("low-old", agent.PriorityLow, time.Minute)
+	add("normal", agent.PriorityNormal, time.Second)
+	add("critical", agent.PriorityCritical, 0)
+	add("low-new", agent.PriorityLow, 0)
+	add("normal", agent.PriorityNormal, time.Second) // Already queued
+
+	ids := func(tasks []*agent.Task) []string {
+		out := make([]string, len(tasks))
+		for i, task := range tasks {
+			out[i] = task.ID
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		aging PriorityAging
+		want  []string
+	}{
+		{"by priority, oldest first", PriorityAging{}, []string{"critical", "normal", "low-old", "low-new"}},
+		{"aged past normal", PriorityAging{Interval: 5 * time.Second, Cap: agent.PriorityHigh}, []string{"critical", "low-old", "normal", "low-new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ids(q.ordered(tt.aging, base))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected %v, got %v", tt.want, got)
+					break
+				}
+			}
+		})
+	}
+
+	q.remove("critical")
+	q.remove("missing")
+	if q.Len() != 3 || q.tasks[0].ID != "normal" {
+		t.Errorf("Expected normal at the head of 3 tasks, got %v", ids(q.tasks))
+	}
+}
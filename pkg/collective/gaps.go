@@ -0,0 +1,350 @@
+package collective
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// GapKind is why the collective falls short on a capability
+type GapKind string
+
+const (
+	GapMissing     GapKind = "missing"         // No agent holds it
+	GapProficiency GapKind = "low_proficiency" // Held, but by no agent proficient enough
+	GapCombination GapKind = "combination"     // Held, but not with the other capabilities its tasks require
+	GapCapacity    GapKind = "capacity"        // Capable agents exist, but too few to go round
+)
+
+// GapConfig tunes capability gap analysis
+type GapConfig struct {
+	Window         time.Duration `json:"window"`          // How far back auctions are considered
+	MinProficiency float64       `json:"min_proficiency"` // Requirements met below this count as low-score assignments
+	TasksPerAgent  int           `json:"tasks_per_agent"` // Unmet tasks one suggested agent is expected to absorb
+	MaxSuggestions int           `json:"max_suggestions"`
+}
+
+// DefaultGapConfig returns default gap analysis settings
+func DefaultGapConfig() GapConfig {
+	return GapConfig{
+		Window:         time.Hour,
+		MinProficiency: 0.5,
+		TasksPerAgent:  5,
+		MaxSuggestions: 5,
+	}
+}
+
+// withDefaults fills unset settings from DefaultGapConfig
+func (cfg GapConfig) withDefaults() GapConfig {
+	d := DefaultGapConfig()
+	if cfg.Window <= 0 {
+		cfg.Window = d.Window
+	}
+	if cfg.MinProficiency <= 0 {
+		cfg.MinProficiency = d.MinProficiency
+	}
+	if cfg.TasksPerAgent <= 0 {
+		cfg.TasksPerAgent = d.TasksPerAgent
+	}
+	if cfg.MaxSuggestions <= 0 {
+		cfg.MaxSuggestions = d.MaxSuggestions
+	}
+	return cfg
+}
+
+// CapabilityGap is unmet demand for one capability
+type CapabilityGap struct {
+	Capability identity.CapabilityType `json:"capability"`
+	Kind       GapKind                 `json:"kind"`
+	Demand     int                     `json:"demand"`     // Sum of the counts below
+	Unassigned int                     `json:"unassigned"` // Auctions no capable agent bid in
+	LowScore   int                     `json:"low_score"`  // Assignments that met it below MinProficiency
+	Pending    int                     `json:"pending"`    // Queued tasks no agent is capable of
+	Capacity   int                     `json:"capacity"`   // Tasks that waited only because capable agents were busy
+
+	Holders         int     `json:"holders"` // Agents holding it directly
+	BestProficiency float64 `json:"best_proficiency"`
+	AvgProficiency  float64 `json:"avg_proficiency"`
+}
+
+// AgentTemplate is an agent worth spawning to close gaps
+type AgentTemplate struct {
+	Name         string                    `json:"name"`
+	Capabilities []identity.CapabilityType `json:"capabilities"`
+	Count        int                       `json:"count"` // Agents to spawn
+	Tasks        int                       `json:"tasks"` // Unmet tasks it would have served
+	Reason       GapKind                   `json:"reason"`
+}
+
+// GapReport is the collective's capability gaps over a recent window
+type GapReport struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Window      time.Duration   `json:"window"`
+	Auctions    int             `json:"auctions"`
+	Unassigned  int             `json:"unassigned"`
+	LowScore    int             `json:"low_score"`
+	Pending     int             `json:"pending"`
+	Unplaceable int             `json:"unplaceable"` // Tasks no agent's labels satisfied
+	Gaps        []CapabilityGap `json:"gaps"`
+	Suggestions []AgentTemplate `json:"suggestions"`
+}
+
+// Kinds returns the capability gaps as "capability:kind" pairs, sorted
+func (r *GapReport) Kinds() []string {
+	kinds := make([]string, len(r.Gaps))
+	for i, g := range r.Gaps {
+		kinds[i] = string(g.Capability) + ":" + string(g.Kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// gapAnalysis accumulates unmet demand while a report is built
+type gapAnalysis struct {
+	cfg       GapConfig
+	agents    []*agent.Agent
+	gaps      map[identity.CapabilityType]*CapabilityGap
+	templates map[string]*AgentTemplate
+	threshold float64 // Capability score needed to bid
+}
+
+// capable reports whether any agent matches required well enough to bid
+func (g *gapAnalysis) capable(required []identity.CapabilityType) bool {
+	for _, a := range g.agents {
+		if a.Capabilities.MatchScore(required) >= g.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// gap returns the entry for a capability, creating it if needed
+func (g *gapAnalysis) gap(c identity.CapabilityType) *CapabilityGap {
+	if gap, ok := g.gaps[c]; ok {
+		return gap
+	}
+	gap := &CapabilityGap{Capability: c}
+	g.gaps[c] = gap
+	return gap
+}
+
+// suggest counts a task toward an agent template holding its required
+// capabilities
+func (g *gapAnalysis) suggest(required []identity.CapabilityType, reason GapKind) {
+	caps := append([]identity.CapabilityType(nil), required...)
+	sort.Slice(caps, func(i, j int) bool { return caps[i] < caps[j] })
+	names := make([]string, 0, len(caps))
+	for i, c := range caps {
+		if i > 0 && c == caps[i-1] {
+			continue
+		}
+		names = append(names, string(c))
+	}
+	key := strings.Join(names, "+")
+	if key == "" {
+		key = "generalist"
+	}
+
+	t, ok := g.templates[key]
+	if !ok {
+		t = &AgentTemplate{Name: key, Reason: reason}
+		for _, n := range names {
+			t.Capabilities = append(t.Capabilities, identity.CapabilityType(n))
+		}
+		g.templates[key] = t
+	}
+	t.Tasks++
+	if reason != GapCapacity {
+		t.Reason = reason
+	}
+}
+
+// CapabilityGaps reports which capabilities the collective lacks or holds
+// too weakly, from recent auctions no capable agent bid in or won only
+// weakly and from queued tasks no agent is capable of, and suggests agents
+// to spawn. Zero settings in cfg take their defaults.
+func (c *Collective) CapabilityGaps(cfg GapConfig) *GapReport {
+	cfg = cfg.withDefaults()
+	now := time.Now()
+
+	c.mu.RLock()
+	pending := append([]*agent.Task(nil), c.pending.tasks...)
+	c.mu.RUnlock()
+
+	g := &gapAnalysis{
+		cfg:       cfg,
+		agents:    c.GetAgents(),
+		gaps:      make(map[identity.CapabilityType]*CapabilityGap),
+		templates: make(map[string]*AgentTemplate),
+		threshold: c.market.BidThreshold().Min,
+	}
+	report := &GapReport{GeneratedAt: now, Window: cfg.Window}
+
+	waiting := make(map[string]bool, len(pending))
+	for _, task := range pending {
+		waiting[task.ID] = true
+	}
+
+	for _, exp := range c.explanations.Since(now.Add(-cfg.Window)) {
+		if exp.Pinned {
+			continue
+		}
+		report.Auctions++
+		switch {
+		case exp.Scope == "" && exp.Winner == "":
+			report.Unplaceable++
+		case exp.Winner == "":
+			if waiting[exp.TaskID] {
+				continue // Counted with the pending tasks
+			}
+			report.Unassigned++
+			if g.capable(exp.Required) {
+				for _, r := range exp.Required {
+					g.gap(r).Capacity++
+				}
+				g.suggest(exp.Required, GapCapacity)
+				continue
+			}
+			for _, r := range exp.Required {
+				g.gap(r).Unassigned++
+			}
+			g.suggest(exp.Required, GapMissing)
+		default:
+			if g.lowScore(exp) {
+				report.LowScore++
+				g.suggest(exp.Required, GapProficiency)
+			}
+		}
+	}
+
+	for _, task := range pending {
+		report.Pending++
+		if g.capable(task.Required) {
+			for _, r := range task.Required {
+				g.gap(r).Capacity++
+			}
+			g.suggest(task.Required, GapCapacity)
+			continue
+		}
+		for _, r := range task.Required {
+			g.gap(r).Pending++
+		}
+		g.suggest(task.Required, GapMissing)
+	}
+
+	report.Gaps = g.classify()
+	report.Suggestions = g.suggestions()
+	return report
+}
+
+// lowScore counts the requirements the winner of an auction met below
+// MinProficiency, reporting whether there were any
+func (g *gapAnalysis) lowScore(exp *AssignmentExplanation) bool {
+	for _, b := range exp.Bids {
+		if b.Outcome != OutcomeWon || b.Bid.Match == nil {
+			continue
+		}
+		low := false
+		for _, r := range b.Bid.Match.Requirements {
+			if r.Score < g.cfg.MinProficiency {
+				g.gap(r.Required).LowScore++
+				low = true
+			}
+		}
+		return low
+	}
+	return false
+}
+
+// classify fills in each gap's holders and kind, most demanded first
+func (g *gapAnalysis) classify() []CapabilityGap {
+	gaps := make([]CapabilityGap, 0, len(g.gaps))
+	for c, gap := range g.gaps {
+		total := 0.0
+		for _, a := range g.agents {
+			if !a.Capabilities.Has(c) {
+				continue
+			}
+			p := a.Capabilities.Proficiency(c)
+			gap.Holders++
+			total += p
+			gap.BestProficiency = max(gap.BestProficiency, p)
+		}
+		if gap.Holders > 0 {
+			gap.AvgProficiency = total / float64(gap.Holders)
+		}
+
+		gap.Demand = gap.Unassigned + gap.LowScore + gap.Pending + gap.Capacity
+		switch {
+		case gap.Holders == 0:
+			gap.Kind = GapMissing
+		case gap.BestProficiency < g.cfg.MinProficiency:
+			gap.Kind = GapProficiency
+		case gap.Unassigned+gap.Pending > 0:
+			gap.Kind = GapCombination
+		default:
+			// Proficient holders exist; tasks went weak or waited because they were busy
+			gap.Kind = GapCapacity
+		}
+		gaps = append(gaps, *gap)
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Demand != gaps[j].Demand {
+			return gaps[i].Demand > gaps[j].Demand
+		}
+		return gaps[i].Capability < gaps[j].Capability
+	})
+	return gaps
+}
+
+// suggestions returns the agent templates covering the most unmet tasks,
+// one agent per TasksPerAgent tasks
+func (g *gapAnalysis) suggestions() []AgentTemplate {
+	templates := make([]AgentTemplate, 0, len(g.templates))
+	for _, t := range g.templates {
+		t.Count = (t.Tasks + g.cfg.TasksPerAgent - 1) / g.cfg.TasksPerAgent
+		templates = append(templates, *t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Tasks != templates[j].Tasks {
+			return templates[i].Tasks > templates[j].Tasks
+		}
+		return templates[i].Name < templates[j].Name
+	})
+	if len(templates) > g.cfg.MaxSuggestions {
+		templates = templates[:g.cfg.MaxSuggestions]
+	}
+	return templates
+}
+
+// checkGaps publishes EventCapabilityGaps when the set of capability gaps
+// has changed since the last check, so an autoscaler watching the activity
+// stream can spawn the suggested agents
+func (c *Collective) checkGaps() {
+	// Autoscalers act on these events; hold them back during maintenance
+	if c.InMaintenance() {
+		return
+	}
+
+	report := c.CapabilityGaps(c.config.Gaps)
+	kinds := strings.Join(report.Kinds(), ",")
+
+	c.mu.Lock()
+	changed := kinds != c.gapKinds
+	c.gapKinds = kinds
+	c.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	c.events.Publish(Event{
+		Type: EventCapabilityGaps,
+		Data: map[string]interface{}{
+			"gaps":        report.Gaps,
+			"suggestions": report.Suggestions,
+		},
+	})
+}
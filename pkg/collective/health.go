@@ -0,0 +1,321 @@
+package collective
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// HealthStatus summarizes a health score
+type HealthStatus string
+
+const (
+	HealthHealthy   HealthStatus = "healthy"   // Score of 80 or more
+	HealthDegraded  HealthStatus = "degraded"  // Score of 50 or more
+	HealthUnhealthy HealthStatus = "unhealthy" // Lower, or any check failing outright
+)
+
+// Health check windows and limits
+const (
+	healthWaitSamples    = 100         // Recent queue waits averaged for latency
+	healthNoBidWindow    = time.Hour   // How long a task that found no bidder counts against capacity
+	healthWaitTolerance  = time.Second // Queue waits up to this are healthy
+	healthWaitLimit      = time.Minute // Queue waits this long score zero
+	healthBacklogOK      = 2           // Pending consensus rounds that are normal
+	healthBacklogLimit   = 20          // Pending consensus rounds that score zero
+	healthBudgetHeadroom = 0.2         // Budget fraction left below which the score falls
+)
+
+// HealthCheck is one component of the collective's health
+type HealthCheck struct {
+	Name         string   `json:"name"`
+	Score        float64  `json:"score"` // 0.0 - 1.0
+	Detail       string   `json:"detail"`
+	Remediations []string `json:"remediations,omitempty"`
+}
+
+// HealthReport is the collective's aggregate health
+type HealthReport struct {
+	Score     float64       `json:"score"` // 0-100, the mean of the check scores
+	Status    HealthStatus  `json:"status"`
+	Checks    []HealthCheck `json:"checks"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// Remediations returns the suggestions of every check
+func (r HealthReport) Remediations() []string {
+	var all []string
+	for _, check := range r.Checks {
+		all = append(all, check.Remediations...)
+	}
+	return all
+}
+
+// HealthProbe reports on a component outside the collective, such as the LLM provider
+type HealthProbe func() HealthCheck
+
+// healthTracker records the activity health checks are computed from
+type healthTracker struct {
+	mu sync.Mutex
+
+	waits  []time.Duration // Ring of recent queue waits
+	next   int
+	noBids []noBid
+	probes []HealthProbe
+}
+
+// noBid is a task that found no bidder
+type noBid struct {
+	required []identity.CapabilityType
+	at       time.Time
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{waits: make([]time.Duration, 0, healthWaitSamples)}
+}
+
+// recordWait records how long a task waited for an execution slot
+func (h *healthTracker) recordWait(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.waits) < healthWaitSamples {
+		h.waits = append(h.waits, d)
+		return
+	}
+	h.waits[h.next] = d
+	h.next = (h.next + 1) % healthWaitSamples
+}
+
+// recordNoBids records a task that no agent bid on
+func (h *healthTracker) recordNoBids(required []identity.CapabilityType, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.noBids = append(h.noBids, noBid{required: required, at: at})
+}
+
+// averageWait returns the mean recent queue wait
+func (h *healthTracker) averageWait() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.waits) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range h.waits {
+		total += d
+	}
+	return total / time.Duration(len(h.waits))
+}
+
+// recentNoBids drops no-bid records older than the window and returns the rest
+func (h *healthTracker) recentNoBids(now time.Time) []noBid {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	recent := h.noBids[:0]
+	for _, nb := range h.noBids {
+		if now.Sub(nb.at) <= healthNoBidWindow {
+			recent = append(recent, nb)
+		}
+	}
+	h.noBids = recent
+	return append([]noBid(nil), recent...)
+}
+
+// AddHealthProbe adds an external check to the collective's health report
+func (c *Collective) AddHealthProbe(probe HealthProbe) {
+	c.health.mu.Lock()
+	defer c.health.mu.Unlock()
+	c.health.probes = append(c.health.probes, probe)
+}
+
+// Health computes the collective's health score from agent availability,
+// unmet capability demand, queue latency, consensus backlog, token budget
+// headroom and any registered probes. Each check suggests remediations for
+// whatever is dragging it down.
+func (c *Collective) Health() HealthReport {
+	now := time.Now()
+	checks := []HealthCheck{
+		c.agentHealth(),
+		c.capacityHealth(now),
+		c.queueHealth(),
+		c.consensusHealth(),
+	}
+	if check, ok := c.budgetHealth(); ok {
+		checks = append(checks, check)
+	}
+
+	c.health.mu.Lock()
+	probes := append([]HealthProbe(nil), c.health.probes...)
+	c.health.mu.Unlock()
+	for _, probe := range probes {
+		checks = append(checks, probe())
+	}
+
+	var total float64
+	failing := false
+	for _, check := range checks {
+		total += check.Score
+		failing = failing || check.Score == 0
+	}
+	report := HealthReport{
+		Score:     total / float64(len(checks)) * 100,
+		Checks:    checks,
+		Timestamp: now,
+	}
+	switch {
+	case failing || report.Score < 50:
+		report.Status = HealthUnhealthy
+	case report.Score < 80:
+		report.Status = HealthDegraded
+	default:
+		report.Status = HealthHealthy
+	}
+	return report
+}
+
+// agentHealth scores the share of agents available for work, relative to MinAgents
+func (c *Collective) agentHealth() HealthCheck {
+	agents := c.GetAgents()
+	check := HealthCheck{Name: "agents"}
+
+	available := 0
+	for _, a := range agents {
+		if state := a.GetState(); state == agent.StateIdle || state == agent.StateWorking {
+			available++
+		}
+	}
+	want := c.config.MinAgents
+	if want < 1 {
+		want = 1
+	}
+
+	check.Detail = fmt.Sprintf("%d of %d agents available", available, len(agents))
+	check.Score = clamp(float64(available) / float64(want))
+	if available < want {
+		check.Remediations = append(check.Remediations, fmt.Sprintf("spawn %d more agents (min_agents is %d)", want-available, c.config.MinAgents))
+	}
+	if paused := len(agents) - available; paused > 0 && len(agents) > 0 {
+		check.Score = clamp(check.Score * float64(available) / float64(len(agents)))
+		check.Remediations = append(check.Remediations, fmt.Sprintf("resume or replace %d paused or terminated agents", paused))
+	}
+	return check
+}
+
+// capacityHealth scores recent tasks that found no bidder, naming the
+// capabilities they needed
+func (c *Collective) capacityHealth(now time.Time) HealthCheck {
+	noBids := c.health.recentNoBids(now)
+	check := HealthCheck{Name: "capacity", Score: 1, Detail: "every recent task found a bidder"}
+	if len(noBids) == 0 {
+		return check
+	}
+
+	check.Score = clamp(1 - float64(len(noBids))/10)
+	check.Detail = fmt.Sprintf("%d tasks found no bidder in the last %v", len(noBids), healthNoBidWindow)
+
+	unmet := make(map[identity.CapabilityType]int)
+	for _, nb := range noBids {
+		for _, capType := range nb.required {
+			unmet[capType]++
+		}
+	}
+	caps := make([]identity.CapabilityType, 0, len(unmet))
+	for capType := range unmet {
+		caps = append(caps, capType)
+	}
+	sort.Slice(caps, func(i, j int) bool { return caps[i] < caps[j] })
+
+	agents := c.GetAgents()
+	for _, capType := range caps {
+		held := false
+		for _, a := range agents {
+			held = held || a.Capabilities.Has(capType)
+		}
+		if held {
+			check.Remediations = append(check.Remediations, fmt.Sprintf("raise the bid timeout (now %v) or spawn more %s agents; %d tasks needing it found its agents busy", c.market.BidTimeout(), capType, unmet[capType]))
+		} else {
+			check.Remediations = append(check.Remediations, fmt.Sprintf("spawn an agent with capability %s; %d tasks needed it", capType, unmet[capType]))
+		}
+	}
+	return check
+}
+
+// queueHealth scores how long tasks wait for an execution slot
+func (c *Collective) queueHealth() HealthCheck {
+	wait := c.health.averageWait()
+	stats := c.queue.Stats()
+	waiting := 0
+	for _, n := range stats.Waiting {
+		waiting += n
+	}
+
+	check := HealthCheck{
+		Name:   "queue",
+		Detail: fmt.Sprintf("average wait %v, %d waiting", wait.Round(time.Millisecond), waiting),
+		Score:  1 - clamp(float64(wait-healthWaitTolerance)/float64(healthWaitLimit-healthWaitTolerance)),
+	}
+	if check.Score < 1 {
+		if stats.Slots > 0 {
+			check.Remediations = append(check.Remediations, fmt.Sprintf("raise max_concurrent_tasks (now %d)", stats.Slots))
+		} else {
+			check.Remediations = append(check.Remediations, "spawn more agents to work through the queue")
+		}
+	}
+	return check
+}
+
+// consensusHealth scores the backlog of undecided consensus rounds
+func (c *Collective) consensusHealth() HealthCheck {
+	pending := c.consensus.Stats().PendingRounds
+	for _, team := range c.Teams() {
+		pending += team.GetConsensus().Stats().PendingRounds
+	}
+
+	check := HealthCheck{
+		Name:   "consensus",
+		Detail: fmt.Sprintf("%d rounds pending", pending),
+		Score:  1 - clamp(float64(pending-healthBacklogOK)/float64(healthBacklogLimit-healthBacklogOK)),
+	}
+	if check.Score < 1 {
+		check.Remediations = append(check.Remediations, fmt.Sprintf("add voting agents or lower the consensus threshold (now %.2f)", c.config.ConsensusThreshold))
+	}
+	return check
+}
+
+// budgetHealth scores token budget headroom; false if there is no budget
+func (c *Collective) budgetHealth() (HealthCheck, bool) {
+	budget := c.config.TokenBudget
+	if budget <= 0 {
+		return HealthCheck{}, false
+	}
+
+	used := 0
+	for _, a := range c.GetAgents() {
+		used += a.GetUsage().TokensUsed
+	}
+	headroom := 1 - float64(used)/float64(budget)
+	check := HealthCheck{
+		Name:   "budget",
+		Detail: fmt.Sprintf("%d of %d tokens used", used, budget),
+		Score:  clamp(headroom / healthBudgetHeadroom),
+	}
+	if check.Score < 1 {
+		check.Remediations = append(check.Remediations, fmt.Sprintf("raise token_budget (now %d) or route work to cheaper models", budget))
+	}
+	return check, true
+}
+
+// clamp limits a score to [0, 1]
+func clamp(v float64) float64 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,70 @@
+package collective
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func TestCollective_Health(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.TokenBudget = 1000
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	report := c.Health()
+	if report.Status != HealthUnhealthy {
+		t.Errorf("Expected an empty collective to be unhealthy, got %s", report.Status)
+	}
+	if !containsRemediation(report, "spawn 2 more agents") {
+		t.Errorf("Expected a remediation to spawn agents, got %v", report.Remediations())
+	}
+
+	a1, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	a2, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent2", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a1)
+	_ = c.Join(a2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	report = c.Health()
+	if report.Status != HealthHealthy || report.Score != 100 {
+		t.Errorf("Expected a healthy collective scoring 100, got %s %.1f", report.Status, report.Score)
+	}
+	if len(report.Checks) != 5 {
+		t.Errorf("Expected 5 checks including budget, got %d", len(report.Checks))
+	}
+
+	// Nobody can take a security task
+	if _, err := c.SubmitCtx(ctx, agent.NewTask("Audit", []identity.CapabilityType{identity.CapSecurity})); err == nil {
+		t.Fatal("Expected the security task to find no bidder")
+	}
+	report = c.Health()
+	if report.Score >= 100 || !containsRemediation(report, "spawn an agent with capability security") {
+		t.Errorf("Expected a capacity remediation for security, got %.1f %v", report.Score, report.Remediations())
+	}
+
+	c.AddHealthProbe(func() HealthCheck {
+		return HealthCheck{Name: "providers", Score: 0, Remediations: []string{"rotate the claude API key"}}
+	})
+	report = c.Health()
+	if report.Status != HealthUnhealthy || !containsRemediation(report, "rotate the claude API key") {
+		t.Errorf("Expected a failing probe to make the collective unhealthy, got %s %v", report.Status, report.Remediations())
+	}
+}
+
+func containsRemediation(report HealthReport, substr string) bool {
+	for _, r := range report.Remediations() {
+		if strings.Contains(r, substr) {
+			return true
+		}
+	}
+	return false
+}
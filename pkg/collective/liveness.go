@@ -0,0 +1,196 @@
+package collective
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+)
+
+// HeartbeatConfig sets how the collective watches its agents' liveness. Agents
+// heartbeat over gossip; one that goes silent, terminates, or works on a task
+// past its deadline is removed, its task is requeued, and it is respawned if
+// a lifecycle manager is set.
+type HeartbeatConfig struct {
+	Interval     time.Duration `json:"interval,omitempty"`      // How often agents report (0 = agent.DefaultHeartbeatInterval)
+	Timeout      time.Duration `json:"timeout,omitempty"`       // Silence after which an agent is presumed dead (0 = three intervals)
+	StallTimeout time.Duration `json:"stall_timeout,omitempty"` // Longest a task without a deadline may run (0 = no limit)
+}
+
+// interval returns the heartbeat interval, applying the default
+func (h HeartbeatConfig) interval() time.Duration {
+	if h.Interval <= 0 {
+		return agent.DefaultHeartbeatInterval
+	}
+	return h.Interval
+}
+
+// timeout returns the silence allowed before an agent is presumed dead
+func (h HeartbeatConfig) timeout() time.Duration {
+	if h.Timeout <= 0 {
+		return 3 * h.interval()
+	}
+	return h.Timeout
+}
+
+// SetLifecycle sets the lifecycle manager used to respawn agents removed for
+// being unresponsive (nil = don't respawn)
+func (c *Collective) SetLifecycle(lm *agent.LifecycleManager) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lifecycle = lm
+}
+
+// watchLocked starts tracking an agent's heartbeats. Caller must hold c.mu.
+func (c *Collective) watchLocked(a *agent.Agent) {
+	sid := a.Identity.SID
+	a.SetHeartbeatInterval(c.config.Heartbeat.interval())
+	a.OnHeartbeat(func(hb agent.Heartbeat) {
+		c.gossip.Broadcast(coordination.Message{
+			Type:    coordination.MsgHeartbeat,
+			From:    sid,
+			Payload: hb,
+		})
+	})
+	c.beats[sid] = agent.Heartbeat{AgentSID: sid, State: a.GetState(), Timestamp: time.Now()}
+}
+
+// observeHeartbeat records a heartbeat gossiped by a member
+func (c *Collective) observeHeartbeat(msg coordination.Message) {
+	hb, ok := msg.Payload.(agent.Heartbeat)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if last, member := c.beats[hb.AgentSID]; member && hb.Timestamp.After(last.Timestamp) {
+		c.beats[hb.AgentSID] = hb
+	}
+}
+
+// runLivenessMonitor removes unresponsive agents until ctx is done
+func (c *Collective) runLivenessMonitor(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Heartbeat.interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for sid, reason := range c.unresponsive(time.Now()) {
+				c.recoverAgent(ctx, sid, reason)
+			}
+		}
+	}
+}
+
+// unresponsive returns the agents that are dead or stuck, with the reason
+func (c *Collective) unresponsive(now time.Time) map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.runCtx == nil {
+		return nil
+	}
+
+	found := make(map[string]string)
+	timeout := c.config.Heartbeat.timeout()
+	for sid, hb := range c.beats {
+		switch {
+		case hb.State == agent.StateTerminated:
+			found[sid] = "agent terminated"
+		case now.Sub(hb.Timestamp) > timeout:
+			found[sid] = fmt.Sprintf("no heartbeat for %s", now.Sub(hb.Timestamp).Round(time.Millisecond))
+		case hb.State == agent.StateWorking:
+			for _, task := range hb.Running() {
+				if deadline := c.taskDeadlineLocked(task); !deadline.IsZero() && now.After(deadline) {
+					found[sid] = fmt.Sprintf("task %s overran its deadline", task.TaskID)
+					break
+				}
+			}
+		}
+	}
+	return found
+}
+
+// taskDeadlineLocked returns when a task a heartbeat reports should be done:
+// its own deadline, or StallTimeout after it started. Caller must hold c.mu.
+func (c *Collective) taskDeadlineLocked(running agent.ActiveTask) time.Time {
+	if task, ok := c.activeTasks[running.TaskID]; ok && !task.Deadline.IsZero() {
+		return task.Deadline
+	}
+	if stall := c.config.Heartbeat.StallTimeout; stall > 0 {
+		return running.Started.Add(stall)
+	}
+	return time.Time{}
+}
+
+// recoverAgent removes an unresponsive agent from the collective. Its current
+// tasks are cancelled and requeued for other agents, and each counts as a
+// failure against the agent.
+func (c *Collective) recoverAgent(ctx context.Context, sid, reason string) {
+	c.mu.Lock()
+	a, ok := c.agents[sid]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	tasks := a.GetCurrentTasks()
+	taskID := ""
+	if len(tasks) > 0 {
+		taskID = tasks[0].ID
+		c.requeueLocked(tasks)
+	}
+	c.mu.Unlock()
+
+	c.log().Warn("removing unresponsive agent", "agent", sid, "reason", reason, "task", taskID)
+	c.events.Publish(Event{
+		Type:     EventAgentUnresponsive,
+		AgentSID: sid,
+		TaskID:   taskID,
+		Data:     map[string]interface{}{"reason": reason},
+	})
+
+	for _, task := range tasks {
+		c.reputation.RecordTaskFailure(sid)
+		c.slashStake(task.ID, "abandoned by an unresponsive agent")
+	}
+	a.CancelTask()
+	if err := c.Leave(sid); err != nil {
+		return
+	}
+	c.respawn(ctx, a)
+}
+
+// respawn replaces a removed agent with a fresh one of the same name and
+// capabilities, if a lifecycle manager is set
+func (c *Collective) respawn(ctx context.Context, old *agent.Agent) {
+	c.mu.RLock()
+	lm := c.lifecycle
+	policy := c.config.Admission
+	c.mu.RUnlock()
+
+	if lm == nil {
+		return
+	}
+	_ = lm.Terminate(old.Identity.SID) // Only registered if lm spawned it
+
+	replacement, err := lm.Spawn(ctx, old.Identity.Name, old.Capabilities.List())
+	if err != nil {
+		c.log().Warn("could not respawn agent", "agent", old.Identity.SID, "error", err)
+		return
+	}
+	if policy != nil && policy.WorkDifficulty > 0 {
+		replacement.Identity.ProveWork(policy.WorkDifficulty)
+	}
+	if err := c.Join(replacement); err != nil {
+		c.log().Warn("respawned agent could not join", "agent", replacement.Identity.SID, "error", err)
+		_ = lm.Terminate(replacement.Identity.SID)
+		return
+	}
+	c.log().Info("agent respawned", "agent", old.Identity.SID, "replacement", replacement.Identity.SID)
+}
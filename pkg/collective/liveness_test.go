@@ -0,0 +1,162 @@
+package collective
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+func TestCollective_RecoversStalledAgent(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.Heartbeat = HeartbeatConfig{Interval: 10 * time.Millisecond}
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(20 * time.Millisecond)
+	c.SetLifecycle(agent.NewLifecycleManager(agent.NewRuntime(agent.DefaultRuntimeConfig()), nil, ""))
+
+	stuck, _ := agent.NewAgent(agent.AgentConfig{
+		Name:     "Worker",
+		Provider: &blockingProvider{release: make(chan struct{})},
+	})
+	_ = c.Join(stuck)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	events, unsubscribe := c.SubscribeEvents()
+	defer unsubscribe()
+
+	task := agent.NewTask("Never finishes on the first agent", nil).
+		WithDeadline(time.Now().Add(100 * time.Millisecond))
+	result, err := c.SubmitCtx(ctx, task)
+	if err != nil {
+		t.Fatalf("SubmitCtx failed: %v", err)
+	}
+
+	if result.AgentSID == stuck.Identity.SID {
+		t.Errorf("Expected the task to be requeued to another agent")
+	}
+	if result.Status != agent.TaskCompleted {
+		t.Errorf("Expected status completed, got %s", result.Status)
+	}
+	if _, ok := c.GetAgent(stuck.Identity.SID); ok {
+		t.Errorf("Expected stalled agent to be removed")
+	}
+	waitFor(t, time.Second, func() bool { return stuck.GetState() == agent.StateTerminated })
+
+	agents := c.GetAgents()
+	if len(agents) != 1 || agents[0].Identity.Name != "Worker" {
+		t.Errorf("Expected one respawned agent named Worker, got %d agents", len(agents))
+	}
+
+	found := false
+	for !found {
+		select {
+		case e := <-events:
+			if e.Type == EventAgentUnresponsive {
+				found = true
+				if e.AgentSID != stuck.Identity.SID || e.TaskID != task.ID {
+					t.Errorf("Expected unresponsive event for the stalled agent and its task")
+				}
+				if reason, _ := e.Data["reason"].(string); !strings.Contains(reason, "deadline") {
+					t.Errorf("Expected deadline reason, got %q", reason)
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatal("No agent_unresponsive event published")
+		}
+	}
+}
+
+func TestCollective_Unresponsive(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.Heartbeat = HeartbeatConfig{Interval: time.Second, StallTimeout: time.Minute}
+	c := NewCollective("TestCollective", cfg)
+
+	silent, _ := agent.NewAgent(agent.AgentConfig{Name: "Silent"})
+	busy, _ := agent.NewAgent(agent.AgentConfig{Name: "Busy"})
+	_ = c.Join(silent)
+	_ = c.Join(busy)
+
+	now := time.Now()
+	if found := c.unresponsive(now.Add(time.Hour)); len(found) != 0 {
+		t.Errorf("Expected no checks before the collective starts, got %v", found)
+	}
+
+	// Mark the collective running without starting agents, whose own
+	// heartbeats would replace the ones set here
+	c.mu.Lock()
+	c.runCtx = context.Background()
+	c.beats[silent.Identity.SID] = agent.Heartbeat{AgentSID: silent.Identity.SID, State: agent.StateIdle, Timestamp: now.Add(-4 * time.Second)}
+	c.beats[busy.Identity.SID] = agent.Heartbeat{
+		AgentSID:    busy.Identity.SID,
+		State:       agent.StateWorking,
+		TaskID:      "task-1",
+		TaskStarted: now.Add(-30 * time.Second),
+		Timestamp:   now,
+	}
+	c.mu.Unlock()
+
+	found := c.unresponsive(now)
+	if !strings.HasPrefix(found[silent.Identity.SID], "no heartbeat") {
+		t.Errorf("Expected silent agent to be reported, got %q", found[silent.Identity.SID])
+	}
+	if _, ok := found[busy.Identity.SID]; ok {
+		t.Errorf("Expected busy agent within its stall timeout to be healthy")
+	}
+
+	// Still heartbeating a minute later, but on the same task
+	later := now.Add(time.Minute)
+	c.mu.Lock()
+	beat := c.beats[busy.Identity.SID]
+	beat.Timestamp = later
+	c.beats[busy.Identity.SID] = beat
+	c.mu.Unlock()
+
+	found = c.unresponsive(later)
+	if !strings.Contains(found[busy.Identity.SID], "deadline") {
+		t.Errorf("Expected busy agent past its stall timeout to be reported, got %q", found[busy.Identity.SID])
+	}
+}
+
+func TestCollective_LatencyTargetIsNotADeadline(t *testing.T) {
+	cfg := DefaultCollectiveConfig()
+	cfg.Heartbeat = HeartbeatConfig{Interval: 10 * time.Millisecond, Timeout: time.Second}
+	cfg.QoS = map[agent.QoSClass]QoSPolicy{agent.QoSStandard: {LatencyTarget: 20 * time.Millisecond}}
+	c := NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	c.SetLifecycle(agent.NewLifecycleManager(agent.NewRuntime(agent.DefaultRuntimeConfig()), nil, ""))
+
+	provider := &blockingProvider{release: make(chan struct{})}
+	slow, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider})
+	_ = c.Join(slow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	time.AfterFunc(200*time.Millisecond, func() { close(provider.release) })
+	task := agent.NewTask("Takes longer than the latency target", nil)
+	result, err := c.SubmitCtx(ctx, task)
+	if err != nil {
+		t.Fatalf("SubmitCtx failed: %v", err)
+	}
+
+	if result.Status != agent.TaskCompleted || result.AgentSID != slow.Identity.SID {
+		t.Errorf("Expected the slow agent to complete the task, got %s by %s", result.Status, result.AgentSID)
+	}
+	if !task.Deadline.IsZero() {
+		t.Errorf("Expected the latency target to leave the deadline unset, got %v", task.Deadline)
+	}
+	if _, ok := c.GetAgent(slow.Identity.SID); !ok {
+		t.Error("Expected the slow agent to stay in the collective")
+	}
+	if late := c.QoSStats()[agent.QoSStandard].Late; late != 1 {
+		t.Errorf("Expected the result counted late, got %d", late)
+	}
+}
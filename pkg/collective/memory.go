@@ -1,10 +1,15 @@
 package collective
 
 import (
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/logging"
+	"github.com/square-mind/squaremind/pkg/prompt"
+	"github.com/square-mind/squaremind/pkg/schema"
 )
 
 // CollectiveMemory represents shared memory across the collective
@@ -22,6 +27,10 @@ type CollectiveMemory struct {
 
 	// Semantic Memory - concepts and embeddings
 	concepts map[string]*Concept
+
+	// Persistence; nil keeps memory in this process only
+	store  MemoryStore
+	logger logging.Logger
 }
 
 // NewCollectiveMemory creates a new collective memory
@@ -31,6 +40,57 @@ func NewCollectiveMemory() *CollectiveMemory {
 		episodes:       make([]CollectiveEpisode, 0),
 		activeContexts: make(map[string]*SharedContext),
 		concepts:       make(map[string]*Concept),
+		logger:         logging.Component("memory"),
+	}
+}
+
+// NewPersistentMemory creates a collective memory restored from and written
+// through to store. The most recent episodes are cached in memory; queries
+// search every stored episode.
+func NewPersistentMemory(store MemoryStore) (*CollectiveMemory, error) {
+	snapshot, err := store.Load(memoryCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewCollectiveMemory()
+	m.store = store
+	m.episodes = append(m.episodes, snapshot.Episodes...)
+	for _, c := range snapshot.Concepts {
+		m.concepts[c.ID] = c
+	}
+	for _, ctx := range snapshot.Contexts {
+		m.activeContexts[ctx.ID] = ctx
+	}
+	for _, node := range snapshot.Nodes {
+		m.knowledgeGraph.AddNode(node)
+	}
+	for _, edge := range snapshot.Edges {
+		m.knowledgeGraph.AddEdge(edge)
+	}
+	return m, nil
+}
+
+// Close closes the memory's store, if it has one
+func (m *CollectiveMemory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.store == nil {
+		return nil
+	}
+	err := m.store.Close()
+	m.store = nil
+	return err
+}
+
+// persist runs a store write, logging failures: memory stays usable in
+// process if the database is unavailable. Caller must hold m.mu.
+func (m *CollectiveMemory) persist(what string, write func(MemoryStore) error) {
+	if m.store == nil {
+		return
+	}
+	if err := write(m.store); err != nil {
+		m.logger.Warn("could not persist memory", "what", what, "error", err)
 	}
 }
 
@@ -43,6 +103,8 @@ type CollectiveEpisode struct {
 	Context      map[string]interface{} `json:"context"`
 	Timestamp    time.Time              `json:"timestamp"`
 	Salience     float64                `json:"salience"` // 0.0-1.0 importance
+
+	extra schema.Extra // Fields written by a newer release
 }
 
 // SharedContext represents an active shared context
@@ -54,6 +116,8 @@ type SharedContext struct {
 	CreatedAt    time.Time              `json:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at"`
 	TTL          time.Duration          `json:"ttl"`
+
+	extra schema.Extra // Fields written by a newer release
 }
 
 // Concept represents a semantic concept
@@ -65,6 +129,8 @@ type Concept struct {
 	Relations   []string  `json:"relations"`           // Related concept IDs
 	CreatedBy   string    `json:"created_by"`          // Agent SID
 	CreatedAt   time.Time `json:"created_at"`
+
+	extra schema.Extra // Fields written by a newer release
 }
 
 // KnowledgeGraph represents the collective knowledge graph
@@ -80,6 +146,8 @@ type KnowledgeNode struct {
 	Label      string                 `json:"label"`
 	Properties map[string]interface{} `json:"properties"`
 	CreatedAt  time.Time              `json:"created_at"`
+
+	extra schema.Extra // Fields written by a newer release
 }
 
 // KnowledgeEdge represents an edge in the knowledge graph
@@ -89,6 +157,8 @@ type KnowledgeEdge struct {
 	Type       string                 `json:"type"`
 	Weight     float64                `json:"weight"`
 	Properties map[string]interface{} `json:"properties"`
+
+	extra schema.Extra // Fields written by a newer release
 }
 
 // NewKnowledgeGraph creates a new knowledge graph
@@ -135,10 +205,11 @@ func (m *CollectiveMemory) Contribute(agentSID string, content string, context m
 	}
 
 	m.episodes = append(m.episodes, episode)
+	m.persist("episode", func(s MemoryStore) error { return s.SaveEpisode(episode) })
 
-	// Keep episodes bounded
-	if len(m.episodes) > 1000 {
-		m.episodes = m.episodes[len(m.episodes)-1000:]
+	// Keep episodes bounded; a store keeps them all
+	if len(m.episodes) > memoryCacheSize {
+		m.episodes = m.episodes[len(m.episodes)-memoryCacheSize:]
 	}
 }
 
@@ -147,6 +218,14 @@ func (m *CollectiveMemory) Query(query string) []CollectiveEpisode {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	if m.store != nil {
+		results, err := m.store.QueryEpisodes(query)
+		if err == nil {
+			return results
+		}
+		m.logger.Warn("memory query failed, searching cached episodes", "error", err)
+	}
+
 	// Simple substring search - in production, use vector similarity
 	var results []CollectiveEpisode
 	for _, ep := range m.episodes {
@@ -158,6 +237,55 @@ func (m *CollectiveMemory) Query(query string) []CollectiveEpisode {
 	return results
 }
 
+// Search returns the cached episodes and concepts most similar to query,
+// most similar first, as prompt material. Concepts are offered as
+// "name: description".
+func (m *CollectiveMemory) Search(query string, limit int) []prompt.Candidate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	type scored struct {
+		candidate  prompt.Candidate
+		similarity float64
+	}
+	var matches []scored
+	consider := func(c prompt.Candidate) {
+		if similarity := prompt.Similarity(query, c.Content); similarity > 0 {
+			matches = append(matches, scored{c, similarity})
+		}
+	}
+
+	// Newest first, so ties go to recent episodes
+	for i := len(m.episodes) - 1; i >= 0; i-- {
+		ep := m.episodes[i]
+		consider(prompt.Candidate{Source: ep.Type, Content: ep.Content, Salience: ep.Salience})
+	}
+	ids := make([]string, 0, len(m.concepts))
+	for id := range m.concepts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c := m.concepts[id]
+		consider(prompt.Candidate{Source: "concept", Content: c.Name + ": " + c.Description, Salience: 0.5})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].similarity > matches[j].similarity })
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	results := make([]prompt.Candidate, len(matches))
+	for i, match := range matches {
+		results[i] = match.candidate
+	}
+	return results
+}
+
+// Recall implements agent.SharedMemory with Search
+func (m *CollectiveMemory) Recall(query string, limit int) []prompt.Candidate {
+	return m.Search(query, limit)
+}
+
 // CreateContext creates a new shared context
 func (m *CollectiveMemory) CreateContext(name string, creator string, ttl time.Duration) *SharedContext {
 	m.mu.Lock()
@@ -174,6 +302,7 @@ func (m *CollectiveMemory) CreateContext(name string, creator string, ttl time.D
 	}
 
 	m.activeContexts[ctx.ID] = ctx
+	m.persist("context", func(s MemoryStore) error { return s.SaveContext(ctx) })
 	return ctx
 }
 
@@ -209,6 +338,7 @@ func (m *CollectiveMemory) UpdateContext(id string, agentSID string, key string,
 	if !found {
 		ctx.Contributors = append(ctx.Contributors, agentSID)
 	}
+	m.persist("context", func(s MemoryStore) error { return s.SaveContext(ctx) })
 }
 
 // AddConcept adds a semantic concept
@@ -226,6 +356,7 @@ func (m *CollectiveMemory) AddConcept(name, description string, creator string)
 	}
 
 	m.concepts[concept.ID] = concept
+	m.persist("concept", func(s MemoryStore) error { return s.SaveConcept(concept) })
 	return concept
 }
 
@@ -244,9 +375,11 @@ func (m *CollectiveMemory) LinkConcepts(conceptID1, conceptID2 string) {
 
 	if c1, ok := m.concepts[conceptID1]; ok {
 		c1.Relations = append(c1.Relations, conceptID2)
+		m.persist("concept", func(s MemoryStore) error { return s.SaveConcept(c1) })
 	}
 	if c2, ok := m.concepts[conceptID2]; ok {
 		c2.Relations = append(c2.Relations, conceptID1)
+		m.persist("concept", func(s MemoryStore) error { return s.SaveConcept(c2) })
 	}
 }
 
@@ -264,6 +397,7 @@ func (m *CollectiveMemory) AddKnowledge(nodeType, label string, properties map[s
 	}
 
 	m.knowledgeGraph.AddNode(node)
+	m.persist("knowledge node", func(s MemoryStore) error { return s.SaveNode(node) })
 	return node.ID
 }
 
@@ -280,6 +414,27 @@ func (m *CollectiveMemory) ConnectKnowledge(fromID, toID, edgeType string, weigh
 	}
 
 	m.knowledgeGraph.AddEdge(edge)
+	m.persist("knowledge edge", func(s MemoryStore) error { return s.SaveEdge(edge) })
+}
+
+// KnowledgeSnapshot returns copies of all knowledge graph nodes and edges
+func (m *CollectiveMemory) KnowledgeSnapshot() ([]KnowledgeNode, []KnowledgeEdge) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	nodes := make([]KnowledgeNode, 0, len(m.knowledgeGraph.nodes))
+	for _, node := range m.knowledgeGraph.nodes {
+		nodes = append(nodes, *node)
+	}
+
+	var edges []KnowledgeEdge
+	for _, fromEdges := range m.knowledgeGraph.edges {
+		for _, edge := range fromEdges {
+			edges = append(edges, *edge)
+		}
+	}
+
+	return nodes, edges
 }
 
 // CleanupExpiredContexts removes expired contexts
@@ -291,6 +446,7 @@ func (m *CollectiveMemory) CleanupExpiredContexts() {
 	for id, ctx := range m.activeContexts {
 		if ctx.TTL > 0 && now.Sub(ctx.CreatedAt) > ctx.TTL {
 			delete(m.activeContexts, id)
+			m.persist("context", func(s MemoryStore) error { return s.DeleteContext(id) })
 		}
 	}
 }
@@ -308,8 +464,14 @@ func (m *CollectiveMemory) Stats() MemoryStats {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	episodes := len(m.episodes)
+	if m.store != nil {
+		if n, err := m.store.CountEpisodes(); err == nil {
+			episodes = n
+		}
+	}
 	return MemoryStats{
-		EpisodeCount:   len(m.episodes),
+		EpisodeCount:   episodes,
 		ContextCount:   len(m.activeContexts),
 		ConceptCount:   len(m.concepts),
 		KnowledgeNodes: len(m.knowledgeGraph.nodes),
@@ -0,0 +1,105 @@
+package collective
+
+import (
+	"github.com/square-mind/squaremind/pkg/schema"
+)
+
+// MemoryRecordSchemaVersion is the version of the JSON encoding of memory
+// records: episodes, shared contexts, concepts and knowledge graph nodes and
+// edges
+const MemoryRecordSchemaVersion = 1
+
+// MarshalJSON encodes the episode with its schema version
+func (e CollectiveEpisode) MarshalJSON() ([]byte, error) {
+	type plain CollectiveEpisode
+	return schema.Encode(plain(e), MemoryRecordSchemaVersion, e.extra)
+}
+
+// UnmarshalJSON decodes an episode written by any release
+func (e *CollectiveEpisode) UnmarshalJSON(data []byte) error {
+	type plain CollectiveEpisode
+	var p plain
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*e = CollectiveEpisode(p)
+	e.extra = extra
+	return nil
+}
+
+// MarshalJSON encodes the shared context with its schema version
+func (c SharedContext) MarshalJSON() ([]byte, error) {
+	type plain SharedContext
+	return schema.Encode(plain(c), MemoryRecordSchemaVersion, c.extra)
+}
+
+// UnmarshalJSON decodes a shared context written by any release
+func (c *SharedContext) UnmarshalJSON(data []byte) error {
+	type plain SharedContext
+	var p plain
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*c = SharedContext(p)
+	c.extra = extra
+	return nil
+}
+
+// MarshalJSON encodes the concept with its schema version
+func (c Concept) MarshalJSON() ([]byte, error) {
+	type plain Concept
+	return schema.Encode(plain(c), MemoryRecordSchemaVersion, c.extra)
+}
+
+// UnmarshalJSON decodes a concept written by any release
+func (c *Concept) UnmarshalJSON(data []byte) error {
+	type plain Concept
+	var p plain
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*c = Concept(p)
+	c.extra = extra
+	return nil
+}
+
+// MarshalJSON encodes the node with its schema version
+func (n KnowledgeNode) MarshalJSON() ([]byte, error) {
+	type plain KnowledgeNode
+	return schema.Encode(plain(n), MemoryRecordSchemaVersion, n.extra)
+}
+
+// UnmarshalJSON decodes a node written by any release
+func (n *KnowledgeNode) UnmarshalJSON(data []byte) error {
+	type plain KnowledgeNode
+	var p plain
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*n = KnowledgeNode(p)
+	n.extra = extra
+	return nil
+}
+
+// MarshalJSON encodes the edge with its schema version
+func (e KnowledgeEdge) MarshalJSON() ([]byte, error) {
+	type plain KnowledgeEdge
+	return schema.Encode(plain(e), MemoryRecordSchemaVersion, e.extra)
+}
+
+// UnmarshalJSON decodes an edge written by any release
+func (e *KnowledgeEdge) UnmarshalJSON(data []byte) error {
+	type plain KnowledgeEdge
+	var p plain
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*e = KnowledgeEdge(p)
+	e.extra = extra
+	return nil
+}
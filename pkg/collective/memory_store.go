@@ -0,0 +1,360 @@
+package collective
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // Registers the "sqlite3" database/sql driver
+)
+
+// memoryCacheSize bounds the recent episodes CollectiveMemory keeps in memory
+const memoryCacheSize = 1000
+
+// MemorySchemaVersion is the memory database schema this build reads and
+// writes, stored in the database's user_version
+const MemorySchemaVersion = 1
+
+// ErrMemorySchema is returned when opening a memory database created by a
+// newer release
+var ErrMemorySchema = errors.New("unsupported memory database schema")
+
+// MemoryStore persists collective memory so it survives restarts and can
+// hold more episodes than are cached in memory
+type MemoryStore interface {
+	SaveEpisode(ep CollectiveEpisode) error
+	QueryEpisodes(query string) ([]CollectiveEpisode, error) // Case-insensitive content match, oldest first
+	CountEpisodes() (int, error)
+	SaveConcept(c *Concept) error
+	SaveContext(ctx *SharedContext) error
+	DeleteContext(id string) error
+	SaveNode(node *KnowledgeNode) error
+	SaveEdge(edge *KnowledgeEdge) error
+
+	// Load returns the stored concepts, contexts and knowledge graph, and the
+	// most recent episodes (up to limit, oldest first)
+	Load(limit int) (*MemorySnapshot, error)
+
+	Close() error
+}
+
+// MemorySnapshot is the state a MemoryStore restores
+type MemorySnapshot struct {
+	Episodes []CollectiveEpisode
+	Concepts []*Concept
+	Contexts []*SharedContext
+	Nodes    []*KnowledgeNode
+	Edges    []*KnowledgeEdge
+}
+
+// sqliteSchema creates the memory tables and their indexes
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS episodes (
+	id           TEXT PRIMARY KEY,
+	type         TEXT NOT NULL,
+	participants TEXT NOT NULL,
+	content      TEXT NOT NULL,
+	context      TEXT NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	salience     REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS episodes_timestamp ON episodes (timestamp);
+CREATE INDEX IF NOT EXISTS episodes_type ON episodes (type, timestamp);
+
+CREATE TABLE IF NOT EXISTS concepts (
+	id          TEXT PRIMARY KEY,
+	name        TEXT NOT NULL,
+	description TEXT NOT NULL,
+	embedding   TEXT NOT NULL,
+	relations   TEXT NOT NULL,
+	created_by  TEXT NOT NULL,
+	created_at  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS concepts_name ON concepts (name);
+
+CREATE TABLE IF NOT EXISTS contexts (
+	id           TEXT PRIMARY KEY,
+	name         TEXT NOT NULL,
+	data         TEXT NOT NULL,
+	contributors TEXT NOT NULL,
+	created_at   INTEGER NOT NULL,
+	updated_at   INTEGER NOT NULL,
+	ttl          INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS knowledge_nodes (
+	id         TEXT PRIMARY KEY,
+	type       TEXT NOT NULL,
+	label      TEXT NOT NULL,
+	properties TEXT NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS knowledge_nodes_type ON knowledge_nodes (type);
+
+CREATE TABLE IF NOT EXISTS knowledge_edges (
+	from_id    TEXT NOT NULL,
+	to_id      TEXT NOT NULL,
+	type       TEXT NOT NULL,
+	weight     REAL NOT NULL,
+	properties TEXT NOT NULL,
+	PRIMARY KEY (from_id, to_id, type)
+);
+CREATE INDEX IF NOT EXISTS knowledge_edges_to ON knowledge_edges (to_id);
+`
+
+// SQLiteMemoryStore is a MemoryStore backed by an SQLite database. Maps and
+// slices are stored as JSON and times as Unix nanoseconds.
+type SQLiteMemoryStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteMemoryStore opens (creating if needed) a memory database at path
+func OpenSQLiteMemoryStore(path string) (*SQLiteMemoryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("memory store %s: %w", path, err)
+	}
+	if version > MemorySchemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("%w: %s is version %d, this release reads up to %d", ErrMemorySchema, path, version, MemorySchemaVersion)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("memory store %s: %w", path, err)
+	}
+	if version < MemorySchemaVersion {
+		if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, MemorySchemaVersion)); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("memory store %s: %w", path, err)
+		}
+	}
+	return &SQLiteMemoryStore{db: db}, nil
+}
+
+// Backup writes a consistent copy of the database to path, which must not exist
+func (s *SQLiteMemoryStore) Backup(path string) error {
+	_, err := s.db.Exec(`VACUUM INTO ?`, path)
+	return err
+}
+
+// SaveEpisode inserts or replaces an episode
+func (s *SQLiteMemoryStore) SaveEpisode(ep CollectiveEpisode) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO episodes (id, type, participants, content, context, timestamp, salience) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		ep.ID, ep.Type, toJSON(ep.Participants), ep.Content, toJSON(ep.Context), ep.Timestamp.UnixNano(), ep.Salience)
+	return err
+}
+
+// QueryEpisodes returns the episodes whose content contains query
+func (s *SQLiteMemoryStore) QueryEpisodes(query string) ([]CollectiveEpisode, error) {
+	rows, err := s.db.Query(`SELECT id, type, participants, content, context, timestamp, salience FROM episodes
+		WHERE instr(lower(content), lower(?)) > 0 ORDER BY timestamp`, query)
+	if err != nil {
+		return nil, err
+	}
+	return scanEpisodes(rows)
+}
+
+// CountEpisodes returns the number of stored episodes
+func (s *SQLiteMemoryStore) CountEpisodes() (int, error) {
+	var n int
+	err := s.db.QueryRow(`SELECT count(*) FROM episodes`).Scan(&n)
+	return n, err
+}
+
+// SaveConcept inserts or replaces a concept
+func (s *SQLiteMemoryStore) SaveConcept(c *Concept) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO concepts (id, name, description, embedding, relations, created_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.ID, c.Name, c.Description, toJSON(c.Embedding), toJSON(c.Relations), c.CreatedBy, c.CreatedAt.UnixNano())
+	return err
+}
+
+// SaveContext inserts or replaces a shared context
+func (s *SQLiteMemoryStore) SaveContext(ctx *SharedContext) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO contexts (id, name, data, contributors, created_at, updated_at, ttl) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		ctx.ID, ctx.Name, toJSON(ctx.Data), toJSON(ctx.Contributors), ctx.CreatedAt.UnixNano(), ctx.UpdatedAt.UnixNano(), int64(ctx.TTL))
+	return err
+}
+
+// DeleteContext removes a shared context
+func (s *SQLiteMemoryStore) DeleteContext(id string) error {
+	_, err := s.db.Exec(`DELETE FROM contexts WHERE id = ?`, id)
+	return err
+}
+
+// SaveNode inserts or replaces a knowledge graph node
+func (s *SQLiteMemoryStore) SaveNode(node *KnowledgeNode) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO knowledge_nodes (id, type, label, properties, created_at) VALUES (?, ?, ?, ?, ?)`,
+		node.ID, node.Type, node.Label, toJSON(node.Properties), node.CreatedAt.UnixNano())
+	return err
+}
+
+// SaveEdge inserts or replaces a knowledge graph edge
+func (s *SQLiteMemoryStore) SaveEdge(edge *KnowledgeEdge) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO knowledge_edges (from_id, to_id, type, weight, properties) VALUES (?, ?, ?, ?, ?)`,
+		edge.From, edge.To, edge.Type, edge.Weight, toJSON(edge.Properties))
+	return err
+}
+
+// Load reads the stored memory
+func (s *SQLiteMemoryStore) Load(limit int) (*MemorySnapshot, error) {
+	snapshot := &MemorySnapshot{}
+
+	rows, err := s.db.Query(`SELECT id, type, participants, content, context, timestamp, salience FROM
+		(SELECT * FROM episodes ORDER BY timestamp DESC LIMIT ?) ORDER BY timestamp`, limit)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.Episodes, err = scanEpisodes(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(`SELECT id, name, description, embedding, relations, created_by, created_at FROM concepts`)
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(rows, func() error {
+		var (
+			c                    Concept
+			embedding, relations string
+			createdAt            int64
+		)
+		if err := rows.Scan(&c.ID, &c.Name, &c.Description, &embedding, &relations, &c.CreatedBy, &createdAt); err != nil {
+			return err
+		}
+		c.CreatedAt = time.Unix(0, createdAt)
+		snapshot.Concepts = append(snapshot.Concepts, &c)
+		return fromJSON(embedding, &c.Embedding, relations, &c.Relations)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(`SELECT id, name, data, contributors, created_at, updated_at, ttl FROM contexts`)
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(rows, func() error {
+		var (
+			ctx                            SharedContext
+			data, contributors             string
+			createdAt, updatedAt, ttlNanos int64
+		)
+		if err := rows.Scan(&ctx.ID, &ctx.Name, &data, &contributors, &createdAt, &updatedAt, &ttlNanos); err != nil {
+			return err
+		}
+		ctx.CreatedAt, ctx.UpdatedAt, ctx.TTL = time.Unix(0, createdAt), time.Unix(0, updatedAt), time.Duration(ttlNanos)
+		snapshot.Contexts = append(snapshot.Contexts, &ctx)
+		return fromJSON(data, &ctx.Data, contributors, &ctx.Contributors)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(`SELECT id, type, label, properties, created_at FROM knowledge_nodes`)
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(rows, func() error {
+		var (
+			node       KnowledgeNode
+			properties string
+			createdAt  int64
+		)
+		if err := rows.Scan(&node.ID, &node.Type, &node.Label, &properties, &createdAt); err != nil {
+			return err
+		}
+		node.CreatedAt = time.Unix(0, createdAt)
+		snapshot.Nodes = append(snapshot.Nodes, &node)
+		return fromJSON(properties, &node.Properties)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(`SELECT from_id, to_id, type, weight, properties FROM knowledge_edges`)
+	if err != nil {
+		return nil, err
+	}
+	err = scanRows(rows, func() error {
+		var (
+			edge       KnowledgeEdge
+			properties string
+		)
+		if err := rows.Scan(&edge.From, &edge.To, &edge.Type, &edge.Weight, &properties); err != nil {
+			return err
+		}
+		snapshot.Edges = append(snapshot.Edges, &edge)
+		return fromJSON(properties, &edge.Properties)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Close closes the database
+func (s *SQLiteMemoryStore) Close() error {
+	return s.db.Close()
+}
+
+// scanEpisodes reads episode rows
+func scanEpisodes(rows *sql.Rows) ([]CollectiveEpisode, error) {
+	var episodes []CollectiveEpisode
+	err := scanRows(rows, func() error {
+		var (
+			ep                    CollectiveEpisode
+			participants, context string
+			timestamp             int64
+		)
+		if err := rows.Scan(&ep.ID, &ep.Type, &participants, &ep.Content, &context, &timestamp, &ep.Salience); err != nil {
+			return err
+		}
+		ep.Timestamp = time.Unix(0, timestamp)
+		episodes = append(episodes, ep)
+		return fromJSON(participants, &episodes[len(episodes)-1].Participants, context, &episodes[len(episodes)-1].Context)
+	})
+	return episodes, err
+}
+
+// scanRows calls scan for each row and closes rows
+func scanRows(rows *sql.Rows, scan func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// toJSON encodes a column value. Values JSON can't represent are stored as null.
+func toJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+// fromJSON decodes pairs of column text and destination
+func fromJSON(pairs ...interface{}) error {
+	for i := 0; i < len(pairs); i += 2 {
+		if err := json.Unmarshal([]byte(pairs[i].(string)), pairs[i+1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package collective
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+func TestSQLiteMemoryStore_Restore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.db")
+	store, err := OpenSQLiteMemoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteMemoryStore failed: %v", err)
+	}
+	m, err := NewPersistentMemory(store)
+	if err != nil {
+		t.Fatalf("NewPersistentMemory failed: %v", err)
+	}
+
+	m.Contribute("agent-1", "Cache invalidation is Hard", map[string]interface{}{"task": "t1"})
+	m.Contribute("agent-2", "Naming things", nil)
+	go1 := m.AddConcept("goroutine", "lightweight thread", "agent-1")
+	ch := m.AddConcept("channel", "typed conduit", "agent-1")
+	m.LinkConcepts(go1.ID, ch.ID)
+	shared := m.CreateContext("design", "agent-1", time.Hour)
+	m.UpdateContext(shared.ID, "agent-2", "decision", "use LRU")
+	expired := m.CreateContext("scratch", "agent-1", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	m.CleanupExpiredContexts()
+	n1 := m.AddKnowledge("service", "api", map[string]interface{}{"port": 8080.0})
+	n2 := m.AddKnowledge("service", "db", nil)
+	m.ConnectKnowledge(n1, n2, "depends_on", 0.9)
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	store, err = OpenSQLiteMemoryStore(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	restored, err := NewPersistentMemory(store)
+	if err != nil {
+		t.Fatalf("NewPersistentMemory failed: %v", err)
+	}
+	defer restored.Close()
+
+	stats := restored.Stats()
+	if stats.EpisodeCount != 2 || stats.ConceptCount != 2 || stats.ContextCount != 1 || stats.KnowledgeNodes != 2 {
+		t.Errorf("Expected 2 episodes, 2 concepts, 1 context and 2 nodes, got %+v", stats)
+	}
+
+	results := restored.Query("cache invalidation")
+	if len(results) != 1 || results[0].Participants[0] != "agent-1" || results[0].Context["task"] != "t1" {
+		t.Errorf("Expected case-insensitive match on the restored episode, got %+v", results)
+	}
+	if c := restored.GetConcept(go1.ID); c == nil || len(c.Relations) != 1 || c.Relations[0] != ch.ID {
+		t.Errorf("Expected restored concept relations, got %+v", c)
+	}
+	if ctx := restored.GetContext(shared.ID); ctx == nil || ctx.Data["decision"] != "use LRU" || len(ctx.Contributors) != 2 || ctx.TTL != time.Hour {
+		t.Errorf("Expected restored context, got %+v", ctx)
+	}
+	if restored.GetContext(expired.ID) != nil {
+		t.Error("Expected expired context to stay deleted")
+	}
+	nodes, edges := restored.KnowledgeSnapshot()
+	if len(nodes) != 2 || len(edges) != 1 || edges[0].Weight != 0.9 {
+		t.Errorf("Expected restored knowledge graph, got %d nodes and %+v", len(nodes), edges)
+	}
+}
+
+func TestDocumentMemoryStore_Restore(t *testing.T) {
+	docs := storage.NewMemory().Documents()
+	m, err := NewPersistentMemory(NewDocumentMemoryStore(docs))
+	if err != nil {
+		t.Fatalf("NewPersistentMemory failed: %v", err)
+	}
+
+	m.Contribute("agent-1", "Cache invalidation is Hard", map[string]interface{}{"task": "t1"})
+	time.Sleep(time.Millisecond)
+	m.Contribute("agent-2", "Naming things", nil)
+	goroutine := m.AddConcept("goroutine", "lightweight thread", "agent-1")
+	shared := m.CreateContext("design", "agent-1", time.Hour)
+	m.UpdateContext(shared.ID, "agent-2", "decision", "use LRU")
+	n1 := m.AddKnowledge("service", "api", nil)
+	n2 := m.AddKnowledge("service", "db", nil)
+	m.ConnectKnowledge(n1, n2, "depends_on", 0.9)
+
+	restored, err := NewPersistentMemory(NewDocumentMemoryStore(docs))
+	if err != nil {
+		t.Fatalf("NewPersistentMemory failed: %v", err)
+	}
+	stats := restored.Stats()
+	if stats.EpisodeCount != 2 || stats.ConceptCount != 1 || stats.ContextCount != 1 || stats.KnowledgeNodes != 2 {
+		t.Errorf("Expected 2 episodes, 1 concept, 1 context and 2 nodes, got %+v", stats)
+	}
+	if results := restored.Query("cache invalidation"); len(results) != 1 || results[0].Participants[0] != "agent-1" {
+		t.Errorf("Expected case-insensitive match on the restored episode, got %+v", results)
+	}
+	if c := restored.GetConcept(goroutine.ID); c == nil || c.Name != "goroutine" {
+		t.Errorf("Expected restored concept, got %+v", c)
+	}
+	if ctx := restored.GetContext(shared.ID); ctx == nil || ctx.Data["decision"] != "use LRU" {
+		t.Errorf("Expected restored context, got %+v", ctx)
+	}
+	if _, edges := restored.KnowledgeSnapshot(); len(edges) != 1 || edges[0].Type != "depends_on" {
+		t.Errorf("Expected restored edge, got %+v", edges)
+	}
+
+	// Load keeps the most recent episodes
+	snapshot, err := NewDocumentMemoryStore(docs).Load(1)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(snapshot.Episodes) != 1 || snapshot.Episodes[0].Content != "Naming things" {
+		t.Errorf("Expected the latest episode, got %+v", snapshot.Episodes)
+	}
+}
+
+func TestCollectiveMemory_Search(t *testing.T) {
+	m := NewCollectiveMemory()
+	m.Contribute("sq-1", "Cache invalidation happens on write", nil)
+	m.Contribute("sq-2", "The dashboard polls every three seconds", nil)
+	m.Contribute("sq-3", "Use an LRU cache for sessions", nil)
+	m.AddConcept("eviction", "How a cache chooses entries to drop", "sq-1")
+
+	results := m.Search("add a cache for user sessions", 2)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Content != "Use an LRU cache for sessions" {
+		t.Errorf("Expected the closest episode first, got %q", results[0].Content)
+	}
+	for _, r := range m.Search("add a cache for user sessions", 0) {
+		if r.Content == "The dashboard polls every three seconds" {
+			t.Error("Expected an unrelated episode to be left out")
+		}
+	}
+
+	concepts := m.Search("cache eviction", 0)
+	found := false
+	for _, r := range concepts {
+		found = found || r.Source == "concept"
+	}
+	if !found {
+		t.Errorf("Expected the eviction concept among %+v", concepts)
+	}
+}
+
+func TestSQLiteMemoryStore_SchemaVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memory.db")
+	store, err := OpenSQLiteMemoryStore(path)
+	if err != nil {
+		t.Fatalf("OpenSQLiteMemoryStore failed: %v", err)
+	}
+
+	var version int
+	if err := store.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatalf("Reading schema version failed: %v", err)
+	}
+	if version != MemorySchemaVersion {
+		t.Errorf("Expected schema version %d, got %d", MemorySchemaVersion, version)
+	}
+
+	// A database from a newer release is refused rather than misread
+	if _, err := store.db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, MemorySchemaVersion+1)); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if _, err := OpenSQLiteMemoryStore(path); !errors.Is(err, ErrMemorySchema) {
+		t.Errorf("Expected ErrMemorySchema, got %v", err)
+	}
+}
+
+func TestMemoryRecords_JSONCompatibility(t *testing.T) {
+	// A concept from a newer release, with a field this one doesn't know
+	var concept Concept
+	data := []byte(`{"schema_version":2,"id":"c1","name":"retry","relations":["c2"],"confidence":0.9}`)
+	if err := json.Unmarshal(data, &concept); err != nil {
+		t.Fatalf("Failed to decode concept: %v", err)
+	}
+	if concept.Name != "retry" || len(concept.Relations) != 1 {
+		t.Errorf("Expected known fields decoded, got %+v", concept)
+	}
+
+	out, err := json.Marshal(concept)
+	if err != nil {
+		t.Fatalf("Failed to encode concept: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(out, &fields); err != nil {
+		t.Fatalf("Failed to decode fields: %v", err)
+	}
+	if string(fields["confidence"]) != "0.9" {
+		t.Errorf("Expected unknown field preserved, got %s", out)
+	}
+	if string(fields["schema_version"]) != fmt.Sprint(MemoryRecordSchemaVersion) {
+		t.Errorf("Expected schema version %d, got %s", MemoryRecordSchemaVersion, fields["schema_version"])
+	}
+
+	// Episodes written before versioning decode as before
+	var episode CollectiveEpisode
+	if err := json.Unmarshal([]byte(`{"id":"e1","type":"task_completed","salience":0.5}`), &episode); err != nil {
+		t.Fatalf("Failed to decode episode: %v", err)
+	}
+	if episode.ID != "e1" || episode.Salience != 0.5 {
+		t.Errorf("Expected legacy episode decoded, got %+v", episode)
+	}
+}
@@ -0,0 +1,239 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+)
+
+var (
+	ErrInvalidParameter  = errors.New("invalid parameter change")
+	ErrParameterRejected = errors.New("parameter change rejected by consensus")
+)
+
+// Parameters are the collective settings that change only by consensus.
+// In a change, zero values leave a parameter as it is.
+type Parameters struct {
+	ConsensusThreshold float64       `json:"consensus_threshold,omitempty"`
+	MaxAgents          int           `json:"max_agents,omitempty"`
+	BidTimeout         time.Duration `json:"bid_timeout,omitempty"`
+	MinBidScore        float64       `json:"min_bid_score,omitempty"` // Capability score needed to bid
+}
+
+// IsZero reports whether a change sets no parameter
+func (p Parameters) IsZero() bool {
+	return p == Parameters{}
+}
+
+// ParameterVoter decides how an agent votes on a proposed parameter change
+type ParameterVoter func(voter *agent.Agent, current, change Parameters) bool
+
+// DefaultParameterVoter approves every change
+func DefaultParameterVoter(voter *agent.Agent, current, change Parameters) bool {
+	return true
+}
+
+// ParameterProposal is the outcome of a parameter change proposal
+type ParameterProposal struct {
+	ProposalID string     `json:"proposal_id"`
+	Proposer   string     `json:"proposer"`
+	Change     Parameters `json:"change"`
+	Previous   Parameters `json:"previous"`
+	Accepted   bool       `json:"accepted"`
+	Result     string     `json:"result"` // accepted, rejected or timeout
+	For        int        `json:"for"`
+	Against    int        `json:"against"`
+}
+
+// SetParameterVoter overrides how agents vote on parameter changes
+func (c *Collective) SetParameterVoter(voter ParameterVoter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parameterVoter = voter
+}
+
+// Parameters returns the collective's current consensus-gated settings
+func (c *Collective) Parameters() Parameters {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Parameters{
+		ConsensusThreshold: c.config.ConsensusThreshold,
+		MaxAgents:          c.config.MaxAgents,
+		BidTimeout:         c.market.BidTimeout(),
+		MinBidScore:        c.market.BidThreshold().Min,
+	}
+}
+
+// ProposeParameters puts a parameter change to the collective's agents. Each
+// votes by the ParameterVoter, weighted by reputation; if the change reaches
+// the consensus threshold, the accept callback of the consensus engine
+// applies all of it at once before this returns. A rejected change fails
+// with ErrParameterRejected and leaves every parameter as it was. With no
+// agents, nobody can object and the change is applied.
+func (c *Collective) ProposeParameters(ctx context.Context, proposer string, change Parameters) (*ParameterProposal, error) {
+	if err := c.validateParameters(change); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	voter := c.parameterVoter
+	c.mu.RUnlock()
+	current := c.Parameters()
+	agents := c.agentMap()
+
+	round, err := c.consensus.Propose(ctx, proposer, coordination.ConsensusTypeParameterChange, map[string]interface{}{
+		"change":   change,
+		"previous": current,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The accept callback signals once it has applied the change
+	applied := make(chan struct{})
+	c.mu.Lock()
+	c.parameterWaits[round.Proposal.ID] = applied
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.parameterWaits, round.Proposal.ID)
+		c.mu.Unlock()
+	}()
+	proposal := &ParameterProposal{
+		ProposalID: round.Proposal.ID,
+		Proposer:   proposer,
+		Change:     change,
+		Previous:   current,
+	}
+
+	weights := make(map[string]float64, len(agents))
+	for sid, a := range agents {
+		weight := 50.0 // Default
+		if rep := c.reputation.Get(sid); rep != nil {
+			weight = rep.Overall
+		}
+		weights[sid] = weight
+		if sid == proposer {
+			continue // Voted for its own proposal
+		}
+		_ = c.consensus.SubmitVote(coordination.Vote{
+			AgentSID:   sid,
+			ProposalID: round.Proposal.ID,
+			Value:      voter(a, current, change),
+		})
+	}
+
+	accepted, result := c.consensus.CheckWeightedConsensus(round.Proposal.ID, weights)
+	proposal.Accepted, proposal.Result = accepted, result
+	if decided := c.consensus.GetRound(round.Proposal.ID); decided != nil {
+		for sid, vote := range decided.Votes {
+			if _, eligible := weights[sid]; !eligible {
+				continue
+			}
+			if vote.Value {
+				proposal.For++
+			} else {
+				proposal.Against++
+			}
+		}
+	}
+	if !accepted {
+		c.logger.Info("parameter change rejected", "proposal", proposal.ProposalID, "proposer", proposer, "for", proposal.For, "against", proposal.Against)
+		return proposal, fmt.Errorf("%w: %d for, %d against", ErrParameterRejected, proposal.For, proposal.Against)
+	}
+
+	select {
+	case <-applied:
+		return proposal, nil
+	case <-ctx.Done():
+		return proposal, ctx.Err()
+	}
+}
+
+// validateParameters checks a change is one the collective can apply
+func (c *Collective) validateParameters(change Parameters) error {
+	if change.IsZero() {
+		return fmt.Errorf("%w: no parameter set", ErrInvalidParameter)
+	}
+	if change.ConsensusThreshold < 0 || change.ConsensusThreshold > 1 {
+		return fmt.Errorf("%w: consensus_threshold must be between 0 and 1", ErrInvalidParameter)
+	}
+	if change.BidTimeout < 0 {
+		return fmt.Errorf("%w: bid_timeout must be positive", ErrInvalidParameter)
+	}
+	if change.MinBidScore < 0 || change.MinBidScore > 1 {
+		return fmt.Errorf("%w: min_bid_score must be between 0 and 1", ErrInvalidParameter)
+	}
+	if change.MaxAgents < 0 {
+		return fmt.Errorf("%w: max_agents must be positive", ErrInvalidParameter)
+	}
+	if size := c.Size(); change.MaxAgents > 0 && change.MaxAgents < size {
+		return fmt.Errorf("%w: max_agents %d is below the current %d agents", ErrInvalidParameter, change.MaxAgents, size)
+	}
+	return nil
+}
+
+// onProposalAccepted is the consensus engine's accept callback. It applies
+// accepted parameter changes; other proposals are acted on by whoever made
+// them.
+func (c *Collective) onProposalAccepted(p *coordination.Proposal) {
+	if p.Type != coordination.ConsensusTypeParameterChange {
+		return
+	}
+	change, ok := p.Data["change"].(Parameters)
+	if !ok {
+		return
+	}
+	c.applyParameters(p.ID, change)
+
+	c.logger.Info("parameters changed", "proposal", p.ID, "proposer", p.Proposer,
+		"consensus_threshold", change.ConsensusThreshold, "max_agents", change.MaxAgents, "bid_timeout", change.BidTimeout, "min_bid_score", change.MinBidScore)
+	c.events.Publish(Event{
+		Type:     EventParametersChanged,
+		AgentSID: p.Proposer,
+		Data: map[string]interface{}{
+			"proposal":   p.ID,
+			"change":     change,
+			"parameters": c.Parameters(),
+		},
+	})
+}
+
+// applyParameters sets the non-zero parameters of a change together and
+// tells the proposer it has. The bid timeout and minimum bid score apply to
+// the markets of teams too; teams keep their own consensus thresholds.
+func (c *Collective) applyParameters(proposalID string, change Parameters) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if applied, ok := c.parameterWaits[proposalID]; ok {
+		defer close(applied)
+	}
+
+	if change.ConsensusThreshold > 0 {
+		c.config.ConsensusThreshold = change.ConsensusThreshold
+		c.consensus.SetThreshold(change.ConsensusThreshold)
+	}
+	if change.MaxAgents > 0 {
+		c.config.MaxAgents = change.MaxAgents
+	}
+	if change.BidTimeout > 0 {
+		c.market.SetBidTimeout(change.BidTimeout)
+		for _, team := range c.teams {
+			team.market.SetBidTimeout(change.BidTimeout)
+		}
+	}
+	if change.MinBidScore > 0 {
+		threshold := c.market.BidThreshold()
+		threshold.Min = change.MinBidScore
+		threshold.Floor = min(threshold.Floor, threshold.Min)
+		_ = c.market.SetBidThreshold(threshold)
+		for _, team := range c.teams {
+			_ = team.market.SetBidThreshold(threshold)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// ErrDraining is returned for tasks submitted while the collective drains
+var ErrDraining = errors.New("collective is draining: not accepting tasks")
+
+// RunMode is whether the collective is dispatching tasks
+type RunMode string
+
+const (
+	ModeRunning     RunMode = "running"
+	ModePaused      RunMode = "paused"      // Submissions queue; tasks already assigned run to completion
+	ModeMaintenance RunMode = "maintenance" // Paused, with schedules and capability gap events suspended too
+	ModeDraining    RunMode = "draining"    // Paused, refusing new submissions, e.g. before shutting down
+)
+
+// ModeStatus describes the collective's run mode
+type ModeStatus struct {
+	Mode     RunMode   `json:"mode"`
+	Reason   string    `json:"reason,omitempty"`
+	Since    time.Time `json:"since"`
+	Held     int       `json:"held"`      // Submissions waiting to be dispatched
+	InFlight int       `json:"in_flight"` // Assigned tasks still running
+}
+
+// Pause stops dispatching tasks, for deploys or incident response.
+// Submissions are accepted and wait; tasks already assigned finish.
+func (c *Collective) Pause(reason string) {
+	c.setMode(ModePaused, reason)
+}
+
+// EnterMaintenance pauses the collective and also suspends its schedules and
+// the capability gap events autoscalers act on
+func (c *Collective) EnterMaintenance(reason string) {
+	c.setMode(ModeMaintenance, reason)
+}
+
+// Drain pauses the collective and refuses new submissions with ErrDraining,
+// so it can be stopped once the tasks already assigned have finished
+func (c *Collective) Drain(reason string) {
+	c.setMode(ModeDraining, reason)
+}
+
+// Resume dispatches the held submissions and returns to normal operation
+func (c *Collective) Resume() {
+	c.setMode(ModeRunning, "")
+}
+
+// Mode returns the collective's run mode. A paused collective has drained
+// once InFlight reaches zero.
+func (c *Collective) Mode() ModeStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := c.mode
+	status.Held = c.held
+	status.InFlight = len(c.activeTasks)
+	return status
+}
+
+// InMaintenance reports whether the collective is in maintenance mode
+func (c *Collective) InMaintenance() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mode.Mode == ModeMaintenance
+}
+
+// setMode switches the run mode, releasing held submissions on resume
+func (c *Collective) setMode(mode RunMode, reason string) {
+	c.mu.Lock()
+	previous := c.mode.Mode
+	if previous == mode && c.mode.Reason == reason {
+		c.mu.Unlock()
+		return
+	}
+	if previous != mode {
+		c.mode.Since = time.Now()
+	}
+	c.mode.Mode = mode
+	c.mode.Reason = reason
+
+	switch {
+	case mode == ModeRunning && c.resumed != nil:
+		close(c.resumed)
+		c.resumed = nil
+	case mode != ModeRunning && c.resumed == nil:
+		c.resumed = make(chan struct{})
+	}
+	c.mu.Unlock()
+
+	c.log().Info("collective mode changed", "mode", mode, "previous", previous, "reason", reason)
+	c.events.Publish(Event{
+		Type: EventModeChanged,
+		Data: map[string]interface{}{
+			"mode":     string(mode),
+			"previous": string(previous),
+			"reason":   reason,
+		},
+	})
+}
+
+// accepting returns ErrDraining while the collective drains
+func (c *Collective) accepting() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.mode.Mode == ModeDraining {
+		return ErrDraining
+	}
+	return nil
+}
+
+// PauseAgent stops an idle agent bidding on and taking tasks until
+// ResumeAgent; tasks queued for it wait. It fails with
+// agent.ErrInvalidTransition while the agent is working.
+func (c *Collective) PauseAgent(sid string) error {
+	a, ok := c.GetAgent(sid)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, sid)
+	}
+	if err := a.Pause(); err != nil {
+		return err
+	}
+	c.log().Info("agent paused", "agent", sid)
+	c.events.Publish(Event{Type: EventAgentPaused, AgentSID: sid})
+	return nil
+}
+
+// ResumeAgent returns an agent paused by PauseAgent to work
+func (c *Collective) ResumeAgent(sid string) error {
+	a, ok := c.GetAgent(sid)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, sid)
+	}
+	if err := a.Resume(); err != nil {
+		return err
+	}
+	c.log().Info("agent resumed", "agent", sid)
+	c.events.Publish(Event{Type: EventAgentResumed, AgentSID: sid})
+	return nil
+}
+
+// awaitResume waits while the collective is paused. Returns ctx's error if
+// it ends first.
+func (c *Collective) awaitResume(ctx context.Context, task *agent.Task) error {
+	recorded := false
+	for {
+		c.mu.Lock()
+		resumed := c.resumed
+		if resumed == nil {
+			c.mu.Unlock()
+			return nil
+		}
+		c.held++
+		c.mu.Unlock()
+
+		if !recorded {
+			c.timelines.Record(task.ID, StageWaiting, "", "collective is paused")
+			recorded = true
+		}
+
+		var err error
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		c.mu.Lock()
+		c.held--
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
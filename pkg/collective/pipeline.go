@@ -0,0 +1,314 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+var (
+	ErrInvalidPipeline = errors.New("invalid swarm pipeline")
+	ErrPipelineBudget  = errors.New("swarm pipeline token budget exhausted")
+)
+
+// SwarmPipeline is a declarative swarm: the roles to staff it with and the
+// phases they work through. Each role becomes a team of agents, and each
+// phase a task for its role's team that runs once the phases it depends on
+// have completed. The pipeline's result is the output of its last phase.
+//
+//	name: review-pipeline
+//	token_budget: 50000
+//	roles:
+//	  - {name: coder, capabilities: [code.write]}
+//	  - {name: reviewer, capabilities: [code.review, security]}
+//	phases:
+//	  - id: implement
+//	    role: coder
+//	    prompt: "Implement: {{.Params.task}}"
+//	  - id: review
+//	    role: reviewer
+//	    prompt: "Review this implementation for defects"
+//	    depends_on: [implement]
+//	    max_tokens: 2000
+type SwarmPipeline struct {
+	Name        string       `yaml:"name" json:"name"`
+	Description string       `yaml:"description,omitempty" json:"description,omitempty"`
+	Roles       []SwarmRole  `yaml:"roles" json:"roles"`
+	Phases      []SwarmPhase `yaml:"phases" json:"phases"`
+	Output      string       `yaml:"output,omitempty" json:"output,omitempty"`             // Phase whose output is the result (default: the last)
+	TokenBudget int          `yaml:"token_budget,omitempty" json:"token_budget,omitempty"` // Tokens all phases may spend together (0 = unlimited)
+}
+
+// SwarmRole is a kind of agent a pipeline is staffed with
+type SwarmRole struct {
+	Name         string                    `yaml:"name" json:"name"`
+	Description  string                    `yaml:"description,omitempty" json:"description,omitempty"`
+	Capabilities []identity.CapabilityType `yaml:"capabilities" json:"capabilities"`
+	Model        string                    `yaml:"model,omitempty" json:"model,omitempty"` // LLM model (empty = default)
+	Count        int                       `yaml:"count,omitempty" json:"count,omitempty"` // Agents to spawn (0 = 1)
+}
+
+// SwarmPhase is a step of a pipeline. Prompt is a workflow template: the
+// pipeline's task is {{.Params.task}} and an earlier phase's output is
+// {{(index .Steps "id").Output}}. The outputs of the phases it depends on
+// are appended to the prompt either way.
+type SwarmPhase struct {
+	ID         string   `yaml:"id" json:"id"`
+	Role       string   `yaml:"role" json:"role"`
+	Prompt     string   `yaml:"prompt,omitempty" json:"prompt,omitempty"` // Default: the pipeline's task
+	DependsOn  []string `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Complexity string   `yaml:"complexity,omitempty" json:"complexity,omitempty"`
+	MaxTokens  int      `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"` // Limit on the phase's LLM response (0 = provider default)
+	Retries    int      `yaml:"retries,omitempty" json:"retries,omitempty"`
+}
+
+// ParseSwarmPipeline reads a pipeline definition from YAML (or JSON) and validates it
+func ParseSwarmPipeline(data []byte) (*SwarmPipeline, error) {
+	var p SwarmPipeline
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPipeline, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// LoadSwarmPipeline reads and validates a pipeline definition file
+func LoadSwarmPipeline(path string) (*SwarmPipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParseSwarmPipeline(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate checks that roles are named uniquely and phases name a role and
+// form a valid workflow
+func (p *SwarmPipeline) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPipeline)
+	}
+	if len(p.Roles) == 0 {
+		return fmt.Errorf("%w: %s has no roles", ErrInvalidPipeline, p.Name)
+	}
+	if p.TokenBudget < 0 {
+		return fmt.Errorf("%w: negative token_budget", ErrInvalidPipeline)
+	}
+
+	roles := make(map[string]bool, len(p.Roles))
+	for _, role := range p.Roles {
+		switch {
+		case role.Name == "":
+			return fmt.Errorf("%w: every role needs a name", ErrInvalidPipeline)
+		case roles[role.Name]:
+			return fmt.Errorf("%w: duplicate role %q", ErrInvalidPipeline, role.Name)
+		case role.Count < 0:
+			return fmt.Errorf("%w: role %q has a negative count", ErrInvalidPipeline, role.Name)
+		}
+		roles[role.Name] = true
+	}
+	for _, phase := range p.Phases {
+		if !roles[phase.Role] {
+			return fmt.Errorf("%w: phase %q has unknown role %q", ErrInvalidPipeline, phase.ID, phase.Role)
+		}
+	}
+	if p.Output != "" && !p.hasPhase(p.Output) {
+		return fmt.Errorf("%w: output phase %q does not exist", ErrInvalidPipeline, p.Output)
+	}
+
+	// Phase ids, dependencies and limits are checked as a workflow
+	if _, err := p.Workflow(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// hasPhase reports whether the pipeline has a phase with the given id
+func (p *SwarmPipeline) hasPhase(id string) bool {
+	for _, phase := range p.Phases {
+		if phase.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Workflow converts the pipeline into a workflow with a step per phase, run
+// by the team of the phase's role. The task is passed as the "task" param.
+func (p *SwarmPipeline) Workflow() (*workflow.Workflow, error) {
+	wf := &workflow.Workflow{
+		Name:        p.Name,
+		Description: p.Description,
+	}
+	for _, phase := range p.Phases {
+		var b strings.Builder
+		if phase.Prompt != "" {
+			b.WriteString(phase.Prompt)
+			b.WriteString("\n\nThis is part of a larger task: {{.Params.task}}")
+		} else {
+			b.WriteString("{{.Params.task}}")
+		}
+		if len(phase.DependsOn) > 0 {
+			b.WriteString("\n\nResults of the phases this builds on:")
+			for _, dep := range phase.DependsOn {
+				fmt.Fprintf(&b, "\n\n=== %s ===\n{{(index .Steps %q).Output}}", literal(dep), dep)
+			}
+		}
+
+		wf.Steps = append(wf.Steps, workflow.Step{
+			ID:         phase.ID,
+			Task:       b.String(),
+			Requires:   p.role(phase.Role).Capabilities,
+			Complexity: phase.Complexity,
+			Team:       phase.Role,
+			DependsOn:  phase.DependsOn,
+			Retries:    phase.Retries,
+			MaxTokens:  phase.MaxTokens,
+		})
+	}
+	if p.Output != "" {
+		wf.Output = fmt.Sprintf("{{(index .Steps %q).Output}}", p.Output)
+	} else if len(p.Phases) > 0 {
+		wf.Output = fmt.Sprintf("{{(index .Steps %q).Output}}", p.Phases[len(p.Phases)-1].ID)
+	}
+
+	if err := wf.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPipeline, err)
+	}
+	return wf, nil
+}
+
+// role returns the role with the given name
+func (p *SwarmPipeline) role(name string) SwarmRole {
+	for _, role := range p.Roles {
+		if role.Name == name {
+			return role
+		}
+	}
+	return SwarmRole{}
+}
+
+// Plan describes the pipeline's phases as a swarm plan
+func (p *SwarmPipeline) Plan() *SwarmPlan {
+	plan := &SwarmPlan{}
+	for _, phase := range p.Phases {
+		plan.Subtasks = append(plan.Subtasks, Subtask{
+			ID:         phase.ID,
+			Task:       phase.Prompt,
+			Requires:   p.role(phase.Role).Capabilities,
+			Complexity: phase.Complexity,
+			DependsOn:  phase.DependsOn,
+		})
+	}
+	return plan
+}
+
+// JoinRole adds an agent to the collective, if it isn't a member yet, and
+// to the team of a pipeline role, creating the team on first use
+func (c *Collective) JoinRole(role string, a *agent.Agent) error {
+	if _, ok := c.GetAgent(a.Identity.SID); !ok {
+		if err := c.Join(a); err != nil {
+			return err
+		}
+	}
+	if _, ok := c.GetTeam(role); !ok {
+		if _, err := c.CreateTeam(role, TeamConfig{}); err != nil && !errors.Is(err, ErrTeamExists) {
+			return err
+		}
+	}
+	return c.AddToTeam(role, a.Identity.SID)
+}
+
+// ExecutePipeline runs a pipeline's phases for a task on the teams of its
+// roles, which must already be staffed (see JoinRole). If a phase fails or
+// the token budget runs out, the partial run is returned with the error.
+// With a checkpoint store, the run's progress is saved under the task's ID.
+func (c *Collective) ExecutePipeline(ctx context.Context, p *SwarmPipeline, task *agent.Task) (*SwarmResult, error) {
+	return c.executePipeline(ctx, p, task, newCheckpoint(task, p))
+}
+
+// executePipeline runs the phases of a pipeline that cp hasn't recorded as
+// done. Tokens spent before a resume count against the budget.
+func (c *Collective) executePipeline(ctx context.Context, p *SwarmPipeline, task *agent.Task, cp *SwarmCheckpoint) (*SwarmResult, error) {
+	wf, err := p.Workflow()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range p.Roles {
+		if team, ok := c.GetTeam(role.Name); !ok || team.Size() == 0 {
+			return nil, fmt.Errorf("%w: no agents in role %q", ErrInvalidPipeline, role.Name)
+		}
+	}
+
+	budget := &budgetedSubmitter{collective: c, budget: p.TokenBudget, spent: cp.TokensUsed}
+	engine := c.swarmEngine(budget, cp, budget.Spent)
+
+	swarm := &SwarmResult{Plan: p.Plan()}
+	if cp.Run != nil {
+		swarm.Run, err = engine.Resume(ctx, wf, cp.Run)
+	} else {
+		swarm.Run, err = engine.Run(ctx, wf, map[string]string{"task": task.Description})
+	}
+	swarm.TokensUsed = budget.Spent()
+	if err == nil {
+		swarm.Output = swarm.Run.Output
+	}
+	c.finishCheckpoint(cp, swarm, err)
+	return swarm, err
+}
+
+// budgetedSubmitter submits a pipeline's tasks to the collective, limiting
+// each response to the tokens left in the budget and refusing tasks once it
+// is spent. Phases running in parallel may overshoot it by their responses.
+type budgetedSubmitter struct {
+	mu sync.Mutex
+
+	collective *Collective
+	budget     int // 0 = unlimited
+	spent      int
+}
+
+// SubmitCtx runs a task if budget remains
+func (s *budgetedSubmitter) SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error) {
+	s.mu.Lock()
+	if s.budget > 0 {
+		remaining := s.budget - s.spent
+		if remaining <= 0 {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%w: %d of %d tokens spent", ErrPipelineBudget, s.spent, s.budget)
+		}
+		if task.MaxTokens == 0 || task.MaxTokens > remaining {
+			task.MaxTokens = remaining
+		}
+	}
+	s.mu.Unlock()
+
+	result, err := s.collective.SubmitCtx(ctx, task)
+	if result != nil {
+		s.mu.Lock()
+		s.spent += result.TokensUsed
+		s.mu.Unlock()
+	}
+	return result, err
+}
+
+// Spent returns the tokens used so far
+func (s *budgetedSubmitter) Spent() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spent
+}
@@ -0,0 +1,210 @@
+package collective
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// taskQueue holds the tasks submitted and not yet running, highest priority
+// first and oldest first within a priority. It is a heap indexed by task
+// ID, so tasks leave it from anywhere when they are assigned or abandoned.
+type taskQueue struct {
+	tasks []*agent.Task
+	index map[string]int // Task ID -> position in tasks
+}
+
+func newTaskQueue() *taskQueue {
+	return &taskQueue{index: make(map[string]int)}
+}
+
+// heap.Interface, used through push and remove
+func (q *taskQueue) Len() int { return len(q.tasks) }
+
+func (q *taskQueue) Less(i, j int) bool {
+	if q.tasks[i].Priority != q.tasks[j].Priority {
+		return q.tasks[i].Priority > q.tasks[j].Priority
+	}
+	return q.tasks[i].CreatedAt.Before(q.tasks[j].CreatedAt)
+}
+
+func (q *taskQueue) Swap(i, j int) {
+	q.tasks[i], q.tasks[j] = q.tasks[j], q.tasks[i]
+	q.index[q.tasks[i].ID] = i
+	q.index[q.tasks[j].ID] = j
+}
+
+func (q *taskQueue) Push(x interface{}) {
+	task := x.(*agent.Task)
+	q.index[task.ID] = len(q.tasks)
+	q.tasks = append(q.tasks, task)
+}
+
+func (q *taskQueue) Pop() interface{} {
+	last := q.tasks[len(q.tasks)-1]
+	q.tasks = q.tasks[:len(q.tasks)-1]
+	delete(q.index, last.ID)
+	return last
+}
+
+// push queues a task, unless it is queued already
+func (q *taskQueue) push(task *agent.Task) {
+	if _, ok := q.index[task.ID]; ok {
+		return
+	}
+	heap.Push(q, task)
+}
+
+// remove takes a task out of the queue wherever it is
+func (q *taskQueue) remove(taskID string) {
+	if i, ok := q.index[taskID]; ok {
+		heap.Remove(q, i)
+	}
+}
+
+// ordered returns the queued tasks in the order they'd be served after
+// waiting since submission, with aging applied
+func (q *taskQueue) ordered(aging PriorityAging, now time.Time) []*agent.Task {
+	tasks := append([]*agent.Task(nil), q.tasks...)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		pi := aging.Effective(tasks[i].Priority, now.Sub(tasks[i].CreatedAt))
+		pj := aging.Effective(tasks[j].Priority, now.Sub(tasks[j].CreatedAt))
+		if pi != pj {
+			return pi > pj
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	return tasks
+}
+
+// PreemptionPolicy lets urgent tasks take agents from running low-priority
+// ones. A preempted task checkpoints its work, gives up its agent and
+// execution slot, and resumes from the checkpoint once the task it made
+// way for has finished. Its agent's reputation and stake are untouched.
+type PreemptionPolicy struct {
+	MinPriority       int `json:"min_priority" yaml:"min_priority"`               // Tasks at or above this priority may preempt
+	MaxVictimPriority int `json:"max_victim_priority" yaml:"max_victim_priority"` // Running tasks at or below this priority may be preempted
+}
+
+// DefaultPreemptionPolicy lets critical tasks preempt low-priority ones
+func DefaultPreemptionPolicy() PreemptionPolicy {
+	return PreemptionPolicy{
+		MinPriority:       agent.PriorityCritical,
+		MaxVictimPriority: agent.PriorityLow,
+	}
+}
+
+// preemption is a running task stopped for a more urgent one
+type preemption struct {
+	by   string        // ID of the task it made way for
+	done chan struct{} // Closed when that task finishes
+}
+
+// preemptFor stops the running task that can best make way for an urgent
+// task that has to wait: of the tasks the preemption policy allows, one on
+// an agent capable of the urgent task if any, then the lowest priority,
+// then the most recently submitted (it has the least work to lose). An
+// urgent task has at most one task preempted for it at a time. Returns
+// whether a task was preempted.
+func (c *Collective) preemptFor(task *agent.Task) bool {
+	policy := c.config.Preemption
+	if policy == nil || task.Priority < policy.MinPriority {
+		return false
+	}
+
+	c.mu.Lock()
+	for _, p := range c.preemptions {
+		if p.by == task.ID {
+			c.mu.Unlock()
+			return false
+		}
+	}
+	var (
+		victim        *agent.Task
+		victimAgent   *agent.Agent
+		victimCapable bool
+	)
+	for _, t := range c.activeTasks {
+		a, ok := c.agents[t.AssignedTo]
+		if !ok || t.Priority > policy.MaxVictimPriority || c.preemptions[t.ID] != nil {
+			continue
+		}
+		capable := a.Capabilities.MatchScore(task.Required) >= c.market.BidThreshold().Min
+		better := victim == nil ||
+			(capable && !victimCapable) ||
+			(capable == victimCapable && t.Priority < victim.Priority) ||
+			(capable == victimCapable && t.Priority == victim.Priority && t.CreatedAt.After(victim.CreatedAt))
+		if better {
+			victim, victimAgent, victimCapable = t, a, capable
+		}
+	}
+	if victim == nil {
+		c.mu.Unlock()
+		return false
+	}
+	c.preemptions[victim.ID] = &preemption{by: task.ID, done: make(chan struct{})}
+	c.mu.Unlock()
+
+	if !victimAgent.Preempt(victim.ID) {
+		// Not started yet, or already finished
+		c.mu.Lock()
+		delete(c.preemptions, victim.ID)
+		c.mu.Unlock()
+		return false
+	}
+
+	c.timelines.Record(victim.ID, StagePreempted, victimAgent.Identity.SID, "made way for task "+task.ID)
+	c.events.Publish(Event{
+		Type:     EventTaskPreempted,
+		AgentSID: victimAgent.Identity.SID,
+		TaskID:   victim.ID,
+		Data: map[string]interface{}{
+			"preempted_by": task.ID,
+			"priority":     victim.Priority,
+		},
+	})
+	c.log().Info("task preempted", "task", victim.ID, "agent", victimAgent.Identity.SID, "for", task.ID)
+	return true
+}
+
+// endPreemptions lets the tasks preempted for a finished task resume
+func (c *Collective) endPreemptions(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, p := range c.preemptions {
+		if p.by == taskID {
+			close(p.done)
+			delete(c.preemptions, id)
+		}
+	}
+}
+
+// yield returns a preempted task to the queue: its stake is refunded and
+// its execution slot released until the task it made way for finishes, then
+// the slot is acquired again. On error the task holds no slot.
+func (c *Collective) yield(ctx context.Context, task *agent.Task, result *agent.TaskResult, policy QoSPolicy) error {
+	c.chargeBudgets(task, result.TokensUsed)
+
+	c.mu.Lock()
+	c.refundStakeLocked(task.ID, "preempted")
+	delete(c.activeTasks, task.ID)
+	task.Status = agent.TaskPending
+	task.AssignedTo = ""
+	c.pending.push(task)
+	p := c.preemptions[task.ID]
+	c.mu.Unlock()
+
+	c.queue.Release()
+	if p != nil {
+		c.timelines.Record(task.ID, StageWaiting, "", "paused until task "+p.by+" finishes")
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return c.queue.AcquireDeadline(ctx, task.Submitter, task.Priority, policy.Weight, admissionDeadline(task, policy))
+}
@@ -0,0 +1,168 @@
+package collective
+
+import (
+	"sort"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// DefaultProfileHistory is how many recent reputation events a profile holds
+const DefaultProfileHistory = 10
+
+// AgentProfile is everything the collective knows about one agent
+type AgentProfile struct {
+	SID        string           `json:"sid"`
+	Name       string           `json:"name"`
+	PublicKey  string           `json:"public_key"` // Hex
+	ParentSID  string           `json:"parent_sid,omitempty"`
+	Generation int              `json:"generation"`
+	CreatedAt  time.Time        `json:"created_at"`
+	Model      string           `json:"model,omitempty"`
+	Models     agent.ModelMap   `json:"models,omitempty"` // Models the agent assigns capabilities
+	Labels     agent.Labels     `json:"labels,omitempty"`
+	CostTags   agent.Labels     `json:"cost_tags,omitempty"`
+	State      agent.AgentState `json:"state"`
+
+	Capabilities []CapabilityProfile `json:"capabilities"`
+
+	Reputation agent.Reputation               `json:"reputation"`
+	History    []coordination.ReputationEvent `json:"history"` // Most recent last
+	Events     int                            `json:"events"`  // Reputation events recorded in all
+
+	Memory MemoryProfile `json:"memory"`
+
+	CurrentTask *CurrentTask  `json:"current_task,omitempty"`
+	Slots       int           `json:"slots"`           // Tasks the agent works on at once
+	Tasks       []CurrentTask `json:"tasks,omitempty"` // Every task running, by slot, when the agent has several slots
+	StartedAt   time.Time     `json:"started_at"`
+	Uptime      time.Duration `json:"uptime"`
+	LastActive  time.Time     `json:"last_active"`
+
+	Queue agent.QueueStats `json:"queue"`
+	Usage AgentUsage       `json:"usage"`
+	Share float64          `json:"share"` // Of all tokens the collective spent
+}
+
+// CapabilityProfile is one of an agent's capabilities
+type CapabilityProfile struct {
+	Type        identity.CapabilityType   `json:"type"`
+	Proficiency float64                   `json:"proficiency"` // Learned
+	Effective   float64                   `json:"effective"`   // With any current benchmark proof's boost
+	Proof       *identity.CapabilityProof `json:"proof,omitempty"`
+	ProofValid  bool                      `json:"proof_valid"` // Signed and not expired
+}
+
+// MemoryProfile sizes an agent's memory
+type MemoryProfile struct {
+	ShortTerm   int       `json:"short_term"`
+	LongTerm    int       `json:"long_term"`
+	Episodes    int       `json:"episodes"`
+	LastEpisode time.Time `json:"last_episode,omitempty"`
+}
+
+// CurrentTask is the task an agent is working on
+type CurrentTask struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description"`
+	Started     time.Time `json:"started"`
+}
+
+// Profile returns an agent's full profile with its last history reputation
+// events (0 = DefaultProfileHistory, negative = all)
+func (c *Collective) Profile(sid string, history int) (*AgentProfile, error) {
+	a, ok := c.GetAgent(sid)
+	if !ok {
+		return nil, ErrAgentNotFound
+	}
+	now := time.Now()
+
+	id := a.Identity
+	hb := a.Heartbeat()
+	profile := &AgentProfile{
+		SID:        id.SID,
+		Name:       id.Name,
+		PublicKey:  id.PublicKeyHex(),
+		ParentSID:  id.ParentSID,
+		Generation: id.Generation,
+		CreatedAt:  id.CreatedAt,
+		Model:      a.Model,
+		Models:     a.Models,
+		Labels:     a.Labels,
+		CostTags:   a.CostTags,
+		State:      hb.State,
+		StartedAt:  a.StartedAt,
+		Uptime:     now.Sub(a.StartedAt),
+		LastActive: a.LastActivity(),
+		Queue:      a.QueueStats(),
+		Usage:      AgentUsage{SID: id.SID, Name: id.Name},
+	}
+	if task := a.GetCurrentTask(); task != nil {
+		profile.CurrentTask = &CurrentTask{ID: task.ID, Description: task.Description, Started: hb.TaskStarted}
+	}
+	profile.Slots = a.Slots()
+	if profile.Slots > 1 {
+		started := make(map[string]time.Time, len(hb.Tasks))
+		for _, t := range hb.Tasks {
+			started[t.TaskID] = t.Started
+		}
+		for _, task := range a.GetCurrentTasks() {
+			profile.Tasks = append(profile.Tasks, CurrentTask{ID: task.ID, Description: task.Description, Started: started[task.ID]})
+		}
+	}
+
+	effective := a.Capabilities.ProvenProficiencies(now)
+	for _, t := range a.Capabilities.List() {
+		capability, ok := a.Capabilities.Lookup(t)
+		if !ok {
+			continue
+		}
+		cp := CapabilityProfile{Type: t, Proficiency: capability.Proficiency, Effective: effective[t]}
+		if proof := capability.Proof; proof != nil {
+			copied := *proof
+			cp.Proof = &copied
+			cp.ProofValid = proof.Current(now)
+		}
+		profile.Capabilities = append(profile.Capabilities, cp)
+	}
+	sort.Slice(profile.Capabilities, func(i, j int) bool {
+		return profile.Capabilities[i].Type < profile.Capabilities[j].Type
+	})
+
+	if record, err := c.reputation.Record(sid); err == nil {
+		profile.Reputation = record.Reputation
+		profile.Events = len(record.History)
+		if history == 0 {
+			history = DefaultProfileHistory
+		}
+		events := record.History
+		if history > 0 && len(events) > history {
+			events = events[len(events)-history:]
+		}
+		profile.History = events
+	} else {
+		profile.Reputation = *a.Reputation
+	}
+
+	if m := a.Memory; m != nil {
+		shortTerm, longTerm, _ := m.Size()
+		episodes := m.Episodes()
+		profile.Memory = MemoryProfile{ShortTerm: shortTerm, LongTerm: longTerm, Episodes: len(episodes)}
+		if n := len(episodes); n > 0 {
+			profile.Memory.LastEpisode = episodes[n-1].Timestamp
+		}
+	}
+
+	usage := c.UsageReport("")
+	for _, u := range usage.Agents {
+		if u.SID == sid {
+			profile.Usage = u
+		}
+	}
+	if usage.Tokens > 0 {
+		profile.Share = float64(profile.Usage.Tokens) / float64(usage.Tokens)
+	}
+	return profile, nil
+}
@@ -0,0 +1,298 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+var (
+	ErrUnknownQoSClass = errors.New("unknown QoS class")
+	ErrInvalidQoS      = errors.New("invalid QoS policy")
+)
+
+// QoSPolicy is how the collective serves the tasks of a quality-of-service
+// class: how soon they should finish, the model and response limit they run with,
+// how often a failed one is reassigned, their share of contended execution
+// slots and how fast they are dispatched. Task settings take precedence
+// over the policy's.
+type QoSPolicy struct {
+	LatencyTarget time.Duration `json:"latency_target,omitempty" yaml:"latency_target,omitempty"` // Submission to result; orders tasks without a deadline under EDF (0 = none)
+	Model         string        `json:"model,omitempty" yaml:"model,omitempty"`                   // LLM model for tasks that don't name one (empty = the agent's)
+	MaxTokens     int           `json:"max_tokens,omitempty" yaml:"max_tokens,omitempty"`         // Response limit for tasks that don't set one (0 = the agent's)
+	Retries       int           `json:"retries,omitempty" yaml:"retries,omitempty"`               // Reassignments after a failed attempt
+	Weight        int           `json:"weight,omitempty" yaml:"weight,omitempty"`                 // Scheduler weight relative to other classes (0 = 1)
+	Rate          float64       `json:"rate,omitempty" yaml:"rate,omitempty"`                     // Tasks dispatched per second (0 = unlimited)
+	Burst         int           `json:"burst,omitempty" yaml:"burst,omitempty"`                   // Tasks dispatched back to back before Rate applies (0 = 1)
+}
+
+// DefaultQoSPolicies returns the built-in classes: interactive work is due
+// within seconds, gets short answers, fails fast and outweighs the rest;
+// batch work may take an hour and is retried until it succeeds
+func DefaultQoSPolicies() map[agent.QoSClass]QoSPolicy {
+	return map[agent.QoSClass]QoSPolicy{
+		agent.QoSInteractive: {
+			LatencyTarget: 30 * time.Second,
+			MaxTokens:     2048,
+			Weight:        8,
+		},
+		agent.QoSStandard: {
+			LatencyTarget: 5 * time.Minute,
+			Retries:       1,
+			Weight:        4,
+		},
+		agent.QoSBatch: {
+			LatencyTarget: time.Hour,
+			Retries:       3,
+			Weight:        1,
+		},
+	}
+}
+
+// validate checks a policy's limits
+func (p QoSPolicy) validate() error {
+	if p.LatencyTarget < 0 || p.MaxTokens < 0 || p.Retries < 0 || p.Weight < 0 || p.Rate < 0 || p.Burst < 0 {
+		return fmt.Errorf("%w: negative limit", ErrInvalidQoS)
+	}
+	return nil
+}
+
+// QoSStats counts the tasks a class served
+type QoSStats struct {
+	Tasks   int `json:"tasks"`
+	Failed  int `json:"failed"`
+	Retries int `json:"retries"`
+	Late    int `json:"late"` // Results that took longer than the latency target
+}
+
+// qosState holds the class policies, the dispatch rate limiter of each and
+// what each has served
+type qosState struct {
+	mu sync.Mutex
+
+	policies map[agent.QoSClass]QoSPolicy
+	limiters map[agent.QoSClass]*rateLimiter
+	stats    map[agent.QoSClass]*QoSStats
+}
+
+// newQoSState starts from the default classes, with the configured ones
+// added or replacing them
+func newQoSState(configured map[agent.QoSClass]QoSPolicy) *qosState {
+	s := &qosState{
+		policies: DefaultQoSPolicies(),
+		limiters: make(map[agent.QoSClass]*rateLimiter),
+		stats:    make(map[agent.QoSClass]*QoSStats),
+	}
+	for class, p := range configured {
+		s.policies[class] = p
+	}
+	return s
+}
+
+// QoSPolicies returns the policy of every class
+func (c *Collective) QoSPolicies() map[agent.QoSClass]QoSPolicy {
+	c.qos.mu.Lock()
+	defer c.qos.mu.Unlock()
+
+	policies := make(map[agent.QoSClass]QoSPolicy, len(c.qos.policies))
+	for class, p := range c.qos.policies {
+		policies[class] = p
+	}
+	return policies
+}
+
+// SetQoSPolicy adds a class or replaces its policy. Tasks already waiting
+// keep the settings they were submitted with.
+func (c *Collective) SetQoSPolicy(class agent.QoSClass, policy QoSPolicy) error {
+	if class == "" {
+		return fmt.Errorf("%w: class needs a name", ErrInvalidQoS)
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	c.qos.mu.Lock()
+	defer c.qos.mu.Unlock()
+	c.qos.policies[class] = policy
+	delete(c.qos.limiters, class)
+	return nil
+}
+
+// QoSStats returns what each class has served
+func (c *Collective) QoSStats() map[agent.QoSClass]QoSStats {
+	c.qos.mu.Lock()
+	defer c.qos.mu.Unlock()
+
+	stats := make(map[agent.QoSClass]QoSStats, len(c.qos.stats))
+	for class, s := range c.qos.stats {
+		stats[class] = *s
+	}
+	return stats
+}
+
+// QoSClasses returns the names of the classes, sorted
+func (c *Collective) QoSClasses() []agent.QoSClass {
+	c.qos.mu.Lock()
+	defer c.qos.mu.Unlock()
+
+	classes := make([]agent.QoSClass, 0, len(c.qos.policies))
+	for class := range c.qos.policies {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	return classes
+}
+
+// applyQoS puts a task in its class, standard if it declares none, and fills
+// the settings it leaves unset from the class policy
+func (c *Collective) applyQoS(task *agent.Task) (QoSPolicy, error) {
+	if task.QoS == "" {
+		task.QoS = agent.QoSStandard
+	}
+	c.qos.mu.Lock()
+	policy, ok := c.qos.policies[task.QoS]
+	c.qos.mu.Unlock()
+	if !ok {
+		return QoSPolicy{}, fmt.Errorf("%w: %s", ErrUnknownQoSClass, task.QoS)
+	}
+
+	if task.Model == "" {
+		task.Model = policy.Model
+	}
+	if task.MaxTokens == 0 {
+		task.MaxTokens = policy.MaxTokens
+	}
+	return policy, nil
+}
+
+// admissionDeadline returns the deadline a waiting task is admitted by under
+// EDF: its own, or its class's latency target after submission. The target
+// only orders admission; it isn't the task's deadline, so liveness checks,
+// stakes and enforcement don't hold the task to it.
+func admissionDeadline(task *agent.Task, policy QoSPolicy) time.Time {
+	if task.Deadline.IsZero() && policy.LatencyTarget > 0 {
+		return task.CreatedAt.Add(policy.LatencyTarget)
+	}
+	return task.Deadline
+}
+
+// throttle waits until the task's class may dispatch another task, or ctx ends
+func (c *Collective) throttle(ctx context.Context, task *agent.Task, policy QoSPolicy) error {
+	if policy.Rate <= 0 {
+		return nil
+	}
+	c.qos.mu.Lock()
+	limiter, ok := c.qos.limiters[task.QoS]
+	if !ok {
+		limiter = newRateLimiter(policy.Rate, policy.Burst)
+		c.qos.limiters[task.QoS] = limiter
+	}
+	c.qos.mu.Unlock()
+
+	delay := limiter.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	c.timelines.Record(task.ID, StageWaiting, "", fmt.Sprintf("QoS class %s is limited to %g tasks/s", task.QoS, policy.Rate))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryFailed settles a failed attempt at a task with retries left, so the
+// task can be reassigned: the agent's reputation, stake, the budgets and
+// cost attribution account for it as they would for a final result
+func (c *Collective) retryFailed(task *agent.Task, result *agent.TaskResult, attempt, retries int) {
+	c.reputation.RecordTaskFailure(result.AgentSID)
+	c.settleStake(task, result)
+	c.chargeBudgets(task, result.TokensUsed)
+	c.attributeUsage(task, result)
+
+	c.mu.Lock()
+	delete(c.activeTasks, task.ID)
+	task.Status = agent.TaskPending
+	task.AssignedTo = ""
+	c.pending.push(task)
+	c.mu.Unlock()
+
+	c.qos.mu.Lock()
+	c.qos.statsLocked(task.QoS).Retries++
+	c.qos.mu.Unlock()
+
+	c.timelines.Record(task.ID, StageRequeued, result.AgentSID, fmt.Sprintf("retry %d of %d: %s", attempt, retries, result.Error))
+	c.events.Publish(Event{
+		Type:     EventTaskRetried,
+		AgentSID: result.AgentSID,
+		TaskID:   task.ID,
+		Data: map[string]interface{}{
+			"qos":     string(task.QoS),
+			"attempt": attempt,
+			"retries": retries,
+			"error":   result.Error,
+		},
+	})
+}
+
+// recordQoS counts a task's final result against its class
+func (c *Collective) recordQoS(task *agent.Task, result *agent.TaskResult, policy QoSPolicy) {
+	c.qos.mu.Lock()
+	defer c.qos.mu.Unlock()
+
+	s := c.qos.statsLocked(task.QoS)
+	s.Tasks++
+	if result.Status != agent.TaskCompleted {
+		s.Failed++
+	}
+	if policy.LatencyTarget > 0 && time.Since(task.CreatedAt) > policy.LatencyTarget {
+		s.Late++
+	}
+}
+
+// statsLocked returns a class's counters. Caller must hold s.mu.
+func (s *qosState) statsLocked(class agent.QoSClass) *QoSStats {
+	stats, ok := s.stats[class]
+	if !ok {
+		stats = &QoSStats{}
+		s.stats[class] = stats
+	}
+	return stats
+}
+
+// rateLimiter spaces dispatches rate per second apart, allowing burst back
+// to back
+type rateLimiter struct {
+	mu sync.Mutex
+
+	interval time.Duration
+	burst    int
+	next     time.Time // When the next dispatch would be due at the steady rate
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		burst:    max(burst, 1),
+	}
+}
+
+// reserve books the next dispatch and returns how long to wait for it
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now) - time.Duration(l.burst-1)*l.interval
+	l.next = l.next.Add(l.interval)
+	return max(delay, 0)
+}
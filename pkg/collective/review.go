@@ -0,0 +1,185 @@
+package collective
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// ReviewRotation spreads review stages across the agents able to do them.
+// Instead of always going to the best bid, a review task goes to the bidder
+// next in a round-robin weighted by reputation and discounted by the tasks
+// each was assigned within Window, so reviewing doesn't bottleneck on the
+// top-reputation agent or let it monopolize the reputation reviews earn.
+type ReviewRotation struct {
+	// Capabilities mark review stages: tasks requiring any of them rotate
+	// (default: code.review and security)
+	Capabilities []identity.CapabilityType `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+
+	// Window is how long an assignment counts towards an agent's load
+	// (default: an hour)
+	Window time.Duration `json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+// DefaultReviewRotation rotates code review and security tasks, counting
+// load over the last hour
+func DefaultReviewRotation() ReviewRotation {
+	return ReviewRotation{
+		Capabilities: []identity.CapabilityType{identity.CapCodeReview, identity.CapSecurity},
+		Window:       time.Hour,
+	}
+}
+
+// withDefaults fills unset fields from DefaultReviewRotation
+func (r ReviewRotation) withDefaults() ReviewRotation {
+	d := DefaultReviewRotation()
+	if len(r.Capabilities) == 0 {
+		r.Capabilities = d.Capabilities
+	}
+	if r.Window <= 0 {
+		r.Window = d.Window
+	}
+	return r
+}
+
+// reviewRotation is the state of a smooth weighted round-robin over the
+// bidders for review tasks, and the recent assignments behind each agent's
+// load
+type reviewRotation struct {
+	mu sync.Mutex
+
+	policy   ReviewRotation
+	credit   map[string]float64     // Agent SID -> turns accumulated
+	assigned map[string][]time.Time // Agent SID -> its assignments within the window, oldest first
+}
+
+func newReviewRotation(policy ReviewRotation) *reviewRotation {
+	return &reviewRotation{
+		policy:   policy.withDefaults(),
+		credit:   make(map[string]float64),
+		assigned: make(map[string][]time.Time),
+	}
+}
+
+// covers reports whether a task is a review stage
+func (r *reviewRotation) covers(task *agent.Task) bool {
+	for _, req := range task.Required {
+		for _, c := range r.policy.Capabilities {
+			if req == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// loadLocked counts an agent's assignments within the window, forgetting
+// older ones. Caller must hold r.mu.
+func (r *reviewRotation) loadLocked(sid string, now time.Time) int {
+	times := r.assigned[sid]
+	cutoff := now.Add(-r.policy.Window)
+	i := sort.Search(len(times), func(i int) bool { return times[i].After(cutoff) })
+	if i == len(times) {
+		delete(r.assigned, sid)
+		return 0
+	}
+	r.assigned[sid] = times[i:]
+	return len(times) - i
+}
+
+// noteAssignment counts a task assigned to an agent towards its load
+func (r *reviewRotation) noteAssignment(sid string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.assigned[sid] = append(r.assigned[sid], now)
+}
+
+// order returns the candidates in turn order: each gains credit in
+// proportion to its reputation over one plus its load, and the most
+// credited goes first. take charges the turn to the candidate that
+// actually got the task.
+func (r *reviewRotation) order(ranked []*coordination.TaskAssignment, reputation *coordination.ReputationRegistry, now time.Time) (ordered []*coordination.TaskAssignment, take func(sid string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := 0.0
+	for _, candidate := range ranked {
+		sid := candidate.AgentSID
+		rep := coordination.DefaultBidReputation
+		if score := reputation.Get(sid); score != nil {
+			rep = score.Overall
+		}
+		weight := max(rep, 1) / float64(1+r.loadLocked(sid, now))
+		r.credit[sid] += weight
+		total += weight
+	}
+
+	// Stable over the market's ranking, so equal credit goes to the better bid
+	ordered = append([]*coordination.TaskAssignment(nil), ranked...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return r.credit[ordered[i].AgentSID] > r.credit[ordered[j].AgentSID]
+	})
+
+	return ordered, func(sid string) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.credit[sid] -= total
+	}
+}
+
+// forget drops an agent that left from the rotation
+func (r *reviewRotation) forget(sid string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.credit, sid)
+	delete(r.assigned, sid)
+}
+
+// ReviewLoads returns the tasks assigned to each agent within the review
+// rotation's window, or nil if reviews don't rotate
+func (c *Collective) ReviewLoads() map[string]int {
+	r := c.reviews
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	loads := make(map[string]int, len(r.assigned))
+	for sid := range r.assigned {
+		if n := r.loadLocked(sid, now); n > 0 {
+			loads[sid] = n
+		}
+	}
+	return loads
+}
+
+// assignByRotation assigns a review task to the free bidder whose turn it
+// is
+func (c *Collective) assignByRotation(task *agent.Task, au *auction) (*coordination.TaskAssignment, error) {
+	scope := au.scope
+	if err := scope.market.SolicitBids(task, scope.agents); err != nil {
+		return nil, err
+	}
+	ranked, err := scope.market.RankBids(task.ID, c.reputation)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered, take := c.reviews.order(ranked, c.reputation, time.Now())
+	au.rotated = ordered[0].AgentSID != ranked[0].AgentSID
+	for _, candidate := range ordered {
+		if c.reserve(candidate.AgentSID, true) {
+			take(candidate.AgentSID)
+			return candidate, nil
+		}
+		au.outcome(candidate.AgentSID, OutcomeBusy)
+	}
+	return nil, fmt.Errorf("%w: every bidder is busy", coordination.ErrNoBids)
+}
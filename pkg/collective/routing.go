@@ -0,0 +1,116 @@
+package collective
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// ErrRouteNotFound is returned for a routing rule that doesn't exist
+var ErrRouteNotFound = errors.New("routing rule not found")
+
+// RoutingRule sends tasks submitted without a team to one. A rule matches a
+// task that requires all of its Capabilities, comes from its Submitter and
+// is of its QoS class; empty fields match any task. Of the rules matching a task, the one with
+// the highest Priority wins, then the first by name.
+type RoutingRule struct {
+	Name         string                    `json:"name"`
+	Team         string                    `json:"team"`
+	Capabilities []identity.CapabilityType `json:"capabilities,omitempty"`
+	Submitter    string                    `json:"submitter,omitempty"`
+	QoS          agent.QoSClass            `json:"qos,omitempty"`
+	Priority     int                       `json:"priority,omitempty"`
+}
+
+// matches reports whether a task falls under the rule
+func (r *RoutingRule) matches(task *agent.Task) bool {
+	if r.Submitter != "" && r.Submitter != task.Submitter {
+		return false
+	}
+	if r.QoS != "" && r.QoS != task.QoS {
+		return false
+	}
+	for _, required := range r.Capabilities {
+		found := false
+		for _, c := range task.Required {
+			if c == required {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SetRoutingRule adds a routing rule, or replaces the one with its name
+func (c *Collective) SetRoutingRule(rule RoutingRule) error {
+	if rule.Name == "" || rule.Team == "" {
+		return fmt.Errorf("routing rule needs a name and a team")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routes[rule.Name] = &rule
+	return nil
+}
+
+// RemoveRoutingRule removes a routing rule
+func (c *Collective) RemoveRoutingRule(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.routes[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrRouteNotFound, name)
+	}
+	delete(c.routes, name)
+	return nil
+}
+
+// RoutingRules returns the routing rules in the order they are tried
+func (c *Collective) RoutingRules() []RoutingRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.routingRulesLocked()
+}
+
+// routingRulesLocked returns the rules by priority, then name. Caller must
+// hold c.mu.
+func (c *Collective) routingRulesLocked() []RoutingRule {
+	rules := make([]RoutingRule, 0, len(c.routes))
+	for _, r := range c.routes {
+		rules = append(rules, *r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].Name < rules[j].Name
+	})
+	return rules
+}
+
+// route sets the team of a task submitted without one from the first
+// matching rule whose team exists
+func (c *Collective) route(task *agent.Task) {
+	if task.Team != "" {
+		return
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, rule := range c.routingRulesLocked() {
+		if !rule.matches(task) {
+			continue
+		}
+		if _, ok := c.teams[rule.Team]; !ok {
+			c.logger.Warn("routing rule names a missing team", "rule", rule.Name, "team", rule.Team)
+			continue
+		}
+		task.Team = rule.Team
+		c.logger.Debug("task routed", "task", task.ID, "rule", rule.Name, "team", rule.Team)
+		return
+	}
+}
@@ -0,0 +1,379 @@
+package collective
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+	ErrSchedulerRunning = errors.New("scheduler already running")
+	ErrNoCollective     = errors.New("scheduler has no collective")
+)
+
+// ScheduleKind identifies how a schedule recurs
+type ScheduleKind string
+
+const (
+	ScheduleOnce  ScheduleKind = "once"
+	ScheduleEvery ScheduleKind = "every"
+	ScheduleCron  ScheduleKind = "cron"
+)
+
+// Schedule is a task template submitted to the collective at planned times
+type Schedule struct {
+	ID        string        `json:"id"`
+	Kind      ScheduleKind  `json:"kind"`
+	Task      agent.Task    `json:"task"`
+	At        time.Time     `json:"at,omitempty"`       // ScheduleOnce
+	Interval  time.Duration `json:"interval,omitempty"` // ScheduleEvery
+	Cron      string        `json:"cron,omitempty"`     // ScheduleCron
+	NextRun   time.Time     `json:"next_run"`
+	LastRun   time.Time     `json:"last_run,omitempty"`
+	LastTask  string        `json:"last_task,omitempty"` // ID of the most recently submitted task
+	Runs      int           `json:"runs"`
+	CreatedAt time.Time     `json:"created_at"`
+
+	cron *CronSchedule
+}
+
+// next computes the run after the given time, or the zero time if the
+// schedule is finished
+func (s *Schedule) next(after time.Time) time.Time {
+	switch s.Kind {
+	case ScheduleEvery:
+		return after.Add(s.Interval)
+	case ScheduleCron:
+		return s.cron.Next(after)
+	default:
+		return time.Time{}
+	}
+}
+
+// instance creates a fresh task from the schedule's template
+func (s *Schedule) instance(now time.Time) *agent.Task {
+	task := s.Task
+	task.ID = uuid.New().String()
+	task.Status = agent.TaskPending
+	task.AssignedTo = ""
+	task.CreatedAt = now
+	task.Required = append(task.Required[:0:0], task.Required...)
+
+	// Deadlines are kept relative to when the template was created
+	if !s.Task.Deadline.IsZero() {
+		task.Deadline = now.Add(s.Task.Deadline.Sub(s.Task.CreatedAt))
+	}
+	return &task
+}
+
+// Scheduler submits deferred and recurring tasks to a collective. Schedules
+// are persisted to a JSON file, if configured, so they survive restarts.
+// Runs missed while the scheduler was down fire once on the next tick.
+type Scheduler struct {
+	mu sync.Mutex
+
+	collective *Collective
+	path       string
+	schedules  map[string]*Schedule
+
+	tick    time.Duration
+	running bool
+
+	logger logging.Logger
+}
+
+// NewScheduler creates a scheduler for a collective, loading any schedules
+// persisted at path. An empty path keeps schedules in memory only. The
+// collective may be nil when the scheduler is only used to manage schedules.
+func NewScheduler(c *Collective, path string) (*Scheduler, error) {
+	s := &Scheduler{
+		collective: c,
+		path:       path,
+		schedules:  make(map[string]*Schedule),
+		tick:       time.Second,
+		logger:     logging.Component("scheduler"),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetTickInterval sets how often the scheduler checks for due schedules
+func (s *Scheduler) SetTickInterval(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tick = d
+}
+
+// SetLogger replaces the scheduler's logger
+func (s *Scheduler) SetLogger(l logging.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = l
+}
+
+// RunAt schedules a task to be submitted once at the given time
+func (s *Scheduler) RunAt(task *agent.Task, at time.Time) (*Schedule, error) {
+	return s.add(&Schedule{Kind: ScheduleOnce, Task: *task, At: at, NextRun: at})
+}
+
+// Every schedules a task to be submitted repeatedly at a fixed interval,
+// starting one interval from now
+func (s *Scheduler) Every(task *agent.Task, interval time.Duration) (*Schedule, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("%w: interval must be positive", ErrInvalidSchedule)
+	}
+	return s.add(&Schedule{
+		Kind:     ScheduleEvery,
+		Task:     *task,
+		Interval: interval,
+		NextRun:  time.Now().Add(interval),
+	})
+}
+
+// Cron schedules a task to be submitted on a cron expression
+func (s *Scheduler) Cron(task *agent.Task, expr string) (*Schedule, error) {
+	cron, err := ParseCron(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	next := cron.Next(time.Now())
+	if next.IsZero() {
+		return nil, fmt.Errorf("%w: %q never fires", ErrInvalidSchedule, expr)
+	}
+
+	return s.add(&Schedule{
+		Kind:    ScheduleCron,
+		Task:    *task,
+		Cron:    expr,
+		NextRun: next,
+		cron:    cron,
+	})
+}
+
+// add registers and persists a new schedule
+func (s *Scheduler) add(sched *Schedule) (*Schedule, error) {
+	sched.ID = uuid.New().String()
+	sched.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules[sched.ID] = sched
+	if err := s.saveLocked(); err != nil {
+		delete(s.schedules, sched.ID)
+		return nil, err
+	}
+
+	copied := *sched
+	return &copied, nil
+}
+
+// Cancel removes a schedule
+func (s *Scheduler) Cancel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.schedules[id]; !ok {
+		return ErrScheduleNotFound
+	}
+	delete(s.schedules, id)
+	return s.saveLocked()
+}
+
+// Get returns a schedule by ID
+func (s *Scheduler) Get(id string) (Schedule, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sched, ok := s.schedules[id]
+	if !ok {
+		return Schedule{}, false
+	}
+	return *sched, true
+}
+
+// List returns all schedules ordered by next run
+func (s *Scheduler) List() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Schedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		list = append(list, *sched)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].NextRun.Before(list[j].NextRun)
+	})
+	return list
+}
+
+// Start runs the scheduler until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return ErrSchedulerRunning
+	}
+	if s.collective == nil {
+		s.mu.Unlock()
+		return ErrNoCollective
+	}
+	s.running = true
+	tick := s.tick
+	s.mu.Unlock()
+
+	go s.run(ctx, tick)
+	return nil
+}
+
+// run fires due schedules on every tick
+func (s *Scheduler) run(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	s.RunDue(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.RunDue(now)
+		}
+	}
+}
+
+// RunDue submits every schedule due at or before now and advances it.
+// Returns the submitted tasks. Nothing fires while the collective is in
+// maintenance mode; schedules that came due meanwhile fire once after.
+func (s *Scheduler) RunDue(now time.Time) []*agent.Task {
+	if s.collective != nil && s.collective.InMaintenance() {
+		return nil
+	}
+
+	s.mu.Lock()
+
+	var due []*agent.Task
+	for id, sched := range s.schedules {
+		if sched.NextRun.After(now) {
+			continue
+		}
+
+		task := sched.instance(now)
+		due = append(due, task)
+
+		sched.LastRun = now
+		sched.LastTask = task.ID
+		sched.Runs++
+		s.logger.Info("schedule fired", "schedule", sched.ID, "kind", sched.Kind, "task", task.ID, "runs", sched.Runs)
+
+		// Missed runs are not replayed; the next run is computed from now
+		next := sched.next(now)
+		if next.IsZero() {
+			delete(s.schedules, id)
+			continue
+		}
+		sched.NextRun = next
+	}
+
+	if len(due) > 0 {
+		if err := s.saveLocked(); err != nil {
+			s.logger.Error("failed to persist schedules", "path", s.path, "error", err)
+		}
+	}
+	c := s.collective
+	s.mu.Unlock()
+
+	if c != nil {
+		for _, task := range due {
+			_, _ = c.SubmitAsync(task)
+		}
+	}
+	return due
+}
+
+// load reads persisted schedules, if the file exists
+func (s *Scheduler) load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var schedules []*Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+
+	for _, sched := range schedules {
+		if sched.Kind == ScheduleCron {
+			cron, err := ParseCron(sched.Cron)
+			if err != nil {
+				return fmt.Errorf("%s: schedule %s: %w", s.path, sched.ID, err)
+			}
+			sched.cron = cron
+		}
+		s.schedules[sched.ID] = sched
+	}
+	return nil
+}
+
+// saveLocked writes all schedules to disk. Caller must hold s.mu.
+func (s *Scheduler) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	schedules := make([]*Schedule, 0, len(s.schedules))
+	for _, sched := range s.schedules {
+		schedules = append(schedules, sched)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	// Write atomically so a crash never leaves a truncated file
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
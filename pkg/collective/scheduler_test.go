@@ -0,0 +1,156 @@
+package collective
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	from := time.Date(2024, time.March, 15, 10, 7, 30, 0, time.UTC) // Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, time.March, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2024, time.March, 16, 9, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1-5", time.Date(2024, time.March, 18, 8, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, time.March, 17, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		cron, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := cron.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%q: expected next %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected error parsing %q", expr)
+		}
+	}
+}
+
+func TestScheduler_RunDue(t *testing.T) {
+	s, err := NewScheduler(nil, "")
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	task := agent.NewTask("Nightly report", nil)
+	task.Deadline = task.CreatedAt.Add(10 * time.Minute)
+
+	now := time.Now()
+	once, _ := s.RunAt(task, now.Add(time.Minute))
+	every, _ := s.Every(task, time.Hour)
+
+	if due := s.RunDue(now); len(due) != 0 {
+		t.Errorf("Expected no due tasks, got %d", len(due))
+	}
+
+	later := now.Add(2 * time.Hour)
+	due := s.RunDue(later)
+	if len(due) != 2 {
+		t.Fatalf("Expected 2 due tasks, got %d", len(due))
+	}
+	for _, d := range due {
+		if d.ID == task.ID {
+			t.Error("Scheduled instance should have a fresh task ID")
+		}
+		if !d.Deadline.Equal(later.Add(10 * time.Minute)) {
+			t.Errorf("Expected relative deadline %v, got %v", later.Add(10*time.Minute), d.Deadline)
+		}
+	}
+
+	if _, ok := s.Get(once.ID); ok {
+		t.Error("One-shot schedule should be removed after firing")
+	}
+
+	got, ok := s.Get(every.ID)
+	if !ok {
+		t.Fatal("Recurring schedule should remain")
+	}
+	if got.Runs != 1 {
+		t.Errorf("Expected 1 run, got %d", got.Runs)
+	}
+	if !got.NextRun.Equal(later.Add(time.Hour)) {
+		t.Errorf("Expected next run %v, got %v", later.Add(time.Hour), got.NextRun)
+	}
+}
+
+func TestScheduler_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+
+	s, err := NewScheduler(nil, path)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	sched, err := s.Cron(agent.NewTask("Weekly audit", nil), "0 6 * * 1")
+	if err != nil {
+		t.Fatalf("Cron failed: %v", err)
+	}
+
+	reloaded, err := NewScheduler(nil, path)
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	got, ok := reloaded.Get(sched.ID)
+	if !ok {
+		t.Fatal("Expected schedule to survive reload")
+	}
+	if got.Task.Description != "Weekly audit" {
+		t.Errorf("Expected task 'Weekly audit', got '%s'", got.Task.Description)
+	}
+
+	// Fire the reloaded cron schedule and check it advances to the next Monday
+	reloaded.RunDue(got.NextRun)
+	advanced, _ := reloaded.Get(sched.ID)
+	if !advanced.NextRun.Equal(got.NextRun.AddDate(0, 0, 7)) {
+		t.Errorf("Expected next run %v, got %v", got.NextRun.AddDate(0, 0, 7), advanced.NextRun)
+	}
+
+	if err := reloaded.Cancel(sched.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if err := reloaded.Cancel(sched.ID); err != ErrScheduleNotFound {
+		t.Errorf("Expected ErrScheduleNotFound, got %v", err)
+	}
+}
+
+func TestScheduler_Maintenance(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	s, err := NewScheduler(c, "")
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	now := time.Now()
+	sched, _ := s.Every(agent.NewTask("Hourly report", nil), time.Hour)
+
+	c.EnterMaintenance("incident")
+	if due := s.RunDue(now.Add(3 * time.Hour)); len(due) != 0 {
+		t.Errorf("Expected no tasks during maintenance, got %d", len(due))
+	}
+
+	// Pausing alone doesn't hold schedules back; their tasks wait to be dispatched
+	c.Pause("deploy")
+	if due := s.RunDue(now.Add(3 * time.Hour)); len(due) != 1 {
+		t.Errorf("Expected the missed run to fire once, got %d", len(due))
+	}
+	if got, _ := s.Get(sched.ID); got.Runs != 1 {
+		t.Errorf("Expected 1 run, got %d", got.Runs)
+	}
+}
@@ -0,0 +1,100 @@
+package collective
+
+import (
+	"sort"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// AgentSnapshot is a point-in-time view of an agent for monitoring
+type AgentSnapshot struct {
+	SID          string                              `json:"sid"`
+	Name         string                              `json:"name"`
+	State        agent.AgentState                    `json:"state"`
+	CurrentTask  string                              `json:"current_task,omitempty"`
+	Capabilities map[identity.CapabilityType]float64 `json:"capabilities"`
+	Usage        agent.Usage                         `json:"usage"`
+	Labels       agent.Labels                        `json:"labels,omitempty"`
+}
+
+// TaskSnapshot is a point-in-time view of the collective's task queues
+type TaskSnapshot struct {
+	Pending   []agent.Task       `json:"pending"`
+	Active    []agent.Task       `json:"active"`
+	Completed []agent.TaskResult `json:"completed"`
+}
+
+// AgentSnapshots returns a snapshot of every agent, ordered by name
+func (c *Collective) AgentSnapshots() []AgentSnapshot {
+	agents := c.GetAgents()
+
+	snapshots := make([]AgentSnapshot, 0, len(agents))
+	for _, a := range agents {
+		snapshots = append(snapshots, snapshotAgent(a))
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Name < snapshots[j].Name
+	})
+	return snapshots
+}
+
+// AgentSnapshot returns a snapshot of one agent
+func (c *Collective) AgentSnapshot(sid string) (AgentSnapshot, bool) {
+	a, ok := c.GetAgent(sid)
+	if !ok {
+		return AgentSnapshot{}, false
+	}
+	return snapshotAgent(a), true
+}
+
+// snapshotAgent takes a snapshot of an agent
+func snapshotAgent(a *agent.Agent) AgentSnapshot {
+	snapshot := AgentSnapshot{
+		SID:          a.Identity.SID,
+		Name:         a.Identity.Name,
+		State:        a.GetState(),
+		Capabilities: a.Capabilities.Proficiencies(),
+		Usage:        a.GetUsage(),
+		Labels:       a.Labels,
+	}
+	if task := a.GetCurrentTask(); task != nil {
+		snapshot.CurrentTask = task.ID
+	}
+	return snapshot
+}
+
+// TaskSnapshot returns copies of pending tasks, in the order they'd be
+// served, and of active tasks, and up to completedLimit of the most recent
+// results
+func (c *Collective) TaskSnapshot(completedLimit int) TaskSnapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := TaskSnapshot{
+		Pending: make([]agent.Task, 0, c.pending.Len()),
+		Active:  make([]agent.Task, 0, len(c.activeTasks)),
+	}
+	for _, task := range c.pending.ordered(c.queue.agingPolicy(), time.Now()) {
+		snapshot.Pending = append(snapshot.Pending, *task)
+	}
+	for _, task := range c.activeTasks {
+		snapshot.Active = append(snapshot.Active, *task)
+	}
+	sort.Slice(snapshot.Active, func(i, j int) bool {
+		return snapshot.Active[i].CreatedAt.Before(snapshot.Active[j].CreatedAt)
+	})
+
+	start := len(c.completedTasks) - completedLimit
+	if start < 0 {
+		start = 0
+	}
+	snapshot.Completed = make([]agent.TaskResult, 0, len(c.completedTasks)-start)
+	for _, result := range c.completedTasks[start:] {
+		snapshot.Completed = append(snapshot.Completed, *result)
+	}
+
+	return snapshot
+}
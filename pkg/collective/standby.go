@@ -0,0 +1,490 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var (
+	ErrPrimaryAlive        = errors.New("primary is still reachable")
+	ErrPromotionRejected   = errors.New("promotion rejected by consensus")
+	ErrPromotionInProgress = errors.New("promotion already in progress")
+	ErrAlreadyPromoted     = errors.New("standby already promoted")
+	ErrNotReplicatedYet    = errors.New("standby has not received a replica")
+	ErrStaleEpoch          = errors.New("stale replication epoch")
+)
+
+// Replica is the state a primary replicates to its standbys: a snapshot of
+// its agents, memory and pending tasks, the tasks it is running, and its
+// reputation registry with event history
+type Replica struct {
+	Epoch      uint64                          `json:"epoch"`
+	Primary    string                          `json:"primary"` // ID of the replicated collective
+	Snapshot   *Snapshot                       `json:"snapshot"`
+	Active     []*agent.Task                   `json:"active,omitempty"`
+	Reputation coordination.ReputationSnapshot `json:"reputation"`
+}
+
+// ReplicationSource is how a standby reaches its primary
+type ReplicationSource interface {
+	// Fetch returns the primary's current state
+	Fetch(ctx context.Context) (*Replica, error)
+
+	// Fence tells the primary that the collective by was promoted at epoch,
+	// so it must stop dispatching
+	Fence(ctx context.Context, epoch uint64, by string) error
+}
+
+// Epoch returns the collective's replication epoch
+func (c *Collective) Epoch() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.epoch
+}
+
+// Replicate returns the collective's state for its standbys
+func (c *Collective) Replicate() *Replica {
+	r := &Replica{
+		Epoch:      c.Epoch(),
+		Primary:    c.ID,
+		Snapshot:   c.Export(),
+		Reputation: c.reputation.Snapshot(),
+	}
+	for _, task := range c.TaskSnapshot(0).Active {
+		task := task
+		r.Active = append(r.Active, &task)
+	}
+	return r
+}
+
+// Fence marks the collective as superseded by a standby promoted at epoch
+// and puts it in maintenance mode, so two collectives never dispatch the
+// same work. Fencing again at the same epoch puts a collective that was
+// resumed back in maintenance. An epoch below the collective's own, or
+// equal to it on a collective that wasn't fenced, fails with ErrStaleEpoch.
+func (c *Collective) Fence(epoch uint64, by string) error {
+	c.mu.Lock()
+	if epoch < c.epoch || (epoch == c.epoch && c.fencedBy == "") {
+		current := c.epoch
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %d is not above %d", ErrStaleEpoch, epoch, current)
+	}
+	first := epoch > c.epoch
+	c.epoch = epoch
+	c.fencedBy = by
+	c.mu.Unlock()
+
+	c.EnterMaintenance(fmt.Sprintf("fenced: %s was promoted at epoch %d", by, epoch))
+	if first {
+		c.log().Warn("collective fenced", "epoch", epoch, "by", by)
+		c.events.Publish(Event{
+			Type: EventFenced,
+			Data: map[string]interface{}{"epoch": epoch, "by": by},
+		})
+	}
+	return nil
+}
+
+// FencedBy returns the collective whose promotion fenced this one off, or
+// "" if it hasn't been
+func (c *Collective) FencedBy() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fencedBy
+}
+
+// setEpoch raises the collective's epoch to at least epoch
+func (c *Collective) setEpoch(epoch uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if epoch > c.epoch {
+		c.epoch = epoch
+	}
+}
+
+// PromotionVoter decides how an agent of a standby votes on promoting it
+type PromotionVoter func(voter *agent.Agent, status StandbyStatus) bool
+
+// DefaultPromotionVoter approves a promotion once the primary has missed
+// enough syncs to be considered down, or when an operator forced it
+func DefaultPromotionVoter(voter *agent.Agent, status StandbyStatus) bool {
+	return status.PrimaryDown || status.Forced
+}
+
+// StandbyConfig configures a warm standby
+type StandbyConfig struct {
+	Interval         time.Duration // Between syncs with the primary
+	FailureThreshold int           // Consecutive failed syncs before the primary is considered down
+	AutoPromote      bool          // Propose promotion as soon as the primary is down
+
+	// NewAgent creates the replicated agents (nil = agent.NewAgent)
+	NewAgent func(cfg agent.AgentConfig) (*agent.Agent, error)
+
+	// Voter decides each agent's vote on a promotion (nil = DefaultPromotionVoter)
+	Voter PromotionVoter
+}
+
+// DefaultStandbyConfig returns sensible defaults
+func DefaultStandbyConfig() StandbyConfig {
+	return StandbyConfig{
+		Interval:         5 * time.Second,
+		FailureThreshold: 3,
+	}
+}
+
+// withDefaults fills unset fields from DefaultStandbyConfig
+func (cfg StandbyConfig) withDefaults() StandbyConfig {
+	defaults := DefaultStandbyConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaults.FailureThreshold
+	}
+	if cfg.NewAgent == nil {
+		cfg.NewAgent = agent.NewAgent
+	}
+	if cfg.Voter == nil {
+		cfg.Voter = DefaultPromotionVoter
+	}
+	return cfg
+}
+
+// StandbyStatus describes a standby's replication and promotion state
+type StandbyStatus struct {
+	Primary     string    `json:"primary,omitempty"` // ID of the collective replicated
+	Epoch       uint64    `json:"epoch"`
+	LastSync    time.Time `json:"last_sync,omitempty"`
+	Syncs       uint64    `json:"syncs"`
+	Failures    int       `json:"failures"` // Consecutive failed syncs
+	LastError   string    `json:"last_error,omitempty"`
+	PrimaryDown bool      `json:"primary_down"`
+	Forced      bool      `json:"forced,omitempty"` // An operator is forcing the promotion being voted on
+	Promoted    bool      `json:"promoted"`
+	PromotedAt  time.Time `json:"promoted_at,omitempty"`
+	Fenced      bool      `json:"fenced"` // The old primary acknowledged the promotion
+	Agents      int       `json:"agents"`
+	Pending     int       `json:"pending"` // Tasks to resubmit on promotion
+}
+
+// PromoteOptions controls a promotion
+type PromoteOptions struct {
+	Reason string
+
+	// Force skips checking the primary is unreachable. Agents still vote,
+	// and are told the promotion is forced.
+	Force bool
+}
+
+// Promotion is the outcome of a promotion proposal
+type Promotion struct {
+	ProposalID  string `json:"proposal_id"`
+	Epoch       uint64 `json:"epoch"`
+	Accepted    bool   `json:"accepted"`
+	Result      string `json:"result"` // accepted, rejected or timeout
+	For         int    `json:"for"`
+	Against     int    `json:"against"`
+	Resubmitted int    `json:"resubmitted"` // Pending and in-flight tasks of the primary submitted again
+	Fenced      bool   `json:"fenced"`      // The old primary acknowledged it was superseded
+}
+
+// Standby keeps a collective a warm copy of a primary: it syncs agents,
+// proficiencies, reputation, memory and unfinished tasks from the primary
+// and holds the collective in maintenance mode until it is promoted.
+//
+// Promotion takes a consensus round among the replicated agents, weighted
+// by reputation. Unless forced, it is refused while the primary still
+// answers. Once promoted, the collective runs at the next epoch, resubmits
+// the primary's unfinished tasks and keeps fencing the old primary, which
+// enters maintenance mode whenever it is reachable again, so the two never
+// dispatch side by side.
+type Standby struct {
+	mu sync.Mutex
+
+	collective *Collective
+	source     ReplicationSource
+	config     StandbyConfig
+
+	status    StandbyStatus
+	pending   []*agent.Task // Unfinished tasks of the last replica
+	promoting bool
+}
+
+// NewStandby makes c a standby of the primary source reaches and puts it
+// in maintenance mode. Call Run to start syncing.
+func NewStandby(c *Collective, source ReplicationSource, config StandbyConfig) *Standby {
+	c.EnterMaintenance("standby")
+	return &Standby{
+		collective: c,
+		source:     source,
+		config:     config.withDefaults(),
+	}
+}
+
+// Run syncs with the primary every interval until ctx ends, proposing
+// promotion once the primary is down if AutoPromote is set. After a
+// promotion it keeps fencing the old primary.
+func (s *Standby) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick does one round of Run's work
+func (s *Standby) tick(ctx context.Context) {
+	status := s.Status()
+	if status.Promoted {
+		s.fence(ctx)
+		return
+	}
+
+	if err := s.Sync(ctx); err == nil || !s.config.AutoPromote || !s.Status().PrimaryDown {
+		return
+	}
+	if _, err := s.Promote(ctx, PromoteOptions{Reason: "primary unreachable"}); err != nil {
+		s.collective.log().Warn("automatic promotion failed", "err", err)
+	}
+}
+
+// Sync fetches the primary's state and applies it. Agents the primary no
+// longer has leave; the rest take its proficiencies and reputations.
+func (s *Standby) Sync(ctx context.Context) error {
+	if s.Status().Promoted {
+		return ErrAlreadyPromoted
+	}
+
+	r, err := s.source.Fetch(ctx)
+	if err == nil && r.Snapshot == nil {
+		err = fmt.Errorf("%w: replica without snapshot", ErrInvalidSnapshot)
+	}
+	if err == nil && r.Epoch < s.collective.Epoch() {
+		err = fmt.Errorf("%w: primary at %d, standby at %d", ErrStaleEpoch, r.Epoch, s.collective.Epoch())
+	}
+	if err == nil {
+		err = s.apply(r)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.status.Failures++
+		s.status.LastError = err.Error()
+		if s.status.Failures >= s.config.FailureThreshold && !s.status.PrimaryDown {
+			s.status.PrimaryDown = true
+			s.collective.log().Warn("primary unreachable", "primary", s.status.Primary, "failures", s.status.Failures, "err", err)
+		}
+		return err
+	}
+
+	if s.status.PrimaryDown {
+		s.collective.log().Info("primary reachable again", "primary", r.Primary)
+	}
+	s.status.Primary = r.Primary
+	s.status.Epoch = r.Epoch
+	s.status.LastSync = time.Now()
+	s.status.Syncs++
+	s.status.Failures = 0
+	s.status.LastError = ""
+	s.status.PrimaryDown = false
+	s.status.Agents = len(r.Snapshot.Agents)
+	s.pending = append(append([]*agent.Task(nil), r.Snapshot.Pending...), r.Active...)
+	s.status.Pending = len(s.pending)
+	return nil
+}
+
+// apply mirrors a replica into the collective
+func (s *Standby) apply(r *Replica) error {
+	c := s.collective
+	if err := r.Snapshot.validate(); err != nil {
+		return err
+	}
+	if err := c.reputation.Restore(r.Reputation); err != nil {
+		return err
+	}
+
+	records := make(map[string]AgentRecord, len(r.Snapshot.Agents))
+	for _, rec := range r.Snapshot.Agents {
+		records[rec.Identity.SID] = rec
+	}
+	for _, a := range c.GetAgents() {
+		rec, ok := records[a.Identity.SID]
+		if !ok {
+			_ = c.Leave(a.Identity.SID)
+			continue
+		}
+		for capType, proficiency := range rec.Capabilities {
+			if !a.Capabilities.SetProficiency(capType, proficiency) {
+				a.Capabilities.Add(&identity.Capability{Type: capType, Proficiency: proficiency})
+			}
+		}
+		delete(records, a.Identity.SID)
+	}
+	for _, rec := range r.Snapshot.Agents {
+		if _, ok := records[rec.Identity.SID]; !ok {
+			continue
+		}
+		if _, err := c.importAgent(rec, s.config.NewAgent); err != nil {
+			return err
+		}
+	}
+
+	episodes := c.memory.restore(r.Snapshot.Memory)
+	c.setEpoch(r.Epoch)
+	c.log().Debug("replica applied", "primary", r.Primary, "epoch", r.Epoch, "agents", len(r.Snapshot.Agents), "episodes", episodes)
+	return nil
+}
+
+// Status returns the standby's replication and promotion state
+func (s *Standby) Status() StandbyStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Promote puts taking over from the primary to the replicated agents. Each
+// votes by the PromotionVoter, weighted by reputation. An accepted
+// promotion moves the collective to the next epoch, resumes dispatching,
+// resubmits the primary's pending and in-flight tasks and fences the old
+// primary. Fails with ErrPrimaryAlive if the primary answers and opts.Force
+// isn't set, and with ErrPromotionRejected if the vote fails.
+func (s *Standby) Promote(ctx context.Context, opts PromoteOptions) (*Promotion, error) {
+	s.mu.Lock()
+	switch {
+	case s.status.Promoted:
+		s.mu.Unlock()
+		return nil, ErrAlreadyPromoted
+	case s.promoting:
+		s.mu.Unlock()
+		return nil, ErrPromotionInProgress
+	case s.status.Syncs == 0 && !opts.Force:
+		s.mu.Unlock()
+		return nil, ErrNotReplicatedYet
+	}
+	s.promoting = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.promoting = false
+		s.mu.Unlock()
+	}()
+
+	// Split-brain guard: a primary that still answers keeps its role
+	if !opts.Force {
+		if _, err := s.source.Fetch(ctx); err == nil {
+			return nil, ErrPrimaryAlive
+		}
+	}
+
+	c := s.collective
+	status := s.Status()
+	status.Forced = opts.Force
+	promotion := &Promotion{Epoch: status.Epoch + 1}
+
+	round, err := c.consensus.Propose(ctx, c.ID, coordination.ConsensusTypePromotion, map[string]interface{}{
+		"primary": status.Primary,
+		"epoch":   promotion.Epoch,
+		"reason":  opts.Reason,
+		"forced":  opts.Force,
+	})
+	if err != nil {
+		return nil, err
+	}
+	promotion.ProposalID = round.Proposal.ID
+
+	weights := make(map[string]float64)
+	for sid, a := range c.agentMap() {
+		weight := 50.0 // Default
+		if rep := c.reputation.Get(sid); rep != nil {
+			weight = rep.Overall
+		}
+		weights[sid] = weight
+		_ = c.consensus.SubmitVote(coordination.Vote{
+			AgentSID:   sid,
+			ProposalID: round.Proposal.ID,
+			Value:      s.config.Voter(a, status),
+		})
+	}
+
+	promotion.Accepted, promotion.Result = c.consensus.CheckWeightedConsensus(round.Proposal.ID, weights)
+	if decided := c.consensus.GetRound(round.Proposal.ID); decided != nil {
+		for sid, vote := range decided.Votes {
+			if _, eligible := weights[sid]; !eligible {
+				continue
+			}
+			if vote.Value {
+				promotion.For++
+			} else {
+				promotion.Against++
+			}
+		}
+	}
+	if !promotion.Accepted {
+		c.log().Info("promotion rejected", "proposal", promotion.ProposalID, "for", promotion.For, "against", promotion.Against)
+		return promotion, fmt.Errorf("%w: %d for, %d against", ErrPromotionRejected, promotion.For, promotion.Against)
+	}
+
+	c.setEpoch(promotion.Epoch)
+	s.mu.Lock()
+	s.status.Promoted = true
+	s.status.PromotedAt = time.Now()
+	s.status.Epoch = promotion.Epoch
+	pending := s.pending
+	s.pending = nil
+	s.status.Pending = 0
+	s.mu.Unlock()
+
+	c.Resume()
+	for _, task := range pending {
+		if _, err := c.SubmitAsync(task); err == nil {
+			promotion.Resubmitted++
+		}
+	}
+	promotion.Fenced = s.fence(ctx)
+
+	c.log().Warn("standby promoted", "primary", status.Primary, "epoch", promotion.Epoch, "reason", opts.Reason,
+		"forced", opts.Force, "resubmitted", promotion.Resubmitted, "fenced", promotion.Fenced)
+	c.events.Publish(Event{
+		Type: EventPromoted,
+		Data: map[string]interface{}{
+			"primary":     status.Primary,
+			"epoch":       promotion.Epoch,
+			"reason":      opts.Reason,
+			"forced":      opts.Force,
+			"resubmitted": promotion.Resubmitted,
+		},
+	})
+	return promotion, nil
+}
+
+// fence tells the old primary it was superseded. A primary already at the
+// epoch counts as fenced. Reports whether the primary acknowledged it; an
+// unreachable primary is fenced the next time it answers.
+func (s *Standby) fence(ctx context.Context) bool {
+	c := s.collective
+	err := s.source.Fence(ctx, c.Epoch(), c.ID)
+	if err != nil && !errors.Is(err, ErrStaleEpoch) {
+		return false
+	}
+	s.mu.Lock()
+	if !s.status.Fenced {
+		s.status.Fenced = true
+		c.log().Info("old primary fenced", "primary", s.status.Primary, "epoch", c.Epoch())
+	}
+	s.mu.Unlock()
+	return true
+}
@@ -0,0 +1,162 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// localSource replicates a primary in the same process, and can be taken down
+type localSource struct {
+	mu      sync.Mutex
+	primary *Collective
+	down    bool
+}
+
+var errPrimaryDown = errors.New("primary down")
+
+func (s *localSource) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *localSource) Fetch(ctx context.Context) (*Replica, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return nil, errPrimaryDown
+	}
+	return s.primary.Replicate(), nil
+}
+
+func (s *localSource) Fence(ctx context.Context, epoch uint64, by string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return errPrimaryDown
+	}
+	return s.primary.Fence(epoch, by)
+}
+
+func TestStandby_SyncPromoteFence(t *testing.T) {
+	primary := NewCollective("Primary", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = primary.Join(a)
+	primary.reputation.RecordTaskSuccess(a.Identity.SID, 0.9)
+	primary.memory.Contribute(a.Identity.SID, "deploys happen on fridays", nil)
+
+	source := &localSource{primary: primary}
+	c := NewCollective("Standby", DefaultCollectiveConfig())
+	standby := NewStandby(c, source, StandbyConfig{FailureThreshold: 2})
+	ctx := context.Background()
+
+	if c.Mode().Mode != ModeMaintenance {
+		t.Errorf("Expected a standby in maintenance mode, got %s", c.Mode().Mode)
+	}
+	if _, err := standby.Promote(ctx, PromoteOptions{}); !errors.Is(err, ErrNotReplicatedYet) {
+		t.Errorf("Expected ErrNotReplicatedYet, got %v", err)
+	}
+
+	if err := standby.Sync(ctx); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	replicated, ok := c.GetAgent(a.Identity.SID)
+	if !ok {
+		t.Fatal("Expected the primary's agent to be replicated")
+	}
+	if got, want := c.reputation.Get(a.Identity.SID).TasksCompleted, primary.reputation.Get(a.Identity.SID).TasksCompleted; got != want {
+		t.Errorf("Expected %d completed tasks, got %d", want, got)
+	}
+	if len(c.memory.Query("fridays")) != 1 {
+		t.Error("Expected memory to be replicated")
+	}
+
+	// Proficiency changes follow the primary
+	a.Capabilities.Add(&identity.Capability{Type: identity.CapCodeWrite, Proficiency: 0.95})
+	_ = standby.Sync(ctx)
+	if p := replicated.Capabilities.Proficiency(identity.CapCodeWrite); p != 0.95 {
+		t.Errorf("Expected proficiency 0.95, got %f", p)
+	}
+
+	if _, err := standby.Promote(ctx, PromoteOptions{}); !errors.Is(err, ErrPrimaryAlive) {
+		t.Errorf("Expected ErrPrimaryAlive, got %v", err)
+	}
+
+	source.setDown(true)
+	_ = standby.Sync(ctx)
+	if standby.Status().PrimaryDown {
+		t.Error("Expected one failure to be below the threshold")
+	}
+	_ = standby.Sync(ctx)
+	if !standby.Status().PrimaryDown {
+		t.Error("Expected the primary to be down after two failures")
+	}
+
+	promotion, err := standby.Promote(ctx, PromoteOptions{Reason: "test"})
+	if err != nil {
+		t.Fatalf("Promote failed: %v", err)
+	}
+	if !promotion.Accepted || promotion.For != 1 || promotion.Epoch != 1 || promotion.Fenced {
+		t.Errorf("Expected an accepted, unfenced promotion to epoch 1, got %+v", promotion)
+	}
+	if c.Epoch() != 1 || c.Mode().Mode != ModeRunning {
+		t.Errorf("Expected a running collective at epoch 1, got %s at %d", c.Mode().Mode, c.Epoch())
+	}
+	if _, err := standby.Promote(ctx, PromoteOptions{}); !errors.Is(err, ErrAlreadyPromoted) {
+		t.Errorf("Expected ErrAlreadyPromoted, got %v", err)
+	}
+
+	// The old primary is fenced when it comes back, and again if resumed
+	source.setDown(false)
+	standby.tick(ctx)
+	if primary.FencedBy() != c.ID || primary.Mode().Mode != ModeMaintenance || primary.Epoch() != 1 {
+		t.Errorf("Expected the old primary fenced at epoch 1, got %q %s %d", primary.FencedBy(), primary.Mode().Mode, primary.Epoch())
+	}
+	if !standby.Status().Fenced {
+		t.Error("Expected the standby to record the fence")
+	}
+	primary.Resume()
+	standby.tick(ctx)
+	if primary.Mode().Mode != ModeMaintenance {
+		t.Errorf("Expected a resumed old primary to be fenced again, got %s", primary.Mode().Mode)
+	}
+	if err := c.Fence(1, "someone"); !errors.Is(err, ErrStaleEpoch) {
+		t.Errorf("Expected ErrStaleEpoch fencing the promoted collective at its own epoch, got %v", err)
+	}
+}
+
+func TestStandby_PromotionRejected(t *testing.T) {
+	primary := NewCollective("Primary", DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = primary.Join(a)
+
+	source := &localSource{primary: primary}
+	c := NewCollective("Standby", DefaultCollectiveConfig())
+	standby := NewStandby(c, source, StandbyConfig{})
+	ctx := context.Background()
+	_ = standby.Sync(ctx)
+
+	// The primary still answers, so only a forced promotion gets to a vote,
+	// which agents approving only when the primary is down reject
+	standby.config.Voter = func(voter *agent.Agent, status StandbyStatus) bool { return status.PrimaryDown }
+	promotion, err := standby.Promote(ctx, PromoteOptions{Force: true})
+	if !errors.Is(err, ErrPromotionRejected) {
+		t.Fatalf("Expected ErrPromotionRejected, got %v", err)
+	}
+	if promotion.Against != 1 || c.Epoch() != 0 || c.Mode().Mode != ModeMaintenance {
+		t.Errorf("Expected a rejected promotion to change nothing, got %+v at epoch %d", promotion, c.Epoch())
+	}
+
+	standby.config.Voter = DefaultPromotionVoter
+	if _, err := standby.Promote(ctx, PromoteOptions{Force: true}); err != nil {
+		t.Fatalf("Expected a forced promotion to pass, got %v", err)
+	}
+	if primary.FencedBy() != c.ID {
+		t.Error("Expected the reachable primary to be fenced at once")
+	}
+}
@@ -0,0 +1,359 @@
+package collective
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/sandbox"
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+// Where a collective with a storage backend keeps its state
+const (
+	ResultCollection = "tasks"      // Documents: finished task results by task ID
+	ArtifactPrefix   = "artifacts/" // Blobs: artifacts/sha256/<ab>/<hex>, content-addressed
+	AuditPrefix      = "audit/"     // KV: activity events in time order
+
+	episodeCollection = "memory.episodes"
+	conceptCollection = "memory.concepts"
+	contextCollection = "memory.contexts"
+	nodeCollection    = "memory.nodes"
+	edgeCollection    = "memory.edges"
+)
+
+// DocumentMemoryStore is a MemoryStore in a document store. Episode
+// queries read every stored episode, so large memories are better kept in
+// an SQLiteMemoryStore.
+type DocumentMemoryStore struct {
+	docs storage.Documents
+}
+
+// NewDocumentMemoryStore creates a memory store in docs
+func NewDocumentMemoryStore(docs storage.Documents) *DocumentMemoryStore {
+	return &DocumentMemoryStore{docs: docs}
+}
+
+// docID escapes a record ID into a document ID
+func docID(parts ...string) string {
+	for i, p := range parts {
+		parts[i] = url.QueryEscape(p)
+	}
+	return strings.Join(parts, ":")
+}
+
+// SaveEpisode inserts or replaces an episode
+func (s *DocumentMemoryStore) SaveEpisode(ep CollectiveEpisode) error {
+	return s.docs.Put(context.Background(), episodeCollection, docID(ep.ID), ep)
+}
+
+// QueryEpisodes returns the episodes whose content contains query
+func (s *DocumentMemoryStore) QueryEpisodes(query string) ([]CollectiveEpisode, error) {
+	episodes, err := s.episodes()
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(query)
+	matches := make([]CollectiveEpisode, 0)
+	for _, ep := range episodes {
+		if strings.Contains(strings.ToLower(ep.Content), query) {
+			matches = append(matches, ep)
+		}
+	}
+	return matches, nil
+}
+
+// CountEpisodes returns the number of stored episodes
+func (s *DocumentMemoryStore) CountEpisodes() (int, error) {
+	ids, err := s.docs.List(context.Background(), episodeCollection)
+	return len(ids), err
+}
+
+// SaveConcept inserts or replaces a concept
+func (s *DocumentMemoryStore) SaveConcept(c *Concept) error {
+	return s.docs.Put(context.Background(), conceptCollection, docID(c.ID), c)
+}
+
+// SaveContext inserts or replaces a shared context
+func (s *DocumentMemoryStore) SaveContext(ctx *SharedContext) error {
+	return s.docs.Put(context.Background(), contextCollection, docID(ctx.ID), ctx)
+}
+
+// DeleteContext removes a shared context
+func (s *DocumentMemoryStore) DeleteContext(id string) error {
+	return s.docs.Delete(context.Background(), contextCollection, docID(id))
+}
+
+// SaveNode inserts or replaces a knowledge graph node
+func (s *DocumentMemoryStore) SaveNode(node *KnowledgeNode) error {
+	return s.docs.Put(context.Background(), nodeCollection, docID(node.ID), node)
+}
+
+// SaveEdge inserts or replaces a knowledge graph edge
+func (s *DocumentMemoryStore) SaveEdge(edge *KnowledgeEdge) error {
+	return s.docs.Put(context.Background(), edgeCollection, docID(edge.From, edge.To, edge.Type), edge)
+}
+
+// Load reads the stored memory
+func (s *DocumentMemoryStore) Load(limit int) (*MemorySnapshot, error) {
+	episodes, err := s.episodes()
+	if err != nil {
+		return nil, err
+	}
+	if len(episodes) > limit {
+		episodes = episodes[len(episodes)-limit:]
+	}
+	snapshot := &MemorySnapshot{Episodes: episodes}
+
+	if snapshot.Concepts, err = loadDocuments[Concept](s.docs, conceptCollection); err != nil {
+		return nil, err
+	}
+	if snapshot.Contexts, err = loadDocuments[SharedContext](s.docs, contextCollection); err != nil {
+		return nil, err
+	}
+	if snapshot.Nodes, err = loadDocuments[KnowledgeNode](s.docs, nodeCollection); err != nil {
+		return nil, err
+	}
+	if snapshot.Edges, err = loadDocuments[KnowledgeEdge](s.docs, edgeCollection); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Close is a no-op; the storage belongs to whoever opened it
+func (s *DocumentMemoryStore) Close() error {
+	return nil
+}
+
+// episodes returns every stored episode, oldest first
+func (s *DocumentMemoryStore) episodes() ([]CollectiveEpisode, error) {
+	loaded, err := loadDocuments[CollectiveEpisode](s.docs, episodeCollection)
+	if err != nil {
+		return nil, err
+	}
+	episodes := make([]CollectiveEpisode, len(loaded))
+	for i, ep := range loaded {
+		episodes[i] = *ep
+	}
+	sort.SliceStable(episodes, func(i, j int) bool { return episodes[i].Timestamp.Before(episodes[j].Timestamp) })
+	return episodes, nil
+}
+
+// loadDocuments reads every document of a collection
+func loadDocuments[T any](docs storage.Documents, collection string) ([]*T, error) {
+	ctx := context.Background()
+	ids, err := docs.List(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*T, 0, len(ids))
+	for _, id := range ids {
+		record := new(T)
+		if err := docs.Get(ctx, collection, id, record); err != nil {
+			return nil, fmt.Errorf("%s/%s: %w", collection, id, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// openStorage opens the configured storage backend and restores reputation
+// and memory from it
+func (c *Collective) openStorage(cfg storage.Config) error {
+	s, err := storage.Open(cfg)
+	if err != nil {
+		return err
+	}
+	if err := c.reputation.LoadFrom(context.Background(), s.Documents()); err != nil {
+		c.logger.Warn("could not restore reputation", "driver", cfg.Driver, "error", err)
+	}
+	memory, err := NewPersistentMemory(NewDocumentMemoryStore(s.Documents()))
+	if err != nil {
+		c.logger.Warn("could not restore memory, keeping memory in-process", "driver", cfg.Driver, "error", err)
+	} else {
+		c.memory = memory
+	}
+	c.store = s
+	return nil
+}
+
+// Storage returns the collective's storage backend, or nil if it has none
+func (c *Collective) Storage() storage.Storage {
+	return c.store
+}
+
+// persistResult stores a finished task's result, with its output,
+// checkpoints, tool results and generated files as content-addressed
+// artifacts referenced from result.Artifacts. Call it before the result is
+// shared.
+func (c *Collective) persistResult(result *agent.TaskResult) {
+	if c.store == nil {
+		return
+	}
+	ctx := context.Background()
+	result.Artifacts = c.storeArtifacts(ctx, result)
+	if err := c.store.Documents().Put(ctx, ResultCollection, result.TaskID, result); err != nil {
+		c.log().Warn("could not store task result", "task", result.TaskID, "error", err)
+	}
+}
+
+// storeArtifacts stores the non-empty pieces of a result and returns
+// references to them. Pieces that fail to store are left out.
+func (c *Collective) storeArtifacts(ctx context.Context, result *agent.TaskResult) []agent.Artifact {
+	var artifacts []agent.Artifact
+	add := func(name, content string) {
+		if content == "" {
+			return
+		}
+		digest, err := c.artifactStore().Put(ctx, []byte(content))
+		if err != nil {
+			c.log().Warn("could not store task artifact", "task", result.TaskID, "artifact", name, "error", err)
+			return
+		}
+		artifacts = append(artifacts, agent.Artifact{Name: name, Digest: digest, Size: len(content)})
+	}
+
+	add(agent.ArtifactOutput, result.Output)
+	for _, cp := range result.Checkpoints {
+		add(agent.ArtifactCheckpointPrefix+cp.Label, cp.State)
+	}
+	for i, tr := range result.ToolResults {
+		add(fmt.Sprintf("%s%d-%s", agent.ArtifactToolPrefix, i+1, tr.Tool), tr.Output)
+	}
+	if program, err := sandbox.ExtractProgram(result.Output); err == nil {
+		paths := make([]string, 0, len(program.Files))
+		for path := range program.Files {
+			if storage.ValidKey(path) {
+				paths = append(paths, path)
+			}
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			add(agent.ArtifactFilePrefix+path, program.Files[path])
+		}
+	}
+	return artifacts
+}
+
+// artifactStore returns the content-addressed store of task artifacts
+func (c *Collective) artifactStore() *storage.ContentStore {
+	return storage.NewContentStore(c.store.Blobs(), ArtifactPrefix)
+}
+
+// legacyArtifactKey returns the blob key older releases kept a task's
+// output under
+func legacyArtifactKey(taskID string) string {
+	return ArtifactPrefix + url.QueryEscape(taskID) + "/output"
+}
+
+// StoredResult reads a finished task's result from storage
+func (c *Collective) StoredResult(ctx context.Context, taskID string) (*agent.TaskResult, error) {
+	if c.store == nil {
+		return nil, fmt.Errorf("%w: collective has no storage", storage.ErrNotFound)
+	}
+	var result agent.TaskResult
+	if err := c.store.Documents().Get(ctx, ResultCollection, taskID, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Artifact opens the stored output of a finished task
+func (c *Collective) Artifact(ctx context.Context, taskID string) (io.ReadCloser, error) {
+	result, err := c.StoredResult(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if output, ok := result.Artifact(agent.ArtifactOutput); ok {
+		return c.artifactStore().Get(ctx, output.Digest)
+	}
+	return c.store.Blobs().Get(ctx, legacyArtifactKey(taskID))
+}
+
+// TaskArtifacts returns the artifacts stored for a finished task
+func (c *Collective) TaskArtifacts(ctx context.Context, taskID string) ([]agent.Artifact, error) {
+	result, err := c.StoredResult(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return result.Artifacts, nil
+}
+
+// OpenArtifact opens stored content by digest. Reading it to the end fails
+// with storage.ErrDigestMismatch if the blob no longer matches.
+func (c *Collective) OpenArtifact(ctx context.Context, digest string) (io.ReadCloser, error) {
+	if c.store == nil {
+		return nil, fmt.Errorf("%w: collective has no storage", storage.ErrNotFound)
+	}
+	return c.artifactStore().Get(ctx, digest)
+}
+
+// ArtifactURL returns a URL reading a finished task's stored output until it
+// expires, for sharing with reviewers outside the collective. The blob store
+// must be able to sign URLs (S3 or Cloud Storage).
+func (c *Collective) ArtifactURL(ctx context.Context, taskID string, expires time.Duration) (string, error) {
+	result, err := c.StoredResult(ctx, taskID)
+	if err != nil {
+		return "", err
+	}
+	if result.Output == "" {
+		return "", fmt.Errorf("%w: task %s has no artifact", storage.ErrNotFound, taskID)
+	}
+	if output, ok := result.Artifact(agent.ArtifactOutput); ok {
+		return c.artifactStore().URL(ctx, output.Digest, expires)
+	}
+	return storage.SignedURL(ctx, c.store.Blobs(), legacyArtifactKey(taskID), expires)
+}
+
+// auditSeq orders audit records written in the same nanosecond
+var auditSeq atomic.Uint64
+
+// recordAudit appends every event to the audit log until the event bus closes
+func (c *Collective) recordAudit(events <-chan Event) {
+	kv := c.store.KV()
+	for e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			continue
+		}
+		key := fmt.Sprintf("%s%020d-%08d", AuditPrefix, e.Timestamp.UnixNano(), auditSeq.Add(1)%1e8)
+		if err := kv.Put(context.Background(), key, data); err != nil {
+			c.log().Warn("could not write audit record", "event", e.Type, "error", err)
+		}
+	}
+}
+
+// AuditLog returns the recorded activity events at or after since, oldest
+// first. Events are recorded while the collective runs with storage.
+func (c *Collective) AuditLog(ctx context.Context, since time.Time) ([]Event, error) {
+	if c.store == nil {
+		return nil, fmt.Errorf("%w: collective has no storage", storage.ErrNotFound)
+	}
+	kv := c.store.KV()
+	keys, err := kv.List(ctx, AuditPrefix)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, 0, len(keys))
+	for _, key := range keys {
+		data, err := kv.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if !e.Timestamp.Before(since) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
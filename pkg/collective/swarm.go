@@ -0,0 +1,376 @@
+package collective
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+// ErrInvalidPlan is returned when an orchestrator's decomposition can't be used
+var ErrInvalidPlan = errors.New("invalid swarm plan")
+
+// SwarmConfig bounds task decomposition
+type SwarmConfig struct {
+	MaxSubtasks int `json:"max_subtasks"`
+	Retries     int `json:"retries"` // Extra attempts per subtask before the swarm fails
+}
+
+// DefaultSwarmConfig returns sensible defaults
+func DefaultSwarmConfig() SwarmConfig {
+	return SwarmConfig{
+		MaxSubtasks: 8,
+		Retries:     1,
+	}
+}
+
+// Subtask is one piece of a decomposed task
+type Subtask struct {
+	ID         string                    `json:"id"`
+	Task       string                    `json:"task"`
+	Requires   []identity.CapabilityType `json:"requires,omitempty"`
+	Complexity string                    `json:"complexity,omitempty"`
+	DependsOn  []string                  `json:"depends_on,omitempty"`
+}
+
+// SwarmPlan is an orchestrator's decomposition of a task into subtasks
+type SwarmPlan struct {
+	Orchestrator string    `json:"orchestrator"` // SID of the agent that made the plan
+	Subtasks     []Subtask `json:"subtasks"`
+}
+
+// SwarmResult is the outcome of a swarm execution
+type SwarmResult struct {
+	Plan      *SwarmPlan        `json:"plan"`
+	Run       *workflow.Run     `json:"run"`
+	Synthesis *agent.TaskResult `json:"synthesis,omitempty"`
+	Output    string            `json:"output"`
+
+	TokensUsed int `json:"tokens_used,omitempty"` // Tokens spent by a pipeline's phases
+}
+
+// SetOrchestrator designates the agent that decomposes swarm tasks and
+// synthesizes their results. Without one, the market picks the agent best
+// suited to planning.
+func (c *Collective) SetOrchestrator(sid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.agents[sid]; !ok {
+		return ErrAgentNotFound
+	}
+	c.orchestrator = sid
+	return nil
+}
+
+// Orchestrator returns the designated orchestrator's SID, or "" if none
+func (c *Collective) Orchestrator() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.orchestrator
+}
+
+// Decompose asks the orchestrator to break a task into subtasks
+func (c *Collective) Decompose(ctx context.Context, task *agent.Task) (*SwarmPlan, error) {
+	cfg := c.swarmConfig()
+	planning := agent.NewTask(decomposePrompt(task, cfg.MaxSubtasks), []identity.CapabilityType{identity.CapArchitecture, identity.CapAnalysis}).
+		WithComplexity(task.Complexity).
+		WithTeam(task.Team).
+		WithSubmitter(task.Submitter).
+		WithResponseFormat(planFormat())
+
+	result, err := c.submitToOrchestrator(ctx, planning)
+	if err != nil {
+		return nil, fmt.Errorf("decompose: %w", err)
+	}
+
+	plan, err := ParseSwarmPlan(result.Output)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Subtasks) > cfg.MaxSubtasks {
+		return nil, fmt.Errorf("%w: %d subtasks exceeds the limit of %d", ErrInvalidPlan, len(plan.Subtasks), cfg.MaxSubtasks)
+	}
+	plan.Orchestrator = result.AgentSID
+	c.log().Info("task decomposed", "task", task.ID, "orchestrator", plan.Orchestrator, "subtasks", len(plan.Subtasks))
+	return plan, nil
+}
+
+// ExecuteSwarm decomposes a task, runs the subtasks as a workflow on the
+// collective (independent subtasks in parallel, each seeing the results it
+// depends on), and has the orchestrator synthesize a final answer. If a
+// subtask fails, the partial run is returned with the error. With a
+// checkpoint store, the run's progress is saved under the task's ID.
+func (c *Collective) ExecuteSwarm(ctx context.Context, task *agent.Task) (*SwarmResult, error) {
+	return c.executeSwarm(ctx, task, newCheckpoint(task, nil))
+}
+
+// executeSwarm runs the phases of a swarm that cp hasn't recorded as done
+func (c *Collective) executeSwarm(ctx context.Context, task *agent.Task, cp *SwarmCheckpoint) (swarm *SwarmResult, err error) {
+	defer func() { c.finishCheckpoint(cp, swarm, err) }()
+
+	plan := cp.Plan
+	if plan == nil {
+		if plan, err = c.Decompose(ctx, task); err != nil {
+			return nil, err
+		}
+		cp.Plan = plan
+		c.saveCheckpoint(cp)
+	} else if sid := c.Orchestrator(); sid != "" {
+		// The saved plan's orchestrator belongs to the collective that made it
+		plan.Orchestrator = sid
+	}
+	swarm = &SwarmResult{Plan: plan}
+
+	wf, err := plan.Workflow(task, c.swarmConfig().Retries)
+	if err != nil {
+		return swarm, err
+	}
+	engine := c.swarmEngine(c, cp, nil)
+	if cp.Run != nil {
+		swarm.Run, err = engine.Resume(ctx, wf, cp.Run)
+	} else {
+		swarm.Run, err = engine.Run(ctx, wf, nil)
+	}
+	if err != nil {
+		return swarm, err
+	}
+
+	synthesis := agent.NewTask(synthesisPrompt(task, plan, swarm.Run), []identity.CapabilityType{identity.CapDocumentation, identity.CapAnalysis}).
+		WithComplexity(task.Complexity).
+		WithTeam(task.Team).
+		WithSubmitter(task.Submitter)
+	c.pin(synthesis.ID, plan.Orchestrator)
+	defer c.unpin(synthesis.ID)
+
+	swarm.Synthesis, err = c.SubmitCtx(ctx, synthesis)
+	if err == nil && swarm.Synthesis.Status != agent.TaskCompleted {
+		err = fmt.Errorf("synthesis failed: %s", swarm.Synthesis.Error)
+	}
+	if err != nil {
+		return swarm, err
+	}
+	swarm.Output = swarm.Synthesis.Output
+	return swarm, nil
+}
+
+// swarmEngine returns a workflow engine for a swarm's subtasks that saves
+// the run to cp as each completes. spent reports the tokens used so far, if
+// they are counted.
+func (c *Collective) swarmEngine(s workflow.Submitter, cp *SwarmCheckpoint, spent func() int) *workflow.Engine {
+	engine := workflow.NewEngine(s)
+	c.mu.RLock()
+	logger := c.componentLoggerLocked("swarm")
+	c.mu.RUnlock()
+	if cp.Pipeline != nil {
+		logger = logger.With("pipeline", cp.Pipeline.Name)
+	}
+	engine.SetLogger(logger)
+	engine.SetCheckpoint(func(run *workflow.Run) {
+		cp.Run = run
+		if spent != nil {
+			cp.TokensUsed = spent()
+		}
+		c.saveCheckpoint(cp)
+	})
+	return engine
+}
+
+// submitToOrchestrator runs a task on the designated orchestrator, or the
+// market's choice if there is none
+func (c *Collective) submitToOrchestrator(ctx context.Context, task *agent.Task) (*agent.TaskResult, error) {
+	if sid := c.Orchestrator(); sid != "" {
+		c.pin(task.ID, sid)
+		defer c.unpin(task.ID)
+	}
+
+	result, err := c.SubmitCtx(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status != agent.TaskCompleted {
+		return nil, fmt.Errorf("orchestrator failed: %s", result.Error)
+	}
+	return result, nil
+}
+
+// swarmConfig returns the swarm limits, with defaults for unset fields
+func (c *Collective) swarmConfig() SwarmConfig {
+	cfg := c.config.Swarm
+	if cfg.MaxSubtasks <= 0 {
+		cfg.MaxSubtasks = DefaultSwarmConfig().MaxSubtasks
+	}
+	return cfg
+}
+
+// pin routes a task to a specific agent instead of the market
+func (c *Collective) pin(taskID, sid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pins[taskID] = sid
+}
+
+func (c *Collective) unpin(taskID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pins, taskID)
+}
+
+// pinnedAssignment returns an assignment to the agent a task is pinned to, or
+// nil if it isn't pinned or the agent has left
+func (c *Collective) pinnedAssignment(task *agent.Task) *coordination.TaskAssignment {
+	c.mu.RLock()
+	sid := c.pins[task.ID]
+	a, ok := c.agents[sid]
+	c.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	c.timelines.Record(task.ID, StageListed, sid, "pinned")
+	return &coordination.TaskAssignment{
+		TaskID:   task.ID,
+		AgentSID: sid,
+		Bid: &coordination.Bid{
+			AgentSID:        sid,
+			TaskID:          task.ID,
+			CapabilityScore: a.Capabilities.MatchScore(task.Required),
+			Timestamp:       time.Now(),
+		},
+	}
+}
+
+// ParseSwarmPlan reads a plan from an orchestrator's output: a JSON object
+// with a "subtasks" array, optionally surrounded by prose or a code fence
+func ParseSwarmPlan(output string) (*SwarmPlan, error) {
+	start, end := strings.Index(output, "{"), strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON object in orchestrator output", ErrInvalidPlan)
+	}
+
+	var plan SwarmPlan
+	if err := json.Unmarshal([]byte(output[start:end+1]), &plan); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	if len(plan.Subtasks) == 0 {
+		return nil, fmt.Errorf("%w: no subtasks", ErrInvalidPlan)
+	}
+	for _, s := range plan.Subtasks {
+		if s.ID == "" || strings.TrimSpace(s.Task) == "" {
+			return nil, fmt.Errorf("%w: every subtask needs an id and a task", ErrInvalidPlan)
+		}
+	}
+	return &plan, nil
+}
+
+// Workflow converts the plan into a workflow whose steps are the subtasks.
+// Each step's task includes the overall goal and the output of the subtasks
+// it depends on.
+func (p *SwarmPlan) Workflow(task *agent.Task, retries int) (*workflow.Workflow, error) {
+	wf := &workflow.Workflow{
+		Name:        "swarm-" + task.ID,
+		Description: task.Description,
+	}
+	for _, s := range p.Subtasks {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s\n\nThis is part of a larger task: %s", literal(s.Task), literal(task.Description))
+		if len(s.DependsOn) > 0 {
+			b.WriteString("\n\nResults of the subtasks this builds on:")
+			for _, dep := range s.DependsOn {
+				fmt.Fprintf(&b, "\n\n=== %s ===\n{{(index .Steps %q).Output}}", literal(dep), dep)
+			}
+		}
+
+		wf.Steps = append(wf.Steps, workflow.Step{
+			ID:         s.ID,
+			Task:       b.String(),
+			Requires:   s.Requires,
+			Complexity: s.Complexity,
+			Team:       task.Team,
+			DependsOn:  s.DependsOn,
+			Retries:    retries,
+		})
+	}
+
+	if err := wf.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	return wf, nil
+}
+
+// literal escapes text for use verbatim in a workflow template
+func literal(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+// decomposePrompt asks the orchestrator for a plan, offering it every
+// registered capability, custom ones included, for its subtasks
+func decomposePrompt(task *agent.Task, maxSubtasks int) string {
+	types := identity.DefaultCapabilityRegistry().Types()
+	caps := make([]string, len(types))
+	for i, c := range types {
+		caps[i] = string(c)
+	}
+
+	return fmt.Sprintf(`You are the orchestrator of a collective of specialized agents. Break the task below into at most %d subtasks that agents can work on independently, in parallel where possible.
+
+Task:
+%s
+
+Respond with only a JSON object of this form:
+{"subtasks": [{"id": "research", "task": "what to do", "requires": ["research"], "complexity": "low|medium|high", "depends_on": []}]}
+
+Use short snake_case ids. "depends_on" lists the ids of subtasks whose results this one needs. "requires" lists capabilities from: %s.`,
+		maxSubtasks, task.Description, strings.Join(caps, ", "))
+}
+
+// planFormat holds an orchestrator's response to the form of a plan, with
+// subtasks requiring registered capabilities
+func planFormat() *llm.ResponseFormat {
+	types := identity.DefaultCapabilityRegistry().Types()
+	caps, _ := json.Marshal(types)
+	return llm.JSONSchema("swarm_plan", fmt.Sprintf(`{
+	"type": "object",
+	"properties": {
+		"subtasks": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"id": {"type": "string"},
+					"task": {"type": "string"},
+					"requires": {"type": "array", "items": {"type": "string", "enum": %s}},
+					"complexity": {"type": "string", "enum": ["low", "medium", "high"]},
+					"depends_on": {"type": "array", "items": {"type": "string"}}
+				},
+				"required": ["id", "task", "requires", "complexity", "depends_on"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["subtasks"],
+	"additionalProperties": false
+}`, caps))
+}
+
+// synthesisPrompt asks the orchestrator to combine the subtask results
+func synthesisPrompt(task *agent.Task, plan *SwarmPlan, run *workflow.Run) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Original task:\n%s\n\nYour collective completed these subtasks:", task.Description)
+	for _, s := range plan.Subtasks {
+		step := run.Steps[s.ID]
+		if step == nil || step.Status != workflow.StepCompleted {
+			continue
+		}
+		fmt.Fprintf(&b, "\n\n=== %s: %s ===\n%s", s.ID, s.Task, step.Output)
+	}
+	b.WriteString("\n\nSynthesize these into a single, coherent, well-structured final response to the original task. Resolve any conflicts between them.")
+	return b.String()
+}
@@ -0,0 +1,327 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+// scriptedProvider plans, works and synthesizes according to the prompt
+type scriptedProvider struct {
+	mu      sync.Mutex
+	plan    string
+	prompts []string
+}
+
+func (p *scriptedProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, req.Prompt)
+	p.mu.Unlock()
+
+	// Answer by the task statement, not memories recalled into the prompt
+	task := req.Prompt
+	if i := strings.LastIndex(task, "Your task:\n"); i >= 0 {
+		task = task[i:]
+	}
+	switch {
+	case strings.Contains(task, "orchestrator of a collective"):
+		return &llm.CompletionResponse{Content: p.plan}, nil
+	case strings.Contains(task, "Synthesize these"):
+		return &llm.CompletionResponse{Content: "final answer"}, nil
+	case strings.Contains(task, "Survey caches"):
+		return &llm.CompletionResponse{Content: "LRU is fine"}, nil
+	default:
+		return &llm.CompletionResponse{Content: "design done"}, nil
+	}
+}
+
+func (p *scriptedProvider) Name() string {
+	return "scripted"
+}
+
+func (p *scriptedProvider) prompt(substr string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, prompt := range p.prompts {
+		if strings.Contains(prompt, substr) {
+			return prompt
+		}
+	}
+	return ""
+}
+
+func TestParseSwarmPlan(t *testing.T) {
+	plan, err := ParseSwarmPlan("Here is the plan:\n```json\n{\"subtasks\": [{\"id\": \"a\", \"task\": \"do a\"}]}\n```")
+	if err != nil {
+		t.Fatalf("ParseSwarmPlan failed: %v", err)
+	}
+	if len(plan.Subtasks) != 1 || plan.Subtasks[0].ID != "a" {
+		t.Errorf("Expected subtask a, got %+v", plan.Subtasks)
+	}
+
+	for _, output := range []string{"no json here", `{"subtasks": []}`, `{"subtasks": [{"id": "a"}]}`} {
+		if _, err := ParseSwarmPlan(output); !errors.Is(err, ErrInvalidPlan) {
+			t.Errorf("Expected ErrInvalidPlan for %q, got %v", output, err)
+		}
+	}
+}
+
+func TestSwarmPlan_Workflow(t *testing.T) {
+	task := agent.NewTask("Build {{a}} cache", nil)
+	plan := &SwarmPlan{Subtasks: []Subtask{
+		{ID: "research", Task: "Survey {{caches}}"},
+		{ID: "design", Task: "Design it", DependsOn: []string{"research"}},
+	}}
+	if _, err := plan.Workflow(task, 1); err != nil {
+		t.Errorf("Expected literal braces to be escaped, got %v", err)
+	}
+
+	plan.Subtasks[1].DependsOn = []string{"missing"}
+	if _, err := plan.Workflow(task, 1); !errors.Is(err, ErrInvalidPlan) {
+		t.Errorf("Expected ErrInvalidPlan for unknown dependency, got %v", err)
+	}
+}
+
+func TestCollective_ExecuteSwarm(t *testing.T) {
+	provider := &scriptedProvider{plan: `{"subtasks": [
+		{"id": "research", "task": "Survey caches", "requires": ["research"]},
+		{"id": "design", "task": "Design the cache", "requires": ["architecture"], "depends_on": ["research"]}
+	]}`}
+
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	planner, _ := agent.NewAgent(agent.AgentConfig{Name: "Planner", Provider: provider, Capabilities: []identity.CapabilityType{identity.CapDocumentation}})
+	worker, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider, Capabilities: []identity.CapabilityType{identity.CapResearch, identity.CapArchitecture, identity.CapAnalysis}})
+	_ = c.Join(planner)
+	_ = c.Join(worker)
+	if err := c.SetOrchestrator("missing"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+	if err := c.SetOrchestrator(planner.Identity.SID); err != nil {
+		t.Fatalf("SetOrchestrator failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	result, err := c.ExecuteSwarm(ctx, agent.NewTask("Add a cache", nil))
+	if err != nil {
+		t.Fatalf("ExecuteSwarm failed: %v", err)
+	}
+
+	// The designated orchestrator plans and synthesizes even though the
+	// worker is better suited by capability
+	if result.Plan.Orchestrator != planner.Identity.SID || result.Synthesis.AgentSID != planner.Identity.SID {
+		t.Errorf("Expected planner to orchestrate, got plan by %s and synthesis by %s", result.Plan.Orchestrator, result.Synthesis.AgentSID)
+	}
+	if result.Run.Status != workflow.RunCompleted || result.Output != "final answer" {
+		t.Errorf("Expected completed run with final answer, got %s: %q", result.Run.Status, result.Output)
+	}
+	if !strings.Contains(provider.prompt("Design the cache"), "LRU is fine") {
+		t.Error("Expected dependent subtask to see the research result")
+	}
+	if synthesis := provider.prompt("Synthesize these"); !strings.Contains(synthesis, "design done") {
+		t.Errorf("Expected synthesis to include subtask outputs, got %q", synthesis)
+	}
+}
+
+func TestCollective_ResumeSwarm(t *testing.T) {
+	provider := &scriptedProvider{plan: `{"subtasks": [{"id": "x", "task": "unused"}]}`}
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	worker, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider, Capabilities: []identity.CapabilityType{identity.CapResearch, identity.CapArchitecture, identity.CapAnalysis, identity.CapDocumentation}})
+	_ = c.Join(worker)
+	store := NewFileCheckpointStore(t.TempDir())
+	c.SetCheckpointStore(store)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	if _, err := c.ResumeSwarm(ctx, "missing"); !errors.Is(err, ErrCheckpointNotFound) {
+		t.Errorf("Expected ErrCheckpointNotFound, got %v", err)
+	}
+
+	// A run that crashed after its research subtask
+	task := agent.NewTask("Add a cache", nil)
+	cp := newCheckpoint(task, nil)
+	cp.Plan = &SwarmPlan{Subtasks: []Subtask{
+		{ID: "research", Task: "Survey caches", Requires: []identity.CapabilityType{identity.CapResearch}},
+		{ID: "design", Task: "Design the cache", Requires: []identity.CapabilityType{identity.CapArchitecture}, DependsOn: []string{"research"}},
+	}}
+	cp.Run = &workflow.Run{
+		ID:     "run-1",
+		Status: workflow.RunFailed,
+		Steps: map[string]*workflow.StepRun{
+			"research": {ID: "research", Status: workflow.StepCompleted, Output: "saved survey"},
+			"design":   {ID: "design", Status: workflow.StepFailed},
+		},
+		Order:     []string{"research", "design"},
+		Completed: []string{"research"},
+	}
+	cp.Status = SwarmFailed
+	if err := store.SaveCheckpoint(cp); err != nil {
+		t.Fatalf("SaveCheckpoint failed: %v", err)
+	}
+
+	result, err := c.ResumeSwarm(ctx, task.ID)
+	if err != nil {
+		t.Fatalf("ResumeSwarm failed: %v", err)
+	}
+	if result.Output != "final answer" || result.Run.ID != "run-1" {
+		t.Errorf("Expected run-1 to finish with the final answer, got %s: %q", result.Run.ID, result.Output)
+	}
+	if provider.prompt("orchestrator of a collective") != "" || provider.prompt("Survey caches\n") != "" {
+		t.Error("Expected completed phases to be skipped")
+	}
+	if !strings.Contains(provider.prompt("Design the cache"), "saved survey") {
+		t.Error("Expected resumed subtask to see the checkpointed research result")
+	}
+
+	saved, err := store.LoadCheckpoint(task.ID)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint failed: %v", err)
+	}
+	if saved.Status != SwarmCompleted || saved.Output != "final answer" || len(saved.Run.Completed) != 2 {
+		t.Errorf("Expected completed checkpoint, got %s with %v", saved.Status, saved.Run.Completed)
+	}
+	if _, err := c.ResumeSwarm(ctx, task.ID); !errors.Is(err, ErrSwarmFinished) {
+		t.Errorf("Expected ErrSwarmFinished, got %v", err)
+	}
+	if list, _ := store.ListCheckpoints(); len(list) != 1 || list[0].Description != "Add a cache" {
+		t.Errorf("Expected 1 checkpoint listed, got %d", len(list))
+	}
+}
+
+// meteredProvider reports a fixed token cost per call and records the limits asked for
+type meteredProvider struct {
+	mu        sync.Mutex
+	prompts   []string
+	maxTokens []int
+}
+
+func (p *meteredProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, req.Prompt)
+	p.maxTokens = append(p.maxTokens, req.MaxTokens)
+	return &llm.CompletionResponse{Content: fmt.Sprintf("output %d", len(p.prompts)), TokensUsed: 100}, nil
+}
+
+func (p *meteredProvider) Name() string {
+	return "metered"
+}
+
+const testPipeline = `
+name: code-review
+roles:
+  - {name: coder, capabilities: [code.write]}
+  - {name: reviewer, capabilities: [code.review]}
+phases:
+  - {id: implement, role: coder, prompt: "Implement: {{.Params.task}}"}
+  - id: review
+    role: reviewer
+    prompt: Review the implementation
+    depends_on: [implement]
+    max_tokens: 500
+`
+
+func TestParseSwarmPipeline(t *testing.T) {
+	p, err := ParseSwarmPipeline([]byte(testPipeline))
+	if err != nil {
+		t.Fatalf("ParseSwarmPipeline failed: %v", err)
+	}
+	wf, err := p.Workflow()
+	if err != nil {
+		t.Fatalf("Workflow failed: %v", err)
+	}
+	if len(wf.Steps) != 2 || wf.Steps[1].Team != "reviewer" || wf.Steps[1].MaxTokens != 500 {
+		t.Errorf("Expected review step on the reviewer team, got %+v", wf.Steps[1])
+	}
+
+	tests := []string{
+		"name: x\nphases: [{id: a, role: coder}]",
+		"name: x\nroles: [{name: coder}, {name: coder}]\nphases: [{id: a, role: coder}]",
+		"name: x\nroles: [{name: coder}]\nphases: [{id: a, role: tester}]",
+		"name: x\nroles: [{name: coder}]\nphases: [{id: a, role: coder, depends_on: [b]}]",
+		"name: x\nroles: [{name: coder}]\nphases: [{id: a, role: coder}]\noutput: b",
+		"name: x\nroles: [{name: coder}]",
+	}
+	for _, data := range tests {
+		if _, err := ParseSwarmPipeline([]byte(data)); !errors.Is(err, ErrInvalidPipeline) {
+			t.Errorf("Expected ErrInvalidPipeline for %q, got %v", data, err)
+		}
+	}
+}
+
+func TestCollective_ExecutePipeline(t *testing.T) {
+	pipeline, _ := ParseSwarmPipeline([]byte(testPipeline))
+	provider := &meteredProvider{}
+
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := c.ExecutePipeline(ctx, pipeline, agent.NewTask("Add a cache", nil)); !errors.Is(err, ErrInvalidPipeline) {
+		t.Errorf("Expected ErrInvalidPipeline without staffed roles, got %v", err)
+	}
+
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Provider: provider, Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	reviewer, _ := agent.NewAgent(agent.AgentConfig{Name: "Reviewer", Provider: provider, Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+	if err := c.JoinRole("coder", coder); err != nil {
+		t.Fatalf("JoinRole failed: %v", err)
+	}
+	if err := c.JoinRole("reviewer", reviewer); err != nil {
+		t.Fatalf("JoinRole failed: %v", err)
+	}
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	result, err := c.ExecutePipeline(ctx, pipeline, agent.NewTask("Add a cache", nil))
+	if err != nil {
+		t.Fatalf("ExecutePipeline failed: %v", err)
+	}
+	if result.Run.Status != workflow.RunCompleted || result.Output != "output 2" {
+		t.Errorf("Expected the review phase's output, got %s: %q", result.Run.Status, result.Output)
+	}
+	if result.TokensUsed != 200 {
+		t.Errorf("Expected 200 tokens used, got %d", result.TokensUsed)
+	}
+
+	provider.mu.Lock()
+	if !strings.Contains(provider.prompts[0], "Implement: Add a cache") || !strings.Contains(provider.prompts[1], "output 1") {
+		t.Errorf("Expected rendered prompts with upstream results, got %q", provider.prompts)
+	}
+	if provider.maxTokens[1] != 500 {
+		t.Errorf("Expected the review phase limited to 500 tokens, got %d", provider.maxTokens[1])
+	}
+	provider.mu.Unlock()
+
+	// The budget runs out after the first phase
+	pipeline.TokenBudget = 100
+	result, err = c.ExecutePipeline(ctx, pipeline, agent.NewTask("Add a cache", nil))
+	if !errors.Is(err, ErrPipelineBudget) {
+		t.Errorf("Expected ErrPipelineBudget, got %v", err)
+	}
+	if result == nil || result.TokensUsed != 100 {
+		t.Errorf("Expected the partial run to report 100 tokens, got %+v", result)
+	}
+	provider.mu.Lock()
+	if got := provider.maxTokens[2]; got != 100 {
+		t.Errorf("Expected the first phase capped at the budget, got %d", got)
+	}
+	provider.mu.Unlock()
+}
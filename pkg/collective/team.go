@@ -0,0 +1,271 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+)
+
+var (
+	ErrTeamExists   = errors.New("team already exists")
+	ErrTeamNotFound = errors.New("team not found")
+)
+
+// TeamConfig holds team configuration. Zero values inherit from the parent collective.
+type TeamConfig struct {
+	ConsensusThreshold float64        `json:"consensus_threshold,omitempty"`
+	AssignmentMode     AssignmentMode `json:"assignment_mode,omitempty"`
+}
+
+// Team is a named sub-collective with its own market and consensus engine.
+// Members are agents of the parent collective; an agent may belong to several teams.
+type Team struct {
+	mu sync.RWMutex
+
+	Name string
+
+	members   map[string]bool // SID -> member
+	market    *coordination.TaskMarket
+	consensus *coordination.ConsensusEngine
+	mode      AssignmentMode
+}
+
+// Members returns the SIDs of the team's members
+func (t *Team) Members() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	members := make([]string, 0, len(t.members))
+	for sid := range t.members {
+		members = append(members, sid)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// Has reports whether an agent belongs to the team
+func (t *Team) Has(sid string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.members[sid]
+}
+
+// Size returns the number of team members
+func (t *Team) Size() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.members)
+}
+
+// GetMarket returns the team's task market
+func (t *Team) GetMarket() *coordination.TaskMarket {
+	return t.market
+}
+
+// GetConsensus returns the team's consensus engine
+func (t *Team) GetConsensus() *coordination.ConsensusEngine {
+	return t.consensus
+}
+
+// CreateTeam adds a named team to the collective
+func (c *Collective) CreateTeam(name string, cfg TeamConfig) (*Team, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.teams[name]; exists {
+		return nil, ErrTeamExists
+	}
+
+	threshold := cfg.ConsensusThreshold
+	if threshold == 0 {
+		threshold = c.config.ConsensusThreshold
+	}
+	mode := cfg.AssignmentMode
+	if mode == "" {
+		mode = c.config.AssignmentMode
+	}
+
+	team := &Team{
+		Name:      name,
+		members:   make(map[string]bool),
+		market:    coordination.NewTaskMarket(),
+		consensus: coordination.NewConsensusEngine(threshold),
+		mode:      mode,
+	}
+	team.market.SetBidTimeout(c.market.BidTimeout())
+	team.market.SetAuction(c.market.Auction())
+	team.market.SetTieBreaker(c.market.TieBreaker())
+	_ = team.market.SetBidThreshold(c.market.BidThreshold())
+	team.market.SetLogger(c.componentLoggerLocked("market").With("team", name))
+	team.consensus.SetLogger(c.componentLoggerLocked("consensus").With("team", name))
+	team.market.OnBid(c.publishBid)
+	team.market.SetAgentLookup(c.GetAgent)
+
+	if c.runCtx != nil {
+		go team.market.Start(c.runCtx)
+	}
+
+	c.teams[name] = team
+	c.logger.Info("team created", "team", name, "mode", mode, "threshold", threshold)
+	return team, nil
+}
+
+// UpdateTeam changes a team's consensus threshold and assignment mode. As on
+// creation, zero values inherit from the collective.
+func (c *Collective) UpdateTeam(name string, cfg TeamConfig) (*Team, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	team, exists := c.teams[name]
+	if !exists {
+		return nil, ErrTeamNotFound
+	}
+
+	threshold := cfg.ConsensusThreshold
+	if threshold == 0 {
+		threshold = c.config.ConsensusThreshold
+	}
+	mode := cfg.AssignmentMode
+	if mode == "" {
+		mode = c.config.AssignmentMode
+	}
+
+	team.consensus.SetThreshold(threshold)
+	team.mu.Lock()
+	team.mode = mode
+	team.mu.Unlock()
+	c.logger.Info("team updated", "team", name, "mode", mode, "threshold", threshold)
+	return team, nil
+}
+
+// RemoveTeam removes a team. Its members stay in the collective.
+func (c *Collective) RemoveTeam(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	team, exists := c.teams[name]
+	if !exists {
+		return ErrTeamNotFound
+	}
+
+	delete(c.teams, name)
+	team.market.Close()
+	return nil
+}
+
+// GetTeam returns a team by name
+func (c *Collective) GetTeam(name string) (*Team, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	team, ok := c.teams[name]
+	return team, ok
+}
+
+// Teams returns all teams, ordered by name
+func (c *Collective) Teams() []*Team {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	teams := make([]*Team, 0, len(c.teams))
+	for _, team := range c.teams {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		return teams[i].Name < teams[j].Name
+	})
+	return teams
+}
+
+// AddToTeam adds a collective member to a team
+func (c *Collective) AddToTeam(name, sid string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	team, exists := c.teams[name]
+	if !exists {
+		return ErrTeamNotFound
+	}
+	if _, exists := c.agents[sid]; !exists {
+		return ErrAgentNotFound
+	}
+
+	team.mu.Lock()
+	members := make([]string, 0, len(team.members))
+	for member := range team.members {
+		members = append(members, member)
+	}
+	team.members[sid] = true
+	team.mu.Unlock()
+
+	c.conflicts.noteTeam(name, sid, members)
+	return nil
+}
+
+// RemoveFromTeam removes an agent from a team
+func (c *Collective) RemoveFromTeam(name, sid string) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	team, exists := c.teams[name]
+	if !exists {
+		return ErrTeamNotFound
+	}
+
+	team.mu.Lock()
+	defer team.mu.Unlock()
+
+	if !team.members[sid] {
+		return ErrAgentNotFound
+	}
+	delete(team.members, sid)
+	return nil
+}
+
+// removeFromTeamsLocked drops a departing agent from every team. Caller must hold c.mu.
+func (c *Collective) removeFromTeamsLocked(sid string) {
+	for _, team := range c.teams {
+		team.mu.Lock()
+		delete(team.members, sid)
+		team.mu.Unlock()
+	}
+}
+
+// startTeamsLocked starts every team market. Caller must hold c.mu.
+func (c *Collective) startTeamsLocked(ctx context.Context) {
+	for _, team := range c.teams {
+		go team.market.Start(ctx)
+	}
+}
+
+// closeTeamsLocked closes every team market. Caller must hold c.mu.
+func (c *Collective) closeTeamsLocked() {
+	for _, team := range c.teams {
+		team.market.Close()
+	}
+}
+
+// scope returns the agents, market, consensus engine and mode that assign a team's tasks
+func (t *Team) scope(agents map[string]*agent.Agent) assignmentScope {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	members := make(map[string]*agent.Agent, len(t.members))
+	for sid := range t.members {
+		if a, ok := agents[sid]; ok {
+			members[sid] = a
+		}
+	}
+
+	return assignmentScope{
+		name:      "team:" + t.Name,
+		agents:    members,
+		market:    t.market,
+		consensus: t.consensus,
+		mode:      t.mode,
+	}
+}
@@ -0,0 +1,247 @@
+package collective
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func TestCollective_CreateTeam(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+
+	team, err := c.CreateTeam("review", TeamConfig{ConsensusThreshold: 0.9})
+	if err != nil {
+		t.Fatalf("CreateTeam failed: %v", err)
+	}
+
+	if team.GetMarket() == c.GetMarket() {
+		t.Error("Team should have its own market")
+	}
+
+	if team.mode != AssignmentMarket {
+		t.Errorf("Expected team to inherit assignment mode 'market', got '%s'", team.mode)
+	}
+
+	if _, err := c.CreateTeam("review", TeamConfig{}); err != ErrTeamExists {
+		t.Errorf("Expected ErrTeamExists, got %v", err)
+	}
+
+	if err := c.AddToTeam("review", "non-existent-sid"); err != ErrAgentNotFound {
+		t.Errorf("Expected ErrAgentNotFound, got %v", err)
+	}
+
+	if err := c.RemoveTeam("review"); err != nil {
+		t.Fatalf("RemoveTeam failed: %v", err)
+	}
+
+	if _, ok := c.GetTeam("review"); ok {
+		t.Error("Team should be removed")
+	}
+}
+
+func TestCollective_TeamRouting(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	reviewer, _ := agent.NewAgent(agent.AgentConfig{Name: "Reviewer", Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+	implementer, _ := agent.NewAgent(agent.AgentConfig{Name: "Implementer", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(reviewer)
+	_ = c.Join(implementer)
+
+	if _, err := c.CreateTeam("review", TeamConfig{}); err != nil {
+		t.Fatalf("CreateTeam failed: %v", err)
+	}
+	if err := c.AddToTeam("review", reviewer.Identity.SID); err != nil {
+		t.Fatalf("AddToTeam failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	for i := 0; i < 3; i++ {
+		result, err := c.Submit(agent.NewTask("Review change", nil).WithTeam("review"))
+		if err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+		if result.AgentSID != reviewer.Identity.SID {
+			t.Errorf("Expected team task to be assigned to team member")
+		}
+	}
+
+	_, err := c.Submit(agent.NewTask("Orphan task", nil).WithTeam("missing"))
+	if !errors.Is(err, ErrTeamNotFound) {
+		t.Errorf("Expected ErrTeamNotFound, got %v", err)
+	}
+}
+
+func TestCollective_LeaveRemovesFromTeams(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a)
+
+	team, _ := c.CreateTeam("impl", TeamConfig{})
+	_ = c.AddToTeam("impl", a.Identity.SID)
+
+	if !team.Has(a.Identity.SID) {
+		t.Fatal("Agent should be a team member")
+	}
+
+	_ = c.Leave(a.Identity.SID)
+
+	if team.Size() != 0 {
+		t.Errorf("Expected empty team after leave, got %d members", team.Size())
+	}
+}
+
+func TestCollective_RoutingRules(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	reviewer, _ := agent.NewAgent(agent.AgentConfig{Name: "Reviewer", Capabilities: []identity.CapabilityType{identity.CapCodeReview, identity.CapCodeWrite}})
+	implementer, _ := agent.NewAgent(agent.AgentConfig{Name: "Implementer", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(reviewer)
+	_ = c.Join(implementer)
+	_, _ = c.CreateTeam("review", TeamConfig{})
+	_ = c.AddToTeam("review", reviewer.Identity.SID)
+
+	if err := c.SetRoutingRule(RoutingRule{Name: "no-team"}); err == nil {
+		t.Error("Expected an error for a rule without a team")
+	}
+	_ = c.SetRoutingRule(RoutingRule{Name: "ci", Team: "review", Submitter: "ci", Priority: 1})
+	_ = c.SetRoutingRule(RoutingRule{Name: "missing", Team: "gone", Submitter: "ci", Priority: 2})
+
+	tests := []struct {
+		name      string
+		submitter string
+		team      string
+	}{
+		{"matching rule", "ci", "review"},
+		{"no matching rule", "alice", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := agent.NewTask("Change", []identity.CapabilityType{identity.CapCodeWrite}).WithSubmitter(tt.submitter)
+			c.route(task)
+			if task.Team != tt.team {
+				t.Errorf("Expected team %q, got %q", tt.team, task.Team)
+			}
+		})
+	}
+
+	if err := c.RemoveRoutingRule("ci"); err != nil {
+		t.Errorf("RemoveRoutingRule failed: %v", err)
+	}
+	if err := c.RemoveRoutingRule("ci"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("Expected ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestCollective_Budgets(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	if err := c.SetBudget(Budget{Name: "ci", Tokens: 100, Submitter: "ci"}); err != nil {
+		t.Fatalf("SetBudget failed: %v", err)
+	}
+	if _, err := c.Submit(agent.NewTask("Within budget", nil).WithSubmitter("ci")); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	c.chargeBudgets(agent.NewTask("Spent", nil).WithSubmitter("ci"), 100)
+	if _, err := c.Submit(agent.NewTask("Over budget", nil).WithSubmitter("ci")); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if _, err := c.Submit(agent.NewTask("Other submitter", nil).WithSubmitter("alice")); err != nil {
+		t.Errorf("Expected other submitters to be unaffected, got %v", err)
+	}
+
+	// Raising the limit keeps what was used
+	_ = c.SetBudget(Budget{Name: "ci", Tokens: 200, Submitter: "ci"})
+	if budgets := c.Budgets(); len(budgets) != 1 || budgets[0].Used < 100 {
+		t.Errorf("Expected budget ci to keep its usage, got %+v", budgets)
+	}
+	if _, err := c.Submit(agent.NewTask("Within new budget", nil).WithSubmitter("ci")); err != nil {
+		t.Errorf("Submit failed: %v", err)
+	}
+}
+
+func TestCollective_UsageReport(t *testing.T) {
+	c := NewCollective("TestCollective", DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{
+		Name:         "Agent1",
+		Capabilities: []identity.CapabilityType{identity.CapCodeWrite},
+		Provider:     &meteredProvider{},
+		CostTags:     agent.Labels{"cost-center": "ml", "project": "search"},
+	})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	tasks := []*agent.Task{
+		agent.NewTask("Charged to the agent's cost center", nil),
+		agent.NewTask("Charged to its own", nil).WithCostTags(agent.Labels{"cost-center": "web"}),
+		agent.NewTask("From CI", nil).WithSubmitter("ci"),
+	}
+	for _, task := range tasks {
+		if _, err := c.Submit(task); err != nil {
+			t.Fatalf("Submit failed: %v", err)
+		}
+	}
+
+	report := c.UsageReport("cost-center")
+	if report.Tasks != 3 || report.Tokens != 300 {
+		t.Errorf("Expected 3 tasks and 300 tokens, got %d and %d", report.Tasks, report.Tokens)
+	}
+	if len(report.Agents) != 1 || report.Agents[0].Tokens != 300 || report.Agents[0].Name != "Agent1" {
+		t.Errorf("Expected all spend attributed to Agent1, got %+v", report.Agents)
+	}
+	if len(report.Keys) != 3 {
+		t.Errorf("Expected cost-center, project and submitter keys, got %v", report.Keys)
+	}
+
+	tests := []struct {
+		by   string
+		want map[string]int
+	}{
+		{"cost-center", map[string]int{"ml": 200, "web": 100}},
+		{"project", map[string]int{"search": 300}},
+		{CostTagSubmitter, map[string]int{"ci": 100, Untagged: 200}},
+		{"region", map[string]int{Untagged: 300}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			lines := c.UsageReport(tt.by).Chargeback
+			if len(lines) != len(tt.want) {
+				t.Fatalf("Expected %d chargeback lines, got %+v", len(tt.want), lines)
+			}
+			for _, line := range lines {
+				if line.Tokens != tt.want[line.Value] {
+					t.Errorf("Expected %d tokens for %s, got %d", tt.want[line.Value], line.Value, line.Tokens)
+				}
+			}
+			if lines[0].Share < 0.5 {
+				t.Errorf("Expected the largest line first, got %+v", lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package collective
+
+import (
+	"sync"
+	"time"
+)
+
+// TimelineStage is a step in a task's journey through the collective
+type TimelineStage string
+
+const (
+	StageSubmitted TimelineStage = "submitted"
+	StageListed    TimelineStage = "listed"
+	StageBid       TimelineStage = "bid"
+	StageConsensus TimelineStage = "consensus"
+	StageWaiting   TimelineStage = "waiting" // Every capable agent is busy, or the collective is paused
+	StageAssigned  TimelineStage = "assigned"
+	StageRunning   TimelineStage = "running"
+	StageToolCall  TimelineStage = "tool_call"
+	StageReview    TimelineStage = "review"
+	StageRequeued  TimelineStage = "requeued"
+	StagePreempted TimelineStage = "preempted" // Stopped for a more urgent task; resumes from a checkpoint
+	StageCompleted TimelineStage = "completed"
+	StageFailed    TimelineStage = "failed"
+)
+
+// TimelineEntry records one stage of a task's journey
+type TimelineEntry struct {
+	Stage     TimelineStage `json:"stage"`
+	Actor     string        `json:"actor,omitempty"` // Agent SID, team or component responsible
+	Detail    string        `json:"detail,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// TimelineStore keeps per-task timelines for the most recent tasks
+type TimelineStore struct {
+	mu sync.RWMutex
+
+	timelines map[string][]TimelineEntry // TaskID -> entries in order
+	order     []string                   // TaskIDs, oldest first
+	limit     int
+}
+
+// NewTimelineStore creates a store holding timelines for up to limit tasks
+func NewTimelineStore(limit int) *TimelineStore {
+	return &TimelineStore{
+		timelines: make(map[string][]TimelineEntry),
+		limit:     limit,
+	}
+}
+
+// Record appends a stage to a task's timeline, evicting the oldest task's
+// timeline when the store is full
+func (s *TimelineStore) Record(taskID string, stage TimelineStage, actor, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.timelines[taskID]; !exists {
+		s.order = append(s.order, taskID)
+		if s.limit > 0 && len(s.order) > s.limit {
+			delete(s.timelines, s.order[0])
+			s.order = s.order[1:]
+		}
+	}
+
+	s.timelines[taskID] = append(s.timelines[taskID], TimelineEntry{
+		Stage:     stage,
+		Actor:     actor,
+		Detail:    detail,
+		Timestamp: time.Now(),
+	})
+}
+
+// Get returns a copy of a task's timeline
+func (s *TimelineStore) Get(taskID string) ([]TimelineEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, ok := s.timelines[taskID]
+	if !ok {
+		return nil, false
+	}
+	return append([]TimelineEntry(nil), entries...), true
+}
+
+// Len returns the number of tasks with a timeline
+func (s *TimelineStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.timelines)
+}
@@ -0,0 +1,213 @@
+package collective
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// Implicit cost tags: tasks and agents that don't tag a team or submitter
+// are charged to the team the task was routed to and the client that
+// submitted it
+const (
+	CostTagTeam      = "team"
+	CostTagSubmitter = "submitter"
+)
+
+// Untagged is the chargeback line of the spend not attributed to any value
+// of the reported tag
+const Untagged = "(untagged)"
+
+// AgentUsage is the token spend of one agent's tasks
+type AgentUsage struct {
+	SID      string       `json:"sid"`
+	Name     string       `json:"name"`
+	CostTags agent.Labels `json:"cost_tags,omitempty"`
+	Tasks    int          `json:"tasks"`
+	Failed   int          `json:"failed"`
+	Tokens   int          `json:"tokens"`
+}
+
+// ChargebackLine is the token spend charged to one value of a cost tag
+type ChargebackLine struct {
+	Value  string  `json:"value"`
+	Tasks  int     `json:"tasks"`
+	Failed int     `json:"failed"`
+	Tokens int     `json:"tokens"`
+	Share  float64 `json:"share"` // Of all tokens spent
+}
+
+// UsageReport attributes the tokens spent since Since to agents and, for
+// the cost tag By, to each of its values
+type UsageReport struct {
+	Since      time.Time        `json:"since"`
+	Tasks      int              `json:"tasks"`
+	Failed     int              `json:"failed"`
+	Tokens     int              `json:"tokens"`
+	Agents     []AgentUsage     `json:"agents"`
+	Keys       []string         `json:"keys"` // Cost tags spend has been attributed by
+	By         string           `json:"by,omitempty"`
+	Chargeback []ChargebackLine `json:"chargeback,omitempty"`
+}
+
+// usageTotals is the spend of one agent or tag value
+type usageTotals struct {
+	tasks, failed, tokens int
+}
+
+func (u *usageTotals) add(tokens int, failed bool) {
+	u.tasks++
+	u.tokens += tokens
+	if failed {
+		u.failed++
+	}
+}
+
+// usageLedger accumulates token spend by agent and by cost tag
+type usageLedger struct {
+	mu sync.Mutex
+
+	since  time.Time
+	total  usageTotals
+	agents map[string]*AgentUsage             // SID -> spend
+	tags   map[string]map[string]*usageTotals // Tag -> value -> spend
+}
+
+func newUsageLedger() *usageLedger {
+	return &usageLedger{
+		since:  time.Now(),
+		agents: make(map[string]*AgentUsage),
+		tags:   make(map[string]map[string]*usageTotals),
+	}
+}
+
+// costTags returns the tags a task run by a is charged to: the agent's,
+// overridden by the task's, with the task's team and submitter where
+// neither tags one
+func costTags(task *agent.Task, a *agent.Agent) agent.Labels {
+	tags := make(agent.Labels)
+	if a != nil {
+		for k, v := range a.CostTags {
+			tags[k] = v
+		}
+	}
+	for k, v := range task.CostTags {
+		tags[k] = v
+	}
+	if _, ok := tags[CostTagTeam]; !ok && task.Team != "" {
+		tags[CostTagTeam] = task.Team
+	}
+	if _, ok := tags[CostTagSubmitter]; !ok && task.Submitter != "" {
+		tags[CostTagSubmitter] = task.Submitter
+	}
+	return tags
+}
+
+// attributeUsage charges the tokens a task used to the agent that ran it and
+// to its cost tags
+func (c *Collective) attributeUsage(task *agent.Task, result *agent.TaskResult) {
+	c.mu.RLock()
+	a := c.agents[result.AgentSID]
+	c.mu.RUnlock()
+
+	failed := result.Status != agent.TaskCompleted
+	tags := costTags(task, a)
+
+	l := c.usage
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total.add(result.TokensUsed, failed)
+
+	usage, ok := l.agents[result.AgentSID]
+	if !ok {
+		usage = &AgentUsage{SID: result.AgentSID}
+		l.agents[result.AgentSID] = usage
+	}
+	if a != nil {
+		usage.Name = a.Identity.Name
+		usage.CostTags = a.CostTags
+	}
+	usage.Tasks++
+	usage.Tokens += result.TokensUsed
+	if failed {
+		usage.Failed++
+	}
+
+	for k, v := range tags {
+		values, ok := l.tags[k]
+		if !ok {
+			values = make(map[string]*usageTotals)
+			l.tags[k] = values
+		}
+		if values[v] == nil {
+			values[v] = &usageTotals{}
+		}
+		values[v].add(result.TokensUsed, failed)
+	}
+}
+
+// UsageReport attributes the tokens spent since the collective was created
+// to its agents and, if by names a cost tag, to each value of that tag,
+// largest spend first. Spend on tasks without the tag is charged to
+// Untagged.
+func (c *Collective) UsageReport(by string) *UsageReport {
+	l := c.usage
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	report := &UsageReport{
+		Since:  l.since,
+		Tasks:  l.total.tasks,
+		Failed: l.total.failed,
+		Tokens: l.total.tokens,
+		Agents: make([]AgentUsage, 0, len(l.agents)),
+		Keys:   make([]string, 0, len(l.tags)),
+		By:     by,
+	}
+	for _, usage := range l.agents {
+		report.Agents = append(report.Agents, *usage)
+	}
+	sort.Slice(report.Agents, func(i, j int) bool {
+		if report.Agents[i].Tokens != report.Agents[j].Tokens {
+			return report.Agents[i].Tokens > report.Agents[j].Tokens
+		}
+		return report.Agents[i].SID < report.Agents[j].SID
+	})
+	for k := range l.tags {
+		report.Keys = append(report.Keys, k)
+	}
+	sort.Strings(report.Keys)
+
+	if by == "" {
+		return report
+	}
+	untagged := l.total
+	for value, totals := range l.tags[by] {
+		report.Chargeback = append(report.Chargeback, chargebackLine(value, *totals, l.total.tokens))
+		untagged.tasks -= totals.tasks
+		untagged.failed -= totals.failed
+		untagged.tokens -= totals.tokens
+	}
+	if untagged.tasks > 0 {
+		report.Chargeback = append(report.Chargeback, chargebackLine(Untagged, untagged, l.total.tokens))
+	}
+	sort.Slice(report.Chargeback, func(i, j int) bool {
+		if report.Chargeback[i].Tokens != report.Chargeback[j].Tokens {
+			return report.Chargeback[i].Tokens > report.Chargeback[j].Tokens
+		}
+		return report.Chargeback[i].Value < report.Chargeback[j].Value
+	})
+	return report
+}
+
+// chargebackLine reports the spend of one tag value against the total
+func chargebackLine(value string, totals usageTotals, total int) ChargebackLine {
+	line := ChargebackLine{Value: value, Tasks: totals.tasks, Failed: totals.failed, Tokens: totals.tokens}
+	if total > 0 {
+		line.Share = float64(totals.tokens) / float64(total)
+	}
+	return line
+}
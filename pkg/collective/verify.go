@@ -0,0 +1,51 @@
+package collective
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// VerifyResult proves which agent produced a result: it must carry a
+// signature by the agent named in it, made with the key that agent joined
+// with, over the result as it is now. Agents that have since left still
+// verify.
+func (c *Collective) VerifyResult(result *agent.TaskResult) error {
+	key, err := c.publicKey(result.AgentSID)
+	if err != nil {
+		return err
+	}
+	return agent.VerifyResult(result, key)
+}
+
+// VerifyResultChain verifies a run of one agent's results, oldest first,
+// and that none are missing or out of order
+func (c *Collective) VerifyResultChain(results []*agent.TaskResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	sid := results[0].AgentSID
+	for i, r := range results {
+		if r.AgentSID != sid {
+			return fmt.Errorf("%w: result %d is from agent %s, not %s", agent.ErrBrokenChain, i, r.AgentSID, sid)
+		}
+	}
+	key, err := c.publicKey(sid)
+	if err != nil {
+		return err
+	}
+	return agent.VerifyChain(results, key)
+}
+
+// publicKey returns the key an agent joined with
+func (c *Collective) publicKey(sid string) (ed25519.PublicKey, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key, ok := c.keys[sid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, sid)
+	}
+	return key, nil
+}
@@ -3,15 +3,96 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/eventsink"
+	"github.com/square-mind/squaremind/pkg/incident"
+	"github.com/square-mind/squaremind/pkg/integrations/github"
+	"github.com/square-mind/squaremind/pkg/integrations/slack"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/llmlog"
+	"github.com/square-mind/squaremind/pkg/storage"
+	"github.com/square-mind/squaremind/pkg/tools"
 )
 
-// Config holds the application configuration
+// Config holds the application configuration. Profiles are named sets of
+// overrides of the base settings, selected with --profile.
 type Config struct {
-	AnthropicAPIKey string `yaml:"anthropic_api_key"`
-	OpenAIAPIKey    string `yaml:"openai_api_key"`
-	DefaultModel    string `yaml:"default_model"`
+	AnthropicAPIKey string `yaml:"anthropic_api_key,omitempty"`
+	OpenAIAPIKey    string `yaml:"openai_api_key,omitempty"`
+	DefaultModel    string `yaml:"default_model,omitempty"`
+
+	// Defaults for new collectives (zero = the CLI's defaults)
+	MaxAgents          int           `yaml:"max_agents,omitempty"`
+	ConsensusThreshold float64       `yaml:"consensus_threshold,omitempty"`
+	BidTimeout         time.Duration `yaml:"bid_timeout,omitempty"`
+
+	ReputationDecay      float64 `yaml:"reputation_decay,omitempty"`       // Share of an idle agent's reputation lost per day
+	ReputationDecayCurve string  `yaml:"reputation_decay_curve,omitempty"` // linear, exponential or none
+
+	APITokens []APIToken `yaml:"api_tokens,omitempty"`
+
+	SlackWebhook string `yaml:"slack_webhook,omitempty"` // Incoming webhook notified of workflow approval requests and sent scheduled digests
+
+	WorkflowIndex string `yaml:"workflow_index,omitempty"` // Remote index of shared workflows
+
+	TokenBudget int `yaml:"token_budget,omitempty"` // LLM tokens the collective may spend (0 = unlimited)
+
+	AgentSlots int `yaml:"agent_slots,omitempty"` // Tasks each agent works on at once unless spawned with --slots (0 = 1)
+
+	Storage *storage.Config `yaml:"storage,omitempty"` // Backend for results, artifacts, reputation, memory and the audit log
+
+	Episodes *collective.EpisodeStoreConfig `yaml:"episodes,omitempty"` // S3-compatible bucket keeping episodic memory, shared by collectives using it (unset = with the rest of memory)
+
+	EventSinks []eventsink.Config `yaml:"event_sinks,omitempty"` // NATS subjects and Kafka topics the event stream is published to
+
+	Incidents incident.TriggerConfig `yaml:"incidents,omitempty"` // When 'sqm serve' captures incident bundles automatically
+
+	QoS map[agent.QoSClass]collective.QoSPolicy `yaml:"qos,omitempty"` // Quality-of-service classes added or overriding the built-in ones
+
+	Preemption *collective.PreemptionPolicy `yaml:"preemption,omitempty"` // Lets urgent tasks preempt running low-priority ones (unset = never)
+
+	Deadlines *collective.DeadlinePolicy `yaml:"deadlines,omitempty"` // EDF admission and what becomes of tasks that miss their deadline (unset = not enforced)
+
+	BidThreshold *coordination.BidThreshold `yaml:"bid_threshold,omitempty"` // Capability score needed to bid and how it is relaxed (unset = a fixed 0.5)
+
+	Reviews *collective.ReviewRotation `yaml:"reviews,omitempty"` // Rotates review stages between capable agents (unset = best bid wins)
+
+	AntiAffinity *collective.AntiAffinityPolicy `yaml:"anti_affinity,omitempty"` // Warns about or blocks reviewers with a conflict of interest (unset = not checked)
+
+	Keyring []agent.Credential `yaml:"keyring,omitempty"` // Provider keys and tool credentials bound to capabilities and teams
+
+	Models agent.ModelMap `yaml:"models,omitempty"` // Models tasks run on by required capability, unless the task or agent names one (e.g. documentation: claude-3-5-haiku-20241022)
+
+	Digests []collective.DigestSchedule `yaml:"digests,omitempty"` // When task digests are posted to the Slack webhook
+
+	Contracts *agent.ContractConfig `yaml:"contracts,omitempty"` // Behavior contracts agents' results are checked against (unset = not checked)
+
+	Queues *agent.QueueConfig `yaml:"queues,omitempty"` // Agents' task and result channel sizes and what happens when one is full (unset = 10 each, refuse)
+
+	MCPServers []tools.ServerConfig `yaml:"mcp_servers,omitempty"` // MCP servers whose tools agents may call
+
+	GitHub *github.Config `yaml:"github,omitempty"` // Lets code.write agents open pull requests and code.review agents review them (unset = disabled)
+
+	Slack *slack.Config `yaml:"slack,omitempty"` // Bot taking tasks from Slack channels at /slack/events in 'sqm serve' (unset = disabled)
+
+	LLMLog *llmlog.Config `yaml:"llm_log,omitempty"` // Logs LLM requests and responses, redacted, for 'sqm llm log' (unset = not logged)
+
+	Candidate *llm.CandidateSpec `yaml:"candidate,omitempty"` // Fine-tuned model that takes over from the configured provider once it passes calibration (unset = none)
+
+	Profiles map[string]*Config `yaml:"profiles,omitempty"`
+}
+
+// APIToken authorizes HTTP task submission on behalf of a submitter
+type APIToken struct {
+	Token     string `yaml:"token"`
+	Submitter string `yaml:"submitter"`
+	Weight    int    `yaml:"weight,omitempty"` // Fair-share weight (0 = default)
 }
 
 // DefaultConfigPath returns the default config file path
@@ -23,6 +104,151 @@ func DefaultConfigPath() string {
 	return filepath.Join(home, ".squaremind", "config.yaml")
 }
 
+// DefaultStateDir returns the directory holding the config file and persisted state
+func DefaultStateDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind")
+}
+
+// DefaultExecutionLogPath returns the default execution log path used for training data
+func DefaultExecutionLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "executions.jsonl")
+}
+
+// DefaultSchedulePath returns the default path for persisted task schedules
+func DefaultSchedulePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "schedules.json")
+}
+
+// DefaultReputationPath returns the default path for persisted agent reputation
+func DefaultReputationPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "reputation.json")
+}
+
+// DefaultSnapshotKeyPath returns the default path of the key snapshots are signed with
+func DefaultSnapshotKeyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "snapshot.key")
+}
+
+// DefaultCollectiveKeyPath returns the default path of the key a collective signs its attestations with
+func DefaultCollectiveKeyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "collective.key")
+}
+
+// DefaultMemoryPath returns the default path of the collective memory database
+func DefaultMemoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "memory.db")
+}
+
+// DefaultEpisodeCacheDir returns the default directory of local copies of
+// episodes kept in a bucket
+func DefaultEpisodeCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "episodes")
+}
+
+// DefaultWorkflowDir returns the default directory of the local workflow library
+func DefaultWorkflowDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "workflows")
+}
+
+// DefaultTriggersPath returns the default path of the event trigger rules
+func DefaultTriggersPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "triggers.yaml")
+}
+
+// DefaultCapabilitiesPath returns the default path of the custom capability definitions
+func DefaultCapabilitiesPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "capabilities.yaml")
+}
+
+// DefaultRolesDir returns the default directory of the user's role templates
+func DefaultRolesDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "roles")
+}
+
+// DefaultIncidentDir returns the default directory of incident bundles
+func DefaultIncidentDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "incidents")
+}
+
+// DefaultSwarmDir returns the default directory of swarm run checkpoints
+func DefaultSwarmDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "swarms")
+}
+
+// DefaultRunsDir returns the default directory of recorded task and swarm runs
+func DefaultRunsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "runs")
+}
+
+// DefaultLLMLogPath returns the default log of LLM requests and responses
+func DefaultLLMLogPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".squaremind", "llm.jsonl")
+}
+
 // Load reads configuration from the config file
 func Load() (*Config, error) {
 	return LoadFromPath(DefaultConfigPath())
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+var (
+	ErrUnknownKey     = errors.New("unknown config key")
+	ErrInvalidValue   = errors.New("invalid config value")
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+// Key is a setting `sqm config` can get, set and unset
+type Key struct {
+	Name        string
+	Description string
+	Secret      bool // Masked when listed
+
+	get func(*Config) string
+	set func(*Config, string) error // Empty value unsets
+}
+
+// Keys are the settable configuration keys
+var Keys = []Key{
+	{
+		Name: "api-key", Description: "Anthropic API key", Secret: true,
+		get: func(c *Config) string { return c.AnthropicAPIKey },
+		set: func(c *Config, v string) error { c.AnthropicAPIKey = v; return nil },
+	},
+	{
+		Name: "openai-key", Description: "OpenAI API key", Secret: true,
+		get: func(c *Config) string { return c.OpenAIAPIKey },
+		set: func(c *Config, v string) error { c.OpenAIAPIKey = v; return nil },
+	},
+	{
+		Name: "default-model", Description: "Model agents use unless spawned with --model",
+		get: func(c *Config) string { return c.DefaultModel },
+		set: func(c *Config, v string) error { c.DefaultModel = v; return nil },
+	},
+	{
+		Name: "max-agents", Description: "Maximum agents in a new collective",
+		get: func(c *Config) string { return formatInt(c.MaxAgents) },
+		set: func(c *Config, v string) error { return parsePositiveInt(v, &c.MaxAgents) },
+	},
+	{
+		Name: "consensus-threshold", Description: "Share of votes consensus needs (0-1]",
+		get: func(c *Config) string {
+			if c.ConsensusThreshold == 0 {
+				return ""
+			}
+			return strconv.FormatFloat(c.ConsensusThreshold, 'g', -1, 64)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.ConsensusThreshold = 0
+				return nil
+			}
+			t, err := strconv.ParseFloat(v, 64)
+			if err != nil || t <= 0 || t > 1 {
+				return fmt.Errorf("%w: %q is not a threshold in (0, 1]", ErrInvalidValue, v)
+			}
+			c.ConsensusThreshold = t
+			return nil
+		},
+	},
+	{
+		Name: "bid-timeout", Description: "How long the market collects bids (e.g. 500ms)",
+		get: func(c *Config) string {
+			if c.BidTimeout == 0 {
+				return ""
+			}
+			return c.BidTimeout.String()
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.BidTimeout = 0
+				return nil
+			}
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("%w: %q is not a positive duration", ErrInvalidValue, v)
+			}
+			c.BidTimeout = d
+			return nil
+		},
+	},
+	{
+		Name: "reputation-decay", Description: "Share of an idle agent's reputation lost per day [0-1)",
+		get: func(c *Config) string {
+			if c.ReputationDecay == 0 {
+				return ""
+			}
+			return strconv.FormatFloat(c.ReputationDecay, 'g', -1, 64)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.ReputationDecay = 0
+				return nil
+			}
+			r, err := strconv.ParseFloat(v, 64)
+			if err != nil || r <= 0 || r >= 1 {
+				return fmt.Errorf("%w: %q is not a daily rate in (0, 1)", ErrInvalidValue, v)
+			}
+			c.ReputationDecay = r
+			return nil
+		},
+	},
+	{
+		Name: "reputation-decay-curve", Description: "How idle agents' reputation decays: linear, exponential or none",
+		get: func(c *Config) string { return c.ReputationDecayCurve },
+		set: func(c *Config, v string) error {
+			if _, err := agent.ParseDecayCurve(v); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+			}
+			c.ReputationDecayCurve = v
+			return nil
+		},
+	},
+	{
+		Name: "token-budget", Description: "LLM tokens the collective may spend",
+		get: func(c *Config) string { return formatInt(c.TokenBudget) },
+		set: func(c *Config, v string) error { return parsePositiveInt(v, &c.TokenBudget) },
+	},
+	{
+		Name: "agent-slots", Description: "Tasks each agent works on at once",
+		get: func(c *Config) string { return formatInt(c.AgentSlots) },
+		set: func(c *Config, v string) error { return parsePositiveInt(v, &c.AgentSlots) },
+	},
+	{
+		Name: "slack-webhook", Description: "Incoming webhook notified of approval requests and sent digests", Secret: true,
+		get: func(c *Config) string { return c.SlackWebhook },
+		set: func(c *Config, v string) error { c.SlackWebhook = v; return nil },
+	},
+	{
+		Name: "workflow-index", Description: "Remote index of shared workflows",
+		get: func(c *Config) string { return c.WorkflowIndex },
+		set: func(c *Config, v string) error { c.WorkflowIndex = v; return nil },
+	},
+}
+
+// LookupKey returns the key with the given name
+func LookupKey(name string) (Key, error) {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return Key{}, fmt.Errorf("%w: %s", ErrUnknownKey, name)
+}
+
+// Get returns a key's value ("" if unset)
+func (c *Config) Get(name string) (string, error) {
+	k, err := LookupKey(name)
+	if err != nil {
+		return "", err
+	}
+	return k.get(c), nil
+}
+
+// Set validates and sets a key's value
+func (c *Config) Set(name, value string) error {
+	k, err := LookupKey(name)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("%w: empty value for %s (use unset)", ErrInvalidValue, name)
+	}
+	return k.set(c, value)
+}
+
+// Unset clears a key
+func (c *Config) Unset(name string) error {
+	k, err := LookupKey(name)
+	if err != nil {
+		return err
+	}
+	return k.set(c, "")
+}
+
+// ProfileNames returns the names of the config's profiles, sorted
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the settings of a named profile, creating an empty one if
+// create is set
+func (c *Config) Profile(name string, create bool) (*Config, error) {
+	if p, ok := c.Profiles[name]; ok && p != nil {
+		return p, nil
+	}
+	if !create {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProfile, name)
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Config)
+	}
+	p := &Config{}
+	c.Profiles[name] = p
+	return p, nil
+}
+
+// WithProfile returns a copy of the config with a named profile's settings
+// in place of the base ones. The profile overrides each key it sets, and
+// its API tokens, storage, event sinks, QoS classes, preemption policy,
+// deadline policy, bid threshold, review rotation, anti-affinity policy, keyring, models, digest
+// schedules, contracts, MCP servers, GitHub integration, Slack bot, LLM
+// log and candidate model if it has any.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	p, err := c.Profile(name, false)
+	if err != nil {
+		return nil, err
+	}
+	merged := *c
+	merged.Profiles = nil
+	for _, k := range Keys {
+		if v := k.get(p); v != "" {
+			if err := k.set(&merged, v); err != nil {
+				return nil, fmt.Errorf("profile %s: %w", name, err)
+			}
+		}
+	}
+	if len(p.APITokens) > 0 {
+		merged.APITokens = p.APITokens
+	}
+	if p.Storage != nil {
+		merged.Storage = p.Storage
+	}
+	if p.Episodes != nil {
+		merged.Episodes = p.Episodes
+	}
+	if len(p.EventSinks) > 0 {
+		merged.EventSinks = p.EventSinks
+	}
+	if len(p.QoS) > 0 {
+		merged.QoS = p.QoS
+	}
+	if p.Preemption != nil {
+		merged.Preemption = p.Preemption
+	}
+	if p.Deadlines != nil {
+		merged.Deadlines = p.Deadlines
+	}
+	if p.BidThreshold != nil {
+		merged.BidThreshold = p.BidThreshold
+	}
+	if p.Reviews != nil {
+		merged.Reviews = p.Reviews
+	}
+	if p.AntiAffinity != nil {
+		merged.AntiAffinity = p.AntiAffinity
+	}
+	if len(p.Keyring) > 0 {
+		merged.Keyring = p.Keyring
+	}
+	if len(p.Models) > 0 {
+		merged.Models = p.Models
+	}
+	if len(p.Digests) > 0 {
+		merged.Digests = p.Digests
+	}
+	if p.Contracts != nil {
+		merged.Contracts = p.Contracts
+	}
+	if p.Queues != nil {
+		merged.Queues = p.Queues
+	}
+	if len(p.MCPServers) > 0 {
+		merged.MCPServers = p.MCPServers
+	}
+	if p.GitHub != nil {
+		merged.GitHub = p.GitHub
+	}
+	if p.Slack != nil {
+		merged.Slack = p.Slack
+	}
+	if p.LLMLog != nil {
+		merged.LLMLog = p.LLMLog
+	}
+	if p.Candidate != nil {
+		merged.Candidate = p.Candidate
+	}
+	return &merged, nil
+}
+
+func formatInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func parsePositiveInt(v string, dst *int) error {
+	if v == "" {
+		*dst = 0
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return fmt.Errorf("%w: %q is not a positive number", ErrInvalidValue, v)
+	}
+	*dst = n
+	return nil
+}
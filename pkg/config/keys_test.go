@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/eventsink"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+func TestConfig_Set(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  string // Value Get returns afterwards (empty = the one set)
+		err   error
+	}{
+		{"api-key", "sk-ant-123", "", nil},
+		{"openai-key", "sk-456", "", nil},
+		{"default-model", "claude-3-5-haiku-20241022", "", nil},
+		{"max-agents", "20", "", nil},
+		{"max-agents", "0", "", ErrInvalidValue},
+		{"max-agents", "-3", "", ErrInvalidValue},
+		{"max-agents", "many", "", ErrInvalidValue},
+		{"consensus-threshold", "0.67", "", nil},
+		{"consensus-threshold", "1", "", nil},
+		{"consensus-threshold", "0", "", ErrInvalidValue},
+		{"consensus-threshold", "1.5", "", ErrInvalidValue},
+		{"consensus-threshold", "-0.2", "", ErrInvalidValue},
+		{"consensus-threshold", "most", "", ErrInvalidValue},
+		{"bid-timeout", "500ms", "", nil},
+		{"bid-timeout", "90s", "1m30s", nil},
+		{"bid-timeout", "0s", "", ErrInvalidValue},
+		{"bid-timeout", "-1s", "", ErrInvalidValue},
+		{"bid-timeout", "soon", "", ErrInvalidValue},
+		{"reputation-decay", "0.05", "", nil},
+		{"reputation-decay", "0", "", ErrInvalidValue},
+		{"reputation-decay", "1", "", ErrInvalidValue},
+		{"reputation-decay-curve", "exponential", "", nil},
+		{"reputation-decay-curve", "sigmoid", "", ErrInvalidValue},
+		{"token-budget", "100000", "", nil},
+		{"token-budget", "0", "", ErrInvalidValue},
+		{"agent-slots", "4", "", nil},
+		{"agent-slots", "-1", "", ErrInvalidValue},
+		{"slack-webhook", "https://hooks.slack.com/services/x", "", nil},
+		{"workflow-index", "https://example.com/index.json", "", nil},
+		{"api-key", "", "", ErrInvalidValue},
+		{"colour", "blue", "", ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			c := &Config{}
+			err := c.Set(tt.key, tt.value)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Expected %v, got %v", tt.err, err)
+				}
+				if got, _ := c.Get(tt.key); got != "" {
+					t.Errorf("Expected a rejected value left unset, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set failed: %v", err)
+			}
+			want := tt.want
+			if want == "" {
+				want = tt.value
+			}
+			if got, _ := c.Get(tt.key); got != want {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestConfig_Unset(t *testing.T) {
+	c := &Config{}
+	for _, k := range Keys {
+		value := map[string]string{
+			"max-agents":             "5",
+			"consensus-threshold":    "0.5",
+			"bid-timeout":            "1s",
+			"reputation-decay":       "0.1",
+			"reputation-decay-curve": "linear",
+			"token-budget":           "1000",
+			"agent-slots":            "2",
+		}[k.Name]
+		if value == "" {
+			value = "x"
+		}
+		if err := c.Set(k.Name, value); err != nil {
+			t.Fatalf("Set %s failed: %v", k.Name, err)
+		}
+	}
+
+	for _, k := range Keys {
+		if err := c.Unset(k.Name); err != nil {
+			t.Errorf("Unset %s failed: %v", k.Name, err)
+		}
+		if got, _ := c.Get(k.Name); got != "" {
+			t.Errorf("Expected %s unset, got %q", k.Name, got)
+		}
+	}
+	if c.MaxAgents != 0 || c.ConsensusThreshold != 0 || c.BidTimeout != 0 || c.AnthropicAPIKey != "" {
+		t.Errorf("Expected every field cleared, got %+v", c)
+	}
+	if err := c.Unset("colour"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestConfig_WithProfile(t *testing.T) {
+	base := &Config{
+		AnthropicAPIKey:    "sk-ant-personal",
+		DefaultModel:       "claude-3-5-sonnet-20241022",
+		MaxAgents:          5,
+		ConsensusThreshold: 0.67,
+		APITokens:          []APIToken{{Token: "personal-token", Submitter: "me"}},
+		Storage:            &storage.Config{Driver: storage.DriverFilesystem, Path: "/home/me/results"},
+		EventSinks:         []eventsink.Config{{Kind: "nats", Servers: []string{"nats://localhost:4222"}}},
+		Candidate:          &llm.CandidateSpec{Endpoint: "http://localhost:8000/v1/chat/completions", Model: "personal-ft"},
+		Profiles: map[string]*Config{
+			"work": {
+				AnthropicAPIKey: "sk-ant-work",
+				MaxAgents:       20,
+				BidTimeout:      2 * time.Second,
+				APITokens:       []APIToken{{Token: "work-token", Submitter: "ci"}},
+				Storage:         &storage.Config{Driver: storage.DriverSQLite, Path: "/srv/sqm.db"},
+				EventSinks:      []eventsink.Config{{Kind: "kafka", Servers: []string{"kafka:9092"}}},
+				Candidate:       &llm.CandidateSpec{Endpoint: "http://vllm:8000/v1/chat/completions", Model: "work-ft"},
+			},
+			"empty": {},
+		},
+	}
+
+	work, err := base.WithProfile("work")
+	if err != nil {
+		t.Fatalf("WithProfile failed: %v", err)
+	}
+	if work.AnthropicAPIKey != "sk-ant-work" || work.MaxAgents != 20 || work.BidTimeout != 2*time.Second {
+		t.Errorf("Expected the profile's keys in place of the base ones, got %+v", work)
+	}
+	if work.DefaultModel != base.DefaultModel || work.ConsensusThreshold != 0.67 {
+		t.Errorf("Expected keys the profile doesn't set kept from the base, got %+v", work)
+	}
+	if len(work.APITokens) != 1 || work.APITokens[0].Submitter != "ci" {
+		t.Errorf("Expected the profile's API tokens, got %+v", work.APITokens)
+	}
+	if work.Storage == nil || work.Storage.Driver != storage.DriverSQLite {
+		t.Errorf("Expected the profile's storage, got %+v", work.Storage)
+	}
+	if len(work.EventSinks) != 1 || work.EventSinks[0].Kind != "kafka" {
+		t.Errorf("Expected the profile's event sinks, got %+v", work.EventSinks)
+	}
+	if work.Candidate == nil || work.Candidate.Model != "work-ft" {
+		t.Errorf("Expected the profile's candidate, got %+v", work.Candidate)
+	}
+	if work.Profiles != nil {
+		t.Error("Expected the merged config without profiles")
+	}
+	if base.MaxAgents != 5 || base.AnthropicAPIKey != "sk-ant-personal" {
+		t.Errorf("Expected the base config unchanged, got %+v", base)
+	}
+
+	empty, err := base.WithProfile("empty")
+	if err != nil {
+		t.Fatalf("WithProfile failed: %v", err)
+	}
+	if empty.AnthropicAPIKey != "sk-ant-personal" || empty.MaxAgents != 5 || len(empty.APITokens) != 1 || empty.APITokens[0].Submitter != "me" || empty.Storage.Driver != storage.DriverFilesystem || len(empty.EventSinks) != 1 || empty.Candidate.Model != "personal-ft" {
+		t.Errorf("Expected an empty profile to keep the base settings, got %+v", empty)
+	}
+
+	if _, err := base.WithProfile("missing"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Expected ErrUnknownProfile, got %v", err)
+	}
+	if _, err := base.Profile("missing", false); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Expected ErrUnknownProfile, got %v", err)
+	}
+	if p, err := base.Profile("new", true); err != nil || p == nil || base.Profiles["new"] != p {
+		t.Errorf("Expected the profile created, got %v (%v)", p, err)
+	}
+}
@@ -0,0 +1,108 @@
+package coordination
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrUnknownAuction = errors.New("unknown auction strategy")
+
+// Auction strategies
+const (
+	AuctionWeighted  = "weighted"   // Open bids; the best ScoreBid wins and commits its stake (default)
+	AuctionSealedBid = "sealed_bid" // Sealed first-price: bids stay hidden until bidding closes; the best score wins and commits its stake
+	AuctionVickrey   = "vickrey"    // Sealed second-price: the best score wins but commits only the stake it needed to outscore the runner-up
+	AuctionReverse   = "reverse"    // Reverse auction: the lowest estimated time wins, ties going to the better score
+)
+
+// AuctionStrategy decides how the bids on a task are ranked and what the
+// winner commits
+type AuctionStrategy interface {
+	// Name returns the strategy's name, one of the Auction constants
+	Name() string
+
+	// Sealed reports whether bids stay hidden until bidding closes
+	Sealed() bool
+
+	// Rank sorts scored bids best first
+	Rank(scores []BidScore)
+
+	// Price returns the reputation stake the winner, ranked[0], commits
+	Price(ranked []BidScore) float64
+}
+
+// NewAuctionStrategy returns the named strategy ("" = AuctionWeighted)
+func NewAuctionStrategy(name string) (AuctionStrategy, error) {
+	switch name {
+	case "", AuctionWeighted:
+		return firstPriceAuction{name: AuctionWeighted}, nil
+	case AuctionSealedBid:
+		return firstPriceAuction{name: AuctionSealedBid, sealed: true}, nil
+	case AuctionVickrey:
+		return vickreyAuction{}, nil
+	case AuctionReverse:
+		return reverseAuction{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q (want %s, %s, %s or %s)", ErrUnknownAuction, name, AuctionWeighted, AuctionSealedBid, AuctionVickrey, AuctionReverse)
+}
+
+// firstPriceAuction ranks bids by score; the winner commits its own stake
+type firstPriceAuction struct {
+	name   string
+	sealed bool
+}
+
+func (a firstPriceAuction) Name() string           { return a.name }
+func (a firstPriceAuction) Sealed() bool           { return a.sealed }
+func (a firstPriceAuction) Rank(scores []BidScore) { RankBidScores(scores) }
+
+func (a firstPriceAuction) Price(ranked []BidScore) float64 {
+	if len(ranked) == 0 {
+		return 0
+	}
+	return ranked[0].Bid.ReputationStake
+}
+
+// vickreyAuction ranks sealed bids by score; the winner commits the least
+// stake that would still have matched the runner-up's score, none if it
+// was the only bidder
+type vickreyAuction struct{}
+
+func (vickreyAuction) Name() string           { return AuctionVickrey }
+func (vickreyAuction) Sealed() bool           { return true }
+func (vickreyAuction) Rank(scores []BidScore) { RankBidScores(scores) }
+
+func (vickreyAuction) Price(ranked []BidScore) float64 {
+	if len(ranked) < 2 {
+		return 0
+	}
+	winner, runnerUp := ranked[0], ranked[1]
+	stake, _ := winner.Factor(FactorStake)
+	needed := (runnerUp.Score - (winner.Score - stake.Contribution)) / StakeWeight * 100
+	return min(max(needed, 0), winner.Bid.ReputationStake)
+}
+
+// reverseAuction ranks bids by estimated time, fastest first; the winner
+// commits its own stake
+type reverseAuction struct{}
+
+func (reverseAuction) Name() string { return AuctionReverse }
+func (reverseAuction) Sealed() bool { return false }
+
+func (reverseAuction) Rank(scores []BidScore) {
+	sort.SliceStable(scores, func(i, j int) bool {
+		a, b := scores[i].Bid, scores[j].Bid
+		if a.EstimatedTime != b.EstimatedTime {
+			return a.EstimatedTime < b.EstimatedTime
+		}
+		return compareBidScores(&scores[i], &scores[j]) < 0
+	})
+}
+
+func (reverseAuction) Price(ranked []BidScore) float64 {
+	if len(ranked) == 0 {
+		return 0
+	}
+	return ranked[0].Bid.ReputationStake
+}
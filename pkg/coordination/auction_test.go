@@ -0,0 +1,127 @@
+package coordination
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+func TestNewAuctionStrategy(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		sealed bool
+	}{
+		{"", AuctionWeighted, false},
+		{AuctionWeighted, AuctionWeighted, false},
+		{AuctionSealedBid, AuctionSealedBid, true},
+		{AuctionVickrey, AuctionVickrey, true},
+		{AuctionReverse, AuctionReverse, false},
+	}
+
+	for _, tt := range tests {
+		s, err := NewAuctionStrategy(tt.name)
+		if err != nil {
+			t.Fatalf("NewAuctionStrategy(%q) failed: %v", tt.name, err)
+		}
+		if s.Name() != tt.want || s.Sealed() != tt.sealed {
+			t.Errorf("Expected %s (sealed %v) for %q, got %s (sealed %v)", tt.want, tt.sealed, tt.name, s.Name(), s.Sealed())
+		}
+	}
+	if _, err := NewAuctionStrategy("dutch"); !errors.Is(err, ErrUnknownAuction) {
+		t.Errorf("Expected ErrUnknownAuction, got %v", err)
+	}
+}
+
+func TestTaskMarket_AuctionStrategies(t *testing.T) {
+	// Scores at the default reputation: sq-a 0.56, sq-b 0.552, sq-c 0.41
+	bids := []Bid{
+		{AgentSID: "sq-a", CapabilityScore: 0.85, ReputationStake: 10, EstimatedTime: 10 * time.Minute},
+		{AgentSID: "sq-b", CapabilityScore: 0.84, ReputationStake: 8, EstimatedTime: 5 * time.Minute},
+		{AgentSID: "sq-c", CapabilityScore: 0.5, ReputationStake: 5, EstimatedTime: 5 * time.Minute},
+	}
+	tests := []struct {
+		auction string
+		winner  string
+		stake   float64
+	}{
+		{AuctionWeighted, "sq-a", 10},
+		{AuctionSealedBid, "sq-a", 10},
+		{AuctionVickrey, "sq-a", 6}, // Enough stake to match sq-b's 0.552
+		{AuctionReverse, "sq-b", 8}, // Ties with sq-c on time, wins on score
+	}
+
+	for _, tt := range tests {
+		m := NewTaskMarket()
+		task := agent.NewTask("auction me", nil).WithAuction(tt.auction)
+		if err := m.ListTask(task); err != nil {
+			t.Fatalf("%s: ListTask failed: %v", tt.auction, err)
+		}
+		for i := range bids {
+			bid := bids[i]
+			bid.TaskID = task.ID
+			_ = m.SubmitBid(&bid)
+		}
+
+		ranked, err := m.RankBids(task.ID, NewReputationRegistry())
+		if err != nil {
+			t.Fatalf("%s: RankBids failed: %v", tt.auction, err)
+		}
+		if ranked[0].AgentSID != tt.winner || ranked[0].Auction != tt.auction {
+			t.Errorf("%s: expected %s to win, got %s by %s", tt.auction, tt.winner, ranked[0].AgentSID, ranked[0].Auction)
+		}
+		if math.Abs(ranked[0].Stake-tt.stake) > 1e-9 {
+			t.Errorf("%s: expected stake %f, got %f", tt.auction, tt.stake, ranked[0].Stake)
+		}
+		m.Close()
+	}
+
+	// A Vickrey winner without competition commits nothing
+	m := NewTaskMarket()
+	defer m.Close()
+	m.SetAuction(vickreyAuction{})
+	task := agent.NewTask("alone", nil)
+	_ = m.ListTask(task)
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-a", TaskID: task.ID, CapabilityScore: 1, ReputationStake: 10})
+	if ranked, _ := m.RankBids(task.ID, NewReputationRegistry()); ranked[0].Stake != 0 {
+		t.Errorf("Expected a lone Vickrey bidder to commit 0, got %f", ranked[0].Stake)
+	}
+
+	if err := m.ListTask(agent.NewTask("bad", nil).WithAuction("dutch")); !errors.Is(err, ErrUnknownAuction) {
+		t.Errorf("Expected ErrUnknownAuction, got %v", err)
+	}
+}
+
+func TestTaskMarket_SealedBids(t *testing.T) {
+	m := NewTaskMarket()
+	defer m.Close()
+	var revealed []*Bid
+	m.OnBid(func(b *Bid) { revealed = append(revealed, b) })
+
+	task := agent.NewTask("sealed", nil).WithAuction(AuctionSealedBid)
+	if err := m.ListTask(task); err != nil {
+		t.Fatalf("ListTask failed: %v", err)
+	}
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-a", TaskID: task.ID, CapabilityScore: 0.8})
+	if len(revealed) != 0 || len(m.GetBids(task.ID)) != 0 {
+		t.Errorf("Expected bids hidden while bidding is open, got %d revealed and %d listed", len(revealed), len(m.GetBids(task.ID)))
+	}
+
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-b", TaskID: task.ID, CapabilityScore: 0.7})
+
+	m.CloseBidding(task.ID)
+	if len(revealed) != 2 || len(m.GetBids(task.ID)) != 2 {
+		t.Errorf("Expected 2 bids revealed once bidding closed, got %d revealed and %d listed", len(revealed), len(m.GetBids(task.ID)))
+	}
+
+	// Open auctions reveal bids as they arrive
+	open := agent.NewTask("open", nil)
+	_ = m.ListTask(open)
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-a", TaskID: open.ID, CapabilityScore: 0.8})
+	if len(revealed) != 3 {
+		t.Errorf("Expected open bid revealed at once, got %d revealed", len(revealed))
+	}
+}
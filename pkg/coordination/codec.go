@@ -0,0 +1,194 @@
+package coordination
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+var ErrInvalidMessage = errors.New("invalid gossip message")
+
+// PayloadDecoder decodes the JSON payload of a message type into the value
+// handlers expect
+type PayloadDecoder func(data json.RawMessage) (interface{}, error)
+
+// PayloadEncoder encodes a message type's payload as JSON, rejecting values
+// of the wrong type
+type PayloadEncoder func(payload interface{}) (json.RawMessage, error)
+
+// payloadCodec is how the payload of a message type crosses the wire
+type payloadCodec struct {
+	goType reflect.Type // Type handlers receive (nil = any)
+	encode PayloadEncoder
+	decode PayloadDecoder
+}
+
+var (
+	payloadMu     sync.RWMutex
+	payloadCodecs = make(map[MessageType]payloadCodec)
+)
+
+func init() {
+	RegisterPayloadType[AgentJoined](MsgAgentJoined)
+	RegisterPayloadType[AgentLeft](MsgAgentLeft)
+	RegisterPayloadType[TaskAvailable](MsgTaskAvailable)
+	RegisterPayloadType[Bid](MsgTaskBid)
+	RegisterPayloadType[TaskAssignment](MsgTaskAssigned)
+	RegisterPayloadType[TaskCompleted](MsgTaskCompleted)
+	RegisterPayloadType[agent.Heartbeat](MsgHeartbeat)
+	RegisterPayloadType[ConsensusMessage](MsgConsensus)
+	RegisterPayloadType[MembershipView](MsgMembership)
+}
+
+// RegisterPayload sets the decoder for a message type's payload; it is
+// encoded as whatever JSON it marshals to. Payloads of types without a
+// decoder decode as generic JSON values.
+func RegisterPayload(t MessageType, decode PayloadDecoder) {
+	RegisterPayloadCodec(t, marshalPayload, decode)
+}
+
+// RegisterPayloadCodec sets both the encoder and the decoder of a message
+// type's payload
+func RegisterPayloadCodec(t MessageType, encode PayloadEncoder, decode PayloadDecoder) {
+	payloadMu.Lock()
+	defer payloadMu.Unlock()
+	payloadCodecs[t] = payloadCodec{encode: encode, decode: decode}
+}
+
+// RegisterPayloadType makes messages of type t carry a T: they encode only
+// a T or *T and decode into a T, and Broadcast hands in-process handlers a
+// T for a *T too, so handlers see the same payload either way
+func RegisterPayloadType[T any](t MessageType) {
+	goType := reflect.TypeOf((*T)(nil)).Elem()
+	encode := func(payload interface{}) (json.RawMessage, error) {
+		switch payload.(type) {
+		case T, *T:
+			return marshalPayload(payload)
+		}
+		return nil, fmt.Errorf("payload is %T, want %s", payload, goType)
+	}
+	decode := func(data json.RawMessage) (interface{}, error) {
+		var v T
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+
+	payloadMu.Lock()
+	defer payloadMu.Unlock()
+	payloadCodecs[t] = payloadCodec{goType: goType, encode: encode, decode: decode}
+}
+
+// marshalPayload encodes any payload as JSON
+func marshalPayload(payload interface{}) (json.RawMessage, error) {
+	return json.Marshal(payload)
+}
+
+// unmarshalPayload decodes any payload as a generic JSON value
+func unmarshalPayload(data json.RawMessage) (interface{}, error) {
+	var v interface{}
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// codecFor returns the payload codec of a message type, generic JSON if
+// none is registered
+func codecFor(t MessageType) payloadCodec {
+	payloadMu.RLock()
+	defer payloadMu.RUnlock()
+	if c, ok := payloadCodecs[t]; ok {
+		return c
+	}
+	return payloadCodec{encode: marshalPayload, decode: unmarshalPayload}
+}
+
+// normalizePayload dereferences a pointer to the payload type registered
+// for a message, and reports a payload of another type, which wouldn't
+// survive encoding
+func normalizePayload(msg Message) (interface{}, error) {
+	c := codecFor(msg.Type)
+	if c.goType == nil || msg.Payload == nil {
+		return msg.Payload, nil
+	}
+	v := reflect.ValueOf(msg.Payload)
+	switch {
+	case v.Type() == c.goType:
+		return msg.Payload, nil
+	case v.Kind() == reflect.Pointer && v.Type().Elem() == c.goType && !v.IsNil():
+		return v.Elem().Interface(), nil
+	}
+	return msg.Payload, fmt.Errorf("%w: %s payload is %T, want %s", ErrInvalidMessage, msg.Type, msg.Payload, c.goType)
+}
+
+// Codec encodes messages for a transport between processes. JSON is the
+// default; a transport may use another, such as protobuf, by mapping the
+// typed payloads to its own messages.
+type Codec interface {
+	Name() string
+	Encode(msg Message) ([]byte, error)
+	Decode(data []byte) (Message, error)
+}
+
+// JSONCodec encodes messages as JSON with EncodeMessage and DecodeMessage
+type JSONCodec struct{}
+
+// Name returns "json"
+func (JSONCodec) Name() string { return "json" }
+
+// Encode encodes a message as JSON
+func (JSONCodec) Encode(msg Message) ([]byte, error) { return EncodeMessage(msg) }
+
+// Decode decodes a JSON message
+func (JSONCodec) Decode(data []byte) (Message, error) { return DecodeMessage(data) }
+
+// EncodeMessage encodes a message for the wire with the encoder registered
+// for its type
+func EncodeMessage(msg Message) ([]byte, error) {
+	var payload json.RawMessage
+	if msg.Payload != nil {
+		var err error
+		if payload, err = codecFor(msg.Type).encode(msg.Payload); err != nil {
+			return nil, fmt.Errorf("%w: %s payload: %v", ErrInvalidMessage, msg.Type, err)
+		}
+	}
+	wire := struct {
+		Message
+		Payload json.RawMessage `json:"payload"`
+	}{msg, payload}
+	return json.Marshal(wire)
+}
+
+// DecodeMessage decodes a message from the wire, checking it is well formed
+// and decoding its payload with the decoder registered for its type
+func DecodeMessage(data []byte) (Message, error) {
+	var wire struct {
+		Message
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return Message{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	msg := wire.Message
+	switch {
+	case msg.ID == "":
+		return Message{}, fmt.Errorf("%w: missing id", ErrInvalidMessage)
+	case msg.Type == "":
+		return Message{}, fmt.Errorf("%w: missing type", ErrInvalidMessage)
+	case msg.TTL < 0:
+		return Message{}, fmt.Errorf("%w: negative ttl", ErrInvalidMessage)
+	}
+
+	if len(wire.Payload) == 0 || string(wire.Payload) == "null" {
+		return msg, nil
+	}
+	payload, err := codecFor(msg.Type).decode(wire.Payload)
+	if err != nil {
+		return Message{}, fmt.Errorf("%w: %s payload: %v", ErrInvalidMessage, msg.Type, err)
+	}
+	msg.Payload = payload
+	return msg, nil
+}
@@ -0,0 +1,115 @@
+package coordination_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	sqmtest "github.com/square-mind/squaremind/pkg/testing"
+)
+
+func TestDecodeMessage(t *testing.T) {
+	msg, err := coordination.DecodeMessage([]byte(`{"id":"m1","type":"membership","from":"a","ttl":2,"payload":{"version":3,"members":["a","b"]}}`))
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	view, ok := msg.Payload.(coordination.MembershipView)
+	if !ok || view.Version != 3 || len(view.Members) != 2 {
+		t.Errorf("Expected a membership view payload, got %#v", msg.Payload)
+	}
+
+	msg, err = coordination.DecodeMessage([]byte(`{"id":"m2","type":"custom","payload":{"n":1}}`))
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	if payload, ok := msg.Payload.(map[string]interface{}); !ok || payload["n"] != 1.0 {
+		t.Errorf("Expected a generic payload, got %#v", msg.Payload)
+	}
+
+	for _, bad := range []string{
+		`not json`,
+		`{"type":"heartbeat"}`,
+		`{"id":"m3"}`,
+		`{"id":"m4","type":"task_bid","ttl":-1}`,
+		`{"id":"m5","type":"membership","payload":"nope"}`,
+	} {
+		if _, err := coordination.DecodeMessage([]byte(bad)); !errors.Is(err, coordination.ErrInvalidMessage) {
+			t.Errorf("Expected ErrInvalidMessage for %s, got %v", bad, err)
+		}
+	}
+}
+
+func TestEncodeMessage_TypedPayloads(t *testing.T) {
+	task := agent.NewTask("Write code", nil)
+	msg := coordination.Message{ID: "m1", Type: coordination.MsgTaskAvailable, Payload: &coordination.TaskAvailable{Task: task}}
+	data, err := coordination.EncodeMessage(msg)
+	if err != nil {
+		t.Fatalf("EncodeMessage failed: %v", err)
+	}
+	decoded, err := coordination.DecodeMessage(data)
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	offer, ok := decoded.Payload.(coordination.TaskAvailable)
+	if !ok || offer.Task == nil || offer.Task.ID != task.ID {
+		t.Errorf("Expected a TaskAvailable payload for %s, got %#v", task.ID, decoded.Payload)
+	}
+
+	// A payload of the wrong type would reach remote handlers as something
+	// they don't expect
+	msg = coordination.Message{ID: "m2", Type: coordination.MsgTaskBid, Payload: "bid"}
+	if _, err := coordination.EncodeMessage(msg); !errors.Is(err, coordination.ErrInvalidMessage) {
+		t.Errorf("Expected ErrInvalidMessage for a string bid, got %v", err)
+	}
+}
+
+func TestRegisterPayloadType(t *testing.T) {
+	type ping struct {
+		Seq int `json:"seq"`
+	}
+	const msgPing coordination.MessageType = "test_ping"
+	coordination.RegisterPayloadType[ping](msgPing)
+
+	var codec coordination.Codec = coordination.JSONCodec{}
+	data, err := codec.Encode(coordination.Message{ID: "m1", Type: msgPing, Payload: ping{Seq: 7}})
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	msg, err := codec.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if p, ok := msg.Payload.(ping); !ok || p.Seq != 7 {
+		t.Errorf("Expected ping 7, got %#v", msg.Payload)
+	}
+}
+
+func TestMessage_RoundTrip(t *testing.T) {
+	fuzzer := sqmtest.NewMessageFuzzer(1)
+	for i := 0; i < 200; i++ {
+		if err := sqmtest.CheckMessageRoundTrip(fuzzer.Message()); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func FuzzDecodeMessage(f *testing.F) {
+	for _, data := range sqmtest.NewMessageFuzzer(1).Corpus(32) {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := coordination.DecodeMessage(data)
+		if err != nil {
+			if !errors.Is(err, coordination.ErrInvalidMessage) {
+				t.Fatalf("Expected ErrInvalidMessage, got %v", err)
+			}
+			return
+		}
+		// Anything accepted must survive another trip over the wire
+		if err := sqmtest.CheckMessageRoundTrip(msg); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
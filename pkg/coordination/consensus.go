@@ -3,10 +3,13 @@ package coordination
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/logging"
 )
 
 var (
@@ -22,6 +25,7 @@ const (
 	ConsensusTypeAgentSpawn      ConsensusType = "agent_spawn"
 	ConsensusTypeAgentTerminate  ConsensusType = "agent_terminate"
 	ConsensusTypeParameterChange ConsensusType = "parameter_change"
+	ConsensusTypePromotion       ConsensusType = "promotion"
 )
 
 // Proposal represents a proposal for consensus
@@ -38,6 +42,7 @@ type Vote struct {
 	AgentSID   string    `json:"agent_sid"`
 	ProposalID string    `json:"proposal_id"`
 	Value      bool      `json:"value"` // true = accept, false = reject
+	Weight     float64   `json:"weight,omitempty"`
 	Reason     string    `json:"reason,omitempty"`
 	Signature  []byte    `json:"signature,omitempty"`
 	Timestamp  time.Time `json:"timestamp"`
@@ -64,6 +69,10 @@ type ConsensusEngine struct {
 	// Callbacks
 	onAccept func(*Proposal)
 	onReject func(*Proposal)
+
+	now func() time.Time // Clock rounds are timed by (nil = time.Now)
+
+	logger logging.Logger
 }
 
 // NewConsensusEngine creates a new consensus engine
@@ -72,7 +81,47 @@ func NewConsensusEngine(threshold float64) *ConsensusEngine {
 		rounds:    make(map[string]*ConsensusRound),
 		threshold: threshold,
 		timeout:   30 * time.Second,
+		logger:    logging.Component("consensus"),
+	}
+}
+
+// SetLogger replaces the engine's logger
+func (c *ConsensusEngine) SetLogger(l logging.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger = l
+}
+
+// SetClock replaces the time source rounds are stamped and timed out by
+func (c *ConsensusEngine) SetClock(now func() time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// nowLocked returns the current time by the engine's clock. Caller must
+// hold c.mu.
+func (c *ConsensusEngine) nowLocked() time.Time {
+	if c.now != nil {
+		return c.now()
 	}
+	return time.Now()
+}
+
+// logResult logs the outcome of a decided round. Caller must hold c.mu.
+func (c *ConsensusEngine) logResult(round *ConsensusRound) {
+	args := []interface{}{
+		"proposal", round.Proposal.ID,
+		"type", round.Proposal.Type,
+		"result", round.Result,
+		"votes", len(round.Votes),
+		"elapsed", c.nowLocked().Sub(round.StartedAt),
+	}
+	if round.Result == "timeout" {
+		c.logger.Warn("consensus round timed out", args...)
+		return
+	}
+	c.logger.Info("consensus round decided", args...)
 }
 
 // SetThreshold sets the consensus threshold
@@ -105,24 +154,25 @@ func (c *ConsensusEngine) OnReject(callback func(*Proposal)) {
 
 // Propose starts a new consensus round
 func (c *ConsensusEngine) Propose(ctx context.Context, proposerSID string, cType ConsensusType, data map[string]interface{}) (*ConsensusRound, error) {
+	c.mu.Lock()
+	now := c.nowLocked()
 	proposal := &Proposal{
 		ID:        uuid.New().String(),
 		Type:      cType,
 		Proposer:  proposerSID,
 		Data:      data,
-		CreatedAt: time.Now(),
+		CreatedAt: now,
 	}
-
-	c.mu.Lock()
 	round := &ConsensusRound{
 		Proposal:  proposal,
 		Votes:     make(map[string]*Vote),
 		Threshold: c.threshold,
 		Timeout:   c.timeout,
-		StartedAt: time.Now(),
+		StartedAt: now,
 		Result:    "pending",
 	}
 	c.rounds[proposal.ID] = round
+	c.logger.Debug("consensus round proposed", "proposal", proposal.ID, "type", cType, "proposer", proposerSID)
 	c.mu.Unlock()
 
 	// Proposer automatically votes yes
@@ -131,7 +181,6 @@ func (c *ConsensusEngine) Propose(ctx context.Context, proposerSID string, cType
 		ProposalID: proposal.ID,
 		Value:      true,
 		Reason:     "proposer",
-		Timestamp:  time.Now(),
 	})
 
 	return round, nil
@@ -151,7 +200,7 @@ func (c *ConsensusEngine) SubmitVote(vote Vote) error {
 		return errors.New("consensus already reached")
 	}
 
-	vote.Timestamp = time.Now()
+	vote.Timestamp = c.nowLocked()
 	round.Votes[vote.AgentSID] = &vote
 
 	return nil
@@ -172,8 +221,9 @@ func (c *ConsensusEngine) CheckConsensus(proposalID string, totalVoters int) (bo
 	}
 
 	// Check timeout
-	if time.Since(round.StartedAt) > round.Timeout {
+	if c.nowLocked().Sub(round.StartedAt) > round.Timeout {
 		round.Result = "timeout"
+		c.logResult(round)
 		if c.onReject != nil {
 			go c.onReject(round.Proposal)
 		}
@@ -199,6 +249,7 @@ func (c *ConsensusEngine) CheckConsensus(proposalID string, totalVoters int) (bo
 
 	if accepts >= requiredVotes {
 		round.Result = "accepted"
+		c.logResult(round)
 		if c.onAccept != nil {
 			go c.onAccept(round.Proposal)
 		}
@@ -209,6 +260,75 @@ func (c *ConsensusEngine) CheckConsensus(proposalID string, totalVoters int) (bo
 	remainingVotes := totalVoters - len(round.Votes)
 	if accepts+remainingVotes < requiredVotes {
 		round.Result = "rejected"
+		c.logResult(round)
+		if c.onReject != nil {
+			go c.onReject(round.Proposal)
+		}
+		return false, "rejected"
+	}
+
+	return false, "pending"
+}
+
+// CheckWeightedConsensus checks consensus where each voter's ballot counts by
+// weight (e.g. reputation). Votes from SIDs absent from weights count for nothing.
+// With no eligible voting weight the proposal is accepted, as nobody can object.
+func (c *ConsensusEngine) CheckWeightedConsensus(proposalID string, weights map[string]float64) (bool, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	round, ok := c.rounds[proposalID]
+	if !ok {
+		return false, "not_found"
+	}
+
+	if round.Result != "pending" {
+		return round.Result == "accepted", round.Result
+	}
+
+	if c.nowLocked().Sub(round.StartedAt) > round.Timeout {
+		round.Result = "timeout"
+		c.logResult(round)
+		if c.onReject != nil {
+			go c.onReject(round.Proposal)
+		}
+		return false, "timeout"
+	}
+
+	totalWeight := 0.0
+	for _, w := range weights {
+		totalWeight += w
+	}
+
+	acceptWeight := 0.0
+	castWeight := 0.0
+	for sid, vote := range round.Votes {
+		w, eligible := weights[sid]
+		if !eligible {
+			continue
+		}
+		vote.Weight = w
+		castWeight += w
+		if vote.Value {
+			acceptWeight += w
+		}
+	}
+
+	requiredWeight := totalWeight * round.Threshold
+
+	if acceptWeight >= requiredWeight {
+		round.Result = "accepted"
+		c.logResult(round)
+		if c.onAccept != nil {
+			go c.onAccept(round.Proposal)
+		}
+		return true, "accepted"
+	}
+
+	remainingWeight := totalWeight - castWeight
+	if acceptWeight+remainingWeight < requiredWeight {
+		round.Result = "rejected"
+		c.logResult(round)
 		if c.onReject != nil {
 			go c.onReject(round.Proposal)
 		}
@@ -247,6 +367,25 @@ func (c *ConsensusEngine) GetRound(proposalID string) *ConsensusRound {
 	return c.rounds[proposalID]
 }
 
+// Round returns a copy of a consensus round, safe to read while votes are
+// still being cast
+func (c *ConsensusEngine) Round(proposalID string) (ConsensusRound, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	round, ok := c.rounds[proposalID]
+	if !ok {
+		return ConsensusRound{}, false
+	}
+	cp := *round
+	cp.Votes = make(map[string]*Vote, len(round.Votes))
+	for sid, vote := range round.Votes {
+		v := *vote
+		cp.Votes[sid] = &v
+	}
+	return cp, true
+}
+
 // GetAllRounds returns all consensus rounds
 func (c *ConsensusEngine) GetAllRounds() []*ConsensusRound {
 	c.mu.RLock()
@@ -259,13 +398,53 @@ func (c *ConsensusEngine) GetAllRounds() []*ConsensusRound {
 	return rounds
 }
 
+// RoundSummary is a point-in-time view of a consensus round
+type RoundSummary struct {
+	ProposalID string        `json:"proposal_id"`
+	Type       ConsensusType `json:"type"`
+	Proposer   string        `json:"proposer"`
+	Result     string        `json:"result"`
+	Votes      int           `json:"votes"`
+	Accepts    int           `json:"accepts"`
+	StartedAt  time.Time     `json:"started_at"`
+}
+
+// Summaries returns a summary of every round, newest first
+func (c *ConsensusEngine) Summaries() []RoundSummary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	summaries := make([]RoundSummary, 0, len(c.rounds))
+	for _, round := range c.rounds {
+		summary := RoundSummary{
+			ProposalID: round.Proposal.ID,
+			Type:       round.Proposal.Type,
+			Proposer:   round.Proposal.Proposer,
+			Result:     round.Result,
+			Votes:      len(round.Votes),
+			StartedAt:  round.StartedAt,
+		}
+		for _, vote := range round.Votes {
+			if vote.Value {
+				summary.Accepts++
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	return summaries
+}
+
 // CleanupOldRounds removes old completed rounds
 func (c *ConsensusEngine) CleanupOldRounds(maxAge time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	for id, round := range c.rounds {
-		if round.Result != "pending" && time.Since(round.StartedAt) > maxAge {
+		if round.Result != "pending" && c.nowLocked().Sub(round.StartedAt) > maxAge {
 			delete(c.rounds, id)
 		}
 	}
@@ -2,8 +2,11 @@ package coordination
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
+
+	"github.com/square-mind/squaremind/pkg/logging"
 )
 
 func TestNewConsensusEngine(t *testing.T) {
@@ -156,3 +159,189 @@ func TestConsensusEngine_GetAllRounds(t *testing.T) {
 		t.Errorf("Expected 2 rounds, got %d", len(rounds))
 	}
 }
+
+func TestConsensusEngine_CheckWeightedConsensus(t *testing.T) {
+	ce := NewConsensusEngine(0.67)
+
+	ctx := context.Background()
+	round, _ := ce.Propose(ctx, "market", ConsensusTypeTaskAssignment, nil)
+
+	// A single high-reputation voter outweighs two low-reputation ones
+	_ = ce.SubmitVote(Vote{AgentSID: "agent-1", ProposalID: round.Proposal.ID, Value: true})
+	_ = ce.SubmitVote(Vote{AgentSID: "agent-2", ProposalID: round.Proposal.ID, Value: false})
+	_ = ce.SubmitVote(Vote{AgentSID: "agent-3", ProposalID: round.Proposal.ID, Value: false})
+
+	weights := map[string]float64{"agent-1": 90, "agent-2": 10, "agent-3": 10}
+	reached, result := ce.CheckWeightedConsensus(round.Proposal.ID, weights)
+
+	if !reached || result != "accepted" {
+		t.Errorf("Expected weighted acceptance, got reached=%v, result=%s", reached, result)
+	}
+}
+
+func TestConsensusEngine_CheckWeightedConsensus_Rejected(t *testing.T) {
+	ce := NewConsensusEngine(0.67)
+
+	ctx := context.Background()
+	round, _ := ce.Propose(ctx, "market", ConsensusTypeTaskAssignment, nil)
+
+	_ = ce.SubmitVote(Vote{AgentSID: "agent-1", ProposalID: round.Proposal.ID, Value: false})
+
+	// The proposer is not an eligible voter, so its automatic yes carries no weight
+	weights := map[string]float64{"agent-1": 60, "agent-2": 40}
+	reached, result := ce.CheckWeightedConsensus(round.Proposal.ID, weights)
+
+	if reached || result != "rejected" {
+		t.Errorf("Expected weighted rejection, got reached=%v, result=%s", reached, result)
+	}
+}
+
+func TestConsensusEngine_Summaries(t *testing.T) {
+	ce := NewConsensusEngine(0.67)
+
+	ctx := context.Background()
+	round, _ := ce.Propose(ctx, "agent-1", ConsensusTypeTaskAssignment, nil)
+	_ = ce.SubmitVote(Vote{AgentSID: "agent-2", ProposalID: round.Proposal.ID, Value: false})
+
+	summaries := ce.Summaries()
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 summary, got %d", len(summaries))
+	}
+
+	s := summaries[0]
+	if s.Proposer != "agent-1" {
+		t.Errorf("Expected proposer 'agent-1', got '%s'", s.Proposer)
+	}
+	if s.Votes != 2 || s.Accepts != 1 {
+		t.Errorf("Expected 1 of 2 votes accepting, got %d of %d", s.Accepts, s.Votes)
+	}
+}
+
+func FuzzCheckConsensus(f *testing.F) {
+	f.Add([]byte{1, 3, 5, 0, 2}, uint8(5), uint8(67))
+	f.Add([]byte{0, 2, 4, 6}, uint8(4), uint8(100))
+	f.Add([]byte{}, uint8(1), uint8(1))
+	f.Add([]byte{7, 7, 6, 6, 9}, uint8(3), uint8(50))
+
+	f.Fuzz(func(t *testing.T, ballots []byte, voters, percent uint8) {
+		if voters == 0 || percent == 0 || percent > 100 {
+			return
+		}
+		threshold := float64(percent) / 100
+		ce := NewConsensusEngine(threshold)
+		ce.SetLogger(logging.Nop())
+
+		round, err := ce.Propose(context.Background(), "voter-0", ConsensusTypeParameterChange, nil)
+		if err != nil {
+			t.Fatalf("Propose failed: %v", err)
+		}
+
+		// Each ballot byte names a voter and their choice; later ballots
+		// replace earlier ones until the round is decided
+		accepted, result := ce.CheckConsensus(round.Proposal.ID, int(voters))
+		tally := map[string]bool{"voter-0": true}
+		for _, b := range ballots {
+			if result != "pending" {
+				break
+			}
+			sid := fmt.Sprintf("voter-%d", int(b>>1)%int(voters))
+			if err := ce.SubmitVote(Vote{AgentSID: sid, ProposalID: round.Proposal.ID, Value: b&1 == 1}); err != nil {
+				t.Fatalf("SubmitVote on a pending round failed: %v", err)
+			}
+			tally[sid] = b&1 == 1
+			accepted, result = ce.CheckConsensus(round.Proposal.ID, int(voters))
+		}
+
+		accepts := 0
+		for _, v := range tally {
+			if v {
+				accepts++
+			}
+		}
+		required := max(int(float64(voters)*threshold), 1)
+		undecided := int(voters) - len(tally)
+
+		switch result {
+		case "accepted":
+			if !accepted || accepts < required {
+				t.Fatalf("Accepted with %d of %d required votes", accepts, required)
+			}
+		case "rejected":
+			if accepted || accepts+undecided >= required {
+				t.Fatalf("Rejected while %d accepts and %d undecided could reach %d", accepts, undecided, required)
+			}
+		case "pending":
+			if accepts >= required || accepts+undecided < required {
+				t.Fatalf("Undecided with %d accepts, %d undecided and %d required", accepts, undecided, required)
+			}
+		default:
+			t.Fatalf("Unexpected result %q", result)
+		}
+
+		// A decided round is final
+		if result != "pending" {
+			if err := ce.SubmitVote(Vote{AgentSID: "voter-0", ProposalID: round.Proposal.ID, Value: !accepted}); err == nil {
+				t.Fatal("Expected a vote on a decided round to be refused")
+			}
+			if again, r := ce.CheckConsensus(round.Proposal.ID, int(voters)); again != accepted || r != result {
+				t.Fatalf("Decided round changed from %s to %s", result, r)
+			}
+		}
+	})
+}
+
+func FuzzCheckWeightedConsensus(f *testing.F) {
+	f.Add([]byte{10, 20, 30}, []byte{1, 0, 1}, uint8(67))
+	f.Add([]byte{0, 0}, []byte{0, 1}, uint8(50))
+
+	f.Fuzz(func(t *testing.T, weights, ballots []byte, percent uint8) {
+		if len(weights) == 0 || len(weights) > 64 || percent == 0 || percent > 100 {
+			return
+		}
+		threshold := float64(percent) / 100
+		ce := NewConsensusEngine(threshold)
+		ce.SetLogger(logging.Nop())
+
+		round, _ := ce.Propose(context.Background(), "voter-0", ConsensusTypeParameterChange, nil)
+		w := make(map[string]float64, len(weights))
+		total := 0.0
+		for i, b := range weights {
+			w[fmt.Sprintf("voter-%d", i)] = float64(b)
+			total += float64(b)
+		}
+		for i, b := range ballots {
+			if i >= len(weights) {
+				break
+			}
+			_ = ce.SubmitVote(Vote{AgentSID: fmt.Sprintf("voter-%d", i), ProposalID: round.Proposal.ID, Value: b&1 == 1})
+		}
+
+		accepted, result := ce.CheckWeightedConsensus(round.Proposal.ID, w)
+
+		acceptWeight, cast := 0.0, 0.0
+		for sid, vote := range ce.GetRound(round.Proposal.ID).Votes {
+			cast += w[sid]
+			if vote.Value {
+				acceptWeight += w[sid]
+			}
+		}
+		required := total * threshold
+
+		switch result {
+		case "accepted":
+			if !accepted || acceptWeight < required {
+				t.Fatalf("Accepted with weight %v of %v required", acceptWeight, required)
+			}
+		case "rejected":
+			if accepted || acceptWeight+total-cast >= required {
+				t.Fatalf("Rejected while weight %v could still reach %v", acceptWeight+total-cast, required)
+			}
+		case "pending":
+			if acceptWeight >= required {
+				t.Fatalf("Undecided with weight %v of %v required", acceptWeight, required)
+			}
+		default:
+			t.Fatalf("Unexpected result %q", result)
+		}
+	})
+}
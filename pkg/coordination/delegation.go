@@ -0,0 +1,80 @@
+package coordination
+
+import (
+	"fmt"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// AgentLookup finds an agent by SID
+type AgentLookup func(sid string) (*agent.Agent, bool)
+
+// SetAgentLookup sets how the market finds the delegators of delegation
+// proofs presented in bids. Without one, bids relying on delegations are
+// rejected.
+func (m *TaskMarket) SetAgentLookup(lookup AgentLookup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookup = lookup
+}
+
+// VerifyDelegation checks that a delegation proof lets bidderSID bid on its
+// capability: it must be issued to the bidder, unexpired, signed by its
+// delegator, and the delegator must hold the capability. Returns the
+// delegator.
+func (m *TaskMarket) VerifyDelegation(bidderSID string, proof *identity.DelegationProof) (*agent.Agent, error) {
+	m.mu.RLock()
+	lookup := m.lookup
+	m.mu.RUnlock()
+
+	switch {
+	case proof.DelegateSID != bidderSID:
+		return nil, fmt.Errorf("%w: issued to %s, not %s", identity.ErrInvalidDelegation, proof.DelegateSID, bidderSID)
+	case !proof.IsValid():
+		return nil, fmt.Errorf("%w: %s delegation expired", identity.ErrInvalidDelegation, proof.Capability)
+	case lookup == nil:
+		return nil, fmt.Errorf("%w: market cannot resolve delegators", identity.ErrInvalidDelegation)
+	}
+
+	delegator, ok := lookup(proof.DelegatorSID)
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("%w: unknown delegator %s", identity.ErrInvalidDelegation, proof.DelegatorSID)
+	case !proof.Verify(delegator.Identity.PublicKey):
+		return nil, fmt.Errorf("%w: not signed by delegator %s", identity.ErrInvalidDelegation, proof.DelegatorSID)
+	case !delegator.Capabilities.Has(proof.Capability):
+		return nil, fmt.Errorf("%w: delegator %s does not hold %s", identity.ErrInvalidDelegation, proof.DelegatorSID, proof.Capability)
+	}
+	return delegator, nil
+}
+
+// delegatedMatch matches an agent against required with the capabilities
+// lent to it by valid delegations counted at their delegators' proficiency.
+// Returns the delegations the match relies on.
+func (m *TaskMarket) delegatedMatch(a *agent.Agent, required []identity.CapabilityType) (identity.MatchBreakdown, []*identity.DelegationProof) {
+	held := a.Capabilities.Proficiencies()
+	lent := make(map[identity.CapabilityType]*identity.DelegationProof)
+	for _, proof := range a.Delegations() {
+		delegator, err := m.VerifyDelegation(a.Identity.SID, proof)
+		if err != nil {
+			m.log().Debug("delegation ignored", "agent", a.Identity.SID, "capability", proof.Capability, "error", err)
+			continue
+		}
+		if p := delegator.Capabilities.Proficiency(proof.Capability); p > held[proof.Capability] {
+			held[proof.Capability] = p
+			lent[proof.Capability] = proof
+		}
+	}
+
+	match := identity.Match(held, required, identity.DefaultCapabilityRegistry())
+	var used []*identity.DelegationProof
+	seen := make(map[identity.CapabilityType]bool)
+	for _, r := range match.Requirements {
+		if proof, ok := lent[r.Source]; ok && !seen[r.Source] {
+			seen[r.Source] = true
+			used = append(used, proof)
+		}
+	}
+	return match, used
+}
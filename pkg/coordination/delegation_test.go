@@ -0,0 +1,110 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func TestTaskMarket_DelegatedBid(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	auditor, _ := agent.NewAgent(agent.AgentConfig{Name: "Auditor", Capabilities: []identity.CapabilityType{identity.CapSecurity}})
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = coder.Start(ctx)
+	defer coder.Stop()
+
+	agents := map[string]*agent.Agent{auditor.Identity.SID: auditor, coder.Identity.SID: coder}
+	m := NewTaskMarket()
+	defer m.Close()
+	m.SetBidTimeout(time.Millisecond)
+	m.SetAgentLookup(func(sid string) (*agent.Agent, bool) {
+		a, ok := agents[sid]
+		return a, ok
+	})
+	bidders := map[string]*agent.Agent{coder.Identity.SID: coder}
+
+	task := agent.NewTask("Audit the code", []identity.CapabilityType{identity.CapSecurity})
+	if _, err := m.AssignTask(task, bidders, NewReputationRegistry()); !errors.Is(err, ErrNoBids) {
+		t.Fatalf("Expected ErrNoBids without a delegation, got %v", err)
+	}
+
+	proof, err := auditor.Delegate(coder.Identity.SID, identity.CapSecurity, time.Hour)
+	if err != nil {
+		t.Fatalf("Delegate failed: %v", err)
+	}
+	if err := coder.AddDelegation(proof); err != nil {
+		t.Fatalf("AddDelegation failed: %v", err)
+	}
+
+	task = agent.NewTask("Audit the code", []identity.CapabilityType{identity.CapSecurity})
+	assignment, err := m.AssignTask(task, bidders, NewReputationRegistry())
+	if err != nil {
+		t.Fatalf("Expected the delegate to win, got %v", err)
+	}
+	if assignment.AgentSID != coder.Identity.SID || len(assignment.Bid.Delegations) != 1 {
+		t.Errorf("Expected a bid from the coder presenting 1 delegation, got %+v", assignment.Bid)
+	}
+	if _, err := coder.Delegate(auditor.Identity.SID, identity.CapSecurity, time.Hour); !errors.Is(err, identity.ErrInvalidDelegation) {
+		t.Errorf("Expected an agent to be unable to delegate a capability it lacks, got %v", err)
+	}
+}
+
+func TestTaskMarket_VerifyDelegation(t *testing.T) {
+	auditor, _ := agent.NewAgent(agent.AgentConfig{Name: "Auditor", Capabilities: []identity.CapabilityType{identity.CapSecurity}})
+	forger, _ := agent.NewAgent(agent.AgentConfig{Name: "Forger", Capabilities: []identity.CapabilityType{identity.CapSecurity}})
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+
+	agents := map[string]*agent.Agent{auditor.Identity.SID: auditor, coder.Identity.SID: coder}
+	m := NewTaskMarket()
+	defer m.Close()
+	m.SetAgentLookup(func(sid string) (*agent.Agent, bool) {
+		a, ok := agents[sid]
+		return a, ok
+	})
+
+	valid, _ := identity.NewDelegationProof(auditor.Identity, coder.Identity.SID, identity.CapSecurity, time.Hour)
+	expired, _ := identity.NewDelegationProof(auditor.Identity, coder.Identity.SID, identity.CapSecurity, -time.Minute)
+	other, _ := identity.NewDelegationProof(auditor.Identity, forger.Identity.SID, identity.CapSecurity, time.Hour)
+	unheld, _ := identity.NewDelegationProof(auditor.Identity, coder.Identity.SID, identity.CapTesting, time.Hour)
+	unknown, _ := identity.NewDelegationProof(forger.Identity, coder.Identity.SID, identity.CapSecurity, time.Hour)
+	forged, _ := identity.NewDelegationProof(forger.Identity, coder.Identity.SID, identity.CapSecurity, time.Hour)
+	forged.DelegatorSID = auditor.Identity.SID
+
+	tests := []struct {
+		name  string
+		proof *identity.DelegationProof
+		valid bool
+	}{
+		{"valid", valid, true},
+		{"expired", expired, false},
+		{"issued to another agent", other, false},
+		{"capability not held by delegator", unheld, false},
+		{"unknown delegator", unknown, false},
+		{"forged signature", forged, false},
+	}
+
+	for _, tt := range tests {
+		task := agent.NewTask("Audit the code", []identity.CapabilityType{identity.CapSecurity})
+		_ = m.ListTask(task)
+		err := m.SubmitBid(&Bid{AgentSID: coder.Identity.SID, TaskID: task.ID, CapabilityScore: 0.5, Delegations: []*identity.DelegationProof{tt.proof}})
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected bid accepted, got %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, identity.ErrInvalidDelegation) {
+			t.Errorf("%s: expected ErrInvalidDelegation, got %v", tt.name, err)
+		}
+		if got := len(m.GetBids(task.ID)); tt.valid != (got == 1) {
+			t.Errorf("%s: expected valid=%v, got %d bids", tt.name, tt.valid, got)
+		}
+	}
+
+	if err := coder.AddDelegation(other); !errors.Is(err, identity.ErrInvalidDelegation) {
+		t.Errorf("Expected AddDelegation to refuse a proof issued to another agent, got %v", err)
+	}
+}
@@ -3,10 +3,14 @@ package coordination
 import (
 	"context"
 	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/logging"
 )
 
 // MessageType represents types of gossip messages
@@ -21,6 +25,7 @@ const (
 	MsgTaskCompleted MessageType = "task_completed"
 	MsgHeartbeat     MessageType = "heartbeat"
 	MsgConsensus     MessageType = "consensus"
+	MsgMembership    MessageType = "membership"
 )
 
 // Message represents a gossip message
@@ -31,6 +36,15 @@ type Message struct {
 	Payload   interface{} `json:"payload"`
 	Timestamp time.Time   `json:"timestamp"`
 	TTL       int         `json:"ttl"` // Hops remaining
+
+	local bool // Broadcast by this protocol rather than received from a peer
+}
+
+// MembershipView is a versioned snapshot of collective membership. Peers only
+// apply views newer than the one they hold, so out-of-order delivery is safe.
+type MembershipView struct {
+	Version uint64   `json:"version"`
+	Members []string `json:"members"`
 }
 
 // GossipProtocol implements epidemic-style message propagation
@@ -38,28 +52,98 @@ type GossipProtocol struct {
 	mu sync.RWMutex
 
 	peers    map[string]bool // SID -> active
-	seen     map[string]bool // Message ID -> seen
+	seen     *seenCache      // Messages handled recently
+	limiter  *peerLimiter    // New messages each sender may have handled
+	stale    uint64          // Messages dropped for being older than the seen TTL
 	handlers map[MessageType][]MessageHandler
 
-	fanout   int           // Number of peers to forward to
-	interval time.Duration // Gossip interval
-
-	msgChan chan Message
+	membershipVersion uint64
+
+	// Adaptive tuning, recomputed from config as peers and load change
+	config     GossipConfig
+	priorities map[MessageType]MessagePriority
+	fanout     int           // Number of peers to forward to
+	bulkFanout int           // Fanout for bulk messages, reduced under load
+	ttl        int           // Hops given to new messages
+	interval   time.Duration // Gossip interval: how often deferred bulk forwards are sent
+	rate       float64       // Smoothed messages handled per second
+	handled    int           // Messages handled since the last measurement
+	deferred   []Message     // Bulk messages waiting for the next interval
+
+	queues  [priorityLevels]chan Message
+	dropped atomic.Uint64
+
+	// Simulation hooks: the time messages are stamped with, the source of
+	// fanout selection and where forwards go (nil = the defaults)
+	now       func() time.Time
+	rand      *rand.Rand
+	transport Transport
+
+	logger logging.Logger
 }
 
 // MessageHandler handles incoming gossip messages
 type MessageHandler func(msg Message)
 
+// Transport carries a forwarded message to a peer. It is called with the
+// protocol locked, so it must not call back into it.
+type Transport func(peer string, msg Message)
+
+// queueSize is the capacity of each priority's queue and of the deferred
+// bulk forwards
+const queueSize = 1000
+
+// cleanupInterval is how often expired seen messages and idle senders' rate
+// limits are dropped
+const cleanupInterval = 10 * time.Second
+
 // NewGossipProtocol creates a new gossip protocol instance
 func NewGossipProtocol() *GossipProtocol {
-	return &GossipProtocol{
-		peers:    make(map[string]bool),
-		seen:     make(map[string]bool),
-		handlers: make(map[MessageType][]MessageHandler),
-		fanout:   3,
-		interval: 100 * time.Millisecond,
-		msgChan:  make(chan Message, 1000),
+	cfg := DefaultGossipConfig()
+	g := &GossipProtocol{
+		peers:      make(map[string]bool),
+		seen:       newSeenCache(cfg.SeenTTL, cfg.SeenCapacity),
+		limiter:    newPeerLimiter(cfg.PeerRate, cfg.PeerBurst),
+		handlers:   make(map[MessageType][]MessageHandler),
+		config:     cfg,
+		priorities: defaultPriorities(),
+		logger:     logging.Component("gossip"),
+	}
+	for i := range g.queues {
+		g.queues[i] = make(chan Message, queueSize)
 	}
+	g.retuneLocked()
+	return g
+}
+
+// SetLogger replaces the protocol's logger
+func (g *GossipProtocol) SetLogger(l logging.Logger) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.logger = l
+}
+
+// SetClock replaces the time source messages are stamped with
+func (g *GossipProtocol) SetClock(now func() time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.now = now
+}
+
+// SetRand makes fanout selection draw from r instead of the global source,
+// so that it is reproducible. r must not be shared with other goroutines.
+func (g *GossipProtocol) SetRand(r *rand.Rand) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.rand = r
+}
+
+// SetTransport sends forwarded messages through t instead of re-queueing
+// them locally
+func (g *GossipProtocol) SetTransport(t Transport) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.transport = t
 }
 
 // AddPeer adds a peer to the gossip network
@@ -67,6 +151,7 @@ func (g *GossipProtocol) AddPeer(sid string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.peers[sid] = true
+	g.retuneLocked()
 }
 
 // RemovePeer removes a peer from the gossip network
@@ -74,6 +159,7 @@ func (g *GossipProtocol) RemovePeer(sid string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	delete(g.peers, sid)
+	g.retuneLocked()
 }
 
 // GetPeers returns list of active peers
@@ -95,6 +181,33 @@ func (g *GossipProtocol) PeerCount() int {
 	return len(g.peers)
 }
 
+// ApplyMembership replaces the peer set with a membership view. Views no newer
+// than the current one are ignored; returns true if the view was applied.
+func (g *GossipProtocol) ApplyMembership(view MembershipView) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if view.Version <= g.membershipVersion {
+		return false
+	}
+
+	g.peers = make(map[string]bool, len(view.Members))
+	for _, sid := range view.Members {
+		g.peers[sid] = true
+	}
+	g.membershipVersion = view.Version
+	g.retuneLocked()
+	g.logger.Debug("membership updated", "version", view.Version, "members", len(view.Members))
+	return true
+}
+
+// MembershipVersion returns the version of the last applied membership view
+func (g *GossipProtocol) MembershipVersion() uint64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.membershipVersion
+}
+
 // OnMessage registers a handler for a message type
 func (g *GossipProtocol) OnMessage(msgType MessageType, handler MessageHandler) {
 	g.mu.Lock()
@@ -102,18 +215,76 @@ func (g *GossipProtocol) OnMessage(msgType MessageType, handler MessageHandler)
 	g.handlers[msgType] = append(g.handlers[msgType], handler)
 }
 
-// Broadcast sends a message to the network
+// Broadcast sends a message to the network. Without a TTL it gets enough
+// hops to reach the whole collective. A pointer to the payload type
+// registered for the message's type is sent as the value it points to.
 func (g *GossipProtocol) Broadcast(msg Message) {
 	msg.ID = uuid.New().String()
-	msg.Timestamp = time.Now()
+	msg.local = true
+
+	g.mu.RLock()
+	msg.Timestamp = g.nowLocked()
 	if msg.TTL == 0 {
-		msg.TTL = 10 // Max hops
+		msg.TTL = g.ttl
+	}
+	logger := g.logger
+	g.mu.RUnlock()
+
+	payload, err := normalizePayload(msg)
+	if err != nil {
+		logger.Warn("gossip payload will not survive encoding", "type", msg.Type, "message", msg.ID, "error", err)
+	}
+	msg.Payload = payload
+
+	if !g.enqueue(msg) {
+		logger.Warn("gossip queue full, dropping message", "type", msg.Type, "message", msg.ID)
+	}
+}
+
+// nowLocked returns the current time by the protocol's clock. Caller must
+// hold g.mu.
+func (g *GossipProtocol) nowLocked() time.Time {
+	if g.now != nil {
+		return g.now()
 	}
+	return time.Now()
+}
+
+// enqueue queues a message by its priority; returns false if the queue is full
+func (g *GossipProtocol) enqueue(msg Message) bool {
+	g.mu.RLock()
+	queue := g.queues[g.priorityLocked(msg.Type)]
+	g.mu.RUnlock()
 
 	select {
-	case g.msgChan <- msg:
+	case queue <- msg:
+		return true
 	default:
-		// Channel full, drop message
+		g.dropped.Add(1)
+		return false
+	}
+}
+
+// next returns the next queued message, highest priority first. Blocks until
+// one arrives; returns false when ctx ends.
+func (g *GossipProtocol) next(ctx context.Context) (Message, bool) {
+	for p := priorityLevels - 1; p >= 0; p-- {
+		select {
+		case msg := <-g.queues[p]:
+			return msg, true
+		default:
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return Message{}, false
+	case msg := <-g.queues[PriorityCritical]:
+		return msg, true
+	case msg := <-g.queues[PriorityNormal]:
+		return msg, true
+	case msg := <-g.queues[PriorityBulk]:
+		return msg, true
 	}
 }
 
@@ -126,30 +297,55 @@ func (g *GossipProtocol) Start(ctx context.Context) {
 // processMessages handles incoming messages
 func (g *GossipProtocol) processMessages(ctx context.Context) {
 	for {
-		select {
-		case <-ctx.Done():
+		msg, ok := g.next(ctx)
+		if !ok {
 			return
-		case msg := <-g.msgChan:
-			g.handleMessage(msg)
 		}
+		g.handleMessage(msg)
 	}
 }
 
-// handleMessage processes a single message
+// Deliver handles a message received from a peer at once, as the message
+// loop would: unseen messages reach the handlers and are forwarded
+func (g *GossipProtocol) Deliver(msg Message) {
+	g.handleMessage(msg)
+}
+
+// handleMessage processes a single message. Copies of messages seen within
+// the seen TTL, messages older than it and messages received beyond their
+// sender's rate limit are dropped. The protocol's own broadcasts aren't
+// limited, whatever sender they name.
 func (g *GossipProtocol) handleMessage(msg Message) {
 	g.mu.Lock()
 
-	// Check if already seen
-	if g.seen[msg.ID] {
+	now := g.nowLocked()
+	if !msg.Timestamp.IsZero() && now.Sub(msg.Timestamp) >= g.config.SeenTTL {
+		g.stale++
 		g.mu.Unlock()
 		return
 	}
-	g.seen[msg.ID] = true
+	if g.seen.observe(msg.ID, now) {
+		g.mu.Unlock()
+		return
+	}
+	if !msg.local && !g.limiter.allow(msg.From, now) {
+		// A copy arriving once the sender is within its limit is handled
+		g.seen.forget(msg.ID)
+		g.logger.Debug("sender over its gossip rate limit, dropping message", "from", msg.From, "type", msg.Type, "message", msg.ID)
+		g.mu.Unlock()
+		return
+	}
+	g.handled++
 
 	// Get handlers
 	handlers := g.handlers[msg.Type]
 	g.mu.Unlock()
 
+	// Membership views update the peer set before handlers observe them
+	if view, ok := msg.Payload.(MembershipView); ok && msg.Type == MsgMembership {
+		g.ApplyMembership(view)
+	}
+
 	// Execute handlers
 	for _, h := range handlers {
 		h(msg)
@@ -158,92 +354,168 @@ func (g *GossipProtocol) handleMessage(msg Message) {
 	// Forward to random peers if TTL > 0
 	if msg.TTL > 0 {
 		msg.TTL--
+		msg.local = false
 		g.forward(msg)
 	}
 }
 
-// forward sends message to random subset of peers
+// forward sends message to random subset of peers. Bulk messages wait for
+// the next gossip interval and are sent in a batch.
 func (g *GossipProtocol) forward(msg Message) {
-	g.mu.RLock()
-	defer g.mu.RUnlock()
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	// Get list of peers (excluding sender)
+	if g.priorityLocked(msg.Type) != PriorityBulk {
+		g.sendToPeersLocked(msg, g.fanout)
+		return
+	}
+	if len(g.deferred) >= queueSize {
+		g.dropped.Add(1)
+		return
+	}
+	g.deferred = append(g.deferred, msg)
+}
+
+// sendToPeersLocked sends a message to up to fanout random peers other than
+// its sender. Caller must hold g.mu.
+func (g *GossipProtocol) sendToPeersLocked(msg Message, fanout int) {
+	// Get list of peers (excluding sender), in a stable order for seeded selection
 	var candidates []string
 	for sid := range g.peers {
 		if sid != msg.From {
 			candidates = append(candidates, sid)
 		}
 	}
+	sort.Strings(candidates)
 
 	// Select random subset
-	if len(candidates) <= g.fanout {
+	if len(candidates) <= fanout {
 		// Send to all
 		for _, sid := range candidates {
 			g.sendTo(sid, msg)
 		}
 	} else {
 		// Random selection
-		rand.Shuffle(len(candidates), func(i, j int) {
+		shuffle := rand.Shuffle
+		if g.rand != nil {
+			shuffle = g.rand.Shuffle
+		}
+		shuffle(len(candidates), func(i, j int) {
 			candidates[i], candidates[j] = candidates[j], candidates[i]
 		})
-		for i := 0; i < g.fanout; i++ {
+		for i := 0; i < fanout; i++ {
 			g.sendTo(candidates[i], msg)
 		}
 	}
 }
 
-// sendTo sends a message to a specific peer
+// sendTo sends a message to a specific peer. Caller must hold g.mu.
 func (g *GossipProtocol) sendTo(sid string, msg Message) {
+	if g.transport != nil {
+		g.transport(sid, msg)
+		return
+	}
+	logger := g.logger
+
 	// In a real implementation, this would use network transport
 	// For now, we just re-queue (simulating local delivery)
 	go func() {
-		select {
-		case g.msgChan <- msg:
-		default:
-			// Channel full
+		if !g.enqueue(msg) {
+			logger.Warn("gossip queue full, dropping message", "peer", sid, "type", msg.Type, "message", msg.ID)
 		}
 	}()
 }
 
-// runGossipLoop periodically cleans up and maintains state
+// runGossipLoop runs every gossip interval: it measures the message rate,
+// retunes, sends deferred bulk forwards and periodically cleans up
 func (g *GossipProtocol) runGossipLoop(ctx context.Context) {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
+	timer := time.NewTimer(g.Interval())
+	defer timer.Stop()
 
+	last, lastCleanup := time.Now(), time.Now()
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
-			g.cleanup()
+		case now := <-timer.C:
+			g.tick(now.Sub(last))
+			last = now
+			if now.Sub(lastCleanup) >= cleanupInterval {
+				g.cleanup()
+				lastCleanup = now
+			}
+			timer.Reset(g.Interval())
 		}
 	}
 }
 
-// cleanup removes old seen messages
-func (g *GossipProtocol) cleanup() {
+// tick retunes from the traffic of the elapsed interval and flushes the
+// deferred bulk forwards
+func (g *GossipProtocol) tick(elapsed time.Duration) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	// Keep seen map from growing unbounded
-	// In production, use a time-based eviction
-	if len(g.seen) > 10000 {
-		g.seen = make(map[string]bool)
+	g.measureLocked(elapsed)
+	g.retuneLocked()
+	g.flushLocked()
+}
+
+// Flush sends the deferred bulk forwards without waiting for the next
+// gossip interval
+func (g *GossipProtocol) Flush() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.flushLocked()
+}
+
+// flushLocked sends the deferred bulk forwards. Caller must hold g.mu.
+func (g *GossipProtocol) flushLocked() {
+	for _, msg := range g.deferred {
+		g.sendToPeersLocked(msg, g.bulkFanout)
 	}
+	g.deferred = nil
+}
+
+// cleanup drops expired seen messages and the rate limits of senders that
+// have gone quiet
+func (g *GossipProtocol) cleanup() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.nowLocked()
+	g.seen.expire(now)
+	g.limiter.prune(now)
 }
 
-// SetFanout sets the fanout parameter
+// SetFanout fixes the fanout, turning off its adaptation
 func (g *GossipProtocol) SetFanout(fanout int) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	g.fanout = fanout
+	g.config.MinFanout = fanout
+	g.config.MaxFanout = fanout
+	g.retuneLocked()
 }
 
 // Stats returns gossip protocol statistics
 type GossipStats struct {
-	PeerCount    int
-	SeenMessages int
-	HandlerCount int
+	PeerCount         int
+	SeenMessages      int // Messages in the seen cache
+	HandlerCount      int
+	MembershipVersion uint64
+
+	Fanout      int
+	BulkFanout  int
+	TTL         int
+	Interval    time.Duration
+	MessageRate float64 // Messages handled per second
+	Deferred    int     // Bulk messages waiting for the next interval
+	Dropped     uint64  // Messages dropped because a queue was full
+
+	Duplicates  uint64 // Copies of seen messages dropped
+	Stale       uint64 // Messages dropped for being older than the seen TTL
+	Expired     uint64 // Seen messages forgotten after the TTL
+	Evicted     uint64 // Seen messages forgotten to stay within the capacity
+	RateLimited uint64 // Messages dropped because their sender was over its rate limit
 }
 
 // Stats returns current gossip statistics
@@ -257,8 +529,21 @@ func (g *GossipProtocol) Stats() GossipStats {
 	}
 
 	return GossipStats{
-		PeerCount:    len(g.peers),
-		SeenMessages: len(g.seen),
-		HandlerCount: handlerCount,
+		PeerCount:         len(g.peers),
+		SeenMessages:      g.seen.len(),
+		HandlerCount:      handlerCount,
+		MembershipVersion: g.membershipVersion,
+		Fanout:            g.fanout,
+		BulkFanout:        g.bulkFanout,
+		TTL:               g.ttl,
+		Interval:          g.interval,
+		MessageRate:       g.rate,
+		Deferred:          len(g.deferred),
+		Dropped:           g.dropped.Load(),
+		Duplicates:        g.seen.duplicates,
+		Stale:             g.stale,
+		Expired:           g.seen.expired,
+		Evicted:           g.seen.evicted,
+		RateLimited:       g.limiter.limited,
 	}
 }
@@ -0,0 +1,145 @@
+package coordination
+
+import (
+	"container/list"
+	"time"
+)
+
+// seenCache remembers the messages a protocol has handled so copies arriving
+// over other paths are dropped. Entries expire TTL after a message was last
+// seen, and the least recently seen are evicted beyond the capacity, so the
+// cache stays bounded without forgetting recent messages all at once.
+type seenCache struct {
+	ttl      time.Duration
+	capacity int
+	order    *list.List               // Least recently seen first
+	entries  map[string]*list.Element // Message ID -> element holding a seenEntry
+
+	duplicates uint64 // Copies of messages still in the cache
+	expired    uint64 // Entries dropped by age
+	evicted    uint64 // Entries dropped for room
+}
+
+// seenEntry is a message in the cache
+type seenEntry struct {
+	id   string
+	last time.Time
+}
+
+// newSeenCache creates an empty cache
+func newSeenCache(ttl time.Duration, capacity int) *seenCache {
+	return &seenCache{
+		ttl:      ttl,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// observe records a message seen at now and reports whether it was already
+// in the cache
+func (c *seenCache) observe(id string, now time.Time) bool {
+	c.expire(now)
+	if el, ok := c.entries[id]; ok {
+		el.Value.(*seenEntry).last = now
+		c.order.MoveToBack(el)
+		c.duplicates++
+		return true
+	}
+	for c.order.Len() >= c.capacity {
+		c.remove(c.order.Front())
+		c.evicted++
+	}
+	c.entries[id] = c.order.PushBack(&seenEntry{id: id, last: now})
+	return false
+}
+
+// expire drops the entries not seen within the TTL
+func (c *seenCache) expire(now time.Time) {
+	for el := c.order.Front(); el != nil; el = c.order.Front() {
+		if now.Sub(el.Value.(*seenEntry).last) < c.ttl {
+			return
+		}
+		c.remove(el)
+		c.expired++
+	}
+}
+
+// remove drops an entry
+func (c *seenCache) remove(el *list.Element) {
+	delete(c.entries, el.Value.(*seenEntry).id)
+	c.order.Remove(el)
+}
+
+// forget drops a message, so a later copy is handled
+func (c *seenCache) forget(id string) {
+	if el, ok := c.entries[id]; ok {
+		c.remove(el)
+	}
+}
+
+// resize applies new limits, evicting entries beyond the capacity
+func (c *seenCache) resize(ttl time.Duration, capacity int) {
+	c.ttl, c.capacity = ttl, capacity
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Front())
+		c.evicted++
+	}
+}
+
+// len returns the number of messages in the cache
+func (c *seenCache) len() int {
+	return c.order.Len()
+}
+
+// peerLimiter is a token bucket per sender: each holds up to burst messages
+// and refills at rate per second
+type peerLimiter struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+
+	limited uint64 // Messages refused
+}
+
+// bucket is one sender's allowance
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newPeerLimiter creates a limiter; a rate of zero allows everything
+func newPeerLimiter(rate float64, burst int) *peerLimiter {
+	return &peerLimiter{rate: rate, burst: float64(burst), buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token from a sender's bucket, reporting whether it had one
+func (l *peerLimiter) allow(peer string, now time.Time) bool {
+	if l.rate <= 0 {
+		return true
+	}
+	b, ok := l.buckets[peer]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[peer] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+	if b.tokens < 1 {
+		l.limited++
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune forgets senders whose buckets have refilled, which behave as new
+func (l *peerLimiter) prune(now time.Time) {
+	for peer, b := range l.buckets {
+		if l.rate <= 0 || b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, peer)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package coordination
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 )
@@ -68,6 +70,32 @@ func TestGossipProtocol_OnMessage(t *testing.T) {
 	}
 }
 
+func TestGossipProtocol_BroadcastDereferencesPayload(t *testing.T) {
+	g := NewGossipProtocol()
+
+	received := make(chan Message, 1)
+	g.OnMessage(MsgAgentLeft, func(msg Message) {
+		received <- msg
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g.Start(ctx)
+
+	// Handlers get the value a transport would decode, not the pointer
+	g.Broadcast(Message{Type: MsgAgentLeft, From: "agent-1", Payload: &AgentLeft{SID: "agent-1"}})
+
+	select {
+	case msg := <-received:
+		if left, ok := msg.Payload.(AgentLeft); !ok || left.SID != "agent-1" {
+			t.Errorf("Expected an AgentLeft payload, got %#v", msg.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for message")
+	}
+}
+
 func TestGossipProtocol_SetFanout(t *testing.T) {
 	g := NewGossipProtocol()
 
@@ -95,3 +123,260 @@ func TestGossipProtocol_Stats(t *testing.T) {
 		t.Errorf("Expected 1 handler in stats, got %d", stats.HandlerCount)
 	}
 }
+
+func TestGossipProtocol_ApplyMembership(t *testing.T) {
+	g := NewGossipProtocol()
+	g.AddPeer("stale")
+
+	if !g.ApplyMembership(MembershipView{Version: 2, Members: []string{"agent-1", "agent-2"}}) {
+		t.Fatal("Expected newer view to be applied")
+	}
+
+	if g.PeerCount() != 2 {
+		t.Errorf("Expected 2 peers, got %d", g.PeerCount())
+	}
+
+	// Out-of-order delivery of an older view is ignored
+	if g.ApplyMembership(MembershipView{Version: 1, Members: []string{"agent-1"}}) {
+		t.Error("Expected stale view to be ignored")
+	}
+
+	if g.MembershipVersion() != 2 {
+		t.Errorf("Expected membership version 2, got %d", g.MembershipVersion())
+	}
+
+	if g.PeerCount() != 2 {
+		t.Errorf("Expected 2 peers after stale view, got %d", g.PeerCount())
+	}
+}
+
+func TestGossipProtocol_AdaptsToSize(t *testing.T) {
+	g := NewGossipProtocol()
+
+	small := g.Stats()
+	if small.Fanout != 3 || small.TTL != 3 {
+		t.Errorf("Expected minimum fanout and TTL with no peers, got %d and %d", small.Fanout, small.TTL)
+	}
+
+	for i := 0; i < 100; i++ {
+		g.AddPeer(fmt.Sprintf("agent-%d", i))
+	}
+	medium := g.Stats()
+	if medium.Fanout != 7 {
+		t.Errorf("Expected fanout 7 for 100 peers, got %d", medium.Fanout)
+	}
+	if medium.TTL <= small.TTL {
+		t.Errorf("Expected TTL to grow with peers, got %d", medium.TTL)
+	}
+	if medium.Interval <= small.Interval {
+		t.Errorf("Expected a longer interval on a large mesh, got %v", medium.Interval)
+	}
+
+	for i := 100; i < 5000; i++ {
+		g.AddPeer(fmt.Sprintf("agent-%d", i))
+	}
+	cfg := DefaultGossipConfig()
+	large := g.Stats()
+	if large.Fanout != cfg.MaxFanout || large.TTL > cfg.MaxTTL || large.Interval != cfg.MaxInterval {
+		t.Errorf("Expected values capped, got fanout %d, TTL %d, interval %v", large.Fanout, large.TTL, large.Interval)
+	}
+
+	g.SetFanout(2)
+	if stats := g.Stats(); stats.Fanout != 2 {
+		t.Errorf("Expected fixed fanout 2, got %d", stats.Fanout)
+	}
+}
+
+func TestGossipProtocol_DampsBulkUnderLoad(t *testing.T) {
+	g := NewGossipProtocol()
+	g.SetConfig(GossipConfig{TargetRate: 10})
+	for i := 0; i < 20; i++ {
+		g.AddPeer(fmt.Sprintf("agent-%d", i))
+	}
+
+	// Normal traffic is forwarded at once, bulk traffic waits for the interval
+	g.forward(Message{ID: "vote", Type: MsgConsensus, TTL: 1})
+	g.forward(Message{ID: "offer", Type: MsgTaskAvailable, TTL: 1})
+	if stats := g.Stats(); stats.Deferred != 1 {
+		t.Errorf("Expected 1 deferred bulk message, got %d", stats.Deferred)
+	}
+
+	g.mu.Lock()
+	g.handled = 100
+	g.mu.Unlock()
+	g.tick(time.Second)
+
+	stats := g.Stats()
+	if stats.Deferred != 0 {
+		t.Errorf("Expected deferred messages sent on tick, got %d", stats.Deferred)
+	}
+	if stats.MessageRate != 50 {
+		t.Errorf("Expected smoothed rate 50, got %v", stats.MessageRate)
+	}
+	if stats.BulkFanout >= stats.Fanout {
+		t.Errorf("Expected bulk fanout below %d under load, got %d", stats.Fanout, stats.BulkFanout)
+	}
+	if stats.Interval <= DefaultGossipConfig().MinInterval {
+		t.Errorf("Expected a longer interval under load, got %v", stats.Interval)
+	}
+}
+
+func TestGossipProtocol_Priorities(t *testing.T) {
+	g := NewGossipProtocol()
+
+	var mu sync.Mutex
+	var order []MessageType
+	record := func(msg Message) {
+		mu.Lock()
+		order = append(order, msg.Type)
+		mu.Unlock()
+	}
+	g.OnMessage(MsgTaskAvailable, record)
+	g.OnMessage(MsgConsensus, record)
+
+	// A vote queued behind a burst of bulk traffic is still handled first
+	for i := 0; i < 50; i++ {
+		g.Broadcast(Message{Type: MsgTaskAvailable, From: "agent-1"})
+	}
+	g.Broadcast(Message{Type: MsgConsensus, From: "agent-1"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.Start(ctx)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 51 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 51 || order[0] != MsgConsensus {
+		t.Errorf("Expected the consensus vote handled first of 51, got %d messages starting with %v", len(order), order)
+	}
+
+	if g.Priority(MsgHeartbeat) != PriorityNormal || g.Priority("custom") != PriorityNormal {
+		t.Errorf("Expected heartbeats and unknown types at normal priority")
+	}
+	g.SetPriority("custom", PriorityCritical)
+	if g.Priority("custom") != PriorityCritical {
+		t.Errorf("Expected custom priority to be set")
+	}
+}
+
+func TestGossipProtocol_SeenCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := NewGossipProtocol()
+	g.SetClock(func() time.Time { return now })
+	g.SetConfig(GossipConfig{SeenTTL: time.Minute, SeenCapacity: 3})
+
+	handled := 0
+	g.OnMessage(MsgHeartbeat, func(msg Message) { handled++ })
+	deliver := func(id string) {
+		g.Deliver(Message{ID: id, Type: MsgHeartbeat, From: "agent-1", Timestamp: now})
+	}
+
+	// Copies are dropped while the message is remembered
+	deliver("a")
+	deliver("a")
+	if stats := g.Stats(); handled != 1 || stats.Duplicates != 1 || stats.SeenMessages != 1 {
+		t.Errorf("Expected 1 handled and 1 duplicate, got %d and %+v", handled, stats)
+	}
+
+	// The least recently seen make room beyond the capacity
+	deliver("b")
+	deliver("c")
+	deliver("a")
+	deliver("d")
+	if stats := g.Stats(); stats.Evicted != 1 || stats.SeenMessages != 3 {
+		t.Errorf("Expected 1 eviction keeping 3 messages, got %+v", stats)
+	}
+	deliver("a")
+	if handled != 4 {
+		t.Errorf("Expected the recently seen message to stay remembered, got %d handled", handled)
+	}
+
+	// Entries expire after the TTL, and replays of old messages are dropped
+	sent := now
+	now = now.Add(2 * time.Minute)
+	g.cleanup()
+	if stats := g.Stats(); stats.SeenMessages != 0 || stats.Expired != 3 {
+		t.Errorf("Expected every entry expired, got %+v", stats)
+	}
+	g.Deliver(Message{ID: "a", Type: MsgHeartbeat, From: "agent-1", Timestamp: sent})
+	if stats := g.Stats(); handled != 4 || stats.Stale != 1 {
+		t.Errorf("Expected the replay dropped as stale, got %d handled and %+v", handled, stats)
+	}
+}
+
+func TestGossipProtocol_RateLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := NewGossipProtocol()
+	g.SetClock(func() time.Time { return now })
+	g.SetConfig(GossipConfig{PeerRate: 1, PeerBurst: 2})
+
+	handled := map[string]int{}
+	g.OnMessage(MsgHeartbeat, func(msg Message) { handled[msg.From]++ })
+	for i := 0; i < 3; i++ {
+		g.Deliver(Message{ID: fmt.Sprintf("flood-%d", i), Type: MsgHeartbeat, From: "agent-1"})
+	}
+	g.Deliver(Message{ID: "quiet", Type: MsgHeartbeat, From: "agent-2"})
+	if handled["agent-1"] != 2 || handled["agent-2"] != 1 || g.Stats().RateLimited != 1 {
+		t.Errorf("Expected the third flood message limited, got %v and %d limited", handled, g.Stats().RateLimited)
+	}
+
+	// The bucket refills over time
+	now = now.Add(time.Second)
+	g.Deliver(Message{ID: "flood-2", Type: MsgHeartbeat, From: "agent-1"})
+	if handled["agent-1"] != 3 {
+		t.Errorf("Expected a refilled bucket to admit the message, got %d", handled["agent-1"])
+	}
+
+	g.SetConfig(GossipConfig{PeerRate: -1})
+	for i := 0; i < 500; i++ {
+		g.Deliver(Message{ID: fmt.Sprintf("burst-%d", i), Type: MsgHeartbeat, From: "agent-3"})
+	}
+	if handled["agent-3"] != 500 {
+		t.Errorf("Expected no limit with a negative rate, got %d handled", handled["agent-3"])
+	}
+}
+
+func TestGossipProtocol_RateLimitSparesBroadcasts(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := NewGossipProtocol()
+	g.SetClock(func() time.Time { return now })
+	g.SetConfig(GossipConfig{PeerRate: 1, PeerBurst: 2})
+
+	const burst = 200
+	handled := make(chan Message, burst+3)
+	g.OnMessage(MsgHeartbeat, func(msg Message) { handled <- msg })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g.Start(ctx)
+
+	// The collective's own messages name no sender
+	for i := 0; i < burst; i++ {
+		g.Broadcast(Message{Type: MsgHeartbeat})
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(handled) < burst && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n, limited := len(handled), g.Stats().RateLimited; n != burst || limited != 0 {
+		t.Errorf("Expected every broadcast handled, got %d handled and %d limited", n, limited)
+	}
+
+	// Messages received without a sender are still limited
+	for i := 0; i < 3; i++ {
+		g.Deliver(Message{ID: fmt.Sprintf("anonymous-%d", i), Type: MsgHeartbeat})
+	}
+	if n, limited := len(handled), g.Stats().RateLimited; n != burst+2 || limited != 1 {
+		t.Errorf("Expected the third anonymous message limited, got %d handled in all and %d limited", n, limited)
+	}
+}
@@ -0,0 +1,237 @@
+package coordination
+
+import (
+	"math"
+	"time"
+)
+
+// MessagePriority orders gossip traffic. Each priority has its own queue and
+// higher ones are always handled first, so bulk chatter on a large mesh can
+// neither delay nor crowd out consensus votes.
+type MessagePriority int
+
+const (
+	PriorityBulk     MessagePriority = iota // Task availability and bids; batched and damped under load
+	PriorityNormal                          // Assignments, completions, heartbeats
+	PriorityCritical                        // Consensus votes and membership changes
+)
+
+// priorityLevels is the number of message priorities
+const priorityLevels = int(PriorityCritical) + 1
+
+// String returns the priority's name
+func (p MessagePriority) String() string {
+	switch p {
+	case PriorityBulk:
+		return "bulk"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// defaultPriorities returns the priority of each built-in message type;
+// types not listed are PriorityNormal
+func defaultPriorities() map[MessageType]MessagePriority {
+	return map[MessageType]MessagePriority{
+		MsgConsensus:     PriorityCritical,
+		MsgMembership:    PriorityCritical,
+		MsgAgentJoined:   PriorityCritical,
+		MsgAgentLeft:     PriorityCritical,
+		MsgTaskAssigned:  PriorityNormal,
+		MsgTaskCompleted: PriorityNormal,
+		MsgHeartbeat:     PriorityNormal,
+		MsgTaskAvailable: PriorityBulk,
+		MsgTaskBid:       PriorityBulk,
+	}
+}
+
+// GossipConfig bounds how the protocol adapts to the collective. Fanout and
+// TTL grow with the number of peers so a message still reaches everyone;
+// the interval at which bulk messages are forwarded grows with size and
+// message rate. Above TargetRate, bulk messages go to fewer peers. Every
+// value stays within its Min and Max.
+type GossipConfig struct {
+	MinFanout   int           `json:"min_fanout,omitempty"`
+	MaxFanout   int           `json:"max_fanout,omitempty"`
+	MinTTL      int           `json:"min_ttl,omitempty"`
+	MaxTTL      int           `json:"max_ttl,omitempty"`
+	MinInterval time.Duration `json:"min_interval,omitempty"`
+	MaxInterval time.Duration `json:"max_interval,omitempty"`
+	TargetRate  float64       `json:"target_rate,omitempty"` // Messages per second handled before bulk traffic is damped
+
+	// Messages are remembered for SeenTTL after they were last seen, up to
+	// SeenCapacity of them; older messages are dropped as replays
+	SeenTTL      time.Duration `json:"seen_ttl,omitempty"`
+	SeenCapacity int           `json:"seen_capacity,omitempty"`
+
+	// Each peer sending messages may have PeerBurst new ones handled at
+	// once, refilled at PeerRate per second (negative PeerRate = unlimited).
+	// The protocol's own broadcasts aren't limited.
+	PeerRate  float64 `json:"peer_rate,omitempty"`
+	PeerBurst int     `json:"peer_burst,omitempty"`
+}
+
+// DefaultGossipConfig returns the default gossip bounds
+func DefaultGossipConfig() GossipConfig {
+	return GossipConfig{
+		MinFanout:   3,
+		MaxFanout:   8,
+		MinTTL:      3,
+		MaxTTL:      10,
+		MinInterval: 100 * time.Millisecond,
+		MaxInterval: 2 * time.Second,
+		TargetRate:  200,
+
+		SeenTTL:      5 * time.Minute,
+		SeenCapacity: 100000,
+		PeerRate:     50,
+		PeerBurst:    100,
+	}
+}
+
+// withDefaults fills unset fields from DefaultGossipConfig and keeps each
+// maximum at or above its minimum
+func (cfg GossipConfig) withDefaults() GossipConfig {
+	def := DefaultGossipConfig()
+	if cfg.MinFanout <= 0 {
+		cfg.MinFanout = def.MinFanout
+	}
+	if cfg.MaxFanout <= 0 {
+		cfg.MaxFanout = def.MaxFanout
+	}
+	if cfg.MinTTL <= 0 {
+		cfg.MinTTL = def.MinTTL
+	}
+	if cfg.MaxTTL <= 0 {
+		cfg.MaxTTL = def.MaxTTL
+	}
+	if cfg.MinInterval <= 0 {
+		cfg.MinInterval = def.MinInterval
+	}
+	if cfg.MaxInterval <= 0 {
+		cfg.MaxInterval = def.MaxInterval
+	}
+	if cfg.TargetRate <= 0 {
+		cfg.TargetRate = def.TargetRate
+	}
+	if cfg.SeenTTL <= 0 {
+		cfg.SeenTTL = def.SeenTTL
+	}
+	if cfg.SeenCapacity <= 0 {
+		cfg.SeenCapacity = def.SeenCapacity
+	}
+	if cfg.PeerRate == 0 {
+		cfg.PeerRate = def.PeerRate
+	}
+	if cfg.PeerBurst <= 0 {
+		cfg.PeerBurst = def.PeerBurst
+	}
+	if cfg.MaxFanout < cfg.MinFanout {
+		cfg.MaxFanout = cfg.MinFanout
+	}
+	if cfg.MaxTTL < cfg.MinTTL {
+		cfg.MaxTTL = cfg.MinTTL
+	}
+	if cfg.MaxInterval < cfg.MinInterval {
+		cfg.MaxInterval = cfg.MinInterval
+	}
+	return cfg
+}
+
+// rateSmoothing weights the latest measurement in the message rate average
+const rateSmoothing = 0.5
+
+// SetConfig replaces the adaptation bounds (zero fields = defaults)
+func (g *GossipProtocol) SetConfig(cfg GossipConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.config = cfg.withDefaults()
+	g.seen.resize(g.config.SeenTTL, g.config.SeenCapacity)
+	g.limiter = newPeerLimiter(g.config.PeerRate, g.config.PeerBurst)
+	g.retuneLocked()
+}
+
+// SetPriority sets the priority of a message type
+func (g *GossipProtocol) SetPriority(msgType MessageType, p MessagePriority) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.priorities[msgType] = p
+}
+
+// Priority returns the priority of a message type
+func (g *GossipProtocol) Priority(msgType MessageType) MessagePriority {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.priorityLocked(msgType)
+}
+
+// priorityLocked looks up a message type's priority. Caller must hold g.mu.
+func (g *GossipProtocol) priorityLocked(msgType MessageType) MessagePriority {
+	if p, ok := g.priorities[msgType]; ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// Interval returns the current gossip interval
+func (g *GossipProtocol) Interval() time.Duration {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.interval
+}
+
+// measureLocked folds the messages handled since the last measurement into
+// the message rate. Caller must hold g.mu.
+func (g *GossipProtocol) measureLocked(elapsed time.Duration) {
+	if elapsed <= 0 {
+		return
+	}
+	current := float64(g.handled) / elapsed.Seconds()
+	g.rate = rateSmoothing*current + (1-rateSmoothing)*g.rate
+	g.handled = 0
+}
+
+// retuneLocked recomputes fanout, TTL and interval from the peer count and
+// message rate. Caller must hold g.mu.
+func (g *GossipProtocol) retuneLocked() {
+	cfg := g.config
+	n := float64(len(g.peers))
+
+	// About log2(n) peers per hop reaches the whole mesh with high probability
+	g.fanout = clampInt(int(math.Ceil(math.Log2(n+1))), cfg.MinFanout, cfg.MaxFanout)
+
+	// Enough hops for the fanout to cover every peer, plus slack for overlap
+	hops := cfg.MaxTTL
+	if g.fanout > 1 {
+		hops = int(math.Ceil(math.Log(n+1)/math.Log(float64(g.fanout)))) + 2
+	}
+	g.ttl = clampInt(hops, cfg.MinTTL, cfg.MaxTTL)
+
+	// Batch bulk forwards over longer periods on large or busy meshes, and
+	// send them to fewer peers once the rate passes the target
+	load := g.rate / cfg.TargetRate
+	scale := math.Max(1, load) * math.Max(1, n/50)
+	interval := time.Duration(float64(cfg.MinInterval) * scale)
+	if interval > cfg.MaxInterval || interval < 0 {
+		interval = cfg.MaxInterval
+	}
+	g.interval = interval
+
+	g.bulkFanout = g.fanout
+	if load > 1 {
+		g.bulkFanout = clampInt(int(float64(g.fanout)/load), 1, g.fanout)
+	}
+}
+
+// clampInt limits v to [lo, hi]
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func TestTaskMarket_RoutingHints(t *testing.T) {
+	caps := []identity.CapabilityType{identity.CapCodeWrite}
+	plain, _ := agent.NewAgent(agent.AgentConfig{Name: "Plain", Capabilities: caps})
+	local, _ := agent.NewAgent(agent.AgentConfig{Name: "Local", Capabilities: caps, Labels: map[string]string{"region": "eu"}})
+	expert, _ := agent.NewAgent(agent.AgentConfig{Name: "Expert", Capabilities: caps, Labels: map[string]string{"region": "eu"}})
+	banned, _ := agent.NewAgent(agent.AgentConfig{Name: "Banned", Capabilities: caps})
+	for _, a := range []*agent.Agent{plain, local, expert, banned} {
+		_ = a.Start(context.Background())
+		defer a.Stop()
+	}
+
+	m := NewTaskMarket()
+	defer m.Close()
+	near, _ := agent.ParseConstraints([]string{"region=eu"})
+	task := agent.NewTask("steer me", caps).WithHints(agent.RoutingHints{
+		Prefer: []string{expert.Identity.SID[:8]},
+		Ban:    []string{banned.Identity.SID},
+		Near:   near,
+	})
+	if err := m.ListTask(task); err != nil {
+		t.Fatalf("ListTask failed: %v", err)
+	}
+
+	if _, reason := CheckEligibility(banned, task); reason != IneligibleBanned {
+		t.Errorf("Expected the banned agent to be ineligible, got %q", reason)
+	}
+	if bid := m.MakeBid(banned, task); bid != nil {
+		t.Error("Expected no bid from the banned agent")
+	}
+	forged := &Bid{AgentSID: banned.Identity.SID, TaskID: task.ID, CapabilityScore: 1}
+	if err := m.SubmitBid(forged); !errors.Is(err, ErrBannedBidder) {
+		t.Errorf("Expected ErrBannedBidder, got %v", err)
+	}
+
+	for _, a := range []*agent.Agent{plain, local, expert} {
+		if err := m.SubmitBid(m.MakeBid(a, task)); err != nil {
+			t.Fatalf("SubmitBid failed: %v", err)
+		}
+	}
+	scores, err := m.ScoreBids(task.ID, NewReputationRegistry())
+	if err != nil {
+		t.Fatalf("ScoreBids failed: %v", err)
+	}
+	for i, want := range []*agent.Agent{expert, local, plain} {
+		if scores[i].Bid.AgentSID != want.Identity.SID {
+			t.Errorf("Expected %s at rank %d, got %s", want.Identity.Name, i+1, scores[i].Bid.AgentSID)
+		}
+	}
+	hint, ok := scores[1].Factor(FactorHint)
+	if !ok || hint.Value != 0.5 || hint.Weight != DefaultHintWeight {
+		t.Errorf("Expected a half-met hint at the default weight, got %+v", hint)
+	}
+}
+
+func TestScoreHintedBid(t *testing.T) {
+	bid := &Bid{CapabilityScore: 0.8, ReputationStake: 5, Hint: 1}
+	if got, want := ScoreHintedBid(bid, 50, 0), ScoreBid(bid, 50); got.Score != want.Score || len(got.Factors) != len(want.Factors) {
+		t.Errorf("Expected a zero weight to score as ScoreBid, got %+v", got)
+	}
+	if got, want := ScoreHintedBid(bid, 50, 0.5).Score, ScoreBid(bid, 50).Score+0.5; got != want {
+		t.Errorf("Expected score %.4f, got %.4f", want, got)
+	}
+}
@@ -3,17 +3,19 @@ package coordination
 import (
 	"context"
 	"errors"
-	"sort"
 	"sync"
 	"time"
 
 	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/logging"
 )
 
 var (
 	ErrTaskNotFound = errors.New("task not found")
 	ErrNoBids       = errors.New("no bids received")
 	ErrMarketClosed = errors.New("market closed")
+	ErrBannedBidder = errors.New("bidder is banned by the task's routing hints")
 )
 
 // Bid represents an agent's bid on a task
@@ -24,6 +26,26 @@ type Bid struct {
 	ReputationStake float64       `json:"reputation_stake"`
 	EstimatedTime   time.Duration `json:"estimated_time"`
 	Timestamp       time.Time     `json:"timestamp"`
+
+	// Proficiencies holds the bidder's learned proficiency for each required capability
+	Proficiencies map[identity.CapabilityType]float64 `json:"proficiencies,omitempty"`
+
+	// Match explains CapabilityScore
+	Match *identity.MatchBreakdown `json:"match,omitempty"`
+
+	// Delegations lend the bidder required capabilities it lacks; the
+	// market verifies them before accepting the bid
+	Delegations []*identity.DelegationProof `json:"delegations,omitempty"`
+
+	// Hint is how well the bidder fits the task's routing hints (0-1)
+	Hint float64 `json:"hint,omitempty"`
+
+	// Load is the share of the bidder's worker slots already busy (0-1)
+	Load float64 `json:"load,omitempty"`
+
+	// Signature is the bidder's signature over Digest; bids from agents the
+	// market can look up must carry a valid one if they carry any
+	Signature []byte `json:"signature,omitempty"`
 }
 
 // TaskAssignment represents the result of task matching
@@ -31,28 +53,96 @@ type TaskAssignment struct {
 	TaskID   string `json:"task_id"`
 	AgentSID string `json:"agent_sid"`
 	Bid      *Bid   `json:"bid"`
+
+	Auction   string  `json:"auction,omitempty"` // Strategy the task was auctioned by
+	Stake     float64 `json:"stake"`             // Reputation the agent commits, as priced by the auction
+	Threshold float64 `json:"threshold"`         // Capability score bids needed, after any relaxation
 }
 
 // TaskMarket implements decentralized task allocation
 type TaskMarket struct {
 	mu sync.RWMutex
 
-	listings map[string]*agent.Task // TaskID -> Task
-	bids     map[string][]*Bid      // TaskID -> Bids
+	listings map[string]*agent.Task     // TaskID -> Task
+	bids     map[string][]*Bid          // TaskID -> Bids
+	auctions map[string]AuctionStrategy // TaskID -> strategy it is auctioned by
+	sealed   map[string]bool            // TaskIDs whose sealed bids are still hidden
+
+	auction AuctionStrategy // Default strategy for tasks that don't name one
+	ties    TieBreaker      // Orders bids the auction ranks level
+
+	threshold   BidThreshold            // Capability score needed to bid, and how it is relaxed
+	thresholds  map[string]float64      // TaskID -> threshold after relaxation
+	relaxations map[string][]Relaxation // TaskID -> how its threshold was lowered
+
+	assigned map[string]time.Time // Agent SID -> when it was last assigned a task
 
 	bidTimeout time.Duration
 	closed     bool
+
+	onBid  []func(*Bid)
+	lookup AgentLookup // Resolves delegators of delegation proofs
+
+	now func() time.Time // Clock bids are stamped and listings expired by (nil = time.Now)
+
+	logger logging.Logger
 }
 
 // NewTaskMarket creates a new task market
 func NewTaskMarket() *TaskMarket {
 	return &TaskMarket{
-		listings:   make(map[string]*agent.Task),
-		bids:       make(map[string][]*Bid),
-		bidTimeout: 100 * time.Millisecond, // Fast local matching
+		listings:    make(map[string]*agent.Task),
+		bids:        make(map[string][]*Bid),
+		auctions:    make(map[string]AuctionStrategy),
+		sealed:      make(map[string]bool),
+		auction:     firstPriceAuction{name: AuctionWeighted},
+		ties:        capabilityTieBreaker{},
+		threshold:   DefaultBidThreshold(),
+		thresholds:  make(map[string]float64),
+		relaxations: make(map[string][]Relaxation),
+		assigned:    make(map[string]time.Time),
+		bidTimeout:  100 * time.Millisecond, // Fast local matching
+		logger:      logging.Component("market"),
 	}
 }
 
+// SetLogger replaces the market's logger
+func (m *TaskMarket) SetLogger(l logging.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = l
+}
+
+// SetClock replaces the time source bids are stamped and listings expired by
+func (m *TaskMarket) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// clock returns the current time by the market's clock
+func (m *TaskMarket) clock() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.nowLocked()
+}
+
+// nowLocked returns the current time by the market's clock. Caller must
+// hold m.mu.
+func (m *TaskMarket) nowLocked() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+// log returns the market's logger
+func (m *TaskMarket) log() logging.Logger {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.logger
+}
+
 // Start begins market operation
 func (m *TaskMarket) Start(ctx context.Context) {
 	// Market runs passively, processing bids as they come
@@ -79,20 +169,17 @@ func (m *TaskMarket) cleanup() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	now := time.Now()
+	now := m.nowLocked()
 	for id, task := range m.listings {
 		// Remove listings older than deadline or 1 hour
-		if !task.Deadline.IsZero() && now.After(task.Deadline) {
-			delete(m.listings, id)
-			delete(m.bids, id)
-		} else if now.Sub(task.CreatedAt) > time.Hour {
-			delete(m.listings, id)
-			delete(m.bids, id)
+		if (!task.Deadline.IsZero() && now.After(task.Deadline)) || now.Sub(task.CreatedAt) > time.Hour {
+			m.unlistLocked(id)
 		}
 	}
 }
 
-// ListTask adds a task to the market
+// ListTask adds a task to the market, to be auctioned by the strategy it
+// names or the market's default
 func (m *TaskMarket) ListTask(task *agent.Task) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -100,9 +187,25 @@ func (m *TaskMarket) ListTask(task *agent.Task) error {
 	if m.closed {
 		return ErrMarketClosed
 	}
+	strategy := m.auction
+	if task.Auction != "" {
+		var err error
+		if strategy, err = NewAuctionStrategy(task.Auction); err != nil {
+			return err
+		}
+	}
 
 	m.listings[task.ID] = task
 	m.bids[task.ID] = make([]*Bid, 0)
+	m.auctions[task.ID] = strategy
+	delete(m.thresholds, task.ID)
+	delete(m.relaxations, task.ID)
+	if strategy.Sealed() {
+		m.sealed[task.ID] = true
+	} else {
+		delete(m.sealed, task.ID)
+	}
+	m.logger.Debug("task listed", "task", task.ID, "complexity", task.Complexity, "auction", strategy.Name())
 	return nil
 }
 
@@ -111,8 +214,47 @@ func (m *TaskMarket) UnlistTask(taskID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.unlistLocked(taskID)
+}
+
+// unlistLocked removes a task and its bids
+func (m *TaskMarket) unlistLocked(taskID string) {
 	delete(m.listings, taskID)
 	delete(m.bids, taskID)
+	delete(m.auctions, taskID)
+	delete(m.thresholds, taskID)
+	delete(m.relaxations, taskID)
+	delete(m.sealed, taskID)
+}
+
+// SetAuction sets the strategy for tasks that don't name one
+func (m *TaskMarket) SetAuction(strategy AuctionStrategy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auction = strategy
+}
+
+// Auction returns the strategy for tasks that don't name one
+func (m *TaskMarket) Auction() AuctionStrategy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.auction
+}
+
+// AuctionFor returns the strategy a listed task is auctioned by, or the
+// market's default if it isn't listed
+func (m *TaskMarket) AuctionFor(taskID string) AuctionStrategy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.auctionLocked(taskID)
+}
+
+// auctionLocked returns the strategy a task is auctioned by
+func (m *TaskMarket) auctionLocked(taskID string) AuctionStrategy {
+	if strategy, ok := m.auctions[taskID]; ok {
+		return strategy
+	}
+	return m.auction
 }
 
 // GetListing returns a task listing
@@ -136,71 +278,240 @@ func (m *TaskMarket) ListAllTasks() []*agent.Task {
 	return tasks
 }
 
-// SubmitBid submits a bid on a task
+// SubmitBid submits a bid on a task. Bids presenting a delegation proof
+// that doesn't verify are rejected. Bids in a sealed auction reach OnBid
+// handlers only once bidding closes.
 func (m *TaskMarket) SubmitBid(bid *Bid) error {
+	if err := m.verifyBidSignature(bid); err != nil {
+		m.log().Warn("bid rejected", "task", bid.TaskID, "agent", bid.AgentSID, "error", err)
+		return err
+	}
+	for _, proof := range bid.Delegations {
+		if _, err := m.VerifyDelegation(bid.AgentSID, proof); err != nil {
+			m.log().Warn("bid rejected", "task", bid.TaskID, "agent", bid.AgentSID, "error", err)
+			return err
+		}
+	}
+
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	if m.closed {
+		m.mu.Unlock()
 		return ErrMarketClosed
 	}
 
-	if _, exists := m.listings[bid.TaskID]; !exists {
+	task, exists := m.listings[bid.TaskID]
+	if !exists {
+		m.mu.Unlock()
 		return ErrTaskNotFound
 	}
+	if task.Hints.Bans(bid.AgentSID) {
+		m.mu.Unlock()
+		m.log().Warn("bid rejected", "task", bid.TaskID, "agent", bid.AgentSID, "error", ErrBannedBidder)
+		return ErrBannedBidder
+	}
 
-	bid.Timestamp = time.Now()
+	bid.Timestamp = m.nowLocked()
 	m.bids[bid.TaskID] = append(m.bids[bid.TaskID], bid)
+	handlers := m.onBid
+	if m.sealed[bid.TaskID] {
+		handlers = nil
+	}
+	m.logger.Debug("bid received", "task", bid.TaskID, "agent", bid.AgentSID, "capability_score", bid.CapabilityScore)
+	m.mu.Unlock()
+
+	for _, h := range handlers {
+		h(bid)
+	}
 	return nil
 }
 
-// GetBids returns all bids for a task
+// OnBid registers a callback invoked for every accepted bid
+func (m *TaskMarket) OnBid(handler func(*Bid)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onBid = append(m.onBid, handler)
+}
+
+// GetBids returns all bids for a task, or none while its bids are sealed
 func (m *TaskMarket) GetBids(taskID string) []*Bid {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	if m.sealed[taskID] {
+		return nil
+	}
 	return m.bids[taskID]
 }
 
+// CloseBidding ends bidding on a task, revealing its bids to OnBid handlers
+// if they were sealed
+func (m *TaskMarket) CloseBidding(taskID string) {
+	m.mu.Lock()
+	if !m.sealed[taskID] {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.sealed, taskID)
+	bids := append([]*Bid(nil), m.bids[taskID]...)
+	handlers := m.onBid
+	m.mu.Unlock()
+
+	for _, bid := range bids {
+		for _, h := range handlers {
+			h(bid)
+		}
+	}
+}
+
 // AssignTask matches a task to the best bidder
 func (m *TaskMarket) AssignTask(
 	task *agent.Task,
 	agents map[string]*agent.Agent,
 	reputation *ReputationRegistry,
 ) (*TaskAssignment, error) {
+	if err := m.SolicitBids(task, agents); err != nil {
+		return nil, err
+	}
+
+	// Select best bid
+	assignment, err := m.selectBestBid(task.ID, reputation)
+	if err != nil {
+		m.log().Warn("task not assigned", "task", task.ID, "error", err)
+		return nil, err
+	}
+	m.log().Info("task assigned", "task", task.ID, "agent", assignment.AgentSID, "capability_score", assignment.Bid.CapabilityScore, "auction", assignment.Auction, "stake", assignment.Stake)
+	return assignment, nil
+}
+
+// SolicitBids lists a task, collects bids from capable idle agents and waits
+// out the bid collection period, then reveals sealed bids. Agents lacking
+// capabilities may still bid on the strength of delegations from agents
+// that hold them. While a round draws no bids, the threshold is relaxed a
+// step and bids are solicited again, down to the threshold's floor.
+func (m *TaskMarket) SolicitBids(task *agent.Task, agents map[string]*agent.Agent) error {
 	// List the task
 	if err := m.ListTask(task); err != nil {
-		return nil, err
+		return err
 	}
 
-	// Generate bids from capable agents
-	for sid, a := range agents {
-		if a.GetState() != agent.StateIdle {
-			continue
+	for {
+		// Generate bids from capable agents
+		for _, a := range agents {
+			if bid := m.MakeBid(a, task); bid != nil {
+				_ = m.SubmitBid(bid)
+			}
 		}
 
-		score := a.Capabilities.MatchScore(task.Required)
-		if score > 0.5 { // Minimum threshold
-			bid := &Bid{
-				AgentSID:        sid,
-				TaskID:          task.ID,
-				CapabilityScore: score,
-				ReputationStake: a.Reputation.Overall * 0.1, // Stake 10% of reputation
-				EstimatedTime:   estimateTime(task, score),
-			}
-			_ = m.SubmitBid(bid)
+		// Wait for bid collection period
+		time.Sleep(m.BidTimeout())
+		m.CloseBidding(task.ID)
+
+		if len(m.GetBids(task.ID)) > 0 || !m.relax(task.ID) {
+			return nil
 		}
 	}
+}
 
-	// Wait for bid collection period
-	time.Sleep(m.bidTimeout)
+// MakeBid returns the bid an agent places on a task, or nil if it may not
+// bid: every slot it has is busy, or it neither holds nor was delegated the
+// capabilities
+func (m *TaskMarket) MakeBid(a *agent.Agent, task *agent.Task) *Bid {
+	threshold := m.Threshold(task.ID)
+	match, reason := CheckEligibilityAt(a, task, threshold, m.clock())
+	var delegations []*identity.DelegationProof
+	if reason == IneligibleCapability {
+		if match, delegations = m.delegatedMatch(a, task.Required); match.Score >= threshold {
+			reason = ""
+		}
+	}
+	if reason != "" {
+		return nil
+	}
 
-	// Select best bid
-	return m.selectBestBid(task.ID, reputation)
+	bid := &Bid{
+		AgentSID:        a.Identity.SID,
+		TaskID:          task.ID,
+		CapabilityScore: match.Score,
+		ReputationStake: a.OverallReputation() * 0.1, // Stake 10% of reputation
+		EstimatedTime:   estimateTime(task, match.Score),
+		Proficiencies:   requiredProficiencies(a, task.Required),
+		Match:           &match,
+		Delegations:     delegations,
+		Hint:            task.Hints.Affinity(a.Identity.SID, a.Labels),
+		Load:            a.Load(),
+	}
+	bid.Signature = a.Identity.Sign(bid.Digest())
+	return bid
+}
+
+// Reasons an agent may not bid on a task
+const (
+	IneligibleNotIdle    = "not_idle"   // Working in every slot, or paused
+	IneligibleCapability = "capability" // Match score below the bid threshold
+	IneligibleBanned     = "banned"     // Banned by the task's routing hints
+)
+
+// CheckEligibility reports why an agent may not bid on a task at
+// MinCapabilityScore, or "" if it may, along with its capability match
+func CheckEligibility(a *agent.Agent, task *agent.Task) (identity.MatchBreakdown, string) {
+	return CheckEligibilityAt(a, task, MinCapabilityScore, time.Now())
+}
+
+// CheckEligibilityAt is CheckEligibility with the capability score an agent
+// needs to bid, counting the benchmark proofs current at now
+func CheckEligibilityAt(a *agent.Agent, task *agent.Task, threshold float64, now time.Time) (identity.MatchBreakdown, string) {
+	match := a.Capabilities.ExplainMatch(task.Required, now)
+	switch state := a.GetState(); {
+	case state != agent.StateIdle && state != agent.StateWorking, a.FreeSlots() == 0:
+		return match, IneligibleNotIdle
+	case task.Hints.Bans(a.Identity.SID):
+		return match, IneligibleBanned
+	case match.Score < threshold:
+		return match, IneligibleCapability
+	}
+	return match, ""
 }
 
 // selectBestBid chooses the winning bid
 func (m *TaskMarket) selectBestBid(taskID string, reputation *ReputationRegistry) (*TaskAssignment, error) {
+	ranked, err := m.RankBids(taskID, reputation)
+	if err != nil {
+		return nil, err
+	}
+	return ranked[0], nil
+}
+
+// RankBids returns a candidate assignment for every bid on a task, best first
+// by the task's auction strategy. Each candidate's stake is priced as if the
+// bids ranked above it had been withdrawn.
+func (m *TaskMarket) RankBids(taskID string, reputation *ReputationRegistry) ([]*TaskAssignment, error) {
+	scores, err := m.ScoreBids(taskID, reputation)
+	if err != nil {
+		return nil, err
+	}
+	strategy := m.AuctionFor(taskID)
+	threshold := m.Threshold(taskID)
+
+	ranked := make([]*TaskAssignment, len(scores))
+	for i, s := range scores {
+		ranked[i] = &TaskAssignment{
+			TaskID:    taskID,
+			AgentSID:  s.Bid.AgentSID,
+			Bid:       s.Bid,
+			Auction:   strategy.Name(),
+			Stake:     strategy.Price(scores[i:]),
+			Threshold: threshold,
+		}
+	}
+	return ranked, nil
+}
+
+// ScoreBids scores every bid on a task with ScoreBid, or ScoreHintedBid if
+// it has routing hints, less ScoreLoad's share for bidders already busy,
+// best first as ranked by the task's auction strategy and the market's
+// tie-breaker
+func (m *TaskMarket) ScoreBids(taskID string, reputation *ReputationRegistry) ([]BidScore, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -209,41 +520,30 @@ func (m *TaskMarket) selectBestBid(taskID string, reputation *ReputationRegistry
 		return nil, ErrNoBids
 	}
 
-	// Score each bid
-	type scoredBid struct {
-		bid   *Bid
-		score float64
-	}
-	scored := make([]scoredBid, len(bids))
-
+	weight := hintWeight(m.listings[taskID])
+	scores := make([]BidScore, len(bids))
 	for i, bid := range bids {
-		rep := reputation.Get(bid.AgentSID)
-		repScore := 50.0 // Default
-		if rep != nil {
-			repScore = rep.Overall
+		repScore := DefaultBidReputation
+		if overall, ok := reputation.Overall(bid.AgentSID); ok {
+			repScore = overall
 		}
-
-		// Combined score: capability (40%), reputation (40%), stake (20%)
-		score := bid.CapabilityScore*0.4 +
-			(repScore/100)*0.4 +
-			(bid.ReputationStake/100)*0.2
-
-		scored[i] = scoredBid{bid, score}
+		scores[i] = ScoreLoad(ScoreHintedBid(bid, repScore, weight))
 	}
+	strategy := m.auctionLocked(taskID)
+	strategy.Rank(scores)
+	BreakTies(strategy, m.ties, m.tieContextLocked(taskID), scores)
+	return scores, nil
+}
 
-	// Sort by score descending
-	sort.Slice(scored, func(i, j int) bool {
-		return scored[i].score > scored[j].score
-	})
-
-	// Winner is highest scored bid
-	winner := scored[0].bid
-
-	return &TaskAssignment{
-		TaskID:   taskID,
-		AgentSID: winner.AgentSID,
-		Bid:      winner,
-	}, nil
+// requiredProficiencies snapshots an agent's proficiency in each required capability
+func requiredProficiencies(a *agent.Agent, required []identity.CapabilityType) map[identity.CapabilityType]float64 {
+	result := make(map[identity.CapabilityType]float64, len(required))
+	for _, req := range required {
+		if a.Capabilities.Has(req) {
+			result[req] = a.Capabilities.Proficiency(req)
+		}
+	}
+	return result
 }
 
 // estimateTime estimates task completion time based on complexity and capability
@@ -277,6 +577,13 @@ func (m *TaskMarket) SetBidTimeout(timeout time.Duration) {
 	m.bidTimeout = timeout
 }
 
+// BidTimeout returns the bid collection timeout
+func (m *TaskMarket) BidTimeout() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.bidTimeout
+}
+
 // Stats returns market statistics
 type MarketStats struct {
 	ActiveListings int
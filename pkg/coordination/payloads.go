@@ -0,0 +1,46 @@
+package coordination
+
+import (
+	"crypto/ed25519"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// Payloads of the built-in message types. Each is registered with
+// RegisterPayloadType, so a handler sees the same type whether a message
+// came from this process or over a transport. Bids carry a Bid, assignments
+// a TaskAssignment, heartbeats an agent.Heartbeat and membership updates a
+// MembershipView.
+
+// AgentJoined announces a new member. It carries the public half of the
+// agent's identity only.
+type AgentJoined struct {
+	SID        string            `json:"sid"`
+	Name       string            `json:"name"`
+	PublicKey  ed25519.PublicKey `json:"public_key"`
+	ParentSID  string            `json:"parent_sid,omitempty"`
+	Generation int               `json:"generation"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// AgentLeft announces a member's departure
+type AgentLeft struct {
+	SID string `json:"sid"`
+}
+
+// TaskAvailable lists a task on the market
+type TaskAvailable struct {
+	Task *agent.Task `json:"task"`
+}
+
+// TaskCompleted reports a task's result
+type TaskCompleted struct {
+	Result *agent.TaskResult `json:"result"`
+}
+
+// ConsensusMessage carries a proposal or a vote on one
+type ConsensusMessage struct {
+	Proposal *Proposal `json:"proposal,omitempty"`
+	Vote     *Vote     `json:"vote,omitempty"`
+}
@@ -1,10 +1,13 @@
 package coordination
 
 import (
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/logging"
 )
 
 // ReputationRegistry manages reputation scores for all agents
@@ -13,12 +16,29 @@ type ReputationRegistry struct {
 
 	scores  map[string]*agent.Reputation // SID -> Reputation
 	history map[string][]ReputationEvent // SID -> Events
+
+	// Reputation of agents not currently registered, kept so it is exported
+	// and restored if the agent (re)joins
+	archived map[string]ReputationRecord
+
+	onChange []func(ReputationEvent)
+
+	// Decay given to every registered reputation (nil = each keeps its own)
+	decay *decaySettings
+
+	logger logging.Logger
+}
+
+// decaySettings is how registered reputations decay
+type decaySettings struct {
+	curve agent.DecayCurve
+	rate  float64
 }
 
 // ReputationEvent represents a reputation change event
 type ReputationEvent struct {
 	AgentSID  string    `json:"agent_sid"`
-	Type      string    `json:"type"` // "task_success", "task_failure", "peer_rating", "decay"
+	Type      string    `json:"type"` // "task_success", "task_failure", "peer_rating", "decay", "stake_locked", "stake_released", "stake_returned", "stake_forfeited"
 	Delta     float64   `json:"delta"`
 	Reason    string    `json:"reason"`
 	Timestamp time.Time `json:"timestamp"`
@@ -27,25 +47,61 @@ type ReputationEvent struct {
 // NewReputationRegistry creates a new reputation registry
 func NewReputationRegistry() *ReputationRegistry {
 	return &ReputationRegistry{
-		scores:  make(map[string]*agent.Reputation),
-		history: make(map[string][]ReputationEvent),
+		scores:   make(map[string]*agent.Reputation),
+		history:  make(map[string][]ReputationEvent),
+		archived: make(map[string]ReputationRecord),
+		logger:   logging.Component("reputation"),
 	}
 }
 
-// Register registers an agent with initial reputation
+// SetDecay sets how the reputations of registered agents, and of agents
+// registered later, decay when idle. rate is the daily decay rate.
+func (r *ReputationRegistry) SetDecay(curve agent.DecayCurve, rate float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decay = &decaySettings{curve: curve, rate: rate}
+	for _, rep := range r.scores {
+		rep.SetDecay(curve, rate)
+	}
+}
+
+// SetLogger replaces the registry's logger
+func (r *ReputationRegistry) SetLogger(l logging.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logger = l
+}
+
+// Register registers an agent with initial reputation. If the agent's
+// reputation was archived or restored from a snapshot, rep is overwritten with
+// it and its history is kept.
 func (r *ReputationRegistry) Register(sid string, rep *agent.Reputation) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if record, ok := r.archived[sid]; ok {
+		*rep = record.Reputation
+		r.history[sid] = record.History
+		delete(r.archived, sid)
+		r.logger.Debug("reputation restored", "agent", sid, "overall", rep.Overall)
+	} else {
+		r.history[sid] = make([]ReputationEvent, 0)
+	}
+	if r.decay != nil {
+		rep.SetDecay(r.decay.curve, r.decay.rate)
+	}
 	r.scores[sid] = rep
-	r.history[sid] = make([]ReputationEvent, 0)
 }
 
-// Unregister removes an agent from the registry
+// Unregister removes an agent from the registry. Its reputation is archived
+// so it survives a rejoin and is included in exports.
 func (r *ReputationRegistry) Unregister(sid string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if rep, ok := r.scores[sid]; ok {
+		r.archived[sid] = ReputationRecord{AgentSID: sid, Reputation: *rep, History: r.history[sid]}
+	}
 	delete(r.scores, sid)
 	delete(r.history, sid)
 }
@@ -58,6 +114,26 @@ func (r *ReputationRegistry) Get(sid string) *agent.Reputation {
 	return r.scores[sid]
 }
 
+// Overall returns a registered agent's overall reputation
+func (r *ReputationRegistry) Overall(sid string) (float64, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rep, ok := r.scores[sid]
+	if !ok {
+		return 0, false
+	}
+	return rep.Overall, true
+}
+
+// Guard runs fn holding the registry's lock, so that fn may change a
+// registered reputation without racing the registry's own updates
+func (r *ReputationRegistry) Guard(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fn()
+}
+
 // GetAll returns all reputations
 func (r *ReputationRegistry) GetAll() map[string]*agent.Reputation {
 	r.mu.RLock()
@@ -81,10 +157,10 @@ func (r *ReputationRegistry) Update(sid string, rep *agent.Reputation) {
 // RecordTaskSuccess records a successful task completion
 func (r *ReputationRegistry) RecordTaskSuccess(sid string, quality float64) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	rep, ok := r.scores[sid]
 	if !ok {
+		r.mu.Unlock()
 		return
 	}
 
@@ -99,21 +175,19 @@ func (r *ReputationRegistry) RecordTaskSuccess(sid string, quality float64) {
 		Reason:    "Task completed successfully",
 		Timestamp: time.Now(),
 	}
-	r.history[sid] = append(r.history[sid], event)
+	handlers := r.appendEvent(event)
+	r.mu.Unlock()
 
-	// Keep history bounded
-	if len(r.history[sid]) > 100 {
-		r.history[sid] = r.history[sid][len(r.history[sid])-100:]
-	}
+	notify(handlers, event)
 }
 
 // RecordTaskFailure records a failed task
 func (r *ReputationRegistry) RecordTaskFailure(sid string) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	rep, ok := r.scores[sid]
 	if !ok {
+		r.mu.Unlock()
 		return
 	}
 
@@ -128,22 +202,54 @@ func (r *ReputationRegistry) RecordTaskFailure(sid string) {
 		Reason:    "Task failed",
 		Timestamp: time.Now(),
 	}
-	r.history[sid] = append(r.history[sid], event)
+	handlers := r.appendEvent(event)
+	r.mu.Unlock()
+
+	notify(handlers, event)
+}
+
+// RecordCalibration records how close the confidence an agent reported for
+// a result was to the quality it was measured at, which moves its honesty
+func (r *ReputationRegistry) RecordCalibration(sid string, confidence, quality float64) {
+	r.mu.Lock()
+
+	rep, ok := r.scores[sid]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+
+	oldOverall := rep.Overall
+	rep.RecordCalibration(confidence, quality)
+
+	// Record event
+	event := ReputationEvent{
+		AgentSID:  sid,
+		Type:      "calibration",
+		Delta:     rep.Overall - oldOverall,
+		Reason:    fmt.Sprintf("Reported confidence %.2f, measured quality %.2f", confidence, quality),
+		Timestamp: time.Now(),
+	}
+	handlers := r.appendEvent(event)
+	r.mu.Unlock()
+
+	notify(handlers, event)
 }
 
 // RecordPeerRating records a peer rating
 func (r *ReputationRegistry) RecordPeerRating(sid string, raterSID string, rating float64) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	rep, ok := r.scores[sid]
 	if !ok {
+		r.mu.Unlock()
 		return
 	}
 
 	// Verify rater exists and has sufficient reputation to rate
 	raterRep, ok := r.scores[raterSID]
 	if !ok || raterRep.Overall < 30 {
+		r.mu.Unlock()
 		return // Rater needs minimum reputation
 	}
 
@@ -153,7 +259,7 @@ func (r *ReputationRegistry) RecordPeerRating(sid string, raterSID string, ratin
 	weight := raterRep.Overall / 100
 	rep.Cooperation = rep.Cooperation*0.9 + rating*100*0.1*weight
 	// Recalculate overall score
-	rep.Overall = (rep.Reliability + rep.Quality + rep.Cooperation + rep.Honesty) / 4
+	rep.Recalculate()
 
 	// Record event
 	event := ReputationEvent{
@@ -163,14 +269,67 @@ func (r *ReputationRegistry) RecordPeerRating(sid string, raterSID string, ratin
 		Reason:    "Rated by peer " + raterSID,
 		Timestamp: time.Now(),
 	}
-	r.history[sid] = append(r.history[sid], event)
+	handlers := r.appendEvent(event)
+	r.mu.Unlock()
+
+	notify(handlers, event)
+}
+
+// LockStake sets aside part of an agent's reputation as a stake, such as when
+// vouching for a new member. Returns ErrUnknownAgent if the agent isn't registered.
+func (r *ReputationRegistry) LockStake(sid string, amount float64, reason string) error {
+	return r.applyStake(sid, "stake_locked", reason, func(rep *agent.Reputation) { rep.LockStake(amount) })
+}
+
+// ReleaseStake returns a previously locked stake
+func (r *ReputationRegistry) ReleaseStake(sid string, amount float64, reason string) error {
+	return r.applyStake(sid, "stake_released", reason, func(rep *agent.Reputation) { rep.UnlockStake(amount) })
+}
+
+// ReturnStake returns a previously locked stake along with a reward, such as
+// when a task the stake was bid on succeeds
+func (r *ReputationRegistry) ReturnStake(sid string, amount, reward float64, reason string) error {
+	return r.applyStake(sid, "stake_returned", reason, func(rep *agent.Reputation) { rep.ReturnStake(amount, reward) })
+}
+
+// ForfeitStake permanently takes a previously locked stake
+func (r *ReputationRegistry) ForfeitStake(sid string, amount float64, reason string) error {
+	return r.applyStake(sid, "stake_forfeited", reason, func(rep *agent.Reputation) { rep.ForfeitStake(amount) })
+}
+
+// applyStake changes a registered agent's stake and records the event
+func (r *ReputationRegistry) applyStake(sid, eventType, reason string, apply func(*agent.Reputation)) error {
+	r.mu.Lock()
+
+	rep, ok := r.scores[sid]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownAgent, sid)
+	}
+
+	oldOverall := rep.Overall
+	apply(rep)
+
+	event := ReputationEvent{
+		AgentSID:  sid,
+		Type:      eventType,
+		Delta:     rep.Overall - oldOverall,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	}
+	handlers := r.appendEvent(event)
+	r.mu.Unlock()
+
+	notify(handlers, event)
+	return nil
 }
 
 // ApplyDecayAll applies decay to all agents
 func (r *ReputationRegistry) ApplyDecayAll() {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
+	var events []ReputationEvent
+	var handlers []func(ReputationEvent)
 	for sid, rep := range r.scores {
 		oldOverall := rep.Overall
 		rep.ApplyDecay()
@@ -183,9 +342,45 @@ func (r *ReputationRegistry) ApplyDecayAll() {
 				Reason:    "Time-based decay",
 				Timestamp: time.Now(),
 			}
-			r.history[sid] = append(r.history[sid], event)
+			handlers = r.appendEvent(event)
+			events = append(events, event)
 		}
 	}
+	r.mu.Unlock()
+
+	for _, event := range events {
+		notify(handlers, event)
+	}
+}
+
+// OnChange registers a callback invoked after every recorded reputation event.
+// Callbacks run outside the registry lock.
+func (r *ReputationRegistry) OnChange(handler func(ReputationEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, handler)
+}
+
+// appendEvent adds an event to an agent's bounded history and returns the
+// change handlers to notify. Caller must hold r.mu.
+func (r *ReputationRegistry) appendEvent(event ReputationEvent) []func(ReputationEvent) {
+	sid := event.AgentSID
+	r.history[sid] = append(r.history[sid], event)
+	r.logger.Debug("reputation changed", "agent", sid, "type", event.Type, "delta", event.Delta)
+
+	// Keep history bounded
+	if len(r.history[sid]) > 100 {
+		r.history[sid] = r.history[sid][len(r.history[sid])-100:]
+	}
+
+	return r.onChange
+}
+
+// notify invokes change handlers for an event
+func notify(handlers []func(ReputationEvent), event ReputationEvent) {
+	for _, h := range handlers {
+		h(event)
+	}
 }
 
 // GetHistory returns reputation history for an agent
@@ -196,6 +391,39 @@ func (r *ReputationRegistry) GetHistory(sid string) []ReputationEvent {
 	return r.history[sid]
 }
 
+// ReputationTrend pairs an agent's current reputation with its recent changes
+type ReputationTrend struct {
+	AgentSID    string            `json:"agent_sid"`
+	Overall     float64           `json:"overall"`
+	Honesty     float64           `json:"honesty"`
+	Calibration agent.Calibration `json:"calibration"`
+	History     []ReputationEvent `json:"history"`
+}
+
+// Trends returns the reputation trend of every registered agent, highest first
+func (r *ReputationRegistry) Trends() []ReputationTrend {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	trends := make([]ReputationTrend, 0, len(r.scores))
+	for sid, rep := range r.scores {
+		history := make([]ReputationEvent, len(r.history[sid]))
+		copy(history, r.history[sid])
+		trends = append(trends, ReputationTrend{
+			AgentSID:    sid,
+			Overall:     rep.Overall,
+			Honesty:     rep.Honesty,
+			Calibration: rep.Calibration,
+			History:     history,
+		})
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		return trends[i].Overall > trends[j].Overall
+	})
+	return trends
+}
+
 // GetTopAgents returns the top N agents by reputation
 func (r *ReputationRegistry) GetTopAgents(n int) []string {
 	r.mu.RLock()
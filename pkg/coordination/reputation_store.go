@@ -0,0 +1,191 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+// ReputationSnapshotVersion is the format version written by ExportJSON
+const ReputationSnapshotVersion = 1
+
+// Where SaveTo keeps the registry in a document store
+const (
+	ReputationCollection = "reputation"
+	reputationDocument   = "registry"
+)
+
+var (
+	// ErrUnknownAgent is returned when no reputation is held for an agent
+	ErrUnknownAgent = errors.New("no reputation recorded for agent")
+
+	// ErrSnapshotVersion is returned when importing a snapshot in an unsupported format
+	ErrSnapshotVersion = errors.New("unsupported reputation snapshot version")
+)
+
+// ReputationRecord is an agent's reputation and event history
+type ReputationRecord struct {
+	AgentSID   string            `json:"agent_sid"`
+	Reputation agent.Reputation  `json:"reputation"`
+	History    []ReputationEvent `json:"history"`
+	Active     bool              `json:"active"` // Registered at the time of the snapshot
+}
+
+// ReputationSnapshot is the serialized state of a registry
+type ReputationSnapshot struct {
+	Version   int                `json:"version"`
+	Timestamp time.Time          `json:"timestamp"`
+	Agents    []ReputationRecord `json:"agents"`
+}
+
+// Record returns an agent's reputation and history, whether the agent is
+// registered or only known from an archive or snapshot
+func (r *ReputationRegistry) Record(sid string) (ReputationRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rep, ok := r.scores[sid]; ok {
+		return r.recordLocked(sid, rep), nil
+	}
+	if record, ok := r.archived[sid]; ok {
+		record.History = append([]ReputationEvent(nil), record.History...)
+		return record, nil
+	}
+	return ReputationRecord{}, fmt.Errorf("%w: %s", ErrUnknownAgent, sid)
+}
+
+// recordLocked copies a registered agent's state. Caller must hold r.mu.
+func (r *ReputationRegistry) recordLocked(sid string, rep *agent.Reputation) ReputationRecord {
+	return ReputationRecord{
+		AgentSID:   sid,
+		Reputation: *rep,
+		History:    append([]ReputationEvent(nil), r.history[sid]...),
+		Active:     true,
+	}
+}
+
+// Snapshot returns the state of every registered and archived agent
+func (r *ReputationRegistry) Snapshot() ReputationSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := ReputationSnapshot{
+		Version:   ReputationSnapshotVersion,
+		Timestamp: time.Now(),
+		Agents:    make([]ReputationRecord, 0, len(r.scores)+len(r.archived)),
+	}
+	for sid, rep := range r.scores {
+		snapshot.Agents = append(snapshot.Agents, r.recordLocked(sid, rep))
+	}
+	for _, record := range r.archived {
+		record.History = append([]ReputationEvent(nil), record.History...)
+		record.Active = false
+		snapshot.Agents = append(snapshot.Agents, record)
+	}
+
+	sort.Slice(snapshot.Agents, func(i, j int) bool {
+		return snapshot.Agents[i].AgentSID < snapshot.Agents[j].AgentSID
+	})
+	return snapshot
+}
+
+// Restore loads a snapshot. Registered agents take the restored values
+// immediately; the rest are archived until they register.
+func (r *ReputationRegistry) Restore(snapshot ReputationSnapshot) error {
+	if snapshot.Version != ReputationSnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, snapshot.Version)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range snapshot.Agents {
+		if record.History == nil {
+			record.History = make([]ReputationEvent, 0)
+		}
+		if rep, ok := r.scores[record.AgentSID]; ok {
+			*rep = record.Reputation
+			r.history[record.AgentSID] = record.History
+			continue
+		}
+		record.Active = false
+		r.archived[record.AgentSID] = record
+	}
+	r.logger.Debug("reputation snapshot restored", "agents", len(snapshot.Agents), "taken", snapshot.Timestamp)
+	return nil
+}
+
+// ExportJSON serializes the registry, including event history
+func (r *ReputationRegistry) ExportJSON() ([]byte, error) {
+	return json.MarshalIndent(r.Snapshot(), "", "  ")
+}
+
+// ImportJSON restores the registry from ExportJSON output
+func (r *ReputationRegistry) ImportJSON(data []byte) error {
+	var snapshot ReputationSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("invalid reputation snapshot: %w", err)
+	}
+	return r.Restore(snapshot)
+}
+
+// SaveFile writes the registry to path atomically
+func (r *ReputationRegistry) SaveFile(path string) error {
+	data, err := r.ExportJSON()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	// Write atomically so a crash never leaves a truncated file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadFile restores the registry from a file written by SaveFile. A missing
+// file is not an error.
+func (r *ReputationRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if err := r.ImportJSON(data); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// SaveTo writes the registry to a document store
+func (r *ReputationRegistry) SaveTo(ctx context.Context, docs storage.Documents) error {
+	return docs.Put(ctx, ReputationCollection, reputationDocument, r.Snapshot())
+}
+
+// LoadFrom restores the registry from a document store written by SaveTo.
+// A store without a saved registry is not an error.
+func (r *ReputationRegistry) LoadFrom(ctx context.Context, docs storage.Documents) error {
+	var snapshot ReputationSnapshot
+	if err := docs.Get(ctx, ReputationCollection, reputationDocument, &snapshot); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return r.Restore(snapshot)
+}
@@ -0,0 +1,193 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/storage"
+)
+
+func TestReputationRegistry_ExportImport(t *testing.T) {
+	r := NewReputationRegistry()
+	rep := agent.NewReputation()
+	r.Register("agent-1", rep)
+	r.RecordTaskSuccess("agent-1", 0.9)
+	r.RecordTaskFailure("agent-1")
+
+	data, err := r.ExportJSON()
+	if err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+
+	// A new session restores the reputation when the agent joins
+	restored := NewReputationRegistry()
+	if err := restored.ImportJSON(data); err != nil {
+		t.Fatalf("ImportJSON failed: %v", err)
+	}
+
+	record, err := restored.Record("agent-1")
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if record.Active {
+		t.Error("Expected imported agent to be inactive until it registers")
+	}
+
+	fresh := agent.NewReputation()
+	restored.Register("agent-1", fresh)
+	if fresh.Overall != rep.Overall {
+		t.Errorf("Expected overall %f, got %f", rep.Overall, fresh.Overall)
+	}
+	if fresh.TasksCompleted != 1 || fresh.TasksFailed != 1 {
+		t.Errorf("Expected 1 completed and 1 failed, got %d and %d", fresh.TasksCompleted, fresh.TasksFailed)
+	}
+	if got := len(restored.GetHistory("agent-1")); got != 2 {
+		t.Errorf("Expected 2 history events, got %d", got)
+	}
+
+	if _, err := restored.Record("missing"); !errors.Is(err, ErrUnknownAgent) {
+		t.Errorf("Expected ErrUnknownAgent, got %v", err)
+	}
+	if err := restored.ImportJSON([]byte(`{"version": 99}`)); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("Expected ErrSnapshotVersion, got %v", err)
+	}
+}
+
+func TestReputationRegistry_UnregisterArchives(t *testing.T) {
+	r := NewReputationRegistry()
+	rep := agent.NewReputation()
+	r.Register("agent-1", rep)
+	r.RecordTaskSuccess("agent-1", 1.0)
+	earned := rep.Overall
+
+	r.Unregister("agent-1")
+	if r.Get("agent-1") != nil {
+		t.Error("Expected agent to be unregistered")
+	}
+	if got := len(r.Snapshot().Agents); got != 1 {
+		t.Errorf("Expected archived agent in snapshot, got %d agents", got)
+	}
+
+	rejoined := agent.NewReputation()
+	r.Register("agent-1", rejoined)
+	if rejoined.Overall != earned {
+		t.Errorf("Expected reputation %f after rejoin, got %f", earned, rejoined.Overall)
+	}
+}
+
+func TestReputationRegistry_SaveLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reputation.json")
+
+	r := NewReputationRegistry()
+	r.Register("agent-1", agent.NewReputation())
+	r.RecordTaskSuccess("agent-1", 0.8)
+	if err := r.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	loaded := NewReputationRegistry()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	record, err := loaded.Record("agent-1")
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if record.Reputation.TasksCompleted != 1 {
+		t.Errorf("Expected 1 completed task, got %d", record.Reputation.TasksCompleted)
+	}
+
+	// A missing file is not an error
+	if err := NewReputationRegistry().LoadFile(filepath.Join(t.TempDir(), "none.json")); err != nil {
+		t.Errorf("Expected no error for missing file, got %v", err)
+	}
+}
+
+func TestReputationRegistry_SaveToLoadFrom(t *testing.T) {
+	ctx := context.Background()
+	docs := storage.NewMemory().Documents()
+
+	// A store without a saved registry is not an error
+	if err := NewReputationRegistry().LoadFrom(ctx, docs); err != nil {
+		t.Errorf("Expected no error for empty store, got %v", err)
+	}
+
+	r := NewReputationRegistry()
+	r.Register("agent-1", agent.NewReputation())
+	r.RecordTaskSuccess("agent-1", 0.8)
+	if err := r.SaveTo(ctx, docs); err != nil {
+		t.Fatalf("SaveTo failed: %v", err)
+	}
+
+	loaded := NewReputationRegistry()
+	if err := loaded.LoadFrom(ctx, docs); err != nil {
+		t.Fatalf("LoadFrom failed: %v", err)
+	}
+	record, err := loaded.Record("agent-1")
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if record.Reputation.TasksCompleted != 1 {
+		t.Errorf("Expected 1 completed task, got %d", record.Reputation.TasksCompleted)
+	}
+}
+
+func TestReputationRegistry_RecordCalibration(t *testing.T) {
+	r := NewReputationRegistry()
+	r.Register("honest", agent.NewReputation())
+	r.Register("boastful", agent.NewReputation())
+
+	for i := 0; i < 5; i++ {
+		r.RecordCalibration("honest", 0.6, 0.6)
+		r.RecordCalibration("boastful", 1.0, 0.2)
+	}
+	honest, boastful := r.Get("honest"), r.Get("boastful")
+	if honest.Honesty <= 50 {
+		t.Errorf("Expected calibrated agent's honesty above 50, got %f", honest.Honesty)
+	}
+	if boastful.Honesty >= 50 {
+		t.Errorf("Expected overconfident agent's honesty below 50, got %f", boastful.Honesty)
+	}
+	if boastful.Calibration.Samples != 5 || boastful.Calibration.Overconfidence() < 0.79 {
+		t.Errorf("Expected 5 samples overconfident by 0.8, got %+v", boastful.Calibration)
+	}
+
+	history := r.GetHistory("boastful")
+	if len(history) != 5 || history[0].Type != "calibration" {
+		t.Errorf("Expected 5 calibration events, got %+v", history)
+	}
+	for _, trend := range r.Trends() {
+		if trend.AgentSID == "honest" && trend.Calibration.MeanError != 0 {
+			t.Errorf("Expected the trend to carry calibration, got %+v", trend.Calibration)
+		}
+	}
+}
+
+func TestReputationRegistry_SetDecay(t *testing.T) {
+	r := NewReputationRegistry()
+	before := agent.NewReputation()
+	r.Register("agent-1", before)
+	r.SetDecay(agent.DecayExponential, 0.05)
+
+	after := agent.NewReputation()
+	r.Register("agent-2", after)
+	for _, rep := range []*agent.Reputation{before, after} {
+		if rep.DecayCurve != agent.DecayExponential || rep.DecayRate != 0.05 {
+			t.Errorf("Expected exponential decay at 0.05, got %s at %v", rep.DecayCurve, rep.DecayRate)
+		}
+	}
+
+	after.LastActive = time.Now().Add(-agent.DecayGrace - 24*time.Hour)
+	r.ApplyDecayAll()
+	if after.Overall >= 50 || before.Overall != 50 {
+		t.Errorf("Expected only the idle agent to decay, got %f and %f", after.Overall, before.Overall)
+	}
+	history := r.GetHistory("agent-2")
+	if len(history) != 1 || history[0].Type != "decay" {
+		t.Errorf("Expected one decay event, got %+v", history)
+	}
+}
@@ -0,0 +1,177 @@
+package coordination
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// MinCapabilityScore is the capability score an agent needs to bid on a
+// task unless the market's BidThreshold says otherwise. A new agent holding
+// every required capability meets it exactly.
+const MinCapabilityScore = 0.5
+
+// Weights of the factors a bid is ranked by. They sum to 1.
+const (
+	CapabilityWeight = 0.4
+	ReputationWeight = 0.4
+	StakeWeight      = 0.2
+)
+
+// DefaultBidReputation is the reputation assumed for a bidder the
+// reputation registry doesn't know
+const DefaultBidReputation = 50.0
+
+// Factors of a bid score
+const (
+	FactorCapability = "capability"
+	FactorReputation = "reputation"
+	FactorStake      = "stake"
+	FactorHint       = "hint"
+	FactorLoad       = "load"
+)
+
+// DefaultHintWeight is the weight of the hint factor for tasks whose
+// routing hints don't set one
+const DefaultHintWeight = 0.2
+
+// LoadWeight is how much a bidder with every other slot busy scores below
+// an idle one
+const LoadWeight = 0.2
+
+// ScoreFactor is one factor's contribution to a bid score
+type ScoreFactor struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"` // The factor scaled to 0-1
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // Value * Weight
+}
+
+// BidScore is a bid's ranking score with the contribution of each factor
+type BidScore struct {
+	Bid     *Bid          `json:"bid"`
+	Score   float64       `json:"score"`
+	Factors []ScoreFactor `json:"factors"`
+}
+
+// Factor returns the named factor of the score
+func (s *BidScore) Factor(name string) (ScoreFactor, bool) {
+	for _, f := range s.Factors {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ScoreFactor{}, false
+}
+
+// ScoreBid scores a bid from its bidder's overall reputation (0-100). It is a
+// pure function of its arguments:
+//
+//	capability  bid.CapabilityScore         * CapabilityWeight
+//	reputation  reputation / 100            * ReputationWeight
+//	stake       bid.ReputationStake / 100   * StakeWeight
+//
+// The score is the sum of the contributions in that order.
+func ScoreBid(bid *Bid, reputation float64) BidScore {
+	factors := []ScoreFactor{
+		{Name: FactorCapability, Value: bid.CapabilityScore, Weight: CapabilityWeight},
+		{Name: FactorReputation, Value: reputation / 100, Weight: ReputationWeight},
+		{Name: FactorStake, Value: bid.ReputationStake / 100, Weight: StakeWeight},
+	}
+	score := 0.0
+	for i := range factors {
+		factors[i].Contribution = factors[i].Value * factors[i].Weight
+		score += factors[i].Contribution
+	}
+	return BidScore{Bid: bid, Score: score, Factors: factors}
+}
+
+// ScoreHintedBid scores a bid on a task with routing hints: ScoreBid plus
+//
+//	hint        bid.Hint                    * weight
+//
+// so a bid meeting every hint can outrank one that is otherwise up to
+// weight better. A weight of 0 scores the bid as ScoreBid does.
+func ScoreHintedBid(bid *Bid, reputation, weight float64) BidScore {
+	score := ScoreBid(bid, reputation)
+	if weight <= 0 {
+		return score
+	}
+	hint := ScoreFactor{Name: FactorHint, Value: bid.Hint, Weight: weight, Contribution: bid.Hint * weight}
+	score.Factors = append(score.Factors, hint)
+	score.Score += hint.Contribution
+	return score
+}
+
+// ScoreLoad lowers a score by the share of its bidder's worker slots that
+// are busy:
+//
+//	load        bid.Load                    * -LoadWeight
+//
+// so of bidders otherwise alike, the one with the most room left wins. An
+// idle bidder's score is unchanged.
+func ScoreLoad(score BidScore) BidScore {
+	if score.Bid.Load <= 0 {
+		return score
+	}
+	load := ScoreFactor{Name: FactorLoad, Value: score.Bid.Load, Weight: -LoadWeight, Contribution: -score.Bid.Load * LoadWeight}
+	score.Factors = append(score.Factors, load)
+	score.Score += load.Contribution
+	return score
+}
+
+// hintWeight returns the weight of a task's hint factor, 0 if it has no hints
+func hintWeight(task *agent.Task) float64 {
+	switch {
+	case task == nil || task.Hints == nil:
+		return 0
+	case task.Hints.Weight > 0:
+		return task.Hints.Weight
+	}
+	return DefaultHintWeight
+}
+
+// RankBidScores sorts scores best first. Equal scores go to the higher
+// capability score, then to the agent SID first in order, so the ranking
+// doesn't depend on the order bids arrived in.
+func RankBidScores(scores []BidScore) {
+	sort.SliceStable(scores, func(i, j int) bool {
+		return compareBidScores(&scores[i], &scores[j]) < 0
+	})
+}
+
+// Tie-break rules, in the order RankBidScores applies them
+const (
+	TieBreakCapability = "capability_score"
+	TieBreakAgentSID   = "agent_sid"
+)
+
+// TieBreak returns the rule that orders two bids with equal scores, or "" if
+// their scores differ
+func TieBreak(a, b *BidScore) string {
+	switch {
+	case a.Score != b.Score:
+		return ""
+	case a.Bid.CapabilityScore != b.Bid.CapabilityScore:
+		return TieBreakCapability
+	}
+	return TieBreakAgentSID
+}
+
+// compareBidScores orders a before b (-1) if it ranks higher
+func compareBidScores(a, b *BidScore) int {
+	switch {
+	case a.Score != b.Score:
+		if a.Score > b.Score {
+			return -1
+		}
+		return 1
+	case a.Bid.CapabilityScore != b.Bid.CapabilityScore:
+		if a.Bid.CapabilityScore > b.Bid.CapabilityScore {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.Bid.AgentSID, b.Bid.AgentSID)
+}
@@ -0,0 +1,204 @@
+package coordination
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+func TestScoreBid(t *testing.T) {
+	tests := []struct {
+		name       string
+		capability float64
+		stake      float64
+		reputation float64
+		want       [3]float64 // Capability, reputation and stake contributions
+	}{
+		{"new agent", 0.5, 5, 50, [3]float64{0.2, 0.2, 0.01}},
+		{"expert", 1, 10, 100, [3]float64{0.4, 0.4, 0.02}},
+		{"unknown", 0, 0, 0, [3]float64{0, 0, 0}},
+		{"low reputation", 0.9, 1, 10, [3]float64{0.36, 0.04, 0.002}},
+	}
+
+	for _, tt := range tests {
+		s := ScoreBid(&Bid{AgentSID: "a", CapabilityScore: tt.capability, ReputationStake: tt.stake}, tt.reputation)
+		if len(s.Factors) != 3 {
+			t.Fatalf("%s: expected 3 factors, got %d", tt.name, len(s.Factors))
+		}
+
+		sum := 0.0
+		for i, name := range []string{FactorCapability, FactorReputation, FactorStake} {
+			f, ok := s.Factor(name)
+			if !ok {
+				t.Fatalf("%s: expected a %s factor", tt.name, name)
+			}
+			if math.Abs(f.Contribution-tt.want[i]) > 1e-9 {
+				t.Errorf("%s: expected %s to contribute %f, got %f", tt.name, name, tt.want[i], f.Contribution)
+			}
+			sum += f.Contribution
+		}
+		if s.Score != sum {
+			t.Errorf("%s: expected score %f to be the sum of contributions %f", tt.name, s.Score, sum)
+		}
+	}
+}
+
+func TestRankBidScores(t *testing.T) {
+	bids := []*Bid{
+		{AgentSID: "sq-c", CapabilityScore: 0.6, ReputationStake: 5},
+		{AgentSID: "sq-b", CapabilityScore: 0.6, ReputationStake: 5},
+		{AgentSID: "sq-a", CapabilityScore: 0.5, ReputationStake: 5},
+		{AgentSID: "sq-d", CapabilityScore: 0.9, ReputationStake: 5},
+	}
+	reputation := map[string]float64{"sq-a": 60, "sq-b": 50, "sq-c": 50, "sq-d": 50}
+
+	// sq-a's reputation makes up for its capability (0.2+0.24 = 0.24+0.2),
+	// so it ties with sq-b and sq-c on score and loses on capability
+	want := []string{"sq-d", "sq-b", "sq-c", "sq-a"}
+
+	rng := rand.New(rand.NewSource(1))
+	for run := 0; run < 20; run++ {
+		rng.Shuffle(len(bids), func(i, j int) { bids[i], bids[j] = bids[j], bids[i] })
+		scores := make([]BidScore, len(bids))
+		for i, bid := range bids {
+			scores[i] = ScoreBid(bid, reputation[bid.AgentSID])
+		}
+		RankBidScores(scores)
+
+		for i, s := range scores {
+			if s.Bid.AgentSID != want[i] {
+				t.Fatalf("Run %d: expected %s at rank %d, got %s", run, want[i], i+1, s.Bid.AgentSID)
+			}
+		}
+
+		rules := []string{"", TieBreakAgentSID, TieBreakCapability}
+		for i, rule := range rules {
+			if got := TieBreak(&scores[i], &scores[i+1]); got != rule {
+				t.Errorf("Expected tie-break %q between ranks %d and %d, got %q", rule, i+1, i+2, got)
+			}
+		}
+	}
+}
+
+func TestTaskMarket_ScoreBids(t *testing.T) {
+	m := NewTaskMarket()
+	defer m.Close()
+	reputation := NewReputationRegistry()
+	reputation.Register("sq-known", &agent.Reputation{Overall: 90})
+
+	task := agent.NewTask("score me", nil)
+	if err := m.ListTask(task); err != nil {
+		t.Fatalf("ListTask failed: %v", err)
+	}
+	if _, err := m.ScoreBids(task.ID, reputation); err != ErrNoBids {
+		t.Errorf("Expected ErrNoBids, got %v", err)
+	}
+
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-new", TaskID: task.ID, CapabilityScore: 0.8})
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-known", TaskID: task.ID, CapabilityScore: 0.8})
+
+	scores, err := m.ScoreBids(task.ID, reputation)
+	if err != nil {
+		t.Fatalf("ScoreBids failed: %v", err)
+	}
+	if scores[0].Bid.AgentSID != "sq-known" {
+		t.Errorf("Expected sq-known to rank first, got %s", scores[0].Bid.AgentSID)
+	}
+	if f, _ := scores[1].Factor(FactorReputation); f.Value != DefaultBidReputation/100 {
+		t.Errorf("Expected an unknown bidder to get the default reputation, got %f", f.Value)
+	}
+
+	ranked, err := m.RankBids(task.ID, reputation)
+	if err != nil {
+		t.Fatalf("RankBids failed: %v", err)
+	}
+	for i := range ranked {
+		if ranked[i].AgentSID != scores[i].Bid.AgentSID {
+			t.Errorf("Expected RankBids to follow ScoreBids at rank %d", i+1)
+		}
+	}
+}
+
+type holdProvider struct {
+	release chan struct{}
+}
+
+func (p *holdProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	select {
+	case <-p.release:
+		return &llm.CompletionResponse{Content: "done"}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *holdProvider) Name() string {
+	return "hold"
+}
+
+func TestScoreLoad(t *testing.T) {
+	m := NewTaskMarket()
+	defer m.Close()
+	task := agent.NewTask("pick the freer agent", nil)
+	_ = m.ListTask(task)
+	// Alike but for load, the busy expert's 0.57 drops to 0.47, below 0.49
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-busy", TaskID: task.ID, CapabilityScore: 0.9, ReputationStake: 5, Load: 0.5})
+	_ = m.SubmitBid(&Bid{AgentSID: "sq-free", TaskID: task.ID, CapabilityScore: 0.7, ReputationStake: 5})
+
+	scores, err := m.ScoreBids(task.ID, NewReputationRegistry())
+	if err != nil {
+		t.Fatalf("ScoreBids failed: %v", err)
+	}
+	if scores[0].Bid.AgentSID != "sq-free" {
+		t.Errorf("Expected the idle agent to win, got %s", scores[0].Bid.AgentSID)
+	}
+	if len(scores[0].Factors) != 3 {
+		t.Errorf("Expected an idle bid's score unchanged, got %+v", scores[0].Factors)
+	}
+	load, ok := scores[1].Factor(FactorLoad)
+	if !ok || math.Abs(load.Contribution+0.1) > 1e-9 {
+		t.Errorf("Expected the busy bid to lose 0.1 to load, got %+v", load)
+	}
+}
+
+func TestCheckEligibility_Slots(t *testing.T) {
+	provider := &holdProvider{release: make(chan struct{})}
+	defer close(provider.release)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Pool", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}, Provider: provider, Slots: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = a.Start(ctx)
+	defer a.Stop()
+
+	task := agent.NewTask("code", []identity.CapabilityType{identity.CapCodeWrite})
+	_ = a.SubmitTask(task)
+	for deadline := time.Now().Add(time.Second); a.FreeSlots() == 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the agent to start the task")
+		}
+	}
+
+	if _, reason := CheckEligibility(a, task); reason != "" {
+		t.Errorf("Expected a working agent with a free slot to be eligible, got %q", reason)
+	}
+	bid := NewTaskMarket().MakeBid(a, task)
+	if bid == nil || bid.Load != 0.5 {
+		t.Fatalf("Expected a bid at half load, got %+v", bid)
+	}
+
+	_ = a.SubmitTask(agent.NewTask("more code", []identity.CapabilityType{identity.CapCodeWrite}))
+	for deadline := time.Now().Add(time.Second); a.FreeSlots() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the agent to start the second task")
+		}
+	}
+	if _, reason := CheckEligibility(a, task); reason != IneligibleNotIdle {
+		t.Errorf("Expected an agent with every slot busy to be ineligible, got %q", reason)
+	}
+}
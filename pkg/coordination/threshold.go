@@ -0,0 +1,133 @@
+package coordination
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var ErrInvalidThreshold = errors.New("invalid bid threshold")
+
+// DefaultBidFloor is the lowest a relaxed bid threshold goes unless
+// BidThreshold.Floor says otherwise
+const DefaultBidFloor = 0.3
+
+// BidThreshold is the capability score an agent needs to bid on a task, and
+// how it is relaxed when a round of bidding draws no bids
+type BidThreshold struct {
+	Min   float64 `yaml:"min" json:"min"`     // Score needed to bid (0 = MinCapabilityScore)
+	Step  float64 `yaml:"step" json:"step"`   // How far each round without bids lowers it (0 = never relaxed)
+	Floor float64 `yaml:"floor" json:"floor"` // Lowest it is relaxed to (0 = DefaultBidFloor, or Min if that is lower)
+}
+
+// DefaultBidThreshold returns the fixed MinCapabilityScore threshold
+func DefaultBidThreshold() BidThreshold {
+	return BidThreshold{Min: MinCapabilityScore}.withDefaults()
+}
+
+// withDefaults fills unset fields
+func (t BidThreshold) withDefaults() BidThreshold {
+	if t.Min == 0 {
+		t.Min = MinCapabilityScore
+	}
+	if t.Floor == 0 {
+		t.Floor = min(DefaultBidFloor, t.Min)
+	}
+	return t
+}
+
+// Validate reports a threshold outside 0-1, a negative step or a floor
+// above the minimum
+func (t BidThreshold) Validate() error {
+	t = t.withDefaults()
+	switch {
+	case t.Min < 0 || t.Min > 1:
+		return fmt.Errorf("%w: min %.2f is outside 0-1", ErrInvalidThreshold, t.Min)
+	case t.Step < 0:
+		return fmt.Errorf("%w: negative step %.2f", ErrInvalidThreshold, t.Step)
+	case t.Floor < 0 || t.Floor > t.Min:
+		return fmt.Errorf("%w: floor %.2f is outside 0-%.2f", ErrInvalidThreshold, t.Floor, t.Min)
+	}
+	return nil
+}
+
+// Relaxation records a task's bid threshold being lowered after a round of
+// bidding drew no bids
+type Relaxation struct {
+	From float64   `json:"from"`
+	To   float64   `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// SetBidThreshold replaces the threshold tasks listed from now on are bid
+// on by
+func (m *TaskMarket) SetBidThreshold(t BidThreshold) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.threshold = t.withDefaults()
+	return nil
+}
+
+// BidThreshold returns the market's bid threshold
+func (m *TaskMarket) BidThreshold() BidThreshold {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.threshold
+}
+
+// Threshold returns the capability score an agent currently needs to bid on
+// a task, after any relaxation
+func (m *TaskMarket) Threshold(taskID string) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.thresholdLocked(taskID)
+}
+
+// thresholdLocked returns a task's current threshold. Caller must hold m.mu.
+func (m *TaskMarket) thresholdLocked(taskID string) float64 {
+	if t, ok := m.thresholds[taskID]; ok {
+		return t
+	}
+	return m.threshold.Min
+}
+
+// Relaxations returns how a task's bid threshold has been lowered, oldest first
+func (m *TaskMarket) Relaxations(taskID string) []Relaxation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Relaxation(nil), m.relaxations[taskID]...)
+}
+
+// relax lowers a listed task's threshold by one step, sealing its bids again
+// if its auction is sealed. It returns false once the floor is reached or
+// if the threshold isn't relaxed at all.
+func (m *TaskMarket) relax(taskID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	strategy, listed := m.auctions[taskID]
+	from := m.thresholdLocked(taskID)
+	if !listed || m.threshold.Step <= 0 || from <= m.threshold.Floor {
+		return false
+	}
+	to := max(from-m.threshold.Step, m.threshold.Floor)
+	m.thresholds[taskID] = to
+	m.relaxations[taskID] = append(m.relaxations[taskID], Relaxation{From: from, To: to, At: m.nowLocked()})
+	if strategy.Sealed() {
+		m.sealed[taskID] = true
+	}
+	m.logger.Info("bid threshold relaxed", "task", taskID, "from", from, "to", to)
+	return true
+}
+
+// Eligibility reports why an agent may not bid on a task at its current
+// threshold, or "" if it may, along with its capability match
+func (m *TaskMarket) Eligibility(a *agent.Agent, task *agent.Task) (identity.MatchBreakdown, string) {
+	return CheckEligibilityAt(a, task, m.Threshold(task.ID), m.clock())
+}
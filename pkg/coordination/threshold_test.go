@@ -0,0 +1,97 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func TestBidThreshold_Validate(t *testing.T) {
+	tests := []struct {
+		threshold BidThreshold
+		valid     bool
+	}{
+		{BidThreshold{}, true},
+		{BidThreshold{Min: 0.6, Step: 0.1, Floor: 0.4}, true},
+		{BidThreshold{Min: 0.2}, true}, // Floor defaults to the minimum
+		{BidThreshold{Min: 1.5}, false},
+		{BidThreshold{Step: -0.1}, false},
+		{BidThreshold{Min: 0.4, Floor: 0.45}, false},
+	}
+	for _, tt := range tests {
+		err := tt.threshold.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", tt.threshold, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidThreshold) {
+			t.Errorf("Expected ErrInvalidThreshold for %+v, got %v", tt.threshold, err)
+		}
+	}
+
+	m := NewTaskMarket()
+	defer m.Close()
+	if err := m.SetBidThreshold(BidThreshold{Min: 2}); !errors.Is(err, ErrInvalidThreshold) {
+		t.Errorf("Expected ErrInvalidThreshold, got %v", err)
+	}
+	if got := m.BidThreshold(); got.Min != MinCapabilityScore || got.Floor != DefaultBidFloor {
+		t.Errorf("Expected the default threshold to be kept, got %+v", got)
+	}
+}
+
+func TestTaskMarket_RelaxThreshold(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Holding one of two required capabilities at 0.5 matches 0.25
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = coder.Start(ctx)
+	defer coder.Stop()
+	agents := map[string]*agent.Agent{coder.Identity.SID: coder}
+	required := []identity.CapabilityType{identity.CapCodeWrite, identity.CapTesting}
+
+	m := NewTaskMarket()
+	defer m.Close()
+	m.SetBidTimeout(time.Millisecond)
+
+	task := agent.NewTask("fixed threshold", required)
+	if _, err := m.AssignTask(task, agents, NewReputationRegistry()); !errors.Is(err, ErrNoBids) {
+		t.Errorf("Expected ErrNoBids without relaxation, got %v", err)
+	}
+	if r := m.Relaxations(task.ID); len(r) != 0 {
+		t.Errorf("Expected no relaxations, got %+v", r)
+	}
+
+	if err := m.SetBidThreshold(BidThreshold{Min: 0.5, Step: 0.2, Floor: 0.2}); err != nil {
+		t.Fatalf("SetBidThreshold failed: %v", err)
+	}
+	task = agent.NewTask("relaxed threshold", required)
+	assignment, err := m.AssignTask(task, agents, NewReputationRegistry())
+	if err != nil {
+		t.Fatalf("AssignTask failed: %v", err)
+	}
+	if assignment.AgentSID != coder.Identity.SID || assignment.Threshold != 0.2 {
+		t.Errorf("Expected the coder to win at threshold 0.2, got %s at %.2f", assignment.AgentSID, assignment.Threshold)
+	}
+
+	relaxed := m.Relaxations(task.ID)
+	if len(relaxed) != 2 {
+		t.Fatalf("Expected 2 relaxations, got %+v", relaxed)
+	}
+	if relaxed[0].From != 0.5 || math.Abs(relaxed[0].To-0.3) > 1e-9 || relaxed[1].To != 0.2 {
+		t.Errorf("Expected 0.5 -> 0.3 -> 0.2, got %+v", relaxed)
+	}
+
+	// The floor stops relaxation
+	task = agent.NewTask("out of reach", []identity.CapabilityType{identity.CapSecurity})
+	if _, err := m.AssignTask(task, agents, NewReputationRegistry()); !errors.Is(err, ErrNoBids) {
+		t.Errorf("Expected ErrNoBids below the floor, got %v", err)
+	}
+	if r := m.Relaxations(task.ID); len(r) != 2 {
+		t.Errorf("Expected relaxation to stop at the floor, got %+v", r)
+	}
+}
@@ -0,0 +1,271 @@
+package coordination
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrUnknownTieBreaker   = errors.New("unknown tie-breaker")
+	ErrInvalidBidSignature = errors.New("bid signature does not verify")
+)
+
+// Tie-break rules a TieBreaker may decide by, beyond the TieBreakCapability
+// and TieBreakAgentSID rules every ranking falls back to
+const (
+	TieBreakLeastRecent = "least_recently_assigned"
+	TieBreakLowestCost  = "lowest_cost"
+	TieBreakLottery     = "lottery"
+)
+
+// TieContext is what tie-breakers decide between bids on a task by
+type TieContext struct {
+	TaskID       string
+	LastAssigned map[string]time.Time // Agent SID -> when it was last assigned a task
+	Seed         []byte               // Lottery seed, from LotterySeed
+}
+
+// TieBreaker orders bids their auction strategy ranks level. Bids it leaves
+// level go to the higher capability score, then the agent SID first in order.
+type TieBreaker interface {
+	// Name returns the tie-breaker's name, one of the TieBreak constants
+	Name() string
+
+	// Compare orders a before b (-1) or after it (1), or 0 to leave them level
+	Compare(tc *TieContext, a, b *BidScore) int
+}
+
+// NewTieBreaker returns the named tie-breaker ("" = TieBreakCapability)
+func NewTieBreaker(name string) (TieBreaker, error) {
+	switch name {
+	case "", TieBreakCapability:
+		return capabilityTieBreaker{}, nil
+	case TieBreakLeastRecent:
+		return leastRecentTieBreaker{}, nil
+	case TieBreakLowestCost:
+		return lowestCostTieBreaker{}, nil
+	case TieBreakLottery:
+		return lotteryTieBreaker{}, nil
+	}
+	return nil, fmt.Errorf("%w: %q (want %s, %s, %s or %s)", ErrUnknownTieBreaker, name, TieBreakCapability, TieBreakLeastRecent, TieBreakLowestCost, TieBreakLottery)
+}
+
+// capabilityTieBreaker leaves ties to the higher capability score (default)
+type capabilityTieBreaker struct{}
+
+func (capabilityTieBreaker) Name() string { return TieBreakCapability }
+
+func (capabilityTieBreaker) Compare(_ *TieContext, a, b *BidScore) int {
+	return compareFloat(b.Bid.CapabilityScore, a.Bid.CapabilityScore)
+}
+
+// leastRecentTieBreaker favours the bidder assigned a task longest ago; one
+// never assigned comes first
+type leastRecentTieBreaker struct{}
+
+func (leastRecentTieBreaker) Name() string { return TieBreakLeastRecent }
+
+func (leastRecentTieBreaker) Compare(tc *TieContext, a, b *BidScore) int {
+	at, bt := tc.LastAssigned[a.Bid.AgentSID], tc.LastAssigned[b.Bid.AgentSID]
+	return at.Compare(bt)
+}
+
+// lowestCostTieBreaker favours the bid promising the shortest estimated
+// time, then the one staking less reputation
+type lowestCostTieBreaker struct{}
+
+func (lowestCostTieBreaker) Name() string { return TieBreakLowestCost }
+
+func (lowestCostTieBreaker) Compare(_ *TieContext, a, b *BidScore) int {
+	if a.Bid.EstimatedTime != b.Bid.EstimatedTime {
+		if a.Bid.EstimatedTime < b.Bid.EstimatedTime {
+			return -1
+		}
+		return 1
+	}
+	return compareFloat(a.Bid.ReputationStake, b.Bid.ReputationStake)
+}
+
+// lotteryTieBreaker draws among tied bids by their LotteryTicket, lowest
+// first. Anyone holding the task's bids can recompute the draw.
+type lotteryTieBreaker struct{}
+
+func (lotteryTieBreaker) Name() string { return TieBreakLottery }
+
+func (lotteryTieBreaker) Compare(tc *TieContext, a, b *BidScore) int {
+	return bytes.Compare(LotteryTicket(tc.Seed, a.Bid), LotteryTicket(tc.Seed, b.Bid))
+}
+
+// Digest returns the hash a bid is signed over: its bidder, task, capability
+// score, stake, estimated time, hint and load. The timestamp the market
+// stamps on arrival is left out.
+func (b *Bid) Digest() []byte {
+	h := sha256.New()
+	for _, field := range []string{
+		b.AgentSID,
+		b.TaskID,
+		strconv.FormatFloat(b.CapabilityScore, 'g', -1, 64),
+		strconv.FormatFloat(b.ReputationStake, 'g', -1, 64),
+		strconv.FormatInt(int64(b.EstimatedTime), 10),
+		strconv.FormatFloat(b.Hint, 'g', -1, 64),
+	} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	// Only busy bidders' load is signed, so idle bids digest as they always have
+	if b.Load > 0 {
+		h.Write([]byte(strconv.FormatFloat(b.Load, 'g', -1, 64)))
+		h.Write([]byte{0})
+	}
+	return h.Sum(nil)
+}
+
+// lotteryEntry is what a bid contributes to a lottery: its signature, or
+// its digest if it is unsigned
+func lotteryEntry(bid *Bid) []byte {
+	if len(bid.Signature) > 0 {
+		return bid.Signature
+	}
+	return bid.Digest()
+}
+
+// LotterySeed derives a task's lottery seed from every bid on it, so no
+// bidder can know the draw before bidding closes
+func LotterySeed(taskID string, bids []*Bid) []byte {
+	entries := make([][]byte, len(bids))
+	for i, bid := range bids {
+		entries[i] = lotteryEntry(bid)
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i], entries[j]) < 0 })
+
+	h := sha256.New()
+	h.Write([]byte(taskID))
+	for _, e := range entries {
+		h.Write(e)
+	}
+	return h.Sum(nil)
+}
+
+// LotteryTicket returns a bid's ticket in the lottery drawn with seed; the
+// lowest ticket wins
+func LotteryTicket(seed []byte, bid *Bid) []byte {
+	h := sha256.New()
+	h.Write(seed)
+	h.Write(lotteryEntry(bid))
+	return h.Sum(nil)
+}
+
+// Tied reports whether a strategy ranks two bids level, leaving the order
+// between them to the tie-breakers
+func Tied(strategy AuctionStrategy, a, b *BidScore) bool {
+	if strategy.Name() == AuctionReverse && a.Bid.EstimatedTime != b.Bid.EstimatedTime {
+		return false
+	}
+	return a.Score == b.Score
+}
+
+// BreakTies reorders each run of bids ranked level by strategy with tb,
+// falling back to the capability score and agent SID
+func BreakTies(strategy AuctionStrategy, tb TieBreaker, tc *TieContext, ranked []BidScore) {
+	for i := 0; i < len(ranked); {
+		j := i + 1
+		for j < len(ranked) && Tied(strategy, &ranked[i], &ranked[j]) {
+			j++
+		}
+		run := ranked[i:j]
+		sort.SliceStable(run, func(x, y int) bool {
+			if c := tb.Compare(tc, &run[x], &run[y]); c != 0 {
+				return c < 0
+			}
+			return compareBidScores(&run[x], &run[y]) < 0
+		})
+		i = j
+	}
+}
+
+// TieBreakBy returns the rule that ordered two bids ranked level by their
+// strategy: tb's name if it told them apart, otherwise as TieBreak
+func TieBreakBy(tb TieBreaker, tc *TieContext, a, b *BidScore) string {
+	if a.Score != b.Score {
+		return ""
+	}
+	if tb.Compare(tc, a, b) != 0 {
+		return tb.Name()
+	}
+	return TieBreak(a, b)
+}
+
+// compareFloat orders a before b (-1) if it is smaller
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// SetTieBreaker replaces the tie-breaker bids ranked level are ordered by
+func (m *TaskMarket) SetTieBreaker(tb TieBreaker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ties = tb
+}
+
+// TieBreaker returns the market's tie-breaker
+func (m *TaskMarket) TieBreaker() TieBreaker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ties
+}
+
+// NoteAssignment records that an agent was assigned a task, for the
+// least-recently-assigned tie-breaker. AssignTask leaves this to its caller,
+// which may pass the winner over.
+func (m *TaskMarket) NoteAssignment(agentSID string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.assigned[agentSID] = at
+}
+
+// TieContext returns what the market's tie-breaker decides between the bids
+// on a task by
+func (m *TaskMarket) TieContext(taskID string) *TieContext {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tieContextLocked(taskID)
+}
+
+// tieContextLocked builds the tie context of a task. Caller must hold m.mu.
+func (m *TaskMarket) tieContextLocked(taskID string) *TieContext {
+	last := make(map[string]time.Time, len(m.assigned))
+	for sid, at := range m.assigned {
+		last[sid] = at
+	}
+	return &TieContext{TaskID: taskID, LastAssigned: last, Seed: LotterySeed(taskID, m.bids[taskID])}
+}
+
+// verifyBidSignature checks a signed bid against its bidder's key, if the
+// market can look the bidder up
+func (m *TaskMarket) verifyBidSignature(bid *Bid) error {
+	m.mu.RLock()
+	lookup := m.lookup
+	m.mu.RUnlock()
+	if len(bid.Signature) == 0 || lookup == nil {
+		return nil
+	}
+	bidder, ok := lookup(bid.AgentSID)
+	if !ok {
+		return nil
+	}
+	if !bidder.Identity.Verify(bid.Digest(), bid.Signature) {
+		return fmt.Errorf("%w: %s", ErrInvalidBidSignature, bid.AgentSID)
+	}
+	return nil
+}
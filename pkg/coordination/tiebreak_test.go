@@ -0,0 +1,149 @@
+package coordination
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func TestNewTieBreaker(t *testing.T) {
+	tests := map[string]string{
+		"":                  TieBreakCapability,
+		TieBreakCapability:  TieBreakCapability,
+		TieBreakLeastRecent: TieBreakLeastRecent,
+		TieBreakLowestCost:  TieBreakLowestCost,
+		TieBreakLottery:     TieBreakLottery,
+	}
+	for name, want := range tests {
+		tb, err := NewTieBreaker(name)
+		if err != nil {
+			t.Fatalf("NewTieBreaker(%q) failed: %v", name, err)
+		}
+		if tb.Name() != want {
+			t.Errorf("Expected %s for %q, got %s", want, name, tb.Name())
+		}
+	}
+	if _, err := NewTieBreaker("coin_flip"); !errors.Is(err, ErrUnknownTieBreaker) {
+		t.Errorf("Expected ErrUnknownTieBreaker, got %v", err)
+	}
+}
+
+// tiedMarket lists a task and submits equally scored bids from sids, the
+// n-th estimating n+1 minutes
+func tiedMarket(t *testing.T, tieBreak string, sids ...string) (*TaskMarket, *agent.Task) {
+	t.Helper()
+	tb, err := NewTieBreaker(tieBreak)
+	if err != nil {
+		t.Fatalf("NewTieBreaker failed: %v", err)
+	}
+	m := NewTaskMarket()
+	t.Cleanup(m.Close)
+	m.SetTieBreaker(tb)
+
+	task := agent.NewTask("tie me", nil)
+	if err := m.ListTask(task); err != nil {
+		t.Fatalf("ListTask failed: %v", err)
+	}
+	for i, sid := range sids {
+		_ = m.SubmitBid(&Bid{AgentSID: sid, TaskID: task.ID, CapabilityScore: 0.8, ReputationStake: 5, EstimatedTime: time.Duration(i+1) * time.Minute})
+	}
+	return m, task
+}
+
+func TestTaskMarket_TieBreakers(t *testing.T) {
+	reputation := NewReputationRegistry()
+
+	m, task := tiedMarket(t, TieBreakCapability, "sq-c", "sq-a", "sq-b")
+	if ranked, _ := m.RankBids(task.ID, reputation); ranked[0].AgentSID != "sq-a" {
+		t.Errorf("Expected the default to fall back to SID order, got %s", ranked[0].AgentSID)
+	}
+
+	m, task = tiedMarket(t, TieBreakLowestCost, "sq-c", "sq-a", "sq-b")
+	if ranked, _ := m.RankBids(task.ID, reputation); ranked[0].AgentSID != "sq-c" {
+		t.Errorf("Expected the fastest bid to win, got %s", ranked[0].AgentSID)
+	}
+
+	m, task = tiedMarket(t, TieBreakLeastRecent, "sq-a", "sq-b", "sq-c")
+	now := time.Now()
+	m.NoteAssignment("sq-a", now)
+	m.NoteAssignment("sq-c", now.Add(-time.Hour))
+	ranked, _ := m.RankBids(task.ID, reputation)
+	for i, want := range []string{"sq-b", "sq-c", "sq-a"} {
+		if ranked[i].AgentSID != want {
+			t.Errorf("Expected %s at rank %d, got %s", want, i+1, ranked[i].AgentSID)
+		}
+	}
+
+	scores, _ := m.ScoreBids(task.ID, reputation)
+	tc := m.TieContext(task.ID)
+	if rule := TieBreakBy(m.TieBreaker(), tc, &scores[0], &scores[1]); rule != TieBreakLeastRecent {
+		t.Errorf("Expected the tie-break rule %s, got %q", TieBreakLeastRecent, rule)
+	}
+}
+
+func TestTaskMarket_Lottery(t *testing.T) {
+	m, task := tiedMarket(t, TieBreakLottery, "sq-a", "sq-b", "sq-c", "sq-d")
+	ranked, err := m.RankBids(task.ID, NewReputationRegistry())
+	if err != nil {
+		t.Fatalf("RankBids failed: %v", err)
+	}
+
+	// Anyone holding the bids can recompute the draw
+	bids := m.GetBids(task.ID)
+	seed := LotterySeed(task.ID, bids)
+	var winner *Bid
+	for _, bid := range bids {
+		if winner == nil || bytes.Compare(LotteryTicket(seed, bid), LotteryTicket(seed, winner)) < 0 {
+			winner = bid
+		}
+	}
+	if ranked[0].AgentSID != winner.AgentSID {
+		t.Errorf("Expected the lowest ticket, %s, to win, got %s", winner.AgentSID, ranked[0].AgentSID)
+	}
+
+	// The draw doesn't depend on the order bids arrived in
+	reversed := make([]*Bid, len(bids))
+	for i, bid := range bids {
+		reversed[len(bids)-1-i] = bid
+	}
+	if !bytes.Equal(LotterySeed(task.ID, reversed), seed) {
+		t.Error("Expected the lottery seed not to depend on bid order")
+	}
+	if bytes.Equal(LotterySeed("another task", bids), seed) {
+		t.Error("Expected each task to draw with its own seed")
+	}
+}
+
+func TestTaskMarket_BidSignature(t *testing.T) {
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Bidder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = a.Start(context.Background())
+	defer a.Stop()
+	m := NewTaskMarket()
+	defer m.Close()
+	m.SetAgentLookup(func(sid string) (*agent.Agent, bool) {
+		return a, sid == a.Identity.SID
+	})
+
+	task := agent.NewTask("sign me", []identity.CapabilityType{identity.CapCodeWrite})
+	if err := m.ListTask(task); err != nil {
+		t.Fatalf("ListTask failed: %v", err)
+	}
+	bid := m.MakeBid(a, task)
+	if bid == nil || !a.Identity.Verify(bid.Digest(), bid.Signature) {
+		t.Fatalf("Expected a signed bid, got %+v", bid)
+	}
+
+	forged := *bid
+	forged.ReputationStake = 50
+	if err := m.SubmitBid(&forged); !errors.Is(err, ErrInvalidBidSignature) {
+		t.Errorf("Expected ErrInvalidBidSignature, got %v", err)
+	}
+	if err := m.SubmitBid(bid); err != nil {
+		t.Errorf("Expected the signed bid to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,469 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrKafka is returned when a Kafka broker rejects a request
+var ErrKafka = errors.New("kafka error")
+
+// Kafka API keys and the versions spoken
+const (
+	kafkaProduce        = 0
+	kafkaProduceVersion = 3 // First version taking record batches
+	kafkaMetadata       = 3
+	kafkaMetadataVer    = 4 // First version with allow_auto_topic_creation
+)
+
+// Kafka error codes the producer recovers from by refreshing metadata
+const (
+	kafkaUnknownTopic       = 3
+	kafkaLeaderNotAvailable = 5
+	kafkaNotLeader          = 6
+)
+
+// kafkaTopicName matches the names Kafka accepts
+var kafkaTopicName = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// castagnoli is the CRC-32C table record batches are checksummed with
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Kafka produces to a Kafka cluster with acks from the partition leader.
+// Messages are partitioned by a hash of their key; keyless messages go to
+// partition 0.
+type Kafka struct {
+	mu sync.Mutex
+
+	bootstrap   []string
+	brokers     map[int32]string      // Broker ID -> host:port
+	leaders     map[string][]int32    // Topic -> leader broker ID by partition
+	conns       map[string]*kafkaConn // host:port -> connection
+	correlation int32
+}
+
+// NewKafka creates a Kafka sink that discovers the cluster from the
+// bootstrap brokers
+func NewKafka(bootstrap []string) *Kafka {
+	return &Kafka{
+		bootstrap: bootstrap,
+		brokers:   make(map[int32]string),
+		leaders:   make(map[string][]int32),
+		conns:     make(map[string]*kafkaConn),
+	}
+}
+
+// Publish produces value with key to topic, retrying once after
+// refreshing the cluster metadata if the leader moved or a connection broke
+func (k *Kafka) Publish(ctx context.Context, topic string, key, value []byte) error {
+	if !kafkaTopicName.MatchString(topic) {
+		return fmt.Errorf("%w: invalid topic %q", ErrKafka, topic)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	batch := encodeRecordBatch(key, value, time.Now())
+	for attempt := 0; ; attempt++ {
+		err := k.produce(ctx, topic, key, batch)
+		if err == nil {
+			return nil
+		}
+		var kerr *kafkaError
+		retriable := !errors.As(err, &kerr) || kerr.retriable()
+		if attempt > 0 || !retriable || ctx.Err() != nil {
+			return err
+		}
+		delete(k.leaders, topic)
+	}
+}
+
+// Close closes the broker connections
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var errs []error
+	for addr, conn := range k.conns {
+		errs = append(errs, conn.Close())
+		delete(k.conns, addr)
+	}
+	return errors.Join(errs...)
+}
+
+// produce sends one batch to the leader of key's partition
+func (k *Kafka) produce(ctx context.Context, topic string, key, batch []byte) error {
+	leaders, err := k.partitions(ctx, topic)
+	if err != nil {
+		return err
+	}
+	partition := int32(0)
+	if len(key) > 0 {
+		h := fnv.New32a()
+		h.Write(key)
+		partition = int32(h.Sum32() % uint32(len(leaders)))
+	}
+	addr, ok := k.brokers[leaders[partition]]
+	if !ok {
+		return &kafkaError{code: kafkaLeaderNotAvailable, topic: topic}
+	}
+
+	var req kafkaEncoder
+	req.putInt16(-1) // No transactional ID
+	req.putInt16(1)  // acks: leader
+	req.putInt32(int32(kafkaTimeout(ctx) / time.Millisecond))
+	req.putInt32(1)
+	req.putString(topic)
+	req.putInt32(1)
+	req.putInt32(partition)
+	req.putBytes(batch)
+
+	resp, err := k.request(ctx, addr, kafkaProduce, kafkaProduceVersion, req.buf)
+	if err != nil {
+		return err
+	}
+	d := kafkaDecoder{buf: resp}
+	for topics := d.int32(); topics > 0; topics-- {
+		name := d.string()
+		for parts := d.int32(); parts > 0; parts-- {
+			d.int32() // Partition
+			if code := d.int16(); code != 0 && d.err == nil {
+				return &kafkaError{code: code, topic: name}
+			}
+			d.int64() // Base offset
+			d.int64() // Log append time
+		}
+	}
+	return d.err
+}
+
+// partitions returns the leader of each of a topic's partitions, fetching
+// the cluster metadata if it isn't known. Brokers create a missing topic.
+func (k *Kafka) partitions(ctx context.Context, topic string) ([]int32, error) {
+	if leaders := k.leaders[topic]; len(leaders) > 0 {
+		return leaders, nil
+	}
+
+	var req kafkaEncoder
+	req.putInt32(1)
+	req.putString(topic)
+	req.putBool(true) // allow_auto_topic_creation
+
+	var errs []error
+	for _, addr := range k.bootstrap {
+		resp, err := k.request(ctx, addr, kafkaMetadata, kafkaMetadataVer, req.buf)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			continue
+		}
+		if err := k.readMetadata(resp); err != nil {
+			return nil, err
+		}
+		leaders := k.leaders[topic]
+		if len(leaders) == 0 {
+			return nil, &kafkaError{code: kafkaLeaderNotAvailable, topic: topic}
+		}
+		return leaders, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// readMetadata records the brokers and partition leaders of a Metadata
+// response
+func (k *Kafka) readMetadata(resp []byte) error {
+	d := kafkaDecoder{buf: resp}
+	d.int32() // Throttle time
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.nullableString() // Rack
+		k.brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.nullableString() // Cluster ID
+	d.int32()          // Controller ID
+
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		code := d.int16()
+		name := d.string()
+		d.bool() // Internal
+		var leaders []int32
+		for p := d.int32(); p > 0 && d.err == nil; p-- {
+			d.int16() // Partition error
+			index := d.int32()
+			leader := d.int32()
+			d.int32Array() // Replicas
+			d.int32Array() // In-sync replicas
+			if index < 0 {
+				continue
+			}
+			for int(index) >= len(leaders) {
+				leaders = append(leaders, -1)
+			}
+			leaders[index] = leader
+		}
+		if d.err != nil {
+			break
+		}
+		if code != 0 {
+			return &kafkaError{code: code, topic: name}
+		}
+		k.leaders[name] = leaders
+	}
+	return d.err
+}
+
+// request sends a request to a broker and returns the response body
+func (k *Kafka) request(ctx context.Context, addr string, apiKey, version int16, body []byte) ([]byte, error) {
+	conn, err := k.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	k.correlation++
+	resp, err := conn.roundTrip(ctx, apiKey, version, k.correlation, body)
+	if err != nil {
+		conn.Close()
+		delete(k.conns, addr)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// conn returns the connection to a broker, dialing it if needed
+func (k *Kafka) conn(ctx context.Context, addr string) (*kafkaConn, error) {
+	if conn, ok := k.conns[addr]; ok {
+		return conn, nil
+	}
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := &kafkaConn{Conn: c, r: bufio.NewReader(c)}
+	k.conns[addr] = conn
+	return conn, nil
+}
+
+// kafkaTimeout returns how long the broker may take to acknowledge
+func kafkaTimeout(ctx context.Context) time.Duration {
+	if deadline, ok := ctx.Deadline(); ok {
+		if d := time.Until(deadline); d > 0 {
+			return d
+		}
+	}
+	return 10 * time.Second
+}
+
+// kafkaError is an error code returned by a broker
+type kafkaError struct {
+	code  int16
+	topic string
+}
+
+func (e *kafkaError) Error() string {
+	return fmt.Sprintf("%v: topic %s: error code %d", ErrKafka, e.topic, e.code)
+}
+
+func (e *kafkaError) Unwrap() error { return ErrKafka }
+
+// retriable reports whether fresh metadata may fix the error
+func (e *kafkaError) retriable() bool {
+	switch e.code {
+	case kafkaUnknownTopic, kafkaLeaderNotAvailable, kafkaNotLeader:
+		return true
+	}
+	return false
+}
+
+// kafkaConn is a connection to one broker
+type kafkaConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// roundTrip writes a request with a v1 header and reads its response
+func (c *kafkaConn) roundTrip(ctx context.Context, apiKey, version int16, correlation int32, body []byte) ([]byte, error) {
+	_ = c.SetDeadline(time.Now().Add(kafkaTimeout(ctx)))
+
+	var req kafkaEncoder
+	req.putInt32(0) // Size, filled in below
+	req.putInt16(apiKey)
+	req.putInt16(version)
+	req.putInt32(correlation)
+	req.putString("squaremind")
+	req.buf = append(req.buf, body...)
+	binary.BigEndian.PutUint32(req.buf, uint32(len(req.buf)-4))
+	if _, err := c.Write(req.buf); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > 64<<20 {
+		return nil, fmt.Errorf("%w: bad response size %d", ErrKafka, size)
+	}
+	if got := int32(binary.BigEndian.Uint32(header[4:])); got != correlation {
+		return nil, fmt.Errorf("%w: response %d to request %d", ErrKafka, got, correlation)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// encodeRecordBatch encodes a record batch (magic 2) holding one record
+func encodeRecordBatch(key, value []byte, at time.Time) []byte {
+	var rec kafkaEncoder
+	rec.buf = append(rec.buf, 0) // Attributes
+	rec.putVarint(0)             // Timestamp delta
+	rec.putVarint(0)             // Offset delta
+	rec.putVarbytes(key)
+	rec.putVarbytes(value)
+	rec.putVarint(0) // Headers
+
+	ts := at.UnixMilli()
+	var b kafkaEncoder
+	b.putInt64(0)  // Base offset
+	b.putInt32(0)  // Length, filled in below
+	b.putInt32(-1) // Partition leader epoch
+	b.buf = append(b.buf, 2)
+	b.putInt32(0) // CRC, filled in below
+	crcStart := len(b.buf)
+	b.putInt16(0) // Attributes: no compression
+	b.putInt32(0) // Last offset delta
+	b.putInt64(ts)
+	b.putInt64(ts)
+	b.putInt64(-1) // Producer ID
+	b.putInt16(-1) // Producer epoch
+	b.putInt32(-1) // Base sequence
+	b.putInt32(1)
+	b.putVarint(int64(len(rec.buf)))
+	b.buf = append(b.buf, rec.buf...)
+
+	binary.BigEndian.PutUint32(b.buf[8:], uint32(len(b.buf)-12))
+	binary.BigEndian.PutUint32(b.buf[crcStart-4:], crc32.Checksum(b.buf[crcStart:], castagnoli))
+	return b.buf
+}
+
+// kafkaEncoder appends Kafka protocol primitives to a buffer
+type kafkaEncoder struct {
+	buf []byte
+}
+
+func (e *kafkaEncoder) putInt16(v int16)  { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *kafkaEncoder) putInt32(v int32)  { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *kafkaEncoder) putInt64(v int64)  { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+func (e *kafkaEncoder) putVarint(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+func (e *kafkaEncoder) putBool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *kafkaEncoder) putString(s string) {
+	e.putInt16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *kafkaEncoder) putBytes(b []byte) {
+	e.putInt32(int32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// putVarbytes writes a record key or value; nil is the null value
+func (e *kafkaEncoder) putVarbytes(b []byte) {
+	if b == nil {
+		e.putVarint(-1)
+		return
+	}
+	e.putVarint(int64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// kafkaDecoder reads Kafka protocol primitives, recording the first error
+type kafkaDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *kafkaDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.err = fmt.Errorf("%w: truncated response", ErrKafka)
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (d *kafkaDecoder) bool() bool {
+	b := d.next(1)
+	return b != nil && b[0] != 0
+}
+
+func (d *kafkaDecoder) string() string {
+	return string(d.next(int(d.int16())))
+}
+
+func (d *kafkaDecoder) nullableString() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *kafkaDecoder) int32Array() []int32 {
+	n := d.int32()
+	if n < 0 || int(n) > len(d.buf)/4 {
+		if n > 0 {
+			d.next(-1)
+		}
+		return nil
+	}
+	out := make([]int32, n)
+	for i := range out {
+		out[i] = d.int32()
+	}
+	return out
+}
@@ -0,0 +1,249 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKafka is a single Kafka broker leading every partition of every topic.
+// It answers Metadata v4 and Produce v3 and records the records produced.
+type fakeKafka struct {
+	mu sync.Mutex
+
+	ln         net.Listener
+	partitions int
+	notReady   int // Metadata requests to answer with LEADER_NOT_AVAILABLE
+	records    []fakeRecord
+	errs       []string
+}
+
+type fakeRecord struct {
+	topic      string
+	partition  int32
+	key, value string
+}
+
+func newFakeKafka(t *testing.T, partitions int) *fakeKafka {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	k := &fakeKafka{ln: ln, partitions: partitions}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go k.serve(conn)
+		}
+	}()
+	return k
+}
+
+func (k *fakeKafka) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, req); err != nil {
+			return
+		}
+		d := kafkaDecoder{buf: req}
+		apiKey, version, correlation := d.int16(), d.int16(), d.int32()
+		d.string() // Client ID
+
+		var resp kafkaEncoder
+		resp.putInt32(0)
+		resp.putInt32(correlation)
+		switch {
+		case apiKey == kafkaMetadata && version == kafkaMetadataVer:
+			k.metadata(&d, &resp)
+		case apiKey == kafkaProduce && version == kafkaProduceVersion:
+			k.produce(&d, &resp)
+		default:
+			k.fail("unexpected request " + strconv.Itoa(int(apiKey)) + " v" + strconv.Itoa(int(version)))
+			return
+		}
+		binary.BigEndian.PutUint32(resp.buf, uint32(len(resp.buf)-4))
+		if _, err := conn.Write(resp.buf); err != nil {
+			return
+		}
+	}
+}
+
+func (k *fakeKafka) metadata(d *kafkaDecoder, resp *kafkaEncoder) {
+	d.int32()
+	topic := d.string()
+	if !d.bool() {
+		k.fail("expected allow_auto_topic_creation")
+	}
+
+	k.mu.Lock()
+	code := int16(0)
+	if k.notReady > 0 {
+		k.notReady--
+		code = kafkaLeaderNotAvailable
+	}
+	k.mu.Unlock()
+
+	host, port, _ := net.SplitHostPort(k.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	resp.putInt32(0) // Throttle time
+	resp.putInt32(1)
+	resp.putInt32(1)
+	resp.putString(host)
+	resp.putInt32(int32(p))
+	resp.putInt16(-1) // Rack
+	resp.putInt16(-1) // Cluster ID
+	resp.putInt32(1)  // Controller
+	resp.putInt32(1)
+	resp.putInt16(code)
+	resp.putString(topic)
+	resp.putBool(false)
+	if code != 0 {
+		resp.putInt32(0)
+		return
+	}
+	resp.putInt32(int32(k.partitions))
+	for i := 0; i < k.partitions; i++ {
+		resp.putInt16(0)
+		resp.putInt32(int32(i))
+		resp.putInt32(1) // Leader
+		resp.putInt32(1)
+		resp.putInt32(1) // Replicas
+		resp.putInt32(1)
+		resp.putInt32(1) // ISR
+	}
+}
+
+func (k *fakeKafka) produce(d *kafkaDecoder, resp *kafkaEncoder) {
+	d.nullableString() // Transactional ID
+	if acks := d.int16(); acks != 1 {
+		k.fail("expected acks=1")
+	}
+	d.int32() // Timeout
+	d.int32()
+	topic := d.string()
+	d.int32()
+	partition := d.int32()
+	batch := d.next(int(d.int32()))
+	if d.err != nil {
+		k.fail(d.err.Error())
+		return
+	}
+	key, value, ok := decodeRecordBatch(batch)
+	if !ok {
+		k.fail("bad record batch")
+	}
+	k.mu.Lock()
+	k.records = append(k.records, fakeRecord{topic, partition, key, value})
+	k.mu.Unlock()
+
+	resp.putInt32(1)
+	resp.putString(topic)
+	resp.putInt32(1)
+	resp.putInt32(partition)
+	resp.putInt16(0)
+	resp.putInt64(0)  // Base offset
+	resp.putInt64(-1) // Log append time
+	resp.putInt32(0)  // Throttle time
+}
+
+func (k *fakeKafka) fail(msg string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.errs = append(k.errs, msg)
+}
+
+// decodeRecordBatch checks a batch's length and CRC and returns its record
+func decodeRecordBatch(b []byte) (key, value string, ok bool) {
+	if len(b) < 61 || b[16] != 2 {
+		return "", "", false
+	}
+	if int(binary.BigEndian.Uint32(b[8:])) != len(b)-12 {
+		return "", "", false
+	}
+	if binary.BigEndian.Uint32(b[17:]) != crc32.Checksum(b[21:], castagnoli) {
+		return "", "", false
+	}
+	if binary.BigEndian.Uint32(b[57:]) != 1 {
+		return "", "", false
+	}
+	rec := b[61:]
+	varint := func() int64 {
+		v, n := binary.Varint(rec)
+		rec = rec[n:]
+		return v
+	}
+	varint()      // Length
+	rec = rec[1:] // Attributes
+	varint()      // Timestamp delta
+	varint()      // Offset delta
+	n := varint()
+	if n >= 0 {
+		key, rec = string(rec[:n]), rec[n:]
+	}
+	n = varint()
+	value = string(rec[:n])
+	return key, value, true
+}
+
+func TestKafka_Publish(t *testing.T) {
+	broker := newFakeKafka(t, 4)
+	broker.notReady = 1 // The topic is being created
+	k := NewKafka([]string{broker.ln.Addr().String()})
+	defer k.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		if err := k.Publish(ctx, "squaremind.events", []byte("task-1"), []byte(`{"n":`+strconv.Itoa(i)+`}`)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	if err := k.Publish(ctx, "squaremind.events", nil, []byte("keyless")); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.errs) > 0 {
+		t.Fatalf("Expected valid requests, got %v", broker.errs)
+	}
+	if len(broker.records) != 4 {
+		t.Fatalf("Expected 4 records, got %d", len(broker.records))
+	}
+	first := broker.records[0]
+	if first.topic != "squaremind.events" || first.key != "task-1" || first.value != `{"n":0}` {
+		t.Errorf("Expected the record produced, got %+v", first)
+	}
+	for _, r := range broker.records[1:3] {
+		if r.partition != first.partition {
+			t.Errorf("Expected records with the same key on partition %d, got %d", first.partition, r.partition)
+		}
+	}
+	if last := broker.records[3]; last.partition != 0 || last.key != "" {
+		t.Errorf("Expected a keyless record on partition 0, got %+v", last)
+	}
+}
+
+func TestKafka_InvalidTopic(t *testing.T) {
+	k := NewKafka([]string{"127.0.0.1:1"})
+	if err := k.Publish(context.Background(), "bad topic", nil, []byte("x")); err == nil {
+		t.Error("Expected an error for a topic with spaces")
+	}
+}
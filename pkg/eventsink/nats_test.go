@@ -0,0 +1,163 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNATS is a NATS server that records the messages published to it
+type fakeNATS struct {
+	mu sync.Mutex
+
+	ln       net.Listener
+	connects []string
+	messages map[string][]string
+	token    string
+}
+
+func newFakeNATS(t *testing.T, token string) *fakeNATS {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s := &fakeNATS{ln: ln, messages: make(map[string][]string), token: token}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"fake\",\"max_payload\":1048576,\"auth_required\":%t}\r\n", s.token != "")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		op, args, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch op {
+		case "CONNECT":
+			s.mu.Lock()
+			s.connects = append(s.connects, args)
+			s.mu.Unlock()
+			if s.token != "" && !strings.Contains(args, `"auth_token":"`+s.token+`"`) {
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "PUB":
+			fields := strings.Fields(args)
+			n, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages[fields[0]] = append(s.messages[fields[0]], string(payload[:n]))
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *fakeNATS) received(subject string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages[subject]...)
+}
+
+// waitFor polls until cond holds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestNATS_Publish(t *testing.T) {
+	server := newFakeNATS(t, "secret")
+	n := NewNATS(Config{Servers: []string{"nats://" + server.ln.Addr().String()}, Token: "secret"})
+	defer n.Close()
+
+	ctx := context.Background()
+	for _, payload := range []string{`{"type":"task_completed"}`, "line one\r\nline two"} {
+		if err := n.Publish(ctx, "squaremind.events", nil, []byte(payload)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+	waitFor(t, func() bool { return len(server.received("squaremind.events")) == 2 })
+	if got := server.received("squaremind.events")[1]; got != "line one\r\nline two" {
+		t.Errorf("Expected the payload intact, got %q", got)
+	}
+
+	if err := n.Publish(ctx, "bad subject", nil, []byte("x")); err == nil {
+		t.Error("Expected an error for a subject with spaces")
+	}
+}
+
+func TestNATS_Failover(t *testing.T) {
+	server := newFakeNATS(t, "")
+	dead, _ := net.Listen("tcp", "127.0.0.1:0")
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	n := NewNATS(Config{Servers: []string{deadAddr, server.ln.Addr().String()}})
+	defer n.Close()
+	if err := n.Publish(context.Background(), "events", nil, []byte("hello")); err != nil {
+		t.Fatalf("Expected the second server to take the message, got %v", err)
+	}
+	waitFor(t, func() bool { return len(server.received("events")) == 1 })
+}
+
+func TestNATS_AuthRejected(t *testing.T) {
+	server := newFakeNATS(t, "secret")
+	n := NewNATS(Config{Servers: []string{server.ln.Addr().String()}, Token: "wrong"})
+	defer n.Close()
+	err := n.Publish(context.Background(), "events", nil, []byte("hello"))
+	if err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+		t.Errorf("Expected the server's error, got %v", err)
+	}
+}
+
+func TestParseNATSURL(t *testing.T) {
+	tests := []struct {
+		server, host, user, password string
+	}{
+		{"nats://localhost:4222", "localhost:4222", "", ""},
+		{"localhost", "localhost:4222", "", ""},
+		{"nats://alice:pw@broker:5222", "broker:5222", "alice", "pw"},
+	}
+	for _, tt := range tests {
+		host, user, password, err := parseNATSURL(tt.server)
+		if err != nil {
+			t.Fatalf("parseNATSURL(%q) failed: %v", tt.server, err)
+		}
+		if host != tt.host || user != tt.user || password != tt.password {
+			t.Errorf("Expected %s %s %s for %s, got %s %s %s", tt.host, tt.user, tt.password, tt.server, host, user, password)
+		}
+	}
+	if _, _, _, err := parseNATSURL("tls://localhost"); err == nil {
+		t.Error("Expected an error for a tls:// URL")
+	}
+}
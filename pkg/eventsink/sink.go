@@ -0,0 +1,281 @@
+// Package eventsink publishes the collective event stream to external
+// messaging systems - NATS subjects or Kafka topics - so events can feed
+// existing data pipelines and SIEMs. The NATS and Kafka clients speak just
+// enough of each wire protocol to publish.
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+var (
+	ErrUnknownKind   = errors.New("unknown event sink")
+	ErrUnknownFormat = errors.New("unknown event format")
+	ErrNoServers     = errors.New("no servers configured")
+)
+
+// Sink kinds
+const (
+	KindNATS  = "nats"
+	KindKafka = "kafka"
+)
+
+// Serialization formats
+const (
+	FormatJSON        = "json"        // The collective's Event encoding (default)
+	FormatCloudEvents = "cloudevents" // CloudEvents 1.0 in structured JSON mode
+)
+
+// Config configures one event sink
+type Config struct {
+	Kind    string   `json:"kind" yaml:"kind"`       // nats or kafka
+	Servers []string `json:"servers" yaml:"servers"` // NATS URLs (nats://host:4222) or Kafka bootstrap brokers (host:9092)
+
+	// Subject is the NATS subject or Kafka topic; {type} is replaced by the
+	// event type (default: squaremind.events)
+	Subject string `json:"subject,omitempty" yaml:"subject"`
+
+	Format string                 `json:"format,omitempty" yaml:"format"` // json (default) or cloudevents
+	Types  []collective.EventType `json:"types,omitempty" yaml:"types"`   // Only forward these (empty = all)
+
+	// NATS credentials: a token, or a user and password
+	Token    string `json:"-" yaml:"token"`
+	User     string `json:"user,omitempty" yaml:"user"`
+	Password string `json:"-" yaml:"password"`
+
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout"` // Per publish, including reconnecting (default 10s)
+}
+
+// DefaultSubject is the subject or topic of a sink that doesn't set one
+const DefaultSubject = "squaremind.events"
+
+// Sink publishes messages to a messaging system
+type Sink interface {
+	// Publish sends value under subject; key groups related messages
+	// (Kafka partitions by it)
+	Publish(ctx context.Context, subject string, key, value []byte) error
+	Close() error
+}
+
+// New creates the sink cfg selects. Sinks connect on first publish and
+// reconnect after failures.
+func New(cfg Config) (Sink, error) {
+	if len(cfg.Servers) == 0 {
+		return nil, fmt.Errorf("%w: %s sink", ErrNoServers, cfg.Kind)
+	}
+	switch cfg.Kind {
+	case KindNATS:
+		return NewNATS(cfg), nil
+	case KindKafka:
+		return NewKafka(cfg.Servers), nil
+	}
+	return nil, fmt.Errorf("%w: %q (want %s or %s)", ErrUnknownKind, cfg.Kind, KindNATS, KindKafka)
+}
+
+// Encoder serializes an event of the collective named by source
+type Encoder func(e collective.Event, source string) ([]byte, error)
+
+// NewEncoder returns the encoder for a format ("" = FormatJSON)
+func NewEncoder(format string) (Encoder, error) {
+	switch format {
+	case "", FormatJSON:
+		return func(e collective.Event, _ string) ([]byte, error) { return json.Marshal(e) }, nil
+	case FormatCloudEvents:
+		return encodeCloudEvent, nil
+	}
+	return nil, fmt.Errorf("%w: %q (want %s or %s)", ErrUnknownFormat, format, FormatJSON, FormatCloudEvents)
+}
+
+// cloudEvent is a CloudEvents 1.0 envelope
+type cloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	ID              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject,omitempty"`
+	Time            time.Time      `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            cloudEventData `json:"data"`
+}
+
+// cloudEventData is the payload of a CloudEvent
+type cloudEventData struct {
+	AgentSID string                 `json:"agent_sid,omitempty"`
+	TaskID   string                 `json:"task_id,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+}
+
+// encodeCloudEvent wraps an event in a CloudEvents envelope typed
+// io.squaremind.<event type>, about its task (or agent)
+func encodeCloudEvent(e collective.Event, source string) ([]byte, error) {
+	subject := e.TaskID
+	if subject == "" {
+		subject = e.AgentSID
+	}
+	return json.Marshal(cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              uuid.New().String(),
+		Source:          "squaremind/" + source,
+		Type:            "io.squaremind." + string(e.Type),
+		Subject:         subject,
+		Time:            e.Timestamp,
+		DataContentType: "application/json",
+		Data:            cloudEventData{AgentSID: e.AgentSID, TaskID: e.TaskID, Data: e.Data},
+	})
+}
+
+// Stats reports a forwarder's activity
+type Stats struct {
+	Published int `json:"published"`
+	Failed    int `json:"failed"`
+	Filtered  int `json:"filtered"`
+}
+
+// Forwarder publishes collective events to a sink
+type Forwarder struct {
+	mu sync.RWMutex
+
+	sink   Sink
+	cfg    Config
+	encode Encoder
+	source string
+	types  map[collective.EventType]bool
+	stats  Stats
+	logger logging.Logger
+}
+
+// NewForwarder creates a forwarder of the events of the collective named
+// source to sink
+func NewForwarder(sink Sink, cfg Config, source string) (*Forwarder, error) {
+	encode, err := NewEncoder(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = DefaultSubject
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	f := &Forwarder{
+		sink:   sink,
+		cfg:    cfg,
+		encode: encode,
+		source: source,
+		logger: logging.Component("eventsink").With("kind", cfg.Kind),
+	}
+	if len(cfg.Types) > 0 {
+		f.types = make(map[collective.EventType]bool, len(cfg.Types))
+		for _, t := range cfg.Types {
+			f.types[t] = true
+		}
+	}
+	return f, nil
+}
+
+// SetLogger replaces the forwarder's logger
+func (f *Forwarder) SetLogger(l logging.Logger) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logger = l
+}
+
+// Run forwards events until the channel closes, then closes the sink. A
+// failed publish is logged and the event dropped; the stream keeps going.
+func (f *Forwarder) Run(events <-chan collective.Event) {
+	defer f.sink.Close()
+	for e := range events {
+		f.Forward(e)
+	}
+}
+
+// Forward publishes one event, unless the sink filters its type
+func (f *Forwarder) Forward(e collective.Event) {
+	if f.types != nil && !f.types[e.Type] {
+		f.count(func(s *Stats) { s.Filtered++ })
+		return
+	}
+
+	value, err := f.encode(e, f.source)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), f.cfg.Timeout)
+		err = f.sink.Publish(ctx, f.Subject(e), eventKey(e), value)
+		cancel()
+	}
+	if err != nil {
+		f.count(func(s *Stats) { s.Failed++ })
+		f.log().Warn("event not published", "event", e.Type, "subject", f.Subject(e), "error", err)
+		return
+	}
+	f.count(func(s *Stats) { s.Published++ })
+}
+
+// Subject returns the subject or topic an event is published under
+func (f *Forwarder) Subject(e collective.Event) string {
+	return strings.ReplaceAll(f.cfg.Subject, "{type}", string(e.Type))
+}
+
+// Stats returns the forwarder's activity so far
+func (f *Forwarder) Stats() Stats {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.stats
+}
+
+func (f *Forwarder) count(update func(*Stats)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	update(&f.stats)
+}
+
+func (f *Forwarder) log() logging.Logger {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.logger
+}
+
+// eventKey groups an event with the others about the same task, or agent
+func eventKey(e collective.Event) []byte {
+	switch {
+	case e.TaskID != "":
+		return []byte(e.TaskID)
+	case e.AgentSID != "":
+		return []byte(e.AgentSID)
+	}
+	return []byte(e.Type)
+}
+
+// Attach forwards a collective's events to each configured sink until the
+// collective stops. Sinks connect lazily, so an unreachable server shows up
+// as failed publishes rather than an error here.
+func Attach(c *collective.Collective, cfgs []Config) ([]*Forwarder, error) {
+	forwarders := make([]*Forwarder, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		sink, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		f, err := NewForwarder(sink, cfg, c.Name)
+		if err != nil {
+			sink.Close()
+			return nil, err
+		}
+		forwarders = append(forwarders, f)
+	}
+	for _, f := range forwarders {
+		events, _ := c.SubscribeEvents()
+		go f.Run(events)
+	}
+	return forwarders, nil
+}
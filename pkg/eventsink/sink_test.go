@@ -0,0 +1,162 @@
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// recordingSink records what it's asked to publish
+type recordingSink struct {
+	mu sync.Mutex
+
+	messages []message
+	err      error
+	closed   bool
+}
+
+type message struct {
+	subject    string
+	key, value []byte
+}
+
+func (s *recordingSink) Publish(_ context.Context, subject string, key, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.messages = append(s.messages, message{subject, key, value})
+	return nil
+}
+
+func (s *recordingSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want error
+	}{
+		{"nats", Config{Kind: KindNATS, Servers: []string{"nats://localhost:4222"}}, nil},
+		{"kafka", Config{Kind: KindKafka, Servers: []string{"localhost:9092"}}, nil},
+		{"no servers", Config{Kind: KindNATS}, ErrNoServers},
+		{"unknown kind", Config{Kind: "amqp", Servers: []string{"localhost"}}, ErrUnknownKind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, err := New(tt.cfg)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Expected error %v, got %v", tt.want, err)
+			}
+			if err == nil {
+				sink.Close()
+			}
+		})
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	e := collective.Event{
+		Type:      collective.EventTaskCompleted,
+		AgentSID:  "sid-1",
+		TaskID:    "task-1",
+		Data:      map[string]interface{}{"tokens": 10.0},
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	encode, err := NewEncoder("")
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	data, _ := encode(e, "hive")
+	var plain collective.Event
+	if err := json.Unmarshal(data, &plain); err != nil {
+		t.Fatalf("Expected the event encoding, got %s", data)
+	}
+	if plain.TaskID != "task-1" || plain.Type != collective.EventTaskCompleted {
+		t.Errorf("Expected the event back, got %+v", plain)
+	}
+
+	encode, _ = NewEncoder(FormatCloudEvents)
+	data, _ = encode(e, "hive")
+	var ce cloudEvent
+	if err := json.Unmarshal(data, &ce); err != nil {
+		t.Fatalf("Expected a CloudEvent, got %s", data)
+	}
+	if ce.SpecVersion != "1.0" || ce.ID == "" {
+		t.Errorf("Expected specversion 1.0 and an ID, got %+v", ce)
+	}
+	if ce.Type != "io.squaremind.task_completed" || ce.Source != "squaremind/hive" || ce.Subject != "task-1" {
+		t.Errorf("Expected type, source and subject from the event, got %+v", ce)
+	}
+	if !ce.Time.Equal(e.Timestamp) || ce.Data.AgentSID != "sid-1" || ce.Data.Data["tokens"] != 10.0 {
+		t.Errorf("Expected the event's time and data, got %+v", ce)
+	}
+
+	if _, err := NewEncoder("avro"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestForwarder_Forward(t *testing.T) {
+	sink := &recordingSink{}
+	f, err := NewForwarder(sink, Config{
+		Subject: "sqm.{type}",
+		Types:   []collective.EventType{collective.EventTaskCompleted, collective.EventAgentJoined},
+	}, "hive")
+	if err != nil {
+		t.Fatalf("NewForwarder failed: %v", err)
+	}
+
+	events := make(chan collective.Event, 3)
+	events <- collective.Event{Type: collective.EventTaskCompleted, TaskID: "task-1", AgentSID: "sid-1"}
+	events <- collective.Event{Type: collective.EventBidPlaced, TaskID: "task-1"}
+	events <- collective.Event{Type: collective.EventAgentJoined, AgentSID: "sid-2"}
+	close(events)
+	f.Run(events)
+
+	if len(sink.messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(sink.messages))
+	}
+	tests := []struct {
+		subject, key string
+	}{
+		{"sqm.task_completed", "task-1"},
+		{"sqm.agent_joined", "sid-2"},
+	}
+	for i, tt := range tests {
+		if m := sink.messages[i]; m.subject != tt.subject || string(m.key) != tt.key {
+			t.Errorf("Expected message %d on %s keyed %s, got %s keyed %s", i, tt.subject, tt.key, m.subject, m.key)
+		}
+	}
+	if !sink.closed {
+		t.Error("Expected the sink closed when the events end")
+	}
+	if s := f.Stats(); s.Published != 2 || s.Filtered != 1 || s.Failed != 0 {
+		t.Errorf("Expected 2 published and 1 filtered, got %+v", s)
+	}
+
+	sink.err = errors.New("down")
+	f.Forward(collective.Event{Type: collective.EventTaskCompleted})
+	if s := f.Stats(); s.Failed != 1 {
+		t.Errorf("Expected 1 failed, got %+v", s)
+	}
+}
+
+func TestForwarder_DefaultSubject(t *testing.T) {
+	f, _ := NewForwarder(&recordingSink{}, Config{}, "hive")
+	if got := f.Subject(collective.Event{Type: collective.EventTaskFailed}); got != DefaultSubject {
+		t.Errorf("Expected subject %s, got %s", DefaultSubject, got)
+	}
+}
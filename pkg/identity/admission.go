@@ -0,0 +1,117 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"math/bits"
+	"time"
+)
+
+// WorkProof shows that computation was spent on a public key: the SHA-256 of
+// the key followed by Nonce has at least Difficulty leading zero bits. It
+// makes minting many throwaway identities expensive.
+type WorkProof struct {
+	Nonce      uint64 `json:"nonce"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// SolveWork finds a proof of work over a public key. Each extra bit of
+// difficulty doubles the expected work.
+func SolveWork(publicKey ed25519.PublicKey, difficulty int) *WorkProof {
+	for nonce := uint64(0); ; nonce++ {
+		if workBits(publicKey, nonce) >= difficulty {
+			return &WorkProof{Nonce: nonce, Difficulty: difficulty}
+		}
+	}
+}
+
+// Verify checks the proof against a public key and a required difficulty
+func (w *WorkProof) Verify(publicKey ed25519.PublicKey, difficulty int) bool {
+	if w == nil || w.Difficulty < difficulty {
+		return false
+	}
+	return workBits(publicKey, w.Nonce) >= w.Difficulty
+}
+
+// workBits returns the number of leading zero bits in SHA-256(publicKey || nonce)
+func workBits(publicKey ed25519.PublicKey, nonce uint64) int {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], nonce)
+
+	h := sha256.New()
+	h.Write(publicKey)
+	h.Write(buf[:])
+	sum := h.Sum(nil)
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
+
+// ProveWork solves a proof of work for this identity's public key and keeps
+// it as an admission credential
+func (s *SquaremindIdentity) ProveWork(difficulty int) *WorkProof {
+	s.Work = SolveWork(s.PublicKey, difficulty)
+	return s.Work
+}
+
+// Voucher is an existing member's signed endorsement of a new identity,
+// backed by a stake of the member's reputation
+type Voucher struct {
+	VoucherSID string            `json:"voucher_sid"`
+	VoucheeSID string            `json:"vouchee_sid"`
+	VoucheeKey ed25519.PublicKey `json:"vouchee_key"`
+	Stake      float64           `json:"stake"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+	Signature  []byte            `json:"signature"`
+}
+
+// NewVoucher creates a voucher for a new identity signed by an existing member
+func NewVoucher(voucher *SquaremindIdentity, vouchee *SquaremindIdentity, stake float64, duration time.Duration) (*Voucher, error) {
+	v := &Voucher{
+		VoucherSID: voucher.SID,
+		VoucheeSID: vouchee.SID,
+		VoucheeKey: vouchee.PublicKey,
+		Stake:      stake,
+		ExpiresAt:  time.Now().Add(duration),
+	}
+
+	data, err := v.signedData()
+	if err != nil {
+		return nil, err
+	}
+	v.Signature = voucher.Sign(data)
+	return v, nil
+}
+
+// Verify checks the voucher's signature against the voucher's public key
+func (v *Voucher) Verify(voucherKey ed25519.PublicKey) bool {
+	data, err := v.signedData()
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(voucherKey, data, v.Signature)
+}
+
+// IsValid checks if the voucher has not expired
+func (v *Voucher) IsValid() bool {
+	return time.Now().Before(v.ExpiresAt)
+}
+
+// signedData returns the fields covered by the signature
+func (v *Voucher) signedData() ([]byte, error) {
+	return json.Marshal(struct {
+		VoucherSID string
+		VoucheeSID string
+		VoucheeKey ed25519.PublicKey
+		Stake      float64
+		ExpiresAt  time.Time
+	}{v.VoucherSID, v.VoucheeSID, v.VoucheeKey, v.Stake, v.ExpiresAt})
+}
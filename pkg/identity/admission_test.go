@@ -0,0 +1,48 @@
+package identity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkProof(t *testing.T) {
+	id, _ := NewSquaremindIdentity("Worker", "")
+
+	proof := id.ProveWork(8)
+	if !proof.Verify(id.PublicKey, 8) {
+		t.Error("Expected proof to verify")
+	}
+	if proof.Verify(id.PublicKey, 64) {
+		t.Error("Expected proof to fail a higher difficulty")
+	}
+
+	var missing *WorkProof
+	if missing.Verify(id.PublicKey, 0) {
+		t.Error("Expected nil proof to fail")
+	}
+}
+
+func TestVoucher(t *testing.T) {
+	member, _ := NewSquaremindIdentity("Member", "")
+	newcomer, _ := NewSquaremindIdentity("Newcomer", "")
+
+	v, err := NewVoucher(member, newcomer, 5, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create voucher: %v", err)
+	}
+	if !v.Verify(member.PublicKey) {
+		t.Error("Expected voucher to verify with the member's key")
+	}
+	if v.Verify(newcomer.PublicKey) {
+		t.Error("Expected voucher to fail with another key")
+	}
+	if !v.IsValid() {
+		t.Error("Expected voucher to be valid")
+	}
+
+	// Raising the stake after signing invalidates the voucher
+	v.Stake = 1
+	if v.Verify(member.PublicKey) {
+		t.Error("Expected tampered voucher to fail")
+	}
+}
@@ -0,0 +1,85 @@
+package identity
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCapabilityProof_Verify(t *testing.T) {
+	signer, err := NewSquaremindIdentity("collective", "")
+	if err != nil {
+		t.Fatalf("Failed to create identity: %v", err)
+	}
+	other, _ := NewSquaremindIdentity("other", "")
+
+	proof := NewBenchmarkProof(signer, "sid-1", CapCodeWrite, "go-basics", 0.8, time.Hour)
+	if !proof.Verify(signer.PublicKey) {
+		t.Error("Expected proof to verify against the signer's key")
+	}
+	if proof.Verify(other.PublicKey) {
+		t.Error("Expected proof not to verify against another key")
+	}
+
+	proof.Score = 1.0
+	if proof.Verify(signer.PublicKey) {
+		t.Error("Expected a proof with a tampered score not to verify")
+	}
+
+	var missing *CapabilityProof
+	if missing.Verify(signer.PublicKey) {
+		t.Error("Expected a nil proof not to verify")
+	}
+}
+
+func TestCapabilityProof_Current(t *testing.T) {
+	signer, _ := NewSquaremindIdentity("collective", "")
+	proof := NewBenchmarkProof(signer, "sid-1", CapCodeWrite, "go-basics", 0.8, time.Hour)
+
+	if !proof.Current(time.Now()) {
+		t.Error("Expected a fresh proof to be current")
+	}
+	if proof.Current(time.Now().Add(2 * time.Hour)) {
+		t.Error("Expected an expired proof not to be current")
+	}
+
+	unsigned := &CapabilityProof{Type: ProofBenchmark, Score: 1, ExpiresAt: time.Now().Add(time.Hour)}
+	if unsigned.Current(time.Now()) {
+		t.Error("Expected an unsigned proof not to be current")
+	}
+}
+
+func TestCapabilitySet_ProvenProficiencies(t *testing.T) {
+	signer, _ := NewSquaremindIdentity("collective", "")
+	cs := NewCapabilitySet()
+	cs.Add(&Capability{Type: CapCodeWrite, Proficiency: 0.6})
+	cs.Add(&Capability{Type: CapCodeReview, Proficiency: 0.6})
+
+	before := cs.MatchScore([]CapabilityType{CapCodeWrite})
+
+	if !cs.Prove(CapCodeWrite, NewBenchmarkProof(signer, "sid-1", CapCodeWrite, "go-basics", 0.8, time.Hour)) {
+		t.Fatal("Expected Prove to attach to a held capability")
+	}
+	if cs.Prove(CapSecurity, NewBenchmarkProof(signer, "sid-1", CapSecurity, "security-basics", 1, time.Hour)) {
+		t.Error("Expected Prove to refuse a capability the set lacks")
+	}
+
+	profs := cs.ProvenProficiencies(time.Now())
+	expected := 0.6 + BenchmarkBoost*0.8*(1-0.6)
+	if math.Abs(profs[CapCodeWrite]-expected) > 1e-9 {
+		t.Errorf("Expected proven proficiency %f, got %f", expected, profs[CapCodeWrite])
+	}
+	if profs[CapCodeReview] != 0.6 {
+		t.Errorf("Expected unproven proficiency 0.6, got %f", profs[CapCodeReview])
+	}
+
+	after := cs.MatchScore([]CapabilityType{CapCodeWrite})
+	if after <= before {
+		t.Errorf("Expected proof to raise match score above %f, got %f", before, after)
+	}
+
+	later := cs.ProvenProficiencies(time.Now().Add(2 * time.Hour))
+	if later[CapCodeWrite] != 0.6 {
+		t.Errorf("Expected expired proof to stop boosting, got %f", later[CapCodeWrite])
+	}
+}
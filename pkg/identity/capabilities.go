@@ -1,6 +1,11 @@
 package identity
 
-import "encoding/json"
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"sync"
+	"time"
+)
 
 // CapabilityType represents different types of agent capabilities
 type CapabilityType string
@@ -32,10 +37,39 @@ type CapabilityProof struct {
 	Benchmark string   `json:"benchmark,omitempty"`
 	Attesters []string `json:"attesters,omitempty"`
 	TaskCount int      `json:"task_count,omitempty"`
+
+	// Benchmark proofs are signed by whoever ran the benchmark
+	Subject    string            `json:"subject,omitempty"` // SID of the agent proven
+	Capability CapabilityType    `json:"capability,omitempty"`
+	IssuedAt   time.Time         `json:"issued_at,omitempty"`
+	ExpiresAt  time.Time         `json:"expires_at,omitempty"`
+	Signer     string            `json:"signer,omitempty"`
+	SignerKey  ed25519.PublicKey `json:"signer_key,omitempty"`
+	Signature  []byte            `json:"signature,omitempty"`
+}
+
+// LearningConfig controls how capability proficiency adapts to task outcomes
+type LearningConfig struct {
+	SuccessRate    float64 `json:"success_rate"`    // Fraction of remaining headroom gained per success (scaled by quality)
+	FailureRate    float64 `json:"failure_rate"`    // Fraction of proficiency above the floor lost per failure
+	MinProficiency float64 `json:"min_proficiency"` // Floor proficiency never drops below
+	MaxProficiency float64 `json:"max_proficiency"` // Ceiling proficiency never exceeds
+}
+
+// DefaultLearningConfig returns the default proficiency learning rates
+func DefaultLearningConfig() LearningConfig {
+	return LearningConfig{
+		SuccessRate:    0.1,
+		FailureRate:    0.1,
+		MinProficiency: 0.05,
+		MaxProficiency: 1.0,
+	}
 }
 
 // CapabilitySet manages an agent's capabilities
 type CapabilitySet struct {
+	mu sync.RWMutex
+
 	Capabilities map[CapabilityType]*Capability `json:"capabilities"`
 }
 
@@ -48,58 +82,141 @@ func NewCapabilitySet() *CapabilitySet {
 
 // Add adds a capability to the set
 func (cs *CapabilitySet) Add(cap *Capability) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	cs.Capabilities[cap.Type] = cap
 }
 
 // Has checks if the set contains a capability type
 func (cs *CapabilitySet) Has(capType CapabilityType) bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
 	_, exists := cs.Capabilities[capType]
 	return exists
 }
 
 // Get retrieves a capability by type
 func (cs *CapabilitySet) Get(capType CapabilityType) *Capability {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
 	return cs.Capabilities[capType]
 }
 
-// List returns all capability types in the set
-func (cs *CapabilitySet) List() []CapabilityType {
-	types := make([]CapabilityType, 0, len(cs.Capabilities))
-	for t := range cs.Capabilities {
-		types = append(types, t)
+// Lookup returns a copy of a capability by type, safe to read while the set
+// changes
+func (cs *CapabilitySet) Lookup(capType CapabilityType) (Capability, bool) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cap, ok := cs.Capabilities[capType]; ok {
+		return *cap, true
 	}
-	return types
+	return Capability{}, false
 }
 
-// MatchScore returns how well this capability set matches required capabilities
-func (cs *CapabilitySet) MatchScore(required []CapabilityType) float64 {
-	if len(required) == 0 {
-		return 1.0
+// Proficiency returns the current proficiency for a capability type (0 if absent)
+func (cs *CapabilitySet) Proficiency(capType CapabilityType) float64 {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if cap, ok := cs.Capabilities[capType]; ok {
+		return cap.Proficiency
 	}
+	return 0.0
+}
+
+// Proficiencies returns a snapshot of proficiency per capability type
+func (cs *CapabilitySet) Proficiencies() map[CapabilityType]float64 {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
 
-	var totalScore float64
-	var matched int
+	result := make(map[CapabilityType]float64, len(cs.Capabilities))
+	for t, cap := range cs.Capabilities {
+		result[t] = cap.Proficiency
+	}
+	return result
+}
+
+// SetProficiency sets the proficiency of a held capability, keeping its
+// proof. Returns false if the set doesn't hold it.
+func (cs *CapabilitySet) SetProficiency(capType CapabilityType, proficiency float64) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cap, ok := cs.Capabilities[capType]
+	if ok {
+		cap.Proficiency = proficiency
+	}
+	return ok
+}
+
+// Learn adjusts proficiency of the held capabilities among required based on a task outcome.
+// Successes move proficiency toward the ceiling in proportion to quality; failures move it
+// toward the floor.
+func (cs *CapabilitySet) Learn(required []CapabilityType, success bool, quality float64, cfg LearningConfig) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 
 	for _, req := range required {
-		if cap := cs.Get(req); cap != nil {
-			totalScore += cap.Proficiency
-			matched++
+		cap, ok := cs.Capabilities[req]
+		if !ok {
+			continue
+		}
+
+		if success {
+			cap.Proficiency += cfg.SuccessRate * quality * (cfg.MaxProficiency - cap.Proficiency)
+		} else {
+			cap.Proficiency -= cfg.FailureRate * (cap.Proficiency - cfg.MinProficiency)
+		}
+
+		if cap.Proficiency > cfg.MaxProficiency {
+			cap.Proficiency = cfg.MaxProficiency
+		}
+		if cap.Proficiency < cfg.MinProficiency {
+			cap.Proficiency = cfg.MinProficiency
 		}
 	}
+}
 
-	if matched == 0 {
-		return 0.0
+// Prove attaches a proof to a held capability. Returns false if the set
+// doesn't hold it.
+func (cs *CapabilitySet) Prove(capType CapabilityType, proof *CapabilityProof) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cap, ok := cs.Capabilities[capType]
+	if ok {
+		cap.Proof = proof
 	}
+	return ok
+}
 
-	// Score based on coverage and proficiency
-	coverage := float64(matched) / float64(len(required))
-	avgProficiency := totalScore / float64(matched)
+// List returns all capability types in the set
+func (cs *CapabilitySet) List() []CapabilityType {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
 
-	return coverage * avgProficiency
+	types := make([]CapabilityType, 0, len(cs.Capabilities))
+	for t := range cs.Capabilities {
+		types = append(types, t)
+	}
+	return types
+}
+
+// MatchScore returns how well this capability set matches required
+// capabilities. A requirement the set lacks is partly met by a capability
+// that inherits from it in the default registry.
+func (cs *CapabilitySet) MatchScore(required []CapabilityType) float64 {
+	return cs.MatchScoreWith(defaultRegistry, required)
+}
+
+// MatchScoreWith is MatchScore using the given capability registry. See
+// Match for how the score is computed; proofs count if current now.
+func (cs *CapabilitySet) MatchScoreWith(registry *CapabilityRegistry, required []CapabilityType) float64 {
+	return cs.ExplainMatchWith(registry, required, time.Now()).Score
 }
 
 // ToJSON serializes the capability set
 func (cs *CapabilitySet) ToJSON() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
 	data, err := json.Marshal(cs)
 	if err != nil {
 		return "{}"
@@ -109,5 +226,7 @@ func (cs *CapabilitySet) ToJSON() string {
 
 // FromJSON deserializes a capability set from JSON
 func (cs *CapabilitySet) FromJSON(data []byte) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
 	return json.Unmarshal(data, cs)
 }
@@ -1,6 +1,7 @@
 package identity
 
 import (
+	"math"
 	"testing"
 )
 
@@ -60,6 +61,41 @@ func TestCapabilitySet_Get(t *testing.T) {
 	}
 }
 
+func TestCapabilitySet_Lookup(t *testing.T) {
+	cs := NewCapabilitySet()
+	cs.Add(&Capability{Type: CapCodeWrite, Proficiency: 0.75})
+
+	c, ok := cs.Lookup(CapCodeWrite)
+	if !ok || c.Proficiency != 0.75 {
+		t.Fatalf("Expected proficiency 0.75, got %+v (%v)", c, ok)
+	}
+	cs.Learn([]CapabilityType{CapCodeWrite}, true, 1, DefaultLearningConfig())
+	if c.Proficiency != 0.75 {
+		t.Errorf("Expected the copy unchanged by learning, got %f", c.Proficiency)
+	}
+
+	if _, ok := cs.Lookup(CapSecurity); ok {
+		t.Error("Lookup should report a missing capability")
+	}
+}
+
+func TestCapabilitySet_SetProficiency(t *testing.T) {
+	cs := NewCapabilitySet()
+	cs.Add(&Capability{Type: CapCodeWrite, Proficiency: 0.5})
+	cs.Prove(CapCodeWrite, &CapabilityProof{Type: ProofBenchmark, Score: 0.9})
+
+	if !cs.SetProficiency(CapCodeWrite, 0.8) {
+		t.Fatal("SetProficiency should succeed for a held capability")
+	}
+	if c := cs.Get(CapCodeWrite); c.Proficiency != 0.8 || c.Proof == nil {
+		t.Errorf("Expected proficiency 0.8 with the proof kept, got %+v", c)
+	}
+
+	if cs.SetProficiency(CapSecurity, 0.8) || cs.Has(CapSecurity) {
+		t.Error("SetProficiency should not add a capability")
+	}
+}
+
 func TestCapabilitySet_List(t *testing.T) {
 	cs := NewCapabilitySet()
 	cs.Add(&Capability{Type: CapCodeWrite, Proficiency: 0.5})
@@ -126,3 +162,83 @@ func TestCapabilitySet_JSON(t *testing.T) {
 		t.Errorf("FromJSON failed: %v", err)
 	}
 }
+
+func TestCapabilitySet_Learn(t *testing.T) {
+	cs := NewCapabilitySet()
+	cs.Add(&Capability{Type: CapCodeWrite, Proficiency: 0.5})
+	cs.Add(&Capability{Type: CapCodeReview, Proficiency: 0.5})
+	cfg := DefaultLearningConfig()
+
+	cs.Learn([]CapabilityType{CapCodeWrite, CapSecurity}, true, 1.0, cfg)
+	if p := cs.Proficiency(CapCodeWrite); p <= 0.5 {
+		t.Errorf("Expected proficiency to rise after success, got %f", p)
+	}
+	if p := cs.Proficiency(CapCodeReview); p != 0.5 {
+		t.Errorf("Expected untouched capability to stay at 0.5, got %f", p)
+	}
+	if cs.Has(CapSecurity) {
+		t.Error("Learn should not add capabilities the set lacks")
+	}
+
+	cs.Learn([]CapabilityType{CapCodeReview}, false, 0, cfg)
+	if p := cs.Proficiency(CapCodeReview); p >= 0.5 {
+		t.Errorf("Expected proficiency to drop after failure, got %f", p)
+	}
+
+	// Repeated failures never go below the floor
+	for i := 0; i < 200; i++ {
+		cs.Learn([]CapabilityType{CapCodeReview}, false, 0, cfg)
+	}
+	if p := cs.Proficiency(CapCodeReview); p < cfg.MinProficiency {
+		t.Errorf("Expected proficiency floor %f, got %f", cfg.MinProficiency, p)
+	}
+}
+
+func FuzzMatchScore(f *testing.F) {
+	f.Add([]byte{0, 255, 2, 128}, []byte{0, 1, 2})
+	f.Add([]byte{2, 200}, []byte{0})
+	f.Add([]byte{}, []byte{3, 9})
+
+	types := append(DefaultCapabilityRegistry().Types(), "custom.unknown")
+
+	f.Fuzz(func(t *testing.T, held, wanted []byte) {
+		// Pairs of bytes pick a capability and its proficiency
+		cs := NewCapabilitySet()
+		for i := 0; i+1 < len(held); i += 2 {
+			cs.Add(&Capability{
+				Type:        types[int(held[i])%len(types)],
+				Proficiency: float64(held[i+1]) / 255,
+			})
+		}
+		required := make([]CapabilityType, len(wanted))
+		for i, b := range wanted {
+			required[i] = types[int(b)%len(types)]
+		}
+
+		score := cs.MatchScore(required)
+		if score < 0 || score > 1 {
+			t.Fatalf("Score %v outside [0, 1]", score)
+		}
+		if len(required) == 0 && score != 1 {
+			t.Fatalf("Expected 1 with no requirements, got %v", score)
+		}
+
+		// The order requirements are listed in doesn't matter
+		reversed := make([]CapabilityType, len(required))
+		for i, c := range required {
+			reversed[len(required)-1-i] = c
+		}
+		if other := cs.MatchScore(reversed); math.Abs(other-score) > 1e-9 {
+			t.Fatalf("Score depends on order: %v vs %v", score, other)
+		}
+
+		// Full proficiency in every requirement is a perfect match
+		expert := NewCapabilitySet()
+		for _, c := range required {
+			expert.Add(&Capability{Type: c, Proficiency: 1})
+		}
+		if got := expert.MatchScore(required); got != 1 {
+			t.Fatalf("Expected 1 for an expert in %v, got %v", required, got)
+		}
+	})
+}
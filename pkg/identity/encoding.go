@@ -0,0 +1,28 @@
+package identity
+
+import (
+	"github.com/square-mind/squaremind/pkg/schema"
+)
+
+// IdentitySchemaVersion is the version of the JSON encoding of identities
+const IdentitySchemaVersion = 1
+
+// MarshalJSON encodes the identity with its schema version. The private key
+// is never included.
+func (s SquaremindIdentity) MarshalJSON() ([]byte, error) {
+	type plain SquaremindIdentity
+	return schema.Encode(plain(s), IdentitySchemaVersion, s.extra)
+}
+
+// UnmarshalJSON decodes an identity written by any release
+func (s *SquaremindIdentity) UnmarshalJSON(data []byte) error {
+	type plain SquaremindIdentity
+	var p plain
+	_, extra, err := schema.Decode(data, &p)
+	if err != nil {
+		return err
+	}
+	*s = SquaremindIdentity(p)
+	s.extra = extra
+	return nil
+}
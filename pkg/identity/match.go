@@ -0,0 +1,92 @@
+package identity
+
+import (
+	"sort"
+	"time"
+)
+
+// RequirementMatch is how one required capability was met
+type RequirementMatch struct {
+	Required    CapabilityType `json:"required"`
+	Source      CapabilityType `json:"source,omitempty"` // Held capability that met it, empty if none did
+	Proficiency float64        `json:"proficiency"`      // Source's proficiency
+	Credit      float64        `json:"credit"`           // Share of it that counts: 1 if held, less if inherited
+	Score       float64        `json:"score"`            // Proficiency * Credit
+}
+
+// MatchBreakdown explains a match score requirement by requirement
+type MatchBreakdown struct {
+	Requirements   []RequirementMatch `json:"requirements"`
+	Matched        int                `json:"matched"`
+	Coverage       float64            `json:"coverage"`        // Matched / len(required)
+	AvgProficiency float64            `json:"avg_proficiency"` // Mean score of the matched requirements
+	Score          float64            `json:"score"`           // Coverage * AvgProficiency
+}
+
+// Match scores held capabilities, given as proficiencies, against required
+// ones. It is a pure function of its arguments, so the same inputs give the
+// same score on every run:
+//
+//   - No requirements score 1.
+//   - A held requirement scores its proficiency.
+//   - Otherwise it scores the best proficiency * registry credit among the
+//     held capabilities, ties going to the capability first by name; a
+//     requirement nothing reaches is unmatched.
+//   - The score is coverage (the share of requirements matched) times the
+//     mean score of the matched ones, summed in the order required.
+//
+// Requirements listed twice count twice.
+func Match(held map[CapabilityType]float64, required []CapabilityType, registry *CapabilityRegistry) MatchBreakdown {
+	if len(required) == 0 {
+		return MatchBreakdown{Requirements: []RequirementMatch{}, Coverage: 1, AvgProficiency: 1, Score: 1}
+	}
+
+	types := make([]CapabilityType, 0, len(held))
+	for t := range held {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	b := MatchBreakdown{Requirements: make([]RequirementMatch, len(required))}
+	var total float64
+	for i, req := range required {
+		m := RequirementMatch{Required: req}
+		if proficiency, ok := held[req]; ok {
+			m.Source, m.Proficiency, m.Credit, m.Score = req, proficiency, 1, proficiency
+		} else {
+			for _, t := range types {
+				credit := registry.Credit(t, req)
+				if score := held[t] * credit; score > m.Score {
+					m.Source, m.Proficiency, m.Credit, m.Score = t, held[t], credit, score
+				}
+			}
+		}
+		if m.Score > 0 {
+			total += m.Score
+			b.Matched++
+		} else {
+			m = RequirementMatch{Required: req}
+		}
+		b.Requirements[i] = m
+	}
+
+	if b.Matched == 0 {
+		return b
+	}
+	b.Coverage = float64(b.Matched) / float64(len(required))
+	b.AvgProficiency = total / float64(b.Matched)
+	b.Score = b.Coverage * b.AvgProficiency
+	return b
+}
+
+// ExplainMatch returns the breakdown of MatchScore at now
+func (cs *CapabilitySet) ExplainMatch(required []CapabilityType, now time.Time) MatchBreakdown {
+	return cs.ExplainMatchWith(defaultRegistry, required, now)
+}
+
+// ExplainMatchWith returns the breakdown of MatchScoreWith at now.
+// Capabilities with a benchmark proof current at now are matched at their
+// proven proficiency.
+func (cs *CapabilitySet) ExplainMatchWith(registry *CapabilityRegistry, required []CapabilityType, now time.Time) MatchBreakdown {
+	return Match(cs.ProvenProficiencies(now), required, registry)
+}
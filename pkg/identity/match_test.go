@@ -0,0 +1,113 @@
+package identity
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	registry := NewCapabilityRegistry()
+	if err := registry.Register(CapabilityDefinition{
+		Type:      "ml.training",
+		Satisfies: map[CapabilityType]float64{CapAnalysis: 0.8},
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		held     map[CapabilityType]float64
+		required []CapabilityType
+		score    float64
+		sources  []CapabilityType
+	}{
+		{"no requirements", map[CapabilityType]float64{CapCodeWrite: 0.8}, nil, 1, nil},
+		{"exact", map[CapabilityType]float64{CapCodeWrite: 0.8}, []CapabilityType{CapCodeWrite}, 0.8, []CapabilityType{CapCodeWrite}},
+		{"all matched", map[CapabilityType]float64{CapCodeWrite: 0.8, CapCodeReview: 0.6}, []CapabilityType{CapCodeWrite, CapCodeReview}, 0.7, []CapabilityType{CapCodeWrite, CapCodeReview}},
+		{"half covered", map[CapabilityType]float64{CapCodeWrite: 0.8}, []CapabilityType{CapCodeWrite, CapSecurity}, 0.4, []CapabilityType{CapCodeWrite, ""}},
+		{"none covered", map[CapabilityType]float64{CapCodeWrite: 0.8}, []CapabilityType{CapSecurity}, 0, []CapabilityType{""}},
+		{"inherited", map[CapabilityType]float64{CapCodeRefactor: 0.8}, []CapabilityType{CapCodeWrite}, 0.4, []CapabilityType{CapCodeRefactor}},
+		{"exact beats inherited", map[CapabilityType]float64{CapCodeRefactor: 0.9, CapCodeWrite: 0.3}, []CapabilityType{CapCodeWrite}, 0.3, []CapabilityType{CapCodeWrite}},
+		{"matching rule", map[CapabilityType]float64{"ml.training": 0.5}, []CapabilityType{CapAnalysis}, 0.4, []CapabilityType{"ml.training"}},
+		{"zero proficiency", map[CapabilityType]float64{CapCodeWrite: 0}, []CapabilityType{CapCodeWrite}, 0, []CapabilityType{""}},
+		{"repeated requirement", map[CapabilityType]float64{CapCodeWrite: 0.8}, []CapabilityType{CapCodeWrite, CapCodeWrite, CapSecurity}, 0.8 * 2 / 3, []CapabilityType{CapCodeWrite, CapCodeWrite, ""}},
+	}
+
+	for _, tt := range tests {
+		b := Match(tt.held, tt.required, registry)
+		if math.Abs(b.Score-tt.score) > 1e-9 {
+			t.Errorf("%s: expected score %f, got %f", tt.name, tt.score, b.Score)
+		}
+		if len(b.Requirements) != len(tt.required) {
+			t.Fatalf("%s: expected %d requirements, got %d", tt.name, len(tt.required), len(b.Requirements))
+		}
+		for i, m := range b.Requirements {
+			if m.Required != tt.required[i] || m.Source != tt.sources[i] {
+				t.Errorf("%s: expected %s met by %q, got %s met by %q", tt.name, tt.required[i], tt.sources[i], m.Required, m.Source)
+			}
+			if math.Abs(m.Score-m.Proficiency*m.Credit) > 1e-9 {
+				t.Errorf("%s: expected %s to score proficiency * credit, got %f", tt.name, m.Required, m.Score)
+			}
+		}
+	}
+}
+
+func TestMatch_TieBreak(t *testing.T) {
+	registry := NewCapabilityRegistry()
+	for _, name := range []CapabilityType{"b.cap", "a.cap", "c.cap"} {
+		if err := registry.Register(CapabilityDefinition{Type: name, Parents: []CapabilityType{CapResearch}}); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+	held := map[CapabilityType]float64{"b.cap": 0.6, "a.cap": 0.6, "c.cap": 0.6}
+
+	first := Match(held, []CapabilityType{CapResearch}, registry)
+	if first.Requirements[0].Source != "a.cap" {
+		t.Errorf("Expected the tie to go to a.cap, got %q", first.Requirements[0].Source)
+	}
+	for i := 0; i < 20; i++ {
+		if b := Match(held, []CapabilityType{CapResearch}, registry); !reflect.DeepEqual(b, first) {
+			t.Fatalf("Expected the same breakdown on every run, got %+v and %+v", first, b)
+		}
+	}
+}
+
+func TestCapabilitySet_ExplainMatch(t *testing.T) {
+	cs := NewCapabilitySet()
+	cs.Add(&Capability{Type: CapCodeWrite, Proficiency: 0.8})
+	cs.Add(&Capability{Type: CapTesting, Proficiency: 0.5})
+
+	required := []CapabilityType{CapCodeWrite, CapTesting, CapSecurity}
+	b := cs.ExplainMatch(required, time.Now())
+	if b.Score != cs.MatchScore(required) {
+		t.Errorf("Expected breakdown score %f to equal MatchScore %f", b.Score, cs.MatchScore(required))
+	}
+	if b.Matched != 2 {
+		t.Errorf("Expected 2 requirements matched, got %d", b.Matched)
+	}
+	if math.Abs(b.Coverage-2.0/3) > 1e-9 || math.Abs(b.AvgProficiency-0.65) > 1e-9 {
+		t.Errorf("Expected coverage 0.667 and proficiency 0.65, got %f and %f", b.Coverage, b.AvgProficiency)
+	}
+}
+
+func TestCapabilitySet_ExplainMatchAt(t *testing.T) {
+	signer, _ := NewSquaremindIdentity("collective", "")
+	cs := NewCapabilitySet()
+	cs.Add(&Capability{Type: CapCodeWrite, Proficiency: 0.6})
+	proof := NewBenchmarkProof(signer, "sid-1", CapCodeWrite, "go-basics", 1, time.Hour)
+	cs.Prove(CapCodeWrite, proof)
+
+	required := []CapabilityType{CapCodeWrite}
+	proven := cs.ExplainMatch(required, proof.IssuedAt.Add(time.Minute))
+	if !reflect.DeepEqual(proven, cs.ExplainMatch(required, proof.IssuedAt.Add(time.Minute))) {
+		t.Error("Expected the same breakdown for the same time")
+	}
+	if expected := 0.6 + BenchmarkBoost*0.4; math.Abs(proven.Score-expected) > 1e-9 {
+		t.Errorf("Expected proven score %f while the proof is current, got %f", expected, proven.Score)
+	}
+	if expired := cs.ExplainMatch(required, proof.ExpiresAt.Add(time.Minute)); expired.Score != 0.6 {
+		t.Errorf("Expected score 0.6 once the proof expired, got %f", expired.Score)
+	}
+}
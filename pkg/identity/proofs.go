@@ -3,9 +3,14 @@ package identity
 import (
 	"crypto/ed25519"
 	"encoding/json"
+	"errors"
 	"time"
 )
 
+// ErrInvalidDelegation is returned for a delegation proof that is expired,
+// wrongly signed or not usable by the agent presenting it
+var ErrInvalidDelegation = errors.New("invalid delegation")
+
 // SignedAction represents an action signed by an agent
 type SignedAction struct {
 	Action    Action       `json:"action"`
@@ -85,11 +90,7 @@ func NewDelegationProof(delegator *SquaremindIdentity, delegateSID string, capab
 	}
 
 	// Sign the delegation
-	data, err := json.Marshal(struct {
-		DelegateSID string
-		Capability  CapabilityType
-		ExpiresAt   time.Time
-	}{delegateSID, capability, proof.ExpiresAt})
+	data, err := proof.signedData()
 	if err != nil {
 		return nil, err
 	}
@@ -98,11 +99,29 @@ func NewDelegationProof(delegator *SquaremindIdentity, delegateSID string, capab
 	return proof, nil
 }
 
+// signedData returns the fields of the delegation its signature covers
+func (dp *DelegationProof) signedData() ([]byte, error) {
+	return json.Marshal(struct {
+		DelegateSID string
+		Capability  CapabilityType
+		ExpiresAt   time.Time
+	}{dp.DelegateSID, dp.Capability, dp.ExpiresAt})
+}
+
 // IsValid checks if the delegation proof is still valid
 func (dp *DelegationProof) IsValid() bool {
 	return time.Now().Before(dp.ExpiresAt)
 }
 
+// Verify verifies the delegator's signature on the delegation
+func (dp *DelegationProof) Verify(delegatorKey ed25519.PublicKey) bool {
+	data, err := dp.signedData()
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(delegatorKey, data, dp.Signature)
+}
+
 // ConsensusProof represents proof of collective consensus
 type ConsensusProof struct {
 	Round     int             `json:"round"`
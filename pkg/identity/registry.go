@@ -0,0 +1,332 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnknownCapability = errors.New("unknown capability")
+	ErrInvalidCapability = errors.New("invalid capability definition")
+	ErrBuiltinCapability = errors.New("built-in capabilities cannot be changed")
+)
+
+// DefaultInheritance is the share of a capability's proficiency credited to
+// its parents when a definition doesn't set one
+const DefaultInheritance = 0.5
+
+// CapabilityDefinition describes a capability and how it relates to others.
+// A capability counts partially towards each of its parents, so an agent
+// with code.refactor can take a task requiring code.write, at a lower score
+// than an agent with code.write itself. Credit carries up the taxonomy,
+// multiplying at each step.
+type CapabilityDefinition struct {
+	Type        CapabilityType   `json:"type" yaml:"type"`
+	Description string           `json:"description,omitempty" yaml:"description,omitempty"`
+	Parents     []CapabilityType `json:"parents,omitempty" yaml:"parents,omitempty"`         // Broader capabilities this one specialises
+	Inheritance float64          `json:"inheritance,omitempty" yaml:"inheritance,omitempty"` // Share credited to each parent (0 = DefaultInheritance)
+
+	// Satisfies are matching rules beyond the taxonomy: other capabilities
+	// this one counts towards, with the share credited to each
+	Satisfies map[CapabilityType]float64 `json:"satisfies,omitempty" yaml:"satisfies,omitempty"`
+
+	Builtin bool `json:"builtin,omitempty" yaml:"-"`
+}
+
+// inheritance returns the share credited to parents, applying the default
+func (d *CapabilityDefinition) inheritance() float64 {
+	if d.Inheritance <= 0 {
+		return DefaultInheritance
+	}
+	return d.Inheritance
+}
+
+// builtinCapabilities defines the capabilities every registry starts with
+func builtinCapabilities() []CapabilityDefinition {
+	return []CapabilityDefinition{
+		{Type: CapCodeWrite, Description: "Write new code"},
+		{Type: CapCodeReview, Description: "Review code for defects and style"},
+		{Type: CapCodeRefactor, Description: "Restructure existing code", Parents: []CapabilityType{CapCodeWrite}},
+		{Type: CapResearch, Description: "Gather and summarise information"},
+		{Type: CapAnalysis, Description: "Analyse problems and data"},
+		{Type: CapSecurity, Description: "Find and fix security issues"},
+		{Type: CapDocumentation, Description: "Write documentation"},
+		{Type: CapTesting, Description: "Write and run tests"},
+		{Type: CapArchitecture, Description: "Design systems"},
+	}
+}
+
+// CapabilityRegistry holds the known capabilities: the built-in ones and
+// any defined by users
+type CapabilityRegistry struct {
+	mu sync.RWMutex
+
+	defs map[CapabilityType]*CapabilityDefinition
+}
+
+// NewCapabilityRegistry creates a registry holding the built-in capabilities
+func NewCapabilityRegistry() *CapabilityRegistry {
+	r := &CapabilityRegistry{defs: make(map[CapabilityType]*CapabilityDefinition)}
+	for _, def := range builtinCapabilities() {
+		def := def
+		def.Builtin = true
+		r.defs[def.Type] = &def
+	}
+	return r
+}
+
+var defaultRegistry = NewCapabilityRegistry()
+
+// DefaultCapabilityRegistry returns the registry MatchScore consults
+func DefaultCapabilityRegistry() *CapabilityRegistry {
+	return defaultRegistry
+}
+
+// Register adds a custom capability or replaces an earlier definition of
+// it. Parents must already be registered, and may not lead back to the
+// capability itself.
+func (r *CapabilityRegistry) Register(def CapabilityDefinition) error {
+	if !validCapabilityName(def.Type) {
+		return fmt.Errorf("%w: bad name %q", ErrInvalidCapability, def.Type)
+	}
+	if def.Inheritance < 0 || def.Inheritance > 1 {
+		return fmt.Errorf("%w: inheritance must be between 0 and 1", ErrInvalidCapability)
+	}
+	for target, share := range def.Satisfies {
+		if share <= 0 || share > 1 {
+			return fmt.Errorf("%w: share towards %s must be above 0 and at most 1", ErrInvalidCapability, target)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.defs[def.Type]; ok && existing.Builtin {
+		return fmt.Errorf("%w: %s", ErrBuiltinCapability, def.Type)
+	}
+	for _, parent := range def.Parents {
+		if _, ok := r.defs[parent]; !ok {
+			return fmt.Errorf("%w: parent %s", ErrUnknownCapability, parent)
+		}
+		if parent == def.Type || r.descendsLocked(parent, def.Type) {
+			return fmt.Errorf("%w: %s cannot be its own ancestor", ErrInvalidCapability, def.Type)
+		}
+	}
+
+	def.Builtin = false
+	def.Parents = append([]CapabilityType(nil), def.Parents...)
+	if def.Satisfies != nil {
+		satisfies := make(map[CapabilityType]float64, len(def.Satisfies))
+		for target, share := range def.Satisfies {
+			satisfies[target] = share
+		}
+		def.Satisfies = satisfies
+	}
+	r.defs[def.Type] = &def
+	return nil
+}
+
+// descendsLocked reports whether capability t has ancestor somewhere among
+// its parents. Caller must hold r.mu.
+func (r *CapabilityRegistry) descendsLocked(t, ancestor CapabilityType) bool {
+	def, ok := r.defs[t]
+	if !ok {
+		return false
+	}
+	for _, parent := range def.Parents {
+		if parent == ancestor || r.descendsLocked(parent, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove deletes a custom capability. Capabilities that name it as a parent
+// must be removed first.
+func (r *CapabilityRegistry) Remove(t CapabilityType) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	def, ok := r.defs[t]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCapability, t)
+	}
+	if def.Builtin {
+		return fmt.Errorf("%w: %s", ErrBuiltinCapability, t)
+	}
+	for _, other := range r.defs {
+		for _, parent := range other.Parents {
+			if parent == t {
+				return fmt.Errorf("%w: %s is the parent of %s", ErrInvalidCapability, t, other.Type)
+			}
+		}
+	}
+	delete(r.defs, t)
+	return nil
+}
+
+// Lookup returns the definition of a capability
+func (r *CapabilityRegistry) Lookup(t CapabilityType) (CapabilityDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.defs[t]
+	if !ok {
+		return CapabilityDefinition{}, false
+	}
+	return *def, true
+}
+
+// Known reports whether a capability is registered
+func (r *CapabilityRegistry) Known(t CapabilityType) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.defs[t]
+	return ok
+}
+
+// List returns every definition, sorted by name
+func (r *CapabilityRegistry) List() []CapabilityDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]CapabilityDefinition, 0, len(r.defs))
+	for _, def := range r.defs {
+		defs = append(defs, *def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Type < defs[j].Type })
+	return defs
+}
+
+// Types returns every registered capability, sorted by name
+func (r *CapabilityRegistry) Types() []CapabilityType {
+	defs := r.List()
+	types := make([]CapabilityType, len(defs))
+	for i, def := range defs {
+		types[i] = def.Type
+	}
+	return types
+}
+
+// Credit returns the share of proficiency in have that counts towards want:
+// 1 for the same capability, the product of the shares along the best path
+// through parents and matching rules, or 0 if want can't be reached
+func (r *CapabilityRegistry) Credit(have, want CapabilityType) float64 {
+	if have == want {
+		return 1
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.creditLocked(have, want, map[CapabilityType]bool{have: true})
+}
+
+// creditLocked searches for the best path from have to want, not revisiting
+// capabilities on the current path. Caller must hold r.mu.
+func (r *CapabilityRegistry) creditLocked(have, want CapabilityType, path map[CapabilityType]bool) float64 {
+	def, ok := r.defs[have]
+	if !ok {
+		return 0
+	}
+
+	best := 0.0
+	step := func(next CapabilityType, share float64) {
+		if path[next] {
+			return
+		}
+		credit := share
+		if next != want {
+			path[next] = true
+			credit *= r.creditLocked(next, want, path)
+			delete(path, next)
+		}
+		if credit > best {
+			best = credit
+		}
+	}
+	for _, parent := range def.Parents {
+		step(parent, def.inheritance())
+	}
+	for target, share := range def.Satisfies {
+		step(target, share)
+	}
+	return best
+}
+
+// capabilityFile is the on-disk form of the custom capabilities
+type capabilityFile struct {
+	Capabilities []CapabilityDefinition `yaml:"capabilities"`
+}
+
+// LoadFile registers the custom capabilities defined in a YAML file. A
+// missing file defines none.
+func (r *CapabilityRegistry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var file capabilityFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCapability, err)
+	}
+
+	// Definitions may name parents defined later in the file
+	pending := file.Capabilities
+	for len(pending) > 0 {
+		var deferred []CapabilityDefinition
+		var lastErr error
+		for _, def := range pending {
+			if err := r.Register(def); err != nil {
+				deferred = append(deferred, def)
+				lastErr = err
+			}
+		}
+		if len(deferred) == len(pending) {
+			return lastErr
+		}
+		pending = deferred
+	}
+	return nil
+}
+
+// SaveFile writes the custom capabilities to a YAML file
+func (r *CapabilityRegistry) SaveFile(path string) error {
+	var file capabilityFile
+	for _, def := range r.List() {
+		if !def.Builtin {
+			file.Capabilities = append(file.Capabilities, def)
+		}
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// validCapabilityName reports whether t is a usable capability name: lower
+// case letters, digits, dots, dashes and underscores
+func validCapabilityName(t CapabilityType) bool {
+	if t == "" {
+		return false
+	}
+	for _, r := range t {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package identity
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestCapabilityRegistry_Builtins(t *testing.T) {
+	r := NewCapabilityRegistry()
+
+	def, ok := r.Lookup(CapCodeRefactor)
+	if !ok || !def.Builtin {
+		t.Fatalf("Expected code.refactor to be built in")
+	}
+	if err := r.Register(CapabilityDefinition{Type: CapCodeWrite}); !errors.Is(err, ErrBuiltinCapability) {
+		t.Errorf("Expected ErrBuiltinCapability, got %v", err)
+	}
+	if err := r.Remove(CapCodeWrite); !errors.Is(err, ErrBuiltinCapability) {
+		t.Errorf("Expected ErrBuiltinCapability, got %v", err)
+	}
+	if credit := r.Credit(CapCodeRefactor, CapCodeWrite); credit != DefaultInheritance {
+		t.Errorf("Expected code.refactor to count %v towards code.write, got %v", DefaultInheritance, credit)
+	}
+	if credit := r.Credit(CapCodeWrite, CapCodeRefactor); credit != 0 {
+		t.Errorf("Expected no credit from parent to child, got %v", credit)
+	}
+}
+
+func TestCapabilityRegistry_Register(t *testing.T) {
+	r := NewCapabilityRegistry()
+
+	if err := r.Register(CapabilityDefinition{Type: "code.refactor.go", Parents: []CapabilityType{CapCodeRefactor}, Inheritance: 0.8}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := r.Register(CapabilityDefinition{Type: "data.sql", Satisfies: map[CapabilityType]float64{CapAnalysis: 0.3}}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	// Credit multiplies up the taxonomy
+	if credit := r.Credit("code.refactor.go", CapCodeWrite); math.Abs(credit-0.4) > 1e-9 {
+		t.Errorf("Expected credit 0.4 towards code.write, got %v", credit)
+	}
+	if credit := r.Credit("data.sql", CapAnalysis); credit != 0.3 {
+		t.Errorf("Expected credit 0.3 towards analysis, got %v", credit)
+	}
+
+	tests := []CapabilityDefinition{
+		{Type: "Bad Name"},
+		{Type: "orphan", Parents: []CapabilityType{"missing"}},
+		{Type: "greedy", Inheritance: 1.5},
+		{Type: "generous", Satisfies: map[CapabilityType]float64{CapAnalysis: 2}},
+	}
+	for _, def := range tests {
+		if err := r.Register(def); err == nil {
+			t.Errorf("Expected %q to be rejected", def.Type)
+		}
+	}
+
+	// A capability can't become its own ancestor
+	if err := r.Register(CapabilityDefinition{Type: "code.refactor.go.generics", Parents: []CapabilityType{"code.refactor.go"}}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	cycle := CapabilityDefinition{Type: "code.refactor.go", Parents: []CapabilityType{"code.refactor.go.generics"}}
+	if err := r.Register(cycle); !errors.Is(err, ErrInvalidCapability) {
+		t.Errorf("Expected ErrInvalidCapability for a cycle, got %v", err)
+	}
+	if err := r.Remove("code.refactor.go"); !errors.Is(err, ErrInvalidCapability) {
+		t.Errorf("Expected a parent in use not to be removable, got %v", err)
+	}
+}
+
+func TestCapabilityRegistry_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capabilities.yaml")
+	r := NewCapabilityRegistry()
+	_ = r.Register(CapabilityDefinition{Type: "ml", Description: "Machine learning"})
+	_ = r.Register(CapabilityDefinition{Type: "ml.training", Parents: []CapabilityType{"ml"}, Inheritance: 0.7})
+	if err := r.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	loaded := NewCapabilityRegistry()
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	def, ok := loaded.Lookup("ml.training")
+	if !ok || def.Inheritance != 0.7 || len(def.Parents) != 1 {
+		t.Errorf("Expected ml.training restored, got %+v", def)
+	}
+	if got := len(loaded.List()); got != len(builtinCapabilities())+2 {
+		t.Errorf("Expected %d capabilities, got %d", len(builtinCapabilities())+2, got)
+	}
+
+	if err := loaded.LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err != nil {
+		t.Errorf("Expected a missing file to define nothing, got %v", err)
+	}
+}
+
+func TestCapabilitySet_MatchScoreInheritance(t *testing.T) {
+	r := NewCapabilityRegistry()
+	_ = r.Register(CapabilityDefinition{Type: "code.refactor.go", Parents: []CapabilityType{CapCodeRefactor}, Inheritance: 0.8})
+
+	cs := NewCapabilitySet()
+	cs.Add(&Capability{Type: "code.refactor.go", Proficiency: 1.0})
+
+	if score := cs.MatchScoreWith(r, []CapabilityType{CapCodeRefactor}); math.Abs(score-0.8) > 1e-9 {
+		t.Errorf("Expected score 0.8 for code.refactor, got %v", score)
+	}
+	if score := cs.MatchScoreWith(r, []CapabilityType{CapCodeWrite}); math.Abs(score-0.4) > 1e-9 {
+		t.Errorf("Expected score 0.4 for code.write, got %v", score)
+	}
+	if score := cs.MatchScoreWith(r, []CapabilityType{CapCodeWrite, CapSecurity}); math.Abs(score-0.2) > 1e-9 {
+		t.Errorf("Expected score 0.2 with one requirement unmet, got %v", score)
+	}
+
+	// The default registry gives code.refactor partial credit for code.write
+	refactorer := NewCapabilitySet()
+	refactorer.Add(&Capability{Type: CapCodeRefactor, Proficiency: 0.8})
+	if score := refactorer.MatchScore([]CapabilityType{CapCodeWrite}); math.Abs(score-0.4) > 1e-9 {
+		t.Errorf("Expected score 0.4, got %v", score)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/schema"
 )
 
 // SquaremindIdentity represents a unique, cryptographic identity for an agent
@@ -19,6 +21,12 @@ type SquaremindIdentity struct {
 	CreatedAt  time.Time `json:"created_at"`
 	ParentSID  string    `json:"parent_sid,omitempty"`
 	Generation int       `json:"generation"`
+
+	// Admission credentials presented when joining a collective
+	Work    *WorkProof `json:"work,omitempty"`
+	Voucher *Voucher   `json:"voucher,omitempty"`
+
+	extra schema.Extra // Fields written by a newer release
 }
 
 // NewSquaremindIdentity creates a new squaremind identity with fresh keypair
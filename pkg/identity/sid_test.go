@@ -1,6 +1,8 @@
 package identity
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -89,3 +91,42 @@ func TestSIDShort(t *testing.T) {
 		t.Errorf("Short SID doesn't match first 8 chars of SID")
 	}
 }
+
+func TestSquaremindIdentity_JSONCompatibility(t *testing.T) {
+	id, err := NewSquaremindIdentity("TestAgent", "")
+	if err != nil {
+		t.Fatalf("Failed to create identity: %v", err)
+	}
+
+	data, err := json.Marshal(id)
+	if err != nil {
+		t.Fatalf("Failed to encode identity: %v", err)
+	}
+	if strings.Contains(string(data), "private") {
+		t.Errorf("Expected private key omitted, got %s", data)
+	}
+
+	// Add a field a newer release might write and round-trip it
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Failed to decode fields: %v", err)
+	}
+	fields["attestation"] = json.RawMessage(`"signed"`)
+	data, _ = json.Marshal(fields)
+
+	var decoded SquaremindIdentity
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode identity: %v", err)
+	}
+	if decoded.SID != id.SID || decoded.Name != id.Name {
+		t.Errorf("Expected identity %s, got %s", id.SID, decoded.SID)
+	}
+
+	data, err = json.Marshal(&decoded)
+	if err != nil {
+		t.Fatalf("Failed to re-encode identity: %v", err)
+	}
+	if !strings.Contains(string(data), `"attestation":"signed"`) {
+		t.Errorf("Expected unknown field preserved, got %s", data)
+	}
+}
@@ -0,0 +1,282 @@
+// Package incident captures the state of a collective for debugging
+// production issues: recent logs and events, task timelines, queue state,
+// consensus rounds, health and provider errors, written as a redacted
+// bundle. Captures are taken on demand or automatically when tasks keep
+// failing or the health score collapses.
+package incident
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// Redacted replaces secrets in a bundle
+const Redacted = "[REDACTED]"
+
+// Manifest describes a bundle
+type Manifest struct {
+	Reason     string    `json:"reason"`
+	Collective string    `json:"collective"`
+	CapturedAt time.Time `json:"captured_at"`
+	Sections   []string  `json:"sections"`
+	Redactions int       `json:"redactions"` // Values replaced with Redacted
+}
+
+// Bundle is a captured, redacted snapshot of a collective. Each section is
+// written to the archive as <name>.json.
+type Bundle struct {
+	Manifest Manifest
+	Sections map[string]interface{}
+}
+
+// Source supplies an extra bundle section, such as provider errors
+type Source func() interface{}
+
+// Capturer takes incident captures of a collective
+type Capturer struct {
+	mu sync.RWMutex
+
+	collective *collective.Collective
+	recorder   *Recorder // nil = no logs or events in bundles
+	sources    map[string]Source
+	secrets    []string
+}
+
+// NewCapturer creates a capturer for c. Logs and events come from rec,
+// which may be nil.
+func NewCapturer(c *collective.Collective, rec *Recorder) *Capturer {
+	return &Capturer{collective: c, recorder: rec, sources: make(map[string]Source)}
+}
+
+// AddSource adds a section to every bundle
+func (k *Capturer) AddSource(name string, src Source) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.sources[name] = src
+}
+
+// AddSecrets adds values, such as API keys and bearer tokens, to scrub from
+// bundles wherever they appear
+func (k *Capturer) AddSecrets(values ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, v := range values {
+		if len(v) >= 6 {
+			k.secrets = append(k.secrets, v)
+		}
+	}
+}
+
+// completedLimit is how many finished tasks a bundle covers
+const completedLimit = 100
+
+// Capture snapshots the collective into a redacted bundle
+func (k *Capturer) Capture(reason string) *Bundle {
+	c := k.collective
+	snapshot := c.TaskSnapshot(completedLimit)
+
+	sections := map[string]interface{}{
+		"stats":     c.Stats(),
+		"health":    c.Health(),
+		"mode":      c.Mode(),
+		"agents":    c.AgentSnapshots(),
+		"queue":     map[string]interface{}{"tasks": snapshot, "fair_queue": c.GetQueue().Stats()},
+		"consensus": c.GetConsensus().Summaries(),
+		"traces":    traces(c, snapshot),
+	}
+	if k.recorder != nil {
+		sections["logs"] = k.recorder.Logs()
+		sections["events"] = k.recorder.Events()
+	}
+
+	k.mu.RLock()
+	for name, src := range k.sources {
+		sections[name] = src()
+	}
+	r := newRedactor(k.secrets)
+	k.mu.RUnlock()
+
+	b := &Bundle{
+		Manifest: Manifest{Reason: reason, Collective: c.Name, CapturedAt: time.Now().UTC()},
+		Sections: make(map[string]interface{}, len(sections)),
+	}
+	for name, section := range sections {
+		b.Sections[name] = r.redact(section)
+		b.Manifest.Sections = append(b.Manifest.Sections, name)
+	}
+	sort.Strings(b.Manifest.Sections)
+	b.Manifest.Redactions = r.count
+	return b
+}
+
+// traces returns the timeline of every task in a snapshot, by task ID
+func traces(c *collective.Collective, snapshot collective.TaskSnapshot) map[string][]collective.TimelineEntry {
+	ids := make([]string, 0, len(snapshot.Pending)+len(snapshot.Active)+len(snapshot.Completed))
+	for _, t := range snapshot.Pending {
+		ids = append(ids, t.ID)
+	}
+	for _, t := range snapshot.Active {
+		ids = append(ids, t.ID)
+	}
+	for _, r := range snapshot.Completed {
+		ids = append(ids, r.TaskID)
+	}
+
+	out := make(map[string][]collective.TimelineEntry, len(ids))
+	for _, id := range ids {
+		if timeline, ok := c.Timeline(id); ok {
+			out[id] = timeline
+		}
+	}
+	return out
+}
+
+// WriteTo writes the bundle as a gzipped tar archive holding manifest.json
+// and a JSON file per section
+func (b *Bundle) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	gz := gzip.NewWriter(cw)
+	tw := tar.NewWriter(gz)
+
+	add := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: b.Manifest.CapturedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	}
+
+	if err := add("manifest.json", b.Manifest); err != nil {
+		return cw.n, err
+	}
+	for _, name := range b.Manifest.Sections {
+		if err := add(name+".json", b.Sections[name]); err != nil {
+			return cw.n, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return cw.n, err
+	}
+	err := gz.Close()
+	return cw.n, err
+}
+
+// FileName returns the bundle's file name: incident-<time>.tar.gz
+func (b *Bundle) FileName() string {
+	return "incident-" + b.Manifest.CapturedAt.Format("20060102T150405Z") + ".tar.gz"
+}
+
+// Save writes the bundle to dir, readable only by its owner. Returns the
+// file's path.
+func (b *Bundle) Save(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, b.FileName())
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := b.WriteTo(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// secretKeys are the field names whose values are always redacted
+var secretKeys = regexp.MustCompile(`(?i)(api_?key|token|secret|passw(or)?d|authorization|credential|private_?key|cookie|webhook)`)
+
+// secretValues match credentials wherever they appear in a string
+var secretValues = regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{8,}|sk-[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|(?i:bearer)\s+[A-Za-z0-9._~+/=-]{8,}|xox[abprs]-[A-Za-z0-9-]{10,}`)
+
+// redactor scrubs secrets from a section
+type redactor struct {
+	secrets []string
+	count   int
+}
+
+func newRedactor(secrets []string) *redactor {
+	// Longest first, so a secret containing another is replaced whole
+	sorted := append([]string(nil), secrets...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	return &redactor{secrets: sorted}
+}
+
+// redact returns v as generic JSON with secrets replaced
+func (r *redactor) redact(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	return r.walk(generic)
+}
+
+func (r *redactor) walk(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && s != "" && secretKeys.MatchString(key) {
+				v[key] = Redacted
+				r.count++
+				continue
+			}
+			v[key] = r.walk(value)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = r.walk(v[i])
+		}
+		return v
+	case string:
+		return r.scrub(v)
+	}
+	return v
+}
+
+// scrub replaces known secrets and credential-shaped substrings of s
+func (r *redactor) scrub(s string) string {
+	for _, secret := range r.secrets {
+		if n := strings.Count(s, secret); n > 0 {
+			s = strings.ReplaceAll(s, secret, Redacted)
+			r.count += n
+		}
+	}
+	return secretValues.ReplaceAllStringFunc(s, func(string) string {
+		r.count++
+		return Redacted
+	})
+}
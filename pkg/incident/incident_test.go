@@ -0,0 +1,173 @@
+package incident
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// readBundle unpacks a bundle into file name -> contents
+func readBundle(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Invalid gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid tar: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(body)
+	}
+	return files
+}
+
+func TestRecorder_Ring(t *testing.T) {
+	r := NewRecorder(3, 2)
+	log := r.Logger(slog.LevelInfo)
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		log.Info(msg)
+	}
+	log.Debug("hidden")
+	for _, id := range []string{"t1", "t2", "t3"} {
+		r.RecordEvent(collective.Event{Type: collective.EventTaskFailed, TaskID: id})
+	}
+
+	logs := r.Logs()
+	if len(logs) != 3 || logs[0].Message != "two" || logs[2].Message != "four" {
+		t.Errorf("Expected the last 3 entries oldest first, got %+v", logs)
+	}
+	events := r.Events()
+	if len(events) != 2 || events[0].TaskID != "t2" {
+		t.Errorf("Expected the last 2 events, got %+v", events)
+	}
+}
+
+func TestCapturer_Capture(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a)
+
+	rec := NewRecorder(0, 0)
+	rec.Logger(slog.LevelInfo).Warn("provider rejected request", "api_key", "sk-live-123456", "detail", "Authorization: Bearer abcdefghijkl")
+	rec.RecordEvent(collective.Event{Type: collective.EventTaskFailed, TaskID: "t1", Data: map[string]interface{}{"error": "webhook https://hooks.example.com/T000/SECRETPATH failed"}})
+
+	k := NewCapturer(c, rec)
+	k.AddSecrets("https://hooks.example.com/T000/SECRETPATH", "short")
+	k.AddSource("providers", func() interface{} {
+		return map[string]string{"last_error": "401 invalid x-api-key sk-ant-REDACTED"}
+	})
+
+	b := k.Capture("test")
+	if b.Manifest.Reason != "test" || b.Manifest.Collective != "TestCollective" {
+		t.Errorf("Expected manifest for test on TestCollective, got %+v", b.Manifest)
+	}
+	if b.Manifest.Redactions != 4 {
+		t.Errorf("Expected 4 redactions, got %d", b.Manifest.Redactions)
+	}
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	files := readBundle(t, buf.Bytes())
+	for _, name := range []string{"manifest.json", "logs.json", "events.json", "traces.json", "queue.json", "consensus.json", "health.json", "agents.json", "providers.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in the bundle", name)
+		}
+	}
+	for name, body := range files {
+		for _, secret := range []string{"sk-live-123456", "abcdefghijkl", "SECRETPATH", "sk-ant-api03"} {
+			if strings.Contains(body, secret) {
+				t.Errorf("Expected %q to be redacted from %s", secret, name)
+			}
+		}
+	}
+	if !strings.Contains(files["agents.json"], "Agent1") {
+		t.Error("Expected the agents in the bundle")
+	}
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil || len(manifest.Sections) != 10 {
+		t.Errorf("Expected 10 sections in the manifest, got %+v (%v)", manifest.Sections, err)
+	}
+}
+
+// failingProvider fails every completion
+type failingProvider struct{}
+
+func (failingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	return nil, errors.New("upstream overloaded")
+}
+
+func (failingProvider) Name() string {
+	return "failing"
+}
+
+func TestCapturer_WatchFailures(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Flaky", Provider: failingProvider{}})
+	_ = c.Join(a)
+	k := NewCapturer(c, nil)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+	captured := make(chan string, 4)
+	go k.Watch(ctx, TriggerConfig{Dir: dir, Failures: 3, HealthBelow: -1, Cooldown: time.Hour}, func(path string, b *Bundle) {
+		captured <- b.Manifest.Reason
+	})
+	time.Sleep(20 * time.Millisecond) // Let Watch subscribe
+
+	for i := 0; i < 6; i++ {
+		_, _ = c.Submit(agent.NewTask("Doomed", nil))
+	}
+
+	select {
+	case reason := <-captured:
+		if !strings.Contains(reason, "3 task failures") {
+			t.Errorf("Expected a failure reason, got %q", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a capture after repeated failures")
+	}
+
+	// The cooldown holds back a second capture
+	select {
+	case reason := <-captured:
+		t.Errorf("Expected no second capture within the cooldown, got %q", reason)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "incident-*.tar.gz"))
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 saved bundle, got %d", len(matches))
+	}
+	if info, _ := os.Stat(matches[0]); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected bundle mode 0600, got %v", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,117 @@
+package incident
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+// Default ring sizes of a Recorder
+const (
+	DefaultLogEntries = 2000
+	DefaultEvents     = 2000
+)
+
+// LogEntry is a log record kept by a Recorder
+type LogEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+}
+
+// Recorder keeps the most recent log entries and collective events in
+// memory, so a capture shows what led up to an incident
+type Recorder struct {
+	mu sync.Mutex
+
+	logs   ring[LogEntry]
+	events ring[collective.Event]
+}
+
+// NewRecorder creates a recorder keeping up to maxLogs log entries and
+// maxEvents events (0 = the defaults)
+func NewRecorder(maxLogs, maxEvents int) *Recorder {
+	if maxLogs <= 0 {
+		maxLogs = DefaultLogEntries
+	}
+	if maxEvents <= 0 {
+		maxEvents = DefaultEvents
+	}
+	return &Recorder{
+		logs:   ring[LogEntry]{max: maxLogs},
+		events: ring[collective.Event]{max: maxEvents},
+	}
+}
+
+// Logger returns a logger recording entries at or above min. Tee it with the
+// process logger to keep recent logs whatever the console's level is.
+func (r *Recorder) Logger(min slog.Level) logging.Logger {
+	return logging.NewHook(min, r.RecordLog)
+}
+
+// RecordLog keeps a log entry
+func (r *Recorder) RecordLog(e logging.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs.add(LogEntry{Time: e.Time, Level: e.Level.String(), Message: e.Message, Attrs: e.Attrs})
+}
+
+// RecordEvent keeps a collective event
+func (r *Recorder) RecordEvent(e collective.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events.add(e)
+}
+
+// Attach records a collective's events until it stops. Returns a function
+// that stops recording earlier.
+func (r *Recorder) Attach(c *collective.Collective) func() {
+	events, unsubscribe := c.SubscribeEvents()
+	go func() {
+		for e := range events {
+			r.RecordEvent(e)
+		}
+	}()
+	return unsubscribe
+}
+
+// Logs returns the kept log entries, oldest first
+func (r *Recorder) Logs() []LogEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.logs.items()
+}
+
+// Events returns the kept events, oldest first
+func (r *Recorder) Events() []collective.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events.items()
+}
+
+// ring is a fixed-size buffer overwriting its oldest item when full
+type ring[T any] struct {
+	buf  []T
+	next int
+	max  int
+}
+
+func (r *ring[T]) add(v T) {
+	if len(r.buf) < r.max {
+		r.buf = append(r.buf, v)
+		return
+	}
+	r.buf[r.next] = v
+	r.next = (r.next + 1) % r.max
+}
+
+// items returns a copy of the buffer, oldest first
+func (r *ring[T]) items() []T {
+	out := make([]T, 0, len(r.buf))
+	out = append(out, r.buf[r.next:]...)
+	return append(out, r.buf[:r.next]...)
+}
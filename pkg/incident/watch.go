@@ -0,0 +1,128 @@
+package incident
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+// TriggerConfig sets when captures are taken automatically. Zero fields
+// take the defaults.
+type TriggerConfig struct {
+	Disabled      bool          `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+	Dir           string        `json:"dir,omitempty" yaml:"dir,omitempty"`                       // Where bundles are saved
+	Failures      int           `json:"failures,omitempty" yaml:"failures,omitempty"`             // Task failures within Window that trigger a capture
+	Window        time.Duration `json:"window,omitempty" yaml:"window,omitempty"`                 // Window failures are counted over
+	HealthBelow   float64       `json:"health_below,omitempty" yaml:"health_below,omitempty"`     // Health score (0-100) that triggers a capture; negative = never
+	CheckInterval time.Duration `json:"check_interval,omitempty" yaml:"check_interval,omitempty"` // How often the health score is checked
+	Cooldown      time.Duration `json:"cooldown,omitempty" yaml:"cooldown,omitempty"`             // Minimum time between automatic captures
+}
+
+// DefaultTriggerConfig captures on 5 failures in 5 minutes or a health
+// score below 30, at most every 15 minutes
+func DefaultTriggerConfig() TriggerConfig {
+	return TriggerConfig{
+		Failures:      5,
+		Window:        5 * time.Minute,
+		HealthBelow:   30,
+		CheckInterval: 30 * time.Second,
+		Cooldown:      15 * time.Minute,
+	}
+}
+
+// withDefaults fills unset fields from DefaultTriggerConfig
+func (t TriggerConfig) withDefaults() TriggerConfig {
+	d := DefaultTriggerConfig()
+	if t.Failures <= 0 {
+		t.Failures = d.Failures
+	}
+	if t.Window <= 0 {
+		t.Window = d.Window
+	}
+	if t.HealthBelow == 0 {
+		t.HealthBelow = d.HealthBelow
+	}
+	if t.CheckInterval <= 0 {
+		t.CheckInterval = d.CheckInterval
+	}
+	if t.Cooldown <= 0 {
+		t.Cooldown = d.Cooldown
+	}
+	return t
+}
+
+// Watch takes a capture into cfg.Dir whenever tasks keep failing or the
+// health score falls below cfg.HealthBelow, until ctx ends or the collective stops. onCapture,
+// if not nil, is called with each saved bundle's path.
+func (k *Capturer) Watch(ctx context.Context, cfg TriggerConfig, onCapture func(path string, b *Bundle)) {
+	if cfg.Disabled || cfg.Dir == "" {
+		return
+	}
+	cfg = cfg.withDefaults()
+	log := logging.Component("incident")
+
+	events, unsubscribe := k.collective.SubscribeEvents()
+	defer unsubscribe()
+	ticker := time.NewTicker(cfg.CheckInterval)
+	defer ticker.Stop()
+
+	var (
+		failures []time.Time
+		last     time.Time
+		healthy  bool // Score was at or above HealthBelow at the last check
+	)
+	capture := func(reason string) {
+		now := time.Now()
+		if !last.IsZero() && now.Sub(last) < cfg.Cooldown {
+			return
+		}
+		last = now
+		failures = failures[:0]
+
+		b := k.Capture(reason)
+		path, err := b.Save(cfg.Dir)
+		if err != nil {
+			log.Error("could not save incident bundle", "dir", cfg.Dir, "error", err)
+			return
+		}
+		log.Warn("incident captured", "reason", reason, "path", path)
+		if onCapture != nil {
+			onCapture(path, b)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if e.Type != collective.EventTaskFailed {
+				continue
+			}
+			now := time.Now()
+			failures = append(failures, now)
+			for len(failures) > 0 && now.Sub(failures[0]) > cfg.Window {
+				failures = failures[1:]
+			}
+			if len(failures) >= cfg.Failures {
+				capture(fmt.Sprintf("%d task failures within %s", len(failures), cfg.Window))
+			}
+		case <-ticker.C:
+			if cfg.HealthBelow < 0 {
+				continue
+			}
+			// Only a drop counts, not a collective that starts out unhealthy
+			report := k.collective.Health()
+			if report.Score < cfg.HealthBelow && healthy {
+				capture(fmt.Sprintf("health score fell to %.0f, below %.0f (%s)", report.Score, cfg.HealthBelow, report.Status))
+			}
+			healthy = report.Score >= cfg.HealthBelow
+		}
+	}
+}
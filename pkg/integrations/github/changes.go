@@ -0,0 +1,68 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrNoChanges is returned when a result holds no patch or files to commit
+var ErrNoChanges = errors.New("no changes to commit")
+
+// ErrUnsafePath is returned for a file outside the repository
+var ErrUnsafePath = errors.New("path outside the repository")
+
+// codeBlock matches a fenced code block with an optional language and file
+// name (```go pkg/foo/foo.go)
+var codeBlock = regexp.MustCompile("(?s)```([A-Za-z0-9_+-]*)[ \\t]*([^\\n`]*)\\n(.*?)```")
+
+// Changes are the edits an agent's output makes to a repository
+type Changes struct {
+	Patches []string          // Unified diffs, applied in order
+	Files   map[string]string // Whole files by path, written after the patches
+}
+
+// Empty reports whether there is nothing to apply
+func (c *Changes) Empty() bool {
+	return len(c.Patches) == 0 && len(c.Files) == 0
+}
+
+// ExtractChanges collects the edits in an LLM response: ```diff and
+// ```patch blocks are unified diffs, other blocks naming a file after
+// their language hold its new content. Blocks without a file name are
+// left out, as there's no telling where they belong.
+func ExtractChanges(text string) (*Changes, error) {
+	changes := &Changes{Files: make(map[string]string)}
+	for _, m := range codeBlock.FindAllStringSubmatch(text, -1) {
+		lang, name, body := strings.ToLower(m[1]), strings.TrimSpace(m[2]), m[3]
+		if lang == "diff" || lang == "patch" {
+			changes.Patches = append(changes.Patches, body)
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		clean, err := cleanPath(name)
+		if err != nil {
+			return nil, err
+		}
+		changes.Files[clean] = body
+	}
+	if changes.Empty() {
+		return nil, ErrNoChanges
+	}
+	return changes, nil
+}
+
+// cleanPath returns a repository-relative path, rejecting paths that would
+// escape it or touch its .git directory
+func cleanPath(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") ||
+		clean == ".git" || strings.HasPrefix(clean, ".git/") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return clean, nil
+}
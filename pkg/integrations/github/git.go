@@ -0,0 +1,102 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// workspace is a scratch clone of a repository
+type workspace struct {
+	dir   string
+	token string // Redacted from command errors
+}
+
+// clone checks out a single branch of a repository into a scratch
+// directory under parent
+func clone(ctx context.Context, parent, url, branch, token string) (*workspace, error) {
+	dir, err := os.MkdirTemp(parent, "sqm-github-")
+	if err != nil {
+		return nil, err
+	}
+	ws := &workspace{dir: dir, token: token}
+	if _, err := ws.git(ctx, "clone", "--depth", "1", "--branch", branch, url, dir); err != nil {
+		ws.remove()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// git runs a git command in the workspace and returns its output
+func (ws *workspace) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = ws.dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(out.String())
+		if ws.token != "" {
+			msg = strings.ReplaceAll(msg, ws.token, "***")
+		}
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+	}
+	return out.String(), nil
+}
+
+// apply applies patches, then writes whole files
+func (ws *workspace) apply(ctx context.Context, changes *Changes) error {
+	for i, patch := range changes.Patches {
+		file := filepath.Join(ws.dir, fmt.Sprintf(".sqm-patch-%d", i))
+		if !strings.HasSuffix(patch, "\n") {
+			patch += "\n"
+		}
+		if err := os.WriteFile(file, []byte(patch), 0600); err != nil {
+			return err
+		}
+		_, err := ws.git(ctx, "apply", "--whitespace=nowarn", "--recount", file)
+		os.Remove(file)
+		if err != nil {
+			return err
+		}
+	}
+	for name, content := range changes.Files {
+		file := filepath.Join(ws.dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commit stages everything and commits it, failing with ErrNoChanges if
+// the tree is unchanged
+func (ws *workspace) commit(ctx context.Context, author Author, message string) error {
+	if _, err := ws.git(ctx, "add", "-A"); err != nil {
+		return err
+	}
+	status, err := ws.git(ctx, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) == "" {
+		return ErrNoChanges
+	}
+	_, err = ws.git(ctx,
+		"-c", "user.name="+author.Name,
+		"-c", "user.email="+author.Email,
+		"commit", "-q", "-m", message)
+	return err
+}
+
+func (ws *workspace) remove() {
+	os.RemoveAll(ws.dir)
+}
@@ -0,0 +1,330 @@
+// Package github lets coding collectives work on GitHub repositories: an
+// agent that can write code clones the repository a task names, commits
+// the patches it generated to a new branch and opens a pull request; an
+// agent that can review code posts its review on the pull request a task
+// names. Git operations run the git command; pull requests and reviews go
+// through the GitHub REST API.
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var (
+	ErrNoToken     = errors.New("no GitHub token configured")
+	ErrInvalidRepo = errors.New("invalid repository")
+)
+
+// Task metadata keys the integration reads
+const (
+	MetaRepo        = "repo"         // owner/name of the repository
+	MetaBaseBranch  = "base_branch"  // Branch the pull request merges into (default: DefaultBaseBranch)
+	MetaBranch      = "branch"       // Branch the changes are pushed to (default: BranchPrefix + task ID)
+	MetaPullRequest = "pull_request" // Number of the pull request a review is posted on
+	MetaDraft       = "draft"        // "true" opens the pull request as a draft
+)
+
+// Defaults
+const (
+	DefaultAPIURL       = "https://api.github.com"
+	DefaultGitURL       = "https://github.com"
+	DefaultBaseBranch   = "main"
+	DefaultBranchPrefix = "sqm/"
+	DefaultTokenEnv     = "GITHUB_TOKEN"
+)
+
+// Author is who commits are attributed to
+type Author struct {
+	Name  string `json:"name" yaml:"name"`
+	Email string `json:"email" yaml:"email"`
+}
+
+// Config configures the GitHub integration
+type Config struct {
+	Token    string `json:"-" yaml:"token,omitempty"`                       // Personal access or installation token
+	TokenEnv string `json:"token_env,omitempty" yaml:"token_env,omitempty"` // Variable the token is read from if Token is empty (default GITHUB_TOKEN)
+
+	APIURL       string `json:"api_url,omitempty" yaml:"api_url,omitempty"`             // REST API root (default https://api.github.com; GitHub Enterprise: https://host/api/v3)
+	GitURL       string `json:"git_url,omitempty" yaml:"git_url,omitempty"`             // Root repositories are cloned from (default https://github.com)
+	BranchPrefix string `json:"branch_prefix,omitempty" yaml:"branch_prefix,omitempty"` // Prefix of generated branch names (default sqm/)
+	WorkDir      string `json:"work_dir,omitempty" yaml:"work_dir,omitempty"`           // Where clones are made (default: the system temp directory)
+
+	Author Author `json:"author,omitempty" yaml:"author,omitempty"` // Commit author (default: the agent, at squaremind.local)
+
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"` // Per task, cloning to pull request (default 5m)
+}
+
+// DefaultConfig returns the default configuration
+func DefaultConfig() Config {
+	return Config{}.withDefaults()
+}
+
+func (c Config) withDefaults() Config {
+	if c.TokenEnv == "" {
+		c.TokenEnv = DefaultTokenEnv
+	}
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+	if c.GitURL == "" {
+		c.GitURL = DefaultGitURL
+	}
+	if c.BranchPrefix == "" {
+		c.BranchPrefix = DefaultBranchPrefix
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Minute
+	}
+	c.APIURL = strings.TrimRight(c.APIURL, "/")
+	c.GitURL = strings.TrimRight(c.GitURL, "/")
+	return c
+}
+
+// token returns the configured token, looking it up in TokenEnv at each
+// call so it can be rotated without a restart
+func (c Config) token() string {
+	if c.Token != "" {
+		return c.Token
+	}
+	return os.Getenv(c.TokenEnv)
+}
+
+// PullRequest is a pull request to open
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body,omitempty"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+	Draft bool   `json:"draft,omitempty"`
+}
+
+// Created is what GitHub returns for a created pull request or review
+type Created struct {
+	Number  int    `json:"number,omitempty"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Integration works on the GitHub repositories named in task metadata. It
+// implements agent.Integration.
+type Integration struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates the integration
+func New(cfg Config) *Integration {
+	return &Integration{cfg: cfg.withDefaults(), client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name returns "github"
+func (g *Integration) Name() string {
+	return "github"
+}
+
+// Handle opens a pull request with the changes in a code.write task's
+// output, or posts a code.review task's output as a review, for tasks
+// naming a repository. Agents without the capability are passed over.
+func (g *Integration) Handle(ctx context.Context, a *agent.Agent, task *agent.Task, result *agent.TaskResult) (string, error) {
+	repo := task.Metadata[MetaRepo]
+	if repo == "" || result.Status != agent.TaskCompleted {
+		return "", nil
+	}
+	if err := validRepo(repo); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.Timeout)
+	defer cancel()
+
+	switch {
+	case requires(task, identity.CapCodeWrite) && a.Capabilities.Has(identity.CapCodeWrite):
+		pr, err := g.OpenPullRequest(ctx, a, task, result.Output)
+		if err != nil {
+			return "", err
+		}
+		return "opened " + pr.HTMLURL, nil
+	case requires(task, identity.CapCodeReview) && a.Capabilities.Has(identity.CapCodeReview) && task.Metadata[MetaPullRequest] != "":
+		number, err := strconv.Atoi(task.Metadata[MetaPullRequest])
+		if err != nil {
+			return "", fmt.Errorf("invalid pull request number %q", task.Metadata[MetaPullRequest])
+		}
+		review, err := g.Review(ctx, repo, number, result.Output)
+		if err != nil {
+			return "", err
+		}
+		return "reviewed " + review.HTMLURL, nil
+	}
+	return "", nil
+}
+
+// OpenPullRequest clones the task's repository at its base branch, commits
+// the changes in output to a new branch, pushes it and opens a pull request
+func (g *Integration) OpenPullRequest(ctx context.Context, a *agent.Agent, task *agent.Task, output string) (*Created, error) {
+	token := g.cfg.token()
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	changes, err := ExtractChanges(output)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := task.Metadata[MetaRepo]
+	base := task.Metadata[MetaBaseBranch]
+	if base == "" {
+		base = DefaultBaseBranch
+	}
+	branch := task.Metadata[MetaBranch]
+	if branch == "" {
+		branch = g.cfg.BranchPrefix + shortID(task.ID)
+	}
+
+	ws, err := clone(ctx, g.cfg.WorkDir, g.cloneURL(repo, token), base, token)
+	if err != nil {
+		return nil, err
+	}
+	defer ws.remove()
+
+	if _, err := ws.git(ctx, "checkout", "-q", "-b", branch); err != nil {
+		return nil, err
+	}
+	if err := ws.apply(ctx, changes); err != nil {
+		return nil, err
+	}
+	if err := ws.commit(ctx, g.author(a), commitMessage(a, task)); err != nil {
+		return nil, err
+	}
+	if _, err := ws.git(ctx, "push", "-q", "origin", branch); err != nil {
+		return nil, err
+	}
+
+	var created Created
+	err = g.api(ctx, http.MethodPost, "/repos/"+repo+"/pulls", PullRequest{
+		Title: title(task),
+		Body:  pullRequestBody(a, task),
+		Head:  branch,
+		Base:  base,
+		Draft: task.Metadata[MetaDraft] == "true",
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Review posts a comment review on a pull request
+func (g *Integration) Review(ctx context.Context, repo string, number int, body string) (*Created, error) {
+	if g.cfg.token() == "" {
+		return nil, ErrNoToken
+	}
+	var created Created
+	path := fmt.Sprintf("/repos/%s/pulls/%d/reviews", repo, number)
+	err := g.api(ctx, http.MethodPost, path, map[string]string{"body": body, "event": "COMMENT"}, &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// api calls the REST API
+func (g *Integration) api(ctx context.Context, method, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.cfg.APIURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.cfg.token())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("github %s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// cloneURL returns the URL a repository is cloned from, carrying the token
+// for HTTPS remotes
+func (g *Integration) cloneURL(repo, token string) string {
+	root := g.cfg.GitURL
+	if rest, ok := strings.CutPrefix(root, "https://"); ok {
+		root = "https://x-access-token:" + token + "@" + rest
+	}
+	return root + "/" + repo + ".git"
+}
+
+// author returns who the agent's commits are attributed to
+func (g *Integration) author(a *agent.Agent) Author {
+	if g.cfg.Author.Name != "" {
+		return g.cfg.Author
+	}
+	return Author{Name: a.Identity.Name, Email: a.Identity.SIDShort() + "@squaremind.local"}
+}
+
+// validRepo checks a repository is named owner/name
+func validRepo(repo string) error {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.ContainsAny(name, "/ ") || strings.Contains(repo, "..") {
+		return fmt.Errorf("%w: %q (want owner/name)", ErrInvalidRepo, repo)
+	}
+	return nil
+}
+
+func requires(task *agent.Task, capType identity.CapabilityType) bool {
+	for _, c := range task.Required {
+		if c == capType {
+			return true
+		}
+	}
+	return false
+}
+
+// title is the first line of the task's description, cut to a readable length
+func title(task *agent.Task) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(task.Description), "\n")
+	if len(line) > 72 {
+		line = strings.TrimSpace(line[:69]) + "..."
+	}
+	return line
+}
+
+func commitMessage(a *agent.Agent, task *agent.Task) string {
+	return fmt.Sprintf("%s\n\nTask: %s\nAgent: %s (%s)", title(task), task.ID, a.Identity.Name, a.Identity.SID)
+}
+
+func pullRequestBody(a *agent.Agent, task *agent.Task) string {
+	return fmt.Sprintf("%s\n\n---\nOpened by squaremind agent %s (`%s`) for task `%s`.",
+		strings.TrimSpace(task.Description), a.Identity.Name, a.Identity.SID, task.ID)
+}
+
+// shortID returns the first eight characters of an ID
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
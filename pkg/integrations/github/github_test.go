@@ -0,0 +1,151 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// bareRepo creates root/acme/widgets.git with a main branch holding README.md
+func bareRepo(t *testing.T, root string) string {
+	t.Helper()
+	bare := filepath.Join(root, "acme", "widgets.git")
+	work := t.TempDir()
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run(root, "init", "-q", "--bare", "-b", "main", bare)
+	run(work, "init", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(work, "README.md"), []byte("widgets\n"), 0644)
+	run(work, "add", "-A")
+	run(work, "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "-q", "-m", "init")
+	run(work, "push", "-q", bare, "main")
+	return bare
+}
+
+func TestIntegration_OpenPullRequest(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	bare := bareRepo(t, root)
+
+	var got PullRequest
+	var auth string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/repos/acme/widgets/pulls":
+			json.NewDecoder(r.Body).Decode(&got)
+			json.NewEncoder(w).Encode(Created{Number: 7, HTMLURL: "https://github.test/acme/widgets/pull/7"})
+		case "/repos/acme/widgets/pulls/7/reviews":
+			json.NewEncoder(w).Encode(Created{HTMLURL: "https://github.test/acme/widgets/pull/7#review"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+
+	g := New(Config{Token: "secret", APIURL: api.URL, GitURL: root})
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	task := agent.NewTask("Add a greeting\n\nSay hello.", []identity.CapabilityType{identity.CapCodeWrite}).
+		WithMetadata(map[string]string{MetaRepo: "acme/widgets", MetaBaseBranch: "main"})
+	output := "Here you go:\n\n```diff\n--- a/README.md\n+++ b/README.md\n@@ -1 +1,2 @@\n widgets\n+hello\n```\n\n```go hello/hello.go\npackage hello\n```\n"
+	result := &agent.TaskResult{TaskID: task.ID, Status: agent.TaskCompleted, Output: output}
+
+	summary, err := g.Handle(context.Background(), coder, task, result)
+	if err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if summary != "opened https://github.test/acme/widgets/pull/7" {
+		t.Errorf("Expected the pull request URL, got %q", summary)
+	}
+	branch := DefaultBranchPrefix + task.ID[:8]
+	if got.Head != branch || got.Base != "main" || got.Title != "Add a greeting" || auth != "Bearer secret" {
+		t.Errorf("Unexpected pull request %+v (auth %q)", got, auth)
+	}
+
+	show := func(file string) string {
+		out, err := exec.Command("git", "-C", bare, "show", branch+":"+file).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git show %s: %v: %s", file, err, out)
+		}
+		return string(out)
+	}
+	if show("README.md") != "widgets\nhello\n" || show("hello/hello.go") != "package hello\n" {
+		t.Errorf("Expected the patch and file committed to %s", branch)
+	}
+
+	// Review tasks post the output on the pull request
+	reviewer, _ := agent.NewAgent(agent.AgentConfig{Name: "reviewer", Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+	review := agent.NewTask("Review", []identity.CapabilityType{identity.CapCodeReview}).
+		WithMetadata(map[string]string{MetaRepo: "acme/widgets", MetaPullRequest: "7"})
+	summary, err = g.Handle(context.Background(), reviewer, review, &agent.TaskResult{Status: agent.TaskCompleted, Output: "No issues found."})
+	if err != nil || !strings.HasPrefix(summary, "reviewed ") {
+		t.Errorf("Expected a review, got %q, %v", summary, err)
+	}
+
+	// Tasks without a repo, and agents without the capability, are passed over
+	if summary, _ := g.Handle(context.Background(), reviewer, task, result); summary != "" {
+		t.Errorf("Expected a reviewer not to open pull requests, got %q", summary)
+	}
+	if summary, _ := g.Handle(context.Background(), coder, agent.NewTask("x", nil), result); summary != "" {
+		t.Errorf("Expected tasks without a repo ignored, got %q", summary)
+	}
+
+	// An output without changes fails
+	empty := &agent.TaskResult{Status: agent.TaskCompleted, Output: "I could not do it."}
+	if _, err := g.Handle(context.Background(), coder, task, empty); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges, got %v", err)
+	}
+}
+
+func TestIntegration_Errors(t *testing.T) {
+	t.Setenv("SQM_TEST_GITHUB_TOKEN", "")
+	g := New(Config{TokenEnv: "SQM_TEST_GITHUB_TOKEN"})
+	coder, _ := agent.NewAgent(agent.AgentConfig{Name: "coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	result := &agent.TaskResult{Status: agent.TaskCompleted, Output: "```go a.go\npackage a\n```"}
+
+	task := agent.NewTask("x", []identity.CapabilityType{identity.CapCodeWrite}).WithMetadata(map[string]string{MetaRepo: "acme/widgets"})
+	if _, err := g.Handle(context.Background(), coder, task, result); !errors.Is(err, ErrNoToken) {
+		t.Errorf("Expected ErrNoToken, got %v", err)
+	}
+	task.Metadata[MetaRepo] = "../etc"
+	if _, err := g.Handle(context.Background(), coder, task, result); !errors.Is(err, ErrInvalidRepo) {
+		t.Errorf("Expected ErrInvalidRepo, got %v", err)
+	}
+}
+
+func TestExtractChanges(t *testing.T) {
+	changes, err := ExtractChanges("```patch\n--- a/x\n+++ b/x\n```\n```python ./tools/run.py\nprint(1)\n```\n```go\nunnamed\n```")
+	if err != nil {
+		t.Fatalf("ExtractChanges failed: %v", err)
+	}
+	if len(changes.Patches) != 1 || len(changes.Files) != 1 || changes.Files["tools/run.py"] != "print(1)\n" {
+		t.Errorf("Expected a patch and one named file, got %+v", changes)
+	}
+
+	for _, name := range []string{"../outside.go", "/etc/passwd", ".git/config"} {
+		if _, err := ExtractChanges("```go " + name + "\nx\n```"); !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("Expected ErrUnsafePath for %s, got %v", name, err)
+		}
+	}
+	if _, err := ExtractChanges("no code here"); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("Expected ErrNoChanges, got %v", err)
+	}
+}
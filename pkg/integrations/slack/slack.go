@@ -0,0 +1,595 @@
+// Package slack runs a Slack bot that takes tasks for a collective. Members
+// mention the bot in a channel, or message it directly, with a task
+// description. The bot answers in a thread and keeps a status message there
+// up to date as the task is assigned and the agent's output streams in,
+// then posts the result. Events arrive through the Slack Events API at the
+// bot's HTTP handler; replies go through the Web API. Each Slack user
+// submits as their own submitter, so fair scheduling, budgets, usage
+// reports and the audit log see who asked for what.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+var (
+	ErrNoToken         = errors.New("no Slack bot token configured")
+	ErrNoSigningSecret = errors.New("no Slack signing secret configured")
+	ErrBadSignature    = errors.New("invalid Slack request signature")
+)
+
+// Task metadata keys the bot sets, recording where a task came from
+const (
+	MetaChannel = "slack_channel"
+	MetaUser    = "slack_user"
+	MetaThread  = "slack_thread"
+)
+
+// Defaults
+const (
+	DefaultAPIURL           = "https://slack.com/api"
+	DefaultTokenEnv         = "SLACK_BOT_TOKEN"
+	DefaultSigningSecretEnv = "SLACK_SIGNING_SECRET"
+	DefaultTimeout          = 30 * time.Minute
+	DefaultUpdateInterval   = 3 * time.Second
+
+	// SubmitterPrefix is prepended to the IDs of Slack users not in Users
+	SubmitterPrefix = "slack:"
+
+	maxRequestAge = 5 * time.Minute // Older signed requests are rejected as replays
+	maxMessageLen = 3000            // Longer results are posted in several messages
+	maxStreamLen  = 1500            // Output tail shown in the status message
+)
+
+// Config configures the bot
+type Config struct {
+	Token            string `json:"-" yaml:"token,omitempty"`                                         // Bot token (xoxb-...)
+	TokenEnv         string `json:"token_env,omitempty" yaml:"token_env,omitempty"`                   // Variable the token is read from if Token is empty (default SLACK_BOT_TOKEN)
+	SigningSecret    string `json:"-" yaml:"signing_secret,omitempty"`                                // Verifies that events come from Slack
+	SigningSecretEnv string `json:"signing_secret_env,omitempty" yaml:"signing_secret_env,omitempty"` // Default SLACK_SIGNING_SECRET
+	APIURL           string `json:"api_url,omitempty" yaml:"api_url,omitempty"`                       // Web API root (default https://slack.com/api)
+
+	Channels []string          `json:"channels,omitempty" yaml:"channels,omitempty"` // Channel IDs tasks are taken from (empty = any the bot is in); direct messages are always taken
+	Users    map[string]string `json:"users,omitempty" yaml:"users,omitempty"`       // Slack user ID -> submitter (others submit as slack:<user ID>)
+
+	Timeout        time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`                 // Per task (default 30m)
+	UpdateInterval time.Duration `json:"update_interval,omitempty" yaml:"update_interval,omitempty"` // How often streamed output is shown (default 3s)
+}
+
+func (c Config) withDefaults() Config {
+	if c.TokenEnv == "" {
+		c.TokenEnv = DefaultTokenEnv
+	}
+	if c.SigningSecretEnv == "" {
+		c.SigningSecretEnv = DefaultSigningSecretEnv
+	}
+	if c.APIURL == "" {
+		c.APIURL = DefaultAPIURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.UpdateInterval <= 0 {
+		c.UpdateInterval = DefaultUpdateInterval
+	}
+	c.APIURL = strings.TrimRight(c.APIURL, "/")
+	return c
+}
+
+// token returns the configured bot token, looking it up in TokenEnv at each
+// call so it can be rotated without a restart
+func (c Config) token() string {
+	if c.Token != "" {
+		return c.Token
+	}
+	return os.Getenv(c.TokenEnv)
+}
+
+// signingSecret returns the configured signing secret
+func (c Config) signingSecret() string {
+	if c.SigningSecret != "" {
+		return c.SigningSecret
+	}
+	return os.Getenv(c.SigningSecretEnv)
+}
+
+// Collective is what the bot submits tasks to; *collective.Collective
+// implements it
+type Collective interface {
+	SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error)
+	SubscribeEvents() (<-chan collective.Event, func())
+	GetAgent(sid string) (*agent.Agent, bool)
+}
+
+// Submission is a task request read from a Slack message
+type Submission struct {
+	Channel string
+	User    string
+	Text    string
+	TS      string // The message's timestamp, which threads replies to it
+}
+
+// Bot takes tasks from Slack. It is an http.Handler for the Events API.
+type Bot struct {
+	mu sync.Mutex
+
+	cfg        Config
+	collective Collective
+	client     *http.Client
+	seen       map[string]time.Time // Event IDs handled, since Slack retries slow deliveries
+	logger     logging.Logger
+	now        func() time.Time
+
+	ctx    context.Context // Ends the tasks in flight on Close
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a bot submitting to c
+func New(cfg Config, c Collective) *Bot {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bot{
+		cfg:        cfg.withDefaults(),
+		collective: c,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		seen:       make(map[string]time.Time),
+		logger:     logging.Component("slack"),
+		now:        time.Now,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Validate checks the bot has its credentials
+func (b *Bot) Validate() error {
+	if b.cfg.token() == "" {
+		return fmt.Errorf("%w (set token or %s)", ErrNoToken, b.cfg.TokenEnv)
+	}
+	if b.cfg.signingSecret() == "" {
+		return fmt.Errorf("%w (set signing_secret or %s)", ErrNoSigningSecret, b.cfg.SigningSecretEnv)
+	}
+	return nil
+}
+
+// Close cancels the tasks in flight and waits for their last replies
+func (b *Bot) Close() {
+	b.cancel()
+	b.wg.Wait()
+}
+
+// Submitter returns who a Slack user submits tasks as
+func (b *Bot) Submitter(user string) string {
+	if s, ok := b.cfg.Users[user]; ok && s != "" {
+		return s
+	}
+	return SubmitterPrefix + user
+}
+
+// envelope is an Events API request
+type envelope struct {
+	Type      string       `json:"type"`
+	Challenge string       `json:"challenge"`
+	EventID   string       `json:"event_id"`
+	Event     messageEvent `json:"event"`
+}
+
+// messageEvent is a message or app_mention event
+type messageEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// ServeHTTP handles the Events API: it answers Slack's URL verification and
+// starts a task for each accepted message, replying before the task runs
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := b.verify(r.Header, body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch env.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, env.Challenge)
+		return
+	case "event_callback":
+		if sub, ok := b.accept(env.Event); ok && b.firstDelivery(env.EventID) {
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				b.Run(b.ctx, sub)
+			}()
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks a request's signature and that it isn't a replay
+func (b *Bot) verify(h http.Header, body []byte) error {
+	secret := b.cfg.signingSecret()
+	if secret == "" {
+		return ErrNoSigningSecret
+	}
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrBadSignature)
+	}
+	if age := b.now().Sub(time.Unix(sec, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return fmt.Errorf("%w: stale timestamp", ErrBadSignature)
+	}
+	if !hmac.Equal([]byte(h.Get("X-Slack-Signature")), []byte(Sign(secret, ts, body))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Sign returns the X-Slack-Signature of a request body sent at ts
+func Sign(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// mention matches a user mention such as <@U123> or <@U123|name>
+var mention = regexp.MustCompile(`<@[A-Z0-9]+(?:\|[^>]*)?>`)
+
+// accept reads a task request from a message: a mention of the bot in an
+// allowed channel, or a direct message. Messages from bots, edits and
+// other subtypes are ignored.
+func (b *Bot) accept(ev messageEvent) (Submission, bool) {
+	if ev.BotID != "" || ev.Subtype != "" || ev.User == "" {
+		return Submission{}, false
+	}
+	switch {
+	case ev.Type == "message" && ev.ChannelType == "im":
+	case ev.Type == "app_mention" && b.allowed(ev.Channel):
+	default:
+		return Submission{}, false
+	}
+
+	text := strings.TrimSpace(mention.ReplaceAllString(ev.Text, ""))
+	if text == "" {
+		return Submission{}, false
+	}
+	ts := ev.ThreadTS
+	if ts == "" {
+		ts = ev.TS
+	}
+	return Submission{Channel: ev.Channel, User: ev.User, Text: text, TS: ts}, true
+}
+
+// allowed reports whether tasks are taken from a channel
+func (b *Bot) allowed(channel string) bool {
+	if len(b.cfg.Channels) == 0 {
+		return true
+	}
+	for _, c := range b.cfg.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// firstDelivery reports whether an event is new, remembering it for a while
+func (b *Bot) firstDelivery(eventID string) bool {
+	if eventID == "" {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	for id, at := range b.seen {
+		if now.Sub(at) > time.Hour {
+			delete(b.seen, id)
+		}
+	}
+	if _, ok := b.seen[eventID]; ok {
+		return false
+	}
+	b.seen[eventID] = now
+	return true
+}
+
+// Run submits a request as a task and reports on it in the request's
+// thread until it finishes or ctx ends
+func (b *Bot) Run(ctx context.Context, sub Submission) *agent.TaskResult {
+	ctx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+
+	progress := agent.NewProgress()
+	task := agent.NewTask(sub.Text, nil).
+		WithSubmitter(b.Submitter(sub.User)).
+		WithMetadata(map[string]string{MetaChannel: sub.Channel, MetaUser: sub.User, MetaThread: sub.TS}).
+		WithProgress(progress)
+	log := b.logger.With("task", task.ID, "channel", sub.Channel, "user", sub.User)
+
+	// Replies must outlive ctx to report a cancelled task
+	reply := context.WithoutCancel(ctx)
+	status := &statusMessage{bot: b, channel: sub.Channel, thread: sub.TS}
+	status.set(reply, fmt.Sprintf(":hourglass_flowing_sand: Working on task `%s` for <@%s>", shortID(task.ID), sub.User), "")
+
+	events, unsubscribe := b.collective.SubscribeEvents()
+	defer unsubscribe()
+	done := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		b.watch(reply, task.ID, events, progress, status, done)
+	}()
+
+	result, err := b.collective.SubmitCtx(ctx, task)
+	close(done)
+	<-watched
+
+	switch {
+	case result != nil && result.Status == agent.TaskCompleted:
+		status.set(reply, fmt.Sprintf(":white_check_mark: Task `%s` completed by %s in %s", shortID(task.ID), b.agentName(result.AgentSID), result.Duration.Round(time.Second)), "")
+		for _, chunk := range chunks(result.Output, maxMessageLen) {
+			if _, err := b.post(reply, sub.Channel, sub.TS, chunk); err != nil {
+				log.Warn("posting result failed", "error", err)
+				break
+			}
+		}
+	default:
+		reason := "unknown error"
+		if err != nil {
+			reason = err.Error()
+		} else if result != nil && result.Error != "" {
+			reason = result.Error
+		}
+		status.set(reply, fmt.Sprintf(":x: Task `%s` failed: %s", shortID(task.ID), reason), "")
+		if result != nil && result.Output != "" {
+			_, _ = b.post(reply, sub.Channel, sub.TS, "Partial output:\n"+truncate(result.Output, maxMessageLen))
+		}
+	}
+	log.Info("slack task finished", "error", err)
+	return result
+}
+
+// watch updates the status message with the task's collective events and
+// streamed output until done is closed
+func (b *Bot) watch(ctx context.Context, taskID string, events <-chan collective.Event, progress *agent.Progress, status *statusMessage, done <-chan struct{}) {
+	ticker := time.NewTicker(b.cfg.UpdateInterval)
+	defer ticker.Stop()
+
+	line := status.current()
+	streamed := ""
+	for {
+		select {
+		case <-done:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if ev.TaskID != taskID {
+				continue
+			}
+			if text := b.describe(ev); text != "" {
+				line = text
+				status.set(ctx, line, streamed)
+			}
+		case <-ticker.C:
+			if out := progress.Output(); out != streamed {
+				streamed = out
+				status.set(ctx, line, streamed)
+			}
+		}
+	}
+}
+
+// describe renders a collective event about a task as a status line
+func (b *Bot) describe(ev collective.Event) string {
+	switch ev.Type {
+	case collective.EventTaskAssigned:
+		return fmt.Sprintf(":gear: Assigned to %s", b.agentName(ev.AgentSID))
+	case collective.EventTaskRequeued:
+		return ":repeat: Requeued for another agent"
+	case collective.EventTaskRetried:
+		return ":repeat: Retrying after a failed attempt"
+	case collective.EventTaskPreempted:
+		return ":pause_button: Preempted by a more urgent task, waiting to resume"
+	case collective.EventTaskExpired:
+		return ":alarm_clock: Deadline passed"
+	}
+	return ""
+}
+
+// agentName returns an agent's name, or its SID once it has left
+func (b *Bot) agentName(sid string) string {
+	if a, ok := b.collective.GetAgent(sid); ok {
+		return "*" + a.Identity.Name + "*"
+	}
+	return "`" + shortID(sid) + "`"
+}
+
+// statusMessage is the thread reply the bot keeps editing
+type statusMessage struct {
+	mu sync.Mutex
+
+	bot     *Bot
+	channel string
+	thread  string
+	ts      string // Set once posted
+	line    string
+}
+
+// current returns the status line
+func (s *statusMessage) current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.line
+}
+
+// set shows a status line and the tail of the streamed output, posting the
+// message the first time
+func (s *statusMessage) set(ctx context.Context, line, output string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.line = line
+	text := line
+	if output != "" {
+		text += "\n```" + tail(output, maxStreamLen) + "```"
+	}
+
+	var err error
+	if s.ts == "" {
+		s.ts, err = s.bot.post(ctx, s.channel, s.thread, text)
+	} else {
+		err = s.bot.update(ctx, s.channel, s.ts, text)
+	}
+	if err != nil {
+		s.bot.logger.Warn("updating slack status failed", "channel", s.channel, "error", err)
+	}
+}
+
+// post sends a message, in a thread if one is given, and returns its
+// timestamp
+func (b *Bot) post(ctx context.Context, channel, thread, text string) (string, error) {
+	var resp struct {
+		TS string `json:"ts"`
+	}
+	err := b.api(ctx, "chat.postMessage", map[string]string{"channel": channel, "thread_ts": thread, "text": text}, &resp)
+	return resp.TS, err
+}
+
+// update replaces the text of a message
+func (b *Bot) update(ctx context.Context, channel, ts, text string) error {
+	return b.api(ctx, "chat.update", map[string]string{"channel": channel, "ts": ts, "text": text}, nil)
+}
+
+// api calls a Web API method
+func (b *Bot) api(ctx context.Context, method string, body, out any) error {
+	token := b.cfg.token()
+	if token == "" {
+		return ErrNoToken
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.cfg.APIURL+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack %s: %s: %s", method, resp.Status, strings.TrimSpace(string(raw)))
+	}
+	// Slack reports failures in the body of a 200
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return fmt.Errorf("slack %s: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s: %s", method, status.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// chunks splits text into pieces of at most n bytes, at line breaks where
+// possible
+func chunks(text string, n int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return []string{"(no output)"}
+	}
+	var out []string
+	for len(text) > n {
+		cut := strings.LastIndex(text[:n], "\n")
+		if cut <= 0 {
+			cut = n
+		}
+		out = append(out, text[:cut])
+		text = strings.TrimLeft(text[cut:], "\n")
+	}
+	return append(out, text)
+}
+
+// tail returns the last n bytes of text
+func tail(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return "..." + text[len(text)-n:]
+}
+
+// truncate returns the first n bytes of text
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return text[:n] + "..."
+}
+
+// shortID returns the first eight characters of an ID
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
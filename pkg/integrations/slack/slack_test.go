@@ -0,0 +1,263 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// fakeCollective records submitted tasks and streams output into them
+type fakeCollective struct {
+	mu     sync.Mutex
+	tasks  []*agent.Task
+	output string
+	err    error
+	events chan collective.Event
+}
+
+func (f *fakeCollective) SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error) {
+	f.mu.Lock()
+	f.tasks = append(f.tasks, task)
+	f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	task.Progress().AppendOutput(f.output)
+	return &agent.TaskResult{TaskID: task.ID, AgentSID: "sid-1", Status: agent.TaskCompleted, Output: f.output, Duration: time.Second}, nil
+}
+
+func (f *fakeCollective) SubscribeEvents() (<-chan collective.Event, func()) {
+	return f.events, func() {}
+}
+
+func (f *fakeCollective) GetAgent(sid string) (*agent.Agent, bool) {
+	return nil, false
+}
+
+func (f *fakeCollective) submitted() []*agent.Task {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*agent.Task(nil), f.tasks...)
+}
+
+// fakeSlack is a Web API recording the messages posted and updated
+type fakeSlack struct {
+	mu      sync.Mutex
+	posts   []map[string]string
+	updates []map[string]string
+	auth    string
+}
+
+func (f *fakeSlack) server() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.auth = r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/chat.postMessage":
+			f.posts = append(f.posts, body)
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": "2000." + strconv.Itoa(len(f.posts))})
+		case "/chat.update":
+			f.updates = append(f.updates, body)
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": "unknown_method"})
+		}
+	}))
+}
+
+// signed builds an Events API request signed with secret
+func signed(t *testing.T, secret string, payload interface{}) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(string(body)))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", Sign(secret, ts, body))
+	return req
+}
+
+func TestBot_URLVerification(t *testing.T) {
+	bot := New(Config{Token: "xoxb", SigningSecret: "shh"}, &fakeCollective{})
+	defer bot.Close()
+
+	rec := httptest.NewRecorder()
+	bot.ServeHTTP(rec, signed(t, "shh", map[string]string{"type": "url_verification", "challenge": "abc"}))
+	if rec.Code != http.StatusOK || rec.Body.String() != "abc" {
+		t.Errorf("Expected challenge abc, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBot_RejectsBadSignature(t *testing.T) {
+	fc := &fakeCollective{}
+	bot := New(Config{Token: "xoxb", SigningSecret: "shh"}, fc)
+	defer bot.Close()
+
+	rec := httptest.NewRecorder()
+	bot.ServeHTTP(rec, signed(t, "wrong", map[string]string{"type": "url_verification", "challenge": "abc"}))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rec.Code)
+	}
+
+	req := signed(t, "shh", map[string]string{"type": "url_verification"})
+	req.Header.Set("X-Slack-Request-Timestamp", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	rec = httptest.NewRecorder()
+	bot.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected stale request to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestBot_MentionSubmitsTask(t *testing.T) {
+	api := &fakeSlack{}
+	srv := api.server()
+	defer srv.Close()
+
+	fc := &fakeCollective{output: "The answer is 42", events: make(chan collective.Event)}
+	bot := New(Config{
+		Token:         "xoxb-test",
+		SigningSecret: "shh",
+		APIURL:        srv.URL,
+		Channels:      []string{"C1"},
+		Users:         map[string]string{"U1": "alice"},
+	}, fc)
+
+	event := map[string]interface{}{
+		"type":     "event_callback",
+		"event_id": "Ev1",
+		"event": map[string]string{
+			"type":    "app_mention",
+			"channel": "C1",
+			"user":    "U1",
+			"text":    "<@UBOT> summarize the report",
+			"ts":      "1000.1",
+		},
+	}
+	for i := 0; i < 2; i++ { // Slack's retry of the same event is ignored
+		rec := httptest.NewRecorder()
+		bot.ServeHTTP(rec, signed(t, "shh", event))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rec.Code)
+		}
+	}
+	bot.Close()
+
+	tasks := fc.submitted()
+	if len(tasks) != 1 {
+		t.Fatalf("Expected 1 task, got %d", len(tasks))
+	}
+	task := tasks[0]
+	if task.Description != "summarize the report" {
+		t.Errorf("Expected mention stripped from description, got %q", task.Description)
+	}
+	if task.Submitter != "alice" {
+		t.Errorf("Expected submitter alice, got %q", task.Submitter)
+	}
+	if task.Metadata[MetaChannel] != "C1" || task.Metadata[MetaUser] != "U1" {
+		t.Errorf("Expected slack metadata, got %v", task.Metadata)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if api.auth != "Bearer xoxb-test" {
+		t.Errorf("Expected bot token, got %q", api.auth)
+	}
+	if len(api.posts) != 2 {
+		t.Fatalf("Expected status and result posts, got %d", len(api.posts))
+	}
+	for _, p := range api.posts {
+		if p["channel"] != "C1" || p["thread_ts"] != "1000.1" {
+			t.Errorf("Expected reply in thread 1000.1, got %v", p)
+		}
+	}
+	if api.posts[1]["text"] != "The answer is 42" {
+		t.Errorf("Expected result posted, got %q", api.posts[1]["text"])
+	}
+	last := api.updates[len(api.updates)-1]
+	if last["ts"] != "2000.1" || !strings.Contains(last["text"], "completed") {
+		t.Errorf("Expected status message marked completed, got %v", last)
+	}
+}
+
+func TestBot_IgnoredMessages(t *testing.T) {
+	fc := &fakeCollective{events: make(chan collective.Event)}
+	bot := New(Config{Token: "xoxb", SigningSecret: "shh", Channels: []string{"C1"}}, fc)
+
+	cases := []messageEvent{
+		{Type: "app_mention", Channel: "C2", User: "U1", Text: "<@UBOT> hi"},            // Channel not allowed
+		{Type: "message", Channel: "C1", User: "U1", Text: "hi"},                        // Not a mention
+		{Type: "message", ChannelType: "im", BotID: "B1", Channel: "D1", Text: "hi"},    // From a bot
+		{Type: "message", ChannelType: "im", Subtype: "message_changed", Channel: "D1"}, // An edit
+		{Type: "app_mention", Channel: "C1", User: "U1", Text: "<@UBOT>"},               // Nothing asked
+	}
+	for i, ev := range cases {
+		if _, ok := bot.accept(ev); ok {
+			t.Errorf("Case %d: expected message to be ignored", i)
+		}
+	}
+	sub, ok := bot.accept(messageEvent{Type: "message", ChannelType: "im", Channel: "D1", User: "U9", Text: "hi", TS: "1.0"})
+	if !ok || bot.Submitter(sub.User) != "slack:U9" {
+		t.Errorf("Expected direct message accepted as slack:U9, got %v %v", sub, ok)
+	}
+	bot.Close()
+}
+
+func TestBot_FailedTask(t *testing.T) {
+	api := &fakeSlack{}
+	srv := api.server()
+	defer srv.Close()
+
+	fc := &fakeCollective{err: errors.New("no capable agent"), events: make(chan collective.Event)}
+	bot := New(Config{Token: "xoxb", SigningSecret: "shh", APIURL: srv.URL}, fc)
+	defer bot.Close()
+
+	bot.Run(context.Background(), Submission{Channel: "C1", User: "U1", Text: "do it", TS: "1.0"})
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.updates) == 0 || !strings.Contains(api.updates[len(api.updates)-1]["text"], "no capable agent") {
+		t.Errorf("Expected failure reported in the status message, got %v", api.updates)
+	}
+}
+
+func TestBot_Validate(t *testing.T) {
+	t.Setenv("SLACK_BOT_TOKEN", "")
+	t.Setenv("SLACK_SIGNING_SECRET", "")
+	bot := New(Config{}, &fakeCollective{})
+	defer bot.Close()
+	if err := bot.Validate(); !errors.Is(err, ErrNoToken) {
+		t.Errorf("Expected ErrNoToken, got %v", err)
+	}
+	t.Setenv("SLACK_BOT_TOKEN", "xoxb")
+	if err := bot.Validate(); !errors.Is(err, ErrNoSigningSecret) {
+		t.Errorf("Expected ErrNoSigningSecret, got %v", err)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	text := strings.Repeat("line\n", 10)
+	got := chunks(text, 12)
+	if strings.Join(got, "\n") != strings.TrimSpace(text) {
+		t.Errorf("Expected chunks to rejoin to the text, got %q", got)
+	}
+	for _, c := range got {
+		if len(c) > 12 {
+			t.Errorf("Expected chunk of at most 12 bytes, got %q", c)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrEmptySuite = errors.New("calibration suite has no tasks")
+
+// Grader scores a model response to a golden task from 0.0 to 1.0
+type Grader func(response string) float64
+
+// GoldenTask is a calibration prompt with a known-good grading rule
+type GoldenTask struct {
+	Name    string
+	Request CompletionRequest
+	Grade   Grader
+	Weight  float64 // Relative importance (0 treated as 1)
+}
+
+// CalibrationSuite is a fixed set of golden tasks used to gate model promotion
+type CalibrationSuite struct {
+	Tasks []GoldenTask
+}
+
+// GoldenResult is the outcome of one golden task
+type GoldenResult struct {
+	Task     string        `json:"task"`
+	Score    float64       `json:"score"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// CalibrationReport summarizes a calibration run
+type CalibrationReport struct {
+	Provider  string         `json:"provider"`
+	Score     float64        `json:"score"` // Weighted mean of task scores
+	Results   []GoldenResult `json:"results"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// Run executes every golden task against a provider. Tasks that error score 0.
+func (s *CalibrationSuite) Run(ctx context.Context, p Provider) (CalibrationReport, error) {
+	report := CalibrationReport{
+		Provider:  p.Name(),
+		Timestamp: time.Now(),
+	}
+
+	if len(s.Tasks) == 0 {
+		return report, ErrEmptySuite
+	}
+
+	var weighted, totalWeight float64
+	for _, task := range s.Tasks {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		weight := task.Weight
+		if weight == 0 {
+			weight = 1
+		}
+
+		start := time.Now()
+		result := GoldenResult{Task: task.Name}
+
+		resp, err := p.Complete(ctx, task.Request)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Score = clampScore(task.Grade(resp.Content))
+		}
+		result.Duration = time.Since(start)
+
+		report.Results = append(report.Results, result)
+		weighted += result.Score * weight
+		totalWeight += weight
+	}
+
+	report.Score = weighted / totalWeight
+	return report, nil
+}
+
+// ContainsGrader scores the fraction of expected substrings present in a response (case-insensitive)
+func ContainsGrader(expected ...string) Grader {
+	return func(response string) float64 {
+		if len(expected) == 0 {
+			return 1
+		}
+		lower := strings.ToLower(response)
+		found := 0
+		for _, e := range expected {
+			if strings.Contains(lower, strings.ToLower(e)) {
+				found++
+			}
+		}
+		return float64(found) / float64(len(expected))
+	}
+}
+
+// ExactGrader scores 1 if the trimmed response equals the expected answer
+func ExactGrader(expected string) Grader {
+	return func(response string) float64 {
+		if strings.TrimSpace(response) == strings.TrimSpace(expected) {
+			return 1
+		}
+		return 0
+	}
+}
+
+// clampScore bounds a grader score to [0, 1]
+func clampScore(score float64) float64 {
+	if score < 0 {
+		return 0
+	}
+	if score > 1 {
+		return 1
+	}
+	return score
+}
@@ -0,0 +1,24 @@
+package llm
+
+import "context"
+
+// Caller identifies the agent and task a request is made for, so provider
+// middleware such as request logs can attribute it
+type Caller struct {
+	AgentSID string `json:"agent_sid,omitempty"`
+	Agent    string `json:"agent,omitempty"`
+	TaskID   string `json:"task_id,omitempty"`
+}
+
+type callerKey struct{}
+
+// ContextWithCaller returns a context whose requests are made for c
+func ContextWithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+// CallerFromContext returns who requests under ctx are made for
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(Caller)
+	return c, ok
+}
@@ -0,0 +1,351 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoCandidateEndpoint = errors.New("candidate has no endpoint")
+
+// CandidateConfig controls promotion and demotion of a candidate model
+type CandidateConfig struct {
+	QualityBar       float64       // Minimum calibration score for promotion
+	RegressionMargin float64       // Demote when live quality falls this far below the bar
+	QualityWindow    int           // Number of live quality samples averaged for regression checks
+	RecheckInterval  time.Duration // How often Run re-calibrates (0 = only once)
+}
+
+// DefaultCandidateConfig returns sensible defaults
+func DefaultCandidateConfig() CandidateConfig {
+	return CandidateConfig{
+		QualityBar:       0.8,
+		RegressionMargin: 0.05,
+		QualityWindow:    20,
+		RecheckInterval:  time.Hour,
+	}
+}
+
+// CandidateSpec configures a candidate slot in the config file. Unset
+// thresholds take DefaultCandidateConfig's values.
+type CandidateSpec struct {
+	Endpoint         string        `json:"endpoint" yaml:"endpoint"`                                       // Chat completions URL of the OpenAI-compatible server hosting the candidate
+	Model            string        `json:"model,omitempty" yaml:"model,omitempty"`                         // Candidate model name
+	QualityBar       float64       `json:"quality_bar,omitempty" yaml:"quality_bar,omitempty"`             // Calibration score needed for promotion
+	RegressionMargin float64       `json:"regression_margin,omitempty" yaml:"regression_margin,omitempty"` // Live quality this far below the bar demotes
+	QualityWindow    int           `json:"quality_window,omitempty" yaml:"quality_window,omitempty"`       // Task results averaged for regression checks
+	RecheckInterval  time.Duration `json:"recheck_interval,omitempty" yaml:"recheck_interval,omitempty"`   // How often the candidate is re-calibrated
+	Suite            []GoldenSpec  `json:"suite" yaml:"suite"`                                             // Golden tasks the candidate must pass
+}
+
+// GoldenSpec is a golden task in the config file, graded by the substrings
+// or the exact answer expected
+type GoldenSpec struct {
+	Name     string   `json:"name" yaml:"name"`
+	System   string   `json:"system,omitempty" yaml:"system,omitempty"`
+	Prompt   string   `json:"prompt" yaml:"prompt"`
+	Contains []string `json:"contains,omitempty" yaml:"contains,omitempty"`
+	Exact    string   `json:"exact,omitempty" yaml:"exact,omitempty"` // Takes precedence over Contains
+	Weight   float64  `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// Slot returns a slot holding the specified candidate alongside primary
+func (c CandidateSpec) Slot(primary Provider) (*CandidateSlot, error) {
+	if c.Endpoint == "" {
+		return nil, ErrNoCandidateEndpoint
+	}
+
+	cfg := DefaultCandidateConfig()
+	if c.QualityBar > 0 {
+		cfg.QualityBar = c.QualityBar
+	}
+	if c.RegressionMargin > 0 {
+		cfg.RegressionMargin = c.RegressionMargin
+	}
+	if c.QualityWindow > 0 {
+		cfg.QualityWindow = c.QualityWindow
+	}
+	if c.RecheckInterval > 0 {
+		cfg.RecheckInterval = c.RecheckInterval
+	}
+
+	suite := &CalibrationSuite{}
+	for _, g := range c.Suite {
+		grade := ContainsGrader(g.Contains...)
+		if g.Exact != "" {
+			grade = ExactGrader(g.Exact)
+		}
+		suite.Tasks = append(suite.Tasks, GoldenTask{
+			Name:    g.Name,
+			Request: CompletionRequest{Model: c.Model, System: g.System, Prompt: g.Prompt},
+			Grade:   grade,
+			Weight:  g.Weight,
+		})
+	}
+	return NewCandidateSlot(primary, NewLocalProvider(c.Endpoint, c.Model), suite, cfg), nil
+}
+
+// QualityReporter is implemented by providers that act on the measured
+// quality of the task results their responses went into
+type QualityReporter interface {
+	ReportQuality(quality float64)
+}
+
+// ReportQuality passes the quality of a task result to p, or to a provider
+// p wraps, if it acts on it
+func ReportQuality(p Provider, quality float64) {
+	for p != nil {
+		if r, ok := p.(QualityReporter); ok {
+			r.ReportQuality(quality)
+			return
+		}
+		w, ok := p.(interface{ Unwrap() Provider })
+		if !ok {
+			return
+		}
+		p = w.Unwrap()
+	}
+}
+
+// CandidateSlot holds a fine-tuned (typically local) model alongside a primary
+// provider. Requests go to the primary until the candidate passes the
+// calibration suite; a promoted candidate is demoted again if a later
+// calibration or its live quality regresses below the bar.
+type CandidateSlot struct {
+	mu sync.RWMutex
+
+	primary   Provider
+	candidate Provider
+	suite     *CalibrationSuite
+	config    CandidateConfig
+
+	promoted   bool
+	lastReport *CalibrationReport
+	quality    []float64 // Recent live quality samples while promoted
+
+	onChange []func(promoted bool, reason string)
+}
+
+// NewCandidateSlot creates a slot that routes to primary until candidate is promoted
+func NewCandidateSlot(primary, candidate Provider, suite *CalibrationSuite, cfg CandidateConfig) *CandidateSlot {
+	return &CandidateSlot{
+		primary:   primary,
+		candidate: candidate,
+		suite:     suite,
+		config:    cfg,
+	}
+}
+
+// Name returns the provider name
+func (s *CandidateSlot) Name() string {
+	return s.active().Name()
+}
+
+// Complete routes the request to the candidate if promoted, otherwise the primary
+func (s *CandidateSlot) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return s.active().Complete(ctx, req)
+}
+
+// Stream streams from the serving provider if it can and completes otherwise
+func (s *CandidateSlot) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	p := s.active()
+	if streamer, ok := p.(StreamingProvider); ok {
+		return streamer.Stream(ctx, req, onDelta)
+	}
+	return p.Complete(ctx, req)
+}
+
+// Chat sends a chat to the serving provider. One that can't chat gets the
+// messages as a transcript.
+func (s *CandidateSlot) Chat(ctx context.Context, req ChatRequest) (*CompletionResponse, error) {
+	return chat(ctx, s.active(), req)
+}
+
+// CompleteTools passes a tool request to the serving provider if it supports tools
+func (s *CandidateSlot) CompleteTools(ctx context.Context, req ToolRequest) (*CompletionResponse, error) {
+	p := s.active()
+	tp, ok := p.(ToolProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrToolsUnsupported, p.Name())
+	}
+	return tp.CompleteTools(ctx, req)
+}
+
+// Unwrap returns the primary provider
+func (s *CandidateSlot) Unwrap() Provider {
+	return s.primary
+}
+
+// chat sends a chat to p, as a transcript if p can't chat
+func chat(ctx context.Context, p Provider, req ChatRequest) (*CompletionResponse, error) {
+	if cp, ok := p.(ChatProvider); ok {
+		return cp.Chat(ctx, req)
+	}
+	system, prompt := chatTranscript(req.Messages)
+	return p.Complete(ctx, CompletionRequest{
+		Model:       req.Model,
+		System:      system,
+		Prompt:      prompt,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stop:        req.Stop,
+		Reasoning:   req.Reasoning,
+	})
+}
+
+// chatTranscript flattens chat messages into a system prompt and a transcript
+// of the turns for a provider that completes single prompts
+func chatTranscript(messages []Message) (string, string) {
+	var system []string
+	var b strings.Builder
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			system = append(system, m.Content)
+		case "assistant":
+			fmt.Fprintf(&b, "Assistant: %s\n\n", m.Content)
+		default:
+			fmt.Fprintf(&b, "User: %s\n\n", m.Content)
+		}
+	}
+	b.WriteString("Assistant:")
+	return strings.Join(system, "\n\n"), b.String()
+}
+
+// active returns the provider currently serving requests
+func (s *CandidateSlot) active() Provider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.promoted {
+		return s.candidate
+	}
+	return s.primary
+}
+
+// Promoted reports whether the candidate is serving requests
+func (s *CandidateSlot) Promoted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.promoted
+}
+
+// LastReport returns the most recent calibration report, if any
+func (s *CandidateSlot) LastReport() (CalibrationReport, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.lastReport == nil {
+		return CalibrationReport{}, false
+	}
+	return *s.lastReport, true
+}
+
+// OnChange registers a callback invoked when the candidate is promoted or demoted
+func (s *CandidateSlot) OnChange(handler func(promoted bool, reason string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = append(s.onChange, handler)
+}
+
+// Evaluate runs the calibration suite against the candidate and promotes or
+// demotes it based on the quality bar
+func (s *CandidateSlot) Evaluate(ctx context.Context) (CalibrationReport, error) {
+	report, err := s.suite.Run(ctx, s.candidate)
+	if err != nil {
+		return report, err
+	}
+
+	s.mu.Lock()
+	s.lastReport = &report
+	passed := report.Score >= s.config.QualityBar
+	handlers := s.setPromotedLocked(passed)
+	s.mu.Unlock()
+
+	if passed {
+		notifyCandidate(handlers, true, "passed calibration")
+	} else {
+		notifyCandidate(handlers, false, "failed calibration")
+	}
+	return report, nil
+}
+
+// ReportQuality feeds a live quality score for a request served by the
+// candidate. The candidate is demoted if the rolling average regresses.
+func (s *CandidateSlot) ReportQuality(quality float64) {
+	s.mu.Lock()
+
+	if !s.promoted {
+		s.mu.Unlock()
+		return
+	}
+
+	s.quality = append(s.quality, quality)
+	window := s.config.QualityWindow
+	if window <= 0 {
+		window = 1
+	}
+	if len(s.quality) > window {
+		s.quality = s.quality[len(s.quality)-window:]
+	}
+	if len(s.quality) < window {
+		s.mu.Unlock()
+		return
+	}
+
+	var sum float64
+	for _, q := range s.quality {
+		sum += q
+	}
+	if sum/float64(len(s.quality)) >= s.config.QualityBar-s.config.RegressionMargin {
+		s.mu.Unlock()
+		return
+	}
+
+	handlers := s.setPromotedLocked(false)
+	s.mu.Unlock()
+
+	notifyCandidate(handlers, false, "live quality regressed")
+}
+
+// Run calibrates the candidate, then re-calibrates it every RecheckInterval
+// until ctx is cancelled
+func (s *CandidateSlot) Run(ctx context.Context) {
+	_, _ = s.Evaluate(ctx)
+	if s.config.RecheckInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.config.RecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, _ = s.Evaluate(ctx)
+		}
+	}
+}
+
+// setPromotedLocked updates promotion state and returns change handlers to
+// notify, or nil if nothing changed. Caller must hold s.mu.
+func (s *CandidateSlot) setPromotedLocked(promoted bool) []func(bool, string) {
+	if s.promoted == promoted {
+		return nil
+	}
+	s.promoted = promoted
+	s.quality = nil
+	return s.onChange
+}
+
+// notifyCandidate invokes promotion change handlers
+func notifyCandidate(handlers []func(bool, string), promoted bool, reason string) {
+	for _, h := range handlers {
+		h(promoted, reason)
+	}
+}
@@ -0,0 +1,205 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTestSlot makes a slot whose candidate answers "candidate", graded by grade
+func newTestSlot(grade Grader, cfg CandidateConfig) (*CandidateSlot, *[]string) {
+	var calls []string
+	primary := &fakeProvider{name: "primary", calls: &calls}
+	cand := &fakeProvider{name: "candidate", calls: &calls}
+	suite := &CalibrationSuite{Tasks: []GoldenTask{
+		{Name: "graded", Grade: grade},
+		{Name: "easy", Grade: ContainsGrader("candidate")},
+	}}
+	return NewCandidateSlot(primary, cand, suite, cfg), &calls
+}
+
+func TestCandidateSlot_Promotion(t *testing.T) {
+	slot, calls := newTestSlot(ContainsGrader("candidate"), DefaultCandidateConfig())
+	var changes []string
+	slot.OnChange(func(promoted bool, reason string) { changes = append(changes, reason) })
+
+	if resp, _ := slot.Complete(context.Background(), CompletionRequest{}); resp.Content != "primary" {
+		t.Errorf("Expected the primary to serve before calibration, got %q", resp.Content)
+	}
+
+	report, err := slot.Evaluate(context.Background())
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if report.Score != 1 || !slot.Promoted() {
+		t.Errorf("Expected a perfect score to promote, got %f promoted %v", report.Score, slot.Promoted())
+	}
+	*calls = nil
+	if resp, _ := slot.Complete(context.Background(), CompletionRequest{}); resp.Content != "candidate" || slot.Name() != "candidate" {
+		t.Errorf("Expected the candidate to serve once promoted, got %q", resp.Content)
+	}
+	if last, ok := slot.LastReport(); !ok || last.Provider != "candidate" {
+		t.Errorf("Expected the report kept, got %+v", last)
+	}
+
+	// Passing again changes nothing
+	if _, err := slot.Evaluate(context.Background()); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if len(changes) != 1 || changes[0] != "passed calibration" {
+		t.Errorf("Expected one promotion, got %v", changes)
+	}
+}
+
+func TestCandidateSlot_CalibrationFailure(t *testing.T) {
+	slot, _ := newTestSlot(ExactGrader("something else"), DefaultCandidateConfig())
+
+	report, err := slot.Evaluate(context.Background())
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if report.Score != 0.5 || slot.Promoted() {
+		t.Errorf("Expected a score of 0.5 below the bar, got %f promoted %v", report.Score, slot.Promoted())
+	}
+
+	// A promoted candidate failing a re-calibration is demoted
+	slot.suite.Tasks[0].Grade = ContainsGrader("candidate")
+	slot.Evaluate(context.Background())
+	slot.suite.Tasks[0].Grade = ExactGrader("something else")
+	var reason string
+	slot.OnChange(func(promoted bool, why string) { reason = why })
+	slot.Evaluate(context.Background())
+	if slot.Promoted() || reason != "failed calibration" {
+		t.Errorf("Expected demotion for failing calibration, got promoted %v (%q)", slot.Promoted(), reason)
+	}
+}
+
+func TestCandidateSlot_ReportQuality(t *testing.T) {
+	cfg := DefaultCandidateConfig()
+	cfg.QualityWindow = 3
+	slot, _ := newTestSlot(ContainsGrader("candidate"), cfg)
+
+	// Quality isn't tracked until the candidate serves
+	slot.ReportQuality(0)
+	if _, err := slot.Evaluate(context.Background()); err != nil || !slot.Promoted() {
+		t.Fatalf("Expected promotion, got %v", err)
+	}
+
+	tests := []struct {
+		quality  float64
+		promoted bool
+	}{
+		{0.9, true},
+		{0.6, true}, // The window isn't full yet
+		{0.9, true}, // Average 0.8
+		{0.9, true}, // Window 0.6, 0.9, 0.9: average 0.8
+		{0.6, true}, // 0.9, 0.9, 0.6: 0.8
+		{0.5, false},
+	}
+	for i, tt := range tests {
+		ReportQuality(NewRecordingProvider(slot), tt.quality)
+		if slot.Promoted() != tt.promoted {
+			t.Errorf("Sample %d (%.1f): expected promoted %v, got %v", i, tt.quality, tt.promoted, slot.Promoted())
+		}
+	}
+	if resp, _ := slot.Complete(context.Background(), CompletionRequest{}); resp.Content != "primary" {
+		t.Errorf("Expected the primary to serve after demotion, got %q", resp.Content)
+	}
+}
+
+func TestCandidateSlot_EmptySuite(t *testing.T) {
+	slot := NewCandidateSlot(&fakeProvider{name: "primary"}, &fakeProvider{name: "candidate"}, &CalibrationSuite{}, DefaultCandidateConfig())
+
+	if _, err := slot.Evaluate(context.Background()); !errors.Is(err, ErrEmptySuite) {
+		t.Errorf("Expected ErrEmptySuite, got %v", err)
+	}
+	if slot.Promoted() {
+		t.Error("Expected an empty suite not to promote")
+	}
+	if _, ok := slot.LastReport(); ok {
+		t.Error("Expected no report from an empty suite")
+	}
+}
+
+func TestCandidateSpec_Slot(t *testing.T) {
+	if _, err := (CandidateSpec{}).Slot(&fakeProvider{name: "primary"}); !errors.Is(err, ErrNoCandidateEndpoint) {
+		t.Errorf("Expected ErrNoCandidateEndpoint, got %v", err)
+	}
+
+	spec := CandidateSpec{
+		Endpoint:      "http://localhost:8000/v1/chat/completions",
+		Model:         "ft-v2",
+		QualityWindow: 5,
+		Suite:         []GoldenSpec{{Name: "sum", Prompt: "2+2?", Exact: "4"}, {Name: "greet", Prompt: "Hi", Contains: []string{"hello"}}},
+	}
+	slot, err := spec.Slot(&fakeProvider{name: "primary"})
+	if err != nil {
+		t.Fatalf("Slot failed: %v", err)
+	}
+	if slot.config.QualityWindow != 5 || slot.config.QualityBar != DefaultCandidateConfig().QualityBar {
+		t.Errorf("Expected the window set and the bar defaulted, got %+v", slot.config)
+	}
+	if len(slot.suite.Tasks) != 2 || slot.suite.Tasks[0].Request.Model != "ft-v2" {
+		t.Fatalf("Expected 2 golden tasks for the candidate model, got %+v", slot.suite.Tasks)
+	}
+	if slot.suite.Tasks[0].Grade(" 4 ") != 1 || slot.suite.Tasks[1].Grade("Hello there") != 1 {
+		t.Error("Expected the exact and contains graders")
+	}
+}
+
+func TestCandidateSlot_ForwardsToServingProvider(t *testing.T) {
+	var calls []string
+	primary := &fakeProvider{name: "primary", deltas: []string{"pri", "mary"}, calls: &calls}
+	candidate := NewMockProvider(MockScript{Responses: []MockResponse{
+		{Content: "called", ToolCalls: []ToolCall{{ID: "1", Name: "search"}}},
+	}})
+	slot := NewCandidateSlot(primary, candidate, &CalibrationSuite{}, DefaultCandidateConfig())
+	ctx := context.Background()
+
+	if slot.Unwrap() != primary {
+		t.Error("Expected Unwrap to return the primary")
+	}
+
+	var streamed []string
+	if _, err := slot.Stream(ctx, CompletionRequest{}, func(d string) { streamed = append(streamed, d) }); err != nil || strings.Join(streamed, "") != "primaryprimary" {
+		t.Errorf("Expected the primary's deltas streamed, got %v (%v)", streamed, err)
+	}
+	resp, err := slot.Chat(ctx, ChatRequest{Messages: []Message{{Role: "system", Content: "Be brief"}, {Role: "user", Content: "Hi"}}})
+	if err != nil || resp.Content != "primary" {
+		t.Errorf("Expected a primary that can't chat to complete the transcript, got %v (%v)", resp, err)
+	}
+	if _, err := slot.CompleteTools(ctx, ToolRequest{Tools: []Tool{{Name: "search"}}}); !errors.Is(err, ErrToolsUnsupported) {
+		t.Errorf("Expected ErrToolsUnsupported from a primary without tools, got %v", err)
+	}
+
+	slot.mu.Lock()
+	slot.promoted = true
+	slot.mu.Unlock()
+
+	resp, err = slot.CompleteTools(ctx, ToolRequest{Tools: []Tool{{Name: "search"}}})
+	if err != nil || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "search" {
+		t.Errorf("Expected the candidate's tool calls, got %+v (%v)", resp, err)
+	}
+	if resp, err = slot.Chat(ctx, ChatRequest{Messages: []Message{{Role: "user", Content: "Hi"}}}); err != nil || resp.Content != "called" {
+		t.Errorf("Expected the candidate to chat, got %+v (%v)", resp, err)
+	}
+	if slot.Unwrap() != primary {
+		t.Error("Expected Unwrap to return the primary once the candidate is promoted")
+	}
+}
+
+func TestChatTranscript(t *testing.T) {
+	system, prompt := chatTranscript([]Message{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello"},
+		{Role: "user", Content: "Bye"},
+	})
+	if system != "Be brief" {
+		t.Errorf("Expected the system message as the system prompt, got %q", system)
+	}
+	if want := "User: Hi\n\nAssistant: Hello\n\nUser: Bye\n\nAssistant:"; prompt != want {
+		t.Errorf("Expected %q, got %q", want, prompt)
+	}
+}
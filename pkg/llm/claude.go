@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -52,8 +53,137 @@ func (p *ClaudeProvider) Name() string {
 	return "claude"
 }
 
-// Complete generates a completion
+// Complete generates a completion. A response format is enforced by
+// forcing the model to call a tool whose input is the format's schema.
 func (p *ClaudeProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if req.ResponseFormat != nil {
+		return completeFormatted(ctx, req, p.complete)
+	}
+	return p.complete(ctx, req)
+}
+
+// complete makes a single completion request
+func (p *ClaudeProvider) complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	claudeReq := p.completionRequest(req)
+
+	// Make request
+	body, err := json.Marshal(claudeReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var claudeResp claudeResponse
+	if err := json.Unmarshal(respBody, &claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	completion := claudeResp.toCompletion()
+	if req.ResponseFormat != nil {
+		// The forced tool call's input is the response
+		for _, call := range completion.ToolCalls {
+			if call.Name == req.ResponseFormat.name() {
+				completion.Content, completion.ToolCalls = string(call.Input), nil
+				break
+			}
+		}
+	}
+	return completion, nil
+}
+
+// Stream generates a completion, passing text to onDelta as it arrives. If
+// the stream is cut short the text received so far is returned with the error.
+// A response with a format is checked whole and delivered as one delta.
+func (p *ClaudeProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	if req.ResponseFormat != nil {
+		return completeWhole(ctx, req, p.Complete, onDelta)
+	}
+
+	claudeReq := p.completionRequest(req)
+	claudeReq.Stream = true
+
+	body, err := json.Marshal(claudeReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var (
+		content, thinking strings.Builder
+		result            CompletionResponse
+		inputTokens       int
+		outputTokens      int
+		stopped           bool
+	)
+	err = readSSE(resp.Body, func(event string, data []byte) error {
+		var ev claudeStreamEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return fmt.Errorf("failed to unmarshal %s event: %w", event, err)
+		}
+
+		switch ev.Type {
+		case "message_start":
+			inputTokens = ev.Message.Usage.InputTokens
+		case "content_block_delta":
+			switch ev.Delta.Type {
+			case "text_delta":
+				content.WriteString(ev.Delta.Text)
+				if onDelta != nil {
+					onDelta(ev.Delta.Text)
+				}
+			case "thinking_delta":
+				thinking.WriteString(ev.Delta.Thinking)
+			}
+		case "message_delta":
+			result.FinishReason = ev.Delta.StopReason
+			outputTokens = ev.Usage.OutputTokens
+		case "message_stop":
+			stopped = true
+		case "error":
+			return fmt.Errorf("stream error: %s: %s", ev.Error.Type, ev.Error.Message)
+		}
+		return nil
+	})
+
+	result.Content = content.String()
+	result.Thinking = thinking.String()
+	result.TokensUsed = inputTokens + outputTokens
+	result.ThinkingTokens = estimateTokens(result.Thinking)
+	if err == nil && !stopped {
+		err = fmt.Errorf("stream ended before message_stop: %w", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return &result, err
+	}
+	return &result, nil
+}
+
+// completionRequest builds the API request for a single-prompt completion
+func (p *ClaudeProvider) completionRequest(req CompletionRequest) claudeRequest {
 	model := req.Model
 	if model == "" {
 		model = p.model
@@ -80,20 +210,29 @@ func (p *ClaudeProvider) Complete(ctx context.Context, req CompletionRequest) (*
 		claudeReq.System = req.System
 	}
 
-	if req.Temperature > 0 {
-		claudeReq.Temperature = &req.Temperature
-	}
+	claudeReq.Temperature = req.Temperature
 
 	if len(req.Stop) > 0 {
 		claudeReq.StopSequences = req.Stop
 	}
 
-	// Make request
-	body, err := json.Marshal(claudeReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	if f := req.ResponseFormat; f != nil {
+		// Claude can't be made to call a tool while thinking
+		claudeReq.Tools = []claudeTool{{
+			Name:        f.name(),
+			Description: "Respond by calling this tool with your answer as its input.",
+			InputSchema: f.schema(),
+		}}
+		claudeReq.ToolChoice = &claudeToolChoice{Type: "tool", Name: f.name()}
+		return claudeReq
 	}
 
+	applyThinking(&claudeReq, req.Reasoning)
+	return claudeReq
+}
+
+// post sends a request body to the messages API
+func (p *ClaudeProvider) post(ctx context.Context, body []byte) (*http.Response, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -107,35 +246,7 @@ func (p *ClaudeProvider) Complete(ctx context.Context, req CompletionRequest) (*
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
-	}
-
-	var claudeResp claudeResponse
-	if err := json.Unmarshal(respBody, &claudeResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	// Extract content
-	content := ""
-	for _, block := range claudeResp.Content {
-		if block.Type == "text" {
-			content += block.Text
-		}
-	}
-
-	return &CompletionResponse{
-		Content:      content,
-		FinishReason: claudeResp.StopReason,
-		TokensUsed:   claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens,
-	}, nil
+	return resp, nil
 }
 
 // Chat implements chat completion for Claude
@@ -168,14 +279,14 @@ func (p *ClaudeProvider) Chat(ctx context.Context, req ChatRequest) (*Completion
 		System:    system,
 	}
 
-	if req.Temperature > 0 {
-		claudeReq.Temperature = &req.Temperature
-	}
+	claudeReq.Temperature = req.Temperature
 
 	if len(req.Stop) > 0 {
 		claudeReq.StopSequences = req.Stop
 	}
 
+	applyThinking(&claudeReq, req.Reasoning)
+
 	body, err := json.Marshal(claudeReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -210,18 +321,127 @@ func (p *ClaudeProvider) Chat(ctx context.Context, req ChatRequest) (*Completion
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	return claudeResp.toCompletion(), nil
+}
+
+// CompleteTools generates a completion that may end in tool calls. Extended
+// thinking is left off, since Claude requires thinking blocks to be sent
+// back with every tool result.
+func (p *ClaudeProvider) CompleteTools(ctx context.Context, req ToolRequest) (*CompletionResponse, error) {
+	base := req.CompletionRequest
+	base.Reasoning, base.ResponseFormat = nil, nil
+	claudeReq := claudeToolRequest{
+		claudeRequest: p.completionRequest(base),
+		Messages: []claudeToolMessage{
+			{Role: "user", Content: []claudeBlock{{Type: "text", Text: req.Prompt}}},
+		},
+	}
+	for _, t := range req.Tools {
+		claudeReq.Tools = append(claudeReq.Tools, claudeTool{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: toolSchema(t),
+		})
+	}
+	for _, turn := range req.Turns {
+		assistant := claudeToolMessage{Role: "assistant"}
+		if turn.Text != "" {
+			assistant.Content = append(assistant.Content, claudeBlock{Type: "text", Text: turn.Text})
+		}
+		for _, call := range turn.Calls {
+			assistant.Content = append(assistant.Content, claudeBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Name,
+				Input: toolInput(call),
+			})
+		}
+		results := claudeToolMessage{Role: "user"}
+		for _, out := range turn.Outputs {
+			results.Content = append(results.Content, claudeBlock{
+				Type:      "tool_result",
+				ToolUseID: out.CallID,
+				Content:   out.Content,
+				IsError:   out.IsError,
+			})
+		}
+		claudeReq.Messages = append(claudeReq.Messages, assistant, results)
+	}
+
+	body, err := json.Marshal(claudeReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := p.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var claudeResp claudeResponse
+	if err := json.Unmarshal(respBody, &claudeResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	return claudeResp.toCompletion(), nil
+}
+
+// applyThinking enables extended thinking on a request. Claude requires max_tokens
+// to exceed the thinking budget and does not accept a custom temperature with thinking.
+func applyThinking(claudeReq *claudeRequest, reasoning *ReasoningConfig) {
+	if reasoning == nil || reasoning.BudgetTokens <= 0 {
+		return
+	}
+
+	budget := reasoning.BudgetTokens
+	if budget < 1024 {
+		budget = 1024
+	}
+
+	claudeReq.Thinking = &claudeThinking{
+		Type:         "enabled",
+		BudgetTokens: budget,
+	}
+	if claudeReq.MaxTokens <= budget {
+		claudeReq.MaxTokens = budget + claudeReq.MaxTokens
+	}
+	claudeReq.Temperature = nil
+}
+
+// toCompletion converts a Claude response into a CompletionResponse
+func (r *claudeResponse) toCompletion() *CompletionResponse {
 	content := ""
-	for _, block := range claudeResp.Content {
-		if block.Type == "text" {
+	thinking := ""
+	var calls []ToolCall
+	for _, block := range r.Content {
+		switch block.Type {
+		case "text":
 			content += block.Text
+		case "thinking":
+			thinking += block.Thinking
+		case "tool_use":
+			calls = append(calls, ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
 		}
 	}
 
 	return &CompletionResponse{
-		Content:      content,
-		FinishReason: claudeResp.StopReason,
-		TokensUsed:   claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens,
-	}, nil
+		Content:        content,
+		FinishReason:   r.StopReason,
+		TokensUsed:     r.Usage.InputTokens + r.Usage.OutputTokens,
+		ThinkingTokens: estimateTokens(thinking), // Claude bills thinking within output tokens
+		Thinking:       thinking,
+		ToolCalls:      calls,
+	}
 }
 
 // Claude API types
@@ -232,6 +452,51 @@ type claudeRequest struct {
 	System        string          `json:"system,omitempty"`
 	Temperature   *float64        `json:"temperature,omitempty"`
 	StopSequences []string        `json:"stop_sequences,omitempty"`
+	Thinking      *claudeThinking `json:"thinking,omitempty"`
+	Stream        bool            `json:"stream,omitempty"`
+
+	Tools      []claudeTool      `json:"tools,omitempty"`       // Set for a response format
+	ToolChoice *claudeToolChoice `json:"tool_choice,omitempty"` // Forces the format's tool
+}
+
+// claudeToolRequest is a request with tools, whose messages carry content
+// blocks rather than plain text
+type claudeToolRequest struct {
+	claudeRequest
+	Messages []claudeToolMessage `json:"messages"`
+	Tools    []claudeTool        `json:"tools,omitempty"`
+}
+
+type claudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type claudeToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type claudeToolMessage struct {
+	Role    string        `json:"role"`
+	Content []claudeBlock `json:"content"`
+}
+
+type claudeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type claudeThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
 }
 
 type claudeMessage struct {
@@ -250,11 +515,32 @@ type claudeResponse struct {
 }
 
 type contentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Thinking string          `json:"thinking,omitempty"`
+	ID       string          `json:"id,omitempty"`    // tool_use blocks
+	Name     string          `json:"name,omitempty"`  // tool_use blocks
+	Input    json.RawMessage `json:"input,omitempty"` // tool_use blocks
 }
 
 type claudeUsage struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
 }
+
+// claudeStreamEvent is a server-sent event from a streaming request
+type claudeStreamEvent struct {
+	Type    string         `json:"type"`
+	Message claudeResponse `json:"message"`
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		Thinking   string `json:"thinking"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage claudeUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
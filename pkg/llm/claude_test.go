@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestApplyThinking(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxTokens int
+		reasoning *ReasoningConfig
+		budget    int // 0 = thinking stays off
+		max       int
+	}{
+		{"no reasoning", 4096, nil, 0, 4096},
+		{"no budget", 4096, &ReasoningConfig{Effort: "high"}, 0, 4096},
+		{"budget within max", 8192, &ReasoningConfig{BudgetTokens: 4096}, 4096, 8192},
+		{"max raised above budget", 4096, &ReasoningConfig{BudgetTokens: 16000}, 16000, 20096},
+		{"budget raised to the minimum", 4096, &ReasoningConfig{BudgetTokens: 100}, 1024, 4096},
+		{"max raised above the minimum", 1000, &ReasoningConfig{BudgetTokens: 100}, 1024, 2024},
+	}
+
+	for _, tt := range tests {
+		temperature := 0.7
+		req := &claudeRequest{MaxTokens: tt.maxTokens, Temperature: &temperature}
+		applyThinking(req, tt.reasoning)
+
+		if tt.budget == 0 {
+			if req.Thinking != nil || req.Temperature == nil {
+				t.Errorf("%s: expected thinking off and temperature kept, got %+v", tt.name, req)
+			}
+			continue
+		}
+		if req.Thinking == nil || req.Thinking.Type != "enabled" || req.Thinking.BudgetTokens != tt.budget {
+			t.Errorf("%s: expected thinking with budget %d, got %+v", tt.name, tt.budget, req.Thinking)
+		}
+		if req.MaxTokens != tt.max {
+			t.Errorf("%s: expected max tokens %d, got %d", tt.name, tt.max, req.MaxTokens)
+		}
+		if req.Temperature != nil {
+			t.Errorf("%s: expected temperature cleared, got %v", tt.name, *req.Temperature)
+		}
+	}
+}
+
+func TestClaudeProvider_ResponseFormat(t *testing.T) {
+	var bodies []map[string]json.RawMessage
+	inputs := []string{`{"verdict":"maybe"}`, `{"verdict":"approve"}`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		_ = json.NewEncoder(w).Encode(claudeResponse{
+			Content: []contentBlock{
+				{Type: "text", Text: "Calling the tool"},
+				{Type: "tool_use", ID: "toolu_1", Name: "review", Input: json.RawMessage(inputs[len(bodies)-1])},
+			},
+			StopReason: "tool_use",
+			Usage:      claudeUsage{InputTokens: 10, OutputTokens: 5},
+		})
+	}))
+	defer server.Close()
+
+	p := NewClaudeProvider("key").WithBaseURL(server.URL)
+	format := JSONSchema("review", `{"type":"object","properties":{"verdict":{"enum":["approve","reject"]}},"required":["verdict"]}`)
+	resp, err := p.Complete(context.Background(), CompletionRequest{
+		Prompt:         "Review this",
+		ResponseFormat: format,
+		Reasoning:      &ReasoningConfig{BudgetTokens: 2048},
+	})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	if resp.Content != `{"verdict":"approve"}` || resp.ToolCalls != nil {
+		t.Errorf("Expected the tool input as the content and no tool calls, got %q and %v", resp.Content, resp.ToolCalls)
+	}
+	if resp.TokensUsed != 30 {
+		t.Errorf("Expected the tokens of both attempts, got %d", resp.TokensUsed)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("Expected an invalid input to be retried once, got %d requests", len(bodies))
+	}
+
+	var tools []claudeTool
+	var choice claudeToolChoice
+	_ = json.Unmarshal(bodies[0]["tools"], &tools)
+	_ = json.Unmarshal(bodies[0]["tool_choice"], &choice)
+	if len(tools) != 1 || tools[0].Name != "review" || string(tools[0].InputSchema) != string(format.Schema) {
+		t.Errorf("Expected the format's tool with its schema, got %+v", tools)
+	}
+	if choice.Type != "tool" || choice.Name != "review" {
+		t.Errorf("Expected the format's tool forced, got %+v", choice)
+	}
+	if _, ok := bodies[0]["thinking"]; ok {
+		t.Error("Expected thinking off while a tool is forced")
+	}
+}
@@ -0,0 +1,249 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidResponse is returned when a response still doesn't match its
+// ResponseFormat after the retries
+var ErrInvalidResponse = errors.New("response does not match the requested format")
+
+// DefaultFormatRetries is how many times an invalid response is retried
+const DefaultFormatRetries = 2
+
+// ResponseFormat makes a completion's content a JSON object, matching Schema
+// if one is given. OpenAI models are held to it with structured outputs and
+// Claude models by forcing a tool whose input is the schema. Responses are
+// checked against the schema either way, and an invalid one is sent back to
+// the model with what was wrong, up to Retries times. Requests with tools
+// ignore it.
+type ResponseFormat struct {
+	Name    string          `json:"name,omitempty"`    // Names the output to the model (default "response")
+	Schema  json.RawMessage `json:"schema,omitempty"`  // JSON Schema of the object (nil = any object)
+	Retries int             `json:"retries,omitempty"` // Retries of invalid responses (0 = DefaultFormatRetries, -1 = none)
+	Strict  bool            `json:"strict,omitempty"`  // OpenAI decodes strictly to the schema, which must then follow its strict-mode rules
+}
+
+// JSONSchema returns a format holding responses to a schema
+func JSONSchema(name string, schema string) *ResponseFormat {
+	return &ResponseFormat{Name: name, Schema: json.RawMessage(schema)}
+}
+
+// name returns the name the output is given to the model
+func (f *ResponseFormat) name() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return "response"
+}
+
+// schema returns the schema, defaulting to any object
+func (f *ResponseFormat) schema() json.RawMessage {
+	if len(f.Schema) == 0 {
+		return json.RawMessage(`{"type":"object"}`)
+	}
+	return f.Schema
+}
+
+// retries returns how many times an invalid response is retried
+func (f *ResponseFormat) retries() int {
+	switch {
+	case f.Retries < 0:
+		return 0
+	case f.Retries == 0:
+		return DefaultFormatRetries
+	}
+	return f.Retries
+}
+
+// Check returns the JSON object in content, which may be surrounded by
+// prose or a code fence, or why it doesn't match the format
+func (f *ResponseFormat) Check(content string) (string, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("%w: no JSON object in response", ErrInvalidResponse)
+	}
+	object := content[start : end+1]
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(object), &value); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(f.schema(), &schema); err != nil {
+		return "", fmt.Errorf("invalid response schema: %w", err)
+	}
+	if err := validate(schema, value, "$"); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	return object, nil
+}
+
+// completeFormatted makes a request whose response must match its format,
+// sending an invalid response back to the model to be corrected until it
+// runs out of retries. The content of the response returned is the bare
+// JSON object, and its tokens include those of the retries.
+func completeFormatted(ctx context.Context, req CompletionRequest, complete func(context.Context, CompletionRequest) (*CompletionResponse, error)) (*CompletionResponse, error) {
+	format := req.ResponseFormat
+	attempt := req
+	tokens, thinking := 0, 0
+	for i := 0; ; i++ {
+		resp, err := complete(ctx, attempt)
+		if resp != nil {
+			tokens += resp.TokensUsed
+			thinking += resp.ThinkingTokens
+			resp.TokensUsed, resp.ThinkingTokens = tokens, thinking
+		}
+		if err != nil {
+			return resp, err
+		}
+
+		object, checkErr := format.Check(resp.Content)
+		if checkErr == nil {
+			resp.Content = object
+			return resp, nil
+		}
+		if i >= format.retries() {
+			return resp, checkErr
+		}
+		attempt.Prompt = correctionPrompt(req.Prompt, resp.Content, checkErr)
+	}
+}
+
+// completeWhole streams a formatted request as one delta once the response
+// has been checked, since a response may be retried
+func completeWhole(ctx context.Context, req CompletionRequest, complete func(context.Context, CompletionRequest) (*CompletionResponse, error), onDelta func(string)) (*CompletionResponse, error) {
+	resp, err := complete(ctx, req)
+	if err == nil && onDelta != nil {
+		onDelta(resp.Content)
+	}
+	return resp, err
+}
+
+// correctionPrompt repeats a request with the invalid response it got
+func correctionPrompt(prompt, response string, problem error) string {
+	return fmt.Sprintf(`%s
+
+Your previous response was:
+
+%s
+
+It was rejected: %v. Reply again with only a JSON object that matches the required format.`, prompt, response, problem)
+}
+
+// validate checks a decoded JSON value against the subset of JSON Schema
+// providers enforce: type, properties, required, additionalProperties,
+// items and enum
+func validate(schema map[string]interface{}, value interface{}, path string) error {
+	if types, ok := schemaTypes(schema["type"]); ok && !matchesType(types, value) {
+		return fmt.Errorf("%s: want %s, got %s", path, strings.Join(types, " or "), jsonType(value))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) && jsonType(allowed) == jsonType(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, _ := r.(string); name != "" {
+					if _, ok := v[name]; !ok {
+						return fmt.Errorf("%s: missing required field %q", path, name)
+					}
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			sub, ok := properties[key].(map[string]interface{})
+			if !ok {
+				if allowed, isBool := schema["additionalProperties"].(bool); isBool && !allowed {
+					return fmt.Errorf("%s: unexpected field %q", path, key)
+				}
+				continue
+			}
+			if err := validate(sub, v[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// schemaTypes reads a schema's type, which may be a name or a list of them
+func schemaTypes(t interface{}) ([]string, bool) {
+	switch t := t.(type) {
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		var types []string
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types, len(types) > 0
+	}
+	return nil, false
+}
+
+// matchesType reports whether a decoded value is of one of the types
+func matchesType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON Schema type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
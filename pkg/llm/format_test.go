@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResponseFormat_Check(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"properties": {
+			"verdict": {"type": "string", "enum": ["approve", "reject"]},
+			"score": {"type": "integer"},
+			"confidence": {"type": "number"},
+			"notes": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["verdict", "score"],
+		"additionalProperties": false
+	}`
+
+	tests := []struct {
+		name    string
+		content string
+		want    string // The object returned, if valid
+		problem string // In the error, if invalid
+	}{
+		{"valid", `{"verdict":"approve","score":3}`, `{"verdict":"approve","score":3}`, ""},
+		{"surrounded by prose and a fence", "Here you go:\n```json\n{\"verdict\":\"reject\",\"score\":1}\n```", `{"verdict":"reject","score":1}`, ""},
+		{"integer is a number", `{"verdict":"approve","score":3,"confidence":1}`, `{"verdict":"approve","score":3,"confidence":1}`, ""},
+		{"no object", "I can't answer that", "", "no JSON object"},
+		{"malformed", `{"verdict": approve}`, "", "invalid character"},
+		{"missing required field", `{"verdict":"approve"}`, "", `missing required field "score"`},
+		{"additional property", `{"verdict":"approve","score":3,"extra":true}`, "", `unexpected field "extra"`},
+		{"not in enum", `{"verdict":"maybe","score":3}`, "", "is not one of"},
+		{"number is not an integer", `{"verdict":"approve","score":2.5}`, "", "$.score: want integer, got number"},
+		{"wrong item type", `{"verdict":"approve","score":3,"notes":["ok",4]}`, "", "$.notes[1]: want string, got integer"},
+	}
+
+	format := JSONSchema("review", schema)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := format.Check(tt.content)
+			if tt.problem == "" {
+				if err != nil || got != tt.want {
+					t.Errorf("Expected %s, got %q (%v)", tt.want, got, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidResponse) || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("Expected ErrInvalidResponse about %q, got %v", tt.problem, err)
+			}
+		})
+	}
+
+	if _, err := (&ResponseFormat{}).Check(`{"anything": [1, "two"]}`); err != nil {
+		t.Errorf("Expected a format without a schema to take any object, got %v", err)
+	}
+	if _, err := JSONSchema("bad", `{"type":`).Check(`{}`); err == nil || errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("Expected an invalid schema reported as such, got %v", err)
+	}
+}
+
+func TestCompleteFormatted(t *testing.T) {
+	format := JSONSchema("answer", `{"type":"object","required":["answer"]}`)
+
+	// complete answers with each of responses in turn, recording the prompts
+	complete := func(responses ...string) (func(context.Context, CompletionRequest) (*CompletionResponse, error), *[]string) {
+		var prompts []string
+		return func(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+			prompts = append(prompts, req.Prompt)
+			content := responses[min(len(prompts), len(responses))-1]
+			return &CompletionResponse{Content: content, TokensUsed: 10, ThinkingTokens: 1}, nil
+		}, &prompts
+	}
+
+	t.Run("retries an invalid response", func(t *testing.T) {
+		fn, prompts := complete(`{"wrong": 1}`, `Sure: {"answer": 42}`)
+		resp, err := completeFormatted(context.Background(), CompletionRequest{Prompt: "What is it?", ResponseFormat: format}, fn)
+		if err != nil {
+			t.Fatalf("completeFormatted failed: %v", err)
+		}
+		if resp.Content != `{"answer": 42}` {
+			t.Errorf("Expected the bare object, got %q", resp.Content)
+		}
+		if resp.TokensUsed != 20 || resp.ThinkingTokens != 2 {
+			t.Errorf("Expected the tokens of both attempts, got %d and %d", resp.TokensUsed, resp.ThinkingTokens)
+		}
+		if len(*prompts) != 2 {
+			t.Fatalf("Expected 2 attempts, got %d", len(*prompts))
+		}
+		retry := (*prompts)[1]
+		if !strings.HasPrefix(retry, "What is it?") || !strings.Contains(retry, `{"wrong": 1}`) || !strings.Contains(retry, `missing required field "answer"`) {
+			t.Errorf("Expected the retry to repeat the prompt, the response and the problem, got %q", retry)
+		}
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		fn, prompts := complete(`not json`)
+		resp, err := completeFormatted(context.Background(), CompletionRequest{Prompt: "What is it?", ResponseFormat: format}, fn)
+		if !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("Expected ErrInvalidResponse, got %v", err)
+		}
+		if want := 1 + DefaultFormatRetries; len(*prompts) != want {
+			t.Errorf("Expected %d attempts, got %d", want, len(*prompts))
+		}
+		if resp == nil || resp.TokensUsed != 10*(1+DefaultFormatRetries) {
+			t.Errorf("Expected the last response with every attempt's tokens, got %+v", resp)
+		}
+	})
+
+	t.Run("no retries", func(t *testing.T) {
+		fn, prompts := complete(`not json`)
+		noRetries := *format
+		noRetries.Retries = -1
+		if _, err := completeFormatted(context.Background(), CompletionRequest{ResponseFormat: &noRetries}, fn); !errors.Is(err, ErrInvalidResponse) || len(*prompts) != 1 {
+			t.Errorf("Expected one attempt failing with ErrInvalidResponse, got %d (%v)", len(*prompts), err)
+		}
+	})
+
+	t.Run("request errors end it", func(t *testing.T) {
+		failure := errors.New("overloaded")
+		calls := 0
+		_, err := completeFormatted(context.Background(), CompletionRequest{ResponseFormat: format}, func(context.Context, CompletionRequest) (*CompletionResponse, error) {
+			calls++
+			return nil, failure
+		})
+		if !errors.Is(err, failure) || calls != 1 {
+			t.Errorf("Expected the request error after one attempt, got %d (%v)", calls, err)
+		}
+	})
+}
@@ -0,0 +1,431 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+var (
+	// ErrNoMockResponse is returned when no scripted response matches a
+	// request and the script has no default
+	ErrNoMockResponse = errors.New("no scripted response matches the request")
+	// ErrInjected is returned by requests the mock fails on purpose
+	ErrInjected = errors.New("injected provider failure")
+)
+
+// MockResponse is a scripted response, given to the requests it matches
+type MockResponse struct {
+	Prompt     string        `json:"prompt,omitempty"`      // Prompt the request must have, UUIDs aside (empty = any)
+	Match      string        `json:"match,omitempty"`       // Regular expression the prompt must match (empty = any)
+	Model      string        `json:"model,omitempty"`       // Prefix of the model the request must name (empty = any)
+	Content    string        `json:"content,omitempty"`     // Text of the response, a text/template of the MockRequest
+	Raw        bool          `json:"raw,omitempty"`         // Content is given as is, not as a template
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`  // Tools to call, when the request offers tools
+	TokensUsed int           `json:"tokens_used,omitempty"` // Tokens reported (0 = about 4 characters each)
+	Latency    time.Duration `json:"latency,omitempty"`     // Delay before answering (0 = the script's)
+	Error      string        `json:"error,omitempty"`       // Fails the request with this message instead
+	Times      int           `json:"times,omitempty"`       // Requests it answers (0 = any number)
+}
+
+// MockScript is what a MockProvider answers. A request gets the first
+// response that matches it and isn't used up; if every matching response
+// is used up, the last of them answers again.
+type MockScript struct {
+	Responses []MockResponse `json:"responses"`
+	Default   string         `json:"default,omitempty"`    // Content template for requests nothing matches (empty = ErrNoMockResponse)
+	Latency   time.Duration  `json:"latency,omitempty"`    // Delay of every response that sets none
+	Jitter    time.Duration  `json:"jitter,omitempty"`     // Random extra delay, up to this
+	ErrorRate float64        `json:"error_rate,omitempty"` // Fraction of requests failed with ErrInjected
+	Seed      int64          `json:"seed,omitempty"`       // Seeds jitter and injected failures, so runs repeat exactly
+}
+
+// MockRequest is the data response templates are executed with
+type MockRequest struct {
+	Model  string
+	System string
+	Prompt string   // The last user message of chats
+	Groups []string // Submatches of the response's Match, the whole match first
+	Call   int      // Requests made to the provider so far, this one included
+}
+
+// LoadMockScript reads a JSON script, such as one saved by a
+// RecordingProvider
+func LoadMockScript(path string) (MockScript, error) {
+	var script MockScript
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return script, err
+	}
+	if err := json.Unmarshal(data, &script); err != nil {
+		return script, fmt.Errorf("mock script %s: %w", path, err)
+	}
+	return script, script.Validate()
+}
+
+// Validate checks the script's patterns and templates
+func (s MockScript) Validate() error {
+	_, err := compileMock(s)
+	return err
+}
+
+// uuidPattern matches the agent SIDs and task IDs that differ between runs
+var uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// samePrompt reports whether two prompts match but for their UUIDs, so a
+// recording replays though agents and tasks get new IDs
+func samePrompt(a, b string) bool {
+	if a == b {
+		return true
+	}
+	return uuidPattern.ReplaceAllString(a, "<id>") == uuidPattern.ReplaceAllString(b, "<id>")
+}
+
+type mockRule struct {
+	MockResponse
+	re      *regexp.Regexp
+	content *template.Template
+	used    int
+}
+
+// compileMock compiles a script's patterns and templates
+func compileMock(s MockScript) ([]*mockRule, error) {
+	if s.ErrorRate < 0 || s.ErrorRate > 1 {
+		return nil, fmt.Errorf("mock script: error rate %v not between 0 and 1", s.ErrorRate)
+	}
+	rules := make([]*mockRule, 0, len(s.Responses)+1)
+	for i, r := range s.Responses {
+		rule := &mockRule{MockResponse: r}
+		var err error
+		if r.Match != "" {
+			if rule.re, err = regexp.Compile(r.Match); err != nil {
+				return nil, fmt.Errorf("mock response %d: %w", i+1, err)
+			}
+		}
+		if !r.Raw {
+			if rule.content, err = template.New("content").Parse(r.Content); err != nil {
+				return nil, fmt.Errorf("mock response %d: %w", i+1, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// MockProvider is a deterministic provider answering from a script, for
+// tests and demos that run offline. It streams, chats and calls tools, and
+// is safe for concurrent use.
+type MockProvider struct {
+	mu sync.Mutex
+
+	script   MockScript
+	rules    []*mockRule
+	fallback *template.Template
+	err      error // From compiling the script, returned by every request
+	rand     *rand.Rand
+	calls    int
+	requests []MockRequest
+}
+
+// NewMockProvider creates a provider answering from script. A script that
+// doesn't validate fails every request.
+func NewMockProvider(script MockScript) *MockProvider {
+	p := &MockProvider{script: script, rand: rand.New(rand.NewSource(script.Seed))}
+	p.rules, p.err = compileMock(script)
+	if p.err == nil && script.Default != "" {
+		p.fallback, p.err = template.New("default").Parse(script.Default)
+	}
+	return p
+}
+
+// Name returns "mock"
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+// Calls returns how many requests the provider has received
+func (p *MockProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// Requests returns the requests received so far
+func (p *MockProvider) Requests() []MockRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MockRequest(nil), p.requests...)
+}
+
+func (p *MockProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return p.answer(ctx, MockRequest{Model: req.Model, System: req.System, Prompt: req.Prompt}, false)
+}
+
+// Stream delivers the response a word at a time
+func (p *MockProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	resp, err := p.Complete(ctx, req)
+	if err != nil || onDelta == nil {
+		return resp, err
+	}
+	for _, word := range strings.SplitAfter(resp.Content, " ") {
+		if word != "" {
+			onDelta(word)
+		}
+	}
+	return resp, nil
+}
+
+// Chat answers the last user message
+func (p *MockProvider) Chat(ctx context.Context, req ChatRequest) (*CompletionResponse, error) {
+	return p.answer(ctx, chatMockRequest(req), false)
+}
+
+// chatMockRequest is what a chat is answered by: its last system and user
+// messages
+func chatMockRequest(req ChatRequest) MockRequest {
+	r := MockRequest{Model: req.Model}
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			r.System = m.Content
+		case "user":
+			r.Prompt = m.Content
+		}
+	}
+	return r
+}
+
+// CompleteTools answers with the response's tool calls, if it has any
+func (p *MockProvider) CompleteTools(ctx context.Context, req ToolRequest) (*CompletionResponse, error) {
+	return p.answer(ctx, MockRequest{Model: req.Model, System: req.System, Prompt: req.Prompt}, true)
+}
+
+// answer picks the response to a request, waits out its latency and
+// renders it
+func (p *MockProvider) answer(ctx context.Context, r MockRequest, tools bool) (*CompletionResponse, error) {
+	p.mu.Lock()
+	p.calls++
+	r.Call = p.calls
+	if p.err != nil {
+		p.mu.Unlock()
+		return nil, p.err
+	}
+	rule, groups := p.pick(r)
+	r.Groups = groups
+	p.requests = append(p.requests, r)
+
+	latency := p.script.Latency
+	if rule != nil && rule.Latency > 0 {
+		latency = rule.Latency
+	}
+	if p.script.Jitter > 0 {
+		latency += time.Duration(p.rand.Int63n(int64(p.script.Jitter)))
+	}
+	injected := p.script.ErrorRate > 0 && p.rand.Float64() < p.script.ErrorRate
+	p.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if injected {
+		return nil, fmt.Errorf("%w: request %d", ErrInjected, r.Call)
+	}
+
+	resp := &CompletionResponse{FinishReason: "stop"}
+	tmpl := p.fallback
+	if rule != nil {
+		if rule.Error != "" {
+			return nil, errors.New(rule.Error)
+		}
+		resp.Content, tmpl = rule.Content, rule.content
+		resp.TokensUsed = rule.TokensUsed
+		if tools && len(rule.ToolCalls) > 0 {
+			resp.ToolCalls = append([]ToolCall(nil), rule.ToolCalls...)
+			resp.FinishReason = "tool_use"
+		}
+	} else if tmpl == nil {
+		return nil, fmt.Errorf("%w: %.60q", ErrNoMockResponse, r.Prompt)
+	}
+	if tmpl != nil {
+		var content bytes.Buffer
+		if err := tmpl.Execute(&content, r); err != nil {
+			return nil, fmt.Errorf("mock response: %w", err)
+		}
+		resp.Content = content.String()
+	}
+	if resp.TokensUsed == 0 {
+		resp.TokensUsed = estimateTokens(r.System) + estimateTokens(r.Prompt) + estimateTokens(resp.Content)
+	}
+	return resp, nil
+}
+
+// pick returns the rule answering a request and its submatches, using it
+// up. Caller must hold p.mu.
+func (p *MockProvider) pick(r MockRequest) (*mockRule, []string) {
+	var (
+		last       *mockRule
+		lastGroups []string
+	)
+	for _, rule := range p.rules {
+		if rule.Prompt != "" && !samePrompt(rule.Prompt, r.Prompt) {
+			continue
+		}
+		if rule.Model != "" && !strings.HasPrefix(r.Model, rule.Model) {
+			continue
+		}
+		var groups []string
+		if rule.re != nil {
+			if groups = rule.re.FindStringSubmatch(r.Prompt); groups == nil {
+				continue
+			}
+		}
+		if rule.Times == 0 || rule.used < rule.Times {
+			rule.used++
+			return rule, groups
+		}
+		last, lastGroups = rule, groups
+	}
+	return last, lastGroups
+}
+
+// RecordingProvider passes requests to another provider and records each
+// prompt and response, so real traffic can be replayed offline by a
+// MockProvider. It streams, chats and calls tools if the provider it wraps
+// does.
+type RecordingProvider struct {
+	mu sync.Mutex
+
+	inner     Provider
+	path      string
+	responses []MockResponse
+}
+
+// NewRecordingProvider creates a provider recording the traffic to p
+func NewRecordingProvider(p Provider) *RecordingProvider {
+	return &RecordingProvider{inner: p}
+}
+
+// WithFile saves the recording to path after every request. A recording
+// that can't be saved never fails a request.
+func (r *RecordingProvider) WithFile(path string) *RecordingProvider {
+	r.path = path
+	return r
+}
+
+// Name returns the wrapped provider's name
+func (r *RecordingProvider) Name() string {
+	return r.inner.Name()
+}
+
+// Unwrap returns the provider being recorded
+func (r *RecordingProvider) Unwrap() Provider {
+	return r.inner
+}
+
+func (r *RecordingProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := r.inner.Complete(ctx, req)
+	r.record(req, resp, err)
+	return resp, err
+}
+
+// Stream streams if the provider can and completes otherwise
+func (r *RecordingProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	streamer, ok := r.inner.(StreamingProvider)
+	if !ok {
+		return r.Complete(ctx, req)
+	}
+	resp, err := streamer.Stream(ctx, req, onDelta)
+	r.record(req, resp, err)
+	return resp, err
+}
+
+// Chat passes a chat on, as a transcript if the provider can't chat. It is
+// recorded by its last user message, which a MockProvider answers chats by.
+func (r *RecordingProvider) Chat(ctx context.Context, req ChatRequest) (*CompletionResponse, error) {
+	resp, err := chat(ctx, r.inner, req)
+	m := chatMockRequest(req)
+	r.record(CompletionRequest{Model: m.Model, Prompt: m.Prompt}, resp, err)
+	return resp, err
+}
+
+// CompleteTools passes a tool request on if the provider supports tools
+func (r *RecordingProvider) CompleteTools(ctx context.Context, req ToolRequest) (*CompletionResponse, error) {
+	tp, ok := r.inner.(ToolProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrToolsUnsupported, r.inner.Name())
+	}
+	resp, err := tp.CompleteTools(ctx, req)
+	r.record(req.CompletionRequest, resp, err)
+	return resp, err
+}
+
+// record adds an exchange to the recording. Requests cut short by their
+// context aren't replayable and are left out.
+func (r *RecordingProvider) record(req CompletionRequest, resp *CompletionResponse, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	m := MockResponse{Prompt: req.Prompt, Model: req.Model, Times: 1}
+	if err != nil {
+		m.Error = err.Error()
+	} else if resp != nil {
+		m.Content, m.Raw = resp.Content, true
+		m.ToolCalls = resp.ToolCalls
+		m.TokensUsed = resp.TokensUsed
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.responses = append(r.responses, m)
+	if r.path != "" {
+		_ = r.save(r.path)
+	}
+}
+
+// Script returns the recording as a script answering each recorded prompt
+// with its response, in the order they were recorded
+func (r *RecordingProvider) Script() MockScript {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return MockScript{Responses: append([]MockResponse(nil), r.responses...)}
+}
+
+// Save writes the recording to path as a JSON script
+func (r *RecordingProvider) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save(path)
+}
+
+// save writes the recording, replacing the file atomically. Caller must
+// hold r.mu.
+func (r *RecordingProvider) save(path string) error {
+	data, err := json.MarshalIndent(MockScript{Responses: r.responses}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,38 @@
+package llm
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRecordingProvider_Chat(t *testing.T) {
+	var calls []string
+	inner := &chattingProvider{fakeProvider: &fakeProvider{name: "live", calls: &calls}}
+	rec := NewRecordingProvider(inner)
+
+	messages := []Message{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello"},
+		{Role: "user", Content: "How are you?"},
+	}
+	resp, err := rec.Chat(context.Background(), ChatRequest{Model: "m", Messages: messages})
+	if err != nil || resp.Content != "live" {
+		t.Fatalf("Expected the wrapped provider's answer, got %v (%v)", resp, err)
+	}
+	if len(inner.chats) != 1 || !reflect.DeepEqual(inner.chats[0], messages) {
+		t.Errorf("Expected the messages chatted to the wrapped provider, got %v", inner.chats)
+	}
+
+	script := rec.Script()
+	if len(script.Responses) != 1 || script.Responses[0].Prompt != "How are you?" || script.Responses[0].Content != "live" {
+		t.Fatalf("Expected the chat recorded by its last user message, got %+v", script.Responses)
+	}
+
+	replay := NewMockProvider(script)
+	resp, err = replay.Chat(context.Background(), ChatRequest{Model: "m", Messages: messages})
+	if err != nil || resp.Content != "live" {
+		t.Errorf("Expected the recorded answer replayed, got %v (%v)", resp, err)
+	}
+}
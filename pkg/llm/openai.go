@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -21,6 +22,21 @@ type OpenAIProvider struct {
 	httpClient *http.Client
 	model      string
 	orgID      string
+	effort     string // Reasoning effort for models not known to reason by name (empty = none)
+}
+
+// reasoningModelPrefixes name the OpenAI models that reason before answering.
+// They take reasoning_effort and max_completion_tokens, which others reject.
+var reasoningModelPrefixes = []string{"o1", "o3", "o4", "gpt-5"}
+
+// isReasoningModel reports whether an OpenAI model reasons before answering
+func isReasoningModel(model string) bool {
+	for _, prefix := range reasoningModelPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
@@ -35,6 +51,13 @@ func NewOpenAIProvider(apiKey string) *OpenAIProvider {
 	}
 }
 
+// NewLocalProvider creates a provider for a locally served OpenAI-compatible
+// model, such as a fine-tuned model behind llama.cpp, vLLM or Ollama. The
+// endpoint is the full chat completions URL.
+func NewLocalProvider(endpoint, model string) *OpenAIProvider {
+	return NewOpenAIProvider("").WithBaseURL(endpoint).WithModel(model)
+}
+
 // WithBaseURL sets a custom base URL
 func (p *OpenAIProvider) WithBaseURL(url string) *OpenAIProvider {
 	p.baseURL = url
@@ -53,13 +76,30 @@ func (p *OpenAIProvider) WithOrgID(orgID string) *OpenAIProvider {
 	return p
 }
 
+// WithReasoningEffort treats the provider's models as reasoning models with
+// the given default effort, for models whose names don't tell, such as local
+// ones. A request's own effort takes precedence.
+func (p *OpenAIProvider) WithReasoningEffort(effort string) *OpenAIProvider {
+	p.effort = effort
+	return p
+}
+
 // Name returns the provider name
 func (p *OpenAIProvider) Name() string {
 	return "openai"
 }
 
-// Complete generates a completion
+// Complete generates a completion. A response format is enforced with
+// structured outputs.
 func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if req.ResponseFormat != nil {
+		return completeFormatted(ctx, req, p.complete)
+	}
+	return p.complete(ctx, req)
+}
+
+// complete makes a single completion request
+func (p *OpenAIProvider) complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
 	// Convert to chat format
 	messages := []openaiMessage{
 		{
@@ -74,7 +114,14 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*
 		}, messages...)
 	}
 
-	return p.doRequest(ctx, req.Model, messages, req.MaxTokens, req.Temperature, req.Stop)
+	openaiReq := p.buildRequest(req.Model, messages, req.MaxTokens, req.Temperature, req.Stop, req.Reasoning)
+	if f := req.ResponseFormat; f != nil {
+		openaiReq.ResponseFormat = &openaiResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: &openaiJSONSchema{Name: f.name(), Schema: f.schema(), Strict: f.Strict},
+		}
+	}
+	return p.doRequest(ctx, openaiReq)
 }
 
 // Chat implements chat completion
@@ -84,54 +131,157 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*Completion
 		messages[i] = openaiMessage(m)
 	}
 
-	return p.doRequest(ctx, req.Model, messages, req.MaxTokens, req.Temperature, req.Stop)
+	return p.doRequest(ctx, p.buildRequest(req.Model, messages, req.MaxTokens, req.Temperature, req.Stop, req.Reasoning))
 }
 
-func (p *OpenAIProvider) doRequest(ctx context.Context, model string, messages []openaiMessage, maxTokens int, temperature float64, stop []string) (*CompletionResponse, error) {
-	if model == "" {
-		model = p.model
+// Stream generates a completion, passing text to onDelta as it arrives. If
+// the stream is cut short the text received so far is returned with the error.
+// A response with a format is checked whole and delivered as one delta.
+func (p *OpenAIProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	if req.ResponseFormat != nil {
+		return completeWhole(ctx, req, p.Complete, onDelta)
 	}
 
-	if maxTokens == 0 {
-		maxTokens = 4096
+	messages := []openaiMessage{{Role: "user", Content: req.Prompt}}
+	if req.System != "" {
+		messages = append([]openaiMessage{{Role: "system", Content: req.System}}, messages...)
 	}
 
-	openaiReq := openaiRequest{
-		Model:    model,
-		Messages: messages,
+	openaiReq := p.buildRequest(req.Model, messages, req.MaxTokens, req.Temperature, req.Stop, req.Reasoning)
+	openaiReq.Stream = true
+	openaiReq.StreamOptions = &openaiStreamOptions{IncludeUsage: true}
+
+	resp, err := p.post(ctx, openaiReq)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	if maxTokens > 0 {
-		openaiReq.MaxTokens = &maxTokens
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
-	if temperature > 0 {
-		openaiReq.Temperature = &temperature
+	var (
+		content strings.Builder
+		result  CompletionResponse
+	)
+	err = readSSE(resp.Body, func(_ string, data []byte) error {
+		var chunk openaiStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+
+		if chunk.Usage != nil {
+			result.TokensUsed = chunk.Usage.TotalTokens
+			result.ThinkingTokens = chunk.Usage.CompletionTokensDetails.ReasoningTokens
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+		if choice.FinishReason != "" {
+			result.FinishReason = choice.FinishReason
+		}
+		return nil
+	})
+
+	result.Content = content.String()
+	if err == nil && result.FinishReason == "" {
+		err = fmt.Errorf("stream ended without a finish reason: %w", io.ErrUnexpectedEOF)
+	}
+	if err != nil {
+		return &result, err
 	}
+	return &result, nil
+}
 
-	if len(stop) > 0 {
-		openaiReq.Stop = stop
+// CompleteTools generates a completion that may end in tool calls
+func (p *OpenAIProvider) CompleteTools(ctx context.Context, req ToolRequest) (*CompletionResponse, error) {
+	var messages []openaiToolMessage
+	if req.System != "" {
+		messages = append(messages, openaiToolMessage{Role: "system", Content: req.System})
+	}
+	messages = append(messages, openaiToolMessage{Role: "user", Content: req.Prompt})
+	for _, turn := range req.Turns {
+		assistant := openaiToolMessage{Role: "assistant", Content: turn.Text}
+		for _, call := range turn.Calls {
+			assistant.ToolCalls = append(assistant.ToolCalls, openaiToolCall{
+				ID:       call.ID,
+				Type:     "function",
+				Function: openaiFunctionCall{Name: call.Name, Arguments: string(toolInput(call))},
+			})
+		}
+		messages = append(messages, assistant)
+		for _, out := range turn.Outputs {
+			messages = append(messages, openaiToolMessage{Role: "tool", Content: out.Content, ToolCallID: out.CallID})
+		}
 	}
 
-	body, err := json.Marshal(openaiReq)
+	openaiReq := openaiToolRequest{
+		openaiRequest: p.buildRequest(req.Model, nil, req.MaxTokens, req.Temperature, req.Stop, req.Reasoning),
+		Messages:      messages,
+	}
+	for _, t := range req.Tools {
+		openaiReq.Tools = append(openaiReq.Tools, openaiTool{
+			Type:     "function",
+			Function: openaiFunction{Name: t.Name, Description: t.Description, Parameters: toolSchema(t)},
+		})
+	}
+
+	resp, err := p.post(ctx, openaiReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewReader(body))
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
-	if p.orgID != "" {
-		httpReq.Header.Set("OpenAI-Organization", p.orgID)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
 
-	resp, err := p.httpClient.Do(httpReq)
+	var openaiResp openaiToolResponse
+	if err := json.Unmarshal(respBody, &openaiResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if len(openaiResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	choice := openaiResp.Choices[0]
+	result := &CompletionResponse{
+		Content:        choice.Message.Content,
+		FinishReason:   choice.FinishReason,
+		TokensUsed:     openaiResp.Usage.TotalTokens,
+		ThinkingTokens: openaiResp.Usage.CompletionTokensDetails.ReasoningTokens,
+	}
+	for _, call := range choice.Message.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, ToolCall{
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: json.RawMessage(call.Function.Arguments),
+		})
+	}
+	return result, nil
+}
+
+// doRequest sends a chat completions request
+func (p *OpenAIProvider) doRequest(ctx context.Context, openaiReq openaiRequest) (*CompletionResponse, error) {
+	resp, err := p.post(ctx, openaiReq)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -156,12 +306,76 @@ func (p *OpenAIProvider) doRequest(ctx context.Context, model string, messages [
 	choice := openaiResp.Choices[0]
 
 	return &CompletionResponse{
-		Content:      choice.Message.Content,
-		FinishReason: choice.FinishReason,
-		TokensUsed:   openaiResp.Usage.TotalTokens,
+		Content:        choice.Message.Content,
+		FinishReason:   choice.FinishReason,
+		TokensUsed:     openaiResp.Usage.TotalTokens,
+		ThinkingTokens: openaiResp.Usage.CompletionTokensDetails.ReasoningTokens,
 	}, nil
 }
 
+// buildRequest assembles a chat completions request
+func (p *OpenAIProvider) buildRequest(model string, messages []openaiMessage, maxTokens int, temperature *float64, stop []string, reasoning *ReasoningConfig) openaiRequest {
+	if model == "" {
+		model = p.model
+	}
+
+	if maxTokens == 0 {
+		maxTokens = 4096
+	}
+
+	openaiReq := openaiRequest{
+		Model:    model,
+		Messages: messages,
+	}
+
+	if p.effort != "" || isReasoningModel(model) {
+		// Reasoning models take max_completion_tokens and a fixed temperature
+		openaiReq.ReasoningEffort = p.effort
+		if reasoning != nil && reasoning.Effort != "" {
+			openaiReq.ReasoningEffort = reasoning.Effort
+		}
+		openaiReq.MaxCompletionTokens = &maxTokens
+	} else {
+		if maxTokens > 0 {
+			openaiReq.MaxTokens = &maxTokens
+		}
+
+		openaiReq.Temperature = temperature
+	}
+
+	if len(stop) > 0 {
+		openaiReq.Stop = stop
+	}
+	return openaiReq
+}
+
+// post sends a request to the chat completions endpoint
+func (p *OpenAIProvider) post(ctx context.Context, openaiReq any) (*http.Response, error) {
+	body, err := json.Marshal(openaiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	if p.orgID != "" {
+		httpReq.Header.Set("OpenAI-Organization", p.orgID)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
 // OpenAI API types
 type openaiRequest struct {
 	Model       string          `json:"model"`
@@ -169,6 +383,84 @@ type openaiRequest struct {
 	MaxTokens   *int            `json:"max_tokens,omitempty"`
 	Temperature *float64        `json:"temperature,omitempty"`
 	Stop        []string        `json:"stop,omitempty"`
+
+	ReasoningEffort     string `json:"reasoning_effort,omitempty"`
+	MaxCompletionTokens *int   `json:"max_completion_tokens,omitempty"`
+
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openaiStreamOptions `json:"stream_options,omitempty"`
+
+	ResponseFormat *openaiResponseFormat `json:"response_format,omitempty"`
+}
+
+// openaiResponseFormat requests structured outputs
+type openaiResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *openaiJSONSchema `json:"json_schema,omitempty"`
+}
+
+type openaiJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict,omitempty"`
+}
+
+// openaiToolRequest is a request with tools, whose messages may carry tool
+// calls and their results
+type openaiToolRequest struct {
+	openaiRequest
+	Messages []openaiToolMessage `json:"messages"`
+	Tools    []openaiTool        `json:"tools,omitempty"`
+}
+
+type openaiTool struct {
+	Type     string         `json:"type"`
+	Function openaiFunction `json:"function"`
+}
+
+type openaiFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type openaiToolMessage struct {
+	Role       string           `json:"role"`
+	Content    string           `json:"content"`
+	ToolCalls  []openaiToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openaiToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openaiFunctionCall `json:"function"`
+}
+
+type openaiFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-encoded
+}
+
+type openaiToolResponse struct {
+	Choices []struct {
+		Message      openaiToolMessage `json:"message"`
+		FinishReason string            `json:"finish_reason"`
+	} `json:"choices"`
+	Usage openaiUsage `json:"usage"`
+}
+
+type openaiStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openaiStreamChunk is one server-sent event from a streaming request
+type openaiStreamChunk struct {
+	Choices []struct {
+		Delta        openaiMessage `json:"delta"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openaiUsage `json:"usage"`
 }
 
 type openaiMessage struct {
@@ -195,4 +487,8 @@ type openaiUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
 }
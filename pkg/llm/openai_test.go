@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIProvider_BuildRequest(t *testing.T) {
+	medium := &ReasoningConfig{BudgetTokens: 4096, Effort: "medium"}
+
+	tests := []struct {
+		name        string
+		effort      string // Configured on the provider
+		model       string
+		reasoning   *ReasoningConfig
+		sentEffort  string
+		completion  bool // max_completion_tokens rather than max_tokens and temperature
+		temperature bool
+	}{
+		{"plain model", "", "gpt-4o", nil, "", false, true},
+		{"plain model ignores effort", "", "gpt-4o", medium, "", false, true},
+		{"default model ignores effort", "", "", medium, "", false, true},
+		{"reasoning model", "", "o3-mini", medium, "medium", true, false},
+		{"reasoning model without effort", "", "o1", nil, "", true, false},
+		{"gpt-5 reasons", "", "gpt-5", medium, "medium", true, false},
+		{"configured effort", "low", "local-reasoner", nil, "low", true, false},
+		{"request effort overrides configured", "low", "local-reasoner", medium, "medium", true, false},
+	}
+
+	for _, tt := range tests {
+		p := NewOpenAIProvider("key").WithReasoningEffort(tt.effort)
+		temperature := 0.7
+		req := p.buildRequest(tt.model, nil, 0, &temperature, nil, tt.reasoning)
+
+		if req.ReasoningEffort != tt.sentEffort {
+			t.Errorf("%s: expected effort %q, got %q", tt.name, tt.sentEffort, req.ReasoningEffort)
+		}
+		if tt.completion {
+			if req.MaxCompletionTokens == nil || *req.MaxCompletionTokens != 4096 || req.MaxTokens != nil {
+				t.Errorf("%s: expected only max_completion_tokens 4096, got %v and %v", tt.name, req.MaxCompletionTokens, req.MaxTokens)
+			}
+		} else if req.MaxTokens == nil || *req.MaxTokens != 4096 || req.MaxCompletionTokens != nil {
+			t.Errorf("%s: expected only max_tokens 4096, got %v and %v", tt.name, req.MaxTokens, req.MaxCompletionTokens)
+		}
+		if (req.Temperature != nil) != tt.temperature {
+			t.Errorf("%s: expected temperature sent %v, got %v", tt.name, tt.temperature, req.Temperature)
+		}
+	}
+
+	if req := NewOpenAIProvider("key").buildRequest("", nil, 100, nil, []string{"END"}, nil); req.Model != string(ModelGPT4) || req.Temperature != nil || len(req.Stop) != 1 {
+		t.Errorf("Expected the default model, no temperature and the stop sequence, got %+v", req)
+	}
+	zero := 0.0
+	if req := NewOpenAIProvider("key").buildRequest("gpt-4o", nil, 100, &zero, nil, nil); req.Temperature == nil || *req.Temperature != 0 {
+		t.Errorf("Expected temperature 0 sent, got %v", req.Temperature)
+	}
+}
+
+func TestOpenAIProvider_ResponseFormat(t *testing.T) {
+	var bodies []openaiRequest
+	contents := []string{"I think it's fine", `{"verdict":"approve"}`}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body openaiRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		content := contents[min(len(bodies), len(contents))-1]
+		_ = json.NewEncoder(w).Encode(openaiResponse{
+			Choices: []openaiChoice{{Message: openaiMessage{Role: "assistant", Content: content}, FinishReason: "stop"}},
+			Usage:   openaiUsage{TotalTokens: 12},
+		})
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key").WithBaseURL(server.URL)
+	format := &ResponseFormat{Name: "review", Schema: json.RawMessage(`{"type":"object","required":["verdict"]}`), Strict: true}
+	resp, err := p.Complete(context.Background(), CompletionRequest{Prompt: "Review this", ResponseFormat: format})
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if resp.Content != `{"verdict":"approve"}` || resp.TokensUsed != 24 {
+		t.Errorf("Expected the object after one retry, got %q with %d tokens", resp.Content, resp.TokensUsed)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(bodies))
+	}
+
+	rf := bodies[0].ResponseFormat
+	if rf == nil || rf.Type != "json_schema" || rf.JSONSchema == nil {
+		t.Fatalf("Expected a json_schema response format, got %+v", rf)
+	}
+	if rf.JSONSchema.Name != "review" || !rf.JSONSchema.Strict || string(rf.JSONSchema.Schema) != string(format.Schema) {
+		t.Errorf("Expected the format's name, schema and strictness, got %+v", rf.JSONSchema)
+	}
+
+	// Responses that never match fail once the retries are used up
+	contents = []string{"no"}
+	bodies = nil
+	format.Retries = 1
+	if _, err := p.Complete(context.Background(), CompletionRequest{Prompt: "Review this", ResponseFormat: format}); !errors.Is(err, ErrInvalidResponse) || len(bodies) != 2 {
+		t.Errorf("Expected ErrInvalidResponse after 2 requests, got %d (%v)", len(bodies), err)
+	}
+}
@@ -16,17 +16,57 @@ type CompletionRequest struct {
 	Model       string            `json:"model"`
 	Prompt      string            `json:"prompt"`
 	MaxTokens   int               `json:"max_tokens,omitempty"`
-	Temperature float64           `json:"temperature,omitempty"`
+	Temperature *float64          `json:"temperature,omitempty"` // Sampling temperature (nil = the provider's default)
 	Stop        []string          `json:"stop,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	System      string            `json:"system,omitempty"`
+	Reasoning   *ReasoningConfig  `json:"reasoning,omitempty"`
+
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"` // Makes the content a JSON object (nil = free text)
 }
 
 // CompletionResponse represents a completion response
 type CompletionResponse struct {
-	Content      string `json:"content"`
-	FinishReason string `json:"finish_reason"`
-	TokensUsed   int    `json:"tokens_used"`
+	Content        string `json:"content"`
+	FinishReason   string `json:"finish_reason"`
+	TokensUsed     int    `json:"tokens_used"`
+	ThinkingTokens int    `json:"thinking_tokens,omitempty"` // Portion of TokensUsed spent on reasoning
+	Thinking       string `json:"thinking,omitempty"`        // Reasoning text, when the provider exposes it
+
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"` // Tools the model asked to call (see ToolProvider)
+}
+
+// ReasoningConfig requests extended thinking from models that support it.
+// Anthropic models use BudgetTokens; OpenAI reasoning models use Effort, and
+// other OpenAI models ignore it.
+type ReasoningConfig struct {
+	BudgetTokens int    `json:"budget_tokens,omitempty"` // Anthropic extended thinking budget (min 1024)
+	Effort       string `json:"effort,omitempty"`        // OpenAI reasoning effort: "low", "medium", "high"
+}
+
+// ReasoningPolicy maps task complexity ("low", "medium", "high") to reasoning settings
+type ReasoningPolicy map[string]ReasoningConfig
+
+// DefaultReasoningPolicy returns budgets that grow with task complexity
+func DefaultReasoningPolicy() ReasoningPolicy {
+	return ReasoningPolicy{
+		"low":    {BudgetTokens: 1024, Effort: "low"},
+		"medium": {BudgetTokens: 4096, Effort: "medium"},
+		"high":   {BudgetTokens: 16000, Effort: "high"},
+	}
+}
+
+// For returns the reasoning settings for a complexity, or nil if none apply
+func (p ReasoningPolicy) For(complexity string) *ReasoningConfig {
+	if cfg, ok := p[complexity]; ok {
+		return &cfg
+	}
+	return nil
+}
+
+// estimateTokens approximates a token count for providers that don't report one
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
 }
 
 // ModelType represents supported model types
@@ -73,11 +113,12 @@ type Message struct {
 
 // ChatRequest represents a chat completion request
 type ChatRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
-	Stop        []string  `json:"stop,omitempty"`
+	Model       string           `json:"model"`
+	Messages    []Message        `json:"messages"`
+	MaxTokens   int              `json:"max_tokens,omitempty"`
+	Temperature *float64         `json:"temperature,omitempty"` // Sampling temperature (nil = the provider's default)
+	Stop        []string         `json:"stop,omitempty"`
+	Reasoning   *ReasoningConfig `json:"reasoning,omitempty"`
 }
 
 // ChatProvider extends Provider with chat capabilities
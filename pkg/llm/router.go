@@ -0,0 +1,359 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAllProvidersFailed is returned when no provider in a router could serve a request
+var ErrAllProvidersFailed = errors.New("all providers failed")
+
+// RouterConfig controls failover and health tracking
+type RouterConfig struct {
+	AttemptTimeout   time.Duration // Limit per provider attempt, so a hung vendor fails over (0 = none)
+	FailureThreshold int           // Consecutive failures that mark a provider unhealthy
+	Cooldown         time.Duration // How long an unhealthy provider is tried only as a last resort
+}
+
+// DefaultRouterConfig returns sensible defaults
+func DefaultRouterConfig() RouterConfig {
+	return RouterConfig{
+		AttemptTimeout:   2 * time.Minute,
+		FailureThreshold: 3,
+		Cooldown:         30 * time.Second,
+	}
+}
+
+// Router sends each request to the provider that serves its model, failing
+// over to the remaining providers on errors and timeouts. Providers that keep
+// failing are marked unhealthy and moved to the back of the line until their
+// cooldown passes, so a collective keeps working through a vendor outage.
+type Router struct {
+	mu sync.RWMutex
+
+	providers []*routedProvider // Primary first, then fallbacks in order
+	routes    []modelRoute
+	config    RouterConfig
+	now       func() time.Time
+
+	requests  int
+	failovers int // Requests served by a provider other than the first choice
+	failures  int // Requests no provider could serve
+	cancelled int // Requests the caller gave up on
+}
+
+// routedProvider is a provider with its health record
+type routedProvider struct {
+	provider Provider
+	health   ProviderHealth
+}
+
+// modelRoute sends models with a name prefix to a provider
+type modelRoute struct {
+	prefix   string
+	provider *routedProvider
+}
+
+// ProviderHealth is a provider's record within a router
+type ProviderHealth struct {
+	Name                string        `json:"name"`
+	Healthy             bool          `json:"healthy"`
+	Requests            int           `json:"requests"`
+	Failures            int           `json:"failures"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	LastError           string        `json:"last_error,omitempty"`
+	LastFailure         time.Time     `json:"last_failure,omitempty"`
+	AvgLatency          time.Duration `json:"avg_latency"` // Of successful requests
+
+	unhealthyUntil time.Time
+}
+
+// RouterStats reports routing activity
+type RouterStats struct {
+	Requests  int              `json:"requests"`
+	Failovers int              `json:"failovers"`
+	Failures  int              `json:"failures"`
+	Cancelled int              `json:"cancelled"`
+	Providers []ProviderHealth `json:"providers"`
+}
+
+// NewRouter creates a router that prefers primary and fails over to
+// fallbacks in the order given
+func NewRouter(primary Provider, fallbacks ...Provider) *Router {
+	r := &Router{
+		config: DefaultRouterConfig(),
+		now:    time.Now,
+	}
+	for _, p := range append([]Provider{primary}, fallbacks...) {
+		r.providers = append(r.providers, &routedProvider{
+			provider: p,
+			health:   ProviderHealth{Name: p.Name(), Healthy: true},
+		})
+	}
+	return r
+}
+
+// WithConfig sets the failover configuration
+func (r *Router) WithConfig(cfg RouterConfig) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config = cfg
+	return r
+}
+
+// Route sends requests for models starting with prefix (e.g. "claude-" or
+// "gpt-") to p first. p is added as a fallback if it isn't one already.
+// The longest matching prefix wins.
+func (r *Router) Route(prefix string, p Provider) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var target *routedProvider
+	for _, rp := range r.providers {
+		if rp.provider == p {
+			target = rp
+			break
+		}
+	}
+	if target == nil {
+		target = &routedProvider{provider: p, health: ProviderHealth{Name: p.Name(), Healthy: true}}
+		r.providers = append(r.providers, target)
+	}
+	r.routes = append(r.routes, modelRoute{prefix: prefix, provider: target})
+	return r
+}
+
+// Name returns the provider name
+func (r *Router) Name() string {
+	return "router"
+}
+
+// Complete sends the request to each candidate provider in turn until one succeeds
+func (r *Router) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return r.do(ctx, req, func(ctx context.Context, p Provider, req CompletionRequest) (*CompletionResponse, bool, error) {
+		resp, err := p.Complete(ctx, req)
+		return resp, false, err
+	})
+}
+
+// Stream streams from the first provider that succeeds. Once a provider has
+// produced output the router can't fail over without duplicating it, so the
+// partial response and error are returned instead. Providers that can't
+// stream deliver their whole completion as a single delta.
+func (r *Router) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	return r.do(ctx, req, func(ctx context.Context, p Provider, req CompletionRequest) (*CompletionResponse, bool, error) {
+		sp, ok := p.(StreamingProvider)
+		if !ok {
+			resp, err := p.Complete(ctx, req)
+			if err == nil {
+				onDelta(resp.Content)
+			}
+			return resp, false, err
+		}
+
+		started := false
+		resp, err := sp.Stream(ctx, req, func(delta string) {
+			started = true
+			onDelta(delta)
+		})
+		return resp, started, err
+	})
+}
+
+// CompleteTools sends a request with tools to each candidate provider in
+// turn until one succeeds. Providers that can't call tools fail with
+// ErrToolsUnsupported, so the router moves on to the next.
+func (r *Router) CompleteTools(ctx context.Context, req ToolRequest) (*CompletionResponse, error) {
+	return r.do(ctx, req.CompletionRequest, func(ctx context.Context, p Provider, base CompletionRequest) (*CompletionResponse, bool, error) {
+		tp, ok := p.(ToolProvider)
+		if !ok {
+			return nil, false, fmt.Errorf("%w: %s", ErrToolsUnsupported, p.Name())
+		}
+		toolReq := req
+		toolReq.CompletionRequest = base
+		resp, err := tp.CompleteTools(ctx, toolReq)
+		return resp, false, err
+	})
+}
+
+// Chat sends a chat to each candidate provider in turn until one succeeds.
+// Providers that can't chat get the messages as a transcript.
+func (r *Router) Chat(ctx context.Context, req ChatRequest) (*CompletionResponse, error) {
+	return r.do(ctx, CompletionRequest{Model: req.Model}, func(ctx context.Context, p Provider, base CompletionRequest) (*CompletionResponse, bool, error) {
+		chatReq := req
+		chatReq.Model = base.Model
+		resp, err := chat(ctx, p, chatReq)
+		return resp, false, err
+	})
+}
+
+// attemptFunc makes one request to a provider. committed reports that output
+// was already delivered, so a failure must not fail over.
+type attemptFunc func(ctx context.Context, p Provider, req CompletionRequest) (resp *CompletionResponse, committed bool, err error)
+
+// do runs attempt against each candidate provider in turn
+func (r *Router) do(ctx context.Context, req CompletionRequest, attempt attemptFunc) (*CompletionResponse, error) {
+	candidates, serving := r.candidates(req.Model)
+	timeout := r.timeout()
+
+	var errs []error
+	for i, rp := range candidates {
+		// A fallback that doesn't serve the requested model uses its own default
+		attemptReq := req
+		if rp != serving {
+			attemptReq.Model = ""
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		start := r.now()
+		resp, committed, err := attempt(attemptCtx, rp.provider, attemptReq)
+		cancel()
+
+		if err == nil {
+			r.recordSuccess(rp, r.now().Sub(start), i > 0)
+			return resp, nil
+		}
+
+		// The caller giving up says nothing about the provider
+		if ctx.Err() != nil {
+			r.recordEnd(&r.cancelled)
+			return resp, err
+		}
+		r.recordFailure(rp, err)
+		errs = append(errs, fmt.Errorf("%s: %w", rp.health.Name, err))
+		if committed {
+			r.recordEnd(&r.failures)
+			return resp, err
+		}
+	}
+
+	r.recordEnd(&r.failures)
+	return nil, fmt.Errorf("%w: %w", ErrAllProvidersFailed, errors.Join(errs...))
+}
+
+// recordEnd counts a request that ended without a response, under outcome
+func (r *Router) recordEnd(outcome *int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+	*outcome++
+}
+
+// candidates returns the providers to try for a model, best first: the routed
+// provider, then healthy providers in order, then unhealthy ones as a last
+// resort. Also returns the provider that serves the model: the routed one, or
+// the primary if no route matched.
+func (r *Router) candidates(model string) ([]*routedProvider, *routedProvider) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var routed *routedProvider
+	longest := -1
+	for _, route := range r.routes {
+		if strings.HasPrefix(model, route.prefix) && len(route.prefix) > longest {
+			routed, longest = route.provider, len(route.prefix)
+		}
+	}
+
+	now := r.now()
+	var healthy, unhealthy []*routedProvider
+	add := func(rp *routedProvider) {
+		if now.Before(rp.health.unhealthyUntil) {
+			unhealthy = append(unhealthy, rp)
+		} else {
+			healthy = append(healthy, rp)
+		}
+	}
+	if routed != nil {
+		add(routed)
+	}
+	for _, rp := range r.providers {
+		if rp != routed {
+			add(rp)
+		}
+	}
+	if routed == nil {
+		routed = r.providers[0]
+	}
+	return append(healthy, unhealthy...), routed
+}
+
+func (r *Router) timeout() time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.config.AttemptTimeout
+}
+
+// recordSuccess restores a provider's health
+func (r *Router) recordSuccess(rp *routedProvider, latency time.Duration, failover bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests++
+	if failover {
+		r.failovers++
+	}
+
+	h := &rp.health
+	h.Requests++
+	successes := h.Requests - h.Failures
+	h.AvgLatency += (latency - h.AvgLatency) / time.Duration(successes)
+	h.ConsecutiveFailures = 0
+	h.Healthy = true
+	h.unhealthyUntil = time.Time{}
+}
+
+// recordFailure counts a failure, marking the provider unhealthy once it
+// reaches the threshold
+func (r *Router) recordFailure(rp *routedProvider, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := &rp.health
+	h.Requests++
+	h.Failures++
+	h.ConsecutiveFailures++
+	h.LastError = err.Error()
+	h.LastFailure = r.now()
+	if h.ConsecutiveFailures >= r.config.FailureThreshold {
+		h.Healthy = false
+		h.unhealthyUntil = h.LastFailure.Add(r.config.Cooldown)
+	}
+}
+
+// Health returns each provider's health, primary first
+func (r *Router) Health() []ProviderHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	health := make([]ProviderHealth, len(r.providers))
+	for i, rp := range r.providers {
+		health[i] = rp.health
+	}
+	return health
+}
+
+// Stats returns routing statistics
+func (r *Router) Stats() RouterStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := RouterStats{
+		Requests:  r.requests,
+		Failovers: r.failovers,
+		Failures:  r.failures,
+		Cancelled: r.cancelled,
+		Providers: make([]ProviderHealth, len(r.providers)),
+	}
+	for i, rp := range r.providers {
+		stats.Providers[i] = rp.health
+	}
+	return stats
+}
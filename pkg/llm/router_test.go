@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var errUpstream = errors.New("upstream unavailable")
+
+// fakeProvider answers with its name, or fails with err after streaming deltas
+type fakeProvider struct {
+	name   string
+	err    error
+	deltas []string
+	cancel context.CancelFunc // Called before answering, as a caller giving up
+	calls  *[]string          // Shared log of calls, as "name:model"
+}
+
+func (p *fakeProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return p.Stream(ctx, req, func(string) {})
+}
+
+func (p *fakeProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error) {
+	*p.calls = append(*p.calls, p.name+":"+req.Model)
+	if p.cancel != nil {
+		p.cancel()
+		return nil, ctx.Err()
+	}
+	for _, d := range p.deltas {
+		onDelta(d)
+	}
+	if p.err != nil {
+		return &CompletionResponse{Content: strings.Join(p.deltas, "")}, p.err
+	}
+	onDelta(p.name)
+	return &CompletionResponse{Content: p.name}, nil
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+// chattingProvider is a fakeProvider that also chats, keeping the messages
+// of each chat
+type chattingProvider struct {
+	*fakeProvider
+	chats [][]Message
+}
+
+func (p *chattingProvider) Chat(ctx context.Context, req ChatRequest) (*CompletionResponse, error) {
+	p.chats = append(p.chats, req.Messages)
+	return p.Complete(ctx, CompletionRequest{Model: req.Model})
+}
+
+// fakeProviders makes a provider per name, failing those marked in fail
+func fakeProviders(calls *[]string, names []string, fail map[string]bool) []Provider {
+	var providers []Provider
+	for _, name := range names {
+		p := &fakeProvider{name: name, calls: calls}
+		if fail[name] {
+			p.err = errUpstream
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+func TestRouter_Failover(t *testing.T) {
+	tests := []struct {
+		name      string
+		fail      map[string]bool
+		routes    map[string]string // Prefix -> provider
+		model     string
+		calls     []string
+		content   string
+		failovers int
+		failures  int
+	}{
+		{"primary serves", nil, nil, "m", []string{"a:m"}, "a", 0, 0},
+		{"fails over in order", map[string]bool{"a": true, "b": true}, nil, "m", []string{"a:m", "b:", "c:"}, "c", 1, 0},
+		{"all fail", map[string]bool{"a": true, "b": true, "c": true}, nil, "m", []string{"a:m", "b:", "c:"}, "", 0, 1},
+		{"routed by prefix", nil, map[string]string{"gpt-": "c"}, "gpt-4o", []string{"c:gpt-4o"}, "c", 0, 0},
+		{"longest prefix wins", nil, map[string]string{"gpt-": "c", "gpt-4": "b"}, "gpt-4o", []string{"b:gpt-4o"}, "b", 0, 0},
+		{"unrouted model goes to primary", nil, map[string]string{"gpt-": "c"}, "claude-3", []string{"a:claude-3"}, "a", 0, 0},
+		{"routed provider fails over to primary", map[string]bool{"c": true}, map[string]string{"gpt-": "c"}, "gpt-4o", []string{"c:gpt-4o", "a:"}, "a", 1, 0},
+	}
+
+	for _, tt := range tests {
+		var calls []string
+		providers := fakeProviders(&calls, []string{"a", "b", "c"}, tt.fail)
+		r := NewRouter(providers[0], providers[1:]...)
+		for prefix, name := range tt.routes {
+			for _, p := range providers {
+				if p.Name() == name {
+					r.Route(prefix, p)
+				}
+			}
+		}
+
+		resp, err := r.Complete(context.Background(), CompletionRequest{Model: tt.model})
+		if !reflect.DeepEqual(calls, tt.calls) {
+			t.Errorf("%s: expected calls %v, got %v", tt.name, tt.calls, calls)
+		}
+		if tt.content == "" {
+			if !errors.Is(err, ErrAllProvidersFailed) || !errors.Is(err, errUpstream) {
+				t.Errorf("%s: expected ErrAllProvidersFailed wrapping the upstream error, got %v", tt.name, err)
+			}
+		} else if err != nil || resp.Content != tt.content {
+			t.Errorf("%s: expected %q, got %v (%v)", tt.name, tt.content, resp, err)
+		}
+		stats := r.Stats()
+		if stats.Requests != 1 || stats.Failovers != tt.failovers || stats.Failures != tt.failures {
+			t.Errorf("%s: expected 1 request, %d failovers, %d failures, got %+v", tt.name, tt.failovers, tt.failures, stats)
+		}
+	}
+}
+
+func TestRouter_Cooldown(t *testing.T) {
+	var calls []string
+	providers := fakeProviders(&calls, []string{"a", "b"}, map[string]bool{"a": true})
+	r := NewRouter(providers[0], providers[1]).WithConfig(RouterConfig{FailureThreshold: 2, Cooldown: 30 * time.Second})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		recover bool // The primary stops failing
+		calls   []string
+		healthy bool // The primary's health afterwards
+	}{
+		{"first failure", 0, false, []string{"a:", "b:"}, true},
+		{"reaches the threshold", 0, false, []string{"a:", "b:"}, false},
+		{"skipped while cooling down", 29 * time.Second, false, []string{"b:"}, false},
+		{"retried after cooldown", 2 * time.Second, true, []string{"a:"}, true},
+	}
+
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		if tt.recover {
+			providers[0].(*fakeProvider).err = nil
+		}
+		calls = nil
+
+		if _, err := r.Complete(context.Background(), CompletionRequest{}); err != nil {
+			t.Fatalf("%s: Complete failed: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(calls, tt.calls) {
+			t.Errorf("%s: expected calls %v, got %v", tt.name, tt.calls, calls)
+		}
+		if h := r.Health()[0]; h.Healthy != tt.healthy {
+			t.Errorf("%s: expected primary healthy %v, got %+v", tt.name, tt.healthy, h)
+		}
+	}
+
+	if h := r.Health()[0]; h.ConsecutiveFailures != 0 || h.Failures != 2 {
+		t.Errorf("Expected recovery to reset consecutive failures only, got %+v", h)
+	}
+}
+
+func TestRouter_Stream(t *testing.T) {
+	tests := []struct {
+		name    string
+		deltas  []string // Streamed by the primary before failing
+		calls   []string
+		content string
+		failed  bool
+	}{
+		{"fails over before output", nil, []string{"a:", "b:"}, "b", false},
+		{"no failover once output started", []string{"par", "tial"}, []string{"a:"}, "partial", true},
+	}
+
+	for _, tt := range tests {
+		var calls []string
+		providers := fakeProviders(&calls, []string{"a", "b"}, map[string]bool{"a": true})
+		providers[0].(*fakeProvider).deltas = tt.deltas
+		r := NewRouter(providers[0], providers[1])
+
+		var streamed strings.Builder
+		resp, err := r.Stream(context.Background(), CompletionRequest{}, func(d string) { streamed.WriteString(d) })
+		if !reflect.DeepEqual(calls, tt.calls) {
+			t.Errorf("%s: expected calls %v, got %v", tt.name, tt.calls, calls)
+		}
+		if (err != nil) != tt.failed {
+			t.Errorf("%s: expected failed %v, got %v", tt.name, tt.failed, err)
+		}
+		if resp == nil || resp.Content != tt.content || streamed.String() != tt.content {
+			t.Errorf("%s: expected %q returned and streamed, got %v and %q", tt.name, tt.content, resp, streamed.String())
+		}
+		if stats := r.Stats(); stats.Requests != 1 {
+			t.Errorf("%s: expected the request counted, got %+v", tt.name, stats)
+		}
+	}
+}
+
+func TestRouter_Chat(t *testing.T) {
+	var calls []string
+	providers := fakeProviders(&calls, []string{"a", "b"}, map[string]bool{"a": true})
+	primary := &chattingProvider{fakeProvider: providers[0].(*fakeProvider)}
+	secondary := &chattingProvider{fakeProvider: providers[1].(*fakeProvider)}
+	r := NewRouter(primary, secondary)
+
+	messages := []Message{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello"},
+		{Role: "user", Content: "How are you?"},
+	}
+	resp, err := r.Chat(context.Background(), ChatRequest{Model: "m", Messages: messages})
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "b" {
+		t.Errorf("Expected the secondary's answer, got %q", resp.Content)
+	}
+	if want := []string{"a:m", "b:"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Expected calls %v, got %v", want, calls)
+	}
+	if len(primary.chats) != 1 || len(secondary.chats) != 1 || !reflect.DeepEqual(secondary.chats[0], messages) {
+		t.Errorf("Expected the messages chatted to each provider in turn, got %v and %v", primary.chats, secondary.chats)
+	}
+
+	stats := r.Stats()
+	if stats.Requests != 1 || stats.Failovers != 1 || stats.Failures != 0 {
+		t.Errorf("Expected 1 request served by failover, got %+v", stats)
+	}
+	if h := stats.Providers[0]; h.Failures != 1 || h.LastError == "" {
+		t.Errorf("Expected the primary's failure recorded, got %+v", h)
+	}
+}
+
+func TestRouter_StatsCountCancelled(t *testing.T) {
+	var calls []string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	providers := fakeProviders(&calls, []string{"a", "b"}, nil)
+	providers[0].(*fakeProvider).cancel = cancel
+	r := NewRouter(providers[0], providers[1])
+
+	if _, err := r.Complete(ctx, CompletionRequest{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Expected no failover after the caller gave up, got calls %v", calls)
+	}
+	stats := r.Stats()
+	if stats.Requests != 1 || stats.Cancelled != 1 || stats.Failures != 0 {
+		t.Errorf("Expected 1 cancelled request, got %+v", stats)
+	}
+	if h := stats.Providers[0]; h.Failures != 0 || !h.Healthy {
+		t.Errorf("Expected the provider not blamed for the cancellation, got %+v", h)
+	}
+}
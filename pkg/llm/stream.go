@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+)
+
+// StreamingProvider is implemented by providers that can deliver a completion
+// incrementally. Callers use it to keep the text generated so far when a
+// request is cancelled or times out mid-stream.
+type StreamingProvider interface {
+	Provider
+
+	// Stream generates a completion, passing text to onDelta as it arrives.
+	// If the stream ends early the partial response is returned with the error.
+	Stream(ctx context.Context, req CompletionRequest, onDelta func(string)) (*CompletionResponse, error)
+}
+
+// readSSE reads a server-sent event stream, calling fn with each event's name
+// and data until the stream ends, fn fails or the data is "[DONE]"
+func readSSE(r io.Reader, fn func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var (
+		event string
+		data  []byte
+	)
+	dispatch := func() error {
+		defer func() { event, data = "", nil }()
+		if len(data) == 0 {
+			return nil
+		}
+		if bytes.Equal(data, []byte("[DONE]")) {
+			return io.EOF
+		}
+		return fn(event, data)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			if err := dispatch(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		case bytes.HasPrefix(line, []byte("event:")):
+			event = string(bytes.TrimSpace(line[len("event:"):]))
+		case bytes.HasPrefix(line, []byte("data:")):
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(line[len("data:"):], []byte(" "))...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// A final event may not be followed by a blank line
+	if err := dispatch(); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrToolsUnsupported is returned when a request with tools reaches a
+// provider that can't call them
+var ErrToolsUnsupported = errors.New("provider does not support tool calling")
+
+// Tool describes a function the model may call. InputSchema is the JSON
+// Schema of its arguments; providers translate it into their own
+// tool-calling format.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+// ToolCall is the model asking for a tool to be called
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+// ToolOutput is the result of a tool call, returned to the model
+type ToolOutput struct {
+	CallID  string `json:"call_id"`
+	Content string `json:"content"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// ToolTurn is one round of tool use: the text and calls the model answered
+// with, and the outputs of those calls
+type ToolTurn struct {
+	Text    string       `json:"text,omitempty"`
+	Calls   []ToolCall   `json:"calls"`
+	Outputs []ToolOutput `json:"outputs"`
+}
+
+// ToolRequest is a completion request the model may answer with tool
+// calls. Turns holds the rounds of tool use so far, oldest first.
+type ToolRequest struct {
+	CompletionRequest
+	Tools []Tool     `json:"tools"`
+	Turns []ToolTurn `json:"turns,omitempty"`
+}
+
+// ToolProvider is implemented by providers whose models can call tools
+type ToolProvider interface {
+	Provider
+
+	// CompleteTools generates a completion that may end in tool calls,
+	// returned in the response's ToolCalls
+	CompleteTools(ctx context.Context, req ToolRequest) (*CompletionResponse, error)
+}
+
+// toolSchema returns a tool's input schema, defaulting to an object without
+// properties since providers require one
+func toolSchema(t Tool) json.RawMessage {
+	if len(t.InputSchema) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return t.InputSchema
+}
+
+// toolInput returns a call's arguments, defaulting to an empty object
+func toolInput(c ToolCall) json.RawMessage {
+	if len(c.Input) == 0 {
+		return json.RawMessage(`{}`)
+	}
+	return c.Input
+}
@@ -0,0 +1,328 @@
+// Package llmlog logs the requests agents make to LLM providers and the
+// responses they get, so it can be seen why an agent produced an output.
+// A Provider wraps another and appends each exchange to a JSONL log, after
+// redacting API keys and, unless configured otherwise, personal
+// information.
+package llmlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+var (
+	ErrEntryNotFound  = errors.New("LLM log entry not found")
+	ErrAmbiguousEntry = errors.New("LLM log entry ID prefix matches several entries")
+)
+
+// DefaultMaxSize is the size a log grows to before it is rotated
+const DefaultMaxSize = 64 << 20
+
+// Config configures the request log
+type Config struct {
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`         // Log file (default ~/.squaremind/llm.jsonl)
+	MaxSize int64  `json:"max_size,omitempty" yaml:"max_size,omitempty"` // Bytes before the log is rotated to <path>.1 (default 64 MiB)
+	KeepPII bool   `json:"keep_pii,omitempty" yaml:"keep_pii,omitempty"` // Don't redact emails, phone, card and social security numbers or IP addresses
+	Rules   []Rule `json:"rules,omitempty" yaml:"rules,omitempty"`       // Further patterns to redact
+}
+
+// Redactor returns the redactor for the configured rules and secrets
+func (c Config) Redactor(secrets ...string) (*Redactor, error) {
+	rules := append([]Rule(nil), KeyRules...)
+	if !c.KeepPII {
+		rules = append(rules, PIIRules...)
+	}
+	return NewRedactor(append(rules, c.Rules...), secrets...)
+}
+
+// Request kinds
+const (
+	KindComplete = "complete"
+	KindStream   = "stream"
+	KindChat     = "chat"
+	KindTools    = "tools"
+)
+
+// Entry is one request and its response, redacted
+type Entry struct {
+	ID       string        `json:"id"`
+	Time     time.Time     `json:"time"`
+	Provider string        `json:"provider"`
+	Kind     string        `json:"kind"`
+	Duration time.Duration `json:"duration"`
+	llm.Caller
+
+	// Request
+	Model       string         `json:"model,omitempty"`
+	System      string         `json:"system,omitempty"`
+	Prompt      string         `json:"prompt,omitempty"`
+	Messages    []llm.Message  `json:"messages,omitempty"`    // Chats
+	Tools       []string       `json:"tools,omitempty"`       // Names of the tools offered
+	Turns       []llm.ToolTurn `json:"turns,omitempty"`       // Rounds of tool use before the request
+	Format      string         `json:"format,omitempty"`      // Name of the response format
+	MaxTokens   int            `json:"max_tokens,omitempty"`  // As requested (0 = the provider default)
+	Temperature *float64       `json:"temperature,omitempty"` // As requested (nil = the provider default)
+
+	// Response
+	Response       string         `json:"response,omitempty"`
+	ToolCalls      []llm.ToolCall `json:"tool_calls,omitempty"`
+	FinishReason   string         `json:"finish_reason,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	TokensUsed     int            `json:"tokens_used,omitempty"`
+	ThinkingTokens int            `json:"thinking_tokens,omitempty"`
+
+	Redactions int `json:"redactions,omitempty"` // Values replaced by the redactor
+}
+
+// redact scrubs every text of the entry
+func (e *Entry) redact(r *Redactor) {
+	scrub := func(s *string) {
+		var n int
+		*s, n = r.Redact(*s)
+		e.Redactions += n
+	}
+	scrubJSON := func(raw *json.RawMessage) {
+		s := string(*raw)
+		scrub(&s)
+		if json.Valid([]byte(s)) {
+			*raw = json.RawMessage(s)
+		} else {
+			*raw, _ = json.Marshal(s) // A replacement broke the JSON
+		}
+	}
+
+	scrub(&e.System)
+	scrub(&e.Prompt)
+	scrub(&e.Response)
+	scrub(&e.Error)
+	for i := range e.Messages {
+		scrub(&e.Messages[i].Content)
+	}
+	for i := range e.ToolCalls {
+		scrubJSON(&e.ToolCalls[i].Input)
+	}
+	for i := range e.Turns {
+		turn := &e.Turns[i]
+		scrub(&turn.Text)
+		for j := range turn.Calls {
+			scrubJSON(&turn.Calls[j].Input)
+		}
+		for j := range turn.Outputs {
+			scrub(&turn.Outputs[j].Content)
+		}
+	}
+}
+
+// Log appends entries to a JSONL file, rotating it to <path>.1 when it
+// outgrows its maximum size
+type Log struct {
+	mu sync.Mutex
+
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+// Open opens the log at path for appending, creating it if needed
+func Open(path string, maxSize int64) (*Log, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	l := &Log{path: path, maxSize: maxSize}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the log file. Caller must hold l.mu or have exclusive access.
+func (l *Log) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Append writes an entry
+func (l *Log) Append(e *Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return os.ErrClosed
+	}
+	if l.size > 0 && l.size+int64(len(data)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	return err
+}
+
+// rotate moves the log to <path>.1, replacing the previous one. Caller must
+// hold l.mu.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Close closes the log file
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Filter selects log entries; zero fields match everything
+type Filter struct {
+	Agent    string    // Agent SID prefix or name
+	Task     string    // Task ID prefix
+	Model    string    // Model name prefix
+	Provider string    // Provider name
+	Errors   bool      // Only failed requests
+	Contains string    // Text in the prompt, messages or response, case-insensitively
+	Since    time.Time // Requests made at or after
+	Limit    int       // Most recent entries returned (0 = all)
+}
+
+// match reports whether an entry passes the filter
+func (f Filter) match(e *Entry) bool {
+	switch {
+	case f.Agent != "" && !strings.HasPrefix(e.AgentSID, f.Agent) && !strings.EqualFold(e.Agent, f.Agent):
+		return false
+	case f.Task != "" && !strings.HasPrefix(e.TaskID, f.Task):
+		return false
+	case f.Model != "" && !strings.HasPrefix(e.Model, f.Model):
+		return false
+	case f.Provider != "" && e.Provider != f.Provider:
+		return false
+	case f.Errors && e.Error == "":
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	}
+	if f.Contains != "" {
+		needle := strings.ToLower(f.Contains)
+		texts := []string{e.System, e.Prompt, e.Response, e.Error}
+		for _, m := range e.Messages {
+			texts = append(texts, m.Content)
+		}
+		for _, t := range texts {
+			if strings.Contains(strings.ToLower(t), needle) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Read returns the entries of the log at path and its rotated predecessor
+// that pass the filter, most recent first. Lines that can't be read are
+// skipped.
+func Read(path string, f Filter) ([]*Entry, error) {
+	var entries []*Entry
+	for _, p := range []string{path + ".1", path} {
+		err := scan(p, func(e *Entry) {
+			if f.match(e) {
+				entries = append(entries, e)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[:f.Limit]
+	}
+	return entries, nil
+}
+
+// Find returns the entry with an ID or a unique prefix of it
+func Find(path, id string) (*Entry, error) {
+	var matches []*Entry
+	for _, p := range []string{path + ".1", path} {
+		err := scan(p, func(e *Entry) {
+			if strings.HasPrefix(e.ID, id) {
+				matches = append(matches, e)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range matches {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrEntryNotFound, id)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrAmbiguousEntry, id)
+}
+
+// scan calls fn with each entry of a log file; a missing file has none
+func scan(path string, fn func(*Entry)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err == nil && e.ID != "" {
+			fn(&e)
+		}
+	}
+	return scanner.Err()
+}
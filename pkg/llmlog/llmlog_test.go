@@ -0,0 +1,129 @@
+package llmlog
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+type echoProvider struct{}
+
+func (p *echoProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	if strings.Contains(req.Prompt, "fail") {
+		return nil, errors.New("upstream failed")
+	}
+	return &llm.CompletionResponse{Content: "You said: " + req.Prompt, TokensUsed: 10}, nil
+}
+
+func (p *echoProvider) Name() string {
+	return "echo"
+}
+
+func TestRedactor_Redact(t *testing.T) {
+	r, err := Config{}.Redactor("hunter2-secret")
+	if err != nil {
+		t.Fatalf("Redactor failed: %v", err)
+	}
+	text := "key sk-ant-api03-abcdefghijk, password hunter2-secret, mail ann@example.com from 10.0.0.12"
+	got, n := r.Redact(text)
+	for _, leaked := range []string{"sk-ant-api03", "hunter2", "ann@example.com", "10.0.0.12"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("Expected %q redacted, got %q", leaked, got)
+		}
+	}
+	if n != 4 || !strings.Contains(got, "[REDACTED:email]") || !strings.Contains(got, "[REDACTED:secret]") {
+		t.Errorf("Expected 4 named redactions, got %d in %q", n, got)
+	}
+
+	keep, _ := Config{KeepPII: true, Rules: []Rule{{Name: "ticket", Pattern: `TICKET-\d+`}}}.Redactor()
+	got, _ = keep.Redact("ann@example.com filed TICKET-42")
+	if got != "ann@example.com filed [REDACTED:ticket]" {
+		t.Errorf("Expected PII kept and the custom rule applied, got %q", got)
+	}
+
+	if _, err := NewRedactor([]Rule{{Name: "bad", Pattern: "("}}); err == nil {
+		t.Error("Expected an invalid pattern to fail")
+	}
+}
+
+func TestProvider_Logs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llm.jsonl")
+	log, err := Open(path, 0)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer log.Close()
+	redactor, _ := Config{}.Redactor()
+	p := Wrap(&echoProvider{}, log, redactor)
+
+	ctx := llm.ContextWithCaller(context.Background(), llm.Caller{AgentSID: "sid-1", Agent: "Coder", TaskID: "task-1"})
+	if _, err := p.Complete(ctx, llm.CompletionRequest{Model: "m1", Prompt: "mail bob@example.com"}); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if _, err := p.Complete(context.Background(), llm.CompletionRequest{Model: "m2", Prompt: "please fail"}); err == nil {
+		t.Fatal("Expected the provider's error")
+	}
+	if _, err := p.(llm.ToolProvider).CompleteTools(ctx, llm.ToolRequest{}); !errors.Is(err, llm.ErrToolsUnsupported) {
+		t.Errorf("Expected ErrToolsUnsupported, got %v", err)
+	}
+
+	entries, err := Read(path, Filter{})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	failed, ok := entries[0], entries[1]
+	if ok.Agent != "Coder" || ok.TaskID != "task-1" || ok.Provider != "echo" || ok.TokensUsed != 10 {
+		t.Errorf("Expected the request attributed to Coder's task, got %+v", ok)
+	}
+	if strings.Contains(ok.Prompt+ok.Response, "bob@") || ok.Redactions != 2 {
+		t.Errorf("Expected the email redacted in prompt and response, got %q / %q", ok.Prompt, ok.Response)
+	}
+	if failed.Error != "upstream failed" || failed.Model != "m2" {
+		t.Errorf("Expected the failed request logged, got %+v", failed)
+	}
+
+	if entries, _ := Read(path, Filter{Agent: "coder"}); len(entries) != 1 {
+		t.Errorf("Expected 1 entry for the agent, got %d", len(entries))
+	}
+	if entries, _ := Read(path, Filter{Errors: true, Contains: "PLEASE"}); len(entries) != 1 || entries[0].ID != failed.ID {
+		t.Errorf("Expected the failed entry, got %d", len(entries))
+	}
+
+	found, err := Find(path, ok.ID[:8])
+	if err != nil || found.ID != ok.ID {
+		t.Errorf("Expected to find %s by prefix, got %v", ok.ID, err)
+	}
+	if _, err := Find(path, "nope"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+}
+
+func TestLog_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llm.jsonl")
+	log, err := Open(path, 300)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer log.Close()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if err := log.Append(&Entry{ID: id, Prompt: strings.Repeat("x", 150)}); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+	entries, _ := Read(path, Filter{})
+	// Only the current log and the one before it are kept
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries after rotation, got %d", len(entries))
+	}
+	if _, err := Find(path, "a"); !errors.Is(err, ErrEntryNotFound) {
+		t.Errorf("Expected the oldest entry rotated out, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package llmlog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// Provider logs the requests made to another provider and its responses.
+// It streams and calls tools if the provider it wraps does.
+type Provider struct {
+	inner    llm.Provider
+	log      *Log
+	redactor *Redactor
+}
+
+// Wrap returns p with its requests logged to log after redaction. The
+// result is a ChatProvider if p is.
+func Wrap(p llm.Provider, log *Log, redactor *Redactor) llm.Provider {
+	w := &Provider{inner: p, log: log, redactor: redactor}
+	if _, ok := p.(llm.ChatProvider); ok {
+		return &chatProvider{w}
+	}
+	return w
+}
+
+// Unwrap returns the provider requests are logged for
+func (p *Provider) Unwrap() llm.Provider {
+	return p.inner
+}
+
+// Name returns the wrapped provider's name
+func (p *Provider) Name() string {
+	return p.inner.Name()
+}
+
+func (p *Provider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	start := time.Now()
+	resp, err := p.inner.Complete(ctx, req)
+	p.record(ctx, completionEntry(KindComplete, req), start, resp, err)
+	return resp, err
+}
+
+// Stream streams if the provider can and completes otherwise
+func (p *Provider) Stream(ctx context.Context, req llm.CompletionRequest, onDelta func(string)) (*llm.CompletionResponse, error) {
+	streamer, ok := p.inner.(llm.StreamingProvider)
+	if !ok {
+		return p.Complete(ctx, req)
+	}
+	start := time.Now()
+	resp, err := streamer.Stream(ctx, req, onDelta)
+	p.record(ctx, completionEntry(KindStream, req), start, resp, err)
+	return resp, err
+}
+
+// CompleteTools passes a tool request on if the provider supports tools
+func (p *Provider) CompleteTools(ctx context.Context, req llm.ToolRequest) (*llm.CompletionResponse, error) {
+	tp, ok := p.inner.(llm.ToolProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", llm.ErrToolsUnsupported, p.inner.Name())
+	}
+	start := time.Now()
+	resp, err := tp.CompleteTools(ctx, req)
+
+	e := completionEntry(KindTools, req.CompletionRequest)
+	for _, t := range req.Tools {
+		e.Tools = append(e.Tools, t.Name)
+	}
+	e.Turns = copyTurns(req.Turns)
+	p.record(ctx, e, start, resp, err)
+	return resp, err
+}
+
+// copyTurns copies tool turns deeply enough to redact them without
+// touching the request's
+func copyTurns(turns []llm.ToolTurn) []llm.ToolTurn {
+	if turns == nil {
+		return nil
+	}
+	out := make([]llm.ToolTurn, len(turns))
+	for i, t := range turns {
+		out[i] = llm.ToolTurn{
+			Text:    t.Text,
+			Calls:   append([]llm.ToolCall(nil), t.Calls...),
+			Outputs: append([]llm.ToolOutput(nil), t.Outputs...),
+		}
+	}
+	return out
+}
+
+// chatProvider is a logged provider of multi-turn chats
+type chatProvider struct {
+	*Provider
+}
+
+func (p *chatProvider) Chat(ctx context.Context, req llm.ChatRequest) (*llm.CompletionResponse, error) {
+	start := time.Now()
+	resp, err := p.inner.(llm.ChatProvider).Chat(ctx, req)
+	p.record(ctx, &Entry{
+		Kind:        KindChat,
+		Model:       req.Model,
+		Messages:    append([]llm.Message(nil), req.Messages...),
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}, start, resp, err)
+	return resp, err
+}
+
+// completionEntry starts the entry of a completion request
+func completionEntry(kind string, req llm.CompletionRequest) *Entry {
+	e := &Entry{
+		Kind:        kind,
+		Model:       req.Model,
+		System:      req.System,
+		Prompt:      req.Prompt,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if req.ResponseFormat != nil {
+		e.Format = req.ResponseFormat.Name
+		if e.Format == "" {
+			e.Format = "json"
+		}
+	}
+	return e
+}
+
+// record completes an entry with the response, redacts and appends it. A
+// log that can't be written never fails the request.
+func (p *Provider) record(ctx context.Context, e *Entry, start time.Time, resp *llm.CompletionResponse, err error) {
+	e.ID = uuid.New().String()
+	e.Time = start
+	e.Duration = time.Since(start)
+	e.Provider = p.inner.Name()
+	e.Caller, _ = llm.CallerFromContext(ctx)
+	if resp != nil {
+		e.Response = resp.Content
+		e.ToolCalls = append([]llm.ToolCall(nil), resp.ToolCalls...)
+		e.FinishReason = resp.FinishReason
+		e.TokensUsed = resp.TokensUsed
+		e.ThinkingTokens = resp.ThinkingTokens
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	e.redact(p.redactor)
+	_ = p.log.Append(e)
+}
@@ -0,0 +1,89 @@
+package llmlog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule redacts the matches of a regular expression, replacing each with
+// [REDACTED:<name>]
+type Rule struct {
+	Name    string `json:"name" yaml:"name"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+}
+
+// KeyRules match API keys and other credentials
+var KeyRules = []Rule{
+	{Name: "anthropic_key", Pattern: `sk-ant-[A-Za-z0-9_-]{8,}`},
+	{Name: "openai_key", Pattern: `sk-[A-Za-z0-9_-]{20,}`},
+	{Name: "aws_key", Pattern: `AKIA[0-9A-Z]{16}`},
+	{Name: "github_token", Pattern: `gh[pousr]_[A-Za-z0-9]{20,}|github_pat_[A-Za-z0-9_]{20,}`},
+	{Name: "slack_token", Pattern: `xox[abprs]-[A-Za-z0-9-]{10,}`},
+	{Name: "bearer", Pattern: `(?i:bearer)\s+[A-Za-z0-9._~+/=-]{8,}`},
+	{Name: "private_key", Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`},
+}
+
+// PIIRules match personal information
+var PIIRules = []Rule{
+	{Name: "email", Pattern: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	{Name: "card", Pattern: `\b(?:\d[ -]?){13,16}\b`},
+	{Name: "ssn", Pattern: `\b\d{3}-\d{2}-\d{4}\b`},
+	{Name: "phone", Pattern: `(?:\+\d{1,3}[ .-]?)?\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`},
+	{Name: "ip", Pattern: `\b(?:\d{1,3}\.){3}\d{1,3}\b`},
+}
+
+type compiledRule struct {
+	replacement string
+	re          *regexp.Regexp
+}
+
+// Redactor scrubs secrets and personal information from logged text
+type Redactor struct {
+	secrets []string
+	rules   []compiledRule
+}
+
+// NewRedactor creates a redactor applying rules in order after replacing
+// the exact secrets, such as the configured API keys
+func NewRedactor(rules []Rule, secrets ...string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, s := range secrets {
+		if len(s) >= 6 {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	// Longest first, so a secret containing another is replaced whole
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %q: %w", rule.Name, err)
+		}
+		r.rules = append(r.rules, compiledRule{replacement: "[REDACTED:" + rule.Name + "]", re: re})
+	}
+	return r, nil
+}
+
+// Redact returns s with secrets replaced, and how many were
+func (r *Redactor) Redact(s string) (string, int) {
+	if r == nil || s == "" {
+		return s, 0
+	}
+	count := 0
+	for _, secret := range r.secrets {
+		if n := strings.Count(s, secret); n > 0 {
+			s = strings.ReplaceAll(s, secret, "[REDACTED:secret]")
+			count += n
+		}
+	}
+	for _, rule := range r.rules {
+		s = rule.re.ReplaceAllStringFunc(s, func(string) string {
+			count++
+			return rule.replacement
+		})
+	}
+	return s, count
+}
@@ -0,0 +1,233 @@
+// Package logging provides the pluggable structured logger used by agents,
+// the collective and coordination components.
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Logger is a leveled, structured logger. Arguments after the message are
+// alternating key/value pairs, as in log/slog.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+
+	// With returns a logger that adds the given key/value pairs to every entry
+	With(args ...interface{}) Logger
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger Logger
+)
+
+// Default returns the process-wide logger. Unless replaced with SetDefault it
+// writes through slog.Default(), resolved at log time.
+func Default() Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	if defaultLogger == nil {
+		return slogLogger{}
+	}
+	return defaultLogger
+}
+
+// SetDefault replaces the process-wide logger. Passing nil restores the slog adapter.
+func SetDefault(l Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// Component returns a logger tagged with a component name. Component loggers
+// resolve the default logger on every call, so SetDefault takes effect for
+// components created earlier.
+func Component(name string) Logger {
+	return componentLogger{args: []interface{}{"component", name}}
+}
+
+// componentLogger forwards to the current default logger with fixed attributes
+type componentLogger struct {
+	args []interface{}
+}
+
+func (l componentLogger) Debug(msg string, args ...interface{}) {
+	Default().Debug(msg, l.merge(args)...)
+}
+
+func (l componentLogger) Info(msg string, args ...interface{}) {
+	Default().Info(msg, l.merge(args)...)
+}
+
+func (l componentLogger) Warn(msg string, args ...interface{}) {
+	Default().Warn(msg, l.merge(args)...)
+}
+
+func (l componentLogger) Error(msg string, args ...interface{}) {
+	Default().Error(msg, l.merge(args)...)
+}
+
+func (l componentLogger) With(args ...interface{}) Logger {
+	return componentLogger{args: l.merge(args)}
+}
+
+// merge prepends the component's fixed attributes to args
+func (l componentLogger) merge(args []interface{}) []interface{} {
+	merged := make([]interface{}, 0, len(l.args)+len(args))
+	merged = append(merged, l.args...)
+	return append(merged, args...)
+}
+
+// NewSlog adapts a *slog.Logger. A nil logger uses slog.Default() at log time.
+func NewSlog(l *slog.Logger) Logger {
+	return slogLogger{logger: l}
+}
+
+// slogLogger adapts log/slog to Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) get() *slog.Logger {
+	if l.logger == nil {
+		return slog.Default()
+	}
+	return l.logger
+}
+
+func (l slogLogger) Debug(msg string, args ...interface{}) {
+	l.get().Debug(msg, args...)
+}
+
+func (l slogLogger) Info(msg string, args ...interface{}) {
+	l.get().Info(msg, args...)
+}
+
+func (l slogLogger) Warn(msg string, args ...interface{}) {
+	l.get().Warn(msg, args...)
+}
+
+func (l slogLogger) Error(msg string, args ...interface{}) {
+	l.get().Error(msg, args...)
+}
+
+func (l slogLogger) With(args ...interface{}) Logger {
+	return slogLogger{logger: l.get().With(args...)}
+}
+
+// Nop returns a logger that discards everything
+func Nop() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+func (n nopLogger) With(...interface{}) Logger { return n }
+
+// Entry is a single log record delivered to a hook
+type Entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   map[string]interface{}
+}
+
+// NewHook returns a logger that passes every entry at or above min to fn,
+// letting library consumers capture activity programmatically
+func NewHook(min slog.Level, fn func(Entry)) Logger {
+	return hookLogger{min: min, fn: fn}
+}
+
+type hookLogger struct {
+	min  slog.Level
+	fn   func(Entry)
+	args []interface{}
+}
+
+func (h hookLogger) Debug(msg string, args ...interface{}) { h.emit(slog.LevelDebug, msg, args) }
+func (h hookLogger) Info(msg string, args ...interface{})  { h.emit(slog.LevelInfo, msg, args) }
+func (h hookLogger) Warn(msg string, args ...interface{})  { h.emit(slog.LevelWarn, msg, args) }
+func (h hookLogger) Error(msg string, args ...interface{}) { h.emit(slog.LevelError, msg, args) }
+
+func (h hookLogger) With(args ...interface{}) Logger {
+	merged := append(append([]interface{}(nil), h.args...), args...)
+	return hookLogger{min: h.min, fn: h.fn, args: merged}
+}
+
+// emit builds an entry from alternating key/value pairs and delivers it
+func (h hookLogger) emit(level slog.Level, msg string, args []interface{}) {
+	if level < h.min {
+		return
+	}
+
+	all := append(append([]interface{}(nil), h.args...), args...)
+	attrs := make(map[string]interface{}, len(all)/2)
+	for i := 0; i < len(all); i += 2 {
+		key := fmt.Sprint(all[i])
+		if i+1 < len(all) {
+			attrs[key] = all[i+1]
+		} else {
+			attrs["!BADKEY"] = all[i]
+		}
+	}
+
+	h.fn(Entry{Time: time.Now(), Level: level, Message: msg, Attrs: attrs})
+}
+
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// Tee returns a logger that writes every entry to each of loggers
+func Tee(loggers ...Logger) Logger {
+	return teeLogger(loggers)
+}
+
+type teeLogger []Logger
+
+func (t teeLogger) Debug(msg string, args ...interface{}) {
+	for _, l := range t {
+		l.Debug(msg, args...)
+	}
+}
+
+func (t teeLogger) Info(msg string, args ...interface{}) {
+	for _, l := range t {
+		l.Info(msg, args...)
+	}
+}
+
+func (t teeLogger) Warn(msg string, args ...interface{}) {
+	for _, l := range t {
+		l.Warn(msg, args...)
+	}
+}
+
+func (t teeLogger) Error(msg string, args ...interface{}) {
+	for _, l := range t {
+		l.Error(msg, args...)
+	}
+}
+
+func (t teeLogger) With(args ...interface{}) Logger {
+	with := make(teeLogger, len(t))
+	for i, l := range t {
+		with[i] = l.With(args...)
+	}
+	return with
+}
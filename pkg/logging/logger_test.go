@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHook_Levels(t *testing.T) {
+	var entries []Entry
+	l := NewHook(slog.LevelInfo, func(e Entry) { entries = append(entries, e) })
+
+	l.Debug("hidden")
+	l.With("component", "market").Info("task assigned", "task", "t1")
+	l.Warn("odd", "dangling")
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Message != "task assigned" {
+		t.Errorf("Expected message 'task assigned', got '%s'", entries[0].Message)
+	}
+	if entries[0].Attrs["component"] != "market" || entries[0].Attrs["task"] != "t1" {
+		t.Errorf("Expected component and task attrs, got %v", entries[0].Attrs)
+	}
+	if entries[1].Attrs["!BADKEY"] != "dangling" {
+		t.Errorf("Expected dangling key to be kept, got %v", entries[1].Attrs)
+	}
+}
+
+func TestComponent_FollowsDefault(t *testing.T) {
+	l := Component("consensus").With("team", "review")
+
+	var entries []Entry
+	SetDefault(NewHook(slog.LevelDebug, func(e Entry) { entries = append(entries, e) }))
+	defer SetDefault(nil)
+
+	l.Debug("round proposed")
+
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+	if entries[0].Attrs["component"] != "consensus" || entries[0].Attrs["team"] != "review" {
+		t.Errorf("Expected component and team attrs, got %v", entries[0].Attrs)
+	}
+}
+
+func TestNewSlog(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlog(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	l.Info("quiet")
+	l.With("agent", "a1").Warn("task failed")
+
+	out := buf.String()
+	if strings.Contains(out, "quiet") {
+		t.Error("Expected info entry to be filtered")
+	}
+	if !strings.Contains(out, "task failed") || !strings.Contains(out, "agent=a1") {
+		t.Errorf("Expected warn entry with agent attr, got %q", out)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var infos, warns []Entry
+	l := Tee(
+		NewHook(slog.LevelInfo, func(e Entry) { infos = append(infos, e) }),
+		NewHook(slog.LevelWarn, func(e Entry) { warns = append(warns, e) }),
+	).With("component", "market")
+
+	l.Info("bid placed")
+	l.Warn("no bids")
+
+	if len(infos) != 2 || len(warns) != 1 {
+		t.Fatalf("Expected 2 info and 1 warn entries, got %d and %d", len(infos), len(warns))
+	}
+	if warns[0].Attrs["component"] != "market" {
+		t.Errorf("Expected component attr on every logger, got %v", warns[0].Attrs)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel("debug"); err != nil || level != slog.LevelDebug {
+		t.Errorf("Expected debug level, got %v (%v)", level, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Error("Expected error for unknown level")
+	}
+}
@@ -0,0 +1,275 @@
+// Package migrate upgrades the state squaremind keeps on disk from one release
+// to the next. The format version of each store is recorded in a manifest in
+// the state directory. Pending migrations run in order after the directory is
+// backed up, and a failed run restores the backup.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+const (
+	// ManifestFile records the format version of each store
+	ManifestFile = "state.json"
+
+	// BackupDir holds a copy of the state directory taken before each run
+	BackupDir = "backups"
+)
+
+var (
+	// ErrInvalidMigration is returned for a malformed migration list
+	ErrInvalidMigration = errors.New("invalid migration")
+
+	// ErrNewerState is returned when the state was written by a newer release
+	ErrNewerState = errors.New("state written by a newer release")
+)
+
+// Migration upgrades one store from Version-1 to Version. Apply receives the
+// state directory and must be a no-op if the store doesn't exist yet.
+type Migration struct {
+	Store       string
+	Version     int
+	Description string
+	Apply       func(dir string) error
+}
+
+// Manifest is the record of each store's format version
+type Manifest struct {
+	Versions map[string]int `json:"versions"`
+	Updated  time.Time      `json:"updated"`
+}
+
+// Result describes a completed run
+type Result struct {
+	Applied []Migration
+	Backup  string // Directory the state was copied to before migrating (empty if nothing ran)
+}
+
+// Migrator brings a state directory up to the latest version of every store
+type Migrator struct {
+	dir        string
+	migrations []Migration
+	logger     logging.Logger
+}
+
+// New creates a migrator for a state directory. Each store's migrations must
+// be numbered 1, 2, 3... in order.
+func New(dir string, migrations []Migration) (*Migrator, error) {
+	next := make(map[string]int)
+	for _, m := range migrations {
+		if m.Store == "" || m.Apply == nil {
+			return nil, fmt.Errorf("%w: migration %d needs a store and an Apply function", ErrInvalidMigration, m.Version)
+		}
+		if want := next[m.Store] + 1; m.Version != want {
+			return nil, fmt.Errorf("%w: %s migration %d out of order, expected %d", ErrInvalidMigration, m.Store, m.Version, want)
+		}
+		next[m.Store] = m.Version
+	}
+	return &Migrator{
+		dir:        dir,
+		migrations: migrations,
+		logger:     logging.Component("migrate"),
+	}, nil
+}
+
+// SetLogger replaces the migrator's logger
+func (m *Migrator) SetLogger(l logging.Logger) {
+	m.logger = l
+}
+
+// Manifest reads the state directory's manifest. State from before manifests
+// were kept has every store at version 0.
+func (m *Migrator) Manifest() (Manifest, error) {
+	manifest := Manifest{Versions: make(map[string]int)}
+
+	data, err := os.ReadFile(filepath.Join(m.dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	if manifest.Versions == nil {
+		manifest.Versions = make(map[string]int)
+	}
+	return manifest, nil
+}
+
+// Pending returns the migrations that haven't been applied, in order. It
+// fails with ErrNewerState if a store is ahead of the latest known migration.
+func (m *Migrator) Pending() ([]Migration, error) {
+	manifest, err := m.Manifest()
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]int)
+	for _, mig := range m.migrations {
+		latest[mig.Store] = mig.Version
+	}
+	stores := make([]string, 0, len(manifest.Versions))
+	for store := range manifest.Versions {
+		stores = append(stores, store)
+	}
+	sort.Strings(stores)
+	for _, store := range stores {
+		if v := manifest.Versions[store]; v > latest[store] {
+			return nil, fmt.Errorf("%w: %s is at version %d, this release knows up to %d", ErrNewerState, store, v, latest[store])
+		}
+	}
+
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if mig.Version > manifest.Versions[mig.Store] {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Run applies the pending migrations. The state directory is backed up first;
+// if a migration fails the backup is restored and the error returned.
+func (m *Migrator) Run() (Result, error) {
+	pending, err := m.Pending()
+	if err != nil || len(pending) == 0 {
+		return Result{}, err
+	}
+	manifest, err := m.Manifest()
+	if err != nil {
+		return Result{}, err
+	}
+
+	backup := filepath.Join(m.dir, BackupDir, time.Now().UTC().Format("20060102T150405.000000000Z"))
+	if err := copyTree(m.dir, backup); err != nil {
+		return Result{}, fmt.Errorf("backing up %s: %w", m.dir, err)
+	}
+	m.logger.Info("state backed up", "backup", backup, "pending", len(pending))
+
+	result := Result{Backup: backup}
+	for _, mig := range pending {
+		if err := mig.Apply(m.dir); err != nil {
+			if restoreErr := restore(backup, m.dir); restoreErr != nil {
+				return result, fmt.Errorf("%s migration %d (%s): %w; restoring %s also failed: %v", mig.Store, mig.Version, mig.Description, err, backup, restoreErr)
+			}
+			m.logger.Warn("migration failed, state restored", "store", mig.Store, "version", mig.Version, "error", err)
+			return result, fmt.Errorf("%s migration %d (%s): %w; state restored from %s", mig.Store, mig.Version, mig.Description, err, backup)
+		}
+
+		manifest.Versions[mig.Store] = mig.Version
+		if err := m.writeManifest(manifest); err != nil {
+			return result, err
+		}
+		result.Applied = append(result.Applied, mig)
+		m.logger.Info("migration applied", "store", mig.Store, "version", mig.Version, "description", mig.Description)
+	}
+	return result, nil
+}
+
+// writeManifest saves the manifest atomically
+func (m *Migrator) writeManifest(manifest Manifest) error {
+	manifest.Updated = time.Now()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(m.dir, ManifestFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// restore returns dir to the state saved in backup, removing files created since
+func restore(backup, dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == BackupDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, err := os.Lstat(filepath.Join(backup, rel)); os.IsNotExist(err) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return copyTree(backup, dir)
+}
+
+// copyTree copies the regular files under src to dst, preserving their
+// permissions. The backup directory itself is skipped.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == src {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if rel == BackupDir {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dst, rel), 0700)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, filepath.Join(dst, rel))
+	})
+}
+
+// copyFile copies one file, preserving its permissions even if dst exists
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, info.Mode().Perm())
+}
@@ -0,0 +1,133 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// appendLine returns a migration step that appends a line to a file in the state directory
+func appendLine(file, line string) func(dir string) error {
+	return func(dir string) error {
+		f, err := os.OpenFile(filepath.Join(dir, file), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteString(line + "\n")
+		return err
+	}
+}
+
+func TestMigrator_Run(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes"), []byte("v0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	migrations := []Migration{
+		{Store: "notes", Version: 1, Description: "add v1", Apply: appendLine("notes", "v1")},
+		{Store: "notes", Version: 2, Description: "add v2", Apply: appendLine("notes", "v2")},
+		{Store: "other", Version: 1, Description: "create other", Apply: appendLine("other", "v1")},
+	}
+	m, err := New(dir, migrations)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	pending, err := m.Pending()
+	if err != nil {
+		t.Fatalf("Pending failed: %v", err)
+	}
+	if len(pending) != 3 {
+		t.Errorf("Expected 3 pending migrations, got %d", len(pending))
+	}
+
+	result, err := m.Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(result.Applied) != 3 {
+		t.Errorf("Expected 3 applied migrations, got %d", len(result.Applied))
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "notes"))
+	if string(data) != "v0\nv1\nv2\n" {
+		t.Errorf("Expected migrations applied in order, got %q", data)
+	}
+	backup, _ := os.ReadFile(filepath.Join(result.Backup, "notes"))
+	if string(backup) != "v0\n" {
+		t.Errorf("Expected backup of the original state, got %q", backup)
+	}
+
+	manifest, _ := m.Manifest()
+	if manifest.Versions["notes"] != 2 || manifest.Versions["other"] != 1 {
+		t.Errorf("Expected manifest notes=2 other=1, got %v", manifest.Versions)
+	}
+
+	// A second run has nothing to do
+	result, err = m.Run()
+	if err != nil || len(result.Applied) != 0 || result.Backup != "" {
+		t.Errorf("Expected no-op second run, got %d applied, backup %q, error %v", len(result.Applied), result.Backup, err)
+	}
+}
+
+func TestMigrator_RunRestoresOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes"), []byte("v0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := errors.New("broken")
+	m, _ := New(dir, []Migration{
+		{Store: "notes", Version: 1, Description: "add v1", Apply: appendLine("notes", "v1")},
+		{Store: "notes", Version: 2, Description: "fail", Apply: func(dir string) error {
+			_ = appendLine("notes", "partial")(dir)
+			return broken
+		}},
+	})
+
+	if _, err := m.Run(); !errors.Is(err, broken) {
+		t.Fatalf("Expected migration error, got %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "notes"))
+	if string(data) != "v0\n" {
+		t.Errorf("Expected state restored, got %q", data)
+	}
+	manifest, _ := m.Manifest()
+	if manifest.Versions["notes"] != 0 {
+		t.Errorf("Expected manifest restored to version 0, got %d", manifest.Versions["notes"])
+	}
+}
+
+func TestMigrator_NewerState(t *testing.T) {
+	dir := t.TempDir()
+	m, _ := New(dir, []Migration{{Store: "notes", Version: 1, Apply: appendLine("notes", "v1")}})
+	if err := m.writeManifest(Manifest{Versions: map[string]int{"notes": 3}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Pending(); !errors.Is(err, ErrNewerState) {
+		t.Errorf("Expected ErrNewerState, got %v", err)
+	}
+	if _, err := m.Run(); !errors.Is(err, ErrNewerState) {
+		t.Errorf("Expected Run to refuse newer state, got %v", err)
+	}
+}
+
+func TestNew_InvalidMigrations(t *testing.T) {
+	apply := func(string) error { return nil }
+	cases := [][]Migration{
+		{{Store: "notes", Version: 2, Apply: apply}},
+		{{Store: "notes", Version: 1, Apply: apply}, {Store: "notes", Version: 1, Apply: apply}},
+		{{Store: "", Version: 1, Apply: apply}},
+		{{Store: "notes", Version: 1}},
+	}
+	for i, migrations := range cases {
+		if _, err := New(t.TempDir(), migrations); !errors.Is(err, ErrInvalidMigration) {
+			t.Errorf("Case %d: expected ErrInvalidMigration, got %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package prompt
+
+import (
+	"sort"
+	"strings"
+)
+
+// SectionKind classifies a section so truncation strategies know what they may cut
+type SectionKind string
+
+const (
+	KindSystem   SectionKind = "system"   // Identity and instructions, never dropped
+	KindTask     SectionKind = "task"     // The task itself, never dropped
+	KindMemory   SectionKind = "memory"   // Recalled memories, compressible
+	KindHistory  SectionKind = "history"  // Prior conversation turns, oldest first
+	KindArtifact SectionKind = "artifact" // Supporting material ranked by salience
+)
+
+// TruncationStrategy names a way of shrinking a context that exceeds its budget
+type TruncationStrategy string
+
+const (
+	StrategyDropOldestHistory   TruncationStrategy = "drop_oldest_history"   // Remove history turns, oldest first
+	StrategyCompressMemoryFirst TruncationStrategy = "compress_memory_first" // Shorten, then drop, memory sections
+	StrategyDropLowSalience     TruncationStrategy = "drop_low_salience"     // Remove artifacts, least salient first
+	StrategyTailTruncate        TruncationStrategy = "tail_truncate"         // Cut the rendered prompt at the budget
+)
+
+// DefaultStrategies is the order applied when a template gives no hints
+var DefaultStrategies = []TruncationStrategy{
+	StrategyDropOldestHistory,
+	StrategyCompressMemoryFirst,
+	StrategyDropLowSalience,
+}
+
+// Section is one block of a prompt
+type Section struct {
+	Title      string      `json:"title,omitempty"`
+	Kind       SectionKind `json:"kind"`
+	Content    string      `json:"content"`
+	Compressed string      `json:"compressed,omitempty"` // Optional shorter form used by compression
+	Salience   float64     `json:"salience"`             // 0.0-1.0 importance
+	Order      int         `json:"order"`                // Chronological order for history (lower = older)
+}
+
+// TemplateHints tell the context manager how a template prefers to be truncated
+type TemplateHints struct {
+	Strategies    []TruncationStrategy `json:"strategies,omitempty"`     // Applied in order before tail truncation
+	ReserveTokens int                  `json:"reserve_tokens,omitempty"` // Held back for the model's response
+}
+
+// Template is a named prompt layout with truncation hints
+type Template struct {
+	Name  string        `json:"name"`
+	Hints TemplateHints `json:"hints"`
+}
+
+// TruncationReport describes what the context manager did to fit the budget
+type TruncationReport struct {
+	TokensBefore int                  `json:"tokens_before"`
+	TokensAfter  int                  `json:"tokens_after"`
+	Applied      []TruncationStrategy `json:"applied,omitempty"`
+	Dropped      []string             `json:"dropped,omitempty"`    // Titles of removed sections
+	Compressed   []string             `json:"compressed,omitempty"` // Titles of compressed sections
+}
+
+// Truncated reports whether any content was removed or shortened
+func (r TruncationReport) Truncated() bool {
+	return len(r.Applied) > 0
+}
+
+// ContextManager assembles prompt sections within a token budget
+type ContextManager struct {
+	tokenizer Tokenizer
+	budget    int
+}
+
+// NewContextManager creates a context manager with a token budget
+func NewContextManager(tokenizer Tokenizer, budget int) *ContextManager {
+	if tokenizer == nil {
+		tokenizer = NewApproxTokenizer()
+	}
+	return &ContextManager{
+		tokenizer: tokenizer,
+		budget:    budget,
+	}
+}
+
+// Tokenizer returns the tokenizer used for budgeting
+func (cm *ContextManager) Tokenizer() Tokenizer {
+	return cm.tokenizer
+}
+
+// Budget returns the token budget
+func (cm *ContextManager) Budget() int {
+	return cm.budget
+}
+
+// Build renders sections into a prompt that fits the budget, applying the
+// template's truncation strategies in order and falling back to tail truncation
+func (cm *ContextManager) Build(tmpl Template, sections []Section) (string, TruncationReport) {
+	limit := cm.budget - tmpl.Hints.ReserveTokens
+	working := make([]Section, len(sections))
+	copy(working, sections)
+
+	report := TruncationReport{TokensBefore: cm.count(working)}
+
+	strategies := tmpl.Hints.Strategies
+	if len(strategies) == 0 {
+		strategies = DefaultStrategies
+	}
+
+	for _, strategy := range strategies {
+		if cm.budget <= 0 || cm.count(working) <= limit {
+			break
+		}
+		var changed bool
+		working, changed = cm.apply(strategy, working, limit, &report)
+		if changed {
+			report.Applied = append(report.Applied, strategy)
+		}
+	}
+
+	rendered := render(working)
+	if cm.budget > 0 && cm.tokenizer.Count(rendered) > limit {
+		rendered = cm.tokenizer.Truncate(rendered, limit)
+		report.Applied = append(report.Applied, StrategyTailTruncate)
+	}
+
+	report.TokensAfter = cm.tokenizer.Count(rendered)
+	return rendered, report
+}
+
+// apply runs a single strategy until the sections fit or the strategy is exhausted
+func (cm *ContextManager) apply(strategy TruncationStrategy, sections []Section, limit int, report *TruncationReport) ([]Section, bool) {
+	switch strategy {
+	case StrategyDropOldestHistory:
+		return cm.dropWhileOver(sections, limit, report, KindHistory, func(a, b Section) bool {
+			return a.Order < b.Order
+		})
+	case StrategyCompressMemoryFirst:
+		changed := false
+		for i := range sections {
+			if cm.count(sections) <= limit {
+				return sections, changed
+			}
+			if sections[i].Kind != KindMemory {
+				continue
+			}
+			if compressed := cm.compress(sections[i]); compressed != sections[i].Content {
+				sections[i].Content = compressed
+				report.Compressed = append(report.Compressed, sections[i].Title)
+				changed = true
+			}
+		}
+		var dropped bool
+		sections, dropped = cm.dropWhileOver(sections, limit, report, KindMemory, bySalience)
+		return sections, changed || dropped
+	case StrategyDropLowSalience:
+		return cm.dropWhileOver(sections, limit, report, KindArtifact, bySalience)
+	}
+	return sections, false
+}
+
+// dropWhileOver removes sections of kind, in the order given by less, until within limit
+func (cm *ContextManager) dropWhileOver(sections []Section, limit int, report *TruncationReport, kind SectionKind, less func(a, b Section) bool) ([]Section, bool) {
+	candidates := make([]int, 0)
+	for i, s := range sections {
+		if s.Kind == kind {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return less(sections[candidates[i]], sections[candidates[j]])
+	})
+
+	drop := make(map[int]bool)
+	total := cm.count(sections)
+	for _, idx := range candidates {
+		if total <= limit {
+			break
+		}
+		drop[idx] = true
+		total -= cm.tokenizer.Count(renderSection(sections[idx]))
+		report.Dropped = append(report.Dropped, sections[idx].Title)
+	}
+
+	if len(drop) == 0 {
+		return sections, false
+	}
+
+	kept := make([]Section, 0, len(sections)-len(drop))
+	for i, s := range sections {
+		if !drop[i] {
+			kept = append(kept, s)
+		}
+	}
+	return kept, true
+}
+
+// compress returns the section's compressed form, or its first sentence if none was provided
+func (cm *ContextManager) compress(s Section) string {
+	if s.Compressed != "" {
+		return s.Compressed
+	}
+	if idx := strings.IndexAny(s.Content, ".\n"); idx > 0 && idx < len(s.Content)-1 {
+		return strings.TrimSpace(s.Content[:idx+1])
+	}
+	return s.Content
+}
+
+// count returns the token count of the rendered sections
+func (cm *ContextManager) count(sections []Section) int {
+	return cm.tokenizer.Count(render(sections))
+}
+
+func bySalience(a, b Section) bool {
+	return a.Salience < b.Salience
+}
+
+// render joins sections into the final prompt text
+func render(sections []Section) string {
+	parts := make([]string, 0, len(sections))
+	for _, s := range sections {
+		if r := renderSection(s); r != "" {
+			parts = append(parts, r)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func renderSection(s Section) string {
+	if s.Title == "" {
+		return s.Content
+	}
+	return s.Title + ":\n" + s.Content
+}
@@ -0,0 +1,88 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApproxTokenizer_Truncate(t *testing.T) {
+	tok := NewApproxTokenizer()
+	text := strings.Repeat("word ", 100)
+
+	cut := tok.Truncate(text, 20)
+	if tok.Count(cut) > 20 {
+		t.Errorf("Expected at most 20 tokens, got %d", tok.Count(cut))
+	}
+	if !strings.HasPrefix(text, cut) {
+		t.Error("Truncate should return a prefix of the input")
+	}
+}
+
+func TestContextManager_FitsWithoutTruncation(t *testing.T) {
+	cm := NewContextManager(nil, 1000)
+
+	out, report := cm.Build(Template{}, []Section{
+		{Title: "Task", Kind: KindTask, Content: "Write a parser"},
+	})
+
+	if report.Truncated() {
+		t.Errorf("Expected no truncation, got %v", report.Applied)
+	}
+	if !strings.Contains(out, "Write a parser") {
+		t.Error("Rendered prompt should contain the task")
+	}
+}
+
+func TestContextManager_DropOldestHistory(t *testing.T) {
+	cm := NewContextManager(nil, 60)
+	long := strings.Repeat("history ", 30)
+
+	out, report := cm.Build(Template{Hints: TemplateHints{
+		Strategies: []TruncationStrategy{StrategyDropOldestHistory},
+	}}, []Section{
+		{Title: "Task", Kind: KindTask, Content: "Do the thing"},
+		{Title: "turn-1", Kind: KindHistory, Content: long, Order: 1},
+		{Title: "turn-2", Kind: KindHistory, Content: "recent reply", Order: 2},
+	})
+
+	if len(report.Dropped) != 1 || report.Dropped[0] != "turn-1" {
+		t.Errorf("Expected oldest turn dropped, got %v", report.Dropped)
+	}
+	if !strings.Contains(out, "recent reply") {
+		t.Error("Newest history turn should be kept")
+	}
+}
+
+func TestContextManager_CompressMemoryFirst(t *testing.T) {
+	cm := NewContextManager(nil, 40)
+
+	_, report := cm.Build(Template{Hints: TemplateHints{
+		Strategies: []TruncationStrategy{StrategyCompressMemoryFirst},
+	}}, []Section{
+		{Title: "Task", Kind: KindTask, Content: "Summarize"},
+		{Title: "mem", Kind: KindMemory, Content: strings.Repeat("detail ", 60), Compressed: "short"},
+	})
+
+	if len(report.Compressed) != 1 {
+		t.Errorf("Expected memory to be compressed, got %v", report.Compressed)
+	}
+	if len(report.Dropped) != 0 {
+		t.Errorf("Compressed memory should fit without dropping, got %v", report.Dropped)
+	}
+}
+
+func TestContextManager_DropLowSalienceThenTail(t *testing.T) {
+	cm := NewContextManager(nil, 30)
+
+	out, report := cm.Build(Template{}, []Section{
+		{Title: "Task", Kind: KindTask, Content: strings.Repeat("task ", 40)},
+		{Title: "low", Kind: KindArtifact, Content: "low value", Salience: 0.1},
+	})
+
+	if report.Applied[len(report.Applied)-1] != StrategyTailTruncate {
+		t.Errorf("Expected tail truncation fallback, got %v", report.Applied)
+	}
+	if cm.Tokenizer().Count(out) > 30 {
+		t.Errorf("Expected output within budget, got %d tokens", cm.Tokenizer().Count(out))
+	}
+}
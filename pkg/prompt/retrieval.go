@@ -0,0 +1,119 @@
+package prompt
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// stopWords are too common to say anything about what a text is about
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "have": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "were": true, "will": true, "with": true,
+}
+
+// Terms counts the words of text that carry meaning: lower-cased, without
+// stop words or single characters, and with plural "s" removed
+func Terms(text string) map[string]int {
+	terms := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if len([]rune(w)) < 2 || stopWords[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = w[:len(w)-1]
+		}
+		terms[w]++
+	}
+	return terms
+}
+
+// Similarity returns the cosine similarity (0-1) of two texts' term counts.
+// It is a lexical stand-in for embeddings: texts sharing distinctive words
+// score high, texts sharing none score 0.
+func Similarity(a, b string) float64 {
+	ta, tb := Terms(a), Terms(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	var dot, na, nb float64
+	for term, n := range ta {
+		na += float64(n * n)
+		dot += float64(n * tb[term])
+	}
+	for _, n := range tb {
+		nb += float64(n * n)
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// Candidate is recalled material competing for a place in a prompt
+type Candidate struct {
+	Source   string  `json:"source,omitempty"` // Where it was recalled from
+	Content  string  `json:"content"`
+	Salience float64 `json:"salience"` // 0.0-1.0 importance
+}
+
+// Retrieved is a candidate chosen for a prompt
+type Retrieved struct {
+	Candidate
+	Similarity float64 `json:"similarity"` // To the query
+	Relevance  float64 `json:"relevance"`  // Similarity weighted by salience
+}
+
+// RetrieveOptions limit what Retrieve selects
+type RetrieveOptions struct {
+	Budget        int     // Tokens the selected contents may take (0 = no limit)
+	Limit         int     // Most candidates selected (0 = no limit)
+	MinSimilarity float64 // Candidates less similar to the query are left out
+}
+
+// Retrieve selects the candidates most relevant to query that fit the
+// options. Relevance is similarity × (0.5 + 0.5 × salience), so an important
+// memory ranks above an equally similar trivial one. Candidates are taken in
+// order of relevance, ties keeping their given order, and one too long for
+// the remaining budget is passed over for shorter ones.
+func Retrieve(tokenizer Tokenizer, query string, candidates []Candidate, opts RetrieveOptions) []Retrieved {
+	if tokenizer == nil {
+		tokenizer = NewApproxTokenizer()
+	}
+
+	ranked := make([]Retrieved, 0, len(candidates))
+	for _, c := range candidates {
+		similarity := Similarity(query, c.Content)
+		if similarity <= 0 || similarity < opts.MinSimilarity {
+			continue
+		}
+		salience := math.Max(0, math.Min(1, c.Salience))
+		ranked = append(ranked, Retrieved{
+			Candidate:  c,
+			Similarity: similarity,
+			Relevance:  similarity * (0.5 + 0.5*salience),
+		})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Relevance > ranked[j].Relevance })
+
+	selected := make([]Retrieved, 0)
+	remaining := opts.Budget
+	for _, r := range ranked {
+		if opts.Limit > 0 && len(selected) >= opts.Limit {
+			break
+		}
+		if opts.Budget > 0 {
+			cost := tokenizer.Count(r.Content)
+			if cost > remaining {
+				continue
+			}
+			remaining -= cost
+		}
+		selected = append(selected, r)
+	}
+	return selected
+}
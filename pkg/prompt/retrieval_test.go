@@ -0,0 +1,62 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"Add a cache to the API", "add a cache to the api", 0.999, 1.001},
+		{"Write tests for the parser", "The parser tests were written", 0.5, 0.999},
+		{"Design the database schema", "Deploy the frontend", 0, 0},
+		{"", "anything", 0, 0},
+		{"the and of", "the and of", 0, 0},
+	}
+
+	for _, tt := range tests {
+		got := Similarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q): expected %.2f-%.2f, got %f", tt.a, tt.b, tt.min, tt.max, got)
+		}
+		if back := Similarity(tt.b, tt.a); back != got {
+			t.Errorf("Expected similarity to be symmetric, got %f and %f", got, back)
+		}
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	candidates := []Candidate{
+		{Content: "Fixed the cache eviction bug", Salience: 0.2},
+		{Content: "Deployed the frontend"},
+		{Content: "Designed the cache eviction policy", Salience: 1},
+		{Content: "Cache eviction notes: " + strings.Repeat("detail ", 200), Salience: 1},
+	}
+
+	got := Retrieve(nil, "cache eviction", candidates, RetrieveOptions{Budget: 50})
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 memories within budget, got %d: %+v", len(got), got)
+	}
+	if got[0].Content != candidates[2].Content {
+		t.Errorf("Expected the salient memory first, got %q", got[0].Content)
+	}
+	for _, r := range got {
+		if r.Content == candidates[1].Content {
+			t.Error("Expected an unrelated memory to be left out")
+		}
+	}
+
+	limited := Retrieve(nil, "cache eviction", candidates, RetrieveOptions{Limit: 1})
+	if len(limited) != 1 {
+		t.Errorf("Expected 1 memory with limit 1, got %d", len(limited))
+	}
+
+	strict := Retrieve(nil, "cache eviction bug", candidates, RetrieveOptions{MinSimilarity: 0.9})
+	if len(strict) != 0 {
+		t.Errorf("Expected no memory similar enough, got %d", len(strict))
+	}
+}
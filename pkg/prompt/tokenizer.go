@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokenizer estimates how many model tokens a piece of text consumes
+type Tokenizer interface {
+	// Count returns the token count for text
+	Count(text string) int
+
+	// Truncate returns the longest prefix of text that fits in maxTokens
+	Truncate(text string, maxTokens int) string
+}
+
+// ApproxTokenizer estimates tokens from character and word counts.
+// It is provider-agnostic and errs on the side of over-counting.
+type ApproxTokenizer struct {
+	CharsPerToken float64
+}
+
+// NewApproxTokenizer creates a tokenizer using the common ~4 chars/token heuristic
+func NewApproxTokenizer() *ApproxTokenizer {
+	return &ApproxTokenizer{CharsPerToken: 4.0}
+}
+
+// Count returns the estimated token count for text
+func (t *ApproxTokenizer) Count(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	byChars := int(float64(utf8.RuneCountInString(text))/t.charsPerToken() + 0.5)
+	byWords := int(float64(len(strings.Fields(text)))*1.3 + 0.5)
+
+	if byWords > byChars {
+		return byWords
+	}
+	if byChars < 1 {
+		return 1
+	}
+	return byChars
+}
+
+// Truncate returns the longest prefix of text estimated to fit in maxTokens
+func (t *ApproxTokenizer) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+	if t.Count(text) <= maxTokens {
+		return text
+	}
+
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if t.Count(string(runes[:mid])) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return string(runes[:lo])
+}
+
+func (t *ApproxTokenizer) charsPerToken() float64 {
+	if t.CharsPerToken <= 0 {
+		return 4.0
+	}
+	return t.CharsPerToken
+}
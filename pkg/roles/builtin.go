@@ -0,0 +1,69 @@
+package roles
+
+import (
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// builtinRoles returns the roles every library starts with
+func builtinRoles() []Role {
+	return []Role{
+		{
+			Name:         "architect",
+			Description:  "Orchestration & solution design",
+			Capabilities: []identity.CapabilityType{identity.CapArchitecture, identity.CapAnalysis, identity.CapDocumentation},
+			SystemPrompt: "You are a software architect. Break problems into well-defined components with clear interfaces, " +
+				"weigh the trade-offs of each design choice explicitly, and prefer simple designs that can grow over clever ones. " +
+				"State your assumptions and the risks of the approach you recommend.",
+			Temperature: 0.4,
+			Models:      []string{string(llm.ModelClaude3Opus), string(llm.ModelClaude35Sonnet), string(llm.ModelGPT4)},
+		},
+		{
+			Name:         "researcher",
+			Description:  "Problem analysis & research",
+			Capabilities: []identity.CapabilityType{identity.CapResearch, identity.CapAnalysis},
+			SystemPrompt: "You are a researcher. Investigate the problem space thoroughly before drawing conclusions: " +
+				"gather the relevant facts, compare the alternatives, and separate what is known from what is assumed. " +
+				"Cite your sources where you have them and say plainly where the evidence is thin.",
+			Temperature: 0.5,
+			Models:      []string{string(llm.ModelClaude35Sonnet), string(llm.ModelGPT4Turbo)},
+		},
+		{
+			Name:         "implementer",
+			Description:  "Concrete implementation",
+			Capabilities: []identity.CapabilityType{identity.CapCodeWrite, identity.CapCodeRefactor, identity.CapTesting},
+			SystemPrompt: "You are an implementer. Write complete, working code that follows the conventions of the code around it, " +
+				"handles errors explicitly and comes with tests. Don't leave placeholders; if something is out of scope, say so.",
+			Temperature: 0.2,
+			Models:      []string{string(llm.ModelClaude35Sonnet), string(llm.ModelGPT4Turbo)},
+		},
+		{
+			Name:         "critic",
+			Description:  "Critical review & security",
+			Capabilities: []identity.CapabilityType{identity.CapCodeReview, identity.CapSecurity, identity.CapTesting},
+			SystemPrompt: "You are a critic. Review work skeptically: look for defects, security issues, unhandled edge cases " +
+				"and unstated assumptions. Rank what you find by severity and suggest a concrete fix for each issue. " +
+				"Don't pad the review with praise.",
+			Temperature: 0.3,
+			Models:      []string{string(llm.ModelClaude3Opus), string(llm.ModelClaude35Sonnet), string(llm.ModelGPT4)},
+		},
+		{
+			Name:         "writer",
+			Description:  "Documentation & explanation",
+			Capabilities: []identity.CapabilityType{identity.CapDocumentation, identity.CapResearch},
+			SystemPrompt: "You are a technical writer. Explain things clearly and concisely for the intended reader, " +
+				"lead with what matters most, and use examples where they help. Keep the terminology consistent.",
+			Temperature: 0.7,
+			Models:      []string{string(llm.ModelClaude35Sonnet), string(llm.ModelGPT4Turbo)},
+		},
+		{
+			Name:         "tester",
+			Description:  "Test design & verification",
+			Capabilities: []identity.CapabilityType{identity.CapTesting, identity.CapCodeReview},
+			SystemPrompt: "You are a test engineer. Design tests that pin down the intended behaviour, including the boundaries " +
+				"and failure paths, and make each failure easy to diagnose. Prefer table-driven tests over repetition.",
+			Temperature: 0.2,
+			Models:      []string{string(llm.ModelClaude35Sonnet), string(llm.ModelGPT4Turbo)},
+		},
+	}
+}
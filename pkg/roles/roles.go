@@ -0,0 +1,203 @@
+// Package roles provides role templates for agents. A role bundles the
+// capabilities an agent bids with, the system prompt it works under, its
+// sampling temperature and the models it does best on. The built-in roles
+// cover the usual specialists of a swarm; users add their own, or adjust the
+// built-in ones, as YAML files in a role directory.
+package roles
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var (
+	ErrUnknownRole = errors.New("unknown role")
+	ErrInvalidRole = errors.New("invalid role definition")
+)
+
+// Role is a template for spawning agents
+type Role struct {
+	Name         string                    `json:"name" yaml:"name"`
+	Description  string                    `json:"description,omitempty" yaml:"description"`
+	Capabilities []identity.CapabilityType `json:"capabilities" yaml:"capabilities"`
+	SystemPrompt string                    `json:"system_prompt,omitempty" yaml:"system_prompt"`
+	Temperature  float64                   `json:"temperature,omitempty" yaml:"temperature"` // 0 = provider default
+	Models       []string                  `json:"models,omitempty" yaml:"models"`           // Preferred models, best first
+	Builtin      bool                      `json:"builtin" yaml:"-"`
+}
+
+// Validate checks a role is usable
+func (r *Role) Validate() error {
+	if !validName(r.Name) {
+		return fmt.Errorf("%w: bad name %q", ErrInvalidRole, r.Name)
+	}
+	if len(r.Capabilities) == 0 {
+		return fmt.Errorf("%w: %s has no capabilities", ErrInvalidRole, r.Name)
+	}
+	if r.Temperature < 0 || r.Temperature > 2 {
+		return fmt.Errorf("%w: %s temperature must be between 0 and 2", ErrInvalidRole, r.Name)
+	}
+	return nil
+}
+
+// Model returns the first of the role's preferred models that available
+// accepts, or "" if none is
+func (r *Role) Model(available func(model string) bool) string {
+	for _, model := range r.Models {
+		if available == nil || available(model) {
+			return model
+		}
+	}
+	return ""
+}
+
+// Apply fills the capabilities, system prompt and temperature of an agent
+// configuration from the role, keeping any already set
+func (r *Role) Apply(cfg *agent.AgentConfig) {
+	if len(cfg.Capabilities) == 0 {
+		cfg.Capabilities = append([]identity.CapabilityType(nil), r.Capabilities...)
+	}
+	if cfg.SystemPrompt == "" {
+		cfg.SystemPrompt = r.SystemPrompt
+	}
+	if cfg.Temperature == 0 {
+		cfg.Temperature = r.Temperature
+	}
+}
+
+// Library holds the known roles: the built-in ones and any defined by users
+type Library struct {
+	mu sync.RWMutex
+
+	roles map[string]*Role
+}
+
+// NewLibrary creates a library holding the built-in roles
+func NewLibrary() *Library {
+	l := &Library{roles: make(map[string]*Role)}
+	for _, role := range builtinRoles() {
+		role := role
+		role.Builtin = true
+		l.roles[role.Name] = &role
+	}
+	return l
+}
+
+var defaultLibrary = NewLibrary()
+
+// Default returns the library the CLI spawns agents from
+func Default() *Library {
+	return defaultLibrary
+}
+
+// Register adds a role, replacing an earlier one of the same name. A user
+// role may replace a built-in one.
+func (l *Library) Register(role Role) error {
+	role.Name = strings.ToLower(role.Name)
+	if err := role.Validate(); err != nil {
+		return err
+	}
+	role.Builtin = false
+	role.Capabilities = append([]identity.CapabilityType(nil), role.Capabilities...)
+	role.Models = append([]string(nil), role.Models...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.roles[role.Name] = &role
+	return nil
+}
+
+// Get returns a copy of the named role. Names are case insensitive.
+func (l *Library) Get(name string) (Role, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	role, ok := l.roles[strings.ToLower(name)]
+	if !ok {
+		return Role{}, fmt.Errorf("%w: %s", ErrUnknownRole, name)
+	}
+	return *role, nil
+}
+
+// List returns the roles, sorted by name
+func (l *Library) List() []Role {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	list := make([]Role, 0, len(l.roles))
+	for _, role := range l.roles {
+		list = append(list, *role)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LoadDir registers the roles defined in a directory, one per YAML file. A
+// file without a name defines the role named after it. A missing directory
+// defines none.
+func (l *Library) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		if err := l.LoadFile(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile registers the role defined in a YAML file
+func (l *Library) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var role Role
+	if err := yaml.Unmarshal(data, &role); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidRole, filepath.Base(path), err)
+	}
+	if role.Name == "" {
+		role.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := l.Register(role); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// validName reports whether name is a usable role name: lower case letters,
+// digits, dots, dashes and underscores, starting with a letter
+func validName(name string) bool {
+	if name == "" || name[0] < 'a' || name[0] > 'z' {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
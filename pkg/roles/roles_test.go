@@ -0,0 +1,179 @@
+package roles
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+func TestLibrary_Builtin(t *testing.T) {
+	l := NewLibrary()
+
+	for _, role := range l.List() {
+		if err := role.Validate(); err != nil {
+			t.Errorf("Expected built-in role %s to be valid, got %v", role.Name, err)
+		}
+		if !role.Builtin || role.SystemPrompt == "" || len(role.Models) == 0 {
+			t.Errorf("Expected built-in role %s to be complete, got %+v", role.Name, role)
+		}
+	}
+
+	role, err := l.Get("Architect")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if role.Name != "architect" || role.Capabilities[0] != identity.CapArchitecture {
+		t.Errorf("Expected the architect role, got %+v", role)
+	}
+
+	if _, err := l.Get("juggler"); !errors.Is(err, ErrUnknownRole) {
+		t.Errorf("Expected ErrUnknownRole, got %v", err)
+	}
+}
+
+func TestLibrary_LoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dba.yaml": `description: Database specialist
+capabilities: [analysis, code.write]
+system_prompt: You are a database administrator.
+temperature: 0.1
+models: [gpt-4]
+`,
+		"critic.yml": `name: critic
+capabilities: [code.review]
+system_prompt: Be brief.
+`,
+		"notes.txt": "not a role",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l := NewLibrary()
+	if err := l.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+
+	dba, err := l.Get("dba")
+	if err != nil {
+		t.Fatalf("Expected the dba role named after its file, got %v", err)
+	}
+	if dba.Builtin || dba.Temperature != 0.1 || len(dba.Capabilities) != 2 || dba.Models[0] != "gpt-4" {
+		t.Errorf("Unexpected dba role: %+v", dba)
+	}
+
+	critic, _ := l.Get("critic")
+	if critic.Builtin || critic.SystemPrompt != "Be brief." {
+		t.Errorf("Expected the user critic to replace the built-in one, got %+v", critic)
+	}
+	if got := len(l.List()); got != len(builtinRoles())+1 {
+		t.Errorf("Expected %d roles, got %d", len(builtinRoles())+1, got)
+	}
+
+	if err := l.LoadDir(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("Expected a missing directory to define nothing, got %v", err)
+	}
+
+	bad := t.TempDir()
+	_ = os.WriteFile(filepath.Join(bad, "empty.yaml"), []byte("description: no capabilities\n"), 0644)
+	if err := l.LoadDir(bad); !errors.Is(err, ErrInvalidRole) {
+		t.Errorf("Expected ErrInvalidRole, got %v", err)
+	}
+}
+
+func TestRole_Validate(t *testing.T) {
+	caps := []identity.CapabilityType{identity.CapAnalysis}
+	tests := []struct {
+		name  string
+		role  Role
+		valid bool
+	}{
+		{"valid", Role{Name: "analyst", Capabilities: caps, Temperature: 0.5}, true},
+		{"empty name", Role{Capabilities: caps}, false},
+		{"bad name", Role{Name: "data analyst", Capabilities: caps}, false},
+		{"no capabilities", Role{Name: "analyst"}, false},
+		{"temperature too high", Role{Name: "analyst", Capabilities: caps, Temperature: 2.5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.role.Validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid=%v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestRole_Model(t *testing.T) {
+	role := Role{Models: []string{"claude-3-opus-20240229", "gpt-4"}}
+
+	if got := role.Model(nil); got != "claude-3-opus-20240229" {
+		t.Errorf("Expected the first preference, got %q", got)
+	}
+	openai := func(model string) bool { return strings.HasPrefix(model, "gpt-") }
+	if got := role.Model(openai); got != "gpt-4" {
+		t.Errorf("Expected gpt-4, got %q", got)
+	}
+	none := func(string) bool { return false }
+	if got := role.Model(none); got != "" {
+		t.Errorf("Expected no model, got %q", got)
+	}
+}
+
+// recordingProvider answers every request and keeps the last one
+type recordingProvider struct {
+	last llm.CompletionRequest
+}
+
+func (p *recordingProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.last = req
+	return &llm.CompletionResponse{Content: "done", TokensUsed: 10}, nil
+}
+
+func (p *recordingProvider) Name() string { return "recording" }
+
+func TestRole_Apply(t *testing.T) {
+	role, _ := NewLibrary().Get("critic")
+	provider := &recordingProvider{}
+
+	cfg := agent.AgentConfig{Name: "reviewer", Provider: provider, Temperature: 0.9}
+	role.Apply(&cfg)
+	if len(cfg.Capabilities) != len(role.Capabilities) || cfg.SystemPrompt != role.SystemPrompt {
+		t.Errorf("Expected capabilities and prompt from the role, got %+v", cfg)
+	}
+	if cfg.Temperature != 0.9 {
+		t.Errorf("Expected the explicit temperature kept, got %v", cfg.Temperature)
+	}
+
+	a, err := agent.NewAgent(cfg)
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := a.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer a.Stop()
+	a.SubmitTask(agent.NewTask("Review the patch", nil))
+	select {
+	case <-a.GetResults():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the result")
+	}
+	if provider.last.System != role.SystemPrompt || provider.last.Temperature == nil || *provider.last.Temperature != 0.9 {
+		t.Errorf("Expected the role prompt and temperature sent, got system=%q temperature=%v", provider.last.System, provider.last.Temperature)
+	}
+}
@@ -0,0 +1,124 @@
+package runlog
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// ErrRecording is returned when a run is begun while another is recorded
+var ErrRecording = errors.New("a run is already being recorded")
+
+// Recorder records the run a collective is working on. It is an
+// agent.ExecutionRecorder: give it to the collective's agents, and every
+// execution between Begin and End becomes part of the run. It records one
+// run at a time; executions outside a run are ignored.
+type Recorder struct {
+	mu sync.Mutex
+
+	collective *collective.Collective
+	store      *Store
+	run        *Run // Being recorded
+}
+
+// NewRecorder creates a recorder for c's runs, saving them to store
+func NewRecorder(c *collective.Collective, store *Store) *Recorder {
+	return &Recorder{collective: c, store: store}
+}
+
+// Begin starts recording a run under the ID of its top-level task. A run
+// already in the store under that ID, such as a resumed swarm's, is
+// continued.
+func (r *Recorder) Begin(id string, kind Kind, task string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.run != nil {
+		return ErrRecording
+	}
+
+	if run, err := r.store.Load(id); err == nil && run.ID == id {
+		run.Status, run.Error, run.FinishedAt = StatusRunning, "", time.Time{}
+		r.run = run
+		return nil
+	}
+	r.run = &Run{
+		ID:        id,
+		Kind:      kind,
+		Task:      task,
+		Status:    StatusRunning,
+		StartedAt: time.Now(),
+	}
+	return nil
+}
+
+// Record adds an execution to the run being recorded
+func (r *Recorder) Record(record agent.ExecutionRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.run == nil {
+		return
+	}
+	r.run.Executions = append(r.run.Executions, Execution{ExecutionRecord: record})
+}
+
+// End finishes the run with its output or the error it failed with, adds
+// each task's bids, assignment and timeline from the collective, and saves
+// it. It returns the run, even if saving fails.
+func (r *Recorder) End(output string, runErr error) (*Run, error) {
+	r.mu.Lock()
+	run := r.run
+	r.run = nil
+	r.mu.Unlock()
+	if run == nil {
+		return nil, nil
+	}
+
+	run.FinishedAt = time.Now()
+	run.Output = output
+	run.Status = StatusCompleted
+	if runErr != nil {
+		run.Status, run.Error = StatusFailed, runErr.Error()
+	}
+	for i := range run.Executions {
+		r.annotate(&run.Executions[i])
+	}
+	return run, r.store.Save(run)
+}
+
+// annotate adds what the collective knows about how an execution's task
+// was assigned and how long it took
+func (r *Recorder) annotate(e *Execution) {
+	if e.Assignment == nil {
+		if explanation, ok := r.collective.ExplainAssignment(e.TaskID); ok {
+			e.Assignment = explanation
+		}
+	}
+	if e.Timeline == nil {
+		e.Timeline, _ = r.collective.Timeline(e.TaskID)
+	}
+	if e.AgentName == "" {
+		if a, ok := r.collective.GetAgent(e.AgentSID); ok {
+			e.AgentName = a.Identity.Name
+		}
+	}
+	if e.Duration == 0 {
+		e.Duration = runningTime(e.Timeline, e.Timestamp)
+	}
+}
+
+// runningTime returns the time from a task's last assignment to the
+// response recorded at done
+func runningTime(timeline []collective.TimelineEntry, done time.Time) time.Duration {
+	for i := len(timeline) - 1; i >= 0; i-- {
+		if timeline[i].Stage == collective.StageAssigned {
+			if d := done.Sub(timeline[i].Timestamp); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+	return 0
+}
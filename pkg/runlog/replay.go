@@ -0,0 +1,160 @@
+package runlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// ErrNothingToReplay is returned for a run without executions
+var ErrNothingToReplay = errors.New("run has no executions to replay")
+
+// Replay sends the prompt of each of a run's executions to model through p
+// and returns the responses as a new run, for comparison with the original.
+// Each prompt goes out as recorded, with the same system prompt and limits,
+// so differences come from the model alone: a swarm's later prompts still
+// carry the original run's earlier outputs. Executions are replayed one at a
+// time; if ctx ends, the partial replay is returned with the error.
+func Replay(ctx context.Context, p llm.Provider, run *Run, model string) (*Run, error) {
+	if len(run.Executions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNothingToReplay, run.ID)
+	}
+
+	replay := &Run{
+		ID:        uuid.New().String(),
+		Kind:      KindReplay,
+		Task:      run.Task,
+		Status:    StatusRunning,
+		ReplayOf:  run.ID,
+		Model:     model,
+		StartedAt: time.Now(),
+	}
+	var failed error
+	for _, original := range run.Executions {
+		if err := ctx.Err(); err != nil {
+			failed = err
+			break
+		}
+
+		record := original.ExecutionRecord
+		record.Model = model
+		record.Output, record.Error, record.Quality = "", "", 0
+
+		start := time.Now()
+		resp, err := p.Complete(ctx, llm.CompletionRequest{
+			Model:       model,
+			System:      record.System,
+			Prompt:      record.Prompt,
+			MaxTokens:   record.MaxTokens,
+			Temperature: record.Temperature,
+		})
+		record.Timestamp = time.Now()
+		if resp != nil {
+			record.TokensUsed = resp.TokensUsed
+		}
+		if err != nil {
+			record.Status, record.Error = agent.TaskFailed, err.Error()
+			if failed == nil {
+				failed = fmt.Errorf("task %s: %w", record.TaskID, err)
+			}
+		} else {
+			record.Status, record.Output = agent.TaskCompleted, resp.Content
+		}
+
+		replay.Executions = append(replay.Executions, Execution{
+			ExecutionRecord: record,
+			AgentName:       original.AgentName,
+			Duration:        record.Timestamp.Sub(start),
+		})
+	}
+
+	replay.FinishedAt = time.Now()
+	replay.Status = StatusCompleted
+	if failed != nil {
+		replay.Status, replay.Error = StatusFailed, failed.Error()
+	}
+	// The last execution answers for the run, as a swarm's synthesis does
+	if n := len(replay.Executions); n == len(run.Executions) {
+		replay.Output = replay.Executions[n-1].Output
+	}
+	return replay, failed
+}
+
+// Comparison sets a replay beside the run it replayed, execution by
+// execution
+type Comparison struct {
+	Base   *Run            `json:"base"`
+	Replay *Run            `json:"replay"`
+	Rows   []ComparisonRow `json:"rows"`
+}
+
+// ComparisonRow compares the two runs' executions of one task
+type ComparisonRow struct {
+	TaskID         string           `json:"task_id"`
+	AgentName      string           `json:"agent_name,omitempty"`
+	BaseModel      string           `json:"base_model"`
+	ReplayModel    string           `json:"replay_model"`
+	BaseStatus     agent.TaskStatus `json:"base_status"`
+	ReplayStatus   agent.TaskStatus `json:"replay_status"`
+	BaseTokens     int              `json:"base_tokens"`
+	ReplayTokens   int              `json:"replay_tokens"`
+	BaseDuration   time.Duration    `json:"base_duration"`
+	ReplayDuration time.Duration    `json:"replay_duration"`
+	Similarity     float64          `json:"similarity"` // Word overlap of the two outputs, 0-1
+}
+
+// Compare pairs a replay's executions with the original run's
+func Compare(base, replay *Run) *Comparison {
+	cmp := &Comparison{Base: base, Replay: replay}
+	for i, b := range base.Executions {
+		if i >= len(replay.Executions) {
+			break
+		}
+		r := replay.Executions[i]
+		cmp.Rows = append(cmp.Rows, ComparisonRow{
+			TaskID:         b.TaskID,
+			AgentName:      b.AgentName,
+			BaseModel:      b.Model,
+			ReplayModel:    r.Model,
+			BaseStatus:     b.Status,
+			ReplayStatus:   r.Status,
+			BaseTokens:     b.TokensUsed,
+			ReplayTokens:   r.TokensUsed,
+			BaseDuration:   b.Duration,
+			ReplayDuration: r.Duration,
+			Similarity:     similarity(b.Output, r.Output),
+		})
+	}
+	return cmp
+}
+
+// similarity is the Jaccard index of two texts' sets of words
+func similarity(a, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 && len(wb) == 0 {
+		return 1
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa)+len(wb)-shared)
+}
+
+// words returns the lower-cased words of a text
+func words(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		set[w] = true
+	}
+	return set
+}
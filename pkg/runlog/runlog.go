@@ -0,0 +1,213 @@
+// Package runlog records runs of tasks and swarms: the prompt each agent was
+// given and its response, the bids and how each task was assigned, and the
+// timings. Recorded runs are kept as JSON files to be listed, shown and
+// replayed against another model to compare the two.
+package runlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+var (
+	ErrRunNotFound  = errors.New("run not found")
+	ErrAmbiguousRun = errors.New("run ID prefix matches several runs")
+)
+
+// Kind is what a run executed
+type Kind string
+
+const (
+	KindTask     Kind = "task"     // A single task
+	KindSwarm    Kind = "swarm"    // A task decomposed by an orchestrator
+	KindPipeline Kind = "pipeline" // A declarative swarm pipeline
+	KindReplay   Kind = "replay"   // Another run's prompts sent to a different model
+)
+
+// Status is how a run ended
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// Run is a recorded run: a task, or a swarm and every task it submitted
+type Run struct {
+	ID         string      `json:"id"` // The top-level task's ID
+	Kind       Kind        `json:"kind"`
+	Task       string      `json:"task"`
+	Status     Status      `json:"status"`
+	Error      string      `json:"error,omitempty"`
+	Output     string      `json:"output,omitempty"`
+	ReplayOf   string      `json:"replay_of,omitempty"` // Replays: the run replayed
+	Model      string      `json:"model,omitempty"`     // Replays: the model the prompts were sent to
+	Executions []Execution `json:"executions"`          // In completion order
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at,omitempty"`
+}
+
+// Execution is an agent's work on one task of a run
+type Execution struct {
+	agent.ExecutionRecord
+	AgentName  string                            `json:"agent_name,omitempty"`
+	Duration   time.Duration                     `json:"duration"`             // From assignment to the response
+	Assignment *collective.AssignmentExplanation `json:"assignment,omitempty"` // The bids and how the task was assigned
+	Timeline   []collective.TimelineEntry        `json:"timeline,omitempty"`
+}
+
+// TokensUsed returns the tokens all of the run's executions used
+func (r *Run) TokensUsed() int {
+	total := 0
+	for _, e := range r.Executions {
+		total += e.TokensUsed
+	}
+	return total
+}
+
+// Duration returns how long the run took, or has taken so far
+func (r *Run) Duration() time.Duration {
+	if r.FinishedAt.IsZero() {
+		return time.Since(r.StartedAt)
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// Models returns the models the run's executions used, in first-use order
+func (r *Run) Models() []string {
+	var models []string
+	seen := make(map[string]bool)
+	for _, e := range r.Executions {
+		if e.Model != "" && !seen[e.Model] {
+			seen[e.Model] = true
+			models = append(models, e.Model)
+		}
+	}
+	return models
+}
+
+// Store keeps each run in a JSON file named after its ID
+type Store struct {
+	mu sync.Mutex
+
+	dir string
+}
+
+// NewStore creates a store in dir, which is created on the first save
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// path returns the file of a run
+func (s *Store) path(id string) (string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("%w: invalid ID %q", ErrRunNotFound, id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// Save writes a run, replacing any earlier copy
+func (s *Store) Save(run *Run) error {
+	path, err := s.path(run.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+
+	// Write atomically so a crash never leaves a truncated run
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads a run by its ID or a unique prefix of it
+func (s *Store) Load(id string) (*Run, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s.loadPrefix(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("run %s: %w", id, err)
+	}
+	return &run, nil
+}
+
+// loadPrefix reads the one run whose ID starts with prefix
+func (s *Store) loadPrefix(prefix string) (*Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var match string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		if match != "" {
+			return nil, fmt.Errorf("%w: %s", ErrAmbiguousRun, prefix)
+		}
+		match = id
+	}
+	if match == "" {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, prefix)
+	}
+	return s.Load(match)
+}
+
+// List returns every run, most recent first. Unreadable files are skipped.
+func (s *Store) List() ([]*Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []*Run
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+		if run, err := s.Load(id); err == nil {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+	return runs, nil
+}
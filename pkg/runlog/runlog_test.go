@@ -0,0 +1,124 @@
+package runlog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// echoProvider answers every prompt with a fixed response
+type echoProvider struct {
+	response string
+	models   []string
+}
+
+func (p *echoProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.models = append(p.models, req.Model)
+	return &llm.CompletionResponse{Content: p.response, TokensUsed: 42}, nil
+}
+
+func (p *echoProvider) Name() string {
+	return "echo"
+}
+
+func TestRecorder_RecordsRun(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	store := NewStore(t.TempDir())
+	rec := NewRecorder(c, store)
+
+	provider := &echoProvider{response: "use an LRU cache"}
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Worker", Provider: provider, Model: "model-a", Recorder: rec, Capabilities: []identity.CapabilityType{identity.CapResearch}})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	task := agent.NewTask("Pick a cache", []identity.CapabilityType{identity.CapResearch})
+	if err := rec.Begin(task.ID, KindTask, task.Description); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := rec.Begin("other", KindTask, "x"); !errors.Is(err, ErrRecording) {
+		t.Errorf("Expected ErrRecording, got %v", err)
+	}
+	result, err := c.SubmitCtx(ctx, task)
+	if err != nil {
+		t.Fatalf("SubmitCtx failed: %v", err)
+	}
+	run, err := rec.End(result.Output, nil)
+	if err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+
+	if run.Status != StatusCompleted || len(run.Executions) != 1 {
+		t.Fatalf("Expected completed run with 1 execution, got %s with %d", run.Status, len(run.Executions))
+	}
+	e := run.Executions[0]
+	if e.TaskID != task.ID || e.Model != "model-a" || e.AgentName != "Worker" || e.TokensUsed != 42 {
+		t.Errorf("Expected execution by Worker on model-a, got %+v", e.ExecutionRecord)
+	}
+	if e.Prompt == "" || e.Output != "use an LRU cache" {
+		t.Errorf("Expected prompt and response recorded, got %q", e.Output)
+	}
+	if e.Assignment == nil || e.Assignment.Winner != a.Identity.SID || len(e.Timeline) == 0 {
+		t.Error("Expected the assignment and timeline recorded")
+	}
+
+	loaded, err := store.Load(task.ID[:8])
+	if err != nil {
+		t.Fatalf("Load by prefix failed: %v", err)
+	}
+	if loaded.Output != "use an LRU cache" {
+		t.Errorf("Expected saved output, got %q", loaded.Output)
+	}
+	if _, err := store.Load("missing"); !errors.Is(err, ErrRunNotFound) {
+		t.Errorf("Expected ErrRunNotFound, got %v", err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	run := &Run{ID: "r1", Kind: KindSwarm, Task: "Pick a cache", Executions: []Execution{
+		{ExecutionRecord: agent.ExecutionRecord{TaskID: "t1", Model: "model-a", Prompt: "research caches", Output: "use an LRU cache", Status: agent.TaskCompleted, TokensUsed: 10}},
+		{ExecutionRecord: agent.ExecutionRecord{TaskID: "t2", Model: "model-a", Prompt: "synthesize", Output: "LRU", Status: agent.TaskCompleted, TokensUsed: 5}},
+	}}
+
+	provider := &echoProvider{response: "use an LRU cache"}
+	replay, err := Replay(context.Background(), provider, run, "model-b")
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if replay.ReplayOf != "r1" || replay.Kind != KindReplay || len(replay.Executions) != 2 {
+		t.Fatalf("Expected replay of r1 with 2 executions, got %+v", replay)
+	}
+	for _, m := range provider.models {
+		if m != "model-b" {
+			t.Errorf("Expected prompts sent to model-b, got %s", m)
+		}
+	}
+	if replay.Output != "use an LRU cache" {
+		t.Errorf("Expected last execution's output, got %q", replay.Output)
+	}
+
+	cmp := Compare(run, replay)
+	if len(cmp.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(cmp.Rows))
+	}
+	if cmp.Rows[0].Similarity != 1 || cmp.Rows[1].Similarity >= 1 {
+		t.Errorf("Expected identical then differing outputs, got %.2f and %.2f", cmp.Rows[0].Similarity, cmp.Rows[1].Similarity)
+	}
+	if cmp.Rows[0].BaseModel != "model-a" || cmp.Rows[0].ReplayModel != "model-b" {
+		t.Errorf("Expected model-a vs model-b, got %s vs %s", cmp.Rows[0].BaseModel, cmp.Rows[0].ReplayModel)
+	}
+
+	if _, err := Replay(context.Background(), provider, &Run{ID: "empty"}, "model-b"); !errors.Is(err, ErrNothingToReplay) {
+		t.Errorf("Expected ErrNothingToReplay, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ContainerExecutor runs programs in a throwaway container with no network,
+// a read-only root filesystem and the memory, CPU and process limits of its
+// Config. The program's directory is the only writable mount.
+type ContainerExecutor struct {
+	cfg Config
+}
+
+// NewContainerExecutor creates a container executor
+func NewContainerExecutor(cfg Config) *ContainerExecutor {
+	return &ContainerExecutor{cfg: cfg.withDefaults()}
+}
+
+// Run writes the program to a scratch directory and runs it in a container
+func (e *ContainerExecutor) Run(ctx context.Context, p *Program) (*Result, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	root, err := os.MkdirTemp("", "sqm-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "work")
+	if err := writeFiles(dir, p); err != nil {
+		return nil, err
+	}
+	// The container's user may not be ours
+	if err := os.Chmod(dir, 0777); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.cfg.Runtime, e.args(dir, p)...)
+	cmd.WaitDelay = time.Second
+	// Program variables are passed by name so their values stay off the
+	// command line
+	if len(p.Env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range p.Env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+
+	output := &limitedBuffer{max: e.cfg.MaxOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	start := time.Now()
+	err = cmd.Run()
+	result := &Result{Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		result.TimedOut = true
+		result.ExitCode = -1
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("running %s: %w", e.cfg.Runtime, err)
+	}
+	result.Output = output.String()
+	p.score(result)
+	return result, nil
+}
+
+// args returns the container runtime's arguments for running a program
+// mounted at /work
+func (e *ContainerExecutor) args(dir string, p *Program) []string {
+	args := []string{
+		"run", "--rm",
+		"--network", "none",
+		"--read-only",
+		"--tmpfs", "/tmp",
+		"--memory", fmt.Sprint(e.cfg.Memory),
+		"--cpus", "1",
+		"--pids-limit", "256",
+		"--ulimit", fmt.Sprintf("nofile=%d", e.cfg.MaxFiles),
+		"--ulimit", fmt.Sprintf("cpu=%d", int(e.cfg.CPUTime.Seconds()+0.5)),
+		"--stop-timeout", "1",
+		"-e", "HOME=/work",
+		"-e", "GOCACHE=/tmp/go-cache",
+		"-e", "GOFLAGS=-mod=mod",
+		"-e", "GOPROXY=off",
+	}
+	keys := make([]string, 0, len(p.Env))
+	for key := range p.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "-e", key)
+	}
+	return append(args,
+		"-v", dir+":/work",
+		"-w", "/work",
+		e.cfg.Image,
+		"sh", "-c", p.command(),
+	)
+}
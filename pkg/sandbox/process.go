@@ -0,0 +1,115 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// ProcessExecutor runs programs as subprocesses of sh in a scratch
+// directory, with a minimal environment and the CPU time, memory, file and
+// output limits of its Config applied through ulimit. It isolates resource
+// use but not the filesystem or network; use ContainerExecutor for that.
+type ProcessExecutor struct {
+	cfg Config
+}
+
+// NewProcessExecutor creates a subprocess executor
+func NewProcessExecutor(cfg Config) *ProcessExecutor {
+	return &ProcessExecutor{cfg: cfg.withDefaults()}
+}
+
+// Run writes the program to a scratch directory, builds and runs it
+func (e *ProcessExecutor) Run(ctx context.Context, p *Program) (*Result, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	root, err := os.MkdirTemp("", "sqm-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(root)
+	dir, tmp := filepath.Join(root, "work"), filepath.Join(root, "tmp")
+	if err := os.Mkdir(tmp, 0700); err != nil {
+		return nil, err
+	}
+	if err := writeFiles(dir, p); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	limits := fmt.Sprintf("ulimit -t %d -v %d -n %d -f %d 2>/dev/null; ",
+		int(e.cfg.CPUTime.Seconds()+0.5), e.cfg.Memory/1024, e.cfg.MaxFiles, 1<<20)
+	cmd := exec.CommandContext(ctx, "sh", "-c", limits+p.command())
+	cmd.Dir = dir
+	cmd.Env = e.env(tmp)
+	for key, value := range p.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.WaitDelay = time.Second
+	isolateGroup(cmd)
+
+	output := &limitedBuffer{max: e.cfg.MaxOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	start := time.Now()
+	err = cmd.Run()
+	result := &Result{Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		result.TimedOut = true
+		result.ExitCode = -1
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, err
+	}
+	result.Output = output.String()
+	p.score(result)
+	return result, nil
+}
+
+// env returns the environment programs run with: enough to find the
+// toolchains, with HOME and temporary files in a scratch directory
+func (e *ProcessExecutor) env(tmp string) []string {
+	env := []string{
+		"HOME=" + tmp,
+		"TMPDIR=" + tmp,
+		"PATH=" + os.Getenv("PATH"),
+		"GOFLAGS=-mod=mod",
+		"GOTOOLCHAIN=local",
+	}
+	if cache, err := os.UserCacheDir(); err == nil {
+		env = append(env, "GOCACHE="+filepath.Join(cache, "squaremind", "sandbox-go"))
+	}
+	for _, key := range []string{"GOROOT", "GOPATH", "GOPROXY"} {
+		if v, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+v)
+		}
+	}
+	return env
+}
+
+// writeFiles writes a program's files under dir
+func writeFiles(dir string, p *Program) error {
+	for name, contents := range p.Files {
+		path := filepath.Join(dir, filepath.Clean(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
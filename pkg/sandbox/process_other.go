@@ -0,0 +1,9 @@
+//go:build !unix
+
+package sandbox
+
+import "os/exec"
+
+// isolateGroup is a no-op where process groups aren't available; only the
+// program's direct process is killed on cancellation
+func isolateGroup(cmd *exec.Cmd) {}
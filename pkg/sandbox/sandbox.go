@@ -0,0 +1,343 @@
+// Package sandbox compiles and runs code generated by agents in isolation,
+// either as a resource-limited subprocess or inside a container, and reports
+// how many of its tests passed.
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoProgram           = errors.New("no runnable code found")
+	ErrUnsupportedLanguage = errors.New("unsupported language")
+	ErrUnsafePath          = errors.New("file path escapes the sandbox")
+)
+
+// Language is a language the sandbox can run
+type Language string
+
+const (
+	LangGo     Language = "go"
+	LangPython Language = "python"
+	LangShell  Language = "shell"
+)
+
+// Program is code to run: a set of files in one language. Go programs run
+// their tests if they have any and main otherwise; Python and shell programs
+// run Entry (default: the first file).
+type Program struct {
+	Language Language          `json:"language"`
+	Files    map[string]string `json:"files"` // Path relative to the work directory -> contents
+	Entry    string            `json:"entry,omitempty"`
+	Env      map[string]string `json:"-"` // Extra environment variables, such as tool credentials
+}
+
+// Result is the outcome of running a program
+type Result struct {
+	Compiled bool          `json:"compiled"`
+	ExitCode int           `json:"exit_code"`
+	Passed   int           `json:"passed"` // Tests passed (1 for a program without tests that exits cleanly)
+	Failed   int           `json:"failed"`
+	Output   string        `json:"output"` // Combined stdout and stderr, truncated to Config.MaxOutput
+	TimedOut bool          `json:"timed_out,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Success reports whether the program compiled and nothing failed
+func (r *Result) Success() bool {
+	return r.Compiled && !r.TimedOut && r.Failed == 0 && r.ExitCode == 0
+}
+
+// PassRate returns the share of tests that passed (0 if none ran)
+func (r *Result) PassRate() float64 {
+	total := r.Passed + r.Failed
+	if total == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(total)
+}
+
+// Summary describes the result for an LLM to act on
+func (r *Result) Summary() string {
+	var b strings.Builder
+	switch {
+	case r.TimedOut:
+		fmt.Fprintf(&b, "Execution timed out after %s.", r.Duration.Round(time.Millisecond))
+	case !r.Compiled:
+		b.WriteString("The code failed to compile.")
+	default:
+		fmt.Fprintf(&b, "Exit code %d: %d passed, %d failed.", r.ExitCode, r.Passed, r.Failed)
+	}
+	if r.Output != "" {
+		b.WriteString("\n\nOutput:\n")
+		b.WriteString(r.Output)
+	}
+	return b.String()
+}
+
+// Executor runs programs in isolation
+type Executor interface {
+	Run(ctx context.Context, p *Program) (*Result, error)
+}
+
+// Config limits what a sandboxed program may use
+type Config struct {
+	Timeout   time.Duration `json:"timeout" yaml:"timeout"`           // Wall clock limit per run
+	CPUTime   time.Duration `json:"cpu_time" yaml:"cpu_time"`         // CPU time limit
+	Memory    int64         `json:"memory" yaml:"memory"`             // Address space limit in bytes
+	MaxFiles  int           `json:"max_files" yaml:"max_files"`       // Open file descriptor limit
+	MaxOutput int           `json:"max_output" yaml:"max_output"`     // Bytes of output kept
+	Image     string        `json:"image,omitempty" yaml:"image"`     // Container image (container executor)
+	Runtime   string        `json:"runtime,omitempty" yaml:"runtime"` // Container CLI (default: docker)
+}
+
+// DefaultConfig returns default limits
+func DefaultConfig() Config {
+	return Config{
+		Timeout:   30 * time.Second,
+		CPUTime:   20 * time.Second,
+		Memory:    2 << 30,
+		MaxFiles:  256,
+		MaxOutput: 16 << 10,
+		Image:     "golang:1.21",
+		Runtime:   "docker",
+	}
+}
+
+// withDefaults fills unset limits from DefaultConfig
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if c.Timeout <= 0 {
+		c.Timeout = d.Timeout
+	}
+	if c.CPUTime <= 0 {
+		c.CPUTime = d.CPUTime
+	}
+	if c.Memory <= 0 {
+		c.Memory = d.Memory
+	}
+	if c.MaxFiles <= 0 {
+		c.MaxFiles = d.MaxFiles
+	}
+	if c.MaxOutput <= 0 {
+		c.MaxOutput = d.MaxOutput
+	}
+	if c.Image == "" {
+		c.Image = d.Image
+	}
+	if c.Runtime == "" {
+		c.Runtime = d.Runtime
+	}
+	return c
+}
+
+// New creates an executor by kind: "process" or "container"
+func New(kind string, cfg Config) (Executor, error) {
+	switch kind {
+	case "process":
+		return NewProcessExecutor(cfg), nil
+	case "container":
+		return NewContainerExecutor(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown sandbox %q (want process or container)", kind)
+	}
+}
+
+// codeBlock matches a fenced code block, with an optional language and an
+// optional file name after it (```go main_test.go)
+var codeBlock = regexp.MustCompile("(?s)```([A-Za-z0-9_+-]*)[ \\t]*([^\\n`]*)\\n(.*?)```")
+
+// ExtractProgram collects the code blocks of an LLM response into a program
+// in the language of the first block. Blocks without a file name get one.
+func ExtractProgram(text string) (*Program, error) {
+	var p *Program
+	for _, m := range codeBlock.FindAllStringSubmatch(text, -1) {
+		lang, ok := parseLanguage(m[1])
+		if !ok {
+			continue
+		}
+		if p == nil {
+			p = &Program{Language: lang, Files: make(map[string]string)}
+		} else if lang != p.Language {
+			continue
+		}
+
+		name := strings.TrimSpace(m[2])
+		if name == "" {
+			name = defaultFileName(p, m[3])
+		}
+		p.Files[name] += m[3]
+	}
+	if p == nil {
+		return nil, ErrNoProgram
+	}
+	return p, nil
+}
+
+// parseLanguage maps a code block's language tag to a Language
+func parseLanguage(tag string) (Language, bool) {
+	switch strings.ToLower(tag) {
+	case "go", "golang":
+		return LangGo, true
+	case "python", "py", "python3":
+		return LangPython, true
+	case "sh", "bash", "shell":
+		return LangShell, true
+	}
+	return "", false
+}
+
+// defaultFileName names an unnamed code block
+func defaultFileName(p *Program, code string) string {
+	switch p.Language {
+	case LangGo:
+		if strings.Contains(code, "func Test") {
+			return uniqueName(p, "generated", "_test.go")
+		}
+		return uniqueName(p, "generated", ".go")
+	case LangPython:
+		return uniqueName(p, "main", ".py")
+	default:
+		return uniqueName(p, "main", ".sh")
+	}
+}
+
+// uniqueName returns base+ext, numbered if a file of that name exists
+func uniqueName(p *Program, base, ext string) string {
+	name := base + ext
+	for i := 2; ; i++ {
+		if _, ok := p.Files[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("%s%d%s", base, i, ext)
+	}
+}
+
+// entry returns the file a Python or shell program starts from
+func (p *Program) entry() string {
+	if p.Entry != "" {
+		return p.Entry
+	}
+	first := ""
+	for name := range p.Files {
+		if first == "" || name < first {
+			first = name
+		}
+	}
+	return first
+}
+
+// hasTests reports whether a Go program has test files
+func (p *Program) hasTests() bool {
+	for name := range p.Files {
+		if strings.HasSuffix(name, "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the program's files stay within the work directory
+func (p *Program) validate() error {
+	if len(p.Files) == 0 {
+		return ErrNoProgram
+	}
+	for name := range p.Files {
+		clean := filepath.Clean(name)
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%w: %s", ErrUnsafePath, name)
+		}
+	}
+	switch p.Language {
+	case LangGo, LangPython, LangShell:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupportedLanguage, p.Language)
+}
+
+// command returns the shell command that builds and runs the program in its
+// work directory
+func (p *Program) command() string {
+	switch p.Language {
+	case LangGo:
+		setup := "[ -f go.mod ] || go mod init sandbox >/dev/null 2>&1; "
+		if p.hasTests() {
+			return setup + "go test -count=1 -run '^$' ./... >/dev/null || exit 200; go test -v -count=1 ./..."
+		}
+		return setup + "go build -o /dev/null ./... || exit 200; go run ."
+	case LangPython:
+		return "python3 -m py_compile " + shellQuote(p.entry()) + " || exit 200; python3 " + shellQuote(p.entry())
+	default:
+		return "sh -n " + shellQuote(p.entry()) + " || exit 200; sh " + shellQuote(p.entry())
+	}
+}
+
+// compileFailed is the exit code command uses when the program doesn't build
+const compileFailed = 200
+
+var (
+	goTestPass = regexp.MustCompile(`(?m)^\s*--- PASS: `)
+	goTestFail = regexp.MustCompile(`(?m)^\s*--- FAIL: `)
+)
+
+// score fills in a result's compile status and test counts from its exit
+// code and output
+func (p *Program) score(r *Result) {
+	r.Compiled = r.ExitCode != compileFailed
+	if !r.Compiled || r.TimedOut {
+		return
+	}
+	if p.Language == LangGo && p.hasTests() {
+		r.Passed = len(goTestPass.FindAllString(r.Output, -1))
+		r.Failed = len(goTestFail.FindAllString(r.Output, -1))
+		if r.Failed == 0 && r.ExitCode != 0 {
+			r.Failed = 1
+		}
+		return
+	}
+	if r.ExitCode == 0 {
+		r.Passed = 1
+	} else {
+		r.Failed = 1
+	}
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// limitedBuffer keeps the first max bytes written to it
+type limitedBuffer struct {
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	room := b.max - len(b.buf)
+	if room <= 0 {
+		b.truncated = true
+		return len(p), nil
+	}
+	if len(p) > room {
+		b.buf = append(b.buf, p[:room]...)
+		b.truncated = true
+		return len(p), nil
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return string(b.buf) + "\n[output truncated]"
+	}
+	return string(b.buf)
+}
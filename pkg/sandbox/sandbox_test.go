@@ -0,0 +1,135 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractProgram(t *testing.T) {
+	text := "Here is the code:\n\n```go\npackage main\n\nfunc Add(a, b int) int { return a + b }\n```\n\n" +
+		"And its tests:\n\n```go add_test.go\npackage main\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {}\n```\n\n" +
+		"```python\nprint('ignored')\n```\n"
+
+	p, err := ExtractProgram(text)
+	if err != nil {
+		t.Fatalf("ExtractProgram failed: %v", err)
+	}
+
+	if p.Language != LangGo {
+		t.Errorf("Expected Go program, got %s", p.Language)
+	}
+	if len(p.Files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(p.Files))
+	}
+	if !strings.Contains(p.Files["generated.go"], "func Add") {
+		t.Errorf("Expected unnamed block in generated.go, got %v", p.Files)
+	}
+	if !strings.Contains(p.Files["add_test.go"], "func TestAdd") {
+		t.Errorf("Expected named block in add_test.go, got %v", p.Files)
+	}
+
+	if _, err := ExtractProgram("no code here"); !errors.Is(err, ErrNoProgram) {
+		t.Errorf("Expected ErrNoProgram, got %v", err)
+	}
+}
+
+func TestProgram_Validate(t *testing.T) {
+	escape := &Program{Language: LangShell, Files: map[string]string{"../evil.sh": "rm -rf ~"}}
+	if err := escape.validate(); !errors.Is(err, ErrUnsafePath) {
+		t.Errorf("Expected ErrUnsafePath, got %v", err)
+	}
+
+	unknown := &Program{Language: "cobol", Files: map[string]string{"main.cob": ""}}
+	if err := unknown.validate(); !errors.Is(err, ErrUnsupportedLanguage) {
+		t.Errorf("Expected ErrUnsupportedLanguage, got %v", err)
+	}
+}
+
+func TestProcessExecutor_Run(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	e := NewProcessExecutor(Config{Timeout: 5 * time.Second})
+	ctx := context.Background()
+
+	ok, err := e.Run(ctx, &Program{Language: LangShell, Files: map[string]string{"main.sh": "echo hello"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !ok.Success() || ok.Passed != 1 || strings.TrimSpace(ok.Output) != "hello" {
+		t.Errorf("Expected a passing run printing hello, got %+v", ok)
+	}
+
+	bad, err := e.Run(ctx, &Program{Language: LangShell, Files: map[string]string{"main.sh": "if then"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if bad.Compiled || bad.Success() {
+		t.Errorf("Expected a syntax error to fail compilation, got %+v", bad)
+	}
+
+	failing, err := e.Run(ctx, &Program{Language: LangShell, Files: map[string]string{"main.sh": "exit 3"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if failing.ExitCode != 3 || failing.Failed != 1 {
+		t.Errorf("Expected exit code 3 counted as a failure, got %+v", failing)
+	}
+
+	env, err := e.Run(ctx, &Program{Language: LangShell, Files: map[string]string{"main.sh": "echo $SCANNER_TOKEN"}, Env: map[string]string{"SCANNER_TOKEN": "s3cret"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if strings.TrimSpace(env.Output) != "s3cret" {
+		t.Errorf("Expected the program to see its env, got %q", env.Output)
+	}
+}
+
+func TestProcessExecutor_Timeout(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	e := NewProcessExecutor(Config{Timeout: 200 * time.Millisecond})
+
+	r, err := e.Run(context.Background(), &Program{Language: LangShell, Files: map[string]string{"main.sh": "sleep 5"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !r.TimedOut || r.Success() {
+		t.Errorf("Expected the run to time out, got %+v", r)
+	}
+	if r.Duration > 3*time.Second {
+		t.Errorf("Expected the run killed promptly, took %v", r.Duration)
+	}
+}
+
+func TestProcessExecutor_GoTests(t *testing.T) {
+	if testing.Short() {
+		t.Skip("compiles Go code")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	e := NewProcessExecutor(Config{Timeout: time.Minute, CPUTime: time.Minute})
+
+	p := &Program{Language: LangGo, Files: map[string]string{
+		"add.go": "package add\n\nfunc Add(a, b int) int { return a - b }\n",
+		"add_test.go": "package add\n\nimport \"testing\"\n\n" +
+			"func TestZero(t *testing.T) { if Add(0, 0) != 0 { t.Fatal(\"zero\") } }\n" +
+			"func TestAdd(t *testing.T) { if Add(1, 2) != 3 { t.Fatal(\"wrong sum\") } }\n",
+	}}
+	r, err := e.Run(context.Background(), p)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !r.Compiled || r.Passed != 1 || r.Failed != 1 {
+		t.Errorf("Expected 1 passed and 1 failed, got %+v", r)
+	}
+	if r.PassRate() != 0.5 {
+		t.Errorf("Expected pass rate 0.5, got %v", r.PassRate())
+	}
+}
@@ -0,0 +1,146 @@
+// Package schema versions the JSON encoding of objects squaremind persists or
+// sends over the wire. Every encoded object carries a schema_version, and
+// decoding works across releases in both directions: fields an older release
+// didn't write keep their defaults, and fields added by a newer release are
+// kept and written back out unchanged, so state that passes through an older
+// release doesn't lose them.
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// VersionField is the JSON key holding an object's schema version. Objects
+// written before versioning have none, which reads as version 0.
+const VersionField = "schema_version"
+
+// Extra holds the fields of an encoded object this release doesn't know
+type Extra map[string]json.RawMessage
+
+// Decode unmarshals an object into v, which should hold the defaults for
+// fields the encoding may lack. It returns the schema version the object was
+// written with and the fields v has no place for.
+func Decode(data []byte, v interface{}) (int, Extra, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return 0, nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return 0, nil, err
+	}
+
+	version := 0
+	if raw, ok := fields[VersionField]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", VersionField, err)
+		}
+		delete(fields, VersionField)
+	}
+
+	known := knownFields(reflect.TypeOf(v))
+	var extra Extra
+	for key, raw := range fields {
+		if known[strings.ToLower(key)] {
+			continue
+		}
+		if extra == nil {
+			extra = make(Extra)
+		}
+		extra[key] = raw
+	}
+	return version, extra, nil
+}
+
+// Encode marshals v, stamped with its schema version and followed by any
+// fields kept from a newer release
+func Encode(v interface{}, version int, extra Extra) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 2 || data[0] != '{' {
+		return nil, fmt.Errorf("schema: %T does not encode as a JSON object", v)
+	}
+
+	var b bytes.Buffer
+	b.Grow(len(data) + 24)
+	b.Write(data[:len(data)-1])
+	if len(data) > 2 {
+		b.WriteByte(',')
+	}
+	fmt.Fprintf(&b, "%q:%d", VersionField, version)
+
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, _ := json.Marshal(key)
+		b.WriteByte(',')
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(extra[key])
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// fieldCache maps struct types to the lower-cased JSON names of their fields
+var fieldCache sync.Map
+
+// knownFields returns the lower-cased JSON names of a struct's fields,
+// including those of embedded structs. encoding/json matches names case
+// insensitively, and so does this.
+func knownFields(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]bool)
+	}
+
+	known := make(map[string]bool)
+	if t.Kind() == reflect.Struct {
+		collectFields(t, known)
+	}
+	fieldCache.Store(t, known)
+	return known
+}
+
+// collectFields adds the JSON names of a struct's fields to known
+func collectFields(t reflect.Type, known map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, known)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		known[strings.ToLower(name)] = true
+	}
+}
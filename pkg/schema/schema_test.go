@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type record struct {
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty"`
+	Note  string
+	Skip  string `json:"-"`
+	embedded
+}
+
+type embedded struct {
+	Tag string `json:"tag"`
+}
+
+func TestDecode(t *testing.T) {
+	data := []byte(`{"schema_version":3,"name":"a","note":"n","tag":"t","added":{"x":1},"Skip":"s"}`)
+
+	r := record{Count: 7}
+	version, extra, err := Decode(data, &r)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	if version != 3 {
+		t.Errorf("Expected version 3, got %d", version)
+	}
+	if r.Name != "a" || r.Note != "n" || r.Tag != "t" {
+		t.Errorf("Expected known fields decoded, got %+v", r)
+	}
+	if r.Count != 7 {
+		t.Errorf("Expected default count kept, got %d", r.Count)
+	}
+	if len(extra) != 2 || string(extra["added"]) != `{"x":1}` {
+		t.Errorf("Expected added and Skip kept as unknown, got %v", extra)
+	}
+
+	if version, _, _ := Decode([]byte(`{"name":"old"}`), &r); version != 0 {
+		t.Errorf("Expected unversioned data to read as version 0, got %d", version)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	extra := Extra{"added": json.RawMessage(`[1,2]`)}
+	data, err := Encode(record{Name: "a"}, 2, extra)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	expected := `{"name":"a","Note":"","tag":"","schema_version":2,"added":[1,2]}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	data, err = Encode(struct{}{}, 1, nil)
+	if err != nil || string(data) != `{"schema_version":1}` {
+		t.Errorf("Expected a bare version for an empty object, got %s (%v)", data, err)
+	}
+
+	if _, err := Encode([]int{1}, 1, nil); err == nil {
+		t.Error("Expected an error encoding a non-object")
+	}
+}
@@ -0,0 +1,373 @@
+// Package selftest verifies an installation end to end. It runs a canonical
+// suite of checks, each against an ephemeral collective whose agents answer
+// from a simulated provider, so no tokens are spent and nothing outside a
+// temporary directory is touched.
+package selftest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/logging"
+	"github.com/square-mind/squaremind/pkg/sim"
+	"github.com/square-mind/squaremind/pkg/storage"
+	sqmtest "github.com/square-mind/squaremind/pkg/testing"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+var ErrUnknownCheck = errors.New("unknown check")
+
+// Check is one verification of the suite
+type Check struct {
+	Name        string
+	Description string
+	Run         func(ctx context.Context, env *Env) error
+}
+
+// Env is what a check runs with
+type Env struct {
+	Dir  string // Scratch directory of the run
+	Seed int64
+}
+
+// Config controls a run of the suite
+type Config struct {
+	Checks  []string      // Names of the checks to run (empty = all)
+	Timeout time.Duration // Limit on each check (default 30s)
+	Seed    int64         // Seeds the simulated checks (default 1)
+	Dir     string        // Scratch directory (empty = a temporary one, removed afterwards)
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+	return c
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Results  []Result      `json:"results"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Duration time.Duration `json:"duration"`
+}
+
+// OK reports whether every check passed
+func (r *Report) OK() bool {
+	return r.Failed == 0
+}
+
+// Checks returns the canonical suite, in the order it runs
+func Checks() []Check {
+	return []Check{
+		{Name: "market", Description: "Task auction assigns work to the capable agent", Run: checkMarket},
+		{Name: "consensus", Description: "Reputation-weighted vote applies and rejects parameter changes", Run: checkConsensus},
+		{Name: "workflow", Description: "Workflow with a review loop revises until approved", Run: checkWorkflow},
+		{Name: "gossip", Description: "Gossip over the local transport reaches every agent", Run: checkGossip},
+		{Name: "persistence", Description: "Results, memory and reputation survive a restart", Run: checkPersistence},
+	}
+}
+
+// Run runs the suite. Checks that fail or panic are reported, not returned;
+// the error is for an unknown check name or an unusable scratch directory.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	cfg = cfg.withDefaults()
+
+	checks := Checks()
+	if len(cfg.Checks) > 0 {
+		byName := make(map[string]Check, len(checks))
+		for _, check := range checks {
+			byName[check.Name] = check
+		}
+		checks = checks[:0:0]
+		for _, name := range cfg.Checks {
+			check, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownCheck, name)
+			}
+			checks = append(checks, check)
+		}
+	}
+
+	dir := cfg.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "sqm-selftest-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	report := &Report{}
+	start := time.Now()
+	for _, check := range checks {
+		env := &Env{Dir: dir, Seed: cfg.Seed}
+		result := runCheck(ctx, check, env, cfg.Timeout)
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// runCheck runs one check under its timeout, recovering from panics
+func runCheck(ctx context.Context, check Check, env *Env, timeout time.Duration) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result.Name = check.Name
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			result.Passed = false
+			result.Error = fmt.Sprintf("panic: %v", r)
+		}
+		result.Duration = time.Since(start)
+	}()
+
+	if err := check.Run(ctx, env); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Passed = true
+	return result
+}
+
+// newCollective creates a quiet ephemeral collective staffed with agents
+// answering from provider, named by their capability
+func newCollective(name string, cfg collective.CollectiveConfig, provider llm.Provider, caps ...identity.CapabilityType) (*collective.Collective, map[identity.CapabilityType]*agent.Agent, error) {
+	c := collective.NewCollective(name, cfg)
+	c.SetLogger(logging.Nop())
+	c.GetMarket().SetBidTimeout(20 * time.Millisecond)
+
+	agents := make(map[identity.CapabilityType]*agent.Agent, len(caps))
+	for _, capType := range caps {
+		a, err := agent.NewAgent(agent.AgentConfig{
+			Name:         strings.ReplaceAll(string(capType), ".", "-"),
+			Capabilities: []identity.CapabilityType{capType},
+			Provider:     provider,
+			Logger:       logging.Nop(),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := c.Join(a); err != nil {
+			return nil, nil, err
+		}
+		agents[capType] = a
+	}
+	return c, agents, nil
+}
+
+// checkMarket auctions a task only one agent can do
+func checkMarket(ctx context.Context, env *Env) error {
+	c, agents, err := newCollective("selftest-market", collective.DefaultCollectiveConfig(), sqmtest.NewFakeProvider("reviewed"),
+		identity.CapCodeWrite, identity.CapCodeReview, identity.CapTesting)
+	if err != nil {
+		return err
+	}
+	if err := c.Start(ctx); err != nil {
+		return err
+	}
+	defer c.Stop()
+
+	result, err := c.SubmitCtx(ctx, agent.NewTask("Review the change", []identity.CapabilityType{identity.CapCodeReview}))
+	if err != nil {
+		return fmt.Errorf("submitting: %w", err)
+	}
+	if result.Status != agent.TaskCompleted {
+		return fmt.Errorf("task %s: %s", result.Status, result.Error)
+	}
+	if want := agents[identity.CapCodeReview].Identity.SID; result.AgentSID != want {
+		return fmt.Errorf("task went to %s, not the only reviewer %s", result.AgentSID, want)
+	}
+	if result.Output != "reviewed" {
+		return fmt.Errorf("unexpected output %q", result.Output)
+	}
+	return nil
+}
+
+// checkConsensus votes on a parameter change twice: accepted, then rejected
+func checkConsensus(ctx context.Context, env *Env) error {
+	c, _, err := newCollective("selftest-consensus", collective.DefaultCollectiveConfig(), sqmtest.NewFakeProvider(),
+		identity.CapCodeWrite, identity.CapCodeReview, identity.CapTesting)
+	if err != nil {
+		return err
+	}
+
+	maxAgents := c.Parameters().MaxAgents + 1
+	if _, err := c.ProposeParameters(ctx, "selftest", collective.Parameters{MaxAgents: maxAgents}); err != nil {
+		return fmt.Errorf("unanimous change: %w", err)
+	}
+	if got := c.Parameters().MaxAgents; got != maxAgents {
+		return fmt.Errorf("accepted change not applied: max_agents %d, want %d", got, maxAgents)
+	}
+
+	c.SetParameterVoter(func(*agent.Agent, collective.Parameters, collective.Parameters) bool { return false })
+	_, err = c.ProposeParameters(ctx, "selftest", collective.Parameters{MaxAgents: maxAgents + 1})
+	if !errors.Is(err, collective.ErrParameterRejected) {
+		return fmt.Errorf("opposed change: expected rejection, got %v", err)
+	}
+	if got := c.Parameters().MaxAgents; got != maxAgents {
+		return fmt.Errorf("rejected change applied: max_agents %d", got)
+	}
+	return nil
+}
+
+// checkWorkflow runs a write step and a review loop the reviewer approves
+// on its second pass
+func checkWorkflow(ctx context.Context, env *Env) error {
+	var (
+		mu      sync.Mutex
+		reviews int
+	)
+	provider := &sqmtest.FakeProvider{Respond: func(req llm.CompletionRequest) (string, error) {
+		if !strings.Contains(req.Prompt, "Review the draft") {
+			return "func hello() string { return \"hello\" }", nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		reviews++
+		if reviews == 1 {
+			return "CHANGES: add a test", nil
+		}
+		return "APPROVED", nil
+	}}
+	c, _, err := newCollective("selftest-workflow", collective.DefaultCollectiveConfig(), provider, identity.CapCodeWrite, identity.CapCodeReview)
+	if err != nil {
+		return err
+	}
+	if err := c.Start(ctx); err != nil {
+		return err
+	}
+	defer c.Stop()
+
+	wf := &workflow.Workflow{
+		Name: "selftest-review",
+		Steps: []workflow.Step{
+			{ID: "write", Task: "Write a hello function", Requires: []identity.CapabilityType{identity.CapCodeWrite}},
+			{
+				ID:        "review",
+				Task:      "Review the draft: {{.Steps.write.Output}}",
+				Requires:  []identity.CapabilityType{identity.CapCodeReview},
+				DependsOn: []string{"write"},
+				Loop: &workflow.Loop{
+					Until:         &workflow.Condition{Field: "output", Equals: "APPROVED"},
+					MaxIterations: 3,
+				},
+			},
+		},
+	}
+	engine := workflow.NewEngine(c)
+	engine.SetLogger(logging.Nop())
+	run, err := engine.Run(ctx, wf, nil)
+	if err != nil {
+		return fmt.Errorf("running: %w", err)
+	}
+	if run.Status != workflow.RunCompleted {
+		return fmt.Errorf("run %s", run.Status)
+	}
+	if review := run.Steps["review"]; review.Iterations != 2 || review.Output != "APPROVED" {
+		return fmt.Errorf("review loop ran %d iterations ending with %q, want 2 ending with APPROVED", review.Iterations, review.Output)
+	}
+	return nil
+}
+
+// checkGossip broadcasts over the simulation's local transport
+func checkGossip(ctx context.Context, env *Env) error {
+	specs := make([]sim.AgentSpec, 8)
+	for i := range specs {
+		specs[i] = sim.AgentSpec{Name: fmt.Sprintf("node-%d", i+1), Capabilities: []identity.CapabilityType{identity.CapResearch}}
+	}
+	s, err := sim.New(sim.Config{Seed: env.Seed, Agents: specs})
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	id, err := s.BroadcastAt(0, "node-1", coordination.MsgHeartbeat, agent.Heartbeat{AgentSID: "selftest", State: agent.StateIdle})
+	if err != nil {
+		return err
+	}
+	if err := s.Run(ctx); err != nil {
+		return err
+	}
+	if reached := s.Reached(id); len(reached) != len(specs) {
+		return fmt.Errorf("broadcast reached %d of %d agents: %v", len(reached), len(specs), reached)
+	}
+	return nil
+}
+
+// checkPersistence finishes a task on a collective with filesystem
+// storage, then reads its state back from a second collective on the same
+// storage
+func checkPersistence(ctx context.Context, env *Env) error {
+	cfg := collective.DefaultCollectiveConfig()
+	cfg.Storage = &storage.Config{Driver: storage.DriverFilesystem, Path: filepath.Join(env.Dir, "storage")}
+
+	first, agents, err := newCollective("selftest-persistence", cfg, sqmtest.NewFakeProvider("persisted"), identity.CapCodeWrite)
+	if err != nil {
+		return err
+	}
+	if err := first.Start(ctx); err != nil {
+		return err
+	}
+	task := agent.NewTask("Write something to keep", []identity.CapabilityType{identity.CapCodeWrite})
+	result, err := first.SubmitCtx(ctx, task)
+	first.GetMemory().Contribute(agents[identity.CapCodeWrite].Identity.SID, "selftest persistence marker", nil)
+	first.Stop()
+	if err != nil {
+		return fmt.Errorf("submitting: %w", err)
+	}
+	sid := result.AgentSID
+
+	second := collective.NewCollective("selftest-persistence", cfg)
+	second.SetLogger(logging.Nop())
+	stored, err := second.StoredResult(ctx, task.ID)
+	if err != nil {
+		return fmt.Errorf("reading result back: %w", err)
+	}
+	if stored.Output != result.Output || stored.AgentSID != sid {
+		return fmt.Errorf("stored result differs: %q by %s, want %q by %s", stored.Output, stored.AgentSID, result.Output, sid)
+	}
+	if len(second.GetMemory().Query("selftest persistence marker")) == 0 {
+		return errors.New("memory episode not restored")
+	}
+	record, err := second.GetReputation().Record(sid)
+	if err != nil {
+		return fmt.Errorf("reading reputation back: %w", err)
+	}
+	if record.Reputation.TasksCompleted < 1 {
+		return fmt.Errorf("restored reputation has %d completed tasks, want at least 1", record.Reputation.TasksCompleted)
+	}
+	return nil
+}
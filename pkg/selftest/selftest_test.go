@@ -0,0 +1,45 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(context.Background(), Config{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	for _, r := range report.Results {
+		if !r.Passed {
+			t.Errorf("Expected check %s to pass, got %s", r.Name, r.Error)
+		}
+	}
+	if len(report.Results) != len(Checks()) || !report.OK() {
+		t.Errorf("Expected all %d checks to pass, got %d passed and %d failed", len(Checks()), report.Passed, report.Failed)
+	}
+}
+
+func TestRun_Selection(t *testing.T) {
+	report, err := Run(context.Background(), Config{Checks: []string{"gossip", "consensus"}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Results) != 2 || report.Results[0].Name != "gossip" || report.Results[1].Name != "consensus" {
+		t.Errorf("Expected gossip then consensus, got %+v", report.Results)
+	}
+
+	if _, err := Run(context.Background(), Config{Checks: []string{"nope"}}); !errors.Is(err, ErrUnknownCheck) {
+		t.Errorf("Expected ErrUnknownCheck, got %v", err)
+	}
+}
+
+func TestRun_ReportsFailures(t *testing.T) {
+	check := Check{Name: "broken", Run: func(ctx context.Context, env *Env) error { panic("boom") }}
+	result := runCheck(context.Background(), check, &Env{}, time.Second)
+	if result.Passed || result.Error != "panic: boom" {
+		t.Errorf("Expected a failed check reporting the panic, got %+v", result)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// addAgentRequest is the body of POST /api/agents
+type addAgentRequest struct {
+	Name string `json:"name"`
+	AgentSpec
+}
+
+// handleAgents returns a snapshot of every agent, or on POST creates an
+// agent with the agent factory and adds it to the collective
+func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, http.StatusOK, s.collective.AgentSnapshots())
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+
+	var req addAgentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" || len(req.Capabilities) == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name and capabilities are required"})
+		return
+	}
+
+	s.mu.RLock()
+	factory := s.agentFactory
+	s.mu.RUnlock()
+	if factory == nil {
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": ErrNoAgentFactory.Error()})
+		return
+	}
+	a, err := factory(req.Name, req.AgentSpec)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	switch err := s.collective.Join(a); {
+	case errors.Is(err, collective.ErrCollectiveFull):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		snapshot, _ := s.collective.AgentSnapshot(a.Identity.SID)
+		writeJSON(w, http.StatusCreated, snapshot)
+	}
+}
+
+// handleAgent manages one agent of the running collective:
+//
+//	POST   /api/agents/{sid}/pause   stop it taking tasks
+//	POST   /api/agents/{sid}/resume  return it to work
+//	DELETE /api/agents/{sid}         stop it and remove it from the collective
+func (s *Server) handleAgent(w http.ResponseWriter, r *http.Request) {
+	sid, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/agents/"), "/")
+	if sid == "" || strings.Contains(action, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	var allow string
+	switch action {
+	case "":
+		allow = http.MethodDelete
+	case "pause", "resume":
+		allow = http.MethodPost
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != allow {
+		w.Header().Set("Allow", allow)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+
+	var err error
+	switch action {
+	case "":
+		err = s.collective.Leave(sid)
+	case "pause":
+		err = s.collective.PauseAgent(sid)
+	case "resume":
+		err = s.collective.ResumeAgent(sid)
+	}
+	switch {
+	case errors.Is(err, collective.ErrAgentNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, agent.ErrInvalidTransition):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	case action == "":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		snapshot, _ := s.collective.AgentSnapshot(sid)
+		writeJSON(w, http.StatusOK, snapshot)
+	}
+}
+
+// authorizeAdmin checks the request carries an API token, writing the error
+// response if not
+func (s *Server) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "agent management is disabled: no API tokens configured"})
+		return false
+	}
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return false
+	}
+	return true
+}
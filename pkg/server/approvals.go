@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+// SetInbox exposes the human requests of workflows at /api/approvals
+func (s *Server) SetInbox(inbox *workflow.Inbox) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inbox = inbox
+}
+
+func (s *Server) getInbox() *workflow.Inbox {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.inbox
+}
+
+// handleApprovals lists pending human requests
+func (s *Server) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	inbox := s.getInbox()
+	if inbox == nil {
+		writeJSON(w, http.StatusOK, []workflow.HumanRequest{})
+		return
+	}
+	writeJSON(w, http.StatusOK, inbox.Pending())
+}
+
+// handleApproval serves /api/approvals/{id}: GET returns the request, POST
+// answers it with a workflow.HumanResponse body. Answering requires an API
+// token, whose submitter is recorded as the responder.
+func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/approvals/")
+	inbox := s.getInbox()
+	if id == "" || strings.Contains(id, "/") || inbox == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		req, ok := inbox.Get(id)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no request " + id})
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	case http.MethodPost:
+		s.respond(w, r, inbox, id)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// respond records a human response
+func (s *Server) respond(w http.ResponseWriter, r *http.Request, inbox *workflow.Inbox, id string) {
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "responding is disabled: no API tokens configured"})
+		return
+	}
+	responder, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	var resp workflow.HumanResponse
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&resp); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	resp.Responder = responder
+
+	if err := inbox.Respond(id, resp); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, workflow.ErrRequestNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, workflow.ErrRequestClosed):
+			status = http.StatusConflict
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	req, _ := inbox.Get(id)
+	writeJSON(w, http.StatusOK, req)
+}
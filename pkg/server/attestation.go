@@ -0,0 +1,53 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// handleAttestation returns a claim signed with the collective's key:
+//
+//	GET /api/attestations/membership           the current members
+//	GET /api/attestations/consensus/{id}       a decided consensus round
+//	GET /api/attestations/advertisement        the collective, advertised at
+//	                                           ?endpoint= (default this server)
+func (s *Server) handleAttestation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	kind, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/attestations/"), "/")
+	var (
+		attestation *collective.Attestation
+		err         error
+	)
+	switch {
+	case kind == collective.AttestMembership && id == "":
+		attestation, err = s.collective.AttestMembership()
+	case kind == collective.AttestConsensus && id != "":
+		attestation, err = s.collective.AttestConsensus(id)
+	case kind == collective.AttestAdvertisement && id == "":
+		endpoint := r.URL.Query().Get("endpoint")
+		if endpoint == "" {
+			endpoint = "http://" + r.Host
+		}
+		attestation, err = s.collective.Advertise(endpoint)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case errors.Is(err, collective.ErrRoundNotDecided):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, attestation)
+	}
+}
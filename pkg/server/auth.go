@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// APIToken authorizes task submission over HTTP. The submitter it maps to is
+// recorded on submitted tasks and used for fair scheduling.
+type APIToken struct {
+	Token     string
+	Submitter string
+	Weight    int // Share of execution slots relative to other submitters (0 = default)
+}
+
+// AddToken registers an API token and applies its fair-share weight
+func (s *Server) AddToken(t APIToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = append(s.tokens, t)
+	if t.Weight > 0 {
+		s.collective.SetSubmitterWeight(t.Submitter, t.Weight)
+	}
+}
+
+// authenticate returns the submitter for the request's bearer token
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	presented, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || presented == "" {
+		return "", false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Compare against every token so timing doesn't reveal which one matched
+	submitter, found := "", false
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(t.Token)) == 1 {
+			submitter, found = t.Submitter, true
+		}
+	}
+	return submitter, found
+}
+
+// hasTokens reports whether any API tokens are configured
+func (s *Server) hasTokens() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tokens) > 0
+}
@@ -0,0 +1,298 @@
+// Squaremind dashboard: polls the stats APIs and listens on the /events stream.
+(function () {
+  'use strict';
+
+  const REFRESH_INTERVAL = 3000;
+  const MAX_EVENTS = 100;
+
+  const $ = (id) => document.getElementById(id);
+
+  const agentNames = {};
+
+  function shortSID(sid) {
+    return sid ? sid.slice(0, 12) : '';
+  }
+
+  function agentLabel(sid) {
+    return agentNames[sid] || shortSID(sid);
+  }
+
+  function el(tag, attrs, text) {
+    const node = document.createElement(tag);
+    Object.entries(attrs || {}).forEach(([k, v]) => node.setAttribute(k, v));
+    if (text !== undefined) node.textContent = text;
+    return node;
+  }
+
+  function svgEl(tag, attrs) {
+    const node = document.createElementNS('http://www.w3.org/2000/svg', tag);
+    Object.entries(attrs || {}).forEach(([k, v]) => node.setAttribute(k, v));
+    return node;
+  }
+
+  async function fetchJSON(path) {
+    const resp = await fetch(path);
+    if (!resp.ok) throw new Error(path + ': ' + resp.status);
+    return resp.json();
+  }
+
+  function renderStats(stats) {
+    $('collective-name').textContent = stats.Name;
+    $('stat-agents').textContent = stats.AgentCount;
+    $('stat-pending').textContent = stats.PendingTasks;
+    $('stat-active').textContent = stats.ActiveTasks;
+    $('stat-completed').textContent = stats.CompletedTasks;
+    $('stat-reputation').textContent = stats.AvgReputation.toFixed(1);
+  }
+
+  function renderAgents(agents) {
+    const body = $('agents');
+    body.replaceChildren();
+    agents.forEach((a) => {
+      agentNames[a.sid] = a.name;
+      const caps = Object.entries(a.capabilities || {})
+        .map(([cap, p]) => cap + ' (' + p.toFixed(2) + ')')
+        .join(', ');
+      const row = el('tr');
+      row.appendChild(el('td', { title: a.sid }, a.name));
+      row.appendChild(el('td', { class: 'state-' + a.state }, a.state));
+      row.appendChild(el('td', {}, caps));
+      row.appendChild(el('td', {}, String(a.usage.tokens_used || 0)));
+      body.appendChild(row);
+    });
+  }
+
+  function renderTaskList(id, items, describe, taskID) {
+    const list = $(id);
+    list.replaceChildren();
+    items.forEach((item) => {
+      const li = el('li', { title: describe(item) }, describe(item));
+      li.addEventListener('click', () => showTimeline(taskID(item)));
+      list.appendChild(li);
+    });
+  }
+
+  function renderTasks(tasks) {
+    renderTaskList('tasks-pending', tasks.pending, (t) => t.description, (t) => t.id);
+    renderTaskList('tasks-active', tasks.active, (t) => t.description + ' → ' + agentLabel(t.assigned_to), (t) => t.id);
+    renderTaskList('tasks-completed', tasks.completed.slice().reverse(),
+      (r) => r.status + ' · q=' + (r.quality || 0).toFixed(2) + ' · ' + agentLabel(r.agent_sid), (r) => r.task_id);
+  }
+
+  let selectedTask = null;
+
+  async function showTimeline(taskID) {
+    selectedTask = taskID;
+    const list = $('timeline');
+    $('timeline-task').textContent = 'Task ' + taskID.slice(0, 8);
+    try {
+      const data = await fetchJSON('/api/tasks/' + encodeURIComponent(taskID) + '/timeline');
+      list.replaceChildren();
+      const start = data.timeline.length ? new Date(data.timeline[0].timestamp) : null;
+      data.timeline.forEach((entry) => {
+        const at = new Date(entry.timestamp);
+        const item = el('li');
+        item.appendChild(el('span', { class: 'time' }, '+' + ((at - start) / 1000).toFixed(2) + 's'));
+        item.appendChild(el('span', { class: 'stage stage-' + entry.stage }, entry.stage));
+        const parts = [];
+        if (entry.actor) parts.push(agentNames[entry.actor] || entry.actor);
+        if (entry.detail) parts.push(entry.detail);
+        item.appendChild(document.createTextNode(parts.join(' · ')));
+        list.appendChild(item);
+      });
+    } catch (err) {
+      list.replaceChildren(el('li', {}, 'No timeline recorded for this task.'));
+    }
+    showAssignment(taskID);
+  }
+
+  // showAssignment lists a task's bids with the contribution of each scoring factor
+  async function showAssignment(taskID) {
+    const body = $('assignment');
+    const note = $('assignment-note');
+    body.replaceChildren();
+    try {
+      const data = await fetchJSON('/api/tasks/' + encodeURIComponent(taskID) + '/explain');
+      const notes = [];
+      if (data.pinned) notes.push('Pinned to ' + agentLabel(data.winner));
+      if (data.error) notes.push(data.error);
+      if (data.tie_break) {
+        notes.push('Tied with ' + agentLabel(data.tie_break.runner_up) + ' at ' + data.tie_break.score.toFixed(3) +
+          ', won on ' + data.tie_break.rule.replace('_', ' '));
+      }
+      if (data.excluded.length) {
+        notes.push('Excluded: ' + data.excluded.map((x) => (x.agent || shortSID(x.agent_sid)) + ' (' + x.reason + ')').join(', '));
+      }
+      note.textContent = notes.join(' · ');
+
+      data.bids.forEach((b) => {
+        const row = el('tr');
+        row.appendChild(el('td', {}, String(b.rank)));
+        row.appendChild(el('td', { title: b.bid.agent_sid }, b.agent || agentLabel(b.bid.agent_sid)));
+        row.appendChild(el('td', {}, b.score.toFixed(3)));
+        b.factors.forEach((f) => {
+          row.appendChild(el('td', { title: f.value.toFixed(2) + ' × ' + f.weight }, f.contribution.toFixed(3)));
+        });
+        row.appendChild(el('td', { class: 'outcome-' + b.outcome }, b.outcome));
+        body.appendChild(row);
+      });
+    } catch (err) {
+      note.textContent = 'No assignment recorded for this task.';
+    }
+  }
+
+  // sparkline reconstructs the score series by walking the deltas back from the current value
+  function sparkline(trend) {
+    const points = [trend.overall];
+    for (let i = trend.history.length - 1; i >= 0; i--) {
+      points.unshift(points[0] - trend.history[i].delta);
+    }
+    const svg = svgEl('svg', { viewBox: '0 0 100 24', preserveAspectRatio: 'none' });
+    if (points.length < 2) return svg;
+
+    const min = Math.min(...points);
+    const max = Math.max(...points);
+    const range = max - min || 1;
+    const coords = points.map((p, i) => {
+      const x = (i / (points.length - 1)) * 100;
+      const y = 22 - ((p - min) / range) * 20;
+      return x.toFixed(1) + ',' + y.toFixed(1);
+    });
+    svg.appendChild(svgEl('polyline', {
+      points: coords.join(' '),
+      fill: 'none',
+      stroke: 'currentColor',
+      'stroke-width': '1.5',
+      'vector-effect': 'non-scaling-stroke',
+    }));
+    return svg;
+  }
+
+  function renderReputation(trends) {
+    const container = $('reputation');
+    container.replaceChildren();
+    trends.forEach((t) => {
+      const row = el('div', { class: 'trend' });
+      row.appendChild(el('span', { class: 'name', title: t.agent_sid }, agentLabel(t.agent_sid)));
+      row.appendChild(sparkline(t));
+      row.appendChild(el('span', { class: 'score' }, t.overall.toFixed(1)));
+      container.appendChild(row);
+    });
+  }
+
+  function renderConsensus(data) {
+    const s = data.stats;
+    $('consensus-stats').textContent = 'pending ' + s.PendingRounds + ' · accepted ' + s.AcceptedRounds +
+      ' · rejected ' + s.RejectedRounds + ' · timeout ' + s.TimeoutRounds;
+    const body = $('consensus');
+    body.replaceChildren();
+    data.rounds.slice(0, 20).forEach((r) => {
+      const row = el('tr');
+      row.appendChild(el('td', {}, r.type));
+      row.appendChild(el('td', {}, agentLabel(r.proposer)));
+      row.appendChild(el('td', {}, r.accepts + '/' + r.votes));
+      row.appendChild(el('td', { class: 'result-' + r.result }, r.result));
+      body.appendChild(row);
+    });
+  }
+
+  // renderKnowledge lays nodes out on a circle; good enough for small graphs
+  function renderKnowledge(graph) {
+    const svg = $('knowledge');
+    svg.replaceChildren();
+    const nodes = graph.nodes || [];
+    const edges = graph.edges || [];
+    const positions = {};
+    const cx = 300, cy = 160, radius = 130;
+
+    nodes.forEach((n, i) => {
+      const angle = (2 * Math.PI * i) / Math.max(nodes.length, 1);
+      positions[n.id] = { x: cx + radius * Math.cos(angle), y: cy + radius * Math.sin(angle) };
+    });
+
+    edges.forEach((e) => {
+      const from = positions[e.from];
+      const to = positions[e.to];
+      if (!from || !to) return;
+      svg.appendChild(svgEl('line', {
+        x1: from.x, y1: from.y, x2: to.x, y2: to.y,
+        'stroke-width': Math.max(0.5, (e.weight || 0.5) * 2),
+      }));
+    });
+
+    nodes.forEach((n) => {
+      const p = positions[n.id];
+      svg.appendChild(svgEl('circle', { cx: p.x, cy: p.y, r: 5 }));
+      const label = svgEl('text', { x: p.x + 8, y: p.y + 3 });
+      label.textContent = n.label;
+      svg.appendChild(label);
+    });
+  }
+
+  async function refresh() {
+    try {
+      const [stats, agents, tasks, reputation, consensus, knowledge] = await Promise.all([
+        fetchJSON('/api/stats'),
+        fetchJSON('/api/agents'),
+        fetchJSON('/api/tasks'),
+        fetchJSON('/api/reputation'),
+        fetchJSON('/api/consensus'),
+        fetchJSON('/api/knowledge'),
+      ]);
+      renderStats(stats);
+      renderAgents(agents);
+      renderTasks(tasks);
+      if (selectedTask) showTimeline(selectedTask);
+      renderReputation(reputation);
+      renderConsensus(consensus);
+      renderKnowledge(knowledge);
+    } catch (err) {
+      console.error('refresh failed', err);
+    }
+  }
+
+  function describeEvent(e) {
+    const parts = [];
+    if (e.agent_sid) parts.push(agentLabel(e.agent_sid));
+    if (e.task_id) parts.push('task ' + e.task_id.slice(0, 8));
+    Object.entries(e.data || {}).forEach(([k, v]) => {
+      parts.push(k + '=' + (typeof v === 'number' ? v.toFixed(2) : v));
+    });
+    return parts.join(' · ');
+  }
+
+  function appendEvent(e) {
+    const list = $('events');
+    const item = el('li');
+    item.appendChild(el('span', { class: 'time' }, new Date(e.timestamp).toLocaleTimeString()));
+    item.appendChild(el('span', { class: 'type' }, e.type));
+    item.appendChild(document.createTextNode(describeEvent(e)));
+    list.prepend(item);
+    while (list.children.length > MAX_EVENTS) list.removeChild(list.lastChild);
+  }
+
+  function connect() {
+    const scheme = location.protocol === 'https:' ? 'wss://' : 'ws://';
+    const ws = new WebSocket(scheme + location.host + '/events');
+    const status = $('connection');
+
+    ws.onopen = () => {
+      status.textContent = 'live';
+      status.className = 'status online';
+    };
+    ws.onmessage = (msg) => {
+      const event = JSON.parse(msg.data);
+      appendEvent(event);
+      if (event.type !== 'bid_placed') refresh();
+    };
+    ws.onclose = () => {
+      status.textContent = 'offline';
+      status.className = 'status offline';
+      setTimeout(connect, 2000);
+    };
+  }
+
+  refresh();
+  setInterval(refresh, REFRESH_INTERVAL);
+  connect();
+})();
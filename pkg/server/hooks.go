@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+// SetTriggers delivers webhooks received at /api/hooks/{name} to a trigger
+// rules engine as workflow.EventWebhook events
+func (s *Server) SetTriggers(t *workflow.Triggers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.triggers = t
+}
+
+// handleHook accepts a webhook. The JSON body (or raw text, for other
+// content) becomes the event's payload. Requires an API token.
+func (s *Server) handleHook(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/hooks/")
+
+	s.mu.RLock()
+	triggers := s.triggers
+	s.mu.RUnlock()
+	if name == "" || strings.Contains(name, "/") || triggers == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "webhooks are disabled: no API tokens configured"})
+		return
+	}
+	submitter, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var payload interface{} = string(body)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+	}
+
+	triggers.Fire(workflow.Event{Type: workflow.EventWebhook, Data: map[string]interface{}{
+		"hook":      name,
+		"payload":   payload,
+		"submitter": submitter,
+	}})
+	writeJSON(w, http.StatusAccepted, map[string]string{"hook": name})
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// handleUsage returns the token spend attributed to agents and, with ?by=,
+// to the values of a cost tag
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.collective.UsageReport(r.URL.Query().Get("by")))
+}
+
+// handleKeys returns the usage of each credential in the collective's
+// keyring
+func (s *Server) handleKeys(w http.ResponseWriter, r *http.Request) {
+	usage := []agent.KeyUsage{}
+	if k := s.collective.GetKeyring(); k != nil {
+		usage = k.Usage()
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// handleMetrics exposes the collective's state and cost attribution in the
+// Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	stats := s.collective.Stats()
+	writeMetric(w, "squaremind_agents", "gauge", "Agents in the collective", nil, float64(stats.AgentCount))
+	writeMetric(w, "squaremind_tasks_active", "gauge", "Tasks being worked on", nil, float64(stats.ActiveTasks))
+	writeMetric(w, "squaremind_tasks_queued", "gauge", "Tasks waiting for an execution slot", nil, float64(stats.QueuedTasks))
+	writeMetric(w, "squaremind_tasks_rejected_total", "counter", "Tasks refused by a full agent queue", nil, float64(stats.RejectedTasks))
+	writeMetric(w, "squaremind_tasks_dropped_total", "counter", "Tasks evicted from full agent queues", nil, float64(stats.DroppedTasks))
+	writeMetric(w, "squaremind_results_dropped_total", "counter", "Results refused or evicted by full result channels", nil, float64(stats.DroppedResults))
+
+	usage := s.collective.UsageReport("")
+	writeMetric(w, "squaremind_tokens_total", "counter", "Tokens spent on tasks", nil, float64(usage.Tokens))
+
+	writeHeader(w, "squaremind_agent_tasks_total", "counter", "Tasks run by an agent")
+	for _, a := range usage.Agents {
+		writeSample(w, "squaremind_agent_tasks_total", []string{"agent", a.SID, "name", a.Name}, float64(a.Tasks))
+	}
+	writeHeader(w, "squaremind_agent_tokens_total", "counter", "Tokens spent on an agent's tasks")
+	for _, a := range usage.Agents {
+		writeSample(w, "squaremind_agent_tokens_total", []string{"agent", a.SID, "name", a.Name}, float64(a.Tokens))
+	}
+
+	chargeback := make(map[string][]collective.ChargebackLine, len(usage.Keys))
+	for _, key := range usage.Keys {
+		chargeback[key] = s.collective.UsageReport(key).Chargeback
+	}
+	writeHeader(w, "squaremind_chargeback_tasks_total", "counter", "Tasks charged to a value of a cost tag")
+	for _, key := range usage.Keys {
+		for _, line := range chargeback[key] {
+			writeSample(w, "squaremind_chargeback_tasks_total", []string{"tag", key, "value", line.Value}, float64(line.Tasks))
+		}
+	}
+	writeHeader(w, "squaremind_chargeback_tokens_total", "counter", "Tokens charged to a value of a cost tag")
+	for _, key := range usage.Keys {
+		for _, line := range chargeback[key] {
+			writeSample(w, "squaremind_chargeback_tokens_total", []string{"tag", key, "value", line.Value}, float64(line.Tokens))
+		}
+	}
+
+	if k := s.collective.GetKeyring(); k != nil {
+		keys := k.Usage()
+		writeHeader(w, "squaremind_key_requests_total", "counter", "Requests made with a keyring credential")
+		for _, u := range keys {
+			writeSample(w, "squaremind_key_requests_total", []string{"credential", u.Name, "kind", string(u.Kind)}, float64(u.Requests))
+		}
+		writeHeader(w, "squaremind_key_tokens_total", "counter", "Tokens spent with a keyring credential")
+		for _, u := range keys {
+			writeSample(w, "squaremind_key_tokens_total", []string{"credential", u.Name, "kind", string(u.Kind)}, float64(u.TokensUsed))
+		}
+	}
+
+	qos := s.collective.QoSStats()
+	classes := s.collective.QoSClasses()
+	writeHeader(w, "squaremind_qos_tasks_total", "counter", "Tasks finished by QoS class")
+	for _, class := range classes {
+		writeSample(w, "squaremind_qos_tasks_total", []string{"class", string(class)}, float64(qos[class].Tasks))
+	}
+	writeHeader(w, "squaremind_qos_retries_total", "counter", "Failed attempts reassigned by QoS class")
+	for _, class := range classes {
+		writeSample(w, "squaremind_qos_retries_total", []string{"class", string(class)}, float64(qos[class].Retries))
+	}
+	writeHeader(w, "squaremind_qos_late_total", "counter", "Tasks that missed their class's latency target")
+	for _, class := range classes {
+		writeSample(w, "squaremind_qos_late_total", []string{"class", string(class)}, float64(qos[class].Late))
+	}
+}
+
+// writeMetric writes a metric with a single sample
+func writeMetric(w io.Writer, name, kind, help string, labels []string, value float64) {
+	writeHeader(w, name, kind, help)
+	writeSample(w, name, labels, value)
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeSample writes one sample; labels alternate names and values
+func writeSample(w io.Writer, name string, labels []string, value float64) {
+	fmt.Fprintf(w, "%s%s %g\n", name, formatLabels(labels), value)
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels formats alternating label names and values as {a="1",b="2"}
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
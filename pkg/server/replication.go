@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// SetStandby serves a standby's status and promotion at /api/standby
+func (s *Server) SetStandby(standby *collective.Standby) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.standby = standby
+}
+
+// handleReplication returns the collective's state for its standbys
+func (s *Server) handleReplication(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "replication is disabled: no API tokens configured"})
+		return
+	}
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.collective.Replicate())
+}
+
+// fenceRequest is the body of POST /api/replication/fence
+type fenceRequest struct {
+	Epoch uint64 `json:"epoch"`
+	By    string `json:"by"`
+}
+
+// handleFence fences the collective off after a standby's promotion. A
+// stale epoch is a conflict.
+func (s *Server) handleFence(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "replication is disabled: no API tokens configured"})
+		return
+	}
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	var req fenceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if err := s.collective.Fence(req.Epoch, req.By); err != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.collective.Mode())
+}
+
+// handleStandby returns the standby's replication and promotion state
+func (s *Server) handleStandby(w http.ResponseWriter, r *http.Request) {
+	standby := s.getStandby()
+	if standby == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not a standby"})
+		return
+	}
+	writeJSON(w, http.StatusOK, standby.Status())
+}
+
+// promoteRequest is the body of POST /api/standby/promote
+type promoteRequest struct {
+	Reason string `json:"reason"`
+	Force  bool   `json:"force"`
+}
+
+// handlePromote proposes promoting the standby. A live primary, a
+// rejected vote or an earlier promotion is a conflict.
+func (s *Server) handlePromote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	standby := s.getStandby()
+	if standby == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not a standby"})
+		return
+	}
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "promotion is disabled: no API tokens configured"})
+		return
+	}
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	var req promoteRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	promotion, err := standby.Promote(r.Context(), collective.PromoteOptions{Reason: req.Reason, Force: req.Force})
+	switch {
+	case errors.Is(err, collective.ErrPromotionRejected):
+		writeJSON(w, http.StatusConflict, promotion)
+	case errors.Is(err, collective.ErrPrimaryAlive),
+		errors.Is(err, collective.ErrAlreadyPromoted),
+		errors.Is(err, collective.ErrPromotionInProgress),
+		errors.Is(err, collective.ErrNotReplicatedYet):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, promotion)
+	}
+}
+
+func (s *Server) getStandby() *collective.Standby {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.standby
+}
+
+// ReplicationClient is a collective.ReplicationSource replicating the
+// collective of another server
+type ReplicationClient struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewReplicationClient creates a client for the primary served at url
+func NewReplicationClient(url, token string) *ReplicationClient {
+	return &ReplicationClient{
+		URL:    strings.TrimRight(url, "/"),
+		Token:  token,
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Fetch returns the primary's state
+func (c *ReplicationClient) Fetch(ctx context.Context) (*collective.Replica, error) {
+	var replica collective.Replica
+	if err := c.do(ctx, http.MethodGet, "/api/replication", nil, &replica); err != nil {
+		return nil, err
+	}
+	return &replica, nil
+}
+
+// Fence tells the primary it was superseded at epoch. A primary already
+// past it fails with collective.ErrStaleEpoch.
+func (c *ReplicationClient) Fence(ctx context.Context, epoch uint64, by string) error {
+	return c.do(ctx, http.MethodPost, "/api/replication/fence", fenceRequest{Epoch: epoch, By: by}, nil)
+}
+
+// do sends a request to the primary and decodes its response into out
+func (c *ReplicationClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.URL+path, reader)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		if resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("%w: %s", collective.ErrStaleEpoch, apiErr.Error)
+		}
+		return fmt.Errorf("primary returned %s: %s", resp.Status, apiErr.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,496 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+var (
+	ErrInvalidSpec    = errors.New("invalid resource spec")
+	ErrNoAgentFactory = errors.New("agent resources are disabled: no agent factory set")
+)
+
+// Kinds of resource managed under /api/resources
+const (
+	KindAgents  = "agents"
+	KindTeams   = "teams"
+	KindRoutes  = "routes"
+	KindBudgets = "budgets"
+)
+
+// Resource is a declaratively managed object of the collective. Its ID is
+// chosen by the client and stays the same across updates; Version changes
+// whenever the spec does and is the resource's ETag.
+type Resource struct {
+	Kind      string          `json:"kind"`
+	ID        string          `json:"id"`
+	Version   uint64          `json:"version"`
+	Spec      json.RawMessage `json:"spec"`
+	Status    interface{}     `json:"status,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+
+	ref string // Agents: the SID of the agent currently realizing the resource
+}
+
+// etag returns the resource's entity tag
+func (r *Resource) etag() string {
+	return `"` + strconv.FormatUint(r.Version, 10) + `"`
+}
+
+// AgentSpec declares an agent. Changing it replaces the agent with a new
+// one (and a new SID) that keeps the old one's team memberships.
+type AgentSpec struct {
+	Capabilities []identity.CapabilityType `json:"capabilities"`
+	Model        string                    `json:"model,omitempty"`
+	Labels       agent.Labels              `json:"labels,omitempty"`
+	CostTags     agent.Labels              `json:"cost_tags,omitempty"`
+	SystemPrompt string                    `json:"system_prompt,omitempty"`
+	Temperature  float64                   `json:"temperature,omitempty"`
+	MaxTokens    int                       `json:"max_tokens,omitempty"`
+}
+
+// TeamSpec declares a team and its members, given as agent resource IDs or
+// agent SIDs
+type TeamSpec struct {
+	ConsensusThreshold float64                   `json:"consensus_threshold,omitempty"`
+	AssignmentMode     collective.AssignmentMode `json:"assignment_mode,omitempty"`
+	Members            []string                  `json:"members,omitempty"`
+}
+
+// RouteSpec declares a routing rule; see collective.RoutingRule
+type RouteSpec struct {
+	Team         string                    `json:"team"`
+	Capabilities []identity.CapabilityType `json:"capabilities,omitempty"`
+	Submitter    string                    `json:"submitter,omitempty"`
+	QoS          agent.QoSClass            `json:"qos,omitempty"`
+	Priority     int                       `json:"priority,omitempty"`
+}
+
+// BudgetSpec declares a token budget; see collective.Budget
+type BudgetSpec struct {
+	Tokens    int    `json:"tokens"`
+	Submitter string `json:"submitter,omitempty"`
+	Team      string `json:"team,omitempty"`
+}
+
+// AgentFactory creates the agent an agent resource declares
+type AgentFactory func(id string, spec AgentSpec) (*agent.Agent, error)
+
+// SetAgentFactory enables agent resources
+func (s *Server) SetAgentFactory(f AgentFactory) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.agentFactory = f
+}
+
+// resourceStore holds the declared resources. Changes are applied to the
+// collective one at a time, under mu.
+type resourceStore struct {
+	mu sync.Mutex
+
+	items   map[string]map[string]*Resource // Kind -> ID -> resource
+	version uint64                          // Last version handed out; versions are never reused
+}
+
+func newResourceStore() *resourceStore {
+	return &resourceStore{items: map[string]map[string]*Resource{
+		KindAgents:  {},
+		KindTeams:   {},
+		KindRoutes:  {},
+		KindBudgets: {},
+	}}
+}
+
+// handleResources serves /api/resources/{kind} and /api/resources/{kind}/{id}:
+// GET lists or reads resources; PUT creates or updates one and DELETE
+// removes it, both idempotent and honoring If-Match and If-None-Match
+func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/resources/"), "/")
+	kind, id, _ := strings.Cut(rest, "/")
+	if _, ok := s.resources.items[kind]; !ok || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if id == "" {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, s.listResources(kind))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		res, ok := s.getResource(kind, id)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": kind + " " + id + " not found"})
+			return
+		}
+		w.Header().Set("ETag", res.etag())
+		writeJSON(w, http.StatusOK, res)
+	case http.MethodPut, http.MethodDelete:
+		if !s.hasTokens() {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "resource management is disabled: no API tokens configured"})
+			return
+		}
+		if _, ok := s.authenticate(r); !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+			return
+		}
+		if r.Method == http.MethodPut {
+			s.putResource(w, r, kind, id)
+		} else {
+			s.deleteResource(w, r, kind, id)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// listResources returns a kind's resources, by ID
+func (s *Server) listResources(kind string) []*Resource {
+	s.resources.mu.Lock()
+	defer s.resources.mu.Unlock()
+
+	list := make([]*Resource, 0, len(s.resources.items[kind]))
+	for _, res := range s.resources.items[kind] {
+		list = append(list, s.withStatus(res))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// getResource returns a resource with its current status
+func (s *Server) getResource(kind, id string) (*Resource, bool) {
+	s.resources.mu.Lock()
+	defer s.resources.mu.Unlock()
+
+	res, ok := s.resources.items[kind][id]
+	if !ok {
+		return nil, false
+	}
+	return s.withStatus(res), true
+}
+
+// putResource creates or updates a resource. Every PUT brings the collective
+// in line with the spec, repairing drift; the version changes only when the
+// spec does.
+func (s *Server) putResource(w http.ResponseWriter, r *http.Request, kind, id string) {
+	body, err := decodeSpec(kind, http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.resources.mu.Lock()
+	defer s.resources.mu.Unlock()
+
+	existing := s.resources.items[kind][id]
+	if status, msg := checkPreconditions(r, existing); status != 0 {
+		writeJSON(w, status, map[string]string{"error": msg})
+		return
+	}
+
+	ref, err := s.applyResource(kind, id, body, existing)
+	switch {
+	case errors.Is(err, ErrInvalidSpec):
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, ErrNoAgentFactory):
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	res := existing
+	if res == nil {
+		status = http.StatusCreated
+		res = &Resource{Kind: kind, ID: id}
+		s.resources.items[kind][id] = res
+	}
+	res.ref = ref
+	if status == http.StatusCreated || !bytes.Equal(res.Spec, body) {
+		s.resources.version++
+		res.Version = s.resources.version
+		res.Spec = body
+		res.UpdatedAt = time.Now()
+	}
+
+	w.Header().Set("ETag", res.etag())
+	writeJSON(w, status, s.withStatus(res))
+}
+
+// deleteResource removes a resource and what it created. Deleting a
+// resource that doesn't exist succeeds, unless If-Match expected it to.
+func (s *Server) deleteResource(w http.ResponseWriter, r *http.Request, kind, id string) {
+	s.resources.mu.Lock()
+	defer s.resources.mu.Unlock()
+
+	existing := s.resources.items[kind][id]
+	if status, msg := checkPreconditions(r, existing); status != 0 {
+		writeJSON(w, status, map[string]string{"error": msg})
+		return
+	}
+	if existing != nil {
+		if err := s.removeResource(kind, id, existing); err != nil {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		delete(s.resources.items[kind], id)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPreconditions evaluates If-Match and If-None-Match against the
+// resource (nil if it doesn't exist), returning 412 and why if they fail
+func checkPreconditions(r *http.Request, existing *Resource) (int, string) {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if existing == nil {
+			return http.StatusPreconditionFailed, "If-Match given but the resource does not exist"
+		}
+		if !etagMatches(match, existing.etag()) {
+			return http.StatusPreconditionFailed, "resource has changed: version is " + existing.etag()
+		}
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && existing != nil {
+		if etagMatches(noneMatch, existing.etag()) {
+			return http.StatusPreconditionFailed, "resource already exists"
+		}
+	}
+	return 0, ""
+}
+
+// etagMatches reports whether an If-Match style header lists etag or is *
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeSpec validates a spec of the given kind and returns it re-encoded,
+// so specs that differ only in formatting compare equal
+func decodeSpec(kind string, body io.Reader) (json.RawMessage, error) {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	var spec interface{}
+	switch kind {
+	case KindAgents:
+		spec = &AgentSpec{}
+	case KindTeams:
+		spec = &TeamSpec{}
+	case KindRoutes:
+		spec = &RouteSpec{}
+	case KindBudgets:
+		spec = &BudgetSpec{}
+	}
+	if err := dec.Decode(spec); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return json.Marshal(spec)
+}
+
+// applyResource makes the collective match a resource's spec. Returns the
+// SID realizing an agent resource.
+func (s *Server) applyResource(kind, id string, body json.RawMessage, existing *Resource) (string, error) {
+	c := s.collective
+	switch kind {
+	case KindAgents:
+		var spec AgentSpec
+		_ = json.Unmarshal(body, &spec)
+		return s.applyAgent(id, spec, body, existing)
+
+	case KindTeams:
+		var spec TeamSpec
+		_ = json.Unmarshal(body, &spec)
+		return "", s.applyTeam(id, spec)
+
+	case KindRoutes:
+		var spec RouteSpec
+		_ = json.Unmarshal(body, &spec)
+		if spec.Team == "" {
+			return "", fmt.Errorf("%w: team is required", ErrInvalidSpec)
+		}
+		return "", c.SetRoutingRule(collective.RoutingRule{
+			Name:         id,
+			Team:         spec.Team,
+			Capabilities: spec.Capabilities,
+			Submitter:    spec.Submitter,
+			QoS:          spec.QoS,
+			Priority:     spec.Priority,
+		})
+
+	case KindBudgets:
+		var spec BudgetSpec
+		_ = json.Unmarshal(body, &spec)
+		if spec.Tokens <= 0 {
+			return "", fmt.Errorf("%w: tokens must be positive", ErrInvalidSpec)
+		}
+		return "", c.SetBudget(collective.Budget{Name: id, Tokens: spec.Tokens, Submitter: spec.Submitter, Team: spec.Team})
+	}
+	return "", nil
+}
+
+// applyAgent creates the agent a resource declares, or replaces it if its
+// spec changed or it has left the collective
+func (s *Server) applyAgent(id string, spec AgentSpec, body json.RawMessage, existing *Resource) (string, error) {
+	c := s.collective
+	if existing != nil && bytes.Equal(existing.Spec, body) {
+		if _, ok := c.GetAgent(existing.ref); ok {
+			return existing.ref, nil
+		}
+	}
+
+	s.mu.RLock()
+	factory := s.agentFactory
+	s.mu.RUnlock()
+	if factory == nil {
+		return "", ErrNoAgentFactory
+	}
+	a, err := factory(id, spec)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+	if err := c.Join(a); err != nil {
+		return "", err
+	}
+
+	// The replacement takes over the old agent's teams
+	if existing != nil && existing.ref != "" {
+		for _, team := range c.Teams() {
+			if team.Has(existing.ref) {
+				_ = c.AddToTeam(team.Name, a.Identity.SID)
+			}
+		}
+		_ = c.Leave(existing.ref)
+	}
+	return a.Identity.SID, nil
+}
+
+// applyTeam creates or updates a team and sets its members
+func (s *Server) applyTeam(name string, spec TeamSpec) error {
+	c := s.collective
+	if spec.ConsensusThreshold < 0 || spec.ConsensusThreshold > 1 {
+		return fmt.Errorf("%w: consensus_threshold must be between 0 and 1", ErrInvalidSpec)
+	}
+	switch spec.AssignmentMode {
+	case "", collective.AssignmentMarket, collective.AssignmentConsensus:
+	default:
+		return fmt.Errorf("%w: unknown assignment_mode %q", ErrInvalidSpec, spec.AssignmentMode)
+	}
+
+	// Resolve members before changing anything
+	want := make(map[string]bool, len(spec.Members))
+	for _, member := range spec.Members {
+		if res, ok := s.resources.items[KindAgents][member]; ok {
+			want[res.ref] = true
+		} else if _, ok := c.GetAgent(member); ok {
+			want[member] = true
+		} else {
+			return fmt.Errorf("%w: unknown agent %s", ErrInvalidSpec, member)
+		}
+	}
+
+	cfg := collective.TeamConfig{ConsensusThreshold: spec.ConsensusThreshold, AssignmentMode: spec.AssignmentMode}
+	team, err := c.UpdateTeam(name, cfg)
+	if errors.Is(err, collective.ErrTeamNotFound) {
+		team, err = c.CreateTeam(name, cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, sid := range team.Members() {
+		if !want[sid] {
+			_ = c.RemoveFromTeam(name, sid)
+		}
+	}
+	for sid := range want {
+		if err := c.AddToTeam(name, sid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeResource undoes what a resource created
+func (s *Server) removeResource(kind, id string, res *Resource) error {
+	c := s.collective
+	var err error
+	switch kind {
+	case KindAgents:
+		err = c.Leave(res.ref)
+		if errors.Is(err, collective.ErrAgentNotFound) {
+			err = nil
+		}
+	case KindTeams:
+		err = c.RemoveTeam(id)
+		if errors.Is(err, collective.ErrTeamNotFound) {
+			err = nil
+		}
+	case KindRoutes:
+		err = c.RemoveRoutingRule(id)
+		if errors.Is(err, collective.ErrRouteNotFound) {
+			err = nil
+		}
+	case KindBudgets:
+		err = c.RemoveBudget(id)
+		if errors.Is(err, collective.ErrBudgetNotFound) {
+			err = nil
+		}
+	}
+	return err
+}
+
+// withStatus returns a copy of a resource with its current state in the
+// collective. Caller must hold s.resources.mu.
+func (s *Server) withStatus(res *Resource) *Resource {
+	out := *res
+	c := s.collective
+	switch res.Kind {
+	case KindAgents:
+		status := map[string]interface{}{"sid": res.ref, "state": "missing"}
+		if a, ok := c.GetAgent(res.ref); ok {
+			status["state"] = a.GetState()
+		}
+		out.Status = status
+	case KindTeams:
+		if team, ok := c.GetTeam(res.ID); ok {
+			out.Status = map[string]interface{}{"members": team.Members()}
+		} else {
+			out.Status = map[string]interface{}{"members": nil, "missing": true}
+		}
+	case KindBudgets:
+		for _, b := range c.Budgets() {
+			if b.Name == res.ID {
+				out.Status = map[string]interface{}{"used": b.Used, "remaining": max(b.Tokens-b.Used, 0)}
+			}
+		}
+	}
+	return &out
+}
@@ -0,0 +1,690 @@
+package server
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/incident"
+	"github.com/square-mind/squaremind/pkg/storage"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+// completedTaskLimit bounds how many recent results /api/tasks returns
+const completedTaskLimit = 50
+
+// ProtocolVersion is the version of the HTTP API, reported by /healthz. It
+// changes when an endpoint changes incompatibly, so clients can tell they are
+// talking to a daemon they don't understand.
+const ProtocolVersion = 1
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// Server exposes a running collective over HTTP
+type Server struct {
+	mu sync.RWMutex
+
+	collective *collective.Collective
+	mux        *http.ServeMux
+	tokens     []APIToken
+	inbox      *workflow.Inbox
+	triggers   *workflow.Triggers
+	version    string
+
+	// Declaratively managed agents, teams, routing rules and budgets
+	resources    *resourceStore
+	agentFactory AgentFactory
+
+	// Incident captures served at /api/incident (nil = without logs or events)
+	incidents *incident.Capturer
+
+	// Standby served at /api/standby (nil = the collective isn't one)
+	standby *collective.Standby
+}
+
+// New creates a server for a collective
+func New(c *collective.Collective) *Server {
+	s := &Server{
+		collective: c,
+		mux:        http.NewServeMux(),
+		resources:  newResourceStore(),
+	}
+
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/api/stats", s.handleStats)
+	s.mux.HandleFunc("/api/mode", s.handleMode)
+	s.mux.HandleFunc("/api/parameters", s.handleParameters)
+	s.mux.HandleFunc("/api/agents", s.handleAgents)
+	s.mux.HandleFunc("/api/agents/", s.handleAgent)
+	s.mux.HandleFunc("/api/tasks", s.handleTasks)
+	s.mux.HandleFunc("/api/tasks/", s.handleTask)
+	s.mux.HandleFunc("/api/artifacts/", s.handleArtifact)
+	s.mux.HandleFunc("/api/knowledge", s.handleKnowledge)
+	s.mux.HandleFunc("/api/reputation", s.handleReputation)
+	s.mux.HandleFunc("/api/consensus", s.handleConsensus)
+	s.mux.HandleFunc("/api/attestations/", s.handleAttestation)
+	s.mux.HandleFunc("/api/gaps", s.handleGaps)
+	s.mux.HandleFunc("/api/usage", s.handleUsage)
+	s.mux.HandleFunc("/api/keys", s.handleKeys)
+	s.mux.HandleFunc("/api/digest", s.handleDigest)
+	s.mux.HandleFunc("/api/incident", s.handleIncident)
+	s.mux.HandleFunc("/api/snapshot", s.handleSnapshot)
+	s.mux.HandleFunc("/api/replication", s.handleReplication)
+	s.mux.HandleFunc("/api/replication/fence", s.handleFence)
+	s.mux.HandleFunc("/api/standby", s.handleStandby)
+	s.mux.HandleFunc("/api/standby/promote", s.handlePromote)
+	s.mux.HandleFunc("/api/approvals", s.handleApprovals)
+	s.mux.HandleFunc("/api/approvals/", s.handleApproval)
+	s.mux.HandleFunc("/api/hooks/", s.handleHook)
+	s.mux.HandleFunc("/api/resources/", s.handleResources)
+	s.mux.HandleFunc("/events", s.handleEvents)
+
+	dashboard, _ := fs.Sub(dashboardFiles, "dashboard")
+	s.mux.Handle("/", http.FileServer(http.FS(dashboard)))
+
+	return s
+}
+
+// SetVersion sets the software version reported by /healthz
+func (s *Server) SetVersion(version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+}
+
+// SetIncidentCapturer sets the capturer behind /api/incident
+func (s *Server) SetIncidentCapturer(k *incident.Capturer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.incidents = k
+}
+
+// Handle serves an extra handler, such as an integration's webhook, at
+// pattern. The handler does its own authentication.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+// Handler returns the server's HTTP handler
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves on addr until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return httpServer.Shutdown(shutdownCtx)
+	case err := <-errChan:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	}
+}
+
+// handleHealth reports liveness and the collective's health score
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	version := s.version
+	s.mu.RUnlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":   "ok",
+		"version":  version,
+		"protocol": ProtocolVersion,
+		"agents":   s.collective.Size(),
+		"health":   s.collective.Health(),
+	})
+}
+
+// handleStats returns collective statistics
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.collective.Stats())
+}
+
+// modeRequest is the body of a run mode change
+type modeRequest struct {
+	Mode   collective.RunMode `json:"mode"`
+	Reason string             `json:"reason,omitempty"`
+}
+
+// handleMode returns the collective's run mode, or on PUT pauses, resumes,
+// drains or puts it in maintenance
+func (s *Server) handleMode(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, http.StatusOK, s.collective.Mode())
+		return
+	case http.MethodPut:
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "mode changes are disabled: no API tokens configured"})
+		return
+	}
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	var req modeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	switch req.Mode {
+	case collective.ModeRunning:
+		s.collective.Resume()
+	case collective.ModePaused:
+		s.collective.Pause(req.Reason)
+	case collective.ModeMaintenance:
+		s.collective.EnterMaintenance(req.Reason)
+	case collective.ModeDraining:
+		s.collective.Drain(req.Reason)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unknown mode " + strconv.Quote(string(req.Mode)) + " (want running, paused, maintenance or draining)"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.collective.Mode())
+}
+
+// parametersRequest is the body of a parameter change proposal. Unset
+// fields are left as they are.
+type parametersRequest struct {
+	ConsensusThreshold float64 `json:"consensus_threshold,omitempty"`
+	MaxAgents          int     `json:"max_agents,omitempty"`
+	BidTimeout         string  `json:"bid_timeout,omitempty"` // e.g. "10s"
+	MinBidScore        float64 `json:"min_bid_score,omitempty"`
+}
+
+// handleParameters returns the collective's consensus-gated parameters, or
+// on POST proposes a change to them, proposed by the token's submitter
+func (s *Server) handleParameters(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, http.StatusOK, s.collective.Parameters())
+		return
+	case http.MethodPost:
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "parameter changes are disabled: no API tokens configured"})
+		return
+	}
+	proposer, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	var req parametersRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	change := collective.Parameters{ConsensusThreshold: req.ConsensusThreshold, MaxAgents: req.MaxAgents, MinBidScore: req.MinBidScore}
+	if req.BidTimeout != "" {
+		d, err := time.ParseDuration(req.BidTimeout)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid bid_timeout: " + err.Error()})
+			return
+		}
+		change.BidTimeout = d
+	}
+
+	proposal, err := s.collective.ProposeParameters(r.Context(), proposer, change)
+	switch {
+	case errors.Is(err, collective.ErrInvalidParameter):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, collective.ErrParameterRejected):
+		writeJSON(w, http.StatusConflict, proposal)
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, proposal)
+	}
+}
+
+// handleTasks returns the pending, active and recently completed tasks, or
+// submits a task on POST
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		writeJSON(w, http.StatusOK, s.collective.TaskSnapshot(completedTaskLimit))
+	case http.MethodPost:
+		s.submitTask(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// submitRequest is the body of POST /api/tasks
+type submitRequest struct {
+	Description  string                    `json:"description"`
+	Requirements string                    `json:"requirements,omitempty"`
+	Required     []identity.CapabilityType `json:"required_capabilities,omitempty"`
+	Complexity   string                    `json:"complexity,omitempty"`
+	Reward       float64                   `json:"reward,omitempty"`
+	Priority     *int                      `json:"priority,omitempty"` // Default agent.PriorityNormal
+	QoS          agent.QoSClass            `json:"qos,omitempty"`      // interactive, standard (default) or batch
+	Team         string                    `json:"team,omitempty"`
+	Placement    []agent.Constraint        `json:"placement,omitempty"`     // e.g. ["region=eu", "gpu"]
+	Steps        []string                  `json:"steps,omitempty"`         // Multi-step task, performed as one conversation
+	Auction      string                    `json:"auction,omitempty"`       // weighted, sealed_bid, vickrey or reverse (default: the collective's)
+	CostTags     agent.Labels              `json:"cost_tags,omitempty"`     // Cost attribution labels, e.g. {"cost-center": "ml"}
+	Author       string                    `json:"author,omitempty"`        // SID of the agent whose work the task reviews
+	SystemPrompt string                    `json:"system_prompt,omitempty"` // Replaces the agent's role instructions
+	Temperature  *float64                  `json:"temperature,omitempty"`   // 0 included (default: the agent's)
+	MaxTokens    int                       `json:"max_tokens,omitempty"`    // Limit on the response (default: the agent's)
+	Metadata     map[string]string         `json:"metadata,omitempty"`      // Settings for integrations, e.g. {"repo": "acme/widgets"}
+	Hints        *agent.RoutingHints       `json:"hints,omitempty"`         // Preferred, banned and nearby agents, weighed in bid scores
+}
+
+// submitTask queues a task for the submitter identified by the request's API token
+func (s *Server) submitTask(w http.ResponseWriter, r *http.Request) {
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "task submission is disabled: no API tokens configured"})
+		return
+	}
+	submitter, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	var req submitRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "description is required"})
+		return
+	}
+	if (req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2)) || req.MaxTokens < 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "temperature must be between 0 and 2 and max_tokens positive"})
+		return
+	}
+	if req.Auction != "" {
+		if _, err := coordination.NewAuctionStrategy(req.Auction); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if req.Hints != nil {
+		if err := req.Hints.Validate(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if _, ok := s.collective.QoSPolicies()[req.QoS]; req.QoS != "" && !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": collective.ErrUnknownQoSClass.Error() + ": " + string(req.QoS)})
+		return
+	}
+
+	task := agent.NewTask(req.Description, req.Required).
+		WithRequirements(req.Requirements).
+		WithReward(req.Reward).
+		WithTeam(req.Team).
+		WithSubmitter(submitter).
+		WithPlacement(req.Placement...).
+		WithAuction(req.Auction).
+		WithCostTags(req.CostTags).
+		WithSystemPrompt(req.SystemPrompt).
+		WithMaxTokens(req.MaxTokens).
+		WithQoS(req.QoS).
+		WithAuthor(req.Author).
+		WithMetadata(req.Metadata)
+	task.Steps = req.Steps
+	if req.Temperature != nil {
+		task.WithTemperature(*req.Temperature)
+	}
+	if req.Hints != nil {
+		task.WithHints(*req.Hints)
+	}
+	if req.Complexity != "" {
+		task.WithComplexity(req.Complexity)
+	}
+	if req.Priority != nil {
+		task.WithPriority(*req.Priority)
+	}
+
+	id, err := s.collective.SubmitAsync(task)
+	if errors.Is(err, collective.ErrDraining) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"task_id": id, "submitter": submitter})
+}
+
+// handleTask serves /api/tasks/{id}/timeline and /api/tasks/{id}/explain
+func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/tasks/")
+	taskID, view, ok := strings.Cut(rest, "/")
+	if !ok || taskID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch view {
+	case "timeline":
+		s.handleTaskTimeline(w, taskID)
+	case "explain":
+		s.handleTaskExplain(w, taskID)
+	case "artifact":
+		s.handleTaskArtifact(w, r, taskID)
+	case "artifact-url":
+		s.handleTaskArtifactURL(w, r, taskID)
+	case "artifacts":
+		s.handleTaskArtifacts(w, r, taskID)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleTaskTimeline returns a task's journey through the collective
+func (s *Server) handleTaskTimeline(w http.ResponseWriter, taskID string) {
+	timeline, found := s.collective.Timeline(taskID)
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no timeline for task " + taskID})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task_id":  taskID,
+		"timeline": timeline,
+	})
+}
+
+// handleTaskExplain returns how a task's assignment was decided
+func (s *Server) handleTaskExplain(w http.ResponseWriter, taskID string) {
+	explanation, found := s.collective.ExplainAssignment(taskID)
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no assignment recorded for task " + taskID})
+		return
+	}
+	writeJSON(w, http.StatusOK, explanation)
+}
+
+// handleTaskArtifact streams a finished task's stored output
+func (s *Server) handleTaskArtifact(w http.ResponseWriter, r *http.Request, taskID string) {
+	artifact, err := s.collective.Artifact(r.Context(), taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no artifact stored for task " + taskID})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	defer artifact.Close()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.Copy(w, artifact)
+}
+
+// handleTaskArtifacts lists the content-addressed artifacts stored for a
+// finished task
+func (s *Server) handleTaskArtifacts(w http.ResponseWriter, r *http.Request, taskID string) {
+	artifacts, err := s.collective.TaskArtifacts(r.Context(), taskID)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no result stored for task " + taskID})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if artifacts == nil {
+		artifacts = []agent.Artifact{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"task_id":   taskID,
+		"artifacts": artifacts,
+	})
+}
+
+// handleArtifact streams stored content by digest at
+// /api/artifacts/sha256:<hex>. Content never changes, so it may be cached
+// indefinitely.
+func (s *Server) handleArtifact(w http.ResponseWriter, r *http.Request) {
+	digest := strings.TrimPrefix(r.URL.Path, "/api/artifacts/")
+	artifact, err := s.collective.OpenArtifact(r.Context(), digest)
+	switch {
+	case errors.Is(err, storage.ErrInvalidKey):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, storage.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no artifact stored with digest " + digest})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	defer artifact.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", `"`+digest+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	_, _ = io.Copy(w, artifact)
+}
+
+// handleIncident captures an incident bundle and returns it as a gzipped
+// tar archive. Bundles are redacted but still describe the deployment in
+// detail, so an API token is required.
+func (s *Server) handleIncident(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "incident capture is disabled: no API tokens configured"})
+		return
+	}
+	submitter, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	s.mu.RLock()
+	capturer := s.incidents
+	s.mu.RUnlock()
+	if capturer == nil {
+		capturer = incident.NewCapturer(s.collective, nil)
+	}
+
+	reason := r.URL.Query().Get("reason")
+	if reason == "" {
+		reason = "requested by " + submitter
+	}
+	bundle := capturer.Capture(reason)
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+bundle.FileName()+`"`)
+	_, _ = bundle.WriteTo(w)
+}
+
+// defaultArtifactURLExpiry is how long an artifact URL stays valid unless
+// the request sets expires
+const defaultArtifactURLExpiry = 24 * time.Hour
+
+// handleTaskArtifactURL issues a signed URL for a task's artifact, valid for
+// the optional expires parameter (default 24h). A signed URL grants access
+// to anyone holding it, so issuing one takes an API token.
+func (s *Server) handleTaskArtifactURL(w http.ResponseWriter, r *http.Request, taskID string) {
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "artifact sharing is disabled: no API tokens configured"})
+		return
+	}
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+	expires := defaultArtifactURLExpiry
+	if param := r.URL.Query().Get("expires"); param != "" {
+		d, err := time.ParseDuration(param)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid expires: " + err.Error()})
+			return
+		}
+		expires = d
+	}
+
+	u, err := s.collective.ArtifactURL(r.Context(), taskID, expires)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no artifact stored for task " + taskID})
+	case errors.Is(err, storage.ErrNoSignedURLs):
+		writeJSON(w, http.StatusNotImplemented, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"task_id":    taskID,
+			"url":        u,
+			"expires_at": time.Now().Add(expires),
+		})
+	}
+}
+
+// handleKnowledge returns the collective knowledge graph
+func (s *Server) handleKnowledge(w http.ResponseWriter, r *http.Request) {
+	nodes, edges := s.collective.GetMemory().KnowledgeSnapshot()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"nodes": nodes,
+		"edges": edges,
+	})
+}
+
+// handleReputation returns per-agent reputation trends
+func (s *Server) handleReputation(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.collective.GetReputation().Trends())
+}
+
+// handleConsensus returns consensus statistics and recent rounds
+func (s *Server) handleConsensus(w http.ResponseWriter, r *http.Request) {
+	consensus := s.collective.GetConsensus()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"stats":  consensus.Stats(),
+		"rounds": consensus.Summaries(),
+	})
+}
+
+// handleGaps returns the collective's capability gap report. The optional
+// window parameter (e.g. 30m) sets how far back auctions are considered.
+func (s *Server) handleGaps(w http.ResponseWriter, r *http.Request) {
+	cfg := collective.GapConfig{}
+	if window := r.URL.Query().Get("window"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid window: " + err.Error()})
+			return
+		}
+		cfg.Window = d
+	}
+	writeJSON(w, http.StatusOK, s.collective.CapabilityGaps(cfg))
+}
+
+// handleDigest returns the digest of the tasks finished in the last day,
+// or the last week with period=weekly
+func (s *Server) handleDigest(w http.ResponseWriter, r *http.Request) {
+	period := collective.DigestDaily
+	if p := r.URL.Query().Get("period"); p != "" {
+		var err error
+		if period, err = collective.ParseDigestPeriod(p); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s.collective.Digest(period, time.Time{}))
+}
+
+// handleEvents streams collective events to a WebSocket client as JSON text frames
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := s.collective.SubscribeEvents()
+	defer unsubscribe()
+
+	disconnected := make(chan struct{})
+	go func() {
+		conn.ReadLoop()
+		close(disconnected)
+	}()
+
+	for {
+		select {
+		case <-disconnected:
+			return
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if err := conn.WriteText(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeJSON writes a JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,1359 @@
+package server
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/storage"
+	sqmtest "github.com/square-mind/squaremind/pkg/testing"
+	"github.com/square-mind/squaremind/pkg/workflow"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	got := acceptKey("dGhlIHNhbXBsZSBub25jZQ==")
+	if got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Expected s3pPLMBiTxaQ9kYGzzhZRbK+xOo=, got %s", got)
+	}
+}
+
+func TestServer_Health(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	s := New(c)
+	s.SetVersion("1.2.3")
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Version  string `json:"version"`
+		Protocol int    `json:"protocol"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Version != "1.2.3" {
+		t.Errorf("Expected version 1.2.3, got %q", body.Version)
+	}
+	if body.Protocol != ProtocolVersion {
+		t.Errorf("Expected protocol %d, got %d", ProtocolVersion, body.Protocol)
+	}
+}
+
+func TestServer_Dashboard(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("Request for %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_AgentsAPI(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a)
+
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/agents")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var agents []collective.AgentSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&agents); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+
+	if len(agents) != 1 {
+		t.Fatalf("Expected 1 agent, got %d", len(agents))
+	}
+	if agents[0].Name != "Agent1" {
+		t.Errorf("Expected agent name 'Agent1', got '%s'", agents[0].Name)
+	}
+	if agents[0].Capabilities[identity.CapCodeWrite] != 0.5 {
+		t.Errorf("Expected code_write proficiency 0.5, got %f", agents[0].Capabilities[identity.CapCodeWrite])
+	}
+}
+
+func TestServer_TaskTimeline(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	c.RecordTimeline("task-1", collective.StageSubmitted, "", "Write docs")
+	c.RecordTimeline("task-1", collective.StageReview, "reviewer", "approved")
+
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/tasks/task-1/timeline")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		TaskID   string                     `json:"task_id"`
+		Timeline []collective.TimelineEntry `json:"timeline"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(body.Timeline) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(body.Timeline))
+	}
+	if body.Timeline[1].Stage != collective.StageReview {
+		t.Errorf("Expected stage 'review', got '%s'", body.Timeline[1].Stage)
+	}
+
+	missing, err := http.Get(srv.URL + "/api/tasks/unknown/timeline")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", missing.StatusCode)
+	}
+}
+
+func TestServer_TaskExplain(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	task := agent.NewTask("Explain me", nil)
+	if _, err := c.Submit(task); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/tasks/" + task.ID + "/explain")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body collective.AssignmentExplanation
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if body.Winner != a.Identity.SID {
+		t.Errorf("Expected winner %s, got %s", a.Identity.SID, body.Winner)
+	}
+	if len(body.Bids) != 1 || len(body.Bids[0].Factors) != 3 {
+		t.Errorf("Expected 1 bid with 3 factors, got %+v", body.Bids)
+	}
+
+	for _, path := range []string{"/api/tasks/unknown/explain", "/api/tasks/" + task.ID + "/other"} {
+		missing, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		missing.Body.Close()
+		if missing.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected status 404, got %d", path, missing.StatusCode)
+		}
+	}
+}
+
+func TestServer_SubmitTask(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	s := New(c)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	post := func(token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/tasks", strings.NewReader(`{"description":"Write docs"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	// Submission is disabled until a token is configured
+	resp := post("secret")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 without tokens, got %d", resp.StatusCode)
+	}
+
+	s.AddToken(APIToken{Token: "secret", Submitter: "ci", Weight: 3})
+	if w := c.GetQueue().Weight("ci"); w != 3 {
+		t.Errorf("Expected submitter weight 3, got %d", w)
+	}
+
+	resp = post("wrong")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for bad token, got %d", resp.StatusCode)
+	}
+
+	resp = post("secret")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	var body struct {
+		TaskID    string `json:"task_id"`
+		Submitter string `json:"submitter"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.TaskID == "" {
+		t.Error("Expected a task ID")
+	}
+	if body.Submitter != "ci" {
+		t.Errorf("Expected submitter ci, got %s", body.Submitter)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/tasks", strings.NewReader(`{"description":"Write docs","temperature":3}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	invalid, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	invalid.Body.Close()
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an out of range temperature, got %d", invalid.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, srv.URL+"/api/tasks", strings.NewReader(`{"description":"Write docs","qos":"realtime"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	invalid, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	invalid.Body.Close()
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown QoS class, got %d", invalid.StatusCode)
+	}
+}
+
+func TestServer_EventsRequiresUpgrade(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_EventStream(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+	defer c.Stop()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	_, _ = conn.Write([]byte("GET /events HTTP/1.1\r\n" +
+		"Host: localhost\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status 101, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		t.Error("Unexpected Sec-WebSocket-Accept header")
+	}
+
+	// Wait until the handler has subscribed before generating activity
+	time.Sleep(50 * time.Millisecond)
+
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = c.Join(a)
+
+	ws := &wsConn{conn: conn, reader: reader}
+	opcode, payload, err := ws.readFrame()
+	if err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	if opcode != opText {
+		t.Errorf("Expected text frame, got opcode %d", opcode)
+	}
+
+	var event collective.Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("Invalid event JSON: %v", err)
+	}
+	if event.Type != collective.EventAgentJoined {
+		t.Errorf("Expected agent_joined event, got %s", event.Type)
+	}
+	if event.AgentSID != a.Identity.SID {
+		t.Errorf("Expected agent SID %s, got %s", a.Identity.SID, event.AgentSID)
+	}
+}
+
+func TestServer_Approvals(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	s := New(c)
+	inbox := workflow.NewInbox()
+	s.SetInbox(inbox)
+	s.AddToken(APIToken{Token: "secret", Submitter: "alice"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	opened := make(chan workflow.HumanRequest, 1)
+	inbox.OnRequest(func(req workflow.HumanRequest) { opened <- req })
+	answered := make(chan workflow.HumanRequest, 1)
+	go func() {
+		req, _ := inbox.Ask(context.Background(), workflow.HumanRequest{Workflow: "deploy", StepID: "approve", Prompt: "Ship it?"})
+		answered <- req
+	}()
+	id := (<-opened).ID
+
+	resp, err := http.Get(srv.URL + "/api/approvals")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var pending []workflow.HumanRequest
+	if err := json.NewDecoder(resp.Body).Decode(&pending); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	resp.Body.Close()
+	if len(pending) != 1 || pending[0].ID != id {
+		t.Fatalf("Expected the pending request, got %+v", pending)
+	}
+
+	post := func(token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/approvals/"+id, strings.NewReader(`{"approved":true}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for bad token, got %d", resp.StatusCode)
+	}
+	if resp := post("secret"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := post("secret"); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409 for a closed request, got %d", resp.StatusCode)
+	}
+
+	req := <-answered
+	if req.Status != workflow.HumanApproved || req.Response.Responder != "alice" {
+		t.Errorf("Expected approval by alice, got %s by %q", req.Status, req.Response.Responder)
+	}
+}
+
+type echoSubmitter struct{}
+
+func (echoSubmitter) SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error) {
+	return &agent.TaskResult{TaskID: task.ID, Status: agent.TaskCompleted, Output: task.Description}, nil
+}
+
+func TestServer_Hooks(t *testing.T) {
+	engine := workflow.NewEngine(echoSubmitter{})
+	registry := workflow.NewRegistry(t.TempDir())
+	wf, _ := workflow.Parse([]byte("name: deploy\nparams: {ref: main}\nsteps: [{id: ship, task: 'deploy {{.Params.ref}}'}]\n"))
+	registry.Register(wf)
+	engine.SetRegistry(registry)
+
+	triggers := workflow.NewTriggers(engine)
+	triggers.AddRule(workflow.TriggerRule{
+		Name:     "deploy-on-push",
+		On:       workflow.EventWebhook,
+		Match:    map[string]string{"hook": "github"},
+		Workflow: "deploy",
+		Params:   map[string]string{"ref": "{{.Event.Data.payload.ref}}"},
+	})
+	runs := make(chan workflow.TriggerRun, 1)
+	triggers.OnRun(func(tr workflow.TriggerRun) { runs <- tr })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	triggers.Start(ctx)
+
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	s := New(c)
+	s.SetTriggers(triggers)
+	s.AddToken(APIToken{Token: "secret", Submitter: "ci"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	post := func(token string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/hooks/github", strings.NewReader(`{"ref":"v1.2.0"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if status := post("wrong"); status != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for bad token, got %d", status)
+	}
+	if status := post("secret"); status != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", status)
+	}
+
+	select {
+	case tr := <-runs:
+		if tr.Err != nil || tr.Run.Steps["ship"].Output != "deploy v1.2.0" {
+			t.Errorf("Expected deploy of v1.2.0, got %+v", tr)
+		}
+		if tr.Event.Data["submitter"] != "ci" {
+			t.Errorf("Expected submitter ci, got %v", tr.Event.Data["submitter"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for triggered run")
+	}
+}
+
+func TestServer_Gaps(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/gaps?window=30m")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body collective.GapReport
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if body.Window != 30*time.Minute {
+		t.Errorf("Expected window 30m, got %s", body.Window)
+	}
+
+	invalid, err := http.Get(srv.URL + "/api/gaps?window=soon")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	invalid.Body.Close()
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid window, got %d", invalid.StatusCode)
+	}
+}
+
+func TestServer_UsageAndMetrics(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1", CostTags: agent.Labels{"cost-center": "ml"}})
+	_ = c.Join(a)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	if _, err := c.Submit(agent.NewTask("Agent's cost center", nil)); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if _, err := c.Submit(agent.NewTask("Own cost center", nil).WithCostTags(agent.Labels{"cost-center": "web \"eu\""})); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/usage?by=cost-center")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var report collective.UsageReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Tasks != 2 || len(report.Chargeback) != 2 || len(report.Agents) != 1 {
+		t.Errorf("Expected 2 tasks charged to 2 cost centers by 1 agent, got %+v", report)
+	}
+
+	metrics, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer metrics.Body.Close()
+	body, _ := io.ReadAll(metrics.Body)
+
+	for _, want := range []string{
+		"# TYPE squaremind_agents gauge\nsquaremind_agents 1\n",
+		`squaremind_agent_tasks_total{agent="` + a.Identity.SID + `",name="Agent1"} 2`,
+		`squaremind_chargeback_tasks_total{tag="cost-center",value="ml"} 1`,
+		`squaremind_chargeback_tasks_total{tag="cost-center",value="web \"eu\""} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestServer_Keys(t *testing.T) {
+	audited := sqmtest.NewFakeProvider("patched")
+	keyring, err := agent.NewKeyring(agent.Credential{
+		Name:         "audited",
+		Kind:         agent.CredentialAnthropic,
+		Provider:     audited,
+		Capabilities: []identity.CapabilityType{identity.CapSecurity},
+	})
+	if err != nil {
+		t.Fatalf("NewKeyring failed: %v", err)
+	}
+	cfg := collective.DefaultCollectiveConfig()
+	cfg.Keyring = keyring
+	c := collective.NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Auditor", Capabilities: []identity.CapabilityType{identity.CapSecurity}})
+	_ = c.Join(a)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	result, err := c.Submit(agent.NewTask("Audit the login flow", []identity.CapabilityType{identity.CapSecurity}))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if result.Output != "patched" || audited.Calls() != 1 {
+		t.Errorf("Expected the task to run on the keyring's provider, got %q after %d calls", result.Output, audited.Calls())
+	}
+
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/keys")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var usage []agent.KeyUsage
+	if err := json.NewDecoder(resp.Body).Decode(&usage); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(usage) != 1 || usage[0].Name != "audited" || usage[0].Tasks != 1 || usage[0].Requests != 1 {
+		t.Errorf("Expected one task and request on the audited key, got %+v", usage)
+	}
+
+	metrics, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer metrics.Body.Close()
+	body, _ := io.ReadAll(metrics.Body)
+	if want := `squaremind_key_requests_total{credential="audited",kind="anthropic"} 1`; !strings.Contains(string(body), want) {
+		t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+	}
+}
+
+func TestServer_Digest(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	if _, err := c.Submit(agent.NewTask("Write the changelog", nil)); err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/digest?period=weekly")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var digest collective.Digest
+	if err := json.NewDecoder(resp.Body).Decode(&digest); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if digest.Period != collective.DigestWeekly || digest.Tasks != 1 || digest.Summaries[0].Asked != "Write the changelog" {
+		t.Errorf("Expected a weekly digest of 1 task, got %+v", digest)
+	}
+
+	invalid, err := http.Get(srv.URL + "/api/digest?period=hourly")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	invalid.Body.Close()
+	if invalid.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown period, got %d", invalid.StatusCode)
+	}
+}
+
+func TestServer_Artifact(t *testing.T) {
+	cfg := collective.DefaultCollectiveConfig()
+	cfg.Storage = &storage.Config{Driver: storage.DriverMemory}
+	c := collective.NewCollective("TestCollective", cfg)
+	c.GetMarket().SetBidTimeout(time.Millisecond)
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+
+	task := agent.NewTask("Shared task", nil)
+	result, err := c.Submit(task)
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+
+	s := New(c)
+	s.AddToken(APIToken{Token: "secret", Submitter: "ci"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/tasks/" + task.ID + "/artifact")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != result.Output {
+		t.Errorf("Expected the task output, got %d %q", resp.StatusCode, data)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/tasks/" + task.ID + "/artifacts")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var listed struct {
+		Artifacts []agent.Artifact `json:"artifacts"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&listed)
+	resp.Body.Close()
+	if len(listed.Artifacts) == 0 || listed.Artifacts[0].Name != agent.ArtifactOutput {
+		t.Fatalf("Expected the output artifact listed, got %+v", listed.Artifacts)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/artifacts/" + listed.Artifacts[0].Digest)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	data, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != result.Output {
+		t.Errorf("Expected the output by digest, got %d %q", resp.StatusCode, data)
+	}
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{"/api/tasks/missing/artifact", "", http.StatusNotFound},
+		{"/api/tasks/missing/artifacts", "", http.StatusNotFound},
+		{"/api/artifacts/sha256:nothex", "", http.StatusBadRequest},
+		{"/api/artifacts/" + storage.Digest([]byte("absent")), "", http.StatusNotFound},
+		{"/api/tasks/" + task.ID + "/artifact-url", "", http.StatusUnauthorized},
+		{"/api/tasks/" + task.ID + "/artifact-url?expires=soon", "secret", http.StatusBadRequest},
+		{"/api/tasks/missing/artifact-url", "secret", http.StatusNotFound},
+		{"/api/tasks/" + task.ID + "/artifact-url", "secret", http.StatusNotImplemented}, // The memory backend can't sign URLs
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.path, tt.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_Resources(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	s := New(c)
+	s.AddToken(APIToken{Token: "secret", Submitter: "iac"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	do := func(method, path, body string, header map[string]string) (*http.Response, Resource) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+"/api/resources/"+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var res Resource
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return resp, res
+	}
+
+	// Agents need a factory
+	resp, _ := do(http.MethodPut, "agents/coder", `{"capabilities":["code.write"]}`, nil)
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without an agent factory, got %d", resp.StatusCode)
+	}
+	s.SetAgentFactory(func(name string, spec AgentSpec) (*agent.Agent, error) {
+		return agent.NewAgent(agent.AgentConfig{Name: name, Capabilities: spec.Capabilities})
+	})
+
+	resp, _ = do(http.MethodPut, "agents/coder", `{"capabilities":["code.write"]}`, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+	agents := c.GetAgents()
+	if len(agents) != 1 {
+		t.Fatalf("Expected 1 agent, got %d", len(agents))
+	}
+	sid := agents[0].Identity.SID
+
+	// Applying the same spec again changes nothing
+	resp, _ = do(http.MethodPut, "agents/coder", `{"capabilities": ["code.write"]}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") != etag {
+		t.Errorf("Expected unchanged ETag %s, got %s", etag, resp.Header.Get("ETag"))
+	}
+	if _, ok := c.GetAgent(sid); !ok || len(c.GetAgents()) != 1 {
+		t.Error("Expected the agent to be kept")
+	}
+
+	resp, _ = do(http.MethodPut, "agents/coder", `{"capabilities":["code.write"]}`, map[string]string{"If-None-Match": "*"})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 for If-None-Match on an existing resource, got %d", resp.StatusCode)
+	}
+
+	// Teams resolve members by resource ID
+	resp, _ = do(http.MethodPut, "teams/backend", `{"assignment_mode":"consensus","members":["coder"]}`, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201 for team, got %d", resp.StatusCode)
+	}
+	team, ok := c.GetTeam("backend")
+	if !ok || !team.Has(sid) {
+		t.Fatal("Expected team backend with the coder")
+	}
+	resp, _ = do(http.MethodPut, "teams/frontend", `{"members":["nobody"]}`, nil)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an unknown member, got %d", resp.StatusCode)
+	}
+
+	// Changing the agent's spec replaces it, keeping its teams
+	resp, _ = do(http.MethodPut, "agents/coder", `{"capabilities":["code.write","testing"]}`, map[string]string{"If-Match": etag})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("ETag") == etag {
+		t.Error("Expected a new ETag after a spec change")
+	}
+	if _, ok := c.GetAgent(sid); ok {
+		t.Error("Expected the old agent to have left")
+	}
+	agents = c.GetAgents()
+	if len(agents) != 1 || !team.Has(agents[0].Identity.SID) {
+		t.Error("Expected the replacement agent in team backend")
+	}
+
+	// A stale ETag is refused
+	resp, _ = do(http.MethodPut, "agents/coder", `{"capabilities":["code.write"]}`, map[string]string{"If-Match": etag})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 for a stale ETag, got %d", resp.StatusCode)
+	}
+
+	resp, _ = do(http.MethodPut, "routes/backend-code", `{"team":"backend","capabilities":["code.write"]}`, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201 for route, got %d", resp.StatusCode)
+	}
+	if rules := c.RoutingRules(); len(rules) != 1 || rules[0].Team != "backend" {
+		t.Errorf("Expected 1 routing rule to backend, got %+v", rules)
+	}
+
+	resp, _ = do(http.MethodPut, "budgets/iac", `{"tokens":0}`, nil)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an empty budget, got %d", resp.StatusCode)
+	}
+	resp, _ = do(http.MethodPut, "budgets/iac", `{"tokens":1000,"submitter":"iac","extra":1}`, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unknown field, got %d", resp.StatusCode)
+	}
+	resp, _ = do(http.MethodPut, "budgets/iac", `{"tokens":1000,"submitter":"iac"}`, nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status 201 for budget, got %d", resp.StatusCode)
+	}
+
+	// Listing is open to everyone
+	list, err := http.Get(srv.URL + "/api/resources/budgets")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var budgets []Resource
+	_ = json.NewDecoder(list.Body).Decode(&budgets)
+	list.Body.Close()
+	if len(budgets) != 1 || budgets[0].ID != "iac" {
+		t.Errorf("Expected budget iac, got %+v", budgets)
+	}
+
+	// Deletes are idempotent, unless If-Match expects the resource
+	resp, _ = do(http.MethodDelete, "routes/backend-code", "", nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if len(c.RoutingRules()) != 0 {
+		t.Error("Expected the routing rule to be removed")
+	}
+	resp, _ = do(http.MethodDelete, "routes/backend-code", "", nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204 for a repeated delete, got %d", resp.StatusCode)
+	}
+	resp, _ = do(http.MethodDelete, "routes/backend-code", "", map[string]string{"If-Match": "*"})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412, got %d", resp.StatusCode)
+	}
+	resp, _ = do(http.MethodDelete, "agents/coder", "", nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if len(c.GetAgents()) != 0 {
+		t.Error("Expected the agent to have left")
+	}
+
+	resp, _ = do(http.MethodPut, "widgets/x", `{}`, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown kind, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Mode(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	s := New(c)
+	s.AddToken(APIToken{Token: "secret", Submitter: "ops"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	put := func(token, body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/mode", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := put("wrong", `{"mode":"paused"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for bad token, got %d", resp.StatusCode)
+	}
+
+	resp = put("secret", `{"mode":"asleep"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown mode, got %d", resp.StatusCode)
+	}
+
+	resp = put("secret", `{"mode":"maintenance","reason":"incident 42"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var status collective.ModeStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.Mode != collective.ModeMaintenance || status.Reason != "incident 42" {
+		t.Errorf("Expected maintenance for incident 42, got %+v", status)
+	}
+	if !c.InMaintenance() {
+		t.Error("Expected the collective to be in maintenance")
+	}
+
+	resp = put("secret", `{"mode":"running"}`)
+	resp.Body.Close()
+	if mode := c.Mode().Mode; mode != collective.ModeRunning {
+		t.Errorf("Expected mode running, got %s", mode)
+	}
+}
+
+func TestServer_AdminAgents(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = c.Start(ctx)
+	defer c.Stop()
+	s := New(c)
+	s.AddToken(APIToken{Token: "secret", Submitter: "ops"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	do := func(method, path, token, body string) *http.Response {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/api/agents", "secret", `{"name":"coder","capabilities":["code.write"]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("Expected status 501 without an agent factory, got %d", resp.StatusCode)
+	}
+	s.SetAgentFactory(func(name string, spec AgentSpec) (*agent.Agent, error) {
+		return agent.NewAgent(agent.AgentConfig{Name: name, Capabilities: spec.Capabilities})
+	})
+
+	resp = do(http.MethodPost, "/api/agents", "", `{"name":"coder","capabilities":["code.write"]}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, "/api/agents", "secret", `{"name":"coder","capabilities":["code.write"]}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", resp.StatusCode)
+	}
+	var created collective.AgentSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	resp.Body.Close()
+	if created.Name != "coder" || created.SID == "" {
+		t.Errorf("Expected a snapshot of coder, got %+v", created)
+	}
+
+	resp = do(http.MethodPost, "/api/agents/"+created.SID+"/pause", "secret", "")
+	var paused collective.AgentSnapshot
+	_ = json.NewDecoder(resp.Body).Decode(&paused)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || paused.State != agent.StatePaused {
+		t.Errorf("Expected 200 and a paused agent, got %d and %s", resp.StatusCode, paused.State)
+	}
+
+	resp = do(http.MethodPost, "/api/agents/"+created.SID+"/resume", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, "/api/agents/sq:unknown/pause", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status 404 for an unknown agent, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodGet, "/api/agents/"+created.SID+"/pause", "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodDelete, "/api/agents/"+created.SID, "secret", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+	if _, ok := c.GetAgent(created.SID); ok {
+		t.Error("Expected the agent to be removed")
+	}
+}
+
+func TestServer_DrainMode(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+	s := New(c)
+	s.AddToken(APIToken{Token: "secret", Submitter: "ops"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/mode", strings.NewReader(`{"mode":"draining","reason":"upgrade"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if mode := c.Mode().Mode; mode != collective.ModeDraining {
+		t.Errorf("Expected mode draining, got %s", mode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, srv.URL+"/api/tasks", strings.NewReader(`{"description":"Refused"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Parameters(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Agent1"})
+	_ = c.Join(a)
+	s := New(c)
+	s.AddToken(APIToken{Token: "secret", Submitter: "ops"})
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	post := func(body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/parameters", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post(`{"bid_timeout":"soon"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid bid timeout, got %d", resp.StatusCode)
+	}
+
+	resp = post(`{"max_agents":20,"bid_timeout":"2s"}`)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var proposal collective.ParameterProposal
+	if err := json.NewDecoder(resp.Body).Decode(&proposal); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !proposal.Accepted || proposal.Proposer != "ops" {
+		t.Errorf("Expected an accepted proposal from ops, got %+v", proposal)
+	}
+	if params := c.Parameters(); params.MaxAgents != 20 || params.BidTimeout != 2*time.Second {
+		t.Errorf("Expected max agents 20 and bid timeout 2s, got %+v", params)
+	}
+
+	c.SetParameterVoter(func(*agent.Agent, collective.Parameters, collective.Parameters) bool { return false })
+	rejected := post(`{"max_agents":30}`)
+	rejected.Body.Close()
+	if rejected.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409 for a rejected change, got %d", rejected.StatusCode)
+	}
+}
+
+func TestServer_Incident(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	s := New(c)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	get := func(token string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/incident?reason=outage", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := get("secret")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 without tokens, got %d", resp.StatusCode)
+	}
+
+	s.AddToken(APIToken{Token: "secret", Submitter: "oncall"})
+	resp = get("")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", resp.StatusCode)
+	}
+
+	resp = get("secret")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/gzip" {
+		t.Errorf("Expected a gzip bundle, got %s", ct)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != "manifest.json" {
+		t.Fatalf("Expected manifest.json first, got %v (%v)", hdr, err)
+	}
+	var manifest struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil || manifest.Reason != "outage" {
+		t.Errorf("Expected reason outage, got %q (%v)", manifest.Reason, err)
+	}
+}
+
+func TestServer_Snapshot(t *testing.T) {
+	src := collective.NewCollective("Source", collective.DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Reviewer", Capabilities: []identity.CapabilityType{identity.CapCodeReview}})
+	_ = src.Join(a)
+	dst := collective.NewCollective("Destination", collective.DefaultCollectiveConfig())
+	s := New(dst)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	do := func(method, token string, body []byte) *http.Response {
+		req, _ := http.NewRequest(method, srv.URL+"/api/snapshot", bytes.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodGet, "secret", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403 without tokens, got %d", resp.StatusCode)
+	}
+
+	s.AddToken(APIToken{Token: "secret", Submitter: "ops"})
+	body, _ := json.Marshal(src.Export())
+	resp = do(http.MethodPost, "", body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, "secret", body)
+	var result collective.ImportResult
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || result.Agents != 1 {
+		t.Fatalf("Expected one agent imported, got status %d and %+v", resp.StatusCode, result)
+	}
+	if _, ok := dst.GetAgent(a.Identity.SID); !ok {
+		t.Error("Expected the imported agent to keep its SID")
+	}
+
+	resp = do(http.MethodGet, "secret", nil)
+	defer resp.Body.Close()
+	var snapshot collective.Snapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if snapshot.Collective != "Destination" || len(snapshot.Agents) != 1 || snapshot.Agents[0].Identity.Name != "Reviewer" {
+		t.Errorf("Expected the destination's snapshot with the reviewer, got %+v", snapshot)
+	}
+
+	resp = do(http.MethodPost, "secret", []byte(`{"version":99}`))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an unsupported version, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_Standby(t *testing.T) {
+	primary := collective.NewCollective("Primary", collective.DefaultCollectiveConfig())
+	a, _ := agent.NewAgent(agent.AgentConfig{Name: "Coder", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}})
+	_ = primary.Join(a)
+	ps := New(primary)
+	ps.AddToken(APIToken{Token: "replicate", Submitter: "standby"})
+	primarySrv := httptest.NewServer(ps.Handler())
+	defer primarySrv.Close()
+
+	c := collective.NewCollective("Standby", collective.DefaultCollectiveConfig())
+	standby := collective.NewStandby(c, NewReplicationClient(primarySrv.URL, "replicate"), collective.StandbyConfig{})
+	ss := New(c)
+	ss.AddToken(APIToken{Token: "secret", Submitter: "ops"})
+	ss.SetStandby(standby)
+	standbySrv := httptest.NewServer(ss.Handler())
+	defer standbySrv.Close()
+
+	if err := standby.Sync(context.Background()); err != nil {
+		t.Fatalf("Sync over HTTP failed: %v", err)
+	}
+	if _, ok := c.GetAgent(a.Identity.SID); !ok {
+		t.Error("Expected the primary's agent to be replicated")
+	}
+
+	resp, err := http.Get(standbySrv.URL + "/api/standby")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var status collective.StandbyStatus
+	_ = json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if status.Primary != primary.ID || status.Syncs != 1 || status.Agents != 1 {
+		t.Errorf("Expected one sync of the primary's agent, got %+v", status)
+	}
+
+	promote := func(body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, standbySrv.URL+"/api/standby/promote", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp = promote(`{"reason":"drill"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected status 409 while the primary is alive, got %d", resp.StatusCode)
+	}
+
+	resp = promote(`{"reason":"drill","force":true}`)
+	var promotion collective.Promotion
+	_ = json.NewDecoder(resp.Body).Decode(&promotion)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !promotion.Accepted || !promotion.Fenced || promotion.Epoch != 1 {
+		t.Fatalf("Expected an accepted promotion that fenced the primary, got status %d and %+v", resp.StatusCode, promotion)
+	}
+	if primary.FencedBy() != c.ID || primary.Mode().Mode != collective.ModeMaintenance {
+		t.Errorf("Expected the primary fenced into maintenance, got %q %s", primary.FencedBy(), primary.Mode().Mode)
+	}
+
+	// Replication takes a token on the primary
+	resp, err = http.Get(primarySrv.URL + "/api/replication")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", resp.StatusCode)
+	}
+	if err := NewReplicationClient(primarySrv.URL, "replicate").Fence(context.Background(), 0, "other"); !errors.Is(err, collective.ErrStaleEpoch) {
+		t.Errorf("Expected ErrStaleEpoch fencing at a stale epoch, got %v", err)
+	}
+}
+
+func TestServer_Attestations(t *testing.T) {
+	c := collective.NewCollective("TestCollective", collective.DefaultCollectiveConfig())
+	srv := httptest.NewServer(New(c).Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/attestations/advertisement")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var attestation collective.Attestation
+	if err := json.NewDecoder(resp.Body).Decode(&attestation); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if err := attestation.Verify(c.Identity().PublicKey); err != nil {
+		t.Errorf("Expected the advertisement to verify, got %v", err)
+	}
+	var claim collective.AdvertisementClaim
+	_ = attestation.Decode(&claim)
+	if claim.Endpoint != srv.URL {
+		t.Errorf("Expected the server's address %s, got %s", srv.URL, claim.Endpoint)
+	}
+
+	missing, err := http.Get(srv.URL + "/api/attestations/consensus/no-such-round")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown round, got %d", missing.StatusCode)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+)
+
+// maxSnapshotBody limits the size of an imported snapshot
+const maxSnapshotBody = 64 << 20
+
+// handleSnapshot exports the collective as a snapshot (GET) or imports one
+// (POST). Snapshots carry the collective's memory and pending tasks, so
+// both take an API token; signatures are checked by the client.
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.hasTokens() {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "snapshots are disabled: no API tokens configured"})
+		return
+	}
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing API token"})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, s.collective.Export())
+		return
+	}
+
+	var snapshot collective.Snapshot
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSnapshotBody)).Decode(&snapshot); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid snapshot: " + err.Error()})
+		return
+	}
+
+	var opts collective.ImportOptions
+	s.mu.RLock()
+	factory := s.agentFactory
+	s.mu.RUnlock()
+	if factory != nil {
+		// Agents are created the way agent resources are, with this host's provider
+		opts.NewAgent = func(cfg agent.AgentConfig) (*agent.Agent, error) {
+			a, err := factory(cfg.Name, AgentSpec{
+				Capabilities: cfg.Capabilities,
+				Model:        cfg.Model,
+				Labels:       cfg.Labels,
+				CostTags:     cfg.CostTags,
+				SystemPrompt: cfg.SystemPrompt,
+				Temperature:  cfg.Temperature,
+				MaxTokens:    cfg.MaxTokens,
+			})
+			if err == nil {
+				a.Identity.Name = cfg.Name
+				a.Identity.ParentSID = cfg.ParentSID
+			}
+			return a, err
+		}
+	}
+
+	result, err := s.collective.Import(&snapshot, opts)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, collective.ErrInvalidSnapshot) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
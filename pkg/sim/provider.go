@@ -0,0 +1,82 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// Response is one scripted LLM response
+type Response struct {
+	Content string        `json:"content"`
+	Latency time.Duration `json:"latency,omitempty"` // Virtual time the response takes (default: Config.LLMLatency)
+	Err     string        `json:"error,omitempty"`   // Fails the call with this message instead
+}
+
+// ScriptedProvider is an LLM provider answering from a script, in turn and
+// repeating the last response. It doesn't wait out latencies; it adds them
+// up for the simulation to charge to virtual time.
+type ScriptedProvider struct {
+	mu sync.Mutex
+
+	script  []Response
+	latency time.Duration // For responses that set none
+	calls   int
+	elapsed time.Duration // Latency accumulated since the last take
+}
+
+// NewScriptedProvider creates a provider answering with the script, each
+// response taking latency unless it sets its own
+func NewScriptedProvider(latency time.Duration, script ...Response) *ScriptedProvider {
+	if len(script) == 0 {
+		script = []Response{{Content: "ok"}}
+	}
+	return &ScriptedProvider{script: script, latency: latency}
+}
+
+// Complete returns the next scripted response
+func (p *ScriptedProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	r := p.script[min(p.calls, len(p.script)-1)]
+	p.calls++
+	latency := r.Latency
+	if latency <= 0 {
+		latency = p.latency
+	}
+	p.elapsed += latency
+	p.mu.Unlock()
+
+	if r.Err != "" {
+		return nil, errors.New(r.Err)
+	}
+	tokens := (len(req.Prompt) + len(r.Content) + 3) / 4
+	return &llm.CompletionResponse{Content: r.Content, FinishReason: "stop", TokensUsed: tokens}, nil
+}
+
+// Name returns "sim"
+func (p *ScriptedProvider) Name() string {
+	return "sim"
+}
+
+// Calls returns how many requests the provider has answered
+func (p *ScriptedProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// take returns the latency accumulated since it was last called
+func (p *ScriptedProvider) take() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	elapsed := p.elapsed
+	p.elapsed = 0
+	return elapsed
+}
@@ -0,0 +1,657 @@
+// Package sim runs coordination scenarios deterministically. Time is
+// virtual and jumps from one scheduled event to the next; gossip fanout and
+// the delays of bids, votes and message hops are drawn from a seeded
+// source; and agents answer from a scripted LLM provider. Two runs of a
+// scenario with the same seed produce the same trace, so tests of
+// consensus, markets and emergent behavior are reproducible.
+//
+// The simulation drives the real TaskMarket, ConsensusEngine and one
+// GossipProtocol per agent through their clock, seed and transport hooks.
+// Agents run their tasks as soon as they are assigned; the task then holds
+// its agent for the virtual time its responses take.
+package sim
+
+import (
+	"container/heap"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/logging"
+	sqmtest "github.com/square-mind/squaremind/pkg/testing"
+)
+
+var (
+	ErrUnknownAgent   = errors.New("unknown agent")
+	ErrDuplicateAgent = errors.New("duplicate agent name")
+)
+
+// AgentSpec describes a simulated agent
+type AgentSpec struct {
+	Name         string
+	Capabilities []identity.CapabilityType
+	Proficiency  float64 // Starting proficiency in each capability (default 0.5)
+}
+
+// Config describes a simulation. Durations are virtual.
+type Config struct {
+	Seed   int64
+	Start  time.Time // When the simulation starts (zero = 2024-01-01 UTC)
+	Agents []AgentSpec
+
+	// Script holds the LLM responses, in turn; the last repeats (default: "ok")
+	Script     []Response
+	LLMLatency time.Duration // Time a response that sets none takes (default 1s)
+
+	BidTimeout time.Duration // How long bidding stays open (default 100ms)
+	BidLatency time.Duration // Bids arrive up to this long after listing (default 80ms)
+
+	GossipLatency time.Duration      // Each gossip hop takes up to this long (default 10ms)
+	Fanout        int                // Peers each hop forwards to (0 = adaptive)
+	Codec         coordination.Codec // Encodes each hop as a transport between processes would (nil = hops stay in-process)
+
+	Threshold        float64                                                 // Reputation-weighted consensus threshold (default 0.67)
+	ConsensusTimeout time.Duration                                           // Default 30s
+	VoteLatency      time.Duration                                           // Votes arrive up to this long after a proposal (default 50ms)
+	Voter            func(voter *agent.Agent, p *coordination.Proposal) bool // How agents vote (default: approve)
+}
+
+// withDefaults fills unset fields
+func (c Config) withDefaults() Config {
+	if c.Start.IsZero() {
+		c.Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if c.LLMLatency <= 0 {
+		c.LLMLatency = time.Second
+	}
+	if c.BidTimeout <= 0 {
+		c.BidTimeout = 100 * time.Millisecond
+	}
+	if c.BidLatency <= 0 {
+		c.BidLatency = 80 * time.Millisecond
+	}
+	if c.GossipLatency <= 0 {
+		c.GossipLatency = 10 * time.Millisecond
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = 0.67
+	}
+	if c.ConsensusTimeout <= 0 {
+		c.ConsensusTimeout = 30 * time.Second
+	}
+	if c.VoteLatency <= 0 {
+		c.VoteLatency = 50 * time.Millisecond
+	}
+	if c.Voter == nil {
+		c.Voter = func(*agent.Agent, *coordination.Proposal) bool { return true }
+	}
+	return c
+}
+
+// EventKind is something that happened in a simulation
+type EventKind string
+
+const (
+	EventTaskListed     EventKind = "task_listed"
+	EventBid            EventKind = "bid"
+	EventBidLate        EventKind = "bid_late" // Arrived after bidding closed
+	EventTaskAssigned   EventKind = "task_assigned"
+	EventTaskUnassigned EventKind = "task_unassigned"
+	EventTaskCompleted  EventKind = "task_completed"
+	EventTaskFailed     EventKind = "task_failed"
+	EventGossip         EventKind = "gossip"         // A message reached an agent for the first time
+	EventGossipDropped  EventKind = "gossip_dropped" // A hop failed to cross the codec
+	EventProposed       EventKind = "proposed"
+	EventVote           EventKind = "vote"
+	EventDecided        EventKind = "decided"
+)
+
+// Event is one entry of a simulation's trace
+type Event struct {
+	At      time.Duration `json:"at"` // Since the start
+	Kind    EventKind     `json:"kind"`
+	Agent   string        `json:"agent,omitempty"`   // Name
+	Subject string        `json:"subject,omitempty"` // Task, message or proposal ID
+	Detail  string        `json:"detail,omitempty"`
+}
+
+// Round is the outcome of a simulated consensus round
+type Round struct {
+	ID       string        `json:"id"`
+	Proposer string        `json:"proposer"`
+	Result   string        `json:"result"` // "pending", "accepted", "rejected" or "timeout"
+	Votes    int           `json:"votes"`
+	Accepts  int           `json:"accepts"`
+	Decided  time.Duration `json:"decided,omitempty"` // When, since the start
+	proposal string        // The engine's proposal ID
+	weights  map[string]float64
+}
+
+// Sim is a deterministic simulation. It is not safe for concurrent use.
+type Sim struct {
+	cfg      Config
+	rand     *rand.Rand
+	clock    *sqmtest.FakeClock
+	provider *ScriptedProvider
+	queue    eventQueue
+	seq      int
+
+	agents []*agent.Agent // In spec order
+	byName map[string]*agent.Agent
+	bySID  map[string]*agent.Agent
+	nodes  map[string]*coordination.GossipProtocol // Agent SID -> its gossip protocol
+
+	market     *coordination.TaskMarket
+	consensus  *coordination.ConsensusEngine
+	reputation *coordination.ReputationRegistry
+
+	busyUntil map[string]time.Time // Agent SID -> when its task finishes
+	flushing  map[string]bool      // Agent SIDs with a bulk flush scheduled
+	watched   map[coordination.MessageType]bool
+
+	tasks, messages int
+	results         []*agent.TaskResult
+	reached         map[string][]string // Message ID -> agents it reached
+	rounds          []*Round
+	trace           []Event
+
+	ctx    context.Context // Agents' lifetime
+	cancel context.CancelFunc
+	runCtx context.Context
+}
+
+// New creates a simulation and starts its agents; Close stops them
+func New(cfg Config) (*Sim, error) {
+	cfg = cfg.withDefaults()
+	s := &Sim{
+		cfg:        cfg,
+		rand:       rand.New(rand.NewSource(cfg.Seed)),
+		clock:      sqmtest.NewFakeClock(cfg.Start),
+		provider:   NewScriptedProvider(cfg.LLMLatency, cfg.Script...),
+		byName:     make(map[string]*agent.Agent),
+		bySID:      make(map[string]*agent.Agent),
+		nodes:      make(map[string]*coordination.GossipProtocol),
+		market:     coordination.NewTaskMarket(),
+		consensus:  coordination.NewConsensusEngine(cfg.Threshold),
+		reputation: coordination.NewReputationRegistry(),
+		busyUntil:  make(map[string]time.Time),
+		flushing:   make(map[string]bool),
+		watched:    make(map[coordination.MessageType]bool),
+		reached:    make(map[string][]string),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.runCtx = s.ctx
+
+	s.market.SetClock(s.clock.Now)
+	s.market.SetLogger(logging.Nop())
+	s.consensus.SetClock(s.clock.Now)
+	s.consensus.SetTimeout(cfg.ConsensusTimeout)
+	s.consensus.SetLogger(logging.Nop())
+
+	for i, spec := range cfg.Agents {
+		if _, exists := s.byName[spec.Name]; exists {
+			s.Close()
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateAgent, spec.Name)
+		}
+		a, err := s.newAgent(i, spec)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.agents = append(s.agents, a)
+		s.byName[spec.Name] = a
+		s.bySID[a.Identity.SID] = a
+		s.reputation.Register(a.Identity.SID, a.Reputation)
+	}
+
+	for _, a := range s.agents {
+		sid := a.Identity.SID
+		node := coordination.NewGossipProtocol()
+		node.SetLogger(logging.Nop())
+		node.SetClock(s.clock.Now)
+		node.SetRand(rand.New(rand.NewSource(s.rand.Int63())))
+		node.SetTransport(s.transport)
+		for _, peer := range s.agents {
+			if peer != a {
+				node.AddPeer(peer.Identity.SID)
+			}
+		}
+		if cfg.Fanout > 0 {
+			node.SetFanout(cfg.Fanout)
+		}
+		s.nodes[sid] = node
+	}
+	return s, nil
+}
+
+// newAgent creates the i-th agent, with an identity derived from the seed
+// so that SIDs, and the tie-breaks ordered by them, repeat across runs
+func (s *Sim) newAgent(i int, spec AgentSpec) (*agent.Agent, error) {
+	a, err := agent.NewAgent(agent.AgentConfig{
+		Name:         spec.Name,
+		Capabilities: spec.Capabilities,
+		Provider:     s.provider,
+		Logger:       logging.Nop(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	s.rand.Read(seed)
+	key := ed25519.NewKeyFromSeed(seed)
+	a.Identity.SID = fmt.Sprintf("sim-%03d", i+1)
+	a.Identity.PrivateKey = key
+	a.Identity.PublicKey = key.Public().(ed25519.PublicKey)
+	a.Identity.CreatedAt = s.cfg.Start
+
+	if spec.Proficiency > 0 {
+		for _, c := range spec.Capabilities {
+			a.Capabilities.Add(&identity.Capability{Type: c, Proficiency: spec.Proficiency})
+		}
+	}
+	if err := a.Start(s.ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Close stops the agents
+func (s *Sim) Close() {
+	s.cancel()
+	for _, a := range s.agents {
+		a.Stop()
+	}
+}
+
+// Run processes scheduled events in virtual time order until none remain
+// or ctx ends
+func (s *Sim) Run(ctx context.Context) error {
+	s.runCtx = ctx
+	defer func() { s.runCtx = s.ctx }()
+
+	for s.queue.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next := heap.Pop(&s.queue).(*scheduled)
+		s.clock.Set(next.at)
+		next.run()
+	}
+	return nil
+}
+
+// SubmitAt lists a task on the market at a virtual time and returns its ID.
+// Free agents able to take it bid after a seeded delay; when bidding closes
+// the best bid from an agent that is still free wins.
+func (s *Sim) SubmitAt(at time.Duration, task *agent.Task) string {
+	s.tasks++
+	task.ID = fmt.Sprintf("task-%d", s.tasks)
+	task.CreatedAt = s.cfg.Start.Add(at)
+	s.schedule(s.cfg.Start.Add(at), func() { s.list(task) })
+	return task.ID
+}
+
+// list opens bidding on a task
+func (s *Sim) list(task *agent.Task) {
+	if err := s.market.ListTask(task); err != nil {
+		s.record(EventTaskUnassigned, "", task.ID, err.Error())
+		return
+	}
+	s.record(EventTaskListed, "", task.ID, "")
+
+	now := s.clock.Now()
+	for _, a := range s.agents {
+		if s.busy(a, now) {
+			continue
+		}
+		if bid := s.market.MakeBid(a, task); bid != nil {
+			s.after(s.jitter(s.cfg.BidLatency), func() { s.bid(bid) })
+		}
+	}
+	s.after(s.cfg.BidTimeout, func() { s.closeAuction(task) })
+}
+
+// bid places a bid that has arrived
+func (s *Sim) bid(bid *coordination.Bid) {
+	name := s.bySID[bid.AgentSID].Identity.Name
+	if err := s.market.SubmitBid(bid); err != nil {
+		s.record(EventBidLate, name, bid.TaskID, "")
+		return
+	}
+	s.record(EventBid, name, bid.TaskID, fmt.Sprintf("capability %.3f", bid.CapabilityScore))
+}
+
+// closeAuction ends bidding on a task and runs it on the best free bidder
+func (s *Sim) closeAuction(task *agent.Task) {
+	s.market.CloseBidding(task.ID)
+	ranked, err := s.market.RankBids(task.ID, s.reputation)
+	s.market.UnlistTask(task.ID)
+
+	now := s.clock.Now()
+	var winner *agent.Agent
+	for _, candidate := range ranked {
+		if a := s.bySID[candidate.AgentSID]; !s.busy(a, now) {
+			winner = a
+			break
+		}
+	}
+	if winner == nil {
+		if err == nil {
+			err = fmt.Errorf("%w: every bidder is busy", coordination.ErrNoBids)
+		}
+		s.record(EventTaskUnassigned, "", task.ID, err.Error())
+		s.results = append(s.results, &agent.TaskResult{TaskID: task.ID, Status: agent.TaskFailed, Error: err.Error(), Timestamp: now})
+		return
+	}
+	s.record(EventTaskAssigned, winner.Identity.Name, task.ID, fmt.Sprintf("%d bids", len(ranked)))
+	s.execute(task, winner)
+}
+
+// execute runs a task on an agent and schedules its completion after the
+// virtual time its responses took
+func (s *Sim) execute(task *agent.Task, a *agent.Agent) {
+	sid := a.Identity.SID
+	task.AssignedTo = sid
+	a.SubmitTask(task)
+
+	var result *agent.TaskResult
+	select {
+	case result = <-a.GetResults():
+	case <-s.runCtx.Done():
+		return
+	}
+
+	done := s.clock.Now().Add(s.provider.take())
+	result.Duration = done.Sub(s.clock.Now())
+	result.Timestamp = done
+	s.busyUntil[sid] = done
+	s.schedule(done, func() {
+		s.results = append(s.results, result)
+		if result.Status == agent.TaskFailed {
+			s.record(EventTaskFailed, a.Identity.Name, task.ID, result.Error)
+			return
+		}
+		s.record(EventTaskCompleted, a.Identity.Name, task.ID, fmt.Sprintf("quality %.2f", result.Quality))
+	})
+}
+
+// busy reports whether an agent's task is still running at a virtual time
+func (s *Sim) busy(a *agent.Agent, now time.Time) bool {
+	return now.Before(s.busyUntil[a.Identity.SID])
+}
+
+// BroadcastAt has an agent gossip a message at a virtual time and returns
+// the message's ID. Each hop forwards to a seeded choice of peers and takes
+// a seeded delay.
+func (s *Sim) BroadcastAt(at time.Duration, from string, msgType coordination.MessageType, payload interface{}) (string, error) {
+	a, ok := s.byName[from]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAgent, from)
+	}
+	s.watch(msgType)
+
+	s.messages++
+	id := fmt.Sprintf("msg-%d", s.messages)
+	sid := a.Identity.SID
+	s.schedule(s.cfg.Start.Add(at), func() {
+		node := s.nodes[sid]
+		s.deliver(sid, coordination.Message{
+			ID:        id,
+			Type:      msgType,
+			From:      sid,
+			Payload:   payload,
+			Timestamp: s.clock.Now(),
+			TTL:       node.Stats().TTL,
+		})
+	})
+	return id, nil
+}
+
+// watch records which agents a type of message reaches
+func (s *Sim) watch(msgType coordination.MessageType) {
+	if s.watched[msgType] {
+		return
+	}
+	s.watched[msgType] = true
+	for _, a := range s.agents {
+		name := a.Identity.Name
+		s.nodes[a.Identity.SID].OnMessage(msgType, func(msg coordination.Message) {
+			s.reached[msg.ID] = append(s.reached[msg.ID], name)
+			s.record(EventGossip, name, msg.ID, fmt.Sprintf("ttl %d", msg.TTL))
+		})
+	}
+}
+
+// transport carries a forwarded message to a peer after a seeded delay
+func (s *Sim) transport(peer string, msg coordination.Message) {
+	if s.cfg.Codec != nil {
+		var err error
+		if msg, err = s.roundTrip(msg); err != nil {
+			s.record(EventGossipDropped, s.bySID[peer].Identity.Name, msg.ID, err.Error())
+			return
+		}
+	}
+	s.after(s.jitter(s.cfg.GossipLatency), func() { s.deliver(peer, msg) })
+}
+
+// roundTrip encodes and decodes a message with the configured codec
+func (s *Sim) roundTrip(msg coordination.Message) (coordination.Message, error) {
+	data, err := s.cfg.Codec.Encode(msg)
+	if err != nil {
+		return msg, err
+	}
+	return s.cfg.Codec.Decode(data)
+}
+
+// deliver hands a message to an agent's gossip protocol. Bulk forwards it
+// defers go out at its next gossip interval.
+func (s *Sim) deliver(sid string, msg coordination.Message) {
+	node := s.nodes[sid]
+	node.Deliver(msg)
+	if node.Stats().Deferred > 0 && !s.flushing[sid] {
+		s.flushing[sid] = true
+		s.after(node.Interval(), func() {
+			s.flushing[sid] = false
+			node.Flush()
+		})
+	}
+}
+
+// ProposeAt has an agent put a proposal to a reputation-weighted vote of
+// every agent at a virtual time and returns the round's ID. The others'
+// votes, decided by Config.Voter, arrive after seeded delays.
+func (s *Sim) ProposeAt(at time.Duration, proposer string, cType coordination.ConsensusType, data map[string]interface{}) (string, error) {
+	a, ok := s.byName[proposer]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownAgent, proposer)
+	}
+	r := &Round{ID: fmt.Sprintf("proposal-%d", len(s.rounds)+1), Proposer: proposer, Result: "pending"}
+	s.rounds = append(s.rounds, r)
+	s.schedule(s.cfg.Start.Add(at), func() { s.propose(r, a, cType, data) })
+	return r.ID, nil
+}
+
+// propose opens a consensus round and schedules its votes and timeout
+func (s *Sim) propose(r *Round, proposer *agent.Agent, cType coordination.ConsensusType, data map[string]interface{}) {
+	round, err := s.consensus.Propose(s.ctx, proposer.Identity.SID, cType, data)
+	if err != nil {
+		r.Result = err.Error()
+		return
+	}
+	r.proposal = round.Proposal.ID
+	r.weights = make(map[string]float64, len(s.agents))
+	for _, a := range s.agents {
+		r.weights[a.Identity.SID] = s.reputation.Get(a.Identity.SID).Overall
+	}
+	s.record(EventProposed, proposer.Identity.Name, r.ID, string(cType))
+
+	for _, a := range s.agents {
+		if a == proposer {
+			continue
+		}
+		voter := a
+		s.after(s.jitter(s.cfg.VoteLatency), func() {
+			value := s.cfg.Voter(voter, round.Proposal)
+			vote := coordination.Vote{AgentSID: voter.Identity.SID, ProposalID: r.proposal, Value: value}
+			if s.consensus.SubmitVote(vote) != nil {
+				return // Already decided
+			}
+			s.record(EventVote, voter.Identity.Name, r.ID, fmt.Sprintf("%t", value))
+			s.check(r)
+		})
+	}
+	s.after(s.cfg.ConsensusTimeout+time.Nanosecond, func() { s.check(r) })
+	s.check(r)
+}
+
+// check records a round's result once it is decided
+func (s *Sim) check(r *Round) {
+	if r.Result != "pending" {
+		return
+	}
+	_, result := s.consensus.CheckWeightedConsensus(r.proposal, r.weights)
+	if result == "pending" {
+		return
+	}
+
+	round := s.consensus.GetRound(r.proposal)
+	r.Result = result
+	r.Votes = len(round.Votes)
+	for _, vote := range round.Votes {
+		if vote.Value {
+			r.Accepts++
+		}
+	}
+	r.Decided = s.clock.Now().Sub(s.cfg.Start)
+	s.record(EventDecided, r.Proposer, r.ID, result)
+}
+
+// Now returns the virtual time
+func (s *Sim) Now() time.Time {
+	return s.clock.Now()
+}
+
+// Elapsed returns the virtual time since the start
+func (s *Sim) Elapsed() time.Duration {
+	return s.clock.Now().Sub(s.cfg.Start)
+}
+
+// Agents returns the agents in the order of Config.Agents
+func (s *Sim) Agents() []*agent.Agent {
+	return append([]*agent.Agent(nil), s.agents...)
+}
+
+// Agent returns an agent by name, or nil
+func (s *Sim) Agent(name string) *agent.Agent {
+	return s.byName[name]
+}
+
+// Provider returns the scripted provider the agents answer from
+func (s *Sim) Provider() *ScriptedProvider {
+	return s.provider
+}
+
+// Market returns the simulated market
+func (s *Sim) Market() *coordination.TaskMarket {
+	return s.market
+}
+
+// Reputation returns the agents' reputation registry
+func (s *Sim) Reputation() *coordination.ReputationRegistry {
+	return s.reputation
+}
+
+// Results returns the results of finished tasks, in the order they finished
+func (s *Sim) Results() []*agent.TaskResult {
+	return append([]*agent.TaskResult(nil), s.results...)
+}
+
+// Reached returns the names of the agents a message reached, in order
+func (s *Sim) Reached(messageID string) []string {
+	return append([]string(nil), s.reached[messageID]...)
+}
+
+// Rounds returns the consensus rounds in the order they were proposed
+func (s *Sim) Rounds() []Round {
+	rounds := make([]Round, len(s.rounds))
+	for i, r := range s.rounds {
+		rounds[i] = *r
+	}
+	return rounds
+}
+
+// Trace returns everything that happened, in virtual time order
+func (s *Sim) Trace() []Event {
+	return append([]Event(nil), s.trace...)
+}
+
+// Fingerprint returns a hash of the trace: runs with the same seed and
+// scenario have the same fingerprint
+func (s *Sim) Fingerprint() string {
+	data, _ := json.Marshal(s.trace)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// record appends an event to the trace
+func (s *Sim) record(kind EventKind, agentName, subject, detail string) {
+	s.trace = append(s.trace, Event{At: s.Elapsed(), Kind: kind, Agent: agentName, Subject: subject, Detail: detail})
+}
+
+// jitter returns a seeded delay in [0, max)
+func (s *Sim) jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(s.rand.Int63n(int64(max)))
+}
+
+// schedule runs fn at a virtual time; events in the past run next
+func (s *Sim) schedule(at time.Time, fn func()) {
+	if now := s.clock.Now(); at.Before(now) {
+		at = now
+	}
+	s.seq++
+	heap.Push(&s.queue, &scheduled{at: at, seq: s.seq, run: fn})
+}
+
+// after runs fn once d of virtual time has passed
+func (s *Sim) after(d time.Duration, fn func()) {
+	s.schedule(s.clock.Now().Add(d), fn)
+}
+
+// scheduled is an event waiting for its virtual time
+type scheduled struct {
+	at  time.Time
+	seq int // Breaks ties in the order events were scheduled
+	run func()
+}
+
+// eventQueue orders scheduled events by time, then by when they were scheduled
+type eventQueue []*scheduled
+
+func (q eventQueue) Len() int { return len(q) }
+func (q eventQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q eventQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *eventQueue) Push(x interface{}) { *q = append(*q, x.(*scheduled)) }
+func (q *eventQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,186 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// scenario runs a market, gossip and consensus workload with a seed and
+// returns the simulation after it finishes
+func scenario(t *testing.T, seed int64) *Sim {
+	t.Helper()
+	s, err := New(Config{
+		Seed: seed,
+		Agents: []AgentSpec{
+			{Name: "Coder1", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}},
+			{Name: "Coder2", Capabilities: []identity.CapabilityType{identity.CapCodeWrite}, Proficiency: 0.9},
+			{Name: "Reviewer", Capabilities: []identity.CapabilityType{identity.CapCodeReview}},
+			{Name: "Writer", Capabilities: []identity.CapabilityType{identity.CapDocumentation}},
+			{Name: "Researcher", Capabilities: []identity.CapabilityType{identity.CapResearch}},
+			{Name: "Analyst", Capabilities: []identity.CapabilityType{identity.CapAnalysis}},
+		},
+		Script:     []Response{{Content: "done", Latency: 2 * time.Second}, {Content: "done"}},
+		BidLatency: 150 * time.Millisecond, // Some bids miss the 100ms window
+		Fanout:     2,
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	for i := 0; i < 5; i++ {
+		s.SubmitAt(time.Duration(i)*500*time.Millisecond, agent.NewTask("Write code", []identity.CapabilityType{identity.CapCodeWrite}))
+	}
+	if _, err := s.BroadcastAt(0, "Writer", coordination.MsgAgentJoined, "hello"); err != nil {
+		t.Fatalf("BroadcastAt failed: %v", err)
+	}
+	if _, err := s.ProposeAt(time.Second, "Reviewer", coordination.ConsensusTypeTaskAssignment, map[string]interface{}{"task_id": "task-1"}); err != nil {
+		t.Fatalf("ProposeAt failed: %v", err)
+	}
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	return s
+}
+
+func TestSim_Deterministic(t *testing.T) {
+	first := scenario(t, 7)
+	if len(first.Trace()) == 0 {
+		t.Fatal("Expected a trace")
+	}
+	for i := 0; i < 3; i++ {
+		if again := scenario(t, 7); again.Fingerprint() != first.Fingerprint() {
+			t.Fatalf("Expected the same trace for the same seed, got:\n%v\nand\n%v", first.Trace(), again.Trace())
+		}
+	}
+
+	differs := false
+	for seed := int64(8); seed < 12 && !differs; seed++ {
+		differs = scenario(t, seed).Fingerprint() != first.Fingerprint()
+	}
+	if !differs {
+		t.Error("Expected other seeds to change the trace")
+	}
+}
+
+func TestSim_Market(t *testing.T) {
+	s := scenario(t, 1)
+
+	results := s.Results()
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(results))
+	}
+	byAgent := make(map[string]int)
+	for _, r := range results {
+		if r.Status == agent.TaskCompleted {
+			byAgent[r.AgentSID]++
+		}
+	}
+	// The proficient coder wins whenever it is free and bid in time
+	if byAgent[s.Agent("Coder2").Identity.SID] == 0 {
+		t.Errorf("Expected Coder2 to win tasks, got %v", byAgent)
+	}
+	if s.Elapsed() < 2*time.Second {
+		t.Errorf("Expected virtual time to cover the scripted latency, got %s", s.Elapsed())
+	}
+}
+
+func TestSim_Gossip(t *testing.T) {
+	s := scenario(t, 3)
+	reached := s.Reached("msg-1")
+	if len(reached) != len(s.Agents()) {
+		t.Errorf("Expected the broadcast to reach all %d agents, got %v", len(s.Agents()), reached)
+	}
+	if len(reached) > 0 && reached[0] != "Writer" {
+		t.Errorf("Expected the sender first, got %s", reached[0])
+	}
+}
+
+func TestSim_Codec(t *testing.T) {
+	s, err := New(Config{
+		Seed:   1,
+		Codec:  coordination.JSONCodec{},
+		Agents: []AgentSpec{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer s.Close()
+
+	left, _ := s.BroadcastAt(0, "A", coordination.MsgAgentLeft, coordination.AgentLeft{SID: "a"})
+	bid, _ := s.BroadcastAt(0, "B", coordination.MsgTaskBid, "not a bid")
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if reached := s.Reached(left); len(reached) != 4 {
+		t.Errorf("Expected the typed broadcast to reach all 4 agents, got %v", reached)
+	}
+	if reached := s.Reached(bid); len(reached) != 1 {
+		t.Errorf("Expected the untyped bid to stop at its sender, got %v", reached)
+	}
+	dropped := 0
+	for _, e := range s.Trace() {
+		if e.Kind == EventGossipDropped && e.Subject == bid {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		t.Error("Expected the untyped bid's hops to be traced as dropped")
+	}
+}
+
+func TestSim_Consensus(t *testing.T) {
+	tests := []struct {
+		name  string
+		voter func(*agent.Agent, *coordination.Proposal) bool
+		want  string
+	}{
+		{"approve", nil, "accepted"},
+		{"reject", func(*agent.Agent, *coordination.Proposal) bool { return false }, "rejected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(Config{
+				Seed:   1,
+				Voter:  tt.voter,
+				Agents: []AgentSpec{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}},
+			})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			defer s.Close()
+
+			_, _ = s.ProposeAt(0, "A", coordination.ConsensusTypeTaskAssignment, nil)
+			if err := s.Run(context.Background()); err != nil {
+				t.Fatalf("Run failed: %v", err)
+			}
+			rounds := s.Rounds()
+			if len(rounds) != 1 || rounds[0].Result != tt.want {
+				t.Fatalf("Expected the round %s, got %+v", tt.want, rounds)
+			}
+			if rounds[0].Decided <= 0 || rounds[0].Decided >= 50*time.Millisecond {
+				t.Errorf("Expected a decision within the vote latency, got %s", rounds[0].Decided)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Agents: []AgentSpec{{Name: "A"}, {Name: "A"}}})
+	if !errors.Is(err, ErrDuplicateAgent) {
+		t.Errorf("Expected ErrDuplicateAgent, got %v", err)
+	}
+
+	s, _ := New(Config{Agents: []AgentSpec{{Name: "A"}}})
+	defer s.Close()
+	if _, err := s.BroadcastAt(0, "B", coordination.MsgHeartbeat, nil); !errors.Is(err, ErrUnknownAgent) {
+		t.Errorf("Expected ErrUnknownAgent, got %v", err)
+	}
+}
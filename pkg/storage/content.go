@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrDigestMismatch = errors.New("content does not match its digest")
+
+// digestPrefix names the hash of a content digest
+const digestPrefix = "sha256:"
+
+// Digest returns the content address of data: "sha256:" and the hex SHA-256
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// ContentStore keeps blobs content-addressed: each is stored once, under
+// the SHA-256 of its content, so identical outputs of different tasks share
+// a blob and a reader can check what it got
+type ContentStore struct {
+	blobs  Blobs
+	prefix string
+}
+
+// NewContentStore keeps content in blobs under prefix
+func NewContentStore(blobs Blobs, prefix string) *ContentStore {
+	return &ContentStore{blobs: blobs, prefix: prefix}
+}
+
+// Key returns the blob key of a digest: <prefix>sha256/<first two hex
+// digits>/<hex>. Returns ErrInvalidKey for a malformed digest.
+func (s *ContentStore) Key(digest string) (string, error) {
+	sum, ok := strings.CutPrefix(digest, digestPrefix)
+	if !ok || len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("%w: digest %q", ErrInvalidKey, digest)
+	}
+	if _, err := hex.DecodeString(sum); err != nil || strings.ToLower(sum) != sum {
+		return "", fmt.Errorf("%w: digest %q", ErrInvalidKey, digest)
+	}
+	return s.prefix + "sha256/" + sum[:2] + "/" + sum, nil
+}
+
+// Put stores data unless it is already stored and returns its digest
+func (s *ContentStore) Put(ctx context.Context, data []byte) (string, error) {
+	digest := Digest(data)
+	key, _ := s.Key(digest)
+	if r, err := s.blobs.Get(ctx, key); err == nil {
+		r.Close()
+		return digest, nil
+	}
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// Get opens the content with a digest. Reading it to the end fails with
+// ErrDigestMismatch if the stored blob has changed.
+func (s *ContentStore) Get(ctx context.Context, digest string) (io.ReadCloser, error) {
+	key, err := s.Key(digest)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{ReadCloser: r, hash: sha256.New(), want: digest}, nil
+}
+
+// Read returns the content with a digest, checked against it
+func (s *ContentStore) Read(ctx context.Context, digest string) ([]byte, error) {
+	r, err := s.Get(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// URL returns a URL reading the content with a digest until it expires, if
+// the blob store can sign one
+func (s *ContentStore) URL(ctx context.Context, digest string, expires time.Duration) (string, error) {
+	key, err := s.Key(digest)
+	if err != nil {
+		return "", err
+	}
+	return SignedURL(ctx, s.blobs, key, expires)
+}
+
+// verifyingReader hashes what is read and checks it at EOF
+type verifyingReader struct {
+	io.ReadCloser
+	hash hash.Hash
+	want string
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if got := digestPrefix + hex.EncodeToString(r.hash.Sum(nil)); got != r.want {
+			return n, fmt.Errorf("%w: %s read as %s", ErrDigestMismatch, r.want, got)
+		}
+	}
+	return n, err
+}
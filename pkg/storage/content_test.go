@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestContentStore(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		content := NewContentStore(s.Blobs(), "artifacts/")
+
+		digest, err := content.Put(ctx, []byte("hello"))
+		if err != nil {
+			t.Fatalf("%s: Put failed: %v", name, err)
+		}
+		if digest != Digest([]byte("hello")) || !strings.HasPrefix(digest, "sha256:") {
+			t.Errorf("%s: expected the sha256 digest of the content, got %s", name, digest)
+		}
+		if again, _ := content.Put(ctx, []byte("hello")); again != digest {
+			t.Errorf("%s: expected the same digest for the same content, got %s", name, again)
+		}
+		if keys, _ := s.Blobs().List(ctx, "artifacts/"); len(keys) != 1 {
+			t.Errorf("%s: expected one blob for repeated content, got %v", name, keys)
+		}
+
+		if data, err := content.Read(ctx, digest); err != nil || string(data) != "hello" {
+			t.Errorf("%s: expected hello, got %q (%v)", name, data, err)
+		}
+
+		// A blob changed behind the store's back fails verification
+		key, _ := content.Key(digest)
+		_ = s.Blobs().Put(ctx, key, strings.NewReader("tampered"))
+		r, err := content.Get(ctx, digest)
+		if err != nil {
+			t.Fatalf("%s: Get failed: %v", name, err)
+		}
+		if _, err := io.ReadAll(r); !errors.Is(err, ErrDigestMismatch) {
+			t.Errorf("%s: expected ErrDigestMismatch, got %v", name, err)
+		}
+		r.Close()
+
+		if _, err := content.Get(ctx, Digest([]byte("absent"))); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+	}
+}
+
+func TestContentStore_Key(t *testing.T) {
+	content := NewContentStore(NewMemory().Blobs(), "artifacts/")
+	digest := Digest([]byte("x"))
+	key, err := content.Key(digest)
+	if err != nil {
+		t.Fatalf("Key failed: %v", err)
+	}
+	sum := strings.TrimPrefix(digest, "sha256:")
+	if key != "artifacts/sha256/"+sum[:2]+"/"+sum {
+		t.Errorf("Expected a key sharded by the first two digits, got %s", key)
+	}
+
+	for _, bad := range []string{"", "sha256:abc", "md5:" + sum, "sha256:" + strings.ToUpper(sum), "sha256:../" + sum[3:]} {
+		if _, err := content.Key(bad); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: expected ErrInvalidKey, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fsStore keeps each key in its own file in a directory, named by the
+// escaped key. Writes go through a temporary file so a crash never leaves
+// one truncated.
+type fsStore struct {
+	dir string
+	tmp string
+}
+
+func (s *fsStore) path(key string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, url.PathEscape(key)), nil
+}
+
+func (s *fsStore) Get(ctx context.Context, key string) ([]byte, error) {
+	r, err := s.open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (s *fsStore) Put(ctx context.Context, key string, value []byte) error {
+	return s.write(key, bytes.NewReader(value))
+}
+
+func (s *fsStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fsStore) List(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for _, e := range entries {
+		key, err := url.PathUnescape(e.Name())
+		if err != nil || e.IsDir() || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// open opens the file holding key
+func (s *fsStore) open(key string) (*os.File, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return f, err
+}
+
+// write replaces the file holding key with r's content
+func (s *fsStore) write(key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.tmp, "write-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// fsBlobs streams blobs to and from their files
+type fsBlobs struct {
+	*fsStore
+}
+
+func (b fsBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.open(key)
+}
+
+func (b fsBlobs) Put(ctx context.Context, key string, r io.Reader) error {
+	return b.write(key, r)
+}
+
+// Filesystem is a Storage kept in files under a directory
+type Filesystem struct {
+	kv    *fsStore
+	docs  Documents
+	blobs fsBlobs
+}
+
+// OpenFilesystem opens (creating if needed) a storage directory
+func OpenFilesystem(dir string) (*Filesystem, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("%w: fs driver", ErrNoPath)
+	}
+	tmp := filepath.Join(dir, "tmp")
+	stores := make(map[string]*fsStore)
+	for _, name := range []string{"kv", "docs", "blobs", "tmp"} {
+		sub := filepath.Join(dir, name)
+		if err := os.MkdirAll(sub, 0700); err != nil {
+			return nil, err
+		}
+		stores[name] = &fsStore{dir: sub, tmp: tmp}
+	}
+	return &Filesystem{
+		kv:    stores["kv"],
+		docs:  NewDocuments(stores["docs"]),
+		blobs: fsBlobs{stores["blobs"]},
+	}, nil
+}
+
+func (f *Filesystem) KV() KV               { return f.kv }
+func (f *Filesystem) Documents() Documents { return f.docs }
+func (f *Filesystem) Blobs() Blobs         { return f.blobs }
+func (f *Filesystem) Close() error         { return nil }
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// memoryKV is a KV held in a map
+type memoryKV struct {
+	mu sync.RWMutex
+
+	values map[string][]byte
+}
+
+func newMemoryKV() *memoryKV {
+	return &memoryKV{values: make(map[string][]byte)}
+}
+
+func (m *memoryKV) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, ok := m.values[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (m *memoryKV) Put(ctx context.Context, key string, value []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *memoryKV) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryKV) List(ctx context.Context, prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0)
+	for key := range m.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Memory is a Storage held in process; nothing survives a restart
+type Memory struct {
+	kv    *memoryKV
+	docs  Documents
+	blobs Blobs
+}
+
+// NewMemory creates an empty in-memory storage
+func NewMemory() *Memory {
+	return &Memory{
+		kv:    newMemoryKV(),
+		docs:  NewDocuments(newMemoryKV()),
+		blobs: &kvBlobs{kv: newMemoryKV()},
+	}
+}
+
+func (m *Memory) KV() KV               { return m.kv }
+func (m *Memory) Documents() Documents { return m.docs }
+func (m *Memory) Blobs() Blobs         { return m.blobs }
+func (m *Memory) Close() error         { return nil }
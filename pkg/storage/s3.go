@@ -0,0 +1,385 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrS3 is returned when S3 rejects a request
+var ErrS3 = errors.New("s3 request failed")
+
+// S3Config locates an S3 bucket (or an S3-compatible service) holding
+// blobs. Credentials and region left empty are read from the standard
+// AWS_* environment variables.
+type S3Config struct {
+	Bucket          string `json:"bucket" yaml:"bucket"`
+	Prefix          string `json:"prefix,omitempty" yaml:"prefix"` // Prepended to every key
+	Region          string `json:"region,omitempty" yaml:"region"`
+	Endpoint        string `json:"endpoint,omitempty" yaml:"endpoint"` // e.g. http://localhost:9000 (default: AWS); implies path-style URLs
+	PathStyle       bool   `json:"path_style,omitempty" yaml:"path_style"`
+	AccessKeyID     string `json:"access_key_id,omitempty" yaml:"access_key_id"`
+	SecretAccessKey string `json:"-" yaml:"secret_access_key"`
+	SessionToken    string `json:"-" yaml:"session_token"`
+
+	// Server-side encryption of stored objects: AES256 (S3-managed keys) or
+	// aws:kms, with KMSKeyID naming the key (empty = the account's default)
+	Encryption string `json:"encryption,omitempty" yaml:"encryption"`
+	KMSKeyID   string `json:"kms_key_id,omitempty" yaml:"kms_key_id"`
+}
+
+// Server-side encryption modes
+const (
+	EncryptionAES256 = "AES256"
+	EncryptionKMS    = "aws:kms"
+)
+
+// MaxSignedURLExpiry is the longest a signed URL may stay valid
+const MaxSignedURLExpiry = 7 * 24 * time.Hour
+
+// S3Blobs stores blobs as objects in an S3 bucket, signing requests with
+// AWS Signature Version 4
+type S3Blobs struct {
+	cfg    S3Config
+	client *http.Client
+	now    func() time.Time
+
+	// encryption holds the headers that request server-side encryption
+	// on upload
+	encryption map[string]string
+}
+
+// NewS3Blobs creates a blob store in an S3 bucket
+func NewS3Blobs(cfg S3Config) (*S3Blobs, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("%w: no bucket configured", ErrS3)
+	}
+	encryption := make(map[string]string)
+	switch cfg.Encryption {
+	case "":
+		if cfg.KMSKeyID != "" {
+			return nil, fmt.Errorf("%w: kms_key_id requires encryption %s", ErrS3, EncryptionKMS)
+		}
+	case EncryptionAES256:
+		encryption["X-Amz-Server-Side-Encryption"] = EncryptionAES256
+	case EncryptionKMS:
+		encryption["X-Amz-Server-Side-Encryption"] = EncryptionKMS
+		if cfg.KMSKeyID != "" {
+			encryption["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"] = cfg.KMSKeyID
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported encryption %q (want %s or %s)", ErrS3, cfg.Encryption, EncryptionAES256, EncryptionKMS)
+	}
+	if cfg.Region == "" {
+		cfg.Region = os.Getenv("AWS_REGION")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.AccessKeyID == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	} else {
+		cfg.PathStyle = true
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &S3Blobs{cfg: cfg, client: &http.Client{Timeout: 5 * time.Minute}, now: time.Now, encryption: encryption}, nil
+}
+
+func (s *S3Blobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.cfg.Prefix+key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3Blobs) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	// The payload is hashed into the signature, so it is read up front
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.cfg.Prefix+key, nil, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Blobs) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.cfg.Prefix+key, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listResult is the part of a ListObjectsV2 response read
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (s *S3Blobs) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	token := ""
+	for {
+		query := map[string]string{"list-type": "2", "prefix": s.cfg.Prefix + prefix}
+		if token != "" {
+			query["continuation-token"] = token
+		}
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: list: %v", ErrS3, err)
+		}
+
+		for _, c := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(c.Key, s.cfg.Prefix))
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// SignedURL returns a URL that reads the blob at key without credentials
+// until it expires, for sharing with whoever doesn't hold the bucket's keys
+func (s *S3Blobs) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > MaxSignedURLExpiry {
+		return "", fmt.Errorf("%w: signed URL expiry must be between 1s and %s, got %s", ErrS3, MaxSignedURLExpiry, expires)
+	}
+	u, err := s.objectURL(s.cfg.Prefix+key, nil)
+	if err != nil {
+		return "", err
+	}
+
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    s.cfg.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(expires / time.Second)),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if s.cfg.SessionToken != "" {
+		query["X-Amz-Security-Token"] = s.cfg.SessionToken
+	}
+	u.RawQuery = canonicalQuery(query)
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		u.RawQuery,
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	signature := s.signature(day, amzDate, scope, canonical)
+	return u.String() + "&X-Amz-Signature=" + signature, nil
+}
+
+// objectURL returns the URL of an object (or the bucket if key is empty)
+func (s *S3Blobs) objectURL(key string, query map[string]string) (*url.URL, error) {
+	u, err := url.Parse(s.cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: endpoint: %v", ErrS3, err)
+	}
+	path := "/" + key
+	if s.cfg.PathStyle {
+		path = "/" + s.cfg.Bucket + path
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = path
+	u.RawPath = s3Escape(path, false)
+	u.RawQuery = canonicalQuery(query)
+	return u, nil
+}
+
+// do sends a signed request for an object (or the bucket if key is empty)
+// and returns the response if it succeeded
+func (s *S3Blobs) do(ctx context.Context, method, key string, query map[string]string, body []byte) (*http.Response, error) {
+	u, err := s.objectURL(key, query)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if method == http.MethodPut {
+		for name, value := range s.encryption {
+			req.Header.Set(name, value)
+		}
+	}
+	s.sign(req, body)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, strings.TrimPrefix(key, s.cfg.Prefix))
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, fmt.Errorf("%w: %s %s: %s: %s", ErrS3, method, u.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// sign adds AWS Signature Version 4 headers to a request
+func (s *S3Blobs) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	signedHeaders, canonicalHeaders := canonicalHeaders(req)
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	signature := s.signature(day, amzDate, scope, canonical)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// signature signs a canonical request with a key derived for the day
+func (s *S3Blobs) signature(day, amzDate, scope, canonical string) string {
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), day)
+	for _, part := range []string{s.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+// canonicalHeaders returns the signed header names and their canonical
+// form: the host and every header set on the request, lower-cased and
+// sorted
+func canonicalHeaders(req *http.Request) (string, string) {
+	values := map[string]string{"host": req.URL.Host}
+	for name, v := range req.Header {
+		values[strings.ToLower(name)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + values[name] + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+// canonicalQuery encodes query parameters sorted by name, as signed
+func canonicalQuery(query map[string]string) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = s3Escape(name, true) + "=" + s3Escape(query[name], true)
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash is set
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "github.com/mattn/go-sqlite3" // Registers the "sqlite3" database/sql driver
+)
+
+// sqliteSchema creates a key-value table for each kind of store
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS kv (key TEXT PRIMARY KEY, value BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS documents (key TEXT PRIMARY KEY, value BLOB NOT NULL);
+CREATE TABLE IF NOT EXISTS blobs (key TEXT PRIMARY KEY, value BLOB NOT NULL);
+`
+
+// sqliteKV is a KV in one table of an SQLite database
+type sqliteKV struct {
+	db    *sql.DB
+	table string
+}
+
+func (s *sqliteKV) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	var value []byte
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM "+s.table+" WHERE key = ?", key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return value, err
+}
+
+func (s *sqliteKV) Put(ctx context.Context, key string, value []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := s.db.ExecContext(ctx, "INSERT OR REPLACE INTO "+s.table+" (key, value) VALUES (?, ?)", key, value)
+	return err
+}
+
+func (s *sqliteKV) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE key = ?", key)
+	return err
+}
+
+func (s *sqliteKV) List(ctx context.Context, prefix string) ([]string, error) {
+	// Keys are UTF-8, so no key with the prefix sorts after prefix+0xff
+	rows, err := s.db.QueryContext(ctx, "SELECT key FROM "+s.table+" WHERE key >= ? AND key < ? ORDER BY key", prefix, prefix+"\xff")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// SQLite is a Storage in an SQLite database, one table per kind of store
+type SQLite struct {
+	db    *sql.DB
+	kv    *sqliteKV
+	docs  Documents
+	blobs Blobs
+}
+
+// OpenSQLite opens (creating if needed) a storage database at path
+func OpenSQLite(path string) (*SQLite, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: sqlite driver", ErrNoPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLite{
+		db:    db,
+		kv:    &sqliteKV{db: db, table: "kv"},
+		docs:  NewDocuments(&sqliteKV{db: db, table: "documents"}),
+		blobs: &kvBlobs{kv: &sqliteKV{db: db, table: "blobs"}},
+	}, nil
+}
+
+func (s *SQLite) KV() KV               { return s.kv }
+func (s *SQLite) Documents() Documents { return s.docs }
+func (s *SQLite) Blobs() Blobs         { return s.blobs }
+func (s *SQLite) Close() error         { return s.db.Close() }
@@ -0,0 +1,263 @@
+// Package storage defines the storage interfaces squaremind persists state
+// through - key-value records, JSON documents and blobs - with in-memory,
+// filesystem, SQLite, S3 and Cloud Storage implementations. Callers work
+// against the interfaces and deployments pick a backend in configuration.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrInvalidKey    = errors.New("invalid storage key")
+	ErrUnknownDriver = errors.New("unknown storage driver")
+	ErrNoPath        = errors.New("storage path required")
+	ErrNoSignedURLs  = errors.New("blob store cannot issue signed URLs")
+)
+
+// KV stores small values by key. Keys are slash-separated paths.
+type KV interface {
+	Get(ctx context.Context, key string) ([]byte, error) // ErrNotFound if absent
+	Put(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error              // Deleting a missing key is not an error
+	List(ctx context.Context, prefix string) ([]string, error) // Keys with prefix, sorted
+}
+
+// Documents stores JSON documents by collection and ID
+type Documents interface {
+	Get(ctx context.Context, collection, id string, v interface{}) error // ErrNotFound if absent
+	Put(ctx context.Context, collection, id string, v interface{}) error
+	Delete(ctx context.Context, collection, id string) error
+	List(ctx context.Context, collection string) ([]string, error) // IDs, sorted
+}
+
+// Blobs stores large opaque objects by key
+type Blobs interface {
+	Get(ctx context.Context, key string) (io.ReadCloser, error) // ErrNotFound if absent
+	Put(ctx context.Context, key string, r io.Reader) error
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// URLSigner is a blob store that can issue time-limited URLs reading a blob
+// without credentials
+type URLSigner interface {
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// SignedURL returns a URL reading the blob at key until it expires, if
+// blobs can issue one
+func SignedURL(ctx context.Context, blobs Blobs, key string, expires time.Duration) (string, error) {
+	signer, ok := blobs.(URLSigner)
+	if !ok {
+		return "", fmt.Errorf("%w: %T", ErrNoSignedURLs, blobs)
+	}
+	return signer.SignedURL(ctx, key, expires)
+}
+
+// Storage is a backend providing all three kinds of store
+type Storage interface {
+	KV() KV
+	Documents() Documents
+	Blobs() Blobs
+	Close() error
+}
+
+// Drivers
+const (
+	DriverMemory     = "memory"
+	DriverFilesystem = "fs"
+	DriverSQLite     = "sqlite"
+)
+
+// Config selects a storage backend
+type Config struct {
+	Driver string     `json:"driver" yaml:"driver"`               // memory (default), fs or sqlite
+	Path   string     `json:"path,omitempty" yaml:"path"`         // Directory (fs) or database file (sqlite)
+	S3     *S3Config  `json:"s3,omitempty" yaml:"s3,omitempty"`   // Keep blobs in S3 instead of the backend
+	GCS    *GCSConfig `json:"gcs,omitempty" yaml:"gcs,omitempty"` // Keep blobs in Cloud Storage instead of the backend
+}
+
+// Open opens the backend cfg selects
+func Open(cfg Config) (Storage, error) {
+	var (
+		s   Storage
+		err error
+	)
+	switch cfg.Driver {
+	case "", DriverMemory:
+		s = NewMemory()
+	case DriverFilesystem:
+		s, err = OpenFilesystem(cfg.Path)
+	case DriverSQLite:
+		s, err = OpenSQLite(cfg.Path)
+	default:
+		return nil, fmt.Errorf("%w: %q (want memory, fs or sqlite)", ErrUnknownDriver, cfg.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var blobs *S3Blobs
+	switch {
+	case cfg.S3 != nil && cfg.GCS != nil:
+		err = fmt.Errorf("%w: configure s3 or gcs, not both", ErrS3)
+	case cfg.S3 != nil:
+		blobs, err = NewS3Blobs(*cfg.S3)
+	case cfg.GCS != nil:
+		blobs, err = NewGCSBlobs(*cfg.GCS)
+	default:
+		return s, nil
+	}
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return WithBlobs(s, blobs), nil
+}
+
+// WithBlobs returns s with its blobs kept in blobs instead
+func WithBlobs(s Storage, blobs Blobs) Storage {
+	return &composite{Storage: s, blobs: blobs}
+}
+
+type composite struct {
+	Storage
+	blobs Blobs
+}
+
+func (c *composite) Blobs() Blobs { return c.blobs }
+
+// ValidKey reports whether key is a relative slash-separated path without
+// empty, "." or ".." segments
+func ValidKey(key string) bool {
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// checkKey returns ErrInvalidKey for keys ValidKey rejects
+func checkKey(key string) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return nil
+}
+
+// documents stores JSON documents in a KV as collection/id
+type documents struct {
+	kv KV
+}
+
+// NewDocuments stores JSON documents in a KV, keyed collection/id
+func NewDocuments(kv KV) Documents {
+	return &documents{kv: kv}
+}
+
+// docKey returns a document's key. Collections and IDs are single segments.
+func docKey(collection, id string) (string, error) {
+	if strings.Contains(collection, "/") || strings.Contains(id, "/") {
+		return "", fmt.Errorf("%w: %q/%q", ErrInvalidKey, collection, id)
+	}
+	key := collection + "/" + id
+	return key, checkKey(key)
+}
+
+func (d *documents) Get(ctx context.Context, collection, id string, v interface{}) error {
+	key, err := docKey(collection, id)
+	if err != nil {
+		return err
+	}
+	data, err := d.kv.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (d *documents) Put(ctx context.Context, collection, id string, v interface{}) error {
+	key, err := docKey(collection, id)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return d.kv.Put(ctx, key, data)
+}
+
+func (d *documents) Delete(ctx context.Context, collection, id string) error {
+	key, err := docKey(collection, id)
+	if err != nil {
+		return err
+	}
+	return d.kv.Delete(ctx, key)
+}
+
+func (d *documents) List(ctx context.Context, collection string) ([]string, error) {
+	if _, err := docKey(collection, "x"); err != nil {
+		return nil, err
+	}
+	keys, err := d.kv.List(ctx, collection+"/")
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = strings.TrimPrefix(key, collection+"/")
+	}
+	return ids, nil
+}
+
+// kvBlobs stores blobs as KV values, read whole into memory
+type kvBlobs struct {
+	kv KV
+}
+
+func (b *kvBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, err := b.kv.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (b *kvBlobs) Put(ctx context.Context, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return b.kv.Put(ctx, key, data)
+}
+
+func (b *kvBlobs) Delete(ctx context.Context, key string) error {
+	return b.kv.Delete(ctx, key)
+}
+
+func (b *kvBlobs) List(ctx context.Context, prefix string) ([]string, error) {
+	return b.kv.List(ctx, prefix)
+}
+
+// ReadBlob returns the whole content of a blob
+func ReadBlob(ctx context.Context, blobs Blobs, key string) ([]byte, error) {
+	r, err := blobs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
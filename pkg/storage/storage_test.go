@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// backends opens a fresh instance of every local storage backend
+func backends(t *testing.T) map[string]Storage {
+	t.Helper()
+	dir := t.TempDir()
+
+	fs, err := Open(Config{Driver: DriverFilesystem, Path: filepath.Join(dir, "fs")})
+	if err != nil {
+		t.Fatalf("Open fs failed: %v", err)
+	}
+	db, err := Open(Config{Driver: DriverSQLite, Path: filepath.Join(dir, "storage.db")})
+	if err != nil {
+		t.Fatalf("Open sqlite failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return map[string]Storage{"memory": NewMemory(), "fs": fs, "sqlite": db}
+}
+
+func TestStorage_KV(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		kv := s.KV()
+		if _, err := kv.Get(ctx, "audit/1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		for _, key := range []string{"audit/2", "audit/1", "auditor", "tasks/1"} {
+			if err := kv.Put(ctx, key, []byte("v-"+key)); err != nil {
+				t.Fatalf("%s: Put %s failed: %v", name, key, err)
+			}
+		}
+		_ = kv.Put(ctx, "audit/1", []byte("replaced"))
+
+		if value, err := kv.Get(ctx, "audit/1"); err != nil || string(value) != "replaced" {
+			t.Errorf("%s: expected replaced value, got %q (%v)", name, value, err)
+		}
+		keys, err := kv.List(ctx, "audit/")
+		if err != nil || strings.Join(keys, ",") != "audit/1,audit/2" {
+			t.Errorf("%s: expected sorted audit keys, got %v (%v)", name, keys, err)
+		}
+		if all, _ := kv.List(ctx, ""); len(all) != 4 {
+			t.Errorf("%s: expected 4 keys in total, got %v", name, all)
+		}
+
+		_ = kv.Delete(ctx, "audit/1")
+		if _, err := kv.Get(ctx, "audit/1"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound after Delete, got %v", name, err)
+		}
+	}
+}
+
+func TestStorage_InvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		for _, key := range []string{"", "/abs", "a//b", "../escape", "a/./b", "trailing/"} {
+			if err := s.KV().Put(ctx, key, []byte("x")); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("%s: expected ErrInvalidKey for %q, got %v", name, key, err)
+			}
+		}
+		if err := s.Documents().Put(ctx, "tasks", "a/b", "x"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%s: expected ErrInvalidKey for a document ID with a slash, got %v", name, err)
+		}
+	}
+}
+
+func TestStorage_Documents(t *testing.T) {
+	type doc struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	ctx := context.Background()
+	for name, s := range backends(t) {
+		docs := s.Documents()
+		_ = docs.Put(ctx, "tasks", "b", doc{Name: "second", Count: 2})
+		_ = docs.Put(ctx, "tasks", "a", doc{Name: "first", Count: 1})
+		_ = docs.Put(ctx, "results", "a", doc{Name: "other"})
+
+		var got doc
+		if err := docs.Get(ctx, "tasks", "a", &got); err != nil || got.Name != "first" || got.Count != 1 {
+			t.Errorf("%s: expected the first document, got %+v (%v)", name, got, err)
+		}
+		ids, err := docs.List(ctx, "tasks")
+		if err != nil || strings.Join(ids, ",") != "a,b" {
+			t.Errorf("%s: expected IDs a,b, got %v (%v)", name, ids, err)
+		}
+		_ = docs.Delete(ctx, "tasks", "a")
+		if err := docs.Get(ctx, "tasks", "a", &got); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound after Delete, got %v", name, err)
+		}
+	}
+}
+
+func TestStorage_Blobs(t *testing.T) {
+	for name, s := range backends(t) {
+		t.Run(name, func(t *testing.T) { testBlobs(t, s.Blobs()) })
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		cfg  Config
+		err  error
+	}{
+		{"default", Config{}, nil},
+		{"memory", Config{Driver: DriverMemory}, nil},
+		{"fs", Config{Driver: DriverFilesystem, Path: dir}, nil},
+		{"fs without path", Config{Driver: DriverFilesystem}, ErrNoPath},
+		{"unknown", Config{Driver: "tape"}, ErrUnknownDriver},
+		{"s3 without bucket", Config{S3: &S3Config{}}, ErrS3},
+	}
+	for _, tt := range tests {
+		s, err := Open(tt.cfg)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+		if s != nil {
+			s.Close()
+		}
+	}
+
+	// Filesystem storage survives reopening
+	ctx := context.Background()
+	first, _ := OpenFilesystem(dir)
+	_ = first.KV().Put(ctx, "reputation", []byte("saved"))
+	second, _ := OpenFilesystem(dir)
+	if value, err := second.KV().Get(ctx, "reputation"); err != nil || string(value) != "saved" {
+		t.Errorf("Expected the value to survive reopening, got %q (%v)", value, err)
+	}
+}
@@ -0,0 +1,100 @@
+package testing
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeClock is a deterministic clock for code that takes its time from a
+// function or interface: it stands still until advanced, and timers created
+// with After fire when it passes their deadline. It is safe for concurrent
+// use.
+type FakeClock struct {
+	mu sync.Mutex
+
+	now    time.Time
+	timers []fakeTimer
+}
+
+// fakeTimer is a pending After channel
+type fakeTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock creates a clock reading start (zero = 2024-01-01 UTC)
+func NewFakeClock(start time.Time) *FakeClock {
+	if start.IsZero() {
+		start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return &FakeClock{now: start}
+}
+
+// Now returns the clock's time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed on the clock since t
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// After returns a channel that receives the clock's time once it has
+// advanced by d
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.timers = append(c.timers, fakeTimer{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the timers it passes in
+// deadline order
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing the timers it passes. The clock never
+// moves backwards.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.setLocked(t)
+	}
+}
+
+// Pending returns how many timers have yet to fire
+func (c *FakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// setLocked moves the clock and fires due timers. Caller must hold c.mu.
+func (c *FakeClock) setLocked(t time.Time) {
+	c.now = t
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].deadline.Before(c.timers[j].deadline) })
+
+	fired := 0
+	for _, timer := range c.timers {
+		if timer.deadline.After(t) {
+			break
+		}
+		timer.ch <- timer.deadline
+		fired++
+	}
+	c.timers = c.timers[fired:]
+}
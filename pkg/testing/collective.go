@@ -0,0 +1,141 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+// CollectiveBuilder assembles a collective for a test: its configuration,
+// the provider its agents use and the agents to staff it with
+type CollectiveBuilder struct {
+	name     string
+	config   collective.CollectiveConfig
+	provider llm.Provider
+	agents   []agent.AgentConfig
+	start    bool
+}
+
+// NewCollectiveBuilder starts building a collective with the default
+// configuration and a FakeProvider answering "ok"
+func NewCollectiveBuilder(name string) *CollectiveBuilder {
+	return &CollectiveBuilder{
+		name:     name,
+		config:   collective.DefaultCollectiveConfig(),
+		provider: NewFakeProvider(),
+		start:    true,
+	}
+}
+
+// WithConfig replaces the collective's configuration
+func (b *CollectiveBuilder) WithConfig(cfg collective.CollectiveConfig) *CollectiveBuilder {
+	b.config = cfg
+	return b
+}
+
+// WithProvider sets the provider of agents added without their own
+func (b *CollectiveBuilder) WithProvider(p llm.Provider) *CollectiveBuilder {
+	b.provider = p
+	return b
+}
+
+// WithAgent adds an agent with the given capabilities
+func (b *CollectiveBuilder) WithAgent(name string, caps ...identity.CapabilityType) *CollectiveBuilder {
+	return b.WithAgentConfig(agent.AgentConfig{Name: name, Capabilities: caps})
+}
+
+// WithAgents adds n agents named agent-1 to agent-n with the given capabilities
+func (b *CollectiveBuilder) WithAgents(n int, caps ...identity.CapabilityType) *CollectiveBuilder {
+	for i := 1; i <= n; i++ {
+		b.WithAgent(fmt.Sprintf("agent-%d", len(b.agents)+1), caps...)
+	}
+	return b
+}
+
+// WithAgentConfig adds an agent configured in full
+func (b *CollectiveBuilder) WithAgentConfig(cfg agent.AgentConfig) *CollectiveBuilder {
+	b.agents = append(b.agents, cfg)
+	return b
+}
+
+// Unstarted leaves the collective stopped when built
+func (b *CollectiveBuilder) Unstarted() *CollectiveBuilder {
+	b.start = false
+	return b
+}
+
+// TestCollective is a built collective and its agents, in the order added
+type TestCollective struct {
+	*collective.Collective
+	Agents []*agent.Agent
+}
+
+// Build creates the collective, joins its agents and starts it. The
+// collective is stopped when the test ends. Logging is discarded unless an
+// agent config sets a logger.
+func (b *CollectiveBuilder) Build(tb TB) *TestCollective {
+	tb.Helper()
+
+	c := collective.NewCollective(b.name, b.config)
+	c.SetLogger(logging.Nop())
+
+	tc := &TestCollective{Collective: c}
+	for _, cfg := range b.agents {
+		if cfg.Provider == nil {
+			cfg.Provider = b.provider
+		}
+		if cfg.Logger == nil {
+			cfg.Logger = logging.Nop()
+		}
+		a, err := agent.NewAgent(cfg)
+		if err != nil {
+			tb.Fatalf("creating agent %s: %v", cfg.Name, err)
+		}
+		if err := c.Join(a); err != nil {
+			tb.Fatalf("joining agent %s: %v", cfg.Name, err)
+		}
+		tc.Agents = append(tc.Agents, a)
+	}
+
+	if b.start {
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := c.Start(ctx); err != nil {
+			cancel()
+			tb.Fatalf("starting collective: %v", err)
+		}
+		tb.Cleanup(func() {
+			c.Stop()
+			cancel()
+		})
+	}
+	return tc
+}
+
+// Agent returns the agent with the given name
+func (tc *TestCollective) Agent(name string) *agent.Agent {
+	for _, a := range tc.Agents {
+		if a.Identity.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// Eventually polls cond until it holds, failing the test if it doesn't
+// within timeout
+func Eventually(tb TB, timeout time.Duration, cond func() bool) {
+	tb.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			tb.Fatalf("condition not met within %v", timeout)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,234 @@
+package testing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+)
+
+// messageTypes are the built-in gossip message types MessageFuzzer draws from
+var messageTypes = []coordination.MessageType{
+	coordination.MsgAgentJoined,
+	coordination.MsgAgentLeft,
+	coordination.MsgTaskAvailable,
+	coordination.MsgTaskBid,
+	coordination.MsgTaskAssigned,
+	coordination.MsgTaskCompleted,
+	coordination.MsgHeartbeat,
+	coordination.MsgConsensus,
+	coordination.MsgMembership,
+}
+
+// MessageFuzzer generates gossip messages and corrupts their encodings, to
+// seed fuzz targets and drive property checks of message handling.
+// Generation is deterministic for a seed.
+type MessageFuzzer struct {
+	rand *rand.Rand
+}
+
+// NewMessageFuzzer creates a fuzzer with its own random source
+func NewMessageFuzzer(seed int64) *MessageFuzzer {
+	return &MessageFuzzer{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Message returns a well-formed message of a random built-in type, with a
+// payload of the type registered for it
+func (f *MessageFuzzer) Message() coordination.Message {
+	t := messageTypes[f.rand.Intn(len(messageTypes))]
+	msg := coordination.Message{
+		ID:        fmt.Sprintf("msg-%08x", f.rand.Uint32()),
+		Type:      t,
+		From:      fmt.Sprintf("sid-%d", f.rand.Intn(16)),
+		Timestamp: time.Unix(1700000000+f.rand.Int63n(1<<24), 0).UTC(),
+		TTL:       f.rand.Intn(10),
+	}
+	msg.Payload = f.payload(msg)
+	return msg
+}
+
+// payload returns a random payload of the type registered for a message's
+// type
+func (f *MessageFuzzer) payload(msg coordination.Message) interface{} {
+	taskID := fmt.Sprintf("task-%d", f.rand.Intn(100))
+	switch msg.Type {
+	case coordination.MsgAgentJoined:
+		return coordination.AgentJoined{
+			SID:        msg.From,
+			Name:       fmt.Sprintf("agent-%d", f.rand.Intn(16)),
+			PublicKey:  f.bytes(32),
+			Generation: f.rand.Intn(3),
+			CreatedAt:  msg.Timestamp,
+		}
+	case coordination.MsgAgentLeft:
+		return coordination.AgentLeft{SID: msg.From}
+	case coordination.MsgTaskAvailable:
+		return coordination.TaskAvailable{Task: &agent.Task{
+			ID:          taskID,
+			Description: fmt.Sprintf("task %d", f.rand.Intn(100)),
+			Priority:    f.rand.Intn(10),
+			Status:      agent.TaskPending,
+			CreatedAt:   msg.Timestamp,
+		}}
+	case coordination.MsgTaskBid:
+		return f.bid(msg, taskID)
+	case coordination.MsgTaskAssigned:
+		bid := f.bid(msg, taskID)
+		return coordination.TaskAssignment{TaskID: taskID, AgentSID: msg.From, Bid: &bid, Stake: bid.ReputationStake}
+	case coordination.MsgTaskCompleted:
+		return coordination.TaskCompleted{Result: &agent.TaskResult{
+			TaskID:     taskID,
+			AgentSID:   msg.From,
+			Status:     agent.TaskCompleted,
+			Output:     fmt.Sprintf("v%d", f.rand.Intn(100)),
+			TokensUsed: f.rand.Intn(1000),
+			Timestamp:  msg.Timestamp,
+		}}
+	case coordination.MsgHeartbeat:
+		return agent.Heartbeat{AgentSID: msg.From, State: agent.StateIdle, Timestamp: msg.Timestamp}
+	case coordination.MsgConsensus:
+		if f.rand.Intn(2) == 0 {
+			return coordination.ConsensusMessage{Vote: &coordination.Vote{
+				AgentSID:   msg.From,
+				ProposalID: fmt.Sprintf("proposal-%d", f.rand.Intn(100)),
+				Value:      f.rand.Intn(2) == 0,
+				Signature:  f.bytes(64),
+				Timestamp:  msg.Timestamp,
+			}}
+		}
+		return coordination.ConsensusMessage{Proposal: &coordination.Proposal{
+			ID:        fmt.Sprintf("proposal-%d", f.rand.Intn(100)),
+			Type:      coordination.ConsensusTypeTaskAssignment,
+			Proposer:  msg.From,
+			Data:      map[string]interface{}{"task_id": taskID},
+			CreatedAt: msg.Timestamp,
+		}}
+	case coordination.MsgMembership:
+		view := coordination.MembershipView{Version: uint64(f.rand.Intn(100))}
+		for i := f.rand.Intn(5); i > 0; i-- {
+			view.Members = append(view.Members, fmt.Sprintf("sid-%d", f.rand.Intn(16)))
+		}
+		return view
+	}
+	return f.value(2)
+}
+
+// bid returns a random bid by a message's sender
+func (f *MessageFuzzer) bid(msg coordination.Message, taskID string) coordination.Bid {
+	return coordination.Bid{
+		AgentSID:        msg.From,
+		TaskID:          taskID,
+		CapabilityScore: float64(f.rand.Intn(100)) / 100,
+		ReputationStake: float64(f.rand.Intn(50)),
+		EstimatedTime:   time.Duration(f.rand.Intn(60)) * time.Second,
+		Timestamp:       msg.Timestamp,
+	}
+}
+
+// bytes returns n random bytes
+func (f *MessageFuzzer) bytes(n int) []byte {
+	b := make([]byte, n)
+	f.rand.Read(b)
+	return b
+}
+
+// value returns a random JSON value nested at most depth levels
+func (f *MessageFuzzer) value(depth int) interface{} {
+	kind := f.rand.Intn(6)
+	if depth == 0 {
+		kind %= 4
+	}
+	switch kind {
+	case 0:
+		return nil
+	case 1:
+		return f.rand.Intn(2) == 0
+	case 2:
+		return float64(f.rand.Intn(1000))
+	case 3:
+		return fmt.Sprintf("v%d", f.rand.Intn(100))
+	case 4:
+		list := make([]interface{}, f.rand.Intn(4))
+		for i := range list {
+			list[i] = f.value(depth - 1)
+		}
+		return list
+	default:
+		obj := make(map[string]interface{})
+		for i := f.rand.Intn(4); i > 0; i-- {
+			obj[fmt.Sprintf("k%d", f.rand.Intn(10))] = f.value(depth - 1)
+		}
+		return obj
+	}
+}
+
+// Encoded returns the wire encoding of a random message
+func (f *MessageFuzzer) Encoded() []byte {
+	data, err := coordination.EncodeMessage(f.Message())
+	if err != nil {
+		panic(err) // Generated messages always encode
+	}
+	return data
+}
+
+// Mutate returns a corrupted copy of data: bytes flipped, dropped,
+// duplicated or replaced with JSON punctuation, or the data truncated
+func (f *MessageFuzzer) Mutate(data []byte) []byte {
+	out := append([]byte(nil), data...)
+	for n := 1 + f.rand.Intn(3); n > 0 && len(out) > 0; n-- {
+		i := f.rand.Intn(len(out))
+		switch f.rand.Intn(5) {
+		case 0:
+			out[i] ^= 1 << uint(f.rand.Intn(8))
+		case 1:
+			out = append(out[:i], out[i+1:]...)
+		case 2:
+			out = append(out[:i+1], out[i:]...)
+		case 3:
+			out[i] = `{}[]":,-0e`[f.rand.Intn(10)]
+		default:
+			out = out[:i]
+		}
+	}
+	return out
+}
+
+// Corpus returns n encoded messages, every other one mutated, for seeding a
+// fuzz target with f.Add
+func (f *MessageFuzzer) Corpus(n int) [][]byte {
+	corpus := make([][]byte, n)
+	for i := range corpus {
+		corpus[i] = f.Encoded()
+		if i%2 == 1 {
+			corpus[i] = f.Mutate(corpus[i])
+		}
+	}
+	return corpus
+}
+
+// CheckMessageRoundTrip verifies a message survives encoding and decoding
+// with its identity, routing fields and payload intact
+func CheckMessageRoundTrip(msg coordination.Message) error {
+	data, err := coordination.EncodeMessage(msg)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	decoded, err := coordination.DecodeMessage(data)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", data, err)
+	}
+	if decoded.ID != msg.ID || decoded.Type != msg.Type || decoded.From != msg.From ||
+		decoded.TTL != msg.TTL || !decoded.Timestamp.Equal(msg.Timestamp) {
+		return fmt.Errorf("header changed: %+v became %+v", msg, decoded)
+	}
+
+	want, _ := json.Marshal(msg.Payload)
+	got, _ := json.Marshal(decoded.Payload)
+	if string(want) != string(got) {
+		return fmt.Errorf("payload changed: %s became %s", want, got)
+	}
+	return nil
+}
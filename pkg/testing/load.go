@@ -0,0 +1,268 @@
+package testing
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/collective"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+// LoadConfig shapes a load test: simulated agents (no LLM provider) and the
+// synthetic tasks submitted to them
+type LoadConfig struct {
+	Agents      int           // Simulated agents to spawn
+	Tasks       int           // Synthetic tasks to submit
+	Concurrency int           // Tasks submitted at once (default: Agents)
+	BidTimeout  time.Duration // Market bid collection period (0 = the market's default)
+	Seed        int64         // Seeds the task mix and agent capabilities
+
+	// Collective configures the collective under load (zero value =
+	// DefaultCollectiveConfig sized for Agents)
+	Collective collective.CollectiveConfig
+}
+
+// loadCapabilities are dealt out to simulated agents and required by
+// synthetic tasks
+var loadCapabilities = []identity.CapabilityType{
+	identity.CapCodeWrite, identity.CapCodeReview, identity.CapTesting,
+	identity.CapResearch, identity.CapAnalysis, identity.CapDocumentation,
+}
+
+var loadComplexities = []string{"low", "medium", "high"}
+
+// LatencyStats summarizes a set of durations
+type LatencyStats struct {
+	Mean time.Duration `json:"mean"`
+	P50  time.Duration `json:"p50"`
+	P95  time.Duration `json:"p95"`
+	P99  time.Duration `json:"p99"`
+	Max  time.Duration `json:"max"`
+}
+
+// newLatencyStats summarizes samples, sorting them in place
+func newLatencyStats(samples []time.Duration) LatencyStats {
+	if len(samples) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	var total time.Duration
+	for _, d := range samples {
+		total += d
+	}
+	at := func(q float64) time.Duration {
+		return samples[min(int(q*float64(len(samples))), len(samples)-1)]
+	}
+	return LatencyStats{
+		Mean: total / time.Duration(len(samples)),
+		P50:  at(0.50),
+		P95:  at(0.95),
+		P99:  at(0.99),
+		Max:  samples[len(samples)-1],
+	}
+}
+
+// LoadReport is what a load test measured
+type LoadReport struct {
+	Agents     int           `json:"agents"`
+	Tasks      int           `json:"tasks"`
+	Completed  int           `json:"completed"`
+	Failed     int           `json:"failed"` // Failed results and tasks no agent took
+	Duration   time.Duration `json:"duration"`
+	Throughput float64       `json:"throughput"` // Tasks finished per second
+
+	// Assignment latency, from submission until an agent was assigned
+	Assignment LatencyStats `json:"assignment"`
+
+	// Bids received per assigned task
+	BidsMean float64 `json:"bids_mean"`
+	BidsMin  int     `json:"bids_min"`
+	BidsMax  int     `json:"bids_max"`
+
+	// Lock contention across the process while the load ran: contended
+	// mutex events and the time goroutines spent blocked on mutexes
+	LockContentions int64         `json:"lock_contentions"`
+	LockWait        time.Duration `json:"lock_wait"`
+}
+
+// withDefaults fills unset load settings
+func (cfg LoadConfig) withDefaults() LoadConfig {
+	if cfg.Agents <= 0 {
+		cfg.Agents = 10
+	}
+	if cfg.Tasks <= 0 {
+		cfg.Tasks = 100
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = cfg.Agents
+	}
+	if cfg.Collective.MaxAgents == 0 {
+		cfg.Collective = collective.DefaultCollectiveConfig()
+		cfg.Collective.MinAgents = 1
+		cfg.Collective.MaxAgents = cfg.Agents
+	}
+	return cfg
+}
+
+// RunLoad spawns simulated agents, submits synthetic tasks of mixed
+// complexity and capability requirements from Concurrency workers, and
+// measures the collective's throughput, assignment latency, bidding and
+// lock contention. Mutex profiling is enabled while it runs.
+func RunLoad(ctx context.Context, cfg LoadConfig) (*LoadReport, error) {
+	cfg = cfg.withDefaults()
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	c := collective.NewCollective("bench", cfg.Collective)
+	c.SetLogger(logging.Nop())
+	if cfg.BidTimeout > 0 {
+		c.GetMarket().SetBidTimeout(cfg.BidTimeout)
+	}
+	for i := 1; i <= cfg.Agents; i++ {
+		caps := make([]identity.CapabilityType, 0, 2)
+		for _, j := range rng.Perm(len(loadCapabilities))[:2] {
+			caps = append(caps, loadCapabilities[j])
+		}
+		a, err := agent.NewAgent(agent.AgentConfig{
+			Name:         fmt.Sprintf("bench-%d", i),
+			Capabilities: caps,
+			Logger:       logging.Nop(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := c.Join(a); err != nil {
+			return nil, fmt.Errorf("joining agent %d: %w", i, err)
+		}
+	}
+
+	tasks := make([]*agent.Task, cfg.Tasks)
+	for i := range tasks {
+		required := []identity.CapabilityType{loadCapabilities[rng.Intn(len(loadCapabilities))]}
+		tasks[i] = agent.NewTask(fmt.Sprintf("Synthetic task %d", i+1), required).
+			WithComplexity(loadComplexities[rng.Intn(len(loadComplexities))])
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if err := c.Start(runCtx); err != nil {
+		return nil, err
+	}
+	defer c.Stop()
+
+	previous := runtime.SetMutexProfileFraction(1)
+	defer runtime.SetMutexProfileFraction(previous)
+	contentionsBefore, waitBefore := mutexContention()
+
+	var (
+		mu        sync.Mutex
+		latencies = make([]time.Duration, 0, cfg.Tasks)
+		bids      = make([]int, 0, cfg.Tasks)
+		report    = &LoadReport{Agents: cfg.Agents, Tasks: cfg.Tasks}
+		next      = make(chan *agent.Task)
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range next {
+				submitted := time.Now()
+				result, err := c.SubmitCtx(runCtx, task)
+
+				latency, assigned := assignedAfter(c, task.ID, submitted)
+				exp, _ := c.ExplainAssignment(task.ID)
+
+				mu.Lock()
+				if err == nil && result.Status == agent.TaskCompleted {
+					report.Completed++
+				} else {
+					report.Failed++
+				}
+				if assigned {
+					latencies = append(latencies, latency)
+				}
+				if exp != nil && exp.Winner != "" {
+					bids = append(bids, len(exp.Bids))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, task := range tasks {
+		select {
+		case next <- task:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	report.Duration = time.Since(start)
+	if secs := report.Duration.Seconds(); secs > 0 {
+		report.Throughput = float64(report.Completed+report.Failed) / secs
+	}
+	report.Assignment = newLatencyStats(latencies)
+	if len(bids) > 0 {
+		report.BidsMin = bids[0]
+		total := 0
+		for _, n := range bids {
+			total += n
+			report.BidsMin = min(report.BidsMin, n)
+			report.BidsMax = max(report.BidsMax, n)
+		}
+		report.BidsMean = float64(total) / float64(len(bids))
+	}
+	contentionsAfter, waitAfter := mutexContention()
+	report.LockContentions = contentionsAfter - contentionsBefore
+	report.LockWait = waitAfter - waitBefore
+	return report, ctx.Err()
+}
+
+// assignedAfter returns how long after submission a task was last assigned
+func assignedAfter(c *collective.Collective, taskID string, submitted time.Time) (time.Duration, bool) {
+	entries, _ := c.Timeline(taskID)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Stage == collective.StageAssigned {
+			return entries[i].Timestamp.Sub(submitted), true
+		}
+	}
+	return 0, false
+}
+
+// mutexWaitMetric is the runtime's running total of time spent blocked on
+// sync.Mutex and sync.RWMutex
+const mutexWaitMetric = "/sync/mutex/wait/total:seconds"
+
+// mutexContention returns the contended mutex events in the mutex profile
+// and the time the process has spent blocked on mutexes so far
+func mutexContention() (int64, time.Duration) {
+	var records []runtime.BlockProfileRecord
+	n, ok := runtime.MutexProfile(nil)
+	for !ok {
+		records = make([]runtime.BlockProfileRecord, n+16)
+		n, ok = runtime.MutexProfile(records)
+	}
+	var events int64
+	for _, r := range records[:n] {
+		events += r.Count
+	}
+
+	sample := []metrics.Sample{{Name: mutexWaitMetric}}
+	metrics.Read(sample)
+	var wait time.Duration
+	if sample[0].Value.Kind() == metrics.KindFloat64 {
+		wait = time.Duration(sample[0].Value.Float64() * float64(time.Second))
+	}
+	return events, wait
+}
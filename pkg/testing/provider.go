@@ -0,0 +1,86 @@
+package testing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+)
+
+// FakeProvider is an LLM provider for tests. It answers with Respond if set,
+// otherwise with its scripted Responses in turn, repeating the last, and
+// records every request it receives. It is safe for concurrent use.
+type FakeProvider struct {
+	mu sync.Mutex
+
+	Responses []string                                            // Scripted responses (default: "ok")
+	Respond   func(req llm.CompletionRequest) (string, error)     // Computes each response, overriding Responses
+	Err       error                                               // Returned by every call when set
+	Latency   time.Duration                                       // Delay before answering, cut short by cancellation
+	Tokens    func(req llm.CompletionRequest, content string) int // Tokens reported (default: about 4 characters each)
+
+	requests []llm.CompletionRequest
+}
+
+// NewFakeProvider creates a provider answering with the given responses
+func NewFakeProvider(responses ...string) *FakeProvider {
+	return &FakeProvider{Responses: responses}
+}
+
+// Complete records the request and returns the next response
+func (p *FakeProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	n := len(p.requests)
+	latency, respond, fail, tokens := p.Latency, p.Respond, p.Err, p.Tokens
+	content := "ok"
+	if len(p.Responses) > 0 {
+		content = p.Responses[min(n, len(p.Responses))-1]
+	}
+	p.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if fail != nil {
+		return nil, fail
+	}
+	if respond != nil {
+		var err error
+		if content, err = respond(req); err != nil {
+			return nil, err
+		}
+	}
+
+	used := (len(req.Prompt) + len(content) + 3) / 4
+	if tokens != nil {
+		used = tokens(req, content)
+	}
+	return &llm.CompletionResponse{Content: content, FinishReason: "stop", TokensUsed: used}, nil
+}
+
+// Name returns "fake"
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Requests returns the requests received so far
+func (p *FakeProvider) Requests() []llm.CompletionRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]llm.CompletionRequest(nil), p.requests...)
+}
+
+// Calls returns how many requests have been received
+func (p *FakeProvider) Calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.requests)
+}
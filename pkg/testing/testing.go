@@ -0,0 +1,62 @@
+// Package testing helps test code built on squaremind: collectives staffed
+// with agents backed by fake LLM providers, a clock that only moves when told
+// to, generators and mutators for gossip messages, a property checker that
+// reports the seed of any failure so it can be replayed, and a load generator
+// for measuring the scheduler and market.
+//
+// Import it under another name to keep the standard library's testing:
+//
+//	import sqmtest "github.com/square-mind/squaremind/pkg/testing"
+package testing
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+)
+
+// TB is the part of testing.TB the helpers use
+type TB interface {
+	Helper()
+	Cleanup(func())
+	Fatalf(format string, args ...interface{})
+	Logf(format string, args ...interface{})
+}
+
+// SeedEnv is the environment variable that fixes the seed CheckProperty
+// starts from, to replay a reported failure
+const SeedEnv = "SQM_TEST_SEED"
+
+// CheckProperty runs a property runs times, each with a random source seeded
+// from a sequence that starts at SQM_TEST_SEED if set. The first failure
+// stops the check, reporting the seed that reproduces it.
+func CheckProperty(tb TB, runs int, property func(r *rand.Rand) error) {
+	tb.Helper()
+
+	start := int64(1)
+	if env := os.Getenv(SeedEnv); env != "" {
+		seed, err := strconv.ParseInt(env, 10, 64)
+		if err != nil {
+			tb.Fatalf("%s: %v", SeedEnv, err)
+		}
+		start = seed
+	}
+
+	for i := 0; i < runs; i++ {
+		seed := start + int64(i)
+		if err := runProperty(seed, property); err != nil {
+			tb.Fatalf("property failed with %s=%d: %v", SeedEnv, seed, err)
+		}
+	}
+}
+
+// runProperty runs a property once, turning a panic into an error
+func runProperty(seed int64, property func(r *rand.Rand) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return property(rand.New(rand.NewSource(seed)))
+}
@@ -0,0 +1,173 @@
+package testing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/coordination"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/llm"
+	sqmtest "github.com/square-mind/squaremind/pkg/testing"
+)
+
+// recordingTB captures failures instead of failing the test
+type recordingTB struct {
+	testing.TB
+	failure string
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestCheckProperty(t *testing.T) {
+	runs := 0
+	sqmtest.CheckProperty(t, 50, func(r *rand.Rand) error {
+		runs++
+		return nil
+	})
+	if runs != 50 {
+		t.Errorf("Expected 50 runs, got %d", runs)
+	}
+
+	tb := &recordingTB{TB: t}
+	sqmtest.CheckProperty(tb, 50, func(r *rand.Rand) error {
+		if r.Intn(4) == 0 {
+			panic("boom")
+		}
+		return nil
+	})
+	if tb.failure == "" {
+		t.Fatal("Expected the panicking property to fail")
+	}
+	t.Logf("reported: %s", tb.failure)
+}
+
+func TestFakeProvider(t *testing.T) {
+	p := sqmtest.NewFakeProvider("first", "second")
+	ctx := context.Background()
+
+	for _, want := range []string{"first", "second", "second"} {
+		resp, err := p.Complete(ctx, llm.CompletionRequest{Prompt: want})
+		if err != nil || resp.Content != want {
+			t.Errorf("Expected %q, got %+v (%v)", want, resp, err)
+		}
+	}
+	if p.Calls() != 3 || p.Requests()[0].Prompt != "first" {
+		t.Errorf("Expected 3 recorded requests, got %d", p.Calls())
+	}
+
+	p.Err = errors.New("rate limited")
+	if _, err := p.Complete(ctx, llm.CompletionRequest{}); err == nil {
+		t.Error("Expected the configured error")
+	}
+
+	p.Err = nil
+	p.Latency = time.Hour
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := p.Complete(cancelled, llm.CompletionRequest{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancellation to cut latency short, got %v", err)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := sqmtest.NewFakeClock(time.Time{})
+	start := clock.Now()
+
+	late := clock.After(time.Minute)
+	early := clock.After(time.Second)
+	if clock.Pending() != 2 {
+		t.Errorf("Expected 2 pending timers, got %d", clock.Pending())
+	}
+
+	clock.Advance(30 * time.Second)
+	select {
+	case at := <-early:
+		if !at.Equal(start.Add(time.Second)) {
+			t.Errorf("Expected the timer to fire at its deadline, got %v", at)
+		}
+	default:
+		t.Error("Expected the 1s timer to fire")
+	}
+	select {
+	case <-late:
+		t.Error("Expected the 1m timer to wait")
+	default:
+	}
+
+	if clock.Since(start) != 30*time.Second {
+		t.Errorf("Expected 30s elapsed, got %v", clock.Since(start))
+	}
+	clock.Set(start)
+	if !clock.Now().Equal(start.Add(30 * time.Second)) {
+		t.Error("Expected the clock not to move backwards")
+	}
+}
+
+func TestCollectiveBuilder(t *testing.T) {
+	provider := sqmtest.NewFakeProvider("reviewed")
+	tc := sqmtest.NewCollectiveBuilder("test").
+		WithProvider(provider).
+		WithAgents(2, identity.CapCodeReview).
+		WithAgent("writer", identity.CapDocumentation).
+		Build(t)
+
+	if len(tc.Agents) != 3 || tc.Size() != 3 {
+		t.Fatalf("Expected 3 agents, got %d", tc.Size())
+	}
+	if tc.Agent("writer") == nil {
+		t.Error("Expected to find the writer by name")
+	}
+
+	result, err := tc.Submit(agent.NewTask("Review this", []identity.CapabilityType{identity.CapCodeReview}))
+	if err != nil {
+		t.Fatalf("Submit failed: %v", err)
+	}
+	if result.Output != "reviewed" || provider.Calls() != 1 {
+		t.Errorf("Expected the fake provider's answer, got %q after %d calls", result.Output, provider.Calls())
+	}
+}
+
+func TestMessageFuzzer(t *testing.T) {
+	a, b := sqmtest.NewMessageFuzzer(7), sqmtest.NewMessageFuzzer(7)
+	if string(a.Encoded()) != string(b.Encoded()) {
+		t.Error("Expected the same seed to generate the same messages")
+	}
+
+	sqmtest.CheckProperty(t, 200, func(r *rand.Rand) error {
+		fuzzer := sqmtest.NewMessageFuzzer(r.Int63())
+		data := fuzzer.Mutate(fuzzer.Encoded())
+		if _, err := coordination.DecodeMessage(data); err != nil && !errors.Is(err, coordination.ErrInvalidMessage) {
+			return fmt.Errorf("decoding %q: unexpected error %v", data, err)
+		}
+		return nil
+	})
+}
+
+func TestRunLoad(t *testing.T) {
+	report, err := sqmtest.RunLoad(context.Background(), sqmtest.LoadConfig{
+		Agents:      6,
+		Tasks:       30,
+		Concurrency: 1,
+		BidTimeout:  time.Millisecond,
+		Seed:        7,
+	})
+	if err != nil {
+		t.Fatalf("RunLoad failed: %v", err)
+	}
+	if report.Completed+report.Failed != 30 || report.Completed == 0 {
+		t.Errorf("Expected 30 tasks finished, most completed, got %+v", report)
+	}
+	if report.Throughput <= 0 || report.Assignment.Max < report.Assignment.P50 || report.Assignment.P50 <= 0 {
+		t.Errorf("Expected throughput and ordered latencies, got %+v", report)
+	}
+	if report.BidsMin < 1 || report.BidsMax > 6 || report.BidsMean < float64(report.BidsMin) {
+		t.Errorf("Expected 1 to 6 bids per assigned task, got min %d mean %.1f max %d", report.BidsMin, report.BidsMean, report.BidsMax)
+	}
+}
@@ -0,0 +1,500 @@
+// Package tools connects agents to tools exposed by Model Context Protocol
+// (MCP) servers - filesystems, GitHub, databases - and maps the tools'
+// schemas to the LLM providers' tool-calling format. The MCP client speaks
+// JSON-RPC 2.0 over a server's stdio or its streamable HTTP endpoint.
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrClosed     = errors.New("mcp connection closed")
+	ErrToolFailed = errors.New("tool call failed")
+)
+
+// ProtocolVersion is the MCP revision the client speaks
+const ProtocolVersion = "2025-03-26"
+
+// clientName identifies squaremind to MCP servers
+const clientName = "squaremind"
+
+// RPCError is a JSON-RPC error returned by a server
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// message is a JSON-RPC request, notification or response
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// Transport carries JSON-RPC messages to an MCP server
+type Transport interface {
+	// RoundTrip sends a request and returns its response, or sends a
+	// notification (no ID) and returns nil
+	RoundTrip(ctx context.Context, msg *message) (*message, error)
+	Close() error
+}
+
+// Tool is a tool an MCP server exposes
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+}
+
+// Content is one item of a tool call's result
+type Content struct {
+	Type     string    `json:"type"` // text, image, audio or resource
+	Text     string    `json:"text,omitempty"`
+	MimeType string    `json:"mimeType,omitempty"`
+	Resource *Resource `json:"resource,omitempty"`
+}
+
+// Resource is a resource embedded in a tool call's result
+type Resource struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+}
+
+// CallResult is the result of a tool call
+type CallResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text renders the result as text for an LLM: text and embedded text
+// resources as they are, other content as a placeholder naming its type
+func (r *CallResult) Text() string {
+	parts := make([]string, 0, len(r.Content))
+	for _, c := range r.Content {
+		switch {
+		case c.Type == "text":
+			parts = append(parts, c.Text)
+		case c.Resource != nil && c.Resource.Text != "":
+			parts = append(parts, fmt.Sprintf("[%s]\n%s", c.Resource.URI, c.Resource.Text))
+		case c.Resource != nil:
+			parts = append(parts, fmt.Sprintf("[resource: %s]", c.Resource.URI))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s: %s]", c.Type, c.MimeType))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ServerInfo identifies a connected MCP server
+type ServerInfo struct {
+	Name            string `json:"name"`
+	Version         string `json:"version"`
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+// Client is a connection to an MCP server
+type Client struct {
+	transport Transport
+	nextID    atomic.Int64
+	server    ServerInfo
+}
+
+// NewClient creates a client over a transport. Call Initialize before
+// anything else.
+func NewClient(t Transport) *Client {
+	return &Client{transport: t}
+}
+
+// Initialize performs the MCP handshake
+func (c *Client) Initialize(ctx context.Context) error {
+	var result struct {
+		ProtocolVersion string     `json:"protocolVersion"`
+		ServerInfo      ServerInfo `json:"serverInfo"`
+	}
+	err := c.call(ctx, "initialize", map[string]any{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": clientName, "version": "1"},
+	}, &result)
+	if err != nil {
+		return err
+	}
+	c.server = result.ServerInfo
+	c.server.ProtocolVersion = result.ProtocolVersion
+	return c.notify(ctx, "notifications/initialized")
+}
+
+// Server returns what the server reported about itself when initialized
+func (c *Client) Server() ServerInfo {
+	return c.server
+}
+
+// ListTools returns every tool the server exposes
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls a tool with JSON-encoded arguments
+func (c *Client) CallTool(ctx context.Context, name string, args json.RawMessage) (*CallResult, error) {
+	if len(args) == 0 {
+		args = json.RawMessage(`{}`)
+	}
+	var result CallResult
+	err := c.call(ctx, "tools/call", map[string]any{"name": name, "arguments": args}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	id := c.nextID.Add(1)
+	resp, err := c.transport.RoundTrip(ctx, &message{JSONRPC: "2.0", ID: &id, Method: method, Params: raw})
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: %w", method, resp.Error)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("%s: invalid result: %w", method, err)
+	}
+	return nil
+}
+
+func (c *Client) notify(ctx context.Context, method string) error {
+	_, err := c.transport.RoundTrip(ctx, &message{JSONRPC: "2.0", Method: method})
+	return err
+}
+
+// StreamTransport speaks newline-delimited JSON-RPC over a pair of streams,
+// as MCP servers do on stdio. Requests from the server are answered: pings
+// with an empty result, anything else with "method not found".
+type StreamTransport struct {
+	mu      sync.Mutex
+	wmu     sync.Mutex // Serializes writes to w
+	w       io.WriteCloser
+	pending map[int64]chan *message
+	err     error // Set once reading stops
+	done    chan struct{}
+	closeFn func() error
+}
+
+// NewStreamTransport reads messages from r and writes them to w
+func NewStreamTransport(r io.Reader, w io.WriteCloser) *StreamTransport {
+	t := &StreamTransport{
+		w:       w,
+		pending: make(map[int64]chan *message),
+		done:    make(chan struct{}),
+	}
+	go t.read(r)
+	return t
+}
+
+// NewStdioTransport starts an MCP server command and speaks to it over its
+// stdin and stdout. env is added to the current environment.
+func NewStdioTransport(command []string, env map[string]string) (*StreamTransport, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("%w: no command", ErrInvalidServer)
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = io.Discard
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting %s: %w", command[0], err)
+	}
+	t := NewStreamTransport(stdout, stdin)
+	t.closeFn = func() error {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil
+	}
+	return t, nil
+}
+
+// RoundTrip writes a message and, for requests, waits for its response
+func (t *StreamTransport) RoundTrip(ctx context.Context, msg *message) (*message, error) {
+	var ch chan *message
+	t.mu.Lock()
+	if t.err != nil {
+		err := t.err
+		t.mu.Unlock()
+		return nil, err
+	}
+	if msg.ID != nil {
+		ch = make(chan *message, 1)
+		t.pending[*msg.ID] = ch
+	}
+	t.mu.Unlock()
+	err := t.write(msg)
+	if err != nil || ch == nil {
+		if ch != nil {
+			t.forget(*msg.ID)
+		}
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, t.err
+	case <-ctx.Done():
+		t.forget(*msg.ID)
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the server's input and stops it
+func (t *StreamTransport) Close() error {
+	err := t.w.Close()
+	if t.closeFn != nil {
+		_ = t.closeFn()
+	}
+	return err
+}
+
+func (t *StreamTransport) forget(id int64) {
+	t.mu.Lock()
+	delete(t.pending, id)
+	t.mu.Unlock()
+}
+
+func (t *StreamTransport) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	_, err = t.w.Write(append(data, '\n'))
+	return err
+}
+
+// read dispatches the server's messages until the stream ends
+func (t *StreamTransport) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue // Servers may log to stdout; skip what isn't JSON-RPC
+		}
+		t.dispatch(&msg)
+	}
+
+	t.mu.Lock()
+	t.err = scanner.Err()
+	if t.err == nil {
+		t.err = ErrClosed
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+func (t *StreamTransport) dispatch(msg *message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch {
+	case msg.Method != "" && msg.ID != nil:
+		reply := &message{JSONRPC: "2.0", ID: msg.ID}
+		if msg.Method == "ping" {
+			reply.Result = json.RawMessage(`{}`)
+		} else {
+			reply.Error = &RPCError{Code: -32601, Message: "method not found"}
+		}
+		// Answered off the read loop, which mustn't block on writing
+		go func() { _ = t.write(reply) }()
+	case msg.Method != "":
+		// Notifications (logging, list changes) need no answer
+	case msg.ID != nil:
+		if ch, ok := t.pending[*msg.ID]; ok {
+			delete(t.pending, *msg.ID)
+			ch <- msg
+		}
+	}
+}
+
+// HTTPTransport speaks to an MCP server's streamable HTTP endpoint. Each
+// message is POSTed; responses come back as JSON or as a server-sent event
+// stream.
+type HTTPTransport struct {
+	URL     string
+	Headers map[string]string // Sent with every request (e.g. Authorization)
+	Client  *http.Client
+
+	mu      sync.Mutex
+	session string // Mcp-Session-Id assigned by the server
+}
+
+// NewHTTPTransport creates a transport for a streamable HTTP endpoint
+func NewHTTPTransport(url string, headers map[string]string) *HTTPTransport {
+	return &HTTPTransport{URL: url, Headers: headers, Client: http.DefaultClient}
+}
+
+// RoundTrip POSTs a message and, for requests, reads its response
+func (t *HTTPTransport) RoundTrip(ctx context.Context, msg *message) (*message, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.session != "" {
+		req.Header.Set("Mcp-Session-Id", t.session)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if session := resp.Header.Get("Mcp-Session-Id"); session != "" {
+		t.mu.Lock()
+		t.session = session
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("mcp server returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if msg.ID == nil {
+		return nil, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return readEventResponse(resp.Body, *msg.ID)
+	}
+	var reply message
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("invalid mcp response: %w", err)
+	}
+	return &reply, nil
+}
+
+// Close ends the session
+func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	session := t.session
+	t.mu.Unlock()
+	if session == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", session)
+	for k, v := range t.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// readEventResponse reads a server-sent event stream until the response to
+// a request arrives
+func readEventResponse(r io.Reader, id int64) (*message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data:") {
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		var msg message
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err == nil && msg.Method == "" && msg.ID != nil && *msg.ID == id {
+			return &msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: event stream ended without a response", ErrClosed)
+}
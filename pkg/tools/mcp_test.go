@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers MCP requests with two tools: echo, and fail which
+// returns an error result. tools/list is paginated one tool per page.
+func fakeServer(msg *message) *message {
+	if msg.ID == nil {
+		return nil
+	}
+	reply := &message{JSONRPC: "2.0", ID: msg.ID}
+	switch msg.Method {
+	case "initialize":
+		reply.Result = json.RawMessage(`{"protocolVersion":"2025-03-26","capabilities":{"tools":{}},"serverInfo":{"name":"fake","version":"1.0"}}`)
+	case "tools/list":
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		if params.Cursor == "" {
+			reply.Result = json.RawMessage(`{"tools":[{"name":"echo","description":"Echo the text","inputSchema":{"type":"object","properties":{"text":{"type":"string"}}}}],"nextCursor":"2"}`)
+		} else {
+			reply.Result = json.RawMessage(`{"tools":[{"name":"fail","description":"Always fails"}]}`)
+		}
+	case "tools/call":
+		var params struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				Text string `json:"text"`
+			} `json:"arguments"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		if params.Name == "fail" {
+			reply.Result = json.RawMessage(`{"content":[{"type":"text","text":"boom"}],"isError":true}`)
+			break
+		}
+		result, _ := json.Marshal(CallResult{Content: []Content{{Type: "text", Text: params.Arguments.Text}}})
+		reply.Result = result
+	default:
+		reply.Error = &RPCError{Code: -32601, Message: "method not found"}
+	}
+	return reply
+}
+
+func TestStreamTransport(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(serverR)
+		for scanner.Scan() {
+			var msg message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				continue
+			}
+			// A log line and a ping from the server come before each answer
+			fmt.Fprintln(serverW, "starting up")
+			fmt.Fprintln(serverW, `{"jsonrpc":"2.0","id":99,"method":"ping"}`)
+			if reply := fakeServer(&msg); reply != nil {
+				data, _ := json.Marshal(reply)
+				fmt.Fprintln(serverW, string(data))
+			}
+		}
+		serverW.Close()
+	}()
+
+	client := NewClient(NewStreamTransport(clientR, clientW))
+	ctx := context.Background()
+	if err := client.Initialize(ctx); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	if client.Server().Name != "fake" {
+		t.Errorf("Expected server fake, got %q", client.Server().Name)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Errorf("Expected both pages of tools, got %v", tools)
+	}
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"text":"hi"}`))
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if result.Text() != "hi" || result.IsError {
+		t.Errorf("Expected hi, got %q", result.Text())
+	}
+
+	if err := client.call(ctx, "resources/list", nil, &struct{}{}); err == nil {
+		t.Error("Expected an error for an unknown method")
+	}
+
+	client.Close()
+	if _, err := client.ListTools(ctx); err == nil {
+		t.Error("Expected an error after closing")
+	}
+}
+
+func TestToolbox_HTTP(t *testing.T) {
+	var sessions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		sessions = append(sessions, r.Header.Get("Mcp-Session-Id"))
+		var msg message
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		reply := fakeServer(&msg)
+		if reply == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := json.Marshal(reply)
+		w.Header().Set("Mcp-Session-Id", "s1")
+		// Tool calls are answered as an event stream
+		if msg.Method == "tools/call" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	box, err := NewToolbox(ServerConfig{
+		Name:    "remote.fs",
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+	})
+	if err != nil {
+		t.Fatalf("NewToolbox failed: %v", err)
+	}
+	defer box.Close()
+
+	ctx := context.Background()
+	defs, err := box.Tools(ctx)
+	if err != nil {
+		t.Fatalf("Tools failed: %v", err)
+	}
+	if len(defs) != 2 || defs[0].Name != "remote_fs__echo" {
+		t.Fatalf("Expected prefixed tool names, got %v", defs)
+	}
+	if !strings.Contains(string(defs[0].InputSchema), `"text"`) || !strings.Contains(defs[0].Description, "remote.fs") {
+		t.Errorf("Expected the schema and server carried over, got %s and %q", defs[0].InputSchema, defs[0].Description)
+	}
+	if sessions[len(sessions)-1] != "s1" {
+		t.Errorf("Expected the session ID sent back, got %v", sessions)
+	}
+
+	out, err := box.Call(ctx, "remote_fs__echo", json.RawMessage(`{"text":"hello"}`))
+	if err != nil || out != "hello" {
+		t.Errorf("Expected hello, got %q, %v", out, err)
+	}
+	if out, err := box.Call(ctx, "remote_fs__fail", nil); !errors.Is(err, ErrToolFailed) || out != "boom" {
+		t.Errorf("Expected ErrToolFailed with the output, got %q, %v", out, err)
+	}
+	if _, err := box.Call(ctx, "nope", nil); !errors.Is(err, ErrUnknownTool) {
+		t.Errorf("Expected ErrUnknownTool, got %v", err)
+	}
+}
+
+func TestToolbox_Filter(t *testing.T) {
+	box, _ := NewToolbox(ServerConfig{Name: "fs", URL: "http://unused", Tools: []string{"fail"}})
+	box.addTools(box.servers[0], nil, []Tool{{Name: "echo"}, {Name: "fail"}})
+	if list := box.List(); len(list) != 1 || list[0].Name != "fs__fail" {
+		t.Errorf("Expected only the allowed tool, got %v", list)
+	}
+
+	for _, cfg := range [][]ServerConfig{
+		{{Name: "x"}},
+		{{Name: "x", URL: "http://a", Command: []string{"srv"}}},
+		{{Name: "x", URL: "http://a"}, {Name: "x", URL: "http://b"}},
+	} {
+		if _, err := NewToolbox(cfg...); !errors.Is(err, ErrInvalidServer) {
+			t.Errorf("Expected ErrInvalidServer for %v, got %v", cfg, err)
+		}
+	}
+}
+
+func TestToolName(t *testing.T) {
+	if name := ToolName("git hub", "search.issues"); name != "git_hub__search_issues" {
+		t.Errorf("Expected git_hub__search_issues, got %s", name)
+	}
+	if name := ToolName("s", strings.Repeat("x", 100)); len(name) != 64 {
+		t.Errorf("Expected a name cut to 64 characters, got %d", len(name))
+	}
+}
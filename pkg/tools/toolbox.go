@@ -0,0 +1,249 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/square-mind/squaremind/pkg/llm"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+var (
+	ErrInvalidServer = errors.New("invalid mcp server")
+	ErrUnknownTool   = errors.New("unknown tool")
+)
+
+// maxToolName is the longest tool name providers accept
+const maxToolName = 64
+
+// invalidToolChars are the characters providers don't accept in tool names
+var invalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// ServerConfig configures an MCP server: a command speaking MCP on stdio,
+// or a streamable HTTP endpoint
+type ServerConfig struct {
+	Name    string            `json:"name" yaml:"name"`                           // Prefixes the server's tool names
+	Command []string          `json:"command,omitempty" yaml:"command,omitempty"` // Program and arguments run for stdio
+	Env     map[string]string `json:"-" yaml:"env,omitempty"`                     // Added to the command's environment
+	URL     string            `json:"url,omitempty" yaml:"url,omitempty"`         // Streamable HTTP endpoint
+	Headers map[string]string `json:"-" yaml:"headers,omitempty"`                 // Sent with every HTTP request
+	Tools   []string          `json:"tools,omitempty" yaml:"tools,omitempty"`     // Only expose these (empty = all)
+}
+
+func (c ServerConfig) validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("%w: no name", ErrInvalidServer)
+	case len(c.Command) == 0 && c.URL == "":
+		return fmt.Errorf("%w: %s needs a command or a url", ErrInvalidServer, c.Name)
+	case len(c.Command) > 0 && c.URL != "":
+		return fmt.Errorf("%w: %s has both a command and a url", ErrInvalidServer, c.Name)
+	}
+	return nil
+}
+
+// connect starts or dials the server and performs the handshake
+func (c ServerConfig) connect(ctx context.Context) (*Client, error) {
+	var t Transport
+	if c.URL != "" {
+		t = NewHTTPTransport(c.URL, c.Headers)
+	} else {
+		stdio, err := NewStdioTransport(c.Command, c.Env)
+		if err != nil {
+			return nil, err
+		}
+		t = stdio
+	}
+	client := NewClient(t)
+	if err := client.Initialize(ctx); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// ToolInfo describes a tool in a toolbox
+type ToolInfo struct {
+	Name        string `json:"name"` // As the LLM sees it: server__tool
+	Server      string `json:"server"`
+	Tool        string `json:"tool"` // As the server names it
+	Description string `json:"description,omitempty"`
+}
+
+// toolRef locates a toolbox tool on its server
+type toolRef struct {
+	info   ToolInfo
+	schema json.RawMessage
+	client *Client
+}
+
+// Toolbox gathers the tools of several MCP servers under unique names. It
+// connects to the servers the first time its tools are asked for; a server
+// that can't be reached is skipped and retried on the next call.
+type Toolbox struct {
+	mu      sync.Mutex
+	servers []ServerConfig
+	clients map[string]*Client
+	tools   map[string]toolRef
+	logger  logging.Logger
+}
+
+// NewToolbox creates a toolbox for the configured servers
+func NewToolbox(servers ...ServerConfig) (*Toolbox, error) {
+	seen := make(map[string]bool, len(servers))
+	for _, s := range servers {
+		if err := s.validate(); err != nil {
+			return nil, err
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("%w: duplicate name %s", ErrInvalidServer, s.Name)
+		}
+		seen[s.Name] = true
+	}
+	return &Toolbox{
+		servers: servers,
+		clients: make(map[string]*Client),
+		tools:   make(map[string]toolRef),
+		logger:  logging.Component("tools"),
+	}, nil
+}
+
+// Connect connects to the servers not yet connected and discovers their
+// tools. Errors from unreachable servers are joined; the others stay usable.
+func (b *Toolbox) Connect(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var errs []error
+	for _, s := range b.servers {
+		if _, ok := b.clients[s.Name]; ok {
+			continue
+		}
+		client, err := s.connect(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, err))
+			continue
+		}
+		tools, err := client.ListTools(ctx)
+		if err != nil {
+			_ = client.Close()
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, err))
+			continue
+		}
+		b.clients[s.Name] = client
+		b.addTools(s, client, tools)
+		b.logger.Info("mcp server connected", "server", s.Name, "tools", len(tools), "name", client.Server().Name)
+	}
+	return errors.Join(errs...)
+}
+
+// addTools registers the tools a server exposes under prefixed names
+func (b *Toolbox) addTools(s ServerConfig, client *Client, tools []Tool) {
+	allowed := make(map[string]bool, len(s.Tools))
+	for _, name := range s.Tools {
+		allowed[name] = true
+	}
+	for _, t := range tools {
+		if len(allowed) > 0 && !allowed[t.Name] {
+			continue
+		}
+		name := ToolName(s.Name, t.Name)
+		b.tools[name] = toolRef{
+			info:   ToolInfo{Name: name, Server: s.Name, Tool: t.Name, Description: t.Description},
+			schema: t.InputSchema,
+			client: client,
+		}
+	}
+}
+
+// ToolName is the name a server's tool is offered to the LLM under:
+// server__tool, with characters providers reject replaced and cut to the
+// length they accept
+func ToolName(server, tool string) string {
+	name := invalidToolChars.ReplaceAllString(server+"__"+tool, "_")
+	if len(name) > maxToolName {
+		name = name[:maxToolName]
+	}
+	return name
+}
+
+// List returns the tools discovered so far, by name
+func (b *Toolbox) List() []ToolInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	infos := make([]ToolInfo, 0, len(b.tools))
+	for _, t := range b.tools {
+		infos = append(infos, t.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Tools connects to the servers if needed and returns their tools as LLM
+// tool definitions. Servers that can't be reached are logged and left out.
+func (b *Toolbox) Tools(ctx context.Context) ([]llm.Tool, error) {
+	if err := b.Connect(ctx); err != nil {
+		b.logger.Warn("mcp servers unavailable", "error", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	defs := make([]llm.Tool, 0, len(b.tools))
+	for name, t := range b.tools {
+		defs = append(defs, llm.Tool{
+			Name:        name,
+			Description: describeTool(t.info),
+			InputSchema: t.schema,
+		})
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
+
+// describeTool notes the server a tool comes from in its description
+func describeTool(info ToolInfo) string {
+	desc := strings.TrimSpace(info.Description)
+	if desc == "" {
+		return fmt.Sprintf("%s (from %s)", info.Tool, info.Server)
+	}
+	return fmt.Sprintf("%s (from %s)", desc, info.Server)
+}
+
+// Call calls a tool by its toolbox name and returns its result as text. A
+// result the server flags as an error is returned with ErrToolFailed.
+func (b *Toolbox) Call(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	b.mu.Lock()
+	t, ok := b.tools[name]
+	b.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	result, err := t.client.CallTool(ctx, t.info.Tool, input)
+	if err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return result.Text(), fmt.Errorf("%w: %s", ErrToolFailed, result.Text())
+	}
+	return result.Text(), nil
+}
+
+// Close disconnects from every server
+func (b *Toolbox) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var errs []error
+	for name, client := range b.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	b.clients = make(map[string]*Client)
+	b.tools = make(map[string]toolRef)
+	return errors.Join(errs...)
+}
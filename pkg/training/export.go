@@ -0,0 +1,165 @@
+package training
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// Format selects the shape of each exported JSONL line
+type Format string
+
+const (
+	// FormatChat emits {"messages": [...]} with system, user and assistant turns
+	FormatChat Format = "chat"
+	// FormatCompletion emits {"prompt": ..., "completion": ...}
+	FormatCompletion Format = "completion"
+)
+
+// Filter selects which executions are exported
+type Filter struct {
+	MinQuality    float64                   // Minimum quality score (inclusive)
+	Capabilities  []identity.CapabilityType // Keep executions requiring any of these (empty = all)
+	IncludeFailed bool                      // Export failed executions too
+}
+
+// ExportOptions configures a dataset export
+type ExportOptions struct {
+	Format          Format
+	Filter          Filter
+	IncludeCritique bool // Append reviewer critique to the system prompt
+}
+
+// DefaultExportOptions returns sensible defaults
+func DefaultExportOptions() ExportOptions {
+	return ExportOptions{
+		Format: FormatChat,
+		Filter: Filter{MinQuality: 0.7},
+	}
+}
+
+// ExportStats reports the outcome of an export
+type ExportStats struct {
+	Total      int
+	Exported   int
+	LowQuality int
+	Failed     int
+	Unmatched  int // Did not require any of the filtered capabilities
+}
+
+// chatMessage is one turn in a chat-format example
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatExample is one line of a chat-format dataset
+type chatExample struct {
+	Messages []chatMessage `json:"messages"`
+}
+
+// completionExample is one line of a completion-format dataset
+type completionExample struct {
+	Prompt     string `json:"prompt"`
+	Completion string `json:"completion"`
+}
+
+// Export writes the records that pass the filter to w as a JSONL fine-tuning dataset
+func Export(w io.Writer, records []agent.ExecutionRecord, opts ExportOptions) (ExportStats, error) {
+	stats := ExportStats{Total: len(records)}
+	enc := json.NewEncoder(w)
+
+	for _, record := range records {
+		if !opts.Filter.IncludeFailed && record.Status != agent.TaskCompleted {
+			stats.Failed++
+			continue
+		}
+		if record.Quality < opts.Filter.MinQuality {
+			stats.LowQuality++
+			continue
+		}
+		if !matchesCapabilities(record, opts.Filter.Capabilities) {
+			stats.Unmatched++
+			continue
+		}
+
+		var example interface{}
+		switch opts.Format {
+		case FormatChat, "":
+			example = toChat(record, opts.IncludeCritique)
+		case FormatCompletion:
+			example = toCompletion(record)
+		default:
+			return stats, fmt.Errorf("unknown export format %q", opts.Format)
+		}
+
+		if err := enc.Encode(example); err != nil {
+			return stats, err
+		}
+		stats.Exported++
+	}
+
+	return stats, nil
+}
+
+// matchesCapabilities reports whether a record required any of the given capabilities
+func matchesCapabilities(record agent.ExecutionRecord, caps []identity.CapabilityType) bool {
+	if len(caps) == 0 {
+		return true
+	}
+	for _, want := range caps {
+		for _, have := range record.Capabilities {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// userContent combines the prompt with any supplied context
+func userContent(record agent.ExecutionRecord) string {
+	if record.Context == "" || strings.Contains(record.Prompt, record.Context) {
+		return record.Prompt
+	}
+	return record.Prompt + "\n\nContext:\n" + record.Context
+}
+
+// toChat converts a record into a chat-format example
+func toChat(record agent.ExecutionRecord, includeCritique bool) chatExample {
+	var messages []chatMessage
+
+	system := record.System
+	if includeCritique && record.Critique != "" {
+		if system != "" {
+			system += "\n\n"
+		}
+		system += "Reviewer guidance:\n" + record.Critique
+	}
+	if system != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: system})
+	}
+
+	messages = append(messages,
+		chatMessage{Role: "user", Content: userContent(record)},
+		chatMessage{Role: "assistant", Content: record.Output},
+	)
+
+	return chatExample{Messages: messages}
+}
+
+// toCompletion converts a record into a completion-format example
+func toCompletion(record agent.ExecutionRecord) completionExample {
+	prompt := userContent(record)
+	if record.System != "" {
+		prompt = record.System + "\n\n" + prompt
+	}
+	return completionExample{
+		Prompt:     prompt,
+		Completion: record.Output,
+	}
+}
@@ -0,0 +1,121 @@
+package training
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+func testRecords() []agent.ExecutionRecord {
+	now := time.Now()
+	return []agent.ExecutionRecord{
+		{TaskID: "t1", Prompt: "Write a parser", Output: "func parse() {}", Status: agent.TaskCompleted, Quality: 0.9,
+			Capabilities: []identity.CapabilityType{identity.CapCodeWrite}, Timestamp: now},
+		{TaskID: "t2", Prompt: "Review the parser", Output: "Looks fine", Status: agent.TaskCompleted, Quality: 0.5,
+			Capabilities: []identity.CapabilityType{identity.CapCodeReview}, Timestamp: now.Add(time.Second)},
+		{TaskID: "t3", Prompt: "Write docs", Output: "", Status: agent.TaskFailed, Quality: 0,
+			Capabilities: []identity.CapabilityType{identity.CapDocumentation}, Timestamp: now.Add(2 * time.Second)},
+		{TaskID: "t4", Prompt: "Review the lexer", Output: "Handle EOF", Status: agent.TaskCompleted, Quality: 0.85,
+			Capabilities: []identity.CapabilityType{identity.CapCodeReview}, Timestamp: now.Add(3 * time.Second)},
+	}
+}
+
+func TestExport_Filter(t *testing.T) {
+	var buf bytes.Buffer
+	stats, err := Export(&buf, testRecords(), ExportOptions{
+		Format: FormatChat,
+		Filter: Filter{MinQuality: 0.8, Capabilities: []identity.CapabilityType{identity.CapCodeWrite}},
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if stats.Exported != 1 {
+		t.Errorf("Expected 1 exported example, got %d", stats.Exported)
+	}
+	if stats.Failed != 1 || stats.LowQuality != 1 || stats.Unmatched != 1 {
+		t.Errorf("Unexpected skip counts: %+v", stats)
+	}
+
+	var example chatExample
+	if err := json.Unmarshal(buf.Bytes(), &example); err != nil {
+		t.Fatalf("Invalid JSONL line: %v", err)
+	}
+	if len(example.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(example.Messages))
+	}
+	if example.Messages[1].Role != "assistant" || example.Messages[1].Content != "func parse() {}" {
+		t.Errorf("Unexpected assistant message: %+v", example.Messages[1])
+	}
+}
+
+func TestExport_CompletionFormat(t *testing.T) {
+	var buf bytes.Buffer
+	stats, err := Export(&buf, testRecords(), ExportOptions{
+		Format: FormatCompletion,
+		Filter: Filter{MinQuality: 0.8},
+	})
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if stats.Exported != 2 || len(lines) != 2 {
+		t.Fatalf("Expected 2 exported lines, got %d (%d lines)", stats.Exported, len(lines))
+	}
+
+	var example completionExample
+	if err := json.Unmarshal([]byte(lines[1]), &example); err != nil {
+		t.Fatalf("Invalid JSONL line: %v", err)
+	}
+	if example.Prompt != "Review the lexer" {
+		t.Errorf("Expected prompt 'Review the lexer', got '%s'", example.Prompt)
+	}
+}
+
+func TestExecutionLog_PersistAndAnnotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "executions.jsonl")
+
+	log, err := OpenExecutionLog(path)
+	if err != nil {
+		t.Fatalf("OpenExecutionLog failed: %v", err)
+	}
+	for _, record := range testRecords() {
+		log.Record(record)
+	}
+	if err := log.Annotate("t2", "Missed the nil check", 0.3); err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	if err := log.Annotate("missing", "", 0); err != ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound, got %v", err)
+	}
+	_ = log.Close()
+
+	reopened, err := OpenExecutionLog(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	if reopened.Len() != 4 {
+		t.Errorf("Expected 4 records, got %d", reopened.Len())
+	}
+
+	record, ok := reopened.Get("t2")
+	if !ok {
+		t.Fatal("Expected annotated record")
+	}
+	if record.Critique != "Missed the nil check" || record.Quality != 0.3 {
+		t.Errorf("Annotation not persisted: %+v", record)
+	}
+
+	if records := reopened.Records(); records[0].TaskID != "t1" {
+		t.Errorf("Expected records oldest first, got %s first", records[0].TaskID)
+	}
+}
@@ -0,0 +1,195 @@
+package training
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+var ErrRecordNotFound = errors.New("execution record not found")
+
+// ExecutionLog collects execution records, optionally appending them to a JSONL
+// file. It implements agent.ExecutionRecorder.
+type ExecutionLog struct {
+	mu sync.RWMutex
+
+	records map[string]*agent.ExecutionRecord // TaskID -> latest record
+	order   []string                          // TaskIDs in first-seen order
+
+	file *os.File
+}
+
+// NewExecutionLog creates an in-memory execution log
+func NewExecutionLog() *ExecutionLog {
+	return &ExecutionLog{
+		records: make(map[string]*agent.ExecutionRecord),
+	}
+}
+
+// OpenExecutionLog loads an execution log from a JSONL file and appends new
+// records to it. Later lines for the same task supersede earlier ones, so
+// annotations are stored as updated copies of the record.
+func OpenExecutionLog(path string) (*ExecutionLog, error) {
+	l := NewExecutionLog()
+
+	if err := l.load(path); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l.file = f
+
+	return l, nil
+}
+
+// LoadExecutionLog reads an execution log from a JSONL file without opening it
+// for writing. A missing file yields an empty log.
+func LoadExecutionLog(path string) (*ExecutionLog, error) {
+	l := NewExecutionLog()
+	if err := l.load(path); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// load reads existing records from a JSONL file, if it exists
+func (l *ExecutionLog) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record agent.ExecutionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		l.put(record)
+	}
+
+	return scanner.Err()
+}
+
+// put stores a record, replacing any earlier record for the same task. Caller
+// must hold l.mu or have exclusive access.
+func (l *ExecutionLog) put(record agent.ExecutionRecord) {
+	if _, exists := l.records[record.TaskID]; !exists {
+		l.order = append(l.order, record.TaskID)
+	}
+	l.records[record.TaskID] = &record
+}
+
+// append writes a record to the backing file, if any. Caller must hold l.mu.
+func (l *ExecutionLog) append(record agent.ExecutionRecord) error {
+	if l.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// Record adds an execution record to the log
+func (l *ExecutionLog) Record(record agent.ExecutionRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.put(record)
+	_ = l.append(record)
+}
+
+// Annotate attaches a reviewer critique and revised quality score to a recorded execution
+func (l *ExecutionLog) Annotate(taskID, critique string, quality float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	existing, ok := l.records[taskID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	record := *existing
+	record.Critique = critique
+	record.Quality = quality
+	l.put(record)
+
+	return l.append(record)
+}
+
+// Get returns the record for a task
+func (l *ExecutionLog) Get(taskID string) (agent.ExecutionRecord, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	record, ok := l.records[taskID]
+	if !ok {
+		return agent.ExecutionRecord{}, false
+	}
+	return *record, true
+}
+
+// Records returns all records, oldest first
+func (l *ExecutionLog) Records() []agent.ExecutionRecord {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	records := make([]agent.ExecutionRecord, 0, len(l.order))
+	for _, id := range l.order {
+		records = append(records, *l.records[id])
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records
+}
+
+// Len returns the number of recorded executions
+func (l *ExecutionLog) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.records)
+}
+
+// Close closes the backing file, if any
+func (l *ExecutionLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
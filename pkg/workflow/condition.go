@@ -0,0 +1,133 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Condition tests a field of a step's result. Field is one of status,
+// quality, iterations or output, or output.<path> to read a field of the JSON
+// object in the step's output (e.g. output.verdict or output.review.score).
+// Every operator given must hold; a missing field matches nothing.
+type Condition struct {
+	Step      string   `yaml:"step,omitempty" json:"step,omitempty"` // Upstream step (empty in a loop's until = the looping step)
+	Field     string   `yaml:"field" json:"field"`
+	Equals    string   `yaml:"equals,omitempty" json:"equals,omitempty"`
+	NotEquals string   `yaml:"not_equals,omitempty" json:"not_equals,omitempty"`
+	In        []string `yaml:"in,omitempty" json:"in,omitempty"`
+	Min       *float64 `yaml:"min,omitempty" json:"min,omitempty"` // Numeric, inclusive
+	Max       *float64 `yaml:"max,omitempty" json:"max,omitempty"` // Numeric, inclusive
+}
+
+// Loop repeats a step until its result satisfies Until or MaxIterations is
+// reached. Each iteration after the first sees the previous result as
+// .Previous and its 1-based number as .Iteration, so a review-revise cycle is
+// a single step whose task asks to improve .Previous.Output.
+type Loop struct {
+	Until         *Condition `yaml:"until,omitempty" json:"until,omitempty"`
+	MaxIterations int        `yaml:"max_iterations" json:"max_iterations"`
+}
+
+// validate checks that the condition names a field and an operator
+func (c *Condition) validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("%w: condition has no field", ErrInvalidWorkflow)
+	}
+	switch field, _, _ := strings.Cut(c.Field, "."); field {
+	case "status", "quality", "iterations", "output":
+	default:
+		return fmt.Errorf("%w: unknown condition field %q", ErrInvalidWorkflow, c.Field)
+	}
+	if c.Equals == "" && c.NotEquals == "" && len(c.In) == 0 && c.Min == nil && c.Max == nil {
+		return fmt.Errorf("%w: condition on %q has no operator", ErrInvalidWorkflow, c.Field)
+	}
+	return nil
+}
+
+// Evaluate reports whether a step's result satisfies the condition
+func (c *Condition) Evaluate(sr *StepRun) bool {
+	value, ok := c.lookup(sr)
+	if !ok {
+		return false
+	}
+
+	if c.Equals != "" && value != c.Equals {
+		return false
+	}
+	if c.NotEquals != "" && value == c.NotEquals {
+		return false
+	}
+	if len(c.In) > 0 {
+		found := false
+		for _, v := range c.In {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.Min != nil || c.Max != nil {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		if (c.Min != nil && n < *c.Min) || (c.Max != nil && n > *c.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the condition's field as a string
+func (c *Condition) lookup(sr *StepRun) (string, bool) {
+	switch c.Field {
+	case "status":
+		return string(sr.Status), true
+	case "quality":
+		return strconv.FormatFloat(sr.Quality, 'f', -1, 64), true
+	case "iterations":
+		return strconv.Itoa(sr.Iterations), true
+	case "output":
+		return strings.TrimSpace(sr.Output), true
+	}
+
+	path := strings.Split(strings.TrimPrefix(c.Field, "output."), ".")
+	var value interface{} = outputObject(sr.Output)
+	for _, key := range path {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = obj[key]; !ok {
+			return "", false
+		}
+	}
+	switch v := value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		return "", false
+	case string:
+		return v, true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// outputObject parses the JSON object in an agent's output, tolerating prose
+// or a code fence around it. Returns nil if there is none.
+func outputObject(output string) map[string]interface{} {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &obj); err != nil {
+		return nil
+	}
+	return obj
+}
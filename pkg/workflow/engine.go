@@ -0,0 +1,751 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+	"github.com/square-mind/squaremind/pkg/identity"
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+// ErrUnknownAction is returned when a compensation names an unregistered action
+var ErrUnknownAction = errors.New("unknown workflow action")
+
+// Submitter runs tasks; *collective.Collective satisfies it
+type Submitter interface {
+	SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error)
+}
+
+// Action is a named Go function a workflow can invoke, such as reverting a
+// git branch or deleting generated files
+type Action func(ctx context.Context, run *Run, step *StepRun) error
+
+// RunStatus is the state of a workflow run
+type RunStatus string
+
+const (
+	RunRunning   RunStatus = "running"
+	RunCompleted RunStatus = "completed"
+	RunFailed    RunStatus = "failed"
+)
+
+// StepStatus is the state of a step within a run
+type StepStatus string
+
+const (
+	StepPending            StepStatus = "pending"
+	StepRunning            StepStatus = "running"
+	StepCompleted          StepStatus = "completed"
+	StepFailed             StepStatus = "failed"
+	StepSkipped            StepStatus = "skipped" // Branch not taken, or the workflow failed first
+	StepCompensated        StepStatus = "compensated"
+	StepCompensationFailed StepStatus = "compensation_failed"
+)
+
+// StepRun records a step's execution
+type StepRun struct {
+	ID         string            `json:"id"`
+	Status     StepStatus        `json:"status"`
+	TaskID     string            `json:"task_id,omitempty"`
+	Output     string            `json:"output,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Attempts   int               `json:"attempts"`
+	Iterations int               `json:"iterations,omitempty"` // Loop iterations run
+	Quality    float64           `json:"quality,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"` // Human steps
+	Responder  string            `json:"responder,omitempty"`  // Human steps
+	StartedAt  time.Time         `json:"started_at,omitempty"`
+	FinishedAt time.Time         `json:"finished_at,omitempty"`
+	Result     *agent.TaskResult `json:"-"`
+	Sub        *Run              `json:"sub_run,omitempty"` // Workflow steps
+
+	subWorkflow *Workflow
+
+	SkipReason        string `json:"skip_reason,omitempty"`
+	CompensationError string `json:"compensation_error,omitempty"`
+}
+
+// Run records a workflow execution
+type Run struct {
+	ID         string              `json:"id"`
+	Workflow   string              `json:"workflow"`
+	Params     map[string]string   `json:"params,omitempty"`
+	Status     RunStatus           `json:"status"`
+	Error      string              `json:"error,omitempty"`
+	Steps      map[string]*StepRun `json:"steps"`
+	Order      []string            `json:"order"`     // Step IDs in definition order
+	Completed  []string            `json:"completed"` // Step IDs in completion order
+	Output     string              `json:"output,omitempty"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at,omitempty"`
+}
+
+// Engine executes workflows by submitting their steps as tasks
+type Engine struct {
+	mu sync.RWMutex
+
+	submitter  Submitter
+	inbox      *Inbox
+	resolver   Resolver
+	actions    map[string]Action
+	logger     logging.Logger
+	checkpoint func(run *Run)
+}
+
+// NewEngine creates an engine that submits step tasks to s
+func NewEngine(s Submitter) *Engine {
+	return &Engine{
+		submitter: s,
+		inbox:     NewInbox(),
+		actions:   make(map[string]Action),
+		logger:    logging.Component("workflow"),
+	}
+}
+
+// SetLogger replaces the engine's logger
+func (e *Engine) SetLogger(l logging.Logger) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logger = l
+}
+
+func (e *Engine) log() logging.Logger {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.logger
+}
+
+// Inbox returns the inbox human steps wait on
+func (e *Engine) Inbox() *Inbox {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.inbox
+}
+
+// SetInbox shares an inbox, e.g. one served over HTTP, between engines
+func (e *Engine) SetInbox(inbox *Inbox) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.inbox = inbox
+}
+
+// SetRegistry sets where workflow steps look up the workflows they invoke
+func (e *Engine) SetRegistry(r Resolver) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resolver = r
+}
+
+func (e *Engine) registry() Resolver {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.resolver
+}
+
+// SetCheckpoint sets a function called with the run each time a step
+// completes, e.g. to save it for Resume. It is called from the run's loop,
+// so it may read the run but must not keep it past the call.
+func (e *Engine) SetCheckpoint(fn func(run *Run)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checkpoint = fn
+}
+
+func (e *Engine) checkpointer() func(run *Run) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.checkpoint
+}
+
+// RegisterAction makes a named action available to workflow compensations
+func (e *Engine) RegisterAction(name string, action Action) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.actions[name] = action
+}
+
+// action returns a registered action
+func (e *Engine) action(name string) (Action, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	action, ok := e.actions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAction, name)
+	}
+	return action, nil
+}
+
+// stepOutcome is the result of running a step, reported to the run loop
+type stepOutcome struct {
+	id         string
+	taskID     string
+	result     *agent.TaskResult
+	attempts   int
+	iterations int
+	err        error
+
+	// Human and workflow steps have no task result
+	requestID   string
+	output      string
+	responder   string
+	subRun      *Run
+	subWorkflow *Workflow
+}
+
+// Run executes a workflow and blocks until it finishes. Steps run as soon as
+// their dependencies are resolved, unless their condition skips them. If a step fails permanently (after its
+// retries) or ctx ends, no further steps start and the compensations of
+// completed steps run in reverse completion order. The returned error is the
+// cause of failure; the Run is returned either way.
+func (e *Engine) Run(ctx context.Context, wf *Workflow, params map[string]string) (*Run, error) {
+	run := newRun(wf, uuid.New().String(), mergeParams(wf.Params, params))
+	return e.execute(ctx, wf, run)
+}
+
+// Resume continues an earlier run of a workflow, such as one saved by a
+// checkpoint before a crash. Steps the earlier run completed keep their
+// output and are not run again; the rest run as in Run, under the earlier
+// run's ID and parameters.
+func (e *Engine) Resume(ctx context.Context, wf *Workflow, prior *Run) (*Run, error) {
+	run := newRun(wf, prior.ID, prior.Params)
+	for _, id := range prior.Completed {
+		sr, ok := prior.Steps[id]
+		if !ok || sr.Status != StepCompleted || run.Steps[id] == nil {
+			continue
+		}
+		restored := *sr
+		run.Steps[id] = &restored
+		run.Completed = append(run.Completed, id)
+	}
+	return e.execute(ctx, wf, run)
+}
+
+// newRun creates a run of a workflow with every step pending
+func newRun(wf *Workflow, id string, params map[string]string) *Run {
+	run := &Run{
+		ID:        id,
+		Workflow:  wf.Name,
+		Params:    params,
+		Status:    RunRunning,
+		Steps:     make(map[string]*StepRun, len(wf.Steps)),
+		StartedAt: time.Now(),
+	}
+	for _, step := range wf.Steps {
+		run.Steps[step.ID] = &StepRun{ID: step.ID, Status: StepPending}
+		run.Order = append(run.Order, step.ID)
+	}
+	return run
+}
+
+// execute runs the pending steps of run
+func (e *Engine) execute(ctx context.Context, wf *Workflow, run *Run) (*Run, error) {
+	if err := wf.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, err := enterWorkflow(ctx, wf.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := e.log().With("workflow", wf.Name, "run", run.ID)
+	if len(run.Completed) > 0 {
+		logger.Info("workflow resumed", "steps", len(wf.Steps), "completed", len(run.Completed))
+	} else {
+		logger.Info("workflow started", "steps", len(wf.Steps))
+	}
+	checkpoint := e.checkpointer()
+
+	stepCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	outcomes := make(chan stepOutcome)
+	inFlight := 0
+	var failure error
+
+	for {
+		// Start every step whose dependencies are resolved. Skipping a step can
+		// resolve others, so scan until nothing changes.
+		for changed := failure == nil; changed; {
+			changed = false
+			for _, step := range wf.Steps {
+				sr := run.Steps[step.ID]
+				if sr.Status != StepPending || !dependenciesResolved(run, step) {
+					continue
+				}
+				changed = true
+
+				if reason := skipReason(run, step); reason != "" {
+					sr.Status = StepSkipped
+					sr.SkipReason = reason
+					logger.Debug("step skipped", "step", step.ID, "reason", reason)
+					continue
+				}
+
+				data := templateData(run, nil)
+				description, err := render(step.Task, data)
+				var with map[string]string
+				if err == nil && step.Type == StepTypeWorkflow {
+					with, err = renderParams(step.With, data)
+				}
+				if err != nil {
+					sr.Status = StepFailed
+					sr.Error = err.Error()
+					failure = fmt.Errorf("step %s: %w", step.ID, err)
+					break
+				}
+
+				sr.Status = StepRunning
+				sr.StartedAt = time.Now()
+				inFlight++
+				go func(step Step) {
+					switch step.Type {
+					case StepTypeHuman:
+						outcomes <- e.askHuman(stepCtx, run.ID, wf.Name, step, description)
+						return
+					case StepTypeWorkflow:
+						outcomes <- e.runSubWorkflow(stepCtx, step, with)
+						return
+					}
+					outcomes <- e.runStep(stepCtx, step, description, data)
+				}(step)
+				logger.Debug("step started", "step", step.ID)
+			}
+			if failure != nil {
+				break
+			}
+		}
+		if failure != nil {
+			cancel()
+		}
+
+		if inFlight == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		inFlight--
+
+		sr := run.Steps[outcome.id]
+		sr.TaskID = outcome.taskID
+		sr.Attempts = outcome.attempts
+		sr.Iterations = outcome.iterations
+		sr.FinishedAt = time.Now()
+		sr.Result = outcome.result
+		sr.RequestID = outcome.requestID
+		sr.Responder = outcome.responder
+		sr.Output = outcome.output
+		sr.Sub = outcome.subRun
+		sr.subWorkflow = outcome.subWorkflow
+		if outcome.result != nil {
+			sr.Output = outcome.result.Output
+			sr.Quality = outcome.result.Quality
+		}
+
+		if outcome.err != nil {
+			sr.Status = StepFailed
+			sr.Error = outcome.err.Error()
+			logger.Warn("step failed", "step", outcome.id, "attempts", outcome.attempts, "error", outcome.err)
+			if failure == nil {
+				failure = fmt.Errorf("step %s: %w", outcome.id, outcome.err)
+			}
+			continue
+		}
+
+		// A step that finished despite cancellation still applied its effects
+		sr.Status = StepCompleted
+		run.Completed = append(run.Completed, outcome.id)
+		logger.Debug("step completed", "step", outcome.id, "attempts", outcome.attempts)
+		if checkpoint != nil {
+			checkpoint(run)
+		}
+	}
+
+	if failure == nil {
+		if err := ctx.Err(); err != nil {
+			failure = err
+		}
+	}
+	if failure == nil {
+		if run.Output, err = workflowOutput(wf, run); err != nil {
+			failure = fmt.Errorf("output: %w", err)
+		}
+	}
+
+	// Unreached steps are skipped
+	for _, id := range run.Order {
+		if sr := run.Steps[id]; sr.Status == StepPending {
+			sr.Status = StepSkipped
+			sr.SkipReason = "workflow failed"
+		}
+	}
+
+	if failure != nil {
+		run.Status = RunFailed
+		run.Error = failure.Error()
+		e.compensate(context.WithoutCancel(ctx), wf, run, logger)
+	} else {
+		run.Status = RunCompleted
+	}
+	run.FinishedAt = time.Now()
+	logger.Info("workflow finished", "status", run.Status, "duration", run.FinishedAt.Sub(run.StartedAt))
+
+	return run, failure
+}
+
+// runStep runs a step, repeating it if it loops. data is the template data
+// the step's task was rendered with; the worker owns it.
+func (e *Engine) runStep(ctx context.Context, step Step, description string, data map[string]interface{}) stepOutcome {
+	outcome := stepOutcome{id: step.ID}
+	if step.Loop == nil {
+		e.attempt(ctx, step, description, &outcome)
+		return outcome
+	}
+
+	for i := 1; i <= step.Loop.MaxIterations; i++ {
+		if i > 1 {
+			data["Iteration"] = i
+			data["Previous"] = StepData{ID: step.ID, Status: StepCompleted, Output: outcome.result.Output, Quality: outcome.result.Quality}
+			var err error
+			if description, err = render(step.Task, data); err != nil {
+				outcome.err = err
+				return outcome
+			}
+		}
+
+		outcome.iterations = i
+		e.attempt(ctx, step, description, &outcome)
+		if outcome.err != nil {
+			return outcome
+		}
+
+		if until := step.Loop.Until; until != nil {
+			sr := &StepRun{ID: step.ID, Status: StepCompleted, Output: outcome.result.Output, Quality: outcome.result.Quality, Iterations: i}
+			if until.Evaluate(sr) {
+				return outcome
+			}
+		}
+	}
+	// The loop is bounded: the last iteration's result stands
+	return outcome
+}
+
+// askHuman opens a request in the inbox and waits for the answer. A rejected
+// approval fails the step, which compensates the workflow like any failure.
+func (e *Engine) askHuman(ctx context.Context, runID, workflow string, step Step, prompt string) stepOutcome {
+	outcome := stepOutcome{id: step.ID, attempts: 1}
+
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+
+	kind := step.Kind
+	if kind == "" {
+		kind = HumanApproval
+	}
+	req := HumanRequest{RunID: runID, Workflow: workflow, StepID: step.ID, Kind: kind, Prompt: prompt}
+	req, err := e.Inbox().Ask(ctx, req)
+	outcome.requestID = req.ID
+	if err != nil {
+		outcome.err = fmt.Errorf("waiting for %s: %w", kind, err)
+		return outcome
+	}
+
+	resp := *req.Response
+	outcome.responder = resp.Responder
+	outcome.output = resp.Value
+	if kind == HumanApproval && !resp.Approved {
+		outcome.err = ErrRejected
+		if resp.Responder != "" {
+			outcome.err = fmt.Errorf("%w by %s", ErrRejected, resp.Responder)
+		}
+		if resp.Value != "" {
+			outcome.err = fmt.Errorf("%w: %s", outcome.err, resp.Value)
+		}
+	}
+	return outcome
+}
+
+// runSubWorkflow runs the workflow a step invokes. If the sub-workflow fails
+// it has already compensated its own steps.
+func (e *Engine) runSubWorkflow(ctx context.Context, step Step, params map[string]string) stepOutcome {
+	outcome := stepOutcome{id: step.ID, attempts: 1}
+
+	resolver := e.registry()
+	if resolver == nil {
+		outcome.err = fmt.Errorf("%w: no registry to find %s", ErrWorkflowNotFound, step.Workflow)
+		return outcome
+	}
+	sub, err := resolver.Get(ctx, step.Workflow)
+	if err != nil {
+		outcome.err = err
+		return outcome
+	}
+
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+	run, err := e.Run(ctx, sub, params)
+	outcome.subRun = run
+	outcome.subWorkflow = sub
+	if run != nil {
+		outcome.output = run.Output
+	}
+	if err != nil {
+		outcome.err = fmt.Errorf("workflow %s: %w", sub.Name, err)
+	}
+	return outcome
+}
+
+// attempt submits a step's task, retrying failures up to step.Retries times
+func (e *Engine) attempt(ctx context.Context, step Step, description string, outcome *stepOutcome) {
+	outcome.err = nil
+	for attempt := 0; attempt <= step.Retries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			if outcome.err == nil {
+				outcome.err = err
+			}
+			return
+		}
+		outcome.attempts++
+
+		task := newTask(description, step.Requires, step.Complexity, step.Team).WithMaxTokens(step.MaxTokens)
+		outcome.taskID = task.ID
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if step.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+		}
+		result, err := e.submitter.SubmitCtx(attemptCtx, task)
+		cancel()
+
+		outcome.result = result
+		outcome.err = taskError(result, err)
+		if outcome.err == nil {
+			return
+		}
+	}
+}
+
+// compensate undoes completed steps in reverse completion order. A workflow
+// step without its own compensation is undone by compensating the steps of
+// the workflow it ran. Failures are recorded and the remaining compensations
+// still run; returns false if any failed.
+func (e *Engine) compensate(ctx context.Context, wf *Workflow, run *Run, logger logging.Logger) bool {
+	ok := true
+	for i := len(run.Completed) - 1; i >= 0; i-- {
+		id := run.Completed[i]
+		step := wf.Step(id)
+		sr := run.Steps[id]
+
+		var err error
+		switch {
+		case step.Compensate != nil:
+			err = e.runCompensation(ctx, run, sr, step.Compensate)
+		case sr.Sub != nil:
+			if !e.compensate(ctx, sr.subWorkflow, sr.Sub, logger.With("sub_workflow", sr.Sub.Workflow)) {
+				err = fmt.Errorf("compensating workflow %s: some steps failed", sr.Sub.Workflow)
+			}
+		default:
+			continue
+		}
+
+		if err != nil {
+			ok = false
+			sr.Status = StepCompensationFailed
+			sr.CompensationError = err.Error()
+			logger.Error("compensation failed", "step", id, "error", err)
+			continue
+		}
+		sr.Status = StepCompensated
+		logger.Info("step compensated", "step", id)
+	}
+	return ok
+}
+
+// runCompensation runs a single compensation action or task
+func (e *Engine) runCompensation(ctx context.Context, run *Run, sr *StepRun, c *Compensation) error {
+	if c.Action != "" {
+		action, err := e.action(c.Action)
+		if err != nil {
+			return err
+		}
+		return action(ctx, run, sr)
+	}
+
+	description, err := render(c.Task, templateData(run, sr))
+	if err != nil {
+		return err
+	}
+	result, err := e.submitter.SubmitCtx(ctx, newTask(description, c.Requires, c.Complexity, ""))
+	return taskError(result, err)
+}
+
+// newTask builds a step task
+func newTask(description string, requires []identity.CapabilityType, complexity, team string) *agent.Task {
+	task := agent.NewTask(description, requires).WithTeam(team)
+	if complexity != "" {
+		task.WithComplexity(complexity)
+	}
+	return task
+}
+
+// taskError turns a failed result into an error
+func taskError(result *agent.TaskResult, err error) error {
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return errors.New("no result")
+	}
+	if result.Status != agent.TaskCompleted {
+		if result.Error != "" {
+			return errors.New(result.Error)
+		}
+		return fmt.Errorf("task %s", result.Status)
+	}
+	return nil
+}
+
+// dependenciesResolved reports whether all of a step's dependencies completed or were skipped
+func dependenciesResolved(run *Run, step Step) bool {
+	for _, dep := range step.DependsOn {
+		if status := run.Steps[dep].Status; status != StepCompleted && status != StepSkipped {
+			return false
+		}
+	}
+	return true
+}
+
+// skipReason returns why a step whose dependencies are resolved should be
+// skipped, or "" if it should run
+func skipReason(run *Run, step Step) string {
+	if len(step.DependsOn) > 0 {
+		completed := false
+		for _, dep := range step.DependsOn {
+			if run.Steps[dep].Status == StepCompleted {
+				completed = true
+				break
+			}
+		}
+		if !completed {
+			return "all dependencies skipped"
+		}
+	}
+
+	if c := step.When; c != nil {
+		if upstream := run.Steps[c.Step]; upstream.Status != StepCompleted || !c.Evaluate(upstream) {
+			return fmt.Sprintf("condition on %s.%s not met", c.Step, c.Field)
+		}
+	}
+	return ""
+}
+
+// mergeParams overlays supplied parameters on the workflow's defaults
+func mergeParams(defaults, params map[string]string) map[string]string {
+	merged := make(map[string]string, len(defaults)+len(params))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range params {
+		merged[k] = v
+	}
+	return merged
+}
+
+// callStackKey is the context key for the workflows a run is nested in
+type callStackKey struct{}
+
+// enterWorkflow records that a workflow is running in ctx, rejecting a
+// workflow that (indirectly) invokes itself
+func enterWorkflow(ctx context.Context, name string) (context.Context, error) {
+	stack, _ := ctx.Value(callStackKey{}).([]string)
+	for _, caller := range stack {
+		if caller == name {
+			return nil, fmt.Errorf("%w: %s invokes itself via %s", ErrInvalidWorkflow, name, strings.Join(append(stack, name), " -> "))
+		}
+	}
+	nested := append(append([]string(nil), stack...), name)
+	return context.WithValue(ctx, callStackKey{}, nested), nil
+}
+
+// workflowOutput renders a workflow's Output template, or returns the output
+// of its last completed step in definition order
+func workflowOutput(wf *Workflow, run *Run) (string, error) {
+	if wf.Output != "" {
+		return render(wf.Output, templateData(run, nil))
+	}
+	for i := len(run.Order) - 1; i >= 0; i-- {
+		if sr := run.Steps[run.Order[i]]; sr.Status == StepCompleted {
+			return sr.Output, nil
+		}
+	}
+	return "", nil
+}
+
+// renderParams renders a workflow step's parameter templates
+func renderParams(with map[string]string, data interface{}) (map[string]string, error) {
+	params := make(map[string]string, len(with))
+	for k, v := range with {
+		rendered, err := render(v, data)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", k, err)
+		}
+		params[k] = rendered
+	}
+	return params, nil
+}
+
+// StepData is a step's result as seen by templates
+type StepData struct {
+	ID      string
+	Status  StepStatus
+	Output  string
+	Quality float64
+}
+
+// templateData builds the data available to task templates
+func templateData(run *Run, current *StepRun) map[string]interface{} {
+	steps := make(map[string]StepData, len(run.Steps))
+	for id, sr := range run.Steps {
+		steps[id] = StepData{ID: id, Status: sr.Status, Output: sr.Output, Quality: sr.Quality}
+	}
+	data := map[string]interface{}{
+		"Params": run.Params,
+		"Steps":  steps,
+		"Run":    run.ID,
+
+		// Set for loop iterations after the first
+		"Iteration": 1,
+		"Previous":  StepData{},
+	}
+	if current != nil {
+		data["Step"] = steps[current.ID]
+	}
+	return data
+}
+
+// render executes a task template
+func render(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("task").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidWorkflow, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("rendering task: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,213 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrRequestNotFound is returned when responding to an unknown human request
+	ErrRequestNotFound = errors.New("human request not found")
+
+	// ErrRequestClosed is returned when responding to a request that was
+	// already answered or cancelled
+	ErrRequestClosed = errors.New("human request already closed")
+
+	// ErrRejected is the failure of a human step whose approval was refused
+	ErrRejected = errors.New("rejected")
+)
+
+// HumanKind is what a human step asks for
+type HumanKind string
+
+const (
+	HumanApproval HumanKind = "approval" // Approve or reject
+	HumanInput    HumanKind = "input"    // Free-form value
+)
+
+// HumanStatus is the state of a human request
+type HumanStatus string
+
+const (
+	HumanPending   HumanStatus = "pending"
+	HumanApproved  HumanStatus = "approved"
+	HumanRejected  HumanStatus = "rejected"
+	HumanAnswered  HumanStatus = "answered"
+	HumanCancelled HumanStatus = "cancelled" // The workflow stopped waiting
+)
+
+// HumanRequest asks a person to approve a workflow step or supply input
+type HumanRequest struct {
+	ID        string      `json:"id"`
+	RunID     string      `json:"run_id"`
+	Workflow  string      `json:"workflow"`
+	StepID    string      `json:"step_id"`
+	Kind      HumanKind   `json:"kind"`
+	Prompt    string      `json:"prompt"`
+	Status    HumanStatus `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+
+	Response    *HumanResponse `json:"response,omitempty"`
+	RespondedAt time.Time      `json:"responded_at,omitempty"`
+}
+
+// HumanResponse answers a human request. Approved is ignored for input requests.
+type HumanResponse struct {
+	Approved  bool   `json:"approved"`
+	Value     string `json:"value,omitempty"` // Input value, or a comment on an approval
+	Responder string `json:"responder,omitempty"`
+}
+
+// Notifier is told about each new human request, e.g. to post it to chat
+type Notifier func(req HumanRequest)
+
+// Inbox holds the human requests of running workflows until someone responds.
+// The CLI, HTTP API and notifiers all answer through the same inbox.
+type Inbox struct {
+	mu sync.RWMutex
+
+	requests  map[string]*HumanRequest
+	waiters   map[string]chan struct{} // Pending request ID -> closed on response
+	notifiers []Notifier
+}
+
+// NewInbox creates an empty inbox
+func NewInbox() *Inbox {
+	return &Inbox{
+		requests: make(map[string]*HumanRequest),
+		waiters:  make(map[string]chan struct{}),
+	}
+}
+
+// OnRequest registers a notifier for new requests
+func (i *Inbox) OnRequest(n Notifier) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.notifiers = append(i.notifiers, n)
+}
+
+// Ask opens a request and blocks until someone responds or ctx ends, in
+// which case the request is cancelled. Returns the request as closed.
+func (i *Inbox) Ask(ctx context.Context, req HumanRequest) (HumanRequest, error) {
+	req.ID = uuid.New().String()
+	req.Status = HumanPending
+	req.CreatedAt = time.Now()
+	if req.Kind == "" {
+		req.Kind = HumanApproval
+	}
+	answered := make(chan struct{})
+
+	i.mu.Lock()
+	stored := req
+	i.requests[req.ID] = &stored
+	i.waiters[req.ID] = answered
+	notifiers := append([]Notifier(nil), i.notifiers...)
+	i.mu.Unlock()
+
+	for _, n := range notifiers {
+		n(req)
+	}
+
+	select {
+	case <-answered:
+	case <-ctx.Done():
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, waiting := i.waiters[req.ID]; waiting {
+		delete(i.waiters, req.ID)
+		stored.Status = HumanCancelled
+		return stored, ctx.Err()
+	}
+	// Answered, possibly just as ctx ended
+	return stored, nil
+}
+
+// Respond answers a pending request
+func (i *Inbox) Respond(id string, resp HumanResponse) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	req, ok := i.requests[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRequestNotFound, id)
+	}
+	answer, waiting := i.waiters[id]
+	if !waiting {
+		return fmt.Errorf("%w: %s is %s", ErrRequestClosed, id, req.Status)
+	}
+	delete(i.waiters, id)
+
+	switch {
+	case req.Kind == HumanInput:
+		req.Status = HumanAnswered
+	case resp.Approved:
+		req.Status = HumanApproved
+	default:
+		req.Status = HumanRejected
+	}
+	req.Response = &resp
+	req.RespondedAt = time.Now()
+	close(answer)
+	return nil
+}
+
+// Get returns a request by ID
+func (i *Inbox) Get(id string) (HumanRequest, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	req, ok := i.requests[id]
+	if !ok {
+		return HumanRequest{}, false
+	}
+	return *req, true
+}
+
+// Pending returns the requests awaiting a response, oldest first
+func (i *Inbox) Pending() []HumanRequest {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	pending := make([]HumanRequest, 0, len(i.waiters))
+	for id := range i.waiters {
+		pending = append(pending, *i.requests[id])
+	}
+	sort.Slice(pending, func(a, b int) bool {
+		return pending[a].CreatedAt.Before(pending[b].CreatedAt)
+	})
+	return pending
+}
+
+// SlackNotifier posts new requests to a Slack incoming webhook. Responses are
+// given through the CLI or HTTP API using the request ID in the message.
+func SlackNotifier(webhookURL string) Notifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return func(req HumanRequest) {
+		action := "Approve or reject with `sqm workflow respond " + req.ID + " --approve|--reject`"
+		if req.Kind == HumanInput {
+			action = "Answer with `sqm workflow respond " + req.ID + " --value <text>`"
+		}
+		body, _ := json.Marshal(map[string]string{
+			"text": fmt.Sprintf("*%s* workflow step `%s` needs %s:\n>%s\n%s", req.Workflow, req.StepID, req.Kind, req.Prompt, action),
+		})
+
+		// Deliver in the background so a slow webhook doesn't delay the workflow
+		go func() {
+			resp, err := client.Post(webhookURL, "application/json", bytes.NewReader(body))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+}
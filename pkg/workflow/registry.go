@@ -0,0 +1,292 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrWorkflowNotFound is returned when a registry has no workflow with a name
+var ErrWorkflowNotFound = errors.New("workflow not found")
+
+// maxRemoteSize bounds remote index and workflow downloads
+const maxRemoteSize = 1 << 20
+
+// Resolver looks up workflows by name for sub-workflow steps
+type Resolver interface {
+	Get(ctx context.Context, name string) (*Workflow, error)
+}
+
+// RegistryEntry describes a workflow available from a registry
+type RegistryEntry struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`  // Remote definition, relative to the index
+	Path        string `json:"path,omitempty"` // Local definition
+	Remote      bool   `json:"remote"`
+}
+
+// remoteIndex is the JSON document served at a registry's index URL
+type remoteIndex struct {
+	Workflows []RegistryEntry `json:"workflows"`
+}
+
+// Registry is a library of shared workflows: registered in memory, stored as
+// YAML files in a local directory, or listed in an optional remote index.
+// Lookups try them in that order.
+type Registry struct {
+	mu sync.RWMutex
+
+	dir       string
+	indexURL  string
+	client    *http.Client
+	workflows map[string]*Workflow
+}
+
+// NewRegistry creates a registry backed by a local directory (empty = none)
+func NewRegistry(dir string) *Registry {
+	return &Registry{
+		dir:       dir,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		workflows: make(map[string]*Workflow),
+	}
+}
+
+// WithIndex adds a remote index: a JSON document {"workflows": [{"name",
+// "description", "url"}]} whose URLs may be relative to the index
+func (r *Registry) WithIndex(indexURL string) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.indexURL = indexURL
+	return r
+}
+
+// Register adds a workflow in memory, taking precedence over stored ones
+func (r *Registry) Register(wf *Workflow) error {
+	if err := wf.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.workflows[wf.Name] = wf
+	return nil
+}
+
+// Get returns the named workflow
+func (r *Registry) Get(ctx context.Context, name string) (*Workflow, error) {
+	r.mu.RLock()
+	wf, ok := r.workflows[name]
+	r.mu.RUnlock()
+	if ok {
+		return wf, nil
+	}
+
+	wf, err := r.getLocal(name)
+	if err == nil || !errors.Is(err, ErrWorkflowNotFound) {
+		return wf, err
+	}
+
+	data, err := r.fetchRemote(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	wf, err = Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("remote workflow %s: %w", name, err)
+	}
+	return wf, nil
+}
+
+// Install downloads a workflow from the remote index into the local directory
+func (r *Registry) Install(ctx context.Context, name string) (string, error) {
+	if r.dir == "" {
+		return "", errors.New("registry has no local directory")
+	}
+
+	data, err := r.fetchRemote(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := Parse(data); err != nil {
+		return "", fmt.Errorf("remote workflow %s: %w", name, err)
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(r.dir, name+".yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// List returns the registered, local and remote workflows, sorted by name.
+// A name available from several sources is listed once, from the source
+// Get would use.
+func (r *Registry) List(ctx context.Context) ([]RegistryEntry, error) {
+	seen := make(map[string]bool)
+	var entries []RegistryEntry
+
+	r.mu.RLock()
+	for name, wf := range r.workflows {
+		seen[name] = true
+		entries = append(entries, RegistryEntry{Name: name, Description: wf.Description})
+	}
+	r.mu.RUnlock()
+
+	local, err := r.localFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range local {
+		wf, err := LoadFile(path)
+		if err != nil || seen[wf.Name] {
+			continue
+		}
+		seen[wf.Name] = true
+		entries = append(entries, RegistryEntry{Name: wf.Name, Description: wf.Description, Path: path})
+	}
+
+	index, err := r.index(ctx)
+	if err != nil {
+		return entries, err
+	}
+	for _, entry := range index {
+		if seen[entry.Name] {
+			continue
+		}
+		seen[entry.Name] = true
+		entry.Remote = true
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// getLocal loads a workflow from the local directory, by file name first and
+// then by the name declared in each file
+func (r *Registry) getLocal(name string) (*Workflow, error) {
+	if r.dir == "" {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, name)
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(r.dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return LoadFile(path)
+		}
+	}
+
+	files, err := r.localFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		if wf, err := LoadFile(path); err == nil && wf.Name == name {
+			return wf, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, name)
+}
+
+// localFiles returns the workflow files in the local directory
+func (r *Registry) localFiles() ([]string, error) {
+	if r.dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(r.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(r.dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// index fetches the remote index, or returns nil if none is configured
+func (r *Registry) index(ctx context.Context) ([]RegistryEntry, error) {
+	r.mu.RLock()
+	indexURL := r.indexURL
+	r.mu.RUnlock()
+	if indexURL == "" {
+		return nil, nil
+	}
+
+	data, err := r.download(ctx, indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("workflow index: %w", err)
+	}
+	var index remoteIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("workflow index: %w", err)
+	}
+
+	base, err := url.Parse(indexURL)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range index.Workflows {
+		ref, err := url.Parse(entry.URL)
+		if err != nil {
+			return nil, fmt.Errorf("workflow index: %s: %w", entry.Name, err)
+		}
+		index.Workflows[i].URL = base.ResolveReference(ref).String()
+	}
+	return index.Workflows, nil
+}
+
+// fetchRemote downloads a workflow definition listed in the remote index
+func (r *Registry) fetchRemote(ctx context.Context, name string) ([]byte, error) {
+	index, err := r.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range index {
+		if entry.Name == name {
+			return r.download(ctx, entry.URL)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrWorkflowNotFound, name)
+}
+
+// download fetches a remote document over HTTP(S)
+func (r *Registry) download(ctx context.Context, rawURL string) ([]byte, error) {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return nil, fmt.Errorf("unsupported URL %q", rawURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize))
+}
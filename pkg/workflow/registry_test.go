@@ -0,0 +1,147 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry_Get(t *testing.T) {
+	dir := t.TempDir()
+	local := "name: local-pipeline\nsteps: [{id: a, task: x}]\n"
+	if err := os.WriteFile(filepath.Join(dir, "pipeline.yaml"), []byte(local), 0644); err != nil {
+		t.Fatalf("Failed to write workflow: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"workflows": [{"name": "secure-feature-delivery", "description": "shared", "url": "defs/sfd.yaml"}]}`))
+	})
+	mux.HandleFunc("/defs/sfd.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("name: secure-feature-delivery\nsteps: [{id: a, task: x}]\n"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	registry := NewRegistry(dir).WithIndex(srv.URL + "/index.json")
+	ctx := context.Background()
+
+	// Local files are found by declared name as well as file name
+	if wf, err := registry.Get(ctx, "local-pipeline"); err != nil || wf.Name != "local-pipeline" {
+		t.Errorf("Expected local workflow, got %v, %v", wf, err)
+	}
+	if _, err := registry.Get(ctx, "secure-feature-delivery"); err != nil {
+		t.Errorf("Expected remote workflow, got %v", err)
+	}
+	if _, err := registry.Get(ctx, "missing"); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Errorf("Expected ErrWorkflowNotFound, got %v", err)
+	}
+
+	entries, err := registry.List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 || !entries[1].Remote || entries[1].URL != srv.URL+"/defs/sfd.yaml" {
+		t.Errorf("Expected local and remote entries, got %+v", entries)
+	}
+
+	path, err := registry.Install(ctx, "secure-feature-delivery")
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if _, err := LoadFile(path); err != nil {
+		t.Errorf("Expected installed workflow to load, got %v", err)
+	}
+}
+
+func TestEngine_SubWorkflow(t *testing.T) {
+	registry := NewRegistry("")
+	child, err := Parse([]byte(`
+name: deliver
+params: {feature: none}
+steps:
+  - id: branch
+    task: "branch for {{.Params.feature}}"
+    compensate: {task: "delete branch for {{.Params.feature}}"}
+  - id: implement
+    task: "implement {{.Params.feature}}"
+    depends_on: [branch]
+output: "shipped {{.Params.feature}}"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+	if err := registry.Register(child); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	parent, err := Parse([]byte(`
+name: mission
+steps:
+  - id: deliver
+    type: workflow
+    workflow: deliver
+    with: {feature: "{{.Params.feature}}"}
+  - id: announce
+    task: "announce {{.Steps.deliver.Output}}"
+    depends_on: [deliver]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	sub := newFakeSubmitter()
+	engine := NewEngine(sub)
+	engine.SetRegistry(registry)
+
+	run, err := engine.Run(context.Background(), parent, map[string]string{"feature": "sso"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	tasks := sub.submitted()
+	if tasks[len(tasks)-1] != "announce shipped sso" {
+		t.Errorf("Expected sub-workflow output to flow on, got %q", tasks)
+	}
+	if run.Steps["deliver"].Sub == nil || run.Steps["deliver"].Sub.Status != RunCompleted {
+		t.Error("Expected the sub-run to be recorded")
+	}
+
+	// A later failure compensates the steps the sub-workflow completed
+	sub = newFakeSubmitter()
+	sub.fail["announce"] = -1
+	engine = NewEngine(sub)
+	engine.SetRegistry(registry)
+
+	run, _ = engine.Run(context.Background(), parent, map[string]string{"feature": "sso"})
+	if got := run.Steps["deliver"].Status; got != StepCompensated {
+		t.Errorf("Expected deliver to be compensated, got %s", got)
+	}
+	if got := run.Steps["deliver"].Sub.Steps["branch"].Status; got != StepCompensated {
+		t.Errorf("Expected the sub-workflow's branch to be compensated, got %s", got)
+	}
+	tasks = sub.submitted()
+	if tasks[len(tasks)-1] != "delete branch for sso" {
+		t.Errorf("Expected sub-workflow compensation, got %q", tasks)
+	}
+}
+
+func TestEngine_RecursiveWorkflow(t *testing.T) {
+	registry := NewRegistry("")
+	loop, _ := Parse([]byte("name: loop\nsteps: [{id: again, type: workflow, workflow: loop}]"))
+	registry.Register(loop)
+
+	engine := NewEngine(newFakeSubmitter())
+	engine.SetRegistry(registry)
+
+	run, err := engine.Run(context.Background(), loop, nil)
+	if !errors.Is(err, ErrInvalidWorkflow) {
+		t.Errorf("Expected recursion to be rejected, got %v", err)
+	}
+	if run == nil || run.Status != RunFailed {
+		t.Error("Expected the outer run to fail")
+	}
+}
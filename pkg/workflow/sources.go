@@ -0,0 +1,177 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WatchFiles polls dir every interval and fires an EventFileCreated for each
+// new file whose name matches pattern (a filepath.Match glob; empty = any).
+// Files present when watching starts don't fire. Blocks until ctx is cancelled.
+func WatchFiles(ctx context.Context, dir, pattern string, interval time.Duration, fire func(Event)) error {
+	seen, err := listFiles(dir, pattern)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := listFiles(dir, pattern)
+		if err != nil {
+			continue // The directory may be briefly unavailable
+		}
+		var created []string
+		for path := range current {
+			if !seen[path] {
+				created = append(created, path)
+			}
+		}
+		sort.Strings(created)
+		for _, path := range created {
+			fire(Event{Type: EventFileCreated, Data: map[string]interface{}{
+				"path": path,
+				"name": filepath.Base(path),
+				"dir":  dir,
+			}})
+		}
+		seen = current
+	}
+}
+
+// listFiles returns the regular files in dir matching pattern
+func listFiles(dir, pattern string) (map[string]bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if pattern != "" {
+			if ok, _ := filepath.Match(pattern, entry.Name()); !ok {
+				continue
+			}
+		}
+		files[filepath.Join(dir, entry.Name())] = true
+	}
+	return files, nil
+}
+
+// BudgetMonitor tracks token spend against a limit and fires an
+// EventBudgetThreshold the first time each threshold fraction is crossed
+type BudgetMonitor struct {
+	mu sync.Mutex
+
+	limit      int
+	thresholds []float64
+	used       int
+	crossed    int // Thresholds already fired
+}
+
+// NewBudgetMonitor creates a monitor for a token limit. Thresholds are
+// fractions of the limit and default to 0.5, 0.8 and 1.0.
+func NewBudgetMonitor(limit int, thresholds ...float64) *BudgetMonitor {
+	if len(thresholds) == 0 {
+		thresholds = []float64{0.5, 0.8, 1.0}
+	}
+	sorted := append([]float64(nil), thresholds...)
+	sort.Float64s(sorted)
+	return &BudgetMonitor{limit: limit, thresholds: sorted}
+}
+
+// Add records spent tokens and returns an event for each threshold crossed
+func (b *BudgetMonitor) Add(tokens int) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used += tokens
+	var events []Event
+	for b.crossed < len(b.thresholds) && float64(b.used) >= b.thresholds[b.crossed]*float64(b.limit) {
+		events = append(events, Event{Type: EventBudgetThreshold, Data: map[string]interface{}{
+			"used":      b.used,
+			"limit":     b.limit,
+			"threshold": b.thresholds[b.crossed],
+		}})
+		b.crossed++
+	}
+	return events
+}
+
+// Used returns the tokens spent so far
+func (b *BudgetMonitor) Used() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// ReputationWatch flags an agent whose reputation falls by more than Drop
+// within Window, which usually means it is failing or misbehaving rather
+// than drifting
+type ReputationWatch struct {
+	mu sync.Mutex
+
+	drop   float64
+	window time.Duration
+	deltas map[string][]reputationDelta
+}
+
+type reputationDelta struct {
+	delta float64
+	at    time.Time
+}
+
+// NewReputationWatch creates a detector for drops larger than drop within window
+func NewReputationWatch(drop float64, window time.Duration) *ReputationWatch {
+	return &ReputationWatch{
+		drop:   drop,
+		window: window,
+		deltas: make(map[string][]reputationDelta),
+	}
+}
+
+// Observe records a reputation change and returns an EventReputationAnomaly
+// if the agent's drop within the window now exceeds the limit. The window is
+// reset after an anomaly so one collapse fires once.
+func (w *ReputationWatch) Observe(agentSID string, delta float64, reason string, at time.Time) (Event, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	recent := w.deltas[agentSID][:0]
+	for _, d := range w.deltas[agentSID] {
+		if at.Sub(d.at) <= w.window {
+			recent = append(recent, d)
+		}
+	}
+	recent = append(recent, reputationDelta{delta: delta, at: at})
+
+	var total float64
+	for _, d := range recent {
+		total += d.delta
+	}
+	if -total <= w.drop {
+		w.deltas[agentSID] = recent
+		return Event{}, false
+	}
+
+	delete(w.deltas, agentSID)
+	return Event{Type: EventReputationAnomaly, Timestamp: at, Data: map[string]interface{}{
+		"agent_sid": agentSID,
+		"drop":      -total,
+		"window":    w.window.String(),
+		"reason":    reason,
+	}}, true
+}
@@ -0,0 +1,330 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/square-mind/squaremind/pkg/logging"
+)
+
+var (
+	// ErrInvalidTrigger is returned for malformed trigger rules
+	ErrInvalidTrigger = errors.New("invalid trigger")
+
+	// ErrTriggersRunning is returned when starting triggers twice
+	ErrTriggersRunning = errors.New("triggers already running")
+)
+
+// Event types produced by the built-in event sources. Collective activity
+// events (task_failed, agent_left, ...) can be fired with their own types.
+const (
+	EventWebhook           = "webhook"            // Data: hook, payload, submitter
+	EventFileCreated       = "file_created"       // Data: path, name, dir
+	EventBudgetThreshold   = "budget_threshold"   // Data: used, limit, threshold
+	EventReputationAnomaly = "reputation_anomaly" // Data: agent_sid, drop, window, reason
+)
+
+// Event is something that happened which may start workflows
+type Event struct {
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// TriggerRule starts a workflow when a matching event fires. Params are
+// templates rendered with the event as .Event, e.g. {{.Event.Data.path}}.
+type TriggerRule struct {
+	Name     string            `yaml:"name" json:"name"`
+	On       string            `yaml:"on" json:"on"`                           // Event type
+	Match    map[string]string `yaml:"match,omitempty" json:"match,omitempty"` // Event data fields that must equal these values
+	Workflow string            `yaml:"workflow" json:"workflow"`               // Name in the engine's registry
+	Params   map[string]string `yaml:"params,omitempty" json:"params,omitempty"`
+	Cooldown time.Duration     `yaml:"cooldown,omitempty" json:"cooldown,omitempty"` // Minimum time between runs
+}
+
+// TriggerConfig is the layout of a triggers file: the event sources to run
+// and the rules that map their events to workflows
+type TriggerConfig struct {
+	Watch      []WatchSource     `yaml:"watch,omitempty"`
+	Budget     *BudgetSource     `yaml:"budget,omitempty"`
+	Reputation *ReputationSource `yaml:"reputation,omitempty"`
+	Triggers   []TriggerRule     `yaml:"triggers"`
+}
+
+// WatchSource fires EventFileCreated for new files in a workspace directory
+type WatchSource struct {
+	Dir      string        `yaml:"dir"`
+	Pattern  string        `yaml:"pattern,omitempty"`  // Glob on the file name
+	Interval time.Duration `yaml:"interval,omitempty"` // Poll interval (default 5s)
+}
+
+// BudgetSource fires EventBudgetThreshold as token spend crosses fractions of a limit
+type BudgetSource struct {
+	Tokens     int       `yaml:"tokens"`
+	Thresholds []float64 `yaml:"thresholds,omitempty"` // Default 0.5, 0.8, 1.0
+}
+
+// ReputationSource fires EventReputationAnomaly when an agent's reputation
+// falls by more than Drop within Window
+type ReputationSource struct {
+	Drop   float64       `yaml:"drop"`
+	Window time.Duration `yaml:"window"`
+}
+
+// LoadTriggerConfig reads a triggers file. A missing file configures nothing.
+func LoadTriggerConfig(path string) (*TriggerConfig, error) {
+	var cfg TriggerConfig
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w: %v", path, ErrInvalidTrigger, err)
+	}
+	for _, rule := range cfg.Triggers {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	for _, w := range cfg.Watch {
+		if w.Dir == "" {
+			return nil, fmt.Errorf("%s: %w: watch needs a dir", path, ErrInvalidTrigger)
+		}
+	}
+	if b := cfg.Budget; b != nil && b.Tokens <= 0 {
+		return nil, fmt.Errorf("%s: %w: budget needs a positive token limit", path, ErrInvalidTrigger)
+	}
+	if r := cfg.Reputation; r != nil && (r.Drop <= 0 || r.Window <= 0) {
+		return nil, fmt.Errorf("%s: %w: reputation needs a positive drop and window", path, ErrInvalidTrigger)
+	}
+	return &cfg, nil
+}
+
+// Validate checks that the rule names an event and a workflow
+func (r *TriggerRule) Validate() error {
+	switch {
+	case r.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidTrigger)
+	case r.On == "":
+		return fmt.Errorf("%w: %s: on is required", ErrInvalidTrigger, r.Name)
+	case r.Workflow == "":
+		return fmt.Errorf("%w: %s: workflow is required", ErrInvalidTrigger, r.Name)
+	}
+	for k, v := range r.Params {
+		if err := checkTemplate(v); err != nil {
+			return fmt.Errorf("%w: %s: param %s: %v", ErrInvalidTrigger, r.Name, k, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether an event satisfies the rule
+func (r *TriggerRule) matches(e Event) bool {
+	if r.On != e.Type {
+		return false
+	}
+	for k, want := range r.Match {
+		got, ok := e.Data[k]
+		if !ok || fmt.Sprint(got) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// TriggerRun reports a workflow run started by a trigger
+type TriggerRun struct {
+	Rule  string
+	Event Event
+	Run   *Run
+	Err   error
+}
+
+// TriggerStats reports trigger activity
+type TriggerStats struct {
+	Events     int `json:"events"`
+	Runs       int `json:"runs"`
+	Failed     int `json:"failed"`
+	Suppressed int `json:"suppressed"` // Matches skipped during a rule's cooldown
+	Dropped    int `json:"dropped"`    // Events lost because the queue was full
+}
+
+// Triggers is a rules engine that starts workflows in response to events.
+// Events are queued by Fire and dispatched once Start is called.
+type Triggers struct {
+	mu sync.RWMutex
+
+	engine    *Engine
+	rules     []TriggerRule
+	lastFired map[string]time.Time
+	events    chan Event
+	onRun     []func(TriggerRun)
+	stats     TriggerStats
+	running   bool
+	runs      sync.WaitGroup
+	now       func() time.Time
+	logger    logging.Logger
+}
+
+// NewTriggers creates a rules engine that runs workflows on engine, looking
+// them up in the engine's registry
+func NewTriggers(engine *Engine) *Triggers {
+	return &Triggers{
+		engine:    engine,
+		lastFired: make(map[string]time.Time),
+		events:    make(chan Event, 256),
+		now:       time.Now,
+		logger:    logging.Component("triggers"),
+	}
+}
+
+// AddRule adds a trigger rule
+func (t *Triggers) AddRule(rule TriggerRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = append(t.rules, rule)
+	return nil
+}
+
+// Rules returns the configured rules
+func (t *Triggers) Rules() []TriggerRule {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]TriggerRule(nil), t.rules...)
+}
+
+// OnRun registers a callback invoked when a triggered workflow run finishes
+func (t *Triggers) OnRun(handler func(TriggerRun)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onRun = append(t.onRun, handler)
+}
+
+// Fire queues an event without blocking. Events are dropped if the queue is full.
+func (t *Triggers) Fire(e Event) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = t.now()
+	}
+	select {
+	case t.events <- e:
+	default:
+		t.mu.Lock()
+		t.stats.Dropped++
+		t.mu.Unlock()
+	}
+}
+
+// Start dispatches queued events until ctx is cancelled. Triggered runs
+// inherit ctx, so cancelling it also stops (and compensates) them.
+func (t *Triggers) Start(ctx context.Context) error {
+	t.mu.Lock()
+	if t.running {
+		t.mu.Unlock()
+		return ErrTriggersRunning
+	}
+	t.running = true
+	t.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				t.mu.Lock()
+				t.running = false
+				t.mu.Unlock()
+				return
+			case e := <-t.events:
+				t.dispatch(ctx, e)
+			}
+		}
+	}()
+	return nil
+}
+
+// Wait blocks until every triggered run has finished
+func (t *Triggers) Wait() {
+	t.runs.Wait()
+}
+
+// dispatch starts a run for every rule the event matches
+func (t *Triggers) dispatch(ctx context.Context, e Event) {
+	t.mu.Lock()
+	t.stats.Events++
+	var matched []TriggerRule
+	now := t.now()
+	for _, rule := range t.rules {
+		if !rule.matches(e) {
+			continue
+		}
+		if last, ok := t.lastFired[rule.Name]; ok && rule.Cooldown > 0 && now.Sub(last) < rule.Cooldown {
+			t.stats.Suppressed++
+			continue
+		}
+		t.lastFired[rule.Name] = now
+		t.stats.Runs++
+		matched = append(matched, rule)
+	}
+	logger := t.logger
+	t.mu.Unlock()
+
+	for _, rule := range matched {
+		logger.Info("trigger fired", "rule", rule.Name, "event", e.Type, "workflow", rule.Workflow)
+		t.runs.Add(1)
+		go func(rule TriggerRule) {
+			defer t.runs.Done()
+			run, err := t.start(ctx, rule, e)
+			if err != nil {
+				logger.Warn("triggered workflow failed", "rule", rule.Name, "workflow", rule.Workflow, "error", err)
+				t.mu.Lock()
+				t.stats.Failed++
+				t.mu.Unlock()
+			}
+
+			t.mu.RLock()
+			handlers := make([]func(TriggerRun), len(t.onRun))
+			copy(handlers, t.onRun)
+			t.mu.RUnlock()
+			for _, h := range handlers {
+				h(TriggerRun{Rule: rule.Name, Event: e, Run: run, Err: err})
+			}
+		}(rule)
+	}
+}
+
+// start resolves and runs a rule's workflow
+func (t *Triggers) start(ctx context.Context, rule TriggerRule, e Event) (*Run, error) {
+	resolver := t.engine.registry()
+	if resolver == nil {
+		return nil, fmt.Errorf("%w: no registry to find %s", ErrWorkflowNotFound, rule.Workflow)
+	}
+	wf, err := resolver.Get(ctx, rule.Workflow)
+	if err != nil {
+		return nil, err
+	}
+	params, err := renderParams(rule.Params, map[string]interface{}{"Event": e})
+	if err != nil {
+		return nil, err
+	}
+	return t.engine.Run(ctx, wf, params)
+}
+
+// Stats returns trigger statistics
+func (t *Triggers) Stats() TriggerStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.stats
+}
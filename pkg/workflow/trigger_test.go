@@ -0,0 +1,157 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTriggers_Fire(t *testing.T) {
+	submitter := newFakeSubmitter()
+	engine := NewEngine(submitter)
+	registry := NewRegistry(t.TempDir())
+	wf, err := Parse([]byte("name: triage\nparams: {file: none}\nsteps: [{id: read, task: 'triage {{.Params.file}}'}]\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	registry.Register(wf)
+	engine.SetRegistry(registry)
+
+	triggers := NewTriggers(engine)
+	err = triggers.AddRule(TriggerRule{
+		Name:     "triage-specs",
+		On:       EventFileCreated,
+		Match:    map[string]string{"dir": "specs"},
+		Workflow: "triage",
+		Params:   map[string]string{"file": "{{.Event.Data.name}}"},
+		Cooldown: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("AddRule failed: %v", err)
+	}
+	runs := make(chan TriggerRun, 4)
+	triggers.OnRun(func(tr TriggerRun) { runs <- tr })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := triggers.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := triggers.Start(ctx); err != ErrTriggersRunning {
+		t.Errorf("Expected ErrTriggersRunning, got %v", err)
+	}
+
+	triggers.Fire(Event{Type: EventFileCreated, Data: map[string]interface{}{"dir": "other", "name": "x.md"}})
+	triggers.Fire(Event{Type: EventFileCreated, Data: map[string]interface{}{"dir": "specs", "name": "auth.md"}})
+	triggers.Fire(Event{Type: EventFileCreated, Data: map[string]interface{}{"dir": "specs", "name": "billing.md"}})
+
+	tr := <-runs
+	if tr.Err != nil || tr.Run.Status != RunCompleted {
+		t.Fatalf("Expected completed run, got %+v", tr)
+	}
+	triggers.Wait()
+
+	if got := submitter.submitted(); len(got) != 1 || got[0] != "triage auth.md" {
+		t.Errorf("Expected one run rendered from the event, got %v", got)
+	}
+	// Wait for the dispatcher to count the last event
+	deadline := time.Now().Add(time.Second)
+	for triggers.Stats().Events < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	stats := triggers.Stats()
+	if stats.Events != 3 || stats.Runs != 1 || stats.Suppressed != 1 {
+		t.Errorf("Expected 3 events, 1 run and 1 suppressed, got %+v", stats)
+	}
+}
+
+func TestTriggerRule_Validate(t *testing.T) {
+	rules := []TriggerRule{
+		{On: EventWebhook, Workflow: "w"},
+		{Name: "r", Workflow: "w"},
+		{Name: "r", On: EventWebhook},
+		{Name: "r", On: EventWebhook, Workflow: "w", Params: map[string]string{"p": "{{.Event"}},
+	}
+	for i, rule := range rules {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("Expected rule %d to be invalid", i)
+		}
+	}
+}
+
+func TestBudgetMonitor_Add(t *testing.T) {
+	b := NewBudgetMonitor(1000, 0.8, 0.5)
+
+	if events := b.Add(400); len(events) != 0 {
+		t.Errorf("Expected no events below 50%%, got %v", events)
+	}
+	// Jumping past both thresholds fires both, lowest first
+	events := b.Add(500)
+	if len(events) != 2 || events[0].Data["threshold"] != 0.5 || events[1].Data["threshold"] != 0.8 {
+		t.Fatalf("Expected both thresholds, got %v", events)
+	}
+	if events := b.Add(100); len(events) != 0 {
+		t.Errorf("Expected thresholds to fire once, got %v", events)
+	}
+	if b.Used() != 1000 {
+		t.Errorf("Expected 1000 tokens used, got %d", b.Used())
+	}
+}
+
+func TestReputationWatch_Observe(t *testing.T) {
+	w := NewReputationWatch(10, time.Hour)
+	start := time.Now()
+
+	if _, ok := w.Observe("agent-1", -6, "task failed", start); ok {
+		t.Error("Expected no anomaly for a small drop")
+	}
+	// A drop outside the window doesn't accumulate
+	if _, ok := w.Observe("agent-1", -6, "task failed", start.Add(2*time.Hour)); ok {
+		t.Error("Expected drops outside the window to be forgotten")
+	}
+	e, ok := w.Observe("agent-1", -6, "task failed", start.Add(2*time.Hour+time.Minute))
+	if !ok || e.Type != EventReputationAnomaly || e.Data["drop"] != 12.0 {
+		t.Errorf("Expected anomaly with drop 12, got %v, %v", e, ok)
+	}
+	if _, ok := w.Observe("agent-2", 5, "task completed", start); ok {
+		t.Error("Expected no anomaly for a gain")
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing.md"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan Event, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchFiles(ctx, dir, "*.md", 10*time.Millisecond, func(e Event) { events <- e })
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "spec.md"), nil, 0644)
+
+	select {
+	case e := <-events:
+		if e.Type != EventFileCreated || e.Data["name"] != "spec.md" {
+			t.Errorf("Expected file_created for spec.md, got %v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for file event")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean stop, got %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no other events, got %d", len(events))
+	}
+}
@@ -0,0 +1,286 @@
+// Package workflow runs declarative multi-step missions on a collective. A
+// workflow is a graph of steps, each submitted as a task once the steps it
+// depends on have completed.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/square-mind/squaremind/pkg/identity"
+)
+
+// ErrInvalidWorkflow is returned for malformed workflow definitions
+var ErrInvalidWorkflow = errors.New("invalid workflow")
+
+// Workflow is a declarative multi-step mission
+type Workflow struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Params      map[string]string `yaml:"params,omitempty" json:"params,omitempty"` // Parameter defaults
+	Steps       []Step            `yaml:"steps" json:"steps"`
+
+	// Output is a template for the workflow's result when it is invoked as a
+	// sub-workflow. By default it is the output of the last completed step.
+	Output string `yaml:"output,omitempty" json:"output,omitempty"`
+}
+
+// StepType says who performs a step
+type StepType string
+
+const (
+	StepTypeTask     StepType = "task"     // Submitted to the collective (the default)
+	StepTypeHuman    StepType = "human"    // Asks a person through the engine's inbox
+	StepTypeWorkflow StepType = "workflow" // Runs another workflow from the engine's registry
+)
+
+// Step is a unit of work in a workflow. Task is a text/template rendered with
+// .Params and the results of earlier steps as .Steps.<id>.Output. For a human
+// step Task is the prompt shown to the person, and the step's output is their
+// answer (or comment, for an approval). A workflow step runs the named
+// Workflow with the parameters in With, which are templates like Task.
+type Step struct {
+	ID         string                    `yaml:"id" json:"id"`
+	Type       StepType                  `yaml:"type,omitempty" json:"type,omitempty"`
+	Kind       HumanKind                 `yaml:"kind,omitempty" json:"kind,omitempty"` // Human steps: approval (default) or input
+	Task       string                    `yaml:"task,omitempty" json:"task,omitempty"`
+	Workflow   string                    `yaml:"workflow,omitempty" json:"workflow,omitempty"`
+	With       map[string]string         `yaml:"with,omitempty" json:"with,omitempty"`
+	Requires   []identity.CapabilityType `yaml:"requires,omitempty" json:"requires,omitempty"`
+	Complexity string                    `yaml:"complexity,omitempty" json:"complexity,omitempty"`
+	Team       string                    `yaml:"team,omitempty" json:"team,omitempty"`
+	DependsOn  []string                  `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Retries    int                       `yaml:"retries,omitempty" json:"retries,omitempty"`       // Extra attempts before the step fails permanently
+	Timeout    time.Duration             `yaml:"timeout,omitempty" json:"timeout,omitempty"`       // Per attempt, or how long a human has to respond (0 = no limit)
+	MaxTokens  int                       `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"` // Limit on each attempt's LLM response (0 = provider default)
+
+	// When makes the step conditional on an upstream result; if it doesn't
+	// hold the step is skipped. A step runs only if at least one of its
+	// dependencies completed, so skipping propagates down an untaken branch
+	// while a step joining several branches still runs.
+	When *Condition `yaml:"when,omitempty" json:"when,omitempty"`
+
+	// Loop repeats the step until its result is good enough
+	Loop *Loop `yaml:"loop,omitempty" json:"loop,omitempty"`
+
+	// Compensate undoes the step's side effects if the workflow fails after
+	// the step completed
+	Compensate *Compensation `yaml:"compensate,omitempty" json:"compensate,omitempty"`
+}
+
+// Compensation undoes a completed step, either by submitting a task or by
+// running an action registered with the engine. Task is a template like
+// Step.Task, with the step being undone available as .Step.
+type Compensation struct {
+	Task       string                    `yaml:"task,omitempty" json:"task,omitempty"`
+	Action     string                    `yaml:"action,omitempty" json:"action,omitempty"`
+	Requires   []identity.CapabilityType `yaml:"requires,omitempty" json:"requires,omitempty"`
+	Complexity string                    `yaml:"complexity,omitempty" json:"complexity,omitempty"`
+}
+
+// Parse reads a workflow definition from YAML (or JSON) and validates it
+func Parse(data []byte) (*Workflow, error) {
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWorkflow, err)
+	}
+	if err := wf.Validate(); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+// LoadFile reads and validates a workflow definition file
+func LoadFile(path string) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return wf, nil
+}
+
+// Validate checks that step IDs are unique, dependencies exist and form no
+// cycle, and every step and compensation says what to do
+func (w *Workflow) Validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidWorkflow)
+	}
+	if len(w.Steps) == 0 {
+		return fmt.Errorf("%w: %s has no steps", ErrInvalidWorkflow, w.Name)
+	}
+
+	steps := make(map[string]*Step, len(w.Steps))
+	for i := range w.Steps {
+		step := &w.Steps[i]
+		switch {
+		case step.ID == "":
+			return fmt.Errorf("%w: step %d has no id", ErrInvalidWorkflow, i+1)
+		case steps[step.ID] != nil:
+			return fmt.Errorf("%w: duplicate step id %q", ErrInvalidWorkflow, step.ID)
+		case step.Task == "" && step.Type != StepTypeWorkflow:
+			return fmt.Errorf("%w: step %q has no task", ErrInvalidWorkflow, step.ID)
+		case step.Retries < 0:
+			return fmt.Errorf("%w: step %q has negative retries", ErrInvalidWorkflow, step.ID)
+		case step.MaxTokens < 0:
+			return fmt.Errorf("%w: step %q has negative max_tokens", ErrInvalidWorkflow, step.ID)
+		}
+		if c := step.Compensate; c != nil && (c.Task == "") == (c.Action == "") {
+			return fmt.Errorf("%w: step %q compensation needs exactly one of task or action", ErrInvalidWorkflow, step.ID)
+		}
+		steps[step.ID] = step
+	}
+
+	for _, step := range w.Steps {
+		for _, dep := range step.DependsOn {
+			if steps[dep] == nil {
+				return fmt.Errorf("%w: step %q depends on unknown step %q", ErrInvalidWorkflow, step.ID, dep)
+			}
+		}
+		if err := step.validateControl(); err != nil {
+			return fmt.Errorf("step %q: %w", step.ID, err)
+		}
+		if err := step.validateTemplates(); err != nil {
+			return fmt.Errorf("step %q: %w", step.ID, err)
+		}
+	}
+	if err := checkTemplate(w.Output); err != nil {
+		return fmt.Errorf("output: %w", err)
+	}
+
+	// Depth-first search for cycles
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(steps))
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("%w: dependency cycle through step %q", ErrInvalidWorkflow, id)
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		for _, dep := range steps[id].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		return nil
+	}
+	for _, step := range w.Steps {
+		if err := visit(step.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateControl checks the step's type, condition and loop
+func (s *Step) validateControl() error {
+	if s.Kind != "" && s.Type != StepTypeHuman {
+		return fmt.Errorf("%w: kind is only valid on human steps", ErrInvalidWorkflow)
+	}
+	if s.Type != StepTypeWorkflow && (s.Workflow != "" || len(s.With) > 0) {
+		return fmt.Errorf("%w: workflow and with are only valid on workflow steps", ErrInvalidWorkflow)
+	}
+
+	switch s.Type {
+	case "", StepTypeTask:
+	case StepTypeHuman:
+		if s.Kind != "" && s.Kind != HumanApproval && s.Kind != HumanInput {
+			return fmt.Errorf("%w: unknown human step kind %q", ErrInvalidWorkflow, s.Kind)
+		}
+		if s.Loop != nil || s.Retries > 0 {
+			return fmt.Errorf("%w: human steps can't loop or retry", ErrInvalidWorkflow)
+		}
+	case StepTypeWorkflow:
+		if s.Workflow == "" {
+			return fmt.Errorf("%w: workflow step names no workflow", ErrInvalidWorkflow)
+		}
+		if s.Task != "" || s.Loop != nil || s.Retries > 0 {
+			return fmt.Errorf("%w: workflow steps can't have a task, loop or retry", ErrInvalidWorkflow)
+		}
+	default:
+		return fmt.Errorf("%w: unknown step type %q", ErrInvalidWorkflow, s.Type)
+	}
+	if c := s.When; c != nil {
+		if err := c.validate(); err != nil {
+			return err
+		}
+		if !s.dependsOn(c.Step) {
+			return fmt.Errorf("%w: condition step %q is not a dependency", ErrInvalidWorkflow, c.Step)
+		}
+	}
+	if l := s.Loop; l != nil {
+		if l.MaxIterations < 1 {
+			return fmt.Errorf("%w: loop needs max_iterations of at least 1", ErrInvalidWorkflow)
+		}
+		if c := l.Until; c != nil {
+			if err := c.validate(); err != nil {
+				return err
+			}
+			if c.Step != "" && c.Step != s.ID {
+				return fmt.Errorf("%w: loop condition must test the looping step", ErrInvalidWorkflow)
+			}
+		}
+	}
+	return nil
+}
+
+// validateTemplates checks that the step's templates parse
+func (s *Step) validateTemplates() error {
+	texts := []string{s.Task}
+	if s.Compensate != nil {
+		texts = append(texts, s.Compensate.Task)
+	}
+	for _, v := range s.With {
+		texts = append(texts, v)
+	}
+	for _, text := range texts {
+		if err := checkTemplate(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTemplate reports a template syntax error
+func checkTemplate(text string) error {
+	if _, err := template.New("check").Parse(text); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWorkflow, err)
+	}
+	return nil
+}
+
+// dependsOn reports whether id is a direct dependency of the step
+func (s *Step) dependsOn(id string) bool {
+	for _, dep := range s.DependsOn {
+		if dep == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Step returns the step with the given ID, or nil
+func (w *Workflow) Step(id string) *Step {
+	for i := range w.Steps {
+		if w.Steps[i].ID == id {
+			return &w.Steps[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,476 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/square-mind/squaremind/pkg/agent"
+)
+
+// fakeSubmitter completes tasks by echoing their description, failing any
+// whose description contains a registered substring
+type fakeSubmitter struct {
+	mu    sync.Mutex
+	fail  map[string]int // Substring -> remaining failures (-1 = always)
+	tasks []string
+}
+
+func newFakeSubmitter() *fakeSubmitter {
+	return &fakeSubmitter{fail: make(map[string]int)}
+}
+
+func (f *fakeSubmitter) SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tasks = append(f.tasks, task.Description)
+	for substr, remaining := range f.fail {
+		if remaining != 0 && strings.Contains(task.Description, substr) {
+			f.fail[substr] = remaining - 1
+			return &agent.TaskResult{TaskID: task.ID, Status: agent.TaskFailed, Error: "boom"}, nil
+		}
+	}
+	return &agent.TaskResult{TaskID: task.ID, Status: agent.TaskCompleted, Output: "done: " + task.Description, Quality: 0.9}, nil
+}
+
+func (f *fakeSubmitter) submitted() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.tasks...)
+}
+
+func TestWorkflow_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"no name", "steps: [{id: a, task: x}]"},
+		{"no steps", "name: w"},
+		{"duplicate id", "name: w\nsteps: [{id: a, task: x}, {id: a, task: y}]"},
+		{"unknown dependency", "name: w\nsteps: [{id: a, task: x, depends_on: [b]}]"},
+		{"cycle", "name: w\nsteps: [{id: a, task: x, depends_on: [b]}, {id: b, task: y, depends_on: [a]}]"},
+		{"ambiguous compensation", "name: w\nsteps: [{id: a, task: x, compensate: {task: u, action: v}}]"},
+		{"condition on non-dependency", "name: w\nsteps: [{id: a, task: x}, {id: b, task: y, when: {step: a, field: status, equals: completed}}]"},
+		{"template syntax", "name: w\nsteps: [{id: a, task: \"{{.Params.x\"}]"},
+		{"unknown condition field", "name: w\nsteps: [{id: a, task: x}, {id: b, task: y, depends_on: [a], when: {step: a, field: cost, min: 1}}]"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.yaml)); !errors.Is(err, ErrInvalidWorkflow) {
+			t.Errorf("%s: expected ErrInvalidWorkflow, got %v", tt.name, err)
+		}
+	}
+
+	wf, err := Parse([]byte("name: w\nsteps: [{id: a, task: x, timeout: 30s}, {id: b, task: y, depends_on: [a]}]"))
+	if err != nil {
+		t.Fatalf("Expected valid workflow, got %v", err)
+	}
+	if wf.Step("a").Timeout.Seconds() != 30 {
+		t.Errorf("Expected 30s timeout, got %v", wf.Step("a").Timeout)
+	}
+}
+
+func TestEngine_Run(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: chain
+params: {lang: go}
+steps:
+  - id: plan
+    task: "plan in {{.Params.lang}}"
+  - id: build
+    task: "build from {{.Steps.plan.Output}}"
+    depends_on: [plan]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	sub := newFakeSubmitter()
+	run, err := NewEngine(sub).Run(context.Background(), wf, map[string]string{"lang": "rust"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if run.Status != RunCompleted {
+		t.Errorf("Expected completed run, got %s", run.Status)
+	}
+
+	tasks := sub.submitted()
+	if len(tasks) != 2 || tasks[0] != "plan in rust" || tasks[1] != "build from done: plan in rust" {
+		t.Errorf("Expected templated tasks in dependency order, got %q", tasks)
+	}
+}
+
+func TestEngine_Resume(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: chain
+steps:
+  - {id: plan, task: "plan {{.Params.goal}}"}
+  - {id: build, task: "build from {{(index .Steps \"plan\").Output}}", depends_on: [plan]}
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	sub := newFakeSubmitter()
+	sub.fail["build"] = -1
+	engine := NewEngine(sub)
+	var saved []byte
+	engine.SetCheckpoint(func(run *Run) {
+		saved, _ = json.Marshal(run)
+	})
+	if _, err := engine.Run(context.Background(), wf, map[string]string{"goal": "x"}); err == nil {
+		t.Fatal("Expected build to fail")
+	}
+
+	var prior Run
+	if err := json.Unmarshal(saved, &prior); err != nil {
+		t.Fatalf("Expected checkpoint after plan, got %v", err)
+	}
+	if len(prior.Completed) != 1 || prior.Completed[0] != "plan" {
+		t.Fatalf("Expected plan checkpointed, got %v", prior.Completed)
+	}
+
+	sub = newFakeSubmitter()
+	run, err := NewEngine(sub).Resume(context.Background(), wf, &prior)
+	if err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if run.ID != prior.ID || run.Status != RunCompleted {
+		t.Errorf("Expected run %s completed, got %s %s", prior.ID, run.ID, run.Status)
+	}
+	tasks := sub.submitted()
+	if len(tasks) != 1 || tasks[0] != "build from done: plan x" {
+		t.Errorf("Expected only build to run, on the saved plan output, got %v", tasks)
+	}
+}
+
+func TestEngine_Compensation(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: saga
+steps:
+  - id: branch
+    task: "create branch"
+    compensate: {task: "delete branch"}
+  - id: generate
+    task: "generate files"
+    depends_on: [branch]
+    compensate: {action: cleanup}
+  - id: publish
+    task: "publish release"
+    depends_on: [generate]
+    retries: 1
+  - id: announce
+    task: "announce release"
+    depends_on: [publish]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	sub := newFakeSubmitter()
+	sub.fail["publish"] = -1
+
+	var order []string
+	engine := NewEngine(sub)
+	engine.RegisterAction("cleanup", func(ctx context.Context, run *Run, step *StepRun) error {
+		order = append(order, "cleanup:"+step.Output)
+		return nil
+	})
+
+	run, err := engine.Run(context.Background(), wf, nil)
+	if err == nil {
+		t.Fatal("Expected workflow to fail")
+	}
+	if run.Status != RunFailed {
+		t.Errorf("Expected failed run, got %s", run.Status)
+	}
+
+	want := map[string]StepStatus{
+		"branch":   StepCompensated,
+		"generate": StepCompensated,
+		"publish":  StepFailed,
+		"announce": StepSkipped,
+	}
+	for id, status := range want {
+		if got := run.Steps[id].Status; got != status {
+			t.Errorf("Expected step %s to be %s, got %s", id, status, got)
+		}
+	}
+	if run.Steps["publish"].Attempts != 2 {
+		t.Errorf("Expected 2 attempts at publish, got %d", run.Steps["publish"].Attempts)
+	}
+
+	// The action undoes generate before the task undoes branch
+	tasks := sub.submitted()
+	if len(order) != 1 || order[0] != "cleanup:done: generate files" {
+		t.Errorf("Expected cleanup action with step output, got %q", order)
+	}
+	if tasks[len(tasks)-1] != "delete branch" {
+		t.Errorf("Expected branch compensation last, got %q", tasks)
+	}
+}
+
+func TestEngine_CompensationFailure(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: saga
+steps:
+  - id: first
+    task: "first"
+    compensate: {task: "undo first"}
+  - id: second
+    task: "second"
+    compensate: {action: missing}
+  - id: third
+    task: "third"
+    depends_on: [first, second]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	sub := newFakeSubmitter()
+	sub.fail["third"] = -1
+
+	run, _ := NewEngine(sub).Run(context.Background(), wf, nil)
+
+	// An unregistered action fails its compensation but the others still run
+	if got := run.Steps["second"].Status; got != StepCompensationFailed {
+		t.Errorf("Expected compensation_failed, got %s", got)
+	}
+	if !strings.Contains(run.Steps["second"].CompensationError, ErrUnknownAction.Error()) {
+		t.Errorf("Expected unknown action error, got %q", run.Steps["second"].CompensationError)
+	}
+	if got := run.Steps["first"].Status; got != StepCompensated {
+		t.Errorf("Expected first to be compensated, got %s", got)
+	}
+}
+
+func TestCondition_Evaluate(t *testing.T) {
+	min := 0.8
+	sr := &StepRun{
+		Status:  StepCompleted,
+		Quality: 0.85,
+		Output:  "Review done.\n```json\n{\"verdict\": \"approve\", \"review\": {\"score\": 7}}\n```",
+	}
+
+	tests := []struct {
+		cond Condition
+		want bool
+	}{
+		{Condition{Field: "status", Equals: "completed"}, true},
+		{Condition{Field: "quality", Min: &min}, true},
+		{Condition{Field: "output.verdict", Equals: "approve"}, true},
+		{Condition{Field: "output.verdict", In: []string{"reject", "revise"}}, false},
+		{Condition{Field: "output.review.score", Min: &min}, true},
+		{Condition{Field: "output.missing", NotEquals: "x"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.cond.Evaluate(sr); got != tt.want {
+			t.Errorf("Expected %+v to be %v, got %v", tt.cond, tt.want, got)
+		}
+	}
+}
+
+func TestEngine_ConditionalBranch(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: branch
+steps:
+  - id: review
+    task: "review"
+  - id: ship
+    task: "ship"
+    depends_on: [review]
+    when: {step: review, field: output, equals: "done: review"}
+  - id: fix
+    task: "fix"
+    depends_on: [review]
+    when: {step: review, field: output, not_equals: "done: review"}
+  - id: fix_tests
+    task: "fix tests"
+    depends_on: [fix]
+  - id: notify
+    task: "notify"
+    depends_on: [ship, fix_tests]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	sub := newFakeSubmitter()
+	run, err := NewEngine(sub).Run(context.Background(), wf, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	want := map[string]StepStatus{
+		"review":    StepCompleted,
+		"ship":      StepCompleted,
+		"fix":       StepSkipped,
+		"fix_tests": StepSkipped, // Skipping propagates down the untaken branch
+		"notify":    StepCompleted,
+	}
+	for id, status := range want {
+		if got := run.Steps[id].Status; got != status {
+			t.Errorf("Expected step %s to be %s, got %s", id, status, got)
+		}
+	}
+	if len(sub.submitted()) != 3 {
+		t.Errorf("Expected 3 tasks, got %q", sub.submitted())
+	}
+}
+
+// revisingSubmitter improves quality by 0.2 with every task
+type revisingSubmitter struct {
+	mu      sync.Mutex
+	quality float64
+	tasks   []string
+}
+
+func (r *revisingSubmitter) SubmitCtx(ctx context.Context, task *agent.Task) (*agent.TaskResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quality += 0.2
+	r.tasks = append(r.tasks, task.Description)
+	return &agent.TaskResult{TaskID: task.ID, Status: agent.TaskCompleted, Output: "draft", Quality: r.quality}, nil
+}
+
+func TestEngine_Loop(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: revise
+steps:
+  - id: write
+    task: "{{if eq .Iteration 1}}write{{else}}revise {{.Previous.Output}} (iteration {{.Iteration}}){{end}}"
+    loop:
+      until: {field: quality, min: 0.55}
+      max_iterations: 5
+  - id: capped
+    task: "again"
+    depends_on: [write]
+    loop: {max_iterations: 2}
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	sub := &revisingSubmitter{}
+	run, err := NewEngine(sub).Run(context.Background(), wf, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if got := run.Steps["write"].Iterations; got != 3 {
+		t.Errorf("Expected loop to stop after 3 iterations, got %d", got)
+	}
+	if got := run.Steps["capped"].Iterations; got != 2 {
+		t.Errorf("Expected loop to stop at max_iterations 2, got %d", got)
+	}
+	if len(sub.tasks) != 5 || sub.tasks[2] != "revise draft (iteration 3)" {
+		t.Errorf("Expected revision tasks, got %q", sub.tasks)
+	}
+
+	if _, err := Parse([]byte("name: w\nsteps: [{id: a, task: x, loop: {max_iterations: 0}}]")); !errors.Is(err, ErrInvalidWorkflow) {
+		t.Errorf("Expected unbounded loop to be rejected, got %v", err)
+	}
+}
+
+func TestEngine_HumanStep(t *testing.T) {
+	wf, err := Parse([]byte(`
+name: deploy
+steps:
+  - id: build
+    task: "build"
+    compensate: {task: "delete build"}
+  - id: version
+    type: human
+    kind: input
+    task: "Which version?"
+    depends_on: [build]
+  - id: approve
+    type: human
+    task: "Deploy {{.Steps.version.Output}}?"
+    depends_on: [version]
+  - id: deploy
+    task: "deploy {{.Steps.version.Output}}"
+    depends_on: [approve]
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse workflow: %v", err)
+	}
+
+	answer := func(inbox *Inbox, approve bool) Notifier {
+		return func(req HumanRequest) {
+			go func() {
+				resp := HumanResponse{Value: "v2", Responder: "alice"}
+				if req.Kind == HumanApproval {
+					resp = HumanResponse{Approved: approve, Value: "not today", Responder: "alice"}
+				}
+				_ = inbox.Respond(req.ID, resp)
+			}()
+		}
+	}
+
+	// Approved: the answer flows into later steps
+	sub := newFakeSubmitter()
+	engine := NewEngine(sub)
+	engine.Inbox().OnRequest(answer(engine.Inbox(), true))
+	run, err := engine.Run(context.Background(), wf, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := sub.submitted(); got[len(got)-1] != "deploy v2" {
+		t.Errorf("Expected deploy of the version given, got %q", got)
+	}
+	if run.Steps["approve"].Responder != "alice" {
+		t.Errorf("Expected responder alice, got %q", run.Steps["approve"].Responder)
+	}
+
+	// Rejected: the step fails and completed steps are compensated
+	sub = newFakeSubmitter()
+	engine = NewEngine(sub)
+	engine.Inbox().OnRequest(answer(engine.Inbox(), false))
+	run, err = engine.Run(context.Background(), wf, nil)
+	if !errors.Is(err, ErrRejected) {
+		t.Fatalf("Expected ErrRejected, got %v", err)
+	}
+	if got := run.Steps["build"].Status; got != StepCompensated {
+		t.Errorf("Expected build to be compensated, got %s", got)
+	}
+	if got := run.Steps["deploy"].Status; got != StepSkipped {
+		t.Errorf("Expected deploy to be skipped, got %s", got)
+	}
+}
+
+func TestInbox_Respond(t *testing.T) {
+	inbox := NewInbox()
+	opened := make(chan HumanRequest, 1)
+	inbox.OnRequest(func(req HumanRequest) { opened <- req })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan HumanRequest, 1)
+	go func() {
+		req, _ := inbox.Ask(ctx, HumanRequest{StepID: "gate", Prompt: "ok?"})
+		done <- req
+	}()
+
+	req := <-opened
+	if pending := inbox.Pending(); len(pending) != 1 || pending[0].ID != req.ID {
+		t.Errorf("Expected the request to be pending, got %+v", pending)
+	}
+	if err := inbox.Respond("missing", HumanResponse{}); !errors.Is(err, ErrRequestNotFound) {
+		t.Errorf("Expected ErrRequestNotFound, got %v", err)
+	}
+
+	cancel()
+	if got := <-done; got.Status != HumanCancelled {
+		t.Errorf("Expected cancelled request, got %s", got.Status)
+	}
+	if err := inbox.Respond(req.ID, HumanResponse{Approved: true}); !errors.Is(err, ErrRequestClosed) {
+		t.Errorf("Expected ErrRequestClosed, got %v", err)
+	}
+	if len(inbox.Pending()) != 0 {
+		t.Error("Expected no pending requests")
+	}
+}